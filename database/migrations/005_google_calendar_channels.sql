@@ -0,0 +1,24 @@
+-- Migration: 005_google_calendar_channels
+-- Description: Google Calendar push notification channels and incremental sync state
+
+BEGIN;
+
+-- One row per active watch channel. sync_token is carried over when a channel is renewed.
+CREATE TABLE IF NOT EXISTS google_calendar_channels (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    calendar_id VARCHAR(255) NOT NULL DEFAULT 'primary',
+    resource_id VARCHAR(255) NOT NULL,
+    token VARCHAR(255) NOT NULL,
+    expiration TIMESTAMPTZ NOT NULL,
+    sync_token TEXT,
+    last_synced_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_google_calendar_channels_user ON google_calendar_channels(user_id);
+CREATE INDEX IF NOT EXISTS idx_google_calendar_channels_expiration ON google_calendar_channels(expiration);
+CREATE INDEX IF NOT EXISTS idx_calendar_events_google_event_id ON calendar_events(user_id, google_event_id);
+
+COMMIT;
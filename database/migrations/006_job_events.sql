@@ -0,0 +1,42 @@
+-- Migration: 006_job_events
+-- Description: Append-only job status history; jobs.status becomes a projection of the
+-- latest event
+
+-- ADD VALUE can't be used in the same transaction that adds it, so it runs first
+ALTER TYPE job_status ADD VALUE IF NOT EXISTS 'CANCELLED';
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS job_events (
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    -- 1-based and gapless per job; the primary key rejects concurrent writers that read
+    -- the same latest event
+    sequence INTEGER NOT NULL,
+    from_status job_status,
+    to_status job_status NOT NULL,
+    current_step VARCHAR(255),
+    error_message TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (job_id, sequence)
+);
+
+-- Existing jobs start their history at their current status
+INSERT INTO job_events (job_id, sequence, from_status, to_status, current_step, error_message, created_at)
+SELECT id, 1, NULL, status, current_step, error_message, COALESCE(updated_at, created_at, NOW())
+FROM jobs
+ON CONFLICT DO NOTHING;
+
+CREATE OR REPLACE FUNCTION reject_job_event_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'job_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+-- Deletes stay allowed so deleting a job cascades to its history
+CREATE TRIGGER trigger_job_events_append_only
+    BEFORE UPDATE ON job_events
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_job_event_update();
+
+COMMIT;
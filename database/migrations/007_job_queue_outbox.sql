@@ -0,0 +1,19 @@
+-- Migration: 007_job_queue_outbox
+-- Description: Jobs whose queue push failed (Redis unavailable), retried until delivered
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS job_queue_outbox (
+    job_id UUID PRIMARY KEY REFERENCES jobs(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    target_date DATE NOT NULL,
+    input_data TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_queue_outbox_next_attempt ON job_queue_outbox(next_attempt_at);
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Migration: 008_job_priority
+-- Description: Job priority; interactive jobs are queued ahead of batch re-planning
+
+BEGIN;
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS priority VARCHAR(20) NOT NULL DEFAULT 'INTERACTIVE'
+    CHECK (priority IN ('INTERACTIVE', 'BATCH'));
+
+-- Buffered jobs keep their priority until they reach the queue
+ALTER TABLE job_queue_outbox ADD COLUMN IF NOT EXISTS priority VARCHAR(20) NOT NULL DEFAULT 'INTERACTIVE'
+    CHECK (priority IN ('INTERACTIVE', 'BATCH'));
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Migration: 009_job_schedule
+-- Description: Jobs created ahead of time are enqueued at scheduled_at
+
+BEGIN;
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMPTZ;
+
+COMMIT;
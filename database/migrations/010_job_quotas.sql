@@ -0,0 +1,19 @@
+-- Migration: 010_job_quotas
+-- Description: Per-user overrides of the default job quotas, and an index for the
+-- per-day quota count
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS job_quota_overrides (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- NULL falls back to the configured default
+    max_queued_jobs INTEGER,
+    max_jobs_per_day INTEGER,
+    exempt BOOLEAN NOT NULL DEFAULT FALSE,
+    reason TEXT,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_user_created ON jobs(user_id, created_at);
+
+COMMIT;
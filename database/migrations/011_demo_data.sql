@@ -0,0 +1,11 @@
+-- Migration: 011_demo_data
+-- Description: Flag generated demo calendar events so demo generation and cleanup never
+-- touch a user's real (synced or imported) events
+
+BEGIN;
+
+ALTER TABLE calendar_events ADD COLUMN IF NOT EXISTS is_demo BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_calendar_events_user_demo ON calendar_events(user_id) WHERE is_demo;
+
+COMMIT;
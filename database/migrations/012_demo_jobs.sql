@@ -0,0 +1,11 @@
+-- Migration: 012_demo_jobs
+-- Description: Flag demo jobs seeded with canned recommendations, so demo cleanup removes
+-- them (and their recommendations) without touching jobs run by the AI service
+
+BEGIN;
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS is_demo BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_user_demo ON jobs(user_id) WHERE is_demo;
+
+COMMIT;
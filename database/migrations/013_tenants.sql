@@ -0,0 +1,69 @@
+-- Migration: 013_tenants
+-- Description: Tenants for multi-tenant deployments. Users, jobs, calendar events and
+-- recommendations carry a tenant_id; existing rows belong to the 'default' tenant. Jobs and
+-- events inherit the tenant of their user and recommendations that of their job, so
+-- writers that don't know about tenants (the AI service, background sync) can't put rows
+-- in the wrong one.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS tenants (
+    id VARCHAR(63) PRIMARY KEY CHECK (id ~ '^[a-z0-9]([a-z0-9-]*[a-z0-9])?$'),
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+INSERT INTO tenants (id, name) VALUES ('default', 'Default') ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE calendar_events ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE commute_recommendations ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+
+-- Emails and external identities are unique within a tenant rather than globally
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_email ON users(tenant_id, email);
+DROP INDEX IF EXISTS idx_users_provider_external_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_provider_external_id
+ON users(tenant_id, auth_provider, external_id)
+WHERE auth_provider != 'local';
+
+CREATE INDEX IF NOT EXISTS idx_jobs_tenant ON jobs(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_calendar_events_tenant ON calendar_events(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_commute_recommendations_tenant ON commute_recommendations(tenant_id);
+
+CREATE OR REPLACE FUNCTION inherit_user_tenant()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.tenant_id := COALESCE((SELECT tenant_id FROM users WHERE id = NEW.user_id), NEW.tenant_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION inherit_job_tenant()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.tenant_id := COALESCE((SELECT tenant_id FROM jobs WHERE id = NEW.job_id), NEW.tenant_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_jobs_tenant ON jobs;
+CREATE TRIGGER trigger_jobs_tenant
+    BEFORE INSERT ON jobs
+    FOR EACH ROW
+    EXECUTE FUNCTION inherit_user_tenant();
+
+DROP TRIGGER IF EXISTS trigger_calendar_events_tenant ON calendar_events;
+CREATE TRIGGER trigger_calendar_events_tenant
+    BEFORE INSERT ON calendar_events
+    FOR EACH ROW
+    EXECUTE FUNCTION inherit_user_tenant();
+
+DROP TRIGGER IF EXISTS trigger_commute_recommendations_tenant ON commute_recommendations;
+CREATE TRIGGER trigger_commute_recommendations_tenant
+    BEFORE INSERT ON commute_recommendations
+    FOR EACH ROW
+    EXECUTE FUNCTION inherit_job_tenant();
+
+COMMIT;
@@ -0,0 +1,30 @@
+-- Migration: 014_offices
+-- Description: Offices a tenant's users can commute to (e.g. HQ and a satellite). Users
+-- may pick a default office, and each recommendation records the office it plans for.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS offices (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id),
+    name VARCHAR(255) NOT NULL,
+    address TEXT,
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    amenities JSONB NOT NULL DEFAULT '[]',
+    capacity INTEGER CHECK (capacity >= 0),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (tenant_id, name)
+);
+
+DROP TRIGGER IF EXISTS trigger_offices_updated_at ON offices;
+CREATE TRIGGER trigger_offices_updated_at
+    BEFORE UPDATE ON offices
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS default_office_id UUID REFERENCES offices(id) ON DELETE SET NULL;
+ALTER TABLE commute_recommendations ADD COLUMN IF NOT EXISTS office_id UUID REFERENCES offices(id) ON DELETE SET NULL;
+
+COMMIT;
@@ -0,0 +1,32 @@
+-- Migration: 015_geocoding
+-- Description: Coordinates for user homes and meeting locations, and a cache of geocoding
+-- results shared by every tenant. Misses are cached too, so free-text locations like
+-- "Zoom" or "Room 4B" aren't looked up again.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS geocode_cache (
+    -- Normalised address: lowercased with whitespace collapsed
+    address_key TEXT PRIMARY KEY,
+    found BOOLEAN NOT NULL,
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    formatted_address TEXT,
+    provider VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS home_address TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS home_latitude DOUBLE PRECISION;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS home_longitude DOUBLE PRECISION;
+
+ALTER TABLE calendar_events ADD COLUMN IF NOT EXISTS location_latitude DOUBLE PRECISION;
+ALTER TABLE calendar_events ADD COLUMN IF NOT EXISTS location_longitude DOUBLE PRECISION;
+-- Set once the location has been looked up, whether or not it was found
+ALTER TABLE calendar_events ADD COLUMN IF NOT EXISTS location_geocoded_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_calendar_events_ungeocoded
+ON calendar_events(created_at)
+WHERE location IS NOT NULL AND location_geocoded_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Migration: 016_offsite_meetings
+-- Description: Meetings held away from the office (e.g. at a client) and the travel legs a
+-- recommendation plans around them, such as home -> office -> client -> home
+
+BEGIN;
+
+ALTER TABLE commute_recommendations ADD COLUMN IF NOT EXISTS offsite_meetings JSONB DEFAULT '[]';
+-- Each leg: {"from", "to", "depart", "arrive", "minutes"}
+ALTER TABLE commute_recommendations ADD COLUMN IF NOT EXISTS travel_legs JSONB DEFAULT '[]';
+
+COMMIT;
@@ -0,0 +1,25 @@
+-- Migration: 017_travel_modes
+-- Description: Per-user travel profiles listing the transport modes a user will commute by,
+-- and the mode each recommendation plans with alongside the alternatives it compared
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS travel_profiles (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- JSON array of DRIVE, TRANSIT, BIKE and WALK
+    modes JSONB NOT NULL DEFAULT '["DRIVE", "TRANSIT", "BIKE", "WALK"]',
+    preferred_mode VARCHAR(20) CHECK (preferred_mode IN ('DRIVE', 'TRANSIT', 'BIKE', 'WALK')),
+    -- Trips longer than these aren't planned by bike or on foot; NULL uses the planner's defaults
+    max_bike_minutes INTEGER CHECK (max_bike_minutes > 0),
+    max_walk_minutes INTEGER CHECK (max_walk_minutes > 0),
+    -- Parking per day and a transit fare per trip; NULL uses the planner's defaults
+    parking_cost DOUBLE PRECISION CHECK (parking_cost >= 0),
+    transit_fare DOUBLE PRECISION CHECK (transit_fare >= 0),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+ALTER TABLE commute_recommendations ADD COLUMN IF NOT EXISTS travel_mode VARCHAR(20);
+-- Each option: {"mode", "minutes", "distance_km", "cost", "co2_kg", "feasible", "chosen"}
+ALTER TABLE commute_recommendations ADD COLUMN IF NOT EXISTS mode_options JSONB DEFAULT '[]';
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- Migration: 018_commute_costs
+-- Description: Commute cost settings. Users set what driving costs them per km; offices
+-- set their daily parking and any congestion charge for driving there.
+
+BEGIN;
+
+-- Fuel and running costs per km; NULL uses the planner's default
+ALTER TABLE travel_profiles ADD COLUMN IF NOT EXISTS fuel_cost_per_km DOUBLE PRECISION CHECK (fuel_cost_per_km >= 0);
+
+-- Parking at the office per day, overriding the user's parking cost; NULL when unknown
+ALTER TABLE offices ADD COLUMN IF NOT EXISTS parking_cost DOUBLE PRECISION CHECK (parking_cost >= 0);
+-- Daily charge for driving into the office's area, e.g. a city congestion zone
+ALTER TABLE offices ADD COLUMN IF NOT EXISTS congestion_charge DOUBLE PRECISION CHECK (congestion_charge >= 0);
+
+-- commute_recommendations.mode_options entries now also carry
+-- "cost_breakdown": {"fuel", "parking", "congestion", "fares"}
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Migration: 019_calendar_event_search
+-- Description: Full-text index over calendar event summaries, descriptions and locations
+-- for event search. The expression must match searchDocument in the backend's
+-- repository/events.go for the planner to use it.
+
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_calendar_events_search ON calendar_events USING GIN (
+    to_tsvector('english', coalesce(summary, '') || ' ' || coalesce(description, '') || ' ' || coalesce(location, ''))
+);
+
+COMMIT;
@@ -0,0 +1,45 @@
+-- Migration: 020_tenant_user_settings
+-- Description: Webhook endpoints, Google Calendar channels, job quota overrides and travel
+-- profiles carry the tenant_id of their user, like jobs and calendar events, so their
+-- repositories can be scoped to the request's tenant. Existing rows take their user's tenant.
+
+BEGIN;
+
+ALTER TABLE webhook_endpoints ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE google_calendar_channels ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE job_quota_overrides ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE travel_profiles ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+
+UPDATE webhook_endpoints t SET tenant_id = u.tenant_id FROM users u WHERE u.id = t.user_id;
+UPDATE google_calendar_channels t SET tenant_id = u.tenant_id FROM users u WHERE u.id = t.user_id;
+UPDATE job_quota_overrides t SET tenant_id = u.tenant_id FROM users u WHERE u.id = t.user_id;
+UPDATE travel_profiles t SET tenant_id = u.tenant_id FROM users u WHERE u.id = t.user_id;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_tenant ON webhook_endpoints(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_google_calendar_channels_tenant ON google_calendar_channels(tenant_id);
+
+DROP TRIGGER IF EXISTS trigger_webhook_endpoints_tenant ON webhook_endpoints;
+CREATE TRIGGER trigger_webhook_endpoints_tenant
+    BEFORE INSERT ON webhook_endpoints
+    FOR EACH ROW
+    EXECUTE FUNCTION inherit_user_tenant();
+
+DROP TRIGGER IF EXISTS trigger_google_calendar_channels_tenant ON google_calendar_channels;
+CREATE TRIGGER trigger_google_calendar_channels_tenant
+    BEFORE INSERT ON google_calendar_channels
+    FOR EACH ROW
+    EXECUTE FUNCTION inherit_user_tenant();
+
+DROP TRIGGER IF EXISTS trigger_job_quota_overrides_tenant ON job_quota_overrides;
+CREATE TRIGGER trigger_job_quota_overrides_tenant
+    BEFORE INSERT ON job_quota_overrides
+    FOR EACH ROW
+    EXECUTE FUNCTION inherit_user_tenant();
+
+DROP TRIGGER IF EXISTS trigger_travel_profiles_tenant ON travel_profiles;
+CREATE TRIGGER trigger_travel_profiles_tenant
+    BEFORE INSERT ON travel_profiles
+    FOR EACH ROW
+    EXECUTE FUNCTION inherit_user_tenant();
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- Migration: 021_experiments
+-- Description: Planner A/B experiments. A job records the experiment it was planned under
+-- and the variant its user was assigned; its recommendations inherit both, so the AI
+-- service doesn't need to know about experiments to tag what it writes.
+
+BEGIN;
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS experiment VARCHAR(100);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS variant VARCHAR(100);
+ALTER TABLE commute_recommendations ADD COLUMN IF NOT EXISTS experiment VARCHAR(100);
+ALTER TABLE commute_recommendations ADD COLUMN IF NOT EXISTS variant VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_experiment ON jobs(experiment, variant) WHERE experiment IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION inherit_job_variant()
+RETURNS TRIGGER AS $$
+BEGIN
+    SELECT experiment, variant INTO NEW.experiment, NEW.variant FROM jobs WHERE id = NEW.job_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_commute_recommendations_variant ON commute_recommendations;
+CREATE TRIGGER trigger_commute_recommendations_variant
+    BEFORE INSERT ON commute_recommendations
+    FOR EACH ROW
+    EXECUTE FUNCTION inherit_job_variant();
+
+COMMIT;
@@ -0,0 +1,27 @@
+-- Migration: 022_preference_feedback
+-- Description: Corrections users make to the preferences learned from their commute
+-- history. A learned preference is keyed by what it is about (e.g. REMOTE_WEEKDAY:FRIDAY);
+-- users confirm it, optionally with a corrected time or mode, or reject it so planning
+-- ignores it.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS preference_feedback (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('CONFIRMED', 'REJECTED')),
+    -- HH:MM in the user's timezone, for ARRIVE_BEFORE
+    time VARCHAR(5),
+    mode VARCHAR(20) CHECK (mode IN ('DRIVE', 'TRANSIT', 'BIKE', 'WALK')),
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, key)
+);
+
+DROP TRIGGER IF EXISTS trigger_preference_feedback_tenant ON preference_feedback;
+CREATE TRIGGER trigger_preference_feedback_tenant
+    BEFORE INSERT ON preference_feedback
+    FOR EACH ROW
+    EXECUTE FUNCTION inherit_user_tenant();
+
+COMMIT;
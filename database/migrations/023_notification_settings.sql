@@ -0,0 +1,26 @@
+-- Migration: 023_notification_settings
+-- Description: Per-user notification opt-ins. Users who turn on the weekly digest are
+-- emailed a summary of the coming week on Sunday evening in their timezone;
+-- last_digest_week records the week last sent so each week goes out once, even with
+-- several backend instances.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    weekly_digest BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Monday of the week the last digest covered
+    last_digest_week DATE,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_settings_weekly_digest ON notification_settings(user_id) WHERE weekly_digest;
+
+DROP TRIGGER IF EXISTS trigger_notification_settings_tenant ON notification_settings;
+CREATE TRIGGER trigger_notification_settings_tenant
+    BEFORE INSERT ON notification_settings
+    FOR EACH ROW
+    EXECUTE FUNCTION inherit_user_tenant();
+
+COMMIT;
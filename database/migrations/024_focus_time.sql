@@ -0,0 +1,9 @@
+-- Migration: 024_focus_time
+-- Description: The shortest deep-work block a user wants kept free each day. The planner
+-- prefers options whose travel leaves a free block at least this long.
+
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS focus_minutes INTEGER CHECK (focus_minutes > 0);
+
+COMMIT;
@@ -0,0 +1,10 @@
+-- Migration: 025_json_columns
+-- Description: Store the job outbox's input data as JSONB like jobs.input_data. It was the
+-- last JSON column kept as TEXT; SQLite stores JSON as TEXT throughout.
+
+BEGIN;
+
+ALTER TABLE job_queue_outbox
+    ALTER COLUMN input_data TYPE JSONB USING NULLIF(input_data, '')::jsonb;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Migration: 026_row_versions
+-- Description: Version jobs, users and calendar events for optimistic concurrency. Each
+-- update increments the version; an update that names the version it read is rejected
+-- when the row has moved on since.
+
+BEGIN;
+
+ALTER TABLE jobs ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE calendar_events ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+COMMIT;
@@ -0,0 +1,39 @@
+-- Migration: 027_job_retention
+-- Description: Cold storage for finished jobs past their retention period. The archiver
+-- moves a job, its recommendations and its status history into job_archive as JSON and
+-- deletes the originals; retention_runs records each archival run for operators.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS job_archive (
+    job_id UUID PRIMARY KEY,
+    -- Archived jobs go with their user
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id),
+    status job_status NOT NULL,
+    target_date DATE NOT NULL,
+    job JSONB NOT NULL,
+    recommendations JSONB NOT NULL DEFAULT '[]',
+    events JSONB NOT NULL DEFAULT '[]',
+    archived_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_archive_user ON job_archive(user_id, target_date);
+
+CREATE TABLE IF NOT EXISTS retention_runs (
+    id UUID PRIMARY KEY,
+    triggered_by VARCHAR(20) NOT NULL CHECK (triggered_by IN ('SCHEDULE', 'ADMIN')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('RUNNING', 'COMPLETED', 'FAILED')),
+    -- Finished jobs last updated before these were archived; NULL when the class is kept
+    completed_before TIMESTAMPTZ,
+    failed_before TIMESTAMPTZ,
+    jobs_archived INTEGER NOT NULL DEFAULT 0,
+    recommendations_archived INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_runs_started ON retention_runs(started_at DESC);
+
+COMMIT;
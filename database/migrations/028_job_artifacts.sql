@@ -0,0 +1,22 @@
+-- Migration: 028_job_artifacts
+-- Description: References to large job artifacts (reasoning traces, route maps) the AI
+-- worker uploads to object storage. Only the object key is stored here; the backend hands
+-- out presigned URLs to upload and download the object itself.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS job_artifacts (
+    id UUID PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL CHECK (kind IN ('REASONING_TRACE', 'ROUTE_MAP')),
+    -- Key of the object in the artifact bucket; objects outlive their rows, so expire
+    -- them with a bucket lifecycle rule at least as long as the job retention period
+    storage_key TEXT NOT NULL UNIQUE,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_artifacts_job ON job_artifacts(job_id, created_at);
+
+COMMIT;
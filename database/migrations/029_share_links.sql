@@ -0,0 +1,19 @@
+-- Migration: 029_share_links
+-- Description: Read-only links to a job's recommendations, shared without signing in.
+-- The URL carries an HMAC signature of the link ID and expiry; the row lets its owner
+-- list and revoke links before they expire.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS share_links (
+    id UUID PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_job ON share_links(job_id, created_at);
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Migration: 030_calendar_feeds
+-- Description: Secret ICS feed URLs users subscribe to in their calendar apps. Only a
+-- SHA-256 hash of each feed's token is stored; the URL is shown once, when created.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+COMMIT;
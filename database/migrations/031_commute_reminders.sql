@@ -0,0 +1,30 @@
+-- Migration: 031_commute_reminders
+-- Description: Reminders to leave, sent a user-chosen number of minutes before each
+-- departure of an accepted plan over the channels the user picked. Accepting another
+-- option for the day cancels the reminders still scheduled for it.
+
+BEGIN;
+
+ALTER TABLE notification_settings ADD COLUMN IF NOT EXISTS commute_reminders BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notification_settings ADD COLUMN IF NOT EXISTS reminder_lead_minutes INTEGER NOT NULL DEFAULT 15;
+ALTER TABLE notification_settings ADD COLUMN IF NOT EXISTS reminder_channels TEXT[] NOT NULL DEFAULT '{EMAIL}';
+
+CREATE TABLE IF NOT EXISTS commute_reminders (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recommendation_id UUID NOT NULL REFERENCES commute_recommendations(id) ON DELETE CASCADE,
+    target_date DATE NOT NULL,
+    summary TEXT NOT NULL,
+    depart_at TIMESTAMPTZ NOT NULL,
+    remind_at TIMESTAMPTZ NOT NULL,
+    channels TEXT[] NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'SCHEDULED' CHECK (status IN ('SCHEDULED', 'SENT', 'CANCELLED', 'FAILED')),
+    error_message TEXT,
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_commute_reminders_due ON commute_reminders(remind_at) WHERE status = 'SCHEDULED';
+CREATE INDEX IF NOT EXISTS idx_commute_reminders_user ON commute_reminders(user_id, target_date);
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Migration: 032_job_follow_ups
+-- Description: Links a job re-optimizing the rest of a day (replanNow) to the job whose
+-- plan it follows up, so clients can show the two together.
+
+BEGIN;
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS follow_up_of UUID REFERENCES jobs(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_follow_up_of ON jobs(follow_up_of) WHERE follow_up_of IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,15 @@
+-- Migration: 033_org_reporting
+-- Description: Org-level reporting. Org admins see aggregate reports on their tenant's
+-- accepted plans; tenants may set an office-day policy the reports measure compliance
+-- with, and users may opt out of being counted.
+
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_org_admin BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS org_reporting_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The office days a week the tenant expects of its users; NULL without a policy
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS office_days_per_week INTEGER
+    CHECK (office_days_per_week BETWEEN 1 AND 7);
+
+COMMIT;
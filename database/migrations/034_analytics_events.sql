@@ -0,0 +1,25 @@
+-- Migration: 034_analytics_events
+-- Description: Anonymized product analytics. Events carry a salted hash of the user ID,
+-- never the ID itself, and are kept here until forwarded to the configured sink, if any.
+-- Users may opt out of analytics altogether.
+
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS analytics_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS analytics_events (
+    id UUID PRIMARY KEY,
+    event VARCHAR(100) NOT NULL,
+    anonymous_id VARCHAR(64) NOT NULL,
+    properties JSONB NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMPTZ NOT NULL,
+    -- When the event was forwarded to the analytics sink; NULL until then, and for good
+    -- when events are only kept here
+    forwarded_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_analytics_events_unforwarded ON analytics_events(occurred_at) WHERE forwarded_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_analytics_events_event ON analytics_events(event, occurred_at);
+
+COMMIT;
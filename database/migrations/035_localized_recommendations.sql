@@ -0,0 +1,14 @@
+-- Migration: 035_localized_recommendations
+-- Description: Localized recommendation text. Recommendations keep the structured reason
+-- codes their reasoning and trade-offs were written from, which the backend renders in
+-- the locale each user picked.
+
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(16);
+
+-- [{"code": "FULL_DAY_OFFICE", "params": {"officeMeetings": 3}}, ...]; NULL for
+-- recommendations planned before reason codes existed
+ALTER TABLE commute_recommendations ADD COLUMN IF NOT EXISTS reason_codes JSONB;
+
+COMMIT;
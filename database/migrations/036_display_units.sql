@@ -0,0 +1,13 @@
+-- Migration: 036_display_units
+-- Description: Display preferences for what the backend renders itself: digest emails,
+-- reminder notifications and calendar feeds. NULL keeps the defaults of kilometres,
+-- amounts without a currency and 24-hour times.
+
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS distance_unit VARCHAR(2) CHECK (distance_unit IN ('KM', 'MI')),
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) CHECK (currency ~ '^[A-Z]{3}$'),
+    ADD COLUMN IF NOT EXISTS time_format VARCHAR(16) CHECK (time_format IN ('TWENTY_FOUR_HOUR', 'TWELVE_HOUR'));
+
+COMMIT;
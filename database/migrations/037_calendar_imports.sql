@@ -0,0 +1,25 @@
+-- Migration: 037_calendar_imports
+-- Description: Calendar files users import through the GraphQL endpoint. Each import is
+-- recorded with its progress, which clients poll while its events are added.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS calendar_imports (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename TEXT NOT NULL,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('ICS')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('RUNNING', 'COMPLETED', 'FAILED')),
+    total_events INTEGER NOT NULL DEFAULT 0,
+    processed_events INTEGER NOT NULL DEFAULT 0,
+    created_events INTEGER NOT NULL DEFAULT 0,
+    -- [{"index": 3, "uid": "...", "message": "..."}, ...]: the events that weren't imported
+    errors JSONB NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendar_imports_user ON calendar_imports(user_id, started_at DESC);
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Migration: 038_job_replays
+-- Description: Links a job created by replayJob to the job whose inputs it replays, so
+-- clients can show a job's lineage, e.g. after a planner upgrade or a failed run.
+
+BEGIN;
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS replay_of UUID REFERENCES jobs(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_replay_of ON jobs(replay_of) WHERE replay_of IS NOT NULL;
+
+COMMIT;
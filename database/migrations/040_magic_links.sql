@@ -0,0 +1,17 @@
+-- Migration: 040_magic_links
+-- Description: Passwordless login links emailed to users. The link's token is signed;
+-- the row makes it single-use.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS magic_links (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_magic_links_user ON magic_links(user_id, created_at DESC);
+
+COMMIT;
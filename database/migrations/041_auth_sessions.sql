@@ -0,0 +1,21 @@
+-- Migration: 041_auth_sessions
+-- Description: The sessions the jwt auth provider issues tokens for, with the device they
+-- were started from, so users can see where they're logged in and end single sessions.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS auth_sessions (
+    -- The jti of the session's token
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user ON auth_sessions(user_id, created_at DESC);
+
+COMMIT;
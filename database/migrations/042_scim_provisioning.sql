@@ -0,0 +1,17 @@
+-- Migration: 042_scim_provisioning
+-- Description: SCIM provisioning of users by a tenant's identity provider. Tenants get a
+-- hashed SCIM bearer token; users get the identity provider's ID for them and can be
+-- deactivated, which stops them signing in while keeping their data.
+
+BEGIN;
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS scim_token_hash TEXT UNIQUE;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS scim_external_id TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_scim_external_id
+ON users(tenant_id, scim_external_id)
+WHERE scim_external_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Migration: 043_encrypted_oauth_tokens
+-- Description: Store users' OAuth tokens as TEXT, since the backend encrypts them before
+-- writing them when data_encryption_keys is configured and ciphertext isn't JSON. SQLite
+-- already stores the column as TEXT.
+
+BEGIN;
+
+ALTER TABLE users
+    ALTER COLUMN oauth_tokens TYPE TEXT USING oauth_tokens::text;
+
+COMMENT ON COLUMN users.oauth_tokens IS 'OAuth access/refresh tokens JSON, encrypted (enc:v1:<key id>:...) when encryption keys are configured';
+
+COMMIT;
@@ -0,0 +1,10 @@
+-- Migration: 044_share_redaction
+-- Description: What a user's share links leave out of their plans. NULL strips meeting
+-- titles, attendees and the text written about them (MEETING_DETAILS).
+
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS share_redaction VARCHAR(16) CHECK (share_redaction IN ('NONE', 'MEETING_DETAILS', 'TIME_BLOCKS'));
+
+COMMIT;
@@ -0,0 +1,23 @@
+-- Migration: 045_deprecated_field_usage
+-- Description: Daily counts of the requests selecting each deprecated GraphQL field or
+-- argument, by the client that sent them, so a field is only removed once no client
+-- still uses it.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS deprecated_field_usage (
+    -- The field or argument, e.g. CommuteRecommendation.travelLegs
+    coordinate VARCHAR(255) NOT NULL,
+    -- From the X-Client-Name and X-Client-Version headers; 'unknown' without them
+    client_name VARCHAR(100) NOT NULL,
+    client_version VARCHAR(100) NOT NULL,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    first_used_at TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (coordinate, client_name, client_version, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deprecated_field_usage_day ON deprecated_field_usage(day);
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- Migration: 046_client_usage
+-- Description: The client application (X-Client-Name and X-Client-Version headers) jobs
+-- were created from and analytics events were recorded in, and daily counts of the GraphQL
+-- operations each client version runs, so breaking changes can be timed by who they break.
+
+BEGIN;
+
+-- NULL for jobs and events not started by a client request, e.g. scheduled re-plans
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS client_name VARCHAR(100);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS client_version VARCHAR(100);
+ALTER TABLE analytics_events ADD COLUMN IF NOT EXISTS client_name VARCHAR(100);
+ALTER TABLE analytics_events ADD COLUMN IF NOT EXISTS client_version VARCHAR(100);
+
+CREATE TABLE IF NOT EXISTS client_operation_usage (
+    -- 'unknown' for clients that don't identify themselves
+    client_name VARCHAR(100) NOT NULL,
+    client_version VARCHAR(100) NOT NULL,
+    -- The operation's name, or else its first field
+    operation VARCHAR(100) NOT NULL,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    first_used_at TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (client_name, client_version, operation, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_client_operation_usage_day ON client_operation_usage(day);
+
+COMMIT;
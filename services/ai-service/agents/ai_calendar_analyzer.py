@@ -15,6 +15,8 @@ from langchain.prompts import ChatPromptTemplate
 from tools.google_calendar_mock import MockGoogleCalendarTool
 from services.backend_service import BackendService
 from utils.event_normalizer import EventNormalizer
+from utils.all_day import day_meetings
+from utils.replan import remaining_meetings
 
 logger = logging.getLogger(__name__)
 
@@ -124,6 +126,12 @@ Format your response as structured analysis, not just a list.""")
                 logger.info("No database events found, generating mock calendar data")
                 calendar_events = await self.calendar_tool.get_calendar_events(target_date)
             
+            # All-day entries mark the day rather than take up time in it
+            calendar_events, day_markers = day_meetings(calendar_events, state.get("input_data", {}))
+            state["day_markers"] = day_markers
+            # A re-plan only plans the rest of the day
+            calendar_events = remaining_meetings(calendar_events, state.get("input_data", {}))
+            
             if not calendar_events:
                 logger.warning("No calendar events found")
                 state["calendar_events"] = []
@@ -12,6 +12,14 @@ from langchain_core.language_models import BaseLanguageModel
 from langchain.prompts import ChatPromptTemplate
 
 from tools.google_maps_mock import MockGoogleMapsTool
+from utils.constraints import (
+    get_constraints, get_preferences, get_timezone, constrained_profile, check_constraints,
+    constraints_prompt, preferences_prompt
+)
+from utils.focus_time import get_focus, check_focus
+from utils.office_choice import get_offices, office_destination, choose_office
+from utils.offsite import offsite_meetings, plan_travel_legs
+from utils.travel_modes import get_travel_profile, choose_travel_mode
 
 logger = logging.getLogger(__name__)
 
@@ -45,6 +53,10 @@ OFFICE PRESENCE BLOCKS:
 
 TARGET DATE: {target_date}
 USER CONTEXT: {user_id}
+USER CONSTRAINTS (times in the user's timezone; plans must respect these):
+{constraints}
+LEARNED PREFERENCES (soft; habits from the user's history, to follow unless a constraint or meeting requires otherwise):
+{preferences}
 TIMEZONE CONTEXT:
 - User timezone: {user_timezone}
 - All times should be interpreted in the user's local timezone
@@ -97,10 +109,26 @@ Generate detailed, actionable commute strategies.""")
                 self.maps_tool = MockGoogleMapsTool(user_id)
             
             # AI-POWERED OPTIMIZATION: Use LLM for intelligent commute planning
-            ai_optimizations = await self._optimize_with_ai(presence_blocks, target_date, user_id, user_timezone)
+            constraints = get_constraints(state.get("input_data", {}))
+            preferences = get_preferences(state.get("input_data", {}))
+            ai_optimizations = await self._optimize_with_ai(
+                presence_blocks, target_date, user_id, user_timezone, constraints, preferences
+            )
+            
+            # Process AI optimizations with real route data, comparing offices when the tenant has several
+            offices, default_office_id = get_offices(state.get("input_data", {}))
+            commute_options = await self._process_ai_optimizations(
+                ai_optimizations, presence_blocks, target_date, user_timezone, offices, default_office_id,
+                offsite_meetings(state.get("meeting_classifications", [])),
+                get_travel_profile(state.get("input_data", {})),
+                constraints, get_timezone(state.get("input_data", {}))
+            )
             
-            # Process AI optimizations with real route data
-            commute_options = await self._process_ai_optimizations(ai_optimizations, presence_blocks, target_date, user_timezone)
+            # Find the focus windows each option's travel leaves
+            focus = get_focus(state.get("input_data", {}))
+            commute_options = [
+                check_focus(option, focus, get_timezone(state.get("input_data", {}))) for option in commute_options
+            ]
             
             # Update state with AI insights
             state["commute_options"] = commute_options
@@ -121,7 +149,7 @@ Generate detailed, actionable commute strategies.""")
             state["error_message"] = f"AI commute optimization failed: {str(e)}"
             return state
     
-    async def _optimize_with_ai(self, presence_blocks: List[Dict[str, Any]], target_date: str, user_id: str, user_timezone: str = "UTC") -> Dict[str, Any]:
+    async def _optimize_with_ai(self, presence_blocks: List[Dict[str, Any]], target_date: str, user_id: str, user_timezone: str = "UTC", constraints: List[Dict[str, Any]] = None, preferences: List[Dict[str, Any]] = None) -> Dict[str, Any]:
         """Use LLM for intelligent commute optimization with timezone awareness"""
         
         try:
@@ -145,7 +173,9 @@ Generate detailed, actionable commute strategies.""")
                 presence_blocks_json=blocks_json,
                 target_date=target_date,
                 user_id=user_id,
-                user_timezone=user_timezone
+                user_timezone=user_timezone,
+                constraints=constraints_prompt(constraints or []),
+                preferences=preferences_prompt(preferences or [])
             )
             
             # Get AI optimization analysis
@@ -175,26 +205,59 @@ Generate detailed, actionable commute strategies.""")
                 "environmental_analysis": {}
             }
     
-    async def _process_ai_optimizations(self, ai_data: Dict[str, Any], presence_blocks: List[Dict[str, Any]], target_date: str, user_timezone: str = "UTC") -> List[Dict[str, Any]]:
-        """Process AI optimizations with real route data"""
+    async def _process_ai_optimizations(self, ai_data: Dict[str, Any], presence_blocks: List[Dict[str, Any]], target_date: str, user_timezone: str = "UTC", offices: List[Dict[str, Any]] = None, default_office_id: str = None, meetings: List[Dict[str, Any]] = None, profile: Dict[str, Any] = None, constraints: List[Dict[str, Any]] = None, tz: ZoneInfo = None) -> List[Dict[str, Any]]:
+        """Process AI optimizations with real route data, routing via any offsite meetings,
+        picking each option's travel mode and checking it against the job's constraints"""
         
         commute_options = []
+        meetings = meetings or []
+        profile = profile or get_travel_profile({})
         
         for block in presence_blocks:
             if block.get("type") == "FULL_REMOTE_RECOMMENDED":
                 # Create remote work option
                 remote_option = await self._create_remote_option(block, ai_data, target_date, user_timezone)
-                commute_options.append(remote_option)
+                commute_options.append(await self._route_day(remote_option, meetings, profile, None, constraints, tz))
+            elif offices:
+                # Plan the block for every office, including mid-day travel, and keep the best one
+                candidates = []
+                for office in offices:
+                    office_option = await self._create_office_option(block, ai_data, target_date, user_timezone, office)
+                    office_option = await self._route_day(office_option, meetings, profile, office, constraints, tz)
+                    candidates.append((office, office_option))
+                commute_options.append(choose_office(candidates, default_office_id))
             else:
                 # Create office commute option with AI optimization
                 office_option = await self._create_office_option(block, ai_data, target_date, user_timezone)
-                commute_options.append(office_option)
+                commute_options.append(await self._route_day(office_option, meetings, profile, None, constraints, tz))
         
         return commute_options
     
-    async def _create_office_option(self, presence_block: Dict[str, Any], ai_data: Dict[str, Any], target_date: str, user_timezone: str = "UTC") -> Dict[str, Any]:
+    async def _route_day(
+        self,
+        option: Dict[str, Any],
+        meetings: List[Dict[str, Any]],
+        profile: Dict[str, Any],
+        office: Dict[str, Any] = None,
+        constraints: List[Dict[str, Any]] = None,
+        tz: ZoneInfo = None
+    ) -> Dict[str, Any]:
+        """Route an option's day to the office (the generic "office" when there are none) via
+        any offsite meetings, pick its travel mode without the modes the job's constraints
+        avoid, and check it against the constraints"""
+        
+        constraints = constraints or []
+        
+        destination = office_destination(office)
+        option = await plan_travel_legs(self.maps_tool, option, meetings, destination)
+        option = await choose_travel_mode(self.maps_tool, option, constrained_profile(profile, constraints), destination, office)
+        return check_constraints(option, constraints, tz)
+    
+    async def _create_office_option(self, presence_block: Dict[str, Any], ai_data: Dict[str, Any], target_date: str, user_timezone: str = "UTC", office: Dict[str, Any] = None) -> Dict[str, Any]:
         """Create AI-optimized office commute option with timezone awareness"""
         
+        destination = office_destination(office)
+        
         try:
             arrival_hour = presence_block.get("arrival_hour", 9)
             departure_hour = presence_block.get("departure_hour", 17)
@@ -233,14 +296,14 @@ Generate detailed, actionable commute strategies.""")
             
             # Get AI-informed optimal departure time (convert to UTC for API)
             commute_start_info = await self.maps_tool.calculate_optimal_departure_time(
-                destination="office",
+                destination=destination,
                 target_arrival=office_arrival_dt.astimezone(ZoneInfo("UTC")).isoformat(),
                 origin="home"
             )
             
             # Get return journey with AI considerations (convert to UTC for API)
             return_commute_info = await self.maps_tool.get_route_duration(
-                origin="office",
+                origin=destination,
                 destination="home",
                 departure_time=office_departure_dt.astimezone(ZoneInfo("UTC")).isoformat()
             )
@@ -261,7 +324,7 @@ Generate detailed, actionable commute strategies.""")
             # Get AI-enhanced route alternatives (convert to UTC for API)
             route_alternatives = await self.maps_tool.get_multiple_route_options(
                 origin="home",
-                destination="office", 
+                destination=destination,
                 departure_time=commute_start_dt.astimezone(ZoneInfo("UTC")).isoformat()
             )
             
@@ -396,7 +459,8 @@ Generate detailed, actionable commute strategies.""")
             
             "ai_confidence": ai_data.get("confidence", 0.9),
             "warnings": presence_block.get("warnings", []),
-            "compliance_score": presence_block.get("compliance_score", 1.0)
+            "compliance_score": presence_block.get("compliance_score", 1.0),
+            "day_note": presence_block.get("day_note")
         }
     
     def _parse_ai_optimizations(self, ai_response: str) -> Dict[str, Any]:
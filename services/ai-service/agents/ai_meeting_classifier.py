@@ -11,6 +11,8 @@ from zoneinfo import ZoneInfo
 from langchain_core.language_models import BaseLanguageModel
 from langchain.prompts import ChatPromptTemplate
 from utils.event_normalizer import EventNormalizer
+from utils.office_choice import get_offices
+from utils.offsite import detect_offsite
 
 logger = logging.getLogger(__name__)
 
@@ -81,7 +83,8 @@ Return a structured JSON response with classifications.""")
             ai_classifications = await self._classify_meetings_with_ai(calendar_events, user_timezone)
             
             # Process AI classifications into standard format
-            meeting_classifications = self._process_ai_classifications(calendar_events, ai_classifications)
+            offices, _ = get_offices(state.get("input_data", {}))
+            meeting_classifications = self._process_ai_classifications(calendar_events, ai_classifications, offices)
             
             # Update state with AI insights
             state["meeting_classifications"] = meeting_classifications
@@ -92,10 +95,12 @@ Return a structured JSON response with classifications.""")
             # Analyze overall meeting distribution
             office_meetings = [m for m in meeting_classifications if m["requires_office"]]
             remote_meetings = [m for m in meeting_classifications if not m["requires_office"]]
+            offsite = [m for m in meeting_classifications if m["location_type"] == "offsite"]
             
             state["ai_insights"]["meeting_distribution"] = {
                 "office_required": len(office_meetings),
                 "remote_viable": len(remote_meetings),
+                "offsite": len(offsite),
                 "total_meetings": len(meeting_classifications)
             }
             
@@ -103,7 +108,7 @@ Return a structured JSON response with classifications.""")
             
             logger.info(
                 f"AI meeting classification complete: {len(office_meetings)} office-required, "
-                f"{len(remote_meetings)} remote-viable"
+                f"{len(remote_meetings)} remote-viable ({len(offsite)} offsite)"
             )
             
             return state
@@ -197,7 +202,12 @@ Return a structured JSON response with classifications.""")
         
         return classifications
     
-    def _process_ai_classifications(self, meetings: List[Dict[str, Any]], ai_data: Dict[str, Any]) -> List[Dict[str, Any]]:
+    def _process_ai_classifications(
+        self,
+        meetings: List[Dict[str, Any]],
+        ai_data: Dict[str, Any],
+        offices: List[Dict[str, Any]]
+    ) -> List[Dict[str, Any]]:
         """Process AI classifications into standard format"""
         
         classifications = []
@@ -228,6 +238,19 @@ Return a structured JSON response with classifications.""")
                 final_confidence = ai_result.get("confidence", 0.7)
                 final_reasoning = ai_result.get("reasoning", "AI classification applied")
                 classification_method = "ai_llm_powered"
+
+            # Meetings held at an external location are attended in person, but not at the office
+            offsite = None
+            if not existing_attendance_mode or existing_attendance_mode in ["UNKNOWN", "FLEXIBLE"]:
+                offsite = detect_offsite(normalized_meeting, offices)
+            if offsite:
+                final_requires_office = False
+                final_attendance_mode = "OFFSITE_IN_PERSON"
+                final_reasoning = offsite["reason"]
+                classification_method = "offsite_location"
+                location_type = "offsite"
+            else:
+                location_type = "office" if final_requires_office else "remote"
             
             classification = {
                 "meeting_id": meeting_id,
@@ -240,6 +263,9 @@ Return a structured JSON response with classifications.""")
                 # Final classification decisions (respects demo data)
                 "requires_office": final_requires_office,
                 "attendance_mode": final_attendance_mode,
+                "location_type": location_type,
+                "location": normalized_meeting.get("location", ""),
+                "offsite": offsite,
                 "business_impact": ai_result.get("business_impact", "Medium"),
                 "collaboration_intensity": ai_result.get("collaboration_intensity", "Medium"),
                 
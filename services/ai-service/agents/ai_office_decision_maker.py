@@ -10,6 +10,8 @@ from typing import Dict, Any, List
 from langchain_core.language_models import BaseLanguageModel
 from langchain.prompts import ChatPromptTemplate
 
+from utils.all_day import get_day, day_remote_block
+
 logger = logging.getLogger(__name__)
 
 
@@ -70,6 +72,14 @@ Format as structured analysis with clear recommendations.""")
             state["progress_step"] = "AI determining optimal office presence strategy"
             state["progress_percentage"] = 0.55
             
+            # Out of office, or blocked for remote work: the calendar already decided
+            day = get_day(state.get("input_data", {}))
+            if day:
+                logger.info(f"{day.get('reason')}: planning the day remote")
+                state["office_presence_blocks"] = [day_remote_block(day, meeting_classifications)]
+                state["progress_percentage"] = 0.65
+                return state
+            
             if not meeting_classifications:
                 logger.warning("No meeting classifications to analyze")
                 state["office_presence_blocks"] = []
@@ -10,6 +10,12 @@ from typing import Dict, Any, List
 from langchain_core.language_models import BaseLanguageModel
 from langchain.prompts import ChatPromptTemplate
 
+from utils.all_day import with_day_note
+from utils.constraints import describe_constraint_results
+from utils.focus_time import splits_focus, describe_focus
+from utils.meeting_load import get_meeting_load_warnings
+from utils.travel_modes import chosen_cost, describe_cost
+
 logger = logging.getLogger(__name__)
 
 
@@ -88,6 +94,12 @@ Make it personal, practical, and persuasive while maintaining professional tone.
             # Process AI presentation into structured recommendations
             recommendations = self._process_ai_presentation(ai_presentation, commute_options)
             
+            # A heavy day of meetings is a trade-off of every option
+            meeting_load = get_meeting_load_warnings(state.get("input_data", {}))
+            if meeting_load:
+                for recommendation in recommendations:
+                    recommendation["considerations"] = list(recommendation.get("considerations", [])) + meeting_load
+            
             # Update state with AI insights
             state["recommendations"] = recommendations
             state["llm_reasoning"]["recommendation_presentation"] = ai_presentation["reasoning"]
@@ -232,8 +244,9 @@ Make it personal, practical, and persuasive while maintaining professional tone.
             recommendations.append({
                 "rank": 1,
                 "option_type": primary_option.get("option_type", ""),
+                "office": primary_option.get("office"),
                 "title": self._generate_recommendation_title(primary_option),
-                "ai_summary": self._extract_ai_summary(ai_response, primary_option),
+                "ai_summary": self._with_constraints(self._extract_ai_summary(ai_response, primary_option), primary_option),
                 "detailed_schedule": self._create_detailed_schedule(primary_option),
                 "key_benefits": self._extract_benefits(ai_response, primary_option),
                 "considerations": self._extract_considerations(ai_response, primary_option),
@@ -249,8 +262,9 @@ Make it personal, practical, and persuasive while maintaining professional tone.
                 recommendations.append({
                     "rank": i + 2,
                     "option_type": option.get("option_type", ""),
+                    "office": option.get("office"),
                     "title": self._generate_recommendation_title(option),
-                    "ai_summary": f"Alternative option with different trade-offs",
+                    "ai_summary": self._with_constraints("Alternative option with different trade-offs", option),
                     "detailed_schedule": self._create_detailed_schedule(option),
                     "key_benefits": self._extract_benefits(ai_response, option),
                     "considerations": option.get("warnings", []),
@@ -275,6 +289,12 @@ Make it personal, practical, and persuasive while maintaining professional tone.
             if compliant_options:
                 best_option = max(compliant_options, key=lambda x: x.get("ai_confidence", 0))
         
+        # Prefer an option that keeps a block free for the user's focus time, when any does
+        if best_option and splits_focus(best_option):
+            keeping = [opt for opt in options if not splits_focus(opt)]
+            if keeping:
+                best_option = max(keeping, key=lambda x: (x.get("compliance_score", 0) >= 0.8, x.get("ai_confidence", 0)))
+        
         return best_option
     
     def _generate_recommendation_title(self, option: Dict[str, Any]) -> str:
@@ -308,18 +328,29 @@ Make it personal, practical, and persuasive while maintaining professional tone.
         else:
             return f"AI-optimized schedule with {option.get('office_duration', 'flexible')} office presence"
     
+    def _with_constraints(self, summary: str, option: Dict[str, Any]) -> str:
+        """Echo the job's constraints, and whether the option meets them, and the focus
+        windows it keeps after a summary, led by the calendar's reason when an all-day entry
+        decided the day"""
+        
+        summary = with_day_note(summary, option)
+        for note in (describe_constraint_results(option), describe_focus(option)):
+            if note:
+                summary = f"{summary} {note}"
+        return summary
+    
     def _create_detailed_schedule(self, option: Dict[str, Any]) -> Dict[str, Any]:
         """Create detailed schedule from option data"""
         
         if option.get("option_type") == "FULL_REMOTE_RECOMMENDED":
-            return {
+            schedule = {
                 "work_location": "Home",
                 "schedule": "Flexible 8-hour workday",
                 "commute_time": "0 minutes",
                 "key_activities": ["Remote meetings", "Focused work", "Flexible scheduling"]
             }
         else:
-            return {
+            schedule = {
                 "work_location": "Hybrid (Home + Office)",
                 "commute_start": option.get("commute_start", "TBD"),
                 "office_arrival": option.get("office_arrival", "TBD"),
@@ -328,6 +359,20 @@ Make it personal, practical, and persuasive while maintaining professional tone.
                 "office_duration": option.get("office_duration", "TBD"),
                 "total_day_duration": self._calculate_total_day_duration(option)
             }
+
+        if option.get("travel_mode"):
+            schedule["travel_mode"] = option["travel_mode"]
+            schedule["mode_options"] = option.get("mode_options", [])
+            schedule["commute_cost"] = chosen_cost(option)
+
+        # Offsite meetings add mid-day travel, e.g. home -> office -> client -> home
+        if option.get("travel_legs"):
+            schedule["commute_time"] = f"{option.get('efficiency_metrics', {}).get('total_commute_minutes', 0)} minutes"
+            schedule["travel_legs"] = option["travel_legs"]
+            schedule["offsite_meetings"] = [m.get("summary", "") for m in option.get("offsite_meetings", [])]
+        if option.get("constraints"):
+            schedule["constraints"] = option["constraints"]
+        return schedule
     
     def _extract_benefits(self, ai_response: str, option: Dict[str, Any]) -> List[str]:
         """Extract key benefits from AI analysis"""
@@ -373,6 +418,9 @@ Make it personal, practical, and persuasive while maintaining professional tone.
             commute_minutes = option.get("efficiency_metrics", {}).get("total_commute_minutes", 0)
             if commute_minutes > 90:
                 considerations.append(f"Significant commute time: {commute_minutes} minutes total")
+            cost = chosen_cost(option)
+            if cost and cost["total"] > 0:
+                considerations.append(f"Commute costs {describe_cost(cost)}")
         
         return considerations[:4]  # Limit to 4 considerations
     
@@ -441,8 +489,9 @@ Make it personal, practical, and persuasive while maintaining professional tone.
             recommendations.append({
                 "rank": i + 1,
                 "option_type": option.get("option_type", ""),
+                "office": option.get("office"),
                 "title": self._generate_recommendation_title(option),
-                "ai_summary": "Standard recommendation based on business rules",
+                "ai_summary": self._with_constraints("Standard recommendation based on business rules", option),
                 "detailed_schedule": self._create_detailed_schedule(option),
                 "key_benefits": ["Meets business requirements", "Practical implementation"],
                 "considerations": option.get("warnings", []),
@@ -5,9 +5,15 @@ Commute Optimizer Agent - Travel time and route optimization
 import logging
 from datetime import datetime, timedelta
 from typing import Dict, Any, List
+from zoneinfo import ZoneInfo
 
 from models.workflow_state import CommuteState
 from tools.google_maps_mock import MockGoogleMapsTool
+from utils.constraints import get_constraints, get_timezone, constrained_profile, check_constraints
+from utils.focus_time import get_focus, check_focus
+from utils.office_choice import get_offices, office_destination, choose_office
+from utils.offsite import offsite_meetings, plan_travel_legs
+from utils.travel_modes import get_travel_profile, choose_travel_mode
 
 logger = logging.getLogger(__name__)
 
@@ -44,19 +50,36 @@ class CommuteOptimizerAgent:
             
             presence_blocks = state.get("office_presence_blocks", [])
             target_date = state["target_date"]
+            offices, default_office_id = get_offices(state.get("input_data", {}))
+            meetings = offsite_meetings(state.get("meeting_classifications", []))
+            profile = get_travel_profile(state.get("input_data", {}))
+            constraints = get_constraints(state.get("input_data", {}))
+            tz = get_timezone(state.get("input_data", {}))
             
             commute_options = []
             
             for block in presence_blocks:
                 if block["type"] == "FULL_REMOTE_RECOMMENDED":
-                    # No commute needed for remote work
+                    # No commute needed for remote work, except to offsite meetings
                     commute_option = self._create_remote_commute_option(block, target_date)
-                    commute_options.append(commute_option)
+                    commute_options.append(await self._route_day(commute_option, meetings, profile, None, constraints, tz))
+                elif offices:
+                    # Compare the commute to each office, including mid-day travel, and keep the best one
+                    candidates = []
+                    for office in offices:
+                        commute_option = await self._optimize_office_commute(block, target_date, office)
+                        commute_option = await self._route_day(commute_option, meetings, profile, office, constraints, tz)
+                        candidates.append((office, commute_option))
+                    commute_options.append(choose_office(candidates, default_office_id))
                 else:
                     # Calculate commute timing for office presence
                     commute_option = await self._optimize_office_commute(block, target_date)
-                    commute_options.append(commute_option)
+                    commute_options.append(await self._route_day(commute_option, meetings, profile, None, constraints, tz))
                     
+            # Find the focus windows each option's travel leaves
+            focus = get_focus(state.get("input_data", {}))
+            commute_options = [check_focus(option, focus, tz) for option in commute_options]
+            
             # Update state
             state["commute_options"] = commute_options
             state["progress_percentage"] = 0.8
@@ -70,9 +93,31 @@ class CommuteOptimizerAgent:
             state["error_message"] = f"Commute optimization failed: {str(e)}"
             return state
             
-    async def _optimize_office_commute(self, presence_block: Dict[str, Any], target_date: str) -> Dict[str, Any]:
+    async def _route_day(
+        self,
+        option: Dict[str, Any],
+        meetings: List[Dict[str, Any]],
+        profile: Dict[str, Any],
+        office: Dict[str, Any] = None,
+        constraints: List[Dict[str, Any]] = None,
+        tz: ZoneInfo = None
+    ) -> Dict[str, Any]:
+        """Route an option's day to the office (the generic "office" when there are none) via
+        any offsite meetings, pick its travel mode without the modes the job's constraints
+        avoid, and check it against the constraints"""
+        
+        constraints = constraints or []
+        
+        destination = office_destination(office)
+        option = await plan_travel_legs(self.maps_tool, option, meetings, destination)
+        option = await choose_travel_mode(self.maps_tool, option, constrained_profile(profile, constraints), destination, office)
+        return check_constraints(option, constraints, tz)
+        
+    async def _optimize_office_commute(self, presence_block: Dict[str, Any], target_date: str, office: Dict[str, Any] = None) -> Dict[str, Any]:
         """Optimize commute timing for an office presence block"""
         
+        destination = office_destination(office)
+        
         arrival_hour = presence_block["arrival_hour"]
         departure_hour = presence_block["departure_hour"]
         
@@ -99,15 +144,15 @@ class CommuteOptimizerAgent:
         
         # Calculate optimal departure time from home
         commute_start_info = await self.maps_tool.calculate_optimal_departure_time(
-            destination="office",
+            destination=destination,
             target_arrival=office_arrival_dt.isoformat() + "Z",
             origin="home"
         )
         
         # Calculate return journey timing
         return_commute_info = await self.maps_tool.get_route_duration(
-            origin="office",
-            destination="home", 
+            origin=destination,
+            destination="home",
             departure_time=office_departure_dt.isoformat() + "Z"
         )
         
@@ -122,7 +167,7 @@ class CommuteOptimizerAgent:
         )
         
         # Get parking information
-        parking_info = await self.maps_tool.get_parking_info("office")
+        parking_info = await self.maps_tool.get_parking_info(destination)
         
         # Calculate total office duration
         office_duration_timedelta = office_departure_dt - office_arrival_dt
@@ -208,7 +253,8 @@ class CommuteOptimizerAgent:
                 "day_efficiency": 1.0  # 100% efficiency
             },
             "warnings": presence_block.get("warnings", []),
-            "compliance_score": presence_block["compliance_score"]
+            "compliance_score": presence_block["compliance_score"],
+            "day_note": presence_block.get("day_note")
         }
         
     def _format_duration(self, duration: timedelta) -> str:
@@ -8,6 +8,8 @@ from datetime import datetime
 from typing import Dict, Any, List
 
 from models.workflow_state import CommuteState
+from utils.office_choice import get_offices
+from utils.offsite import detect_offsite
 
 logger = logging.getLogger(__name__)
 
@@ -56,14 +58,16 @@ class MeetingClassifierAgent:
             state["progress_percentage"] = 0.3
             
             meeting_classifications = []
+            offices, _ = get_offices(state["input_data"])
             
             for event in state["calendar_events"]:
-                classification = self._classify_single_meeting(event)
+                classification = self._classify_single_meeting(event, offices)
                 meeting_classifications.append(classification)
                 
             # Analyze overall meeting distribution
             office_meetings = [m for m in meeting_classifications if m["requires_office"]]
             remote_meetings = [m for m in meeting_classifications if not m["requires_office"]]
+            offsite = [m for m in meeting_classifications if m["location_type"] == "offsite"]
             
             # Update state
             state["meeting_classifications"] = meeting_classifications
@@ -71,7 +75,7 @@ class MeetingClassifierAgent:
             
             logger.info(
                 f"Meeting classification complete: {len(office_meetings)} office-required, "
-                f"{len(remote_meetings)} remote-friendly"
+                f"{len(remote_meetings)} remote-friendly ({len(offsite)} offsite)"
             )
             
             return state
@@ -81,8 +85,8 @@ class MeetingClassifierAgent:
             state["error_message"] = f"Meeting classification failed: {str(e)}"
             return state
             
-    def _classify_single_meeting(self, event: Dict[str, Any]) -> Dict[str, Any]:
-        """Classify a single meeting for office vs remote attendance"""
+    def _classify_single_meeting(self, event: Dict[str, Any], offices: List[Dict[str, Any]]) -> Dict[str, Any]:
+        """Classify a single meeting for office, remote or offsite attendance"""
         
         meeting_id = event["id"]
         summary = event["summary"].lower()
@@ -164,6 +168,18 @@ class MeetingClassifierAgent:
                     requires_office = False
                     confidence = "medium" if remote_score > office_score else "low"
                     reason = "; ".join(reasons[:3]) if reasons else "Default to remote-friendly"
+
+        # Meetings held at an external location are attended in person, but not at the office
+        offsite = None
+        if attendance_mode not in ("MUST_BE_IN_OFFICE", "CAN_BE_REMOTE"):
+            offsite = detect_offsite(event, offices)
+        if offsite:
+            requires_office = False
+            confidence = offsite["confidence"]
+            reason = offsite["reason"]
+            location_type = "offsite"
+        else:
+            location_type = "office" if requires_office else "remote"
                     
         return {
             "meeting_id": meeting_id,
@@ -171,6 +187,9 @@ class MeetingClassifierAgent:
             "start_time": event["start_time"],
             "end_time": event["end_time"],
             "requires_office": requires_office,
+            "location_type": location_type,
+            "location": event.get("location", ""),
+            "offsite": offsite,
             "confidence": confidence,
             "reason": reason,
             "meeting_type": meeting_type,
@@ -7,6 +7,7 @@ from datetime import datetime, timedelta
 from typing import Dict, Any, List, Tuple
 
 from models.workflow_state import CommuteState
+from utils.all_day import get_day, day_remote_block
 
 logger = logging.getLogger(__name__)
 
@@ -55,6 +56,14 @@ class OfficePresenceValidatorAgent:
             meeting_classifications = state.get("meeting_classifications", [])
             target_date = state.get("target_date", "")
             
+            # Out of office, or blocked for remote work: there's nothing to validate
+            day = get_day(state.get("input_data", {}))
+            if day:
+                logger.info(f"{day.get('reason')}: planning the day remote")
+                state["office_presence_blocks"] = [day_remote_block(day, meeting_classifications)]
+                state["progress_percentage"] = 0.6
+                return state
+            
             # Check company policy requirements first
             policy_requirements = self._check_company_policy(target_date)
             
@@ -7,6 +7,10 @@ from datetime import datetime
 from typing import Dict, Any, List
 
 from models.workflow_state import CommuteState
+from utils.constraints import describe_constraint_results, missed_constraints
+from utils.focus_time import splits_focus, describe_focus
+from utils.meeting_load import get_meeting_load_warnings
+from utils.travel_modes import chosen_cost, describe_cost
 
 logger = logging.getLogger(__name__)
 
@@ -41,9 +45,13 @@ class OptionPresenterAgent:
             ranked_options = self._rank_options(commute_options)
             
             # Format each option according to ARCHITECTURE.md specification
+            meeting_load = get_meeting_load_warnings(state.get("input_data", {}))
             formatted_recommendations = []
             for rank, option in enumerate(ranked_options, 1):
                 formatted_rec = self._format_recommendation(option, rank)
+                # A heavy day of meetings is a trade-off of every option
+                if meeting_load:
+                    formatted_rec["trade_offs"]["meeting_load"] = meeting_load
                 formatted_recommendations.append(formatted_rec)
                 
             # Update state
@@ -94,6 +102,13 @@ class OptionPresenterAgent:
             warnings = option.get("warnings", [])
             total_score -= len(warnings) * 5
             
+            # The user asked for these, so missing one costs more than a warning (-25 points each)
+            total_score -= missed_constraints(option) * 25
+            
+            # Splitting the focus time the user asked for costs more than a warning too (-20 points)
+            if splits_focus(option):
+                total_score -= 20
+            
             # Penalty for high commute ratio (-10 points if ratio > 0.5)
             commute_ratio = efficiency.get("commute_to_office_ratio", 0)
             if commute_ratio > 0.5:
@@ -113,6 +128,7 @@ class OptionPresenterAgent:
         # Extract meeting IDs
         office_meeting_ids = [m["meeting_id"] for m in option.get("office_meetings", [])]
         remote_meeting_ids = [m["meeting_id"] for m in option.get("remote_meetings", [])]
+        offsite_meeting_ids = [m["meeting_id"] for m in option.get("offsite_meetings", [])]
         
         # Format business rule compliance
         compliance = option.get("business_rule_compliance", {})
@@ -140,6 +156,9 @@ class OptionPresenterAgent:
         # Generate trade-offs analysis
         trade_offs = self._analyze_trade_offs(option)
         
+        # Structured reasons the backend renders in the user's language
+        reason_codes = self._reason_codes(option, rank)
+        
         return {
             "option_rank": rank,
             "type": option["option_type"],
@@ -150,10 +169,19 @@ class OptionPresenterAgent:
             "office_duration": option.get("office_duration"),
             "office_meetings": office_meeting_ids,
             "remote_meetings": remote_meeting_ids,
+            "offsite_meetings": offsite_meeting_ids,
+            "travel_legs": option.get("travel_legs", []),
+            "travel_mode": option.get("travel_mode"),
+            "mode_options": option.get("mode_options", []),
+            "constraints": option.get("constraints", []),
             "business_rule_compliance": formatted_compliance,
             "perception_analysis": perception,
             "reasoning": reasoning,
-            "trade_offs": trade_offs
+            "trade_offs": trade_offs,
+            "reason_codes": reason_codes,
+            "office_id": option.get("office_id"),
+            "office": option.get("office"),
+            "office_comparison": option.get("office_comparison", [])
         }
         
     def _analyze_professional_perception(self, option: Dict[str, Any]) -> Dict[str, Any]:
@@ -227,6 +255,10 @@ class OptionPresenterAgent:
         else:
             reasoning_parts.append(f"Option #{rank}:")
             
+        # An all-day entry decided the day
+        if option.get("day_note"):
+            reasoning_parts.append(f"{option['day_note']}.")
+            
         # Type-specific reasoning
         if option_type == "FULL_REMOTE_RECOMMENDED":
             reasoning_parts.append(
@@ -272,6 +304,16 @@ class OptionPresenterAgent:
         if warnings:
             reasoning_parts.append(f"Note: {len(warnings)} considerations including {warnings[0].lower()}.")
             
+        # Echo the job's constraints and whether this option meets them
+        constraints = describe_constraint_results(option)
+        if constraints:
+            reasoning_parts.append(constraints)
+            
+        # Report the focus windows the option keeps free
+        focus = describe_focus(option)
+        if focus:
+            reasoning_parts.append(focus)
+            
         return " ".join(reasoning_parts)
         
     def _analyze_trade_offs(self, option: Dict[str, Any]) -> Dict[str, Any]:
@@ -283,6 +325,9 @@ class OptionPresenterAgent:
         remote_meetings = option.get("remote_meetings", [])
         
         trade_offs = {}
+        cost = chosen_cost(option)
+        if cost:
+            trade_offs["commute_cost"] = cost
         
         if option_type == "FULL_REMOTE_RECOMMENDED":
             trade_offs.update({
@@ -297,7 +342,7 @@ class OptionPresenterAgent:
                     "Potential visibility concerns with management",
                     "May miss spontaneous collaboration opportunities"
                 ],
-                "cost_impact": "Saves ~$40-60/day in commute costs"
+                "cost_impact": f"Travel to offsite meetings costs {describe_cost(cost)}" if cost else "No commuting costs"
             })
             
         else:
@@ -314,10 +359,10 @@ class OptionPresenterAgent:
                 ],
                 "cons": [
                     f"{commute_time} minutes total commute time",
-                    f"Commute costs (parking, gas, time value)",
+                    f"Commute costs {describe_cost(cost)}" if cost else "Commute costs (parking, gas, time value)",
                     f"Less flexibility for personal schedule"
                 ],
-                "cost_impact": f"~$40-60/day in commute expenses",
+                "cost_impact": f"{cost['total']:.2f}/day in commute expenses" if cost else "Commute costs not estimated",
                 "time_investment": f"{commute_time} min commute for {office_time} min office time"
             })
             
@@ -333,4 +378,68 @@ class OptionPresenterAgent:
         if efficiency:
             trade_offs["efficiency_score"] = f"{efficiency.get('day_efficiency', 0):.1%}"
             
-        return trade_offs
\ No newline at end of file
+        return trade_offs
+        
+    def _reason_codes(self, option: Dict[str, Any], rank: int) -> List[Dict[str, Any]]:
+        """Structured reasons mirroring the reasoning and trade-offs, which the backend
+        renders in the user's language (pkg/i18n/catalog.go holds a message per code)"""
+        
+        def code(name: str, **params) -> Dict[str, Any]:
+            return {"code": name, "params": params}
+        
+        option_type = option["option_type"]
+        office_meetings = len(option.get("office_meetings", []))
+        remote_meetings = len(option.get("remote_meetings", []))
+        efficiency = option.get("efficiency_metrics", {})
+        warnings = option.get("warnings", [])
+        
+        if rank == 1:
+            codes = [code("RECOMMENDED")]
+        elif rank == 2:
+            codes = [code("ALTERNATIVE")]
+        else:
+            codes = [code("OPTION_RANK", rank=rank)]
+            
+        if option.get("day_note"):
+            codes.append(code("DAY_NOTE", note=option["day_note"]))
+            
+        if option_type == "FULL_REMOTE_RECOMMENDED":
+            codes.append(code("FULL_REMOTE", remoteMeetings=remote_meetings))
+        elif option_type == "FULL_DAY_OFFICE":
+            codes.append(code("FULL_DAY_OFFICE", officeMeetings=office_meetings))
+        elif option_type == "STRATEGIC_AFTERNOON":
+            codes.append(code("STRATEGIC_AFTERNOON", officeMeetings=office_meetings, remoteMeetings=remote_meetings))
+        else:
+            codes.append(code("PARTIAL_OFFICE", officeMeetings=office_meetings, remoteMeetings=remote_meetings))
+            
+        if efficiency:
+            commute_ratio = efficiency.get("commute_to_office_ratio", 0)
+            if efficiency.get("day_efficiency", 0) > 0.8:
+                codes.append(code("EFFICIENT_DAY"))
+            elif commute_ratio > 0.4:
+                codes.append(code("COMMUTE_SHARE", commutePercent=int(commute_ratio * 100)))
+                
+        if warnings:
+            codes.append(code("CONSIDERATIONS", count=len(warnings)))
+            
+        if option_type == "FULL_REMOTE_RECOMMENDED":
+            codes += [code(name) for name in (
+                "PRO_NO_COMMUTE", "PRO_FLEXIBILITY", "PRO_WORK_LIFE_BALANCE", "PRO_NO_TRAVEL_EMISSIONS",
+                "CON_LIMITED_FACE_TIME", "CON_VISIBILITY_RISK", "CON_MISSED_COLLABORATION"
+            )]
+            return codes
+            
+        codes += [
+            code("PRO_IN_PERSON_MEETINGS", officeMeetings=office_meetings),
+            code("PRO_VISIBILITY"),
+            code("PRO_SPONTANEOUS_COLLABORATION"),
+            code("PRO_OFFICE_RESOURCES"),
+            code("CON_COMMUTE_TIME", commuteMinutes=efficiency.get("total_commute_minutes", 0)),
+            code("CON_COMMUTE_COSTS"),
+            code("CON_LESS_FLEXIBILITY")
+        ]
+        if option_type == "FULL_DAY_OFFICE":
+            codes += [code("PRO_MAX_COLLABORATION"), code("CON_LONG_DAY")]
+        elif option_type in ["STRATEGIC_AFTERNOON", "STRATEGIC_MORNING"]:
+            codes += [code("PRO_BALANCE"), code("CON_SPLIT_DAY")]
+        return codes
\ No newline at end of file
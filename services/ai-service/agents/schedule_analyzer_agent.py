@@ -9,6 +9,8 @@ from typing import Dict, Any
 from models.workflow_state import CommuteState
 from tools.google_calendar_mock import MockGoogleCalendarTool
 from services.database_service import DatabaseService
+from utils.all_day import day_meetings
+from utils.replan import remaining_meetings
 
 logger = logging.getLogger(__name__)
 
@@ -55,6 +57,12 @@ class ScheduleAnalyzerAgent:
                 mock_events = await calendar_tool.get_calendar_events(state["target_date"])
                 calendar_events = self._normalize_mock_events(mock_events)
             
+            # All-day entries mark the day rather than take up time in it
+            calendar_events, day_markers = day_meetings(calendar_events, state.get("input_data", {}))
+            state["day_markers"] = day_markers
+            # A re-plan only plans the rest of the day
+            calendar_events = remaining_meetings(calendar_events, state.get("input_data", {}))
+            
             # Analyze calendar patterns
             analysis = self._analyze_calendar_patterns(calendar_events, state["target_date"])
             
@@ -69,6 +69,7 @@ class CommuteWorkflow:
             "progress_step": "Initializing workflow",
             "progress_percentage": 0.0,
             "calendar_events": [],
+            "day_markers": [],
             "meeting_classifications": [],
             "office_presence_blocks": [],
             "commute_options": [],
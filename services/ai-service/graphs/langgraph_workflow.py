@@ -39,6 +39,7 @@ class AICommuteState(BaseModel):
     
     # Data through pipeline
     calendar_events: List[Dict[str, Any]] = Field(default_factory=list)
+    day_markers: List[Dict[str, Any]] = Field(default_factory=list)
     meeting_classifications: List[Dict[str, Any]] = Field(default_factory=list)
     office_presence_blocks: List[Dict[str, Any]] = Field(default_factory=list)
     commute_options: List[Dict[str, Any]] = Field(default_factory=list)
@@ -13,6 +13,7 @@ import uvicorn
 
 from config.settings import get_settings
 from services.redis_service import RedisService
+from services.rabbitmq_service import RabbitMQService
 from workers.job_worker import JobWorker
 from api.routes import health, jobs
 
@@ -22,13 +23,14 @@ logger = logging.getLogger(__name__)
 
 # Global services
 redis_service: RedisService = None
+rabbitmq_service: RabbitMQService = None
 job_worker: JobWorker = None
 
 
 @asynccontextmanager
 async def lifespan(app: FastAPI):
     """Application lifespan management"""
-    global redis_service, job_worker
+    global redis_service, rabbitmq_service, job_worker
     
     settings = get_settings()
     
@@ -39,8 +41,15 @@ async def lifespan(app: FastAPI):
     redis_service = RedisService(settings.redis_url)
     await redis_service.connect()
     
+    # Jobs come from RabbitMQ instead of the Redis streams when it runs the job pipeline
+    if settings.queue_broker == "rabbitmq":
+        rabbitmq_service = RabbitMQService(settings.rabbitmq_url, settings.rabbitmq_queue)
+        await rabbitmq_service.connect()
+    elif settings.queue_broker != "redis":
+        raise ValueError(f"Unknown QUEUE_BROKER {settings.queue_broker!r} (expected redis or rabbitmq)")
+    
     # Initialize and start job worker
-    job_worker = JobWorker(redis_service)
+    job_worker = JobWorker(redis_service, rabbitmq_service)
     worker_task = asyncio.create_task(job_worker.start())
     
     logger.info("AI Service initialized successfully")
@@ -51,6 +60,8 @@ async def lifespan(app: FastAPI):
     logger.info("Shutting down AI Service...")
     await job_worker.stop()
     worker_task.cancel()
+    if rabbitmq_service:
+        await rabbitmq_service.disconnect()
     await redis_service.disconnect()
     logger.info("AI Service shutdown complete")
 
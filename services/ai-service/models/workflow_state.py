@@ -20,6 +20,7 @@ class CommuteState(TypedDict):
     
     # Calendar analysis
     calendar_events: List[Dict[str, Any]]
+    day_markers: List[Dict[str, Any]]              # all-day entries, kept out of calendar_events
     
     # Meeting classification
     meeting_classifications: List[Dict[str, Any]]  # remote vs office decisions
@@ -0,0 +1,81 @@
+"""
+Checks the messages the worker sends against the backend's contracts.
+
+Builds each kind of progress update and a job result with the worker's own builders
+(utils/messages.py) and has a running backend validate them, failing on the first one it
+rejects. Run it in CI against a backend built from the same commit:
+
+    python -m scripts.check_contracts --backend-url http://localhost:8080
+"""
+
+import argparse
+import json
+import sys
+from typing import Any, Dict, List, Tuple
+
+import httpx
+
+from utils.messages import job_result, progress_message
+
+# A recommendation shaped like the option presenter's output
+SAMPLE_RECOMMENDATION: Dict[str, Any] = {
+    "option_rank": 1,
+    "type": "FULL_DAY_OFFICE",
+    "commute_start": "2026-03-02T07:30:00Z",
+    "office_arrival": "2026-03-02T08:15:00Z",
+    "office_departure": "2026-03-02T17:00:00Z",
+    "commute_end": "2026-03-02T17:45:00Z",
+    "office_duration": "8h 45m",
+    "office_meetings": ["event-1"],
+    "remote_meetings": [],
+    "offsite_meetings": [],
+    "travel_legs": [],
+    "travel_mode": "TRANSIT",
+    "mode_options": [],
+    "business_rule_compliance": {},
+    "perception_analysis": {},
+    "reasoning": "All meetings are in the office",
+    "trade_offs": {},
+    "reason_codes": [{"code": "IN_PERSON_MEETINGS", "params": {"count": 1}}],
+    "office_id": None,
+}
+
+
+def payloads() -> List[Tuple[str, str, Dict[str, Any]]]:
+    """(kind, description, payload) for every message the worker sends"""
+
+    result = job_result([SAMPLE_RECOMMENDATION])
+    return [
+        ("job_progress", "started", progress_message("job-1", "IN_PROGRESS", progress=0.0, current_step="Starting workflow")),
+        ("job_progress", "completed", progress_message(
+            "job-1", "COMPLETED", progress=1.0, current_step="Workflow completed", result=json.dumps(result),
+        )),
+        ("job_progress", "failed", progress_message("job-1", "FAILED", error_message="Calendar unavailable")),
+        ("job_result", "with recommendations", result),
+        ("job_result", "without recommendations", job_result([])),
+    ]
+
+
+def main() -> int:
+    parser = argparse.ArgumentParser(description=__doc__.split("\n\n")[1])
+    parser.add_argument("--backend-url", default="http://localhost:8080")
+    args = parser.parse_args()
+
+    failed = 0
+    with httpx.Client(base_url=args.backend_url, timeout=10) as client:
+        for kind, description, payload in payloads():
+            response = client.post(f"/contracts/{kind}/validate", json=payload)
+            if response.status_code == 200:
+                print(f"ok    {kind}: {description}")
+                continue
+            failed += 1
+            try:
+                error = response.json().get("error")
+            except ValueError:
+                error = response.text
+            print(f"FAIL  {kind}: {description}: {error}")
+    return 1 if failed else 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
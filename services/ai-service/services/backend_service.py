@@ -8,6 +8,7 @@ import logging
 from typing import Optional, Dict, Any, List
 import httpx
 from config.settings import get_settings
+from utils.messages import job_result
 
 settings = get_settings()
 
@@ -27,6 +28,13 @@ class BackendService:
     
     async def make_graphql_request(self, query: str, variables: Optional[Dict[str, Any]] = None) -> Dict[str, Any]:
         """Make a GraphQL request to the backend service"""
+        headers = {
+            "Content-Type": "application/json",
+            "Accept": "application/json"
+        }
+        # Job operations need the admin token, as they act on other users' jobs
+        if settings.backend_admin_token:
+            headers["X-Admin-Token"] = settings.backend_admin_token
         try:
             response = await self.client.post(
                 self.backend_url,
@@ -34,10 +42,7 @@ class BackendService:
                     "query": query,
                     "variables": variables or {}
                 },
-                headers={
-                    "Content-Type": "application/json",
-                    "Accept": "application/json"
-                }
+                headers=headers
             )
             
             response.raise_for_status()
@@ -144,6 +149,8 @@ class BackendService:
                 startTime
                 endTime
                 location
+                locationLatitude
+                locationLongitude
                 attendees
                 meetingType
                 attendanceMode
@@ -187,11 +194,7 @@ class BackendService:
                 status="COMPLETED",
                 progress=1.0,
                 current_step="Recommendations complete",
-                result={
-                    "recommendations": recommendations,
-                    "total_options": len(recommendations),
-                    "analysis_complete": True
-                }
+                result=job_result(recommendations)
             )
             
             if result:
@@ -205,6 +208,49 @@ class BackendService:
             logger.error(f"Error saving commute recommendations for job {job_id}: {error}")
             return False
     
+    async def upload_job_artifact(
+        self,
+        job_id: str,
+        kind: str,
+        content: bytes,
+        content_type: str = "application/json"
+    ) -> Optional[str]:
+        """Upload a large job artifact to object storage through a presigned URL from the
+        backend. Returns the artifact's ID, or None if it couldn't be stored."""
+        
+        mutation = """
+        mutation CreateJobArtifactUpload($jobId: ID!, $input: JobArtifactUploadInput!) {
+            createJobArtifactUpload(jobId: $jobId, input: $input) {
+                artifact { id }
+                uploadUrl
+            }
+        }
+        """
+        
+        variables = {
+            "jobId": job_id,
+            "input": {
+                "kind": kind,
+                "contentType": content_type,
+                "sizeBytes": len(content)
+            }
+        }
+        
+        try:
+            data = await self.make_graphql_request(mutation, variables)
+            upload = data["createJobArtifactUpload"]
+            # The presigned URL signs the content type; the object goes straight to the bucket
+            response = await self.client.put(
+                upload["uploadUrl"],
+                content=content,
+                headers={"Content-Type": content_type}
+            )
+            response.raise_for_status()
+            return upload["artifact"]["id"]
+        except Exception as error:
+            logger.error(f"Failed to upload {kind} artifact of job {job_id}: {error}")
+            return None
+    
     async def health_check(self) -> bool:
         """Check if backend service is healthy"""
         try:
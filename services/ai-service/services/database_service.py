@@ -65,6 +65,7 @@ class DatabaseService:
                         current_step = COALESCE($3, current_step),
                         result = COALESCE($4, result),
                         error_message = COALESCE($5, error_message),
+                        version = version + 1,
                         updated_at = NOW()
                     WHERE id = $6
                     RETURNING id
@@ -172,10 +173,11 @@ class DatabaseService:
                             id, job_id, option_rank, option_type,
                             commute_start, office_arrival, office_departure, commute_end,
                             office_duration, office_meetings, remote_meetings,
+                            offsite_meetings, travel_legs, travel_mode, mode_options,
                             business_rule_compliance, perception_analysis,
-                            reasoning, trade_offs, created_at
+                            reasoning, trade_offs, reason_codes, office_id, created_at
                         ) VALUES (
-                            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW()
+                            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, NOW()
                         )
                     """
                     
@@ -192,10 +194,16 @@ class DatabaseService:
                         rec.get("office_duration"),
                         json.dumps(rec.get("office_meetings", [])),
                         json.dumps(rec.get("remote_meetings", [])),
+                        json.dumps(rec.get("offsite_meetings", [])),
+                        json.dumps(rec.get("travel_legs", [])),
+                        rec.get("travel_mode"),
+                        json.dumps(rec.get("mode_options", [])),
                         json.dumps(rec.get("business_rule_compliance", {})),
                         json.dumps(rec.get("perception_analysis", {})),
                         rec.get("reasoning"),
-                        json.dumps(rec.get("trade_offs", {}))
+                        json.dumps(rec.get("trade_offs", {})),
+                        json.dumps(rec["reason_codes"]) if rec.get("reason_codes") else None,
+                        rec.get("office_id")
                     )
                     
                 logger.info(f"Saved {len(recommendations)} recommendations for job {job_id}")
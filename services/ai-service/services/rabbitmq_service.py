@@ -0,0 +1,94 @@
+"""
+RabbitMQ job source for deployments that run the job pipeline on RabbitMQ (QUEUE_BROKER=rabbitmq)
+
+The backend publishes interactive jobs to a durable queue and batch jobs to the same queue
+name with a ".batch" suffix. Messages are taken with manual acknowledgement: a job that isn't
+acknowledged when the worker's connection drops is redelivered to another worker, which
+replaces the stream claiming the Redis workers do. Redis is still used for progress updates.
+"""
+
+import json
+import logging
+from typing import Dict, Any, List, Optional, Tuple
+
+import aio_pika
+from aio_pika.abc import AbstractRobustConnection, AbstractChannel, AbstractIncomingMessage, AbstractQueue
+
+logger = logging.getLogger(__name__)
+
+
+class RabbitMQService:
+    """Reads jobs from the RabbitMQ job queues"""
+
+    def __init__(self, rabbitmq_url: str, queue_name: str):
+        self.rabbitmq_url = rabbitmq_url
+        self.queue_names = [queue_name, f"{queue_name}.batch"]
+        self.connection: Optional[AbstractRobustConnection] = None
+        self.channel: Optional[AbstractChannel] = None
+        self.queues: Dict[str, AbstractQueue] = {}
+
+    async def connect(self) -> None:
+        """Connect and declare the job queues. The declaration matches the backend's, so
+        either side can start first."""
+        try:
+            # A robust connection reconnects by itself after a broker restart
+            self.connection = await aio_pika.connect_robust(self.rabbitmq_url, timeout=5)
+            self.channel = await self.connection.channel()
+            for name in self.queue_names:
+                self.queues[name] = await self.channel.declare_queue(name, durable=True)
+            logger.info(f"Connected to RabbitMQ, reading {', '.join(self.queue_names)}")
+
+        except Exception as e:
+            logger.error(f"Failed to connect to RabbitMQ: {e}")
+            raise
+
+    async def disconnect(self) -> None:
+        """Disconnect; unacknowledged jobs are requeued by the broker"""
+        if self.connection:
+            await self.connection.close()
+            logger.info("Disconnected from RabbitMQ")
+
+    async def read_jobs(self, count: int) -> List[Tuple[str, AbstractIncomingMessage, Optional[Dict[str, Any]]]]:
+        """Take up to count jobs as (queue, message, job) without waiting. The batch queue is
+        only read while the interactive queue is empty. Messages stay unacknowledged until
+        ack_job is called; undecodable ones are returned with None so the caller drops them."""
+        if not self.channel:
+            raise RuntimeError("RabbitMQ not connected")
+
+        jobs = []
+        for name in self.queue_names:
+            while len(jobs) < count:
+                message = await self.queues[name].get(no_ack=False, fail=False)
+                if message is None:
+                    break
+                jobs.append((name, message, self._decode(message)))
+            if jobs:
+                break
+        return jobs
+
+    async def ack_job(self, message: AbstractIncomingMessage) -> None:
+        """Acknowledge a finished job so it isn't delivered again"""
+        await message.ack()
+
+    async def get_queue_lengths(self) -> Dict[str, int]:
+        """Ready (not yet delivered) messages per job queue"""
+        if not self.channel:
+            raise RuntimeError("RabbitMQ not connected")
+
+        lengths = {}
+        for name in self.queue_names:
+            # A passive declare returns the queue's current message count
+            queue = await self.channel.declare_queue(name, passive=True)
+            lengths[name] = queue.declaration_result.message_count
+        return lengths
+
+    def _decode(self, message: AbstractIncomingMessage) -> Optional[Dict[str, Any]]:
+        try:
+            return json.loads(message.body)
+        except (json.JSONDecodeError, UnicodeDecodeError):
+            logger.error(f"Discarding malformed job message {message.delivery_tag}: {message.body!r}")
+            return None
+
+    async def health_check(self) -> bool:
+        """Check RabbitMQ connection health"""
+        return bool(self.connection and not self.connection.is_closed)
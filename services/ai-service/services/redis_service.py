@@ -4,10 +4,11 @@ Redis service for event-driven job processing and pub/sub messaging
 
 import json
 import logging
-from typing import Dict, Any, Optional
+from typing import Dict, Any, List, Optional, Tuple
 
 import redis.asyncio as redis
 from redis.asyncio import Redis
+from redis.exceptions import ResponseError
 
 logger = logging.getLogger(__name__)
 
@@ -78,6 +79,106 @@ class RedisService:
             logger.error(f"Error popping job from queue {queue_name}: {e}")
             return None
             
+    async def pop_job_nowait(self, queue_name: str) -> Optional[Dict[str, Any]]:
+        """Pop a job from a list without blocking (used to drain the legacy list)"""
+        if not self.redis:
+            raise RuntimeError("Redis not connected")
+            
+        job_json = await self.redis.rpop(queue_name)
+        if job_json:
+            job_data = json.loads(job_json)
+            logger.info(f"Popped job from legacy queue {queue_name}: {job_data.get('job_id', 'unknown')}")
+            return job_data
+        return None
+        
+    async def ensure_consumer_group(self, stream: str, group: str) -> None:
+        """Create the consumer group (and the stream) if they don't exist yet"""
+        if not self.redis:
+            raise RuntimeError("Redis not connected")
+            
+        try:
+            await self.redis.xgroup_create(stream, group, id="0", mkstream=True)
+            logger.info(f"Created consumer group {group} on stream {stream}")
+        except ResponseError as e:
+            if "BUSYGROUP" not in str(e):
+                raise
+                
+    async def read_jobs(
+        self, streams: List[str], group: str, consumer: str, count: int, block_ms: Optional[int]
+    ) -> List[Tuple[str, str, Optional[Dict[str, Any]]]]:
+        """Read new jobs for this consumer as (stream, entry_id, job) in the order of
+        streams. Entries stay pending until ack_job is called. block_ms=None doesn't block."""
+        if not self.redis:
+            raise RuntimeError("Redis not connected")
+            
+        response = await self.redis.xreadgroup(
+            group, consumer, {stream: ">" for stream in streams}, count=count, block=block_ms
+        )
+        by_stream = {stream: entries for stream, entries in response or []}
+        jobs = []
+        for stream in streams:
+            for entry_id, job_data in self._decode_entries(by_stream.get(stream, [])):
+                jobs.append((stream, entry_id, job_data))
+        return jobs
+        
+    async def claim_stale_jobs(
+        self, stream: str, group: str, consumer: str, min_idle_ms: int, count: int
+    ) -> List[Tuple[str, Optional[Dict[str, Any]]]]:
+        """Take over jobs left pending by consumers that stopped acknowledging (crashed)"""
+        if not self.redis:
+            raise RuntimeError("Redis not connected")
+            
+        # XAUTOCLAIM returns [next_cursor, entries, deleted_ids] (Redis 7) or
+        # [next_cursor, entries] (Redis 6.2)
+        response = await self.redis.xautoclaim(
+            stream, group, consumer, min_idle_time=min_idle_ms, start_id="0-0", count=count
+        )
+        jobs = self._decode_entries(response[1])
+        for entry_id, job_data in jobs:
+            job_id = job_data.get("job_id", "unknown") if job_data else "unknown"
+            logger.warning(f"Claimed stale job {job_id} (entry {entry_id})")
+        return jobs
+        
+    async def touch_jobs(self, stream: str, group: str, consumer: str, entry_ids: List[str]) -> None:
+        """Reset the idle time of entries this consumer is still working on so long-running
+        jobs aren't claimed by another consumer"""
+        if not self.redis or not entry_ids:
+            return
+            
+        await self.redis.xclaim(
+            stream, group, consumer, min_idle_time=0, message_ids=entry_ids, justid=True
+        )
+        
+    async def ack_job(self, stream: str, group: str, entry_id: str) -> None:
+        """Acknowledge a finished job so it is removed from the pending entries list"""
+        if not self.redis:
+            raise RuntimeError("Redis not connected")
+            
+        await self.redis.xack(stream, group, entry_id)
+        
+    async def get_stream_backlog(self, stream: str, group: str) -> Dict[str, int]:
+        """Number of entries in the stream and pending (delivered, unacknowledged) for the group"""
+        if not self.redis:
+            raise RuntimeError("Redis not connected")
+            
+        length = await self.redis.xlen(stream)
+        pending = await self.redis.xpending(stream, group)
+        return {"length": length, "pending": pending.get("pending", 0) if pending else 0}
+        
+    def _decode_entries(self, entries) -> List[Tuple[str, Optional[Dict[str, Any]]]]:
+        """Decode stream entries. Entries that can't be processed (deleted from the stream,
+        or malformed) are returned with None so the caller acknowledges them."""
+        jobs = []
+        for entry_id, fields in entries:
+            job_data = None
+            if fields and "job" in fields:
+                try:
+                    job_data = json.loads(fields["job"])
+                except json.JSONDecodeError:
+                    logger.error(f"Discarding malformed job entry {entry_id}: {fields}")
+            jobs.append((entry_id, job_data))
+        return jobs
+        
     async def publish_progress(self, channel: str, progress_data: Dict[str, Any]) -> None:
         """Publish progress update to a channel"""
         if not self.redis:
@@ -41,6 +41,16 @@ class MockGoogleMapsTool:
         }
     }
     
+    # Average speeds for modes that don't sit in traffic (km/h)
+    MODE_SPEEDS_KMH = {
+        "bicycling": 15,
+        "walking": 5
+    }
+    
+    # Transit takes longer than an off-peak drive but is barely slowed by rush hour
+    TRANSIT_FACTOR = 1.25
+    TRANSIT_RUSH_MULTIPLIER = 1.1
+    
     # Rush hour definitions
     MORNING_RUSH = (7, 10)  # 7 AM - 10 AM
     EVENING_RUSH = (17, 19)  # 5 PM - 7 PM
@@ -56,12 +66,16 @@ class MockGoogleMapsTool:
         origin: str,
         destination: str,
         departure_time: str = None,
-        arrival_time: str = None
+        arrival_time: str = None,
+        mode: str = "driving"
     ) -> Dict[str, Any]:
-        """Get mock route duration with realistic traffic patterns"""
+        """Get mock route duration with realistic traffic patterns for a Google Maps travel
+        mode (driving, transit, bicycling or walking)"""
         
         scenario_data = self.BASE_COMMUTE_TIMES[self.scenario]
-        base_duration = scenario_data["base"]
+        base_duration = scenario_data["base"] * self._location_factor(origin, destination)
+        if mode != "driving":
+            return self._non_driving_route(base_duration, mode, departure_time or arrival_time, departure_time, arrival_time)
         
         # Determine time of day for traffic calculations
         if departure_time:
@@ -99,8 +113,9 @@ class MockGoogleMapsTool:
         
         final_duration = int(base_duration * multiplier * random_factor)
         
-        # Calculate distance (roughly 1 mile per 2-3 minutes in city traffic)
-        distance_miles = final_duration / 2.5
+        # Calculate distance (roughly 1 mile per 2-3 minutes without traffic); traffic
+        # changes the duration, not the route's length
+        distance_miles = base_duration / 2.5
         
         return {
             "duration": {
@@ -120,6 +135,59 @@ class MockGoogleMapsTool:
             "arrival_time": arrival_time
         }
         
+    def _non_driving_route(
+        self,
+        base_duration: float,
+        mode: str,
+        at: str,
+        departure_time: str,
+        arrival_time: str
+    ) -> Dict[str, Any]:
+        """Route for transit, bicycling or walking over the off-peak driving distance"""
+        
+        distance_km = base_duration / 2.5 * 1.609
+        if mode == "transit":
+            dt = datetime.fromisoformat(at.replace('Z', '+00:00')) if at else datetime.now()
+            rush = dt.weekday() < 5 and (
+                self.MORNING_RUSH[0] <= dt.hour <= self.MORNING_RUSH[1] or
+                self.EVENING_RUSH[0] <= dt.hour <= self.EVENING_RUSH[1]
+            )
+            duration = base_duration * self.TRANSIT_FACTOR * (self.TRANSIT_RUSH_MULTIPLIER if rush else 1.0)
+            conditions = "Crowded rush hour service" if rush else "Regular service"
+        else:
+            duration = distance_km / self.MODE_SPEEDS_KMH[mode] * 60
+            conditions = "Not affected by traffic"
+        duration = int(duration)
+        
+        return {
+            "duration": {
+                "value": duration * 60,
+                "text": f"{duration} mins"
+            },
+            "distance": {
+                "value": int(distance_km * 1000),
+                "text": f"{distance_km / 1.609:.1f} miles"
+            },
+            "traffic_info": {
+                "conditions": conditions,
+                "delay_minutes": 0
+            },
+            "route_summary": f"{mode.title()} via {self.scenario.replace('_to_', ' → ').title()}",
+            "departure_time": departure_time,
+            "arrival_time": arrival_time
+        }
+        
+    def _location_factor(self, origin: str, destination: str) -> float:
+        """Scale the scenario's commute for a specific office (e.g. HQ vs. satellite).
+
+        The generic "home" and "office" endpoints keep the base commute; any other
+        endpoint gets a consistent factor seeded by its name.
+        """
+        for endpoint in (destination, origin):
+            if endpoint and endpoint not in ("home", "office"):
+                return random.Random(f"{self.user_id}_{endpoint}").uniform(0.6, 1.4)
+        return 1.0
+        
     async def get_multiple_route_options(
         self,
         origin: str,
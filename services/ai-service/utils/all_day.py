@@ -0,0 +1,118 @@
+"""
+All-day calendar entries.
+
+An all-day entry marks the day rather than taking up time in it: out of office, vacation,
+a day blocked for focus work or working from home, or just information such as a birthday.
+The backend reads them (pkg/allday), including vacations that started on an earlier day,
+and adds what they mean for the job's date to its input_data as context.day:
+
+    {"status": "SKIP" | "REMOTE", "reason": "Out of office: Vacation",
+     "markers": [{"eventId": ..., "summary": ..., "kind": "OUT_OF_OFFICE"}]}
+
+SKIP days need no commute: the plan is a single remote option and the day's meetings are
+dropped, so no travel to offsite meetings is planned either. REMOTE days get only the remote
+option. Either way the entries themselves aren't meetings: they are left out of meeting
+classification and of the free/busy analysis.
+"""
+
+import logging
+import re
+from typing import Dict, Any, List, Optional, Tuple
+
+from utils.offsite import parse_timestamp
+
+logger = logging.getLogger(__name__)
+
+DAY_SKIP = "SKIP"
+DAY_REMOTE = "REMOTE"
+
+# Mirrors allday.fullDay: a timed entry this long that reads as out of office marks the day
+FULL_DAY_HOURS = 8
+
+# Mirrors allday.outOfOfficePattern
+OUT_OF_OFFICE = re.compile(
+    r"(\bo\.?o\.?o\b|\bout of (the )?office\b|\bvacation\b|\bholidays?\b|\bannual leave\b|"
+    r"\b(parental|maternity|paternity|sick|medical|bereavement) leave\b|\bon leave\b|\bpto\b|"
+    r"\btime off\b|\bday off\b|\b(off )?sick\b|\btravell?ing\b)",
+    re.IGNORECASE
+)
+
+
+def get_day(input_data: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+    """What the job's all-day entries make of its date, when they change the plan"""
+
+    context = input_data.get("context", {}) if isinstance(input_data, dict) else {}
+    day = context.get("day") if isinstance(context, dict) else None
+    if not isinstance(day, dict) or day.get("status") not in (DAY_SKIP, DAY_REMOTE):
+        return None
+    return day
+
+
+def is_day_marker(event: Dict[str, Any]) -> bool:
+    """Whether an event marks the day instead of taking up time: every all-day entry, and
+    timed out-of-office blocks spanning a working day"""
+
+    if event.get("is_all_day", event.get("isAllDay")):
+        return True
+    text = f"{event.get('summary') or ''}\n{event.get('description') or ''}"
+    if not OUT_OF_OFFICE.search(text):
+        return False
+    try:
+        start, end = parse_timestamp(event["start_time"]), parse_timestamp(event["end_time"])
+    except (KeyError, AttributeError, ValueError):
+        return False
+    return (end - start).total_seconds() >= FULL_DAY_HOURS * 3600
+
+
+def split_day_markers(events: List[Dict[str, Any]]) -> Tuple[List[Dict[str, Any]], List[Dict[str, Any]]]:
+    """Split a day's events into its meetings and its markers"""
+
+    meetings, markers = [], []
+    for event in events:
+        (markers if is_day_marker(event) else meetings).append(event)
+    return meetings, markers
+
+
+def day_meetings(events: List[Dict[str, Any]], input_data: Dict[str, Any]) -> Tuple[List[Dict[str, Any]], List[Dict[str, Any]]]:
+    """The meetings to plan a day around, and its markers. A day off has none to plan."""
+
+    meetings, markers = split_day_markers(events)
+    day = get_day(input_data)
+    if day and day["status"] == DAY_SKIP and meetings:
+        logger.info(f"{day.get('reason')}: not planning around {len(meetings)} meetings")
+        meetings = []
+    return meetings, markers
+
+
+def day_remote_block(day: Dict[str, Any], classifications: List[Dict[str, Any]]) -> Dict[str, Any]:
+    """The only presence option of a marked day: remote, with no commute"""
+
+    reason = day.get("reason") or "Marked in the calendar"
+    skip = day["status"] == DAY_SKIP
+    return {
+        "type": "FULL_REMOTE_RECOMMENDED",
+        "arrival_hour": None,
+        "departure_hour": None,
+        "office_duration_hours": 0,
+        "office_meetings": [],
+        "remote_meetings": [] if skip else classifications,
+        "business_rule_compliance": {
+            "calendar": {
+                "status": "PASS",
+                "message": reason
+            }
+        },
+        "compliance_score": 100,
+        "is_valid": True,
+        "force_include": True,
+        "warnings": [],
+        "day_note": f"{reason}; no commute planned" if skip else f"{reason}; planned remote",
+        "ai_rationale": reason
+    }
+
+
+def with_day_note(summary: str, option: Dict[str, Any]) -> str:
+    """Lead a summary with why the calendar decided the day, if it did"""
+
+    note = option.get("day_note")
+    return f"{note}. {summary}" if note else summary
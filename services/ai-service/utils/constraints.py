@@ -0,0 +1,225 @@
+"""
+One-off planning constraints.
+
+Users can add constraints to a job, which the backend validates and adds to the job's
+input_data context: be home by a time (HOME_BY), don't leave before a time (LEAVE_AFTER),
+a busy slot no travel may overlap (BUSY, e.g. the gym) and a mode not to use (AVOID_MODE).
+Times are HH:MM in the user's timezone. Avoided modes are taken out of the travel profile
+before a mode is chosen; the time constraints are checked against each planned option,
+which records whether it meets them and warns about the ones it misses.
+
+The backend also adds the habits it learned from the user's history that apply on the
+job's date (e.g. "Works from home on Fridays") as context.preferences. They are soft:
+the planner should follow them when nothing else decides, but constraints win.
+"""
+
+import logging
+from typing import Dict, Any, List, Optional
+from zoneinfo import ZoneInfo
+
+from utils.offsite import parse_timestamp
+
+logger = logging.getLogger(__name__)
+
+# How an avoided mode reads, e.g. "no driving"
+MODE_ACTIVITIES = {
+    "DRIVE": "driving",
+    "TRANSIT": "transit",
+    "BIKE": "biking",
+    "WALK": "walking"
+}
+
+
+def get_constraints(input_data: Dict[str, Any]) -> List[Dict[str, Any]]:
+    """Return a job's constraints from its input data, each with a description"""
+
+    context = input_data.get("context", {}) if isinstance(input_data, dict) else {}
+    constraints = context.get("constraints") if isinstance(context, dict) else None
+    if not isinstance(constraints, list):
+        return []
+    return [
+        {**constraint, "description": describe_constraint(constraint)}
+        for constraint in constraints
+        if isinstance(constraint, dict) and constraint.get("type")
+    ]
+
+
+def get_preferences(input_data: Dict[str, Any]) -> List[Dict[str, Any]]:
+    """Return the learned preferences in a job's input data"""
+
+    context = input_data.get("context", {}) if isinstance(input_data, dict) else {}
+    preferences = context.get("preferences") if isinstance(context, dict) else None
+    if not isinstance(preferences, list):
+        return []
+    return [
+        preference for preference in preferences
+        if isinstance(preference, dict) and preference.get("description")
+    ]
+
+
+def get_timezone(input_data: Dict[str, Any]) -> ZoneInfo:
+    """The user's timezone from a job's input data; UTC when it's missing or unknown"""
+
+    context = input_data.get("context", {}) if isinstance(input_data, dict) else {}
+    name = context.get("user_timezone") if isinstance(context, dict) else None
+    try:
+        return ZoneInfo(name or "UTC")
+    except (KeyError, ValueError):
+        logger.warning(f"Unknown user timezone {name!r}; checking constraints in UTC")
+        return ZoneInfo("UTC")
+
+
+def describe_constraint(constraint: Dict[str, Any]) -> str:
+    """A constraint as text, e.g. "busy 07:00-08:00 (gym)" """
+
+    kind = constraint.get("type")
+    if kind == "HOME_BY":
+        text = f"home by {constraint.get('time')}"
+    elif kind == "LEAVE_AFTER":
+        text = f"leave after {constraint.get('time')}"
+    elif kind == "BUSY":
+        text = f"busy {constraint.get('start')}-{constraint.get('end')}"
+    elif kind == "AVOID_MODE":
+        mode = constraint.get("mode")
+        text = f"no {MODE_ACTIVITIES.get(mode, str(mode).lower())}"
+    else:
+        text = str(kind).lower()
+    return f"{text} ({constraint['label']})" if constraint.get("label") else text
+
+
+def constrained_profile(profile: Dict[str, Any], constraints: List[Dict[str, Any]]) -> Dict[str, Any]:
+    """
+    The travel profile without the modes the constraints avoid. When that would leave no
+    mode the profile is kept, and the option is flagged for missing the constraint.
+    """
+
+    avoided = {c.get("mode") for c in constraints if c.get("type") == "AVOID_MODE"}
+    modes = [mode for mode in profile["modes"] if mode not in avoided]
+    if not avoided or not modes:
+        return profile
+    preferred = profile["preferred_mode"]
+    return {**profile, "modes": modes, "preferred_mode": preferred if preferred in modes else None}
+
+
+def travel_trips(option: Dict[str, Any]) -> List[Dict[str, Any]]:
+    """The trips of a planned option with their departure, arrival and destination"""
+
+    if option.get("travel_legs"):
+        return [
+            {"depart": leg["depart"], "arrive": leg["arrive"], "to": leg.get("to")}
+            for leg in option["travel_legs"]
+        ]
+    trips = []
+    if option.get("commute_start") and option.get("office_arrival"):
+        trips.append({"depart": option["commute_start"], "arrive": option["office_arrival"], "to": "office"})
+    if option.get("office_departure") and option.get("commute_end"):
+        trips.append({"depart": option["office_departure"], "arrive": option["commute_end"], "to": "home"})
+    return trips
+
+
+def _check(constraint: Dict[str, Any], option: Dict[str, Any], trips: List[Dict[str, Any]], tz: ZoneInfo) -> Dict[str, Any]:
+    """Whether an option meets one constraint, with what decided it"""
+
+    def clock(timestamp: str) -> str:
+        return parse_timestamp(timestamp).astimezone(tz).strftime("%H:%M")
+
+    kind = constraint.get("type")
+    if kind == "AVOID_MODE":
+        mode = option.get("travel_mode")
+        if not trips or not mode:
+            return {"met": True, "detail": "no travel"}
+        return {"met": mode != constraint.get("mode"), "detail": f"travels by {mode.lower()}"}
+
+    if not trips:
+        return {"met": True, "detail": "no travel"}
+    if kind == "HOME_BY":
+        home = [trip for trip in trips if trip["to"] == "home"]
+        if not home:
+            return {"met": False, "detail": "doesn't return home"}
+        arrive = clock(home[-1]["arrive"])
+        return {"met": arrive <= constraint.get("time", ""), "detail": f"home at {arrive}"}
+    if kind == "LEAVE_AFTER":
+        depart = clock(trips[0]["depart"])
+        return {"met": depart >= constraint.get("time", ""), "detail": f"leaves at {depart}"}
+    if kind == "BUSY":
+        start, end = constraint.get("start", ""), constraint.get("end", "")
+        for trip in trips:
+            depart, arrive = clock(trip["depart"]), clock(trip["arrive"])
+            if depart < end and arrive > start:
+                return {"met": False, "detail": f"travels {depart}-{arrive}"}
+        return {"met": True, "detail": "no travel then"}
+    return {"met": True, "detail": "not checked"}
+
+
+def check_constraints(option: Dict[str, Any], constraints: List[Dict[str, Any]], tz: ZoneInfo) -> Dict[str, Any]:
+    """
+    Check a planned option against the job's constraints. The option gains constraints,
+    each with whether it's met and what decided it, and a warning for each it misses.
+    """
+
+    if not constraints:
+        return option
+
+    trips = travel_trips(option)
+    results = [
+        {
+            "type": constraint.get("type"),
+            "description": constraint["description"],
+            **_check(constraint, option, trips, tz)
+        }
+        for constraint in constraints
+    ]
+    missed = [result for result in results if not result["met"]]
+
+    checked = dict(option)
+    checked["constraints"] = results
+    checked["warnings"] = list(option.get("warnings", [])) + [
+        f"Misses constraint {result['description']}: {result['detail']}" for result in missed
+    ]
+    if missed:
+        logger.info(f"{option.get('option_type')} misses {len(missed)} of {len(results)} constraints")
+    return checked
+
+
+def missed_constraints(option: Dict[str, Any]) -> int:
+    """How many of the job's constraints a checked option misses"""
+
+    return sum(1 for result in option.get("constraints", []) if not result["met"])
+
+
+def describe_constraint_results(option: Dict[str, Any]) -> Optional[str]:
+    """A checked option's constraints as text for its reasoning, or None if the job had none"""
+
+    results = option.get("constraints")
+    if not results:
+        return None
+    parts = [
+        f"{result['description']} {'met' if result['met'] else 'missed'} ({result['detail']})"
+        for result in results
+    ]
+    return f"Your constraints: {'; '.join(parts)}."
+
+
+def constraints_prompt(constraints: List[Dict[str, Any]]) -> str:
+    """The constraints as a list for an LLM prompt"""
+
+    if not constraints:
+        return "None"
+    return "\n".join(f"- {constraint['description']}" for constraint in constraints)
+
+
+def preferences_prompt(preferences: List[Dict[str, Any]]) -> str:
+    """The learned preferences as a list for an LLM prompt, with how often they held"""
+
+    if not preferences:
+        return "None"
+    lines = []
+    for preference in preferences:
+        if preference.get("status") == "CONFIRMED":
+            lines.append(f"- {preference['description']} (confirmed by the user)")
+        else:
+            lines.append(
+                f"- {preference['description']} "
+                f"({round(preference.get('confidence', 0) * 100)}% of {preference.get('observations', 0)} days)"
+            )
+    return "\n".join(lines)
@@ -51,6 +51,8 @@ class EventNormalizer:
                 
                 # Location and attendees
                 "location": event.get("location", ""),
+                "location_latitude": event.get("locationLatitude", event.get("location_latitude")),
+                "location_longitude": event.get("locationLongitude", event.get("location_longitude")),
                 "attendees": EventNormalizer._normalize_attendees(event.get("attendees")),
                 
                 # Enum fields (camelCase → snake_case)
@@ -91,6 +93,8 @@ class EventNormalizer:
                 "start_time": EventNormalizer._normalize_timestamp(event.get("start_time")),
                 "end_time": EventNormalizer._normalize_timestamp(event.get("end_time")),
                 "location": event.get("location", ""),
+                "location_latitude": event.get("location_latitude"),
+                "location_longitude": event.get("location_longitude"),
                 "attendees": EventNormalizer._normalize_attendees(event.get("attendees")),
                 "meeting_type": event.get("meeting_type", "UNKNOWN"),
                 "attendance_mode": event.get("attendance_mode", "FLEXIBLE"),
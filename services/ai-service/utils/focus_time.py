@@ -0,0 +1,128 @@
+"""
+Focus time.
+
+Users can ask for a deep-work block of some minimum length to be kept free every day. For
+them the backend adds the day's free blocks of working hours (08:00-18:00 in the user's
+timezone, between meetings) to the job's input_data as context.focus:
+
+    {"minimumMinutes": 120, "free": [{"start": ..., "end": ..., "minutes": ...}]}
+
+Each planned option's travel is taken out of the free blocks; the ones still at least the
+minimum long are the focus windows the option protects. Options that protect none, e.g. a
+commute that splits the only free two hours, are ranked below those that do, and the
+windows are reported with each recommendation.
+"""
+
+import logging
+from typing import Dict, Any, List, Optional
+from zoneinfo import ZoneInfo
+
+from utils.constraints import travel_trips
+from utils.offsite import parse_timestamp
+
+logger = logging.getLogger(__name__)
+
+
+def get_focus(input_data: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+    """The user's focus time and the day's free blocks, or None when they haven't set one"""
+
+    context = input_data.get("context", {}) if isinstance(input_data, dict) else {}
+    focus = context.get("focus") if isinstance(context, dict) else None
+    if not isinstance(focus, dict) or not focus.get("minimumMinutes"):
+        return None
+    return focus
+
+
+def _subtract(spans: List[tuple], cut: List[tuple]) -> List[tuple]:
+    """Remove every span of cut from each span of spans"""
+
+    left = []
+    for start, end in spans:
+        for cut_start, cut_end in sorted(cut):
+            if cut_end <= start or cut_start >= end:
+                continue
+            if cut_start > start:
+                left.append((start, cut_start))
+            start = cut_end
+            if start >= end:
+                break
+        if start < end:
+            left.append((start, end))
+    return left
+
+
+def _clock(start, end, tz: ZoneInfo) -> str:
+    return f"{start.astimezone(tz).strftime('%H:%M')}-{end.astimezone(tz).strftime('%H:%M')}"
+
+
+def _duration(minutes: int) -> str:
+    """Minutes as "45m", "2h" or "2h 30m" """
+
+    if minutes < 60:
+        return f"{minutes}m"
+    return f"{minutes // 60}h" if minutes % 60 == 0 else f"{minutes // 60}h {minutes % 60}m"
+
+
+def check_focus(option: Dict[str, Any], focus: Optional[Dict[str, Any]], tz: ZoneInfo) -> Dict[str, Any]:
+    """
+    Find the focus windows a planned option's travel leaves. The option gains focus, with
+    the windows, whether it protects any and a note for its reasoning, and a warning when
+    it protects none.
+    """
+
+    if not focus:
+        return option
+
+    minimum = int(focus["minimumMinutes"])
+    free = []
+    for window in focus.get("free") or []:
+        try:
+            free.append((parse_timestamp(window["start"]), parse_timestamp(window["end"])))
+        except (KeyError, AttributeError, ValueError):
+            continue
+    travel = []
+    for trip in travel_trips(option):
+        try:
+            travel.append((parse_timestamp(trip["depart"]), parse_timestamp(trip["arrive"])))
+        except (AttributeError, ValueError):
+            continue
+
+    windows = [
+        (start, end) for start, end in _subtract(free, travel)
+        if (end - start).total_seconds() >= minimum * 60
+    ]
+    if windows:
+        note = "Protects focus time " + ", ".join(
+            f"{_clock(start, end, tz)} ({_duration(int((end - start).total_seconds() // 60))})" for start, end in windows
+        ) + "."
+    else:
+        note = f"Leaves no free block of {_duration(minimum)} for focus time."
+
+    checked = dict(option)
+    checked["focus"] = {
+        "minimum_minutes": minimum,
+        "windows": [
+            {"start": start.isoformat(), "end": end.isoformat(), "minutes": int((end - start).total_seconds() // 60)}
+            for start, end in windows
+        ],
+        "protected": bool(windows),
+        "note": note
+    }
+    if not windows:
+        checked["warnings"] = list(option.get("warnings", [])) + [note.rstrip(".")]
+        logger.info(f"{option.get('option_type')} leaves no {minimum}-minute focus block")
+    return checked
+
+
+def splits_focus(option: Dict[str, Any]) -> bool:
+    """Whether a checked option leaves the user no focus window"""
+
+    focus = option.get("focus")
+    return bool(focus) and not focus["protected"]
+
+
+def describe_focus(option: Dict[str, Any]) -> Optional[str]:
+    """A checked option's focus windows as text for its reasoning, or None without focus time"""
+
+    focus = option.get("focus")
+    return focus["note"] if focus else None
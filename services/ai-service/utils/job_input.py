@@ -0,0 +1,65 @@
+"""
+Job input versions.
+
+The backend writes a job's input_data as a JSON object with a schemaVersion saying which
+version of the format it follows (the schemas are in the backend's pkg/jobinput/schemas).
+Jobs queued before versions existed, or by an older backend, have none and are version 1:
+any JSON value, with anything that isn't an object read as raw_input.
+
+Input data is upgraded here to the version the worker reads, the same way the backend
+upgrades it, so either can be deployed first. A version newer than the worker's means
+the backend was deployed ahead of it; the job fails with a clear error rather than being
+planned from input the worker might misread.
+"""
+
+import json
+from typing import Any, Dict
+
+SCHEMA_VERSION = 2
+VERSION_KEY = "schemaVersion"
+
+
+class UnsupportedSchemaVersion(ValueError):
+    """Input data of a version this worker can't read"""
+
+
+def _upgrade_v1(data: Dict[str, Any]) -> Dict[str, Any]:
+    """Adds the version; a context that isn't an object was never read, so it is dropped"""
+
+    if "context" in data and not isinstance(data["context"], dict):
+        del data["context"]
+    data[VERSION_KEY] = 2
+    return data
+
+
+_UPGRADES = {1: _upgrade_v1}
+
+
+def upgrade_input_data(raw: Any) -> Dict[str, Any]:
+    """A job's input_data, a JSON string or decoded value, as a payload of SCHEMA_VERSION"""
+
+    if raw is None or (isinstance(raw, str) and not raw.strip()):
+        data: Any = {}
+    elif isinstance(raw, str):
+        try:
+            data = json.loads(raw)
+        except json.JSONDecodeError:
+            data = raw
+    else:
+        data = raw
+    if isinstance(data, str):
+        data = {"raw_input": data}
+    elif not isinstance(data, dict):
+        data = {"raw_input": raw if isinstance(raw, str) else json.dumps(data)}
+
+    version = data.get(VERSION_KEY, 1)
+    if isinstance(version, bool) or not isinstance(version, int) or version < 1:
+        raise ValueError(f"invalid {VERSION_KEY} {version!r}: expected a positive integer")
+    if version > SCHEMA_VERSION:
+        raise UnsupportedSchemaVersion(
+            f"unsupported {VERSION_KEY} {version}: this worker reads up to version {SCHEMA_VERSION}"
+        )
+    while version < SCHEMA_VERSION:
+        data = _UPGRADES[version](data)
+        version += 1
+    return data
@@ -0,0 +1,28 @@
+"""
+Meeting load.
+
+The backend flags days with too many meetings: runs of back-to-back meetings without a
+break, no time left for lunch, and more meeting hours than the configured limit. When a
+job's day has any, they are in its input_data as context.meeting_load:
+
+    {"date": "2026-03-02", "meetings": 7, "meetingMinutes": 435, "longestRunMinutes": 300,
+     "warnings": [{"type": "BACK_TO_BACK", "message": "...", "start": ..., "end": ...}]}
+
+The meetings are the same whichever option is picked, so every recommendation lists the
+warnings among its trade-offs.
+"""
+
+from typing import Dict, Any, List
+
+
+def get_meeting_load_warnings(input_data: Dict[str, Any]) -> List[str]:
+    """The messages of the warnings about a job's day of meetings"""
+
+    context = input_data.get("context", {}) if isinstance(input_data, dict) else {}
+    load = context.get("meeting_load") if isinstance(context, dict) else None
+    if not isinstance(load, dict) or not isinstance(load.get("warnings"), list):
+        return []
+    return [
+        warning["message"] for warning in load["warnings"]
+        if isinstance(warning, dict) and warning.get("message")
+    ]
@@ -0,0 +1,43 @@
+"""
+Messages the worker sends the backend.
+
+Progress updates are published on the Redis progress channel and results are stored on
+the job with updateJob. Their formats are defined by the backend (pkg/contracts, with a
+JSON Schema per kind); scripts/check_contracts.py checks these builders against them.
+"""
+
+from datetime import datetime, timezone
+from typing import Any, Dict, List, Optional
+
+
+def progress_message(
+    job_id: str,
+    status: str,
+    progress: Optional[float] = None,
+    current_step: Optional[str] = None,
+    result: Optional[str] = None,
+    error_message: Optional[str] = None,
+) -> Dict[str, Any]:
+    """A progress update for the progress channel; result is the job result as JSON"""
+
+    message: Dict[str, Any] = {"jobId": job_id, "status": status}
+    if progress is not None:
+        message["progress"] = progress
+    if current_step is not None:
+        message["currentStep"] = current_step
+    if result is not None:
+        message["result"] = result
+    if error_message is not None:
+        message["errorMessage"] = error_message
+    message["timestamp"] = datetime.now(timezone.utc).isoformat()
+    return message
+
+
+def job_result(recommendations: List[Dict[str, Any]]) -> Dict[str, Any]:
+    """The result stored on a job completed with recommendations"""
+
+    return {
+        "recommendations": recommendations,
+        "total_options": len(recommendations),
+        "analysis_complete": True,
+    }
@@ -0,0 +1,97 @@
+"""
+Office selection for tenants with several offices (e.g. HQ and a satellite).
+
+The backend adds the tenant's offices and the user's default office to the job's
+input_data context. Commute options are planned once per office and the best office
+is chosen per option.
+"""
+
+import logging
+from typing import Dict, Any, List, Optional, Tuple
+
+from utils.constraints import missed_constraints
+from utils.travel_modes import chosen_cost
+
+logger = logging.getLogger(__name__)
+
+# The default office wins unless another office saves more than this much commuting
+DEFAULT_OFFICE_TOLERANCE_MINUTES = 10
+
+
+def get_offices(input_data: Dict[str, Any]) -> Tuple[List[Dict[str, Any]], Optional[str]]:
+    """Return the offices and the default office ID from a job's input data"""
+
+    context = input_data.get("context", {}) if isinstance(input_data, dict) else {}
+    if not isinstance(context, dict):
+        return [], None
+
+    offices = [office for office in context.get("offices") or [] if isinstance(office, dict) and office.get("id")]
+    default_office_id = context.get("default_office_id")
+    return offices, default_office_id
+
+
+def office_destination(office: Optional[Dict[str, Any]]) -> str:
+    """Destination used for route lookups; the generic "office" when there are no offices"""
+
+    if not office:
+        return "office"
+    return office.get("address") or office.get("name") or "office"
+
+
+def choose_office(
+    candidates: List[Tuple[Dict[str, Any], Dict[str, Any]]],
+    default_office_id: Optional[str]
+) -> Dict[str, Any]:
+    """
+    Pick the commute option with the shortest total commute from (office, option) pairs
+    planned for the same presence block, preferring the default office within
+    DEFAULT_OFFICE_TOLERANCE_MINUTES. Options missing fewer of the job's constraints win
+    over shorter commutes. The chosen option is annotated with its office and a
+    comparison of every office.
+    """
+
+    def commute_minutes(option: Dict[str, Any]) -> float:
+        # Fallback options have no commute estimate and rank last
+        minutes = option.get("efficiency_metrics", {}).get("total_commute_minutes")
+        return float("inf") if minutes is None else minutes
+
+    ranked = sorted(candidates, key=lambda candidate: (missed_constraints(candidate[1]), commute_minutes(candidate[1])))
+    best_office, best_option = ranked[0]
+
+    for office, option in ranked:
+        if office.get("id") == default_office_id:
+            if missed_constraints(option) == missed_constraints(best_option) and \
+                    commute_minutes(option) - commute_minutes(best_option) <= DEFAULT_OFFICE_TOLERANCE_MINUTES:
+                best_office, best_option = office, option
+            break
+
+    best_option["office_id"] = best_office["id"]
+    best_option["office"] = {
+        "id": best_office["id"],
+        "name": best_office.get("name"),
+        "address": best_office.get("address"),
+        "amenities": best_office.get("amenities") or [],
+        "is_default": best_office.get("id") == default_office_id
+    }
+    best_option["office_comparison"] = [
+        {
+            "office_id": office["id"],
+            "name": office.get("name"),
+            "total_commute_minutes": option.get("efficiency_metrics", {}).get("total_commute_minutes"),
+            "commute_cost": (chosen_cost(option) or {}).get("total"),
+            "commute_start": option.get("commute_start"),
+            "commute_end": option.get("commute_end"),
+            "missed_constraints": missed_constraints(option),
+            "is_default": office.get("id") == default_office_id,
+            "chosen": office is best_office
+        }
+        for office, option in ranked
+    ]
+
+    if len(ranked) > 1:
+        logger.info(
+            f"Chose office {best_office.get('name')} for {best_option.get('option_type')} "
+            f"({commute_minutes(best_option)} commute minutes across {len(ranked)} offices)"
+        )
+
+    return best_option
@@ -0,0 +1,254 @@
+"""
+Offsite meeting detection and mid-day travel planning.
+
+A meeting held at an external location (e.g. a client's office) is neither an office nor a
+remote meeting: the user has to travel there. Meetings are placed with the coordinates the
+backend geocodes from their location, falling back to the location text. Commute options are
+then planned as a sequence of travel legs, e.g. home -> office -> client -> home.
+"""
+
+import logging
+import math
+import re
+from datetime import datetime, timedelta, timezone
+from typing import Dict, Any, List, Optional
+
+logger = logging.getLogger(__name__)
+
+# A meeting further than this from every office is offsite
+OFFSITE_RADIUS_KM = 0.5
+
+# Time to find the room and settle in before an offsite meeting
+OFFSITE_ARRIVAL_BUFFER_MINUTES = 10
+
+# Mirrors geo.IsVirtualLocation in the backend: video or phone calls have no place
+VIRTUAL_LOCATION = re.compile(
+    r"(https?://|\bzoom\b|google meet|\bmeet\.google\b|microsoft teams|\bteams\b|\bwebex\b|\bskype\b|\bonline\b|\bvirtual\b|\bphone\b)",
+    re.IGNORECASE
+)
+
+# Locations inside the user's own office
+IN_OFFICE_LOCATION = re.compile(
+    r"\b(room|conference|boardroom|meeting room|floor|desk|kitchen|lobby|office|hq|headquarters)\b",
+    re.IGNORECASE
+)
+
+# Text that reads like an external place: a street address or an explicit offsite
+EXTERNAL_LOCATION = re.compile(
+    r"(\d+\s+\w+|\b(street|st|avenue|ave|road|rd|boulevard|blvd|plaza|suite|client|customer|offsite|off-site|on-site at)\b)",
+    re.IGNORECASE
+)
+
+
+def distance_km(a: Dict[str, float], b: Dict[str, float]) -> float:
+    """Great-circle distance between two {"latitude", "longitude"} points"""
+
+    lat1, lng1 = math.radians(a["latitude"]), math.radians(a["longitude"])
+    lat2, lng2 = math.radians(b["latitude"]), math.radians(b["longitude"])
+    h = math.sin((lat2 - lat1) / 2) ** 2 + math.cos(lat1) * math.cos(lat2) * math.sin((lng2 - lng1) / 2) ** 2
+    return 2 * 6371.0 * math.asin(math.sqrt(h))
+
+
+def _point(latitude: Any, longitude: Any) -> Optional[Dict[str, float]]:
+    if latitude is None or longitude is None:
+        return None
+    return {"latitude": float(latitude), "longitude": float(longitude)}
+
+
+def detect_offsite(event: Dict[str, Any], offices: List[Dict[str, Any]]) -> Optional[Dict[str, Any]]:
+    """
+    Return where an offsite meeting is held, or None for meetings at the office, virtual
+    meetings and meetings without a location. Coordinates decide when both the meeting and
+    an office have them; otherwise the location text is used.
+    """
+
+    location = (event.get("location") or "").strip()
+    if not location or VIRTUAL_LOCATION.search(location):
+        return None
+
+    meeting_point = _point(
+        event.get("location_latitude", event.get("locationLatitude")),
+        event.get("location_longitude", event.get("locationLongitude"))
+    )
+    office_points = [
+        point for point in (_point(o.get("latitude"), o.get("longitude")) for o in offices) if point
+    ]
+
+    if meeting_point and office_points:
+        nearest_km = min(distance_km(meeting_point, point) for point in office_points)
+        if nearest_km <= OFFSITE_RADIUS_KM:
+            return None
+        return {
+            "location": location,
+            **meeting_point,
+            "distance_km": round(nearest_km, 1),
+            "reason": f"{location} is {nearest_km:.1f} km from the nearest office",
+            "confidence": "high"
+        }
+
+    lowered = location.lower()
+    for office in offices:
+        for label in (office.get("name"), office.get("address")):
+            if label and label.lower() in lowered:
+                return None
+    if IN_OFFICE_LOCATION.search(location) or not EXTERNAL_LOCATION.search(location):
+        return None
+
+    return {
+        "location": location,
+        **(meeting_point or {}),
+        "reason": f"{location} looks like an external address",
+        "confidence": "medium"
+    }
+
+
+def offsite_meetings(classifications: List[Dict[str, Any]]) -> List[Dict[str, Any]]:
+    """The classified meetings attended in person away from the office, by start time"""
+
+    meetings = [c for c in classifications if c.get("location_type") == "offsite"]
+    return sorted(meetings, key=lambda m: m.get("start_time", ""))
+
+
+def parse_timestamp(timestamp: str) -> datetime:
+    """Parse an ISO timestamp; naive timestamps are taken to be UTC"""
+
+    dt = datetime.fromisoformat(timestamp.replace("Z", "+00:00"))
+    return dt if dt.tzinfo else dt.replace(tzinfo=timezone.utc)
+
+
+def _visits(option: Dict[str, Any], meetings: List[Dict[str, Any]]) -> List[Dict[str, Any]]:
+    """
+    The places an option's day visits, in time order. The office window is split around
+    offsite meetings held during it; office visits are flexible, so their ends can move to
+    make room for travel.
+    """
+
+    visits = [
+        {
+            "place": m["offsite"]["location"],
+            "start": parse_timestamp(m["start_time"]),
+            "end": parse_timestamp(m["end_time"]),
+            "flexible": False,
+            "meeting_id": m["meeting_id"]
+        }
+        for m in meetings if m.get("start_time") and m.get("end_time")
+    ]
+
+    if option.get("office_arrival") and option.get("office_departure"):
+        cursor, departure = parse_timestamp(option["office_arrival"]), parse_timestamp(option["office_departure"])
+        for visit in sorted(visits, key=lambda v: v["start"]):
+            if visit["end"] <= cursor or visit["start"] >= departure:
+                continue
+            if visit["start"] > cursor:
+                visits.append({"place": "office", "start": cursor, "end": visit["start"], "flexible": True})
+            cursor = max(cursor, visit["end"])
+        if cursor < departure:
+            visits.append({"place": "office", "start": cursor, "end": departure, "flexible": True})
+
+    return sorted(visits, key=lambda v: v["start"])
+
+
+async def plan_travel_legs(
+    maps_tool,
+    option: Dict[str, Any],
+    meetings: List[Dict[str, Any]],
+    office_destination: str = "office"
+) -> Dict[str, Any]:
+    """
+    Plan the day's route for a commute option with offsite meetings, starting and ending at
+    home, e.g. home -> office -> client -> home. Travel to an offsite meeting arrives
+    OFFSITE_ARRIVAL_BUFFER_MINUTES early, leaving the office sooner if needed; travel that
+    can't make it after a fixed meeting is flagged in the warnings. The option's commute
+    times, remote meetings and efficiency metrics are updated to include the mid-day
+    travel; options without offsite meetings are returned unchanged.
+    """
+
+    if not meetings:
+        return option
+
+    warnings = list(option.get("warnings", []))
+    legs = []
+    place = "home"
+    free_from = None
+    leaving_flexible = True
+
+    for visit in _visits(option, meetings):
+        destination = office_destination if visit["flexible"] else visit["place"]
+        if destination == place:
+            free_from = visit["end"]
+            continue
+
+        arrive = visit["start"]
+        if not visit["flexible"]:
+            arrive -= timedelta(minutes=OFFSITE_ARRIVAL_BUFFER_MINUTES)
+        route = await maps_tool.get_route_duration(origin=place, destination=destination, arrival_time=arrive.isoformat())
+        minutes = route["duration"]["value"] // 60
+        depart = arrive - timedelta(minutes=minutes)
+
+        if free_from and depart < free_from and not leaving_flexible:
+            # The previous stop is a meeting with a fixed end: leave when it ends
+            depart = free_from
+            arrive = depart + timedelta(minutes=minutes)
+            if visit["flexible"]:
+                if arrive >= visit["end"]:
+                    # Not worth going back to the office for what's left of the day
+                    continue
+            else:
+                late = int((arrive - visit["start"]).total_seconds() // 60)
+                if late > 0:
+                    warnings.append(f"Arrives {late} min late to the meeting at {destination} after leaving {place}")
+
+        legs.append({
+            "from": place,
+            "to": destination,
+            "depart": depart.isoformat(),
+            "arrive": arrive.isoformat(),
+            "minutes": minutes,
+            "meeting_id": visit.get("meeting_id")
+        })
+        place = destination
+        free_from = visit["end"]
+        leaving_flexible = visit["flexible"]
+
+    if place != "home":
+        route = await maps_tool.get_route_duration(origin=place, destination="home", departure_time=free_from.isoformat())
+        minutes = route["duration"]["value"] // 60
+        legs.append({
+            "from": place,
+            "to": "home",
+            "depart": free_from.isoformat(),
+            "arrive": (free_from + timedelta(minutes=minutes)).isoformat(),
+            "minutes": minutes,
+            "meeting_id": None
+        })
+    if not legs:
+        return option
+
+    offsite_ids = {m["meeting_id"] for m in meetings}
+    travel_minutes = sum(leg["minutes"] for leg in legs)
+    midday_minutes = sum(leg["minutes"] for leg in legs[1:-1])
+
+    planned = dict(option)
+    planned["commute_start"] = legs[0]["depart"]
+    planned["commute_end"] = legs[-1]["arrive"]
+    planned["travel_legs"] = legs
+    planned["offsite_meetings"] = meetings
+    planned["remote_meetings"] = [m for m in option.get("remote_meetings", []) if m.get("meeting_id") not in offsite_ids]
+    planned["warnings"] = warnings
+
+    metrics = dict(option.get("efficiency_metrics", {}))
+    metrics["total_commute_minutes"] = travel_minutes
+    metrics["midday_travel_minutes"] = midday_minutes
+    day_minutes = (parse_timestamp(legs[-1]["arrive"]) - parse_timestamp(legs[0]["depart"])).total_seconds() / 60
+    if day_minutes > 0:
+        metrics["total_day_minutes"] = int(day_minutes)
+        metrics["day_efficiency"] = round(max(0.0, 1 - travel_minutes / day_minutes), 2)
+    if metrics.get("office_minutes"):
+        metrics["commute_to_office_ratio"] = round(travel_minutes / metrics["office_minutes"], 2)
+    planned["efficiency_metrics"] = metrics
+
+    logger.info(
+        f"Planned {len(legs)} travel legs for {option.get('option_type')} with "
+        f"{len(meetings)} offsite meetings ({midday_minutes} mid-day travel minutes)"
+    )
+    return planned
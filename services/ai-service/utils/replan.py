@@ -0,0 +1,65 @@
+"""
+Re-planning the rest of a day.
+
+When the day changes under a plan, e.g. an afternoon meeting goes remote, the user can
+ask the backend to re-plan from now (replanNow). It creates a follow-up job whose
+input_data context has
+
+    {"replan": {"followUpOf": ..., "now": ..., "location": "HOME" | "OFFICE" | "TRAVELLING",
+                "officeId": ..., "currentPlan": {...}, "remainingMeetings": [...]}}
+
+and a LEAVE_AFTER constraint at the current time, so no option leaves before now. The
+meetings that ended before now are dropped from the day; they can't shape the rest of it.
+Travel is routed for departures from now, i.e. in the traffic there is now.
+"""
+
+import logging
+from datetime import datetime
+from typing import Dict, Any, List, Optional
+
+from utils.offsite import parse_timestamp
+
+logger = logging.getLogger(__name__)
+
+
+def get_replan(input_data: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+    """The re-plan context of a follow-up job, or None for other jobs"""
+
+    context = input_data.get("context", {}) if isinstance(input_data, dict) else {}
+    replan = context.get("replan") if isinstance(context, dict) else None
+    if not isinstance(replan, dict) or not replan.get("now"):
+        return None
+    return replan
+
+
+def replan_now(replan: Dict[str, Any]) -> Optional[datetime]:
+    """When the re-plan was asked for; None when it can't be read"""
+
+    try:
+        return parse_timestamp(replan["now"])
+    except (KeyError, AttributeError, ValueError):
+        logger.warning(f"Unreadable re-plan time {replan.get('now')!r}; planning the whole day")
+        return None
+
+
+def remaining_meetings(events: List[Dict[str, Any]], input_data: Dict[str, Any]) -> List[Dict[str, Any]]:
+    """The meetings still to plan around: for a re-plan, those that haven't ended"""
+
+    replan = get_replan(input_data)
+    now = replan_now(replan) if replan else None
+    if now is None:
+        return events
+
+    remaining = []
+    for event in events:
+        try:
+            ended = parse_timestamp(event["end_time"]) <= now
+        except (KeyError, AttributeError, ValueError):
+            ended = False
+        if not ended:
+            remaining.append(event)
+    logger.info(
+        f"Re-planning from {now.isoformat()} ({replan.get('location') or 'no plan'}): "
+        f"{len(remaining)} of {len(events)} meetings left"
+    )
+    return remaining
@@ -0,0 +1,240 @@
+"""
+Multi-modal commute planning.
+
+The backend adds the user's travel profile to the job's input_data context: the modes they
+will commute by (DRIVE, TRANSIT, BIKE, WALK), the one they prefer and what parking and
+fares cost them. Each commute option is evaluated for every mode in the profile with
+per-mode duration, cost and CO2, and planned with the preferred mode when it's feasible,
+otherwise the fastest feasible one. Driving is costed with the user's fuel cost per km,
+the office's parking price (or the user's own) and the office's congestion charge.
+"""
+
+import logging
+from datetime import timedelta
+from typing import Dict, Any, List, Optional
+
+from utils.offsite import parse_timestamp
+
+logger = logging.getLogger(__name__)
+
+MODES = ["DRIVE", "TRANSIT", "BIKE", "WALK"]
+
+# Google Maps travel mode of each mode
+MAPS_MODES = {
+    "DRIVE": "driving",
+    "TRANSIT": "transit",
+    "BIKE": "bicycling",
+    "WALK": "walking"
+}
+
+# Average emissions per passenger-km: a petrol car and a mix of bus and rail. Mirrored by
+# carbon.KgPerKm in the backend, which totals the commute history.
+CO2_KG_PER_KM = {
+    "DRIVE": 0.171,
+    "TRANSIT": 0.041,
+    "BIKE": 0.0,
+    "WALK": 0.0
+}
+
+# Running cost of a car (fuel, wear) per km, unless the travel profile sets its own
+DRIVE_COST_PER_KM = 0.40
+
+# Used when the travel profile doesn't say: parking per day, a fare per trip and the
+# longest trip people will usually bike or walk
+DEFAULT_PARKING_COST = 0.0
+DEFAULT_TRANSIT_FARE = 2.90
+DEFAULT_MAX_BIKE_MINUTES = 45
+DEFAULT_MAX_WALK_MINUTES = 30
+
+
+def get_travel_profile(input_data: Dict[str, Any]) -> Dict[str, Any]:
+    """Return the user's travel profile from a job's input data, with defaults filled in"""
+
+    context = input_data.get("context", {}) if isinstance(input_data, dict) else {}
+    profile = context.get("travel_profile") if isinstance(context, dict) else None
+    if not isinstance(profile, dict):
+        profile = {}
+
+    def value(key: str, default: Any) -> Any:
+        return default if profile.get(key) is None else profile[key]
+
+    modes = [mode for mode in profile.get("modes") or [] if mode in MODES] or list(MODES)
+    preferred = profile.get("preferredMode")
+    return {
+        "modes": modes,
+        "preferred_mode": preferred if preferred in modes else None,
+        "max_bike_minutes": value("maxBikeMinutes", DEFAULT_MAX_BIKE_MINUTES),
+        "max_walk_minutes": value("maxWalkMinutes", DEFAULT_MAX_WALK_MINUTES),
+        "parking_cost": value("parkingCost", DEFAULT_PARKING_COST),
+        "transit_fare": value("transitFare", DEFAULT_TRANSIT_FARE),
+        "fuel_cost_per_km": value("fuelCostPerKm", DRIVE_COST_PER_KM)
+    }
+
+
+def _trips(option: Dict[str, Any], office_destination: str) -> List[Dict[str, Any]]:
+    """
+    The trips an option makes. Each keeps the end that's fixed when the mode changes: trips
+    to the office or to a meeting keep their arrival, the others their departure.
+    """
+
+    if option.get("travel_legs"):
+        return [
+            {**leg, "anchor": "arrive" if i == 0 or leg.get("meeting_id") else "depart"}
+            for i, leg in enumerate(option["travel_legs"])
+        ]
+    if option.get("office_arrival") and option.get("office_departure"):
+        return [
+            {"from": "home", "to": office_destination, "arrive": option["office_arrival"], "anchor": "arrive"},
+            {"from": office_destination, "to": "home", "depart": option["office_departure"], "anchor": "depart"}
+        ]
+    return []
+
+
+def _cost_breakdown(
+    mode: str,
+    legs: List[Dict[str, Any]],
+    profile: Dict[str, Any],
+    office: Optional[Dict[str, Any]],
+    office_destination: str
+) -> Dict[str, float]:
+    """
+    What a day's trips cost by mode. Parking and congestion are per day: the office's
+    prices apply when the day drives to the office, the user's parking cost otherwise.
+    """
+
+    breakdown = {"fuel": 0.0, "parking": 0.0, "congestion": 0.0, "fares": 0.0}
+    if mode == "DRIVE":
+        to_office = any(leg["to"] == office_destination for leg in legs)
+        office = office if to_office and office else {}
+        breakdown["fuel"] = sum(leg["distance_km"] for leg in legs) * profile["fuel_cost_per_km"]
+        parking = office.get("parkingCost")
+        breakdown["parking"] = profile["parking_cost"] if parking is None else parking
+        breakdown["congestion"] = office.get("congestionCharge") or 0.0
+    elif mode == "TRANSIT":
+        breakdown["fares"] = profile["transit_fare"] * len(legs)
+    return {key: round(value, 2) for key, value in breakdown.items()}
+
+
+def chosen_cost(option: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+    """The cost of an option's chosen travel mode with its breakdown, or None if it has no travel"""
+
+    chosen = next((m for m in option.get("mode_options", []) if m.get("chosen")), None)
+    if not chosen:
+        return None
+    return {"mode": chosen["mode"], "total": chosen["cost"], **chosen.get("cost_breakdown", {})}
+
+
+def describe_cost(cost: Dict[str, Any]) -> str:
+    """A cost as text, e.g. "14.40 (fuel 10.40, parking 4.00)" """
+
+    parts = [f"{key} {cost[key]:.2f}" for key in ("fuel", "parking", "congestion", "fares") if cost.get(key)]
+    return f"{cost['total']:.2f} ({', '.join(parts)})" if parts else f"{cost['total']:.2f}"
+
+
+def _max_minutes(mode: str, profile: Dict[str, Any]) -> Optional[int]:
+    if mode == "BIKE":
+        return profile["max_bike_minutes"]
+    if mode == "WALK":
+        return profile["max_walk_minutes"]
+    return None
+
+
+async def choose_travel_mode(
+    maps_tool,
+    option: Dict[str, Any],
+    profile: Dict[str, Any],
+    office_destination: str = "office",
+    office: Optional[Dict[str, Any]] = None
+) -> Dict[str, Any]:
+    """
+    Evaluate every mode in the travel profile for an option's trips and plan the option with
+    the preferred mode if it's feasible, otherwise the fastest feasible mode. A mode is
+    infeasible when a trip by bike or on foot takes longer than the profile allows. The
+    option gains travel_mode and mode_options, and its commute times, travel legs and
+    efficiency metrics follow the chosen mode. office is the office the option commutes to,
+    for its parking and congestion charges. Options without travel are returned unchanged.
+    """
+
+    trips = _trips(option, office_destination)
+    if not trips:
+        return option
+
+    mode_options = []
+    routed = {}
+    for mode in profile["modes"]:
+        legs = []
+        for trip in trips:
+            anchor = trip["anchor"]
+            route = await maps_tool.get_route_duration(
+                origin=trip["from"],
+                destination=trip["to"],
+                arrival_time=trip[anchor] if anchor == "arrive" else None,
+                departure_time=trip[anchor] if anchor == "depart" else None,
+                mode=MAPS_MODES[mode]
+            )
+            minutes = route["duration"]["value"] // 60
+            at = parse_timestamp(trip[anchor])
+            depart, arrive = (at - timedelta(minutes=minutes), at) if anchor == "arrive" else (at, at + timedelta(minutes=minutes))
+            leg_km = round(route["distance"]["value"] / 1000, 1)
+            legs.append({
+                **{key: value for key, value in trip.items() if key != "anchor"},
+                "depart": depart.isoformat(),
+                "arrive": arrive.isoformat(),
+                "minutes": minutes,
+                "distance_km": leg_km,
+                "co2_kg": round(leg_km * CO2_KG_PER_KM[mode], 2),
+                "mode": mode
+            })
+
+        minutes = sum(leg["minutes"] for leg in legs)
+        distance_km = sum(leg["distance_km"] for leg in legs)
+        limit = _max_minutes(mode, profile)
+        breakdown = _cost_breakdown(mode, legs, profile, office, office_destination)
+
+        routed[mode] = legs
+        mode_options.append({
+            "mode": mode,
+            "minutes": minutes,
+            "distance_km": round(distance_km, 1),
+            "cost": round(sum(breakdown.values()), 2),
+            "cost_breakdown": breakdown,
+            "co2_kg": round(distance_km * CO2_KG_PER_KM[mode], 2),
+            "feasible": limit is None or all(leg["minutes"] <= limit for leg in legs),
+            "chosen": False
+        })
+
+    warnings = list(option.get("warnings", []))
+    feasible = [m for m in mode_options if m["feasible"]]
+    preferred = next((m for m in feasible if m["mode"] == profile["preferred_mode"]), None)
+    chosen = preferred or min(feasible or mode_options, key=lambda m: m["minutes"])
+    chosen["chosen"] = True
+    if not feasible:
+        warnings.append(f"No travel mode fits the travel profile's limits; planned with {chosen['mode'].title()}")
+    elif profile["preferred_mode"] and not preferred:
+        warnings.append(f"{profile['preferred_mode'].title()} takes too long for this day; planned with {chosen['mode'].title()}")
+
+    legs = routed[chosen["mode"]]
+    planned = dict(option)
+    planned["travel_mode"] = chosen["mode"]
+    planned["mode_options"] = mode_options
+    planned["commute_start"] = legs[0]["depart"]
+    planned["commute_end"] = legs[-1]["arrive"]
+    if option.get("travel_legs"):
+        planned["travel_legs"] = legs
+    planned["warnings"] = warnings
+
+    metrics = dict(option.get("efficiency_metrics", {}))
+    metrics["total_commute_minutes"] = chosen["minutes"]
+    day_minutes = (parse_timestamp(legs[-1]["arrive"]) - parse_timestamp(legs[0]["depart"])).total_seconds() / 60
+    if day_minutes > 0:
+        metrics["total_day_minutes"] = int(day_minutes)
+        metrics["day_efficiency"] = round(max(0.0, 1 - chosen["minutes"] / day_minutes), 2)
+    if metrics.get("office_minutes"):
+        metrics["commute_to_office_ratio"] = round(chosen["minutes"] / metrics["office_minutes"], 2)
+    planned["efficiency_metrics"] = metrics
+
+    logger.info(
+        f"Planned {option.get('option_type')} by {chosen['mode']} ({chosen['minutes']} min) out of "
+        f"{len(mode_options)} modes"
+    )
+    return planned
@@ -1,18 +1,24 @@
 """
-Event-driven job worker with Redis BRPOP and concurrency control
+Event-driven job worker reading the Redis job streams through a consumer group, or the
+RabbitMQ job queues when QUEUE_BROKER=rabbitmq, with concurrency control. Jobs are
+acknowledged only once they finish, so a crashed worker's jobs are picked up by another
+worker. Interactive jobs are always taken before batch jobs.
 """
 
 import asyncio
 import json
 import logging
-from typing import Dict, Any, Optional
-from datetime import datetime, timezone
+import time
+from typing import Dict, Any, List, Optional, Tuple
 import traceback
 
 from config.settings import get_settings
 from services.redis_service import RedisService
+from services.rabbitmq_service import RabbitMQService
 from services.backend_service import backend_service
 from graphs.workflow_orchestrator import create_workflow_orchestrator
+from utils.job_input import upgrade_input_data
+from utils.messages import progress_message
 
 logger = logging.getLogger(__name__)
 
@@ -20,8 +26,10 @@ logger = logging.getLogger(__name__)
 class JobWorker:
     """Event-driven job worker with concurrency control"""
     
-    def __init__(self, redis_service: RedisService):
+    def __init__(self, redis_service: RedisService, rabbitmq_service: Optional[RabbitMQService] = None):
         self.redis_service = redis_service
+        # Jobs come from RabbitMQ when set; progress is published through Redis either way
+        self.rabbitmq_service = rabbitmq_service
         self.backend_service = backend_service
         self.settings = get_settings()
         
@@ -29,6 +37,10 @@ class JobWorker:
         self.semaphore = asyncio.Semaphore(self.settings.max_concurrent_jobs)
         self.running = False
         self.active_jobs: Dict[str, asyncio.Task] = {}
+        # (stream, entry ID) or (queue, message) per active job; None for jobs taken from a
+        # legacy list
+        self.active_entries: Dict[str, Optional[Tuple[str, Any]]] = {}
+        self.last_claim = 0.0
         
         # Initialize workflow orchestrator (handles both rule-based and AI workflows)
         self.workflow_orchestrator = create_workflow_orchestrator(redis_service)
@@ -40,23 +52,90 @@ class JobWorker:
             f"Starting job worker with max {self.settings.max_concurrent_jobs} concurrent jobs"
         )
         
+        if self.rabbitmq_service:
+            await self._consume_rabbitmq()
+        else:
+            await self._consume_redis()
+                
+        logger.info("Job worker stopped")
+        
+    async def _consume_redis(self) -> None:
+        """Take jobs from the Redis job streams until stopped"""
+        for stream in self._streams():
+            await self.redis_service.ensure_consumer_group(
+                stream, self.settings.redis_consumer_group
+            )
+        
         while self.running:
             try:
-                # Use blocking pop to wait for jobs - no polling!
-                job_data = await self.redis_service.pop_job(
-                    self.settings.redis_job_queue,
-                    timeout=1  # 1 second timeout to allow checking self.running
-                )
+                free_slots = self.settings.max_concurrent_jobs - len(self.active_jobs)
                 
-                if job_data:
-                    # Process job immediately when received
-                    await self._handle_job(job_data)
+                if time.monotonic() - self.last_claim >= self.settings.redis_claim_interval_seconds:
+                    self.last_claim = time.monotonic()
+                    await self._keep_alive_and_claim(free_slots)
+                    free_slots = self.settings.max_concurrent_jobs - len(self.active_jobs)
+                    
+                if free_slots <= 0:
+                    # Leave new entries in the stream for workers with capacity
+                    await asyncio.sleep(0.5)
+                    continue
+                    
+                # Interactive jobs first: batch entries are only read when no
+                # interactive entry is waiting
+                entries = await self.redis_service.read_jobs(
+                    [self.settings.redis_job_stream],
+                    self.settings.redis_consumer_group,
+                    self.settings.redis_consumer_name,
+                    count=free_slots,
+                    block_ms=None
+                )
+                if not entries:
+                    # Blocking read to wait for jobs - no polling! The 1 second block
+                    # allows checking self.running. Entries beyond free_slots wait on
+                    # the semaphore.
+                    entries = await self.redis_service.read_jobs(
+                        self._streams(),
+                        self.settings.redis_consumer_group,
+                        self.settings.redis_consumer_name,
+                        count=free_slots,
+                        block_ms=1000
+                    )
+                for stream, entry_id, job_data in entries:
+                    await self._handle_job(job_data, (stream, entry_id))
+                    
+                # Compatibility: drain jobs pushed to the legacy lists by older backends
+                if not entries:
+                    for queue_name in (
+                        self.settings.redis_job_queue, self.settings.redis_batch_job_queue
+                    ):
+                        job_data = await self.redis_service.pop_job_nowait(queue_name)
+                        if job_data:
+                            await self._handle_job(job_data)
+                            break
                     
             except Exception as e:
                 logger.error(f"Error in job worker loop: {e}")
                 await asyncio.sleep(1)  # Brief pause before retrying
                 
-        logger.info("Job worker stopped")
+    async def _consume_rabbitmq(self) -> None:
+        """Take jobs from the RabbitMQ job queues until stopped. The broker redelivers the
+        unacknowledged jobs of a worker whose connection dropped, so nothing needs claiming."""
+        while self.running:
+            try:
+                free_slots = self.settings.max_concurrent_jobs - len(self.active_jobs)
+                entries = []
+                if free_slots > 0:
+                    entries = await self.rabbitmq_service.read_jobs(free_slots)
+                if not entries:
+                    # Reads don't block, so wait a little before asking again
+                    await asyncio.sleep(0.5)
+                    continue
+                for queue_name, message, job_data in entries:
+                    await self._handle_job(job_data, (queue_name, message))
+                    
+            except Exception as e:
+                logger.error(f"Error in job worker loop: {e}")
+                await asyncio.sleep(1)  # Brief pause before retrying
         
     async def stop(self) -> None:
         """Stop the job worker and wait for active jobs to complete"""
@@ -74,32 +153,88 @@ class JobWorker:
             
         logger.info("Job worker stopped successfully")
         
-    async def _handle_job(self, job_data: Dict[str, Any]) -> None:
+    def _streams(self) -> List[str]:
+        """Job streams in priority order"""
+        return [self.settings.redis_job_stream, self.settings.redis_batch_job_stream]
+        
+    async def _keep_alive_and_claim(self, free_slots: int) -> None:
+        """Reset the idle time of our in-flight entries, then claim entries abandoned by
+        crashed workers"""
+        group = self.settings.redis_consumer_group
+        consumer = self.settings.redis_consumer_name
+        
+        for stream in self._streams():
+            in_flight = [
+                entry[1] for entry in self.active_entries.values() if entry and entry[0] == stream
+            ]
+            await self.redis_service.touch_jobs(stream, group, consumer, in_flight)
+        
+        for stream in self._streams():
+            if free_slots <= 0:
+                return
+            claimed = await self.redis_service.claim_stale_jobs(
+                stream, group, consumer,
+                min_idle_ms=self.settings.redis_claim_idle_ms,
+                count=free_slots
+            )
+            for entry_id, job_data in claimed:
+                await self._handle_job(job_data, (stream, entry_id))
+            free_slots -= len(claimed)
+            
+    async def _ack(self, entry: Optional[Tuple[str, Any]]) -> None:
+        """Acknowledge a stream entry or queue message; legacy list jobs have nothing to
+        acknowledge"""
+        if not entry:
+            return
+        stream, entry_id = entry
+        try:
+            if self.rabbitmq_service:
+                await self.rabbitmq_service.ack_job(entry_id)
+            else:
+                await self.redis_service.ack_job(
+                    stream, self.settings.redis_consumer_group, entry_id
+                )
+        except Exception as e:
+            # The job is delivered again: claimed after the idle timeout, or redelivered
+            # by RabbitMQ once the connection drops
+            logger.error(f"Failed to acknowledge job entry {entry_id} on {stream}: {e}")
+            
+    async def _handle_job(self, job_data: Optional[Dict[str, Any]], entry: Optional[Tuple[str, Any]] = None) -> None:
         """Handle incoming job with concurrency control"""
-        job_id = job_data.get("job_id")
+        job_id = job_data.get("job_id") if job_data else None
         if not job_id:
-            logger.error("Received job without job_id")
+            logger.error(f"Received job without job_id (entry {entry})")
+            await self._ack(entry)
             return
             
         # Check if we're already processing this job
         if job_id in self.active_jobs:
             logger.warning(f"Job {job_id} already being processed, skipping")
+            # A second entry for the same job (e.g. a requeue) is redundant
+            if entry and self.active_entries.get(job_id) != entry:
+                await self._ack(entry)
             return
             
         # Create task for job processing with concurrency control
-        task = asyncio.create_task(self._process_job_with_semaphore(job_data))
+        task = asyncio.create_task(self._process_job_with_semaphore(job_data, entry))
         self.active_jobs[job_id] = task
+        self.active_entries[job_id] = entry
         
         # Set up task completion callback
-        task.add_done_callback(lambda t: self.active_jobs.pop(job_id, None))
+        def _done(t: asyncio.Task) -> None:
+            self.active_jobs.pop(job_id, None)
+            self.active_entries.pop(job_id, None)
+        task.add_done_callback(_done)
         
         logger.info(
-            f"Started processing job {job_id} "
+            f"Started processing {job_data.get('priority', 'INTERACTIVE').lower()} job {job_id} "
             f"({len(self.active_jobs)}/{self.settings.max_concurrent_jobs} active)"
         )
         
-    async def _process_job_with_semaphore(self, job_data: Dict[str, Any]) -> None:
-        """Process job with semaphore-based concurrency control"""
+    async def _process_job_with_semaphore(self, job_data: Dict[str, Any], entry: Optional[Tuple[str, Any]] = None) -> None:
+        """Process job with semaphore-based concurrency control. The stream entry is
+        acknowledged once the job completed or was marked FAILED; if the worker is stopped
+        mid-job the entry stays pending for another worker to claim."""
         job_id = job_data.get("job_id")
         
         async with self.semaphore:
@@ -120,14 +255,11 @@ class JobWorker:
                 # Publish failure notification
                 await self.redis_service.publish_progress(
                     self.settings.redis_progress_channel,
-                    {
-                        "jobId": job_id,
-                        "status": "FAILED",
-                        "errorMessage": str(e),
-                        "timestamp": datetime.now(timezone.utc).isoformat()
-                    }
+                    progress_message(job_id, "FAILED", error_message=str(e))
                 )
                 
+            await self._ack(entry)
+                
     async def _process_job(self, job_data: Dict[str, Any]) -> None:
         """Process a single job using LangGraph workflow"""
         job_id = job_data.get("job_id")
@@ -148,30 +280,11 @@ class JobWorker:
             # Publish progress update
             await self.redis_service.publish_progress(
                 self.settings.redis_progress_channel,
-                {
-                    "jobId": job_id,
-                    "status": "IN_PROGRESS",
-                    "progress": 0.0,
-                    "currentStep": "Starting workflow",
-                    "timestamp": datetime.now(timezone.utc).isoformat()
-                }
+                progress_message(job_id, "IN_PROGRESS", progress=0.0, current_step="Starting workflow")
             )
             
-            # Handle input_data (can be either dict or JSON string)
-            raw_input_data = job_data.get("input_data", {})
-            if isinstance(raw_input_data, str):
-                try:
-                    parsed_input_data = json.loads(raw_input_data)
-                    logger.info(f"Parsed input_data from JSON string for job {job_id}")
-                except json.JSONDecodeError:
-                    logger.warning(f"Failed to parse input_data as JSON, using as raw string: {raw_input_data}")
-                    parsed_input_data = {"raw_input": raw_input_data}
-            elif isinstance(raw_input_data, dict):
-                parsed_input_data = raw_input_data
-                logger.info(f"Using input_data as dict for job {job_id}")
-            else:
-                logger.warning(f"Unexpected input_data type for job {job_id}: {type(raw_input_data)}")
-                parsed_input_data = {"raw_input": str(raw_input_data)}
+            # input_data is a JSON string or dict of any schema version up to the worker's
+            parsed_input_data = upgrade_input_data(job_data.get("input_data"))
             
             # Extract user timezone from input data for timezone-aware processing
             user_timezone = "UTC"  # Default fallback
@@ -190,6 +303,8 @@ class JobWorker:
             }
             
             result = await self.workflow_orchestrator.execute(workflow_input)
+            if self.settings.artifact_uploads_enabled:
+                await self._offload_reasoning_trace(job_id, result)
             
             # Update job status to completed
             await self.backend_service.update_job_status(
@@ -203,14 +318,13 @@ class JobWorker:
             # Publish completion notification
             await self.redis_service.publish_progress(
                 self.settings.redis_progress_channel,
-                {
-                    "jobId": job_id,
-                    "status": "COMPLETED",
-                    "progress": 1.0,
-                    "currentStep": "Workflow completed",
-                    "result": json.dumps(result) if result else None,
-                    "timestamp": datetime.now(timezone.utc).isoformat()
-                }
+                progress_message(
+                    job_id,
+                    "COMPLETED",
+                    progress=1.0,
+                    current_step="Workflow completed",
+                    result=json.dumps(result) if result else None,
+                )
             )
             
             logger.info(f"Successfully completed job {job_id}")
@@ -219,16 +333,53 @@ class JobWorker:
             logger.error(f"Job {job_id} failed: {e}")
             raise  # Re-raise to be handled by _process_job_with_semaphore
             
+    async def _offload_reasoning_trace(self, job_id: str, result: Any) -> None:
+        """Move a large LLM reasoning trace out of the job result into object storage,
+        leaving the artifact's ID in its place. Kept in the result if the upload fails."""
+        metadata = result.get("ai_metadata") if isinstance(result, dict) else None
+        if not isinstance(metadata, dict) or not metadata.get("llm_reasoning"):
+            return
+        
+        trace = json.dumps(metadata["llm_reasoning"]).encode("utf-8")
+        if len(trace) < self.settings.artifact_min_bytes:
+            return
+        artifact_id = await self.backend_service.upload_job_artifact(job_id, "REASONING_TRACE", trace)
+        if artifact_id:
+            del metadata["llm_reasoning"]
+            metadata["llm_reasoning_artifact_id"] = artifact_id
+            
     async def get_worker_stats(self) -> Dict[str, Any]:
         """Get current worker statistics"""
+        if self.rabbitmq_service:
+            queue_lengths = await self.rabbitmq_service.get_queue_lengths()
+            return {
+                "running": self.running,
+                "broker": "rabbitmq",
+                "active_jobs": len(self.active_jobs),
+                "max_concurrent_jobs": self.settings.max_concurrent_jobs,
+                "queue_lengths": queue_lengths,
+                "active_job_ids": list(self.active_jobs.keys())
+            }
+            
         queue_length = await self.redis_service.get_queue_length(
             self.settings.redis_job_queue
         )
+        stream_backlog = await self.redis_service.get_stream_backlog(
+            self.settings.redis_job_stream, self.settings.redis_consumer_group
+        )
+        batch_backlog = await self.redis_service.get_stream_backlog(
+            self.settings.redis_batch_job_stream, self.settings.redis_consumer_group
+        )
         
         return {
             "running": self.running,
             "active_jobs": len(self.active_jobs),
             "max_concurrent_jobs": self.settings.max_concurrent_jobs,
             "queue_length": queue_length,
+            "stream_length": stream_backlog["length"],
+            "stream_pending": stream_backlog["pending"],
+            "batch_stream_length": batch_backlog["length"],
+            "batch_stream_pending": batch_backlog["pending"],
+            "consumer": self.settings.redis_consumer_name,
             "active_job_ids": list(self.active_jobs.keys())
         }
\ No newline at end of file
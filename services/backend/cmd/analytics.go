@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/internal/config"
+	"github.com/commute-planner/backend/pkg/analytics"
+)
+
+// analyticsSinkTimeout bounds one batch sent to an analytics sink
+const analyticsSinkTimeout = 10 * time.Second
+
+// analyticsSink builds the sink analytics events are forwarded to from config, or nil when
+// they are only kept in the analytics_events table
+func analyticsSink(cfg config.AnalyticsConfig) (analytics.Sink, error) {
+	switch cfg.Sink {
+	case "", "table":
+		return nil, nil
+	case "segment":
+		if cfg.SegmentWriteKey == "" {
+			return nil, fmt.Errorf("SEGMENT_WRITE_KEY is required for the segment sink")
+		}
+		return analytics.NewSegmentSink(cfg.SegmentWriteKey, cfg.SegmentEndpoint, analyticsSinkTimeout), nil
+	case "posthog":
+		if cfg.PostHogAPIKey == "" {
+			return nil, fmt.Errorf("POSTHOG_API_KEY is required for the posthog sink")
+		}
+		return analytics.NewPostHogSink(cfg.PostHogAPIKey, cfg.PostHogHost, analyticsSinkTimeout), nil
+	default:
+		return nil, fmt.Errorf("unknown ANALYTICS_SINK %q: expected table, segment or posthog", cfg.Sink)
+	}
+}
@@ -0,0 +1,451 @@
+// Command cpctl runs administration tasks against a commute planner deployment. It works
+// on the database (and job broker) directly and reads the same environment variables as
+// the server.
+//
+//	cpctl [-tenant ID] <command> [flags]
+//
+//	users create -email EMAIL -name NAME [-password PASSWORD]
+//	jobs requeue JOB_ID
+//	jobs dump JOB_ID
+//	queue depth
+//	migrate [-dir DIR] [-baseline]
+//	demo generate -user USER_ID [-days N] [-density D] [-seed N] [-weekends] [-recommendations]
+//	seed [-dir DIR] [-reset]
+//	encryption rotate
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/internal/config"
+	"github.com/commute-planner/backend/pkg/auth"
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/encryption"
+	"github.com/commute-planner/backend/pkg/handlers"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/commute-planner/backend/pkg/queue/rabbitmq"
+	"github.com/commute-planner/backend/pkg/redis"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/seed"
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+const usage = `usage: cpctl [-tenant ID] <command> [flags]
+
+commands:
+  users create -email EMAIL -name NAME [-password PASSWORD]
+                        create a local user; the password is read from stdin when omitted
+  jobs requeue JOB_ID   put a PENDING or stuck IN_PROGRESS job back on the queue
+  jobs dump JOB_ID      print a job with its status history and recommendations as JSON
+  queue depth           print the backlog of the job queues as JSON
+  migrate [-dir DIR] [-baseline]
+                        apply pending Postgres migrations from DIR
+  demo generate -user USER_ID [-days N] [-density D] [-seed N] [-weekends] [-recommendations]
+                        replace a user's demo data
+  seed [-dir DIR] [-reset]
+                        create the users, events, jobs and recommendations in DIR's YAML fixtures
+  encryption rotate     encrypt the encrypted columns' values with the current key
+`
+
+// cli holds what the commands share
+type cli struct {
+	cfg *config.Config
+	db  *database.DB
+	// secretsLoaded is set once cfg holds the credentials
+	secretsLoaded bool
+}
+
+func main() {
+	log.SetFlags(0)
+	flags := flag.NewFlagSet("cpctl", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	tenantID := flags.String("tenant", "", "scope the command to a tenant (multi-tenant deployments)")
+	flags.Parse(os.Args[1:])
+	args := flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	if *tenantID != "" {
+		if !tenant.ValidID(*tenantID) {
+			log.Fatalf("invalid tenant %q", *tenantID)
+		}
+		ctx = tenant.WithID(ctx, *tenantID)
+	}
+
+	c := &cli{cfg: config.Load()}
+	var err error
+	switch command := strings.Join(args[:min(2, len(args))], " "); {
+	case command == "users create":
+		err = c.createUser(ctx, args[2:])
+	case command == "jobs requeue":
+		err = c.requeueJob(ctx, args[2:])
+	case command == "jobs dump":
+		err = c.dumpJob(ctx, args[2:])
+	case command == "queue depth":
+		err = c.queueDepth(ctx)
+	case args[0] == "migrate":
+		err = c.migrate(ctx, args[1:])
+	case command == "demo generate":
+		err = c.generateDemo(ctx, args[2:])
+	case args[0] == "seed":
+		err = c.seed(ctx, args[1:])
+	case command == "encryption rotate":
+		err = c.rotateEncryption(ctx)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+	if c.db != nil {
+		c.db.Close()
+	}
+	if err != nil {
+		log.Fatalf("cpctl: %v", err)
+	}
+}
+
+// connect opens the database the server uses, encrypting the columns it encrypts
+func (c *cli) connect(ctx context.Context) (*database.DB, error) {
+	if c.db != nil {
+		return c.db, nil
+	}
+	if err := c.loadSecrets(ctx); err != nil {
+		return nil, err
+	}
+	keys, err := encryption.ParseKeys(c.cfg.Encryption.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data_encryption_keys: %w", err)
+	}
+	db, err := database.NewConnection(database.Config{
+		Driver:       c.cfg.DatabaseDriver,
+		URL:          c.cfg.DatabaseURL,
+		MaxOpenConns: 2,
+		QueryTimeout: c.cfg.DBPool.QueryTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	columns := []string{database.ColumnOAuthTokens}
+	if c.cfg.Encryption.EventDescriptions {
+		columns = append(columns, database.ColumnEventDescription)
+	}
+	db.SetEncryption(keys, columns...)
+	c.db = db
+	return db, nil
+}
+
+// loadSecrets reads the credentials, such as the Redis password, from the server's
+// secrets provider
+func (c *cli) loadSecrets(ctx context.Context) error {
+	if c.secretsLoaded {
+		return nil
+	}
+	provider, err := c.cfg.Secrets.NewProvider()
+	if err != nil {
+		return fmt.Errorf("invalid secrets config: %w", err)
+	}
+	if err := c.cfg.LoadSecrets(ctx, provider); err != nil {
+		return err
+	}
+	c.secretsLoaded = true
+	return nil
+}
+
+func (c *cli) repos(ctx context.Context) (repository.Repositories, error) {
+	db, err := c.connect(ctx)
+	if err != nil {
+		return repository.Repositories{}, err
+	}
+	return repository.NewSQLRepositories(db), nil
+}
+
+func (c *cli) createUser(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("users create", flag.ExitOnError)
+	email := flags.String("email", "", "email address (required)")
+	name := flags.String("name", "", "display name (required)")
+	password := flags.String("password", "", "password; read from stdin when omitted")
+	flags.Parse(args)
+	if *email == "" || *name == "" {
+		return errors.New("-email and -name are required")
+	}
+	if *password == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+	if *password == "" {
+		return errors.New("password must not be empty")
+	}
+
+	db, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := auth.CreateLocalUser(ctx, db, *email, *password, *name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created user %s (%s) in tenant %s\n", user.ID, user.Email, user.TenantID)
+	return nil
+}
+
+// requeueJob hands a job to the outbox, which the running server publishes from within
+// QUEUE_OUTBOX_FLUSH_INTERVAL, so it works the same with either broker. Finished jobs
+// can't go back: create a new job to plan the day again.
+func (c *cli) requeueJob(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: cpctl jobs requeue JOB_ID")
+	}
+	repos, err := c.repos(ctx)
+	if err != nil {
+		return err
+	}
+	job, err := repos.Jobs.Get(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load job %s: %w", args[0], err)
+	}
+
+	switch job.Status {
+	case models.JobStatusPending:
+	case models.JobStatusInProgress:
+		status := string(models.JobStatusPending)
+		step := "Requeued by an administrator"
+		progress := 0.0
+		reset, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &status, Progress: &progress, CurrentStep: &step})
+		if err != nil {
+			return fmt.Errorf("failed to reset job %s: %w", job.ID, err)
+		}
+		job = reset
+	default:
+		return fmt.Errorf("job %s is %s; only PENDING and IN_PROGRESS jobs can be requeued", job.ID, job.Status)
+	}
+
+	targetDate := job.TargetDate
+	if len(targetDate) > 10 {
+		targetDate = targetDate[:10]
+	}
+	reason := "requeued with cpctl"
+	err = repos.JobOutbox.Add(ctx, &models.JobOutboxEntry{
+		JobID:         job.ID,
+		UserID:        job.UserID,
+		TargetDate:    targetDate,
+		InputData:     job.InputData,
+		Priority:      job.Priority,
+		LastError:     &reason,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue job %s: %w", job.ID, err)
+	}
+	fmt.Printf("Job %s is queued; the server publishes it within %s\n", job.ID, c.cfg.Queue.OutboxFlushInterval)
+	return nil
+}
+
+// jobDump is everything stored about a job
+type jobDump struct {
+	Job             *models.Job                     `json:"job"`
+	Events          []*models.JobEvent              `json:"events"`
+	Recommendations []*models.CommuteRecommendation `json:"recommendations"`
+}
+
+func (c *cli) dumpJob(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: cpctl jobs dump JOB_ID")
+	}
+	repos, err := c.repos(ctx)
+	if err != nil {
+		return err
+	}
+	dump := jobDump{}
+	if dump.Job, err = repos.Jobs.Get(ctx, args[0]); err != nil {
+		return fmt.Errorf("failed to load job %s: %w", args[0], err)
+	}
+	if dump.Events, err = repos.Jobs.Events(ctx, dump.Job.ID); err != nil {
+		return fmt.Errorf("failed to load job events: %w", err)
+	}
+	if dump.Recommendations, err = repos.Recommendations.ListByJob(ctx, dump.Job.ID); err != nil {
+		return fmt.Errorf("failed to load recommendations: %w", err)
+	}
+	return printJSON(dump)
+}
+
+// queueDepth reports the broker's queues, the scheduled jobs and the outbox
+func (c *cli) queueDepth(ctx context.Context) error {
+	if err := c.loadSecrets(ctx); err != nil {
+		return err
+	}
+	redisClient := redis.NewClient(redis.Config{
+		Addr:        c.cfg.Redis.Addr,
+		Password:    c.cfg.Redis.Password,
+		DB:          c.cfg.Redis.DB,
+		TLS:         c.cfg.Redis.TLS,
+		DialTimeout: c.cfg.Redis.DialTimeout,
+		QueueMode:   c.cfg.Redis.QueueMode,
+	})
+	defer redisClient.Close()
+
+	var depths []queue.Depth
+	switch c.cfg.Queue.Broker {
+	case queue.BrokerRedis:
+		redisDepths, err := redisClient.QueueDepth(ctx)
+		if err != nil {
+			return err
+		}
+		depths = redisDepths
+	case queue.BrokerRabbitMQ:
+		publisher := rabbitmq.NewPublisher(rabbitmq.Config{URL: c.cfg.Queue.RabbitMQURL, Queue: c.cfg.Queue.RabbitMQQueue})
+		defer publisher.Close()
+		rabbitDepths, err := publisher.QueueDepth(ctx)
+		if err != nil {
+			return err
+		}
+		// Scheduled jobs wait in Redis whichever broker runs the pipeline
+		redisDepths, err := redisClient.QueueDepth(ctx)
+		if err != nil {
+			return err
+		}
+		depths = append(rabbitDepths, redisDepths[len(redisDepths)-1])
+	default:
+		return fmt.Errorf("unknown QUEUE_BROKER %q", c.cfg.Queue.Broker)
+	}
+
+	repos, err := c.repos(ctx)
+	if err != nil {
+		return err
+	}
+	buffered, err := repos.JobOutbox.Count(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count the outbox: %w", err)
+	}
+	depths = append(depths, queue.Depth{Queue: "outbox", Messages: int64(buffered)})
+	return printJSON(depths)
+}
+
+// rotateEncryption re-encrypts values written before encryption was enabled or with an
+// earlier key, after which earlier keys can be removed from data_encryption_keys
+func (c *cli) rotateEncryption(ctx context.Context) error {
+	db, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	rewritten, err := repository.Reencrypt(ctx, db)
+	if err == nil || rewritten > 0 {
+		fmt.Printf("Re-encrypted %d values with key %s\n", rewritten, db.Encryption().Current())
+	}
+	return err
+}
+
+func (c *cli) migrate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dir := flags.String("dir", "database/migrations", "directory of the Postgres migrations")
+	baseline := flags.Bool("baseline", false, "record every migration as applied without running it")
+	flags.Parse(args)
+
+	db, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	if db.Driver() != database.DriverPostgres {
+		fmt.Println("SQLite databases are migrated when they are opened")
+		return nil
+	}
+	applied, err := database.MigratePostgres(ctx, db.DB, *dir, *baseline)
+	for _, version := range applied {
+		if *baseline {
+			fmt.Printf("Recorded %s\n", version)
+		} else {
+			fmt.Printf("Applied %s\n", version)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println("Database is up to date")
+	}
+	return nil
+}
+
+func (c *cli) generateDemo(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("demo generate", flag.ExitOnError)
+	userID := flags.String("user", "", "user ID (required)")
+	days := flags.Int("days", 0, "days to generate, starting today (default 14)")
+	density := flags.String("density", "", "light, normal (default) or heavy")
+	seed := flags.Int64("seed", 0, "seed for reproducible data; 0 picks one")
+	weekends := flags.Bool("weekends", false, "include weekend events")
+	recommendations := flags.Bool("recommendations", false, "also seed a completed demo job")
+	flags.Parse(args)
+	if *userID == "" {
+		return errors.New("-user is required")
+	}
+
+	repos, err := c.repos(ctx)
+	if err != nil {
+		return err
+	}
+	demo := handlers.NewDemoHandler(repos.Users, repos.Events, repos.Jobs, repos.Recommendations)
+	result, message, err := demo.Generate(ctx, *userID, handlers.DemoRequest{
+		Days:                   *days,
+		Density:                *density,
+		Seed:                   *seed,
+		IncludeWeekends:        *weekends,
+		IncludeRecommendations: *recommendations,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s (seed %d)\n", message, result.Seed)
+	return nil
+}
+
+func (c *cli) seed(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	dir := flags.String("dir", "fixtures", "directory of the YAML fixtures")
+	reset := flags.Bool("reset", false, "delete and recreate fixture users that already exist")
+	flags.Parse(args)
+
+	fixtures, err := seed.Load(*dir)
+	if err != nil {
+		return err
+	}
+	db, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	seeder := &seed.Seeder{
+		Repos: repository.NewSQLRepositories(db),
+		CreateUser: func(ctx context.Context, email, password, name string) (*models.User, error) {
+			return auth.CreateLocalUser(ctx, db, email, password, name)
+		},
+	}
+	result, err := seeder.Apply(ctx, fixtures, time.Now(), *reset)
+	if result != nil {
+		for _, email := range result.Skipped {
+			fmt.Printf("Skipped %s: already exists (use -reset to recreate)\n", email)
+		}
+		fmt.Printf("Created %d users, %d events, %d jobs and %d recommendations\n",
+			result.Users, result.Events, result.Jobs, result.Recommendations)
+	}
+	return err
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
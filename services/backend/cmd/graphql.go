@@ -0,0 +1,1250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	backend "github.com/commute-planner/backend"
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/clientinfo"
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/deprecation"
+	"github.com/commute-planner/backend/pkg/diagnostics"
+	"github.com/commute-planner/backend/pkg/handlers"
+	"github.com/commute-planner/backend/pkg/i18n"
+	"github.com/commute-planner/backend/pkg/introspection"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/redact"
+	"github.com/commute-planner/backend/pkg/resolvers"
+	"github.com/commute-planner/backend/pkg/tenant"
+	"github.com/commute-planner/backend/pkg/upload"
+)
+
+// errorRedactor scrubs GraphQL errors, which are returned with status 200 and so pass
+// middleware.RedactErrors by; nil when redaction is off
+var errorRedactor *redact.Redactor
+
+// versionHandler reports the build in every GraphQL response; nil leaves it out
+var versionHandler *handlers.VersionHandler
+
+// deprecationTracker counts the use of deprecated fields; nil counts none
+var deprecationTracker *deprecation.Tracker
+
+// clientUsageTracker counts the operations each client version runs; nil counts none
+var clientUsageTracker *clientinfo.UsageTracker
+
+// maxGraphQLBatch caps the operations of one batched request
+const maxGraphQLBatch = 20
+
+// Results holding a list of at least graphQLStreamMin items are streamed, flushing every
+// graphQLStreamChunk items
+const (
+	graphQLStreamMin   = 200
+	graphQLStreamChunk = 100
+)
+
+// authorization enforces the schema's @auth and @owner directives
+var authorization = authz.MustLoad(backend.Schema)
+
+// introspectionSchema answers introspection queries; nil when introspection is disabled
+var introspectionSchema *introspection.Schema
+
+// graphqlOperations tracks the operations being executed, for /debug/statusz
+var graphqlOperations = diagnostics.NewOperations()
+
+// operationName names an operation for diagnostics: its operationName, or else its first
+// root field. Variables and arguments are left out, as they may hold personal data.
+func operationName(req GraphQLRequest) string {
+	if req.OperationName != "" {
+		return req.OperationName
+	}
+	start := strings.Index(req.Query, "{")
+	if start < 0 {
+		return "anonymous"
+	}
+	field := strings.TrimLeft(req.Query[start+1:], " \t\r\n")
+	end := strings.IndexFunc(field, func(r rune) bool {
+		return !(r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if end >= 0 {
+		field = field[:end]
+	}
+	if field == "" {
+		return "anonymous"
+	}
+	return field
+}
+
+// executeGraphQL runs one operation. A job created by createJob is returned rather than
+// queued, so batches can queue their jobs once they're committed.
+func executeGraphQL(ctx context.Context, resolver *resolvers.Resolver, req GraphQLRequest) (response GraphQLResponse, created *models.Job) {
+	defer graphqlOperations.Begin(operationName(req))()
+	if clientUsageTracker != nil {
+		clientUsageTracker.Track(clientinfo.FromContext(ctx), operationName(req))
+	}
+
+	viewer := graphQLViewer(ctx)
+	// Operations run by the root fields they select, all of which the schema has
+	op, err := authorization.Authorize(req.Query, viewer)
+	if err != nil {
+		response.Errors = []string{err.Error()}
+		return response, nil
+	}
+	if op.Root == "Subscription" {
+		response.Errors = []string{"subscriptions are served over server-sent events at /graphql/stream"}
+		return response, nil
+	}
+	if deprecationTracker != nil {
+		deprecationTracker.Track(clientinfo.FromContext(ctx), req.Query)
+	}
+	defer func() {
+		if data, ok := response.Data.(map[string]interface{}); ok {
+			response.Data = authorization.Redact(op.Root, data, viewer)
+		}
+	}()
+
+	// Handle basic queries and mutations
+	switch {
+	case op.Has("__schema") || op.Has("__type"):
+		if introspectionSchema == nil {
+			response.Errors = []string{"GraphQL introspection is disabled"}
+			break
+		}
+		data, err := introspectionSchema.Execute(req.Query, req.OperationName, req.Variables)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = data
+		}
+	case op.Has("__typename"):
+		// The gateway's health check of its subgraphs, __ApolloServiceHealthCheck__
+		response.Data = map[string]interface{}{"__typename": op.Root}
+	case op.Has("_service"):
+		// The gateway composes the supergraph from the subgraph's SDL
+		response.Data = map[string]interface{}{"_service": map[string]string{"sdl": backend.Subgraph}}
+	case op.Has("_entities"):
+		representations, _ := req.Variables["representations"].([]interface{})
+		entities, err := resolver.Entities(ctx, representations)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"_entities": entities}
+		}
+	case op.Has("clientUsage"):
+		since, _ := req.Variables["since"].(string)
+		usage, err := resolver.ClientUsage(ctx, viewer, since)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"clientUsage": usage}
+		}
+	case op.Has("deprecatedFieldUsage"):
+		since, _ := req.Variables["since"].(string)
+		fields, err := resolver.DeprecatedFieldUsage(ctx, viewer, since)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"deprecatedFieldUsage": fields}
+		}
+	case op.Has("health"):
+		health, _ := resolver.Health(ctx)
+		response.Data = map[string]interface{}{"health": health}
+	case op.Has("users"):
+		users, err := resolver.Users(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"users": users}
+		}
+	case op.Has("importCalendar"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		file, ok := req.Variables["file"].(*upload.Upload)
+		if !ok {
+			response.Errors = []string{"file must be uploaded as a multipart request"}
+			break
+		}
+		content, err := file.Open()
+		if err != nil {
+			response.Errors = []string{"error reading upload: " + err.Error()}
+			break
+		}
+		imp, err := resolver.ImportCalendar(ctx, user.ID, file.Filename, content)
+		content.Close()
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"importCalendar": imp}
+		}
+	case op.Has("calendarImport"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		id, _ := req.Variables["id"].(string)
+		imp, err := resolver.CalendarImport(ctx, user.ID, id)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"calendarImport": imp}
+		}
+	case op.Has("bulkCreateCalendarEvents"):
+		// Like POST /api/v1/calendar-events:batch, it imports into the signed-in user's
+		// calendar only
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		var input []resolvers.CalendarEventInput
+		raw, _ := json.Marshal(req.Variables["input"])
+		if err := json.Unmarshal(raw, &input); err != nil {
+			response.Errors = []string{"invalid input: " + err.Error()}
+			break
+		}
+		result, err := resolver.BulkCreateCalendarEvents(ctx, user.ID, input)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"bulkCreateCalendarEvents": result}
+		}
+	case op.Has("createWebhookEndpoint"):
+		// Webhook operations are @auth and act on the signed-in user's endpoints only
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		var input resolvers.CreateWebhookEndpointInput
+		raw, _ := json.Marshal(req.Variables["input"])
+		if err := json.Unmarshal(raw, &input); err != nil {
+			response.Errors = []string{"invalid input: " + err.Error()}
+			break
+		}
+		endpoint, err := resolver.CreateWebhookEndpoint(ctx, user.ID, input)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"createWebhookEndpoint": endpoint}
+		}
+	case op.Has("deleteWebhookEndpoint"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		id, _ := req.Variables["id"].(string)
+		deleted, err := resolver.DeleteWebhookEndpoint(ctx, user.ID, id)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"deleteWebhookEndpoint": deleted}
+		}
+	case op.Has("replanNow"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		jobID, _ := req.Variables["jobId"].(string)
+		job, err := resolver.ReplanNow(ctx, user.ID, jobID)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"replanNow": job}
+			// Queued by the caller, once the job is committed
+			created = job
+		}
+	case op.Has("replayJob"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		id, _ := req.Variables["id"].(string)
+		job, err := resolver.ReplayJob(ctx, user.ID, id)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"replayJob": job}
+			// Queued by the caller, once the job is committed
+			created = job
+		}
+	case op.Has("acceptCommuteRecommendation"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		id, _ := req.Variables["id"].(string)
+		rec, err := resolver.AcceptCommuteRecommendation(ctx, user.ID, id)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"acceptCommuteRecommendation": rec}
+		}
+	case op.Has("setTravelProfile"):
+		// Like PUT /me/travel-profile, it acts on the signed-in user's profile
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		var input resolvers.TravelProfileInput
+		raw, _ := json.Marshal(req.Variables["input"])
+		if err := json.Unmarshal(raw, &input); err != nil {
+			response.Errors = []string{"invalid input: " + err.Error()}
+			break
+		}
+		profile, err := resolver.SetTravelProfile(ctx, user.ID, input)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"setTravelProfile": profile}
+		}
+	case op.Has("setPreferenceFeedback"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		key, _ := req.Variables["key"].(string)
+		var input resolvers.PreferenceFeedbackInput
+		raw, _ := json.Marshal(req.Variables["input"])
+		if err := json.Unmarshal(raw, &input); err != nil {
+			response.Errors = []string{"invalid input: " + err.Error()}
+			break
+		}
+		learned, err := resolver.SetPreferenceFeedback(ctx, user.ID, key, input)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"setPreferenceFeedback": learned}
+		}
+	case op.Has("resetPreferenceFeedback"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		key, _ := req.Variables["key"].(string)
+		reset, err := resolver.ResetPreferenceFeedback(ctx, user.ID, key)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"resetPreferenceFeedback": reset}
+		}
+	case op.Has("setFocusTime"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		var minutes *int
+		if m, ok := req.Variables["minimumMinutes"].(float64); ok {
+			n := int(m)
+			minutes = &n
+		}
+		updated, err := resolver.SetFocusTime(ctx, user.ID, minutes)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"setFocusTime": updated}
+		}
+	case op.Has("setNotificationSettings"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		var input resolvers.NotificationSettingsInput
+		raw, _ := json.Marshal(req.Variables["input"])
+		if err := json.Unmarshal(raw, &input); err != nil {
+			response.Errors = []string{"invalid input: " + err.Error()}
+			break
+		}
+		settings, err := resolver.SetNotificationSettings(ctx, user.ID, input)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"setNotificationSettings": settings}
+		}
+	case op.Has("setAnalyticsOptOut"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		optOut, _ := req.Variables["optOut"].(bool)
+		updated, err := resolver.SetAnalyticsOptOut(ctx, user.ID, optOut)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"setAnalyticsOptOut": updated}
+		}
+	case op.Has("setLocale"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		var locale *string
+		if l, ok := req.Variables["locale"].(string); ok {
+			locale = &l
+		}
+		updated, err := resolver.SetLocale(ctx, user.ID, locale)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"setLocale": updated}
+		}
+	case op.Has("setDisplayUnits"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		var distanceUnit *models.DistanceUnit
+		if u, ok := req.Variables["distanceUnit"].(string); ok {
+			unit := models.DistanceUnit(u)
+			distanceUnit = &unit
+		}
+		var currency *string
+		if c, ok := req.Variables["currency"].(string); ok {
+			currency = &c
+		}
+		var timeFormat *models.TimeFormat
+		if f, ok := req.Variables["timeFormat"].(string); ok {
+			format := models.TimeFormat(f)
+			timeFormat = &format
+		}
+		updated, err := resolver.SetDisplayUnits(ctx, user.ID, distanceUnit, currency, timeFormat)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"setDisplayUnits": updated}
+		}
+	case op.Has("setShareRedaction"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		var policy *models.ShareRedaction
+		if p, ok := req.Variables["policy"].(string); ok {
+			redaction := models.ShareRedaction(p)
+			policy = &redaction
+		}
+		updated, err := resolver.SetShareRedaction(ctx, user.ID, policy)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"setShareRedaction": updated}
+		}
+	case op.Has("supportedLocales"):
+		response.Data = map[string]interface{}{"supportedLocales": i18n.Locales}
+	case op.Has("setOrgReportingOptOut"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		optOut, _ := req.Variables["optOut"].(bool)
+		updated, err := resolver.SetOrgReportingOptOut(ctx, user.ID, optOut)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"setOrgReportingOptOut": updated}
+		}
+	case op.Has("setOrgAdmin"):
+		userID, _ := req.Variables["userId"].(string)
+		orgAdmin, _ := req.Variables["orgAdmin"].(bool)
+		user, err := resolver.SetOrgAdmin(ctx, viewer, viewerTenant(ctx), userID, orgAdmin)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"setOrgAdmin": user}
+		}
+	case op.Has("setOfficeDayPolicy"):
+		var days *int
+		if d, ok := req.Variables["officeDaysPerWeek"].(float64); ok {
+			n := int(d)
+			days = &n
+		}
+		org, err := resolver.SetOfficeDayPolicy(ctx, viewer, viewerTenant(ctx), days)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"setOfficeDayPolicy": org}
+		}
+	case op.Has("orgReport"):
+		from, _ := req.Variables["from"].(string)
+		to, _ := req.Variables["to"].(string)
+		report, err := resolver.OrgReport(ctx, viewer, viewerTenant(ctx), from, to)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"orgReport": report}
+		}
+	case op.Has("webhookDeliveries"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		endpointID, _ := req.Variables["endpointId"].(string)
+		var limit *int
+		if l, ok := req.Variables["limit"].(float64); ok {
+			n := int(l)
+			limit = &n
+		}
+		deliveries, err := resolver.WebhookDeliveries(ctx, user.ID, endpointID, limit)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			if deliveries == nil {
+				deliveries = []*models.WebhookDelivery{}
+			}
+			response.Data = map[string]interface{}{"webhookDeliveries": deliveries}
+		}
+	case op.Has("webhookEndpoints"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		endpoints, err := resolver.WebhookEndpoints(ctx, user.ID)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			if endpoints == nil {
+				endpoints = []*models.WebhookEndpoint{}
+			}
+			response.Data = map[string]interface{}{"webhookEndpoints": endpoints}
+		}
+	case op.Has("compareJobs"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		jobIDA, _ := req.Variables["jobIdA"].(string)
+		jobIDB, _ := req.Variables["jobIdB"].(string)
+		if jobIDA == "" || jobIDB == "" {
+			response.Errors = []string{"jobIdA and jobIdB variables are required for compareJobs query"}
+			break
+		}
+		comparison, err := resolver.CompareJobs(ctx, user.ID, jobIDA, jobIDB)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"compareJobs": comparison}
+		}
+	case op.Has("commuteRecommendations"):
+		jobID, _ := req.Variables["jobId"].(string)
+		if jobID == "" {
+			response.Errors = []string{"jobId variable is required for commuteRecommendations query"}
+			break
+		}
+		recommendations, err := resolver.CommuteRecommendations(ctx, viewer, jobID)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			if recommendations == nil {
+				recommendations = []*models.CommuteRecommendation{}
+			}
+			response.Data = map[string]interface{}{"commuteRecommendations": recommendations}
+		}
+	case op.Has("createJobArtifactUpload"):
+		// Called by the AI worker with the admin token; it PUTs the artifact to uploadUrl
+		jobID, _ := req.Variables["jobId"].(string)
+		var input resolvers.JobArtifactUploadInput
+		raw, _ := json.Marshal(req.Variables["input"])
+		if err := json.Unmarshal(raw, &input); err != nil {
+			response.Errors = []string{"invalid input: " + err.Error()}
+			break
+		}
+		upload, err := resolver.CreateJobArtifactUpload(ctx, viewer, jobID, input)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"createJobArtifactUpload": upload}
+		}
+	case op.Has("createCalendarFeed"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		feed, err := resolver.CreateCalendarFeed(ctx, user.ID)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"createCalendarFeed": feed}
+		}
+	case op.Has("deleteCalendarFeed"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		deleted, err := resolver.DeleteCalendarFeed(ctx, user.ID)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"deleteCalendarFeed": deleted}
+		}
+	case op.Has("cancelCommuteReminder"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		id, _ := req.Variables["id"].(string)
+		cancelled, err := resolver.CancelCommuteReminder(ctx, user.ID, id)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"cancelCommuteReminder": cancelled}
+		}
+	case op.Has("createShareLink"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		jobID, _ := req.Variables["jobId"].(string)
+		var ttl *int
+		if value, ok := req.Variables["ttl"].(float64); ok {
+			n := int(value)
+			ttl = &n
+		}
+		link, err := resolver.CreateShareLink(ctx, user.ID, jobID, ttl)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"createShareLink": link}
+		}
+	case op.Has("revokeShareLink"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		id, _ := req.Variables["id"].(string)
+		revoked, err := resolver.RevokeShareLink(ctx, user.ID, id)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"revokeShareLink": revoked}
+		}
+	case op.Has("shareLinks"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		jobID, _ := req.Variables["jobId"].(string)
+		links, err := resolver.ShareLinks(ctx, user.ID, jobID)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			if links == nil {
+				links = []*models.ShareLink{}
+			}
+			response.Data = map[string]interface{}{"shareLinks": links}
+		}
+	case op.Has("jobArtifacts"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		jobID, _ := req.Variables["jobId"].(string)
+		var expiresIn *int
+		if e, ok := req.Variables["expiresIn"].(float64); ok {
+			n := int(e)
+			expiresIn = &n
+		}
+		artifacts, err := resolver.JobArtifacts(ctx, user.ID, jobID, expiresIn)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			if artifacts == nil {
+				artifacts = []*models.JobArtifact{}
+			}
+			response.Data = map[string]interface{}{"jobArtifacts": artifacts}
+		}
+	case op.Has("jobsByIds"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		var ids []string
+		raw, _ := json.Marshal(req.Variables["ids"])
+		if err := json.Unmarshal(raw, &ids); err != nil || ids == nil {
+			response.Errors = []string{"ids variable is required for jobsByIds query"}
+			break
+		}
+		statuses, err := resolver.JobsByIDs(ctx, user.ID, ids)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"jobsByIds": statuses}
+		}
+	case op.Has("jobStatuses"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		userID, _ := req.Variables["userId"].(string)
+		if userID == "" {
+			response.Errors = []string{"userId variable is required for jobStatuses query"}
+			break
+		}
+		if userID != user.ID {
+			response.Errors = []string{"jobStatuses only reports the signed-in user's jobs"}
+			break
+		}
+		var since *time.Time
+		if value, ok := req.Variables["since"].(string); ok && value != "" {
+			at, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				response.Errors = []string{fmt.Sprintf("invalid since %q: expected an RFC 3339 time", value)}
+				break
+			}
+			since = &at
+		}
+		statuses, err := resolver.JobStatuses(ctx, user.ID, since)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"jobStatuses": statuses}
+		}
+	case op.Has("job"):
+		id, _ := req.Variables["id"].(string)
+		if id == "" {
+			response.Errors = []string{"id variable is required for job query"}
+			break
+		}
+		job, err := resolver.Job(ctx, viewer, id)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		// Nested recommendations are only loaded when selected
+		if op.Selects("job", "recommendations") {
+			job.Recommendations, err = resolver.CommuteRecommendations(ctx, viewer, id)
+			if err != nil {
+				response.Errors = []string{err.Error()}
+				break
+			}
+			if job.Recommendations == nil {
+				job.Recommendations = []*models.CommuteRecommendation{}
+			}
+		}
+		if op.Selects("job", "timeline") {
+			if job.Timeline, err = resolver.JobTimeline(ctx, viewer, id); err != nil {
+				response.Errors = []string{err.Error()}
+				break
+			}
+		}
+		response.Data = map[string]interface{}{"job": job}
+	case op.Has("jobEvents"):
+		jobID, _ := req.Variables["jobId"].(string)
+		events, err := resolver.JobEvents(ctx, viewer, jobID)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			if events == nil {
+				events = []*models.JobEvent{}
+			}
+			response.Data = map[string]interface{}{"jobEvents": events}
+		}
+	case op.Has("commuteCosts"):
+		// Like GET /me/commute-costs, it reports the signed-in user's trips
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		var months *int
+		if m, ok := req.Variables["months"].(float64); ok {
+			n := int(m)
+			months = &n
+		}
+		monthly, err := resolver.CommuteCosts(ctx, user.ID, months)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"commuteCosts": monthly}
+		}
+	case op.Has("carbonStats"):
+		// Like GET /me/carbon-stats, it reports the signed-in user's trips
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		period, _ := req.Variables["period"].(string)
+		stats, err := resolver.CarbonStats(ctx, user.ID, period)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"carbonStats": stats}
+		}
+	case op.Has("meetingLoad"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		date, _ := req.Variables["date"].(string)
+		load, err := resolver.MeetingLoad(ctx, user.ID, date)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"meetingLoad": load}
+		}
+	case op.Has("notificationSettings"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		settings, err := resolver.NotificationSettings(ctx, user.ID)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"notificationSettings": settings}
+		}
+	case op.Has("commuteReminders"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		reminders, err := resolver.CommuteReminders(ctx, user.ID)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"commuteReminders": reminders}
+		}
+	case op.Has("weeklyDigest"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		weekStart, _ := req.Variables["weekStart"].(string)
+		digest, err := resolver.WeeklyDigest(ctx, user.ID, weekStart)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"weeklyDigest": digest}
+		}
+	case op.Has("impactOfChange"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		eventID, _ := req.Variables["eventId"].(string)
+		var change models.ProposedMeetingChange
+		raw, _ := json.Marshal(req.Variables["proposedChange"])
+		if err := json.Unmarshal(raw, &change); err != nil {
+			response.Errors = []string{"invalid proposedChange: " + err.Error()}
+			break
+		}
+		impact, err := resolver.ImpactOfChange(ctx, user.ID, eventID, change)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"impactOfChange": impact}
+		}
+	case op.Has("learnedPreferences"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		learned, err := resolver.LearnedPreferences(ctx, user.ID)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"learnedPreferences": learned}
+		}
+	case op.Has("travelProfile"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		profile, err := resolver.TravelProfile(ctx, user.ID)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"travelProfile": profile}
+		}
+	case op.Has("offices"):
+		offices, err := resolver.Offices(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			if offices == nil {
+				offices = []*models.Office{}
+			}
+			response.Data = map[string]interface{}{"offices": offices}
+		}
+	case op.Has("searchCalendarEvents"):
+		user, err := signedInUser(ctx)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+			break
+		}
+		// The filters are top-level variables named like the input's fields
+		var input resolvers.CalendarEventSearchInput
+		raw, _ := json.Marshal(req.Variables)
+		if err := json.Unmarshal(raw, &input); err != nil {
+			response.Errors = []string{"invalid input: " + err.Error()}
+			break
+		}
+		events, err := resolver.SearchCalendarEvents(ctx, user.ID, input)
+		if err != nil {
+			response.Errors = []string{err.Error()}
+		} else {
+			response.Data = map[string]interface{}{"searchCalendarEvents": events}
+		}
+	case op.Has("calendarEvents"):
+		// Handle calendarEvents query
+		if req.Variables != nil {
+			if userID, ok := req.Variables["userId"].(string); ok {
+				// Check for optional targetDate parameter
+				var targetDate *string
+				if td, ok := req.Variables["targetDate"].(string); ok {
+					targetDate = &td
+				}
+
+				events, err := resolver.CalendarEvents(ctx, viewer, userID, targetDate)
+				if err != nil {
+					response.Errors = []string{err.Error()}
+				} else {
+					// Ensure we always return an array, never null
+					if events == nil {
+						events = []*models.CalendarEvent{}
+					}
+					response.Data = map[string]interface{}{"calendarEvents": events}
+				}
+			} else {
+				response.Errors = []string{"userId variable is required for calendarEvents query"}
+			}
+		} else {
+			response.Errors = []string{"variables are required for calendarEvents query"}
+		}
+	default:
+		// Handle job mutations
+		if req.Variables != nil {
+			if input, ok := req.Variables["input"].(map[string]interface{}); ok {
+				if op.Has("createJob") {
+					// Handle createJob mutation. Jobs are the signed-in user's, also when the
+					// request carries one of their API tokens.
+					user, err := signedInUser(ctx)
+					if err != nil {
+						response.Errors = []string{err.Error()}
+						return
+					}
+					userID, _ := input["userId"].(string)
+					if userID == "" {
+						userID = user.ID
+					}
+					if userID != user.ID {
+						response.Errors = []string{"createJob only creates jobs for the signed-in user"}
+						return
+					}
+					createInput := resolvers.CreateJobInput{
+						UserID:     userID,
+						TargetDate: input["targetDate"].(string),
+					}
+					if inputData, hasInputData := input["inputData"]; hasInputData && inputData != nil {
+						inputDataStr := inputData.(string)
+						createInput.InputData = &inputDataStr
+					}
+					if priority, ok := input["priority"].(string); ok {
+						createInput.Priority = &priority
+					}
+					if scheduleAt, ok := input["scheduleAt"].(string); ok {
+						createInput.ScheduleAt = &scheduleAt
+					}
+					if constraints, ok := input["constraints"]; ok && constraints != nil {
+						raw, _ := json.Marshal(constraints)
+						if err := json.Unmarshal(raw, &createInput.Constraints); err != nil {
+							response.Errors = []string{"invalid constraints: " + err.Error()}
+							return
+						}
+					}
+
+					job, err := resolver.CreateJob(ctx, createInput)
+					if err != nil {
+						response.Errors = []string{err.Error()}
+					} else {
+						response.Data = map[string]interface{}{"createJob": job}
+					}
+
+					// Queued by the caller, once the job is committed
+					created = job
+
+					// Return early to prevent "not supported" error
+					return
+				}
+			}
+
+			// Handle updateJob mutation
+			if id, ok := req.Variables["id"].(string); ok && op.Has("updateJob") {
+				if input, ok := req.Variables["input"].(map[string]interface{}); ok {
+					updateInput := resolvers.UpdateJobInput{}
+
+					if status, exists := input["status"]; exists && status != nil {
+						statusStr := status.(string)
+						updateInput.Status = &statusStr
+					}
+					if progress, exists := input["progress"]; exists && progress != nil {
+						progressFloat := progress.(float64)
+						updateInput.Progress = &progressFloat
+					}
+					if currentStep, exists := input["currentStep"]; exists && currentStep != nil {
+						currentStepStr := currentStep.(string)
+						updateInput.CurrentStep = &currentStepStr
+					}
+					if result, exists := input["result"]; exists && result != nil {
+						resultStr := result.(string)
+						updateInput.Result = &resultStr
+					}
+					if errorMessage, exists := input["errorMessage"]; exists && errorMessage != nil {
+						errorMessageStr := errorMessage.(string)
+						updateInput.ErrorMessage = &errorMessageStr
+					}
+					if expectedVersion, ok := input["expectedVersion"].(float64); ok {
+						version := int(expectedVersion)
+						updateInput.ExpectedVersion = &version
+					}
+
+					job, err := resolver.UpdateJob(ctx, id, updateInput)
+					if err != nil {
+						response.Errors = []string{err.Error()}
+					} else {
+						response.Data = map[string]interface{}{"updateJob": job}
+					}
+
+					// Return early to prevent "not supported" error
+					return
+				}
+			}
+		}
+		response.Errors = []string{"Query not supported in this basic implementation. Try: { health } or { users { id email name } } or createJob/updateJob mutations"}
+	}
+
+	return
+}
+
+// signedInUser returns the user an @auth operation acts on. Authorize keeps anonymous
+// viewers from such operations; this fails closed should one get by it.
+func signedInUser(ctx context.Context) (*models.User, error) {
+	if user := handlers.GetUserFromContext(ctx); user != nil {
+		return user, nil
+	}
+	return nil, authz.ErrUnauthenticated
+}
+
+// graphQLViewer is who runs an operation, for authorization
+func graphQLViewer(ctx context.Context) authz.Viewer {
+	viewer := authz.Viewer{Admin: authz.IsAdmin(ctx)}
+	if user := handlers.GetUserFromContext(ctx); user != nil {
+		viewer.UserID, viewer.OrgAdmin = user.ID, user.IsOrgAdmin
+	}
+	viewer.Scopes, viewer.Token = authz.TokenScopes(ctx)
+	return viewer
+}
+
+// graphQLRequestFrom reads an operation decoded from a multipart request's operations
+func graphQLRequestFrom(operation map[string]interface{}) GraphQLRequest {
+	req := GraphQLRequest{}
+	req.Query, _ = operation["query"].(string)
+	req.OperationName, _ = operation["operationName"].(string)
+	req.Variables, _ = operation["variables"].(map[string]interface{})
+	return req
+}
+
+// viewerTenant is the tenant org admin operations act on: the signed-in user's, else the
+// one the request is scoped to
+func viewerTenant(ctx context.Context) string {
+	if user := handlers.GetUserFromContext(ctx); user != nil {
+		return user.TenantID
+	}
+	return tenant.OrDefault(ctx)
+}
+
+// batchError reports which operation of a batch failed
+type batchError struct {
+	index int
+}
+
+func (e *batchError) Error() string {
+	return fmt.Sprintf("operation %d failed", e.index)
+}
+
+// executeGraphQLBatch runs a batch of operations in one transaction and returns a response
+// for each, in order. When an operation fails the whole batch is rolled back: it keeps its
+// errors and every other operation reports that it wasn't applied. Jobs are queued, and
+// analytics events and plan feed updates sent (see database.AfterCommit), after the commit.
+func executeGraphQLBatch(ctx context.Context, db *database.DB, resolver *resolvers.Resolver, requests []GraphQLRequest) []GraphQLResponse {
+	responses := make([]GraphQLResponse, len(requests))
+	if len(requests) > maxGraphQLBatch {
+		for i := range responses {
+			responses[i].Errors = []string{fmt.Sprintf("too many operations in batch: %d (max %d)", len(requests), maxGraphQLBatch)}
+		}
+		return responses
+	}
+
+	var created []*models.Job
+	err := db.InTx(ctx, func(ctx context.Context) error {
+		for i, req := range requests {
+			response, job := executeGraphQL(ctx, resolver, req)
+			responses[i] = response
+			if len(response.Errors) > 0 {
+				return &batchError{index: i}
+			}
+			if job != nil {
+				created = append(created, job)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		failed := -1
+		var opErr *batchError
+		if errors.As(err, &opErr) {
+			failed = opErr.index
+		}
+		for i := range responses {
+			switch {
+			case i == failed:
+			case failed >= 0:
+				responses[i] = GraphQLResponse{Errors: []string{fmt.Sprintf("not applied: operation %d failed and the batch was rolled back", failed)}}
+			default:
+				responses[i] = GraphQLResponse{Errors: []string{"batch failed: " + err.Error()}}
+			}
+		}
+		return responses
+	}
+
+	for _, job := range created {
+		queueJob(ctx, resolver, job)
+	}
+	return responses
+}
+
+// writeGraphQLResponse encodes a response. A result that is one long list (e.g. a month
+// of calendar events) is written item by item and flushed in chunks, so the client starts
+// receiving it, compressed, before all of it is encoded.
+func writeGraphQLResponse(w http.ResponseWriter, response GraphQLResponse) error {
+	response.Errors = errorRedactor.Strings(response.Errors)
+	data, ok := response.Data.(map[string]interface{})
+	if !ok || len(data) != 1 || len(response.Errors) > 0 {
+		return json.NewEncoder(w).Encode(response)
+	}
+	var field string
+	var value interface{}
+	for field, value = range data {
+	}
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice || list.Len() < graphQLStreamMin {
+		return json.NewEncoder(w).Encode(response)
+	}
+
+	flusher, _ := w.(http.Flusher)
+	key, _ := json.Marshal(field)
+	if _, err := fmt.Fprintf(w, `{"data":{%s:[`, key); err != nil {
+		return err
+	}
+	for i := 0; i < list.Len(); i++ {
+		item, err := json.Marshal(list.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			item = append([]byte{','}, item...)
+		}
+		if _, err := w.Write(item); err != nil {
+			return err
+		}
+		if flusher != nil && (i+1)%graphQLStreamChunk == 0 {
+			flusher.Flush()
+		}
+	}
+	if _, err := io.WriteString(w, "]}"); err != nil {
+		return err
+	}
+	if response.Extensions != nil {
+		extensions, err := json.Marshal(response.Extensions)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, `,"extensions":%s`, extensions); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
+
+// graphQLExtensions returns the extensions of GraphQL responses: the build that served
+// them and its migration status, so responses from a mixed rollout can be told apart
+func graphQLExtensions(ctx context.Context) map[string]interface{} {
+	if versionHandler == nil {
+		return nil
+	}
+	return map[string]interface{}{"version": versionHandler.Report(ctx)}
+}
+
+// serveSchema serves the GraphQL schema's SDL
+func serveSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, introspectionSchema.SDL())
+}
+
+// queueJob sends a created job to the worker queue
+func queueJob(ctx context.Context, resolver *resolvers.Resolver, job *models.Job) {
+	// Queue the input data as stored, which includes the tenant's offices
+	var inputData interface{}
+	if job.InputData != nil {
+		inputData = *job.InputData
+	}
+	jobData := map[string]interface{}{
+		"job_id":       job.ID,
+		"user_id":      job.UserID,
+		"target_date":  job.TargetDate,
+		"input_data":   inputData,
+		"priority":     job.Priority,
+		"scheduled_at": job.ScheduledAt,
+	}
+
+	if err := resolver.QueueJob(ctx, jobData); err != nil {
+		log.Printf("Failed to queue job %s: %v", job.ID, err)
+	} else {
+		log.Printf("Queued job %s for processing", job.ID)
+	}
+}
@@ -0,0 +1,350 @@
+// Command loadgen drives a commute planner deployment with simulated users and reports the
+// latency of each call and of jobs end to end. Each virtual user signs up, generates demo
+// calendar data, then plans days back to back: it creates a job through GraphQL and polls
+// it until it finishes.
+//
+// Run the server with --synthetic-worker (or the AI service) to process the jobs, and with
+// JOB_QUOTA_MAX_PER_DAY=0 so long runs aren't cut off by the daily quota:
+//
+//	loadgen -url http://localhost:8080 -users 50 -duration 2m
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+type options struct {
+	url      string
+	users    int
+	duration time.Duration
+	think    time.Duration
+	poll     time.Duration
+	timeout  time.Duration
+	demoDays int
+}
+
+func main() {
+	log.SetFlags(0)
+	var opts options
+	flag.StringVar(&opts.url, "url", "http://localhost:8080", "base URL of the backend")
+	flag.IntVar(&opts.users, "users", 10, "concurrent virtual users")
+	flag.DurationVar(&opts.duration, "duration", time.Minute, "how long users keep creating jobs")
+	flag.DurationVar(&opts.think, "think", 0, "pause between a user's jobs")
+	flag.DurationVar(&opts.poll, "poll", 250*time.Millisecond, "how often a running job is polled")
+	flag.DurationVar(&opts.timeout, "job-timeout", 2*time.Minute, "how long a job may take before it counts as timed out")
+	flag.IntVar(&opts.demoDays, "demo-days", 14, "days of demo calendar data per user (the days jobs are planned for)")
+	flag.Parse()
+	if opts.users <= 0 || opts.demoDays <= 0 {
+		log.Fatal("loadgen: -users and -demo-days must be positive")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	deadline := time.Now().Add(opts.duration)
+	run := time.Now().Unix()
+	stats := newStats()
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	log.Printf("Running %d users against %s for %v", opts.users, opts.url, opts.duration)
+	started := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < opts.users; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u := &user{
+				opts:   opts,
+				client: client,
+				stats:  stats,
+				email:  fmt.Sprintf("loadgen-%d-%d@example.com", run, i),
+				seed:   int64(i + 1),
+			}
+			if err := u.run(ctx, deadline); err != nil && ctx.Err() == nil {
+				log.Printf("user %d: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	stats.print(os.Stdout, time.Since(started))
+}
+
+// user is one simulated user
+type user struct {
+	opts   options
+	client *http.Client
+	stats  *stats
+	email  string
+	seed   int64
+
+	token  string
+	userID string
+	days   []string
+}
+
+func (u *user) run(ctx context.Context, deadline time.Time) error {
+	if err := u.signup(ctx); err != nil {
+		return fmt.Errorf("signup: %w", err)
+	}
+	if err := u.generateDemo(ctx); err != nil {
+		return fmt.Errorf("demo data: %w", err)
+	}
+	for i := 0; time.Now().Before(deadline) && ctx.Err() == nil; i++ {
+		u.planDay(ctx, u.days[i%len(u.days)])
+		if u.opts.think > 0 {
+			select {
+			case <-time.After(u.opts.think):
+			case <-ctx.Done():
+			}
+		}
+	}
+	return nil
+}
+
+func (u *user) signup(ctx context.Context) error {
+	var response struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+		Data    struct {
+			AccessToken string `json:"accessToken"`
+			User        struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"data"`
+	}
+	err := u.timed(ctx, "signup", http.MethodPost, "/auth/signup", map[string]string{
+		"email":    u.email,
+		"password": "loadgen-password",
+		"name":     "Load Test",
+	}, &response)
+	if err != nil {
+		return err
+	}
+	if !response.Success {
+		return errors.New(response.Error)
+	}
+	u.token, u.userID = response.Data.AccessToken, response.Data.User.ID
+	return nil
+}
+
+func (u *user) generateDemo(ctx context.Context) error {
+	var response struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+		Data    struct {
+			Days []struct {
+				Date string `json:"date"`
+			} `json:"days"`
+		} `json:"data"`
+	}
+	err := u.timed(ctx, "demo generate", http.MethodPost, "/demo/generate", map[string]interface{}{
+		"days": u.opts.demoDays,
+		"seed": u.seed,
+	}, &response)
+	if err != nil {
+		return err
+	}
+	if !response.Success {
+		return errors.New(response.Error)
+	}
+	for _, day := range response.Data.Days {
+		u.days = append(u.days, day.Date)
+	}
+	if len(u.days) == 0 {
+		return errors.New("no days generated")
+	}
+	return nil
+}
+
+// planDay creates a job for date and polls it until it finishes, recording the outcome
+func (u *user) planDay(ctx context.Context, date string) {
+	var created struct {
+		CreateJob struct {
+			ID string `json:"id"`
+		} `json:"createJob"`
+	}
+	err := u.graphql(ctx, "createJob", `mutation CreateJob($input: CreateJobInput!) { createJob(input: $input) { id status } }`,
+		map[string]interface{}{"input": map[string]interface{}{"userId": u.userID, "targetDate": date}}, &created)
+	if err != nil {
+		return
+	}
+	start := time.Now()
+
+	for {
+		select {
+		case <-time.After(u.opts.poll):
+		case <-ctx.Done():
+			return
+		}
+		var polled struct {
+			Job struct {
+				Status string `json:"status"`
+			} `json:"job"`
+		}
+		if err := u.graphql(ctx, "job", `query Job($id: ID!) { job(id: $id) { id status progress currentStep } }`,
+			map[string]interface{}{"id": created.CreateJob.ID}, &polled); err != nil {
+			continue
+		}
+		switch polled.Job.Status {
+		case "COMPLETED":
+			u.stats.record("job completed", time.Since(start), nil)
+			// Reading the result is part of every plan
+			var result json.RawMessage
+			u.graphql(ctx, "job with recommendations", `query Job($id: ID!) { job(id: $id) { id status recommendations { id optionType reasoning } } }`,
+				map[string]interface{}{"id": created.CreateJob.ID}, &result)
+			return
+		case "FAILED", "CANCELLED":
+			u.stats.record("job completed", time.Since(start), fmt.Errorf("job %s", polled.Job.Status))
+			return
+		}
+		if time.Since(start) > u.opts.timeout {
+			u.stats.record("job completed", time.Since(start), errors.New("timed out"))
+			return
+		}
+	}
+}
+
+// graphql runs a GraphQL request, recording its latency under name
+func (u *user) graphql(ctx context.Context, name, query string, variables map[string]interface{}, out interface{}) error {
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	started := time.Now()
+	err := u.do(ctx, http.MethodPost, "/graphql", map[string]interface{}{"query": query, "variables": variables}, &response)
+	if err == nil && len(response.Errors) > 0 {
+		err = errors.New(response.Errors[0])
+	}
+	if err == nil {
+		err = json.Unmarshal(response.Data, out)
+	}
+	u.stats.record(name, time.Since(started), err)
+	return err
+}
+
+// timed sends a request, recording its latency under name
+func (u *user) timed(ctx context.Context, name, method, path string, body, out interface{}) error {
+	started := time.Now()
+	err := u.do(ctx, method, path, body, out)
+	u.stats.record(name, time.Since(started), err)
+	return err
+}
+
+func (u *user) do(ctx context.Context, method, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.opts.url+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if u.token != "" {
+		req.Header.Set("Authorization", "Bearer "+u.token)
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("%s %s: %s: %w", method, path, resp.Status, err)
+	}
+	return nil
+}
+
+// stats collects latencies by operation
+type stats struct {
+	mu  sync.Mutex
+	ops map[string]*series
+}
+
+type series struct {
+	latencies []time.Duration
+	errors    int
+	lastError error
+}
+
+func newStats() *stats {
+	return &stats{ops: map[string]*series{}}
+}
+
+func (s *stats) record(name string, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op, ok := s.ops[name]
+	if !ok {
+		op = &series{}
+		s.ops[name] = op
+	}
+	if err != nil {
+		op.errors++
+		op.lastError = err
+		return
+	}
+	op.latencies = append(op.latencies, latency)
+}
+
+// print writes a table of each operation's throughput and latency percentiles
+func (s *stats) print(w io.Writer, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.ops))
+	for name := range s.ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "operation\tok\terrors\tper sec\tp50\tp95\tp99\tmax\t")
+	for _, name := range names {
+		op := s.ops[name]
+		sort.Slice(op.latencies, func(i, j int) bool { return op.latencies[i] < op.latencies[j] })
+		fmt.Fprintf(table, "%s\t%d\t%d\t%.1f\t%v\t%v\t%v\t%v\t\n", name, len(op.latencies), op.errors,
+			float64(len(op.latencies))/elapsed.Seconds(),
+			percentile(op.latencies, 0.50), percentile(op.latencies, 0.95), percentile(op.latencies, 0.99),
+			percentile(op.latencies, 1))
+	}
+	table.Flush()
+	for _, name := range names {
+		if op := s.ops[name]; op.lastError != nil {
+			fmt.Fprintf(w, "last %s error: %v\n", name, op.lastError)
+		}
+	}
+}
+
+// percentile returns the p-th (0-1) of sorted latencies, rounded to the millisecond
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i].Round(time.Millisecond)
+}
@@ -1,267 +1,841 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"flag"
+	"io/fs"
 	"log"
 	"net/http"
-	"strings"
+	"net/http/pprof"
+	"os"
 	"time"
 
+	backend "github.com/commute-planner/backend"
 	"github.com/commute-planner/backend/internal/config"
+	"github.com/commute-planner/backend/pkg/ai"
+	"github.com/commute-planner/backend/pkg/analytics"
 	"github.com/commute-planner/backend/pkg/auth"
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/breaker"
+	"github.com/commute-planner/backend/pkg/buildinfo"
+	"github.com/commute-planner/backend/pkg/clientinfo"
 	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/deprecation"
+	"github.com/commute-planner/backend/pkg/diagnostics"
+	"github.com/commute-planner/backend/pkg/digest"
+	"github.com/commute-planner/backend/pkg/encryption"
+	"github.com/commute-planner/backend/pkg/experiments"
+	"github.com/commute-planner/backend/pkg/geo"
+	"github.com/commute-planner/backend/pkg/googlecalendar"
 	"github.com/commute-planner/backend/pkg/handlers"
+	"github.com/commute-planner/backend/pkg/introspection"
+	"github.com/commute-planner/backend/pkg/jobqueue"
+	"github.com/commute-planner/backend/pkg/meetingload"
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/middleware"
 	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/planfeed"
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/commute-planner/backend/pkg/queue/rabbitmq"
+	"github.com/commute-planner/backend/pkg/queuemonitor"
+	"github.com/commute-planner/backend/pkg/reaper"
+	"github.com/commute-planner/backend/pkg/redact"
 	"github.com/commute-planner/backend/pkg/redis"
+	"github.com/commute-planner/backend/pkg/reminders"
+	"github.com/commute-planner/backend/pkg/repository"
 	"github.com/commute-planner/backend/pkg/resolvers"
+	"github.com/commute-planner/backend/pkg/retention"
+	"github.com/commute-planner/backend/pkg/sharelink"
+	"github.com/commute-planner/backend/pkg/storage"
+	"github.com/commute-planner/backend/pkg/tenant"
+	"github.com/commute-planner/backend/pkg/upload"
+	"github.com/commute-planner/backend/pkg/webhooks"
+	"github.com/commute-planner/backend/pkg/webui"
 	"github.com/gorilla/mux"
-	"github.com/rs/cors"
 )
 
 type GraphQLRequest struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables"`
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
 }
 
 type GraphQLResponse struct {
-	Data   interface{} `json:"data,omitempty"`
-	Errors []string    `json:"errors,omitempty"`
+	Data       interface{}            `json:"data,omitempty"`
+	Errors     []string               `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
 func main() {
+	syntheticWorker := flag.Bool("synthetic-worker", false, "complete jobs with simulated AI processing instead of the AI service, for load testing")
+	flag.Parse()
+
 	cfg := config.Load()
+	if *syntheticWorker {
+		cfg.SyntheticWorker.Enabled = true
+	}
+
+	// Production logs and client errors carry no emails, tokens or event details
+	if cfg.Redaction.Enabled {
+		errorRedactor = redact.New(cfg.Redaction.SafeFields...)
+		log.SetOutput(errorRedactor.Writer(os.Stderr))
+		log.Printf("Redacting personal data from logs and error messages")
+	}
+
+	// Credentials come from the configured secrets provider, never the config defaults
+	secretsProvider, err := cfg.Secrets.NewProvider()
+	if err != nil {
+		log.Fatalf("Invalid secrets config: %v", err)
+	}
+	if err := cfg.LoadSecrets(context.Background(), secretsProvider); err != nil {
+		log.Fatalf("Failed to load secrets: %v", err)
+	}
+	log.Printf("Loaded secrets from the %s provider", cfg.Secrets.Provider)
+
+	// Calls to a failing dependency fail fast instead of each waiting out a timeout
+	var breakerCfg *breaker.Config
+	if cfg.CircuitBreaker.Enabled {
+		breakerCfg = &breaker.Config{
+			MaxFailures:   uint32(cfg.CircuitBreaker.MaxFailures),
+			OpenTimeout:   cfg.CircuitBreaker.OpenTimeout,
+			HalfOpenCalls: uint32(cfg.CircuitBreaker.HalfOpenCalls),
+		}
+	}
 
-	db, err := database.NewConnection()
+	db, err := database.NewConnection(database.Config{
+		Driver:          cfg.DatabaseDriver,
+		URL:             cfg.DatabaseURL,
+		ReplicaURL:      cfg.DatabaseReplicaURL,
+		MaxOpenConns:    cfg.DBPool.MaxOpenConns,
+		MaxIdleConns:    cfg.DBPool.MaxIdleConns,
+		ConnMaxLifetime: cfg.DBPool.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DBPool.ConnMaxIdleTime,
+		QueryTimeout:    cfg.DBPool.QueryTimeout,
+		ReadTimeout:     cfg.DBPool.ReadTimeout,
+		WriteTimeout:    cfg.DBPool.WriteTimeout,
+		CircuitBreaker:  breakerCfg,
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
+	metrics.RegisterDB(db.DB, db.Driver())
+
+	// Sensitive columns are encrypted when keys are configured
+	encryptionKeys, err := encryption.ParseKeys(cfg.Encryption.Keys)
+	if err != nil {
+		log.Fatalf("Invalid data_encryption_keys: %v", err)
+	}
+	encryptedColumns := []string{database.ColumnOAuthTokens}
+	if cfg.Encryption.EventDescriptions {
+		encryptedColumns = append(encryptedColumns, database.ColumnEventDescription)
+	}
+	db.SetEncryption(encryptionKeys, encryptedColumns...)
+	if encryptionKeys != nil {
+		log.Printf("Encrypting %v with key %s", encryptedColumns, encryptionKeys.Current())
+	} else if cfg.Environment == "production" {
+		log.Printf("Warning: no data_encryption_keys secret; OAuth tokens are stored unencrypted")
+	}
+	if replica := db.Replica(); replica != nil {
+		metrics.RegisterDB(replica, db.Driver()+"_replica")
+	}
 
 	// Initialize Redis client
 	log.Printf("Initializing Redis client...")
-	redisClient := redis.NewClient("redis:6379")
+	if cfg.Redis.QueueMode != redis.QueueModeStream && cfg.Redis.QueueMode != redis.QueueModeList {
+		log.Fatalf("Unknown REDIS_QUEUE_MODE %q (expected stream or list)", cfg.Redis.QueueMode)
+	}
+	redisClient := redis.NewClient(redis.Config{
+		Addr:                cfg.Redis.Addr,
+		Password:            cfg.Redis.Password,
+		DB:                  cfg.Redis.DB,
+		TLS:                 cfg.Redis.TLS,
+		InsecureSkipVerify:  cfg.Redis.TLSInsecureSkipVerify,
+		MaxRetries:          cfg.Redis.MaxRetries,
+		MinRetryBackoff:     cfg.Redis.MinRetryBackoff,
+		MaxRetryBackoff:     cfg.Redis.MaxRetryBackoff,
+		DialTimeout:         cfg.Redis.DialTimeout,
+		CommandTimeout:      cfg.Redis.CommandTimeout,
+		HealthCheckInterval: cfg.Redis.HealthCheckInterval,
+		QueueMode:           cfg.Redis.QueueMode,
+		StreamMaxLen:        cfg.Redis.StreamMaxLen,
+		CircuitBreaker:      breakerCfg,
+	})
 	defer redisClient.Close()
+	go redisClient.Monitor(context.Background())
 	log.Printf("Redis client initialized")
 
-	resolver := resolvers.NewResolver(db, redisClient)
+	repos := repository.NewSQLRepositories(db)
+
+	// Job pipeline broker
+	var jobPublisher queue.Publisher
+	// queueDepth reports the broker's job queues and the scheduled jobs, for /debug/statusz
+	queueDepth := redisClient.QueueDepth
+	switch cfg.Queue.Broker {
+	case queue.BrokerRedis:
+		jobPublisher = redisClient
+	case queue.BrokerRabbitMQ:
+		rabbitPublisher := rabbitmq.NewPublisher(rabbitmq.Config{
+			URL:   cfg.Queue.RabbitMQURL,
+			Queue: cfg.Queue.RabbitMQQueue,
+		})
+		defer rabbitPublisher.Close()
+		go rabbitPublisher.Monitor(context.Background())
+		jobPublisher = rabbitPublisher
+		queueDepth = func(ctx context.Context) ([]queue.Depth, error) {
+			depths, err := rabbitPublisher.QueueDepth(ctx)
+			if err != nil {
+				return nil, err
+			}
+			// Scheduled jobs wait in Redis whichever broker runs the pipeline
+			scheduled, err := redisClient.ScheduledDepth(ctx)
+			if err != nil {
+				return depths, err
+			}
+			return append(depths, scheduled), nil
+		}
+	default:
+		log.Fatalf("Unknown QUEUE_BROKER %q (expected redis or rabbitmq)", cfg.Queue.Broker)
+	}
+
+	// Jobs that can't be published are buffered in the database and flushed once the
+	// broker is reachable again
+	// Scheduled jobs wait in a Redis sorted set whichever broker runs the pipeline
+	jobQueue := jobqueue.New(jobPublisher, redisClient, repos.JobOutbox, jobqueue.Config{
+		FlushInterval: cfg.Queue.OutboxFlushInterval,
+		MoveInterval:  cfg.Queue.SchedulerInterval,
+	})
+	go jobQueue.Run(context.Background())
+	go jobQueue.RunMover(context.Background())
+	// Outbound webhooks: deliveries are queued in Postgres and sent by a background worker
+	webhookDispatcher := webhooks.NewDispatcher(repos.Webhooks, webhooks.Config{
+		PollInterval:      cfg.Webhooks.PollInterval,
+		Timeout:           cfg.Webhooks.Timeout,
+		MaxAttempts:       cfg.Webhooks.MaxAttempts,
+		AllowInsecureURLs: cfg.Webhooks.AllowInsecureURLs,
+	})
+	go webhookDispatcher.Run(context.Background())
+	// The myPlans subscription hears the same events, from every instance over Redis
+	planFeed := planfeed.New(redisClient)
+	go planFeed.Run(context.Background())
+	eventPublisher := planfeed.NewPublisher(webhookDispatcher, planFeed)
+
+	// Optionally write recommendation text in the backend rather than only in the AI service
+	var explainer resolvers.RecommendationExplainer
+	if cfg.AI.Provider != "" {
+		llm, err := ai.NewClient(ai.Config{
+			Provider:       cfg.AI.Provider,
+			Model:          cfg.AI.Model,
+			APIKey:         cfg.AI.APIKey,
+			BaseURL:        cfg.AI.BaseURL,
+			Timeout:        cfg.AI.Timeout,
+			CircuitBreaker: breakerCfg,
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure AI provider: %v", err)
+		}
+		explainer = ai.NewExplainer(llm)
+		log.Printf("Recommendation reasoning will be generated with %s", llm.Provider())
+	}
+
+	// Optionally geocode home, office and meeting locations
+	var geocoder geo.Geocoder
+	if cfg.Geo.Provider != "" {
+		provider, err := geo.NewGeocoder(geo.Config{
+			Provider:       cfg.Geo.Provider,
+			APIKey:         cfg.Geo.APIKey,
+			BaseURL:        cfg.Geo.BaseURL,
+			UserAgent:      cfg.Geo.UserAgent,
+			Timeout:        cfg.Geo.Timeout,
+			CircuitBreaker: breakerCfg,
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure geocoder: %v", err)
+		}
+		geocoder = geo.NewCachedGeocoder(provider, repos.GeocodeCache, cfg.Geo.CacheTTL)
+		go geo.NewLocator(repos.Events, geocoder, cfg.Geo.LocateInterval).Run(context.Background())
+		log.Printf("Locations will be geocoded with %s", provider.Provider())
+	}
+
+	resolver := resolvers.NewResolver(repos, jobQueue, eventPublisher, resolvers.JobQuotaLimits{
+		MaxQueuedJobs: cfg.JobQuota.MaxQueuedJobs,
+		MaxJobsPerDay: cfg.JobQuota.MaxJobsPerDay,
+	}, explainer, geocoder)
+	if err := resolver.ApplyBackpressure(resolvers.BackpressureLimits{
+		MaxQueued:     cfg.Backpressure.MaxQueued,
+		MaxInProgress: cfg.Backpressure.MaxInProgress,
+		Mode:          cfg.Backpressure.Mode,
+		MaxOverflow:   cfg.Backpressure.MaxOverflow,
+	}); err != nil {
+		log.Fatalf("Invalid backpressure config: %v", err)
+	}
+	resolver.LimitMeetingLoad(meetingload.Limits{
+		MaxMeetingHours: cfg.MeetingLoad.MaxHours,
+		MaxBackToBack:   cfg.MeetingLoad.MaxBackToBack,
+	})
+	resolver.AnonymizeOrgReports(cfg.OrgReports.MinGroupSize)
+	// Record anonymized product analytics events, forwarding them to a sink if configured
+	var tracker *analytics.Tracker
+	if cfg.Analytics.Enabled {
+		// Without a salt of its own the user IDs in events could be hashed back
+		if cfg.Analytics.Salt == "" {
+			log.Fatalf("Invalid analytics config: ANALYTICS_SALT is required when ANALYTICS_ENABLED is set")
+		}
+		sink, err := analyticsSink(cfg.Analytics)
+		if err != nil {
+			log.Fatalf("Invalid analytics config: %v", err)
+		}
+		tracker = analytics.NewTracker(repos.Analytics, repos.Users, sink, analytics.Config{
+			Salt:          cfg.Analytics.Salt,
+			FlushInterval: cfg.Analytics.FlushInterval,
+		})
+		go tracker.Run(context.Background())
+		resolver.TrackAnalyticsWith(tracker)
+		log.Printf("Analytics events will be kept in the %s sink", cfg.Analytics.Sink)
+	}
+	// Count each client's use of deprecated schema fields, for deprecatedFieldUsage
+	deprecations, err := deprecation.Load(backend.Schema)
+	if err != nil {
+		log.Fatalf("Failed to index the GraphQL schema's deprecations: %v", err)
+	}
+	deprecationTracker = deprecation.NewTracker(deprecations, repos.Deprecations, time.Minute)
+	go deprecationTracker.Run(context.Background())
+	resolver.ReportDeprecationsOf(deprecations)
+	// Count the operations each client version runs, for clientUsage
+	clientUsageTracker = clientinfo.NewUsageTracker(repos.ClientUsage, time.Minute)
+	go clientUsageTracker.Run(context.Background())
+	// Re-plan upcoming days when their calendar changes through the API or a sync
+	if cfg.Replan.Enabled {
+		resolver.ReplanOnCalendarChanges(resolvers.ReplanConfig{
+			Debounce:    cfg.Replan.Debounce,
+			HorizonDays: cfg.Replan.HorizonDays,
+		})
+	}
+	// A/B test the planner: tag new jobs with their user's variant
+	experiment, err := experiments.Parse(cfg.Experiment.Name, cfg.Experiment.Variants)
+	if err != nil {
+		log.Fatalf("Invalid planner experiment: %v", err)
+	}
+	if experiment != nil {
+		resolver.RunExperiment(experiment)
+		log.Printf("Running planner experiment %s with variants %v", experiment.Name, cfg.Experiment.Variants)
+	}
+	// Re-rank completed jobs' recommendations with the configured strategies
+	rankers, err := rankerSelector(cfg.Ranking)
+	if err != nil {
+		log.Fatalf("Invalid ranking config: %v", err)
+	}
+	if rankers != nil {
+		resolver.RankWith(*rankers)
+		log.Printf("Recommendations will be ranked with %s unless a tenant or variant picks another ranker", rankers.Select("", nil))
+	}
+	// Keep large job artifacts in object storage, handing out presigned URLs
+	if cfg.ArtifactStorage.Bucket != "" {
+		store, err := storage.NewClient(storage.Config{
+			Endpoint:        cfg.ArtifactStorage.Endpoint,
+			Region:          cfg.ArtifactStorage.Region,
+			Bucket:          cfg.ArtifactStorage.Bucket,
+			AccessKeyID:     cfg.ArtifactStorage.AccessKeyID,
+			SecretAccessKey: cfg.ArtifactStorage.SecretAccessKey,
+			PathStyle:       cfg.ArtifactStorage.PathStyle,
+		})
+		if err != nil {
+			log.Fatalf("Invalid artifact storage config: %v", err)
+		}
+		resolver.StoreArtifactsIn(store, cfg.ArtifactStorage.URLTTL)
+		log.Printf("Job artifacts will be stored in bucket %s", cfg.ArtifactStorage.Bucket)
+	}
+	// Let users share read-only links to their plans
+	if cfg.ShareLinks.Secret != "" {
+		signer, err := sharelink.NewSigner(cfg.ShareLinks.Secret, cfg.ShareLinks.BaseURL)
+		if err != nil {
+			log.Fatalf("Invalid share link config: %v", err)
+		}
+		resolver.ShareLinksWith(signer, cfg.ShareLinks.TTL)
+		log.Printf("Share links will point at %s", cfg.ShareLinks.BaseURL)
+	}
+	// Calendar apps subscribe to users' plans with secret feed URLs
+	resolver.ServeCalendarFeedsAt(cfg.PublicURL)
+
+	// Fail (or requeue) jobs a crashed worker left IN_PROGRESS
+	jobReaper := reaper.NewReaper(db, repos.Jobs, jobQueue, eventPublisher, reaper.Config{
+		Interval:    cfg.JobReaper.Interval,
+		StaleAfter:  cfg.JobReaper.StaleAfter,
+		MaxRequeues: cfg.JobReaper.MaxRequeues,
+	})
+	go jobReaper.Run(context.Background())
+
+	// Export the job queues' backlog and alert when workers fall behind
+	queueMonitor, err := queuemonitor.New(queueDepth, repos.Jobs, redisClient, queuemonitor.Config{
+		Interval:    cfg.QueueMonitor.Interval,
+		StuckAfter:  cfg.QueueMonitor.StuckAfter,
+		MaxMessages: int64(cfg.QueueMonitor.MaxMessages),
+		MaxAge:      cfg.QueueMonitor.MaxAge,
+		MaxStuck:    cfg.QueueMonitor.MaxStuck,
+		AlertURL:    cfg.QueueMonitor.AlertURL,
+		AlertFormat: cfg.QueueMonitor.AlertFormat,
+	})
+	if err != nil {
+		log.Fatalf("Invalid queue monitor configuration: %v", err)
+	}
+	go queueMonitor.Run(context.Background())
+
+	// Archive finished jobs past their retention period
+	archiver := retention.NewArchiver(repos.Jobs, repos.Retention, retention.Config{
+		Interval:     cfg.Retention.Interval,
+		CompletedTTL: cfg.Retention.CompletedTTL,
+		FailedTTL:    cfg.Retention.FailedTTL,
+		BatchSize:    cfg.Retention.BatchSize,
+	})
+	if cfg.Retention.Enabled {
+		go archiver.Run(context.Background())
+	}
+
+	// Email opted-in users the week ahead on Sunday evening
+	if cfg.WeeklyDigest.Enabled {
+		notifier, err := emailNotifier(cfg)
+		if err != nil {
+			log.Fatalf("Invalid email config: %v", err)
+		}
+		if notifier != nil {
+			go digest.NewScheduler(resolver, repos.Notifications, repos.Users, notifier, digest.Config{
+				Interval: cfg.WeeklyDigest.Interval,
+				SendHour: cfg.WeeklyDigest.SendHour,
+			}).Run(context.Background())
+		} else {
+			log.Printf("Weekly digest disabled: SMTP_HOST is not set")
+		}
+	}
+
+	// Remind opted-in users to leave for the plans they accepted
+	if cfg.CommuteReminders.Enabled {
+		notifier, err := emailNotifier(cfg)
+		if err != nil {
+			log.Fatalf("Invalid email config: %v", err)
+		}
+		go reminders.NewScheduler(repos.Reminders, repos.Users, notifier, eventPublisher, reminders.Config{
+			Interval: cfg.CommuteReminders.Interval,
+		}).Run(context.Background())
+	}
+
+	// Load testing: stand-in workers complete jobs in place of the AI service
+	if cfg.SyntheticWorker.Enabled {
+		if err := startSyntheticWorkers(context.Background(), cfg, repos, redisClient); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	// Initialize OAuth-ready auth system (starts with JWT, migrates to OAuth easily)
-	jwtSecret := "your-jwt-secret-key-change-in-production" // TODO: Move to env var
-	authProvider := auth.NewJWTProvider(db, jwtSecret)
+	var authProvider auth.AuthProvider
+	switch cfg.AuthProvider {
+	case "oidc":
+		oidcProvider, err := auth.NewOIDCProvider(context.Background(), db, auth.OIDCConfig{
+			OAuthConfig: auth.OAuthConfig{
+				ClientID:     cfg.OIDC.ClientID,
+				ClientSecret: cfg.OIDC.ClientSecret,
+				RedirectURL:  cfg.OIDC.RedirectURL,
+				Scopes:       cfg.OIDC.Scopes,
+			},
+			IssuerURL:    cfg.OIDC.IssuerURL,
+			Audience:     cfg.OIDC.Audience,
+			ProviderName: cfg.OIDC.ProviderName,
+		}, redisClient)
+		if err != nil {
+			log.Fatalf("Failed to initialize OIDC provider: %v", err)
+		}
+		authProvider = oidcProvider
+		log.Printf("Using OIDC auth provider %s (%s)", cfg.OIDC.ProviderName, cfg.OIDC.IssuerURL)
+	case "jwt":
+		keys, err := auth.LoadKeySet(auth.KeyConfig{
+			Algorithm:            cfg.JWT.Algorithm,
+			Secret:               cfg.JWT.Secret,
+			LegacySecret:         cfg.JWT.LegacySecret,
+			LegacySecretExpires:  cfg.JWT.LegacySecretExpires,
+			SigningKeyFile:       cfg.JWT.SigningKeyFile,
+			SigningKey:           cfg.JWT.SigningKey,
+			VerificationKeyFiles: cfg.JWT.VerificationKeyFiles,
+		})
+		if err != nil {
+			log.Fatalf("Failed to load JWT signing keys: %v", err)
+		}
+		authProvider = auth.NewJWTProvider(db, keys, redisClient)
+		log.Printf("Using JWT auth provider (%s)", cfg.JWT.Algorithm)
+	default:
+		log.Fatalf("Unknown AUTH_PROVIDER %q (expected jwt or oidc)", cfg.AuthProvider)
+	}
 	authHandler := handlers.NewAuthHandler(authProvider)
-	demoHandler := handlers.NewDemoHandler(db)
+
+	// Passwordless login with single-use links emailed to users
+	if cfg.MagicLinks.Enabled {
+		notifier, err := emailNotifier(cfg)
+		if err != nil {
+			log.Fatalf("Invalid email config: %v", err)
+		}
+		if notifier != nil {
+			if err := authHandler.SendMagicLinksWith(notifier, cfg.MagicLinks.BaseURL, cfg.MagicLinks.RedirectURL, cfg.MagicLinks.TTL); err != nil {
+				log.Fatalf("Invalid magic link config: %v", err)
+			}
+			log.Printf("Magic link login enabled, links point at %s", cfg.MagicLinks.BaseURL)
+		} else {
+			log.Printf("Magic link login disabled: SMTP_HOST is not set")
+		}
+	}
+	demoHandler := handlers.NewDemoHandler(repos.Users, repos.Events, repos.Jobs, repos.Recommendations)
+	if tracker != nil {
+		demoHandler.TrackAnalyticsWith(tracker)
+	}
+	calendarEventHandler := handlers.NewCalendarEventHandler(resolver)
+	exportHandler := handlers.NewExportHandler(repos.Events, repos.Recommendations)
+
+	// Multi-tenant deployments scope every request to the tenant of its subdomain or user
+	switch cfg.Tenancy.Mode {
+	case tenant.ModeSingle:
+	case tenant.ModeMulti:
+		if cfg.Tenancy.DefaultTenant != "" && !tenant.ValidID(cfg.Tenancy.DefaultTenant) {
+			log.Fatalf("Invalid TENANT_DEFAULT %q", cfg.Tenancy.DefaultTenant)
+		}
+		log.Printf("Multi-tenant mode (base domain %q, default tenant %q)", cfg.Tenancy.BaseDomain, cfg.Tenancy.DefaultTenant)
+	default:
+		log.Fatalf("Unknown TENANCY_MODE %q (expected single or multi)", cfg.Tenancy.Mode)
+	}
+	tenantMiddleware := handlers.NewTenantMiddleware(repos.Tenants, cfg.Tenancy.Mode, cfg.Tenancy.BaseDomain, cfg.Tenancy.DefaultTenant)
 
 	router := mux.NewRouter()
 
+	// Resolve the tenant from the subdomain before auth, so tokens are checked against it
+	router.Use(tenantMiddleware.FromHost)
+
+	// API tokens authenticate ahead of session JWTs, with only the scopes they were given
+	apiTokenHandler := handlers.NewAPITokenHandler(resolver)
+	router.Use(apiTokenHandler.Middleware)
+
 	// Apply auth middleware to all routes FIRST (parses JWT and sets user in context)
 	router.Use(authHandler.AuthMiddleware)
 
 	// Auth endpoints - OAuth ready architecture
-	router.HandleFunc("/auth/signup", authHandler.Signup).Methods("POST")
-	router.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
-	router.HandleFunc("/auth/me", authHandler.Me).Methods("GET")
+	router.Handle("/auth/signup", tenantMiddleware.Require(http.HandlerFunc(authHandler.Signup))).Methods("POST")
+	router.Handle("/auth/login", tenantMiddleware.Require(http.HandlerFunc(authHandler.Login))).Methods("POST")
+	router.Handle("/auth/magic-link", tenantMiddleware.Require(http.HandlerFunc(authHandler.RequestMagicLink))).Methods("POST")
+	// The link's token names its tenant, so the callback works from the API's public URL.
+	// Opening the link asks to confirm; only the confirmation's POST uses the link up.
+	router.HandleFunc(handlers.MagicLinkCallbackPath, authHandler.ConfirmMagicLink).Methods("GET")
+	router.HandleFunc(handlers.MagicLinkCallbackPath, authHandler.MagicLinkCallback).Methods("POST")
+	router.Handle("/auth/me", middleware.ETag(http.HandlerFunc(authHandler.Me))).Methods("GET")
+	router.HandleFunc("/.well-known/jwks.json", authHandler.JWKS).Methods("GET")
+	router.Handle("/auth/logout", handlers.RequireAuth(http.HandlerFunc(authHandler.Logout))).Methods("POST")
+	router.Handle("/auth/sessions", handlers.RequireAuth(http.HandlerFunc(authHandler.ListSessions))).Methods("GET")
+	router.Handle("/auth/sessions/{id}", handlers.RequireAuth(http.HandlerFunc(authHandler.RevokeSession))).Methods("DELETE")
+
+	// Personal access tokens, managed with a session; a token can't mint or revoke tokens
+	router.Handle("/auth/tokens", handlers.RequireAuth(http.HandlerFunc(apiTokenHandler.ListTokens))).Methods("GET")
+	router.Handle("/auth/tokens", handlers.RequireAuth(http.HandlerFunc(apiTokenHandler.CreateToken))).Methods("POST")
+	router.Handle("/auth/tokens/{id}", handlers.RequireAuth(http.HandlerFunc(apiTokenHandler.RevokeToken))).Methods("DELETE")
+
+	// SCIM provisioning from enterprise identity providers, with each tenant's own token.
+	// Provisioned users sign in with the configured auth provider.
+	scimProvider := "local"
+	if cfg.AuthProvider == "oidc" {
+		scimProvider = cfg.OIDC.ProviderName
+	}
+	scimHandler := handlers.NewSCIMHandler(resolver, scimProvider)
+	router.Handle(handlers.SCIMPath+"/Users", scimHandler.RequireToken(http.HandlerFunc(scimHandler.ListUsers))).Methods("GET")
+	router.Handle(handlers.SCIMPath+"/Users", scimHandler.RequireToken(http.HandlerFunc(scimHandler.CreateUser))).Methods("POST")
+	router.Handle(handlers.SCIMPath+"/Users/{id}", scimHandler.RequireToken(http.HandlerFunc(scimHandler.GetUser))).Methods("GET")
+	router.Handle(handlers.SCIMPath+"/Users/{id}", scimHandler.RequireToken(http.HandlerFunc(scimHandler.ReplaceUser))).Methods("PUT")
+	router.Handle(handlers.SCIMPath+"/Users/{id}", scimHandler.RequireToken(http.HandlerFunc(scimHandler.PatchUser))).Methods("PATCH")
 	
 	// Demo data endpoints (protected - requires authentication)
 	router.Handle("/demo/generate", handlers.RequireAuth(http.HandlerFunc(demoHandler.GenerateDemoData))).Methods("POST")
-	router.Handle("/demo/check", handlers.RequireAuth(http.HandlerFunc(demoHandler.CheckDemoData))).Methods("GET")
+	router.Handle("/demo/check", handlers.RequireAuth(middleware.ETag(http.HandlerFunc(demoHandler.CheckDemoData)))).Methods("GET")
+	router.Handle("/demo/clear", handlers.RequireAuth(http.HandlerFunc(demoHandler.ClearDemoData))).Methods("DELETE")
 	
+	// Calendar event REST API (protected)
+	router.Handle("/api/v1/calendar-events:batch", handlers.RequireAuth(http.HandlerFunc(calendarEventHandler.BatchCreate))).Methods("POST")
+	router.Handle("/api/v1/calendar-events:search", handlers.RequireScope(models.ScopeReadCalendar, middleware.ETag(http.HandlerFunc(calendarEventHandler.Search)))).Methods("GET")
+
+	// Today at a glance for mobile widgets (protected)
+	todayHandler := handlers.NewTodayHandler(resolver)
+	router.Handle("/api/v1/today", handlers.RequireAuth(middleware.ETag(http.HandlerFunc(todayHandler.Today)))).Methods("GET")
+
+	// Offices (protected); editing them is an operator endpoint below
+	officeHandler := handlers.NewOfficeHandler(resolver)
+	router.Handle("/offices", handlers.RequireAuth(http.HandlerFunc(officeHandler.ListOffices))).Methods("GET")
+	router.Handle("/me/default-office", handlers.RequireAuth(http.HandlerFunc(officeHandler.SetDefaultOffice))).Methods("PUT")
+
+	// Home address, travel profile, carbon stats and commute costs (protected); addresses
+	// are geocoded when a geocoder is configured
+	locationHandler := handlers.NewLocationHandler(resolver)
+	router.Handle("/me/home-address", handlers.RequireAuth(http.HandlerFunc(locationHandler.SetHomeAddress))).Methods("PUT")
+	router.Handle("/me/travel-profile", handlers.RequireAuth(http.HandlerFunc(locationHandler.GetTravelProfile))).Methods("GET")
+	router.Handle("/me/travel-profile", handlers.RequireAuth(http.HandlerFunc(locationHandler.SetTravelProfile))).Methods("PUT")
+	router.Handle("/me/carbon-stats", handlers.RequireAuth(http.HandlerFunc(locationHandler.GetCarbonStats))).Methods("GET")
+	router.Handle("/me/commute-costs", handlers.RequireAuth(http.HandlerFunc(locationHandler.GetCommuteCosts))).Methods("GET")
+
+	// CSV exports (protected)
+	router.Handle("/export/calendar-events.csv", handlers.RequireScope(models.ScopeReadCalendar, http.HandlerFunc(exportHandler.ExportCalendarEvents))).Methods("GET")
+	router.Handle("/export/recommendations.csv", handlers.RequireAuth(http.HandlerFunc(exportHandler.ExportRecommendations))).Methods("GET")
+
+	// Plans shared with a signed link; the link is the credential
+	shareHandler := handlers.NewShareHandler(resolver)
+	router.HandleFunc(sharelink.Path+"{id}", shareHandler.GetSharedPlan).Methods("GET")
+
+	// Calendar feeds; the secret token in the URL is the credential
+	calendarFeedHandler := handlers.NewCalendarFeedHandler(resolver)
+	router.HandleFunc(resolvers.CalendarFeedPath+"{token}.ics", calendarFeedHandler.Feed).Methods("GET", "HEAD")
+
+	// Schemas of the messages exchanged with the AI workers, and validation of payloads
+	// against them for the AI service's CI
+	contractsHandler := handlers.NewContractsHandler()
+	router.HandleFunc("/contracts", contractsHandler.Kinds).Methods("GET")
+	router.HandleFunc("/contracts/{kind}", contractsHandler.Schema).Methods("GET")
+	router.HandleFunc("/contracts/{kind}/validate", contractsHandler.Validate).Methods("POST")
+
+	// Operator endpoints; disabled unless an admin token is configured
+	if cfg.AdminToken != "" {
+		adminHandler := handlers.NewAdminHandler(resolver)
+		requireAdmin := handlers.RequireAdminToken(cfg.AdminToken)
+		router.Handle("/admin/users/{id}/job-quota", requireAdmin(http.HandlerFunc(adminHandler.GetJobQuota))).Methods("GET")
+		router.Handle("/admin/users/{id}/job-quota", requireAdmin(http.HandlerFunc(adminHandler.SetJobQuota))).Methods("PUT")
+		router.Handle("/admin/users/{id}/job-quota", requireAdmin(http.HandlerFunc(adminHandler.DeleteJobQuota))).Methods("DELETE")
+		router.Handle("/admin/tenants", requireAdmin(http.HandlerFunc(adminHandler.ListTenants))).Methods("GET")
+		router.Handle("/admin/tenants/{id}", requireAdmin(http.HandlerFunc(adminHandler.PutTenant))).Methods("PUT")
+		router.Handle("/admin/tenants/{id}/scim-token", requireAdmin(http.HandlerFunc(adminHandler.RotateSCIMToken))).Methods("POST")
+		router.Handle("/admin/tenants/{id}/scim-token", requireAdmin(http.HandlerFunc(adminHandler.DisableSCIM))).Methods("DELETE")
+		router.Handle("/admin/experiments/{name}/results", requireAdmin(http.HandlerFunc(adminHandler.ExperimentResults))).Methods("GET")
+		router.Handle("/admin/offices", requireAdmin(http.HandlerFunc(officeHandler.CreateOffice))).Methods("POST")
+		router.Handle("/admin/offices/{id}", requireAdmin(http.HandlerFunc(officeHandler.UpdateOffice))).Methods("PUT")
+		router.Handle("/admin/offices/{id}", requireAdmin(http.HandlerFunc(officeHandler.DeleteOffice))).Methods("DELETE")
+		retentionHandler := handlers.NewRetentionHandler(archiver)
+		router.Handle("/admin/retention/runs", requireAdmin(http.HandlerFunc(retentionHandler.StartRun))).Methods("POST")
+		router.Handle("/admin/retention/runs", requireAdmin(http.HandlerFunc(retentionHandler.ListRuns))).Methods("GET")
+		router.Handle("/admin/retention/runs/{id}", requireAdmin(http.HandlerFunc(retentionHandler.GetRun))).Methods("GET")
+		router.Handle("/admin/archive/jobs/{id}", requireAdmin(http.HandlerFunc(retentionHandler.GetArchivedJob))).Methods("GET")
+
+		// Production troubleshooting: pools, queues and in-flight operations, and profiles
+		if cfg.DebugEndpoints {
+			router.Handle("/debug/statusz", requireAdmin(diagnostics.Handler(diagnostics.Sources{
+				DBPools:    map[string]*sql.DB{"primary": db.DB, "replica": db.Replica()},
+				Redis:      redisClient,
+				QueueDepth: queueDepth,
+				Operations: graphqlOperations,
+			}))).Methods("GET")
+			router.Handle("/debug/pprof/cmdline", requireAdmin(http.HandlerFunc(pprof.Cmdline)))
+			router.Handle("/debug/pprof/profile", requireAdmin(http.HandlerFunc(pprof.Profile)))
+			router.Handle("/debug/pprof/symbol", requireAdmin(http.HandlerFunc(pprof.Symbol)))
+			router.Handle("/debug/pprof/trace", requireAdmin(http.HandlerFunc(pprof.Trace)))
+			router.PathPrefix("/debug/pprof/").Handler(requireAdmin(http.HandlerFunc(pprof.Index)))
+		}
+	}
+
+	// Google Calendar push sync; only enabled once a public webhook URL is configured
+	if cfg.GoogleCalendar.WebhookURL != "" {
+		syncer := googlecalendar.NewSyncer(repos.GoogleCalendar, repos.Events, googlecalendar.NewStoredTokenSource(repos.Users), googlecalendar.Config{
+			WebhookURL:     cfg.GoogleCalendar.WebhookURL,
+			BaseURL:        cfg.GoogleCalendar.APIBaseURL,
+			RenewBefore:    cfg.GoogleCalendar.RenewBefore,
+			RenewInterval:  cfg.GoogleCalendar.RenewInterval,
+			CircuitBreaker: breakerCfg,
+			OnChange: func(ctx context.Context, previous, current *models.CalendarEvent) {
+				resolver.CalendarChanged(ctx, previous, current)
+			},
+		})
+		go syncer.RunRenewals(context.Background())
+
+		googleCalendarHandler := handlers.NewGoogleCalendarHandler(syncer)
+		router.HandleFunc("/webhooks/google-calendar", googleCalendarHandler.Notify).Methods("POST")
+		router.Handle("/google-calendar/watch", handlers.RequireAuth(http.HandlerFunc(googleCalendarHandler.Watch))).Methods("POST")
+		router.Handle("/google-calendar/watch", handlers.RequireAuth(http.HandlerFunc(googleCalendarHandler.Channels))).Methods("GET")
+		router.Handle("/google-calendar/watch", handlers.RequireAuth(http.HandlerFunc(googleCalendarHandler.Unwatch))).Methods("DELETE")
+	}
+
+	// OAuth / OIDC login flow (only active when AUTH_PROVIDER=oidc)
+	router.Handle("/auth/oidc/login", tenantMiddleware.Require(http.HandlerFunc(authHandler.OAuthLogin))).Methods("GET")
+	router.Handle("/auth/oidc/callback", tenantMiddleware.Require(http.HandlerFunc(authHandler.OAuthCallback))).Methods("GET")
+
 	// Future OAuth endpoints (ready for Google Calendar integration)
 	// router.HandleFunc("/auth/google", authHandler.GoogleOAuth).Methods("GET")
 	// router.HandleFunc("/auth/google/callback", authHandler.GoogleOAuthCallback).Methods("GET")
 
-	// Health check endpoint
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status": "OK", "timestamp": "` + time.Now().UTC().Format(time.RFC3339) + `"}`))
-	}).Methods("GET")
+	// Health check endpoints
+	healthHandler := handlers.NewHealthHandler(2 * time.Second)
+	healthHandler.AddCheck("postgres", db.PingContext)
+	if replica := db.Replica(); replica != nil {
+		healthHandler.AddCheck("postgres_replica", replica.PingContext)
+	}
+	// The broker being down degrades the service (queued jobs wait in the outbox) but
+	// doesn't make it unready
+	healthHandler.AddOptionalCheck("redis", redisClient.Ping)
+	if cfg.Queue.Broker != queue.BrokerRedis {
+		healthHandler.AddOptionalCheck(cfg.Queue.Broker, jobPublisher.Ping)
+	}
+	router.HandleFunc("/healthz", healthHandler.Liveness).Methods("GET")
+	router.HandleFunc("/readyz", healthHandler.Readiness).Methods("GET")
+	// Kept for existing clients and docker healthchecks
+	router.HandleFunc("/health", healthHandler.Liveness).Methods("GET")
+	// Where Apollo gateways and routers probe their subgraphs
+	router.HandleFunc("/.well-known/apollo/server-health", healthHandler.Liveness).Methods("GET")
+
+	// The build each instance runs, for telling the instances of a rollout apart
+	versionHandler = handlers.NewVersionHandler(buildinfo.Get(), db.MigrationStatus)
+	router.HandleFunc("/version", versionHandler.Version).Methods("GET")
+	if report := versionHandler.Report(context.Background()); report.Migrations != nil && report.Migrations.Status == database.MigrationsPending {
+		log.Printf("WARNING: the database is at migration %q but this build needs %s; run cpctl migrate", report.Migrations.Latest, report.Migrations.Required)
+	}
+
+	// Prometheus metrics
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// The schema for tooling such as GraphQL Codegen: the SDL at /graphql/schema and
+	// introspection queries, unless GRAPHQL_INTROSPECTION turns them off (the production default)
+	if cfg.GraphQL.Introspection {
+		schema, err := introspection.Load(backend.Schema)
+		if err != nil {
+			log.Fatalf("Failed to load the GraphQL schema: %v", err)
+		}
+		introspectionSchema = schema
+		router.Handle("/graphql/schema", middleware.ETag(http.HandlerFunc(serveSchema))).Methods("GET")
+	}
+
+	// GraphiQL, in development only
+	if cfg.GraphQL.Playground {
+		router.HandleFunc("/graphql", handlers.GraphiQL).Methods("GET")
+	}
 
 	// Simple GraphQL endpoint for basic queries
-	router.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+	// Requests are counted by the client application they name, which may be required
+	router.Handle("/graphql", middleware.Client(cfg.GraphQL.RequireClientName)(tenantMiddleware.Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		
-		if r.Method == "GET" {
-			// GraphQL playground HTML
-			playground := `
-<!DOCTYPE html>
-<html>
-<head>
-	<meta charset=utf-8/>
-	<title>GraphQL Playground</title>
-	<link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/graphql-playground-react/build/static/css/index.css"/>
-	<script src="https://cdn.jsdelivr.net/npm/graphql-playground-react/build/static/js/middleware.js"></script>
-</head>
-<body>
-	<div id="root">
-		<style>body { background-color: rgb(23, 42, 58); font-family: Open Sans, sans-serif; height: 90vh; }</style>
-		<div style="color: white; text-align: center; padding: 20px;">
-			<h1>Commute Planner GraphQL API</h1>
-			<p>Send POST requests to this endpoint with GraphQL queries</p>
-			<p>Example query:</p>
-			<pre style="background: #1a1a1a; color: #f8f8f2; padding: 20px; border-radius: 5px; text-align: left; max-width: 500px; margin: 0 auto;">
-{
-  "query": "{ health }"
-}
-			</pre>
-		</div>
-	</div>
-</body>
-</html>`
-			w.Write([]byte(playground))
-			return
-		}
 
-		if r.Method != "POST" {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
+		// The admin token lifts @owner redaction and allows @auth(requires: ADMIN) operations
+		ctx := r.Context()
+		if handlers.HasAdminToken(r, cfg.AdminToken) {
+			ctx = authz.AsAdmin(ctx)
 		}
 
 		var req GraphQLRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid JSON", http.StatusBadRequest)
-			return
-		}
-
-		var response GraphQLResponse
-
-		// Handle basic queries and mutations
-		switch {
-		case req.Query == "{ health }" || req.Query == "query { health }":
-			health, _ := resolver.Health(r.Context())
-			response.Data = map[string]interface{}{"health": health}
-		case req.Query == "{ users }" || req.Query == "{ users { id email name } }" || req.Query == "query { users { id email name } }":
-			users, err := resolver.Users(r.Context())
+		if upload.IsMultipart(r) {
+			// Files are uploaded as multipart forms, per the GraphQL multipart request spec
+			r.Body = http.MaxBytesReader(w, r.Body, cfg.GraphQL.MaxUploadBytes)
+			operations, err := upload.Parse(r)
 			if err != nil {
-				response.Errors = []string{err.Error()}
-			} else {
-				response.Data = map[string]interface{}{"users": users}
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
 			}
-		case strings.Contains(req.Query, "calendarEvents"):
-			// Handle calendarEvents query
-			if req.Variables != nil {
-				if userID, ok := req.Variables["userId"].(string); ok {
-					// Check for optional targetDate parameter
-					var targetDate *string
-					if td, ok := req.Variables["targetDate"].(string); ok {
-						targetDate = &td
-					}
-					
-					events, err := resolver.CalendarEvents(r.Context(), userID, targetDate)
-					if err != nil {
-						response.Errors = []string{err.Error()}
-					} else {
-						// Ensure we always return an array, never null
-						if events == nil {
-							events = []*models.CalendarEvent{}
-						}
-						response.Data = map[string]interface{}{"calendarEvents": events}
-					}
-				} else {
-					response.Errors = []string{"userId variable is required for calendarEvents query"}
-				}
-			} else {
-				response.Errors = []string{"variables are required for calendarEvents query"}
+			// Uploads start work outside the transaction a batch would run in
+			operation, ok := operations.(map[string]interface{})
+			if !ok {
+				http.Error(w, "Uploads can't be sent in a batch", http.StatusBadRequest)
+				return
 			}
-		default:
-			// Handle job mutations
-			if req.Variables != nil {
-				if input, ok := req.Variables["input"].(map[string]interface{}); ok {
-					if userID, exists := input["userId"]; exists {
-						// This is likely a createJob mutation
-						createInput := resolvers.CreateJobInput{
-							UserID:     userID.(string),
-							TargetDate: input["targetDate"].(string),
-						}
-						if inputData, hasInputData := input["inputData"]; hasInputData && inputData != nil {
-							inputDataStr := inputData.(string)
-							createInput.InputData = &inputDataStr
-						}
-						
-						job, err := resolver.CreateJob(r.Context(), createInput)
-						if err != nil {
-							response.Errors = []string{err.Error()}
-						} else {
-							response.Data = map[string]interface{}{"createJob": job}
-						}
-						
-						// Send job to Redis queue for processing
-						if job != nil {
-							jobData := map[string]interface{}{
-								"job_id":      job.ID,
-								"user_id":     job.UserID,
-								"target_date": job.TargetDate,
-								"input_data":  input["inputData"], // Pass original input_data
-							}
-							
-							// Add job to Redis queue
-							if err := resolver.QueueJob(r.Context(), jobData); err != nil {
-								log.Printf("Failed to queue job %s: %v", job.ID, err)
-							} else {
-								log.Printf("Added job %s to Redis queue for processing", job.ID)
-							}
-						}
-						
-						// Return early to prevent "not supported" error
-						json.NewEncoder(w).Encode(response)
-						return
-					}
+			req = graphQLRequestFrom(operation)
+		} else {
+			var body json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			// A JSON array is a batch, run in one transaction
+			if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+				var requests []GraphQLRequest
+				if err := json.Unmarshal(body, &requests); err != nil {
+					http.Error(w, "Invalid JSON", http.StatusBadRequest)
+					return
 				}
-				
-				// Handle updateJob mutation
-				if id, ok := req.Variables["id"].(string); ok {
-					if input, ok := req.Variables["input"].(map[string]interface{}); ok {
-						updateInput := resolvers.UpdateJobInput{}
-						
-						if status, exists := input["status"]; exists && status != nil {
-							statusStr := status.(string)
-							updateInput.Status = &statusStr
-						}
-						if progress, exists := input["progress"]; exists && progress != nil {
-							progressFloat := progress.(float64)
-							updateInput.Progress = &progressFloat
-						}
-						if currentStep, exists := input["currentStep"]; exists && currentStep != nil {
-							currentStepStr := currentStep.(string)
-							updateInput.CurrentStep = &currentStepStr
-						}
-						if result, exists := input["result"]; exists && result != nil {
-							resultStr := result.(string)
-							updateInput.Result = &resultStr
-						}
-						if errorMessage, exists := input["errorMessage"]; exists && errorMessage != nil {
-							errorMessageStr := errorMessage.(string)
-							updateInput.ErrorMessage = &errorMessageStr
-						}
-						
-						job, err := resolver.UpdateJob(r.Context(), id, updateInput)
-						if err != nil {
-							response.Errors = []string{err.Error()}
-						} else {
-							response.Data = map[string]interface{}{"updateJob": job}
-						}
-						
-						// Return early to prevent "not supported" error
-						json.NewEncoder(w).Encode(response)
-						return
-					}
+				responses := executeGraphQLBatch(ctx, db, resolver, requests)
+				extensions := graphQLExtensions(ctx)
+				for i := range responses {
+					responses[i].Errors = errorRedactor.Strings(responses[i].Errors)
+					responses[i].Extensions = extensions
 				}
+				json.NewEncoder(w).Encode(responses)
+				return
 			}
-			response.Errors = []string{"Query not supported in this basic implementation. Try: { health } or { users { id email name } } or createJob/updateJob mutations"}
+
+			if err := json.Unmarshal(body, &req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+		}
+		response, created := executeGraphQL(ctx, resolver, req)
+		if created != nil {
+			queueJob(ctx, resolver, created)
 		}
+		response.Extensions = graphQLExtensions(ctx)
+		if err := writeGraphQLResponse(w, response); err != nil {
+			log.Printf("Failed to write GraphQL response: %v", err)
+		}
+	})))).Methods("POST")
 
-		json.NewEncoder(w).Encode(response)
-	}).Methods("GET", "POST")
+	// Subscriptions, streamed as server-sent events
+	resolver.StreamPlansWith(planFeed)
+	router.Handle("/graphql/stream", middleware.Client(cfg.GraphQL.RequireClientName)(tenantMiddleware.Require(serveSubscription(resolver, cfg.AdminToken)))).Methods("POST")
 
-	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowCredentials: true,
-		AllowedHeaders:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+	// The frontend takes every path the API doesn't, so it must be registered last
+	if cfg.Frontend.Enabled {
+		var assets fs.FS
+		if cfg.Frontend.Dir != "" {
+			assets = os.DirFS(cfg.Frontend.Dir)
+		} else {
+			embedded, ok := webui.Embedded()
+			if !ok {
+				log.Fatalf("SERVE_FRONTEND needs FRONTEND_DIR or a binary built with -tags embedui")
+			}
+			assets = embedded
+		}
+		router.PathPrefix("/").Handler(webui.Handler(assets))
+		log.Printf("Serving the frontend at /")
+	}
+
+	corsMiddleware, err := middleware.NewCORS(middleware.CORSOptions{
+		AllowedOrigins:        cfg.CORS.AllowedOrigins,
+		AllowedOriginPatterns: cfg.CORS.AllowedOriginPatterns,
+		AllowedHeaders:        cfg.CORS.AllowedHeaders,
+		AllowedMethods:        cfg.CORS.AllowedMethods,
+		AllowCredentials:      cfg.CORS.AllowCredentials,
+		MaxAge:                cfg.CORS.MaxAge,
 	})
+	if err != nil {
+		log.Fatalf("Invalid CORS configuration: %v", err)
+	}
 
-	handler := c.Handler(router)
+	// Streamed exports run as long as the client keeps reading, profiles as long as asked
+	// and subscriptions until the client disconnects
+	var handler http.Handler = middleware.Timeout(cfg.RequestTimeout, "/export/", "/debug/pprof/", "/graphql/stream")(router)
+	handler = middleware.RedactErrors(errorRedactor)(handler)
+	if cfg.Compression.Enabled {
+		handler = middleware.Compress(cfg.Compression.MinSize)(handler)
+	}
+	if cfg.Security.CSRFProtection {
+		// The frontend's origins may send state-changing requests like the API's own
+		csrf, err := middleware.NewCSRF(middleware.CSRFOptions{
+			TrustedOrigins:        cfg.CORS.AllowedOrigins,
+			TrustedOriginPatterns: cfg.CORS.AllowedOriginPatterns,
+		})
+		if err != nil {
+			log.Fatalf("Invalid CSRF configuration: %v (restrict the CORS origins or set CSRF_PROTECTION=false)", err)
+		}
+		handler = csrf(handler)
+	}
+	handler = corsMiddleware(handler)
+	handler = middleware.SecurityHeaders(middleware.SecurityHeadersOptions{
+		HSTSMaxAge:            cfg.Security.HSTSMaxAge,
+		HSTSIncludeSubdomains: cfg.Security.HSTSIncludeSubdomains,
+		FrameOptions:          cfg.Security.FrameOptions,
+		ContentSecurityPolicy: cfg.Security.ContentSecurityPolicy,
+		ReferrerPolicy:        cfg.Security.ReferrerPolicy,
+	})(handler)
 
-	log.Printf("Connect to http://localhost:%s/ for GraphQL playground", cfg.Port)
-	log.Printf("Health check available at http://localhost:%s/health", cfg.Port)
-	log.Fatal(http.ListenAndServe(":"+cfg.Port, handler))
+	scheme := "http"
+	if cfg.TLS.CertFile != "" || len(cfg.TLS.AutocertDomains) > 0 {
+		scheme = "https"
+	}
+	if cfg.GraphQL.Playground {
+		log.Printf("Connect to %s://localhost:%s/graphql for GraphiQL", scheme, cfg.Port)
+	}
+	log.Printf("Health checks available at %s://localhost:%s/healthz and /readyz", scheme, cfg.Port)
+	log.Fatal(serve(cfg, handler))
 }
\ No newline at end of file
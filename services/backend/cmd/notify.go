@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/commute-planner/backend/internal/config"
+	"github.com/commute-planner/backend/pkg/notify"
+)
+
+// emailNotifier builds the notifier for user emails from config: the SMTP relay, a
+// logger in development without one, or nil when emails can't be sent
+func emailNotifier(cfg *config.Config) (notify.Notifier, error) {
+	if cfg.Email.SMTPHost == "" {
+		if cfg.Environment == "production" {
+			return nil, nil
+		}
+		return notify.LogNotifier{}, nil
+	}
+	smtp, err := notify.NewSMTP(notify.SMTPConfig{
+		Host:     cfg.Email.SMTPHost,
+		Port:     cfg.Email.SMTPPort,
+		Username: cfg.Email.SMTPUsername,
+		Password: cfg.Email.SMTPPassword,
+		From:     cfg.Email.From,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return smtp, nil
+}
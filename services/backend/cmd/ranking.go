@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/commute-planner/backend/internal/config"
+	"github.com/commute-planner/backend/pkg/planner"
+)
+
+// rankerSelector builds the ranker selection from config, or nil when every job keeps
+// the AI service's order
+func rankerSelector(cfg config.RankingConfig) (*planner.Selector, error) {
+	byTenant, err := planner.ParseMapping(cfg.ByTenant)
+	if err != nil {
+		return nil, err
+	}
+	byVariant, err := planner.ParseMapping(cfg.ByVariant)
+	if err != nil {
+		return nil, err
+	}
+	selector := &planner.Selector{Default: cfg.Default, ByTenant: byTenant, ByVariant: byVariant}
+	if err := selector.Validate(); err != nil {
+		return nil, err
+	}
+	if (cfg.Default == "" || cfg.Default == planner.RankerPlanner) && len(byTenant) == 0 && len(byVariant) == 0 {
+		return nil, nil
+	}
+	return selector, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/commute-planner/backend/internal/config"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// serve runs the API on cfg.Port until it fails. With TLS configured it serves HTTPS,
+// negotiating HTTP/2 with clients that support it; otherwise plain HTTP, optionally with
+// cleartext HTTP/2.
+func serve(cfg *config.Config, handler http.Handler) error {
+	tlsCfg := cfg.TLS
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	switch {
+	case len(tlsCfg.AutocertDomains) > 0:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(tlsCfg.AutocertDomains...),
+			Cache:      autocert.DirCache(tlsCfg.AutocertCacheDir),
+			Email:      tlsCfg.AutocertEmail,
+		}
+		// Offers h2 and the ACME TLS-ALPN challenge protocol
+		srv.TLSConfig = manager.TLSConfig()
+		if tlsCfg.HTTPPort != "" {
+			go serveRedirect(tlsCfg.HTTPPort, manager.HTTPHandler(httpsRedirect(cfg.Port)))
+		}
+		log.Printf("Serving HTTPS on :%s with Let's Encrypt certificates for %v", cfg.Port, tlsCfg.AutocertDomains)
+		return srv.ListenAndServeTLS("", "")
+	case tlsCfg.CertFile != "" || tlsCfg.KeyFile != "":
+		if tlsCfg.CertFile == "" || tlsCfg.KeyFile == "" {
+			return errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		}
+		if tlsCfg.HTTPPort != "" {
+			go serveRedirect(tlsCfg.HTTPPort, httpsRedirect(cfg.Port))
+		}
+		log.Printf("Serving HTTPS on :%s", cfg.Port)
+		return srv.ListenAndServeTLS(tlsCfg.CertFile, tlsCfg.KeyFile)
+	}
+
+	if tlsCfg.H2C {
+		srv.Handler = h2c.NewHandler(handler, &http2.Server{})
+		log.Printf("Serving HTTP on :%s with cleartext HTTP/2", cfg.Port)
+	}
+	return srv.ListenAndServe()
+}
+
+// serveRedirect serves plain HTTP next to the HTTPS server
+func serveRedirect(port string, handler http.Handler) {
+	srv := &http.Server{Addr: ":" + port, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	if err := srv.ListenAndServe(); err != nil {
+		log.Printf("HTTP redirect server on :%s stopped: %v", port, err)
+	}
+}
+
+// httpsRedirect sends requests to the same URL over HTTPS on httpsPort
+func httpsRedirect(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/clientinfo"
+	"github.com/commute-planner/backend/pkg/handlers"
+	"github.com/commute-planner/backend/pkg/resolvers"
+)
+
+// subscriptionKeepAlive is how often an idle subscription stream sends a comment, so
+// proxies and load balancers don't close it
+const subscriptionKeepAlive = 15 * time.Second
+
+// serveSubscription runs a subscription over server-sent events, per the GraphQL over
+// SSE protocol's distinct connections mode: the operation is POSTed like to /graphql,
+// each result is sent as a "next" event and a "complete" event ends the stream. The
+// stream lasts until the client disconnects. Only myPlans is served.
+func serveSubscription(resolver *resolvers.Resolver, adminToken string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if handlers.HasAdminToken(r, adminToken) {
+			ctx = authz.AsAdmin(ctx)
+		}
+		var req GraphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if clientUsageTracker != nil {
+			clientUsageTracker.Track(clientinfo.FromContext(ctx), operationName(req))
+		}
+
+		viewer := graphQLViewer(ctx)
+		op, err := authorization.Authorize(req.Query, viewer)
+		switch {
+		case err != nil:
+		case op.Root != "Subscription":
+			err = errors.New("only subscriptions are served at /graphql/stream; send queries and mutations to /graphql")
+		case !op.Has("myPlans"):
+			err = errors.New("subscription not supported in this basic implementation. Try: subscription { myPlans { kind jobId targetDate } }")
+		}
+		if err != nil {
+			writeSubscriptionError(w, http.StatusBadRequest, err)
+			return
+		}
+		if deprecationTracker != nil {
+			deprecationTracker.Track(clientinfo.FromContext(ctx), req.Query)
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeSubscriptionError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+			return
+		}
+		updates, cancel, err := resolver.MyPlans(ctx, viewer.UserID)
+		if err != nil {
+			writeSubscriptionError(w, http.StatusBadRequest, err)
+			return
+		}
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		// Stops nginx buffering the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(subscriptionKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-keepAlive.C:
+				if _, err := io.WriteString(w, ":\n\n"); err != nil {
+					return
+				}
+			case update, open := <-updates:
+				if !open {
+					io.WriteString(w, "event: complete\ndata:\n\n")
+					flusher.Flush()
+					return
+				}
+				data, err := json.Marshal(GraphQLResponse{Data: map[string]interface{}{"myPlans": update}})
+				if err != nil {
+					log.Printf("Failed to encode a plan update: %v", err)
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: next\ndata: %s\n\n", data); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	})
+}
+
+// writeSubscriptionError answers a subscription that can't start with a GraphQL error
+func writeSubscriptionError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(GraphQLResponse{Errors: errorRedactor.Strings([]string{err.Error()})})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/commute-planner/backend/internal/config"
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/commute-planner/backend/pkg/queue/rabbitmq"
+	"github.com/commute-planner/backend/pkg/redis"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/synthetic"
+)
+
+// syntheticConsumerGroup is the AI service's consumer group, so synthetic workers never
+// process a job an AI worker already took
+const syntheticConsumerGroup = "commute_workers"
+
+// startSyntheticWorkers runs cfg.SyntheticWorker.Concurrency workers completing jobs in
+// place of the AI service, each with its own consumer on the configured broker
+func startSyntheticWorkers(ctx context.Context, cfg *config.Config, repos repository.Repositories, redisClient *redis.Client) error {
+	if cfg.SyntheticWorker.Concurrency <= 0 {
+		return fmt.Errorf("SYNTHETIC_WORKER_CONCURRENCY must be positive")
+	}
+	worker := synthetic.NewWorker(repos, synthetic.Config{
+		Latency:     cfg.SyntheticWorker.Latency,
+		Jitter:      cfg.SyntheticWorker.Jitter,
+		FailureRate: cfg.SyntheticWorker.FailureRate,
+	})
+	for i := 0; i < cfg.SyntheticWorker.Concurrency; i++ {
+		name := fmt.Sprintf("synthetic-%d", i+1)
+		var consumer queue.Consumer
+		var err error
+		switch {
+		case cfg.Queue.Broker == queue.BrokerRabbitMQ:
+			consumer, err = rabbitmq.NewConsumer(rabbitmq.ConsumerConfig{
+				Config: rabbitmq.Config{URL: cfg.Queue.RabbitMQURL, Queue: cfg.Queue.RabbitMQQueue},
+				Name:   name,
+			})
+		case cfg.Redis.QueueMode == redis.QueueModeStream:
+			consumer, err = redis.NewStreamConsumer(ctx, redisClient, redis.ConsumerConfig{
+				Group:    syntheticConsumerGroup,
+				Consumer: name,
+			})
+		default:
+			return fmt.Errorf("the synthetic worker needs REDIS_QUEUE_MODE=stream or QUEUE_BROKER=rabbitmq")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to start synthetic worker: %w", err)
+		}
+		go worker.Run(ctx, consumer)
+	}
+	log.Printf("Synthetic workers are completing jobs (%d at a time, %v ± %v each)",
+		cfg.SyntheticWorker.Concurrency, cfg.SyntheticWorker.Latency, cfg.SyntheticWorker.Jitter)
+	return nil
+}
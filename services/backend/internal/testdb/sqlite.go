@@ -0,0 +1,34 @@
+//go:build sqlite
+
+package testdb
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+)
+
+// Open returns a SQLite database in the test's temporary directory, migrated by the
+// database package like a local development database
+func Open(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.NewConnection(database.Config{
+		Driver: database.DriverSQLite,
+		URL:    filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Backdate sets a row's updated_at
+func Backdate(t *testing.T, db *database.DB, table, id string, at time.Time) {
+	t.Helper()
+	if _, err := db.Exec(`UPDATE `+table+` SET updated_at = $1 WHERE id = $2`, at.UTC(), id); err != nil {
+		t.Fatal(err)
+	}
+}
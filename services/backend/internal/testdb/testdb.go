@@ -0,0 +1,15 @@
+// Package testdb gives tests a database with the current schema, so repository code is
+// tested against real SQL rather than the in-memory fakes.
+package testdb
+
+import (
+	"path/filepath"
+	"runtime"
+)
+
+// schemaDir is the repository's database directory, found relative to this file so tests
+// can run from any package
+func schemaDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "..", "database")
+}
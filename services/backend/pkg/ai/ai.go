@@ -0,0 +1,146 @@
+// Package ai talks to large language model providers. It lets the backend generate
+// recommendation text itself instead of relying solely on the Python AI service.
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/breaker"
+)
+
+// Supported providers, selected with AI_PROVIDER
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
+// Message roles
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a conversation
+type Message struct {
+	Role    string
+	Content string
+}
+
+// CompletionRequest is a provider-neutral chat completion request
+type CompletionRequest struct {
+	// System is the system prompt; providers that lack one get it as a leading message
+	System   string
+	Messages []Message
+	// MaxTokens bounds the reply. 0 uses 1024.
+	MaxTokens   int
+	Temperature float64
+	// JSON asks the provider to reply with a JSON object, where supported. The prompt
+	// should still ask for JSON, since not every provider enforces it.
+	JSON bool
+}
+
+// LLMClient completes a conversation with a language model
+type LLMClient interface {
+	Complete(ctx context.Context, req CompletionRequest) (string, error)
+	// Provider names the backing provider, for logs
+	Provider() string
+}
+
+// Config selects and configures a provider
+type Config struct {
+	Provider string
+	// Model is provider specific; empty uses a small, cheap default
+	Model  string
+	APIKey string
+	// BaseURL overrides the API endpoint, e.g. for a proxy or a remote Ollama host
+	BaseURL string
+	Timeout time.Duration
+	// CircuitBreaker, when set, fails requests fast while the provider is failing; the
+	// AI service's text is kept in the meantime
+	CircuitBreaker *breaker.Config
+}
+
+// NewClient creates the client for cfg.Provider
+func NewClient(cfg Config) (LLMClient, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	httpClient := &http.Client{Timeout: cfg.Timeout}
+	if cfg.CircuitBreaker != nil {
+		httpClient.Transport = breaker.Transport(breaker.New("ai_"+cfg.Provider, *cfg.CircuitBreaker), nil)
+	}
+
+	switch cfg.Provider {
+	case ProviderOpenAI:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("the openai provider requires an API key")
+		}
+		return newOpenAIClient(cfg, httpClient), nil
+	case ProviderAnthropic:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("the anthropic provider requires an API key")
+		}
+		return newAnthropicClient(cfg, httpClient), nil
+	case ProviderOllama:
+		return newOllamaClient(cfg, httpClient), nil
+	default:
+		return nil, fmt.Errorf("unsupported AI provider %q", cfg.Provider)
+	}
+}
+
+// APIError is a non-2xx response from a provider
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API returned %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// postJSON sends body to url and decodes the response into out
+func postJSON(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &APIError{Provider: provider, StatusCode: resp.StatusCode, Body: string(data)}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", provider, err)
+	}
+	return nil
+}
+
+func maxTokens(req CompletionRequest) int {
+	if req.MaxTokens > 0 {
+		return req.MaxTokens
+	}
+	return 1024
+}
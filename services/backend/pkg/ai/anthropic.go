@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	defaultAnthropicModel   = "claude-3-5-haiku-latest"
+	anthropicVersion        = "2023-06-01"
+)
+
+// anthropicClient uses the Messages API
+type anthropicClient struct {
+	baseURL string
+	model   string
+	apiKey  string
+	http    *http.Client
+}
+
+func newAnthropicClient(cfg Config, httpClient *http.Client) *anthropicClient {
+	c := &anthropicClient{baseURL: defaultAnthropicBaseURL, model: defaultAnthropicModel, apiKey: cfg.APIKey, http: httpClient}
+	if cfg.BaseURL != "" {
+		c.baseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	if cfg.Model != "" {
+		c.model = cfg.Model
+	}
+	return c
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+func (c *anthropicClient) Provider() string {
+	return ProviderAnthropic
+}
+
+// Complete ignores req.JSON; the Messages API has no JSON mode, so the prompt has to ask
+// for it
+func (c *anthropicClient) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	body := anthropicRequest{
+		Model:       c.model,
+		System:      req.System,
+		MaxTokens:   maxTokens(req),
+		Temperature: req.Temperature,
+	}
+	for _, m := range req.Messages {
+		body.Messages = append(body.Messages, anthropicMessage{Role: m.Role, Content: m.Content})
+	}
+
+	var resp anthropicResponse
+	headers := map[string]string{
+		"x-api-key":         c.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+	if err := postJSON(ctx, c.http, ProviderAnthropic, c.baseURL+"/messages", headers, body, &resp); err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return text.String(), nil
+}
@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// Perception is the perception analysis stored on a recommendation, in the same shape the
+// Python option presenter writes
+type Perception struct {
+	ProfessionalImpact string `json:"professional_impact"`
+	Reasoning          string `json:"reasoning"`
+	TeamVisibility     string `json:"team_visibility"`
+}
+
+// Explanation is the generated text of one recommendation
+type Explanation struct {
+	Reasoning  string     `json:"reasoning"`
+	Perception Perception `json:"perception_analysis"`
+}
+
+// PerceptionJSON encodes the perception analysis for the perception_analysis column
+func (e *Explanation) PerceptionJSON() (string, error) {
+	data, err := json.Marshal(e.Perception)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Explainer writes the reasoning and perception analysis of commute recommendations
+type Explainer struct {
+	client LLMClient
+}
+
+// NewExplainer creates an explainer backed by client
+func NewExplainer(client LLMClient) *Explainer {
+	return &Explainer{client: client}
+}
+
+// Provider names the provider behind the explainer
+func (e *Explainer) Provider() string {
+	return e.client.Provider()
+}
+
+const explainSystemPrompt = `You explain hybrid-work commute recommendations to the employee they were made for.
+Reply with a JSON object only, with these fields:
+  "reasoning": two to four sentences on why this option ranks where it does and what it trades off,
+  "perception_analysis": {
+    "professional_impact": one of "VERY_POSITIVE", "POSITIVE", "NEUTRAL_TO_POSITIVE", "NEUTRAL", "NEGATIVE",
+    "reasoning": one sentence on how the choice is likely to be perceived by colleagues and leadership,
+    "team_visibility": one of "HIGH", "MEDIUM", "LOW"
+  }
+Only use the facts given. Be concrete and neutral; don't invent meetings or people.`
+
+// Explain generates the text for one recommendation of job
+func (e *Explainer) Explain(ctx context.Context, job *models.Job, rec *models.CommuteRecommendation) (*Explanation, error) {
+	reply, err := e.client.Complete(ctx, CompletionRequest{
+		System:      explainSystemPrompt,
+		Messages:    []Message{{Role: RoleUser, Content: describeRecommendation(job, rec)}},
+		MaxTokens:   600,
+		Temperature: 0.3,
+		JSON:        true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var explanation Explanation
+	if err := json.Unmarshal([]byte(extractJSON(reply)), &explanation); err != nil {
+		return nil, fmt.Errorf("%s returned an invalid explanation: %w", e.client.Provider(), err)
+	}
+	if explanation.Reasoning == "" {
+		return nil, fmt.Errorf("%s returned an explanation without reasoning", e.client.Provider())
+	}
+	return &explanation, nil
+}
+
+// describeRecommendation renders the facts of a recommendation as the prompt
+func describeRecommendation(job *models.Job, rec *models.CommuteRecommendation) string {
+	var b strings.Builder
+	day := job.TargetDate
+	if len(day) > len("2006-01-02") {
+		day = day[:len("2006-01-02")]
+	}
+	fmt.Fprintf(&b, "Day: %s\n", day)
+	fmt.Fprintf(&b, "Option rank: %d\n", rec.OptionRank)
+	fmt.Fprintf(&b, "Option type: %s\n", rec.OptionType)
+
+	writeTime := func(label string, t *time.Time) {
+		if t != nil {
+			fmt.Fprintf(&b, "%s: %s\n", label, t.Format("15:04"))
+		}
+	}
+	writeTime("Leave home", rec.CommuteStart)
+	writeTime("Arrive at office", rec.OfficeArrival)
+	writeTime("Leave office", rec.OfficeDeparture)
+	writeTime("Arrive home", rec.CommuteEnd)
+
+	writeText := func(label string, s *string) {
+		// JSON columns hold "[]" when there is nothing to list
+		if s != nil && *s != "" && *s != "[]" {
+			fmt.Fprintf(&b, "%s: %s\n", label, *s)
+		}
+	}
+	writeText("Time in office", rec.OfficeDuration)
+	writeText("Meetings attended in the office", rec.OfficeMeetings)
+	writeText("Meetings attended remotely", rec.RemoteMeetings)
+	writeText("Meetings attended in person away from the office", rec.OffsiteMeetings)
+	writeText("Travel legs", rec.TravelLegs)
+	if rec.TravelMode != nil {
+		fmt.Fprintf(&b, "Travel mode: %s\n", *rec.TravelMode)
+	}
+	writeText("Travel modes compared", rec.ModeOptions)
+	writeText("Office policy compliance", rec.BusinessRuleCompliance)
+	writeText("Trade-offs", rec.TradeOffs)
+	return b.String()
+}
+
+// extractJSON trims prose or code fences some models put around a JSON reply
+func extractJSON(reply string) string {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return reply
+	}
+	return reply[start : end+1]
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOllamaBaseURL = "http://localhost:11434"
+	defaultOllamaModel   = "llama3.1"
+)
+
+// ollamaClient uses a local Ollama server's chat API, for deployments that must keep
+// calendar data on their own hardware
+type ollamaClient struct {
+	baseURL string
+	model   string
+	http    *http.Client
+}
+
+func newOllamaClient(cfg Config, httpClient *http.Client) *ollamaClient {
+	c := &ollamaClient{baseURL: defaultOllamaBaseURL, model: defaultOllamaModel, http: httpClient}
+	if cfg.BaseURL != "" {
+		c.baseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	if cfg.Model != "" {
+		c.model = cfg.Model
+	}
+	return c
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Format   string                 `json:"format,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message ollamaMessage `json:"message"`
+}
+
+func (c *ollamaClient) Provider() string {
+	return ProviderOllama
+}
+
+func (c *ollamaClient) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	body := ollamaRequest{
+		Model: c.model,
+		Options: map[string]interface{}{
+			"num_predict": maxTokens(req),
+			"temperature": req.Temperature,
+		},
+	}
+	if req.System != "" {
+		body.Messages = append(body.Messages, ollamaMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		body.Messages = append(body.Messages, ollamaMessage{Role: m.Role, Content: m.Content})
+	}
+	if req.JSON {
+		body.Format = "json"
+	}
+
+	var resp ollamaResponse
+	if err := postJSON(ctx, c.http, ProviderOllama, c.baseURL+"/api/chat", nil, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Message.Content, nil
+}
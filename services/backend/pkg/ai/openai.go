@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "gpt-4o-mini"
+)
+
+// openAIClient uses the chat completions API, which OpenAI-compatible gateways also serve
+type openAIClient struct {
+	baseURL string
+	model   string
+	apiKey  string
+	http    *http.Client
+}
+
+func newOpenAIClient(cfg Config, httpClient *http.Client) *openAIClient {
+	c := &openAIClient{baseURL: defaultOpenAIBaseURL, model: defaultOpenAIModel, apiKey: cfg.APIKey, http: httpClient}
+	if cfg.BaseURL != "" {
+		c.baseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	if cfg.Model != "" {
+		c.model = cfg.Model
+	}
+	return c
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model          string            `json:"model"`
+	Messages       []openAIMessage   `json:"messages"`
+	MaxTokens      int               `json:"max_tokens"`
+	Temperature    float64           `json:"temperature"`
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (c *openAIClient) Provider() string {
+	return ProviderOpenAI
+}
+
+func (c *openAIClient) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	body := openAIRequest{
+		Model:       c.model,
+		MaxTokens:   maxTokens(req),
+		Temperature: req.Temperature,
+	}
+	if req.System != "" {
+		body.Messages = append(body.Messages, openAIMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		body.Messages = append(body.Messages, openAIMessage{Role: m.Role, Content: m.Content})
+	}
+	if req.JSON {
+		body.ResponseFormat = map[string]string{"type": "json_object"}
+	}
+
+	var resp openAIResponse
+	headers := map[string]string{"Authorization": "Bearer " + c.apiKey}
+	if err := postJSON(ctx, c.http, ProviderOpenAI, c.baseURL+"/chat/completions", headers, body, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("openai returned no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,152 @@
+// Package allday interprets calendar entries that mark a day rather than take up time in
+// it: out-of-office and vacation days, days blocked for focus or working from home, and
+// informational all-day entries such as birthdays or another country's holidays. Markers
+// aren't meetings; they decide whether the day needs a commute at all.
+package allday
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// Kind is what a marker says about its day
+type Kind string
+
+const (
+	// OutOfOffice days aren't worked: no commute is planned
+	OutOfOffice Kind = "OUT_OF_OFFICE"
+	// Focus days are blocked for heads-down work, best done without a commute
+	Focus Kind = "FOCUS"
+	// Remote days are announced as worked from home
+	Remote Kind = "REMOTE"
+	// Info entries don't change the plan
+	Info Kind = "INFO"
+)
+
+// Status is the plan a day's markers call for
+type Status string
+
+const (
+	// StatusSkip means the user is out: no commute is planned
+	StatusSkip Status = "SKIP"
+	// StatusRemote means the day is planned fully remote
+	StatusRemote Status = "REMOTE"
+)
+
+// MaxSpan is the longest marker looked back for: a vacation that started up to this long
+// before a day can still cover it
+const MaxSpan = 31 * 24 * time.Hour
+
+// fullDay is how long a timed entry must last to mark the day, e.g. a vacation synced as
+// 00:00-23:59 or an "OOO" block over working hours
+const fullDay = 8 * time.Hour
+
+var (
+	outOfOfficePattern = regexp.MustCompile(`(?i)(\bo\.?o\.?o\b|\bout of (the )?office\b|\bvacation\b|\bholidays?\b|\bannual leave\b|\b(parental|maternity|paternity|sick|medical|bereavement) leave\b|\bon leave\b|\bpto\b|\btime off\b|\bday off\b|\b(off )?sick\b|\btravell?ing\b)`)
+	focusPattern       = regexp.MustCompile(`(?i)(\bfocus\b|\bdeep work\b|\bheads?[- ]down\b|\bno meetings\b|\bdo not (book|disturb)\b|\bmaker time\b)`)
+	remotePattern      = regexp.MustCompile(`(?i)(\bwfh\b|\bwork(ing)? (from|at) home\b|\bremote( day| work)?\b|\bhome office\b)`)
+	// otherHolidayPattern is a holiday observed elsewhere, e.g. "Holiday in United Kingdom"
+	// from a shared holidays calendar, which doesn't keep the user off work
+	otherHolidayPattern = regexp.MustCompile(`(?i)\bholiday in\b|\bobserved in\b`)
+)
+
+// Classify returns what event marks its day as, or "" for an ordinary event that takes up
+// its time. All-day entries are always markers; timed ones only when they span a working
+// day and read as out of office.
+func Classify(event *models.CalendarEvent) Kind {
+	text := event.Summary
+	if event.Description != nil {
+		text += "\n" + *event.Description
+	}
+	if !event.IsAllDay {
+		if event.EndTime.Sub(event.StartTime) >= fullDay && outOfOfficePattern.MatchString(text) {
+			return OutOfOffice
+		}
+		return ""
+	}
+
+	// The title decides; the description only when the title says nothing
+	for _, candidate := range []string{event.Summary, text} {
+		switch {
+		case otherHolidayPattern.MatchString(candidate):
+			return Info
+		case outOfOfficePattern.MatchString(candidate):
+			return OutOfOffice
+		case focusPattern.MatchString(candidate):
+			return Focus
+		case remotePattern.MatchString(candidate):
+			return Remote
+		}
+	}
+	return Info
+}
+
+// Busy reports whether event takes up its time, i.e. is a meeting rather than a marker
+func Busy(event *models.CalendarEvent) bool {
+	return Classify(event) == ""
+}
+
+// Marker is a calendar entry that marks the day
+type Marker struct {
+	EventID string `json:"eventId"`
+	Summary string `json:"summary"`
+	Kind    Kind   `json:"kind"`
+}
+
+// Day is what a day's markers mean for its plan
+type Day struct {
+	// Status is empty when the markers don't change the plan
+	Status  Status   `json:"status,omitempty"`
+	Reason  string   `json:"reason,omitempty"`
+	Markers []Marker `json:"markers"`
+}
+
+// Covers reports whether event falls on date (midnight UTC) in location. All-day events
+// are stored from midnight UTC of their first day to midnight UTC after their last, and
+// cover those dates wherever the user is; timed ones cover the local days they overlap.
+func Covers(event *models.CalendarEvent, date time.Time, location *time.Location) bool {
+	if event.IsAllDay {
+		end := event.EndTime
+		if !end.After(event.StartTime) {
+			end = event.StartTime.Add(24 * time.Hour)
+		}
+		return !date.Before(event.StartTime.UTC().Truncate(24*time.Hour)) && date.Before(end)
+	}
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+	return event.StartTime.Before(start.AddDate(0, 0, 1)) && event.EndTime.After(start)
+}
+
+// Plan reads the markers among events that cover date (midnight UTC) in location. Out of
+// office wins over focus and remote days; nil when the day has no markers.
+func Plan(events []*models.CalendarEvent, date time.Time, location *time.Location) *Day {
+	var day Day
+	var out, home []string
+	for _, event := range events {
+		kind := Classify(event)
+		if kind == "" || !Covers(event, date, location) {
+			continue
+		}
+		day.Markers = append(day.Markers, Marker{EventID: event.ID, Summary: event.Summary, Kind: kind})
+		switch kind {
+		case OutOfOffice:
+			out = append(out, event.Summary)
+		case Focus, Remote:
+			home = append(home, event.Summary)
+		}
+	}
+	if len(day.Markers) == 0 {
+		return nil
+	}
+	switch {
+	case len(out) > 0:
+		day.Status = StatusSkip
+		day.Reason = "Out of office: " + strings.Join(out, ", ")
+	case len(home) > 0:
+		day.Status = StatusRemote
+		day.Reason = "Blocked for remote work: " + strings.Join(home, ", ")
+	}
+	return &day
+}
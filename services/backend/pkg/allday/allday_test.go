@@ -0,0 +1,105 @@
+package allday
+
+import (
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func allDay(summary string) *models.CalendarEvent {
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	return &models.CalendarEvent{ID: summary, Summary: summary, StartTime: start, EndTime: start.AddDate(0, 0, 1), IsAllDay: true}
+}
+
+func timed(summary string, hours int) *models.CalendarEvent {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	return &models.CalendarEvent{ID: summary, Summary: summary, StartTime: start, EndTime: start.Add(time.Duration(hours) * time.Hour)}
+}
+
+func TestClassify(t *testing.T) {
+	description := "Working from home to finish the quarterly plan"
+	described := allDay("Q2 planning")
+	described.Description = &description
+
+	for _, tc := range []struct {
+		event *models.CalendarEvent
+		want  Kind
+	}{
+		{allDay("OOO"), OutOfOffice},
+		{allDay("Vacation in Lisbon"), OutOfOffice},
+		{allDay("Annual leave"), OutOfOffice},
+		{allDay("PTO"), OutOfOffice},
+		{allDay("Holiday in United Kingdom"), Info},
+		{allDay("Focus day"), Focus},
+		{allDay("Heads-down: no meetings"), Focus},
+		{allDay("WFH"), Remote},
+		{described, Remote},
+		{allDay("Ada's birthday"), Info},
+		{timed("Out of office", 9), OutOfOffice},
+		{timed("Out of office", 2), ""},
+		{timed("Focus time", 9), ""},
+		{timed("Standup", 1), ""},
+	} {
+		if got := Classify(tc.event); got != tc.want {
+			t.Errorf("Classify(%q, all day %v) = %q, want %q", tc.event.Summary, tc.event.IsAllDay, got, tc.want)
+		}
+	}
+}
+
+func TestCovers(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	date := func(day int) time.Time { return time.Date(2026, 3, day, 0, 0, 0, 0, time.UTC) }
+	vacation := allDay("Vacation")
+	vacation.EndTime = date(7) // Monday to Friday
+
+	for _, tc := range []struct {
+		name  string
+		event *models.CalendarEvent
+		day   int
+		want  bool
+	}{
+		{"first day of a vacation", vacation, 2, true},
+		{"middle of a vacation", vacation, 4, true},
+		{"last day of a vacation", vacation, 6, true},
+		{"after a vacation", vacation, 7, false},
+		{"before a vacation", vacation, 1, false},
+		{"timed, same day", timed("Out of office", 9), 2, true},
+		{"timed, next day", timed("Out of office", 9), 3, false},
+	} {
+		if got := Covers(tc.event, date(tc.day), newYork); got != tc.want {
+			t.Errorf("%s: Covers = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// 9:00-18:00 UTC on Monday runs from Monday evening into Tuesday in Tokyo: timed
+	// blocks are placed in the user's own days
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	if late := timed("Out of office", 9); !Covers(late, date(3), tokyo) || Covers(late, date(1), tokyo) {
+		t.Error("timed block not placed in the local day")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	plan := func(events ...*models.CalendarEvent) *Day { return Plan(events, monday, time.UTC) }
+
+	if day := plan(timed("Standup", 1)); day != nil {
+		t.Errorf("day without markers = %+v, want nil", day)
+	}
+
+	day := plan(allDay("Ada's birthday"), timed("Standup", 1))
+	if day == nil || day.Status != "" || len(day.Markers) != 1 || day.Markers[0].Kind != Info {
+		t.Errorf("informational marker: %+v", day)
+	}
+
+	day = plan(allDay("Focus day"), timed("Standup", 1))
+	if day == nil || day.Status != StatusRemote || day.Reason != "Blocked for remote work: Focus day" {
+		t.Errorf("focus day: %+v", day)
+	}
+
+	day = plan(allDay("WFH"), allDay("Vacation"), timed("Standup", 1))
+	if day == nil || day.Status != StatusSkip || day.Reason != "Out of office: Vacation" || len(day.Markers) != 2 {
+		t.Errorf("vacation: %+v", day)
+	}
+}
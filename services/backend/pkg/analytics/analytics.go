@@ -0,0 +1,199 @@
+// Package analytics records anonymized product analytics events. Events identify users by
+// a salted hash of their ID, skip users who opted out, and are written to the
+// analytics_events table off the request path; with a sink configured they are then
+// forwarded to Segment or PostHog.
+package analytics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/clientinfo"
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Events recorded
+const (
+	EventPlanRequested    = "plan_requested"
+	EventPlanAccepted     = "plan_accepted"
+	EventOptionRankChosen = "option_rank_chosen"
+	EventDemoGenerated    = "demo_generated"
+)
+
+// Config tunes how events are recorded
+type Config struct {
+	// Salt keys the hash users are identified by; changing it unlinks later events from
+	// earlier ones
+	Salt string
+	// FlushInterval is how often queued events are written and stored ones forwarded
+	FlushInterval time.Duration
+	// QueueSize bounds the events waiting to be written; more are dropped
+	QueueSize int
+	// BatchSize bounds the events forwarded to the sink per request
+	BatchSize int
+}
+
+// queued is an event waiting to be written
+type queued struct {
+	userID     string
+	event      string
+	properties map[string]interface{}
+	at         time.Time
+	// clientName and clientVersion are nil for events not caused by a client request
+	clientName, clientVersion *string
+}
+
+// Tracker records events. Track only queues them, so recording never slows or fails a
+// request; Run writes them.
+type Tracker struct {
+	events repository.AnalyticsEventRepository
+	users  repository.UserRepository
+	sink   Sink
+	cfg    Config
+	queue  chan queued
+	now    func() time.Time
+}
+
+// NewTracker creates a tracker; call Run to start writing. sink may be nil to keep events
+// in the table only.
+func NewTracker(events repository.AnalyticsEventRepository, users repository.UserRepository, sink Sink, cfg Config) *Tracker {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 10 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &Tracker{events: events, users: users, sink: sink, cfg: cfg, queue: make(chan queued, cfg.QueueSize), now: time.Now}
+}
+
+// Track queues an event of the user's. properties must not identify anyone. Inside a
+// transaction the event is queued once it commits, so rolled back actions aren't counted.
+func (t *Tracker) Track(ctx context.Context, userID, event string, properties map[string]interface{}) {
+	q := queued{userID: userID, event: event, properties: properties, at: t.now()}
+	q.clientName, q.clientVersion = clientinfo.Fields(ctx)
+	database.AfterCommit(ctx, func() {
+		select {
+		case t.queue <- q:
+		default:
+			metrics.AnalyticsEvents.WithLabelValues("dropped").Inc()
+		}
+	})
+}
+
+// AnonymousID is the ID the user's events carry
+func (t *Tracker) AnonymousID(userID string) string {
+	mac := hmac.New(sha256.New, []byte(t.cfg.Salt))
+	mac.Write([]byte(userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run writes queued events and forwards stored ones until ctx is cancelled, then writes
+// what is still queued
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			t.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			t.flush(ctx)
+			t.forward(ctx)
+		}
+	}
+}
+
+// flush writes the queued events of users who haven't opted out
+func (t *Tracker) flush(ctx context.Context) {
+	var events []*models.AnalyticsEvent
+	optedOut := map[string]bool{}
+	for {
+		var q queued
+		select {
+		case q = <-t.queue:
+		default:
+			if len(events) == 0 {
+				return
+			}
+			if err := t.events.Create(ctx, events); err != nil {
+				log.Printf("Analytics: failed to write %d events: %v", len(events), err)
+				return
+			}
+			metrics.AnalyticsEvents.WithLabelValues("recorded").Add(float64(len(events)))
+			return
+		}
+
+		out, checked := optedOut[q.userID]
+		if !checked {
+			user, err := t.users.Get(ctx, q.userID)
+			// A user who can't be found can't have agreed to being counted either
+			out = err != nil || user.AnalyticsOptOut
+			optedOut[q.userID] = out
+		}
+		if out {
+			metrics.AnalyticsEvents.WithLabelValues("opted_out").Inc()
+			continue
+		}
+		properties, err := json.Marshal(q.properties)
+		if err != nil || q.properties == nil {
+			properties = []byte("{}")
+		}
+		events = append(events, &models.AnalyticsEvent{
+			Event:         q.event,
+			AnonymousID:   t.AnonymousID(q.userID),
+			Properties:    string(properties),
+			OccurredAt:    q.at,
+			ClientName:    q.clientName,
+			ClientVersion: q.clientVersion,
+		})
+	}
+}
+
+// forward sends stored events to the sink, if there is one, oldest first
+func (t *Tracker) forward(ctx context.Context) {
+	if t.sink == nil {
+		return
+	}
+	for ctx.Err() == nil {
+		events, err := t.events.ListUnforwarded(ctx, t.cfg.BatchSize)
+		if err != nil {
+			log.Printf("Analytics: failed to list events to forward: %v", err)
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+		if err := t.sink.Send(ctx, events); err != nil {
+			metrics.AnalyticsEvents.WithLabelValues("failed").Add(float64(len(events)))
+			log.Printf("Analytics: failed to forward %d events: %v", len(events), err)
+			return
+		}
+		ids := make([]string, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		if err := t.events.MarkForwarded(ctx, ids, t.now()); err != nil {
+			log.Printf("Analytics: forwarded %d events but failed to record it: %v", len(events), err)
+			return
+		}
+		metrics.AnalyticsEvents.WithLabelValues("forwarded").Add(float64(len(events)))
+		if len(events) < t.cfg.BatchSize {
+			return
+		}
+	}
+}
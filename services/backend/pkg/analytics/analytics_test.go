@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/clientinfo"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+type recordingSink struct {
+	batches [][]*models.AnalyticsEvent
+	err     error
+}
+
+func (s *recordingSink) Send(ctx context.Context, events []*models.AnalyticsEvent) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func TestTracker(t *testing.T) {
+	ctx := context.Background()
+	users := repository.NewMemoryUserRepository()
+	store := repository.NewMemoryAnalyticsEventRepository()
+	ada, err := users.Create(ctx, repository.NewUser{Email: "ada@example.com", Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	grace, err := users.Create(ctx, repository.NewUser{Email: "grace@example.com", Name: "Grace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.SetAnalyticsOptOut(ctx, grace.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &recordingSink{err: errors.New("unavailable")}
+	tracker := NewTracker(store, users, sink, Config{Salt: "pepper", QueueSize: 3, BatchSize: 2})
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	web := clientinfo.WithClient(ctx, clientinfo.Client{Name: "web", Version: "2.1.0"})
+	tracker.Track(web, ada.ID, EventPlanRequested, map[string]interface{}{"priority": "INTERACTIVE"})
+	tracker.Track(ctx, grace.ID, EventPlanRequested, nil)
+	tracker.Track(ctx, ada.ID, EventOptionRankChosen, map[string]interface{}{"rank": 2})
+	// The queue is full, so this one is dropped rather than blocking
+	tracker.Track(ctx, ada.ID, EventPlanAccepted, nil)
+	tracker.flush(ctx)
+
+	stored, err := store.ListUnforwarded(ctx, 10)
+	if err != nil || len(stored) != 2 {
+		t.Fatalf("stored = %+v, %v, want ada's two events", stored, err)
+	}
+	anonymousID := tracker.AnonymousID(ada.ID)
+	if anonymousID == ada.ID || len(anonymousID) != 64 {
+		t.Errorf("anonymous ID = %q", anonymousID)
+	}
+	for _, event := range stored {
+		if event.AnonymousID != anonymousID || !event.OccurredAt.Equal(now) {
+			t.Errorf("event = %+v", event)
+		}
+	}
+	if stored[0].Properties != `{"priority":"INTERACTIVE"}` || stored[1].Properties != `{"rank":2}` {
+		t.Errorf("properties = %s, %s", stored[0].Properties, stored[1].Properties)
+	}
+	if stored[0].ClientName == nil || *stored[0].ClientName != "web" || stored[1].ClientName != nil {
+		t.Errorf("clients = %v, %v, want only the first event's", stored[0].ClientName, stored[1].ClientName)
+	}
+	if other := NewTracker(store, users, nil, Config{Salt: "salt"}); other.AnonymousID(ada.ID) == anonymousID {
+		t.Error("anonymous ID doesn't depend on the salt")
+	}
+
+	// A failing sink leaves events to be forwarded later
+	tracker.forward(ctx)
+	if stored, _ := store.ListUnforwarded(ctx, 10); len(stored) != 2 {
+		t.Fatalf("%d events left unforwarded, want 2", len(stored))
+	}
+	sink.err = nil
+	tracker.forward(ctx)
+	if len(sink.batches) != 1 || len(sink.batches[0]) != 2 {
+		t.Errorf("batches = %+v", sink.batches)
+	}
+	if stored, _ := store.ListUnforwarded(ctx, 10); len(stored) != 0 {
+		t.Errorf("%d events left unforwarded", len(stored))
+	}
+}
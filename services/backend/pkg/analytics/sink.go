@@ -0,0 +1,172 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// Sink is a product analytics service stored events are forwarded to
+type Sink interface {
+	// Send delivers a batch of events; an error leaves them to be sent again
+	Send(ctx context.Context, events []*models.AnalyticsEvent) error
+}
+
+// Default API endpoints of the sinks
+const (
+	DefaultSegmentEndpoint = "https://api.segment.io/v1/batch"
+	DefaultPostHogHost     = "https://app.posthog.com"
+)
+
+// SegmentSink sends events to Segment's batch API as track calls
+type SegmentSink struct {
+	writeKey string
+	endpoint string
+	client   *http.Client
+}
+
+// NewSegmentSink creates a Segment sink; an empty endpoint uses DefaultSegmentEndpoint
+func NewSegmentSink(writeKey, endpoint string, timeout time.Duration) *SegmentSink {
+	if endpoint == "" {
+		endpoint = DefaultSegmentEndpoint
+	}
+	return &SegmentSink{writeKey: writeKey, endpoint: endpoint, client: &http.Client{Timeout: timeout}}
+}
+
+// Send delivers events to Segment
+func (s *SegmentSink) Send(ctx context.Context, events []*models.AnalyticsEvent) error {
+	type track struct {
+		Type        string          `json:"type"`
+		MessageID   string          `json:"messageId"`
+		AnonymousID string          `json:"anonymousId"`
+		Event       string          `json:"event"`
+		Properties  json.RawMessage `json:"properties"`
+		Context     interface{}     `json:"context,omitempty"`
+		Timestamp   time.Time       `json:"timestamp"`
+	}
+	batch := make([]track, len(events))
+	for i, event := range events {
+		batch[i] = track{
+			Type:        "track",
+			MessageID:   event.ID,
+			AnonymousID: event.AnonymousID,
+			Event:       event.Event,
+			Properties:  properties(event),
+			Timestamp:   event.OccurredAt.UTC(),
+		}
+		if event.ClientName != nil {
+			// Segment's context.app is the app the event happened in
+			batch[i].Context = map[string]interface{}{"app": map[string]string{"name": *event.ClientName, "version": stringValue(event.ClientVersion)}}
+		}
+	}
+	return post(ctx, s.client, s.endpoint, map[string]interface{}{"batch": batch}, func(req *http.Request) {
+		req.SetBasicAuth(s.writeKey, "")
+	})
+}
+
+// PostHogSink sends events to PostHog's batch API
+type PostHogSink struct {
+	apiKey string
+	host   string
+	client *http.Client
+}
+
+// NewPostHogSink creates a PostHog sink for the project with apiKey; an empty host uses
+// DefaultPostHogHost
+func NewPostHogSink(apiKey, host string, timeout time.Duration) *PostHogSink {
+	if host == "" {
+		host = DefaultPostHogHost
+	}
+	return &PostHogSink{apiKey: apiKey, host: strings.TrimSuffix(host, "/"), client: &http.Client{Timeout: timeout}}
+}
+
+// Send delivers events to PostHog
+func (s *PostHogSink) Send(ctx context.Context, events []*models.AnalyticsEvent) error {
+	type capture struct {
+		UUID       string          `json:"uuid"`
+		Event      string          `json:"event"`
+		DistinctID string          `json:"distinct_id"`
+		Properties json.RawMessage `json:"properties"`
+		Timestamp  time.Time       `json:"timestamp"`
+	}
+	batch := make([]capture, len(events))
+	for i, event := range events {
+		batch[i] = capture{
+			UUID:       event.ID,
+			Event:      event.Event,
+			DistinctID: event.AnonymousID,
+			Properties: properties(event),
+			Timestamp:  event.OccurredAt.UTC(),
+		}
+		if event.ClientName != nil {
+			batch[i].Properties = withProperties(batch[i].Properties, map[string]interface{}{
+				"$app_name":    *event.ClientName,
+				"$app_version": stringValue(event.ClientVersion),
+			})
+		}
+	}
+	return post(ctx, s.client, s.host+"/batch/", map[string]interface{}{"api_key": s.apiKey, "batch": batch}, nil)
+}
+
+// properties returns the event's properties, or an empty object when they aren't valid
+func properties(event *models.AnalyticsEvent) json.RawMessage {
+	if !json.Valid([]byte(event.Properties)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(event.Properties)
+}
+
+// withProperties adds extra to the properties object; properties that aren't an object are
+// replaced
+func withProperties(properties json.RawMessage, extra map[string]interface{}) json.RawMessage {
+	merged := map[string]interface{}{}
+	json.Unmarshal(properties, &merged)
+	for name, value := range extra {
+		merged[name] = value
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return properties
+	}
+	return data
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// post sends body as JSON to url, failing on any non-2xx response
+func post(ctx context.Context, client *http.Client, url string, body interface{}, authorize func(*http.Request)) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authorize != nil {
+		authorize(req)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("analytics sink responded %d", resp.StatusCode)
+	}
+	return nil
+}
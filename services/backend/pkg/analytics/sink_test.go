@@ -0,0 +1,68 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSinks(t *testing.T) {
+	var path, user string
+	var body map[string]interface{}
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		user, _, _ = r.BasicAuth()
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client, version := "web", "2.1.0"
+	events := []*models.AnalyticsEvent{{
+		ID:            "e1",
+		ClientName:    &client,
+		ClientVersion: &version,
+		Event:         EventPlanAccepted,
+		AnonymousID:   "a1",
+		Properties:    `{"optionType":"FULL_DAY_OFFICE"}`,
+		OccurredAt:    time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+	}}
+	ctx := context.Background()
+
+	if err := NewSegmentSink("write-key", server.URL+"/v1/batch", time.Second).Send(ctx, events); err != nil {
+		t.Fatal(err)
+	}
+	track := body["batch"].([]interface{})[0].(map[string]interface{})
+	if path != "/v1/batch" || user != "write-key" || track["type"] != "track" || track["anonymousId"] != "a1" || track["messageId"] != "e1" {
+		t.Errorf("segment request to %s as %q: %v", path, user, body)
+	}
+	if track["properties"].(map[string]interface{})["optionType"] != "FULL_DAY_OFFICE" {
+		t.Errorf("segment properties = %v", track["properties"])
+	}
+	if app := track["context"].(map[string]interface{})["app"].(map[string]interface{}); app["name"] != "web" || app["version"] != "2.1.0" {
+		t.Errorf("segment app = %v", app)
+	}
+
+	if err := NewPostHogSink("phc_key", server.URL+"/", time.Second).Send(ctx, events); err != nil {
+		t.Fatal(err)
+	}
+	capture := body["batch"].([]interface{})[0].(map[string]interface{})
+	if path != "/batch/" || body["api_key"] != "phc_key" || capture["distinct_id"] != "a1" || capture["event"] != EventPlanAccepted {
+		t.Errorf("posthog request to %s: %v", path, body)
+	}
+	if props := capture["properties"].(map[string]interface{}); props["$app_name"] != "web" || props["optionType"] != "FULL_DAY_OFFICE" {
+		t.Errorf("posthog properties = %v", props)
+	}
+
+	status = http.StatusBadRequest
+	if err := NewPostHogSink("phc_key", server.URL, time.Second).Send(ctx, events); err == nil {
+		t.Error("a rejected batch didn't fail")
+	}
+}
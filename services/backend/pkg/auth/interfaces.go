@@ -21,6 +21,10 @@ type AuthProvider interface {
 	// Token validation (works for both JWT and OAuth)
 	ValidateToken(ctx context.Context, token string) (*models.User, error)
 	
+	// Session management (logout / token revocation)
+	RevokeToken(ctx context.Context, token string) error
+	RevokeAllTokens(ctx context.Context, userID string) error
+	
 	// User management
 	GetUserByID(ctx context.Context, userID string) (*models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
@@ -38,6 +42,7 @@ type AuthResult struct {
 
 // TokenClaims represents JWT token claims (OAuth-compatible)
 type TokenClaims struct {
+	TokenID      string    `json:"jti"`
 	UserID       string    `json:"sub"`
 	Email        string    `json:"email"`
 	Name         string    `json:"name"`
@@ -47,6 +52,15 @@ type TokenClaims struct {
 	ExpiresAt    time.Time `json:"exp"`
 }
 
+// TokenDenylist stores revoked tokens so they can be rejected before they expire
+// Implemented by the Redis client; kept as an interface so providers don't depend on Redis directly
+type TokenDenylist interface {
+	RevokeToken(ctx context.Context, jti string, ttl time.Duration) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeAllUserTokens(ctx context.Context, userID string, ttl time.Duration) error
+	UserTokensRevokedBefore(ctx context.Context, userID string) (time.Time, error)
+}
+
 // OAuthConfig holds OAuth provider configuration
 // This will be used when we migrate to Google OAuth
 type OAuthConfig struct {
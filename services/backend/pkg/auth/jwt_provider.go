@@ -31,70 +31,43 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/commute-planner/backend/pkg/database"
 	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/tenant"
 	"github.com/google/uuid"
 )
 
 // JWTProvider implements AuthProvider using JWT tokens
 // This provides local authentication while being OAuth-ready
 type JWTProvider struct {
-	db        *database.DB
-	jwtSecret []byte
-	tokenTTL  time.Duration
+	db       *database.DB
+	keys     *KeySet
+	tokenTTL time.Duration
+	denylist TokenDenylist
 }
 
 // NewJWTProvider creates a new JWT auth provider
-func NewJWTProvider(db *database.DB, jwtSecret string) *JWTProvider {
+// denylist may be nil, in which case tokens cannot be revoked before they expire
+func NewJWTProvider(db *database.DB, keys *KeySet, denylist TokenDenylist) *JWTProvider {
 	return &JWTProvider{
-		db:        db,
-		jwtSecret: []byte(jwtSecret),
-		tokenTTL:  24 * time.Hour, // 24 hours
+		db:       db,
+		keys:     keys,
+		tokenTTL: 24 * time.Hour, // 24 hours
+		denylist: denylist,
 	}
 }
 
 // Signup creates a new local user account
 func (p *JWTProvider) Signup(ctx context.Context, email, password, name string) (*AuthResult, error) {
-	// Check if user exists
-	existingUser, _ := p.GetUserByEmail(ctx, email)
-	if existingUser != nil {
-		return nil, fmt.Errorf("user already exists")
-	}
-
-	// Hash password
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	user, err := CreateLocalUser(ctx, p.db, email, password, name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash password: %w", err)
-	}
-
-	// Create user
-	userID := uuid.New().String()
-	now := time.Now()
-	
-	query := `INSERT INTO users (id, email, name, password_hash, auth_provider, is_email_verified, created_at, updated_at) 
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
-	          RETURNING id, email, name, auth_provider, is_email_verified, created_at, updated_at`
-
-	user := &models.User{}
-	err = p.db.QueryRow(query, userID, email, name, string(passwordHash), "local", false, now, now).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Name,
-		&user.AuthProvider,
-		&user.IsEmailVerified,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	
-	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return nil, err
 	}
 
 	// Generate JWT token
-	token, err := p.generateJWT(user)
+	token, err := p.generateJWT(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -110,15 +83,16 @@ func (p *JWTProvider) Signup(ctx context.Context, email, password, name string)
 
 // Login authenticates a user with email/password
 func (p *JWTProvider) Login(ctx context.Context, email, password string) (*AuthResult, error) {
-	// Get user
-	query := `SELECT id, email, name, password_hash, auth_provider, is_email_verified, created_at, updated_at 
-	          FROM users WHERE email = $1 AND auth_provider = 'local'`
+	// Get user (emails are unique per tenant)
+	query := `SELECT id, tenant_id, email, name, password_hash, auth_provider, is_email_verified, created_at, updated_at 
+	          FROM users WHERE email = $1 AND auth_provider = 'local' AND tenant_id = $2 AND deactivated_at IS NULL`
 	
 	user := &models.User{}
 	var passwordHash string
 	
-	err := p.db.QueryRow(query, email).Scan(
+	err := p.db.QueryRowContext(ctx, query, email, tenant.OrDefault(ctx)).Scan(
 		&user.ID,
+		&user.TenantID,
 		&user.Email,
 		&user.Name,
 		&passwordHash,
@@ -139,14 +113,14 @@ func (p *JWTProvider) Login(ctx context.Context, email, password string) (*AuthR
 	}
 
 	// Update last login
-	_, err = p.db.Exec("UPDATE users SET last_login = NOW() WHERE id = $1", user.ID)
+	_, err = p.db.ExecContext(ctx, "UPDATE users SET last_login = CURRENT_TIMESTAMP WHERE id = $1", user.ID)
 	if err != nil {
 		// Log but don't fail the login
 		fmt.Printf("Failed to update last login: %v\n", err)
 	}
 
 	// Generate JWT token
-	token, err := p.generateJWT(user)
+	token, err := p.generateJWT(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -162,20 +136,14 @@ func (p *JWTProvider) Login(ctx context.Context, email, password string) (*AuthR
 
 // ValidateToken validates and parses a JWT token
 func (p *JWTProvider) ValidateToken(ctx context.Context, tokenString string) (*models.User, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return p.jwtSecret, nil
-	})
-
-	if err != nil || !token.Valid {
-		return nil, fmt.Errorf("invalid token")
+	claims, err := p.parseJWT(tokenString)
+	if err != nil {
+		return nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, fmt.Errorf("invalid token claims")
+	// Magic link tokens only sign in through RedeemMagicLink
+	if _, ok := claims["purpose"]; ok {
+		return nil, fmt.Errorf("invalid token")
 	}
 
 	userID, ok := claims["sub"].(string)
@@ -183,33 +151,97 @@ func (p *JWTProvider) ValidateToken(ctx context.Context, tokenString string) (*m
 		return nil, fmt.Errorf("invalid user ID in token")
 	}
 
+	// Tokens issued before multi-tenancy carry no tenant and belong to the default one
+	ctx, err = scopeToTokenTenant(ctx, claims, tenant.DefaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject revoked tokens (logout / logout from all devices). The session is the record
+	// of a token's revocation; only tokens issued before sessions were recorded fall back
+	// to the denylist.
+	jti, _ := claims["jti"].(string)
+	found, revoked, err := p.checkSession(ctx, jti)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if revoked, err = isTokenRevoked(ctx, p.denylist, jti, claims, userID); err != nil {
+			return nil, err
+		}
+	}
+	if revoked {
+		return nil, fmt.Errorf("token has been revoked")
+	}
+
 	// Get fresh user data from database
 	return p.GetUserByID(ctx, userID)
 }
 
+// RevokeToken logs out a single session by ending it and adding the token's jti to the denylist
+func (p *JWTProvider) RevokeToken(ctx context.Context, tokenString string) error {
+	claims, err := p.parseJWT(tokenString)
+	if err != nil {
+		return err
+	}
+
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return fmt.Errorf("token has no ID and cannot be revoked individually")
+	}
+
+	ended, err := p.revokeSessions(ctx, "id = $2", jti)
+	if err != nil {
+		return err
+	}
+	if p.denylist == nil {
+		if !ended {
+			return fmt.Errorf("token revocation is not available")
+		}
+		return nil
+	}
+
+	// Only keep the denylist entry for as long as the token would have been valid
+	ttl := p.tokenTTL
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		ttl = time.Until(exp.Time)
+	}
+
+	return p.denylist.RevokeToken(ctx, jti, ttl)
+}
+
+// RevokeAllTokens logs a user out of every device by invalidating all tokens issued before now
+func (p *JWTProvider) RevokeAllTokens(ctx context.Context, userID string) error {
+	if _, err := p.revokeSessions(ctx, "user_id = $2", userID); err != nil {
+		return err
+	}
+	// Tokens issued before sessions were recorded can only be revoked with the denylist
+	if p.denylist == nil {
+		return nil
+	}
+
+	return p.denylist.RevokeAllUserTokens(ctx, userID, p.tokenTTL)
+}
+
+// parseJWT verifies the signature and expiry of a token and returns its claims
+func (p *JWTProvider) parseJWT(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, p.keys.Keyfunc, jwt.WithValidMethods(p.keys.ValidMethods()))
+
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+
+	return claims, nil
+}
+
 // GetUserByID retrieves a user by ID
 func (p *JWTProvider) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
-	query := `SELECT id, email, name, auth_provider, is_email_verified, COALESCE(oauth_scopes, '{}'::text[]), last_login, created_at, updated_at 
-	          FROM users WHERE id = $1`
-	
-	user := &models.User{}
-	var scopes pq.StringArray
-	err := p.db.QueryRow(query, userID).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Name,
-		&user.AuthProvider,
-		&user.IsEmailVerified,
-		&scopes,
-		&user.LastLogin,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	
-	if err == nil {
-		user.OAuthScopes = []string(scopes)
-	}
-	
+	user, err := findUser(ctx, p.db, "id = $1", userID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
@@ -219,27 +251,7 @@ func (p *JWTProvider) GetUserByID(ctx context.Context, userID string) (*models.U
 
 // GetUserByEmail retrieves a user by email
 func (p *JWTProvider) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	query := `SELECT id, email, name, auth_provider, is_email_verified, COALESCE(oauth_scopes, '{}'::text[]), last_login, created_at, updated_at 
-	          FROM users WHERE email = $1`
-	
-	user := &models.User{}
-	var scopes pq.StringArray
-	err := p.db.QueryRow(query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Name,
-		&user.AuthProvider,
-		&user.IsEmailVerified,
-		&scopes,
-		&user.LastLogin,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	
-	if err == nil {
-		user.OAuthScopes = []string(scopes)
-	}
-	
+	user, err := findUser(ctx, p.db, "email = $1", email)
 	if err != nil {
 		return nil, fmt.Errorf("user not found")
 	}
@@ -247,12 +259,15 @@ func (p *JWTProvider) GetUserByEmail(ctx context.Context, email string) (*models
 	return user, nil
 }
 
-// generateJWT creates a JWT token for a user
-func (p *JWTProvider) generateJWT(user *models.User) (string, error) {
+// generateJWT creates a JWT token for a user and records its session
+func (p *JWTProvider) generateJWT(ctx context.Context, user *models.User) (string, error) {
 	now := time.Now()
+	jti := uuid.New().String()
 	
 	claims := jwt.MapClaims{
+		"jti":           jti,
 		"sub":           user.ID,
+		"tenant_id":     user.TenantID,
 		"email":         user.Email,
 		"name":          user.Name,
 		"auth_provider": user.AuthProvider,
@@ -261,8 +276,19 @@ func (p *JWTProvider) generateJWT(user *models.User) (string, error) {
 		"exp":           now.Add(p.tokenTTL).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(p.jwtSecret)
+	token, err := p.keys.Sign(claims)
+	if err != nil {
+		return "", err
+	}
+	if err := p.startSession(ctx, jti, user.ID, now, now.Add(p.tokenTTL)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// JWKS returns the public signing keys so other services can verify our tokens
+func (p *JWTProvider) JWKS() JWKS {
+	return p.keys.JWKS()
 }
 
 // OAuth methods - stubbed for future implementation
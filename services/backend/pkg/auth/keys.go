@@ -0,0 +1,419 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyConfig describes where signing and verification keys come from
+type KeyConfig struct {
+	// Algorithm used for new tokens: HS256, RS256 or EdDSA
+	Algorithm string
+	// Secret is the HMAC secret for HS256 signing. It is ignored for RS256/EdDSA.
+	Secret string
+	// LegacySecret is the HMAC secret of tokens issued before switching to RS256/EdDSA.
+	// Their kid-less HS256 tokens are accepted until LegacySecretExpires, which is required.
+	LegacySecret        string
+	LegacySecretExpires time.Time
+	// SigningKeyFile is a PEM private key (PKCS#1, PKCS#8) for RS256/EdDSA
+	SigningKeyFile string
+	// SigningKey is the PEM private key itself, e.g. from a secrets manager, used instead
+	// of SigningKeyFile when set
+	SigningKey string
+	// VerificationKeyFiles are PEM keys (public or private) of previous signing keys that
+	// are still accepted during a rotation window
+	VerificationKeyFiles []string
+}
+
+// JSONWebKey is a single entry of a JWKS document
+type JSONWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// verificationKey is a public key accepted for token validation
+type verificationKey struct {
+	id     string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// KeySet holds the current signing key plus any previous keys still accepted for validation
+type KeySet struct {
+	signingID     string
+	signingMethod jwt.SigningMethod
+	signingKey    interface{}
+
+	verificationKeys map[string]verificationKey
+
+	// hmacSecret verifies kid-less HS256 tokens until hmacExpires (zero when it doesn't)
+	hmacSecret  []byte
+	hmacExpires time.Time
+	now         func() time.Time
+}
+
+// minSecretLength is the shortest HMAC secret accepted, 256 bits as HS256 needs
+const minSecretLength = 32
+
+// insecureSecrets are placeholder secrets that were once shipped as defaults
+var insecureSecrets = map[string]bool{
+	"your-jwt-secret-key-change-in-production": true,
+}
+
+// LoadKeySet builds a KeySet from configuration
+func LoadKeySet(cfg KeyConfig) (*KeySet, error) {
+	ks := &KeySet{
+		verificationKeys: map[string]verificationKey{},
+		now:              time.Now,
+	}
+
+	switch cfg.Algorithm {
+	case "", "HS256":
+		if err := checkSecret(cfg.Secret); err != nil {
+			return nil, fmt.Errorf("HS256 signing: %w", err)
+		}
+		if cfg.LegacySecret != "" {
+			return nil, fmt.Errorf("a legacy HS256 secret is only used with RS256/EdDSA signing")
+		}
+		ks.signingMethod = jwt.SigningMethodHS256
+		ks.signingKey = []byte(cfg.Secret)
+		ks.hmacSecret = []byte(cfg.Secret)
+	case "RS256", "EdDSA":
+		var private interface{}
+		var err error
+		source := cfg.SigningKeyFile
+		switch {
+		case cfg.SigningKey != "":
+			source = "from the secrets provider"
+			private, err = parsePEMKey([]byte(cfg.SigningKey), source)
+		case cfg.SigningKeyFile != "":
+			private, err = loadPEMKey(cfg.SigningKeyFile)
+		default:
+			return nil, fmt.Errorf("%s signing requires a private key", cfg.Algorithm)
+		}
+		if err != nil {
+			return nil, err
+		}
+		key, err := newVerificationKey(private)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", source, err)
+		}
+		if key.method.Alg() != cfg.Algorithm {
+			return nil, fmt.Errorf("signing key %s is not a %s key", source, cfg.Algorithm)
+		}
+		ks.signingID = key.id
+		ks.signingMethod = key.method
+		ks.signingKey = private
+		ks.verificationKeys[key.id] = key
+
+		if cfg.LegacySecret != "" {
+			if err := checkSecret(cfg.LegacySecret); err != nil {
+				return nil, fmt.Errorf("legacy HS256 secret: %w", err)
+			}
+			if cfg.LegacySecretExpires.IsZero() {
+				return nil, fmt.Errorf("legacy HS256 secret needs an expiry date")
+			}
+			ks.hmacSecret = []byte(cfg.LegacySecret)
+			ks.hmacExpires = cfg.LegacySecretExpires
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT signing algorithm %q", cfg.Algorithm)
+	}
+
+	for _, path := range cfg.VerificationKeyFiles {
+		raw, err := loadPEMKey(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := newVerificationKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("verification key %s: %w", path, err)
+		}
+		ks.verificationKeys[key.id] = key
+	}
+
+	return ks, nil
+}
+
+// checkSecret rejects missing, short and placeholder HMAC secrets
+func checkSecret(secret string) error {
+	switch {
+	case secret == "":
+		return fmt.Errorf("a secret is required")
+	case insecureSecrets[secret]:
+		return fmt.Errorf("the secret is a published placeholder, set a random one")
+	case len(secret) < minSecretLength:
+		return fmt.Errorf("the secret must be at least %d bytes", minSecretLength)
+	}
+	return nil
+}
+
+// Sign creates a signed token, stamping the key ID into the header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signingMethod, claims)
+	if ks.signingID != "" {
+		token.Header["kid"] = ks.signingID
+	}
+	return token.SignedString(ks.signingKey)
+}
+
+// Keyfunc resolves the verification key for a token by its kid header.
+// Tokens without a kid are HS256 tokens, checked against the HMAC secret when signing
+// with HS256 or, after moving to asymmetric keys, the legacy secret until it expires.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || ks.hmacSecret == nil {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		if !ks.hmacExpires.IsZero() && !ks.now().Before(ks.hmacExpires) {
+			return nil, fmt.Errorf("legacy HS256 tokens are no longer accepted")
+		}
+		return ks.hmacSecret, nil
+	}
+
+	key, ok := ks.verificationKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// ValidMethods lists the algorithms the key set can verify
+func (ks *KeySet) ValidMethods() []string {
+	seen := map[string]bool{}
+	var methods []string
+	add := func(alg string) {
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+
+	add(ks.signingMethod.Alg())
+	if ks.hmacSecret != nil {
+		add(jwt.SigningMethodHS256.Alg())
+	}
+	for _, key := range ks.verificationKeys {
+		add(key.method.Alg())
+	}
+	return methods
+}
+
+// JWKS returns the public keys (current and previous) for publication.
+// HMAC secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	doc := JWKS{Keys: []JSONWebKey{}}
+
+	// Current key first, then previous keys in a stable order
+	ids := make([]string, 0, len(ks.verificationKeys))
+	for id := range ks.verificationKeys {
+		if id != ks.signingID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if _, ok := ks.verificationKeys[ks.signingID]; ok {
+		ids = append([]string{ks.signingID}, ids...)
+	}
+
+	for _, id := range ids {
+		key := ks.verificationKeys[id]
+		jwk, err := publicJWK(key.public)
+		if err != nil {
+			continue
+		}
+		jwk.Kid = key.id
+		jwk.Use = "sig"
+		jwk.Alg = key.method.Alg()
+		doc.Keys = append(doc.Keys, jwk)
+	}
+	return doc
+}
+
+// newVerificationKey derives the public key, algorithm and key ID from a parsed PEM key
+func newVerificationKey(raw interface{}) (verificationKey, error) {
+	var key verificationKey
+	switch k := raw.(type) {
+	case *rsa.PrivateKey:
+		key.public, key.method = &k.PublicKey, jwt.SigningMethodRS256
+	case *rsa.PublicKey:
+		key.public, key.method = k, jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		key.public, key.method = k.Public(), jwt.SigningMethodEdDSA
+	case ed25519.PublicKey:
+		key.public, key.method = k, jwt.SigningMethodEdDSA
+	default:
+		return key, fmt.Errorf("unsupported key type %T (use RSA or Ed25519)", raw)
+	}
+
+	jwk, err := publicJWK(key.public)
+	if err != nil {
+		return key, err
+	}
+	key.id, err = jwk.thumbprint()
+	return key, err
+}
+
+// loadPEMKey reads a PEM file holding a private or public key
+func loadPEMKey(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	return parsePEMKey(data, path)
+}
+
+// parsePEMKey parses a PEM private or public key; source names it in errors
+func parsePEMKey(data []byte, source string) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", source)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, source)
+	}
+}
+
+// publicJWK encodes a public key as a JWK (without kid/use/alg)
+func publicJWK(public crypto.PublicKey) (JSONWebKey, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JSONWebKey{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k),
+		}, nil
+	default:
+		return JSONWebKey{}, fmt.Errorf("unsupported public key type %T", public)
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint, used as a stable key ID
+func (k JSONWebKey) thumbprint() (string, error) {
+	var members interface{}
+	switch k.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{k.Crv, k.Kty, k.X}
+	default:
+		return "", fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// publicKey converts a JWK into an *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey
+func (k JSONWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testSecret   = "0123456789abcdef0123456789abcdef"
+	legacySecret = "fedcba9876543210fedcba9876543210"
+)
+
+// writeKey writes a PKCS#8 PEM private key to a temporary file
+func writeKey(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newEd25519Key(t *testing.T) string {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeKey(t, private)
+}
+
+// parse validates a token against the key set the way the JWT provider does
+func parse(ks *KeySet, token string) error {
+	_, err := jwt.Parse(token, ks.Keyfunc, jwt.WithValidMethods(ks.ValidMethods()))
+	return err
+}
+
+func hs256(t *testing.T, secret string, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user"})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// RFC 7638 section 3.1
+	key := JSONWebKey{
+		Kty: "RSA",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECP" +
+			"ebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY3" +
+			"68QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4l" +
+			"Fd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+		Alg: "RS256",
+		Kid: "2011-04-29",
+	}
+	got, err := key.thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("thumbprint() = %s, want %s", got, want)
+	}
+
+	// The thumbprint round-trips through the public key
+	public, err := key.publicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := publicJWK(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := jwk.thumbprint(); again != got {
+		t.Errorf("thumbprint of re-encoded key = %s, want %s", again, got)
+	}
+}
+
+func TestLoadKeySetSecrets(t *testing.T) {
+	keyFile := newEd25519Key(t)
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		cfg     KeyConfig
+		wantErr string
+	}{
+		{"HS256", KeyConfig{Secret: testSecret}, ""},
+		{"HS256 without a secret", KeyConfig{Algorithm: "HS256"}, "a secret is required"},
+		{"placeholder secret", KeyConfig{Secret: "your-jwt-secret-key-change-in-production"}, "placeholder"},
+		{"short secret", KeyConfig{Secret: "secret"}, "at least 32 bytes"},
+		{"HS256 with a legacy secret", KeyConfig{Secret: testSecret, LegacySecret: legacySecret}, "only used with RS256/EdDSA"},
+		{"EdDSA", KeyConfig{Algorithm: "EdDSA", SigningKeyFile: keyFile}, ""},
+		{"EdDSA with a legacy secret", KeyConfig{Algorithm: "EdDSA", SigningKeyFile: keyFile, LegacySecret: legacySecret, LegacySecretExpires: time.Now().Add(time.Hour)}, ""},
+		{"legacy secret without expiry", KeyConfig{Algorithm: "EdDSA", SigningKeyFile: keyFile, LegacySecret: legacySecret}, "expiry"},
+		{"EdDSA key for RS256", KeyConfig{Algorithm: "RS256", SigningKeyFile: keyFile}, "not a RS256 key"},
+		{"EdDSA key from a secret", KeyConfig{Algorithm: "EdDSA", SigningKey: string(keyPEM)}, ""},
+		{"EdDSA without a key", KeyConfig{Algorithm: "EdDSA"}, "requires a private key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadKeySet(tt.cfg)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("LoadKeySet() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("LoadKeySet() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyfunc(t *testing.T) {
+	keyFile := newEd25519Key(t)
+	previous := newEd25519Key(t)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	hmac, err := LoadKeySet(KeyConfig{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	asymmetric, err := LoadKeySet(KeyConfig{Algorithm: "EdDSA", SigningKeyFile: keyFile, VerificationKeyFiles: []string{previous}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withLegacy, err := LoadKeySet(KeyConfig{
+		Algorithm:           "EdDSA",
+		SigningKeyFile:      keyFile,
+		LegacySecret:        legacySecret,
+		LegacySecretExpires: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withLegacy.now = func() time.Time { return now }
+	expiredLegacy := *withLegacy
+	expiredLegacy.now = func() time.Time { return now.Add(time.Hour) }
+	rotated, err := LoadKeySet(KeyConfig{Algorithm: "EdDSA", SigningKeyFile: previous})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(ks *KeySet) string {
+		token, err := ks.Sign(jwt.MapClaims{"sub": "user"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name  string
+		keys  *KeySet
+		token string
+		valid bool
+	}{
+		{"HS256 token", hmac, sign(hmac), true},
+		{"HS256 token with another secret", hmac, hs256(t, legacySecret, ""), false},
+		{"EdDSA token", asymmetric, sign(asymmetric), true},
+		{"token from a previous key", asymmetric, sign(rotated), true},
+		{"unknown kid", withLegacy, sign(rotated), false},
+		{"HS256 token with an EdDSA kid", asymmetric, hs256(t, testSecret, asymmetric.signingID), false},
+		{"kid-less HS256 without a legacy secret", asymmetric, hs256(t, testSecret, ""), false},
+		{"kid-less HS256 with the legacy secret", withLegacy, hs256(t, legacySecret, ""), true},
+		{"kid-less HS256 with another secret", withLegacy, hs256(t, testSecret, ""), false},
+		{"kid-less HS256 after the legacy secret expires", &expiredLegacy, hs256(t, legacySecret, ""), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parse(tt.keys, tt.token)
+			if tt.valid && err != nil {
+				t.Fatalf("token rejected: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("token accepted")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := writeKey(t, rsaKey)
+	previous := newEd25519Key(t)
+
+	ks, err := LoadKeySet(KeyConfig{
+		Algorithm:            "RS256",
+		SigningKeyFile:       current,
+		VerificationKeyFiles: []string{previous},
+		LegacySecret:         legacySecret,
+		LegacySecretExpires:  time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := ks.JWKS()
+	if len(doc.Keys) != 2 {
+		t.Fatalf("JWKS has %d keys, want 2 (the HMAC secret is never published)", len(doc.Keys))
+	}
+	if first := doc.Keys[0]; first.Kid != ks.signingID || first.Kty != "RSA" || first.Alg != "RS256" || first.Use != "sig" {
+		t.Errorf("first key = %+v, want the current RS256 signing key", first)
+	}
+	if second := doc.Keys[1]; second.Kty != "OKP" || second.Crv != "Ed25519" || second.Alg != "EdDSA" {
+		t.Errorf("second key = %+v, want the previous Ed25519 key", second)
+	}
+	for _, key := range doc.Keys {
+		if thumbprint, _ := key.thumbprint(); thumbprint != key.Kid {
+			t.Errorf("kid %s is not the key's thumbprint %s", key.Kid, thumbprint)
+		}
+		if _, err := key.publicKey(); err != nil {
+			t.Errorf("key %s doesn't decode: %v", key.Kid, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+// magicLinkPurpose marks the tokens of magic links, so they can't be used as session
+// tokens and session tokens can't be used as links
+const magicLinkPurpose = "magic_link"
+
+// magicLinkInterval is how long a user waits between links, so the endpoint can't be
+// used to flood their inbox
+const magicLinkInterval = time.Minute
+
+// ErrMagicLinkInvalid is returned for forged, expired and already used magic links
+var ErrMagicLinkInvalid = errors.New("invalid or expired login link")
+
+// MagicLink is a single-use login token for a user, to be emailed to them
+type MagicLink struct {
+	Email     string
+	Token     string
+	ExpiresAt time.Time
+}
+
+// IssueMagicLink creates a login token valid for ttl for the user with email, in the
+// tenant ctx is scoped to. It returns nil when no user has the email or one was sent a
+// link within the last minute, so callers can answer the same either way.
+func (p *JWTProvider) IssueMagicLink(ctx context.Context, email string, ttl time.Duration) (*MagicLink, error) {
+	user, err := findUser(ctx, p.db, "email = $1", email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	now := time.Now().UTC()
+	var recent int
+	err = p.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM magic_links WHERE user_id = $1 AND created_at > $2`,
+		user.ID, now.Add(-magicLinkInterval)).Scan(&recent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check recent login links: %w", err)
+	}
+	if recent > 0 {
+		return nil, nil
+	}
+
+	link := &MagicLink{Email: user.Email, ExpiresAt: now.Add(ttl).Truncate(time.Second)}
+	id := uuid.New().String()
+	_, err = p.db.ExecContext(ctx, `INSERT INTO magic_links (id, user_id, expires_at, created_at) VALUES ($1, $2, $3, $4)`,
+		id, user.ID, link.ExpiresAt, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save login link: %w", err)
+	}
+	link.Token, err = p.keys.Sign(jwt.MapClaims{
+		"jti":       id,
+		"sub":       user.ID,
+		"tenant_id": user.TenantID,
+		"purpose":   magicLinkPurpose,
+		"iat":       now.Unix(),
+		"exp":       link.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign login link: %w", err)
+	}
+	return link, nil
+}
+
+// RedeemMagicLink signs a user in with the token of a magic link, which can't be used
+// again. Following the link proves the user owns their email address.
+func (p *JWTProvider) RedeemMagicLink(ctx context.Context, token string) (*AuthResult, error) {
+	claims, err := p.parseJWT(token)
+	if err != nil || claims["purpose"] != magicLinkPurpose {
+		return nil, ErrMagicLinkInvalid
+	}
+	id, _ := claims["jti"].(string)
+	userID, _ := claims["sub"].(string)
+	ctx, err = scopeToTokenTenant(ctx, claims, tenant.DefaultID)
+	if err != nil {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	now := time.Now().UTC()
+	result, err := p.db.ExecContext(ctx, `UPDATE magic_links SET used_at = $1
+	                                      WHERE id = $2 AND user_id = $3 AND used_at IS NULL AND expires_at > $1`, now, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem login link: %w", err)
+	}
+	if used, err := result.RowsAffected(); err != nil || used != 1 {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	user, err := p.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, ErrMagicLinkInvalid
+	}
+	_, err = p.db.ExecContext(ctx, `UPDATE users SET last_login = $1, is_email_verified = $2 WHERE id = $3`, now, true, user.ID)
+	if err != nil {
+		// Log but don't fail the login
+		log.Printf("Failed to update last login: %v", err)
+	}
+	verified := true
+	user.IsEmailVerified = &verified
+
+	session, err := p.generateJWT(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return &AuthResult{
+		User:        user,
+		AccessToken: session,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(p.tokenTTL.Seconds()),
+		Scopes:      []string{"read", "write"},
+	}, nil
+}
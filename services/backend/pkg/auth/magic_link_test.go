@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+)
+
+func TestMagicLinks(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	keys, err := LoadKeySet(KeyConfig{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := NewJWTProvider(db, keys, nil)
+	user, err := CreateLocalUser(ctx, db, "ada@example.com", "correct horse", "Ada")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if link, err := provider.IssueMagicLink(ctx, "nobody@example.com", time.Minute); err != nil || link != nil {
+		t.Fatalf("unknown email: link = %+v, %v; want none", link, err)
+	}
+	link, err := provider.IssueMagicLink(ctx, user.Email, 15*time.Minute)
+	if err != nil || link == nil || link.Email != user.Email {
+		t.Fatalf("link = %+v, %v", link, err)
+	}
+	if again, err := provider.IssueMagicLink(ctx, user.Email, 15*time.Minute); err != nil || again != nil {
+		t.Errorf("second link within a minute: %+v, %v; want none", again, err)
+	}
+
+	// The link isn't a session token
+	if _, err := provider.ValidateToken(ctx, link.Token); err == nil {
+		t.Error("magic link token accepted as a session token")
+	}
+	session, err := provider.generateJWT(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.RedeemMagicLink(ctx, session); !errors.Is(err, ErrMagicLinkInvalid) {
+		t.Errorf("session token redeemed: err = %v", err)
+	}
+
+	result, err := provider.RedeemMagicLink(ctx, link.Token)
+	if err != nil || result.User.ID != user.ID || result.User.IsEmailVerified == nil || !*result.User.IsEmailVerified {
+		t.Fatalf("redeem = %+v, %v", result, err)
+	}
+	if validated, err := provider.ValidateToken(ctx, result.AccessToken); err != nil || validated.ID != user.ID {
+		t.Errorf("session from the link: %+v, %v", validated, err)
+	}
+	if _, err := provider.RedeemMagicLink(ctx, link.Token); !errors.Is(err, ErrMagicLinkInvalid) {
+		t.Errorf("link used twice: err = %v", err)
+	}
+}
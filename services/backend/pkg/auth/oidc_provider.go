@@ -0,0 +1,500 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+// OIDCConfig configures a generic OpenID Connect provider (Auth0, Keycloak, Azure AD, ...)
+type OIDCConfig struct {
+	OAuthConfig
+	IssuerURL    string // e.g. https://tenant.auth0.com/
+	Audience     string // expected "aud" claim; defaults to ClientID
+	ProviderName string // stored in users.auth_provider, e.g. "auth0"
+}
+
+// oidcDiscovery is the subset of /.well-known/openid-configuration we use
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcUserInfo holds the identity claims we map onto local users
+type oidcUserInfo struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// OIDCProvider implements AuthProvider against any OpenID Connect compliant identity provider.
+// Bearer tokens are provider-issued JWTs validated against the provider's JWKS; users are
+// created locally on first sight and linked via (auth_provider, external_id).
+type OIDCProvider struct {
+	db         *database.DB
+	config     OIDCConfig
+	discovery  oidcDiscovery
+	httpClient *http.Client
+	denylist   TokenDenylist
+
+	keysMu      sync.RWMutex
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+// oidcKeyRefreshInterval limits how often an unknown kid triggers a JWKS refetch
+const oidcKeyRefreshInterval = time.Minute
+
+// NewOIDCProvider creates an OIDC provider, loading the issuer's discovery document and signing keys
+func NewOIDCProvider(ctx context.Context, db *database.DB, config OIDCConfig, denylist TokenDenylist) (*OIDCProvider, error) {
+	if config.IssuerURL == "" || config.ClientID == "" {
+		return nil, fmt.Errorf("OIDC issuer URL and client ID are required")
+	}
+	if config.Audience == "" {
+		config.Audience = config.ClientID
+	}
+	if config.ProviderName == "" {
+		config.ProviderName = "oidc"
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	p := &OIDCProvider{
+		db:         db,
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		denylist:   denylist,
+		keys:       map[string]interface{}{},
+	}
+
+	discoveryURL := strings.TrimSuffix(config.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, discoveryURL, "", &p.discovery); err != nil {
+		return nil, fmt.Errorf("failed to load OIDC discovery document: %w", err)
+	}
+	if p.discovery.JWKSURI == "" || p.discovery.Issuer == "" {
+		return nil, fmt.Errorf("OIDC discovery document is missing issuer or jwks_uri")
+	}
+
+	// Explicit endpoints in config win over discovery
+	if p.config.AuthURL == "" {
+		p.config.AuthURL = p.discovery.AuthorizationEndpoint
+	}
+	if p.config.TokenURL == "" {
+		p.config.TokenURL = p.discovery.TokenEndpoint
+	}
+
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// AuthCodeURL returns the provider's authorization URL for the code flow
+func (p *OIDCProvider) AuthCodeURL(state string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	if p.config.Audience != p.config.ClientID {
+		// Auth0 uses "audience" to issue JWT access tokens for an API
+		params.Set("audience", p.config.Audience)
+	}
+	return p.config.AuthURL + "?" + params.Encode()
+}
+
+// Signup is not supported - accounts are created by the identity provider
+func (p *OIDCProvider) Signup(ctx context.Context, email, password, name string) (*AuthResult, error) {
+	return nil, fmt.Errorf("signup is handled by the %s identity provider", p.config.ProviderName)
+}
+
+// Login is not supported - users authenticate with the identity provider
+func (p *OIDCProvider) Login(ctx context.Context, email, password string) (*AuthResult, error) {
+	return nil, fmt.Errorf("password login is not available, sign in with %s", p.config.ProviderName)
+}
+
+// HandleOAuth exchanges an authorization code for tokens and signs the user in
+func (p *OIDCProvider) HandleOAuth(ctx context.Context, provider string, code string) (*AuthResult, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	return p.exchangeToken(ctx, form)
+}
+
+// RefreshToken exchanges a provider refresh token for fresh tokens
+func (p *OIDCProvider) RefreshToken(ctx context.Context, refreshToken string) (*AuthResult, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	return p.exchangeToken(ctx, form)
+}
+
+// ValidateToken verifies a provider-issued JWT and returns the matching local user
+func (p *OIDCProvider) ValidateToken(ctx context.Context, tokenString string) (*models.User, error) {
+	claims, err := p.parseJWT(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("invalid subject in token")
+	}
+
+	// Providers that know the tenant put it in a tenant_id claim; otherwise the request's
+	// own scope (its subdomain) decides
+	ctx, err = scopeToTokenTenant(ctx, claims, "")
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := findUser(ctx, p.db, "auth_provider = $1 AND external_id = $2", p.config.ProviderName, subject)
+	if err != nil {
+		// First time we see this identity - resolve the profile and provision a local user
+		info := userInfoFromClaims(claims)
+		if info.Email == "" {
+			if info, err = p.fetchUserInfo(ctx, tokenString); err != nil {
+				return nil, err
+			}
+		}
+		if user, err = p.provisionUser(ctx, info); err != nil {
+			return nil, err
+		}
+	}
+
+	revoked, err := isTokenRevoked(ctx, p.denylist, oidcTokenID(claims, tokenString), claims, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, fmt.Errorf("token has been revoked")
+	}
+
+	return user, nil
+}
+
+// RevokeToken denylists a token until it expires. Provider tokens don't always carry a jti,
+// so the token hash is used as its ID in that case.
+func (p *OIDCProvider) RevokeToken(ctx context.Context, tokenString string) error {
+	if p.denylist == nil {
+		return fmt.Errorf("token revocation is not available")
+	}
+
+	claims, err := p.parseJWT(ctx, tokenString)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Hour
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		ttl = time.Until(exp.Time)
+	}
+
+	return p.denylist.RevokeToken(ctx, oidcTokenID(claims, tokenString), ttl)
+}
+
+// RevokeAllTokens invalidates every token issued to the user before now
+func (p *OIDCProvider) RevokeAllTokens(ctx context.Context, userID string) error {
+	if p.denylist == nil {
+		return fmt.Errorf("token revocation is not available")
+	}
+
+	// Provider tokens are short-lived; a day comfortably outlives them
+	return p.denylist.RevokeAllUserTokens(ctx, userID, 24*time.Hour)
+}
+
+// GetUserByID retrieves a user by ID
+func (p *OIDCProvider) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	user, err := findUser(ctx, p.db, "id = $1", userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	return user, nil
+}
+
+// GetUserByEmail retrieves a user by email
+func (p *OIDCProvider) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := findUser(ctx, p.db, "email = $1", email)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+// exchangeToken calls the token endpoint and turns the response into an AuthResult,
+// with the bearer token picked by bearerToken
+func (p *OIDCProvider) exchangeToken(ctx context.Context, form url.Values) (*AuthResult, error) {
+	form.Set("client_id", p.config.ClientID)
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		IDToken      string `json:"id_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		Scope        string `json:"scope"`
+		Error        string `json:"error"`
+		ErrorDesc    string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.Error != "" {
+		return nil, fmt.Errorf("token exchange failed: %s %s", tokens.Error, tokens.ErrorDesc)
+	}
+
+	bearer, err := p.bearerToken(tokens.IDToken, tokens.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := p.ValidateToken(ctx, bearer)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.db.ExecContext(ctx, "UPDATE users SET last_login = CURRENT_TIMESTAMP WHERE id = $1", user.ID)
+	if err != nil {
+		// Log but don't fail the login
+		log.Printf("Failed to update last login: %v", err)
+	}
+
+	return &AuthResult{
+		User:         user,
+		AccessToken:  bearer,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
+		Scopes:       strings.Fields(tokens.Scope),
+	}, nil
+}
+
+// bearerToken picks the token API requests authenticate with, which must carry the "aud"
+// parseJWT expects. With an API audience configured that is the access token, issued as a
+// JWT for the audience. Otherwise the audience is the client ID, which is the audience of
+// the ID token, preferred because access tokens may be opaque.
+func (p *OIDCProvider) bearerToken(idToken, accessToken string) (string, error) {
+	if p.config.Audience != p.config.ClientID {
+		if accessToken == "" {
+			return "", fmt.Errorf("identity provider returned no access token for audience %s", p.config.Audience)
+		}
+		return accessToken, nil
+	}
+	if idToken != "" {
+		return idToken, nil
+	}
+	if accessToken == "" {
+		return "", fmt.Errorf("identity provider returned no token")
+	}
+	return accessToken, nil
+}
+
+// provisionUser creates (or links) the local user for an external identity
+func (p *OIDCProvider) provisionUser(ctx context.Context, info *oidcUserInfo) (*models.User, error) {
+	if info.Subject == "" || info.Email == "" {
+		return nil, fmt.Errorf("identity provider did not return a subject and email")
+	}
+
+	existing, err := findUser(ctx, p.db, "email = $1", info.Email)
+	if err == nil {
+		// Never silently take over an account created with a different sign-in method
+		if existing.AuthProvider == nil || *existing.AuthProvider != p.config.ProviderName || !info.EmailVerified {
+			return nil, fmt.Errorf("an account with this email already exists with a different sign-in method")
+		}
+		_, err = p.db.ExecContext(ctx, "UPDATE users SET external_id = $1 WHERE id = $2", info.Subject, existing.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to link user: %w", err)
+		}
+		return existing, nil
+	}
+
+	name := info.Name
+	if name == "" {
+		name = info.Email
+	}
+
+	_, err = p.db.ExecContext(ctx, `INSERT INTO users (id, tenant_id, email, name, auth_provider, external_id, is_email_verified, created_at, updated_at)
+	                    VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		uuid.New().String(), tenant.OrDefault(ctx), info.Email, name, p.config.ProviderName, info.Subject, info.EmailVerified)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return findUser(ctx, p.db, "auth_provider = $1 AND external_id = $2", p.config.ProviderName, info.Subject)
+}
+
+// fetchUserInfo calls the userinfo endpoint for tokens that don't carry profile claims
+func (p *OIDCProvider) fetchUserInfo(ctx context.Context, tokenString string) (*oidcUserInfo, error) {
+	if p.discovery.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("token has no email claim and provider has no userinfo endpoint")
+	}
+
+	info := &oidcUserInfo{}
+	if err := p.getJSON(ctx, p.discovery.UserinfoEndpoint, tokenString, info); err != nil {
+		return nil, fmt.Errorf("failed to fetch user info: %w", err)
+	}
+	return info, nil
+}
+
+// parseJWT verifies signature, issuer, audience and expiry of a provider token
+func (p *OIDCProvider) parseJWT(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(p.discovery.Issuer),
+		jwt.WithAudience(p.config.Audience),
+	)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	if exp, err := claims.GetExpirationTime(); err != nil || exp == nil {
+		return nil, fmt.Errorf("token has no expiry")
+	}
+
+	return claims, nil
+}
+
+// signingKey looks up a JWKS key by ID, refetching the key set when an unknown kid appears
+// (providers rotate keys without notice)
+func (p *OIDCProvider) signingKey(ctx context.Context, kid string) (interface{}, error) {
+	p.keysMu.RLock()
+	key, ok := p.keys[kid]
+	stale := time.Since(p.keysFetched) > oidcKeyRefreshInterval
+	p.keysMu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	if stale {
+		if err := p.refreshKeys(ctx); err != nil {
+			return nil, err
+		}
+		p.keysMu.RLock()
+		key, ok = p.keys[kid]
+		p.keysMu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// refreshKeys downloads and parses the provider's JWKS
+func (p *OIDCProvider) refreshKeys(ctx context.Context) error {
+	var jwks struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.discovery.JWKSURI, "", &jwks); err != nil {
+		return fmt.Errorf("failed to load OIDC signing keys: %w", err)
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip key types we don't support rather than failing the whole set
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("OIDC provider published no usable signing keys")
+	}
+
+	p.keysMu.Lock()
+	p.keys = keys
+	p.keysFetched = time.Now()
+	p.keysMu.Unlock()
+	return nil
+}
+
+// getJSON performs a GET request, optionally with a bearer token, and decodes the JSON body
+func (p *OIDCProvider) getJSON(ctx context.Context, target string, bearer string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, target)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// userInfoFromClaims maps standard OIDC claims from a token
+func userInfoFromClaims(claims jwt.MapClaims) *oidcUserInfo {
+	info := &oidcUserInfo{}
+	info.Subject, _ = claims["sub"].(string)
+	info.Email, _ = claims["email"].(string)
+	info.EmailVerified, _ = claims["email_verified"].(bool)
+	info.Name, _ = claims["name"].(string)
+	return info
+}
+
+// oidcTokenID returns the jti claim, falling back to a hash of the raw token
+func oidcTokenID(claims jwt.MapClaims, tokenString string) string {
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		return jti
+	}
+	sum := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(sum[:])
+}
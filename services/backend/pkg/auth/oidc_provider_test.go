@@ -0,0 +1,32 @@
+package auth
+
+import "testing"
+
+func TestOIDCBearerToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		audience    string
+		idToken     string
+		accessToken string
+		want        string
+		wantErr     bool
+	}{
+		{"client audience prefers the ID token", "client", "id", "access", "id", false},
+		{"client audience falls back to the access token", "client", "", "access", "access", false},
+		{"API audience uses the access token", "https://api.example.com", "id", "access", "access", false},
+		{"API audience without an access token", "https://api.example.com", "id", "", "", true},
+		{"no tokens", "client", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &OIDCProvider{config: OIDCConfig{OAuthConfig: OAuthConfig{ClientID: "client"}, Audience: tt.audience}}
+			got, err := p.bearerToken(tt.idToken, tt.accessToken)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bearerToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("bearerToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// sessionTouchInterval is how often a session's last use is recorded, so that every
+// request doesn't write it
+const sessionTouchInterval = 5 * time.Minute
+
+// Session is a login of a user on a device, lasting as long as its token
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// Current is set for the session of the request listing the sessions
+	Current bool `json:"current"`
+}
+
+// Client is the device a session is started from. The IP address is as the client
+// reported it, for users to recognise their sessions, and not to be trusted.
+type Client struct {
+	UserAgent string
+	IPAddress string
+}
+
+type clientKey struct{}
+
+// WithClient records the device of a login request, for the session it starts
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// startSession records the session of a newly issued token. Expired sessions of the user
+// are cleared on the way.
+func (p *JWTProvider) startSession(ctx context.Context, id, userID string, issuedAt, expiresAt time.Time) error {
+	client, _ := ctx.Value(clientKey{}).(Client)
+	now := issuedAt.UTC()
+	if _, err := p.db.ExecContext(ctx, `DELETE FROM auth_sessions WHERE user_id = $1 AND expires_at < $2`, userID, now); err != nil {
+		return fmt.Errorf("failed to clear expired sessions: %w", err)
+	}
+	_, err := p.db.ExecContext(ctx, `INSERT INTO auth_sessions (id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at)
+	                                 VALUES ($1, $2, $3, $4, $5, $5, $6)`,
+		id, userID, truncate(client.UserAgent, 512), truncate(client.IPAddress, 64), now, expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record session: %w", err)
+	}
+	return nil
+}
+
+// checkSession reports whether a token has a session and whether it was ended, recording
+// that it's in use otherwise. Tokens issued before sessions were recorded have none.
+func (p *JWTProvider) checkSession(ctx context.Context, id string) (found, revoked bool, err error) {
+	var revokedAt *time.Time
+	var lastSeenAt time.Time
+	err = p.db.QueryRowContext(ctx, `SELECT revoked_at, last_seen_at FROM auth_sessions WHERE id = $1`, id).Scan(&revokedAt, &lastSeenAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to check session: %w", err)
+	}
+	if revokedAt != nil {
+		return true, true, nil
+	}
+	if now := time.Now(); now.Sub(lastSeenAt) >= sessionTouchInterval {
+		if _, err := p.db.ExecContext(ctx, `UPDATE auth_sessions SET last_seen_at = $1 WHERE id = $2`, now.UTC(), id); err != nil {
+			return true, false, fmt.Errorf("failed to record session use: %w", err)
+		}
+	}
+	return true, false, nil
+}
+
+// Sessions returns the user's live sessions, most recently used first. The session of
+// currentToken, the token of the request, is marked Current.
+func (p *JWTProvider) Sessions(ctx context.Context, userID, currentToken string) ([]*Session, error) {
+	var current string
+	if claims, err := p.parseJWT(currentToken); err == nil {
+		current, _ = claims["jti"].(string)
+	}
+
+	rows, err := p.db.QueryContext(ctx, `SELECT id, user_agent, ip_address, created_at, last_seen_at, expires_at
+	                                     FROM auth_sessions
+	                                     WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+	                                     ORDER BY last_seen_at DESC, id ASC`, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		session := &Session{}
+		if err := rows.Scan(&session.ID, &session.UserAgent, &session.IPAddress, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		session.Current = session.ID == current
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession ends one of the user's sessions, reporting whether it was live
+func (p *JWTProvider) RevokeSession(ctx context.Context, userID, id string) (bool, error) {
+	return p.revokeSessions(ctx, "id = $2 AND user_id = $3", id, userID)
+}
+
+// revokeSessions ends the live sessions matching where, whose arguments start at $2
+func (p *JWTProvider) revokeSessions(ctx context.Context, where string, args ...interface{}) (bool, error) {
+	args = append([]interface{}{time.Now().UTC()}, args...)
+	result, err := p.db.ExecContext(ctx, `UPDATE auth_sessions SET revoked_at = $1 WHERE revoked_at IS NULL AND (`+where+`)`, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	revoked, err := result.RowsAffected()
+	return revoked > 0, err
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+)
+
+func TestSessions(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	keys, err := LoadKeySet(KeyConfig{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	denylist := &fakeDenylist{revoked: map[string]bool{}, cutoffs: map[string]time.Time{}}
+	provider := NewJWTProvider(db, keys, denylist)
+	if _, err := provider.Signup(ctx, "ada@example.com", "correct horse", "Ada"); err != nil {
+		t.Fatal(err)
+	}
+
+	laptop, err := provider.Login(WithClient(ctx, Client{UserAgent: "Firefox", IPAddress: "203.0.113.7"}), "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := laptop.User.ID
+	phone, err := provider.Login(WithClient(ctx, Client{UserAgent: "Safari"}), "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := provider.Sessions(ctx, userID, laptop.AccessToken)
+	if err != nil || len(sessions) != 3 {
+		t.Fatalf("sessions = %+v, %v; want the signup's and both logins'", sessions, err)
+	}
+	var current, other *Session
+	for _, session := range sessions {
+		switch session.UserAgent {
+		case "Firefox":
+			current = session
+		case "Safari":
+			other = session
+		}
+	}
+	if current == nil || !current.Current || current.IPAddress != "203.0.113.7" || other == nil || other.Current {
+		t.Fatalf("sessions = %+v, want the Firefox one current", sessions)
+	}
+
+	// Ending a session logs its token out, without a denylist
+	if revoked, err := provider.RevokeSession(ctx, "someone-else", other.ID); err != nil || revoked {
+		t.Errorf("another user revoked the session: %v, %v", revoked, err)
+	}
+	if revoked, err := provider.RevokeSession(ctx, userID, other.ID); err != nil || !revoked {
+		t.Fatalf("revoke = %v, %v", revoked, err)
+	}
+	if _, err := provider.ValidateToken(ctx, phone.AccessToken); err == nil {
+		t.Error("revoked session's token still valid")
+	}
+	if _, err := provider.ValidateToken(ctx, laptop.AccessToken); err != nil {
+		t.Errorf("other session logged out: %v", err)
+	}
+
+	if err := provider.RevokeToken(ctx, laptop.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.ValidateToken(ctx, laptop.AccessToken); err == nil {
+		t.Error("logged out token still valid")
+	}
+	if err := provider.RevokeAllTokens(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, _ := provider.Sessions(ctx, userID, ""); len(sessions) != 0 {
+		t.Errorf("sessions after logging out everywhere = %+v", sessions)
+	}
+
+	// Signing in again right away works, though the new token's iat is likely the cutoff's
+	// second, and keeps working while the denylist is down: its session decides
+	again, err := provider.Login(ctx, "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.ValidateToken(ctx, again.AccessToken); err != nil {
+		t.Errorf("token issued after logging out everywhere rejected: %v", err)
+	}
+	denylist.err = errors.New("connection refused")
+	if _, err := provider.ValidateToken(ctx, again.AccessToken); err != nil {
+		t.Errorf("token with a session rejected while the denylist is down: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+// Helpers shared by every AuthProvider implementation
+
+// userColumns is the column list scanned by findUser
+const userColumns = `id, tenant_id, email, name, auth_provider, is_email_verified, oauth_scopes, last_login, default_office_id, home_address, home_latitude, home_longitude, focus_minutes, version, created_at, updated_at`
+
+// findUser loads a single user matching a WHERE clause, e.g. findUser(ctx, db, "id = $1", id).
+// Lookups are limited to the tenant ctx is scoped to. Deactivated users aren't found, so
+// they can't sign in and their tokens stop working.
+func findUser(ctx context.Context, db *database.DB, where string, args ...interface{}) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE (` + where + `) AND deactivated_at IS NULL`
+	if id, ok := tenant.FromContext(ctx); ok {
+		args = append(args, id)
+		query += fmt.Sprintf(` AND tenant_id = $%d`, len(args))
+	}
+
+	user := &models.User{}
+	var scopes pq.StringArray
+	err := db.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.TenantID,
+		&user.Email,
+		&user.Name,
+		&user.AuthProvider,
+		&user.IsEmailVerified,
+		&scopes,
+		&user.LastLogin,
+		&user.DefaultOfficeID,
+		&user.HomeAddress,
+		&user.HomeLatitude,
+		&user.HomeLongitude,
+		&user.FocusMinutes,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user.OAuthScopes = []string(scopes)
+	if user.OAuthScopes == nil {
+		user.OAuthScopes = []string{}
+	}
+	return user, nil
+}
+
+// CreateLocalUser creates a user who signs in with a password, in the tenant ctx is
+// scoped to (or the default tenant)
+func CreateLocalUser(ctx context.Context, db *database.DB, email, password, name string) (*models.User, error) {
+	if existing, _ := findUser(ctx, db, "email = $1", email); existing != nil {
+		return nil, fmt.Errorf("user already exists")
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	now := time.Now()
+	query := `INSERT INTO users (id, tenant_id, email, name, password_hash, auth_provider, is_email_verified, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	          RETURNING id, tenant_id, email, name, auth_provider, is_email_verified, version, created_at, updated_at`
+
+	user := &models.User{}
+	err = db.QueryRowContext(ctx, query, uuid.New().String(), tenant.OrDefault(ctx), email, name, string(passwordHash), "local", false, now, now).Scan(
+		&user.ID,
+		&user.TenantID,
+		&user.Email,
+		&user.Name,
+		&user.AuthProvider,
+		&user.IsEmailVerified,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// scopeToTokenTenant scopes ctx to the tenant named by the token's tenant_id claim, or to
+// fallback for tokens without one (an empty fallback leaves ctx as it is). A claim that
+// disagrees with the tenant the request is already scoped to, e.g. by its subdomain, is
+// rejected.
+func scopeToTokenTenant(ctx context.Context, claims jwt.MapClaims, fallback string) (context.Context, error) {
+	claimed, _ := claims["tenant_id"].(string)
+	if claimed == "" {
+		claimed = fallback
+	}
+	if claimed == "" {
+		return ctx, nil
+	}
+	if scoped, ok := tenant.FromContext(ctx); ok && scoped != claimed {
+		return nil, fmt.Errorf("token was issued for another tenant")
+	}
+	return tenant.WithID(ctx, claimed), nil
+}
+
+// errRevocationUnchecked rejects tokens whose revocation couldn't be checked
+var errRevocationUnchecked = errors.New("could not check whether the token has been revoked")
+
+// isTokenRevoked checks the denylist for a token. A denylist that can't be read fails
+// closed: the error is returned and the caller rejects the token.
+func isTokenRevoked(ctx context.Context, denylist TokenDenylist, tokenID string, claims jwt.MapClaims, userID string) (bool, error) {
+	if denylist == nil {
+		return false, nil
+	}
+
+	if tokenID != "" {
+		revoked, err := denylist.IsTokenRevoked(ctx, tokenID)
+		if err != nil {
+			log.Printf("Warning: could not check token denylist: %v", err)
+			return false, errRevocationUnchecked
+		}
+		if revoked {
+			return true, nil
+		}
+	}
+
+	cutoff, err := denylist.UserTokensRevokedBefore(ctx, userID)
+	if err != nil {
+		log.Printf("Warning: could not check user token cutoff: %v", err)
+		return false, errRevocationUnchecked
+	}
+	if cutoff.IsZero() {
+		return false, nil
+	}
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		// Tokens without iat can't be proven newer than the cutoff
+		return true, nil
+	}
+	// iat and the cutoff are whole seconds, so tokens of the cutoff's second can't be told
+	// apart; they are kept, so a sign-in right after logging out everywhere works. Only
+	// tokens issued strictly before it are revoked.
+	return issuedAt.Time.Before(cutoff), nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeDenylist is a TokenDenylist with a fixed set of revoked IDs and per-user cutoffs.
+// Reads fail with err when it's set, like Redis being down.
+type fakeDenylist struct {
+	revoked map[string]bool
+	cutoffs map[string]time.Time
+	err     error
+}
+
+func (d *fakeDenylist) RevokeToken(ctx context.Context, jti string, ttl time.Duration) error {
+	d.revoked[jti] = true
+	return nil
+}
+
+func (d *fakeDenylist) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return d.revoked[jti], d.err
+}
+
+func (d *fakeDenylist) RevokeAllUserTokens(ctx context.Context, userID string, ttl time.Duration) error {
+	d.cutoffs[userID] = time.Unix(time.Now().Unix(), 0)
+	return nil
+}
+
+func (d *fakeDenylist) UserTokensRevokedBefore(ctx context.Context, userID string) (time.Time, error) {
+	return d.cutoffs[userID], d.err
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	cutoff := time.Unix(1_700_000_000, 0)
+	denylist := &fakeDenylist{
+		revoked: map[string]bool{"revoked-jti": true},
+		cutoffs: map[string]time.Time{"logged-out": cutoff},
+	}
+	issuedAt := func(at time.Time) jwt.MapClaims {
+		return jwt.MapClaims{"iat": float64(at.Unix())}
+	}
+
+	tests := []struct {
+		name    string
+		tokenID string
+		claims  jwt.MapClaims
+		userID  string
+		want    bool
+	}{
+		{"no revocation", "jti", issuedAt(cutoff), "other-user", false},
+		{"revoked token ID", "revoked-jti", issuedAt(cutoff), "other-user", true},
+		{"issued before the cutoff", "jti", issuedAt(cutoff.Add(-time.Second)), "logged-out", true},
+		{"issued in the cutoff second", "jti", issuedAt(cutoff), "logged-out", false},
+		{"issued after the cutoff", "jti", issuedAt(cutoff.Add(time.Second)), "logged-out", false},
+		{"no iat with a cutoff", "jti", jwt.MapClaims{}, "logged-out", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := isTokenRevoked(context.Background(), denylist, tt.tokenID, tt.claims, tt.userID); err != nil || got != tt.want {
+				t.Errorf("isTokenRevoked() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	if revoked, err := isTokenRevoked(context.Background(), nil, "revoked-jti", jwt.MapClaims{}, "logged-out"); err != nil || revoked {
+		t.Errorf("isTokenRevoked() with no denylist = %v, %v, want false", revoked, err)
+	}
+	down := &fakeDenylist{err: errors.New("connection refused")}
+	if _, err := isTokenRevoked(context.Background(), down, "jti", issuedAt(cutoff), "logged-out"); err == nil {
+		t.Error("isTokenRevoked() with the denylist down succeeded, want it to fail closed")
+	}
+}
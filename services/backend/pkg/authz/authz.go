@@ -0,0 +1,369 @@
+// Package authz enforces the GraphQL schema's authorization directives:
+//
+//	@auth(requires: Role) on a Query or Mutation field: the operation needs a signed-in
+//	user (USER), a signed-in user acting on their own data or the admin token (OWNER), an
+//	org admin of the user's tenant or the admin token (ORG_ADMIN), or the admin token
+//	(ADMIN)
+//	@owner on an object field: only the user the object belongs to, and admins, can read
+//	it; everyone else gets null
+//	@scope(requires: String) on a Query or Mutation field: requests authenticated with an
+//	API token need the scope; they can't run fields without the directive at all. The
+//	scope only names operations: what they act on is bound to the token's user by the
+//	operations themselves, as for a session.
+//
+// An object belongs to the user in its userId field; a User belongs to itself.
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// Role is what an @auth directive requires
+type Role string
+
+const (
+	RoleUser     Role = "USER"
+	RoleOwner    Role = "OWNER"
+	RoleOrgAdmin Role = "ORG_ADMIN"
+	RoleAdmin    Role = "ADMIN"
+)
+
+// Errors of operations the viewer isn't allowed to run
+var (
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrForbidden       = errors.New("admin access required")
+	ErrOrgForbidden    = errors.New("org admin access required")
+	ErrScopeForbidden  = errors.New("the API token can't run this operation")
+)
+
+// ErrUnknownField rejects operations selecting a root field the schema doesn't have
+var ErrUnknownField = errors.New("cannot query field")
+
+// Viewer is who a request runs as
+type Viewer struct {
+	// UserID is the signed-in user; empty for anonymous requests
+	UserID string
+	// OrgAdmin is set for signed-in org admins of their tenant
+	OrgAdmin bool
+	// Admin is set for requests carrying the admin token
+	Admin bool
+	// Token is set for requests authenticated with an API token, which may only run
+	// operations within its Scopes
+	Token  bool
+	Scopes []string
+}
+
+// Has reports whether the viewer has role. The admin token alone isn't a user: USER
+// operations act on the signed-in user. OWNER operations also serve the admin token, which
+// the AI worker runs with; they check the data is the viewer's with Owns.
+func (v Viewer) Has(role Role) bool {
+	switch role {
+	case RoleAdmin:
+		return v.Admin
+	case RoleOwner:
+		return v.Admin || v.UserID != ""
+	case RoleOrgAdmin:
+		return v.Admin || (v.UserID != "" && v.OrgAdmin)
+	}
+	return v.UserID != ""
+}
+
+// Owns reports whether the viewer may read the @owner fields of an object of userID
+func (v Viewer) Owns(userID string) bool {
+	return v.Admin || (v.UserID != "" && v.UserID == userID)
+}
+
+// HasScope reports whether the viewer may run an operation needing scope
+func (v Viewer) HasScope(scope string) bool {
+	if !v.Token {
+		return true
+	}
+	for _, s := range v.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type adminKey struct{}
+
+type scopesKey struct{}
+
+// WithTokenScopes marks ctx as a request authenticated with an API token of scopes
+func WithTokenScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// TokenScopes returns the scopes of the API token ctx's request was authenticated with;
+// ok is false for requests without one
+func TokenScopes(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(scopesKey{}).([]string)
+	return scopes, ok
+}
+
+// AsAdmin marks ctx as a request carrying the admin token
+func AsAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// IsAdmin reports whether ctx is a request carrying the admin token
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
+
+// Policy is the directives of a schema
+type Policy struct {
+	schema *ast.Schema
+	// owned are the @owner fields of each type
+	owned map[string]map[string]bool
+	// sensitive are the types with @owner fields, directly or in a field's type
+	sensitive map[string]bool
+}
+
+// Load reads the directives of the schema sdl
+func Load(sdl string) (*Policy, error) {
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: "schema.graphql", Input: sdl})
+	if err != nil {
+		return nil, fmt.Errorf("invalid GraphQL schema: %w", err)
+	}
+	p := &Policy{schema: schema, owned: map[string]map[string]bool{}, sensitive: map[string]bool{}}
+	for name, def := range schema.Types {
+		for _, field := range def.Fields {
+			if field.Directives.ForName("owner") == nil {
+				continue
+			}
+			if p.owned[name] == nil {
+				p.owned[name] = map[string]bool{}
+			}
+			p.owned[name][field.Name] = true
+			p.sensitive[name] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, def := range schema.Types {
+			if p.sensitive[name] {
+				continue
+			}
+			for _, field := range def.Fields {
+				if p.sensitive[field.Type.Name()] {
+					p.sensitive[name], changed = true, true
+					break
+				}
+			}
+			// A union is sensitive when one of its members is
+			for _, member := range def.Types {
+				if p.sensitive[member] {
+					p.sensitive[name], changed = true, true
+					break
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// MustLoad is Load for schemas compiled into the binary
+func MustLoad(sdl string) *Policy {
+	p, err := Load(sdl)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Operation is an authorized query: the root type it runs on, for Redact, and the root
+// fields it selects, to run them by
+type Operation struct {
+	Root   string
+	Fields []string
+
+	doc    *ast.QueryDocument
+	fields []*ast.Field
+}
+
+// Has reports whether the operation selects the root field name
+func (o *Operation) Has(name string) bool {
+	for _, field := range o.Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Selects reports whether the operation selects child in the root field name, so nested
+// fields are only loaded when asked for
+func (o *Operation) Selects(name, child string) bool {
+	for _, field := range o.fields {
+		if field.Name != name {
+			continue
+		}
+		for _, selected := range rootFields(o.doc, field.SelectionSet) {
+			if selected.Name == child {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Authorize checks the root fields of query against their @auth directives. Root fields
+// the schema doesn't have are rejected, so the operation's Fields are all known ones.
+func (p *Policy) Authorize(query string, viewer Viewer) (*Operation, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	var root *ast.Definition
+	var fields []string
+	var selected []*ast.Field
+	for _, op := range doc.Operations {
+		root = p.schema.Query
+		switch op.Operation {
+		case ast.Mutation:
+			root = p.schema.Mutation
+		case ast.Subscription:
+			root = p.schema.Subscription
+		}
+		if root == nil {
+			return nil, fmt.Errorf("the schema has no %s type", op.Operation)
+		}
+		for _, field := range rootFields(doc, op.SelectionSet) {
+			fields, selected = append(fields, field.Name), append(selected, field)
+			if field.Name == "__typename" {
+				continue
+			}
+			def := root.Fields.ForName(field.Name)
+			if def == nil {
+				return nil, fmt.Errorf("%w %q on type %q", ErrUnknownField, field.Name, root.Name)
+			}
+			if err := authorizeField(def, viewer); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("invalid query: no operation")
+	}
+	return &Operation{Root: root.Name, Fields: fields, doc: doc, fields: selected}, nil
+}
+
+func authorizeField(def *ast.FieldDefinition, viewer Viewer) error {
+	if viewer.Token {
+		scope := def.Directives.ForName("scope")
+		if scope == nil {
+			return fmt.Errorf("%w: %s isn't available to API tokens", ErrScopeForbidden, def.Name)
+		}
+		// The schema requires the argument
+		if required := scope.Arguments.ForName("requires").Value.Raw; !viewer.HasScope(required) {
+			return fmt.Errorf("%w: %s needs a token with the %s scope", ErrScopeForbidden, def.Name, required)
+		}
+	}
+	directive := def.Directives.ForName("auth")
+	if directive == nil {
+		return nil
+	}
+	role := RoleUser
+	if arg := directive.Arguments.ForName("requires"); arg != nil {
+		role = Role(arg.Value.Raw)
+	}
+	switch {
+	case viewer.Has(role):
+		return nil
+	case role == RoleAdmin:
+		return ErrForbidden
+	case role == RoleOrgAdmin && viewer.UserID != "":
+		return ErrOrgForbidden
+	default:
+		return ErrUnauthenticated
+	}
+}
+
+// rootFields flattens a selection set's fragments into its fields
+func rootFields(doc *ast.QueryDocument, set ast.SelectionSet) []*ast.Field {
+	var fields []*ast.Field
+	for _, selection := range set {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			fields = append(fields, selection)
+		case *ast.InlineFragment:
+			fields = append(fields, rootFields(doc, selection.SelectionSet)...)
+		case *ast.FragmentSpread:
+			if fragment := doc.Fragments.ForName(selection.Name); fragment != nil {
+				fields = append(fields, rootFields(doc, fragment.SelectionSet)...)
+			}
+		}
+	}
+	return fields
+}
+
+// Redact nulls the @owner fields the viewer doesn't own in data, the result of an
+// operation on root keyed by root field
+func (p *Policy) Redact(root string, data map[string]interface{}, viewer Viewer) map[string]interface{} {
+	def := p.schema.Types[root]
+	if def == nil {
+		return data
+	}
+	for key, value := range data {
+		field := def.Fields.ForName(key)
+		if field == nil || !p.sensitive[field.Type.Name()] {
+			continue
+		}
+		// Results are Go values; redact their JSON form
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var generic interface{}
+		if err := json.Unmarshal(encoded, &generic); err != nil {
+			continue
+		}
+		data[key] = p.redact(generic, field.Type.Name(), viewer)
+	}
+	return data
+}
+
+func (p *Policy) redact(value interface{}, typeName string, viewer Viewer) interface{} {
+	switch value := value.(type) {
+	case []interface{}:
+		for i, item := range value {
+			value[i] = p.redact(item, typeName, viewer)
+		}
+	case map[string]interface{}:
+		def := p.schema.Types[typeName]
+		if def != nil && def.Kind == ast.Union {
+			// A union's values name their member type
+			typeName, _ = value["__typename"].(string)
+			def = p.schema.Types[typeName]
+		}
+		if def == nil {
+			return value
+		}
+		ownerField := "userId"
+		if typeName == "User" {
+			ownerField = "id"
+		}
+		owner, _ := value[ownerField].(string)
+		for key, child := range value {
+			field := def.Fields.ForName(key)
+			switch {
+			case field == nil:
+			case p.owned[typeName][key] && !viewer.Owns(owner):
+				value[key] = nil
+			case p.sensitive[field.Type.Name()]:
+				value[key] = p.redact(child, field.Type.Name(), viewer)
+			}
+		}
+	}
+	return value
+}
@@ -0,0 +1,187 @@
+package authz
+
+import (
+	"errors"
+	"testing"
+
+	backend "github.com/commute-planner/backend"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestAuthorize(t *testing.T) {
+	policy, err := Load(backend.Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ada := Viewer{UserID: "ada"}
+
+	if _, err := policy.Authorize(`{ webhookEndpoints { id } }`, Viewer{}); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("anonymous webhookEndpoints: err = %v, want %v", err, ErrUnauthenticated)
+	}
+	if _, err := policy.Authorize(`{ webhookEndpoints { id } }`, Viewer{Admin: true}); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("admin token without a user: err = %v, want %v", err, ErrUnauthenticated)
+	}
+	op, err := policy.Authorize(`mutation Delete($id: ID!) { ...Ops } fragment Ops on Mutation { deleteWebhookEndpoint(id: $id) }`, ada)
+	if err != nil || op.Root != "Mutation" || !op.Has("deleteWebhookEndpoint") {
+		t.Errorf("signed-in deleteWebhookEndpoint: %+v, err %v", op, err)
+	}
+	if _, err := policy.Authorize(`mutation { deleteWebhookEndpoint(id: "1") }`, Viewer{}); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("fragment-free anonymous mutation: err = %v", err)
+	}
+	bulk := `mutation { bulkCreateCalendarEvents(input: []) { created } }`
+	if _, err := policy.Authorize(bulk, Viewer{}); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("anonymous bulkCreateCalendarEvents: err = %v, want %v", err, ErrUnauthenticated)
+	}
+	report := `{ orgReport(from: "2026-03-02", to: "2026-03-08") { officeDays } }`
+	if _, err := policy.Authorize(report, ada); !errors.Is(err, ErrOrgForbidden) {
+		t.Errorf("orgReport by a user: err = %v, want %v", err, ErrOrgForbidden)
+	}
+	if _, err := policy.Authorize(report, Viewer{}); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("anonymous orgReport: err = %v, want %v", err, ErrUnauthenticated)
+	}
+	for _, viewer := range []Viewer{{UserID: "grace", OrgAdmin: true}, {Admin: true}} {
+		if _, err := policy.Authorize(report, viewer); err != nil {
+			t.Errorf("orgReport by %+v: %v", viewer, err)
+		}
+	}
+	// OWNER operations serve signed-in users and the admin token
+	job := `query Job($id: ID!) { job(id: $id) { id ...Plan } } fragment Plan on Job { recommendations { id } }`
+	if _, err := policy.Authorize(job, Viewer{}); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("anonymous job: err = %v, want %v", err, ErrUnauthenticated)
+	}
+	for _, viewer := range []Viewer{ada, {Admin: true}} {
+		op, err := policy.Authorize(job, viewer)
+		if err != nil || !op.Selects("job", "recommendations") || op.Selects("job", "timeline") {
+			t.Errorf("job by %+v: %+v, err %v; want recommendations selected only", viewer, op, err)
+		}
+	}
+	// Job and calendar operations are closed to anonymous requests; the worker's job
+	// updates need the admin token
+	for _, query := range []string{
+		`{ jobs { id } }`,
+		`{ jobEvents(jobId: "1") { id } }`,
+		`{ calendarEvents(userId: "ada") { id } }`,
+		`{ commuteRecommendations(jobId: "1") { id } }`,
+		`mutation { createJob(input: {targetDate: "2026-03-02"}) { id } }`,
+		`mutation { deleteJob(id: "1") }`,
+		`mutation { createCalendarEvent(input: {id: "1", userId: "ada", summary: "Standup"}) { id } }`,
+	} {
+		if _, err := policy.Authorize(query, Viewer{}); !errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("anonymous %s: err = %v, want %v", query, err, ErrUnauthenticated)
+		}
+	}
+	update := `mutation { updateJob(id: "1", input: {progress: 0.5}) { id } }`
+	if _, err := policy.Authorize(update, ada); !errors.Is(err, ErrForbidden) {
+		t.Errorf("updateJob by a user: err = %v, want %v", err, ErrForbidden)
+	}
+	if _, err := policy.Authorize(update, Viewer{Admin: true}); err != nil {
+		t.Errorf("updateJob by the admin token: %v", err)
+	}
+	// Comments, aliases and arguments don't select nested fields
+	op, err = policy.Authorize("{ timeline: job(id: \"recommendations\") { id } # timeline recommendations\n}", ada)
+	if err != nil || op.Selects("job", "timeline") || op.Selects("job", "recommendations") {
+		t.Errorf("job selecting only id: %+v, err %v", op, err)
+	}
+	if op, err := policy.Authorize(`{ health }`, Viewer{}); err != nil || op.Root != "Query" {
+		t.Errorf("public query: %+v, err %v", op, err)
+	}
+	// Fields the schema doesn't have can't slip a protected operation's name past the check
+	for _, query := range []string{
+		`{ x_clientUsage }`,
+		"{ health # clientUsage\n x_orgReport }",
+		`mutation { health_setOrgAdmin }`,
+	} {
+		if _, err := policy.Authorize(query, Viewer{}); !errors.Is(err, ErrUnknownField) {
+			t.Errorf("%s: err = %v, want %v", query, err, ErrUnknownField)
+		}
+	}
+	if op, err := policy.Authorize(`query __ApolloServiceHealthCheck__ { __typename }`, Viewer{}); err != nil || !op.Has("__typename") {
+		t.Errorf("__typename: %+v, err %v", op, err)
+	}
+	if _, err := policy.Authorize(`{ health`, Viewer{}); err == nil {
+		t.Error("unparsable query authorized")
+	}
+}
+
+func TestAuthorizeTokenScopes(t *testing.T) {
+	policy, err := Load(backend.Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := Viewer{UserID: "ada", Token: true, Scopes: []string{string(models.ScopeReadCalendar)}}
+
+	if _, err := policy.Authorize(`{ calendarEvents(userId: "ada") { id } }`, token); err != nil {
+		t.Errorf("calendarEvents with read:calendar: %v", err)
+	}
+	if _, err := policy.Authorize(`mutation { createJob(input: {userId: "ada", targetDate: "2026-03-02"}) { id } }`, token); !errors.Is(err, ErrScopeForbidden) {
+		t.Errorf("createJob without write:jobs: err = %v, want %v", err, ErrScopeForbidden)
+	}
+	// Nor through a field merely named like createJob
+	if _, err := policy.Authorize(`mutation { x_createJob }`, token); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("x_createJob without write:jobs: err = %v, want %v", err, ErrUnknownField)
+	}
+	jobs := `query Jobs($ids: [ID!]!) { jobsByIds(ids: $ids) { id status } }`
+	if _, err := policy.Authorize(jobs, token); !errors.Is(err, ErrScopeForbidden) {
+		t.Errorf("jobsByIds without write:jobs: err = %v, want %v", err, ErrScopeForbidden)
+	}
+	if _, err := policy.Authorize(jobs, Viewer{}); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("anonymous jobsByIds: err = %v, want %v", err, ErrUnauthenticated)
+	}
+	if _, err := policy.Authorize(`{ jobStatuses(userId: "ada") { id status } }`, Viewer{}); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("anonymous jobStatuses: err = %v, want %v", err, ErrUnauthenticated)
+	}
+	// Fields without @scope are closed to every token
+	if _, err := policy.Authorize(`{ webhookEndpoints { id } }`, token); !errors.Is(err, ErrScopeForbidden) {
+		t.Errorf("webhookEndpoints by a token: err = %v, want %v", err, ErrScopeForbidden)
+	}
+	if _, err := policy.Authorize(`{ webhookEndpoints { id } }`, Viewer{UserID: "ada"}); err != nil {
+		t.Errorf("webhookEndpoints by a session: %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	policy, err := Load(backend.Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := func() map[string]interface{} {
+		return map[string]interface{}{"users": []*models.User{
+			{ID: "ada", Email: "ada@example.com", Name: "Ada", OAuthScopes: []string{"calendar"}},
+			{ID: "bob", Email: "bob@example.com", Name: "Bob"},
+		}}
+	}
+
+	data := policy.Redact("Query", users(), Viewer{UserID: "ada"})
+	list := data["users"].([]interface{})
+	ada, bob := list[0].(map[string]interface{}), list[1].(map[string]interface{})
+	if ada["email"] != "ada@example.com" || ada["oauthScopes"] == nil {
+		t.Errorf("own user redacted: %v", ada)
+	}
+	if bob["email"] != nil || bob["name"] != "Bob" {
+		t.Errorf("other user = %v, want the email hidden and the name kept", bob)
+	}
+
+	data = policy.Redact("Query", users(), Viewer{Admin: true})
+	if bob := data["users"].([]interface{})[1].(map[string]interface{}); bob["email"] != "bob@example.com" {
+		t.Errorf("admin sees %v, want every email", bob)
+	}
+
+	// Owned fields nested in other types are redacted too
+	job := map[string]interface{}{"job": map[string]interface{}{
+		"id": "job-1", "userId": "bob", "user": map[string]interface{}{"id": "bob", "email": "bob@example.com"},
+	}}
+	data = policy.Redact("Query", job, Viewer{UserID: "ada"})
+	if user := data["job"].(map[string]interface{})["user"].(map[string]interface{}); user["email"] != nil {
+		t.Errorf("job.user = %v, want the email hidden", user)
+	}
+
+	// Federation entities are redacted as the member type their __typename names
+	entities := map[string]interface{}{"_entities": []interface{}{
+		map[string]interface{}{"__typename": "User", "id": "bob", "email": "bob@example.com"},
+		nil,
+	}}
+	data = policy.Redact("Query", entities, Viewer{UserID: "ada"})
+	if user := data["_entities"].([]interface{})[0].(map[string]interface{}); user["email"] != nil || user["id"] != "bob" {
+		t.Errorf("entity = %v, want the email hidden", user)
+	}
+}
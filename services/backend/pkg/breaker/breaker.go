@@ -0,0 +1,149 @@
+// Package breaker fails calls to an unhealthy dependency fast. After MaxFailures
+// consecutive failures a breaker opens and rejects calls with ErrOpen for OpenTimeout,
+// then lets trial calls through and closes again once they succeed. Callers degrade as
+// they already do when the dependency errors - jobs wait in the outbox, recommendation
+// text comes only from the AI service, locations are geocoded later - without every
+// request first waiting out a timeout and tying up a goroutine.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/sony/gobreaker"
+)
+
+// ErrOpen is returned (wrapped with the breaker's name) for calls rejected by an open breaker
+var ErrOpen = errors.New("circuit breaker open")
+
+// Config tunes a breaker
+type Config struct {
+	// MaxFailures consecutive failures open the breaker. 0 uses 5.
+	MaxFailures uint32
+	// OpenTimeout is how long it stays open before trial calls are let through. 0 uses 30s.
+	OpenTimeout time.Duration
+	// HalfOpenCalls is how many trial calls may run at once; that many successes close it.
+	// 0 uses 1.
+	HalfOpenCalls uint32
+	// IsFailure reports whether an error means the dependency is unhealthy, as opposed to
+	// a bad request or a missing row. Nil counts every error except a cancelled context.
+	IsFailure func(error) bool
+}
+
+// Breaker guards one dependency. A nil *Breaker lets every call through, so optional
+// breakers need no checks at call sites.
+type Breaker struct {
+	name      string
+	cb        *gobreaker.TwoStepCircuitBreaker
+	isFailure func(error) bool
+}
+
+// New creates a breaker, exported in metrics and logs as name
+func New(name string, cfg Config) *Breaker {
+	if cfg.MaxFailures == 0 {
+		cfg.MaxFailures = 5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenCalls == 0 {
+		cfg.HalfOpenCalls = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool { return !errors.Is(err, context.Canceled) }
+	}
+
+	b := &Breaker{name: name, isFailure: cfg.IsFailure}
+	b.cb = gobreaker.NewTwoStepCircuitBreaker(gobreaker.Settings{
+		Name:        name,
+		MaxRequests: cfg.HalfOpenCalls,
+		Timeout:     cfg.OpenTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= cfg.MaxFailures
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			log.Printf("Circuit breaker %s: %s -> %s", name, from, to)
+			metrics.CircuitBreakerState.WithLabelValues(name).Set(float64(to))
+		},
+	})
+	metrics.CircuitBreakerState.WithLabelValues(name).Set(float64(gobreaker.StateClosed))
+	return b
+}
+
+// Name returns the breaker's name
+func (b *Breaker) Name() string {
+	if b == nil {
+		return ""
+	}
+	return b.name
+}
+
+// Open reports whether the breaker is rejecting calls
+func (b *Breaker) Open() bool {
+	return b != nil && b.cb.State() == gobreaker.StateOpen
+}
+
+// Allow admits one call, or returns ErrOpen. An admitted call must be reported with done,
+// passing its error.
+func (b *Breaker) Allow() (done func(err error), err error) {
+	if b == nil {
+		return func(error) {}, nil
+	}
+	report, err := b.cb.Allow()
+	if err != nil {
+		// Open, or half-open with its trial calls already running
+		metrics.CircuitBreakerRejections.WithLabelValues(b.name).Inc()
+		return nil, fmt.Errorf("%s: %w", b.name, ErrOpen)
+	}
+	return func(err error) {
+		report(err == nil || !b.isFailure(err))
+	}, nil
+}
+
+// Do runs fn unless the breaker is open, counting its error
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err)
+	return err
+}
+
+// Transport guards HTTP calls to an external API: requests are rejected while the breaker
+// is open, and connection errors, 5xx and 429 responses count as failures. base nil uses
+// http.DefaultTransport; with a nil breaker base is returned as is.
+func Transport(b *Breaker, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if b == nil {
+		return base
+	}
+	return &transport{breaker: b, base: base}
+}
+
+type transport struct {
+	breaker *Breaker
+	base    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done, err := t.breaker.Allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && (resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests) {
+		done(fmt.Errorf("%s", resp.Status))
+	} else {
+		done(err)
+	}
+	return resp, err
+}
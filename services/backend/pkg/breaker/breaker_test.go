@@ -0,0 +1,113 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	errDown := errors.New("connection refused")
+	errNotFound := errors.New("not found")
+	b := New("test", Config{
+		MaxFailures: 3,
+		OpenTimeout: 50 * time.Millisecond,
+		IsFailure:   func(err error) bool { return !errors.Is(err, errNotFound) },
+	})
+	fail := func() error { return errDown }
+	succeed := func() error { return nil }
+
+	for i := 0; i < 5; i++ {
+		if err := b.Do(func() error { return errNotFound }); !errors.Is(err, errNotFound) {
+			t.Fatalf("Do = %v, want the call's own error", err)
+		}
+	}
+	if b.Open() {
+		t.Fatal("breaker opened on errors that aren't failures")
+	}
+
+	for i := 0; i < 3; i++ {
+		b.Do(fail)
+	}
+	if !b.Open() {
+		t.Fatal("breaker still closed after 3 consecutive failures")
+	}
+	called := false
+	if err := b.Do(func() error { called = true; return nil }); !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("Do = %v (called %v), want ErrOpen without calling", err, called)
+	}
+
+	// After the open timeout a trial call goes through and closes it
+	time.Sleep(60 * time.Millisecond)
+	if err := b.Do(succeed); err != nil {
+		t.Fatalf("trial call = %v, want it let through", err)
+	}
+	if b.Open() {
+		t.Error("breaker still open after a successful trial call")
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	var b *Breaker
+	if err := b.Do(func() error { return nil }); err != nil || b.Open() {
+		t.Errorf("nil breaker: Do = %v, Open = %v; want calls let through", err, b.Open())
+	}
+}
+
+func TestCancellationIsNotAFailure(t *testing.T) {
+	b := New("cancel", Config{MaxFailures: 1})
+	b.Do(func() error { return context.Canceled })
+	if b.Open() {
+		t.Error("a cancelled call opened the breaker")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	b := New("api", Config{MaxFailures: 2, OpenTimeout: time.Hour})
+	client := &http.Client{Transport: Transport(b, nil)}
+	get := func() (*http.Response, error) {
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	status.Store(http.StatusNotFound)
+	for i := 0; i < 3; i++ {
+		if _, err := get(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.Open() {
+		t.Fatal("4xx responses opened the breaker")
+	}
+
+	status.Store(http.StatusServiceUnavailable)
+	for i := 0; i < 2; i++ {
+		resp, err := get()
+		if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("get = %v, %v; want the 503 passed through", resp, err)
+		}
+	}
+	before := calls.Load()
+	if _, err := get(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("get = %v, want ErrOpen", err)
+	}
+	if calls.Load() != before {
+		t.Error("an open breaker still sent the request")
+	}
+}
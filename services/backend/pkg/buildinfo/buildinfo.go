@@ -0,0 +1,66 @@
+// Package buildinfo describes the running build: the commit it was built from, when, and
+// the GraphQL schema it serves, so instances of a rollout can be told apart.
+package buildinfo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	backend "github.com/commute-planner/backend"
+)
+
+// Commit and BuildTime are set when building:
+//
+//	go build -ldflags "-X github.com/commute-planner/backend/pkg/buildinfo.Commit=$(git rev-parse HEAD)
+//	  -X github.com/commute-planner/backend/pkg/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without them they are read from the VCS stamp the Go toolchain embeds in builds from a
+// git checkout, or left "unknown".
+var (
+	Commit    string
+	BuildTime string
+)
+
+// Info is the running build
+type Info struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+	// SchemaVersion is a hash of the GraphQL schema; instances serving the same schema
+	// report the same version
+	SchemaVersion string `json:"schemaVersion"`
+	// Instance is the host name, e.g. the pod name
+	Instance string `json:"instance"`
+}
+
+// Get returns the running build's info
+func Get() Info {
+	info := Info{Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version(), SchemaVersion: SchemaVersion()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	info.Instance, _ = os.Hostname()
+	return info
+}
+
+// SchemaVersion returns the first 12 hex digits of the SHA-256 of the GraphQL schema
+func SchemaVersion() string {
+	sum := sha256.Sum256([]byte(backend.Schema))
+	return hex.EncodeToString(sum[:6])
+}
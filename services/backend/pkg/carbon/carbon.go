@@ -0,0 +1,145 @@
+// Package carbon estimates the CO2 of commute options from their travel modes and
+// distances, and is what commute history and carbon stats are totalled with.
+package carbon
+
+import (
+	"math"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// KgPerKm is the average CO2 per passenger-km of each travel mode: a petrol car and a mix
+// of bus and rail. Mirrors CO2_KG_PER_KM in the AI service's utils/travel_modes.py.
+var KgPerKm = map[models.TravelMode]float64{
+	models.TravelModeDrive:   0.171,
+	models.TravelModeTransit: 0.041,
+	models.TravelModeBike:    0,
+	models.TravelModeWalk:    0,
+}
+
+// TripKg is the CO2 of travelling km by mode; unknown modes count as driving
+func TripKg(mode models.TravelMode, km float64) float64 {
+	factor, ok := KgPerKm[mode]
+	if !ok {
+		factor = KgPerKm[models.TravelModeDrive]
+	}
+	return km * factor
+}
+
+// Estimate returns the emissions of rec's travel. Trips come from its travel legs when
+// they carry distances, otherwise from the chosen mode option (two trips for a plain
+// office day). Options without travel emit nothing; nil means the distances aren't
+// known, e.g. for recommendations planned before travel modes existed.
+func Estimate(rec *models.CommuteRecommendation) *models.Emissions {
+	legs := rec.DecodeTravelLegs()
+	options := rec.DecodeModeOptions()
+	if rec.CommuteStart == nil && len(legs) == 0 {
+		return &models.Emissions{ByMode: []models.ModeEmissions{}}
+	}
+
+	mode := models.TravelModeDrive
+	if rec.TravelMode != nil {
+		mode = *rec.TravelMode
+	}
+	var chosen, drive *models.ModeOption
+	for i, option := range options {
+		if option.Chosen || (chosen == nil && option.Mode == mode) {
+			chosen = &options[i]
+		}
+		if option.Mode == models.TravelModeDrive {
+			drive = &options[i]
+		}
+	}
+
+	var trips []models.ModeEmissions
+	switch {
+	case len(legs) > 0 && legsHaveDistances(legs):
+		for _, l := range legs {
+			legMode := mode
+			if l.Mode != nil {
+				if _, known := KgPerKm[*l.Mode]; known {
+					legMode = *l.Mode
+				}
+			}
+			trips = append(trips, models.ModeEmissions{Mode: legMode, Trips: 1, DistanceKm: *l.DistanceKm})
+		}
+	case chosen != nil && chosen.DistanceKm != nil:
+		count := len(legs)
+		if count == 0 {
+			count = 2
+		}
+		trips = append(trips, models.ModeEmissions{Mode: chosen.Mode, Trips: count, DistanceKm: *chosen.DistanceKm})
+	default:
+		return nil
+	}
+
+	emissions := &models.Emissions{ByMode: SumByMode(trips)}
+	for _, m := range emissions.ByMode {
+		emissions.Trips += m.Trips
+		emissions.DistanceKm += m.DistanceKm
+		emissions.CO2Kg += m.CO2Kg
+	}
+	// Transit and bike routes differ from the road route, so the drive baseline uses the
+	// driving distance when the drive was compared
+	if drive != nil && drive.DistanceKm != nil {
+		emissions.DriveCO2Kg = TripKg(models.TravelModeDrive, *drive.DistanceKm)
+	} else {
+		emissions.DriveCO2Kg = TripKg(models.TravelModeDrive, emissions.DistanceKm)
+	}
+	emissions.SavedCO2Kg = math.Max(0, emissions.DriveCO2Kg-emissions.CO2Kg)
+
+	emissions.DistanceKm = Round(emissions.DistanceKm)
+	emissions.CO2Kg = Round(emissions.CO2Kg)
+	emissions.DriveCO2Kg = Round(emissions.DriveCO2Kg)
+	emissions.SavedCO2Kg = Round(emissions.SavedCO2Kg)
+	return emissions
+}
+
+// SumByMode adds up travel per mode, in models.TravelModes order. Entries without a CO2
+// figure have it estimated from their distance.
+func SumByMode(lists ...[]models.ModeEmissions) []models.ModeEmissions {
+	totals := map[models.TravelMode]*models.ModeEmissions{}
+	for _, list := range lists {
+		for _, m := range list {
+			total, ok := totals[m.Mode]
+			if !ok {
+				total = &models.ModeEmissions{Mode: m.Mode}
+				totals[m.Mode] = total
+			}
+			co2 := m.CO2Kg
+			if co2 == 0 {
+				co2 = TripKg(m.Mode, m.DistanceKm)
+			}
+			total.Trips += m.Trips
+			total.DistanceKm += m.DistanceKm
+			total.CO2Kg += co2
+		}
+	}
+
+	byMode := []models.ModeEmissions{}
+	for _, mode := range models.TravelModes {
+		if total, ok := totals[mode]; ok {
+			byMode = append(byMode, models.ModeEmissions{
+				Mode:       mode,
+				Trips:      total.Trips,
+				DistanceKm: Round(total.DistanceKm),
+				CO2Kg:      Round(total.CO2Kg),
+			})
+		}
+	}
+	return byMode
+}
+
+// Round rounds kg and km to two decimals for display
+func Round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func legsHaveDistances(legs []models.TravelLeg) bool {
+	for _, l := range legs {
+		if l.DistanceKm == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package carbon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestTripKg(t *testing.T) {
+	tests := []struct {
+		mode models.TravelMode
+		km   float64
+		want float64
+	}{
+		{models.TravelModeDrive, 10, 1.71},
+		{models.TravelModeTransit, 10, 0.41},
+		{models.TravelModeBike, 10, 0},
+		{models.TravelModeWalk, 10, 0},
+		{"FERRY", 10, 1.71},
+		{models.TravelModeDrive, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := Round(TripKg(tt.mode, tt.km)); got != tt.want {
+			t.Errorf("TripKg(%s, %v) = %v, want %v", tt.mode, tt.km, got, tt.want)
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	mode := func(m models.TravelMode) *models.TravelMode { return &m }
+	text := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		rec  models.CommuteRecommendation
+		want *models.Emissions
+	}{
+		{
+			name: "no travel",
+			rec:  models.CommuteRecommendation{},
+			want: &models.Emissions{ByMode: []models.ModeEmissions{}},
+		},
+		{
+			name: "distances unknown",
+			rec:  models.CommuteRecommendation{CommuteStart: &start, TravelMode: mode(models.TravelModeDrive)},
+			want: nil,
+		},
+		{
+			name: "malformed columns count as unset",
+			rec:  models.CommuteRecommendation{CommuteStart: &start, TravelLegs: text("["), ModeOptions: text("{")},
+			want: nil,
+		},
+		{
+			name: "chosen option, drive baseline from the road distance",
+			rec: models.CommuteRecommendation{
+				CommuteStart: &start,
+				TravelMode:   mode(models.TravelModeTransit),
+				ModeOptions:  text(`[{"mode": "DRIVE", "distance_km": 20}, {"mode": "TRANSIT", "distance_km": 22, "chosen": true}]`),
+			},
+			want: &models.Emissions{
+				Trips: 2, DistanceKm: 22, CO2Kg: 0.9, DriveCO2Kg: 3.42, SavedCO2Kg: 2.52,
+				ByMode: []models.ModeEmissions{{Mode: models.TravelModeTransit, Trips: 2, DistanceKm: 22, CO2Kg: 0.9}},
+			},
+		},
+		{
+			name: "option matching the travel mode when none is chosen",
+			rec: models.CommuteRecommendation{
+				CommuteStart: &start,
+				TravelMode:   mode(models.TravelModeBike),
+				ModeOptions:  text(`[{"mode": "DRIVE", "distance_km": 20}, {"mode": "BIKE", "distance_km": 18}]`),
+			},
+			want: &models.Emissions{
+				Trips: 2, DistanceKm: 18, CO2Kg: 0, DriveCO2Kg: 3.42, SavedCO2Kg: 3.42,
+				ByMode: []models.ModeEmissions{{Mode: models.TravelModeBike, Trips: 2, DistanceKm: 18}},
+			},
+		},
+		{
+			name: "legs with distances, mixed modes",
+			rec: models.CommuteRecommendation{
+				CommuteStart: &start,
+				TravelMode:   mode(models.TravelModeTransit),
+				TravelLegs: text(`[{"mode": "TRANSIT", "distance_km": 12}, {"mode": "DRIVE", "distance_km": 8},
+					{"mode": "TRANSIT", "distance_km": 12}]`),
+			},
+			want: &models.Emissions{
+				Trips: 3, DistanceKm: 32, CO2Kg: 2.35, DriveCO2Kg: 5.47, SavedCO2Kg: 3.12,
+				ByMode: []models.ModeEmissions{
+					{Mode: models.TravelModeDrive, Trips: 1, DistanceKm: 8, CO2Kg: 1.37},
+					{Mode: models.TravelModeTransit, Trips: 2, DistanceKm: 24, CO2Kg: 0.98},
+				},
+			},
+		},
+		{
+			name: "unknown leg mode counts as the option's mode",
+			rec: models.CommuteRecommendation{
+				TravelMode: mode(models.TravelModeBike),
+				TravelLegs: text(`[{"mode": "FERRY", "distance_km": 10}]`),
+			},
+			want: &models.Emissions{
+				Trips: 1, DistanceKm: 10, CO2Kg: 0, DriveCO2Kg: 1.71, SavedCO2Kg: 1.71,
+				ByMode: []models.ModeEmissions{{Mode: models.TravelModeBike, Trips: 1, DistanceKm: 10}},
+			},
+		},
+		{
+			name: "legs without distances take the chosen option, one trip per leg",
+			rec: models.CommuteRecommendation{
+				TravelMode:  mode(models.TravelModeDrive),
+				TravelLegs:  text(`[{"mode": "DRIVE"}, {"mode": "DRIVE"}, {"mode": "DRIVE"}]`),
+				ModeOptions: text(`[{"mode": "DRIVE", "distance_km": 30, "chosen": true}]`),
+			},
+			want: &models.Emissions{
+				Trips: 3, DistanceKm: 30, CO2Kg: 5.13, DriveCO2Kg: 5.13, SavedCO2Kg: 0,
+				ByMode: []models.ModeEmissions{{Mode: models.TravelModeDrive, Trips: 3, DistanceKm: 30, CO2Kg: 5.13}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Estimate(&tt.rec); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Estimate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumByMode(t *testing.T) {
+	got := SumByMode(
+		[]models.ModeEmissions{
+			{Mode: models.TravelModeWalk, Trips: 2, DistanceKm: 3},
+			{Mode: models.TravelModeDrive, Trips: 2, DistanceKm: 10},
+		},
+		[]models.ModeEmissions{
+			// A recorded figure is kept rather than re-estimated
+			{Mode: models.TravelModeDrive, Trips: 1, DistanceKm: 5, CO2Kg: 2},
+		},
+		nil,
+	)
+	want := []models.ModeEmissions{
+		{Mode: models.TravelModeDrive, Trips: 3, DistanceKm: 15, CO2Kg: 3.71},
+		{Mode: models.TravelModeWalk, Trips: 2, DistanceKm: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SumByMode() = %+v, want %+v", got, want)
+	}
+
+	if got := SumByMode(); got == nil || len(got) != 0 {
+		t.Fatalf("SumByMode() with nothing = %#v, want an empty list", got)
+	}
+}
@@ -0,0 +1,80 @@
+// Package clientinfo identifies the client application a request comes from, by the
+// X-Client-Name and X-Client-Version headers it sends. Apollo clients' own
+// apollographql-client-name and apollographql-client-version headers are read too. The
+// client is stored with the jobs and analytics events a request creates, and the
+// operations each client version runs are counted, so breaking changes can be timed by
+// the versions they would break.
+package clientinfo
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Unknown names clients that don't identify themselves, and their version
+const Unknown = "unknown"
+
+// maxLength bounds the stored name and version; longer ones are cut
+const maxLength = 64
+
+// Client is a client application and its version
+type Client struct {
+	Name    string
+	Version string
+}
+
+// FromRequest returns the client r comes from
+func FromRequest(r *http.Request) Client {
+	return Client{
+		Name:    header(r, "X-Client-Name", "Apollographql-Client-Name"),
+		Version: header(r, "X-Client-Version", "Apollographql-Client-Version"),
+	}
+}
+
+// header returns the first of names r carries, trimmed and bounded, or Unknown
+func header(r *http.Request, names ...string) string {
+	for _, name := range names {
+		value := strings.TrimSpace(r.Header.Get(name))
+		if len(value) > maxLength {
+			value = value[:maxLength]
+		}
+		if value != "" {
+			return value
+		}
+	}
+	return Unknown
+}
+
+type clientKey struct{}
+
+// WithClient marks ctx as a request from client
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// FromContext returns the client ctx's request comes from; Unknown for requests not
+// marked with WithClient
+func FromContext(ctx context.Context) Client {
+	if client, ok := Lookup(ctx); ok {
+		return client
+	}
+	return Client{Name: Unknown, Version: Unknown}
+}
+
+// Lookup returns the client ctx's request comes from; ok is false for work not started by
+// a client request, such as background jobs
+func Lookup(ctx context.Context) (client Client, ok bool) {
+	client, ok = ctx.Value(clientKey{}).(Client)
+	return client, ok
+}
+
+// Fields returns the client's name and version as stored with jobs and analytics events:
+// nil for work not started by a client request
+func Fields(ctx context.Context) (name, version *string) {
+	client, ok := Lookup(ctx)
+	if !ok {
+		return nil, nil
+	}
+	return &client.Name, &client.Version
+}
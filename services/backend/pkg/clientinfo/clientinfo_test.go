@@ -0,0 +1,30 @@
+package clientinfo
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFromRequest(t *testing.T) {
+	r := httptest.NewRequest("POST", "/graphql", nil)
+	r.Header.Set("X-Client-Name", " web ")
+	r.Header.Set("Apollographql-Client-Name", "ios")
+	r.Header.Set("Apollographql-Client-Version", strings.Repeat("9", 100))
+	client := FromRequest(r)
+	if client.Name != "web" || client.Version != strings.Repeat("9", maxLength) {
+		t.Errorf("client = %+v", client)
+	}
+
+	if client := FromRequest(httptest.NewRequest("POST", "/graphql", nil)); client.Name != Unknown || client.Version != Unknown {
+		t.Errorf("anonymous client = %+v", client)
+	}
+	if client := FromContext(context.Background()); client.Name != Unknown {
+		t.Errorf("client of an unmarked context = %+v", client)
+	}
+	ctx := WithClient(context.Background(), Client{Name: "web", Version: "1.2.0"})
+	if client := FromContext(ctx); client.Name != "web" || client.Version != "1.2.0" {
+		t.Errorf("client = %+v", client)
+	}
+}
@@ -0,0 +1,126 @@
+package clientinfo
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// operationKey is an operation as run by a client version
+type operationKey struct {
+	client    Client
+	operation string
+}
+
+// UsageTracker counts the GraphQL operations each client version runs. Track only adds to
+// counts in memory, so tracking never slows or fails a request; Run writes them.
+type UsageTracker struct {
+	usage    repository.ClientUsageRepository
+	interval time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	counts map[operationKey]*models.ClientOperationUsage
+}
+
+// NewUsageTracker creates a tracker writing counts to usage every interval; call Run to
+// start writing
+func NewUsageTracker(usage repository.ClientUsageRepository, interval time.Duration) *UsageTracker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &UsageTracker{usage: usage, interval: interval, now: time.Now, counts: map[operationKey]*models.ClientOperationUsage{}}
+}
+
+// Track counts a request of client running operation
+func (t *UsageTracker) Track(client Client, operation string) {
+	if len(operation) > maxLength {
+		operation = operation[:maxLength]
+	}
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := operationKey{client: client, operation: operation}
+	count, ok := t.counts[key]
+	if !ok {
+		count = &models.ClientOperationUsage{
+			ClientName:    client.Name,
+			ClientVersion: client.Version,
+			Operation:     operation,
+			FirstUsedAt:   now,
+		}
+		t.counts[key] = count
+	}
+	count.Requests++
+	count.LastUsedAt = now
+}
+
+// Run writes the counts every interval until ctx is cancelled, then writes what is left
+func (t *UsageTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			t.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			t.flush(ctx)
+		}
+	}
+}
+
+// flush writes the counts since the last flush; those that fail to be written are lost
+func (t *UsageTracker) flush(ctx context.Context) {
+	t.mu.Lock()
+	counts := t.counts
+	t.counts = map[operationKey]*models.ClientOperationUsage{}
+	t.mu.Unlock()
+
+	if len(counts) == 0 {
+		return
+	}
+	usage := make([]*models.ClientOperationUsage, 0, len(counts))
+	for _, count := range counts {
+		usage = append(usage, count)
+	}
+	if err := t.usage.Record(ctx, usage); err != nil {
+		log.Printf("Client usage: failed to record %d operation counts: %v", len(usage), err)
+	}
+}
+
+// Report groups usage by client version, the versions sending the most requests first
+func Report(usage []*models.ClientOperationUsage) []*models.ClientUsage {
+	report := []*models.ClientUsage{}
+	byClient := map[Client]*models.ClientUsage{}
+	for _, u := range usage {
+		client := Client{Name: u.ClientName, Version: u.ClientVersion}
+		entry, ok := byClient[client]
+		if !ok {
+			entry = &models.ClientUsage{ClientName: u.ClientName, ClientVersion: u.ClientVersion, FirstUsedAt: u.FirstUsedAt}
+			byClient[client] = entry
+			report = append(report, entry)
+		}
+		entry.Requests += u.Requests
+		if u.FirstUsedAt.Before(entry.FirstUsedAt) {
+			entry.FirstUsedAt = u.FirstUsedAt
+		}
+		if u.LastUsedAt.After(entry.LastUsedAt) {
+			entry.LastUsedAt = u.LastUsedAt
+		}
+		entry.Operations = append(entry.Operations, u)
+	}
+	for _, entry := range report {
+		sort.SliceStable(entry.Operations, func(i, j int) bool { return entry.Operations[i].Requests > entry.Operations[j].Requests })
+	}
+	sort.SliceStable(report, func(i, j int) bool { return report[i].Requests > report[j].Requests })
+	return report
+}
@@ -0,0 +1,48 @@
+package clientinfo
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestUsageTracker(t *testing.T) {
+	ctx := context.Background()
+	usage := repository.NewMemoryClientUsageRepository()
+	tracker := NewUsageTracker(usage, 0)
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	web := Client{Name: "web", Version: "2.1.0"}
+	ios := Client{Name: "ios", Version: "1.0"}
+	tracker.Track(web, "createJob")
+	tracker.Track(web, "jobs")
+	tracker.Track(web, "jobs")
+	tracker.Track(ios, "jobs")
+	tracker.Track(ios, strings.Repeat("a", 100))
+	tracker.flush(ctx)
+	tracker.flush(ctx)
+
+	stored, err := usage.List(ctx, now)
+	if err != nil || len(stored) != 4 {
+		t.Fatalf("usage = %+v, %v", stored, err)
+	}
+	if long := stored[0]; long.ClientName != "ios" || len(long.Operation) != maxLength {
+		t.Errorf("operation name kept at %d characters", len(long.Operation))
+	}
+
+	report := Report(stored)
+	if len(report) != 2 {
+		t.Fatalf("report = %+v, want one entry per client version", report)
+	}
+	first := report[0]
+	if first.ClientName != "web" || first.Requests != 3 || !first.LastUsedAt.Equal(now) {
+		t.Errorf("busiest client = %+v, want web", first)
+	}
+	if ops := first.Operations; len(ops) != 2 || ops[0].Operation != "jobs" || ops[0].Requests != 2 {
+		t.Errorf("web operations = %+v, want jobs first", ops)
+	}
+}
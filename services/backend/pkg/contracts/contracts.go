@@ -0,0 +1,142 @@
+// Package contracts defines the messages the backend and the AI workers exchange: the job
+// message the backend queues, the progress updates the workers publish while running a
+// job and the result they store on it. Each has a JSON Schema in schemas/ and a golden
+// example in testdata/, which the tests hold the Go types to. The AI service checks its
+// own payloads against the same schemas through POST /contracts/{kind}/validate
+// (ai-service/scripts/check_contracts.py), so a change on either side that the other
+// doesn't expect fails CI instead of jobs.
+//
+// Adding an optional field is compatible. Anything else changes both services: update the
+// schema, the golden file (go test ./pkg/contracts -update) and the AI service together.
+package contracts
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/jobinput"
+	"github.com/commute-planner/backend/pkg/jsonschema"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/queue"
+)
+
+// Kind names a message format
+type Kind string
+
+const (
+	// KindJobMessage is a JobMessage
+	KindJobMessage Kind = "job_message"
+	// KindJobProgress is a JobProgress
+	KindJobProgress Kind = "job_progress"
+	// KindJobResult is a JobResult
+	KindJobResult Kind = "job_result"
+)
+
+// Kinds are all the message formats
+var Kinds = []Kind{KindJobMessage, KindJobProgress, KindJobResult}
+
+// ErrUnknownKind is returned for a kind that isn't one of Kinds
+var ErrUnknownKind = errors.New("unknown message kind")
+
+// JobMessage is the job the backend queues for the workers
+type JobMessage = queue.Message
+
+// JobProgress is a progress update a worker publishes while running a job
+type JobProgress struct {
+	JobID        string           `json:"jobId"`
+	Status       models.JobStatus `json:"status"`
+	Progress     *float64         `json:"progress,omitempty"`
+	CurrentStep  *string          `json:"currentStep,omitempty"`
+	Result       *string          `json:"result,omitempty"`
+	ErrorMessage *string          `json:"errorMessage,omitempty"`
+	Timestamp    time.Time        `json:"timestamp"`
+}
+
+// JobResult is the result stored on a completed job
+type JobResult struct {
+	TotalOptions     int  `json:"total_options"`
+	AnalysisComplete bool `json:"analysis_complete"`
+	// Recommendations are the options as the worker presented them. The backend reads
+	// the stored recommendation rows rather than these.
+	Recommendations []map[string]interface{} `json:"recommendations,omitempty"`
+	// Synthetic and Demo mark results the backend made up itself rather than a worker
+	Synthetic bool `json:"synthetic,omitempty"`
+	Demo      bool `json:"demo,omitempty"`
+}
+
+// Encode returns the result as the JSON string stored on the job
+func (r JobResult) Encode() (string, error) {
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode job result: %w", err)
+	}
+	return string(encoded), nil
+}
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+var schemas = map[Kind]*jsonschema.Schema{}
+
+func init() {
+	for _, kind := range Kinds {
+		data, err := Schema(kind)
+		if err != nil {
+			panic(fmt.Sprintf("contracts: %v", err))
+		}
+		s, err := jsonschema.Parse(data)
+		if err != nil {
+			panic(fmt.Sprintf("contracts: %s: %v", kind, err))
+		}
+		schemas[kind] = s
+	}
+}
+
+// Schema returns the JSON Schema of kind
+func Schema(kind Kind) ([]byte, error) {
+	if !known(kind) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKind, kind)
+	}
+	data, err := schemaFiles.ReadFile("schemas/" + string(kind) + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("no schema for %s: %w", kind, err)
+	}
+	return data, nil
+}
+
+// Validate checks that payload, a JSON document, is a valid message of kind. A job
+// message's input_data must also be job input the backend can read, of the current
+// version or one it upgrades.
+func Validate(kind Kind, payload []byte) error {
+	s, ok := schemas[kind]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownKind, kind)
+	}
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return fmt.Errorf("%s: invalid JSON: %w", kind, err)
+	}
+	if err := s.Validate(value, string(kind)); err != nil {
+		return err
+	}
+	if kind == KindJobMessage {
+		if inputData, ok := value.(map[string]interface{})["input_data"].(string); ok {
+			if _, err := jobinput.Normalize(&inputData); err != nil {
+				return fmt.Errorf("%s.input_data: %w", kind, err)
+			}
+		}
+	}
+	return nil
+}
+
+func known(kind Kind) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
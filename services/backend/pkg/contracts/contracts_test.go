@@ -0,0 +1,122 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/jobinput"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files from the Go types")
+
+// examples are the canonical message of each kind, as the Go types encode them
+func examples(t *testing.T) map[Kind]interface{} {
+	t.Helper()
+	inputData, err := jobinput.Normalize(stringPtr(`{"context":{"user_timezone":"Europe/Berlin"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := 0.5
+	return map[Kind]interface{}{
+		KindJobMessage: JobMessage{
+			JobID:      "job-1",
+			UserID:     "user-1",
+			TargetDate: "2026-03-02",
+			InputData:  inputData,
+			Priority:   models.JobPriorityInteractive,
+		},
+		KindJobProgress: JobProgress{
+			JobID:       "job-1",
+			Status:      models.JobStatusInProgress,
+			Progress:    &progress,
+			CurrentStep: stringPtr("Analyzing calendar"),
+			Timestamp:   time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC),
+		},
+		KindJobResult: JobResult{
+			TotalOptions:     1,
+			AnalysisComplete: true,
+			Recommendations: []map[string]interface{}{{
+				"option_rank":     1,
+				"type":            "FULL_DAY_OFFICE",
+				"commute_start":   "2026-03-02T07:30:00Z",
+				"office_arrival":  "2026-03-02T08:15:00Z",
+				"office_meetings": []string{"event-1"},
+				"remote_meetings": []string{},
+				"reasoning":       "All meetings are in the office",
+				"reason_codes":    []map[string]interface{}{{"code": "IN_PERSON_MEETINGS", "params": map[string]interface{}{"count": 1}}},
+			}},
+		},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	for kind, example := range examples(t) {
+		encoded, err := json.MarshalIndent(example, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded = append(encoded, '\n')
+		path := filepath.Join("testdata", string(kind)+".golden.json")
+		if *update {
+			if err := os.WriteFile(path, encoded, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		golden, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encoded, golden) {
+			t.Errorf("%s: the Go type encodes\n%s\nbut %s holds\n%s\nrun go test ./pkg/contracts -update if the format changed on purpose", kind, encoded, path, golden)
+		}
+		if err := Validate(kind, golden); err != nil {
+			t.Errorf("%s: golden file doesn't match the schema: %v", kind, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tt := range []struct {
+		kind    Kind
+		payload string
+		wantErr string
+	}{
+		{KindJobMessage, `{"job_id": "j", "target_date": "2026-03-02"}`, "user_id is required"},
+		{KindJobMessage, `{"job_id": "j", "user_id": "u", "target_date": "2026-03-02", "priority": "LOW"}`, "job_message.priority"},
+		{KindJobMessage, `{"job_id": "j", "user_id": "u", "target_date": "2026-03-02", "input_data": "{\"schemaVersion\": 9}"}`, "job_message.input_data"},
+		{KindJobProgress, `{"jobId": "j", "status": "IN_PROGRESS", "progress": 50, "timestamp": "2026-03-02T07:00:00Z"}`, "at most 1"},
+		{KindJobProgress, `{"jobId": "j", "status": "DONE", "timestamp": "2026-03-02T07:00:00Z"}`, "job_progress.status"},
+		{KindJobResult, `{"total_options": "3", "analysis_complete": true}`, "expected integer"},
+		{KindJobResult, `{"total_options": 1, "analysis_complete": true, "recommendations": [{"reason_codes": [{"params": {}}]}]}`, "code is required"},
+		{KindJobResult, `{`, "invalid JSON"},
+	} {
+		err := Validate(tt.kind, []byte(tt.payload))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Validate(%s, %s) = %v, want %q", tt.kind, tt.payload, err, tt.wantErr)
+		}
+	}
+
+	// What the backend writes without a worker is a valid result too
+	result, err := JobResult{TotalOptions: 3, AnalysisComplete: true, Demo: true}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(KindJobResult, []byte(result)); err != nil {
+		t.Errorf("demo result: %v", err)
+	}
+	if err := Validate("job_event", []byte(`{}`)); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("unknown kind: %v", err)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,50 @@
+// Package costs reads what commute options cost from the mode comparison the AI service
+// plans them with. Costs are priced at planning time from the user's travel profile and
+// the office's parking and congestion charges, so later price changes don't rewrite the
+// commute history.
+package costs
+
+import (
+	"math"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// Estimate returns the cost of rec's chosen travel mode. Options without travel cost
+// nothing; nil means the option wasn't costed. Options planned before the cost was
+// broken down count a drive's cost as fuel and a transit cost as fares.
+func Estimate(rec *models.CommuteRecommendation) *models.CommuteCost {
+	options := rec.DecodeModeOptions()
+	if rec.CommuteStart == nil && len(options) == 0 {
+		return &models.CommuteCost{}
+	}
+
+	var chosen *models.ModeOption
+	for i, option := range options {
+		if option.Chosen || (chosen == nil && rec.TravelMode != nil && option.Mode == *rec.TravelMode) {
+			chosen = &options[i]
+		}
+	}
+	if chosen == nil || chosen.Cost == nil {
+		return nil
+	}
+
+	cost := &models.CommuteCost{Total: Round(*chosen.Cost)}
+	switch {
+	case chosen.CostBreakdown != nil:
+		cost.Fuel = Round(chosen.CostBreakdown.Fuel)
+		cost.Parking = Round(chosen.CostBreakdown.Parking)
+		cost.Congestion = Round(chosen.CostBreakdown.Congestion)
+		cost.Fares = Round(chosen.CostBreakdown.Fares)
+	case chosen.Mode == models.TravelModeDrive:
+		cost.Fuel = cost.Total
+	case chosen.Mode == models.TravelModeTransit:
+		cost.Fares = cost.Total
+	}
+	return cost
+}
+
+// Round rounds an amount to cents
+func Round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
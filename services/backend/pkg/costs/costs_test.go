@@ -0,0 +1,106 @@
+package costs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestEstimate(t *testing.T) {
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	mode := func(m models.TravelMode) *models.TravelMode { return &m }
+	text := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		rec  models.CommuteRecommendation
+		want *models.CommuteCost
+	}{
+		{
+			name: "no travel",
+			rec:  models.CommuteRecommendation{},
+			want: &models.CommuteCost{},
+		},
+		{
+			name: "travel without a mode comparison",
+			rec:  models.CommuteRecommendation{CommuteStart: &start, TravelMode: mode(models.TravelModeDrive)},
+			want: nil,
+		},
+		{
+			name: "malformed mode options",
+			rec:  models.CommuteRecommendation{CommuteStart: &start, ModeOptions: text("{")},
+			want: nil,
+		},
+		{
+			name: "chosen option not costed",
+			rec: models.CommuteRecommendation{
+				CommuteStart: &start,
+				ModeOptions:  text(`[{"mode": "DRIVE", "cost": 12}, {"mode": "BIKE", "chosen": true}]`),
+			},
+			want: nil,
+		},
+		{
+			name: "breakdown, rounded to cents",
+			rec: models.CommuteRecommendation{
+				CommuteStart: &start,
+				TravelMode:   mode(models.TravelModeDrive),
+				ModeOptions: text(`[{"mode": "DRIVE", "cost": 27.456, "chosen": true,
+					"cost_breakdown": {"fuel": 8.456, "parking": 4, "congestion": 15, "fares": 0}}]`),
+			},
+			want: &models.CommuteCost{Total: 27.46, Fuel: 8.46, Parking: 4, Congestion: 15},
+		},
+		{
+			name: "option matching the travel mode when none is chosen",
+			rec: models.CommuteRecommendation{
+				CommuteStart: &start,
+				TravelMode:   mode(models.TravelModeTransit),
+				ModeOptions: text(`[{"mode": "DRIVE", "cost": 12}, {"mode": "TRANSIT", "cost": 5.6,
+					"cost_breakdown": {"fares": 5.6}}]`),
+			},
+			want: &models.CommuteCost{Total: 5.6, Fares: 5.6},
+		},
+		{
+			name: "the chosen flag wins over the travel mode",
+			rec: models.CommuteRecommendation{
+				CommuteStart: &start,
+				TravelMode:   mode(models.TravelModeDrive),
+				ModeOptions:  text(`[{"mode": "DRIVE", "cost": 12}, {"mode": "BIKE", "cost": 0, "chosen": true}]`),
+			},
+			want: &models.CommuteCost{},
+		},
+		{
+			name: "drive without a breakdown counts as fuel",
+			rec: models.CommuteRecommendation{
+				CommuteStart: &start,
+				ModeOptions:  text(`[{"mode": "DRIVE", "cost": 9.5, "chosen": true}]`),
+			},
+			want: &models.CommuteCost{Total: 9.5, Fuel: 9.5},
+		},
+		{
+			name: "transit without a breakdown counts as fares",
+			rec: models.CommuteRecommendation{
+				CommuteStart: &start,
+				ModeOptions:  text(`[{"mode": "TRANSIT", "cost": 6.4, "chosen": true}]`),
+			},
+			want: &models.CommuteCost{Total: 6.4, Fares: 6.4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Estimate(&tt.rec); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Estimate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := map[float64]float64{0: 0, 3.1: 3.1, 12.344: 12.34, 12.346: 12.35, -4.999: -5}
+	for amount, want := range tests {
+		if got := Round(amount); got != want {
+			t.Errorf("Round(%v) = %v, want %v", amount, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/commute-planner/backend/pkg/breaker"
+	"github.com/lib/pq"
+)
+
+// circuit is a pool's circuit breaker. A nil *circuit lets every statement through.
+type circuit struct {
+	b *breaker.Breaker
+	// rejected answers single-row queries while the breaker is open: a *sql.Row can't be
+	// built with an error, but one from a pool that can't connect carries it to Scan
+	rejected *sql.DB
+}
+
+func newCircuit(name string, cfg *breaker.Config) *circuit {
+	if cfg == nil {
+		return nil
+	}
+	settings := *cfg
+	settings.IsFailure = isFailure
+	b := breaker.New(name, settings)
+	return &circuit{b: b, rejected: sql.OpenDB(rejectingConnector{fmt.Errorf("%s: %w", name, breaker.ErrOpen)})}
+}
+
+func (c *circuit) breaker() *breaker.Breaker {
+	if c == nil {
+		return nil
+	}
+	return c.b
+}
+
+func (c *circuit) allow() (func(error), error) {
+	return c.breaker().Allow()
+}
+
+// begin starts a transaction on db through the breaker
+func (c *circuit) begin(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*sql.Tx, error) {
+	done, err := c.allow()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.BeginTx(ctx, opts)
+	done(err)
+	return tx, err
+}
+
+func (c *circuit) close() {
+	if c != nil {
+		c.rejected.Close()
+	}
+}
+
+// isFailure counts errors meaning Postgres is unreachable, overloaded or too slow, not
+// ones caused by the statement itself (constraint violations, missing rows)
+func isFailure(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, sql.ErrNoRows) || errors.Is(err, sql.ErrTxDone) {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		// Connection exception, insufficient resources, operator intervention (which
+		// includes statement timeouts), system error, internal error
+		case "08", "53", "57", "58", "XX":
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// rejectingConnector fails every connection attempt with err
+type rejectingConnector struct {
+	err error
+}
+
+func (r rejectingConnector) Connect(context.Context) (driver.Conn, error) { return nil, r.err }
+func (r rejectingConnector) Driver() driver.Driver                        { return rejectingDriver(r) }
+
+type rejectingDriver struct {
+	err error
+}
+
+func (d rejectingDriver) Open(string) (driver.Conn, error) { return nil, d.err }
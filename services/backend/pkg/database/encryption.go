@@ -0,0 +1,60 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/commute-planner/backend/pkg/encryption"
+)
+
+// Columns whose values repositories encrypt when they are enabled with SetEncryption
+const (
+	ColumnOAuthTokens      = "users.oauth_tokens"
+	ColumnEventDescription = "calendar_events.description"
+)
+
+// SetEncryption makes repositories encrypt the values they write to columns, such as
+// ColumnOAuthTokens, with keys. Encrypted values are decrypted on read whether or not
+// their column is listed, so a column can stop being encrypted without losing data. It is
+// called once, before the connection is used.
+func (db *DB) SetEncryption(keys *encryption.Keyring, columns ...string) {
+	db.keys = keys
+	db.encrypted = map[string]bool{}
+	for _, column := range columns {
+		db.encrypted[column] = true
+	}
+}
+
+// Encryption returns the keys values are encrypted with, nil when encryption is off
+func (db *DB) Encryption() *encryption.Keyring {
+	return db.keys
+}
+
+// Encrypts reports whether values written to column are encrypted
+func (db *DB) Encrypts(column string) bool {
+	return db.keys != nil && db.encrypted[column]
+}
+
+// EncryptColumn returns value as it should be written to column: encrypted when the
+// column is, otherwise unchanged
+func (db *DB) EncryptColumn(column string, value *string) (*string, error) {
+	if value == nil || !db.Encrypts(column) {
+		return value, nil
+	}
+	encrypted, err := db.keys.Encrypt(*value, column)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting %s: %w", column, err)
+	}
+	return &encrypted, nil
+}
+
+// DecryptColumn returns the plaintext of a value read from column
+func (db *DB) DecryptColumn(column string, value *string) (*string, error) {
+	if value == nil || !encryption.IsEncrypted(*value) {
+		return value, nil
+	}
+	decrypted, err := db.keys.Decrypt(*value, column)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s: %w", column, err)
+	}
+	return &decrypted, nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// skippedPostgresMigrations are covered by schemas/init.sql, which creates every database:
+// 001_initial_setup duplicates it and 002_functions_and_triggers only adds helpers the
+// backend doesn't use along with a view later migrations can't alter
+var skippedPostgresMigrations = map[string]bool{
+	"001_initial_setup":          true,
+	"002_functions_and_triggers": true,
+}
+
+// MigratePostgres applies the migrations in dir (database/migrations) that aren't recorded
+// in schema_migrations, in file name order, each in its own transaction, and returns the
+// versions applied. Databases migrated before versions were recorded should be baselined
+// once: baseline records every migration as applied without running any, since not all of
+// them can run twice.
+func MigratePostgres(ctx context.Context, db *sql.DB, dir string, baseline bool) ([]string, error) {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version TEXT PRIMARY KEY,
+		applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no migrations found in %s", dir)
+	}
+	sort.Strings(files)
+
+	var applied []string
+	for _, file := range files {
+		version := strings.TrimSuffix(filepath.Base(file), ".sql")
+		if skippedPostgresMigrations[version] {
+			continue
+		}
+
+		var count int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations WHERE version = $1`, version).Scan(&count); err != nil {
+			return applied, fmt.Errorf("failed to check migration %s: %w", version, err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		script, err := os.ReadFile(file)
+		if err != nil {
+			return applied, err
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return applied, err
+		}
+		if !baseline {
+			if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+				tx.Rollback()
+				return applied, fmt.Errorf("migration %s failed: %w", version, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("failed to record migration %s: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return applied, err
+		}
+		applied = append(applied, version)
+	}
+	return applied, nil
+}
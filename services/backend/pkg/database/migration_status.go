@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// RequiredMigration is the latest Postgres migration this build's queries rely on. It
+// moves with every migration added to database/migrations; a test keeps them in step.
+const RequiredMigration = "046_client_usage"
+
+// Migration statuses
+const (
+	// MigrationsCurrent: the database has every migration the build needs, and no later one
+	MigrationsCurrent = "current"
+	// MigrationsPending: migrations the build needs haven't been applied yet
+	MigrationsPending = "pending"
+	// MigrationsAhead: a newer build has migrated the database, as during a rollout
+	MigrationsAhead = "ahead"
+)
+
+// MigrationStatus compares the database's migrations with the build's
+type MigrationStatus struct {
+	// Latest is the latest migration applied; empty before any was recorded
+	Latest   string `json:"latest"`
+	Required string `json:"required"`
+	Status   string `json:"status"`
+}
+
+// MigrationStatus reads the latest migration recorded in schema_migrations. SQLite
+// databases are migrated from the build's own migrations when opened, so they are always
+// current.
+func (db *DB) MigrationStatus(ctx context.Context) (*MigrationStatus, error) {
+	ctx, cancel := db.WithReadTimeout(ctx)
+	defer cancel()
+
+	var latest sql.NullString
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&latest); err != nil {
+		return nil, err
+	}
+	status := &MigrationStatus{Latest: latest.String, Required: RequiredMigration}
+	switch {
+	case db.Driver() == DriverSQLite:
+		status.Required, status.Status = latest.String, MigrationsCurrent
+	case latest.String < RequiredMigration:
+		status.Status = MigrationsPending
+	case latest.String > RequiredMigration:
+		status.Status = MigrationsAhead
+	default:
+		status.Status = MigrationsCurrent
+	}
+	return status, nil
+}
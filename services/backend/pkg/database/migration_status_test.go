@@ -0,0 +1,19 @@
+package database
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRequiredMigration(t *testing.T) {
+	files, err := filepath.Glob("../../../../database/migrations/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Skip("database/migrations isn't checked out")
+	}
+	sort.Strings(files)
+	if latest := strings.TrimSuffix(filepath.Base(files[len(files)-1]), ".sql"); latest != RequiredMigration {
+		t.Errorf("RequiredMigration = %s, want the latest migration, %s", RequiredMigration, latest)
+	}
+}
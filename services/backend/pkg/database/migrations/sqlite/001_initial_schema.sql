@@ -0,0 +1,87 @@
+-- SQLite schema for local development and tests.
+-- Mirrors database/schemas/init.sql plus database/migrations 001-003 with Postgres types mapped
+-- to SQLite equivalents: UUID/JSONB/enums -> TEXT (enums enforced with CHECK), TEXT[] -> TEXT
+-- holding a Postgres array literal (read via pq.StringArray), INTERVAL -> TEXT.
+-- Keep in sync when adding Postgres migrations.
+
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    google_calendar_token TEXT,
+    user_preferences TEXT,
+    password_hash VARCHAR(255),
+    auth_provider VARCHAR(50) DEFAULT 'local',
+    external_id VARCHAR(255),
+    oauth_tokens TEXT,
+    oauth_scopes TEXT,
+    is_email_verified BOOLEAN DEFAULT FALSE,
+    last_login TIMESTAMP,
+    preferred_timezone VARCHAR(50) DEFAULT 'UTC',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_users_provider_external_id
+ON users(auth_provider, external_id)
+WHERE auth_provider != 'local';
+
+CREATE TABLE jobs (
+    id TEXT PRIMARY KEY,
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'IN_PROGRESS', 'COMPLETED', 'FAILED')),
+    progress REAL DEFAULT 0.0,
+    current_step VARCHAR(255),
+    target_date DATE NOT NULL,
+    input_data TEXT,
+    result TEXT,
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- meeting_type/attendance_mode are not CHECK-constrained: the Go models use values
+-- (e.g. MUST_BE_IN_PERSON) that predate the Postgres enum definitions.
+CREATE TABLE calendar_events (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    summary VARCHAR(500) NOT NULL,
+    description TEXT,
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP NOT NULL,
+    location VARCHAR(255),
+    attendees TEXT DEFAULT '[]',
+    meeting_type TEXT DEFAULT 'UNKNOWN',
+    attendance_mode TEXT DEFAULT 'FLEXIBLE',
+    is_all_day BOOLEAN DEFAULT FALSE,
+    is_recurring BOOLEAN DEFAULT FALSE,
+    google_event_id VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE commute_recommendations (
+    id TEXT PRIMARY KEY,
+    job_id TEXT REFERENCES jobs(id) ON DELETE CASCADE,
+    option_rank INTEGER NOT NULL,
+    option_type TEXT NOT NULL CHECK (option_type IN ('FULL_DAY_OFFICE', 'STRATEGIC_AFTERNOON', 'FULL_REMOTE_RECOMMENDED')),
+    commute_start TIMESTAMP,
+    office_arrival TIMESTAMP,
+    office_departure TIMESTAMP,
+    commute_end TIMESTAMP,
+    office_duration TEXT,
+    office_meetings TEXT DEFAULT '[]',
+    remote_meetings TEXT DEFAULT '[]',
+    business_rule_compliance TEXT,
+    perception_analysis TEXT,
+    reasoning TEXT,
+    trade_offs TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_jobs_user_id ON jobs(user_id);
+CREATE INDEX idx_jobs_status ON jobs(status);
+CREATE INDEX idx_jobs_target_date ON jobs(target_date);
+CREATE INDEX idx_calendar_events_user_id ON calendar_events(user_id);
+CREATE INDEX idx_calendar_events_user_date ON calendar_events(user_id, start_time);
+CREATE INDEX idx_commute_recommendations_job_id ON commute_recommendations(job_id);
@@ -0,0 +1,18 @@
+-- Mirrors database/migrations/005_google_calendar_channels.sql
+
+CREATE TABLE google_calendar_channels (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    calendar_id VARCHAR(255) NOT NULL DEFAULT 'primary',
+    resource_id VARCHAR(255) NOT NULL,
+    token VARCHAR(255) NOT NULL,
+    expiration TIMESTAMP NOT NULL,
+    sync_token TEXT,
+    last_synced_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_google_calendar_channels_user ON google_calendar_channels(user_id);
+CREATE INDEX idx_google_calendar_channels_expiration ON google_calendar_channels(expiration);
+CREATE INDEX idx_calendar_events_google_event_id ON calendar_events(user_id, google_event_id);
@@ -0,0 +1,47 @@
+-- Mirrors database/migrations/006_job_events.sql
+
+-- SQLite can't alter a CHECK constraint, so jobs is rebuilt to allow CANCELLED. The
+-- migration runner disables foreign keys while migrating, so dropping the old table
+-- doesn't cascade to commute_recommendations.
+CREATE TABLE jobs_new (
+    id TEXT PRIMARY KEY,
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'IN_PROGRESS', 'COMPLETED', 'FAILED', 'CANCELLED')),
+    progress REAL DEFAULT 0.0,
+    current_step VARCHAR(255),
+    target_date DATE NOT NULL,
+    input_data TEXT,
+    result TEXT,
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO jobs_new SELECT id, user_id, status, progress, current_step, target_date, input_data, result, error_message, created_at, updated_at FROM jobs;
+DROP TABLE jobs;
+ALTER TABLE jobs_new RENAME TO jobs;
+
+CREATE INDEX idx_jobs_user_id ON jobs(user_id);
+CREATE INDEX idx_jobs_status ON jobs(status);
+CREATE INDEX idx_jobs_target_date ON jobs(target_date);
+
+CREATE TABLE job_events (
+    job_id TEXT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    sequence INTEGER NOT NULL,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    current_step VARCHAR(255),
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (job_id, sequence)
+);
+
+INSERT INTO job_events (job_id, sequence, from_status, to_status, current_step, error_message, created_at)
+SELECT id, 1, NULL, status, current_step, error_message, COALESCE(updated_at, created_at, CURRENT_TIMESTAMP)
+FROM jobs;
+
+CREATE TRIGGER trigger_job_events_append_only
+BEFORE UPDATE ON job_events
+BEGIN
+    SELECT RAISE(ABORT, 'job_events is append-only');
+END;
@@ -0,0 +1,14 @@
+-- Mirrors database/migrations/007_job_queue_outbox.sql
+
+CREATE TABLE job_queue_outbox (
+    job_id TEXT PRIMARY KEY REFERENCES jobs(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    target_date DATE NOT NULL,
+    input_data TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_job_queue_outbox_next_attempt ON job_queue_outbox(next_attempt_at);
@@ -0,0 +1,7 @@
+-- Mirrors database/migrations/008_job_priority.sql
+
+ALTER TABLE jobs ADD COLUMN priority TEXT NOT NULL DEFAULT 'INTERACTIVE'
+    CHECK (priority IN ('INTERACTIVE', 'BATCH'));
+
+ALTER TABLE job_queue_outbox ADD COLUMN priority TEXT NOT NULL DEFAULT 'INTERACTIVE'
+    CHECK (priority IN ('INTERACTIVE', 'BATCH'));
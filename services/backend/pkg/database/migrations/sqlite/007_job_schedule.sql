@@ -0,0 +1,3 @@
+-- Mirrors database/migrations/009_job_schedule.sql
+
+ALTER TABLE jobs ADD COLUMN scheduled_at TIMESTAMP;
@@ -0,0 +1,12 @@
+-- Mirrors database/migrations/010_job_quotas.sql
+
+CREATE TABLE job_quota_overrides (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    max_queued_jobs INTEGER,
+    max_jobs_per_day INTEGER,
+    exempt BOOLEAN NOT NULL DEFAULT FALSE,
+    reason TEXT,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_jobs_user_created ON jobs(user_id, created_at);
@@ -0,0 +1,5 @@
+-- Mirrors database/migrations/011_demo_data.sql
+
+ALTER TABLE calendar_events ADD COLUMN is_demo BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_calendar_events_user_demo ON calendar_events(user_id) WHERE is_demo;
@@ -0,0 +1,5 @@
+-- Mirrors database/migrations/012_demo_jobs.sql
+
+ALTER TABLE jobs ADD COLUMN is_demo BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_jobs_user_demo ON jobs(user_id) WHERE is_demo;
@@ -0,0 +1,67 @@
+-- Mirrors database/migrations/013_tenants.sql
+
+CREATE TABLE tenants (
+    id VARCHAR(63) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO tenants (id, name) VALUES ('default', 'Default');
+
+-- SQLite can't drop the inline UNIQUE on email, so users is rebuilt with emails unique per
+-- tenant. Foreign keys are off while migrating, so referencing tables are untouched.
+CREATE TABLE users_new (
+    id TEXT PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id),
+    email VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    google_calendar_token TEXT,
+    user_preferences TEXT,
+    password_hash VARCHAR(255),
+    auth_provider VARCHAR(50) DEFAULT 'local',
+    external_id VARCHAR(255),
+    oauth_tokens TEXT,
+    oauth_scopes TEXT,
+    is_email_verified BOOLEAN DEFAULT FALSE,
+    last_login TIMESTAMP,
+    preferred_timezone VARCHAR(50) DEFAULT 'UTC',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, email)
+);
+
+INSERT INTO users_new (id, email, name, google_calendar_token, user_preferences, password_hash, auth_provider, external_id,
+                       oauth_tokens, oauth_scopes, is_email_verified, last_login, preferred_timezone, created_at, updated_at)
+SELECT id, email, name, google_calendar_token, user_preferences, password_hash, auth_provider, external_id,
+       oauth_tokens, oauth_scopes, is_email_verified, last_login, preferred_timezone, created_at, updated_at
+FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE UNIQUE INDEX idx_users_provider_external_id
+ON users(tenant_id, auth_provider, external_id)
+WHERE auth_provider != 'local';
+
+ALTER TABLE jobs ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE calendar_events ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE commute_recommendations ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+
+CREATE INDEX idx_jobs_tenant ON jobs(tenant_id);
+CREATE INDEX idx_calendar_events_tenant ON calendar_events(tenant_id);
+CREATE INDEX idx_commute_recommendations_tenant ON commute_recommendations(tenant_id);
+
+-- SQLite triggers can't assign NEW, so the inherited tenant is written after the insert
+CREATE TRIGGER trigger_jobs_tenant AFTER INSERT ON jobs
+BEGIN
+    UPDATE jobs SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = NEW.user_id), NEW.tenant_id) WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER trigger_calendar_events_tenant AFTER INSERT ON calendar_events
+BEGIN
+    UPDATE calendar_events SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = NEW.user_id), NEW.tenant_id) WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER trigger_commute_recommendations_tenant AFTER INSERT ON commute_recommendations
+BEGIN
+    UPDATE commute_recommendations SET tenant_id = COALESCE((SELECT tenant_id FROM jobs WHERE id = NEW.job_id), NEW.tenant_id) WHERE id = NEW.id;
+END;
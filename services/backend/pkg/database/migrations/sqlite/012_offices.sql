@@ -0,0 +1,18 @@
+-- Mirrors database/migrations/014_offices.sql
+
+CREATE TABLE offices (
+    id TEXT PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id),
+    name VARCHAR(255) NOT NULL,
+    address TEXT,
+    latitude REAL CHECK (latitude BETWEEN -90 AND 90),
+    longitude REAL CHECK (longitude BETWEEN -180 AND 180),
+    amenities TEXT NOT NULL DEFAULT '[]',
+    capacity INTEGER CHECK (capacity >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, name)
+);
+
+ALTER TABLE users ADD COLUMN default_office_id TEXT REFERENCES offices(id) ON DELETE SET NULL;
+ALTER TABLE commute_recommendations ADD COLUMN office_id TEXT REFERENCES offices(id) ON DELETE SET NULL;
@@ -0,0 +1,23 @@
+-- Mirrors database/migrations/015_geocoding.sql
+
+CREATE TABLE geocode_cache (
+    address_key TEXT PRIMARY KEY,
+    found BOOLEAN NOT NULL,
+    latitude REAL,
+    longitude REAL,
+    formatted_address TEXT,
+    provider VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN home_address TEXT;
+ALTER TABLE users ADD COLUMN home_latitude REAL;
+ALTER TABLE users ADD COLUMN home_longitude REAL;
+
+ALTER TABLE calendar_events ADD COLUMN location_latitude REAL;
+ALTER TABLE calendar_events ADD COLUMN location_longitude REAL;
+ALTER TABLE calendar_events ADD COLUMN location_geocoded_at TIMESTAMP;
+
+CREATE INDEX idx_calendar_events_ungeocoded
+ON calendar_events(created_at)
+WHERE location IS NOT NULL AND location_geocoded_at IS NULL;
@@ -0,0 +1,4 @@
+-- Mirrors database/migrations/016_offsite_meetings.sql
+
+ALTER TABLE commute_recommendations ADD COLUMN offsite_meetings TEXT DEFAULT '[]';
+ALTER TABLE commute_recommendations ADD COLUMN travel_legs TEXT DEFAULT '[]';
@@ -0,0 +1,15 @@
+-- Mirrors database/migrations/017_travel_modes.sql
+
+CREATE TABLE travel_profiles (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    modes TEXT NOT NULL DEFAULT '["DRIVE", "TRANSIT", "BIKE", "WALK"]',
+    preferred_mode VARCHAR(20) CHECK (preferred_mode IN ('DRIVE', 'TRANSIT', 'BIKE', 'WALK')),
+    max_bike_minutes INTEGER CHECK (max_bike_minutes > 0),
+    max_walk_minutes INTEGER CHECK (max_walk_minutes > 0),
+    parking_cost REAL CHECK (parking_cost >= 0),
+    transit_fare REAL CHECK (transit_fare >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE commute_recommendations ADD COLUMN travel_mode VARCHAR(20);
+ALTER TABLE commute_recommendations ADD COLUMN mode_options TEXT DEFAULT '[]';
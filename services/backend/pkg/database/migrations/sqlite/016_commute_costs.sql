@@ -0,0 +1,6 @@
+-- Mirrors database/migrations/018_commute_costs.sql
+
+ALTER TABLE travel_profiles ADD COLUMN fuel_cost_per_km REAL CHECK (fuel_cost_per_km >= 0);
+
+ALTER TABLE offices ADD COLUMN parking_cost REAL CHECK (parking_cost >= 0);
+ALTER TABLE offices ADD COLUMN congestion_charge REAL CHECK (congestion_charge >= 0);
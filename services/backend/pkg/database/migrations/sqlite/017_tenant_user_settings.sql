@@ -0,0 +1,35 @@
+-- Mirrors database/migrations/020_tenant_user_settings.sql
+
+ALTER TABLE webhook_endpoints ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE google_calendar_channels ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE job_quota_overrides ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE travel_profiles ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+
+UPDATE webhook_endpoints SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = webhook_endpoints.user_id), tenant_id);
+UPDATE google_calendar_channels SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = google_calendar_channels.user_id), tenant_id);
+UPDATE job_quota_overrides SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = job_quota_overrides.user_id), tenant_id);
+UPDATE travel_profiles SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = travel_profiles.user_id), tenant_id);
+
+CREATE INDEX idx_webhook_endpoints_tenant ON webhook_endpoints(tenant_id);
+CREATE INDEX idx_google_calendar_channels_tenant ON google_calendar_channels(tenant_id);
+
+-- SQLite triggers can't assign NEW, so the inherited tenant is written after the insert
+CREATE TRIGGER trigger_webhook_endpoints_tenant AFTER INSERT ON webhook_endpoints
+BEGIN
+    UPDATE webhook_endpoints SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = NEW.user_id), NEW.tenant_id) WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER trigger_google_calendar_channels_tenant AFTER INSERT ON google_calendar_channels
+BEGIN
+    UPDATE google_calendar_channels SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = NEW.user_id), NEW.tenant_id) WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER trigger_job_quota_overrides_tenant AFTER INSERT ON job_quota_overrides
+BEGIN
+    UPDATE job_quota_overrides SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = NEW.user_id), NEW.tenant_id) WHERE user_id = NEW.user_id;
+END;
+
+CREATE TRIGGER trigger_travel_profiles_tenant AFTER INSERT ON travel_profiles
+BEGIN
+    UPDATE travel_profiles SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = NEW.user_id), NEW.tenant_id) WHERE user_id = NEW.user_id;
+END;
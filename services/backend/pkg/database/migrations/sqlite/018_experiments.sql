@@ -0,0 +1,16 @@
+-- Mirrors database/migrations/021_experiments.sql
+
+ALTER TABLE jobs ADD COLUMN experiment VARCHAR(100);
+ALTER TABLE jobs ADD COLUMN variant VARCHAR(100);
+ALTER TABLE commute_recommendations ADD COLUMN experiment VARCHAR(100);
+ALTER TABLE commute_recommendations ADD COLUMN variant VARCHAR(100);
+
+CREATE INDEX idx_jobs_experiment ON jobs(experiment, variant) WHERE experiment IS NOT NULL;
+
+CREATE TRIGGER trigger_commute_recommendations_variant AFTER INSERT ON commute_recommendations
+BEGIN
+    UPDATE commute_recommendations
+    SET experiment = (SELECT experiment FROM jobs WHERE id = NEW.job_id),
+        variant = (SELECT variant FROM jobs WHERE id = NEW.job_id)
+    WHERE id = NEW.id;
+END;
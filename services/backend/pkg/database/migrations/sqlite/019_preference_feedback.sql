@@ -0,0 +1,18 @@
+-- Mirrors database/migrations/022_preference_feedback.sql
+
+CREATE TABLE preference_feedback (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('CONFIRMED', 'REJECTED')),
+    time VARCHAR(5),
+    mode VARCHAR(20) CHECK (mode IN ('DRIVE', 'TRANSIT', 'BIKE', 'WALK')),
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE TRIGGER trigger_preference_feedback_tenant AFTER INSERT ON preference_feedback
+BEGIN
+    UPDATE preference_feedback SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = NEW.user_id), NEW.tenant_id)
+    WHERE user_id = NEW.user_id AND key = NEW.key;
+END;
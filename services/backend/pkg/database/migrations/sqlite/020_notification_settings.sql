@@ -0,0 +1,16 @@
+-- Mirrors database/migrations/023_notification_settings.sql
+
+CREATE TABLE notification_settings (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    weekly_digest BOOLEAN NOT NULL DEFAULT FALSE,
+    last_digest_week DATE,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notification_settings_weekly_digest ON notification_settings(user_id) WHERE weekly_digest;
+
+CREATE TRIGGER trigger_notification_settings_tenant AFTER INSERT ON notification_settings
+BEGIN
+    UPDATE notification_settings SET tenant_id = COALESCE((SELECT tenant_id FROM users WHERE id = NEW.user_id), NEW.tenant_id) WHERE user_id = NEW.user_id;
+END;
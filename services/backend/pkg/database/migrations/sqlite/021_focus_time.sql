@@ -0,0 +1,3 @@
+-- Mirrors database/migrations/024_focus_time.sql
+
+ALTER TABLE users ADD COLUMN focus_minutes INTEGER CHECK (focus_minutes > 0);
@@ -0,0 +1,5 @@
+-- Mirrors database/migrations/026_row_versions.sql
+
+ALTER TABLE jobs ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE calendar_events ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
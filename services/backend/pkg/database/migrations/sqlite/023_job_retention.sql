@@ -0,0 +1,30 @@
+-- Mirrors database/migrations/027_job_retention.sql
+
+CREATE TABLE job_archive (
+    job_id TEXT PRIMARY KEY,
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id),
+    status TEXT NOT NULL,
+    target_date DATE NOT NULL,
+    job TEXT NOT NULL,
+    recommendations TEXT NOT NULL DEFAULT '[]',
+    events TEXT NOT NULL DEFAULT '[]',
+    archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_job_archive_user ON job_archive(user_id, target_date);
+
+CREATE TABLE retention_runs (
+    id TEXT PRIMARY KEY,
+    triggered_by VARCHAR(20) NOT NULL CHECK (triggered_by IN ('SCHEDULE', 'ADMIN')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('RUNNING', 'COMPLETED', 'FAILED')),
+    completed_before TIMESTAMP,
+    failed_before TIMESTAMP,
+    jobs_archived INTEGER NOT NULL DEFAULT 0,
+    recommendations_archived INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_retention_runs_started ON retention_runs(started_at DESC);
@@ -0,0 +1,13 @@
+-- Mirrors database/migrations/028_job_artifacts.sql
+
+CREATE TABLE job_artifacts (
+    id TEXT PRIMARY KEY,
+    job_id TEXT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL CHECK (kind IN ('REASONING_TRACE', 'ROUTE_MAP')),
+    storage_key TEXT NOT NULL UNIQUE,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_job_artifacts_job ON job_artifacts(job_id, created_at);
@@ -0,0 +1,7 @@
+-- Mirrors database/migrations/030_calendar_feeds.sql
+
+CREATE TABLE calendar_feeds (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,23 @@
+-- Mirrors database/migrations/031_commute_reminders.sql
+
+ALTER TABLE notification_settings ADD COLUMN commute_reminders BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notification_settings ADD COLUMN reminder_lead_minutes INTEGER NOT NULL DEFAULT 15;
+ALTER TABLE notification_settings ADD COLUMN reminder_channels TEXT NOT NULL DEFAULT '{EMAIL}';
+
+CREATE TABLE commute_reminders (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recommendation_id TEXT NOT NULL REFERENCES commute_recommendations(id) ON DELETE CASCADE,
+    target_date DATE NOT NULL,
+    summary TEXT NOT NULL,
+    depart_at TIMESTAMP NOT NULL,
+    remind_at TIMESTAMP NOT NULL,
+    channels TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'SCHEDULED' CHECK (status IN ('SCHEDULED', 'SENT', 'CANCELLED', 'FAILED')),
+    error_message TEXT,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_commute_reminders_due ON commute_reminders(remind_at) WHERE status = 'SCHEDULED';
+CREATE INDEX idx_commute_reminders_user ON commute_reminders(user_id, target_date);
@@ -0,0 +1,5 @@
+-- Mirrors database/migrations/032_job_follow_ups.sql
+
+ALTER TABLE jobs ADD COLUMN follow_up_of TEXT REFERENCES jobs(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_jobs_follow_up_of ON jobs(follow_up_of) WHERE follow_up_of IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Mirrors database/migrations/033_org_reporting.sql
+
+ALTER TABLE users ADD COLUMN is_org_admin BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN org_reporting_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE tenants ADD COLUMN office_days_per_week INTEGER
+    CHECK (office_days_per_week BETWEEN 1 AND 7);
@@ -0,0 +1,16 @@
+-- Mirrors database/migrations/034_analytics_events.sql
+
+ALTER TABLE users ADD COLUMN analytics_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE analytics_events (
+    id TEXT PRIMARY KEY,
+    event VARCHAR(100) NOT NULL,
+    anonymous_id VARCHAR(64) NOT NULL,
+    properties TEXT NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMP NOT NULL,
+    forwarded_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_analytics_events_unforwarded ON analytics_events(occurred_at) WHERE forwarded_at IS NULL;
+CREATE INDEX idx_analytics_events_event ON analytics_events(event, occurred_at);
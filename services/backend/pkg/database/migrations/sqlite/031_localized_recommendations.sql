@@ -0,0 +1,5 @@
+-- Mirrors database/migrations/035_localized_recommendations.sql
+
+ALTER TABLE users ADD COLUMN locale VARCHAR(16);
+
+ALTER TABLE commute_recommendations ADD COLUMN reason_codes TEXT;
@@ -0,0 +1,7 @@
+-- Mirrors database/migrations/036_display_units.sql
+
+ALTER TABLE users ADD COLUMN distance_unit VARCHAR(2);
+
+ALTER TABLE users ADD COLUMN currency CHAR(3);
+
+ALTER TABLE users ADD COLUMN time_format VARCHAR(16);
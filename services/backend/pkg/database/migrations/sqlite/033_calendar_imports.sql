@@ -0,0 +1,18 @@
+-- Mirrors database/migrations/037_calendar_imports.sql
+
+CREATE TABLE calendar_imports (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename TEXT NOT NULL,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('ICS')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('RUNNING', 'COMPLETED', 'FAILED')),
+    total_events INTEGER NOT NULL DEFAULT 0,
+    processed_events INTEGER NOT NULL DEFAULT 0,
+    created_events INTEGER NOT NULL DEFAULT 0,
+    errors TEXT NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_calendar_imports_user ON calendar_imports(user_id, started_at DESC);
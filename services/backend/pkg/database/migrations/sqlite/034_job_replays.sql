@@ -0,0 +1,5 @@
+-- Mirrors database/migrations/038_job_replays.sql
+
+ALTER TABLE jobs ADD COLUMN replay_of TEXT REFERENCES jobs(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_jobs_replay_of ON jobs(replay_of) WHERE replay_of IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Mirrors database/migrations/040_magic_links.sql
+
+CREATE TABLE magic_links (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_magic_links_user ON magic_links(user_id, created_at DESC);
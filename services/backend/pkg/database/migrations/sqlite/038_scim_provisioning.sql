@@ -0,0 +1,12 @@
+-- Mirrors database/migrations/042_scim_provisioning.sql
+
+ALTER TABLE tenants ADD COLUMN scim_token_hash TEXT;
+
+CREATE UNIQUE INDEX idx_tenants_scim_token_hash ON tenants(scim_token_hash);
+
+ALTER TABLE users ADD COLUMN scim_external_id TEXT;
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMP;
+
+CREATE UNIQUE INDEX idx_users_tenant_scim_external_id
+ON users(tenant_id, scim_external_id)
+WHERE scim_external_id IS NOT NULL;
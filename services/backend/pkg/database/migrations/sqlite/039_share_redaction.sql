@@ -0,0 +1,3 @@
+-- Mirrors database/migrations/044_share_redaction.sql
+
+ALTER TABLE users ADD COLUMN share_redaction VARCHAR(16);
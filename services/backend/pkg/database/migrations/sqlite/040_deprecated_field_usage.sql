@@ -0,0 +1,14 @@
+-- Mirrors database/migrations/045_deprecated_field_usage.sql
+
+CREATE TABLE deprecated_field_usage (
+    coordinate VARCHAR(255) NOT NULL,
+    client_name VARCHAR(100) NOT NULL,
+    client_version VARCHAR(100) NOT NULL,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    first_used_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (coordinate, client_name, client_version, day)
+);
+
+CREATE INDEX idx_deprecated_field_usage_day ON deprecated_field_usage(day);
@@ -0,0 +1,19 @@
+-- Mirrors database/migrations/046_client_usage.sql
+
+ALTER TABLE jobs ADD COLUMN client_name VARCHAR(100);
+ALTER TABLE jobs ADD COLUMN client_version VARCHAR(100);
+ALTER TABLE analytics_events ADD COLUMN client_name VARCHAR(100);
+ALTER TABLE analytics_events ADD COLUMN client_version VARCHAR(100);
+
+CREATE TABLE client_operation_usage (
+    client_name VARCHAR(100) NOT NULL,
+    client_version VARCHAR(100) NOT NULL,
+    operation VARCHAR(100) NOT NULL,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    first_used_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (client_name, client_version, operation, day)
+);
+
+CREATE INDEX idx_client_operation_usage_day ON client_operation_usage(day);
@@ -0,0 +1,11 @@
+//go:build !sqlite
+
+package database
+
+import "fmt"
+
+// newSQLiteConnection is a stub for builds without the sqlite tag, which keeps the
+// SQLite driver (and its size) out of production binaries
+func newSQLiteConnection(cfg Config) (*DB, error) {
+	return nil, fmt.Errorf("SQLite support is not compiled in; rebuild with -tags sqlite")
+}
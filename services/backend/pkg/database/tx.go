@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Querier runs statements; satisfied by *DB, *Tx, *sql.DB and *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+type commitHooksKey struct{}
+
+// commitHooks are what to do once a transaction commits
+type commitHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// savepoints numbers the savepoints of nested transactions
+var savepoints uint64
+
+// InTx runs fn in a transaction, committing it when fn returns nil and rolling it back
+// otherwise. Statements run through db with fn's context (or one derived from it) join
+// the transaction, reads sent to Reader included, and transactions begun inside it with
+// BeginTx are savepoints. An InTx inside another joins the outer transaction.
+func (db *DB) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.circuit.begin(ctx, db.DB, nil)
+	if err != nil {
+		return err
+	}
+	hooks := &commitHooks{}
+	ctx = context.WithValue(context.WithValue(ctx, txKey{}, tx), commitHooksKey{}, hooks)
+	if err := fn(ctx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, hook := range hooks.fns {
+		hook()
+	}
+	return nil
+}
+
+// AfterCommit runs fn once the transaction ctx runs in (InTx's) commits, and never if it
+// rolls back; outside a transaction it runs fn straight away. It is for side effects
+// outside the database, such as analytics events or pushes to subscribers, that mustn't
+// report writes a rollback undoes.
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if !ok {
+		fn()
+		return
+	}
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.fns = append(hooks.fns, fn)
+}
+
+// conn returns the transaction ctx runs in, or pool when there is none
+func conn(ctx context.Context, pool *sql.DB) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return pool
+}
+
+// ExecContext runs a statement on the primary, in ctx's transaction if it has one
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return pool{db.DB, db.circuit}.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs a query on the primary, in ctx's transaction if it has one
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return pool{db.DB, db.circuit}.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a single-row query on the primary, in ctx's transaction if it has one
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return pool{db.DB, db.circuit}.QueryRowContext(ctx, query, args...)
+}
+
+// pool sends statements to a connection pool unless their context has a transaction,
+// through the pool's circuit breaker
+type pool struct {
+	db      *sql.DB
+	circuit *circuit
+}
+
+func (p pool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	done, err := p.circuit.allow()
+	if err != nil {
+		return nil, err
+	}
+	result, err := conn(ctx, p.db).ExecContext(ctx, query, args...)
+	done(err)
+	return result, err
+}
+
+func (p pool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	done, err := p.circuit.allow()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn(ctx, p.db).QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+func (p pool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	done, err := p.circuit.allow()
+	if err != nil {
+		return p.circuit.rejected.QueryRowContext(ctx, query, args...)
+	}
+	row := conn(ctx, p.db).QueryRowContext(ctx, query, args...)
+	// Err reports the query's failure without consuming the row
+	done(row.Err())
+	return row
+}
+
+// Tx is a transaction begun with BeginTx. Inside InTx it is a savepoint of the outer
+// transaction, so committing it only releases the savepoint.
+type Tx struct {
+	tx        *sql.Tx
+	savepoint string
+	done      bool
+}
+
+// BeginTx starts a transaction, or a savepoint when ctx is inside InTx
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if outer, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		name := fmt.Sprintf("nested_%d", atomic.AddUint64(&savepoints, 1))
+		if _, err := outer.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return nil, err
+		}
+		return &Tx{tx: outer, savepoint: name}, nil
+	}
+
+	tx, err := db.circuit.begin(ctx, db.DB, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx: tx}, nil
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
+
+// Commit commits the transaction, or releases the savepoint
+func (t *Tx) Commit() error {
+	if t.savepoint == "" {
+		return t.tx.Commit()
+	}
+	return t.end("RELEASE SAVEPOINT " + t.savepoint)
+}
+
+// Rollback aborts the transaction, or rolls back to the savepoint. Like sql.Tx, it
+// returns sql.ErrTxDone once the transaction was committed or rolled back.
+func (t *Tx) Rollback() error {
+	if t.savepoint == "" {
+		return t.tx.Rollback()
+	}
+	return t.end("ROLLBACK TO SAVEPOINT " + t.savepoint)
+}
+
+func (t *Tx) end(statement string) error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	_, err := t.tx.Exec(statement)
+	return err
+}
@@ -0,0 +1,199 @@
+// Package deprecation tracks which clients still use the deprecated parts of the GraphQL
+// schema, so they can be removed safely. A deprecated field or argument may carry a sunset
+// date, after which it is due for removal:
+//
+//	travelLegs: String @deprecated(reason: "Use legs") @sunset(date: "2026-12-01")
+//
+// Requests selecting deprecated fields or passing deprecated arguments are counted per
+// client and day; Index.Report joins those counts with the schema.
+package deprecation
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// maxCachedQueries bounds the queries whose deprecated fields are remembered; the cache
+// is emptied when it fills
+const maxCachedQueries = 1000
+
+// Field is a deprecated field, Type.field, or argument, Type.field(argument:)
+type Field struct {
+	Coordinate string
+	Reason     string
+	// Sunset is the YYYY-MM-DD date the field is due for removal; empty when none is set
+	Sunset string
+}
+
+// Index is the deprecated fields and arguments of a schema
+type Index struct {
+	schema *ast.Schema
+	fields []Field
+	byName map[string]Field
+
+	mu    sync.Mutex
+	cache map[string][]string
+}
+
+// Load indexes the deprecated fields and arguments of sdl
+func Load(sdl string) (*Index, error) {
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: "schema.graphql", Input: sdl})
+	if err != nil {
+		return nil, fmt.Errorf("invalid GraphQL schema: %w", err)
+	}
+	ix := &Index{schema: schema, byName: map[string]Field{}, cache: map[string][]string{}}
+	for _, def := range schema.Types {
+		if def.BuiltIn || (def.Kind != ast.Object && def.Kind != ast.Interface) {
+			continue
+		}
+		for _, field := range def.Fields {
+			coordinate := def.Name + "." + field.Name
+			if err := ix.add(coordinate, field.Directives); err != nil {
+				return nil, err
+			}
+			for _, arg := range field.Arguments {
+				if err := ix.add(coordinate+"("+arg.Name+":)", arg.Directives); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	sort.Slice(ix.fields, func(i, j int) bool { return ix.fields[i].Coordinate < ix.fields[j].Coordinate })
+	return ix, nil
+}
+
+// add indexes the field at coordinate if its directives deprecate it
+func (ix *Index) add(coordinate string, directives ast.DirectiveList) error {
+	deprecated := directives.ForName("deprecated")
+	if deprecated == nil {
+		if directives.ForName("sunset") != nil {
+			return fmt.Errorf("%s has a sunset date but isn't deprecated", coordinate)
+		}
+		return nil
+	}
+	field := Field{Coordinate: coordinate, Reason: "No longer supported"}
+	if reason := deprecated.Arguments.ForName("reason"); reason != nil {
+		field.Reason = reason.Value.Raw
+	}
+	if sunset := directives.ForName("sunset"); sunset != nil {
+		if date := sunset.Arguments.ForName("date"); date != nil {
+			field.Sunset = date.Value.Raw
+		}
+		if _, err := time.Parse("2006-01-02", field.Sunset); err != nil {
+			return fmt.Errorf("invalid sunset date %q of %s: expected YYYY-MM-DD", field.Sunset, coordinate)
+		}
+	}
+	ix.fields = append(ix.fields, field)
+	ix.byName[coordinate] = field
+	return nil
+}
+
+// Fields returns the deprecated fields and arguments, by coordinate
+func (ix *Index) Fields() []Field {
+	return ix.fields
+}
+
+// Used returns the coordinates of the deprecated fields query selects and arguments it
+// passes, once each. Queries that don't validate use none: they aren't run.
+func (ix *Index) Used(query string) []string {
+	if len(ix.fields) == 0 {
+		return nil
+	}
+	ix.mu.Lock()
+	used, ok := ix.cache[query]
+	ix.mu.Unlock()
+	if ok {
+		return used
+	}
+
+	doc, errs := gqlparser.LoadQuery(ix.schema, query)
+	if len(errs) == 0 {
+		seen := map[string]bool{}
+		visit := func(coordinate string) {
+			if _, deprecated := ix.byName[coordinate]; deprecated && !seen[coordinate] {
+				seen[coordinate] = true
+				used = append(used, coordinate)
+			}
+		}
+		for _, op := range doc.Operations {
+			walk(op.SelectionSet, visit)
+		}
+		for _, fragment := range doc.Fragments {
+			walk(fragment.SelectionSet, visit)
+		}
+	}
+
+	ix.mu.Lock()
+	if len(ix.cache) >= maxCachedQueries {
+		ix.cache = map[string][]string{}
+	}
+	ix.cache[query] = used
+	ix.mu.Unlock()
+	return used
+}
+
+// walk visits the coordinates of the fields and arguments of set. Fragment spreads are
+// skipped: their fragments are walked on their own.
+func walk(set ast.SelectionSet, visit func(coordinate string)) {
+	for _, selection := range set {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if selection.ObjectDefinition != nil && selection.Definition != nil {
+				coordinate := selection.ObjectDefinition.Name + "." + selection.Name
+				visit(coordinate)
+				for _, arg := range selection.Arguments {
+					visit(coordinate + "(" + arg.Name + ":)")
+				}
+			}
+			walk(selection.SelectionSet, visit)
+		case *ast.InlineFragment:
+			walk(selection.SelectionSet, visit)
+		}
+	}
+}
+
+// Report lists every deprecated field and argument with its use by each client, the
+// clients using it most first. Fields past their sunset date on now are flagged; those no
+// client used can be removed.
+func (ix *Index) Report(usage []*models.DeprecatedFieldUsage, now time.Time) []*models.DeprecatedField {
+	report := make([]*models.DeprecatedField, len(ix.fields))
+	byName := map[string]*models.DeprecatedField{}
+	today := now.UTC().Format("2006-01-02")
+	for i, field := range ix.fields {
+		reason := field.Reason
+		report[i] = &models.DeprecatedField{
+			Coordinate: field.Coordinate,
+			Reason:     &reason,
+			Clients:    []*models.DeprecatedFieldUsage{},
+		}
+		if field.Sunset != "" {
+			sunset := field.Sunset
+			report[i].Sunset = &sunset
+			report[i].PastSunset = today >= sunset
+		}
+		byName[field.Coordinate] = report[i]
+	}
+	for _, u := range usage {
+		// Usage of fields that are no longer deprecated, or were removed, isn't reported
+		field, ok := byName[u.Coordinate]
+		if !ok {
+			continue
+		}
+		field.Requests += u.Requests
+		if field.LastUsedAt == nil || u.LastUsedAt.After(*field.LastUsedAt) {
+			lastUsedAt := u.LastUsedAt
+			field.LastUsedAt = &lastUsedAt
+		}
+		field.Clients = append(field.Clients, u)
+	}
+	for _, field := range report {
+		sort.SliceStable(field.Clients, func(i, j int) bool { return field.Clients[i].Requests > field.Clients[j].Requests })
+	}
+	return report
+}
@@ -0,0 +1,111 @@
+package deprecation
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	backend "github.com/commute-planner/backend"
+	"github.com/commute-planner/backend/pkg/clientinfo"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+const testSchema = `
+directive @sunset(date: String!) on FIELD_DEFINITION | ARGUMENT_DEFINITION
+
+type Query {
+  plan(day: String, date: String @deprecated(reason: "Use day")): Plan
+}
+
+type Plan {
+  legs: [String!]
+  travelLegs: String @deprecated(reason: "Use legs") @sunset(date: "2026-03-01")
+  notes: String @deprecated
+}`
+
+func TestUsed(t *testing.T) {
+	index, err := Load(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := index.Fields()
+	if len(fields) != 3 || fields[1] != (Field{Coordinate: "Plan.travelLegs", Reason: "Use legs", Sunset: "2026-03-01"}) {
+		t.Errorf("fields = %+v", fields)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`{ plan(day: "2026-03-02") { legs } }`, nil},
+		{`{ plan(date: "2026-03-02") { travelLegs ...more } } fragment more on Plan { notes travelLegs }`,
+			[]string{"Query.plan(date:)", "Plan.travelLegs", "Plan.notes"}},
+		{`{ plan { ... on Plan { notes } } }`, []string{"Plan.notes"}},
+		// Invalid queries aren't run
+		{`{ plan { travelLegs nope } }`, nil},
+	}
+	for _, tt := range tests {
+		if got := index.Used(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Used(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	if _, err := Load(testSchema + `
+extend type Plan { mode: String @sunset(date: "2026-03-01") }`); err == nil {
+		t.Error("sunset date on a field that isn't deprecated accepted")
+	}
+	if _, err := Load(testSchema + `
+extend type Plan { mode: String @deprecated @sunset(date: "soon") }`); err == nil {
+		t.Error("invalid sunset date accepted")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	if _, err := Load(backend.Schema); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	index, err := Load(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	usage := repository.NewMemoryDeprecationUsageRepository()
+	tracker := NewTracker(index, usage, 0)
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	ios := clientinfo.Client{Name: "ios", Version: "1.0"}
+	web := clientinfo.Client{Name: "web", Version: "7"}
+	tracker.Track(ios, `{ plan { travelLegs } }`)
+	tracker.Track(ios, `{ plan { travelLegs } }`)
+	tracker.Track(web, `{ plan { travelLegs notes } }`)
+	tracker.Track(web, `{ plan { legs } }`)
+	tracker.flush(ctx)
+
+	stored, err := usage.List(ctx, now)
+	if err != nil || len(stored) != 3 {
+		t.Fatalf("usage = %+v, %v", stored, err)
+	}
+
+	report := index.Report(stored, now)
+	if len(report) != 3 {
+		t.Fatalf("report = %+v, want every deprecated field", report)
+	}
+	notes, travelLegs, date := report[0], report[1], report[2]
+	if date.Coordinate != "Query.plan(date:)" || date.Requests != 0 || date.LastUsedAt != nil || len(date.Clients) != 0 {
+		t.Errorf("unused field = %+v", date)
+	}
+	if notes.Reason == nil || *notes.Reason != "No longer supported" || notes.Sunset != nil || notes.PastSunset {
+		t.Errorf("notes = %+v", notes)
+	}
+	if travelLegs.Requests != 3 || !travelLegs.PastSunset || *travelLegs.Sunset != "2026-03-01" || !travelLegs.LastUsedAt.Equal(now) {
+		t.Errorf("travelLegs = %+v", travelLegs)
+	}
+	if len(travelLegs.Clients) != 2 || travelLegs.Clients[0].ClientName != "ios" || travelLegs.Clients[0].Requests != 2 {
+		t.Errorf("travelLegs clients = %+v, want ios first", travelLegs.Clients)
+	}
+}
@@ -0,0 +1,105 @@
+package deprecation
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/clientinfo"
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// usageKey is a deprecated field as used by a client
+type usageKey struct {
+	coordinate string
+	client     clientinfo.Client
+}
+
+// Tracker counts the use of deprecated fields. Track only adds to counts in memory, so
+// tracking never slows or fails a request; Run writes them.
+type Tracker struct {
+	index    *Index
+	usage    repository.DeprecationUsageRepository
+	interval time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	counts map[usageKey]*models.DeprecatedFieldUsage
+}
+
+// NewTracker creates a tracker writing counts to usage every interval; call Run to start
+// writing
+func NewTracker(index *Index, usage repository.DeprecationUsageRepository, interval time.Duration) *Tracker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Tracker{index: index, usage: usage, interval: interval, now: time.Now, counts: map[usageKey]*models.DeprecatedFieldUsage{}}
+}
+
+// Track counts the deprecated fields query uses as used by client
+func (t *Tracker) Track(client clientinfo.Client, query string) {
+	used := t.index.Used(query)
+	if len(used) == 0 {
+		return
+	}
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, coordinate := range used {
+		metrics.DeprecatedFieldUsage.WithLabelValues(coordinate).Inc()
+		key := usageKey{coordinate: coordinate, client: client}
+		count, ok := t.counts[key]
+		if !ok {
+			count = &models.DeprecatedFieldUsage{
+				Coordinate:    coordinate,
+				ClientName:    client.Name,
+				ClientVersion: client.Version,
+				FirstUsedAt:   now,
+			}
+			t.counts[key] = count
+		}
+		count.Requests++
+		count.LastUsedAt = now
+	}
+}
+
+// Run writes the counts every interval until ctx is cancelled, then writes what is left
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			t.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			t.flush(ctx)
+		}
+	}
+}
+
+// flush writes the counts since the last flush. Counts that fail to be written are lost,
+// like those of an instance that crashes; the report only needs to show a field is in use.
+func (t *Tracker) flush(ctx context.Context) {
+	t.mu.Lock()
+	counts := t.counts
+	t.counts = map[usageKey]*models.DeprecatedFieldUsage{}
+	t.mu.Unlock()
+
+	if len(counts) == 0 {
+		return
+	}
+	usage := make([]*models.DeprecatedFieldUsage, 0, len(counts))
+	for _, count := range counts {
+		usage = append(usage, count)
+	}
+	if err := t.usage.Record(ctx, usage); err != nil {
+		log.Printf("Deprecations: failed to record the use of %d deprecated fields: %v", len(usage), err)
+	}
+}
@@ -0,0 +1,192 @@
+// Package diagnostics serves /debug/statusz: a snapshot of what the process holds on to
+// (goroutines, connection pools, queue backlogs and in-flight GraphQL operations) for
+// tracking down leaks and stalls in production.
+package diagnostics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/commute-planner/backend/pkg/redis"
+)
+
+// queueDepthTimeout bounds the broker round trips of a status request
+const queueDepthTimeout = 2 * time.Second
+
+// Operations tracks the GraphQL operations being executed
+type Operations struct {
+	mu       sync.Mutex
+	next     uint64
+	inFlight map[uint64]Operation
+}
+
+// Operation is an operation being executed
+type Operation struct {
+	Name      string    `json:"name"`
+	StartedAt time.Time `json:"startedAt"`
+	// RunningFor is how long it had been running when the status was taken
+	RunningFor string `json:"runningFor"`
+}
+
+// NewOperations creates an empty operation tracker
+func NewOperations() *Operations {
+	return &Operations{inFlight: map[uint64]Operation{}}
+}
+
+// Begin records an operation as started; call the returned func when it finishes
+func (o *Operations) Begin(name string) func() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.next++
+	id := o.next
+	o.inFlight[id] = Operation{Name: name, StartedAt: time.Now()}
+	return func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		delete(o.inFlight, id)
+	}
+}
+
+// InFlight returns the operations being executed, longest running first
+func (o *Operations) InFlight() []Operation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	operations := make([]Operation, 0, len(o.inFlight))
+	for _, op := range o.inFlight {
+		op.RunningFor = now.Sub(op.StartedAt).Round(time.Millisecond).String()
+		operations = append(operations, op)
+	}
+	sort.Slice(operations, func(i, j int) bool { return operations[i].StartedAt.Before(operations[j].StartedAt) })
+	return operations
+}
+
+// Sources are what the status reports on; nil ones are left out
+type Sources struct {
+	// DBPools are the database connection pools by name, e.g. primary and replica
+	DBPools map[string]*sql.DB
+	Redis   *redis.Client
+	// QueueDepth reports the job queues' backlogs
+	QueueDepth func(ctx context.Context) ([]queue.Depth, error)
+	Operations *Operations
+}
+
+// Status is the body of /debug/statusz
+type Status struct {
+	StartedAt         time.Time          `json:"startedAt"`
+	Uptime            string             `json:"uptime"`
+	GoVersion         string             `json:"goVersion"`
+	Goroutines        int                `json:"goroutines"`
+	Memory            Memory             `json:"memory"`
+	DBPools           map[string]DBPool  `json:"dbPools,omitempty"`
+	RedisPool         *redis.PoolStats   `json:"redisPool,omitempty"`
+	Queues            []queue.Depth      `json:"queues,omitempty"`
+	QueueError        string             `json:"queueError,omitempty"`
+	GraphQLOperations *GraphQLOperations `json:"graphqlOperations,omitempty"`
+}
+
+// Memory is the Go heap
+type Memory struct {
+	HeapAllocBytes uint64 `json:"heapAllocBytes"`
+	HeapObjects    uint64 `json:"heapObjects"`
+	SysBytes       uint64 `json:"sysBytes"`
+	NumGC          uint32 `json:"numGC"`
+}
+
+// DBPool is the state of a database connection pool
+type DBPool struct {
+	MaxOpen           int    `json:"maxOpen"`
+	Open              int    `json:"open"`
+	InUse             int    `json:"inUse"`
+	Idle              int    `json:"idle"`
+	WaitCount         int64  `json:"waitCount"`
+	WaitDuration      string `json:"waitDuration"`
+	MaxIdleClosed     int64  `json:"maxIdleClosed"`
+	MaxIdleTimeClosed int64  `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed int64  `json:"maxLifetimeClosed"`
+}
+
+// GraphQLOperations are the GraphQL operations being executed
+type GraphQLOperations struct {
+	InFlight   int         `json:"inFlight"`
+	Operations []Operation `json:"operations"`
+}
+
+var started = time.Now()
+
+// Collect takes the status of src
+func Collect(ctx context.Context, src Sources) Status {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	status := Status{
+		StartedAt:  started,
+		Uptime:     time.Since(started).Round(time.Second).String(),
+		GoVersion:  runtime.Version(),
+		Goroutines: runtime.NumGoroutine(),
+		Memory: Memory{
+			HeapAllocBytes: mem.HeapAlloc,
+			HeapObjects:    mem.HeapObjects,
+			SysBytes:       mem.Sys,
+			NumGC:          mem.NumGC,
+		},
+	}
+
+	for name, db := range src.DBPools {
+		if db == nil {
+			continue
+		}
+		if status.DBPools == nil {
+			status.DBPools = map[string]DBPool{}
+		}
+		stats := db.Stats()
+		status.DBPools[name] = DBPool{
+			MaxOpen:           stats.MaxOpenConnections,
+			Open:              stats.OpenConnections,
+			InUse:             stats.InUse,
+			Idle:              stats.Idle,
+			WaitCount:         stats.WaitCount,
+			WaitDuration:      stats.WaitDuration.String(),
+			MaxIdleClosed:     stats.MaxIdleClosed,
+			MaxIdleTimeClosed: stats.MaxIdleTimeClosed,
+			MaxLifetimeClosed: stats.MaxLifetimeClosed,
+		}
+	}
+	if src.Redis != nil {
+		stats := src.Redis.PoolStats()
+		status.RedisPool = &stats
+	}
+	if src.QueueDepth != nil {
+		ctx, cancel := context.WithTimeout(ctx, queueDepthTimeout)
+		defer cancel()
+		depths, err := src.QueueDepth(ctx)
+		if err != nil {
+			status.QueueError = err.Error()
+		}
+		status.Queues = depths
+	}
+	if src.Operations != nil {
+		operations := src.Operations.InFlight()
+		status.GraphQLOperations = &GraphQLOperations{InFlight: len(operations), Operations: operations}
+	}
+	return status
+}
+
+// Handler serves the status of src as JSON
+func Handler(src Sources) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(Collect(r.Context(), src))
+	})
+}
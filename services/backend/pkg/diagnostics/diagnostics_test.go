@@ -0,0 +1,56 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/queue"
+)
+
+func TestOperations(t *testing.T) {
+	ops := NewOperations()
+	doneA := ops.Begin("GetJob")
+	doneB := ops.Begin("CreateJob")
+	if inFlight := ops.InFlight(); len(inFlight) != 2 || inFlight[0].Name != "GetJob" {
+		t.Fatalf("in flight = %+v, want GetJob first", inFlight)
+	}
+	doneA()
+	doneB()
+	doneB()
+	if inFlight := ops.InFlight(); len(inFlight) != 0 {
+		t.Errorf("finished operations still in flight: %+v", inFlight)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ops := NewOperations()
+	defer ops.Begin("Slow")()
+	handler := Handler(Sources{
+		QueueDepth: func(ctx context.Context) ([]queue.Depth, error) {
+			return []queue.Depth{{Queue: "commute_jobs:stream", Messages: 3}}, errors.New("batch stream unreachable")
+		},
+		Operations: ops,
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/statusz", nil))
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("invalid status %s: %v", rec.Body, err)
+	}
+	if status.Goroutines == 0 || status.GoVersion == "" {
+		t.Errorf("status = %+v, want the runtime reported", status)
+	}
+	if len(status.Queues) != 1 || status.Queues[0].Messages != 3 || status.QueueError == "" {
+		t.Errorf("queues = %+v (%q), want the depth and the error", status.Queues, status.QueueError)
+	}
+	if status.GraphQLOperations == nil || status.GraphQLOperations.InFlight != 1 || status.GraphQLOperations.Operations[0].Name != "Slow" {
+		t.Errorf("operations = %+v", status.GraphQLOperations)
+	}
+	if status.DBPools != nil || status.RedisPool != nil {
+		t.Errorf("unconfigured sources reported: %+v, %+v", status.DBPools, status.RedisPool)
+	}
+}
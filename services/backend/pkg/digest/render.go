@@ -0,0 +1,112 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/notify"
+	"github.com/commute-planner/backend/pkg/units"
+)
+
+// Render writes the digest email for user, with times in location and distances, costs
+// and times in the user's units
+func Render(user *models.User, digest *models.WeeklyDigest, location *time.Location) notify.Message {
+	start, _ := time.Parse("2006-01-02", digest.WeekStart)
+	format := units.For(user, location)
+	clock := func(t *time.Time) string { return format.Time(*t) }
+
+	var b strings.Builder
+	name := user.Name
+	if name == "" {
+		name = "there"
+	}
+	fmt.Fprintf(&b, "Hi %s,\n\nHere's your plan for the week of Monday %s.\n\n", name, start.Format("2 January"))
+
+	for _, day := range digest.Days {
+		date, _ := time.Parse("2006-01-02", day.Date)
+		weekend := date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+		if weekend && day.RecommendationID == nil && len(day.InPersonMeetings) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%-16s", date.Format("Mon 2 Jan"))
+		switch {
+		case day.OutOfOffice:
+			b.WriteString("Out of office")
+		case day.RecommendationID == nil:
+			b.WriteString("Not planned yet")
+		case day.InOffice && day.OfficeArrival != nil && day.OfficeDeparture != nil:
+			fmt.Fprintf(&b, "Office, %s-%s", clock(day.OfficeArrival), clock(day.OfficeDeparture))
+			if day.CommuteStart != nil {
+				fmt.Fprintf(&b, " (leave home %s)", clock(day.CommuteStart))
+			}
+		case day.InOffice:
+			b.WriteString("Office")
+		default:
+			b.WriteString("Remote")
+		}
+		if day.RecommendationID != nil && !day.Accepted && !day.OutOfOffice {
+			b.WriteString(", recommended")
+		}
+		b.WriteString("\n")
+		for _, event := range day.InPersonMeetings {
+			fmt.Fprintf(&b, "%-16s  %s %s\n", "", clock(&event.StartTime), event.Summary)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n%s in the office, %s remote", plural(digest.OfficeDays, "day"), plural(digest.RemoteDays, "day"))
+	if digest.OutOfOfficeDays > 0 {
+		fmt.Fprintf(&b, ", %s out of office", plural(digest.OutOfOfficeDays, "day"))
+	}
+	if digest.UnplannedDays > 0 {
+		fmt.Fprintf(&b, ", %d not planned yet", digest.UnplannedDays)
+	}
+	if digest.CommuteMinutes > 0 {
+		fmt.Fprintf(&b, "; about %s of commuting", duration(digest.CommuteMinutes))
+		var extent []string
+		if digest.CommuteDistanceKm != nil {
+			extent = append(extent, format.Distance(*digest.CommuteDistanceKm))
+		}
+		if digest.CommuteCost != nil {
+			extent = append(extent, format.Money(*digest.CommuteCost))
+		}
+		if len(extent) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(extent, ", "))
+		}
+	}
+	b.WriteString(".\n")
+
+	if len(digest.Conflicts) > 0 {
+		b.WriteString("\nHeads up:\n")
+		for _, conflict := range digest.Conflicts {
+			fmt.Fprintf(&b, "- %s\n", conflict.Description)
+		}
+	}
+	b.WriteString("\nYou're getting this because you turned on the weekly digest. You can turn it off in your notification settings.\n")
+
+	return notify.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("Your week ahead: %s in the office", plural(digest.OfficeDays, "day")),
+		Text:    b.String(),
+	}
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// duration formats minutes as "45m", "2h" or "3h 15m"
+func duration(minutes int) string {
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	default:
+		return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+	}
+}
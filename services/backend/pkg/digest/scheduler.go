@@ -0,0 +1,129 @@
+// Package digest emails users who opted in a summary of the coming week's plan every
+// Sunday evening in their own timezone.
+package digest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/notify"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Source builds a user's weekly digest; implemented by the GraphQL resolver
+type Source interface {
+	WeeklyDigest(ctx context.Context, userID, weekStart string) (*models.WeeklyDigest, error)
+}
+
+// Config tunes when digests go out
+type Config struct {
+	// Interval is how often subscribers are checked for a due digest
+	Interval time.Duration
+	// SendHour is the local hour on Sunday from which a user's digest is sent
+	SendHour int
+}
+
+// Scheduler sends each subscriber the digest of the week ahead once, on Sunday evening
+type Scheduler struct {
+	source   Source
+	settings repository.NotificationSettingsRepository
+	users    repository.UserRepository
+	notifier notify.Notifier
+	cfg      Config
+	now      func() time.Time
+}
+
+// NewScheduler creates a scheduler; call Run to start sending
+func NewScheduler(source Source, settings repository.NotificationSettingsRepository, users repository.UserRepository, notifier notify.Notifier, cfg Config) *Scheduler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.SendHour <= 0 || cfg.SendHour > 23 {
+		cfg.SendHour = 18
+	}
+	return &Scheduler{source: source, settings: settings, users: users, notifier: notifier, cfg: cfg, now: time.Now}
+}
+
+// Run sends due digests until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) sweep(ctx context.Context) {
+	subscribers, err := s.settings.ListDigestSubscribers(ctx)
+	if err != nil {
+		log.Printf("Weekly digest: failed to list subscribers: %v", err)
+		return
+	}
+	now := s.now()
+	for _, subscriber := range subscribers {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.deliver(ctx, subscriber, now); err != nil {
+			metrics.DigestEmails.WithLabelValues("failed").Inc()
+			log.Printf("Weekly digest: failed to send to user %s: %v", subscriber.UserID, err)
+		}
+	}
+}
+
+// deliver sends a subscriber the digest of the coming week if it's Sunday evening where
+// they are and they haven't had it yet
+func (s *Scheduler) deliver(ctx context.Context, subscriber *models.NotificationSettings, now time.Time) error {
+	timezone, err := s.users.PreferredTimezone(ctx, subscriber.UserID)
+	if err != nil {
+		return fmt.Errorf("fetching timezone: %w", err)
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+	if local.Weekday() != time.Sunday || local.Hour() < s.cfg.SendHour {
+		return nil
+	}
+	week := local.AddDate(0, 0, 1).Format("2006-01-02")
+	if subscriber.LastDigestWeek != nil && *subscriber.LastDigestWeek >= week {
+		return nil
+	}
+
+	user, err := s.users.Get(ctx, subscriber.UserID)
+	if err != nil {
+		return fmt.Errorf("fetching user: %w", err)
+	}
+	// Claiming first keeps two backend instances from both sending it
+	claimed, err := s.settings.ClaimDigest(ctx, user.ID, week)
+	if err != nil || !claimed {
+		return err
+	}
+	release := func(cause error) error {
+		if err := s.settings.ReleaseDigest(ctx, user.ID, week); err != nil {
+			log.Printf("Weekly digest: failed to release week %s for user %s: %v", week, user.ID, err)
+		}
+		return cause
+	}
+
+	digest, err := s.source.WeeklyDigest(ctx, user.ID, week)
+	if err != nil {
+		return release(fmt.Errorf("building digest: %w", err))
+	}
+	if err := s.notifier.Send(ctx, Render(user, digest, location)); err != nil {
+		return release(err)
+	}
+	metrics.DigestEmails.WithLabelValues("sent").Inc()
+	return nil
+}
@@ -0,0 +1,150 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/notify"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+type fakeSource struct{}
+
+func (fakeSource) WeeklyDigest(ctx context.Context, userID, weekStart string) (*models.WeeklyDigest, error) {
+	start, _ := time.Parse("2006-01-02", weekStart)
+	digest := &models.WeeklyDigest{UserID: userID, WeekStart: weekStart, UnplannedDays: 6, OfficeDays: 1, CommuteMinutes: 90}
+	for i := 0; i < 7; i++ {
+		date := start.AddDate(0, 0, i)
+		digest.Days = append(digest.Days, models.DigestDay{Date: date.Format("2006-01-02"), Weekday: date.Weekday().String()})
+	}
+	id, arrival, departure := "rec-1", start.Add(9*time.Hour), start.Add(17*time.Hour)
+	digest.Days[0].RecommendationID, digest.Days[0].InOffice = &id, true
+	digest.Days[0].OfficeArrival, digest.Days[0].OfficeDeparture = &arrival, &departure
+	return digest, nil
+}
+
+type recordingNotifier struct {
+	sent []notify.Message
+	err  error
+}
+
+func (n *recordingNotifier) Send(ctx context.Context, msg notify.Message) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func TestSchedulerSweep(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	users := repository.NewSQLUserRepository(db)
+	settings := repository.NewSQLNotificationSettingsRepository(db)
+
+	subscribe := func(email, timezone string, weekly bool) *models.User {
+		t.Helper()
+		user, err := users.Create(ctx, repository.NewUser{Email: email, Name: "Ada"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, `UPDATE users SET preferred_timezone = $1 WHERE id = $2`, timezone, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := settings.Put(ctx, &models.NotificationSettings{UserID: user.ID, WeeklyDigest: weekly}); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	subscribe("ny@example.com", "America/New_York", true)
+	subscribe("berlin@example.com", "Europe/Berlin", true)
+	subscribe("optout@example.com", "Europe/Berlin", false)
+
+	notifier := &recordingNotifier{}
+	scheduler := NewScheduler(fakeSource{}, settings, users, notifier, Config{SendHour: 18})
+	sweepAt := func(now string) []string {
+		t.Helper()
+		scheduler.now = func() time.Time {
+			at, _ := time.Parse(time.RFC3339, now)
+			return at
+		}
+		notifier.sent = nil
+		scheduler.sweep(ctx)
+		var to []string
+		for _, msg := range notifier.sent {
+			to = append(to, msg.To)
+		}
+		return to
+	}
+
+	// Sunday 1 March, 17:00 in Berlin and 11:00 in New York: too early for both
+	if sent := sweepAt("2026-03-01T16:00:00Z"); len(sent) != 0 {
+		t.Errorf("sent %v before the send hour", sent)
+	}
+	// 19:00 in Berlin
+	sent := sweepAt("2026-03-01T18:00:00Z")
+	if len(sent) != 1 || sent[0] != "berlin@example.com" {
+		t.Fatalf("sent %v, want only the Berlin subscriber", sent)
+	}
+	if msg := notifier.sent[0]; !strings.Contains(msg.Text, "week of Monday 2 March") || !strings.Contains(msg.Text, "Office, 10:00-18:00") {
+		t.Errorf("digest text:\n%s", msg.Text)
+	}
+	// 18:30 in New York, after midnight in Berlin: a failed send is retried next sweep
+	notifier.err = errors.New("relay unavailable")
+	sweepAt("2026-03-01T23:30:00Z")
+	notifier.err = nil
+	if sent := sweepAt("2026-03-01T23:35:00Z"); len(sent) != 1 || sent[0] != "ny@example.com" {
+		t.Errorf("sent %v, want the New York subscriber once the relay is back", sent)
+	}
+	if sent := sweepAt("2026-03-01T23:40:00Z"); len(sent) != 0 {
+		t.Errorf("sent %v again", sent)
+	}
+	// The following Sunday evening brings the next week's digest
+	if sent := sweepAt("2026-03-08T22:30:00Z"); len(sent) != 2 {
+		t.Errorf("sent %v on the next Sunday, want both subscribers", sent)
+	}
+	// Monday
+	if sent := sweepAt("2026-03-09T23:00:00Z"); len(sent) != 0 {
+		t.Errorf("sent %v on a Monday", sent)
+	}
+}
+
+func TestRender(t *testing.T) {
+	digest, _ := fakeSource{}.WeeklyDigest(context.Background(), "user-1", "2026-03-02")
+	digest.Conflicts = []models.DigestConflict{{Description: "Interview at 11:00 on Thursday needs you in the office, but the day isn't planned yet"}}
+	msg := Render(&models.User{Email: "ada@example.com", Name: "Ada"}, digest, time.UTC)
+
+	if msg.To != "ada@example.com" || msg.Subject != "Your week ahead: 1 day in the office" {
+		t.Errorf("message to %q with subject %q", msg.To, msg.Subject)
+	}
+	for _, want := range []string{
+		"Hi Ada,",
+		"Mon 2 Mar       Office, 09:00-17:00, recommended\n",
+		"Tue 3 Mar       Not planned yet\n",
+		"1 day in the office, 0 days remote, 6 not planned yet; about 1h 30m of commuting.",
+		"Heads up:\n- Interview at 11:00",
+	} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("digest is missing %q:\n%s", want, msg.Text)
+		}
+	}
+	if strings.Contains(msg.Text, "Sat 7 Mar") {
+		t.Errorf("digest lists an empty weekend day:\n%s", msg.Text)
+	}
+
+	// In the user's units
+	distance, cost := 32.2, 9.5
+	digest.CommuteDistanceKm, digest.CommuteCost = &distance, &cost
+	mi, gbp, twelve := models.DistanceUnitMi, "GBP", models.TimeFormat12Hour
+	msg = Render(&models.User{Email: "ada@example.com", DistanceUnit: &mi, Currency: &gbp, TimeFormat: &twelve}, digest, time.UTC)
+	for _, want := range []string{"Office, 9:00am-5:00pm", "about 1h 30m of commuting (20.0 mi, £9.50)."} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("digest is missing %q:\n%s", want, msg.Text)
+		}
+	}
+}
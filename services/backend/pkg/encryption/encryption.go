@@ -0,0 +1,131 @@
+// Package encryption encrypts sensitive column values, such as users' OAuth tokens, with
+// AES-256-GCM before they are stored. Encrypted values carry the ID of their key, so keys
+// can be rotated: new values are encrypted with the current key while values encrypted
+// with earlier keys still decrypt, until they are re-encrypted.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix starts every encrypted value, followed by the key ID and the sealed value
+const prefix = "enc:v1:"
+
+// ErrNoKey is returned when decrypting a value whose key isn't in the keyring
+var ErrNoKey = errors.New("the value's encryption key is not configured")
+
+// Keyring holds the current key, which encrypts, and earlier keys that still decrypt.
+// A nil Keyring leaves values as they are.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// ParseKeys parses a comma-separated list of keys, each an ID and a base64-encoded 32-byte
+// key, e.g. "2026-03:<key>,2025-09:<key>". The first key is the current one. An empty list
+// returns a nil Keyring.
+func ParseKeys(spec string) (*Keyring, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	k := &Keyring{keys: map[string]cipher.AEAD{}}
+	for _, entry := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("encryption keys must be listed as id:base64key")
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			if raw, err = base64.RawURLEncoding.DecodeString(encoded); err != nil {
+				return nil, fmt.Errorf("encryption key %s is not base64", id)
+			}
+		}
+		if len(raw) != 32 {
+			return nil, fmt.Errorf("encryption key %s must be 32 bytes, not %d", id, len(raw))
+		}
+		if _, ok := k.keys[id]; ok {
+			return nil, fmt.Errorf("encryption key %s is listed twice", id)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if k.current == "" {
+			k.current = id
+		}
+		k.keys[id] = aead
+	}
+	return k, nil
+}
+
+// Current returns the ID of the key new values are encrypted with
+func (k *Keyring) Current() string {
+	if k == nil {
+		return ""
+	}
+	return k.current
+}
+
+// Encrypt encrypts value with the current key. The context, such as the value's column,
+// is authenticated with it, so a value copied into another column doesn't decrypt.
+func (k *Keyring) Encrypt(value, context string) (string, error) {
+	if k == nil || IsEncrypted(value) {
+		return value, nil
+	}
+	aead := k.keys[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(context))
+	return prefix + k.current + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of a value Encrypt returned with the same context. Values
+// that were stored before encryption was enabled are returned as they are.
+func (k *Keyring) Decrypt(value, context string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	if k == nil || k.keys[id] == nil {
+		return "", fmt.Errorf("%w: %s", ErrNoKey, id)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	aead := k.keys[id]
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(context))
+	if err != nil {
+		return "", fmt.Errorf("encrypted value failed authentication: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether a value isn't encrypted with the current key, either
+// because it predates encryption or because it was encrypted with an earlier key
+func (k *Keyring) NeedsRotation(value string) bool {
+	if k == nil {
+		return false
+	}
+	return !strings.HasPrefix(value, prefix+k.current+":")
+}
+
+// IsEncrypted reports whether value was returned by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
@@ -0,0 +1,76 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+}
+
+func TestKeyring(t *testing.T) {
+	old, err := ParseKeys("2025-09:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := old.Encrypt(`{"refresh_token":"1//0g"}`, "users.oauth_tokens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v1:2025-09:") || strings.Contains(sealed, "refresh_token") {
+		t.Fatalf("encrypted value = %s", sealed)
+	}
+	if again, _ := old.Encrypt(`{"refresh_token":"1//0g"}`, "users.oauth_tokens"); again == sealed {
+		t.Error("encrypting twice gave the same ciphertext")
+	}
+	if _, err := old.Decrypt(sealed, "calendar_events.description"); err == nil {
+		t.Error("decrypted a value copied into another column")
+	}
+
+	// After rotation the earlier key still decrypts, but its values need re-encrypting
+	rotated, err := ParseKeys("2026-03:" + testKey('b') + ", 2025-09:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := rotated.Decrypt(sealed, "users.oauth_tokens"); err != nil || plaintext != `{"refresh_token":"1//0g"}` {
+		t.Errorf("decrypted = %q, %v", plaintext, err)
+	}
+	if !rotated.NeedsRotation(sealed) || !rotated.NeedsRotation("plaintext") {
+		t.Error("values not under the current key don't need rotation")
+	}
+	current, _ := rotated.Encrypt("x", "c")
+	if rotated.NeedsRotation(current) || rotated.Current() != "2026-03" {
+		t.Errorf("value under the current key %s needs rotation", rotated.Current())
+	}
+
+	// Values stored before encryption read as they are; encrypted ones need their key
+	var none *Keyring
+	if value, err := none.Decrypt("plaintext", "c"); err != nil || value != "plaintext" {
+		t.Errorf("plaintext = %q, %v", value, err)
+	}
+	if value, _ := none.Encrypt("plaintext", "c"); value != "plaintext" {
+		t.Errorf("nil keyring encrypted to %q", value)
+	}
+	if _, err := none.Decrypt(sealed, "users.oauth_tokens"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("decrypting without the key: error = %v, want ErrNoKey", err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	if k, err := ParseKeys(""); k != nil || err != nil {
+		t.Errorf("empty keys = %v, %v; want no keyring", k, err)
+	}
+	for _, spec := range []string{
+		testKey('a'),
+		"k1:not base64!",
+		"k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"k1:" + testKey('a') + ",k1:" + testKey('b'),
+	} {
+		if _, err := ParseKeys(spec); err == nil {
+			t.Errorf("parsed %q", spec)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Package experiments runs A/B tests of the planner. Users are bucketed into an
+// experiment's variants by hashing the experiment name with their ID, so a user keeps one
+// variant for the life of an experiment without assignments being stored, and separate
+// experiments bucket independently. Jobs are tagged with the variant they were planned
+// under, and the planner reads it from the job's input data.
+package experiments
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// buckets is the resolution of the weights: a variant gets weight/total of them
+const buckets = 10000
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,99}$`)
+
+// Variant is one arm of an experiment. Its share of users is Weight over the sum of the
+// experiment's weights.
+type Variant struct {
+	Name   string
+	Weight int
+}
+
+// Experiment splits users between variants. The first variant is the control the others
+// are compared against.
+type Experiment struct {
+	Name     string
+	Variants []Variant
+	total    int
+}
+
+// Assignment is the variant a job was planned under, as passed to the planner
+type Assignment struct {
+	Experiment string `json:"name"`
+	Variant    string `json:"variant"`
+}
+
+// New validates an experiment. It needs at least two variants with unique names and
+// positive weights.
+func New(name string, variants []Variant) (*Experiment, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid experiment name %q: use lowercase letters, digits, '_', '.' and '-'", name)
+	}
+	if len(variants) < 2 {
+		return nil, fmt.Errorf("experiment %s needs at least two variants", name)
+	}
+	e := &Experiment{Name: name}
+	seen := map[string]bool{}
+	for _, v := range variants {
+		if !namePattern.MatchString(v.Name) {
+			return nil, fmt.Errorf("experiment %s: invalid variant name %q", name, v.Name)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("experiment %s: duplicate variant %q", name, v.Name)
+		}
+		if v.Weight <= 0 {
+			return nil, fmt.Errorf("experiment %s: variant %s needs a positive weight", name, v.Name)
+		}
+		seen[v.Name] = true
+		e.total += v.Weight
+		e.Variants = append(e.Variants, v)
+	}
+	return e, nil
+}
+
+// Parse reads variants written as "name=weight" (or "name" for a weight of 1), e.g.
+// "control=50", "ranked=50". An empty name means no experiment is running: it returns nil.
+func Parse(name string, specs []string) (*Experiment, error) {
+	if name == "" {
+		return nil, nil
+	}
+	variants := make([]Variant, 0, len(specs))
+	for _, spec := range specs {
+		variantName, weight, hasWeight := strings.Cut(strings.TrimSpace(spec), "=")
+		v := Variant{Name: strings.TrimSpace(variantName), Weight: 1}
+		if hasWeight {
+			w, err := strconv.Atoi(strings.TrimSpace(weight))
+			if err != nil {
+				return nil, fmt.Errorf("experiment %s: invalid weight in %q", name, spec)
+			}
+			v.Weight = w
+		}
+		variants = append(variants, v)
+	}
+	return New(name, variants)
+}
+
+// Control is the variant the others are compared against
+func (e *Experiment) Control() string {
+	return e.Variants[0].Name
+}
+
+// Assign returns the user's variant. It only changes if the experiment's variants or
+// weights do.
+func (e *Experiment) Assign(userID string) Assignment {
+	sum := sha256.Sum256([]byte(e.Name + ":" + userID))
+	bucket := int(binary.BigEndian.Uint64(sum[:8]) % buckets)
+
+	variant := e.Variants[len(e.Variants)-1].Name
+	upper := 0
+	for _, v := range e.Variants {
+		upper += v.Weight
+		if bucket < upper*buckets/e.total {
+			variant = v.Name
+			break
+		}
+	}
+	return Assignment{Experiment: e.Name, Variant: variant}
+}
+
+// Rate is successes over trials, or 0 without trials
+func Rate(successes, trials int) float64 {
+	if trials == 0 {
+		return 0
+	}
+	return float64(successes) / float64(trials)
+}
+
+// PValue is the two-sided p-value of a two-proportion z-test of whether rate b
+// (successesB/trialsB) differs from rate a. It is nil when either side has no trials or
+// neither has any variance to test (every trial succeeded, or none did).
+func PValue(successesA, trialsA, successesB, trialsB int) *float64 {
+	if trialsA == 0 || trialsB == 0 {
+		return nil
+	}
+	pooled := Rate(successesA+successesB, trialsA+trialsB)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(trialsA) + 1/float64(trialsB)))
+	if se == 0 {
+		return nil
+	}
+	z := (Rate(successesB, trialsB) - Rate(successesA, trialsA)) / se
+	p := math.Erfc(math.Abs(z) / math.Sqrt2)
+	return &p
+}
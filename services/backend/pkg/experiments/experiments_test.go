@@ -0,0 +1,85 @@
+package experiments
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestAssign(t *testing.T) {
+	e, err := Parse("ranking", []string{"control=75", "ranked=25"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		a := e.Assign(userID)
+		if again := e.Assign(userID); again != a {
+			t.Fatalf("user %s assigned %v then %v", userID, a, again)
+		}
+		counts[a.Variant]++
+	}
+	if share := float64(counts["ranked"]) / 4000; math.Abs(share-0.25) > 0.03 {
+		t.Errorf("ranked got %.1f%% of users, want about 25%%", share*100)
+	}
+
+	// Another experiment buckets the same users independently
+	other, _ := Parse("other", []string{"control=75", "ranked=25"})
+	same := 0
+	for i := 0; i < 1000; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		if e.Assign(userID).Variant == other.Assign(userID).Variant {
+			same++
+		}
+	}
+	if same > 700 {
+		t.Errorf("%d of 1000 users got the same variant in both experiments, want about 625", same)
+	}
+}
+
+func TestParse(t *testing.T) {
+	if e, err := Parse("", []string{"a", "b"}); e != nil || err != nil {
+		t.Errorf("Parse with no name = %v, %v; want no experiment", e, err)
+	}
+	e, err := Parse("test", []string{"a", " b = 3 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Control() != "a" || e.Variants[0].Weight != 1 || e.Variants[1] != (Variant{Name: "b", Weight: 3}) {
+		t.Errorf("variants = %+v", e.Variants)
+	}
+
+	for _, specs := range [][]string{
+		{"a"},
+		{"a", "a"},
+		{"a=0", "b"},
+		{"a=x", "b"},
+		{"A", "b"},
+	} {
+		if _, err := Parse("test", specs); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", specs)
+		}
+	}
+	if _, err := Parse("Bad Name", []string{"a", "b"}); err == nil {
+		t.Error("Parse accepted an invalid experiment name")
+	}
+}
+
+func TestPValue(t *testing.T) {
+	// 40/100 against 60/100: z is about 2.83
+	p := PValue(40, 100, 60, 100)
+	if p == nil || math.Abs(*p-0.0047) > 0.0005 {
+		t.Errorf("PValue = %v, want about 0.0047", p)
+	}
+	if p := PValue(50, 100, 50, 100); p == nil || *p != 1 {
+		t.Errorf("PValue of equal rates = %v, want 1", p)
+	}
+	if p := PValue(0, 0, 5, 10); p != nil {
+		t.Errorf("PValue without control trials = %v, want nil", *p)
+	}
+	if p := PValue(10, 10, 5, 5); p != nil {
+		t.Errorf("PValue without variance = %v, want nil", *p)
+	}
+}
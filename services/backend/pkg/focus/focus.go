@@ -0,0 +1,111 @@
+// Package focus finds the free blocks of a working day that are long enough for deep work,
+// and which of them a commute option's travel leaves whole. A commute that cuts through
+// the only free two hours of a day costs more than its travel time.
+package focus
+
+import (
+	"sort"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/allday"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// Working hours focus time is looked for in, in the user's timezone
+const (
+	DayStart = 8
+	DayEnd   = 18
+)
+
+// The focus time a user can ask for, in minutes
+const (
+	MinMinutes = 30
+	MaxMinutes = 240
+)
+
+// Free returns the blocks of date's working hours, in location, that no meeting takes up.
+// date is a midnight UTC date; all-day entries and out-of-office blocks aren't meetings.
+func Free(events []*models.CalendarEvent, date time.Time, location *time.Location) []models.FocusWindow {
+	start := time.Date(date.Year(), date.Month(), date.Day(), DayStart, 0, 0, 0, location)
+	end := time.Date(date.Year(), date.Month(), date.Day(), DayEnd, 0, 0, 0, location)
+
+	var busy []span
+	for _, event := range events {
+		if allday.Busy(event) {
+			busy = append(busy, span{event.StartTime, event.EndTime})
+		}
+	}
+	return windows(subtract([]span{{start, end}}, busy), 1)
+}
+
+// Protected returns the blocks of free that rec's travel leaves at least minutes long
+func Protected(rec *models.CommuteRecommendation, free []models.FocusWindow, minutes int) []models.FocusWindow {
+	spans := make([]span, len(free))
+	for i, window := range free {
+		spans[i] = span{window.Start, window.End}
+	}
+	return windows(subtract(spans, travel(rec)), minutes)
+}
+
+// span is a half-open interval of time
+type span struct {
+	start, end time.Time
+}
+
+// subtract removes every span of cut from each span of from
+func subtract(from, cut []span) []span {
+	sort.Slice(cut, func(i, j int) bool { return cut[i].start.Before(cut[j].start) })
+	var left []span
+	for _, s := range from {
+		for _, c := range cut {
+			if !c.end.After(s.start) || !c.start.Before(s.end) {
+				continue
+			}
+			if c.start.After(s.start) {
+				left = append(left, span{s.start, c.start})
+			}
+			s.start = c.end
+			if !s.start.Before(s.end) {
+				break
+			}
+		}
+		if s.start.Before(s.end) {
+			left = append(left, s)
+		}
+	}
+	return left
+}
+
+// windows returns the spans at least minutes long
+func windows(spans []span, minutes int) []models.FocusWindow {
+	result := []models.FocusWindow{}
+	for _, s := range spans {
+		length := int(s.end.Sub(s.start).Minutes())
+		if length >= minutes {
+			result = append(result, models.FocusWindow{Start: s.start, End: s.end, Minutes: length})
+		}
+	}
+	return result
+}
+
+// travel returns when rec is on the move: its travel legs when it has them, otherwise the
+// trips to and from the office. Remote options don't travel.
+func travel(rec *models.CommuteRecommendation) []span {
+	var trips []span
+	for _, l := range rec.DecodeTravelLegs() {
+		if l.Depart != nil && l.Arrive != nil {
+			trips = append(trips, span{*l.Depart, *l.Arrive})
+		}
+	}
+	if len(trips) > 0 {
+		return trips
+	}
+
+	if rec.CommuteStart != nil && rec.OfficeArrival != nil {
+		trips = append(trips, span{*rec.CommuteStart, *rec.OfficeArrival})
+	}
+	if rec.OfficeDeparture != nil && rec.CommuteEnd != nil {
+		trips = append(trips, span{*rec.OfficeDeparture, *rec.CommuteEnd})
+	}
+	return trips
+}
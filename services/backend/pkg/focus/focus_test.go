@@ -0,0 +1,92 @@
+package focus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+var monday = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+func at(hour, minute int) time.Time {
+	return monday.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func meeting(summary string, start, end time.Time) *models.CalendarEvent {
+	return &models.CalendarEvent{ID: summary, Summary: summary, StartTime: start, EndTime: end}
+}
+
+func describe(windows []models.FocusWindow) []string {
+	var got []string
+	for _, window := range windows {
+		got = append(got, window.Start.UTC().Format("15:04")+"-"+window.End.UTC().Format("15:04"))
+	}
+	return got
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFree(t *testing.T) {
+	vacation := &models.CalendarEvent{ID: "vacation", Summary: "Birthday", StartTime: monday, EndTime: monday.AddDate(0, 0, 1), IsAllDay: true}
+	events := []*models.CalendarEvent{
+		meeting("Standup", at(9, 0), at(9, 15)),
+		meeting("Design review", at(11, 0), at(12, 0)),
+		meeting("Lunch", at(11, 30), at(13, 0)), // overlaps the review
+		meeting("Retro", at(17, 0), at(19, 0)),  // runs past the working day
+		vacation,
+	}
+
+	got := describe(Free(events, monday, time.UTC))
+	want := []string{"08:00-09:00", "09:15-11:00", "13:00-17:00"}
+	if !equal(got, want) {
+		t.Errorf("Free = %v, want %v", got, want)
+	}
+
+	// Working hours are the user's own: 08:00-18:00 in New York is 13:00-23:00 UTC before DST
+	newYork, _ := time.LoadLocation("America/New_York")
+	if got := describe(Free(nil, monday, newYork)); !equal(got, []string{"13:00-23:00"}) {
+		t.Errorf("Free in New York = %v, want 13:00-23:00 UTC", got)
+	}
+}
+
+func TestProtected(t *testing.T) {
+	free := Free([]*models.CalendarEvent{meeting("Offsite planning", at(10, 0), at(14, 0))}, monday, time.UTC)
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	remote := &models.CommuteRecommendation{}
+	if got := describe(Protected(remote, free, 120)); !equal(got, []string{"08:00-10:00", "14:00-18:00"}) {
+		t.Errorf("remote keeps %v, want both blocks", got)
+	}
+
+	// Commuting in cuts into the morning, and home at 15:30 splits the afternoon into two
+	// blocks under two hours
+	office := &models.CommuteRecommendation{
+		CommuteStart: ptr(at(8, 15)), OfficeArrival: ptr(at(9, 0)),
+		OfficeDeparture: ptr(at(15, 30)), CommuteEnd: ptr(at(16, 15)),
+	}
+	if got := describe(Protected(office, free, 120)); len(got) != 0 {
+		t.Errorf("office keeps %v, want none", got)
+	}
+	if got := describe(Protected(office, free, 60)); !equal(got, []string{"09:00-10:00", "14:00-15:30", "16:15-18:00"}) {
+		t.Errorf("office keeps %v of an hour or more", got)
+	}
+
+	// Travel legs take precedence over the commute times
+	legs := `[{"depart": "2026-03-02T08:15:00Z", "arrive": "2026-03-02T09:00:00Z"},
+		{"depart": "2026-03-02T15:00:00+00:00", "arrive": "2026-03-02T15:30:00.000000+00:00"}]`
+	office.TravelLegs = &legs
+	if got := describe(Protected(office, free, 120)); !equal(got, []string{"15:30-18:00"}) {
+		t.Errorf("legs keep %v, want 15:30-18:00", got)
+	}
+}
@@ -0,0 +1,74 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// CachedGeocoder answers from the geocode cache and only asks the provider about addresses
+// it hasn't seen within ttl. Addresses the provider couldn't place are cached as misses.
+type CachedGeocoder struct {
+	next  Geocoder
+	cache repository.GeocodeCacheRepository
+	ttl   time.Duration
+}
+
+// NewCachedGeocoder wraps next with the persistent cache; a ttl of 0 keeps entries forever
+func NewCachedGeocoder(next Geocoder, cache repository.GeocodeCacheRepository, ttl time.Duration) *CachedGeocoder {
+	return &CachedGeocoder{next: next, cache: cache, ttl: ttl}
+}
+
+func (g *CachedGeocoder) Provider() string {
+	return g.next.Provider()
+}
+
+// Geocode returns the cached result when fresh. Cache failures are logged and fall through
+// to the provider, so a cache outage only costs extra lookups.
+func (g *CachedGeocoder) Geocode(ctx context.Context, address string) (*Result, error) {
+	key := NormalizeAddress(address)
+	if key == "" {
+		return nil, ErrNoResults
+	}
+
+	entry, err := g.cache.Get(ctx, key)
+	switch {
+	case err == nil && g.fresh(entry):
+		if !entry.Found {
+			return nil, ErrNoResults
+		}
+		result := &Result{Point: Point{Lat: entry.Latitude, Lng: entry.Longitude}, Provider: entry.Provider}
+		if entry.FormattedAddress != nil {
+			result.FormattedAddress = *entry.FormattedAddress
+		}
+		return result, nil
+	case err != nil && !errors.Is(err, repository.ErrNotFound):
+		log.Printf("Failed to read geocode cache for %q: %v", key, err)
+	}
+
+	result, err := g.next.Geocode(ctx, address)
+	if err != nil && !errors.Is(err, ErrNoResults) {
+		return nil, err
+	}
+
+	entry = &models.GeocodeCacheEntry{AddressKey: key, Provider: g.next.Provider()}
+	if result != nil {
+		entry.Found = true
+		entry.Latitude, entry.Longitude = result.Lat, result.Lng
+		if result.FormattedAddress != "" {
+			entry.FormattedAddress = &result.FormattedAddress
+		}
+	}
+	if putErr := g.cache.Put(ctx, entry); putErr != nil {
+		log.Printf("Failed to write geocode cache for %q: %v", key, putErr)
+	}
+	return result, err
+}
+
+func (g *CachedGeocoder) fresh(entry *models.GeocodeCacheEntry) bool {
+	return g.ttl <= 0 || time.Since(entry.CreatedAt) < g.ttl
+}
@@ -0,0 +1,161 @@
+// Package geo turns addresses into coordinates. Home, office and meeting locations are
+// geocoded so travel times can be computed between them and meetings away from the office
+// can be recognised.
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/breaker"
+)
+
+// Supported providers, selected with GEOCODER_PROVIDER
+const (
+	ProviderGoogle    = "google"
+	ProviderMapbox    = "mapbox"
+	ProviderNominatim = "nominatim"
+)
+
+// ErrNoResults is returned for addresses the provider couldn't place
+var ErrNoResults = errors.New("no geocoding results")
+
+// Point is a WGS84 coordinate
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Result is the best match for an address
+type Result struct {
+	Point
+	FormattedAddress string
+	Provider         string
+}
+
+// Geocoder resolves addresses to coordinates
+type Geocoder interface {
+	// Geocode returns the best match for address, or ErrNoResults
+	Geocode(ctx context.Context, address string) (*Result, error)
+	// Provider names the backing provider, for logs and the cache
+	Provider() string
+}
+
+// Config selects and configures a provider
+type Config struct {
+	Provider string
+	APIKey   string
+	// BaseURL overrides the API endpoint, e.g. for a self-hosted Nominatim
+	BaseURL string
+	// UserAgent identifies the deployment to Nominatim, whose usage policy requires one
+	UserAgent string
+	Timeout   time.Duration
+	// CircuitBreaker, when set, fails lookups fast while the provider is failing; the
+	// locations are geocoded once it recovers
+	CircuitBreaker *breaker.Config
+}
+
+// NewGeocoder creates the geocoder for cfg.Provider
+func NewGeocoder(cfg Config) (Geocoder, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	httpClient := &http.Client{Timeout: cfg.Timeout}
+	if cfg.CircuitBreaker != nil {
+		httpClient.Transport = breaker.Transport(breaker.New("geocoder_"+cfg.Provider, *cfg.CircuitBreaker), nil)
+	}
+
+	switch cfg.Provider {
+	case ProviderGoogle:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("the google geocoder requires an API key")
+		}
+		return newGoogleGeocoder(cfg, httpClient), nil
+	case ProviderMapbox:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("the mapbox geocoder requires an access token")
+		}
+		return newMapboxGeocoder(cfg, httpClient), nil
+	case ProviderNominatim:
+		if cfg.UserAgent == "" {
+			return nil, fmt.Errorf("the nominatim geocoder requires a user agent")
+		}
+		return newNominatimGeocoder(cfg, httpClient), nil
+	default:
+		return nil, fmt.Errorf("unsupported geocoding provider %q", cfg.Provider)
+	}
+}
+
+// APIError is a non-2xx response from a provider
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API returned %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// getJSON fetches url and decodes the response into out
+func getJSON(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &APIError{Provider: provider, StatusCode: resp.StatusCode, Body: string(data)}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", provider, err)
+	}
+	return nil
+}
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+// DistanceKm is the great-circle distance between two points
+func DistanceKm(a, b Point) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(b.Lat - a.Lat)
+	dLng := toRad(b.Lng - a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(a.Lat))*math.Cos(toRad(b.Lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// virtualLocation matches meeting locations that are video calls rather than places
+var virtualLocation = regexp.MustCompile(`(?i)(https?://|\bzoom\b|google meet|\bmeet\.google\b|microsoft teams|\bteams\b|\bwebex\b|\bskype\b|\bonline\b|\bvirtual\b|\bphone\b)`)
+
+// IsVirtualLocation reports whether a meeting location names a video or phone call, which
+// has no coordinates
+func IsVirtualLocation(location string) bool {
+	return virtualLocation.MatchString(location)
+}
+
+// NormalizeAddress is the cache key of an address: lowercased, with runs of whitespace and
+// trailing punctuation collapsed so trivially different spellings share an entry
+func NormalizeAddress(address string) string {
+	return strings.Trim(strings.Join(strings.Fields(strings.ToLower(address)), " "), " ,.;")
+}
@@ -0,0 +1,71 @@
+package geo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultGoogleBaseURL = "https://maps.googleapis.com/maps/api"
+
+// googleGeocoder uses the Google Maps Geocoding API
+type googleGeocoder struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+func newGoogleGeocoder(cfg Config, httpClient *http.Client) *googleGeocoder {
+	g := &googleGeocoder{baseURL: defaultGoogleBaseURL, apiKey: cfg.APIKey, http: httpClient}
+	if cfg.BaseURL != "" {
+		g.baseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	return g
+}
+
+type googleResponse struct {
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message"`
+	Results      []struct {
+		FormattedAddress string `json:"formatted_address"`
+		Geometry         struct {
+			Location struct {
+				Lat float64 `json:"lat"`
+				Lng float64 `json:"lng"`
+			} `json:"location"`
+		} `json:"geometry"`
+	} `json:"results"`
+}
+
+func (g *googleGeocoder) Provider() string {
+	return ProviderGoogle
+}
+
+func (g *googleGeocoder) Geocode(ctx context.Context, address string) (*Result, error) {
+	query := url.Values{"address": {address}, "key": {g.apiKey}}
+	var resp googleResponse
+	if err := getJSON(ctx, g.http, ProviderGoogle, g.baseURL+"/geocode/json?"+query.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	// The API reports most failures with a 200 and a status
+	switch resp.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return nil, ErrNoResults
+	default:
+		return nil, fmt.Errorf("google geocoding failed: %s %s", resp.Status, resp.ErrorMessage)
+	}
+	if len(resp.Results) == 0 {
+		return nil, ErrNoResults
+	}
+
+	best := resp.Results[0]
+	return &Result{
+		Point:            Point{Lat: best.Geometry.Location.Lat, Lng: best.Geometry.Location.Lng},
+		FormattedAddress: best.FormattedAddress,
+		Provider:         ProviderGoogle,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// locatorBatchSize bounds the events geocoded per pass
+const locatorBatchSize = 100
+
+// Locator geocodes the locations of calendar events in the background, so planning can
+// tell meetings at the office from ones across town. Virtual locations ("Zoom", meeting
+// links) and places the provider can't find are marked as looked up without coordinates.
+type Locator struct {
+	events   repository.EventRepository
+	geocoder Geocoder
+	interval time.Duration
+}
+
+// NewLocator creates a locator; call Run to start it
+func NewLocator(events repository.EventRepository, geocoder Geocoder, interval time.Duration) *Locator {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Locator{events: events, geocoder: geocoder, interval: interval}
+}
+
+// Run geocodes new event locations every interval until ctx is cancelled
+func (l *Locator) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		l.locatePending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// locatePending works through events until none are left or the provider fails, in which
+// case the rest wait for the next tick
+func (l *Locator) locatePending(ctx context.Context) {
+	for {
+		events, err := l.events.ListUngeocoded(ctx, locatorBatchSize)
+		if err != nil {
+			log.Printf("Failed to list events to geocode: %v", err)
+			return
+		}
+
+		for _, event := range events {
+			var latitude, longitude *float64
+			if !IsVirtualLocation(*event.Location) {
+				result, err := l.geocoder.Geocode(ctx, *event.Location)
+				switch {
+				case err == nil:
+					latitude, longitude = &result.Lat, &result.Lng
+				case !errors.Is(err, ErrNoResults):
+					log.Printf("Failed to geocode location of event %s: %v", event.ID, err)
+					return
+				}
+			}
+			if err := l.events.SetLocationPoint(ctx, event.ID, latitude, longitude); err != nil && !errors.Is(err, repository.ErrNotFound) {
+				log.Printf("Failed to store location of event %s: %v", event.ID, err)
+				return
+			}
+		}
+
+		if len(events) < locatorBatchSize {
+			return
+		}
+	}
+}
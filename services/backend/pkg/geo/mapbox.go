@@ -0,0 +1,56 @@
+package geo
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultMapboxBaseURL = "https://api.mapbox.com"
+
+// mapboxGeocoder uses the Mapbox Geocoding API (v5, mapbox.places)
+type mapboxGeocoder struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newMapboxGeocoder(cfg Config, httpClient *http.Client) *mapboxGeocoder {
+	g := &mapboxGeocoder{baseURL: defaultMapboxBaseURL, token: cfg.APIKey, http: httpClient}
+	if cfg.BaseURL != "" {
+		g.baseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	return g
+}
+
+type mapboxResponse struct {
+	Features []struct {
+		PlaceName string `json:"place_name"`
+		// Center is [longitude, latitude]
+		Center []float64 `json:"center"`
+	} `json:"features"`
+}
+
+func (g *mapboxGeocoder) Provider() string {
+	return ProviderMapbox
+}
+
+func (g *mapboxGeocoder) Geocode(ctx context.Context, address string) (*Result, error) {
+	query := url.Values{"access_token": {g.token}, "limit": {"1"}}
+	endpoint := g.baseURL + "/geocoding/v5/mapbox.places/" + url.PathEscape(address) + ".json?" + query.Encode()
+	var resp mapboxResponse
+	if err := getJSON(ctx, g.http, ProviderMapbox, endpoint, nil, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Features) == 0 || len(resp.Features[0].Center) != 2 {
+		return nil, ErrNoResults
+	}
+
+	best := resp.Features[0]
+	return &Result{
+		Point:            Point{Lat: best.Center[1], Lng: best.Center[0]},
+		FormattedAddress: best.PlaceName,
+		Provider:         ProviderMapbox,
+	}, nil
+}
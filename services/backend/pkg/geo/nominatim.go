@@ -0,0 +1,96 @@
+package geo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultNominatimBaseURL = "https://nominatim.openstreetmap.org"
+
+// nominatimInterval is the minimum gap between requests; the public instance allows one
+// request per second
+const nominatimInterval = time.Second
+
+// nominatimGeocoder uses the OpenStreetMap Nominatim search API
+type nominatimGeocoder struct {
+	baseURL   string
+	userAgent string
+	http      *http.Client
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func newNominatimGeocoder(cfg Config, httpClient *http.Client) *nominatimGeocoder {
+	g := &nominatimGeocoder{baseURL: defaultNominatimBaseURL, userAgent: cfg.UserAgent, http: httpClient}
+	if cfg.BaseURL != "" {
+		g.baseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	return g
+}
+
+type nominatimPlace struct {
+	// Coordinates are returned as strings
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	DisplayName string `json:"display_name"`
+}
+
+func (g *nominatimGeocoder) Provider() string {
+	return ProviderNominatim
+}
+
+func (g *nominatimGeocoder) Geocode(ctx context.Context, address string) (*Result, error) {
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"q": {address}, "format": {"jsonv2"}, "limit": {"1"}}
+	var places []nominatimPlace
+	headers := map[string]string{"User-Agent": g.userAgent}
+	if err := getJSON(ctx, g.http, ProviderNominatim, g.baseURL+"/search?"+query.Encode(), headers, &places); err != nil {
+		return nil, err
+	}
+	if len(places) == 0 {
+		return nil, ErrNoResults
+	}
+
+	lat, err := strconv.ParseFloat(places[0].Lat, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nominatim latitude %q", places[0].Lat)
+	}
+	lng, err := strconv.ParseFloat(places[0].Lon, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nominatim longitude %q", places[0].Lon)
+	}
+	return &Result{
+		Point:            Point{Lat: lat, Lng: lng},
+		FormattedAddress: places[0].DisplayName,
+		Provider:         ProviderNominatim,
+	}, nil
+}
+
+// wait spaces requests nominatimInterval apart
+func (g *nominatimGeocoder) wait(ctx context.Context) error {
+	g.mu.Lock()
+	next := g.last.Add(nominatimInterval)
+	now := time.Now()
+	if next.Before(now) {
+		next = now
+	}
+	g.last = next
+	g.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(next)):
+		return nil
+	}
+}
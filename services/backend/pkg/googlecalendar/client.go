@@ -0,0 +1,187 @@
+package googlecalendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultBaseURL is the Calendar API v3 endpoint
+const DefaultBaseURL = "https://www.googleapis.com/calendar/v3"
+
+// ErrSyncTokenExpired is returned by ListEvents when Google rejects the sync token
+// (HTTP 410); the caller must discard it and do a full sync
+var ErrSyncTokenExpired = errors.New("google calendar sync token expired")
+
+// APIError is a non-2xx response from the Calendar API
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("google calendar API returned %d: %s", e.StatusCode, e.Body)
+}
+
+// Client is a minimal Calendar API client covering push channels and incremental sync
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client; an empty baseURL uses DefaultBaseURL
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{baseURL: baseURL, http: &http.Client{Timeout: timeout}}
+}
+
+// WatchRequest is the body of events.watch
+type WatchRequest struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	Token   string `json:"token,omitempty"`
+}
+
+// Channel is the events.watch response
+type Channel struct {
+	ID          string `json:"id"`
+	ResourceID  string `json:"resourceId"`
+	ResourceURI string `json:"resourceUri"`
+	// Expiration is in milliseconds since the epoch, encoded as a string
+	Expiration string `json:"expiration"`
+}
+
+// ExpiresAt parses the channel expiration
+func (c *Channel) ExpiresAt() (time.Time, error) {
+	ms, err := strconv.ParseInt(c.Expiration, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid channel expiration %q: %w", c.Expiration, err)
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// EventTime is an event start or end; Date is set instead of DateTime for all-day events
+type EventTime struct {
+	Date     string     `json:"date,omitempty"`
+	DateTime *time.Time `json:"dateTime,omitempty"`
+	TimeZone string     `json:"timeZone,omitempty"`
+}
+
+// Attendee is an event attendee
+type Attendee struct {
+	Email       string `json:"email"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// Event is the subset of a Calendar event the planner uses
+type Event struct {
+	ID               string     `json:"id"`
+	Status           string     `json:"status"` // confirmed, tentative or cancelled
+	Summary          string     `json:"summary"`
+	Description      string     `json:"description"`
+	Location         string     `json:"location"`
+	Start            EventTime  `json:"start"`
+	End              EventTime  `json:"end"`
+	Attendees        []Attendee `json:"attendees"`
+	RecurringEventID string     `json:"recurringEventId"`
+}
+
+// EventList is one page of events.list
+type EventList struct {
+	Items         []Event `json:"items"`
+	NextPageToken string  `json:"nextPageToken"`
+	NextSyncToken string  `json:"nextSyncToken"`
+}
+
+// Watch opens a push notification channel on a calendar's events
+func (c *Client) Watch(ctx context.Context, accessToken, calendarID string, req WatchRequest) (*Channel, error) {
+	var channel Channel
+	path := "/calendars/" + url.PathEscape(calendarID) + "/events/watch"
+	if err := c.do(ctx, accessToken, http.MethodPost, path, nil, req, &channel); err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// Stop closes a push notification channel
+func (c *Client) Stop(ctx context.Context, accessToken, channelID, resourceID string) error {
+	body := map[string]string{"id": channelID, "resourceId": resourceID}
+	return c.do(ctx, accessToken, http.MethodPost, "/channels/stop", nil, body, nil)
+}
+
+// ListEvents fetches one page of events. With a syncToken only changes since the token was
+// issued are returned, including cancelled events.
+func (c *Client) ListEvents(ctx context.Context, accessToken, calendarID, syncToken, pageToken string) (*EventList, error) {
+	query := url.Values{}
+	query.Set("singleEvents", "true")
+	query.Set("showDeleted", "true")
+	query.Set("maxResults", "250")
+	if syncToken != "" {
+		query.Set("syncToken", syncToken)
+	}
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+
+	var list EventList
+	path := "/calendars/" + url.PathEscape(calendarID) + "/events"
+	err := c.do(ctx, accessToken, http.MethodGet, path, query, nil, &list)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusGone {
+		return nil, ErrSyncTokenExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (c *Client) do(ctx context.Context, accessToken, method, path string, query url.Values, body, out interface{}) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(data)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,427 @@
+package googlecalendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/breaker"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/google/uuid"
+)
+
+// ErrUnknownChannel is returned for notifications that don't match a stored channel
+var ErrUnknownChannel = errors.New("unknown google calendar channel")
+
+// Config tunes watch channels and renewal
+type Config struct {
+	// WebhookURL is the public https address of /webhooks/google-calendar
+	WebhookURL string
+	// BaseURL overrides the Calendar API endpoint
+	BaseURL string
+	Timeout time.Duration // per API request
+	// RenewBefore is how long before expiry a channel is replaced
+	RenewBefore   time.Duration
+	RenewInterval time.Duration
+	// SyncTimeout bounds a single incremental sync triggered by a notification
+	SyncTimeout time.Duration
+	// CircuitBreaker, when set, fails API calls fast while Google is failing; channels
+	// are renewed on the next pass and calendars synced on the next notification
+	CircuitBreaker *breaker.Config
+	// OnChange, when set, is called after each event a sync creates, updates or deletes,
+	// with the previous copy (nil for new events) and the current one (nil once deleted)
+	OnChange func(ctx context.Context, previous, current *models.CalendarEvent)
+}
+
+// Syncer manages watch channels and keeps calendar_events in step with Google
+type Syncer struct {
+	channels repository.GoogleCalendarRepository
+	events   repository.EventRepository
+	tokens   TokenSource
+	client   *Client
+	cfg      Config
+
+	mu sync.Mutex
+	// running holds user/calendar keys with a sync in flight; true means another
+	// notification arrived meanwhile and the sync must run again
+	running map[string]bool
+}
+
+// NewSyncer creates a syncer; call RunRenewals to keep channels alive
+func NewSyncer(channels repository.GoogleCalendarRepository, events repository.EventRepository, tokens TokenSource, cfg Config) *Syncer {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 15 * time.Second
+	}
+	if cfg.RenewBefore <= 0 {
+		cfg.RenewBefore = 24 * time.Hour
+	}
+	if cfg.RenewInterval <= 0 {
+		cfg.RenewInterval = time.Hour
+	}
+	if cfg.SyncTimeout <= 0 {
+		cfg.SyncTimeout = 2 * time.Minute
+	}
+	client := NewClient(cfg.BaseURL, cfg.Timeout)
+	if cfg.CircuitBreaker != nil {
+		client.http.Transport = breaker.Transport(breaker.New("google_calendar", *cfg.CircuitBreaker), nil)
+	}
+	return &Syncer{
+		channels: channels,
+		events:   events,
+		tokens:   tokens,
+		client:   client,
+		cfg:      cfg,
+		running:  map[string]bool{},
+	}
+}
+
+// Watch opens a push channel for a user's calendar. The sync token of an existing channel
+// on the same calendar is carried over so renewals don't force a full sync.
+func (s *Syncer) Watch(ctx context.Context, userID, calendarID string) (*models.GoogleCalendarChannel, error) {
+	accessToken, err := s.tokens.AccessToken(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.channels.ListChannelsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var syncToken *string
+	var lastSynced *time.Time
+	for _, channel := range existing {
+		if channel.CalendarID == calendarID && channel.SyncToken != nil {
+			syncToken, lastSynced = channel.SyncToken, channel.LastSyncedAt
+		}
+	}
+
+	secret, err := channelToken()
+	if err != nil {
+		return nil, err
+	}
+	id := uuid.New().String()
+	watched, err := s.client.Watch(ctx, accessToken, calendarID, WatchRequest{
+		ID:      id,
+		Type:    "web_hook",
+		Address: s.cfg.WebhookURL,
+		Token:   secret,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch calendar: %w", err)
+	}
+	expiration, err := watched.ExpiresAt()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	channel := &models.GoogleCalendarChannel{
+		ID:           id,
+		UserID:       userID,
+		CalendarID:   calendarID,
+		ResourceID:   watched.ResourceID,
+		Token:        secret,
+		Expiration:   expiration,
+		SyncToken:    syncToken,
+		LastSyncedAt: lastSynced,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.channels.CreateChannel(ctx, channel); err != nil {
+		// Don't leave Google pushing to a channel we can't authenticate
+		_ = s.client.Stop(ctx, accessToken, id, watched.ResourceID)
+		return nil, err
+	}
+
+	if syncToken == nil {
+		s.Trigger(userID, calendarID)
+	}
+	return channel, nil
+}
+
+// Unwatch stops and removes all of a user's channels
+func (s *Syncer) Unwatch(ctx context.Context, userID string) error {
+	channels, err := s.channels.ListChannelsByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		if err := s.stop(ctx, channel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Channels lists a user's channels
+func (s *Syncer) Channels(ctx context.Context, userID string) ([]*models.GoogleCalendarChannel, error) {
+	return s.channels.ListChannelsByUser(ctx, userID)
+}
+
+// Authenticate matches a notification's headers against the stored channel
+func (s *Syncer) Authenticate(ctx context.Context, channelID, token, resourceID string) (*models.GoogleCalendarChannel, error) {
+	channel, err := s.channels.GetChannel(ctx, channelID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUnknownChannel
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(channel.Token), []byte(token)) != 1 || channel.ResourceID != resourceID {
+		return nil, ErrUnknownChannel
+	}
+	return channel, nil
+}
+
+// Trigger starts an incremental sync in the background. Notifications that arrive while a
+// sync for the same calendar is running are folded into one follow-up sync.
+func (s *Syncer) Trigger(userID, calendarID string) {
+	key := userID + "/" + calendarID
+
+	s.mu.Lock()
+	if _, running := s.running[key]; running {
+		s.running[key] = true
+		s.mu.Unlock()
+		return
+	}
+	s.running[key] = false
+	s.mu.Unlock()
+
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), s.cfg.SyncTimeout)
+			if err := s.Sync(ctx, userID, calendarID); err != nil {
+				log.Printf("Google Calendar sync for user %s failed: %v", userID, err)
+			}
+			cancel()
+
+			s.mu.Lock()
+			if !s.running[key] {
+				delete(s.running, key)
+				s.mu.Unlock()
+				return
+			}
+			s.running[key] = false
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// Sync applies changes since the stored sync token, falling back to a full sync when there
+// is no token or Google has expired it
+func (s *Syncer) Sync(ctx context.Context, userID, calendarID string) error {
+	accessToken, err := s.tokens.AccessToken(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	channels, err := s.channels.ListChannelsByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	syncToken := ""
+	for _, channel := range channels {
+		if channel.CalendarID == calendarID && channel.SyncToken != nil {
+			syncToken = *channel.SyncToken
+		}
+	}
+
+	pageToken := ""
+	for {
+		list, err := s.client.ListEvents(ctx, accessToken, calendarID, syncToken, pageToken)
+		if errors.Is(err, ErrSyncTokenExpired) && syncToken != "" {
+			log.Printf("Google Calendar sync token expired for user %s, running full sync", userID)
+			syncToken, pageToken = "", ""
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for i := range list.Items {
+			if err := s.apply(ctx, userID, &list.Items[i]); err != nil {
+				return fmt.Errorf("event %s: %w", list.Items[i].ID, err)
+			}
+		}
+
+		if list.NextPageToken != "" {
+			pageToken = list.NextPageToken
+			continue
+		}
+		var next *string
+		if list.NextSyncToken != "" {
+			next = &list.NextSyncToken
+		}
+		return s.channels.UpdateSyncState(ctx, userID, calendarID, next, time.Now())
+	}
+}
+
+// RunRenewals replaces channels before they expire until ctx is cancelled
+func (s *Syncer) RunRenewals(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		s.renewExpiring(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Syncer) renewExpiring(ctx context.Context) {
+	channels, err := s.channels.ChannelsExpiringBefore(ctx, time.Now().Add(s.cfg.RenewBefore))
+	if err != nil {
+		log.Printf("Failed to list expiring Google Calendar channels: %v", err)
+		return
+	}
+
+	for _, channel := range channels {
+		if _, err := s.Watch(ctx, channel.UserID, channel.CalendarID); err != nil {
+			log.Printf("Failed to renew Google Calendar channel %s: %v", channel.ID, err)
+			// An expired channel receives nothing; drop it so it isn't retried forever
+			if time.Now().After(channel.Expiration) {
+				_, _ = s.channels.DeleteChannel(ctx, channel.ID)
+			}
+			continue
+		}
+		if err := s.stop(ctx, channel); err != nil {
+			log.Printf("Failed to stop old Google Calendar channel %s: %v", channel.ID, err)
+		}
+	}
+}
+
+// stop closes a channel at Google and deletes it. A channel Google no longer knows about
+// is deleted anyway.
+func (s *Syncer) stop(ctx context.Context, channel *models.GoogleCalendarChannel) error {
+	if time.Now().Before(channel.Expiration) {
+		accessToken, err := s.tokens.AccessToken(ctx, channel.UserID)
+		if err == nil {
+			err = s.client.Stop(ctx, accessToken, channel.ID, channel.ResourceID)
+		}
+		var apiErr *APIError
+		if err != nil && !(errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound) && !errors.Is(err, ErrNotConnected) {
+			return err
+		}
+	}
+	_, err := s.channels.DeleteChannel(ctx, channel.ID)
+	return err
+}
+
+// apply upserts or deletes the local copy of a Google event
+func (s *Syncer) apply(ctx context.Context, userID string, event *Event) error {
+	var previous *models.CalendarEvent
+	if s.cfg.OnChange != nil {
+		var err error
+		previous, err = s.events.GetByGoogleID(ctx, userID, event.ID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+	}
+
+	if event.Status == "cancelled" {
+		deleted, err := s.events.DeleteByGoogleID(ctx, userID, event.ID)
+		if err == nil && deleted && s.cfg.OnChange != nil {
+			s.cfg.OnChange(ctx, previous, nil)
+		}
+		return err
+	}
+	converted, err := toCalendarEvent(userID, event)
+	if err != nil {
+		return err
+	}
+	if err := s.events.UpsertByGoogleID(ctx, converted); err != nil {
+		return err
+	}
+	if s.cfg.OnChange != nil {
+		s.cfg.OnChange(ctx, previous, converted)
+	}
+	return nil
+}
+
+// toCalendarEvent maps a Google event onto a new calendar_events row. Meeting type and
+// attendance mode aren't known from Google and start out as UNKNOWN/FLEXIBLE.
+func toCalendarEvent(userID string, event *Event) (*models.CalendarEvent, error) {
+	start, allDay, err := parseEventTime(event.Start)
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	end, _, err := parseEventTime(event.End)
+	if err != nil {
+		return nil, fmt.Errorf("end: %w", err)
+	}
+
+	now := time.Now()
+	googleID := event.ID
+	converted := &models.CalendarEvent{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		Summary:        event.Summary,
+		StartTime:      start,
+		EndTime:        end,
+		MeetingType:    models.MeetingTypeUnknown,
+		AttendanceMode: models.AttendanceFlexible,
+		IsAllDay:       allDay,
+		IsRecurring:    event.RecurringEventID != "",
+		GoogleEventID:  &googleID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if converted.Summary == "" {
+		converted.Summary = "(No title)"
+	}
+	if event.Description != "" {
+		converted.Description = &event.Description
+	}
+	if event.Location != "" {
+		converted.Location = &event.Location
+	}
+	if len(event.Attendees) > 0 {
+		names := make([]string, len(event.Attendees))
+		for i, attendee := range event.Attendees {
+			names[i] = attendee.Email
+			if attendee.DisplayName != "" {
+				names[i] = attendee.DisplayName
+			}
+		}
+		data, err := json.Marshal(names)
+		if err != nil {
+			return nil, err
+		}
+		attendees := string(data)
+		converted.Attendees = &attendees
+	}
+	return converted, nil
+}
+
+// parseEventTime returns the instant and whether it is an all-day date
+func parseEventTime(t EventTime) (time.Time, bool, error) {
+	if t.DateTime != nil {
+		return *t.DateTime, false, nil
+	}
+	if t.Date == "" {
+		return time.Time{}, false, fmt.Errorf("missing date")
+	}
+	date, err := time.Parse("2006-01-02", t.Date)
+	return date, true, err
+}
+
+// channelToken generates the secret Google echoes back in X-Goog-Channel-Token
+func channelToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,60 @@
+package googlecalendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// ErrNotConnected means the user has no usable Google credentials
+var ErrNotConnected = errors.New("google calendar is not connected for this user")
+
+// TokenSource supplies a Calendar API access token for a user
+type TokenSource interface {
+	AccessToken(ctx context.Context, userID string) (string, error)
+}
+
+// storedTokens is the users.oauth_tokens JSON written by the Google OAuth flow
+type storedTokens struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// StoredTokenSource reads access tokens from users.oauth_tokens. Refreshing expired tokens
+// belongs to the OAuth flow; until it lands an expired token is reported as not connected.
+type StoredTokenSource struct {
+	users repository.UserRepository
+}
+
+// NewStoredTokenSource creates a token source backed by the user repository
+func NewStoredTokenSource(users repository.UserRepository) *StoredTokenSource {
+	return &StoredTokenSource{users: users}
+}
+
+// AccessToken returns the user's stored access token
+func (s *StoredTokenSource) AccessToken(ctx context.Context, userID string) (string, error) {
+	raw, err := s.users.OAuthTokens(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if raw == nil || *raw == "" {
+		return "", ErrNotConnected
+	}
+
+	var tokens storedTokens
+	if err := json.Unmarshal([]byte(*raw), &tokens); err != nil {
+		return "", fmt.Errorf("invalid stored oauth tokens: %w", err)
+	}
+	if tokens.AccessToken == "" {
+		return "", ErrNotConnected
+	}
+	if !tokens.Expiry.IsZero() && time.Now().After(tokens.Expiry) {
+		return "", fmt.Errorf("%w: access token expired", ErrNotConnected)
+	}
+	return tokens.AccessToken, nil
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/resolvers"
+	"github.com/gorilla/mux"
+)
+
+// AdminHandler serves operator endpoints. They are authenticated with a shared token
+// (X-Admin-Token) rather than a user session, since there are no admin accounts.
+type AdminHandler struct {
+	resolver *resolvers.Resolver
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(resolver *resolvers.Resolver) *AdminHandler {
+	return &AdminHandler{resolver: resolver}
+}
+
+// JobQuotaResponse is the response of the job quota endpoints
+type JobQuotaResponse struct {
+	Success bool          `json:"success"`
+	Data    *JobQuotaData `json:"data,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// JobQuotaData shows a user's override next to the defaults it replaces
+type JobQuotaData struct {
+	Defaults resolvers.JobQuotaLimits `json:"defaults"`
+	Override *models.JobQuota         `json:"override"`
+}
+
+// HasAdminToken reports whether r carries the configured admin token in X-Admin-Token
+func HasAdminToken(r *http.Request, token string) bool {
+	given := r.Header.Get("X-Admin-Token")
+	return token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// RequireAdminToken rejects requests without the configured admin token
+func RequireAdminToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasAdminToken(r, token) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(JobQuotaResponse{Success: false, Error: "Admin token required"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetJobQuota handles GET /admin/users/{id}/job-quota
+func (h *AdminHandler) GetJobQuota(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	quota, err := h.resolver.JobQuota(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(JobQuotaResponse{Success: false, Error: "Failed to load job quota"})
+		return
+	}
+	json.NewEncoder(w).Encode(JobQuotaResponse{Success: true, Data: &JobQuotaData{Defaults: h.resolver.DefaultJobQuota(), Override: quota}})
+}
+
+// SetJobQuota handles PUT /admin/users/{id}/job-quota, replacing the user's override
+func (h *AdminHandler) SetJobQuota(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input resolvers.SetJobQuotaInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(JobQuotaResponse{Success: false, Error: "Invalid request body"})
+		return
+	}
+
+	quota, err := h.resolver.SetJobQuota(r.Context(), mux.Vars(r)["id"], input)
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.HasPrefix(err.Error(), "error ") {
+			status = http.StatusInternalServerError
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(JobQuotaResponse{Success: false, Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(JobQuotaResponse{Success: true, Data: &JobQuotaData{Defaults: h.resolver.DefaultJobQuota(), Override: quota}})
+}
+
+// DeleteJobQuota handles DELETE /admin/users/{id}/job-quota, restoring the defaults
+func (h *AdminHandler) DeleteJobQuota(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err := h.resolver.DeleteJobQuota(r.Context(), mux.Vars(r)["id"]); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(JobQuotaResponse{Success: false, Error: "Failed to delete job quota"})
+		return
+	}
+	json.NewEncoder(w).Encode(JobQuotaResponse{Success: true, Data: &JobQuotaData{Defaults: h.resolver.DefaultJobQuota()}})
+}
+
+// TenantResponse is the response of the tenant endpoints
+type TenantResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// ListTenants handles GET /admin/tenants
+func (h *AdminHandler) ListTenants(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tenants, err := h.resolver.Tenants(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TenantResponse{Success: false, Error: "Failed to load tenants"})
+		return
+	}
+	json.NewEncoder(w).Encode(TenantResponse{Success: true, Data: tenants})
+}
+
+// PutTenant handles PUT /admin/tenants/{id}, creating or renaming a tenant
+func (h *AdminHandler) PutTenant(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TenantResponse{Success: false, Error: "Invalid request body"})
+		return
+	}
+
+	t, err := h.resolver.PutTenant(r.Context(), mux.Vars(r)["id"], input.Name)
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.HasPrefix(err.Error(), "error ") {
+			status = http.StatusInternalServerError
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(TenantResponse{Success: false, Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(TenantResponse{Success: true, Data: t})
+}
+
+// RotateSCIMToken handles POST /admin/tenants/{id}/scim-token, giving the tenant a new
+// token for its identity provider's SCIM client. The old token stops working.
+func (h *AdminHandler) RotateSCIMToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	token, err := h.resolver.RotateSCIMToken(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		status := http.StatusNotFound
+		if strings.HasPrefix(err.Error(), "error ") {
+			status = http.StatusInternalServerError
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(TenantResponse{Success: false, Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(TenantResponse{Success: true, Data: map[string]string{
+		"token":   token,
+		"baseUrl": SCIMPath,
+	}})
+}
+
+// DisableSCIM handles DELETE /admin/tenants/{id}/scim-token, stopping provisioning
+func (h *AdminHandler) DisableSCIM(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := h.resolver.DisableSCIM(r.Context(), mux.Vars(r)["id"]); err != nil {
+		status := http.StatusNotFound
+		if strings.HasPrefix(err.Error(), "error ") {
+			status = http.StatusInternalServerError
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(TenantResponse{Success: false, Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(TenantResponse{Success: true})
+}
+
+// ExperimentResults handles GET /admin/experiments/{name}/results, comparing the
+// acceptance rate of each variant. The optional from and to query parameters
+// (YYYY-MM-DD, inclusive) limit it to jobs targeting those days.
+func (h *AdminHandler) ExperimentResults(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	results, err := h.resolver.ExperimentResults(r.Context(), mux.Vars(r)["name"], query.Get("from"), query.Get("to"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.HasPrefix(err.Error(), "error ") {
+			status = http.StatusInternalServerError
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(TenantResponse{Success: false, Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(TenantResponse{Success: true, Data: results})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/resolvers"
+	"github.com/commute-planner/backend/pkg/tenant"
+	"github.com/gorilla/mux"
+)
+
+// APITokenHandler manages the personal access tokens a browser extension or CLI uses
+// to act for a user, and authenticates requests made with them
+type APITokenHandler struct {
+	resolver *resolvers.Resolver
+}
+
+// NewAPITokenHandler creates a new API token handler
+func NewAPITokenHandler(resolver *resolvers.Resolver) *APITokenHandler {
+	return &APITokenHandler{resolver: resolver}
+}
+
+// APITokenResponse is the response of the API token endpoints
+type APITokenResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// ListTokens handles GET /auth/tokens
+func (h *APITokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	tokens, err := h.resolver.APITokens(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to list API tokens: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APITokenResponse{Success: false, Error: "Failed to load API tokens"})
+		return
+	}
+	json.NewEncoder(w).Encode(APITokenResponse{Success: true, Data: tokens})
+}
+
+// CreateToken handles POST /auth/tokens with {"name": "...", "scopes": [...],
+// "expiresInDays": 90}. The token itself is only in this response.
+func (h *APITokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	var input resolvers.CreateAPITokenInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APITokenResponse{Success: false, Error: "Invalid request body"})
+		return
+	}
+
+	user := GetUserFromContext(r.Context())
+	token, err := h.resolver.CreateAPIToken(r.Context(), user.ID, input)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APITokenResponse{Success: false, Error: err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(APITokenResponse{Success: true, Data: token, Message: "Store the token now; it can't be shown again"})
+}
+
+// RevokeToken handles DELETE /auth/tokens/{id}
+func (h *APITokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	revoked, err := h.resolver.RevokeAPIToken(r.Context(), user.ID, mux.Vars(r)["id"])
+	if err != nil {
+		log.Printf("Failed to revoke API token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APITokenResponse{Success: false, Error: "Failed to revoke API token"})
+		return
+	}
+	if !revoked {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APITokenResponse{Success: false, Error: "API token not found"})
+		return
+	}
+	json.NewEncoder(w).Encode(APITokenResponse{Success: true, Message: "API token revoked"})
+}
+
+// Middleware authenticates requests with an "Authorization: Bearer cpat_..." API token,
+// adding its user to the context like AuthMiddleware does for session tokens, along with
+// the token's scopes. Other requests pass through untouched. It runs before
+// AuthMiddleware, after the tenant is resolved from the host.
+func (h *APITokenHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := bearerToken(r)
+		if !strings.HasPrefix(secret, resolvers.APITokenPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, token, err := h.resolver.AuthenticateAPIToken(r.Context(), secret)
+		if err != nil {
+			if !errors.Is(err, resolvers.ErrInvalidAPIToken) {
+				log.Printf("Failed to authenticate API token: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(APITokenResponse{Success: false, Error: "Invalid or expired API token"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withAPIToken(r.Context(), user, token)))
+	})
+}
+
+func withAPIToken(ctx context.Context, user *models.User, token *models.APIToken) context.Context {
+	ctx = context.WithValue(ctx, "user", user)
+	if user.TenantID != "" {
+		ctx = tenant.WithID(ctx, user.TenantID)
+	}
+	scopes := make([]string, len(token.Scopes))
+	for i, scope := range token.Scopes {
+		scopes[i] = string(scope)
+	}
+	return authz.WithTokenScopes(ctx, scopes)
+}
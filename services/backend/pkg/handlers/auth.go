@@ -2,17 +2,23 @@ package handlers
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"strings"
 
 	"github.com/commute-planner/backend/pkg/auth"
+	"github.com/commute-planner/backend/pkg/authz"
 	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/tenant"
 )
 
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
 	authProvider auth.AuthProvider
+	// magicLinks is set when magic link login is enabled
+	magicLinks *magicLinkSender
 }
 
 // NewAuthHandler creates a new auth handler
@@ -35,10 +41,16 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// LogoutRequest represents the logout request payload
+type LogoutRequest struct {
+	AllDevices bool `json:"allDevices"`
+}
+
 // AuthResponse represents the auth response
 type AuthResponse struct {
 	Success bool               `json:"success"`
 	Data    *auth.AuthResult   `json:"data,omitempty"`
+	Message string             `json:"message,omitempty"`
 	Error   string             `json:"error,omitempty"`
 }
 
@@ -70,7 +82,7 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.authProvider.Signup(r.Context(), req.Email, req.Password, req.Name)
+	result, err := h.authProvider.Signup(withClient(r), req.Email, req.Password, req.Name)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(AuthResponse{
@@ -114,7 +126,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.authProvider.Login(r.Context(), req.Email, req.Password)
+	result, err := h.authProvider.Login(withClient(r), req.Email, req.Password)
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(AuthResponse{
@@ -157,36 +169,206 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// AuthMiddleware validates JWT tokens and adds user to context
-func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			next.ServeHTTP(w, r)
+// Logout revokes the current token, or every token for the user when allDevices is set
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := GetUserFromContext(r.Context())
+	token := bearerToken(r)
+	if user == nil || token == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(AuthResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+
+	// Body is optional - an empty body logs out the current session only
+	var req LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(AuthResponse{
+				Success: false,
+				Error:   "Invalid request payload",
+			})
 			return
 		}
+	}
+
+	if err := h.authProvider.RevokeToken(r.Context(), token); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(AuthResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if req.AllDevices {
+		if err := h.authProvider.RevokeAllTokens(r.Context(), user.ID); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(AuthResponse{
+		Success: true,
+	})
+}
+
+// authCodeURLProvider is implemented by providers that support the OAuth authorization code flow
+type authCodeURLProvider interface {
+	AuthCodeURL(state string) string
+}
+
+// oauthStateCookie holds the CSRF state between the login redirect and the callback
+const oauthStateCookie = "oauth_state"
+
+// OAuthLogin redirects the browser to the identity provider's login page
+func (h *AuthHandler) OAuthLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.authProvider.(authCodeURLProvider)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(AuthResponse{
+			Success: false,
+			Error:   "OAuth login is not enabled",
+		})
+		return
+	}
+
+	stateBytes := make([]byte, 32)
+	if _, err := rand.Read(stateBytes); err != nil {
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	state := base64.RawURLEncoding.EncodeToString(stateBytes)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/auth",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, provider.AuthCodeURL(state), http.StatusFound)
+}
+
+// OAuthCallback completes the authorization code flow and returns the usual auth payload
+func (h *AuthHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if errParam := r.URL.Query().Get("error"); errParam != "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(AuthResponse{
+			Success: false,
+			Error:   "Login failed: " + errParam,
+		})
+		return
+	}
+
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || cookie.Value == "" || cookie.Value != r.URL.Query().Get("state") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(AuthResponse{
+			Success: false,
+			Error:   "Invalid OAuth state",
+		})
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/auth", MaxAge: -1})
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(AuthResponse{
+			Success: false,
+			Error:   "Authorization code is required",
+		})
+		return
+	}
+
+	result, err := h.authProvider.HandleOAuth(withClient(r), "oidc", code)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(AuthResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(AuthResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// jwksProvider is implemented by providers that sign their own tokens with asymmetric keys
+type jwksProvider interface {
+	JWKS() auth.JWKS
+}
+
+// JWKS serves the public signing keys at /.well-known/jwks.json
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	provider, ok := h.authProvider.(jwksProvider)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(AuthResponse{
+			Success: false,
+			Error:   "JWKS is not available for this auth provider",
+		})
+		return
+	}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+	// Keys change only on rotation; let verifiers cache briefly
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(provider.JWKS())
+}
+
+// AuthMiddleware validates JWT tokens and adds user to context
+// Revoked tokens fail validation, so they are treated like anonymous requests
+func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" || GetUserFromContext(r.Context()) != nil {
+			// No token, or an API token already authenticated the request
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		token := parts[1]
 		user, err := h.authProvider.ValidateToken(r.Context(), token)
 		if err != nil {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Add user to context
+		// Add user to context, scoped to the user's tenant
 		ctx := context.WithValue(r.Context(), "user", user)
+		if user.TenantID != "" {
+			ctx = tenant.WithID(ctx, user.TenantID)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
 // RequireAuth middleware that requires authentication
+// API tokens are refused; routes open to them use RequireScope instead
 func RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := GetUserFromContext(r.Context())
@@ -199,10 +381,54 @@ func RequireAuth(next http.Handler) http.Handler {
 			})
 			return
 		}
+		if _, ok := authz.TokenScopes(r.Context()); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(AuthResponse{
+				Success: false,
+				Error:   "This endpoint isn't available to API tokens",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireScope middleware that requires authentication, allowing API tokens with scope
+func RequireScope(scope models.APITokenScope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := GetUserFromContext(r.Context())
+		if user == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(AuthResponse{
+				Success: false,
+				Error:   "Authentication required",
+			})
+			return
+		}
+		if scopes, ok := authz.TokenScopes(r.Context()); ok && !(authz.Viewer{Token: true, Scopes: scopes}).HasScope(string(scope)) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(AuthResponse{
+				Success: false,
+				Error:   "This endpoint needs an API token with the " + string(scope) + " scope",
+			})
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) string {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return ""
+	}
+	return parts[1]
+}
+
 // GetUserFromContext extracts user from request context
 func GetUserFromContext(ctx context.Context) *models.User {
 	user, ok := ctx.Value("user").(*models.User)
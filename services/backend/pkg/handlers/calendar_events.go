@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/resolvers"
+)
+
+// CalendarEventHandler serves the REST calendar event API
+type CalendarEventHandler struct {
+	resolver *resolvers.Resolver
+}
+
+// NewCalendarEventHandler creates a new calendar event handler
+func NewCalendarEventHandler(resolver *resolvers.Resolver) *CalendarEventHandler {
+	return &CalendarEventHandler{resolver: resolver}
+}
+
+// BatchCreateRequest is the body of POST /api/v1/calendar-events:batch
+type BatchCreateRequest struct {
+	Events []resolvers.CalendarEventInput `json:"events"`
+}
+
+// BatchCreateResponse is the response of POST /api/v1/calendar-events:batch
+type BatchCreateResponse struct {
+	Success bool                                      `json:"success"`
+	Data    *resolvers.BulkCreateCalendarEventsResult `json:"data,omitempty"`
+	Error   string                                    `json:"error,omitempty"`
+}
+
+// BatchCreate imports events for the authenticated user (e.g. when migrating from another
+// calendar tool). Rows may omit userId; rows for a different user are rejected.
+func (h *CalendarEventHandler) BatchCreate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(BatchCreateResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+
+	var req BatchCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(BatchCreateResponse{Success: false, Error: fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+	if len(req.Events) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(BatchCreateResponse{Success: false, Error: "events is required"})
+		return
+	}
+	if len(req.Events) > resolvers.MaxBulkCalendarEvents {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(BatchCreateResponse{
+			Success: false,
+			Error:   fmt.Sprintf("At most %d events per request", resolvers.MaxBulkCalendarEvents),
+		})
+		return
+	}
+
+	// Only rows for the caller are imported; rows for other users are reported as errors
+	result, err := h.resolver.BulkCreateCalendarEvents(r.Context(), user.ID, req.Events)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(BatchCreateResponse{Success: false, Error: "Failed to import calendar events"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(BatchCreateResponse{Success: true, Data: result})
+}
+
+// Search finds the authenticated user's events for GET /api/v1/calendar-events:search.
+// Optional ?q= words, ?from=YYYY-MM-DD&to=YYYY-MM-DD (inclusive), ?meetingType= and
+// ?attendanceMode= (repeated or comma-separated) and ?limit=.
+func (h *CalendarEventHandler) Search(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+
+	params := r.URL.Query()
+	input := resolvers.CalendarEventSearchInput{Query: params.Get("q")}
+	from, to := params.Get("from"), params.Get("to")
+	if from != "" || to != "" {
+		input.DateRange = &resolvers.DateRangeInput{From: &from, To: &to}
+	}
+	for _, value := range listParam(params["meetingType"]) {
+		input.MeetingTypes = append(input.MeetingTypes, models.MeetingType(value))
+	}
+	for _, value := range listParam(params["attendanceMode"]) {
+		input.AttendanceModes = append(input.AttendanceModes, models.AttendanceMode(value))
+	}
+	if value := params.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "limit must be a number"})
+			return
+		}
+		input.Limit = &n
+	}
+
+	events, err := h.resolver.SearchCalendarEvents(r.Context(), user.ID, input)
+	if err != nil {
+		writeOfficeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(OfficeResponse{Success: true, Data: events})
+}
+
+// listParam flattens repeated and comma-separated query parameter values
+func listParam(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, strings.ToUpper(item))
+			}
+		}
+	}
+	return list
+}
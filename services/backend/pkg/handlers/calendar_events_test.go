@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/resolvers"
+)
+
+// newTestCalendarEventHandler returns a handler over in-memory repositories holding one
+// user with a few events
+func newTestCalendarEventHandler(t *testing.T) (*CalendarEventHandler, *models.User) {
+	t.Helper()
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	user, err := repos.Users.Create(ctx, repository.NewUser{Email: "ada@example.com", Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for _, event := range []*models.CalendarEvent{
+		{ID: "standup", Summary: "Team standup", StartTime: day, MeetingType: models.MeetingTypeStatusUpdate, AttendanceMode: models.AttendanceCanBeRemote},
+		{ID: "workshop", Summary: "Design workshop", StartTime: day.AddDate(0, 0, 1), MeetingType: models.MeetingTypeTeamWorkshop, AttendanceMode: models.AttendanceMustBeInOffice},
+		{ID: "one-on-one", Summary: "1:1 with Sam", StartTime: day.AddDate(0, 0, 3), MeetingType: models.MeetingTypeOneOnOne, AttendanceMode: models.AttendanceFlexible},
+	} {
+		event.UserID = user.ID
+		event.EndTime = event.StartTime.Add(30 * time.Minute)
+		if err := repos.Events.Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resolver := resolvers.NewResolver(repos, nil, nil, resolvers.JobQuotaLimits{}, nil, nil)
+	return NewCalendarEventHandler(resolver), user
+}
+
+func TestCalendarEventSearchHandler(t *testing.T) {
+	handler, user := newTestCalendarEventHandler(t)
+
+	tests := []struct {
+		name       string
+		query      string
+		anonymous  bool
+		wantStatus int
+		wantIDs    []string
+	}{
+		{"all events", "", false, http.StatusOK, []string{"standup", "workshop", "one-on-one"}},
+		{"text query", "q=design", false, http.StatusOK, []string{"workshop"}},
+		{"comma-separated, lowercase meeting types", "meetingType=one_on_one,status_update", false, http.StatusOK, []string{"standup", "one-on-one"}},
+		{"repeated attendance modes", "attendanceMode=FLEXIBLE&attendanceMode=MUST_BE_IN_OFFICE", false, http.StatusOK, []string{"workshop", "one-on-one"}},
+		{"inclusive date range", "from=2026-03-02&to=2026-03-03", false, http.StatusOK, []string{"standup", "workshop"}},
+		{"limit", "limit=1", false, http.StatusOK, []string{"standup"}},
+		{"non-numeric limit", "limit=ten", false, http.StatusBadRequest, nil},
+		{"limit out of range", "limit=500", false, http.StatusBadRequest, nil},
+		{"unknown meeting type", "meetingType=party", false, http.StatusBadRequest, nil},
+		{"malformed date", "from=yesterday", false, http.StatusBadRequest, nil},
+		{"unauthenticated", "", true, http.StatusUnauthorized, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/calendar-events:search?"+tt.query, nil)
+			if !tt.anonymous {
+				req = req.WithContext(context.WithValue(req.Context(), "user", user))
+			}
+			rec := httptest.NewRecorder()
+			handler.Search(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			var resp struct {
+				Success bool                    `json:"success"`
+				Data    []*models.CalendarEvent `json:"data"`
+				Error   string                  `json:"error"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if resp.Success || resp.Error == "" {
+					t.Errorf("response = %+v, want an error", resp)
+				}
+				return
+			}
+			if len(resp.Data) != len(tt.wantIDs) {
+				t.Fatalf("got %d events, want %v", len(resp.Data), tt.wantIDs)
+			}
+			for i, event := range resp.Data {
+				if event.ID != tt.wantIDs[i] {
+					t.Errorf("event %d = %s, want %s", i, event.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListParam(t *testing.T) {
+	got := listParam([]string{"one_on_one, status_update", "", " review ,"})
+	want := []string{"ONE_ON_ONE", "STATUS_UPDATE", "REVIEW"}
+	if len(got) != len(want) {
+		t.Fatalf("listParam() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("listParam() = %v, want %v", got, want)
+		}
+	}
+}
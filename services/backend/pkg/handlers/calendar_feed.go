@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/commute-planner/backend/pkg/ics"
+	"github.com/commute-planner/backend/pkg/resolvers"
+	"github.com/gorilla/mux"
+)
+
+// CalendarFeedHandler serves the ICS feeds users subscribe to in their calendar apps
+type CalendarFeedHandler struct {
+	resolver *resolvers.Resolver
+}
+
+// NewCalendarFeedHandler creates a new calendar feed handler
+func NewCalendarFeedHandler(resolver *resolvers.Resolver) *CalendarFeedHandler {
+	return &CalendarFeedHandler{resolver: resolver}
+}
+
+// Feed handles GET /calendar/feed/{token}.ics with the planned office days and commutes
+// of the feed's user. Unknown and replaced tokens answer 404.
+func (h *CalendarFeedHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	events, err := h.resolver.CalendarFeed(r.Context(), mux.Vars(r)["token"])
+	if errors.Is(err, resolvers.ErrCalendarFeedNotFound) {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to build calendar feed: %v", err)
+		http.Error(w, "Failed to build calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="commute-plan.ics"`)
+	// The URL is a secret; keep the feed out of shared caches
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	if err := ics.Write(w, "Commute plan", events); err != nil {
+		log.Printf("Failed to write calendar feed: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/commute-planner/backend/pkg/contracts"
+	"github.com/gorilla/mux"
+)
+
+// maxContractPayload bounds a payload sent for validation
+const maxContractPayload = 1 << 20
+
+// ContractsHandler serves the schemas of the messages exchanged with the AI workers and
+// validates payloads against them, for the AI service's CI. It reads no data, so it needs
+// no authentication.
+type ContractsHandler struct{}
+
+// NewContractsHandler creates a new contracts handler
+func NewContractsHandler() *ContractsHandler {
+	return &ContractsHandler{}
+}
+
+// ContractValidation is the response of the validation endpoint
+type ContractValidation struct {
+	Kind  contracts.Kind `json:"kind"`
+	Valid bool           `json:"valid"`
+	Error string         `json:"error,omitempty"`
+}
+
+// Kinds handles GET /contracts, listing the message kinds
+func (h *ContractsHandler) Kinds(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"kinds": contracts.Kinds})
+}
+
+// Schema handles GET /contracts/{kind}, the JSON Schema of a message kind
+func (h *ContractsHandler) Schema(w http.ResponseWriter, r *http.Request) {
+	schema, err := contracts.Schema(contracts.Kind(mux.Vars(r)["kind"]))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(schema)
+}
+
+// Validate handles POST /contracts/{kind}/validate with a payload as the body. It answers
+// 200 for a valid payload and 422 with the first problem found for an invalid one.
+func (h *ContractsHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	kind := contracts.Kind(mux.Vars(r)["kind"])
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxContractPayload))
+	if err != nil {
+		http.Error(w, "Payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	result := ContractValidation{Kind: kind, Valid: true}
+	if err := contracts.Validate(kind, payload); errors.Is(err, contracts.ErrUnknownKind) {
+		w.WriteHeader(http.StatusNotFound)
+		result.Valid, result.Error = false, err.Error()
+	} else if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		result.Valid, result.Error = false, err.Error()
+	}
+	json.NewEncoder(w).Encode(result)
+}
@@ -3,24 +3,44 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"net/http"
+	"sort"
 	"time"
 
-	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/analytics"
 	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
 	"github.com/google/uuid"
 )
 
 // DemoHandler handles demo data generation
 type DemoHandler struct {
-	db *database.DB
+	users           repository.UserRepository
+	events          repository.EventRepository
+	jobs            repository.JobRepository
+	recommendations repository.RecommendationRepository
+	// analytics records demo_generated events (TrackAnalyticsWith); nil records none
+	analytics AnalyticsTracker
+}
+
+// AnalyticsTracker records anonymized product analytics events; implemented by
+// analytics.Tracker
+type AnalyticsTracker interface {
+	Track(ctx context.Context, userID, event string, properties map[string]interface{})
 }
 
 // NewDemoHandler creates a new demo handler
-func NewDemoHandler(db *database.DB) *DemoHandler {
-	return &DemoHandler{db: db}
+func NewDemoHandler(users repository.UserRepository, events repository.EventRepository, jobs repository.JobRepository, recommendations repository.RecommendationRepository) *DemoHandler {
+	return &DemoHandler{users: users, events: events, jobs: jobs, recommendations: recommendations}
+}
+
+// TrackAnalyticsWith records a demo_generated event with tracker for each generated demo
+func (h *DemoHandler) TrackAnalyticsWith(tracker AnalyticsTracker) {
+	h.analytics = tracker
 }
 
 // DemoResponse represents the demo generation response
@@ -37,16 +57,28 @@ type DemoGenerationResult struct {
 	Events                  []*models.CalendarEvent  `json:"events"`
 	UserID                  string                   `json:"userId"`
 	DateRange               string                   `json:"dateRange"`
+	// Seed reproduces this data set when sent back in the request
+	Seed int64     `json:"seed"`
+	Days []DemoDay `json:"days"`
+	// Job is the seeded COMPLETED demo job with its recommendations, when requested
+	Job *models.Job `json:"job,omitempty"`
+}
+
+// DemoDay describes the scenario generated for one day
+type DemoDay struct {
+	Date     string `json:"date"`
+	Scenario string `json:"scenario"`
+	Events   int    `json:"events"`
 }
 
 // Meeting templates for realistic scenarios
 type MeetingTemplate struct {
-	Summary          string  `json:"summary"`
-	MeetingType      string  `json:"meetingType"`
-	AttendanceMode   string  `json:"attendanceMode"`
-	DurationHours    float64 `json:"durationHours"`
-	Attendees        int     `json:"attendees"`
-	Description      string  `json:"description"`
+	Summary        string                `json:"summary"`
+	MeetingType    models.MeetingType    `json:"meetingType"`
+	AttendanceMode models.AttendanceMode `json:"attendanceMode"`
+	DurationHours  float64               `json:"durationHours"`
+	Attendees      int                   `json:"attendees"`
+	Description    string                `json:"description"`
 }
 
 // Smart meeting templates with business logic
@@ -54,107 +86,233 @@ var meetingTemplates = []MeetingTemplate{
 	// Must be in-person meetings (location-specific)
 	{
 		Summary:        "Onsite Client Presentation - Acme Corp Office",
-		MeetingType:    "CLIENT_MEETING",
-		AttendanceMode: "MUST_BE_IN_PERSON", 
+		MeetingType:    models.MeetingTypeClientMeeting,
+		AttendanceMode: models.AttendanceMustBeInOffice,
 		DurationHours:  2.0,
 		Attendees:      8,
 		Description:    "In-person quarterly review at client's downtown office",
 	},
 	{
 		Summary:        "Onsite Interview - Senior Engineer",
-		MeetingType:    "INTERVIEW",
-		AttendanceMode: "MUST_BE_IN_PERSON",
+		MeetingType:    models.MeetingTypeInterview,
+		AttendanceMode: models.AttendanceMustBeInOffice,
 		DurationHours:  1.5,
 		Attendees:      4,
 		Description:    "On-site technical interview with candidate",
 	},
 	{
 		Summary:        "Hands-on Lab Session - Hardware Testing",
-		MeetingType:    "WORKSHOP",
-		AttendanceMode: "MUST_BE_IN_PERSON",
+		MeetingType:    models.MeetingTypeTeamWorkshop,
+		AttendanceMode: models.AttendanceMustBeInOffice,
 		DurationHours:  3.0,
 		Attendees:      6,
 		Description:    "Physical hardware testing requiring lab equipment",
 	},
-	// Remote meetings requiring video
+	// Remote meetings (video)
 	{
 		Summary:        "Client Presentation - Remote Demo",
-		MeetingType:    "CLIENT_MEETING",
-		AttendanceMode: "REMOTE_WITH_VIDEO",
+		MeetingType:    models.MeetingTypeClientMeeting,
+		AttendanceMode: models.AttendanceCanBeRemote,
 		DurationHours:  1.5,
 		Attendees:      6,
 		Description:    "Product demonstration via video conference",
 	},
 	{
 		Summary:        "Remote Interview - Product Manager",
-		MeetingType:    "INTERVIEW",
-		AttendanceMode: "REMOTE_WITH_VIDEO",
+		MeetingType:    models.MeetingTypeInterview,
+		AttendanceMode: models.AttendanceCanBeRemote,
 		DurationHours:  1.0,
 		Attendees:      3,
 		Description:    "Video interview for product manager role",
 	},
 	{
 		Summary:        "Team Workshop - Sprint Planning",
-		MeetingType:    "TEAM_WORKSHOP", 
-		AttendanceMode: "REMOTE_WITH_VIDEO",
+		MeetingType:    models.MeetingTypeTeamWorkshop,
+		AttendanceMode: models.AttendanceCanBeRemote,
 		DurationHours:  2.0,
 		Attendees:      8,
 		Description:    "Interactive sprint planning session",
 	},
 	{
 		Summary:        "1:1 with Manager",
-		MeetingType:    "ONE_ON_ONE",
-		AttendanceMode: "REMOTE_WITH_VIDEO",
+		MeetingType:    models.MeetingTypeOneOnOne,
+		AttendanceMode: models.AttendanceCanBeRemote,
 		DurationHours:  1.0,
 		Attendees:      2,
 		Description:    "Weekly one-on-one check-in",
 	},
 	{
 		Summary:        "Code Review Session",
-		MeetingType:    "REVIEW",
-		AttendanceMode: "REMOTE_WITH_VIDEO",
+		MeetingType:    models.MeetingTypeReview,
+		AttendanceMode: models.AttendanceCanBeRemote,
 		DurationHours:  1.5,
 		Attendees:      4,
 		Description:    "Technical code review and discussion",
 	},
 	{
 		Summary:        "Feature Brainstorming - Mobile App",
-		MeetingType:    "BRAINSTORMING",
-		AttendanceMode: "REMOTE_WITH_VIDEO",
+		MeetingType:    models.MeetingTypeBrainstorming,
+		AttendanceMode: models.AttendanceCanBeRemote,
 		DurationHours:  1.5,
 		Attendees:      5,
 		Description:    "Creative session for new mobile features",
 	},
-	// Can join while commuting (passive listening)
+	// Flexible: mostly listening, can be joined from anywhere
 	{
 		Summary:        "All-Hands Meeting - Q3 Results",
-		MeetingType:    "ALL_HANDS",
-		AttendanceMode: "CAN_JOIN_WHILE_COMMUTING",
+		MeetingType:    models.MeetingTypePresentation,
+		AttendanceMode: models.AttendanceFlexible,
 		DurationHours:  1.0,
 		Attendees:      50,
 		Description:    "Company-wide updates and announcements",
 	},
 	{
 		Summary:        "Weekly Status Update",
-		MeetingType:    "STATUS_UPDATE",
-		AttendanceMode: "CAN_JOIN_WHILE_COMMUTING",
+		MeetingType:    models.MeetingTypeStatusUpdate,
+		AttendanceMode: models.AttendanceFlexible,
 		DurationHours:  0.5,
 		Attendees:      12,
 		Description:    "Project progress review - mostly listening",
 	},
 	{
 		Summary:        "Daily Standup",
-		MeetingType:    "CHECK_IN",
-		AttendanceMode: "CAN_JOIN_WHILE_COMMUTING",
+		MeetingType:    models.MeetingTypeCheckIn,
+		AttendanceMode: models.AttendanceFlexible,
 		DurationHours:  0.25,
 		Attendees:      8,
 		Description:    "Brief team sync - can listen while commuting",
 	},
 }
 
-// DemoRequest represents the request payload for demo data generation
+// DemoRequest represents the request payload for demo data generation. Every field is
+// optional.
 type DemoRequest struct {
 	UserTimezone string `json:"userTimezone,omitempty"`
+	// Days to generate starting today; default 14, at most maxDemoDays
+	Days int `json:"days,omitempty"`
+	// Density is "light", "normal" (default) or "heavy" meeting load
+	Density string `json:"density,omitempty"`
+	// OfficeRatio is the share of working days built around in-person meetings (0-1,
+	// default 0.5)
+	OfficeRatio *float64 `json:"officeRatio,omitempty"`
+	// IncludeWeekends adds a few weekend events, which the planner should leave remote
+	IncludeWeekends bool `json:"includeWeekends,omitempty"`
+	// Seed makes generation reproducible; 0 picks one, which is returned in the response
+	Seed int64 `json:"seed,omitempty"`
+	// IncludeRecommendations also seeds a COMPLETED demo job with three recommendations
+	// for the first office day, so results can be shown without the AI service
+	IncludeRecommendations bool `json:"includeRecommendations,omitempty"`
+	// Force generates alongside real events instead of refusing; they are still kept
+	Force bool `json:"force,omitempty"`
+}
+
+const (
+	defaultDemoDays = 14
+	maxDemoDays     = 60
+)
+
+// Meeting densities: the range of meetings generated per working day
+var demoDensities = map[string][2]int{
+	"light":  {1, 3},
+	"normal": {3, 5},
+	"heavy":  {5, 8},
+}
+
+// validate fills in defaults and rejects out-of-range parameters
+func (req *DemoRequest) validate() error {
+	if req.Days == 0 {
+		req.Days = defaultDemoDays
+	}
+	if req.Days < 1 || req.Days > maxDemoDays {
+		return fmt.Errorf("days must be between 1 and %d", maxDemoDays)
+	}
+	if req.Density == "" {
+		req.Density = "normal"
+	}
+	if _, ok := demoDensities[req.Density]; !ok {
+		return fmt.Errorf("density must be light, normal or heavy")
+	}
+	if req.OfficeRatio == nil {
+		ratio := 0.5
+		req.OfficeRatio = &ratio
+	}
+	if *req.OfficeRatio < 0 || *req.OfficeRatio > 1 {
+		return fmt.Errorf("officeRatio must be between 0 and 1")
+	}
+	if req.Seed == 0 {
+		req.Seed = time.Now().UnixNano()
+	}
+	return nil
+}
+
+// demoScenario is a correlated day plan: anchor meetings placed back to back from
+// AnchorHour, topped up with fillers drawn from the template pools. Scenarios are rotated
+// so every planner branch (full office, strategic afternoon, full remote) shows up.
+type demoScenario struct {
+	Name string
+	// Office scenarios need the user in the office for their anchors
+	Office     bool
+	AnchorHour int
+	Anchors    []MeetingTemplate
+	// Fillers are the attendance modes the top-up meetings are drawn from
+	Fillers []models.AttendanceMode
+	// MaxEvents caps the day regardless of density; 0 is no cap
+	MaxEvents int
+}
+
+var demoScenarios = []demoScenario{
+	{
+		Name:       "CLIENT_DAY",
+		Office:     true,
+		AnchorHour: 10,
+		Anchors: []MeetingTemplate{
+			{Summary: "Client Kickoff - Globex On-site", MeetingType: models.MeetingTypeClientMeeting, AttendanceMode: models.AttendanceMustBeInOffice, DurationHours: 1.5, Attendees: 8, Description: "Globex leadership visiting our office for the project kickoff"},
+			{Summary: "Working Lunch with Globex Team", MeetingType: models.MeetingTypeClientMeeting, AttendanceMode: models.AttendanceMustBeInOffice, DurationHours: 1.0, Attendees: 6, Description: "Informal lunch with the client team"},
+			{Summary: "Globex Requirements Workshop", MeetingType: models.MeetingTypeTeamWorkshop, AttendanceMode: models.AttendanceMustBeInOffice, DurationHours: 2.0, Attendees: 7, Description: "Whiteboard session on requirements with the client"},
+		},
+		Fillers: []models.AttendanceMode{models.AttendanceFlexible},
+	},
+	{
+		Name:       "INTERVIEW_DAY",
+		Office:     true,
+		AnchorHour: 13,
+		Anchors: []MeetingTemplate{
+			{Summary: "Onsite Interview - Staff Engineer", MeetingType: models.MeetingTypeInterview, AttendanceMode: models.AttendanceMustBeInOffice, DurationHours: 1.0, Attendees: 3, Description: "System design round"},
+			{Summary: "Onsite Interview - Staff Engineer (Culture)", MeetingType: models.MeetingTypeInterview, AttendanceMode: models.AttendanceMustBeInOffice, DurationHours: 1.0, Attendees: 3, Description: "Values and collaboration round"},
+			{Summary: "Hiring Debrief", MeetingType: models.MeetingTypeReview, AttendanceMode: models.AttendanceMustBeInOffice, DurationHours: 1.0, Attendees: 5, Description: "Panel debrief and hire decision"},
+		},
+		Fillers: []models.AttendanceMode{models.AttendanceCanBeRemote, models.AttendanceFlexible},
+	},
+	{
+		Name:       "TEAM_DAY",
+		Office:     true,
+		AnchorHour: 9,
+		Anchors: []MeetingTemplate{
+			{Summary: "Team Offsite Planning - Q4 Roadmap", MeetingType: models.MeetingTypeTeamWorkshop, AttendanceMode: models.AttendanceMustBeInOffice, DurationHours: 3.0, Attendees: 10, Description: "Quarterly in-person planning with the whole team"},
+			{Summary: "Team Lunch", MeetingType: models.MeetingTypeCheckIn, AttendanceMode: models.AttendanceMustBeInOffice, DurationHours: 1.0, Attendees: 10, Description: "Team lunch after planning"},
+		},
+		Fillers: []models.AttendanceMode{models.AttendanceCanBeRemote},
+	},
+	{
+		Name:       "REMOTE_DAY",
+		AnchorHour: 9,
+		Fillers:    []models.AttendanceMode{models.AttendanceCanBeRemote, models.AttendanceFlexible},
+	},
+	{
+		Name:       "FOCUS_DAY",
+		AnchorHour: 9,
+		Anchors: []MeetingTemplate{
+			{Summary: "Focus Block - Deep Work", MeetingType: models.MeetingTypeUnknown, AttendanceMode: models.AttendanceCanBeRemote, DurationHours: 3.0, Attendees: 0, Description: "Protected time, no meetings"},
+		},
+		Fillers:   []models.AttendanceMode{models.AttendanceFlexible},
+		MaxEvents: 2,
+	},
+}
+
+// weekendTemplates are the occasional events of a weekend day
+var weekendTemplates = []MeetingTemplate{
+	{Summary: "On-call Handover", MeetingType: models.MeetingTypeCheckIn, AttendanceMode: models.AttendanceFlexible, DurationHours: 0.5, Attendees: 2, Description: "Weekend on-call rotation handover"},
+	{Summary: "Release Go/No-Go", MeetingType: models.MeetingTypeStatusUpdate, AttendanceMode: models.AttendanceCanBeRemote, DurationHours: 0.5, Attendees: 6, Description: "Weekend release checkpoint"},
 }
 
 // GenerateDemoData creates realistic calendar events for the authenticated user
@@ -177,21 +335,63 @@ func (h *DemoHandler) GenerateDemoData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user's preferred timezone from database first, then fall back to request
-	var userPreferredTimezone string
-	err := h.db.QueryRow("SELECT preferred_timezone FROM users WHERE id = $1", user.ID).Scan(&userPreferredTimezone)
-	if err != nil {
-		userPreferredTimezone = "UTC" // Default fallback
-	}
-	
-	// Parse request body to get browser timezone (as backup)
+	// Parse request body to get browser timezone (as backup) and generation parameters
 	var demoReq DemoRequest
 	if err := json.NewDecoder(r.Body).Decode(&demoReq); err != nil {
-		demoReq.UserTimezone = userPreferredTimezone // Use DB preferred timezone
+		demoReq = DemoRequest{}
 	}
-	
+
+	result, message, err := h.Generate(r.Context(), user.ID, demoReq)
+	if err != nil {
+		status, message := http.StatusInternalServerError, err.Error()
+		var demoErr *demoError
+		if errors.As(err, &demoErr) {
+			status, message = demoErr.status, demoErr.message
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(DemoResponse{
+			Success: false,
+			Error:   message,
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(DemoResponse{
+		Success: true,
+		Message: message,
+		Data:    result,
+	})
+}
+
+// demoError is a generation failure with the status and message it is reported with
+type demoError struct {
+	status  int
+	message string
+	cause   error
+}
+
+func (e *demoError) Error() string {
+	if e.cause != nil {
+		return e.message + ": " + e.cause.Error()
+	}
+	return e.message
+}
+
+func (e *demoError) Unwrap() error { return e.cause }
+
+// Generate replaces a user's demo data with a newly generated calendar (and, if asked, a
+// completed demo job), returning it with a summary message. Calendars holding real events
+// are refused.
+func (h *DemoHandler) Generate(ctx context.Context, userID string, demoReq DemoRequest) (*DemoGenerationResult, string, error) {
+	if err := demoReq.validate(); err != nil {
+		return nil, "", &demoError{status: http.StatusBadRequest, message: err.Error()}
+	}
+
 	// Use user's preferred timezone from DB, fallback to browser timezone, then UTC
-	timezoneToUse := userPreferredTimezone
+	timezoneToUse, err := h.users.PreferredTimezone(ctx, userID)
+	if err != nil {
+		timezoneToUse = "UTC" // Default fallback
+	}
 	if timezoneToUse == "UTC" && demoReq.UserTimezone != "" && demoReq.UserTimezone != "UTC" {
 		timezoneToUse = demoReq.UserTimezone
 	}
@@ -203,149 +403,254 @@ func (h *DemoHandler) GenerateDemoData(w http.ResponseWriter, r *http.Request) {
 		userLocation = time.UTC
 	}
 
-	// Clear existing calendar events for this user (demo data only)
-	_, err = h.db.Exec("DELETE FROM calendar_events WHERE user_id = $1", user.ID)
+	// Demo data is only generated into calendars that hold nothing else, so real synced or
+	// imported events are never mixed with generated ones unless the caller forces it. Only
+	// demo rows are cleared below, so real events are never replaced either way.
+	total, err := h.events.CountByUser(ctx, userID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(DemoResponse{
-			Success: false,
-			Error:   "Failed to clear existing events",
-		})
-		return
+		return nil, "", &demoError{status: http.StatusInternalServerError, message: "Failed to check calendar events", cause: err}
+	}
+	demoCount, err := h.events.CountDemoByUser(ctx, userID)
+	if err != nil {
+		return nil, "", &demoError{status: http.StatusInternalServerError, message: "Failed to check calendar events", cause: err}
+	}
+	if total > demoCount && !demoReq.Force {
+		return nil, "", &demoError{
+			status:  http.StatusConflict,
+			message: fmt.Sprintf("Demo data can only be generated into an empty calendar (found %d real events)", total-demoCount),
+		}
+	}
+
+	// Clear previously generated demo events and jobs for this user
+	_, err = h.events.DeleteDemoByUser(ctx, userID)
+	if err == nil {
+		_, err = h.jobs.DeleteDemoByUser(ctx, userID)
+	}
+	if err != nil {
+		return nil, "", &demoError{status: http.StatusInternalServerError, message: "Failed to clear existing events", cause: err}
 	}
 
 	// Generate smart calendar events with user's timezone
-	events, err := h.generateSmartCalendarEvents(r.Context(), user.ID, userLocation)
+	events, days, err := h.generateSmartCalendarEvents(ctx, userID, userLocation, demoReq)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(DemoResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to generate demo data: %v", err),
+		return nil, "", fmt.Errorf("Failed to generate demo data: %v", err)
+	}
+
+	var job *models.Job
+	message := fmt.Sprintf("Generated %d realistic calendar events for demo purposes", len(events))
+	if demoReq.IncludeRecommendations {
+		job, err = h.seedDemoJob(ctx, userID, events, days, userLocation)
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed to seed demo recommendations: %v", err)
+		}
+		if job != nil {
+			message += fmt.Sprintf(" and a completed demo job with %d recommendations", len(job.Recommendations))
+		}
+	}
+	if h.analytics != nil {
+		h.analytics.Track(ctx, userID, analytics.EventDemoGenerated, map[string]interface{}{
+			"days":                   demoReq.Days,
+			"density":                demoReq.Density,
+			"events":                 len(events),
+			"includeRecommendations": demoReq.IncludeRecommendations,
 		})
-		return
 	}
 
-	json.NewEncoder(w).Encode(DemoResponse{
-		Success: true,
-		Message: fmt.Sprintf("Generated %d realistic calendar events for demo purposes", len(events)),
-		Data: &DemoGenerationResult{
-			CalendarEventsGenerated: len(events),
-			Events:                  events,
-			UserID:                  user.ID,
-			DateRange:               "Next 14 days with smart business scenarios",
-		},
-	})
+	return &DemoGenerationResult{
+		CalendarEventsGenerated: len(events),
+		Events:                  events,
+		UserID:                  userID,
+		DateRange:               fmt.Sprintf("Next %d days with smart business scenarios", demoReq.Days),
+		Seed:                    demoReq.Seed,
+		Days:                    days,
+		Job:                     job,
+	}, message, nil
 }
 
-// generateSmartCalendarEvents creates intelligent, realistic calendar scenarios
-func (h *DemoHandler) generateSmartCalendarEvents(ctx context.Context, userID string, userLocation *time.Location) ([]*models.CalendarEvent, error) {
+// generateSmartCalendarEvents creates intelligent, realistic calendar scenarios. All
+// randomness comes from the request seed, so the same seed reproduces the same calendar.
+func (h *DemoHandler) generateSmartCalendarEvents(ctx context.Context, userID string, userLocation *time.Location, req DemoRequest) ([]*models.CalendarEvent, []DemoDay, error) {
+	rng := rand.New(rand.NewSource(req.Seed))
 	var events []*models.CalendarEvent
+	var days []DemoDay
 	// Use current time in user's timezone as the base for date generation
 	now := time.Now().In(userLocation)
-	
-	// Generate events for next 14 days (realistic planning window)
-	for dayOffset := 0; dayOffset < 14; dayOffset++ {
+
+	var workdays []time.Time
+	for dayOffset := 0; dayOffset < req.Days; dayOffset++ {
 		targetDate := now.AddDate(0, 0, dayOffset)
-		
-		// Skip weekends for most business events
 		if targetDate.Weekday() == time.Saturday || targetDate.Weekday() == time.Sunday {
+			if req.IncludeWeekends {
+				dayEvents := h.generateWeekendEvents(rng, userID, targetDate, userLocation)
+				events = append(events, dayEvents...)
+				days = append(days, DemoDay{Date: targetDate.Format("2006-01-02"), Scenario: "WEEKEND", Events: len(dayEvents)})
+			}
 			continue
 		}
-		
-		// Smart event density based on day of week
-		eventCount := h.getSmartEventCount(targetDate)
-		
-		dayEvents := h.generateDayEvents(ctx, userID, targetDate, eventCount, userLocation)
+		workdays = append(workdays, targetDate)
+	}
+
+	scenarios := h.planScenarios(rng, len(workdays), *req.OfficeRatio)
+	for i, targetDate := range workdays {
+		eventCount := h.getSmartEventCount(rng, targetDate, req.Density)
+		dayEvents := h.generateDayEvents(rng, userID, targetDate, scenarios[i], eventCount, userLocation)
 		events = append(events, dayEvents...)
+		days = append(days, DemoDay{Date: targetDate.Format("2006-01-02"), Scenario: scenarios[i].Name, Events: len(dayEvents)})
 	}
-	
+	sort.Slice(events, func(i, j int) bool { return events[i].StartTime.Before(events[j].StartTime) })
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+
 	// Insert all events into database
 	for _, event := range events {
-		err := h.insertCalendarEvent(ctx, event)
+		err := h.events.Create(ctx, event)
 		if err != nil {
-			return nil, fmt.Errorf("failed to insert event: %w", err)
+			return nil, nil, fmt.Errorf("failed to insert event: %w", err)
 		}
 	}
-	
-	return events, nil
+
+	return events, days, nil
+}
+
+// planScenarios picks a scenario for each working day. officeRatio of the days get an
+// office scenario; within each group the scenarios rotate so all of them appear before
+// any repeats.
+func (h *DemoHandler) planScenarios(rng *rand.Rand, workdays int, officeRatio float64) []demoScenario {
+	var office, remote []demoScenario
+	for _, scenario := range demoScenarios {
+		if scenario.Office {
+			office = append(office, scenario)
+		} else {
+			remote = append(remote, scenario)
+		}
+	}
+
+	officeDays := int(math.Round(officeRatio * float64(workdays)))
+	plan := make([]demoScenario, 0, workdays)
+	for i := 0; i < workdays; i++ {
+		if i < officeDays {
+			plan = append(plan, office[i%len(office)])
+		} else {
+			plan = append(plan, remote[(i-officeDays)%len(remote)])
+		}
+	}
+	rng.Shuffle(len(plan), func(i, j int) { plan[i], plan[j] = plan[j], plan[i] })
+	return plan
 }
 
 // getSmartEventCount returns realistic number of meetings per day
-func (h *DemoHandler) getSmartEventCount(date time.Time) int {
-	switch date.Weekday() {
-	case time.Monday, time.Tuesday: // Busy start of week
-		return rand.Intn(4) + 3 // 3-6 events
-	case time.Wednesday, time.Thursday: // Peak productivity
-		return rand.Intn(3) + 4 // 4-6 events  
-	case time.Friday: // Lighter Friday
-		return rand.Intn(3) + 2 // 2-4 events
-	default:
-		return 0 // Weekends
+func (h *DemoHandler) getSmartEventCount(rng *rand.Rand, date time.Time, density string) int {
+	bounds := demoDensities[density]
+	count := bounds[0] + rng.Intn(bounds[1]-bounds[0]+1)
+	// Lighter Friday
+	if date.Weekday() == time.Friday && count > bounds[0] {
+		count--
 	}
+	return count
 }
 
-// generateDayEvents creates events for a specific day with business logic
-func (h *DemoHandler) generateDayEvents(ctx context.Context, userID string, date time.Time, eventCount int, userLocation *time.Location) []*models.CalendarEvent {
+// generateDayEvents creates events for a specific day with business logic: the scenario's
+// anchors first, then fillers up to eventCount
+func (h *DemoHandler) generateDayEvents(rng *rand.Rand, userID string, date time.Time, scenario demoScenario, eventCount int, userLocation *time.Location) []*models.CalendarEvent {
 	var dayEvents []*models.CalendarEvent
 	usedTimes := make(map[int]bool) // Track used hour slots
-	
-	for i := 0; i < eventCount; i++ {
+	if scenario.MaxEvents > 0 && eventCount > scenario.MaxEvents {
+		eventCount = scenario.MaxEvents
+	}
+
+	hour := scenario.AnchorHour
+	for _, template := range scenario.Anchors {
+		dayEvents = append(dayEvents, h.newDemoEvent(rng, userID, date, hour, template, userLocation))
+		hours := int(math.Ceil(template.DurationHours))
+		for j := 0; j < hours; j++ {
+			usedTimes[hour+j] = true
+		}
+		hour += hours
+	}
+
+	var fillers []MeetingTemplate
+	for _, template := range meetingTemplates {
+		for _, mode := range scenario.Fillers {
+			if template.AttendanceMode == mode {
+				fillers = append(fillers, template)
+			}
+		}
+	}
+
+	for len(dayEvents) < eventCount && len(fillers) > 0 {
 		// Smart time slot selection (business hours 8 AM - 6 PM)
-		hour := h.getAvailableTimeSlot(usedTimes)
+		hour := h.getAvailableTimeSlot(rng, usedTimes)
 		if hour == -1 {
 			break // No more available slots
 		}
-		
+
 		// Select appropriate meeting template
-		template := meetingTemplates[rand.Intn(len(meetingTemplates))]
-		
-		// Create time in user's timezone first
-		localTime := time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, userLocation)
-		// Convert to UTC explicitly to work around lib/pq timezone binding bug
-		startTime := localTime.UTC()
-		endTime := startTime.Add(time.Duration(template.DurationHours * float64(time.Hour)))
-		
-		
-		// Create realistic calendar event
-		event := &models.CalendarEvent{
-			ID:             uuid.New().String(),
-			UserID:         userID,
-			Summary:        template.Summary,
-			Description:    &template.Description,
-			StartTime:      startTime,
-			EndTime:        endTime,
-			Location:       h.getSmartLocation(template.AttendanceMode),
-			Attendees:      h.getAttendeesJSON(template.Attendees),
-			MeetingType:    models.MeetingType(template.MeetingType),
-			AttendanceMode: models.AttendanceMode(template.AttendanceMode),
-			IsAllDay:       false,
-			IsRecurring:    rand.Float32() < 0.2, // 20% recurring
-			GoogleEventID:  nil, // Demo data
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
-		}
-		
-		dayEvents = append(dayEvents, event)
-		
+		template := fillers[rng.Intn(len(fillers))]
+		dayEvents = append(dayEvents, h.newDemoEvent(rng, userID, date, hour, template, userLocation))
+
 		// Mark time slots as used
 		duration := int(template.DurationHours)
 		for j := 0; j <= duration; j++ {
 			usedTimes[hour+j] = true
 		}
 	}
-	
+
 	return dayEvents
 }
 
+// generateWeekendEvents adds at most one light event to a weekend day
+func (h *DemoHandler) generateWeekendEvents(rng *rand.Rand, userID string, date time.Time, userLocation *time.Location) []*models.CalendarEvent {
+	if rng.Intn(2) == 0 {
+		return nil
+	}
+	template := weekendTemplates[rng.Intn(len(weekendTemplates))]
+	return []*models.CalendarEvent{h.newDemoEvent(rng, userID, date, 10, template, userLocation)}
+}
+
+// newDemoEvent creates realistic calendar event from a template, starting at hour in the
+// user's timezone
+func (h *DemoHandler) newDemoEvent(rng *rand.Rand, userID string, date time.Time, hour int, template MeetingTemplate, userLocation *time.Location) *models.CalendarEvent {
+	// Create time in user's timezone first
+	localTime := time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, userLocation)
+	// Convert to UTC explicitly to work around lib/pq timezone binding bug
+	startTime := localTime.UTC()
+	endTime := startTime.Add(time.Duration(template.DurationHours * float64(time.Hour)))
+
+	// IDs come from the seeded generator too, so a seed reproduces them
+	id, err := uuid.NewRandomFromReader(rng)
+	if err != nil {
+		id = uuid.New()
+	}
+	description := template.Description
+
+	return &models.CalendarEvent{
+		ID:             id.String(),
+		UserID:         userID,
+		Summary:        template.Summary,
+		Description:    &description,
+		StartTime:      startTime,
+		EndTime:        endTime,
+		Location:       h.getSmartLocation(rng, template.AttendanceMode),
+		Attendees:      h.getAttendeesJSON(template.Attendees),
+		MeetingType:    template.MeetingType,
+		AttendanceMode: template.AttendanceMode,
+		IsAllDay:       false,
+		IsRecurring:    rng.Float32() < 0.2, // 20% recurring
+		GoogleEventID:  nil,                 // Demo data
+		IsDemo:         true,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+}
+
 // getAvailableTimeSlot finds an available business hour
-func (h *DemoHandler) getAvailableTimeSlot(usedTimes map[int]bool) int {
+func (h *DemoHandler) getAvailableTimeSlot(rng *rand.Rand, usedTimes map[int]bool) int {
 	businessHours := []int{8, 9, 10, 11, 13, 14, 15, 16, 17} // Skip lunch at 12
-	
+
 	// Shuffle for randomness
-	rand.Shuffle(len(businessHours), func(i, j int) {
+	rng.Shuffle(len(businessHours), func(i, j int) {
 		businessHours[i], businessHours[j] = businessHours[j], businessHours[i]
 	})
-	
+
 	for _, hour := range businessHours {
 		if !usedTimes[hour] {
 			return hour
@@ -355,19 +660,19 @@ func (h *DemoHandler) getAvailableTimeSlot(usedTimes map[int]bool) int {
 }
 
 // getSmartLocation returns appropriate location based on attendance mode
-func (h *DemoHandler) getSmartLocation(attendanceMode string) *string {
-	locations := map[string][]string{
-		"MUST_BE_IN_PERSON":         {"Conference Room A", "Boardroom", "Training Room", "Client Meeting Room"},
-		"REMOTE_WITH_VIDEO":         {"Zoom", "Google Meet", "Teams", "Conference Room B (optional)"},
-		"CAN_JOIN_WHILE_COMMUTING": {"Zoom (audio only)", "Google Meet (audio)", "Teams (audio)", "Conference call"},
+func (h *DemoHandler) getSmartLocation(rng *rand.Rand, attendanceMode models.AttendanceMode) *string {
+	locations := map[models.AttendanceMode][]string{
+		models.AttendanceMustBeInOffice: {"Conference Room A", "Boardroom", "Training Room", "Client Meeting Room"},
+		models.AttendanceCanBeRemote:    {"Zoom", "Google Meet", "Teams", "Conference Room B (optional)"},
+		models.AttendanceFlexible:       {"Zoom (audio only)", "Google Meet (audio)", "Teams (audio)", "Conference call"},
 	}
-	
+
 	options := locations[attendanceMode]
 	if len(options) == 0 {
 		return nil
 	}
-	
-	location := options[rand.Intn(len(options))]
+
+	location := options[rng.Intn(len(options))]
 	return &location
 }
 
@@ -385,32 +690,6 @@ func (h *DemoHandler) getAttendeesJSON(count int) *string {
 	return &result
 }
 
-// insertCalendarEvent saves event to database
-func (h *DemoHandler) insertCalendarEvent(ctx context.Context, event *models.CalendarEvent) error {
-	query := `INSERT INTO calendar_events (id, user_id, summary, description, start_time, end_time, location, attendees, meeting_type, attendance_mode, is_all_day, is_recurring, google_event_id, created_at, updated_at)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
-	
-	_, err := h.db.Exec(query,
-		event.ID,
-		event.UserID,
-		event.Summary,
-		event.Description,
-		event.StartTime,
-		event.EndTime,
-		event.Location,
-		event.Attendees,
-		event.MeetingType,
-		event.AttendanceMode,
-		event.IsAllDay,
-		event.IsRecurring,
-		event.GoogleEventID,
-		event.CreatedAt,
-		event.UpdatedAt,
-	)
-	
-	return err
-}
-
 // CheckDemoData returns whether user has existing calendar events
 func (h *DemoHandler) CheckDemoData(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -432,8 +711,17 @@ func (h *DemoHandler) CheckDemoData(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Count calendar events for this user
-	var count int
-	err := h.db.QueryRow("SELECT COUNT(*) FROM calendar_events WHERE user_id = $1", user.ID).Scan(&count)
+	count, err := h.events.CountByUser(r.Context(), user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(DemoResponse{
+			Success: false,
+			Error:   "Failed to check calendar events",
+		})
+		return
+	}
+
+	demoCount, err := h.events.CountDemoByUser(r.Context(), user.ID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(DemoResponse{
@@ -447,7 +735,48 @@ func (h *DemoHandler) CheckDemoData(w http.ResponseWriter, r *http.Request) {
 		"success": true,
 		"hasData": count > 0,
 		"eventCount": count,
+		"demoEventCount": demoCount,
 	}
 
 	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+}
+
+// ClearDemoData removes the authenticated user's generated demo events and demo jobs; real
+// events and jobs are kept
+func (h *DemoHandler) ClearDemoData(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(DemoResponse{
+			Success: false,
+			Error:   "Authentication required",
+		})
+		return
+	}
+
+	deleted, err := h.events.DeleteDemoByUser(r.Context(), user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(DemoResponse{
+			Success: false,
+			Error:   "Failed to clear demo events",
+		})
+		return
+	}
+	deletedJobs, err := h.jobs.DeleteDemoByUser(r.Context(), user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(DemoResponse{
+			Success: false,
+			Error:   "Failed to clear demo jobs",
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(DemoResponse{
+		Success: true,
+		Message: fmt.Sprintf("Removed %d demo calendar events and %d demo jobs", deleted, deletedJobs),
+	})
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/ai"
+	"github.com/commute-planner/backend/pkg/carbon"
+	"github.com/commute-planner/backend/pkg/contracts"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Demo commutes are a fixed 45-minute, 18 km drive each way, starting from a 9:00-17:30
+// office day
+const (
+	demoCommute        = 45 * time.Minute
+	demoCommuteKm      = 18.0
+	demoOfficeStart    = 9 * time.Hour
+	demoOfficeEnd      = 17*time.Hour + 30*time.Minute
+	demoAfternoonStart = 12*time.Hour + 30*time.Minute
+	// demoMeetingBuffer is kept between arriving and the first meeting, and between the
+	// last meeting and leaving
+	demoMeetingBuffer = 15 * time.Minute
+)
+
+// demoOption is one commute option being built for the demo job
+type demoOption struct {
+	optionType models.CommuteOptionType
+	arrival    *time.Time
+	departure  *time.Time
+	office     []string
+	remote     []string
+	// required counts the day's in-person meetings; missed those outside the office window
+	required int
+	missed   int
+}
+
+// seedDemoJob creates a COMPLETED demo job for the first office day in days (the first
+// working day if there is none) with the three commute options the AI service would
+// produce. It returns nil when the range holds no working day.
+func (h *DemoHandler) seedDemoJob(ctx context.Context, userID string, events []*models.CalendarEvent, days []DemoDay, userLocation *time.Location) (*models.Job, error) {
+	day, ok := pickDemoJobDay(days)
+	if !ok {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", day.Date, userLocation)
+	if err != nil {
+		return nil, err
+	}
+	var dayEvents []*models.CalendarEvent
+	for _, event := range events {
+		if event.StartTime.In(userLocation).Format("2006-01-02") == day.Date {
+			dayEvents = append(dayEvents, event)
+		}
+	}
+
+	inputData := `{"source":"demo"}`
+	job, err := h.jobs.Create(ctx, repository.NewJob{
+		UserID:     userID,
+		TargetDate: day.Date,
+		InputData:  &inputData,
+		IsDemo:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create demo job: %w", err)
+	}
+	inProgress := string(models.JobStatusInProgress)
+	step := "Generating demo recommendations"
+	if _, err := h.jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &inProgress, CurrentStep: &step}); err != nil {
+		return nil, fmt.Errorf("failed to start demo job: %w", err)
+	}
+
+	recommendations := DemoRecommendations(job.ID, date, dayEvents)
+	for _, rec := range recommendations {
+		if err := h.recommendations.Create(ctx, rec); err != nil {
+			return nil, fmt.Errorf("failed to create demo recommendation: %w", err)
+		}
+	}
+
+	completed := string(models.JobStatusCompleted)
+	progress := 1.0
+	step = "Recommendations complete"
+	result, err := contracts.JobResult{TotalOptions: len(recommendations), AnalysisComplete: true, Demo: true}.Encode()
+	if err != nil {
+		return nil, err
+	}
+	job, err = h.jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &completed, Progress: &progress, CurrentStep: &step, Result: &result})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete demo job: %w", err)
+	}
+	job.Recommendations = recommendations
+	return job, nil
+}
+
+// DemoRecommendations builds the three commute options the AI service would produce for
+// date (midnight in the user's time zone) and the day's events, ranked like the planner
+// ranks them: every in-person meeting covered with the least time in the office first
+func DemoRecommendations(jobID string, date time.Time, events []*models.CalendarEvent) []*models.CommuteRecommendation {
+	options := []*demoOption{
+		buildDemoOption(models.CommuteOptionFullDayOffice, date, demoOfficeStart, events),
+		buildDemoOption(models.CommuteOptionStrategicAfternoon, date, demoAfternoonStart, events),
+		buildDemoOption(models.CommuteOptionFullRemoteRecommended, date, 0, events),
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		if options[i].missed != options[j].missed {
+			return options[i].missed < options[j].missed
+		}
+		return options[i].officeTime() < options[j].officeTime()
+	})
+
+	recommendations := make([]*models.CommuteRecommendation, 0, len(options))
+	for i, option := range options {
+		recommendations = append(recommendations, option.recommendation(jobID, i+1))
+	}
+	return recommendations
+}
+
+// pickDemoJobDay returns the first office day, or failing that the first working day
+func pickDemoJobDay(days []DemoDay) (DemoDay, bool) {
+	office := map[string]bool{}
+	for _, scenario := range demoScenarios {
+		office[scenario.Name] = scenario.Office
+	}
+	for _, day := range days {
+		if office[day.Scenario] {
+			return day, true
+		}
+	}
+	for _, day := range days {
+		if day.Scenario != "WEEKEND" {
+			return day, true
+		}
+	}
+	return DemoDay{}, false
+}
+
+// buildDemoOption places an office window from arriveBy (stretched to cover in-person
+// meetings after it) and splits the day's meetings into office and remote ones. The
+// remote option has no window.
+func buildDemoOption(optionType models.CommuteOptionType, date time.Time, arriveBy time.Duration, events []*models.CalendarEvent) *demoOption {
+	option := &demoOption{optionType: optionType}
+	if optionType != models.CommuteOptionFullRemoteRecommended {
+		arrival := date.Add(arriveBy)
+		departure := date.Add(demoOfficeEnd)
+		for _, event := range events {
+			if isDemoInPerson(event) && !event.StartTime.Before(arrival.Add(demoMeetingBuffer)) && event.EndTime.Add(demoMeetingBuffer).After(departure) {
+				departure = event.EndTime.Add(demoMeetingBuffer)
+			}
+		}
+		option.arrival = &arrival
+		option.departure = &departure
+	}
+
+	for _, event := range events {
+		if isDemoInPerson(event) {
+			option.required++
+		}
+		inOffice := option.arrival != nil && !event.StartTime.Before(*option.arrival) && !event.EndTime.After(*option.departure)
+		switch {
+		case inOffice:
+			option.office = append(option.office, event.Summary)
+		case isDemoInPerson(event):
+			option.remote = append(option.remote, event.Summary)
+			option.missed++
+		default:
+			option.remote = append(option.remote, event.Summary)
+		}
+	}
+	return option
+}
+
+// isDemoInPerson reports whether a generated event needs the user in the office
+func isDemoInPerson(event *models.CalendarEvent) bool {
+	return event.AttendanceMode == models.AttendanceMustBeInOffice
+}
+
+func (o *demoOption) officeTime() time.Duration {
+	if o.arrival == nil {
+		return 0
+	}
+	return o.departure.Sub(*o.arrival)
+}
+
+// recommendation renders the option as the row the AI service would write
+func (o *demoOption) recommendation(jobID string, rank int) *models.CommuteRecommendation {
+	rec := &models.CommuteRecommendation{
+		JobID:      jobID,
+		OptionRank: rank,
+		OptionType: o.optionType,
+	}
+	legs := []map[string]interface{}{}
+	if o.arrival != nil {
+		start := o.arrival.Add(-demoCommute)
+		end := o.departure.Add(demoCommute)
+		rec.CommuteStart = &start
+		rec.OfficeArrival = o.arrival
+		rec.OfficeDeparture = o.departure
+		rec.CommuteEnd = &end
+		legs = append(legs,
+			demoLeg("home", "office", start, *o.arrival),
+			demoLeg("office", "home", *o.departure, end),
+		)
+	}
+	duration := formatDemoDuration(o.officeTime())
+	rec.OfficeDuration = &duration
+	rec.OfficeMeetings = demoJSON(nonNil(o.office))
+	rec.RemoteMeetings = demoJSON(nonNil(o.remote))
+	rec.OffsiteMeetings = demoJSON([]string{})
+	rec.TravelLegs = demoJSON(legs)
+	if o.arrival != nil {
+		mode := models.TravelModeDrive
+		rec.TravelMode = &mode
+		rec.ModeOptions = demoJSON(demoModeOptions)
+	} else {
+		rec.ModeOptions = demoJSON([]string{})
+	}
+	rec.BusinessRuleCompliance = demoJSON(map[string]interface{}{
+		"compliant":          o.missed == 0,
+		"in_person_covered":  o.required - o.missed,
+		"in_person_required": o.required,
+		"missed_in_person":   o.missed,
+	})
+
+	var reasoning string
+	var perception ai.Perception
+	var pros, cons []string
+	switch o.optionType {
+	case models.CommuteOptionFullDayOffice:
+		reasoning = fmt.Sprintf("A full day in the office (%s) puts %d meetings in person, including all %d that need you there, and leaves time for hallway conversations around them.", duration, len(o.office), o.required-o.missed)
+		perception = ai.Perception{
+			ProfessionalImpact: "Strong: present for the whole day, including informal time with colleagues",
+			Reasoning:          "Being in the office all day signals availability and commitment",
+			TeamVisibility:     "High",
+		}
+		pros = []string{"Every meeting attended in person", "Most face time with the team"}
+		cons = []string{"Longest office day", "Commutes in both rush hours", fmt.Sprintf("Commute costs %.2f in fuel", demoDriveCost)}
+	case models.CommuteOptionStrategicAfternoon:
+		reasoning = fmt.Sprintf("Working from home in the morning and arriving at %s keeps %d meetings in person while avoiding the morning rush.", o.arrival.Format("15:04"), len(o.office))
+		if o.missed > 0 {
+			reasoning += fmt.Sprintf(" %d in-person meeting(s) in the morning would be joined remotely.", o.missed)
+		}
+		perception = ai.Perception{
+			ProfessionalImpact: "Good: present for the afternoon's collaborative meetings",
+			Reasoning:          "Afternoon presence covers most of the day's team interaction",
+			TeamVisibility:     "Medium",
+		}
+		pros = []string{"Focused morning at home", "Skips the morning rush hour"}
+		cons = []string{"Morning meetings joined remotely", fmt.Sprintf("Commute costs %.2f in fuel", demoDriveCost)}
+	default:
+		reasoning = fmt.Sprintf("Staying remote saves %d minutes of commuting; all %d meetings are joined by video.", int(2*demoCommute/time.Minute), len(o.remote))
+		if o.missed > 0 {
+			reasoning += fmt.Sprintf(" %d meeting(s) expect you in person.", o.missed)
+		}
+		perception = ai.Perception{
+			ProfessionalImpact: "Neutral on a light day; noticeable when in-person meetings are missed",
+			Reasoning:          "Keep cameras on so remote attendance stays visible",
+			TeamVisibility:     "Low",
+		}
+		pros = []string{"No commute", "Most time for focused work", fmt.Sprintf("Saves %.2f in commuting costs", demoDriveCost)}
+		cons = []string{"Least face time with the team"}
+	}
+	rec.Reasoning = &reasoning
+	rec.PerceptionAnalysis = demoJSON(perception)
+	rec.TradeOffs = demoJSON(map[string][]string{"pros": pros, "cons": cons})
+	return rec
+}
+
+// formatDemoDuration renders a duration as an interval Postgres accepts
+func formatDemoDuration(d time.Duration) string {
+	hours := int(d / time.Hour)
+	minutes := int((d % time.Hour) / time.Minute)
+	if minutes == 0 {
+		return fmt.Sprintf("%d hours", hours)
+	}
+	return fmt.Sprintf("%d hours %d minutes", hours, minutes)
+}
+
+// demoLeg is one travel leg in the shape the AI service writes
+func demoLeg(from, to string, depart, arrive time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"from":        from,
+		"to":          to,
+		"depart":      depart.UTC().Format(time.RFC3339),
+		"arrive":      arrive.UTC().Format(time.RFC3339),
+		"minutes":     int(arrive.Sub(depart).Minutes()),
+		"distance_km": demoCommuteKm,
+		"co2_kg":      carbon.Round(carbon.TripKg(models.TravelModeDrive, demoCommuteKm)),
+		"mode":        models.TravelModeDrive,
+	}
+}
+
+// demoModeOptions compares the travel modes for the demo's two 45-minute drives of 18 km,
+// priced and rated the way the AI service does by default
+var demoModeOptions = []map[string]interface{}{
+	{"mode": models.TravelModeDrive, "minutes": 90, "distance_km": 36.0, "cost": demoDriveCost, "cost_breakdown": demoCostBreakdown(demoDriveCost, 0), "co2_kg": 6.16, "feasible": true, "chosen": true},
+	{"mode": models.TravelModeTransit, "minutes": 110, "distance_km": 36.0, "cost": 5.8, "cost_breakdown": demoCostBreakdown(0, 5.8), "co2_kg": 1.48, "feasible": true, "chosen": false},
+	{"mode": models.TravelModeBike, "minutes": 144, "distance_km": 36.0, "cost": 0.0, "cost_breakdown": demoCostBreakdown(0, 0), "co2_kg": 0.0, "feasible": false, "chosen": false},
+	{"mode": models.TravelModeWalk, "minutes": 432, "distance_km": 36.0, "cost": 0.0, "cost_breakdown": demoCostBreakdown(0, 0), "co2_kg": 0.0, "feasible": false, "chosen": false},
+}
+
+// demoDriveCost is the fuel for the demo's drives; demo offices charge nothing for parking
+const demoDriveCost = 14.4
+
+func demoCostBreakdown(fuel, fares float64) map[string]float64 {
+	return map[string]float64{"fuel": fuel, "parking": 0, "congestion": 0, "fares": fares}
+}
+
+func demoJSON(v interface{}) *string {
+	data, _ := json.Marshal(v)
+	s := string(data)
+	return &s
+}
+
+// nonNil keeps empty meeting lists encoded as [] rather than null
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// generateDemo runs the generator against fresh in-memory repositories
+func generateDemo(t *testing.T, req DemoRequest) ([]*models.CalendarEvent, []DemoDay) {
+	t.Helper()
+	if err := req.validate(); err != nil {
+		t.Fatal(err)
+	}
+	repos := repository.NewMemoryRepositories()
+	h := NewDemoHandler(repos.Users, repos.Events, repos.Jobs, repos.Recommendations)
+	location, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, days, err := h.generateSmartCalendarEvents(context.Background(), "user-1", location, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events, days
+}
+
+// demoEventKey is what a seed must reproduce; timestamps of creation aren't
+type demoEventKey struct {
+	ID, Summary    string
+	Start, End     time.Time
+	MeetingType    models.MeetingType
+	AttendanceMode models.AttendanceMode
+	Location       string
+	IsRecurring    bool
+}
+
+func demoEventKeys(events []*models.CalendarEvent) []demoEventKey {
+	keys := make([]demoEventKey, len(events))
+	for i, event := range events {
+		keys[i] = demoEventKey{
+			ID: event.ID, Summary: event.Summary, Start: event.StartTime, End: event.EndTime,
+			MeetingType: event.MeetingType, AttendanceMode: event.AttendanceMode, IsRecurring: event.IsRecurring,
+		}
+		if event.Location != nil {
+			keys[i].Location = *event.Location
+		}
+	}
+	return keys
+}
+
+func TestGenerateDemoDataSeeded(t *testing.T) {
+	req := DemoRequest{Days: 21, Density: "heavy", IncludeWeekends: true, Seed: 42}
+	events, days := generateDemo(t, req)
+	again, againDays := generateDemo(t, req)
+
+	if len(events) == 0 {
+		t.Fatal("no events generated")
+	}
+	if !reflect.DeepEqual(demoEventKeys(events), demoEventKeys(again)) || !reflect.DeepEqual(days, againDays) {
+		t.Fatal("the same seed generated different calendars")
+	}
+	other, _ := generateDemo(t, DemoRequest{Days: 21, Density: "heavy", IncludeWeekends: true, Seed: 43})
+	if reflect.DeepEqual(demoEventKeys(events), demoEventKeys(other)) {
+		t.Fatal("different seeds generated the same calendar")
+	}
+
+	// Generated events only use meeting types and attendance modes the planner knows
+	modes := map[models.AttendanceMode]int{}
+	for _, event := range events {
+		if !containsMeetingType(models.MeetingTypes, event.MeetingType) {
+			t.Errorf("%s: unknown meeting type %q", event.Summary, event.MeetingType)
+		}
+		if !containsAttendanceMode(models.AttendanceModes, event.AttendanceMode) {
+			t.Errorf("%s: unknown attendance mode %q", event.Summary, event.AttendanceMode)
+		}
+		modes[event.AttendanceMode]++
+	}
+	for _, mode := range models.AttendanceModes {
+		if modes[mode] == 0 {
+			t.Errorf("no %s events in three weeks of heavy demo data", mode)
+		}
+	}
+
+	// Every scenario shows up, so each planner branch has a day to plan
+	scenarios := map[string]bool{}
+	for _, day := range days {
+		scenarios[day.Scenario] = true
+	}
+	for _, scenario := range demoScenarios {
+		if !scenarios[scenario.Name] {
+			t.Errorf("scenario %s not generated", scenario.Name)
+		}
+	}
+}
+
+func TestDemoTemplatesUseKnownValues(t *testing.T) {
+	templates := append([]MeetingTemplate{}, meetingTemplates...)
+	templates = append(templates, weekendTemplates...)
+	for _, scenario := range demoScenarios {
+		templates = append(templates, scenario.Anchors...)
+		for _, mode := range scenario.Fillers {
+			if !containsAttendanceMode(models.AttendanceModes, mode) {
+				t.Errorf("scenario %s: unknown filler mode %q", scenario.Name, mode)
+			}
+		}
+	}
+	for _, template := range templates {
+		if !containsMeetingType(models.MeetingTypes, template.MeetingType) {
+			t.Errorf("%s: unknown meeting type %q", template.Summary, template.MeetingType)
+		}
+		if !containsAttendanceMode(models.AttendanceModes, template.AttendanceMode) {
+			t.Errorf("%s: unknown attendance mode %q", template.Summary, template.AttendanceMode)
+		}
+	}
+}
+
+func containsMeetingType(types []models.MeetingType, meetingType models.MeetingType) bool {
+	for _, known := range types {
+		if known == meetingType {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAttendanceMode(modes []models.AttendanceMode, mode models.AttendanceMode) bool {
+	for _, known := range modes {
+		if known == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	h := NewDemoHandler(repos.Users, repos.Events, repos.Jobs, repos.Recommendations)
+	user, err := repos.Users.Create(ctx, repository.NewUser{Email: "ada@example.com", Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := h.Generate(ctx, user.ID, DemoRequest{Days: maxDemoDays + 1}); err == nil {
+		t.Fatal("Generate() accepted too many days")
+	}
+
+	req := DemoRequest{Days: 7, Seed: 7, IncludeRecommendations: true}
+	first, _, err := h.Generate(ctx, user.ID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Generating again replaces the demo data
+	second, message, err := h.Generate(ctx, user.ID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := repos.Events.CountByUser(ctx, user.ID); count != second.CalendarEventsGenerated || count != first.CalendarEventsGenerated {
+		t.Errorf("%d events stored after generating twice, want %d", count, second.CalendarEventsGenerated)
+	}
+	if second.Job == nil || message == "" {
+		t.Errorf("Generate() = job %v, message %q; want a demo job and a summary", second.Job, message)
+	}
+
+	// Real events make the calendar off limits
+	now := time.Now()
+	event := &models.CalendarEvent{ID: "real", UserID: user.ID, Summary: "Standup", StartTime: now, EndTime: now.Add(time.Hour)}
+	if err := repos.Events.Create(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = h.Generate(ctx, user.ID, req)
+	var demoErr *demoError
+	if !errors.As(err, &demoErr) || demoErr.status != http.StatusConflict {
+		t.Fatalf("Generate() with real events = %v, want a conflict", err)
+	}
+
+	// Forcing generates anyway and keeps the real event
+	req.Force = true
+	forced, _, err := h.Generate(ctx, user.ID, req)
+	if err != nil {
+		t.Fatalf("Generate() with force: %v", err)
+	}
+	if _, err := repos.Events.Get(ctx, user.ID, "real"); err != nil {
+		t.Errorf("real event gone after a forced generate: %v", err)
+	}
+	if count, _ := repos.Events.CountByUser(ctx, user.ID); count != forced.CalendarEventsGenerated+1 {
+		t.Errorf("%d events stored after a forced generate, want %d", count, forced.CalendarEventsGenerated+1)
+	}
+}
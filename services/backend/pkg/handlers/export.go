@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// exportFlushEvery is how many CSV rows are buffered before flushing a chunk to the client
+const exportFlushEvery = 100
+
+// ExportHandler streams a user's data as CSV for spreadsheet analysis
+type ExportHandler struct {
+	events          repository.EventRepository
+	recommendations repository.RecommendationRepository
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(events repository.EventRepository, recommendations repository.RecommendationRepository) *ExportHandler {
+	return &ExportHandler{events: events, recommendations: recommendations}
+}
+
+// ExportCalendarEvents streams the authenticated user's calendar events.
+// Optional ?from=YYYY-MM-DD&to=YYYY-MM-DD (inclusive) filter on start time (UTC).
+func (h *ExportHandler) ExportCalendarEvents(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeExportError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	dates, err := parseExportRange(r)
+	if err != nil {
+		writeExportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	out := newCSVStream(w, "calendar-events.csv")
+	out.write([]string{"id", "summary", "description", "start_time", "end_time", "location", "attendees", "meeting_type", "attendance_mode", "is_all_day", "is_recurring", "google_event_id", "created_at", "updated_at"})
+
+	err = h.events.Stream(r.Context(), user.ID, dates, func(event *models.CalendarEvent) error {
+		return out.write([]string{
+			event.ID,
+			event.Summary,
+			stringValue(event.Description),
+			event.StartTime.UTC().Format(time.RFC3339),
+			event.EndTime.UTC().Format(time.RFC3339),
+			stringValue(event.Location),
+			stringValue(event.Attendees),
+			string(event.MeetingType),
+			string(event.AttendanceMode),
+			strconv.FormatBool(event.IsAllDay),
+			strconv.FormatBool(event.IsRecurring),
+			stringValue(event.GoogleEventID),
+			event.CreatedAt.UTC().Format(time.RFC3339),
+			event.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	})
+	out.finish(err)
+}
+
+// ExportRecommendations streams the recommendations of the authenticated user's jobs.
+// Optional ?from=YYYY-MM-DD&to=YYYY-MM-DD (inclusive) filter on the job's target date.
+func (h *ExportHandler) ExportRecommendations(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		writeExportError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	dates, err := parseExportRange(r)
+	if err != nil {
+		writeExportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	out := newCSVStream(w, "recommendations.csv")
+	out.write([]string{"job_id", "target_date", "option_rank", "option_type", "commute_start", "office_arrival", "office_departure", "commute_end", "office_duration", "reasoning", "created_at"})
+
+	err = h.recommendations.StreamByUser(r.Context(), user.ID, dates, func(rec *models.CommuteRecommendation) error {
+		return out.write([]string{
+			rec.JobID,
+			rec.Job.TargetDate,
+			strconv.Itoa(rec.OptionRank),
+			string(rec.OptionType),
+			timeValue(rec.CommuteStart),
+			timeValue(rec.OfficeArrival),
+			timeValue(rec.OfficeDeparture),
+			timeValue(rec.CommuteEnd),
+			stringValue(rec.OfficeDuration),
+			stringValue(rec.Reasoning),
+			rec.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	})
+	out.finish(err)
+}
+
+// csvStream writes CSV rows and flushes them to the client in chunks. Without a
+// Content-Length the response goes out with chunked transfer encoding.
+type csvStream struct {
+	w       *csv.Writer
+	flusher http.Flusher
+	pending int
+}
+
+func newCSVStream(w http.ResponseWriter, filename string) *csvStream {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	return &csvStream{w: csv.NewWriter(w), flusher: flusher}
+}
+
+func (s *csvStream) write(record []string) error {
+	for i, cell := range record {
+		record[i] = escapeFormula(cell)
+	}
+	if err := s.w.Write(record); err != nil {
+		return err
+	}
+	s.pending++
+	if s.pending >= exportFlushEvery {
+		s.flush()
+	}
+	return s.w.Error()
+}
+
+func (s *csvStream) flush() {
+	s.w.Flush()
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	s.pending = 0
+}
+
+// finish flushes the remaining rows. The status line has already been sent, so a failure
+// part-way through can only be logged; the client sees a truncated file.
+func (s *csvStream) finish(err error) {
+	s.flush()
+	if err != nil {
+		log.Printf("CSV export aborted: %v", err)
+	}
+}
+
+// escapeFormula stops spreadsheet apps from evaluating user-controlled text (e.g. an event
+// summary of "=HYPERLINK(...)") as a formula
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// parseExportRange reads the inclusive from/to query parameters as UTC days
+func parseExportRange(r *http.Request) (repository.DateRange, error) {
+	var dates repository.DateRange
+	if from := r.URL.Query().Get("from"); from != "" {
+		day, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return dates, fmt.Errorf("invalid from date %q (expected YYYY-MM-DD)", from)
+		}
+		dates.From = &day
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		day, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return dates, fmt.Errorf("invalid to date %q (expected YYYY-MM-DD)", to)
+		}
+		end := day.AddDate(0, 0, 1)
+		dates.To = &end
+	}
+	if dates.From != nil && dates.To != nil && !dates.From.Before(*dates.To) {
+		return dates, fmt.Errorf("from must not be after to")
+	}
+	return dates, nil
+}
+
+func writeExportError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": message})
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func timeValue(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/commute-planner/backend/pkg/googlecalendar"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// GoogleCalendarHandler receives Google Calendar push notifications and manages watches
+type GoogleCalendarHandler struct {
+	syncer *googlecalendar.Syncer
+}
+
+// NewGoogleCalendarHandler creates a new Google Calendar handler
+func NewGoogleCalendarHandler(syncer *googlecalendar.Syncer) *GoogleCalendarHandler {
+	return &GoogleCalendarHandler{syncer: syncer}
+}
+
+// GoogleCalendarWatchResponse is the response of the watch endpoints
+type GoogleCalendarWatchResponse struct {
+	Success bool                            `json:"success"`
+	Data    []*models.GoogleCalendarChannel `json:"data,omitempty"`
+	Error   string                          `json:"error,omitempty"`
+}
+
+// Notify handles POST /webhooks/google-calendar. Google only sends headers; a change
+// notification triggers an incremental sync for the channel's user and is acknowledged
+// immediately, since Google retries slow or failed notifications.
+func (h *GoogleCalendarHandler) Notify(w http.ResponseWriter, r *http.Request) {
+	channelID := r.Header.Get("X-Goog-Channel-ID")
+	resourceID := r.Header.Get("X-Goog-Resource-ID")
+	if channelID == "" || resourceID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	channel, err := h.syncer.Authenticate(r.Context(), channelID, r.Header.Get("X-Goog-Channel-Token"), resourceID)
+	if errors.Is(err, googlecalendar.ErrUnknownChannel) {
+		// 404 tells Google nothing about which channels exist; it stops retrying either way
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to look up Google Calendar channel %s: %v", channelID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// "sync" is the handshake sent when a channel is created; Watch already handles the
+	// initial sync
+	if r.Header.Get("X-Goog-Resource-State") != "sync" {
+		h.syncer.Trigger(channel.UserID, channel.CalendarID)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Watch handles POST /google-calendar/watch, subscribing the user's primary calendar
+func (h *GoogleCalendarHandler) Watch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(GoogleCalendarWatchResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+
+	channel, err := h.syncer.Watch(r.Context(), user.ID, "primary")
+	if errors.Is(err, googlecalendar.ErrNotConnected) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(GoogleCalendarWatchResponse{Success: false, Error: err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to watch Google Calendar for user %s: %v", user.ID, err)
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(GoogleCalendarWatchResponse{Success: false, Error: "Failed to watch Google Calendar"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(GoogleCalendarWatchResponse{Success: true, Data: []*models.GoogleCalendarChannel{channel}})
+}
+
+// Channels handles GET /google-calendar/watch
+func (h *GoogleCalendarHandler) Channels(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(GoogleCalendarWatchResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+
+	channels, err := h.syncer.Channels(r.Context(), user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(GoogleCalendarWatchResponse{Success: false, Error: "Failed to list channels"})
+		return
+	}
+	if channels == nil {
+		channels = []*models.GoogleCalendarChannel{}
+	}
+	json.NewEncoder(w).Encode(GoogleCalendarWatchResponse{Success: true, Data: channels})
+}
+
+// Unwatch handles DELETE /google-calendar/watch
+func (h *GoogleCalendarHandler) Unwatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(GoogleCalendarWatchResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+
+	if err := h.syncer.Unwatch(r.Context(), user.ID); err != nil {
+		log.Printf("Failed to stop Google Calendar channels for user %s: %v", user.ID, err)
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(GoogleCalendarWatchResponse{Success: false, Error: "Failed to stop Google Calendar channels"})
+		return
+	}
+	json.NewEncoder(w).Encode(GoogleCalendarWatchResponse{Success: true})
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed graphiql.html
+var graphiQLPage []byte
+
+// GraphiQL serves GraphiQL, an in-browser IDE for the GraphQL endpoint it's mounted on.
+// Its headers editor starts with the frontend's token when signed in on the same origin.
+func GraphiQL(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(graphiQLPage)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DependencyCheck probes a single dependency (database, cache, ...) and returns an error if it is unhealthy
+type DependencyCheck func(ctx context.Context) error
+
+// HealthHandler serves liveness and readiness probes for orchestrators
+type HealthHandler struct {
+	timeout  time.Duration
+	names    []string
+	checks   map[string]DependencyCheck
+	optional map[string]bool
+}
+
+// NewHealthHandler creates a health handler; each dependency probe is bounded by timeout
+func NewHealthHandler(timeout time.Duration) *HealthHandler {
+	return &HealthHandler{
+		timeout:  timeout,
+		checks:   map[string]DependencyCheck{},
+		optional: map[string]bool{},
+	}
+}
+
+// AddCheck registers a dependency that must be healthy for the service to be ready
+func (h *HealthHandler) AddCheck(name string, check DependencyCheck) {
+	if _, exists := h.checks[name]; !exists {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+	delete(h.optional, name)
+}
+
+// AddOptionalCheck registers a dependency the service can run without. It is reported in
+// readiness responses, and while it is down the status is DEGRADED but still 200.
+func (h *HealthHandler) AddOptionalCheck(name string, check DependencyCheck) {
+	h.AddCheck(name, check)
+	h.optional[name] = true
+}
+
+// DependencyStatus reports the outcome of a single dependency probe
+type DependencyStatus struct {
+	Status    string  `json:"status"` // "up" or "down"
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthResponse represents the health probe response
+type HealthResponse struct {
+	Status       string                      `json:"status"` // "OK", "DEGRADED" or "UNAVAILABLE"
+	Timestamp    string                      `json:"timestamp"`
+	Dependencies map[string]DependencyStatus `json:"dependencies,omitempty"`
+}
+
+// Liveness reports that the process is up. It deliberately ignores dependencies so an
+// outage of Postgres or Redis doesn't get every replica restarted.
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HealthResponse{
+		Status:    "OK",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// Readiness probes every dependency and returns 503 if any is down so traffic is routed elsewhere
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	dependencies := h.probe(r.Context())
+
+	response := HealthResponse{
+		Status:       "OK",
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Dependencies: dependencies,
+	}
+	for name, status := range dependencies {
+		if status.Status == "up" {
+			continue
+		}
+		if !h.optional[name] {
+			response.Status = "UNAVAILABLE"
+		} else if response.Status == "OK" {
+			response.Status = "DEGRADED"
+		}
+	}
+
+	if response.Status == "UNAVAILABLE" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// probe runs all dependency checks concurrently, each with its own timeout
+func (h *HealthHandler) probe(ctx context.Context) map[string]DependencyStatus {
+	results := make(map[string]DependencyStatus, len(h.names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, name := range h.names {
+		wg.Add(1)
+		go func(name string, check DependencyCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(checkCtx)
+			status := DependencyStatus{
+				Status:    "up",
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				status.Status = "down"
+				status.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[name] = status
+			mu.Unlock()
+		}(name, h.checks[name])
+	}
+
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/resolvers"
+)
+
+// LocationHandler serves where the signed-in user commutes from, how they travel and what
+// their commutes cost and emit
+type LocationHandler struct {
+	resolver *resolvers.Resolver
+}
+
+// NewLocationHandler creates a new location handler
+func NewLocationHandler(resolver *resolvers.Resolver) *LocationHandler {
+	return &LocationHandler{resolver: resolver}
+}
+
+// SetHomeAddress handles PUT /me/home-address with {"address": "..."}; a null or empty
+// address clears it. The response is the user, with coordinates when the address could be
+// geocoded.
+func (h *LocationHandler) SetHomeAddress(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+
+	var input struct {
+		Address *string `json:"address"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Invalid request body"})
+		return
+	}
+
+	updated, err := h.resolver.SetHomeAddress(r.Context(), user.ID, input.Address)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.HasSuffix(err.Error(), "not found") {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(OfficeResponse{Success: true, Data: updated})
+}
+
+// GetTravelProfile handles GET /me/travel-profile; users who haven't set one get the
+// default of every mode with no preference
+func (h *LocationHandler) GetTravelProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+
+	profile, err := h.resolver.TravelProfile(r.Context(), user.ID)
+	if err != nil {
+		writeOfficeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(OfficeResponse{Success: true, Data: profile})
+}
+
+// SetTravelProfile handles PUT /me/travel-profile, replacing the whole profile, e.g.
+// {"modes": ["TRANSIT", "BIKE"], "preferredMode": "BIKE", "maxBikeMinutes": 40}
+func (h *LocationHandler) SetTravelProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+
+	var input resolvers.TravelProfileInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Invalid request body"})
+		return
+	}
+
+	profile, err := h.resolver.SetTravelProfile(r.Context(), user.ID, input)
+	if err != nil {
+		writeOfficeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(OfficeResponse{Success: true, Data: profile})
+}
+
+// GetCarbonStats handles GET /me/carbon-stats?period=WEEK|MONTH|QUARTER|YEAR (MONTH by
+// default), the CO2 of the recommendations the user accepted over the current period
+func (h *LocationHandler) GetCarbonStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+
+	stats, err := h.resolver.CarbonStats(r.Context(), user.ID, r.URL.Query().Get("period"))
+	if err != nil {
+		writeOfficeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(OfficeResponse{Success: true, Data: stats})
+}
+
+// GetCommuteCosts handles GET /me/commute-costs?months=N, the cost of the recommendations
+// the user accepted per calendar month over the last N months (6 by default)
+func (h *LocationHandler) GetCommuteCosts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+
+	var months *int
+	if value := r.URL.Query().Get("months"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "months must be a number"})
+			return
+		}
+		months = &n
+	}
+
+	monthly, err := h.resolver.CommuteCosts(r.Context(), user.ID, months)
+	if err != nil {
+		writeOfficeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(OfficeResponse{Success: true, Data: monthly})
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/auth"
+	"github.com/commute-planner/backend/pkg/notify"
+)
+
+// MagicLinkCallbackPath is where the links of magic link emails point
+const MagicLinkCallbackPath = "/auth/magic-link/callback"
+
+// magicLinkProvider is implemented by providers that can sign users in with emailed links
+type magicLinkProvider interface {
+	IssueMagicLink(ctx context.Context, email string, ttl time.Duration) (*auth.MagicLink, error)
+	RedeemMagicLink(ctx context.Context, token string) (*auth.AuthResult, error)
+}
+
+// magicLinkSender emails magic links pointing at the API's public URL
+type magicLinkSender struct {
+	notifier notify.Notifier
+	baseURL  string
+	// redirectURL is the frontend page a redeemed link lands on
+	redirectURL string
+	ttl         time.Duration
+}
+
+// magicLinkPage asks the user to confirm the login. Opening a link only shows it, so
+// mail scanners and prefetchers that follow links don't use them up.
+var magicLinkPage = template.Must(template.New("magic-link").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Log in to Commute Planner</title>
+</head>
+<body>
+{{if .Token}}<form method="post">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Log in to Commute Planner</button>
+</form>{{else}}<p>{{.Error}}</p>{{end}}
+</body>
+</html>
+`))
+
+// magicLinkPageData fills magicLinkPage: the token to redeem, or the error to show
+type magicLinkPageData struct {
+	Token string
+	Error string
+}
+
+// SendMagicLinksWith enables passwordless login, emailing links valid for ttl through
+// notifier. The links point at baseURL, the API's public URL; redeemed links land on
+// redirectURL, the frontend, with the session's tokens in the URL fragment. It fails for
+// auth providers that can't issue magic links.
+func (h *AuthHandler) SendMagicLinksWith(notifier notify.Notifier, baseURL, redirectURL string, ttl time.Duration) error {
+	if _, ok := h.authProvider.(magicLinkProvider); !ok {
+		return fmt.Errorf("the auth provider doesn't support magic links")
+	}
+	if _, err := url.Parse(redirectURL); err != nil || redirectURL == "" {
+		return fmt.Errorf("invalid magic link redirect URL %q", redirectURL)
+	}
+	h.magicLinks = &magicLinkSender{
+		notifier:    notifier,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		redirectURL: redirectURL,
+		ttl:         ttl,
+	}
+	return nil
+}
+
+// RequestMagicLink handles POST /auth/magic-link with {"email": "..."}, emailing the user
+// a single-use login link. It answers the same whether or not the email has an account.
+func (h *AuthHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	provider, ok := h.authProvider.(magicLinkProvider)
+	if !ok || h.magicLinks == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Magic link login is not enabled"})
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Email is required"})
+		return
+	}
+
+	link, err := provider.IssueMagicLink(r.Context(), strings.TrimSpace(req.Email), h.magicLinks.ttl)
+	if err != nil {
+		log.Printf("Failed to issue magic link: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Failed to send login link"})
+		return
+	}
+	if link != nil {
+		if err := h.magicLinks.send(r.Context(), link); err != nil {
+			log.Printf("Failed to email magic link: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Failed to send login link"})
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(AuthResponse{Success: true, Message: "If the email has an account, a login link is on its way"})
+}
+
+// ConfirmMagicLink handles GET /auth/magic-link/callback?token=..., the link of a magic
+// link email, with a page whose button POSTs the token back to redeem it
+func (h *AuthHandler) ConfirmMagicLink(w http.ResponseWriter, r *http.Request) {
+	page := magicLinkPageData{Token: r.URL.Query().Get("token")}
+	status := http.StatusOK
+	if _, ok := h.authProvider.(magicLinkProvider); !ok || h.magicLinks == nil {
+		page.Token, page.Error, status = "", "Magic link login is not enabled.", http.StatusNotFound
+	} else if page.Token == "" {
+		page.Error, status = "This login link is incomplete. Copy the whole link from the email.", http.StatusBadRequest
+	}
+	writeMagicLinkPage(w, status, page)
+}
+
+// MagicLinkCallback handles the POST of ConfirmMagicLink's page, redeeming the link and
+// redirecting to the frontend with the usual auth payload's tokens in the URL fragment
+// (access_token, token_type, expires_in and refresh_token), which never reaches a server
+func (h *AuthHandler) MagicLinkCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.authProvider.(magicLinkProvider)
+	if !ok || h.magicLinks == nil {
+		writeMagicLinkPage(w, http.StatusNotFound, magicLinkPageData{Error: "Magic link login is not enabled."})
+		return
+	}
+
+	result, err := provider.RedeemMagicLink(withClient(r), r.PostFormValue("token"))
+	if errors.Is(err, auth.ErrMagicLinkInvalid) {
+		writeMagicLinkPage(w, http.StatusUnauthorized, magicLinkPageData{Error: "This login link has expired or was already used. Ask for a new one."})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to redeem magic link: %v", err)
+		writeMagicLinkPage(w, http.StatusInternalServerError, magicLinkPageData{Error: "Login failed. Try again."})
+		return
+	}
+
+	fragment := url.Values{
+		"access_token": {result.AccessToken},
+		"token_type":   {result.TokenType},
+		"expires_in":   {strconv.FormatInt(result.ExpiresIn, 10)},
+	}
+	if result.RefreshToken != "" {
+		fragment.Set("refresh_token", result.RefreshToken)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	http.Redirect(w, r, h.magicLinks.redirectURL+"#"+fragment.Encode(), http.StatusSeeOther)
+}
+
+// writeMagicLinkPage renders magicLinkPage, which holds a token and must not be cached,
+// leaked in a Referer or framed
+func writeMagicLinkPage(w http.ResponseWriter, status int, page magicLinkPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; form-action 'self'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := magicLinkPage.Execute(w, page); err != nil {
+		log.Printf("Failed to render magic link page: %v", err)
+	}
+}
+
+func (s *magicLinkSender) send(ctx context.Context, link *auth.MagicLink) error {
+	target := s.baseURL + MagicLinkCallbackPath + "?" + url.Values{"token": {link.Token}}.Encode()
+	minutes := int(s.ttl.Round(time.Minute) / time.Minute)
+	return s.notifier.Send(ctx, notify.Message{
+		To:      link.Email,
+		Subject: "Your Commute Planner login link",
+		Text: fmt.Sprintf("Use this link to log in to Commute Planner:\n\n%s\n\n"+
+			"It works once, for the next %d minutes. If you didn't ask for it, you can ignore this email.\n", target, minutes),
+	})
+}
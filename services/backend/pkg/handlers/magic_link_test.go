@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/auth"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/notify"
+)
+
+// singleUseLinks redeems each of its tokens once
+type singleUseLinks struct {
+	auth.AuthProvider
+	unused map[string]bool
+}
+
+func (p *singleUseLinks) IssueMagicLink(ctx context.Context, email string, ttl time.Duration) (*auth.MagicLink, error) {
+	return nil, nil
+}
+
+func (p *singleUseLinks) RedeemMagicLink(ctx context.Context, token string) (*auth.AuthResult, error) {
+	if !p.unused[token] {
+		return nil, auth.ErrMagicLinkInvalid
+	}
+	delete(p.unused, token)
+	return &auth.AuthResult{User: &models.User{ID: "ada"}, AccessToken: "access", TokenType: "Bearer", ExpiresIn: 3600}, nil
+}
+
+type discardNotifier struct{}
+
+func (discardNotifier) Send(ctx context.Context, msg notify.Message) error { return nil }
+
+func TestMagicLinkCallback(t *testing.T) {
+	provider := &singleUseLinks{unused: map[string]bool{"link": true}}
+	h := NewAuthHandler(provider)
+	if err := h.SendMagicLinksWith(discardNotifier{}, "https://api.example.com", "https://app.example.com/login", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening the link, as a mail scanner would, only shows the confirmation
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.ConfirmMagicLink(rec, httptest.NewRequest(http.MethodGet, MagicLinkCallbackPath+"?token=link", nil))
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || !strings.Contains(rec.Body.String(), `value="link"`) {
+			t.Fatalf("confirmation page: %d %s", rec.Code, rec.Body)
+		}
+		if strings.Contains(rec.Body.String(), "access") {
+			t.Fatal("opening the link logged in")
+		}
+	}
+
+	confirm := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, MagicLinkCallbackPath, strings.NewReader("token=link"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		h.MagicLinkCallback(rec, req)
+		return rec
+	}
+	rec := confirm()
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("confirmation: %d %s", rec.Code, rec.Body)
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fragment, _ := url.ParseQuery(location.Fragment)
+	if location.Host != "app.example.com" || location.RawQuery != "" || fragment.Get("access_token") != "access" || fragment.Get("expires_in") != "3600" {
+		t.Errorf("redirected to %s", location)
+	}
+
+	if rec := confirm(); rec.Code != http.StatusUnauthorized || strings.Contains(rec.Body.String(), "access") {
+		t.Errorf("reused link: %d %s", rec.Code, rec.Body)
+	}
+}
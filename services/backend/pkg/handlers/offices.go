@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/resolvers"
+	"github.com/gorilla/mux"
+)
+
+// OfficeHandler serves the tenant's offices. Anyone signed in can list them and pick a
+// default; creating and editing them are operator endpoints.
+type OfficeHandler struct {
+	resolver *resolvers.Resolver
+}
+
+// NewOfficeHandler creates a new office handler
+func NewOfficeHandler(resolver *resolvers.Resolver) *OfficeHandler {
+	return &OfficeHandler{resolver: resolver}
+}
+
+// OfficeResponse is the response of the office endpoints
+type OfficeResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// ListOffices handles GET /offices
+func (h *OfficeHandler) ListOffices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	offices, err := h.resolver.Offices(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Failed to load offices"})
+		return
+	}
+	data := interface{}(offices)
+	if offices == nil {
+		data = []interface{}{}
+	}
+	json.NewEncoder(w).Encode(OfficeResponse{Success: true, Data: data})
+}
+
+// SetDefaultOffice handles PUT /me/default-office with {"officeId": "..."}; a null
+// officeId clears the default
+func (h *OfficeHandler) SetDefaultOffice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+
+	var input struct {
+		OfficeID *string `json:"officeId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Invalid request body"})
+		return
+	}
+
+	updated, err := h.resolver.SetDefaultOffice(r.Context(), user.ID, input.OfficeID)
+	if err != nil {
+		writeOfficeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(OfficeResponse{Success: true, Data: updated})
+}
+
+// CreateOffice handles POST /admin/offices
+func (h *OfficeHandler) CreateOffice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input resolvers.OfficeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Invalid request body"})
+		return
+	}
+
+	office, err := h.resolver.CreateOffice(r.Context(), input)
+	if err != nil {
+		writeOfficeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(OfficeResponse{Success: true, Data: office})
+}
+
+// UpdateOffice handles PUT /admin/offices/{id}, changing only the fields given
+func (h *OfficeHandler) UpdateOffice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input resolvers.OfficeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "Invalid request body"})
+		return
+	}
+
+	office, err := h.resolver.UpdateOffice(r.Context(), mux.Vars(r)["id"], input)
+	if err != nil {
+		writeOfficeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(OfficeResponse{Success: true, Data: office})
+}
+
+// DeleteOffice handles DELETE /admin/offices/{id}
+func (h *OfficeHandler) DeleteOffice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	deleted, err := h.resolver.DeleteOffice(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeOfficeError(w, err)
+		return
+	}
+	if !deleted {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: "office not found"})
+		return
+	}
+	json.NewEncoder(w).Encode(OfficeResponse{Success: true, Message: "Office deleted"})
+}
+
+// writeOfficeError maps resolver errors to a status: lookups that found nothing are 404,
+// storage failures 500 and everything else a validation error
+func writeOfficeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case strings.HasSuffix(err.Error(), "not found"):
+		status = http.StatusNotFound
+	case strings.HasPrefix(err.Error(), "error "):
+		status = http.StatusInternalServerError
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(OfficeResponse{Success: false, Error: err.Error()})
+}
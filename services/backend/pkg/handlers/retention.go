@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/retention"
+	"github.com/gorilla/mux"
+)
+
+// RetentionHandler serves the operator endpoints that trigger and monitor archival runs
+type RetentionHandler struct {
+	archiver *retention.Archiver
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(archiver *retention.Archiver) *RetentionHandler {
+	return &RetentionHandler{archiver: archiver}
+}
+
+// RetentionResponse is the response of the retention endpoints
+type RetentionResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// StartRun handles POST /admin/retention/runs, archiving in the background. It answers
+// 202 with the started run, or 409 while another run is in progress.
+func (h *RetentionHandler) StartRun(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	run, err := h.archiver.Trigger(r.Context(), models.RetentionTriggerAdmin)
+	if errors.Is(err, retention.ErrRunning) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(RetentionResponse{Success: false, Error: err.Error()})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(RetentionResponse{Success: false, Error: "Failed to start retention run"})
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(RetentionResponse{Success: true, Data: run})
+}
+
+// ListRuns handles GET /admin/retention/runs?limit=N, newest first
+func (h *RetentionHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 100 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(RetentionResponse{Success: false, Error: "limit must be between 1 and 100"})
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := h.archiver.ListRuns(r.Context(), limit)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(RetentionResponse{Success: false, Error: "Failed to load retention runs"})
+		return
+	}
+	data := interface{}(runs)
+	if runs == nil {
+		data = []interface{}{}
+	}
+	json.NewEncoder(w).Encode(RetentionResponse{Success: true, Data: data})
+}
+
+// GetRun handles GET /admin/retention/runs/{id}
+func (h *RetentionHandler) GetRun(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	run, err := h.archiver.GetRun(r.Context(), mux.Vars(r)["id"])
+	writeRetentionResult(w, run, err, "Retention run not found")
+}
+
+// GetArchivedJob handles GET /admin/archive/jobs/{id}, the snapshot of an archived job
+func (h *RetentionHandler) GetArchivedJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	archived, err := h.archiver.GetArchived(r.Context(), mux.Vars(r)["id"])
+	writeRetentionResult(w, archived, err, "Archived job not found")
+}
+
+func writeRetentionResult(w http.ResponseWriter, data interface{}, err error, notFound string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(RetentionResponse{Success: false, Error: notFound})
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(RetentionResponse{Success: false, Error: "Internal server error"})
+	default:
+		json.NewEncoder(w).Encode(RetentionResponse{Success: true, Data: data})
+	}
+}
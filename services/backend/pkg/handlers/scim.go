@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/resolvers"
+	"github.com/commute-planner/backend/pkg/scim"
+	"github.com/commute-planner/backend/pkg/tenant"
+	"github.com/gorilla/mux"
+)
+
+// SCIMPath is where the SCIM API is served
+const SCIMPath = "/scim/v2"
+
+// maxSCIMPage caps the users of one list response
+const maxSCIMPage = 200
+
+// SCIMHandler lets a tenant's identity provider provision and deprovision its users
+// over SCIM 2.0. Requests carry the tenant's SCIM token and only reach its users.
+type SCIMHandler struct {
+	resolver *resolvers.Resolver
+	// authProvider is how users created by provisioning sign in
+	authProvider string
+}
+
+// NewSCIMHandler creates a SCIM handler. Users it creates sign in with authProvider,
+// e.g. "local" or the name of the OIDC provider.
+func NewSCIMHandler(resolver *resolvers.Resolver, authProvider string) *SCIMHandler {
+	return &SCIMHandler{resolver: resolver, authProvider: authProvider}
+}
+
+// RequireToken authenticates requests with a tenant's SCIM token, scoping them to the
+// tenant
+func (h *SCIMHandler) RequireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, err := h.resolver.SCIMTenant(r.Context(), bearerToken(r))
+		if err != nil {
+			if !errors.Is(err, resolvers.ErrSCIMTokenInvalid) {
+				log.Printf("Failed to authenticate SCIM token: %v", err)
+			}
+			writeSCIMError(w, http.StatusUnauthorized, "", "A valid SCIM token is required")
+			return
+		}
+		// A subdomain can't point a tenant's token at another tenant
+		if scoped, ok := tenant.FromContext(r.Context()); ok && scoped != t.ID {
+			writeSCIMError(w, http.StatusUnauthorized, "", "A valid SCIM token is required")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), t.ID)))
+	})
+}
+
+// ListUsers handles GET /scim/v2/Users, with an optional filter such as
+// userName eq "ada@example.com" and startIndex and count paging
+func (h *SCIMHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filter *scim.Filter
+	if raw := query.Get("filter"); raw != "" {
+		var err error
+		if filter, err = scim.ParseFilter(raw); err != nil {
+			writeSCIMError(w, http.StatusBadRequest, scim.ErrInvalidFilter, err.Error())
+			return
+		}
+	}
+	startIndex, count := 1, maxSCIMPage
+	if n, err := strconv.Atoi(query.Get("startIndex")); err == nil && n > 1 {
+		startIndex = n
+	}
+	if n, err := strconv.Atoi(query.Get("count")); err == nil && n >= 0 && n < maxSCIMPage {
+		count = n
+	}
+
+	users, total, err := h.resolver.ProvisionedUsers(r.Context(), filter, startIndex-1, count)
+	if err != nil {
+		if errors.Is(err, resolvers.ErrSCIMFilterUnsupported) {
+			writeSCIMError(w, http.StatusBadRequest, scim.ErrInvalidFilter, err.Error())
+			return
+		}
+		log.Printf("Failed to list SCIM users: %v", err)
+		writeSCIMError(w, http.StatusInternalServerError, "", "Failed to load users")
+		return
+	}
+
+	page := make([]interface{}, len(users))
+	for i, user := range users {
+		page[i] = scimUser(user)
+	}
+	writeSCIM(w, http.StatusOK, scim.ListResponse{
+		Schemas:      []string{scim.ListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	})
+}
+
+// GetUser handles GET /scim/v2/Users/{id}
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+	writeSCIM(w, http.StatusOK, scimUser(user))
+}
+
+// CreateUser handles POST /scim/v2/Users
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var resource scim.User
+	if err := json.NewDecoder(r.Body).Decode(&resource); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, scim.ErrInvalidSyntax, "Invalid request body")
+		return
+	}
+	user, err := h.resolver.ProvisionUser(r.Context(), h.provisioned(&resource))
+	if err != nil {
+		h.writeProvisionError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusCreated, scimUser(user))
+}
+
+// ReplaceUser handles PUT /scim/v2/Users/{id}
+func (h *SCIMHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	var resource scim.User
+	if err := json.NewDecoder(r.Body).Decode(&resource); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, scim.ErrInvalidSyntax, "Invalid request body")
+		return
+	}
+	user, err := h.resolver.UpdateProvisionedUser(r.Context(), mux.Vars(r)["id"], h.provisioned(&resource))
+	if err != nil {
+		h.writeProvisionError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, scimUser(user))
+}
+
+// PatchUser handles PATCH /scim/v2/Users/{id}, which identity providers use to
+// deactivate users ("active": false) as well as to update them
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	var patch scim.PatchOp
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, scim.ErrInvalidSyntax, "Invalid request body")
+		return
+	}
+	user, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	resource := scimUser(user)
+	if err := patch.Apply(resource); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, scim.ErrInvalidValue, err.Error())
+		return
+	}
+	user, err := h.resolver.UpdateProvisionedUser(r.Context(), user.ID, h.provisioned(resource))
+	if err != nil {
+		h.writeProvisionError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, scimUser(user))
+}
+
+// user loads the tenant's user the request is for, writing the error when it fails
+func (h *SCIMHandler) user(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, err := h.resolver.ProvisionedUser(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			writeSCIMError(w, http.StatusNotFound, "", "User not found")
+		} else {
+			log.Printf("Failed to load SCIM user: %v", err)
+			writeSCIMError(w, http.StatusInternalServerError, "", "Failed to load user")
+		}
+		return nil, false
+	}
+	return user, true
+}
+
+// provisioned maps a SCIM user onto the planner's user
+func (h *SCIMHandler) provisioned(resource *scim.User) resolvers.ProvisionedUser {
+	user := resolvers.ProvisionedUser{
+		Email:        resource.Email(),
+		Name:         resource.FullName(),
+		Active:       resource.IsActive(),
+		AuthProvider: h.authProvider,
+	}
+	if user.Name == "" {
+		user.Name = user.Email
+	}
+	if resource.ExternalID != "" {
+		user.ExternalID = &resource.ExternalID
+	}
+	return user
+}
+
+func (h *SCIMHandler) writeProvisionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, resolvers.ErrUserExists):
+		writeSCIMError(w, http.StatusConflict, scim.ErrUniqueness, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		writeSCIMError(w, http.StatusNotFound, "", "User not found")
+	case errors.Is(err, repository.ErrConflict):
+		writeSCIMError(w, http.StatusConflict, "", "The user was updated concurrently; retry")
+	case errors.Is(err, resolvers.ErrProvisionedUserInvalid):
+		writeSCIMError(w, http.StatusBadRequest, scim.ErrInvalidValue, err.Error())
+	default:
+		log.Printf("Failed to provision SCIM user: %v", err)
+		writeSCIMError(w, http.StatusInternalServerError, "", "Failed to save user")
+	}
+}
+
+// scimUser maps a planner user onto a SCIM user
+func scimUser(user *models.User) *scim.User {
+	active := user.DeactivatedAt == nil
+	resource := &scim.User{
+		Schemas:     []string{scim.UserSchema},
+		ID:          user.ID,
+		UserName:    user.Email,
+		Name:        &scim.Name{Formatted: user.Name},
+		DisplayName: user.Name,
+		Emails:      []scim.Email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     SCIMPath + "/Users/" + user.ID,
+			Version:      `W/"` + strconv.Itoa(user.Version) + `"`,
+		},
+	}
+	if user.SCIMExternalID != nil {
+		resource.ExternalID = *user.SCIMExternalID
+	}
+	return resource
+}
+
+func writeSCIM(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeSCIMError(w http.ResponseWriter, status int, scimType, detail string) {
+	writeSCIM(w, status, scim.NewError(status, scimType, detail))
+}
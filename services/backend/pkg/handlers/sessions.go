@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/auth"
+	"github.com/gorilla/mux"
+)
+
+// sessionProvider is implemented by providers that record the sessions they issue tokens for
+type sessionProvider interface {
+	Sessions(ctx context.Context, userID, currentToken string) ([]*auth.Session, error)
+	RevokeSession(ctx context.Context, userID, id string) (bool, error)
+}
+
+// SessionResponse is the response of the session endpoints
+type SessionResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// ListSessions handles GET /auth/sessions, the devices the user is logged in on
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	provider, ok := h.authProvider.(sessionProvider)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(SessionResponse{Success: false, Error: "Sessions are not available for this auth provider"})
+		return
+	}
+
+	user := GetUserFromContext(r.Context())
+	sessions, err := provider.Sessions(r.Context(), user.ID, bearerToken(r))
+	if err != nil {
+		log.Printf("Failed to list sessions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(SessionResponse{Success: false, Error: "Failed to load sessions"})
+		return
+	}
+	json.NewEncoder(w).Encode(SessionResponse{Success: true, Data: sessions})
+}
+
+// RevokeSession handles DELETE /auth/sessions/{id}, logging the user out on one device
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	provider, ok := h.authProvider.(sessionProvider)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(SessionResponse{Success: false, Error: "Sessions are not available for this auth provider"})
+		return
+	}
+
+	user := GetUserFromContext(r.Context())
+	revoked, err := provider.RevokeSession(r.Context(), user.ID, mux.Vars(r)["id"])
+	if err != nil {
+		log.Printf("Failed to revoke session: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(SessionResponse{Success: false, Error: "Failed to revoke session"})
+		return
+	}
+	if !revoked {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(SessionResponse{Success: false, Error: "Session not found"})
+		return
+	}
+	json.NewEncoder(w).Encode(SessionResponse{Success: true, Message: "Session revoked"})
+}
+
+// withClient returns the request's context with the device it came from, for the
+// session a login starts
+func withClient(r *http.Request) context.Context {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	// Behind a load balancer the client is the first forwarded address
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		ip = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	return auth.WithClient(r.Context(), auth.Client{UserAgent: r.UserAgent(), IPAddress: ip})
+}
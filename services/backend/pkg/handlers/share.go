@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/commute-planner/backend/pkg/resolvers"
+	"github.com/gorilla/mux"
+)
+
+// ShareHandler serves plans shared with a signed link, to anyone holding the link
+type ShareHandler struct {
+	resolver *resolvers.Resolver
+}
+
+// NewShareHandler creates a new share handler
+func NewShareHandler(resolver *resolvers.Resolver) *ShareHandler {
+	return &ShareHandler{resolver: resolver}
+}
+
+// ShareResponse is the response of the share endpoint
+type ShareResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// GetSharedPlan handles GET /share/{id}?expires=...&signature=..., the URL of a share
+// link. Invalid, expired and revoked links all answer 404.
+func (h *ShareHandler) GetSharedPlan(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// Shared plans are personal; keep them out of shared caches and search engines
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	query := r.URL.Query()
+	plan, err := h.resolver.SharedPlan(r.Context(), mux.Vars(r)["id"], query.Get("expires"), query.Get("signature"))
+	if errors.Is(err, resolvers.ErrShareLinkInvalid) || errors.Is(err, resolvers.ErrShareLinksDisabled) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ShareResponse{Success: false, Error: resolvers.ErrShareLinkInvalid.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load shared plan: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ShareResponse{Success: false, Error: "Failed to load shared plan"})
+		return
+	}
+	json.NewEncoder(w).Encode(ShareResponse{Success: true, Data: plan})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+// TenantMiddleware scopes requests to a tenant in multi-tenant deployments. A request's
+// tenant comes from its subdomain (FromHost) or, once authenticated, from its user
+// (AuthMiddleware); Require covers unauthenticated endpoints that need one. In
+// single-tenant mode both middlewares pass requests through unchanged.
+type TenantMiddleware struct {
+	tenants       repository.TenantRepository
+	multi         bool
+	baseDomain    string
+	defaultTenant string
+}
+
+// NewTenantMiddleware creates the tenant middleware for a deployment mode
+func NewTenantMiddleware(tenants repository.TenantRepository, mode, baseDomain, defaultTenant string) *TenantMiddleware {
+	return &TenantMiddleware{
+		tenants:       tenants,
+		multi:         mode == tenant.ModeMulti,
+		baseDomain:    baseDomain,
+		defaultTenant: defaultTenant,
+	}
+}
+
+// TenantErrorResponse is returned when a request's tenant is unknown or missing
+type TenantErrorResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+}
+
+// FromHost scopes requests to the tenant named by their subdomain. It runs before
+// authentication, so tokens of another tenant are rejected; unknown tenants get 404.
+func (m *TenantMiddleware) FromHost(next http.Handler) http.Handler {
+	if !m.multi {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := tenant.FromHost(r.Host, m.baseDomain)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, err := m.tenants.Get(r.Context(), id); err != nil {
+			status, message := http.StatusInternalServerError, "Failed to resolve tenant"
+			if errors.Is(err, repository.ErrNotFound) {
+				status, message = http.StatusNotFound, "Unknown tenant"
+			} else {
+				log.Printf("Failed to resolve tenant %s: %v", id, err)
+			}
+			writeTenantError(w, status, message)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), id)))
+	})
+}
+
+// Require scopes requests that neither their subdomain nor their token placed in a tenant
+// to the default tenant, or rejects them when none is configured
+func (m *TenantMiddleware) Require(next http.Handler) http.Handler {
+	if !m.multi {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := tenant.FromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if m.defaultTenant == "" {
+			writeTenantError(w, http.StatusBadRequest, "Tenant required: use your company's subdomain")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), m.defaultTenant)))
+	})
+}
+
+func writeTenantError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(TenantErrorResponse{Success: false, Error: message})
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/commute-planner/backend/pkg/resolvers"
+)
+
+// TodayHandler serves the signed-in user's day at a glance for mobile widgets
+type TodayHandler struct {
+	resolver *resolvers.Resolver
+}
+
+// NewTodayHandler creates a new today handler
+func NewTodayHandler(resolver *resolvers.Resolver) *TodayHandler {
+	return &TodayHandler{resolver: resolver}
+}
+
+// TodayResponse is the response of GET /api/v1/today
+type TodayResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// Today handles GET /api/v1/today: the next departure, today's plan and its status, and
+// the meetings that need the user in the office. Widgets polling it should send
+// If-None-Match; unchanged days answer 304.
+func (h *TodayHandler) Today(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(TodayResponse{Success: false, Error: "Authentication required"})
+		return
+	}
+	today, err := h.resolver.Today(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to build today for user %s: %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TodayResponse{Success: false, Error: "Failed to load today"})
+		return
+	}
+	json.NewEncoder(w).Encode(TodayResponse{Success: true, Data: today})
+}
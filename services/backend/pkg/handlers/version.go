@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/buildinfo"
+	"github.com/commute-planner/backend/pkg/database"
+)
+
+// versionCacheTTL is how long the migration status is reused; GraphQL responses carry it
+const versionCacheTTL = 30 * time.Second
+
+// VersionReport is the running build and the state of its database, served at /version
+// and in the extensions of GraphQL responses
+type VersionReport struct {
+	buildinfo.Info
+	// Migrations is nil when the database can't be read
+	Migrations *database.MigrationStatus `json:"migrations"`
+}
+
+// VersionHandler reports which build an instance runs, so the instances of a rollout can
+// be told apart
+type VersionHandler struct {
+	info       buildinfo.Info
+	migrations func(ctx context.Context) (*database.MigrationStatus, error)
+
+	mu      sync.Mutex
+	status  *database.MigrationStatus
+	checked time.Time
+}
+
+// NewVersionHandler creates a version handler reading the migration status with migrations
+func NewVersionHandler(info buildinfo.Info, migrations func(ctx context.Context) (*database.MigrationStatus, error)) *VersionHandler {
+	return &VersionHandler{info: info, migrations: migrations}
+}
+
+// Report returns the build and its migration status, read at most every versionCacheTTL
+func (h *VersionHandler) Report(ctx context.Context) VersionReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if time.Since(h.checked) >= versionCacheTTL {
+		// A failed read is retried on the next report
+		if status, err := h.migrations(ctx); err == nil {
+			h.status, h.checked = status, time.Now()
+		}
+	}
+	return VersionReport{Info: h.info, Migrations: h.status}
+}
+
+// Version handles GET /version
+func (h *VersionHandler) Version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(h.Report(r.Context()))
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/buildinfo"
+	"github.com/commute-planner/backend/pkg/database"
+)
+
+func TestVersion(t *testing.T) {
+	reads := 0
+	fail := true
+	h := NewVersionHandler(buildinfo.Info{Commit: "abc123", SchemaVersion: buildinfo.SchemaVersion()}, func(ctx context.Context) (*database.MigrationStatus, error) {
+		reads++
+		if fail {
+			return nil, errors.New("database unavailable")
+		}
+		return &database.MigrationStatus{Latest: "044_share_redaction", Required: "044_share_redaction", Status: database.MigrationsCurrent}, nil
+	})
+
+	if report := h.Report(context.Background()); report.Migrations != nil || report.Commit != "abc123" {
+		t.Errorf("report with the database down = %+v", report)
+	}
+
+	fail = false
+	rec := httptest.NewRecorder()
+	h.Version(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	migrations, _ := body["migrations"].(map[string]interface{})
+	if body["commit"] != "abc123" || body["schemaVersion"] == "" || migrations["status"] != database.MigrationsCurrent {
+		t.Errorf("/version = %v", body)
+	}
+
+	// The status is cached once read
+	h.Report(context.Background())
+	if reads != 2 {
+		t.Errorf("migration status read %d times, want 2", reads)
+	}
+}
@@ -0,0 +1,139 @@
+package i18n
+
+// catalog holds each locale's message per reason code; {name} is replaced with the
+// code's param of that name. The AI service writes the same codes
+// (agents/option_presenter_agent.py), so a code added there needs a message here.
+var catalog = map[string]map[string]string{
+	"en": {
+		"RECOMMENDED":         "Recommended option:",
+		"ALTERNATIVE":         "Strong alternative:",
+		"OPTION_RANK":         "Option #{rank}:",
+		"DAY_NOTE":            "{note}.",
+		"FULL_REMOTE":         "Working remotely keeps the day flexible: all {remoteMeetings} meetings can be joined remotely and no time is lost commuting.",
+		"FULL_DAY_OFFICE":     "A full day in the office gives the most visibility and time together, covering all {officeMeetings} in-person meetings.",
+		"STRATEGIC_AFTERNOON": "An afternoon in the office covers {officeMeetings} key meetings and leaves {remoteMeetings} others to join remotely.",
+		"PARTIAL_OFFICE":      "This option covers {officeMeetings} office meetings and keeps {remoteMeetings} remote ones flexible.",
+		"EFFICIENT_DAY":       "Excellent time efficiency with little commute overhead.",
+		"COMMUTE_SHARE":       "Commuting takes {commutePercent}% of the time spent in the office.",
+		"CONSIDERATIONS":      "Note: {count} considerations apply.",
+
+		"PRO_NO_COMMUTE":                "No commute time or costs",
+		"PRO_FLEXIBILITY":               "Maximum flexibility and comfort",
+		"PRO_WORK_LIFE_BALANCE":         "Better work-life balance",
+		"PRO_NO_TRAVEL_EMISSIONS":       "No travel emissions",
+		"PRO_IN_PERSON_MEETINGS":        "Attend {officeMeetings} key meetings in person",
+		"PRO_VISIBILITY":                "High visibility and professional presence",
+		"PRO_SPONTANEOUS_COLLABORATION": "Spontaneous collaboration opportunities",
+		"PRO_OFFICE_RESOURCES":          "Access to office resources and environment",
+		"PRO_MAX_COLLABORATION":         "Most in-person collaboration time",
+		"PRO_BALANCE":                   "Balances presence and flexibility",
+
+		"CON_LIMITED_FACE_TIME":    "Limited face-to-face interaction",
+		"CON_VISIBILITY_RISK":      "Less visibility with management",
+		"CON_MISSED_COLLABORATION": "May miss spontaneous collaboration",
+		"CON_COMMUTE_TIME":         "{commuteMinutes} minutes of commuting in total",
+		"CON_COMMUTE_COSTS":        "Commute costs (parking, fuel, fares)",
+		"CON_LESS_FLEXIBILITY":     "Less flexibility for your personal schedule",
+		"CON_LONG_DAY":             "The longest day, with commuting on top",
+		"CON_SPLIT_DAY":            "The day is split between office and remote work",
+	},
+	"de": {
+		"RECOMMENDED":         "Empfohlene Option:",
+		"ALTERNATIVE":         "Starke Alternative:",
+		"OPTION_RANK":         "Option Nr. {rank}:",
+		"DAY_NOTE":            "{note}.",
+		"FULL_REMOTE":         "Remote-Arbeit hält den Tag flexibel: Alle {remoteMeetings} Meetings lassen sich remote wahrnehmen, und es geht keine Zeit fürs Pendeln verloren.",
+		"FULL_DAY_OFFICE":     "Ein ganzer Tag im Büro bringt die meiste Sichtbarkeit und gemeinsame Zeit und deckt alle {officeMeetings} Präsenzmeetings ab.",
+		"STRATEGIC_AFTERNOON": "Ein Nachmittag im Büro deckt {officeMeetings} wichtige Meetings ab; {remoteMeetings} weitere lassen sich remote wahrnehmen.",
+		"PARTIAL_OFFICE":      "Diese Option deckt {officeMeetings} Büromeetings ab und hält {remoteMeetings} Remote-Meetings flexibel.",
+		"EFFICIENT_DAY":       "Sehr gute Zeiteffizienz mit wenig Pendelaufwand.",
+		"COMMUTE_SHARE":       "Das Pendeln macht {commutePercent} % der Zeit im Büro aus.",
+		"CONSIDERATIONS":      "Hinweis: {count} Punkte sind zu beachten.",
+
+		"PRO_NO_COMMUTE":                "Keine Pendelzeit und -kosten",
+		"PRO_FLEXIBILITY":               "Maximale Flexibilität und Komfort",
+		"PRO_WORK_LIFE_BALANCE":         "Bessere Work-Life-Balance",
+		"PRO_NO_TRAVEL_EMISSIONS":       "Keine Emissionen durch Fahrten",
+		"PRO_IN_PERSON_MEETINGS":        "{officeMeetings} wichtige Meetings vor Ort",
+		"PRO_VISIBILITY":                "Hohe Sichtbarkeit und professionelle Präsenz",
+		"PRO_SPONTANEOUS_COLLABORATION": "Gelegenheiten für spontane Zusammenarbeit",
+		"PRO_OFFICE_RESOURCES":          "Zugang zu Büroausstattung und -umgebung",
+		"PRO_MAX_COLLABORATION":         "Die meiste Zeit für Zusammenarbeit vor Ort",
+		"PRO_BALANCE":                   "Ausgewogen zwischen Präsenz und Flexibilität",
+
+		"CON_LIMITED_FACE_TIME":    "Wenig persönlicher Kontakt",
+		"CON_VISIBILITY_RISK":      "Weniger Sichtbarkeit bei der Führungsebene",
+		"CON_MISSED_COLLABORATION": "Spontane Zusammenarbeit kann entgehen",
+		"CON_COMMUTE_TIME":         "Insgesamt {commuteMinutes} Minuten Pendelzeit",
+		"CON_COMMUTE_COSTS":        "Pendelkosten (Parken, Kraftstoff, Fahrkarten)",
+		"CON_LESS_FLEXIBILITY":     "Weniger Flexibilität für private Termine",
+		"CON_LONG_DAY":             "Der längste Tag, zuzüglich Pendelzeit",
+		"CON_SPLIT_DAY":            "Der Tag ist zwischen Büro und Remote-Arbeit aufgeteilt",
+	},
+	"fr": {
+		"RECOMMENDED":         "Option recommandée :",
+		"ALTERNATIVE":         "Alternative solide :",
+		"OPTION_RANK":         "Option n° {rank} :",
+		"DAY_NOTE":            "{note}.",
+		"FULL_REMOTE":         "Le télétravail garde la journée flexible : les {remoteMeetings} réunions peuvent se faire à distance, sans temps perdu dans les trajets.",
+		"FULL_DAY_OFFICE":     "Une journée complète au bureau offre le plus de visibilité et de temps ensemble, et couvre les {officeMeetings} réunions en présentiel.",
+		"STRATEGIC_AFTERNOON": "Un après-midi au bureau couvre {officeMeetings} réunions clés et laisse {remoteMeetings} autres à suivre à distance.",
+		"PARTIAL_OFFICE":      "Cette option couvre {officeMeetings} réunions au bureau et garde {remoteMeetings} réunions à distance flexibles.",
+		"EFFICIENT_DAY":       "Excellente efficacité, avec peu de temps de trajet.",
+		"COMMUTE_SHARE":       "Les trajets représentent {commutePercent} % du temps passé au bureau.",
+		"CONSIDERATIONS":      "À noter : {count} points à prendre en compte.",
+
+		"PRO_NO_COMMUTE":                "Aucun temps ni coût de trajet",
+		"PRO_FLEXIBILITY":               "Flexibilité et confort maximaux",
+		"PRO_WORK_LIFE_BALANCE":         "Meilleur équilibre vie pro-vie perso",
+		"PRO_NO_TRAVEL_EMISSIONS":       "Aucune émission liée aux trajets",
+		"PRO_IN_PERSON_MEETINGS":        "{officeMeetings} réunions clés en personne",
+		"PRO_VISIBILITY":                "Forte visibilité et présence professionnelle",
+		"PRO_SPONTANEOUS_COLLABORATION": "Occasions de collaboration spontanée",
+		"PRO_OFFICE_RESOURCES":          "Accès aux ressources et à l'environnement du bureau",
+		"PRO_MAX_COLLABORATION":         "Le plus de temps de collaboration en personne",
+		"PRO_BALANCE":                   "Équilibre entre présence et flexibilité",
+
+		"CON_LIMITED_FACE_TIME":    "Peu d'échanges en face à face",
+		"CON_VISIBILITY_RISK":      "Moins de visibilité auprès de la direction",
+		"CON_MISSED_COLLABORATION": "Risque de manquer des collaborations spontanées",
+		"CON_COMMUTE_TIME":         "{commuteMinutes} minutes de trajet au total",
+		"CON_COMMUTE_COSTS":        "Coûts de trajet (stationnement, carburant, billets)",
+		"CON_LESS_FLEXIBILITY":     "Moins de flexibilité pour votre emploi du temps personnel",
+		"CON_LONG_DAY":             "La journée la plus longue, trajets en plus",
+		"CON_SPLIT_DAY":            "Journée partagée entre bureau et télétravail",
+	},
+	"es": {
+		"RECOMMENDED":         "Opción recomendada:",
+		"ALTERNATIVE":         "Alternativa sólida:",
+		"OPTION_RANK":         "Opción n.º {rank}:",
+		"DAY_NOTE":            "{note}.",
+		"FULL_REMOTE":         "Trabajar en remoto mantiene el día flexible: las {remoteMeetings} reuniones pueden hacerse a distancia y no se pierde tiempo en desplazamientos.",
+		"FULL_DAY_OFFICE":     "Un día completo en la oficina da la mayor visibilidad y tiempo en común, y cubre las {officeMeetings} reuniones presenciales.",
+		"STRATEGIC_AFTERNOON": "Una tarde en la oficina cubre {officeMeetings} reuniones clave y deja {remoteMeetings} más para hacer en remoto.",
+		"PARTIAL_OFFICE":      "Esta opción cubre {officeMeetings} reuniones en la oficina y mantiene flexibles {remoteMeetings} reuniones en remoto.",
+		"EFFICIENT_DAY":       "Excelente eficiencia, con poco tiempo de desplazamiento.",
+		"COMMUTE_SHARE":       "Los desplazamientos suponen el {commutePercent} % del tiempo en la oficina.",
+		"CONSIDERATIONS":      "Nota: hay {count} aspectos a tener en cuenta.",
+
+		"PRO_NO_COMMUTE":                "Sin tiempo ni costes de desplazamiento",
+		"PRO_FLEXIBILITY":               "Máxima flexibilidad y comodidad",
+		"PRO_WORK_LIFE_BALANCE":         "Mejor conciliación",
+		"PRO_NO_TRAVEL_EMISSIONS":       "Sin emisiones por desplazamientos",
+		"PRO_IN_PERSON_MEETINGS":        "{officeMeetings} reuniones clave en persona",
+		"PRO_VISIBILITY":                "Alta visibilidad y presencia profesional",
+		"PRO_SPONTANEOUS_COLLABORATION": "Oportunidades de colaboración espontánea",
+		"PRO_OFFICE_RESOURCES":          "Acceso a los recursos y el entorno de la oficina",
+		"PRO_MAX_COLLABORATION":         "El mayor tiempo de colaboración en persona",
+		"PRO_BALANCE":                   "Equilibrio entre presencia y flexibilidad",
+
+		"CON_LIMITED_FACE_TIME":    "Poca interacción cara a cara",
+		"CON_VISIBILITY_RISK":      "Menos visibilidad ante la dirección",
+		"CON_MISSED_COLLABORATION": "Puede perderse la colaboración espontánea",
+		"CON_COMMUTE_TIME":         "{commuteMinutes} minutos de desplazamiento en total",
+		"CON_COMMUTE_COSTS":        "Costes de desplazamiento (aparcamiento, combustible, billetes)",
+		"CON_LESS_FLEXIBILITY":     "Menos flexibilidad para tu agenda personal",
+		"CON_LONG_DAY":             "El día más largo, con los desplazamientos aparte",
+		"CON_SPLIT_DAY":            "El día se reparte entre oficina y trabajo en remoto",
+	},
+}
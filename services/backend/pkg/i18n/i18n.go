@@ -0,0 +1,171 @@
+// Package i18n renders recommendation text in the user's language. Recommendations carry
+// structured reason codes, written by the AI service or derived from the plan itself,
+// which are looked up in a per-locale catalog and filled in with their params.
+package i18n
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// DefaultLocale is the language of users who haven't picked one, and of any message
+// missing from another locale's catalog
+const DefaultLocale = "en"
+
+// Locales are the supported languages, as ISO 639-1 codes
+var Locales = []string{"en", "de", "fr", "es"}
+
+// Match returns the supported locale for a language tag such as "de", "de-AT" or
+// "fr_CA", reporting whether there is one
+func Match(tag string) (string, bool) {
+	language := strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	if _, ok := catalog[language]; !ok {
+		return "", false
+	}
+	return language, true
+}
+
+// Render writes codes out in locale, falling back to DefaultLocale for an unsupported
+// locale or a message it lacks. Codes without a message, or missing a param their
+// message needs, are left out.
+func Render(locale string, codes []models.ReasonCode) *models.LocalizedText {
+	locale, ok := Match(locale)
+	if !ok {
+		locale = DefaultLocale
+	}
+	text := &models.LocalizedText{Locale: locale, Pros: []string{}, Cons: []string{}}
+	var reasoning []string
+	for _, code := range codes {
+		message, ok := catalog[locale][code.Code]
+		if !ok {
+			message, ok = catalog[DefaultLocale][code.Code]
+		}
+		if !ok {
+			continue
+		}
+		message, ok = fill(message, code)
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(code.Code, "PRO_"):
+			text.Pros = append(text.Pros, message)
+		case strings.HasPrefix(code.Code, "CON_"):
+			text.Cons = append(text.Cons, message)
+		default:
+			reasoning = append(reasoning, message)
+		}
+	}
+	text.Reasoning = strings.Join(reasoning, " ")
+	return text
+}
+
+// fill replaces each {name} in message with the code's param, reporting false when a
+// param is missing
+func fill(message string, code models.ReasonCode) (string, bool) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(message, '{')
+		if start < 0 {
+			b.WriteString(message)
+			return b.String(), true
+		}
+		end := strings.IndexByte(message[start:], '}')
+		if end < 0 {
+			b.WriteString(message)
+			return b.String(), true
+		}
+		value := code.Param(message[start+1 : start+end])
+		if value == "" {
+			return "", false
+		}
+		b.WriteString(message[:start])
+		b.WriteString(value)
+		message = message[start+end+1:]
+	}
+}
+
+// ReasonCodes returns the recommendation's reason codes, deriving them from the plan
+// itself for recommendations planned before the AI service wrote any
+func ReasonCodes(rec *models.CommuteRecommendation) []models.ReasonCode {
+	if len(rec.ReasonCodes) > 0 {
+		return rec.ReasonCodes
+	}
+	officeMeetings, remoteMeetings := count(rec.OfficeMeetings), count(rec.RemoteMeetings)
+	commuteMinutes := minutes(rec.CommuteStart, rec.OfficeArrival) + minutes(rec.OfficeDeparture, rec.CommuteEnd)
+
+	var codes []models.ReasonCode
+	switch rec.OptionRank {
+	case 1:
+		codes = append(codes, reason("RECOMMENDED"))
+	case 2:
+		codes = append(codes, reason("ALTERNATIVE"))
+	default:
+		codes = append(codes, reason("OPTION_RANK", param("rank", rec.OptionRank)))
+	}
+	switch rec.OptionType {
+	case models.CommuteOptionFullRemoteRecommended:
+		codes = append(codes, reason("FULL_REMOTE", param("remoteMeetings", remoteMeetings)))
+	case models.CommuteOptionFullDayOffice:
+		codes = append(codes, reason("FULL_DAY_OFFICE", param("officeMeetings", officeMeetings)))
+	case models.CommuteOptionStrategicAfternoon:
+		codes = append(codes, reason("STRATEGIC_AFTERNOON", param("officeMeetings", officeMeetings), param("remoteMeetings", remoteMeetings)))
+	default:
+		codes = append(codes, reason("PARTIAL_OFFICE", param("officeMeetings", officeMeetings), param("remoteMeetings", remoteMeetings)))
+	}
+
+	if rec.OptionType == models.CommuteOptionFullRemoteRecommended {
+		return append(codes,
+			reason("PRO_NO_COMMUTE"), reason("PRO_FLEXIBILITY"), reason("PRO_WORK_LIFE_BALANCE"), reason("PRO_NO_TRAVEL_EMISSIONS"),
+			reason("CON_LIMITED_FACE_TIME"), reason("CON_VISIBILITY_RISK"), reason("CON_MISSED_COLLABORATION"))
+	}
+	codes = append(codes,
+		reason("PRO_IN_PERSON_MEETINGS", param("officeMeetings", officeMeetings)),
+		reason("PRO_VISIBILITY"), reason("PRO_SPONTANEOUS_COLLABORATION"), reason("PRO_OFFICE_RESOURCES"),
+		reason("CON_COMMUTE_TIME", param("commuteMinutes", commuteMinutes)),
+		reason("CON_COMMUTE_COSTS"), reason("CON_LESS_FLEXIBILITY"))
+	switch rec.OptionType {
+	case models.CommuteOptionFullDayOffice:
+		codes = append(codes, reason("PRO_MAX_COLLABORATION"), reason("CON_LONG_DAY"))
+	case models.CommuteOptionStrategicAfternoon:
+		codes = append(codes, reason("PRO_BALANCE"), reason("CON_SPLIT_DAY"))
+	}
+	return codes
+}
+
+// reason builds a reason code
+func reason(code string, params ...models.ReasonParam) models.ReasonCode {
+	return models.ReasonCode{Code: code, Params: append([]models.ReasonParam{}, params...)}
+}
+
+// param is a reason's integer param
+func param(name string, value int) models.ReasonParam {
+	return models.ReasonParam{Name: name, Value: strconv.Itoa(value)}
+}
+
+// minutes is the whole minutes from a to b, 0 when either is unset
+func minutes(a, b *time.Time) int {
+	if a == nil || b == nil || b.Before(*a) {
+		return 0
+	}
+	return int(b.Sub(*a).Minutes())
+}
+
+// count returns the length of a JSON array column, 0 when it's unset or malformed
+func count(data *string) int {
+	if data == nil {
+		return 0
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal([]byte(*data), &items); err != nil {
+		return 0
+	}
+	return len(items)
+}
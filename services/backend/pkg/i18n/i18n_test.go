@@ -0,0 +1,95 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestCatalogComplete(t *testing.T) {
+	if len(catalog) != len(Locales) {
+		t.Errorf("catalog has %d locales, Locales %d", len(catalog), len(Locales))
+	}
+	for _, locale := range Locales {
+		messages, ok := catalog[locale]
+		if !ok {
+			t.Errorf("no catalog for %s", locale)
+			continue
+		}
+		for code, english := range catalog[DefaultLocale] {
+			message, ok := messages[code]
+			if !ok {
+				t.Errorf("%s: no message for %s", locale, code)
+				continue
+			}
+			// Translations fill in the same params
+			if strings.Count(message, "{") != strings.Count(english, "{") {
+				t.Errorf("%s: %s = %q, want the params of %q", locale, code, message, english)
+			}
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for tag, want := range map[string]string{"de": "de", "de-AT": "de", "fr_CA": "fr", " ES ": "es", "pt-BR": "", "": ""} {
+		got, ok := Match(tag)
+		if got != want || ok != (want != "") {
+			t.Errorf("Match(%q) = %q, %v, want %q", tag, got, ok, want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	codes := []models.ReasonCode{
+		{Code: "RECOMMENDED"},
+		{Code: "FULL_DAY_OFFICE", Params: []models.ReasonParam{{Name: "officeMeetings", Value: "3"}}},
+		// Missing its param, so left out
+		{Code: "COMMUTE_SHARE"},
+		{Code: "SOMETHING_NEW"},
+		{Code: "PRO_VISIBILITY"},
+		{Code: "CON_COMMUTE_TIME", Params: []models.ReasonParam{{Name: "commuteMinutes", Value: "95"}}},
+	}
+	text := Render("de-DE", codes)
+	want := "Empfohlene Option: Ein ganzer Tag im Büro bringt die meiste Sichtbarkeit und gemeinsame Zeit und deckt alle 3 Präsenzmeetings ab."
+	if text.Locale != "de" || text.Reasoning != want {
+		t.Errorf("reasoning = %q (%s), want %q", text.Reasoning, text.Locale, want)
+	}
+	if len(text.Pros) != 1 || text.Pros[0] != "Hohe Sichtbarkeit und professionelle Präsenz" {
+		t.Errorf("pros = %q", text.Pros)
+	}
+	if len(text.Cons) != 1 || text.Cons[0] != "Insgesamt 95 Minuten Pendelzeit" {
+		t.Errorf("cons = %q", text.Cons)
+	}
+
+	if text := Render("pt", codes[:1]); text.Locale != DefaultLocale || text.Reasoning != "Recommended option:" {
+		t.Errorf("unsupported locale rendered %+v, want English", text)
+	}
+}
+
+func TestReasonCodes(t *testing.T) {
+	stored := []models.ReasonCode{{Code: "RECOMMENDED"}}
+	if codes := ReasonCodes(&models.CommuteRecommendation{ReasonCodes: stored}); len(codes) != 1 {
+		t.Errorf("codes = %+v, want the stored ones", codes)
+	}
+
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) *time.Time {
+		t := day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		return &t
+	}
+	office, remote := `["m1","m2"]`, `["m3"]`
+	rec := &models.CommuteRecommendation{
+		OptionRank: 3, OptionType: models.CommuteOptionStrategicAfternoon,
+		OfficeMeetings: &office, RemoteMeetings: &remote,
+		CommuteStart: at(12, 15), OfficeArrival: at(13, 0), OfficeDeparture: at(17, 0), CommuteEnd: at(17, 50),
+	}
+	text := Render("fr", ReasonCodes(rec))
+	if !strings.HasPrefix(text.Reasoning, "Option n° 3 : Un après-midi au bureau couvre 2 réunions clés et laisse 1 autres") {
+		t.Errorf("reasoning = %q", text.Reasoning)
+	}
+	if len(text.Pros) != 5 || len(text.Cons) != 4 || text.Cons[0] != "95 minutes de trajet au total" {
+		t.Errorf("trade-offs = %q, %q", text.Pros, text.Cons)
+	}
+}
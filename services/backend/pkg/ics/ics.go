@@ -0,0 +1,101 @@
+// Package ics writes iCalendar (RFC 5545) feeds that calendar apps subscribe to, and
+// reads the calendars users import
+package ics
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest a content line may be before it's folded
+const maxLineOctets = 75
+
+// Event is a VEVENT
+type Event struct {
+	// UID identifies the event across fetches, so apps update it rather than duplicate it
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	// Start and End are instants, or dates for all-day events (End exclusive)
+	Start, End time.Time
+	AllDay     bool
+	// Free events don't block time in the subscriber's calendar
+	Free bool
+	// Stamp is when the event was last changed
+	Stamp time.Time
+	// Recurring is set on parsed events with a recurrence rule, which isn't expanded
+	Recurring bool
+}
+
+// Write writes a calendar named name holding events
+func Write(w io.Writer, name string, events []Event) error {
+	out := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(out, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Commute Planner//Commute Plan Feed//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", escape(name))
+	for _, event := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(event.UID))
+		line("DTSTAMP", utc(event.Stamp))
+		if event.AllDay {
+			line("DTSTART;VALUE=DATE", event.Start.Format("20060102"))
+			line("DTEND;VALUE=DATE", event.End.Format("20060102"))
+		} else {
+			line("DTSTART", utc(event.Start))
+			line("DTEND", utc(event.End))
+		}
+		line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION", escape(event.Description))
+		}
+		if event.Location != "" {
+			line("LOCATION", escape(event.Location))
+		}
+		if event.Free {
+			line("TRANSP", "TRANSPARENT")
+		} else {
+			line("TRANSP", "OPAQUE")
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return out.Flush()
+}
+
+func utc(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escape escapes a TEXT value
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// writeLine writes a content line with CRLF, folding it into lines of at most 75 octets
+// without splitting a UTF-8 character
+func writeLine(out *bufio.Writer, content string) {
+	limit := maxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		out.WriteString(content[:cut])
+		out.WriteString("\r\n ")
+		content = content[cut:]
+		// Continuation lines start with a space, which counts towards their length
+		limit = maxLineOctets - 1
+	}
+	out.WriteString(content)
+	out.WriteString("\r\n")
+}
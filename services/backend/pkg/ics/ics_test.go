@@ -0,0 +1,148 @@
+package ics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWrite(t *testing.T) {
+	start := time.Date(2026, 3, 2, 8, 10, 0, 0, time.FixedZone("CET", 3600))
+	events := []Event{
+		{
+			UID:     "rec-1-office@commute-planner",
+			Summary: "Office day",
+			Start:   time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			End:     time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+			AllDay:  true,
+			Free:    true,
+		},
+		{
+			UID:         "rec-1-leg-0@commute-planner",
+			Summary:     "Commute: home → office, by car",
+			Description: "Leave at 08:10; " + strings.Repeat("é", 60),
+			Start:       start,
+			End:         start.Add(45 * time.Minute),
+			Stamp:       start,
+		},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, "Commute plan", events); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART;VALUE=DATE:20260302\r\nDTEND;VALUE=DATE:20260303\r\n",
+		"TRANSP:TRANSPARENT\r\n",
+		"DTSTART:20260302T071000Z\r\nDTEND:20260302T075500Z\r\n",
+		`SUMMARY:Commute: home → office\, by car`,
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("feed lacks %q:\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("folding split a character: %q", line)
+		}
+	}
+	// Unfolding restores the description
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	if !strings.Contains(unfolded, "DESCRIPTION:Leave at 08:10\\; "+strings.Repeat("é", 60)+"\r\n") {
+		t.Errorf("folded description doesn't unfold:\n%s", unfolded)
+	}
+}
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone data")
+	}
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Berlin",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"SUMMARY:Standup\\, daily",
+		"DTSTART;TZID=Europe/Berlin:20260302T093000",
+		"DURATION:PT15M",
+		"RRULE:FREQ=DAILY",
+		"BEGIN:VALARM",
+		"DESCRIPTION:Reminder",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:offsite@example.com",
+		"SUMMARY:Offsite",
+		"DESCRIPTION:Bring a laptop\\nand a charger; lunch is provi",
+		" ded",
+		"DTSTART;VALUE=DATE:20260303",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:cancelled@example.com",
+		"SUMMARY:Cancelled",
+		"DTSTART:20260304T090000Z",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:floating@example.com",
+		"SUMMARY:Lunch",
+		"DTSTART:20260305T120000",
+		"DTEND:20260305T130000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := Parse(strings.NewReader(calendar), berlin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("events = %+v, want three", events)
+	}
+	standup := events[0]
+	if standup.Summary != "Standup, daily" || !standup.Recurring || standup.Description != "" {
+		t.Errorf("standup = %+v", standup)
+	}
+	if want := time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC); !standup.Start.Equal(want) || !standup.End.Equal(want.Add(15*time.Minute)) {
+		t.Errorf("standup runs %v to %v", standup.Start, standup.End)
+	}
+	offsite := events[1]
+	if !offsite.AllDay || !offsite.Free || offsite.Description != "Bring a laptop\nand a charger; lunch is provided" {
+		t.Errorf("offsite = %+v", offsite)
+	}
+	if !offsite.End.Equal(time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("offsite ends %v, want the next day", offsite.End)
+	}
+	if lunch := events[2]; !lunch.Start.Equal(time.Date(2026, 3, 5, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("floating lunch starts %v, want it read in Berlin", lunch.Start)
+	}
+
+	// What Write writes, Parse reads
+	var buf bytes.Buffer
+	written := []Event{{UID: "a@x", Summary: "A; b, c", Start: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), End: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)}}
+	if err := Write(&buf, "Plans", written); err != nil {
+		t.Fatal(err)
+	}
+	if read, err := Parse(&buf, time.UTC); err != nil || len(read) != 1 || read[0].Summary != "A; b, c" || !read[0].End.Equal(written[0].End) {
+		t.Errorf("round trip = %+v, %v", read, err)
+	}
+
+	for _, bad := range []string{"", "BEGIN:VCARD\r\nEND:VCARD", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n"} {
+		if _, err := Parse(strings.NewReader(bad), time.UTC); err == nil {
+			t.Errorf("Parse(%q): no error", bad)
+		}
+	}
+}
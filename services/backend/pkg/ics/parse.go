@@ -0,0 +1,224 @@
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationPattern matches the DURATION values events use, e.g. PT1H30M or P1D
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// Parse reads the events of an iCalendar file. Times without a timezone are read in
+// location. Cancelled events are left out; an event whose times can't be read keeps
+// zero times, for the caller to reject on its own.
+func Parse(r io.Reader, location *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("not an iCalendar file: expected BEGIN:VCALENDAR")
+	}
+
+	var (
+		events    []Event
+		event     *Event
+		cancelled bool
+		duration  *time.Duration
+		hasEnd    bool
+		// components are the components the current line is nested in, e.g. VEVENT, VALARM
+		components []string
+	)
+	for _, line := range lines {
+		name, params, value := split(line)
+		switch name {
+		case "BEGIN":
+			components = append(components, strings.ToUpper(value))
+			if len(components) == 2 && components[1] == "VEVENT" {
+				event, cancelled, duration, hasEnd = &Event{}, false, nil, false
+			}
+			continue
+		case "END":
+			if len(components) == 0 {
+				return nil, fmt.Errorf("unexpected END:%s", value)
+			}
+			if len(components) == 2 && components[1] == "VEVENT" && event != nil {
+				if !hasEnd && !event.Start.IsZero() {
+					switch {
+					case duration != nil:
+						event.End = event.Start.Add(*duration)
+					case event.AllDay:
+						event.End = event.Start.AddDate(0, 0, 1)
+					default:
+						event.End = event.Start
+					}
+				}
+				if !cancelled {
+					events = append(events, *event)
+				}
+				event = nil
+			}
+			components = components[:len(components)-1]
+			continue
+		}
+		// Only the event's own properties count, not those of its alarms
+		if event == nil || len(components) != 2 {
+			continue
+		}
+		switch name {
+		case "UID":
+			event.UID = value
+		case "SUMMARY":
+			event.Summary = unescape(value)
+		case "DESCRIPTION":
+			event.Description = unescape(value)
+		case "LOCATION":
+			event.Location = unescape(value)
+		case "DTSTART":
+			event.Start, event.AllDay = parseTime(value, params, location)
+		case "DTEND":
+			event.End, _ = parseTime(value, params, location)
+			hasEnd = true
+		case "DURATION":
+			if d, ok := parseDuration(value); ok {
+				duration = &d
+			}
+		case "DTSTAMP", "LAST-MODIFIED":
+			if stamp, _ := parseTime(value, params, location); stamp.After(event.Stamp) {
+				event.Stamp = stamp
+			}
+		case "TRANSP":
+			event.Free = strings.EqualFold(value, "TRANSPARENT")
+		case "STATUS":
+			cancelled = strings.EqualFold(value, "CANCELLED")
+		case "RRULE", "RDATE":
+			event.Recurring = true
+		}
+	}
+	if len(components) != 0 {
+		return nil, fmt.Errorf("unexpected end of file in %s", components[len(components)-1])
+	}
+	return events, nil
+}
+
+// unfold reads the content lines of r, joining folded lines
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// split splits a content line into its upper-case name, its parameters (names upper-case,
+// values unquoted) and its value
+func split(line string) (name string, params map[string]string, value string) {
+	params = map[string]string{}
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return strings.ToUpper(line), params, ""
+	}
+	head := strings.Split(line[:colon], ";")
+	for _, param := range head[1:] {
+		if key, val, ok := strings.Cut(param, "="); ok {
+			params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+		}
+	}
+	return strings.ToUpper(head[0]), params, line[colon+1:]
+}
+
+// parseTime reads a DATE or DATE-TIME value, reporting whether it's a date. Dates are
+// midnight UTC; the zero time means it couldn't be read.
+func parseTime(value string, params map[string]string, location *time.Location) (time.Time, bool) {
+	if strings.EqualFold(params["VALUE"], "DATE") || len(value) == 8 {
+		date, err := time.Parse("20060102", value)
+		if err != nil {
+			return time.Time{}, true
+		}
+		return date, true
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, false
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			location = zone
+		}
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, false
+}
+
+// parseDuration reads a DURATION value
+func parseDuration(value string) (time.Duration, bool) {
+	m := durationPattern.FindStringSubmatch(strings.ToUpper(value))
+	if m == nil {
+		return 0, false
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+2] != "" {
+			n, _ := strconv.Atoi(m[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, true
+}
+
+// unescape reverses escape
+func unescape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
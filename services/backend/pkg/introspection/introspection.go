@@ -0,0 +1,345 @@
+// Package introspection answers GraphQL introspection queries (__schema, __type and the
+// root __typename) from the API's SDL, so tooling such as GraphQL Codegen and Postman can
+// pull the contract without a hand-written copy.
+package introspection
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// lazy describes a type when a query selects it; types refer to each other in cycles
+type lazy func() map[string]interface{}
+
+// Schema is a parsed GraphQL schema
+type Schema struct {
+	sdl    string
+	schema *ast.Schema
+}
+
+// Load parses and validates sdl
+func Load(sdl string) (*Schema, error) {
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: "schema.graphql", Input: sdl})
+	if err != nil {
+		return nil, fmt.Errorf("invalid GraphQL schema: %w", err)
+	}
+	return &Schema{sdl: sdl, schema: schema}, nil
+}
+
+// SDL returns the schema as it was loaded
+func (s *Schema) SDL() string {
+	return s.sdl
+}
+
+var typeField = regexp.MustCompile(`__type\s*\(`)
+
+// IsQuery reports whether query introspects the schema
+func IsQuery(query string) bool {
+	return strings.Contains(query, "__schema") || typeField.MatchString(query)
+}
+
+// Execute runs an introspection query. Its root fields may only be __schema, __type and
+// __typename; the query is validated against the schema like any other.
+func (s *Schema) Execute(query, operationName string, variables map[string]interface{}) (map[string]interface{}, error) {
+	doc, errs := gqlparser.LoadQuery(s.schema, query)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	op := doc.Operations.ForName(operationName)
+	if op == nil {
+		return nil, fmt.Errorf("unknown operation %q", operationName)
+	}
+	if op.Operation != ast.Query {
+		return nil, fmt.Errorf("introspection must be a query, not a %s", op.Operation)
+	}
+
+	data := map[string]interface{}{}
+	for _, field := range fields(op.SelectionSet) {
+		var value interface{}
+		switch field.Name {
+		case "__schema":
+			value = s.project(s.describeSchema(), field.SelectionSet, variables)
+		case "__type":
+			name, err := field.Arguments.ForName("name").Value.Value(variables)
+			if err != nil {
+				return nil, err
+			}
+			if def := s.schema.Types[fmt.Sprint(name)]; def != nil {
+				value = s.project(s.describeType(def), field.SelectionSet, variables)
+			}
+		case "__typename":
+			value = s.schema.Query.Name
+		default:
+			return nil, fmt.Errorf("%s can't be queried with introspection", field.Name)
+		}
+		data[field.Alias] = value
+	}
+	return data, nil
+}
+
+// fields flattens a selection set's fragments into its fields
+func fields(set ast.SelectionSet) []*ast.Field {
+	var flat []*ast.Field
+	for _, selection := range set {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			flat = append(flat, selection)
+		case *ast.InlineFragment:
+			flat = append(flat, fields(selection.SelectionSet)...)
+		case *ast.FragmentSpread:
+			flat = append(flat, fields(selection.Definition.SelectionSet)...)
+		}
+	}
+	return flat
+}
+
+// project picks the fields of set from a described value, recursing into objects and
+// lists. Deprecated fields, arguments and enum values are left out unless the query asks
+// for them with includeDeprecated: true.
+func (s *Schema) project(value interface{}, set ast.SelectionSet, variables map[string]interface{}) interface{} {
+	switch value := value.(type) {
+	case lazy:
+		return s.project(value(), set, variables)
+	case []lazy:
+		projected := make([]interface{}, len(value))
+		for i, item := range value {
+			projected[i] = s.project(item(), set, variables)
+		}
+		return projected
+	case []map[string]interface{}:
+		projected := make([]interface{}, len(value))
+		for i, item := range value {
+			projected[i] = s.project(item, set, variables)
+		}
+		return projected
+	case map[string]interface{}:
+		projected := map[string]interface{}{}
+		for _, field := range fields(set) {
+			if field.Name == "__typename" {
+				projected[field.Alias] = value["__typename"]
+				continue
+			}
+			child := value[field.Name]
+			if items, ok := child.([]map[string]interface{}); ok && !includeDeprecated(field, variables) {
+				child = current(items)
+			}
+			projected[field.Alias] = s.project(child, field.SelectionSet, variables)
+		}
+		return projected
+	default:
+		return value
+	}
+}
+
+func includeDeprecated(field *ast.Field, variables map[string]interface{}) bool {
+	arg := field.Arguments.ForName("includeDeprecated")
+	if arg == nil {
+		return false
+	}
+	include, _ := arg.Value.Value(variables)
+	return include == true
+}
+
+// current drops the deprecated items of a list
+func current(items []map[string]interface{}) []map[string]interface{} {
+	kept := []map[string]interface{}{}
+	for _, item := range items {
+		if item["isDeprecated"] != true {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func (s *Schema) describeSchema() map[string]interface{} {
+	names := make([]string, 0, len(s.schema.Types))
+	for name := range s.schema.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	types := make([]map[string]interface{}, len(names))
+	for i, name := range names {
+		types[i] = s.describeType(s.schema.Types[name])
+	}
+
+	directiveNames := make([]string, 0, len(s.schema.Directives))
+	for name := range s.schema.Directives {
+		directiveNames = append(directiveNames, name)
+	}
+	sort.Strings(directiveNames)
+	directives := make([]map[string]interface{}, len(directiveNames))
+	for i, name := range directiveNames {
+		directive := s.schema.Directives[name]
+		locations := make([]string, len(directive.Locations))
+		for j, location := range directive.Locations {
+			locations[j] = string(location)
+		}
+		directives[i] = map[string]interface{}{
+			"__typename":   "__Directive",
+			"name":         directive.Name,
+			"description":  description(directive.Description),
+			"locations":    locations,
+			"args":         s.describeArguments(directive.Arguments),
+			"isRepeatable": directive.IsRepeatable,
+		}
+	}
+
+	root := func(def *ast.Definition) interface{} {
+		if def == nil {
+			return nil
+		}
+		return s.ref(def)
+	}
+	return map[string]interface{}{
+		"__typename":       "__Schema",
+		"description":      nil,
+		"queryType":        root(s.schema.Query),
+		"mutationType":     root(s.schema.Mutation),
+		"subscriptionType": root(s.schema.Subscription),
+		"types":            types,
+		"directives":       directives,
+	}
+}
+
+func (s *Schema) describeType(def *ast.Definition) map[string]interface{} {
+	described := map[string]interface{}{
+		"__typename":     "__Type",
+		"kind":           string(def.Kind),
+		"name":           def.Name,
+		"description":    description(def.Description),
+		"specifiedByURL": nil,
+		"fields":         nil,
+		"inputFields":    nil,
+		"interfaces":     nil,
+		"enumValues":     nil,
+		"possibleTypes":  nil,
+		"ofType":         nil,
+	}
+	switch def.Kind {
+	case ast.Object, ast.Interface:
+		fields := []map[string]interface{}{}
+		for _, field := range def.Fields {
+			if strings.HasPrefix(field.Name, "__") {
+				continue
+			}
+			reason := deprecation(field.Directives)
+			fields = append(fields, map[string]interface{}{
+				"__typename":        "__Field",
+				"name":              field.Name,
+				"description":       description(field.Description),
+				"args":              s.describeArguments(field.Arguments),
+				"type":              s.describeTypeRef(field.Type),
+				"isDeprecated":      reason != nil,
+				"deprecationReason": reason,
+			})
+		}
+		described["fields"] = fields
+		interfaces := []lazy{}
+		for _, name := range def.Interfaces {
+			interfaces = append(interfaces, s.ref(s.schema.Types[name]))
+		}
+		described["interfaces"] = interfaces
+	case ast.InputObject:
+		inputFields := []map[string]interface{}{}
+		for _, field := range def.Fields {
+			inputFields = append(inputFields, s.describeInputValue(field.Name, field.Description, field.Type, field.DefaultValue, field.Directives))
+		}
+		described["inputFields"] = inputFields
+	case ast.Enum:
+		values := []map[string]interface{}{}
+		for _, value := range def.EnumValues {
+			reason := deprecation(value.Directives)
+			values = append(values, map[string]interface{}{
+				"__typename":        "__EnumValue",
+				"name":              value.Name,
+				"description":       description(value.Description),
+				"isDeprecated":      reason != nil,
+				"deprecationReason": reason,
+			})
+		}
+		described["enumValues"] = values
+	}
+	if def.Kind == ast.Interface || def.Kind == ast.Union {
+		possible := []lazy{}
+		for _, member := range s.schema.GetPossibleTypes(def) {
+			possible = append(possible, s.ref(member))
+		}
+		described["possibleTypes"] = possible
+	}
+	return described
+}
+
+// ref describes def when it's selected
+func (s *Schema) ref(def *ast.Definition) lazy {
+	return func() map[string]interface{} { return s.describeType(def) }
+}
+
+// describeTypeRef describes a field's type, wrapping non-null and list types around the
+// named type
+func (s *Schema) describeTypeRef(t *ast.Type) interface{} {
+	switch {
+	case t.NonNull:
+		inner := *t
+		inner.NonNull = false
+		return map[string]interface{}{"__typename": "__Type", "kind": "NON_NULL", "name": nil, "ofType": s.describeTypeRef(&inner)}
+	case t.Elem != nil:
+		return map[string]interface{}{"__typename": "__Type", "kind": "LIST", "name": nil, "ofType": s.describeTypeRef(t.Elem)}
+	}
+	return s.ref(s.schema.Types[t.NamedType])
+}
+
+func (s *Schema) describeArguments(args ast.ArgumentDefinitionList) []map[string]interface{} {
+	described := []map[string]interface{}{}
+	for _, arg := range args {
+		described = append(described, s.describeInputValue(arg.Name, arg.Description, arg.Type, arg.DefaultValue, arg.Directives))
+	}
+	return described
+}
+
+func (s *Schema) describeInputValue(name, desc string, t *ast.Type, defaultValue *ast.Value, directives ast.DirectiveList) map[string]interface{} {
+	var value interface{}
+	if defaultValue != nil {
+		value = defaultValue.String()
+	}
+	reason := deprecation(directives)
+	return map[string]interface{}{
+		"__typename":        "__InputValue",
+		"name":              name,
+		"description":       description(desc),
+		"type":              s.describeTypeRef(t),
+		"defaultValue":      value,
+		"isDeprecated":      reason != nil,
+		"deprecationReason": reason,
+	}
+}
+
+// deprecation returns the reason of an @deprecated directive, or nil. A @sunset date is
+// appended, since introspection has no field of its own for it.
+func deprecation(directives ast.DirectiveList) interface{} {
+	directive := directives.ForName("deprecated")
+	if directive == nil {
+		return nil
+	}
+	reason := "No longer supported"
+	if arg := directive.Arguments.ForName("reason"); arg != nil {
+		reason = arg.Value.Raw
+	}
+	if sunset := directives.ForName("sunset"); sunset != nil {
+		if date := sunset.Arguments.ForName("date"); date != nil {
+			reason += " (removed on " + date.Value.Raw + ")"
+		}
+	}
+	return reason
+}
+
+func description(text string) interface{} {
+	if text == "" {
+		return nil
+	}
+	return text
+}
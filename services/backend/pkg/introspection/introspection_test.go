@@ -0,0 +1,137 @@
+package introspection
+
+import (
+	"encoding/json"
+	"testing"
+
+	backend "github.com/commute-planner/backend"
+)
+
+// fullQuery is the query graphql-js's getIntrospectionQuery builds, which GraphQL Codegen
+// and Postman send
+const fullQuery = `
+query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives { name description locations args { ...InputValue } }
+  }
+}
+fragment FullType on __Type {
+  kind name description
+  fields(includeDeprecated: true) {
+    name description
+    args { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated deprecationReason
+  }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+  possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue { name description type { ...TypeRef } defaultValue }
+fragment TypeRef on __Type {
+  kind name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}`
+
+func TestExecute(t *testing.T) {
+	schema, err := Load(backend.Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := schema.Execute(fullQuery, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Schema struct {
+			QueryType struct{ Name string }
+			Types     []struct {
+				Kind   string
+				Name   string
+				Fields []struct {
+					Name string
+					Type struct {
+						Kind   string
+						OfType *struct{ Kind, Name string }
+					}
+				}
+			}
+		} `json:"__schema"`
+	}
+	encoded, _ := json.Marshal(data)
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Schema.QueryType.Name != "Query" {
+		t.Errorf("queryType = %q, want Query", result.Schema.QueryType.Name)
+	}
+	var health bool
+	for _, typ := range result.Schema.Types {
+		if typ.Name != "Query" {
+			continue
+		}
+		for _, field := range typ.Fields {
+			if field.Name == "health" {
+				health = field.Type.Kind == "NON_NULL" && field.Type.OfType != nil && field.Type.OfType.Name == "String"
+			}
+		}
+	}
+	if !health {
+		t.Error("Query.health isn't described as String!")
+	}
+
+	// __type by variable, with aliases and only the selected fields
+	data, err = schema.Execute(`query($name: String!) { job: __type(name: $name) { name kind } root: __typename }`, "",
+		map[string]interface{}{"name": "JobStatus"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ = json.Marshal(data)
+	if got, want := string(encoded), `{"job":{"kind":"ENUM","name":"JobStatus"},"root":"Query"}`; got != want {
+		t.Errorf("__type = %s, want %s", got, want)
+	}
+
+	if _, err := schema.Execute(`{ __schema { nope } }`, "", nil); err == nil {
+		t.Error("invalid introspection query accepted")
+	}
+	if _, err := schema.Execute(`{ health __schema { queryType { name } } }`, "", nil); err == nil {
+		t.Error("introspection mixed with other fields accepted")
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	schema, err := Load(`
+type Query {
+  plan: String
+  legacyPlan: String @deprecated(reason: "Use plan") @sunset(date: "2026-12-01")
+}
+
+directive @sunset(date: String!) on FIELD_DEFINITION`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := func(query string) int {
+		data, err := schema.Execute(query, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(data["__type"].(map[string]interface{})["fields"].([]interface{}))
+	}
+	if n := count(`{ __type(name: "Query") { fields { name } } }`); n != 1 {
+		t.Errorf("%d fields without includeDeprecated, want 1", n)
+	}
+	if n := count(`{ __type(name: "Query") { fields(includeDeprecated: true) { name deprecationReason } } }`); n != 2 {
+		t.Errorf("%d fields with includeDeprecated, want 2", n)
+	}
+	data, _ := schema.Execute(`{ __type(name: "Query") { fields(includeDeprecated: true) { deprecationReason } } }`, "", nil)
+	legacy := data["__type"].(map[string]interface{})["fields"].([]interface{})[1].(map[string]interface{})
+	if reason := legacy["deprecationReason"]; reason != "Use plan (removed on 2026-12-01)" {
+		t.Errorf("deprecation reason = %v", reason)
+	}
+}
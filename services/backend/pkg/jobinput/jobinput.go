@@ -0,0 +1,143 @@
+// Package jobinput versions the inputData a job hands the AI worker. Each payload says
+// which version of the format it follows in schemaVersion, each version has a JSON Schema
+// in schemas/, and payloads of older versions are upgraded to the current one, so the
+// backend and the worker can be deployed independently.
+//
+// Adding an optional key the worker may ignore doesn't need a new version. Renaming,
+// removing or changing the meaning of one does: add the new version's schema and an
+// upgrade from the previous one here, and deploy the matching upgrade in the worker
+// (ai-service/utils/job_input.py) before the backend starts writing it.
+package jobinput
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/jsonschema"
+)
+
+// CurrentVersion is the version jobs are created with
+const CurrentVersion = 2
+
+// VersionKey is the key of the version in the payload
+const VersionKey = "schemaVersion"
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// schemas are the schemas of every version, by version
+var schemas = map[int]*jsonschema.Schema{}
+
+// upgrades turn a payload of the version they're keyed by into one of the next version
+var upgrades = map[int]func(map[string]interface{}) map[string]interface{}{
+	1: upgradeV1,
+}
+
+func init() {
+	for version := 1; version <= CurrentVersion; version++ {
+		data, err := schemaFiles.ReadFile(fmt.Sprintf("schemas/v%d.json", version))
+		if err != nil {
+			panic(fmt.Sprintf("jobinput: no schema for version %d: %v", version, err))
+		}
+		s, err := jsonschema.Parse(data)
+		if err != nil {
+			panic(fmt.Sprintf("jobinput: invalid schema for version %d: %v", version, err))
+		}
+		schemas[version] = s
+	}
+}
+
+// Normalize upgrades inputData to the current version and validates it. Empty input data
+// is an empty payload; input data that isn't a JSON object is kept as raw_input, the way
+// the worker always read it.
+func Normalize(inputData *string) (*string, error) {
+	data, err := decode(inputData)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job input: %w", err)
+	}
+	normalized := string(encoded)
+	return &normalized, nil
+}
+
+// Validate checks that inputData is a payload of the current version
+func Validate(inputData *string) error {
+	if inputData == nil {
+		return fmt.Errorf("invalid job input: empty")
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(*inputData), &data); err != nil {
+		return fmt.Errorf("invalid job input: %w", err)
+	}
+	if err := schemas[CurrentVersion].Validate(data, "inputData"); err != nil {
+		return fmt.Errorf("invalid job input: %w", err)
+	}
+	return nil
+}
+
+// decode reads inputData as a payload of the current version
+func decode(inputData *string) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	if inputData != nil && strings.TrimSpace(*inputData) != "" {
+		var value interface{}
+		if err := json.Unmarshal([]byte(*inputData), &value); err != nil {
+			data["raw_input"] = *inputData
+		} else if object, ok := value.(map[string]interface{}); ok {
+			data = object
+		} else if text, ok := value.(string); ok {
+			data["raw_input"] = text
+		} else {
+			data["raw_input"] = *inputData
+		}
+	}
+
+	version, err := Version(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := schemas[version].Validate(data, "inputData"); err != nil {
+		return nil, fmt.Errorf("invalid inputData for schema version %d: %w", version, err)
+	}
+	for ; version < CurrentVersion; version++ {
+		data = upgrades[version](data)
+	}
+	if err := schemas[CurrentVersion].Validate(data, "inputData"); err != nil {
+		return nil, fmt.Errorf("invalid inputData: %w", err)
+	}
+	return data, nil
+}
+
+// Version returns the version of a decoded payload: its schemaVersion, or 1 without one.
+// Versions newer than CurrentVersion are an error; this server can't read them.
+func Version(data map[string]interface{}) (int, error) {
+	value, ok := data[VersionKey]
+	if !ok {
+		return 1, nil
+	}
+	n, ok := value.(float64)
+	if !ok || n != float64(int(n)) || n < 1 {
+		return 0, fmt.Errorf("invalid %s %v: expected a positive integer", VersionKey, value)
+	}
+	if int(n) > CurrentVersion {
+		return 0, fmt.Errorf("unsupported %s %d: this server reads up to version %d", VersionKey, int(n), CurrentVersion)
+	}
+	return int(n), nil
+}
+
+// upgradeV1 adds the version, a float64 like the rest of the decoded payload. A context
+// that isn't an object was always replaced by the backend's and never read by the
+// worker, so it is dropped.
+func upgradeV1(data map[string]interface{}) map[string]interface{} {
+	if context, ok := data["context"]; ok {
+		if _, ok := context.(map[string]interface{}); !ok {
+			delete(data, "context")
+		}
+	}
+	data[VersionKey] = float64(2)
+	return data
+}
@@ -0,0 +1,59 @@
+package jobinput
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, tt := range []struct{ name, input, want string }{
+		{"empty", "", `{"schemaVersion":2}`},
+		{"v1 object", `{"mode":"fast","context":{"user_timezone":"Europe/Berlin"}}`,
+			`{"context":{"user_timezone":"Europe/Berlin"},"mode":"fast","schemaVersion":2}`},
+		{"v1 text", "plan my day", `{"raw_input":"plan my day","schemaVersion":2}`},
+		{"v1 JSON string", `"plan my day"`, `{"raw_input":"plan my day","schemaVersion":2}`},
+		{"v1 list", `[1,2]`, `{"raw_input":"[1,2]","schemaVersion":2}`},
+		{"v1 context not an object", `{"context":"office"}`, `{"schemaVersion":2}`},
+		{"v2", `{"schemaVersion":2,"context":{"focus":{"minimumMinutes":60,"free":null}}}`,
+			`{"context":{"focus":{"free":null,"minimumMinutes":60}},"schemaVersion":2}`},
+	} {
+		input := tt.input
+		got, err := Normalize(&input)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, *got, tt.want)
+		}
+		if err := Validate(got); err != nil {
+			t.Errorf("%s: normalized input invalid: %v", tt.name, err)
+		}
+	}
+	if got, err := Normalize(nil); err != nil || *got != `{"schemaVersion":2}` {
+		t.Errorf("Normalize(nil) = %v, %v", got, err)
+	}
+
+	for _, tt := range []struct{ name, input, wantErr string }{
+		{"newer version", `{"schemaVersion":3}`, "unsupported schemaVersion 3"},
+		{"invalid version", `{"schemaVersion":"2"}`, "invalid schemaVersion"},
+		{"wrong type", `{"schemaVersion":2,"context":{"user_timezone":5}}`, "inputData.context.user_timezone: expected string"},
+		{"not an integer", `{"schemaVersion":2,"context":{"focus":{"minimumMinutes":1.5}}}`, "expected integer"},
+		{"below minimum", `{"schemaVersion":2,"context":{"focus":{"minimumMinutes":-1}}}`, "at least 0"},
+		{"item", `{"schemaVersion":2,"context":{"offices":[{}, "HQ"]}}`, "inputData.context.offices[1]"},
+		{"required", `{"schemaVersion":2,"context":{"home":{"latitude":52.5}}}`, "longitude is required"},
+	} {
+		input := tt.input
+		if _, err := Normalize(&input); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, input := range []string{`{"context":{}}`, `{"schemaVersion":1}`, `"text"`, `{`} {
+		if err := Validate(&input); err == nil {
+			t.Errorf("Validate(%s): no error", input)
+		}
+	}
+}
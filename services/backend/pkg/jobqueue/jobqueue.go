@@ -0,0 +1,254 @@
+package jobqueue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Config tunes outbox flushing and the scheduled job mover
+type Config struct {
+	FlushInterval time.Duration
+	BatchSize     int
+	// MoveInterval is how often due scheduled jobs are moved to the broker
+	MoveInterval time.Duration
+}
+
+// Backoff bounds between attempts for a buffered job
+const (
+	initialBackoff = 5 * time.Second
+	maxBackoff     = 5 * time.Minute
+	// claimLease keeps other instances off an entry while it is being pushed
+	claimLease = time.Minute
+)
+
+// Queue publishes jobs to the broker, buffering them in the database when publishing
+// fails so a broker outage delays jobs instead of dropping them. Jobs scheduled for later
+// wait in the scheduler until a mover publishes them.
+type Queue struct {
+	publisher queue.Publisher
+	scheduler queue.Scheduler
+	outbox    repository.JobOutboxRepository
+	cfg       Config
+}
+
+// New creates a queue; call Run to flush buffered jobs and RunMover to publish scheduled ones
+func New(publisher queue.Publisher, scheduler queue.Scheduler, outbox repository.JobOutboxRepository, cfg Config) *Queue {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MoveInterval <= 0 {
+		cfg.MoveInterval = time.Second
+	}
+	return &Queue{publisher: publisher, scheduler: scheduler, outbox: outbox, cfg: cfg}
+}
+
+// AddJobToQueue publishes a job, buffering it if publishing fails. It only returns an
+// error when the job could neither be published nor buffered.
+func (q *Queue) AddJobToQueue(ctx context.Context, jobID, userID, targetDate string, inputData *string, priority models.JobPriority) error {
+	return q.publish(ctx, newMessage(jobID, userID, targetDate, inputData, priority))
+}
+
+// AddJobToOutbox buffers a job to be published by the next flush, without trying the
+// broker first. The entry is written with ctx, so inside a transaction the job is only
+// queued if the transaction commits.
+func (q *Queue) AddJobToOutbox(ctx context.Context, jobID, userID, targetDate string, inputData *string, priority models.JobPriority) error {
+	msg := newMessage(jobID, userID, targetDate, inputData, priority)
+	err := q.outbox.Add(ctx, &models.JobOutboxEntry{
+		JobID:         msg.JobID,
+		UserID:        msg.UserID,
+		TargetDate:    msg.TargetDate,
+		InputData:     msg.InputData,
+		Priority:      msg.Priority,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add job to the outbox: %w", err)
+	}
+	return nil
+}
+
+// ScheduleJob holds a job until at, then publishes it. Jobs due already are published
+// right away. If the scheduler is unavailable the job waits in the outbox instead,
+// which also delays it until at.
+func (q *Queue) ScheduleJob(ctx context.Context, jobID, userID, targetDate string, inputData *string, priority models.JobPriority, at time.Time) error {
+	msg := newMessage(jobID, userID, targetDate, inputData, priority)
+	if !at.After(time.Now()) {
+		return q.publish(ctx, msg)
+	}
+
+	scheduleErr := q.scheduler.Schedule(ctx, msg, at)
+	if scheduleErr == nil {
+		log.Printf("Scheduled job %s for %s", jobID, at.UTC().Format(time.RFC3339))
+		return nil
+	}
+	return q.buffer(ctx, msg, scheduleErr, at)
+}
+
+func newMessage(jobID, userID, targetDate string, inputData *string, priority models.JobPriority) queue.Message {
+	if priority == "" {
+		priority = models.JobPriorityInteractive
+	}
+	return queue.Message{
+		JobID:      jobID,
+		UserID:     userID,
+		TargetDate: targetDate,
+		InputData:  inputData,
+		Priority:   priority,
+	}
+}
+
+// publish publishes a message, buffering it if publishing fails
+func (q *Queue) publish(ctx context.Context, msg queue.Message) error {
+	pushErr := q.publisher.Publish(ctx, msg)
+	if pushErr == nil {
+		return nil
+	}
+	return q.buffer(ctx, msg, pushErr, time.Now().Add(initialBackoff))
+}
+
+// buffer stores a message in the outbox, to be published from next on
+func (q *Queue) buffer(ctx context.Context, msg queue.Message, cause error, next time.Time) error {
+	message := cause.Error()
+	err := q.outbox.Add(ctx, &models.JobOutboxEntry{
+		JobID:         msg.JobID,
+		UserID:        msg.UserID,
+		TargetDate:    msg.TargetDate,
+		InputData:     msg.InputData,
+		Priority:      msg.Priority,
+		LastError:     &message,
+		NextAttemptAt: next,
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("%v; buffering also failed: %w", cause, err)
+	}
+	log.Printf("Buffered job %s in the outbox: %v", msg.JobID, cause)
+	return nil
+}
+
+// RunMover publishes scheduled jobs as they come due, until ctx is cancelled
+func (q *Queue) RunMover(ctx context.Context) {
+	ticker := time.NewTicker(q.cfg.MoveInterval)
+	defer ticker.Stop()
+
+	for {
+		q.moveDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) moveDue(ctx context.Context) {
+	jobs, err := q.scheduler.ClaimDue(ctx, time.Now(), claimLease, q.cfg.BatchSize)
+	if err != nil {
+		// Scheduler down; due jobs are picked up once it is back
+		return
+	}
+
+	for _, job := range jobs {
+		if job.Message.JobID != "" {
+			// Publish failures are buffered; only a failed buffer leaves the job for
+			// the next lease
+			if err := q.publish(ctx, job.Message); err != nil {
+				log.Printf("Failed to move scheduled job %s: %v", job.Message.JobID, err)
+				continue
+			}
+			log.Printf("Moved scheduled job %s to the queue", job.Message.JobID)
+		}
+		if err := q.scheduler.Remove(ctx, job); err != nil {
+			// The lease expires and the job is published a second time
+			log.Printf("Failed to remove scheduled job %s: %v", job.Message.JobID, err)
+		}
+	}
+}
+
+// Run flushes buffered jobs until ctx is cancelled. Nothing is attempted while the broker
+// is known to be down.
+func (q *Queue) Run(ctx context.Context) {
+	ticker := time.NewTicker(q.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		q.flush(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) flush(ctx context.Context) {
+	defer q.reportBuffered(ctx)
+	if !q.publisher.Healthy() {
+		return
+	}
+
+	entries, err := q.outbox.ClaimDue(ctx, time.Now(), claimLease, q.cfg.BatchSize)
+	if err != nil {
+		log.Printf("Failed to claim buffered jobs: %v", err)
+		return
+	}
+
+	for _, entry := range entries {
+		targetDate := entry.TargetDate
+		if len(targetDate) > 10 {
+			targetDate = targetDate[:10]
+		}
+
+		err := q.publisher.Publish(ctx, queue.Message{
+			JobID:      entry.JobID,
+			UserID:     entry.UserID,
+			TargetDate: targetDate,
+			InputData:  entry.InputData,
+			Priority:   entry.Priority,
+		})
+		if err != nil {
+			next := time.Now().Add(backoff(entry.Attempts + 1))
+			if err := q.outbox.RecordFailure(ctx, entry.JobID, err.Error(), next); err != nil {
+				log.Printf("Failed to record outbox failure for job %s: %v", entry.JobID, err)
+			}
+			continue
+		}
+		if err := q.outbox.Delete(ctx, entry.JobID); err != nil {
+			// The entry's lease expires and the job is pushed a second time
+			log.Printf("Failed to remove job %s from the outbox: %v", entry.JobID, err)
+			continue
+		}
+		log.Printf("Flushed buffered job %s to the queue after %d failed attempts", entry.JobID, entry.Attempts+1)
+	}
+}
+
+func (q *Queue) reportBuffered(ctx context.Context) {
+	count, err := q.outbox.Count(ctx)
+	if err != nil {
+		return
+	}
+	metrics.JobQueueBuffered.Set(float64(count))
+}
+
+// backoff doubles from initialBackoff up to maxBackoff
+func backoff(attempts int) time.Duration {
+	delay := initialBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
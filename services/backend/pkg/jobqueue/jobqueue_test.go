@@ -0,0 +1,225 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/commute-planner/backend/pkg/redis"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+type fakePublisher struct {
+	mu        sync.Mutex
+	published []string
+	// err fails every publish
+	err     error
+	healthy bool
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, msg queue.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, msg.JobID)
+	return nil
+}
+
+func (p *fakePublisher) Healthy() bool                  { return p.healthy }
+func (p *fakePublisher) Ping(ctx context.Context) error { return nil }
+func (p *fakePublisher) Close() error                   { return nil }
+
+// failingOutbox can't buffer jobs
+type failingOutbox struct {
+	*repository.MemoryJobOutboxRepository
+}
+
+func (failingOutbox) Add(ctx context.Context, entry *models.JobOutboxEntry) error {
+	return errors.New("database unavailable")
+}
+
+// newTestScheduler returns a scheduler on an in-memory Redis
+func newTestScheduler(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	c := redis.NewClient(redis.Config{Addr: server.Addr()})
+	t.Cleanup(func() { c.Close() })
+	return c, server
+}
+
+// bufferedIDs lists the jobs in outbox
+func bufferedIDs(t *testing.T, outbox repository.JobOutboxRepository) []string {
+	t.Helper()
+	entries, err := outbox.ClaimDue(context.Background(), time.Now().Add(24*time.Hour), 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.JobID)
+	}
+	return ids
+}
+
+func TestMoverPublishesDueJobs(t *testing.T) {
+	ctx := context.Background()
+	scheduler, _ := newTestScheduler(t)
+	publisher := &fakePublisher{healthy: true}
+	q := New(publisher, scheduler, repository.NewMemoryJobOutboxRepository(), Config{})
+
+	now := time.Now()
+	for id, at := range map[string]time.Time{
+		"second": now.Add(-time.Second),
+		"first":  now.Add(-time.Minute),
+		"future": now.Add(time.Hour),
+	} {
+		if err := scheduler.Schedule(ctx, queue.Message{JobID: id}, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	q.moveDue(ctx)
+	if fmt.Sprint(publisher.published) != "[first second]" {
+		t.Fatalf("published %v, want [first second]", publisher.published)
+	}
+	// Published jobs are removed; only the future one is left
+	depth, err := scheduler.ScheduledDepth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth.Messages != 1 {
+		t.Errorf("%d jobs left scheduled, want 1", depth.Messages)
+	}
+}
+
+func TestMoverRemovesJobsOnlyOnceHandedOff(t *testing.T) {
+	ctx := context.Background()
+	scheduler, server := newTestScheduler(t)
+	publisher := &fakePublisher{err: errors.New("broker down")}
+	outbox := repository.NewMemoryJobOutboxRepository()
+	if err := scheduler.Schedule(ctx, queue.Message{JobID: "job"}, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither published nor buffered: the job stays scheduled, leased to this mover
+	New(publisher, scheduler, failingOutbox{outbox}, Config{}).moveDue(ctx)
+	depth, err := scheduler.ScheduledDepth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth.Messages != 1 {
+		t.Fatalf("%d jobs scheduled after a failed hand-off, want 1", depth.Messages)
+	}
+	leased, err := scheduler.ClaimDue(ctx, time.Now(), claimLease, 10)
+	if err != nil || len(leased) != 0 {
+		t.Fatalf("claimed %d jobs during the lease (%v), want none", len(leased), err)
+	}
+
+	// Once the lease expires it is due again; buffered in the outbox, it is removed
+	members, err := server.ZMembers(redis.JobDelayQueue)
+	if err != nil || len(members) != 1 {
+		t.Fatalf("delay queue members = %v, %v", members, err)
+	}
+	if _, err := server.ZAdd(redis.JobDelayQueue, float64(time.Now().Add(-time.Second).UnixMilli()), members[0]); err != nil {
+		t.Fatal(err)
+	}
+	New(publisher, scheduler, outbox, Config{}).moveDue(ctx)
+	if ids := bufferedIDs(t, outbox); fmt.Sprint(ids) != "[job]" {
+		t.Errorf("outbox holds %v, want [job]", ids)
+	}
+	if depth, _ := scheduler.ScheduledDepth(ctx); depth.Messages != 0 {
+		t.Errorf("%d jobs scheduled after buffering, want none", depth.Messages)
+	}
+}
+
+func TestFlushPublishesBufferedJobs(t *testing.T) {
+	ctx := context.Background()
+	publisher := &fakePublisher{err: errors.New("broker down")}
+	outbox := repository.NewMemoryJobOutboxRepository()
+	scheduler, _ := newTestScheduler(t)
+	q := New(publisher, scheduler, outbox, Config{})
+
+	// Buffered while the broker is down, and left alone while it is known to be
+	if err := q.AddJobToQueue(ctx, "job", "ada", "2026-03-02", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	q.flush(ctx)
+	if ids := bufferedIDs(t, outbox); fmt.Sprint(ids) != "[job]" {
+		t.Fatalf("outbox holds %v, want [job]", ids)
+	}
+
+	// A failed attempt is counted and retried later
+	publisher.healthy = true
+	if err := outbox.RecordFailure(ctx, "job", "broker down", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	q.flush(ctx)
+	entries, _ := outbox.ClaimDue(ctx, time.Now().Add(24*time.Hour), 0, 10)
+	if len(entries) != 1 || entries[0].Attempts != 2 || !entries[0].NextAttemptAt.After(time.Now()) {
+		t.Fatalf("outbox entries = %+v, want the job with 2 attempts, retried later", entries)
+	}
+
+	// Published, it leaves the outbox
+	publisher.err = nil
+	if err := outbox.RecordFailure(ctx, "job", "broker down", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	q.flush(ctx)
+	if fmt.Sprint(publisher.published) != "[job]" {
+		t.Errorf("published %v, want [job]", publisher.published)
+	}
+	if ids := bufferedIDs(t, outbox); len(ids) != 0 {
+		t.Errorf("outbox holds %v after publishing, want nothing", ids)
+	}
+}
+
+func TestAddJobToOutboxJoinsTransaction(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	outbox := repository.NewSQLJobOutboxRepository(db)
+	scheduler, _ := newTestScheduler(t)
+	q := New(&fakePublisher{healthy: true}, scheduler, outbox, Config{})
+	user, err := repository.NewSQLUserRepository(db).Create(ctx, repository.NewUser{Email: "ada@example.com", Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := repository.NewSQLJobRepository(db).Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A rolled back transaction takes the entry with it
+	rollback := errors.New("rollback")
+	err = db.InTx(ctx, func(ctx context.Context) error {
+		if err := q.AddJobToOutbox(ctx, job.ID, user.ID, "2026-03-02", nil, ""); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("InTx = %v, want the rollback", err)
+	}
+	if n, _ := outbox.Count(ctx); n != 0 {
+		t.Fatalf("%d jobs buffered after a rollback, want none", n)
+	}
+
+	err = db.InTx(ctx, func(ctx context.Context) error {
+		return q.AddJobToOutbox(ctx, job.ID, user.ID, "2026-03-02", nil, "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := bufferedIDs(t, outbox); fmt.Sprint(ids) != fmt.Sprintf("[%s]", job.ID) {
+		t.Errorf("outbox holds %v, want the job", ids)
+	}
+}
@@ -0,0 +1,157 @@
+// Package jsonschema validates decoded JSON against the subset of JSON Schema the
+// backend's own schemas use (pkg/jobinput, pkg/contracts): type, const, enum, properties,
+// required, additionalProperties (true or false), items, minimum and maximum. Other keywords,
+// like $schema, title and description, are ignored.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Schema is a parsed schema
+type Schema struct {
+	Type                 types              `json:"type"`
+	Const                json.RawMessage    `json:"const"`
+	Enum                 []interface{}      `json:"enum"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+}
+
+// types is a schema's type keyword, one type name or a list of them
+type types []string
+
+func (t *types) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = types{one}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// Parse reads a schema
+func Parse(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return s, nil
+}
+
+// Validate checks value, decoded from JSON with encoding/json, against s. path names
+// value in errors, which name the first value that doesn't match.
+func (s *Schema) Validate(value interface{}, path string) error {
+	if len(s.Type) > 0 && !s.hasType(value) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(s.Type, " or "), typeOf(value))
+	}
+	if s.Const != nil {
+		var want interface{}
+		if err := json.Unmarshal(s.Const, &want); err != nil {
+			return fmt.Errorf("%s: invalid const in schema: %w", path, err)
+		}
+		if !reflect.DeepEqual(value, want) {
+			return fmt.Errorf("%s: must be %s", path, s.Const)
+		}
+	}
+	if s.Enum != nil && !contains(s.Enum, value) {
+		return fmt.Errorf("%s: must be one of %v", path, s.Enum)
+	}
+	if n, ok := value.(float64); ok {
+		if s.Minimum != nil && n < *s.Minimum {
+			return fmt.Errorf("%s: must be at least %v", path, *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			return fmt.Errorf("%s: must be at most %v", path, *s.Maximum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				return fmt.Errorf("%s: %s is required", path, key)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property, ok := s.Properties[key]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unknown property %s", path, key)
+				}
+				continue
+			}
+			if err := property.Validate(v[key], path+"."+key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.Validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hasType reports whether value is one of the schema's types
+func (s *Schema) hasType(value interface{}) bool {
+	for _, name := range s.Type {
+		if name == typeOf(value) {
+			return true
+		}
+		if n, ok := value.(float64); ok && name == "integer" && n == math.Trunc(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf is the JSON Schema type of value, "number" for any number
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func contains(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
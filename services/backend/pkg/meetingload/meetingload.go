@@ -0,0 +1,178 @@
+// Package meetingload flags days with too many meetings: long runs of back-to-back
+// meetings, no break for lunch, and more hours of meetings than the limit.
+package meetingload
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/allday"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// Limits are the thresholds a day's meetings are flagged at; zero fields take the defaults
+type Limits struct {
+	// MaxMeetingHours is the most meeting time a day holds without a warning
+	MaxMeetingHours float64
+	// MaxBackToBack is the longest run of meetings without a break
+	MaxBackToBack time.Duration
+}
+
+// Default limits
+const (
+	DefaultMaxMeetingHours = 6
+	DefaultMaxBackToBack   = 3 * time.Hour
+)
+
+const (
+	// minBreak is the shortest gap between meetings that counts as a break
+	minBreak = 10 * time.Minute
+	// Lunch needs half an hour free between 11:30 and 14:00 local time
+	lunchFromHour, lunchFromMinute = 11, 30
+	lunchToHour                    = 14
+	lunchBreak                     = 30 * time.Minute
+)
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxMeetingHours <= 0 {
+		l.MaxMeetingHours = DefaultMaxMeetingHours
+	}
+	if l.MaxBackToBack <= 0 {
+		l.MaxBackToBack = DefaultMaxBackToBack
+	}
+	return l
+}
+
+// span is a stretch of meetings
+type span struct {
+	start, end time.Time
+	meetings   int
+}
+
+// Analyze returns the meeting load of date, a midnight UTC date, in location. Meetings are
+// the busy events during the local day; all-day entries and out-of-office blocks aren't.
+func Analyze(events []*models.CalendarEvent, date time.Time, location *time.Location, limits Limits) *models.MeetingLoad {
+	limits = limits.withDefaults()
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	var meetings []span
+	for _, event := range events {
+		if !allday.Busy(event) || !event.StartTime.Before(dayEnd) || !event.EndTime.After(dayStart) {
+			continue
+		}
+		start, end := event.StartTime, event.EndTime
+		if start.Before(dayStart) {
+			start = dayStart
+		}
+		if end.After(dayEnd) {
+			end = dayEnd
+		}
+		meetings = append(meetings, span{start, end, 1})
+	}
+	sort.Slice(meetings, func(i, j int) bool { return meetings[i].start.Before(meetings[j].start) })
+
+	load := &models.MeetingLoad{
+		Date:     date.Format("2006-01-02"),
+		Meetings: len(meetings),
+		Warnings: []models.MeetingLoadWarning{},
+	}
+	busy := merge(meetings, 0)
+	var total time.Duration
+	for _, s := range busy {
+		total += s.end.Sub(s.start)
+	}
+	load.MeetingMinutes = int(total.Minutes())
+
+	for _, run := range merge(meetings, minBreak) {
+		length := run.end.Sub(run.start)
+		if minutes := int(length.Minutes()); minutes > load.LongestRunMinutes {
+			load.LongestRunMinutes = minutes
+		}
+		if run.meetings > 1 && length > limits.MaxBackToBack {
+			start, end := run.start, run.end
+			load.Warnings = append(load.Warnings, models.MeetingLoadWarning{
+				Type: models.MeetingLoadBackToBack,
+				Message: fmt.Sprintf("%d meetings back to back from %s to %s (%s) without a break",
+					run.meetings, clock(start, location), clock(end, location), duration(length)),
+				Start: &start,
+				End:   &end,
+			})
+		}
+	}
+
+	lunchFrom := time.Date(date.Year(), date.Month(), date.Day(), lunchFromHour, lunchFromMinute, 0, 0, location)
+	lunchTo := time.Date(date.Year(), date.Month(), date.Day(), lunchToHour, 0, 0, 0, location)
+	if !hasGap(busy, lunchFrom, lunchTo, lunchBreak) {
+		load.Warnings = append(load.Warnings, models.MeetingLoadWarning{
+			Type: models.MeetingLoadNoLunch,
+			Message: fmt.Sprintf("No lunch break: meetings leave less than %s free between %s and %s",
+				duration(lunchBreak), clock(lunchFrom, location), clock(lunchTo, location)),
+			Start: &lunchFrom,
+			End:   &lunchTo,
+		})
+	}
+
+	if total.Hours() > limits.MaxMeetingHours {
+		load.Warnings = append(load.Warnings, models.MeetingLoadWarning{
+			Type: models.MeetingLoadTooManyHours,
+			Message: fmt.Sprintf("%s of meetings, more than the %s limit",
+				duration(total), duration(time.Duration(limits.MaxMeetingHours*float64(time.Hour)))),
+		})
+	}
+	return load
+}
+
+// merge joins sorted meetings into stretches, bridging gaps shorter than gap
+func merge(meetings []span, gap time.Duration) []span {
+	var merged []span
+	for _, m := range meetings {
+		n := len(merged)
+		if n == 0 || m.start.After(merged[n-1].end) && m.start.Sub(merged[n-1].end) >= gap {
+			merged = append(merged, m)
+			continue
+		}
+		last := &merged[n-1]
+		if m.end.After(last.end) {
+			last.end = m.end
+		}
+		last.meetings += m.meetings
+	}
+	return merged
+}
+
+// hasGap reports whether busy leaves at least length free between from and to
+func hasGap(busy []span, from, to time.Time, length time.Duration) bool {
+	free := from
+	for _, s := range busy {
+		if !s.end.After(free) {
+			continue
+		}
+		if !s.start.Before(to) {
+			break
+		}
+		if s.start.Sub(free) >= length {
+			return true
+		}
+		free = s.end
+	}
+	return to.Sub(free) >= length
+}
+
+func clock(t time.Time, location *time.Location) string {
+	return t.In(location).Format("15:04")
+}
+
+// duration formats d as "45m", "2h" or "3h 15m"
+func duration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	default:
+		return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+	}
+}
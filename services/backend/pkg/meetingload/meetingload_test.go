@@ -0,0 +1,65 @@
+package meetingload
+
+import (
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+var monday = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+func meeting(start, end string) *models.CalendarEvent {
+	parse := func(clock string) time.Time {
+		t, _ := time.Parse("15:04", clock)
+		return monday.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute)
+	}
+	return &models.CalendarEvent{ID: start, Summary: "Meeting at " + start, StartTime: parse(start), EndTime: parse(end)}
+}
+
+func types(load *models.MeetingLoad) []models.MeetingLoadWarningType {
+	var got []models.MeetingLoadWarningType
+	for _, warning := range load.Warnings {
+		got = append(got, warning.Type)
+	}
+	return got
+}
+
+func TestAnalyze(t *testing.T) {
+	birthday := &models.CalendarEvent{ID: "birthday", Summary: "Birthday", StartTime: monday, EndTime: monday.AddDate(0, 0, 1), IsAllDay: true}
+
+	light := Analyze([]*models.CalendarEvent{meeting("09:00", "09:30"), meeting("14:00", "15:00"), birthday}, monday, time.UTC, Limits{})
+	if light.Meetings != 2 || light.MeetingMinutes != 90 || len(light.Warnings) != 0 {
+		t.Errorf("light day = %+v, want 2 meetings, 90 minutes and no warnings", light)
+	}
+
+	// 09:00-12:45 with five-minute gaps, overlapping lunch meetings, and 15:00-17:30
+	heavy := Analyze([]*models.CalendarEvent{
+		meeting("09:00", "10:00"), meeting("10:05", "11:00"), meeting("11:00", "12:00"), meeting("12:05", "12:45"),
+		meeting("12:50", "13:30"), meeting("13:00", "14:00"),
+		meeting("15:00", "17:30"),
+	}, monday, time.UTC, Limits{})
+	got := types(heavy)
+	want := []models.MeetingLoadWarningType{models.MeetingLoadBackToBack, models.MeetingLoadNoLunch, models.MeetingLoadTooManyHours}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("warnings = %v, want %v", got, want)
+	}
+	if heavy.MeetingMinutes != 435 || heavy.LongestRunMinutes != 300 {
+		t.Errorf("heavy day = %d minutes, longest run %d; want 435 and 300", heavy.MeetingMinutes, heavy.LongestRunMinutes)
+	}
+	if message := heavy.Warnings[0].Message; message != "6 meetings back to back from 09:00 to 14:00 (5h) without a break" {
+		t.Errorf("back-to-back message = %q", message)
+	}
+
+	// A higher limit clears the hours warning; a single long meeting isn't back to back
+	relaxed := Analyze([]*models.CalendarEvent{meeting("09:00", "13:00"), meeting("14:00", "17:00")}, monday, time.UTC, Limits{MaxMeetingHours: 8})
+	if len(relaxed.Warnings) != 0 {
+		t.Errorf("relaxed warnings = %v, want none", types(relaxed))
+	}
+
+	// The day is the user's own: a 23:00 UTC meeting is on Monday evening in New York
+	newYork, _ := time.LoadLocation("America/New_York")
+	if load := Analyze([]*models.CalendarEvent{meeting("23:00", "23:30")}, monday, newYork, Limits{}); load.Meetings != 1 {
+		t.Errorf("New York day has %d meetings, want 1", load.Meetings)
+	}
+}
@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric exported by the backend
+const namespace = "commute_planner"
+
+// Registry holds all backend metrics. A dedicated registry (rather than the global default)
+// keeps the exported set explicit.
+var Registry = prometheus.NewRegistry()
+
+// Stuck-job reaper metrics. Alert on increase(commute_planner_jobs_reaped_total[1h]) > 0:
+// every reaped job means a worker died or hung mid-job.
+var (
+	StuckJobs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stuck_jobs",
+		Help:      "IN_PROGRESS jobs without a progress update within the stale window, as of the last reaper sweep.",
+	})
+	JobsReaped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_reaped_total",
+		Help:      "Stuck jobs handled by the reaper, by action (requeued or failed).",
+	}, []string{"action"})
+	JobQuotaRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_quota_rejections_total",
+		Help:      "createJob calls rejected by a per-user quota, by error code.",
+	}, []string{"code"})
+	JobsArchived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_archived_total",
+		Help:      "Jobs moved to the archive by retention runs, by the job's status.",
+	}, []string{"status"})
+)
+
+// Broker and job queue health
+var (
+	RedisUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "redis_up",
+		Help:      "Whether the last Redis health check succeeded (1) or failed (0).",
+	})
+	RabbitMQUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rabbitmq_up",
+		Help:      "Whether the RabbitMQ job publisher is connected (1) or not (0).",
+	})
+	JobQueueBuffered = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_queue_buffered",
+		Help:      "Jobs waiting in the database outbox because they could not be published to the broker.",
+	})
+)
+
+// Job queue backlog, sampled by the queue monitor. Alert on
+// commute_planner_job_queue_oldest_age_seconds > 300: workers aren't keeping up or
+// aren't running.
+var (
+	JobQueueMessages = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_queue_messages",
+		Help:      "Jobs held by each broker queue; for Redis streams this includes retained entries workers already read.",
+	}, []string{"queue"})
+	JobQueuePending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_queue_pending",
+		Help:      "Jobs a worker took from each Redis stream but hasn't acknowledged.",
+	}, []string{"queue"})
+	JobQueueOldestAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_queue_oldest_age_seconds",
+		Help:      "Age of the oldest job no worker has taken yet from each Redis stream; 0 when there is none.",
+	}, []string{"queue"})
+	JobsInProgressStale = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "jobs_in_progress_stale",
+		Help:      "IN_PROGRESS jobs without a progress update within the queue monitor's stuck threshold.",
+	})
+	QueueAlertFiring = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_alert_firing",
+		Help:      "Whether each queue monitor alert is over its threshold (1) or not (0).",
+	}, []string{"alert"})
+	QueueAlertNotifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_alert_notifications_total",
+		Help:      "Queue monitor alert notifications by result (sent or failed).",
+	}, []string{"result"})
+	JobBackpressure = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_backpressure_total",
+		Help:      "Jobs created while the pipeline was saturated, by action (rejected or overflowed).",
+	}, []string{"action"})
+)
+
+// Circuit breakers around the database, Redis and external APIs. Alert on
+// commute_planner_circuit_breaker_state == 2: the dependency is failing and calls to it
+// are being rejected.
+var (
+	CircuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "State of each circuit breaker: 0 closed, 1 half-open, 2 open.",
+	}, []string{"breaker"})
+	CircuitBreakerRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_rejections_total",
+		Help:      "Calls failed fast by an open circuit breaker, by breaker.",
+	}, []string{"breaker"})
+)
+
+// Notifications
+var (
+	DigestEmails = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "digest_emails_total",
+		Help:      "Weekly digest emails by result (sent or failed).",
+	}, []string{"result"})
+	CommuteReminders = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commute_reminders_total",
+		Help:      "Commute reminders by result (sent, failed, or missed when the departure passed first).",
+	}, []string{"result"})
+	AnalyticsEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "analytics_events_total",
+		Help:      "Product analytics events by result (recorded, opted_out, dropped when the queue was full, forwarded, or failed).",
+	}, []string{"result"})
+)
+
+// myPlans subscription streams
+var (
+	PlanFeedSubscribers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "plan_feed_subscribers",
+		Help:      "Open myPlans subscriptions on this instance.",
+	})
+	PlanUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "plan_updates_total",
+		Help:      "Plan updates pushed to myPlans subscribers, by result (delivered, or dropped when the subscriber fell behind).",
+	}, []string{"result"})
+)
+
+// Deprecated schema use. A field still counted here has clients left to migrate; see the
+// deprecatedFieldUsage query for which.
+var DeprecatedFieldUsage = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "graphql_deprecated_field_usage_total",
+	Help:      "GraphQL requests selecting a deprecated field or passing a deprecated argument, by its coordinate (Type.field or Type.field(argument:)).",
+}, []string{"field"})
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		StuckJobs,
+		JobsReaped,
+		JobQuotaRejections,
+		JobsArchived,
+		RedisUp,
+		RabbitMQUp,
+		JobQueueBuffered,
+		JobQueueMessages,
+		JobQueuePending,
+		JobQueueOldestAge,
+		JobsInProgressStale,
+		QueueAlertFiring,
+		QueueAlertNotifications,
+		JobBackpressure,
+		CircuitBreakerState,
+		CircuitBreakerRejections,
+		DigestEmails,
+		CommuteReminders,
+		AnalyticsEvents,
+		DeprecatedFieldUsage,
+		PlanFeedSubscribers,
+		PlanUpdates,
+	)
+}
+
+// RegisterDB exports connection pool statistics (open, in-use, idle, wait count/duration)
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, namespace+"_"+name))
+}
+
+// Handler serves the metrics in Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/commute-planner/backend/pkg/clientinfo"
+)
+
+// Client marks each request's context with the client application it comes from, read
+// from its X-Client-Name and X-Client-Version headers. With required set, requests that
+// don't name their client are rejected with 400.
+func Client(required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := clientinfo.FromRequest(r)
+			if required && client.Name == clientinfo.Unknown {
+				http.Error(w, "Name the client application with the X-Client-Name header", http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(clientinfo.WithClient(r.Context(), client)))
+		})
+	}
+}
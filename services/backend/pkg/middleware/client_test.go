@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/clientinfo"
+)
+
+func TestClient(t *testing.T) {
+	var seen clientinfo.Client
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = clientinfo.FromContext(r.Context())
+	})
+	serve := func(required bool, name string) int {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		if name != "" {
+			req.Header.Set("X-Client-Name", name)
+			req.Header.Set("X-Client-Version", "1.4.0")
+		}
+		rec := httptest.NewRecorder()
+		Client(required)(next).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve(false, ""); code != http.StatusOK || seen.Name != clientinfo.Unknown {
+		t.Errorf("anonymous request: %d as %+v", code, seen)
+	}
+	if code := serve(true, ""); code != http.StatusBadRequest {
+		t.Errorf("anonymous request with the header required: %d, want 400", code)
+	}
+	if code := serve(true, "ios"); code != http.StatusOK || seen.Name != "ios" || seen.Version != "1.4.0" {
+		t.Errorf("named request: %d as %+v", code, seen)
+	}
+}
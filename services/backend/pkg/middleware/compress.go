@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// brotliLevel trades ratio for speed; responses are compressed as they are served
+const brotliLevel = 5
+
+// Compress compresses responses with brotli or gzip, whichever the client prefers of
+// those it accepts. Responses smaller than minSize bytes are sent as they are, as are
+// ones already encoded (e.g. /metrics) and ones whose type doesn't compress. Handlers
+// that flush (streamed exports and GraphQL results) stay streamed: each flush sends the
+// data compressed so far.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize, status: http.StatusOK}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks br or gzip from an Accept-Encoding header, or "" for neither
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		// q=0 means the client refuses the encoding
+		if (name != "br" && name != "gzip") || q <= 0 {
+			continue
+		}
+		// Brotli wins ties: it compresses JSON noticeably better
+		if q > bestQ || (q == bestQ && name == "br") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressible reports whether a content type is worth compressing
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" ||
+		mediaType == "application/javascript" ||
+		mediaType == "application/xml" ||
+		mediaType == "image/svg+xml"
+}
+
+// compressWriter holds back the start of a response until it knows whether it is big
+// enough to compress, then streams it through the encoder
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+	buf      []byte
+	started  bool
+	enc      interface {
+		io.WriteCloser
+		Flush() error
+	}
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.started {
+		return
+	}
+	c.status = status
+	// Responses without a body go out untouched
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		c.start(false)
+	}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if !c.started {
+		c.buf = append(c.buf, p...)
+		if len(c.buf) < c.minSize {
+			return len(p), nil
+		}
+		c.start(true)
+		return len(p), c.flushBuffer()
+	}
+	if c.enc != nil {
+		return c.enc.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Flush sends what was written so far. A handler flushing expects a long response, so
+// it is compressed even if it is still below minSize.
+func (c *compressWriter) Flush() {
+	if !c.started {
+		c.start(true)
+		c.flushBuffer()
+	}
+	if c.enc != nil {
+		c.enc.Flush()
+	}
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close ends the response, sending a small one uncompressed
+func (c *compressWriter) Close() error {
+	if !c.started {
+		c.start(false)
+		if err := c.flushBuffer(); err != nil {
+			return err
+		}
+	}
+	if c.enc != nil {
+		return c.enc.Close()
+	}
+	return nil
+}
+
+// start sends the headers, compressing the body when asked and the response allows it
+func (c *compressWriter) start(compress bool) {
+	c.started = true
+	header := c.Header()
+	if header.Get("Content-Type") == "" && len(c.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(c.buf))
+	}
+	if compress && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", c.encoding)
+		header.Del("Content-Length")
+		// The encoded bytes differ from the ones a strong tag was computed over
+		if tag := header.Get("ETag"); tag != "" && !strings.HasPrefix(tag, "W/") {
+			header.Set("ETag", "W/"+tag)
+		}
+		switch c.encoding {
+		case "br":
+			c.enc = brotli.NewWriterLevel(c.ResponseWriter, brotliLevel)
+		default:
+			c.enc = gzip.NewWriter(c.ResponseWriter)
+		}
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+}
+
+func (c *compressWriter) flushBuffer() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	buf := c.buf
+	c.buf = nil
+	var err error
+	if c.enc != nil {
+		_, err = c.enc.Write(buf)
+	} else {
+		_, err = c.ResponseWriter.Write(buf)
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip;q=0", ""},
+		{"identity, deflate", ""},
+		{"GZIP", "gzip"},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.acceptEncoding); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"data":{"calendarEvents":[` + strings.Repeat(`{"summary":"Standup"},`, 200) + `{}]}}`
+	handler := Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		switch r.URL.Path {
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"health":"ok"}`)
+		case "/large":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, large)
+		case "/streamed":
+			// Flushed before reaching the minimum size
+			w.Header().Set("Content-Type", "text/csv")
+			io.WriteString(w, "a,b\n")
+			w.(http.Flusher).Flush()
+			io.WriteString(w, "1,2\n")
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, large)
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+		}
+	}))
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantEncoding   string
+		wantBody       string
+	}{
+		{"small response", "/small", "gzip, br", "", `{"health":"ok"}`},
+		{"large response, brotli", "/large", "gzip, br", "br", large},
+		{"large response, gzip", "/large", "gzip", "gzip", large},
+		{"no accepted encoding", "/large", "", "", large},
+		{"streamed response", "/streamed", "gzip", "gzip", "a,b\n1,2\n"},
+		{"incompressible type", "/image", "gzip", "", large},
+		{"not modified", "/not-modified", "gzip", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", vary)
+			}
+			var body io.Reader = rec.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = zr
+			case "br":
+				body = brotli.NewReader(rec.Body)
+			}
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			wantTag := `"abc"`
+			if tt.wantEncoding != "" {
+				wantTag = `W/"abc"`
+			}
+			if tag := rec.Header().Get("ETag"); tag != wantTag {
+				t.Errorf("ETag = %q, want %q", tag, wantTag)
+			}
+		})
+	}
+}
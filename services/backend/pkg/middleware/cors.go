@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/rs/cors"
+)
+
+// CORSOptions configures cross-origin access to the API
+type CORSOptions struct {
+	AllowedOrigins        []string // exact origins, e.g. https://app.commuteplanner.com
+	AllowedOriginPatterns []string // regexes matching the whole origin, e.g. https://pr-\d+\.preview\.commuteplanner\.com
+	AllowedHeaders        []string
+	AllowedMethods        []string
+	AllowCredentials      bool
+	MaxAge                int // seconds browsers may cache preflight results
+}
+
+// NewCORS builds the CORS middleware. Origins are always matched explicitly - a wildcard
+// origin combined with credentials is rejected by browsers and would expose the API to any site.
+func NewCORS(opts CORSOptions) (func(http.Handler) http.Handler, error) {
+	var openOrigins string
+	if opts.AllowCredentials {
+		openOrigins = "cannot be combined with credentials"
+	}
+	allowOrigin, err := newOriginMatcher("CORS", opts.AllowedOrigins, opts.AllowedOriginPatterns, openOrigins)
+	if err != nil {
+		return nil, err
+	}
+
+	c := cors.New(cors.Options{
+		AllowOriginFunc:  allowOrigin,
+		AllowedHeaders:   opts.AllowedHeaders,
+		AllowedMethods:   opts.AllowedMethods,
+		AllowCredentials: opts.AllowCredentials,
+		MaxAge:           opts.MaxAge,
+	})
+
+	return c.Handler, nil
+}
+
+// newOriginMatcher matches exact origins and whole-origin patterns of a kind of access.
+// When openOrigins says why they're unsafe, a wildcard or a pattern matching unrelated
+// sites is an error.
+func newOriginMatcher(kind string, origins, originPatterns []string, openOrigins string) (func(origin string) bool, error) {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		if origin == "*" && openOrigins != "" {
+			return nil, fmt.Errorf("wildcard %s origin %s", kind, openOrigins)
+		}
+		allowed[origin] = true
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(originPatterns))
+	for _, pattern := range originPatterns {
+		// Patterns match the whole origin, so pr-\d+\.preview\.commuteplanner\.com can't be
+		// satisfied by pr-1.preview.commuteplanner.com.evil.example
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s origin pattern %q: %w", kind, pattern, err)
+		}
+		if openOrigins != "" && matchesAnyOrigin(re) {
+			return nil, fmt.Errorf("%s origin pattern %q matches arbitrary origins and %s", kind, pattern, openOrigins)
+		}
+		patterns = append(patterns, re)
+	}
+
+	return func(origin string) bool {
+		if allowed["*"] || allowed[origin] {
+			return true
+		}
+		for _, re := range patterns {
+			if re.MatchString(origin) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// unrelatedOrigins are origins no deployment's pattern should allow
+var unrelatedOrigins = []string{"null", "https://example.com", "http://evil.example"}
+
+// matchesAnyOrigin reports whether a pattern is broad enough to allow unrelated sites,
+// such as .* or https?://.+
+func matchesAnyOrigin(re *regexp.Regexp) bool {
+	for _, origin := range unrelatedOrigins {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
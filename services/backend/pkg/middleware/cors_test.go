@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewCORSOrigins(t *testing.T) {
+	preview := `https://pr-\d+\.preview\.commuteplanner\.com`
+	tests := []struct {
+		name    string
+		opts    CORSOptions
+		origin  string
+		allowed bool
+	}{
+		{"exact origin", CORSOptions{AllowedOrigins: []string{"https://app.commuteplanner.com"}}, "https://app.commuteplanner.com", true},
+		{"other origin", CORSOptions{AllowedOrigins: []string{"https://app.commuteplanner.com"}}, "https://evil.example", false},
+		{"pattern match", CORSOptions{AllowedOriginPatterns: []string{preview}}, "https://pr-42.preview.commuteplanner.com", true},
+		{"pattern with a suffix", CORSOptions{AllowedOriginPatterns: []string{preview}}, "https://pr-42.preview.commuteplanner.com.evil.example", false},
+		{"pattern with a prefix", CORSOptions{AllowedOriginPatterns: []string{preview}}, "https://evil.example/https://pr-42.preview.commuteplanner.com", false},
+		{"alternation is anchored as a whole", CORSOptions{AllowedOriginPatterns: []string{`https://a\.example|https://b\.example`}}, "https://a.example.evil", false},
+		{"alternation match", CORSOptions{AllowedOriginPatterns: []string{`https://a\.example|https://b\.example`}}, "https://b.example", true},
+		{"wildcard without credentials", CORSOptions{AllowedOrigins: []string{"*"}}, "https://evil.example", true},
+		{"match-all pattern without credentials", CORSOptions{AllowedOriginPatterns: []string{".*"}}, "https://evil.example", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cors, err := NewCORS(tt.opts)
+			if err != nil {
+				t.Fatalf("NewCORS() error = %v", err)
+			}
+			handler := cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/offices", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get("Access-Control-Allow-Origin") != ""
+			if got != tt.allowed {
+				t.Errorf("origin %s allowed = %v, want %v", tt.origin, got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestNewCORSRejectsOpenOriginsWithCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    CORSOptions
+		wantErr string
+	}{
+		{"wildcard origin", CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}, "wildcard"},
+		{"match-all pattern", CORSOptions{AllowedOriginPatterns: []string{".*"}, AllowCredentials: true}, "arbitrary origins"},
+		{"any https origin", CORSOptions{AllowedOriginPatterns: []string{`https://.+`}, AllowCredentials: true}, "arbitrary origins"},
+		{"any scheme and host", CORSOptions{AllowedOriginPatterns: []string{`https?://[^/]+`}, AllowCredentials: true}, "arbitrary origins"},
+		{"invalid pattern", CORSOptions{AllowedOriginPatterns: []string{"("}}, "invalid CORS origin pattern"},
+		{"preview pattern", CORSOptions{AllowedOriginPatterns: []string{`https://pr-\d+\.preview\.commuteplanner\.com`}, AllowCredentials: true}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCORS(tt.opts)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("NewCORS() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("NewCORS() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	csrf, err := NewCSRF(CSRFOptions{TrustedOrigins: []string{"https://app.commuteplanner.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := csrf(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name          string
+		method        string
+		origin        string
+		secFetchSite  string
+		wantForbidden bool
+	}{
+		{"cross-site GET", http.MethodGet, "https://evil.example", "cross-site", false},
+		{"same-origin POST", http.MethodPost, "https://api.commuteplanner.com", "same-origin", false},
+		{"trusted cross-site POST", http.MethodPost, "https://app.commuteplanner.com", "same-site", false},
+		{"cross-site POST", http.MethodPost, "https://evil.example", "cross-site", true},
+		{"same-site POST from another subdomain", http.MethodDelete, "https://preview.commuteplanner.com", "same-site", true},
+		{"POST without browser headers", http.MethodPost, "", "", false},
+		{"old browser, same host", http.MethodPost, "https://api.commuteplanner.com", "", false},
+		{"old browser, other host", http.MethodPut, "https://evil.example", "", true},
+		{"sandboxed frame", http.MethodPost, "null", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "https://api.commuteplanner.com/graphql", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.secFetchSite != "" {
+				req.Header.Set("Sec-Fetch-Site", tt.secFetchSite)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if forbidden := rec.Code == http.StatusForbidden; forbidden != tt.wantForbidden {
+				t.Errorf("status %d, want forbidden %v", rec.Code, tt.wantForbidden)
+			}
+		})
+	}
+
+	if _, err := NewCSRF(CSRFOptions{TrustedOrigins: []string{"*"}}); err == nil {
+		t.Error("wildcard trusted origin accepted")
+	}
+}
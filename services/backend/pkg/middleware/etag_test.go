@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	body := `{"success":true}`
+	handler := ETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Write([]byte(body))
+	}))
+	serve := func(method, target, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := serve(http.MethodGet, "/auth/me", "")
+	tag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || first.Body.String() != body || tag == "" {
+		t.Fatalf("first response = %d %q with ETag %q, want the body and a tag", first.Code, first.Body.String(), tag)
+	}
+	if cc := first.Header().Get("Cache-Control"); cc != "private, no-cache" {
+		t.Errorf("Cache-Control = %q, want private, no-cache", cc)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		ifNoneMatch string
+		want        int
+	}{
+		{"current tag", http.MethodGet, "/auth/me", tag, http.StatusNotModified},
+		{"weak current tag", http.MethodGet, "/auth/me", "W/" + tag, http.StatusNotModified},
+		{"tag in a list", http.MethodGet, "/auth/me", `"stale", ` + tag, http.StatusNotModified},
+		{"any tag", http.MethodGet, "/auth/me", "*", http.StatusNotModified},
+		{"stale tag", http.MethodGet, "/auth/me", `"stale"`, http.StatusOK},
+		{"error response", http.MethodGet, "/auth/me?fail=1", tag, http.StatusInternalServerError},
+		{"not a read", http.MethodPost, "/auth/me", tag, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(tt.method, tt.target, tt.ifNoneMatch)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 body = %q, want none", rec.Body.String())
+			}
+			if tt.want != http.StatusNotModified && rec.Body.String() != body {
+				t.Errorf("body = %q, want %q", rec.Body.String(), body)
+			}
+		})
+	}
+
+	// A write that changes the representation changes the tag
+	body = `{"success":true,"data":{}}`
+	if changed := serve(http.MethodGet, "/auth/me", tag); changed.Code != http.StatusOK || changed.Header().Get("ETag") == tag {
+		t.Errorf("after a change: %d with ETag %q, want 200 with a new tag", changed.Code, changed.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/commute-planner/backend/pkg/redact"
+)
+
+// RedactErrors scrubs personal data and credentials from error responses (status 400 and
+// up) before they reach the client, so a handler echoing an email address, a token or an
+// event's summary in its error doesn't leak it. Successful responses pass through as they
+// are. A nil redactor disables it.
+func RedactErrors(r *redact.Redactor) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if r == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rw := &redactWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, req)
+			if rw.status >= http.StatusBadRequest {
+				w.WriteHeader(rw.status)
+				w.Write([]byte(r.String(rw.body.String())))
+			}
+		})
+	}
+}
+
+// redactWriter buffers the body of an error response, to scrub it whole
+type redactWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *redactWriter) WriteHeader(status int) {
+	if rw.status != 0 {
+		return
+	}
+	rw.status = status
+	if status >= http.StatusBadRequest {
+		// The scrubbed body's length differs
+		rw.Header().Del("Content-Length")
+		return
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *redactWriter) Write(p []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.status >= http.StatusBadRequest {
+		return rw.body.Write(p)
+	}
+	return rw.ResponseWriter.Write(p)
+}
+
+// Flush keeps successful streamed responses streaming; error bodies are held until the
+// handler returns
+func (rw *redactWriter) Flush() {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.status >= http.StatusBadRequest {
+		return
+	}
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
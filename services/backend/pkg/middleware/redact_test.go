@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/redact"
+)
+
+func TestRedactErrors(t *testing.T) {
+	handler := RedactErrors(redact.New())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "100")
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusConflict)
+		}
+		w.Write([]byte(`{"success":false,"error":"user ada@example.com already exists","summary":"Dentist"}`))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/register?fail=1", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409", rec.Code)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "ada@example.com") || strings.Contains(body, "Dentist") {
+		t.Errorf("error body = %s, want the email and summary scrubbed", body)
+	}
+	if !strings.Contains(body, `"error":"user [email] already exists"`) {
+		t.Errorf("error body = %s, want the scrubbed message kept", body)
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Errorf("Content-Length = %q, want it dropped", rec.Header().Get("Content-Length"))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/register", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "ada@example.com") {
+		t.Errorf("successful response = %d %s, want it as it was", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	serve := func(opts SecurityHeadersOptions) http.Header {
+		handler := SecurityHeaders(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/graphql", nil))
+		return rec.Header()
+	}
+
+	h := serve(SecurityHeadersOptions{HSTSMaxAge: 365 * 24 * time.Hour, HSTSIncludeSubdomains: true, FrameOptions: "DENY", ReferrerPolicy: "no-referrer"})
+	want := map[string]string{
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "no-referrer",
+		"Content-Security-Policy":   "",
+	}
+	for name, value := range want {
+		if got := h.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+
+	// Development over plain HTTP
+	h = serve(SecurityHeadersOptions{})
+	if h.Get("Strict-Transport-Security") != "" || h.Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("headers without options = %v, want only nosniff", h)
+	}
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeoutErrorCode identifies a timed-out request in the error body
+const TimeoutErrorCode = "REQUEST_TIMEOUT"
+
+// errorResponse is the body of a request the middleware rejects: the REST endpoints'
+// error shape, with errors for GraphQL clients and a code to tell it from other failures
+type errorResponse struct {
+	Success bool     `json:"success"`
+	Error   string   `json:"error"`
+	Errors  []string `json:"errors"`
+	Code    string   `json:"code"`
+}
+
+// Timeout bounds each request to timeout. The request context is cancelled at the
+// deadline, so database and Redis calls made with it give up, and a handler that hasn't
+// started its response by then is answered with a 503 timeout error rather than leaving
+// the client hanging. A handler already streaming its response keeps the connection
+// until it notices the cancellation. Requests whose path starts with one of exempt run
+// unbounded; a timeout of 0 disables the middleware.
+func Timeout(timeout time.Duration, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range exempt {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, header: make(http.Header)}
+			done := make(chan struct{})
+			var panicked interface{}
+			go func() {
+				defer func() {
+					panicked = recover()
+					close(done)
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+			}()
+
+			select {
+			case <-done:
+			case <-ctx.Done():
+				// A client that went away gets no answer; one that waited too long does
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) && tw.timeOut() {
+					writeTimeoutError(w, timeout)
+					return
+				}
+				<-done
+			}
+			if panicked != nil {
+				panic(panicked)
+			}
+		})
+	}
+}
+
+func writeTimeoutError(w http.ResponseWriter, timeout time.Duration) {
+	message := fmt.Sprintf("Request timed out after %v", timeout)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(errorResponse{
+		Success: false,
+		Error:   message,
+		Errors:  []string{message},
+		Code:    TimeoutErrorCode,
+	})
+}
+
+// timeoutWriter passes a handler's response through until the request times out. The
+// handler gets its own header map, since it may still be running after the middleware
+// has answered.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.header }
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeader(status)
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+	return tw.w.Write(p)
+}
+
+// Flush keeps streamed responses (GraphQL results) streaming
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+	if flusher, ok := tw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeHeader sends the handler's headers and status; tw.mu must be held
+func (tw *timeoutWriter) writeHeader(status int) {
+	tw.wroteHeader = true
+	dst := tw.w.Header()
+	for key, values := range tw.header {
+		dst[key] = values
+	}
+	tw.w.WriteHeader(status)
+}
+
+// timeOut claims the response for the timeout error, reporting false when the handler
+// has already started its own
+func (tw *timeoutWriter) timeOut() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.wroteHeader {
+		return false
+	}
+	tw.timedOut = true
+	return true
+}
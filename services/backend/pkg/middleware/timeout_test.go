@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	handler := Timeout(50*time.Millisecond, "/export/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fast":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"success":true}`)
+		case "/hang", "/export/events.csv":
+			// Like a database call, give up when the request context does
+			select {
+			case <-r.Context().Done():
+			case <-time.After(200 * time.Millisecond):
+				io.WriteString(w, "finished")
+			}
+		case "/streaming":
+			io.WriteString(w, "a,b\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			io.WriteString(w, "1,2\n")
+		}
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	t.Run("fast", func(t *testing.T) {
+		rec := serve("/fast")
+		if rec.Code != http.StatusOK || rec.Body.String() != `{"success":true}` || rec.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("got %d %q (%s), want the handler's response", rec.Code, rec.Body, rec.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("hangs", func(t *testing.T) {
+		rec := serve("/hang")
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("status = %d, want 503", rec.Code)
+		}
+		var body errorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Success || body.Code != TimeoutErrorCode || len(body.Errors) != 1 {
+			t.Errorf("body = %+v, want a %s error", body, TimeoutErrorCode)
+		}
+	})
+
+	t.Run("already streaming", func(t *testing.T) {
+		rec := serve("/streaming")
+		if rec.Code != http.StatusOK || rec.Body.String() != "a,b\n1,2\n" {
+			t.Errorf("got %d %q, want the streamed response to finish", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("exempt", func(t *testing.T) {
+		rec := serve("/export/events.csv")
+		if rec.Code != http.StatusOK || rec.Body.String() != "finished" {
+			t.Errorf("got %d %q, want the export to run past the timeout", rec.Code, rec.Body)
+		}
+	})
+}
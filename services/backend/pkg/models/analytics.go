@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// AnalyticsEvent is an anonymized product analytics event. AnonymousID is a salted hash
+// of the user's ID, so events of one user can be told apart from another's without
+// saying who either is.
+type AnalyticsEvent struct {
+	ID          string `json:"id" db:"id"`
+	Event       string `json:"event" db:"event"`
+	AnonymousID string `json:"anonymousId" db:"anonymous_id"`
+	// Properties is a JSON object describing the event
+	Properties string    `json:"properties" db:"properties"`
+	OccurredAt time.Time `json:"occurredAt" db:"occurred_at"`
+	// ClientName and ClientVersion are the client application the event happened in; nil
+	// for events not caused by a client request
+	ClientName    *string `json:"clientName" db:"client_name"`
+	ClientVersion *string `json:"clientVersion" db:"client_version"`
+	// ForwardedAt is when the event was sent to the analytics sink; nil until then
+	ForwardedAt *time.Time `json:"forwardedAt" db:"forwarded_at"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+}
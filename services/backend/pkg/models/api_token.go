@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// APITokenScope is what an API token may do on its user's behalf
+type APITokenScope string
+
+const (
+	// ScopeReadCalendar reads the user's calendar events
+	ScopeReadCalendar APITokenScope = "read:calendar"
+	// ScopeWriteJobs creates planning jobs and follows them to their recommendations
+	ScopeWriteJobs APITokenScope = "write:jobs"
+)
+
+// APITokenScopes are the scopes tokens can be given
+var APITokenScopes = []APITokenScope{ScopeReadCalendar, ScopeWriteJobs}
+
+// IsValid reports whether s is a known scope
+func (s APITokenScope) IsValid() bool {
+	for _, scope := range APITokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken is a long-lived personal access token, for a browser extension or CLI to act
+// for its user within its scopes
+type APIToken struct {
+	ID     string `json:"id" db:"id"`
+	UserID string `json:"userId" db:"user_id"`
+	Name   string `json:"name" db:"name"`
+	// Prefix is the start of the token, to tell tokens apart
+	Prefix     string          `json:"prefix" db:"token_prefix"`
+	Scopes     []APITokenScope `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time      `json:"expiresAt" db:"expires_at"`
+	LastUsedAt *time.Time      `json:"lastUsedAt" db:"last_used_at"`
+	CreatedAt  time.Time       `json:"createdAt" db:"created_at"`
+	// Token is the secret; only a hash of it is kept, so it is only known when the token
+	// is created
+	Token string `json:"token,omitempty" db:"-"`
+}
+
+// HasScope reports whether the token has scope
+func (t *APIToken) HasScope(scope APITokenScope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// JobArtifactKind is what a job artifact holds
+type JobArtifactKind string
+
+const (
+	// JobArtifactReasoningTrace is the planner's full reasoning, too large for the job's result
+	JobArtifactReasoningTrace JobArtifactKind = "REASONING_TRACE"
+	// JobArtifactRouteMap is a rendered map of the day's travel legs
+	JobArtifactRouteMap JobArtifactKind = "ROUTE_MAP"
+)
+
+// IsValid reports whether k is a known artifact kind
+func (k JobArtifactKind) IsValid() bool {
+	switch k {
+	case JobArtifactReasoningTrace, JobArtifactRouteMap:
+		return true
+	}
+	return false
+}
+
+// JobArtifact is a large output of a job kept in object storage rather than the database
+type JobArtifact struct {
+	ID          string          `json:"id" db:"id"`
+	JobID       string          `json:"jobId" db:"job_id"`
+	Kind        JobArtifactKind `json:"kind" db:"kind"`
+	StorageKey  string          `json:"-" db:"storage_key"`
+	ContentType string          `json:"contentType" db:"content_type"`
+	SizeBytes   *int64          `json:"sizeBytes" db:"size_bytes"`
+	CreatedAt   time.Time       `json:"createdAt" db:"created_at"`
+	// URL is a presigned download link, set when artifacts are read through the API
+	URL string `json:"url,omitempty" db:"-"`
+}
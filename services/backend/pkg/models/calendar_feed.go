@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// CalendarFeed is a user's secret ICS feed of their planned commutes and office days
+type CalendarFeed struct {
+	UserID    string    `json:"userId" db:"user_id"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	// URL holds the feed's secret token; it is only known when the feed is created
+	URL string `json:"url,omitempty" db:"-"`
+}
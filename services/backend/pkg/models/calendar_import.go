@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// CalendarImportFormat is the kind of file a calendar import reads
+type CalendarImportFormat string
+
+const (
+	CalendarImportICS CalendarImportFormat = "ICS"
+)
+
+// CalendarImportStatus is the state of a calendar import
+type CalendarImportStatus string
+
+const (
+	CalendarImportRunning   CalendarImportStatus = "RUNNING"
+	CalendarImportCompleted CalendarImportStatus = "COMPLETED"
+	// CalendarImportFailed imports stopped early; the events they added stay added
+	CalendarImportFailed CalendarImportStatus = "FAILED"
+)
+
+// CalendarImport is a calendar file a user imported, with how far its events have got
+type CalendarImport struct {
+	ID       string               `json:"id" db:"id"`
+	UserID   string               `json:"userId" db:"user_id"`
+	Filename string               `json:"filename" db:"filename"`
+	Format   CalendarImportFormat `json:"format" db:"format"`
+	Status   CalendarImportStatus `json:"status" db:"status"`
+	// TotalEvents are the events in the file; ProcessedEvents those added or rejected so
+	// far, and CreatedEvents those added
+	TotalEvents     int `json:"totalEvents" db:"total_events"`
+	ProcessedEvents int `json:"processedEvents" db:"processed_events"`
+	CreatedEvents   int `json:"createdEvents" db:"created_events"`
+	// Errors are the events that weren't added, and why
+	Errors       []ImportError `json:"errors" db:"errors"`
+	ErrorMessage *string       `json:"errorMessage" db:"error_message"`
+	StartedAt    time.Time     `json:"startedAt" db:"started_at"`
+	FinishedAt   *time.Time    `json:"finishedAt" db:"finished_at"`
+}
+
+// ImportError is an event of an imported file that wasn't added
+type ImportError struct {
+	// Index is the event's position in the file, from 0
+	Index int `json:"index"`
+	// UID is the event's UID in the file, if it has one
+	UID     *string `json:"uid,omitempty"`
+	Message string  `json:"message"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ClientOperationUsage counts the GraphQL requests one client version sent running an
+// operation
+type ClientOperationUsage struct {
+	ClientName    string `json:"clientName"`
+	ClientVersion string `json:"clientVersion"`
+	// Operation is the operation's name, or else its first field
+	Operation   string    `json:"operation"`
+	Requests    int64     `json:"requests"`
+	FirstUsedAt time.Time `json:"firstUsedAt"`
+	LastUsedAt  time.Time `json:"lastUsedAt"`
+}
+
+// ClientUsage is the GraphQL requests of one client version, by operation
+type ClientUsage struct {
+	ClientName    string    `json:"clientName"`
+	ClientVersion string    `json:"clientVersion"`
+	Requests      int64     `json:"requests"`
+	FirstUsedAt   time.Time `json:"firstUsedAt"`
+	LastUsedAt    time.Time `json:"lastUsedAt"`
+	// Operations are the most run first
+	Operations []*ClientOperationUsage `json:"operations"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// DeprecatedFieldUsage counts the requests one client sent selecting a deprecated GraphQL
+// field or argument
+type DeprecatedFieldUsage struct {
+	// Coordinate is the field or argument, e.g. CommuteRecommendation.travelLegs or
+	// Query.jobs(userId:)
+	Coordinate    string    `json:"coordinate"`
+	ClientName    string    `json:"clientName"`
+	ClientVersion string    `json:"clientVersion"`
+	Requests      int64     `json:"requests"`
+	FirstUsedAt   time.Time `json:"firstUsedAt"`
+	LastUsedAt    time.Time `json:"lastUsedAt"`
+}
+
+// DeprecatedField is a deprecated field or argument of the schema and the clients still
+// using it. Once none has for long enough, it can be removed.
+type DeprecatedField struct {
+	Coordinate string  `json:"coordinate"`
+	Reason     *string `json:"reason"`
+	// Sunset is the day (YYYY-MM-DD) the field is to be removed; nil without one
+	Sunset     *string `json:"sunset"`
+	PastSunset bool    `json:"pastSunset"`
+	// Requests and LastUsedAt are summed over Clients
+	Requests   int64                   `json:"requests"`
+	LastUsedAt *time.Time              `json:"lastUsedAt"`
+	Clients    []*DeprecatedFieldUsage `json:"clients"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ProposedMeetingChange is a change to one meeting to assess before making it. Moving
+// StartTime alone keeps the meeting's length; nil fields are unchanged.
+type ProposedMeetingChange struct {
+	StartTime      *time.Time      `json:"startTime"`
+	EndTime        *time.Time      `json:"endTime"`
+	AttendanceMode *AttendanceMode `json:"attendanceMode"`
+}
+
+// MeetingChangeImpact is what a proposed meeting change would do to the plans of the
+// days it touches: the meeting's day, and the day it moves to if that is another one
+type MeetingChangeImpact struct {
+	EventID string `json:"eventId"`
+	// PlanValid is set when the plan of every day touched still gets the user to each of
+	// its in-person meetings after the change
+	PlanValid bool               `json:"planValid"`
+	Days      []MeetingChangeDay `json:"days"`
+}
+
+// MeetingChangeDay is the impact of a meeting change on one day's plan. The plan is the
+// recommendation the user accepted, or else the top option of the day's latest completed
+// job; RecommendationID is nil on unplanned days.
+type MeetingChangeDay struct {
+	Date             string             `json:"date"`
+	RecommendationID *string            `json:"recommendationId"`
+	OptionType       *CommuteOptionType `json:"optionType"`
+	PlanValid        bool               `json:"planValid"`
+	// Conflicts are the in-person meetings the plan would miss after the change, and
+	// Resolved the ones it misses now that the change would fix
+	Conflicts []DigestConflict `json:"conflicts"`
+	Resolved  []DigestConflict `json:"resolved"`
+	// Changes say what the plan would need to change, e.g. "Arrive at the office by 08:30
+	// instead of 09:00"; empty when it can stay as it is
+	Changes []string `json:"changes"`
+}
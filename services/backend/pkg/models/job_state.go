@@ -0,0 +1,141 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// jobTransitions lists the statuses each status may move to. PENDING may fail directly
+// because the worker reports FAILED when it can't even start a job, and IN_PROGRESS may
+// return to PENDING when a stuck job is put back on the queue.
+var jobTransitions = map[JobStatus][]JobStatus{
+	JobStatusPending:    {JobStatusInProgress, JobStatusFailed, JobStatusCancelled},
+	JobStatusInProgress: {JobStatusCompleted, JobStatusFailed, JobStatusCancelled, JobStatusPending},
+}
+
+// JobEvent is one entry of a job's append-only status history. A change of step within
+// a status is an event from and to the same status.
+type JobEvent struct {
+	JobID        string     `json:"jobId" db:"job_id"`
+	Sequence     int        `json:"sequence" db:"sequence"`
+	FromStatus   *JobStatus `json:"fromStatus" db:"from_status"` // nil for the creation event
+	ToStatus     JobStatus  `json:"toStatus" db:"to_status"`
+	CurrentStep  *string    `json:"currentStep" db:"current_step"`
+	ErrorMessage *string    `json:"errorMessage" db:"error_message"`
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// JobTimelineEntry is a stretch of a job's history spent in one status and step
+type JobTimelineEntry struct {
+	Status JobStatus `json:"status"`
+	// Step is the worker's step; nil when the status was entered without one
+	Step         *string    `json:"step"`
+	ErrorMessage *string    `json:"errorMessage"`
+	StartedAt    time.Time  `json:"startedAt"`
+	EndedAt      *time.Time `json:"endedAt"`
+	// DurationSeconds is how long the entry lasted; nil while it lasts
+	DurationSeconds *float64 `json:"durationSeconds"`
+}
+
+// BuildJobTimeline turns a job's history, oldest first, into a timeline: an entry per
+// event, ending when the next one starts. A finished job's last entry is the instant it
+// finished; an unfinished job's last entry hasn't ended.
+func BuildJobTimeline(events []*JobEvent) []*JobTimelineEntry {
+	timeline := make([]*JobTimelineEntry, len(events))
+	for i, event := range events {
+		timeline[i] = &JobTimelineEntry{
+			Status:       event.ToStatus,
+			Step:         event.CurrentStep,
+			ErrorMessage: event.ErrorMessage,
+			StartedAt:    event.CreatedAt,
+		}
+		if i > 0 {
+			timeline[i-1].end(event.CreatedAt)
+		}
+	}
+	if last := len(timeline) - 1; last >= 0 && timeline[last].Status.IsTerminal() {
+		timeline[last].end(timeline[last].StartedAt)
+	}
+	return timeline
+}
+
+func (e *JobTimelineEntry) end(at time.Time) {
+	duration := at.Sub(e.StartedAt).Seconds()
+	e.EndedAt, e.DurationSeconds = &at, &duration
+}
+
+// JobStatusSummary is the status and progress of a job without its input or result,
+// for dashboards tracking many jobs
+type JobStatusSummary struct {
+	ID           string    `json:"id" db:"id"`
+	UserID       string    `json:"userId" db:"user_id"`
+	Status       JobStatus `json:"status" db:"status"`
+	Progress     float64   `json:"progress" db:"progress"`
+	CurrentStep  *string   `json:"currentStep" db:"current_step"`
+	ErrorMessage *string   `json:"errorMessage" db:"error_message"`
+	TargetDate   string    `json:"targetDate" db:"target_date"`
+	Version      int       `json:"version" db:"version"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// JobTransitionError reports a status change the state machine doesn't allow
+type JobTransitionError struct {
+	From JobStatus
+	To   JobStatus
+}
+
+func (e *JobTransitionError) Error() string {
+	return fmt.Sprintf("invalid job status transition from %s to %s", e.From, e.To)
+}
+
+// IsValid reports whether s is a known job status
+func (s JobStatus) IsValid() bool {
+	switch s {
+	case JobStatusPending, JobStatusInProgress, JobStatusCompleted, JobStatusFailed, JobStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether no further transitions are possible
+func (s JobStatus) IsTerminal() bool {
+	return s.IsValid() && len(jobTransitions[s]) == 0
+}
+
+// ValidateTransition checks a status change. Staying in the same status is allowed (the
+// worker repeats IN_PROGRESS with every progress update) and records no event.
+func (s JobStatus) ValidateTransition(next JobStatus) error {
+	if !next.IsValid() {
+		return fmt.Errorf("unknown job status %q", next)
+	}
+	if s == next {
+		return nil
+	}
+	for _, allowed := range jobTransitions[s] {
+		if allowed == next {
+			return nil
+		}
+	}
+	return &JobTransitionError{From: s, To: next}
+}
+
+// ReplayJobEvents derives a job's current status from its history, validating every step
+func ReplayJobEvents(events []*JobEvent) (JobStatus, error) {
+	if len(events) == 0 {
+		return "", fmt.Errorf("job has no events")
+	}
+	status := events[0].ToStatus
+	for i, event := range events[1:] {
+		if event.Sequence != i+2 {
+			return "", fmt.Errorf("job event sequence gap at %d", event.Sequence)
+		}
+		if event.FromStatus == nil || *event.FromStatus != status {
+			return "", fmt.Errorf("job event %d does not follow status %s", event.Sequence, status)
+		}
+		if err := status.ValidateTransition(event.ToStatus); err != nil {
+			return "", err
+		}
+		status = event.ToStatus
+	}
+	return status, nil
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// JSONColumn scans a JSON (JSONB in Postgres, TEXT in SQLite) column into V and stores V
+// as JSON, so repositories don't encode and decode by hand. NULL leaves V untouched.
+type JSONColumn struct {
+	V interface{}
+}
+
+// JSON wraps v, a pointer when scanning, for a JSON column
+func JSON(v interface{}) *JSONColumn {
+	return &JSONColumn{V: v}
+}
+
+// Scan implements sql.Scanner
+func (c *JSONColumn) Scan(src interface{}) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("JSON column: unsupported type %T", src)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, c.V)
+}
+
+// Value implements driver.Valuer
+func (c *JSONColumn) Value() (driver.Value, error) {
+	data, err := json.Marshal(c.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// TravelLeg is one trip of a recommendation's travel legs
+type TravelLeg struct {
+	From       *string     `json:"from"`
+	To         *string     `json:"to"`
+	MeetingID  *string     `json:"meetingId"`
+	Depart     *time.Time  `json:"depart"`
+	Arrive     *time.Time  `json:"arrive"`
+	Mode       *TravelMode `json:"mode"`
+	Minutes    *int        `json:"minutes"`
+	DistanceKm *float64    `json:"distanceKm"`
+	CO2Kg      *float64    `json:"co2Kg"`
+}
+
+// UnmarshalJSON decodes a leg as the AI service stores it, in snake_case with ISO 8601 times
+func (l *TravelLeg) UnmarshalJSON(data []byte) error {
+	var stored struct {
+		From       *string     `json:"from"`
+		To         *string     `json:"to"`
+		MeetingID  *string     `json:"meeting_id"`
+		Depart     string      `json:"depart"`
+		Arrive     string      `json:"arrive"`
+		Mode       *TravelMode `json:"mode"`
+		Minutes    *int        `json:"minutes"`
+		DistanceKm *float64    `json:"distance_km"`
+		CO2Kg      *float64    `json:"co2_kg"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	*l = TravelLeg{
+		From:       stored.From,
+		To:         stored.To,
+		MeetingID:  stored.MeetingID,
+		Depart:     parseTimestamp(stored.Depart),
+		Arrive:     parseTimestamp(stored.Arrive),
+		Mode:       stored.Mode,
+		Minutes:    stored.Minutes,
+		DistanceKm: stored.DistanceKm,
+		CO2Kg:      stored.CO2Kg,
+	}
+	return nil
+}
+
+// ModeOption is one travel mode a recommendation compared
+type ModeOption struct {
+	Mode          TravelMode     `json:"mode"`
+	Minutes       *int           `json:"minutes"`
+	DistanceKm    *float64       `json:"distanceKm"`
+	Cost          *float64       `json:"cost"`
+	CostBreakdown *CostBreakdown `json:"costBreakdown"`
+	CO2Kg         *float64       `json:"co2Kg"`
+	Feasible      *bool          `json:"feasible"`
+	Chosen        bool           `json:"chosen"`
+}
+
+// UnmarshalJSON decodes a mode option as the AI service stores it, in snake_case
+func (o *ModeOption) UnmarshalJSON(data []byte) error {
+	var stored struct {
+		Mode          TravelMode     `json:"mode"`
+		Minutes       *int           `json:"minutes"`
+		DistanceKm    *float64       `json:"distance_km"`
+		Cost          *float64       `json:"cost"`
+		CostBreakdown *CostBreakdown `json:"cost_breakdown"`
+		CO2Kg         *float64       `json:"co2_kg"`
+		Feasible      *bool          `json:"feasible"`
+		Chosen        bool           `json:"chosen"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	*o = ModeOption(stored)
+	return nil
+}
+
+// CostBreakdown splits a mode's cost
+type CostBreakdown struct {
+	Fuel       float64 `json:"fuel"`
+	Parking    float64 `json:"parking"`
+	Congestion float64 `json:"congestion"`
+	Fares      float64 `json:"fares"`
+}
+
+// Attendee is an entry of a calendar event's attendees. Synced calendars store plain
+// names or email addresses, imports and demo data {"email", "name"} objects; both decode.
+type Attendee struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// UnmarshalJSON accepts an object or a string, an email address when it has an @
+func (a *Attendee) UnmarshalJSON(data []byte) error {
+	var who string
+	if err := json.Unmarshal(data, &who); err == nil {
+		if strings.Contains(who, "@") {
+			*a = Attendee{Email: who}
+		} else {
+			*a = Attendee{Name: who}
+		}
+		return nil
+	}
+	type attendee Attendee
+	return json.Unmarshal(data, (*attendee)(a))
+}
+
+// parseTimestamp parses an ISO 8601 time; nil when it's empty or malformed
+func parseTimestamp(value string) *time.Time {
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &at
+}
+
+// decodeJSON unmarshals a JSON string column, leaving v empty when it's unset or malformed
+func decodeJSON(data *string, v interface{}) {
+	if data == nil || strings.TrimSpace(*data) == "" {
+		return
+	}
+	_ = json.Unmarshal([]byte(*data), v)
+}
+
+// DecodeTravelLegs returns the recommendation's travel legs; none when it has none or
+// they're malformed
+func (r *CommuteRecommendation) DecodeTravelLegs() []TravelLeg {
+	var legs []TravelLeg
+	decodeJSON(r.TravelLegs, &legs)
+	return legs
+}
+
+// DecodeModeOptions returns the recommendation's mode options; none when it has none or
+// they're malformed
+func (r *CommuteRecommendation) DecodeModeOptions() []ModeOption {
+	var options []ModeOption
+	decodeJSON(r.ModeOptions, &options)
+	return options
+}
+
+// DecodeAttendees returns the event's attendees; none when it has none or they're
+// malformed
+func (e *CalendarEvent) DecodeAttendees() []Attendee {
+	var attendees []Attendee
+	decodeJSON(e.Attendees, &attendees)
+	return attendees
+}
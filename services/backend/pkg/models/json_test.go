@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeTravelLegs(t *testing.T) {
+	stored := `[{"from": "home", "to": "office", "depart": "2026-03-02T08:10:00+00:00", "arrive": "2026-03-02T08:55:00+00:00",
+		"minutes": 45, "distance_km": 12.5, "co2_kg": 2.1, "mode": "DRIVE"}]`
+	rec := &CommuteRecommendation{TravelLegs: &stored}
+	legs := rec.DecodeTravelLegs()
+	if len(legs) != 1 || legs[0].Depart == nil || legs[0].Depart.Hour() != 8 || *legs[0].DistanceKm != 12.5 || *legs[0].Mode != TravelModeDrive {
+		t.Fatalf("legs = %+v", legs)
+	}
+	// The API form is camelCase
+	encoded, _ := json.Marshal(legs[0])
+	var api map[string]interface{}
+	json.Unmarshal(encoded, &api)
+	if api["distanceKm"] != 12.5 || api["co2Kg"] != 2.1 {
+		t.Errorf("API leg = %s", encoded)
+	}
+
+	malformed := `{"legs": 1}`
+	if legs := (&CommuteRecommendation{TravelLegs: &malformed}).DecodeTravelLegs(); len(legs) != 0 {
+		t.Errorf("malformed legs decoded to %+v", legs)
+	}
+}
+
+func TestDecodeAttendees(t *testing.T) {
+	stored := `["Ada Lovelace", "bob@example.com", {"email": "cy@example.com", "name": "Cy"}]`
+	attendees := (&CalendarEvent{Attendees: &stored}).DecodeAttendees()
+	want := []Attendee{{Name: "Ada Lovelace"}, {Email: "bob@example.com"}, {Email: "cy@example.com", Name: "Cy"}}
+	if len(attendees) != len(want) {
+		t.Fatalf("attendees = %+v, want %+v", attendees, want)
+	}
+	for i := range want {
+		if attendees[i] != want[i] {
+			t.Errorf("attendee %d = %+v, want %+v", i, attendees[i], want[i])
+		}
+	}
+}
+
+func TestJSONColumn(t *testing.T) {
+	var amenities []string
+	if err := JSON(&amenities).Scan([]byte(`["parking", "gym"]`)); err != nil || len(amenities) != 2 {
+		t.Fatalf("scanned %v, %v", amenities, err)
+	}
+	if err := JSON(&amenities).Scan(nil); err != nil || len(amenities) != 2 {
+		t.Errorf("NULL changed the value to %v (%v)", amenities, err)
+	}
+	value, err := JSON([]TravelMode{TravelModeBike}).Value()
+	if err != nil || value != `["BIKE"]` {
+		t.Errorf("value = %v, %v", value, err)
+	}
+}
@@ -11,8 +11,24 @@ const (
 	JobStatusInProgress JobStatus = "IN_PROGRESS"
 	JobStatusCompleted  JobStatus = "COMPLETED"
 	JobStatusFailed     JobStatus = "FAILED"
+	JobStatusCancelled  JobStatus = "CANCELLED"
 )
 
+// JobPriority decides which worker queue a job goes to. Workers drain the interactive
+// queue before touching batch jobs, so nightly re-planning never delays a user waiting
+// on a result.
+type JobPriority string
+
+const (
+	JobPriorityInteractive JobPriority = "INTERACTIVE"
+	JobPriorityBatch       JobPriority = "BATCH"
+)
+
+// IsValid reports whether p is a known job priority
+func (p JobPriority) IsValid() bool {
+	return p == JobPriorityInteractive || p == JobPriorityBatch
+}
+
 type CommuteOptionType string
 
 const (
@@ -37,6 +53,13 @@ const (
 	MeetingTypeUnknown           MeetingType = "UNKNOWN"
 )
 
+// MeetingTypes lists every meeting type
+var MeetingTypes = []MeetingType{
+	MeetingTypeClientMeeting, MeetingTypePresentation, MeetingTypeTeamWorkshop, MeetingTypeInterview,
+	MeetingTypeStakeholderMeeting, MeetingTypeOneOnOne, MeetingTypeStatusUpdate, MeetingTypeReview,
+	MeetingTypeBrainstorming, MeetingTypeCheckIn, MeetingTypeUnknown,
+}
+
 type AttendanceMode string
 
 const (
@@ -45,11 +68,83 @@ const (
 	AttendanceFlexible       AttendanceMode = "FLEXIBLE"
 )
 
+// AttendanceModes lists every attendance mode
+var AttendanceModes = []AttendanceMode{AttendanceMustBeInOffice, AttendanceCanBeRemote, AttendanceFlexible}
+
+// TravelMode is a way of getting to work
+type TravelMode string
+
+const (
+	TravelModeDrive   TravelMode = "DRIVE"
+	TravelModeTransit TravelMode = "TRANSIT"
+	TravelModeBike    TravelMode = "BIKE"
+	TravelModeWalk    TravelMode = "WALK"
+)
+
+// TravelModes lists every travel mode, fastest first on a typical commute
+var TravelModes = []TravelMode{TravelModeDrive, TravelModeTransit, TravelModeBike, TravelModeWalk}
+
+// DistanceUnit is the unit a user reads distances in
+type DistanceUnit string
+
+const (
+	DistanceUnitKm DistanceUnit = "KM"
+	DistanceUnitMi DistanceUnit = "MI"
+)
+
+// TimeFormat is how a user reads clock times
+type TimeFormat string
+
+const (
+	TimeFormat24Hour TimeFormat = "TWENTY_FOUR_HOUR"
+	TimeFormat12Hour TimeFormat = "TWELVE_HOUR"
+)
+
+// ShareRedaction is what a user's share links leave out of their plans
+type ShareRedaction string
+
+const (
+	// ShareRedactionNone shares the plans as the user sees them
+	ShareRedactionNone ShareRedaction = "NONE"
+	// ShareRedactionMeetingDetails strips meeting titles and attendees, and the text
+	// written about them; the default
+	ShareRedactionMeetingDetails ShareRedaction = "MEETING_DETAILS"
+	// ShareRedactionTimeBlocks keeps only each option's type and time blocks
+	ShareRedactionTimeBlocks ShareRedaction = "TIME_BLOCKS"
+)
+
 type User struct {
 	ID              string     `json:"id" db:"id"`
+	// TenantID is the company the user belongs to; "default" in single-tenant deployments
+	TenantID        string     `json:"tenantId" db:"tenant_id"`
 	Email           string     `json:"email" db:"email"`
 	Name            string     `json:"name" db:"name"`
 	UserPreferences *string    `json:"userPreferences" db:"user_preferences"`
+	// DefaultOfficeID is the office the user normally commutes to, if they picked one
+	DefaultOfficeID *string    `json:"defaultOfficeId" db:"default_office_id"`
+	// HomeAddress is where the user commutes from; the coordinates are geocoded from it
+	HomeAddress     *string    `json:"homeAddress" db:"home_address"`
+	HomeLatitude    *float64   `json:"homeLatitude" db:"home_latitude"`
+	HomeLongitude   *float64   `json:"homeLongitude" db:"home_longitude"`
+	// FocusMinutes is the shortest deep-work block the user wants kept free each day;
+	// nil when they haven't asked for one
+	FocusMinutes    *int       `json:"focusMinutes" db:"focus_minutes"`
+	// IsOrgAdmin lets the user read their tenant's org reports and set its office-day policy
+	IsOrgAdmin      bool       `json:"isOrgAdmin" db:"is_org_admin"`
+	// OrgReportingOptOut leaves the user's plans out of org reports
+	OrgReportingOptOut bool    `json:"orgReportingOptOut" db:"org_reporting_opt_out"`
+	// AnalyticsOptOut keeps the user's actions out of product analytics
+	AnalyticsOptOut bool       `json:"analyticsOptOut" db:"analytics_opt_out"`
+	// Locale is the language recommendation text is shown in, e.g. "de"; nil for English
+	Locale          *string    `json:"locale" db:"locale"`
+	// DistanceUnit, Currency (an ISO 4217 code) and TimeFormat are how digest emails,
+	// reminders and calendar feeds show distances, costs and times; nil for kilometres,
+	// bare amounts and 24-hour times
+	DistanceUnit    *DistanceUnit `json:"distanceUnit" db:"distance_unit"`
+	Currency        *string    `json:"currency" db:"currency"`
+	TimeFormat      *TimeFormat `json:"timeFormat" db:"time_format"`
+	// ShareRedaction is what the user's share links leave out; nil for MEETING_DETAILS
+	ShareRedaction  *ShareRedaction `json:"shareRedaction" db:"share_redaction"`
 	
 	// Auth fields - OAuth ready
 	AuthProvider     *string    `json:"authProvider" db:"auth_provider"`
@@ -57,7 +152,14 @@ type User struct {
 	IsEmailVerified  *bool      `json:"isEmailVerified" db:"is_email_verified"`
 	OAuthScopes      []string   `json:"oauthScopes" db:"oauth_scopes"`
 	LastLogin        *time.Time `json:"lastLogin" db:"last_login"`
+	// SCIMExternalID is the user's ID in the identity provider that provisions them over
+	// SCIM; nil for users who weren't provisioned
+	SCIMExternalID   *string    `json:"scimExternalId" db:"scim_external_id"`
+	// DeactivatedAt is when the user was deactivated; they can't sign in until reactivated
+	DeactivatedAt    *time.Time `json:"deactivatedAt" db:"deactivated_at"`
 	
+	// Version counts the user's updates; an update made with a stale one is rejected
+	Version         int        `json:"version" db:"version"`
 	CreatedAt       time.Time  `json:"createdAt" db:"created_at"`
 	UpdatedAt       time.Time  `json:"updatedAt" db:"updated_at"`
 }
@@ -66,16 +168,41 @@ type Job struct {
 	ID           string     `json:"id" db:"id"`
 	UserID       string     `json:"userId" db:"user_id"`
 	Status       JobStatus  `json:"status" db:"status"`
+	Priority     JobPriority `json:"priority" db:"priority"`
 	Progress     float64    `json:"progress" db:"progress"`
 	CurrentStep  *string    `json:"currentStep" db:"current_step"`
 	TargetDate   string     `json:"targetDate" db:"target_date"`
 	InputData    *string    `json:"inputData" db:"input_data"`
 	Result       *string    `json:"result" db:"result"`
 	ErrorMessage *string    `json:"errorMessage" db:"error_message"`
+	// ScheduledAt is when the job is enqueued, for jobs created ahead of time
+	ScheduledAt  *time.Time `json:"scheduledAt" db:"scheduled_at"`
+	// IsDemo marks jobs seeded with canned recommendations by demo data generation
+	IsDemo       bool       `json:"isDemo" db:"is_demo"`
+	// Experiment and Variant are the planner A/B test the job was planned under and its
+	// user's variant; nil when no experiment was running
+	Experiment   *string    `json:"experiment" db:"experiment"`
+	Variant      *string    `json:"variant" db:"variant"`
+	// FollowUpOf is the job whose plan this one re-optimizes for the rest of the day
+	// (replanNow); nil for other jobs
+	FollowUpOf   *string    `json:"followUpOf" db:"follow_up_of"`
+	// ReplayOf is the job whose inputs this one was created from again (replayJob); nil
+	// for other jobs
+	ReplayOf     *string    `json:"replayOf" db:"replay_of"`
+	// ClientName and ClientVersion are the client application the job was created from;
+	// nil for jobs not created by a client request
+	ClientName    *string   `json:"clientName" db:"client_name"`
+	ClientVersion *string   `json:"clientVersion" db:"client_version"`
+	// Version counts the job's updates; an update made with a stale one is rejected
+	Version      int        `json:"version" db:"version"`
 	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
 	UpdatedAt    time.Time  `json:"updatedAt" db:"updated_at"`
 	User         *User      `json:"user,omitempty"`
 	Recommendations []*CommuteRecommendation `json:"recommendations,omitempty"`
+	Timeline []*JobTimelineEntry `json:"timeline,omitempty"`
+	// EstimatedCompletionAt is when a running job should complete, going by how long its
+	// steps took in other jobs; nil when it can't be estimated
+	EstimatedCompletionAt *time.Time `json:"estimatedCompletionAt,omitempty"`
 }
 
 type CalendarEvent struct {
@@ -86,15 +213,24 @@ type CalendarEvent struct {
 	StartTime      time.Time      `json:"startTime" db:"start_time"`
 	EndTime        time.Time      `json:"endTime" db:"end_time"`
 	Location       *string        `json:"location" db:"location"`
+	// LocationLatitude and LocationLongitude are geocoded from Location in the background;
+	// nil until then, and for locations that aren't places (e.g. "Zoom")
+	LocationLatitude  *float64    `json:"locationLatitude" db:"location_latitude"`
+	LocationLongitude *float64    `json:"locationLongitude" db:"location_longitude"`
 	Attendees      *string        `json:"attendees" db:"attendees"`
 	MeetingType    MeetingType    `json:"meetingType" db:"meeting_type"`
 	AttendanceMode AttendanceMode `json:"attendanceMode" db:"attendance_mode"`
 	IsAllDay       bool           `json:"isAllDay" db:"is_all_day"`
 	IsRecurring    bool           `json:"isRecurring" db:"is_recurring"`
 	GoogleEventID  *string        `json:"googleEventId" db:"google_event_id"`
+	IsDemo         bool           `json:"isDemo" db:"is_demo"`
+	// Version counts the event's updates; an update made with a stale one is rejected
+	Version        int            `json:"version" db:"version"`
 	CreatedAt      time.Time      `json:"createdAt" db:"created_at"`
 	UpdatedAt      time.Time      `json:"updatedAt" db:"updated_at"`
 	User           *User          `json:"user,omitempty"`
+	// AttendeeList is Attendees decoded when events are read
+	AttendeeList []Attendee `json:"attendeeList,omitempty" db:"-"`
 }
 
 type CommuteRecommendation struct {
@@ -109,10 +245,565 @@ type CommuteRecommendation struct {
 	OfficeDuration         *string           `json:"officeDuration" db:"office_duration"`
 	OfficeMeetings         *string           `json:"officeMeetings" db:"office_meetings"`
 	RemoteMeetings         *string           `json:"remoteMeetings" db:"remote_meetings"`
+	// OffsiteMeetings are attended in person away from the office, e.g. at a client
+	OffsiteMeetings        *string           `json:"offsiteMeetings" db:"offsite_meetings"`
+	// TravelLegs is the day's route as JSON legs, including travel to offsite meetings
+	TravelLegs             *string           `json:"travelLegs" db:"travel_legs"`
+	// TravelMode is the mode the option plans with; nil for options without travel
+	TravelMode             *TravelMode       `json:"travelMode" db:"travel_mode"`
+	// ModeOptions compares every mode in the user's travel profile as JSON, with
+	// per-mode duration, cost and CO2
+	ModeOptions            *string           `json:"modeOptions" db:"mode_options"`
 	BusinessRuleCompliance *string           `json:"businessRuleCompliance" db:"business_rule_compliance"`
 	PerceptionAnalysis     *string           `json:"perceptionAnalysis" db:"perception_analysis"`
 	Reasoning              *string           `json:"reasoning" db:"reasoning"`
 	TradeOffs              *string           `json:"tradeOffs" db:"trade_offs"`
+	// ReasonCodes are the structured reasons Reasoning and TradeOffs were written from;
+	// nil for recommendations planned before reason codes existed
+	ReasonCodes            []ReasonCode      `json:"reasonCodes" db:"reason_codes"`
+	// OfficeID is the office the option commutes to; nil for remote options and for
+	// recommendations planned before offices existed
+	OfficeID               *string           `json:"officeId" db:"office_id"`
+	// Experiment and Variant are inherited from the job
+	Experiment             *string           `json:"experiment" db:"experiment"`
+	Variant                *string           `json:"variant" db:"variant"`
+	AcceptedAt             *time.Time        `json:"acceptedAt" db:"accepted_at"`
 	CreatedAt              time.Time         `json:"createdAt" db:"created_at"`
 	Job                    *Job              `json:"job,omitempty"`
-}
\ No newline at end of file
+	// PerceptionBreakdown is computed from the day's calendar when recommendations are read
+	PerceptionBreakdown *PerceptionBreakdown `json:"perceptionBreakdown,omitempty" db:"-"`
+	// Emissions is estimated from the travel modes and distances when recommendations are
+	// read; nil when the option's distances aren't known
+	Emissions *Emissions `json:"emissions,omitempty" db:"-"`
+	// Cost is the planned cost of the chosen travel mode, read from ModeOptions; nil
+	// when the option wasn't costed
+	Cost *CommuteCost `json:"cost,omitempty" db:"-"`
+	// FocusWindows are the free blocks of the day the option's travel leaves at least as
+	// long as the user's focus time, computed when recommendations are read; nil when the
+	// user hasn't set focus time
+	FocusWindows []FocusWindow `json:"focusWindows,omitempty" db:"-"`
+	// Legs and Modes are TravelLegs and ModeOptions decoded when recommendations are read
+	Legs  []TravelLeg  `json:"legs,omitempty" db:"-"`
+	Modes []ModeOption `json:"modes,omitempty" db:"-"`
+	// Localized is the reasoning and trade-offs in the user's locale, rendered from the
+	// reason codes when recommendations are read
+	Localized *LocalizedText `json:"localized,omitempty" db:"-"`
+}
+
+// FocusWindow is a free block of a day's working hours, long enough for deep work
+type FocusWindow struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Minutes int       `json:"minutes"`
+}
+
+// PerceptionBreakdown scores how visible a commute option keeps the user to leadership,
+// their manager and their team, as a weighted set of factors
+type PerceptionBreakdown struct {
+	// Score is 0-100, the weighted average of the applicable factors
+	Score      int                `json:"score"`
+	Visibility string             `json:"visibility"` // HIGH, MEDIUM or LOW
+	Factors    []PerceptionFactor `json:"factors"`
+}
+
+// PerceptionFactor is one visibility factor of a PerceptionBreakdown
+type PerceptionFactor struct {
+	Key    string `json:"key"`
+	Label  string `json:"label"`
+	Weight int    `json:"weight"`
+	Score  int    `json:"score"`
+	// Meetings is how many of the day's meetings the factor looked at, Favorable how many
+	// of them help visibility (attended in person, or camera on when remote)
+	Meetings  int    `json:"meetings"`
+	Favorable int    `json:"favorable"`
+	Detail    string `json:"detail"`
+}
+
+// Emissions is the CO2 a commute option's travel emits, in kg
+type Emissions struct {
+	// Trips is how many one-way trips the option makes; options without travel have none
+	Trips      int     `json:"trips"`
+	DistanceKm float64 `json:"distanceKm"`
+	CO2Kg      float64 `json:"co2Kg"`
+	// DriveCO2Kg is what the same day would emit by car, SavedCO2Kg the difference
+	DriveCO2Kg float64 `json:"driveCo2Kg"`
+	SavedCO2Kg float64 `json:"savedCo2Kg"`
+	// ByMode splits the travel by mode; options that mix modes (e.g. transit to the
+	// office, a drive to a client) have more than one
+	ByMode []ModeEmissions `json:"byMode"`
+}
+
+// ModeEmissions is the travel and CO2 of one travel mode
+type ModeEmissions struct {
+	Mode       TravelMode `json:"mode"`
+	Trips      int        `json:"trips"`
+	DistanceKm float64    `json:"distanceKm"`
+	CO2Kg      float64    `json:"co2Kg"`
+}
+
+// CommuteCost is what a commute option's travel costs, split by what it's paid for
+type CommuteCost struct {
+	Total float64 `json:"total"`
+	// Fuel and running costs of driving, per km
+	Fuel float64 `json:"fuel"`
+	// Parking per day, at the office when it sets a price
+	Parking float64 `json:"parking"`
+	// Congestion is the office's daily charge for driving into its area
+	Congestion float64 `json:"congestion"`
+	Fares      float64 `json:"fares"`
+}
+
+// MonthlyCommuteCost totals the cost of a user's commute history over a calendar month:
+// the recommendation they accepted for each day
+type MonthlyCommuteCost struct {
+	// Month is YYYY-MM in the user's timezone
+	Month       string `json:"month"`
+	CommuteDays int    `json:"commuteDays"`
+	RemoteDays  int    `json:"remoteDays"`
+	// UnestimatedDays are commute days that weren't costed; they're left out of the totals
+	UnestimatedDays int     `json:"unestimatedDays"`
+	Total           float64 `json:"total"`
+	Fuel            float64 `json:"fuel"`
+	Parking         float64 `json:"parking"`
+	Congestion      float64 `json:"congestion"`
+	Fares           float64 `json:"fares"`
+	// AvoidedCost is what remote days would have cost commuting to the office
+	AvoidedCost float64 `json:"avoidedCost"`
+}
+
+// CarbonStats totals the emissions of a user's commute history over a calendar period:
+// the recommendation they accepted for each day
+type CarbonStats struct {
+	UserID string `json:"userId"`
+	Period string `json:"period"`
+	// From and To are the period's first and last day (YYYY-MM-DD) in the user's timezone
+	From        string `json:"from"`
+	To          string `json:"to"`
+	CommuteDays int    `json:"commuteDays"`
+	RemoteDays  int    `json:"remoteDays"`
+	// UnestimatedDays are commute days whose distances aren't known; they're left out of
+	// the totals
+	UnestimatedDays int     `json:"unestimatedDays"`
+	Trips           int     `json:"trips"`
+	DistanceKm      float64 `json:"distanceKm"`
+	CO2Kg           float64 `json:"co2Kg"`
+	// DriveCO2Kg is what driving every commute, including the ones remote days avoided,
+	// would have emitted; SavedCO2Kg is the difference
+	DriveCO2Kg float64         `json:"driveCo2Kg"`
+	SavedCO2Kg float64         `json:"savedCo2Kg"`
+	ByMode     []ModeEmissions `json:"byMode"`
+}
+
+// ExperimentResults compares how often users accept the plans of each variant of a
+// planner experiment
+type ExperimentResults struct {
+	Experiment string `json:"experiment"`
+	// Control is the variant the others are compared against, when it is configured
+	Control  *string         `json:"control"`
+	Variants []VariantResult `json:"variants"`
+}
+
+// VariantResult is one variant's acceptance. A completed job counts as accepted when the
+// user accepted any of its recommendations, and as a top choice when they accepted the
+// first-ranked one.
+type VariantResult struct {
+	Variant           string  `json:"variant"`
+	Jobs              int     `json:"jobs"`
+	CompletedJobs     int     `json:"completedJobs"`
+	AcceptedJobs      int     `json:"acceptedJobs"`
+	TopChoiceAccepted int     `json:"topChoiceAccepted"`
+	AcceptanceRate    float64 `json:"acceptanceRate"`
+	TopChoiceRate     float64 `json:"topChoiceRate"`
+	// Lift is the acceptance rate's difference from the control's, and PValue the
+	// two-sided significance of that difference; both nil for the control itself or when
+	// there is nothing to compare
+	Lift   *float64 `json:"lift"`
+	PValue *float64 `json:"pValue"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "SUCCEEDED"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "FAILED"
+)
+
+type WebhookEndpoint struct {
+	ID     string   `json:"id" db:"id"`
+	UserID string   `json:"userId" db:"user_id"`
+	URL    string   `json:"url" db:"url"`
+	// Secret signs deliveries; it is only returned when the endpoint is created
+	Secret    string    `json:"secret,omitempty" db:"secret"`
+	Events    []string  `json:"events" db:"events"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             string                `json:"id" db:"id"`
+	EndpointID     string                `json:"endpointId" db:"endpoint_id"`
+	EventType      string                `json:"eventType" db:"event_type"`
+	Payload        string                `json:"payload" db:"payload"`
+	Status         WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts       int                   `json:"attempts" db:"attempts"`
+	LastStatusCode *int                  `json:"lastStatusCode" db:"last_status_code"`
+	LastError      *string               `json:"lastError" db:"last_error"`
+	NextAttemptAt  *time.Time            `json:"nextAttemptAt" db:"next_attempt_at"`
+	DeliveredAt    *time.Time            `json:"deliveredAt" db:"delivered_at"`
+	CreatedAt      time.Time             `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time             `json:"updatedAt" db:"updated_at"`
+}
+
+// GoogleCalendarChannel is a Google Calendar push notification channel (events.watch)
+type GoogleCalendarChannel struct {
+	ID         string    `json:"id" db:"id"`
+	UserID     string    `json:"userId" db:"user_id"`
+	CalendarID string    `json:"calendarId" db:"calendar_id"`
+	ResourceID string    `json:"resourceId" db:"resource_id"`
+	// Token is echoed back by Google in X-Goog-Channel-Token to authenticate notifications
+	Token        string     `json:"-" db:"token"`
+	Expiration   time.Time  `json:"expiration" db:"expiration"`
+	SyncToken    *string    `json:"-" db:"sync_token"`
+	LastSyncedAt *time.Time `json:"lastSyncedAt" db:"last_synced_at"`
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// JobOutboxEntry is a job whose push to the worker queue failed and is awaiting retry
+type JobOutboxEntry struct {
+	JobID         string      `json:"jobId" db:"job_id"`
+	UserID        string      `json:"userId" db:"user_id"`
+	TargetDate    string      `json:"targetDate" db:"target_date"`
+	InputData     *string     `json:"inputData" db:"input_data"`
+	Priority      JobPriority `json:"priority" db:"priority"`
+	Attempts      int         `json:"attempts" db:"attempts"`
+	LastError     *string     `json:"lastError" db:"last_error"`
+	NextAttemptAt time.Time   `json:"nextAttemptAt" db:"next_attempt_at"`
+	CreatedAt     time.Time   `json:"createdAt" db:"created_at"`
+}
+
+// Tenant is a company served by a multi-tenant deployment; its ID is also its subdomain
+type Tenant struct {
+	ID   string `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	// OfficeDaysPerWeek is the office-day policy org reports measure compliance with;
+	// nil when the tenant has none
+	OfficeDaysPerWeek *int      `json:"officeDaysPerWeek" db:"office_days_per_week"`
+	// SCIMEnabled is set once the tenant has a SCIM token for its identity provider
+	SCIMEnabled       bool      `json:"scimEnabled" db:"-"`
+	CreatedAt         time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Office is a location a tenant's users can commute to
+type Office struct {
+	ID        string   `json:"id" db:"id"`
+	Name      string   `json:"name" db:"name"`
+	Address   *string  `json:"address" db:"address"`
+	Latitude  *float64 `json:"latitude" db:"latitude"`
+	Longitude *float64 `json:"longitude" db:"longitude"`
+	// Amenities are free-form labels such as "parking", "gym" or "bike storage"
+	Amenities []string `json:"amenities" db:"amenities"`
+	// Capacity is the number of desks; nil if unknown
+	Capacity *int `json:"capacity" db:"capacity"`
+	// ParkingCost is per day and overrides the user's own; CongestionCharge is charged per
+	// day for driving there. Nil when unknown.
+	ParkingCost      *float64  `json:"parkingCost" db:"parking_cost"`
+	CongestionCharge *float64  `json:"congestionCharge" db:"congestion_charge"`
+	CreatedAt        time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt        time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// GeocodeCacheEntry is a cached geocoding result. Addresses that matched nothing are
+// cached too, with Found false, so they aren't looked up again.
+type GeocodeCacheEntry struct {
+	// AddressKey is the normalised address that was looked up
+	AddressKey       string    `json:"addressKey" db:"address_key"`
+	Found            bool      `json:"found" db:"found"`
+	Latitude         float64   `json:"latitude" db:"latitude"`
+	Longitude        float64   `json:"longitude" db:"longitude"`
+	FormattedAddress *string   `json:"formattedAddress" db:"formatted_address"`
+	Provider         string    `json:"provider" db:"provider"`
+	CreatedAt        time.Time `json:"createdAt" db:"created_at"`
+}
+
+// JobQuota overrides the default job quotas for one user. Nil limits fall back to the
+// defaults; Exempt lifts every limit.
+type JobQuota struct {
+	UserID        string    `json:"userId" db:"user_id"`
+	MaxQueuedJobs *int      `json:"maxQueuedJobs" db:"max_queued_jobs"`
+	MaxJobsPerDay *int      `json:"maxJobsPerDay" db:"max_jobs_per_day"`
+	Exempt        bool      `json:"exempt" db:"exempt"`
+	Reason        *string   `json:"reason" db:"reason"`
+	UpdatedAt     time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// TravelProfile is how a user is willing to get to work. Users without one are planned
+// with every travel mode and no preference.
+type TravelProfile struct {
+	UserID string       `json:"userId" db:"user_id"`
+	Modes  []TravelMode `json:"modes" db:"modes"`
+	// PreferredMode is planned with whenever it's feasible; nil picks the fastest mode
+	PreferredMode *TravelMode `json:"preferredMode" db:"preferred_mode"`
+	// Longest trip the user will bike or walk, in minutes; nil uses the planner's defaults
+	MaxBikeMinutes *int `json:"maxBikeMinutes" db:"max_bike_minutes"`
+	MaxWalkMinutes *int `json:"maxWalkMinutes" db:"max_walk_minutes"`
+	// ParkingCost is per day and TransitFare per trip; nil uses the planner's defaults
+	ParkingCost *float64 `json:"parkingCost" db:"parking_cost"`
+	TransitFare *float64 `json:"transitFare" db:"transit_fare"`
+	// FuelCostPerKm is what driving costs per km; nil uses the planner's default
+	FuelCostPerKm *float64 `json:"fuelCostPerKm" db:"fuel_cost_per_km"`
+	// UpdatedAt is nil for the default profile of users who haven't set one
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// PlanningConstraintType is a kind of one-off constraint a user adds to a job
+type PlanningConstraintType string
+
+const (
+	// ConstraintHomeBy: travel ends at home by Time ("must be home by 5:30")
+	ConstraintHomeBy PlanningConstraintType = "HOME_BY"
+	// ConstraintLeaveAfter: travel doesn't start before Time
+	ConstraintLeaveAfter PlanningConstraintType = "LEAVE_AFTER"
+	// ConstraintBusy: no travel between Start and End ("gym at 7am")
+	ConstraintBusy PlanningConstraintType = "BUSY"
+	// ConstraintAvoidMode: Mode isn't used ("no driving today")
+	ConstraintAvoidMode PlanningConstraintType = "AVOID_MODE"
+)
+
+// PlanningConstraint is a one-off constraint on a job's plan. Times are HH:MM on the
+// job's target date in the user's timezone; which fields are set depends on Type.
+type PlanningConstraint struct {
+	Type  PlanningConstraintType `json:"type"`
+	Time  *string                `json:"time,omitempty"`
+	Start *string                `json:"start,omitempty"`
+	End   *string                `json:"end,omitempty"`
+	Mode  *TravelMode            `json:"mode,omitempty"`
+	// Label is what the constraint is for, e.g. "gym", and is echoed in the reasoning
+	Label *string `json:"label,omitempty"`
+}
+
+// JobComparison is what changed between two plans of the same day, e.g. after a calendar
+// update: options added, dropped, re-ranked or retimed, and constraints added or removed.
+// Job A is the earlier plan and job B the later one.
+type JobComparison struct {
+	JobIDA     string `json:"jobIdA"`
+	JobIDB     string `json:"jobIdB"`
+	TargetDate string `json:"targetDate"`
+	// TopOptionChanged is set when a different option is ranked first
+	TopOptionChanged bool `json:"topOptionChanged"`
+	// Options has one entry per option type planned by either job, in job B's rank order
+	// followed by the options job B dropped
+	Options            []*OptionComparison  `json:"options"`
+	ConstraintsAdded   []PlanningConstraint `json:"constraintsAdded"`
+	ConstraintsRemoved []PlanningConstraint `json:"constraintsRemoved"`
+	// Summary describes the changes as sentences, most significant first; empty when
+	// the plans are the same
+	Summary []string `json:"summary"`
+}
+
+// OptionChangeStatus is how an option differs between two plans
+type OptionChangeStatus string
+
+const (
+	OptionAdded     OptionChangeStatus = "ADDED"
+	OptionRemoved   OptionChangeStatus = "REMOVED"
+	OptionChanged   OptionChangeStatus = "CHANGED"
+	OptionUnchanged OptionChangeStatus = "UNCHANGED"
+)
+
+// OptionComparison compares the recommendations of one option type in two plans
+type OptionComparison struct {
+	OptionType CommuteOptionType  `json:"optionType"`
+	Status     OptionChangeStatus `json:"status"`
+	// RankA and RankB are the option's rank in each plan; nil when the plan doesn't have it
+	RankA   *int          `json:"rankA"`
+	RankB   *int          `json:"rankB"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange is a recommendation field whose value differs between two plans. Times are
+// RFC 3339; nil means the field wasn't set.
+type FieldChange struct {
+	Field  string  `json:"field"`
+	Before *string `json:"before"`
+	After  *string `json:"after"`
+	// ShiftMinutes is how far a time moved, positive when later; nil for other fields
+	ShiftMinutes *int `json:"shiftMinutes"`
+}
+
+// PreferenceType is a kind of commute habit learned from a user's history
+type PreferenceType string
+
+const (
+	// PreferenceRemoteWeekday: works from home on Weekday ("never goes in on Fridays")
+	PreferenceRemoteWeekday PreferenceType = "REMOTE_WEEKDAY"
+	// PreferenceOfficeWeekday: goes into the office on Weekday
+	PreferenceOfficeWeekday PreferenceType = "OFFICE_WEEKDAY"
+	// PreferenceArriveBefore: arrives at the office by Time ("prefers arriving before 9")
+	PreferenceArriveBefore PreferenceType = "ARRIVE_BEFORE"
+	// PreferenceTravelMode: travels by Mode
+	PreferenceTravelMode PreferenceType = "TRAVEL_MODE"
+)
+
+// PreferenceStatus is whether the user has reviewed a learned preference
+type PreferenceStatus string
+
+const (
+	PreferenceLearned   PreferenceStatus = "LEARNED"
+	PreferenceConfirmed PreferenceStatus = "CONFIRMED"
+	PreferenceRejected  PreferenceStatus = "REJECTED"
+)
+
+// LearnedPreference is a habit derived from the recommendations a user accepted. The
+// planner treats the ones that aren't rejected as soft constraints. Which of Weekday,
+// Time (HH:MM in the user's timezone) and Mode are set depends on Type.
+type LearnedPreference struct {
+	// Key identifies the preference across re-learning, e.g. REMOTE_WEEKDAY:FRIDAY
+	Key     string         `json:"key"`
+	Type    PreferenceType `json:"type"`
+	Weekday *string        `json:"weekday"`
+	Time    *string        `json:"time"`
+	Mode    *TravelMode    `json:"mode"`
+	// Description states the preference as a sentence, e.g. "Works from home on Fridays"
+	Description string `json:"description"`
+	// Observations is how many days the preference was learned from, and Confidence the
+	// share of them that followed it; both are 0 for confirmed preferences the history
+	// no longer shows
+	Observations int              `json:"observations"`
+	Confidence   float64          `json:"confidence"`
+	Status       PreferenceStatus `json:"status"`
+}
+
+// PreferenceFeedback is a user's review of a learned preference. A confirmation may
+// correct the preference's Time or Mode.
+type PreferenceFeedback struct {
+	UserID    string           `json:"userId" db:"user_id"`
+	Key       string           `json:"key" db:"key"`
+	Status    PreferenceStatus `json:"status" db:"status"`
+	Time      *string          `json:"time" db:"time"`
+	Mode      *TravelMode      `json:"mode" db:"mode"`
+	UpdatedAt time.Time        `json:"updatedAt" db:"updated_at"`
+}
+
+// WeeklyDigest is a user's week at a glance: the plan of each day, the meetings that
+// need them in the office, the expected commute time and the conflicts between them
+type WeeklyDigest struct {
+	UserID string `json:"userId"`
+	// WeekStart and WeekEnd are the first and last day of the week (YYYY-MM-DD)
+	WeekStart  string `json:"weekStart"`
+	WeekEnd    string `json:"weekEnd"`
+	OfficeDays int    `json:"officeDays"`
+	RemoteDays int    `json:"remoteDays"`
+	// UnplannedDays have no completed plan yet; days the user is out of office aren't
+	// counted, they need none
+	UnplannedDays    int              `json:"unplannedDays"`
+	OutOfOfficeDays  int              `json:"outOfOfficeDays"`
+	CommuteMinutes   int              `json:"commuteMinutes"`
+	// CommuteDistanceKm and CommuteCost total the days they're known for; nil when no
+	// day's are
+	CommuteDistanceKm *float64        `json:"commuteDistanceKm"`
+	CommuteCost      *float64         `json:"commuteCost"`
+	InPersonMeetings int              `json:"inPersonMeetings"`
+	Days             []DigestDay      `json:"days"`
+	Conflicts        []DigestConflict `json:"conflicts"`
+}
+
+// DigestDay is one day of a weekly digest. The plan is the recommendation the user
+// accepted, or else the top option of the day's latest completed job; the plan fields are
+// nil on unplanned days.
+type DigestDay struct {
+	Date             string             `json:"date"`
+	Weekday          string             `json:"weekday"`
+	RecommendationID *string            `json:"recommendationId"`
+	OptionType       *CommuteOptionType `json:"optionType"`
+	Accepted         bool               `json:"accepted"`
+	InOffice         bool               `json:"inOffice"`
+	CommuteStart     *time.Time         `json:"commuteStart"`
+	OfficeArrival    *time.Time         `json:"officeArrival"`
+	OfficeDeparture  *time.Time         `json:"officeDeparture"`
+	CommuteEnd       *time.Time         `json:"commuteEnd"`
+	CommuteMinutes   int                `json:"commuteMinutes"`
+	// DistanceKm is the day's travel, both ways, and Cost what the plan's travel costs;
+	// nil when unknown
+	DistanceKm       *float64           `json:"distanceKm"`
+	Cost             *float64           `json:"cost"`
+	// InPersonMeetings are the day's meetings that must be attended in the office
+	InPersonMeetings []*CalendarEvent `json:"inPersonMeetings"`
+	// OutOfOffice is set when an all-day entry has the user out; Note says what the
+	// day's all-day entries mean for it, e.g. "Out of office: Vacation"
+	OutOfOffice bool    `json:"outOfOffice"`
+	Note        *string `json:"note"`
+}
+
+// DigestConflictType is how a week's plan clashes with a meeting
+type DigestConflictType string
+
+const (
+	// ConflictRemoteDay: an in-person meeting falls on a day planned remote
+	ConflictRemoteDay DigestConflictType = "REMOTE_DAY"
+	// ConflictOutsideOfficeHours: an in-person meeting runs outside the planned office window
+	ConflictOutsideOfficeHours DigestConflictType = "OUTSIDE_OFFICE_HOURS"
+	// ConflictUnplannedDay: an in-person meeting falls on a day without a plan
+	ConflictUnplannedDay DigestConflictType = "UNPLANNED_DAY"
+)
+
+// DigestConflict is an in-person meeting the week's plan doesn't get the user to
+type DigestConflict struct {
+	Date        string             `json:"date"`
+	Type        DigestConflictType `json:"type"`
+	EventID     string             `json:"eventId"`
+	Summary     string             `json:"summary"`
+	Description string             `json:"description"`
+}
+
+// NotificationSettings are the notifications a user opted into. Users without settings
+// get none.
+type NotificationSettings struct {
+	UserID string `json:"userId" db:"user_id"`
+	// WeeklyDigest emails a summary of the coming week on Sunday evening
+	WeeklyDigest bool `json:"weeklyDigest" db:"weekly_digest"`
+	// LastDigestWeek is the Monday (YYYY-MM-DD) of the week the last digest covered
+	LastDigestWeek *string `json:"lastDigestWeek" db:"last_digest_week"`
+	// CommuteReminders reminds the user to leave ReminderLeadMinutes before each
+	// departure of the plans they accept, over ReminderChannels
+	CommuteReminders    bool              `json:"commuteReminders" db:"commute_reminders"`
+	ReminderLeadMinutes int               `json:"reminderLeadMinutes" db:"reminder_lead_minutes"`
+	ReminderChannels    []ReminderChannel `json:"reminderChannels" db:"reminder_channels"`
+	// UpdatedAt is nil for the defaults of users who haven't saved settings
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// MeetingLoad is how heavy a user's day of meetings is, with what makes it too heavy
+type MeetingLoad struct {
+	// Date is the day analysed, YYYY-MM-DD in the user's timezone
+	Date     string `json:"date"`
+	Meetings int    `json:"meetings"`
+	// MeetingMinutes counts overlapping meetings once
+	MeetingMinutes int `json:"meetingMinutes"`
+	// LongestRunMinutes is the longest stretch of meetings without a break
+	LongestRunMinutes int                  `json:"longestRunMinutes"`
+	Warnings          []MeetingLoadWarning `json:"warnings"`
+}
+
+// MeetingLoadWarningType is a way a day of meetings is too heavy
+type MeetingLoadWarningType string
+
+const (
+	// MeetingLoadBackToBack: a run of meetings without a break is longer than the limit
+	MeetingLoadBackToBack MeetingLoadWarningType = "BACK_TO_BACK"
+	// MeetingLoadNoLunch: meetings leave no time for lunch
+	MeetingLoadNoLunch MeetingLoadWarningType = "NO_LUNCH"
+	// MeetingLoadTooManyHours: the day has more meeting hours than the limit
+	MeetingLoadTooManyHours MeetingLoadWarningType = "TOO_MANY_HOURS"
+)
+
+// MeetingLoadWarning is one problem with a day's meetings. Start and End bound the
+// meetings it is about; they are nil for warnings about the whole day.
+type MeetingLoadWarning struct {
+	Type    MeetingLoadWarningType `json:"type"`
+	Message string                 `json:"message"`
+	Start   *time.Time             `json:"start"`
+	End     *time.Time             `json:"end"`
+}
@@ -0,0 +1,46 @@
+package models
+
+// OrgReport aggregates the accepted plans of a tenant's users over a date range, for its
+// org admins. No figure identifies a user: users who opted out aren't counted, and
+// figures drawn from fewer than MinGroupSize users are withheld.
+type OrgReport struct {
+	TenantID string `json:"tenantId"`
+	// From and To are the first and last day reported (YYYY-MM-DD)
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Participants are the users with accepted plans in the range; OptedOut the users
+	// left out at their request
+	Participants int `json:"participants"`
+	OptedOut     int `json:"optedOut"`
+	MinGroupSize int `json:"minGroupSize"`
+	// Suppressed is set when fewer than MinGroupSize users took part; the figures below
+	// are then empty
+	Suppressed  bool `json:"suppressed"`
+	PlannedDays int  `json:"plannedDays"`
+	OfficeDays  int  `json:"officeDays"`
+	RemoteDays  int  `json:"remoteDays"`
+	// AttendanceRate is the share of planned days spent in the office
+	AttendanceRate *float64 `json:"attendanceRate"`
+	// AverageCommuteMinutes is the door-to-door travel of an office day, both ways
+	AverageCommuteMinutes *float64 `json:"averageCommuteMinutes"`
+	// OfficeDaysPerWeek is the tenant's office-day policy; nil without one
+	OfficeDaysPerWeek *int `json:"officeDaysPerWeek"`
+	// UserWeeks counts, over the full Monday-Sunday weeks of the range, each user's weeks
+	// with an accepted plan; CompliantUserWeeks those with at least OfficeDaysPerWeek
+	// office days. Compliance is their ratio, nil without a policy or full weeks.
+	UserWeeks          int      `json:"userWeeks"`
+	CompliantUserWeeks int      `json:"compliantUserWeeks"`
+	Compliance         *float64 `json:"compliance"`
+	// Offices break the office days down by office; offices fewer than MinGroupSize
+	// users went to are left out
+	Offices []OfficeAttendance `json:"offices"`
+}
+
+// OfficeAttendance is the office days spent at one office in an org report
+type OfficeAttendance struct {
+	OfficeID              string   `json:"officeId"`
+	Name                  string   `json:"name"`
+	Participants          int      `json:"participants"`
+	OfficeDays            int      `json:"officeDays"`
+	AverageCommuteMinutes *float64 `json:"averageCommuteMinutes"`
+}
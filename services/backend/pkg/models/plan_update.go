@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// PlanUpdateKind is what changed about a user's upcoming plans
+type PlanUpdateKind string
+
+const (
+	PlanUpdateJobCreated PlanUpdateKind = "JOB_CREATED"
+	// PlanUpdateRecommendationsReady: a job completed with new recommendations
+	PlanUpdateRecommendationsReady PlanUpdateKind = "RECOMMENDATIONS_READY"
+	PlanUpdateJobFailed            PlanUpdateKind = "JOB_FAILED"
+	PlanUpdatePlanAccepted         PlanUpdateKind = "PLAN_ACCEPTED"
+	// PlanUpdateCalendarConflict: a planned day's calendar changed and it is being
+	// re-planned
+	PlanUpdateCalendarConflict PlanUpdateKind = "CALENDAR_CONFLICT"
+	PlanUpdateReminderFired    PlanUpdateKind = "REMINDER_FIRED"
+)
+
+// PlanUpdate is a change to one of a user's upcoming jobs or accepted plans, pushed to
+// the myPlans subscription
+type PlanUpdate struct {
+	Kind PlanUpdateKind `json:"kind"`
+	// JobID is the job that changed; for a calendar conflict, the job re-planning the day
+	// when one was created, or else the stale one
+	JobID            *string `json:"jobId"`
+	RecommendationID *string `json:"recommendationId"`
+	// TargetDate is the day (YYYY-MM-DD) planned; nil when the event doesn't carry it
+	TargetDate *string   `json:"targetDate"`
+	At         time.Time `json:"at"`
+	// Data is the event as webhooks deliver it, as JSON
+	Data string `json:"data"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ReasonCode is a structured reason behind a recommendation, which pkg/i18n renders in
+// the user's language. Codes starting PRO_ and CON_ are the option's trade-offs; the rest
+// explain it, in order.
+type ReasonCode struct {
+	Code   string        `json:"code"`
+	Params []ReasonParam `json:"params"`
+}
+
+// ReasonParam is a value a reason's text is filled in with, e.g. a meeting count
+type ReasonParam struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Param returns the value of the named param, or "" without one
+func (c ReasonCode) Param(name string) string {
+	for _, p := range c.Params {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// UnmarshalJSON reads params either as a list of name/value pairs or as the
+// {"name": value} object the AI service writes, sorted by name
+func (c *ReasonCode) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Code   string          `json:"code"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	c.Code, c.Params = raw.Code, nil
+	if len(raw.Params) == 0 || string(raw.Params) == "null" {
+		return nil
+	}
+	if raw.Params[0] == '[' {
+		return json.Unmarshal(raw.Params, &c.Params)
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(raw.Params, &params); err != nil {
+		return err
+	}
+	for name, value := range params {
+		c.Params = append(c.Params, ReasonParam{Name: name, Value: fmt.Sprint(value)})
+	}
+	sort.Slice(c.Params, func(i, j int) bool { return c.Params[i].Name < c.Params[j].Name })
+	return nil
+}
+
+// LocalizedText is a recommendation's reasoning and trade-offs rendered from its reason
+// codes in one locale
+type LocalizedText struct {
+	Locale    string   `json:"locale"`
+	Reasoning string   `json:"reasoning"`
+	Pros      []string `json:"pros"`
+	Cons      []string `json:"cons"`
+}
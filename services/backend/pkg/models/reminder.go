@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// ReminderChannel is how a commute reminder reaches the user
+type ReminderChannel string
+
+const (
+	// ReminderChannelEmail emails the user
+	ReminderChannelEmail ReminderChannel = "EMAIL"
+	// ReminderChannelWebhook sends a commute.reminder event to the user's webhook
+	// endpoints, e.g. to push it to their phone
+	ReminderChannelWebhook ReminderChannel = "WEBHOOK"
+)
+
+// IsValid reports whether c is a known channel
+func (c ReminderChannel) IsValid() bool {
+	return c == ReminderChannelEmail || c == ReminderChannelWebhook
+}
+
+// CommuteReminderStatus is where a commute reminder is in its life
+type CommuteReminderStatus string
+
+const (
+	CommuteReminderScheduled CommuteReminderStatus = "SCHEDULED"
+	CommuteReminderSent      CommuteReminderStatus = "SENT"
+	// CommuteReminderCancelled reminders were dropped because the plan changed, the user
+	// turned reminders off or the departure passed before they could be sent
+	CommuteReminderCancelled CommuteReminderStatus = "CANCELLED"
+	CommuteReminderFailed    CommuteReminderStatus = "FAILED"
+)
+
+// CommuteReminder tells a user to leave for one departure of the plan they accepted
+type CommuteReminder struct {
+	ID               string `json:"id" db:"id"`
+	UserID           string `json:"userId" db:"user_id"`
+	RecommendationID string `json:"recommendationId" db:"recommendation_id"`
+	TargetDate       string `json:"targetDate" db:"target_date"`
+	// Summary says where the user is leaving for, e.g. "Leave for the office"
+	Summary      string                `json:"summary" db:"summary"`
+	DepartAt     time.Time             `json:"departAt" db:"depart_at"`
+	RemindAt     time.Time             `json:"remindAt" db:"remind_at"`
+	Channels     []ReminderChannel     `json:"channels" db:"channels"`
+	Status       CommuteReminderStatus `json:"status" db:"status"`
+	ErrorMessage *string               `json:"errorMessage" db:"error_message"`
+	SentAt       *time.Time            `json:"sentAt" db:"sent_at"`
+	CreatedAt    time.Time             `json:"createdAt" db:"created_at"`
+}
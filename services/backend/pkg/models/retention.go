@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// RetentionTrigger is what started a retention run
+type RetentionTrigger string
+
+const (
+	RetentionTriggerSchedule RetentionTrigger = "SCHEDULE"
+	RetentionTriggerAdmin    RetentionTrigger = "ADMIN"
+)
+
+// RetentionRunStatus is the state of a retention run
+type RetentionRunStatus string
+
+const (
+	RetentionRunRunning   RetentionRunStatus = "RUNNING"
+	RetentionRunCompleted RetentionRunStatus = "COMPLETED"
+	// RetentionRunFailed runs stopped early or couldn't archive some jobs; what they did
+	// archive stays archived
+	RetentionRunFailed RetentionRunStatus = "FAILED"
+)
+
+// RetentionRun is one pass of the archiver over finished jobs past their retention period
+type RetentionRun struct {
+	ID          string             `json:"id" db:"id"`
+	TriggeredBy RetentionTrigger   `json:"triggeredBy" db:"triggered_by"`
+	Status      RetentionRunStatus `json:"status" db:"status"`
+	// CompletedBefore and FailedBefore are the cutoffs: COMPLETED, and FAILED or
+	// CANCELLED, jobs last updated before them are archived. nil when they are kept.
+	CompletedBefore         *time.Time `json:"completedBefore" db:"completed_before"`
+	FailedBefore            *time.Time `json:"failedBefore" db:"failed_before"`
+	JobsArchived            int        `json:"jobsArchived" db:"jobs_archived"`
+	RecommendationsArchived int        `json:"recommendationsArchived" db:"recommendations_archived"`
+	ErrorMessage            *string    `json:"errorMessage" db:"error_message"`
+	StartedAt               time.Time  `json:"startedAt" db:"started_at"`
+	FinishedAt              *time.Time `json:"finishedAt" db:"finished_at"`
+}
+
+// ArchivedJob is a job moved out of the jobs table by retention, with the
+// recommendations and status history that were deleted along with it
+type ArchivedJob struct {
+	Job             Job                      `json:"job"`
+	Recommendations []*CommuteRecommendation `json:"recommendations"`
+	Events          []*JobEvent              `json:"events"`
+	ArchivedAt      time.Time                `json:"archivedAt" db:"archived_at"`
+}
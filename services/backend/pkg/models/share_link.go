@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ShareLink grants read-only access to a job's recommendations to anyone with its URL,
+// until it expires or its owner revokes it
+type ShareLink struct {
+	ID        string     `json:"id" db:"id"`
+	JobID     string     `json:"jobId" db:"job_id"`
+	UserID    string     `json:"userId" db:"user_id"`
+	ExpiresAt time.Time  `json:"expiresAt" db:"expires_at"`
+	RevokedAt *time.Time `json:"revokedAt" db:"revoked_at"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	// URL is the signed link, set when links are created or listed through the API
+	URL string `json:"url,omitempty" db:"-"`
+}
+
+// Active reports whether the link still grants access at now
+func (l *ShareLink) Active(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
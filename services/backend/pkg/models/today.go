@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Today is a user's day at a glance, compact enough for a phone widget or a watch
+// complication
+type Today struct {
+	// Date is today in the user's timezone, YYYY-MM-DD
+	Date string `json:"date"`
+	// Job is the latest job planning today; nil when the day hasn't been planned
+	Job *TodayJob `json:"job"`
+	// Plan is the option the user accepted for today, or else the top option of the
+	// latest completed job; nil without one
+	Plan *TodayPlan `json:"plan"`
+	// NextDeparture is the plan's next time to leave; nil when there's none left today
+	NextDeparture *time.Time `json:"nextDeparture"`
+	// InPersonMeetings are today's meetings that must be attended in the office
+	InPersonMeetings []TodayMeeting `json:"inPersonMeetings"`
+}
+
+// TodayJob is the live status of the job planning today
+type TodayJob struct {
+	ID          string    `json:"id"`
+	Status      JobStatus `json:"status"`
+	Progress    float64   `json:"progress"`
+	CurrentStep *string   `json:"currentStep"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// TodayPlan is the commute option planned for today
+type TodayPlan struct {
+	RecommendationID string            `json:"recommendationId"`
+	OptionType       CommuteOptionType `json:"optionType"`
+	Accepted         bool              `json:"accepted"`
+	InOffice         bool              `json:"inOffice"`
+	TravelMode       *TravelMode       `json:"travelMode"`
+	CommuteStart     *time.Time        `json:"commuteStart"`
+	OfficeArrival    *time.Time        `json:"officeArrival"`
+	OfficeDeparture  *time.Time        `json:"officeDeparture"`
+	CommuteEnd       *time.Time        `json:"commuteEnd"`
+}
+
+// TodayMeeting is a meeting of today's plan
+type TodayMeeting struct {
+	ID        string    `json:"id"`
+	Summary   string    `json:"summary"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Location  *string   `json:"location"`
+}
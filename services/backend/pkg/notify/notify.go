@@ -0,0 +1,166 @@
+// Package notify emails users. Deployments configure an SMTP relay; without one, in
+// development, messages are logged instead of sent.
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email to one user
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Notifier delivers messages
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPConfig describes an SMTP relay. Username and Password are optional; STARTTLS is
+// used whenever the server offers it.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// From is the sender address, e.g. "Commute Planner <planner@example.com>"
+	From string
+	// Timeout bounds each delivery when the context has no earlier deadline
+	Timeout time.Duration
+}
+
+// SMTPNotifier sends messages through an SMTP relay
+type SMTPNotifier struct {
+	cfg  SMTPConfig
+	from string
+}
+
+// NewSMTP validates cfg and creates a notifier
+func NewSMTP(cfg SMTPConfig) (*SMTPNotifier, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("SMTP host is required")
+	}
+	if cfg.Port <= 0 {
+		cfg.Port = 587
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	from, err := envelopeAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", cfg.From, err)
+	}
+	return &SMTPNotifier{cfg: cfg, from: from}, nil
+}
+
+// Send delivers msg, giving up when ctx is done
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	to, err := envelopeAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.cfg.Host}); err != nil {
+			return fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+	if n.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(format(n.cfg.From, msg, time.Now())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// LogNotifier logs messages instead of sending them, for development
+type LogNotifier struct{}
+
+// Send logs msg
+func (LogNotifier) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email to %s (not sent, no SMTP relay configured): %s\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// format renders msg as an RFC 5322 message with CRLF line endings
+func format(from string, msg Message, date time.Time) []byte {
+	var b strings.Builder
+	header := func(name, value string) {
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+	text := strings.ReplaceAll(msg.Text, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+	if !strings.HasSuffix(text, "\n") {
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
+
+// envelopeAddress returns the bare address of "Name <address>" or "address", rejecting
+// anything that could inject SMTP commands or headers
+func envelopeAddress(address string) (string, error) {
+	if strings.ContainsAny(address, "\r\n") {
+		return "", fmt.Errorf("contains a line break")
+	}
+	address = strings.TrimSpace(address)
+	if start := strings.LastIndex(address, "<"); start >= 0 && strings.HasSuffix(address, ">") {
+		address = address[start+1 : len(address)-1]
+	}
+	if at := strings.LastIndex(address, "@"); at <= 0 || at == len(address)-1 || strings.ContainsAny(address, " <>") {
+		return "", fmt.Errorf("not an email address")
+	}
+	return address, nil
+}
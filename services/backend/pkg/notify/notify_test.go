@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	got := string(format("Planner <planner@example.com>", Message{
+		To:      "ada@example.com",
+		Subject: "Your week ahead — 3 office days",
+		Text:    "Monday: office\nTuesday: remote",
+	}, date))
+
+	for _, want := range []string{
+		"From: Planner <planner@example.com>\r\n",
+		"To: ada@example.com\r\n",
+		"Subject: =?utf-8?q?Your_week_ahead_=E2=80=94_3_office_days?=\r\n",
+		"Date: Sun, 01 Mar 2026 18:00:00 +0000\r\n",
+		"\r\n\r\nMonday: office\r\nTuesday: remote\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message is missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestEnvelopeAddress(t *testing.T) {
+	for input, want := range map[string]string{
+		"ada@example.com":                  "ada@example.com",
+		"Ada Lovelace <ada@example.com>":   "ada@example.com",
+		" <planner@mail.example.com> ":     "planner@mail.example.com",
+		"ada@example.com\r\nRCPT TO:<x@y>": "",
+		"not an address":                   "",
+		"@example.com":                     "",
+	} {
+		got, err := envelopeAddress(input)
+		if want == "" {
+			if err == nil {
+				t.Errorf("envelopeAddress(%q) = %q, want an error", input, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("envelopeAddress(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package orgreport aggregates accepted plans into the org-level reports of a tenant.
+// Plans come in anonymized, numbered by participant for one report only, and the report
+// withholds figures drawn from too few participants to hide any one of them.
+package orgreport
+
+import (
+	"math"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// DefaultMinGroupSize is the fewest users a reported figure is drawn from, unless
+// configured otherwise
+const DefaultMinGroupSize = 5
+
+// Plan is one day a participant accepted a plan for
+type Plan struct {
+	// Participant numbers the plan's user within one report; it isn't their ID
+	Participant int
+	// Date is the planned day, at midnight UTC
+	Date     time.Time
+	InOffice bool
+	OfficeID *string
+	// CommuteMinutes is the day's door-to-door travel, both ways
+	CommuteMinutes int
+}
+
+// Options are what a report covers and how it is anonymized
+type Options struct {
+	// From and To are the first and last day reported, at midnight UTC
+	From, To time.Time
+	// OfficeDaysPerWeek is the policy compliance is measured against; nil for none
+	OfficeDaysPerWeek *int
+	// MinGroupSize is the fewest participants a figure is drawn from; DefaultMinGroupSize
+	// when not positive
+	MinGroupSize int
+}
+
+// Build aggregates plans, which must fall within the options' range
+func Build(plans []Plan, opts Options) *models.OrgReport {
+	if opts.MinGroupSize <= 0 {
+		opts.MinGroupSize = DefaultMinGroupSize
+	}
+	report := &models.OrgReport{
+		From:              opts.From.Format("2006-01-02"),
+		To:                opts.To.Format("2006-01-02"),
+		MinGroupSize:      opts.MinGroupSize,
+		OfficeDaysPerWeek: opts.OfficeDaysPerWeek,
+		Offices:           []models.OfficeAttendance{},
+	}
+	participants := map[int]bool{}
+	for _, plan := range plans {
+		participants[plan.Participant] = true
+	}
+	report.Participants = len(participants)
+	if report.Participants < opts.MinGroupSize {
+		report.Suppressed = true
+		return report
+	}
+
+	type office struct {
+		participants map[int]bool
+		days         int
+		minutes      int
+	}
+	offices := map[string]*office{}
+	var order []string
+	commuteMinutes := 0
+	for _, plan := range plans {
+		report.PlannedDays++
+		if !plan.InOffice {
+			report.RemoteDays++
+			continue
+		}
+		report.OfficeDays++
+		commuteMinutes += plan.CommuteMinutes
+		if plan.OfficeID == nil {
+			continue
+		}
+		o, ok := offices[*plan.OfficeID]
+		if !ok {
+			o = &office{participants: map[int]bool{}}
+			offices[*plan.OfficeID] = o
+			order = append(order, *plan.OfficeID)
+		}
+		o.participants[plan.Participant] = true
+		o.days++
+		o.minutes += plan.CommuteMinutes
+	}
+	report.AttendanceRate = ratio(report.OfficeDays, report.PlannedDays)
+	report.AverageCommuteMinutes = average(commuteMinutes, report.OfficeDays)
+	for _, id := range order {
+		o := offices[id]
+		if len(o.participants) < opts.MinGroupSize {
+			continue
+		}
+		report.Offices = append(report.Offices, models.OfficeAttendance{
+			OfficeID:              id,
+			Participants:          len(o.participants),
+			OfficeDays:            o.days,
+			AverageCommuteMinutes: average(o.minutes, o.days),
+		})
+	}
+
+	if opts.OfficeDaysPerWeek != nil {
+		report.UserWeeks, report.CompliantUserWeeks = compliance(plans, opts.From, opts.To, *opts.OfficeDaysPerWeek)
+		report.Compliance = ratio(report.CompliantUserWeeks, report.UserWeeks)
+	}
+	return report
+}
+
+// compliance counts the participants' full Monday-Sunday weeks in [from, to] with an
+// accepted plan, and those of them with at least officeDaysPerWeek office days. Partial
+// weeks at either end aren't counted; they can't meet a weekly policy fairly.
+func compliance(plans []Plan, from, to time.Time, officeDaysPerWeek int) (weeks, compliant int) {
+	firstMonday := from.AddDate(0, 0, (8-int(from.Weekday()))%7)
+	end := to.AddDate(0, 0, 1)
+	type userWeek struct {
+		participant int
+		week        time.Time
+	}
+	officeDays := map[userWeek]int{}
+	for _, plan := range plans {
+		if plan.Date.Before(firstMonday) {
+			continue
+		}
+		week := plan.Date.AddDate(0, 0, -((int(plan.Date.Weekday()) + 6) % 7))
+		if week.AddDate(0, 0, 7).After(end) {
+			continue
+		}
+		key := userWeek{plan.Participant, week}
+		days := officeDays[key]
+		if plan.InOffice {
+			days++
+		}
+		officeDays[key] = days
+	}
+	for _, days := range officeDays {
+		weeks++
+		if days >= officeDaysPerWeek {
+			compliant++
+		}
+	}
+	return weeks, compliant
+}
+
+// ratio is part/whole to three decimals, or nil for an empty whole
+func ratio(part, whole int) *float64 {
+	if whole == 0 {
+		return nil
+	}
+	r := math.Round(float64(part)/float64(whole)*1000) / 1000
+	return &r
+}
+
+// average is total/count to one decimal, or nil without any
+func average(total, count int) *float64 {
+	if count == 0 {
+		return nil
+	}
+	a := math.Round(float64(total)/float64(count)*10) / 10
+	return &a
+}
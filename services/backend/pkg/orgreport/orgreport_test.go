@@ -0,0 +1,64 @@
+package orgreport
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuild(t *testing.T) {
+	// Tuesday 2026-03-03 to Sunday 2026-03-15: one partial week, then a full one
+	from := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	hq, annex := "hq", "annex"
+
+	var plans []Plan
+	// Five participants at HQ on Monday 9th and Tuesday 10th; participant 0 also goes on
+	// Wednesday and in the partial week
+	for p := 0; p < 5; p++ {
+		plans = append(plans,
+			Plan{Participant: p, Date: day(9), InOffice: true, OfficeID: &hq, CommuteMinutes: 60},
+			Plan{Participant: p, Date: day(10), InOffice: true, OfficeID: &hq, CommuteMinutes: 60},
+			Plan{Participant: p, Date: day(11)},
+		)
+	}
+	plans = append(plans,
+		Plan{Participant: 0, Date: day(12), InOffice: true, OfficeID: &hq, CommuteMinutes: 90},
+		Plan{Participant: 0, Date: day(4), InOffice: true, OfficeID: &hq, CommuteMinutes: 60},
+		// One participant at the annex: too few to report on its own
+		Plan{Participant: 1, Date: day(13), InOffice: true, OfficeID: &annex, CommuteMinutes: 20},
+	)
+
+	policy := 3
+	report := Build(plans, Options{From: from, To: to, OfficeDaysPerWeek: &policy})
+	if report.Suppressed || report.Participants != 5 || report.MinGroupSize != DefaultMinGroupSize {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.PlannedDays != 18 || report.OfficeDays != 13 || report.RemoteDays != 5 {
+		t.Errorf("days = %d planned, %d office, %d remote", report.PlannedDays, report.OfficeDays, report.RemoteDays)
+	}
+	if *report.AttendanceRate != 0.722 {
+		t.Errorf("attendance rate = %v, want 0.722", *report.AttendanceRate)
+	}
+	// (10*60 + 90 + 60 + 20) / 13
+	if *report.AverageCommuteMinutes != 59.2 {
+		t.Errorf("average commute = %v, want 59.2", *report.AverageCommuteMinutes)
+	}
+	if len(report.Offices) != 1 || report.Offices[0].OfficeID != hq || report.Offices[0].OfficeDays != 12 {
+		t.Errorf("offices = %+v, want only HQ", report.Offices)
+	}
+	// Only the week of the 9th is full: participants 0 and 1 went in three times
+	if report.UserWeeks != 5 || report.CompliantUserWeeks != 2 || *report.Compliance != 0.4 {
+		t.Errorf("compliance = %d of %d (%v)", report.CompliantUserWeeks, report.UserWeeks, report.Compliance)
+	}
+
+	// Too few participants
+	small := Build(plans[:6], Options{From: from, To: to})
+	if !small.Suppressed || small.Participants != 2 || small.PlannedDays != 0 || small.AttendanceRate != nil {
+		t.Errorf("small report = %+v, want it suppressed", small)
+	}
+	// No policy, no compliance
+	if report := Build(plans, Options{From: from, To: to, MinGroupSize: 2}); report.Compliance != nil || len(report.Offices) != 1 {
+		t.Errorf("report without a policy = %+v", report)
+	}
+}
@@ -0,0 +1,203 @@
+// Package perception scores how a commute option affects the user's visibility at work:
+// whether leadership meetings, manager 1:1s and office-only meetings are attended in
+// person, and how many remote meetings still expect cameras on.
+package perception
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/allday"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// Visibility levels
+const (
+	VisibilityHigh   = "HIGH"
+	VisibilityMedium = "MEDIUM"
+	VisibilityLow    = "LOW"
+)
+
+// Factor keys
+const (
+	FactorLeadership = "LEADERSHIP_IN_PERSON"
+	FactorManager    = "MANAGER_ONE_ON_ONES"
+	FactorCameraOn   = "CAMERA_ON_REMOTE"
+	FactorOfficeOnly = "OFFICE_ONLY_MEETINGS"
+)
+
+// Factor weights. Factors with no matching meetings are left out and the remaining
+// weights renormalised; with none left the score is neutral.
+const (
+	leadershipWeight = 40
+	managerWeight    = 25
+	cameraOnWeight   = 20
+	officeOnlyWeight = 15
+	neutralScore     = 50
+
+	highVisibilityFrom = 70
+	lowVisibilityBelow = 40
+	// smallMeetingSize is the largest meeting assumed to run with cameras on
+	smallMeetingSize = 4
+)
+
+var (
+	leadershipPattern = regexp.MustCompile(`(?i)\b(ceo|cto|cfo|coo|cpo|chief|vp|svp|evp|vice president|director|head of|leadership|executive|exec|board|all[- ]hands|town ?hall|skip[- ]level)\b`)
+	oneOnOnePattern   = regexp.MustCompile(`(?i)(\b1\s*:\s*1\b|\b1[- ]?on[- ]?1\b|\bone[- ]on[- ]one\b)`)
+	cameraPattern     = regexp.MustCompile(`(?i)\b(cameras? on|video on|on camera)\b`)
+)
+
+// Analyze scores rec against the day's calendar events. Meetings inside the option's
+// office window count as attended in person; all-day entries and out-of-office blocks
+// aren't meetings and are ignored.
+func Analyze(rec *models.CommuteRecommendation, events []*models.CalendarEvent) *models.PerceptionBreakdown {
+	var leadership, manager, cameraOn, officeOnly tally
+	for _, event := range events {
+		if !allday.Busy(event) {
+			continue
+		}
+		inPerson := attendedInPerson(rec, event)
+
+		if isLeadershipMeeting(event) {
+			leadership.add(inPerson)
+		}
+		if isOneOnOne(event) {
+			manager.add(inPerson)
+		}
+		if event.AttendanceMode == models.AttendanceMustBeInOffice {
+			officeOnly.add(inPerson)
+		}
+		if !inPerson {
+			// For remote meetings "in person" means the camera is expected on
+			cameraOn.add(expectsCameraOn(event))
+		}
+	}
+
+	factors := []models.PerceptionFactor{
+		leadership.factor(FactorLeadership, "Leadership meetings in person", leadershipWeight,
+			"%d of %d meetings with leadership attended in person"),
+		manager.factor(FactorManager, "Manager 1:1s in person", managerWeight,
+			"%d of %d one-on-ones attended in person"),
+		cameraOn.factor(FactorCameraOn, "Camera-on remote meetings", cameraOnWeight,
+			"%d of %d remote meetings expect cameras on"),
+		officeOnly.factor(FactorOfficeOnly, "Office-only meetings in person", officeOnlyWeight,
+			"%d of %d meetings that need the office attended in person"),
+	}
+
+	breakdown := &models.PerceptionBreakdown{Factors: []models.PerceptionFactor{}}
+	var weighted, weights int
+	for _, factor := range factors {
+		if factor.Meetings == 0 {
+			continue
+		}
+		breakdown.Factors = append(breakdown.Factors, factor)
+		weighted += factor.Score * factor.Weight
+		weights += factor.Weight
+	}
+
+	breakdown.Score = neutralScore
+	if weights > 0 {
+		breakdown.Score = (weighted + weights/2) / weights
+	}
+	switch {
+	case breakdown.Score >= highVisibilityFrom:
+		breakdown.Visibility = VisibilityHigh
+	case breakdown.Score < lowVisibilityBelow:
+		breakdown.Visibility = VisibilityLow
+	default:
+		breakdown.Visibility = VisibilityMedium
+	}
+	return breakdown
+}
+
+// tally counts the meetings matching a factor and how many of them score
+type tally struct {
+	meetings, hits int
+}
+
+func (t *tally) add(hit bool) {
+	t.meetings++
+	if hit {
+		t.hits++
+	}
+}
+
+func (t tally) factor(key, label string, weight int, detail string) models.PerceptionFactor {
+	factor := models.PerceptionFactor{
+		Key:       key,
+		Label:     label,
+		Weight:    weight,
+		Meetings:  t.meetings,
+		Favorable: t.hits,
+		Detail:    fmt.Sprintf(detail, t.hits, t.meetings),
+	}
+	if t.meetings > 0 {
+		factor.Score = (100*t.hits + t.meetings/2) / t.meetings
+	}
+	return factor
+}
+
+// attendedInPerson reports whether the event falls within the option's office window
+func attendedInPerson(rec *models.CommuteRecommendation, event *models.CalendarEvent) bool {
+	if rec.OptionType == models.CommuteOptionFullRemoteRecommended || rec.OfficeArrival == nil || rec.OfficeDeparture == nil {
+		return false
+	}
+	return !event.StartTime.Before(*rec.OfficeArrival) && !event.EndTime.After(*rec.OfficeDeparture)
+}
+
+func isLeadershipMeeting(event *models.CalendarEvent) bool {
+	if event.MeetingType == models.MeetingTypeStakeholderMeeting {
+		return true
+	}
+	if leadershipPattern.MatchString(event.Summary) {
+		return true
+	}
+	for _, attendee := range attendees(event) {
+		if leadershipPattern.MatchString(attendee) {
+			return true
+		}
+	}
+	return false
+}
+
+func isOneOnOne(event *models.CalendarEvent) bool {
+	return event.MeetingType == models.MeetingTypeOneOnOne || oneOnOnePattern.MatchString(event.Summary)
+}
+
+// expectsCameraOn guesses whether a meeting is run with cameras on: external and
+// evaluative meetings, small meetings, and ones that say so
+func expectsCameraOn(event *models.CalendarEvent) bool {
+	switch event.MeetingType {
+	case models.MeetingTypeClientMeeting, models.MeetingTypeInterview, models.MeetingTypePresentation,
+		models.MeetingTypeStakeholderMeeting:
+		return true
+	}
+	if isOneOnOne(event) {
+		return true
+	}
+	if cameraPattern.MatchString(event.Summary) {
+		return true
+	}
+	if event.Description != nil && cameraPattern.MatchString(*event.Description) {
+		return true
+	}
+	count := len(attendees(event))
+	return count > 0 && count <= smallMeetingSize
+}
+
+// attendees returns the event's attendees as their name and email together, so either
+// can match
+func attendees(event *models.CalendarEvent) []string {
+	decoded := event.DecodeAttendees()
+	if decoded == nil {
+		return nil
+	}
+	list := make([]string, 0, len(decoded))
+	for _, attendee := range decoded {
+		if who := strings.TrimSpace(attendee.Name + " " + attendee.Email); who != "" {
+			list = append(list, who)
+		}
+	}
+	return list
+}
@@ -0,0 +1,92 @@
+package perception
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestAttendees(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"names", `["Ada Lovelace", "bob@example.com"]`, []string{"Ada Lovelace", "bob@example.com"}},
+		{"objects", `[{"email": "ada@example.com", "name": "Ada Lovelace"}, {"email": "cto@example.com"}]`,
+			[]string{"Ada Lovelace ada@example.com", "cto@example.com"}},
+		{"name only", `[{"name": "VP Sales"}]`, []string{"VP Sales"}},
+		{"blank objects are dropped", `[{}, {"email": ""}]`, []string{}},
+		{"not a list", `{"email": "ada@example.com"}`, nil},
+		{"malformed", `[`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &models.CalendarEvent{}
+			if tt.json != "" {
+				event.Attendees = &tt.json
+			}
+			if got := attendees(event); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("attendees(%s) = %#v, want %#v", tt.json, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time { return day.Add(time.Duration(hour) * time.Hour) }
+	arrive, leave := at(9), at(17)
+	office := &models.CommuteRecommendation{OptionType: models.CommuteOptionFullDayOffice, OfficeArrival: &arrive, OfficeDeparture: &leave}
+	remote := &models.CommuteRecommendation{OptionType: models.CommuteOptionFullRemoteRecommended}
+
+	event := func(summary string, start int, attendees string) *models.CalendarEvent {
+		e := &models.CalendarEvent{Summary: summary, StartTime: at(start), EndTime: at(start + 1), MeetingType: models.MeetingTypeUnknown}
+		if attendees != "" {
+			e.Attendees = &attendees
+		}
+		return e
+	}
+	// The director is only named in the attendee objects; the large standup isn't camera-on
+	events := []*models.CalendarEvent{
+		event("Quarterly planning", 10, `[{"email": "kim@example.com", "name": "Kim Lee (Director)"}]`),
+		event("Ada / Bob 1:1", 14, ""),
+		event("Standup", 18, `["a", "b", "c", "d", "e", "f"]`),
+		{Summary: "Offsite", StartTime: day, EndTime: day.Add(24 * time.Hour), IsAllDay: true},
+	}
+
+	tests := []struct {
+		name       string
+		rec        *models.CommuteRecommendation
+		events     []*models.CalendarEvent
+		wantScore  int
+		visibility string
+		factors    map[string][2]int // key: meetings, favorable
+	}{
+		{"no meetings is neutral", office, nil, neutralScore, VisibilityMedium, map[string][2]int{}},
+		{"office day", office, events, 76, VisibilityHigh, map[string][2]int{
+			FactorLeadership: {1, 1}, FactorManager: {1, 1}, FactorCameraOn: {1, 0},
+		}},
+		{"remote day", remote, events, 16, VisibilityLow, map[string][2]int{
+			FactorLeadership: {1, 0}, FactorManager: {1, 0}, FactorCameraOn: {3, 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Analyze(tt.rec, tt.events)
+			if got.Score != tt.wantScore || got.Visibility != tt.visibility {
+				t.Fatalf("score = %d %s, want %d %s", got.Score, got.Visibility, tt.wantScore, tt.visibility)
+			}
+			factors := map[string][2]int{}
+			for _, factor := range got.Factors {
+				factors[factor.Key] = [2]int{factor.Meetings, factor.Favorable}
+			}
+			if !reflect.DeepEqual(factors, tt.factors) {
+				t.Fatalf("factors = %v, want %v", factors, tt.factors)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+// Package planfeed streams changes to users' upcoming plans to the myPlans subscription.
+// It collects the events the backend already publishes to webhooks from every source (new
+// jobs and their recommendations, failures, accepted plans, calendar conflicts and fired
+// reminders) and pushes those about upcoming days to the user's subscribers. Updates are
+// relayed between instances over Redis pub/sub, so a subscriber hears of changes made on
+// any instance.
+//
+// Delivery is best effort: an update published while the relay is down, or to a
+// subscriber too slow to take it, is dropped. Clients refetch their plans on each update
+// and on reconnecting, so a dropped update only delays a refresh.
+package planfeed
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/webhooks"
+)
+
+// Channel is the pub/sub channel updates are relayed between instances on
+const Channel = "plan_updates"
+
+// subscriberBuffer is how many updates a subscriber may fall behind by before updates to
+// it are dropped
+const subscriberBuffer = 16
+
+// kinds maps the webhook events that change a user's plans to the update they push
+var kinds = map[string]models.PlanUpdateKind{
+	webhooks.EventJobCreated:             models.PlanUpdateJobCreated,
+	webhooks.EventJobCompleted:           models.PlanUpdateRecommendationsReady,
+	webhooks.EventJobFailed:              models.PlanUpdateJobFailed,
+	webhooks.EventRecommendationAccepted: models.PlanUpdatePlanAccepted,
+	webhooks.EventPlanStale:              models.PlanUpdateCalendarConflict,
+	webhooks.EventCommuteReminder:        models.PlanUpdateReminderFired,
+}
+
+// Relay carries updates between instances; implemented by redis.Client
+type Relay interface {
+	Broadcast(ctx context.Context, channel string, message []byte) error
+	Listen(ctx context.Context, channel string) <-chan []byte
+}
+
+// relayed is an update as relayed between instances
+type relayed struct {
+	UserID string             `json:"userId"`
+	Update *models.PlanUpdate `json:"update"`
+}
+
+// Feed pushes plan updates to the subscribers of each user
+type Feed struct {
+	relay Relay
+	now   func() time.Time
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan *models.PlanUpdate]bool
+}
+
+// New creates a feed relaying updates over relay; nil keeps them to this instance. Call
+// Run to receive relayed updates.
+func New(relay Relay) *Feed {
+	return &Feed{relay: relay, now: time.Now, subscribers: map[string]map[chan *models.PlanUpdate]bool{}}
+}
+
+// Subscribe returns the updates of the user's plans from now on. cancel ends the
+// subscription and closes the channel.
+func (f *Feed) Subscribe(userID string) (updates <-chan *models.PlanUpdate, cancel func()) {
+	ch := make(chan *models.PlanUpdate, subscriberBuffer)
+	f.mu.Lock()
+	if f.subscribers[userID] == nil {
+		f.subscribers[userID] = map[chan *models.PlanUpdate]bool{}
+	}
+	f.subscribers[userID][ch] = true
+	f.mu.Unlock()
+	metrics.PlanFeedSubscribers.Inc()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subscribers[userID], ch)
+			if len(f.subscribers[userID]) == 0 {
+				delete(f.subscribers, userID)
+			}
+			f.mu.Unlock()
+			close(ch)
+			metrics.PlanFeedSubscribers.Dec()
+		})
+	}
+}
+
+// Publish pushes an event of the user's to their subscribers, on every instance, if it
+// changes an upcoming plan. Its signature is webhooks.Dispatcher's, so it can take the
+// same events.
+func (f *Feed) Publish(ctx context.Context, userID, eventType string, data interface{}) error {
+	update, err := f.update(eventType, data)
+	if err != nil || update == nil {
+		return err
+	}
+	if f.relay != nil {
+		message, err := json.Marshal(relayed{UserID: userID, Update: update})
+		if err != nil {
+			return err
+		}
+		if err := f.relay.Broadcast(ctx, Channel, message); err == nil {
+			return nil
+		} else {
+			// This instance's subscribers can still be told
+			log.Printf("Plan feed: failed to relay a %s update: %v", update.Kind, err)
+		}
+	}
+	f.deliver(userID, update)
+	return nil
+}
+
+// Run delivers the updates relayed from every instance, this one included, until ctx is
+// cancelled
+func (f *Feed) Run(ctx context.Context) {
+	if f.relay == nil {
+		return
+	}
+	for message := range f.relay.Listen(ctx, Channel) {
+		var r relayed
+		if err := json.Unmarshal(message, &r); err != nil || r.Update == nil {
+			log.Printf("Plan feed: ignoring an unreadable update: %v", err)
+			continue
+		}
+		f.deliver(r.UserID, r.Update)
+	}
+}
+
+// deliver hands update to the user's subscribers on this instance
+func (f *Feed) deliver(userID string, update *models.PlanUpdate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subscribers[userID] {
+		select {
+		case ch <- update:
+			metrics.PlanUpdates.WithLabelValues("delivered").Inc()
+		default:
+			metrics.PlanUpdates.WithLabelValues("dropped").Inc()
+		}
+	}
+}
+
+// update describes an event as a plan update, or returns nil for events that don't change
+// an upcoming plan
+func (f *Feed) update(eventType string, data interface{}) (*models.PlanUpdate, error) {
+	kind, ok := kinds[eventType]
+	if !ok {
+		return nil, nil
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	// The fields the events' payloads (jobs, recommendations, stale plans and reminders)
+	// identify what changed by
+	var fields struct {
+		ID               string  `json:"id"`
+		JobID            string  `json:"jobId"`
+		RecommendationID string  `json:"recommendationId"`
+		StaleJobID       string  `json:"staleJobId"`
+		ReplanJobID      *string `json:"replanJobId"`
+		TargetDate       string  `json:"targetDate"`
+	}
+	json.Unmarshal(payload, &fields)
+
+	now := f.now()
+	// Upcoming days start yesterday in UTC, so no timezone's today is missed
+	if fields.TargetDate != "" && fields.TargetDate < now.UTC().AddDate(0, 0, -1).Format("2006-01-02") {
+		return nil, nil
+	}
+	update := &models.PlanUpdate{Kind: kind, At: now, Data: string(payload)}
+	switch kind {
+	case models.PlanUpdatePlanAccepted:
+		update.JobID, update.RecommendationID = optional(fields.JobID), optional(fields.ID)
+	case models.PlanUpdateCalendarConflict:
+		update.JobID = fields.ReplanJobID
+		if update.JobID == nil {
+			update.JobID = optional(fields.StaleJobID)
+		}
+	case models.PlanUpdateReminderFired:
+		update.RecommendationID = optional(fields.RecommendationID)
+	default:
+		update.JobID = optional(fields.ID)
+	}
+	update.TargetDate = optional(fields.TargetDate)
+	return update, nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// Publisher publishes events to webhooks and to the plan feed alike
+type Publisher struct {
+	*webhooks.Dispatcher
+	feed *Feed
+}
+
+// NewPublisher creates a publisher sending events to dispatcher and feed
+func NewPublisher(dispatcher *webhooks.Dispatcher, feed *Feed) *Publisher {
+	return &Publisher{Dispatcher: dispatcher, feed: feed}
+}
+
+// Publish queues the event for the user's webhook endpoints and pushes it to the feed.
+// Within a transaction the push waits for the commit (the webhook delivery is written in
+// the transaction), so subscribers aren't told of changes that get rolled back. Failing
+// to push it doesn't keep it from the webhooks.
+func (p *Publisher) Publish(ctx context.Context, userID, eventType string, data interface{}) error {
+	database.AfterCommit(ctx, func() {
+		if err := p.feed.Publish(ctx, userID, eventType, data); err != nil {
+			log.Printf("Plan feed: failed to push %s for user %s: %v", eventType, userID, err)
+		}
+	})
+	return p.Dispatcher.Publish(ctx, userID, eventType, data)
+}
@@ -0,0 +1,119 @@
+package planfeed
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/webhooks"
+)
+
+// loopback relays broadcasts back to its listener, or fails them when down
+type loopback struct {
+	messages chan []byte
+	down     bool
+}
+
+func (l *loopback) Broadcast(ctx context.Context, channel string, message []byte) error {
+	if l.down {
+		return errors.New("relay down")
+	}
+	l.messages <- message
+	return nil
+}
+
+func (l *loopback) Listen(ctx context.Context, channel string) <-chan []byte {
+	return l.messages
+}
+
+func receive(t *testing.T, updates <-chan *models.PlanUpdate) *models.PlanUpdate {
+	t.Helper()
+	select {
+	case update := <-updates:
+		return update
+	case <-time.After(time.Second):
+		t.Fatal("no update")
+		return nil
+	}
+}
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	feed := New(nil)
+	feed.now = func() time.Time { return time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC) }
+	updates, cancel := feed.Subscribe("u1")
+	defer cancel()
+	other, cancelOther := feed.Subscribe("u2")
+	defer cancelOther()
+
+	replan := "j2"
+	stale := map[string]interface{}{"userId": "u1", "targetDate": "2026-03-03", "staleJobId": "j1", "replanJobId": &replan}
+	if err := feed.Publish(ctx, "u1", webhooks.EventPlanStale, stale); err != nil {
+		t.Fatal(err)
+	}
+	update := receive(t, updates)
+	if update.Kind != models.PlanUpdateCalendarConflict || *update.JobID != "j2" || *update.TargetDate != "2026-03-03" {
+		t.Errorf("update = %+v", update)
+	}
+
+	accepted := &models.CommuteRecommendation{ID: "r1", JobID: "j2"}
+	feed.Publish(ctx, "u1", webhooks.EventRecommendationAccepted, accepted)
+	update = receive(t, updates)
+	if update.Kind != models.PlanUpdatePlanAccepted || *update.JobID != "j2" || *update.RecommendationID != "r1" {
+		t.Errorf("update = %+v", update)
+	}
+
+	// Past days and events that don't touch plans aren't pushed
+	feed.Publish(ctx, "u1", webhooks.EventJobCreated, &models.Job{ID: "j0", TargetDate: "2026-02-27"})
+	feed.Publish(ctx, "u1", "webhook.test", map[string]string{"targetDate": "2026-03-03"})
+	feed.Publish(ctx, "u1", webhooks.EventJobCreated, &models.Job{ID: "j3", TargetDate: "2026-03-01"})
+	if update := receive(t, updates); *update.JobID != "j3" {
+		t.Errorf("update = %+v, want yesterday's job j3 and nothing before it", update)
+	}
+	select {
+	case update := <-other:
+		t.Errorf("another user got %+v", update)
+	default:
+	}
+}
+
+func TestRelay(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	relay := &loopback{messages: make(chan []byte, 1)}
+	feed := New(relay)
+	go feed.Run(ctx)
+	updates, cancel := feed.Subscribe("u1")
+
+	feed.Publish(ctx, "u1", webhooks.EventCommuteReminder, map[string]string{"id": "rem1", "recommendationId": "r1"})
+	if update := receive(t, updates); update.Kind != models.PlanUpdateReminderFired || *update.RecommendationID != "r1" {
+		t.Errorf("relayed update = %+v", update)
+	}
+
+	// With the relay down, this instance's subscribers are still told
+	relay.down = true
+	feed.Publish(ctx, "u1", webhooks.EventJobFailed, &models.Job{ID: "j1"})
+	if update := receive(t, updates); update.Kind != models.PlanUpdateJobFailed {
+		t.Errorf("local update = %+v", update)
+	}
+
+	cancel()
+	if _, open := <-updates; open {
+		t.Error("cancelled subscription left open")
+	}
+	cancel()
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	feed := New(nil)
+	updates, cancel := feed.Subscribe("u1")
+	defer cancel()
+	for i := 0; i < subscriberBuffer+5; i++ {
+		feed.Publish(context.Background(), "u1", webhooks.EventJobCreated, &models.Job{ID: "j"})
+	}
+	if len(updates) != subscriberBuffer {
+		t.Errorf("%d updates buffered, want %d", len(updates), subscriberBuffer)
+	}
+}
@@ -0,0 +1,217 @@
+// Package planner orders the commute options of a planned day. The AI service plans the
+// options and suggests an order; when a job completes, the ranker selected for it can
+// re-rank them before they are shown, so alternative strategies can be tried (and A/B
+// tested) without changing the AI pipeline. Rankers register by name; deployments pick
+// one per tenant or experiment variant through a Selector.
+package planner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/commute-planner/backend/pkg/costs"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/perception"
+)
+
+// Built-in rankers
+const (
+	// RankerPlanner keeps the AI service's order
+	RankerPlanner = "planner"
+	// RankerRuleBased puts the options that keep the user most visible first
+	RankerRuleBased = "rule-based"
+	// RankerCostOptimized puts the cheapest options first
+	RankerCostOptimized = "cost-optimized"
+)
+
+// Day is what a ranker sees of one planned day
+type Day struct {
+	Job *models.Job
+	// Options are in the AI service's order
+	Options []*models.CommuteRecommendation
+	// Events are the user's calendar events on the job's target date
+	Events []*models.CalendarEvent
+}
+
+// Ranker orders a day's options
+type Ranker interface {
+	// Rank returns the day's options best first. It may reorder them but must not add
+	// or drop any.
+	Rank(ctx context.Context, day Day) ([]*models.CommuteRecommendation, error)
+}
+
+// RankerFunc adapts a function to Ranker
+type RankerFunc func(ctx context.Context, day Day) ([]*models.CommuteRecommendation, error)
+
+// Rank calls f
+func (f RankerFunc) Rank(ctx context.Context, day Day) ([]*models.CommuteRecommendation, error) {
+	return f(ctx, day)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Ranker{}
+)
+
+func init() {
+	Register(RankerPlanner, RankerFunc(func(ctx context.Context, day Day) ([]*models.CommuteRecommendation, error) {
+		return day.Options, nil
+	}))
+	Register(RankerRuleBased, RankerFunc(rankByVisibility))
+	Register(RankerCostOptimized, RankerFunc(rankByCost))
+}
+
+// Register makes a ranker selectable by name. It panics if the name is taken, like
+// database/sql's Register, since that is a programming error.
+func Register(name string, ranker Ranker) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if ranker == nil {
+		panic("planner: Register ranker is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("planner: Register called twice for ranker " + name)
+	}
+	registry[name] = ranker
+}
+
+// Lookup returns the ranker registered as name
+func Lookup(name string) (Ranker, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	ranker, ok := registry[name]
+	return ranker, ok
+}
+
+// Rankers returns the registered rankers' names, sorted
+func Rankers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rank orders day's options with ranker and renumbers their OptionRank from 1. It fails
+// when the ranker's result isn't a reordering of the options.
+func Rank(ctx context.Context, ranker Ranker, day Day) ([]*models.CommuteRecommendation, error) {
+	ranked, err := ranker.Rank(ctx, day)
+	if err != nil {
+		return nil, err
+	}
+	if len(ranked) != len(day.Options) {
+		return nil, fmt.Errorf("ranker returned %d options for %d", len(ranked), len(day.Options))
+	}
+	remaining := make(map[string]bool, len(day.Options))
+	for _, option := range day.Options {
+		remaining[option.ID] = true
+	}
+	for i, option := range ranked {
+		if option == nil || !remaining[option.ID] {
+			return nil, fmt.Errorf("ranker returned an option that isn't one of the day's")
+		}
+		delete(remaining, option.ID)
+		option.OptionRank = i + 1
+	}
+	return ranked, nil
+}
+
+// Selector picks the ranker of a job. An experiment variant mapped to a ranker wins over
+// the user's tenant, which wins over the default.
+type Selector struct {
+	// Default ranks jobs nothing else applies to; empty means RankerPlanner
+	Default string
+	// ByTenant maps tenant IDs to rankers
+	ByTenant map[string]string
+	// ByVariant maps planner experiment variants to rankers, to A/B test a ranker
+	ByVariant map[string]string
+}
+
+// Validate reports rankers the selector names that aren't registered
+func (s Selector) Validate() error {
+	names := []string{s.Default}
+	for _, name := range s.ByTenant {
+		names = append(names, name)
+	}
+	for _, name := range s.ByVariant {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, ok := Lookup(name); !ok {
+			return fmt.Errorf("unknown ranker %q (registered: %s)", name, strings.Join(Rankers(), ", "))
+		}
+	}
+	return nil
+}
+
+// Select returns the name of the ranker for a job of a user in tenantID, tagged with
+// variant (nil outside experiments)
+func (s Selector) Select(tenantID string, variant *string) string {
+	if variant != nil {
+		if name, ok := s.ByVariant[*variant]; ok {
+			return name
+		}
+	}
+	if name, ok := s.ByTenant[tenantID]; ok {
+		return name
+	}
+	if s.Default != "" {
+		return s.Default
+	}
+	return RankerPlanner
+}
+
+// ParseMapping reads "key=ranker" entries, e.g. "acme=cost-optimized"
+func ParseMapping(entries []string) (map[string]string, error) {
+	mapping := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		key, name, ok := strings.Cut(entry, "=")
+		key, name = strings.TrimSpace(key), strings.TrimSpace(name)
+		if !ok || key == "" || name == "" {
+			return nil, fmt.Errorf("invalid ranker mapping %q: expected key=ranker", entry)
+		}
+		mapping[key] = name
+	}
+	return mapping, nil
+}
+
+// rankByVisibility orders options by their perception score, highest first, keeping
+// the AI service's order between equal scores
+func rankByVisibility(ctx context.Context, day Day) ([]*models.CommuteRecommendation, error) {
+	scores := make(map[string]int, len(day.Options))
+	for _, option := range day.Options {
+		scores[option.ID] = perception.Analyze(option, day.Events).Score
+	}
+	ranked := append([]*models.CommuteRecommendation(nil), day.Options...)
+	sort.SliceStable(ranked, func(i, j int) bool { return scores[ranked[i].ID] > scores[ranked[j].ID] })
+	return ranked, nil
+}
+
+// rankByCost orders options by total cost, cheapest first; options that weren't costed
+// go last, and equal costs keep the AI service's order
+func rankByCost(ctx context.Context, day Day) ([]*models.CommuteRecommendation, error) {
+	totals := make(map[string]*float64, len(day.Options))
+	for _, option := range day.Options {
+		if cost := costs.Estimate(option); cost != nil {
+			totals[option.ID] = &cost.Total
+		}
+	}
+	ranked := append([]*models.CommuteRecommendation(nil), day.Options...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := totals[ranked[i].ID], totals[ranked[j].ID]
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a < *b
+	})
+	return ranked, nil
+}
@@ -0,0 +1,125 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// option is a recommendation whose chosen transit trip costs cost; nil leaves it uncosted
+func option(id string, cost *float64) *models.CommuteRecommendation {
+	mode := models.TravelModeTransit
+	options := `[{"mode":"TRANSIT","chosen":true}]`
+	if cost != nil {
+		options = fmt.Sprintf(`[{"mode":"TRANSIT","cost":%g,"chosen":true}]`, *cost)
+	}
+	return &models.CommuteRecommendation{ID: id, TravelMode: &mode, ModeOptions: &options}
+}
+
+func ids(options []*models.CommuteRecommendation) []string {
+	ids := make([]string, len(options))
+	for i, option := range options {
+		ids[i] = option.ID
+	}
+	return ids
+}
+
+func TestRankCostOptimized(t *testing.T) {
+	cheap, pricey := 4.5, 12.0
+	day := Day{Options: []*models.CommuteRecommendation{
+		option("pricey", &pricey),
+		option("uncosted", nil),
+		option("cheap", &cheap),
+		option("also-pricey", &pricey),
+	}}
+	ranker, _ := Lookup(RankerCostOptimized)
+	ranked, err := Rank(context.Background(), ranker, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"cheap", "pricey", "also-pricey", "uncosted"}
+	if got := ids(ranked); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+	for i, option := range ranked {
+		if option.OptionRank != i+1 {
+			t.Errorf("%s has rank %d, want %d", option.ID, option.OptionRank, i+1)
+		}
+	}
+}
+
+func TestRankRejectsChangedOptions(t *testing.T) {
+	day := Day{Options: []*models.CommuteRecommendation{{ID: "a"}, {ID: "b"}}}
+	for name, ranker := range map[string]RankerFunc{
+		"dropped": func(ctx context.Context, day Day) ([]*models.CommuteRecommendation, error) {
+			return day.Options[:1], nil
+		},
+		"duplicated": func(ctx context.Context, day Day) ([]*models.CommuteRecommendation, error) {
+			return []*models.CommuteRecommendation{day.Options[0], day.Options[0]}, nil
+		},
+		"invented": func(ctx context.Context, day Day) ([]*models.CommuteRecommendation, error) {
+			return []*models.CommuteRecommendation{day.Options[0], {ID: "c"}}, nil
+		},
+	} {
+		if _, err := Rank(context.Background(), ranker, day); err == nil {
+			t.Errorf("%s: Rank accepted a result that isn't a reordering", name)
+		}
+	}
+}
+
+func TestSelector(t *testing.T) {
+	byTenant, err := ParseMapping([]string{"acme=cost-optimized"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byVariant, _ := ParseMapping([]string{"treatment = rule-based"})
+	s := Selector{ByTenant: byTenant, ByVariant: byVariant}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	treatment, control := "treatment", "control"
+	for _, tc := range []struct {
+		tenant  string
+		variant *string
+		want    string
+	}{
+		{"default", nil, RankerPlanner},
+		{"acme", nil, RankerCostOptimized},
+		{"acme", &control, RankerCostOptimized},
+		{"acme", &treatment, RankerRuleBased},
+	} {
+		if got := s.Select(tc.tenant, tc.variant); got != tc.want {
+			t.Errorf("Select(%s, %v) = %s, want %s", tc.tenant, tc.variant, got, tc.want)
+		}
+	}
+
+	if err := (Selector{Default: "ml-scored"}).Validate(); err == nil {
+		t.Error("Validate accepted an unregistered ranker")
+	}
+	if _, err := ParseMapping([]string{"acme"}); err == nil {
+		t.Error("ParseMapping accepted an entry without a ranker")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-reversed", RankerFunc(func(ctx context.Context, day Day) ([]*models.CommuteRecommendation, error) {
+		ranked := make([]*models.CommuteRecommendation, len(day.Options))
+		for i, option := range day.Options {
+			ranked[len(ranked)-1-i] = option
+		}
+		return ranked, nil
+	}))
+	if err := (Selector{Default: "test-reversed"}).Validate(); err != nil {
+		t.Fatalf("registered ranker not selectable: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice didn't panic")
+		}
+	}()
+	Register(RankerPlanner, RankerFunc(nil))
+}
@@ -0,0 +1,313 @@
+// Package preferences learns a user's commute habits from the days they accepted a
+// recommendation for: weekdays they always work from home or always go in, when they
+// arrive at the office and how they travel. Habits need enough days behind them and a
+// clear majority to be learned. Users review what was learned: confirmed preferences are
+// kept (with any corrected time or mode) even once the history stops showing them, and
+// rejected ones aren't planned with.
+package preferences
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// Thresholds for learning a habit
+const (
+	// minWeekdayDays is how many days of a weekday it takes to learn a weekday habit
+	minWeekdayDays = 3
+	// minDays is how many office days it takes to learn an arrival time or travel mode
+	minDays = 5
+	// weekdayShare is the share of a weekday's days that must agree on remote or office
+	weekdayShare = 0.9
+	// modeShare is the share of office days that must use the same travel mode
+	modeShare = 0.8
+	// arrivalPercentile is the share of office days the learned arrival time covers
+	arrivalPercentile = 0.8
+	// maxArrivalSpread is how far apart the 20th and 80th percentile arrivals can be for
+	// the user to have a usual arrival time at all
+	maxArrivalSpread = 90
+	// arrivalStep rounds learned arrival times up to the quarter hour
+	arrivalStep = 15
+)
+
+// Day is one day of a user's commute history: the recommendation they accepted
+type Day struct {
+	Date     time.Time
+	InOffice bool
+	// Arrival is when they got to the office, in their timezone; nil for remote days and
+	// office days planned without times
+	Arrival *time.Time
+	// Mode is how they travelled; nil for remote days
+	Mode *models.TravelMode
+}
+
+// Learn derives preferences from a user's history, in key order, with status LEARNED
+func Learn(days []Day) []models.LearnedPreference {
+	var learned []models.LearnedPreference
+
+	var byWeekday [7]struct{ total, office int }
+	var arrivals []int
+	modes := map[models.TravelMode]int{}
+	modeDays := 0
+	for _, day := range days {
+		counts := &byWeekday[day.Date.Weekday()]
+		counts.total++
+		if !day.InOffice {
+			continue
+		}
+		counts.office++
+		if day.Arrival != nil {
+			arrivals = append(arrivals, day.Arrival.Hour()*60+day.Arrival.Minute())
+		}
+		if day.Mode != nil {
+			modes[*day.Mode]++
+			modeDays++
+		}
+	}
+
+	for _, weekday := range weekdays {
+		counts := byWeekday[weekday]
+		if counts.total < minWeekdayDays {
+			continue
+		}
+		officeShare := float64(counts.office) / float64(counts.total)
+		switch {
+		case 1-officeShare >= weekdayShare:
+			learned = append(learned, weekdayPreference(models.PreferenceRemoteWeekday, weekday, counts.total, 1-officeShare))
+		case officeShare >= weekdayShare:
+			learned = append(learned, weekdayPreference(models.PreferenceOfficeWeekday, weekday, counts.total, officeShare))
+		}
+	}
+
+	if len(arrivals) >= minDays {
+		sort.Ints(arrivals)
+		early, late := percentile(arrivals, 1-arrivalPercentile), percentile(arrivals, arrivalPercentile)
+		if late-early <= maxArrivalSpread {
+			by := (late + arrivalStep - 1) / arrivalStep * arrivalStep
+			covered := 0
+			for _, arrival := range arrivals {
+				if arrival <= by {
+					covered++
+				}
+			}
+			at := fmt.Sprintf("%02d:%02d", by/60, by%60)
+			learned = append(learned, describe(models.LearnedPreference{
+				Key:          Key(models.PreferenceArriveBefore, nil),
+				Type:         models.PreferenceArriveBefore,
+				Time:         &at,
+				Observations: len(arrivals),
+				Confidence:   round(float64(covered) / float64(len(arrivals))),
+				Status:       models.PreferenceLearned,
+			}))
+		}
+	}
+
+	if modeDays >= minDays {
+		var top models.TravelMode
+		for _, mode := range models.TravelModes {
+			if modes[mode] > modes[top] {
+				top = mode
+			}
+		}
+		if share := float64(modes[top]) / float64(modeDays); share >= modeShare {
+			learned = append(learned, describe(models.LearnedPreference{
+				Key:          Key(models.PreferenceTravelMode, nil),
+				Type:         models.PreferenceTravelMode,
+				Mode:         &top,
+				Observations: modeDays,
+				Confidence:   round(share),
+				Status:       models.PreferenceLearned,
+			}))
+		}
+	}
+	return learned
+}
+
+// Apply merges a user's feedback into their learned preferences. Confirmed preferences
+// take the feedback's corrections and are kept even when they weren't learned; they also
+// displace learned preferences that contradict them. Rejected preferences are kept, so
+// users can see what they rejected, but only while they are still learned.
+func Apply(learned []models.LearnedPreference, feedback []*models.PreferenceFeedback) []models.LearnedPreference {
+	byKey := make(map[string]*models.PreferenceFeedback, len(feedback))
+	for _, fb := range feedback {
+		byKey[fb.Key] = fb
+	}
+
+	var merged []models.LearnedPreference
+	seen := map[string]bool{}
+	for _, preference := range learned {
+		if fb, ok := byKey[Contradiction(preference.Key)]; ok && fb.Status == models.PreferenceConfirmed {
+			continue
+		}
+		if fb, ok := byKey[preference.Key]; ok {
+			preference = review(preference, fb)
+		}
+		merged = append(merged, preference)
+		seen[preference.Key] = true
+	}
+	for _, fb := range feedback {
+		if seen[fb.Key] || fb.Status != models.PreferenceConfirmed {
+			continue
+		}
+		preferenceType, weekday, err := ParseKey(fb.Key)
+		if err != nil {
+			continue
+		}
+		merged = append(merged, review(models.LearnedPreference{Key: fb.Key, Type: preferenceType, Weekday: weekday}, fb))
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool { return order(merged[i]) < order(merged[j]) })
+	return merged
+}
+
+// Active returns the preferences the planner should follow: those that aren't rejected
+func Active(preferences []models.LearnedPreference) []models.LearnedPreference {
+	var active []models.LearnedPreference
+	for _, preference := range preferences {
+		if preference.Status != models.PreferenceRejected {
+			active = append(active, preference)
+		}
+	}
+	return active
+}
+
+// Key identifies a preference; weekday is only set for weekday preferences
+func Key(preferenceType models.PreferenceType, weekday *string) string {
+	if weekday == nil {
+		return string(preferenceType)
+	}
+	return string(preferenceType) + ":" + *weekday
+}
+
+// ParseKey reads a preference key back into its type and weekday
+func ParseKey(key string) (models.PreferenceType, *string, error) {
+	name, day, hasDay := strings.Cut(key, ":")
+	preferenceType := models.PreferenceType(name)
+	switch preferenceType {
+	case models.PreferenceRemoteWeekday, models.PreferenceOfficeWeekday:
+		for _, weekday := range weekdays {
+			if hasDay && day == weekdayName(weekday) {
+				return preferenceType, &day, nil
+			}
+		}
+		return "", nil, fmt.Errorf("invalid preference key %q: expected %s:<WEEKDAY>, e.g. %s:FRIDAY", key, name, name)
+	case models.PreferenceArriveBefore, models.PreferenceTravelMode:
+		if !hasDay {
+			return preferenceType, nil, nil
+		}
+	}
+	return "", nil, fmt.Errorf("invalid preference key %q", key)
+}
+
+// weekdays in the order preferences are listed
+var weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+// weekdayName is how weekdays appear in keys, e.g. FRIDAY
+func weekdayName(weekday time.Weekday) string {
+	return strings.ToUpper(weekday.String())
+}
+
+// Weekday returns the weekday a weekday preference applies to
+func Weekday(preference models.LearnedPreference) (time.Weekday, bool) {
+	for _, weekday := range weekdays {
+		if preference.Weekday != nil && *preference.Weekday == weekdayName(weekday) {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
+
+func weekdayPreference(preferenceType models.PreferenceType, weekday time.Weekday, observations int, share float64) models.LearnedPreference {
+	name := weekdayName(weekday)
+	return describe(models.LearnedPreference{
+		Key:          Key(preferenceType, &name),
+		Type:         preferenceType,
+		Weekday:      &name,
+		Observations: observations,
+		Confidence:   round(share),
+		Status:       models.PreferenceLearned,
+	})
+}
+
+// review applies feedback to a preference
+func review(preference models.LearnedPreference, fb *models.PreferenceFeedback) models.LearnedPreference {
+	preference.Status = fb.Status
+	if fb.Status == models.PreferenceConfirmed {
+		if fb.Time != nil && preference.Type == models.PreferenceArriveBefore {
+			preference.Time = fb.Time
+		}
+		if fb.Mode != nil && preference.Type == models.PreferenceTravelMode {
+			preference.Mode = fb.Mode
+		}
+	}
+	return describe(preference)
+}
+
+// Contradiction is the key of the preference that can't hold alongside key, or ""
+func Contradiction(key string) string {
+	preferenceType, weekday, err := ParseKey(key)
+	if err != nil || weekday == nil {
+		return ""
+	}
+	if preferenceType == models.PreferenceRemoteWeekday {
+		return Key(models.PreferenceOfficeWeekday, weekday)
+	}
+	return Key(models.PreferenceRemoteWeekday, weekday)
+}
+
+// describe sets a preference's description from its fields
+func describe(preference models.LearnedPreference) models.LearnedPreference {
+	day := ""
+	if weekday, ok := Weekday(preference); ok {
+		day = weekday.String() + "s"
+	}
+	switch preference.Type {
+	case models.PreferenceRemoteWeekday:
+		preference.Description = "Works from home on " + day
+	case models.PreferenceOfficeWeekday:
+		preference.Description = "Goes into the office on " + day
+	case models.PreferenceArriveBefore:
+		if preference.Time != nil {
+			preference.Description = "Arrives at the office by " + *preference.Time
+		}
+	case models.PreferenceTravelMode:
+		if preference.Mode != nil {
+			preference.Description = "Travels to the office by " + strings.ToLower(string(*preference.Mode))
+		}
+	}
+	return preference
+}
+
+// order sorts preferences by type, then weekday
+func order(preference models.LearnedPreference) int {
+	rank := 0
+	switch preference.Type {
+	case models.PreferenceRemoteWeekday, models.PreferenceOfficeWeekday:
+		if weekday, ok := Weekday(preference); ok {
+			rank = (int(weekday) + 6) % 7
+		}
+	case models.PreferenceArriveBefore:
+		rank = 10
+	case models.PreferenceTravelMode:
+		rank = 11
+	}
+	return rank
+}
+
+// percentile is the nearest-rank percentile p of sorted values
+func percentile(sorted []int, p float64) int {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func round(share float64) float64 {
+	return math.Round(share*100) / 100
+}
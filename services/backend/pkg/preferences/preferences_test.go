@@ -0,0 +1,149 @@
+package preferences
+
+import (
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// history is eight weeks of a user who works from home on Fridays, goes in the rest of
+// the week by transit and arrives between 8:30 and 9:00
+func history() []Day {
+	transit := models.TravelModeTransit
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC) // a Monday
+	var days []Day
+	for i := 0; i < 8*7; i++ {
+		date := start.AddDate(0, 0, i)
+		switch date.Weekday() {
+		case time.Saturday, time.Sunday:
+			continue
+		case time.Friday:
+			days = append(days, Day{Date: date})
+		default:
+			arrival := date.Add(8*time.Hour + time.Duration(30+i%4*10)*time.Minute)
+			days = append(days, Day{Date: date, InOffice: true, Arrival: &arrival, Mode: &transit})
+		}
+	}
+	return days
+}
+
+func keys(preferences []models.LearnedPreference) []string {
+	keys := make([]string, len(preferences))
+	for i, preference := range preferences {
+		keys[i] = preference.Key
+	}
+	return keys
+}
+
+func TestLearn(t *testing.T) {
+	learned := Learn(history())
+	want := []string{
+		"OFFICE_WEEKDAY:MONDAY", "OFFICE_WEEKDAY:TUESDAY", "OFFICE_WEEKDAY:WEDNESDAY",
+		"OFFICE_WEEKDAY:THURSDAY", "REMOTE_WEEKDAY:FRIDAY", "ARRIVE_BEFORE", "TRAVEL_MODE",
+	}
+	if got := keys(learned); len(got) != len(want) {
+		t.Fatalf("learned %v, want %v", got, want)
+	}
+	for i, preference := range learned {
+		if preference.Key != want[i] {
+			t.Errorf("preference %d is %s, want %s", i, preference.Key, want[i])
+		}
+		if preference.Status != models.PreferenceLearned || preference.Description == "" {
+			t.Errorf("%s: status %s, description %q", preference.Key, preference.Status, preference.Description)
+		}
+	}
+
+	friday := learned[4]
+	if friday.Description != "Works from home on Fridays" || friday.Observations != 8 || friday.Confidence != 1 {
+		t.Errorf("friday = %+v", friday)
+	}
+	if arrive := learned[5]; *arrive.Time != "09:00" || arrive.Observations != 32 {
+		t.Errorf("arrival = %s from %d days, want 09:00 from 32", *arrive.Time, arrive.Observations)
+	}
+	if mode := learned[6]; *mode.Mode != models.TravelModeTransit || mode.Description != "Travels to the office by transit" {
+		t.Errorf("mode = %+v", mode)
+	}
+}
+
+func TestLearnNeedsEvidence(t *testing.T) {
+	days := history()[:4] // one week, minus Friday
+	if learned := Learn(days); len(learned) != 0 {
+		t.Errorf("learned %v from four days", keys(learned))
+	}
+
+	// Arrivals all over the day aren't a habit
+	days = history()
+	for i := range days {
+		if days[i].Arrival != nil {
+			arrival := days[i].Arrival.Add(time.Duration(i%5) * time.Hour)
+			days[i].Arrival = &arrival
+		}
+	}
+	for _, preference := range Learn(days) {
+		if preference.Type == models.PreferenceArriveBefore {
+			t.Errorf("learned %s from scattered arrivals", *preference.Time)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	learned := Learn(history())
+	later, bike := "09:30", models.TravelModeBike
+	merged := Apply(learned, []*models.PreferenceFeedback{
+		{Key: "ARRIVE_BEFORE", Status: models.PreferenceConfirmed, Time: &later},
+		{Key: "TRAVEL_MODE", Status: models.PreferenceRejected},
+		// Confirmed against the history: displaces the learned office Monday
+		{Key: "REMOTE_WEEKDAY:MONDAY", Status: models.PreferenceConfirmed},
+		// Rejected and no longer learned: dropped
+		{Key: "REMOTE_WEEKDAY:TUESDAY", Status: models.PreferenceRejected},
+		// Not a key: ignored
+		{Key: "TRAVEL_MODE:BIKE", Status: models.PreferenceConfirmed, Mode: &bike},
+	})
+
+	want := []string{
+		"REMOTE_WEEKDAY:MONDAY", "OFFICE_WEEKDAY:TUESDAY", "OFFICE_WEEKDAY:WEDNESDAY",
+		"OFFICE_WEEKDAY:THURSDAY", "REMOTE_WEEKDAY:FRIDAY", "ARRIVE_BEFORE", "TRAVEL_MODE",
+	}
+	got := keys(merged)
+	if len(got) != len(want) {
+		t.Fatalf("merged %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("merged %v, want %v", got, want)
+		}
+	}
+
+	monday, arrive, mode := merged[0], merged[5], merged[6]
+	if monday.Status != models.PreferenceConfirmed || monday.Observations != 0 || monday.Description != "Works from home on Mondays" {
+		t.Errorf("monday = %+v", monday)
+	}
+	if arrive.Status != models.PreferenceConfirmed || *arrive.Time != "09:30" || arrive.Description != "Arrives at the office by 09:30" {
+		t.Errorf("arrival = %+v", arrive)
+	}
+	if mode.Status != models.PreferenceRejected {
+		t.Errorf("mode status = %s, want REJECTED", mode.Status)
+	}
+	if active := Active(merged); len(active) != len(merged)-1 {
+		t.Errorf("%d active preferences, want %d", len(active), len(merged)-1)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	for _, key := range []string{"REMOTE_WEEKDAY:FRIDAY", "OFFICE_WEEKDAY:SUNDAY", "ARRIVE_BEFORE", "TRAVEL_MODE"} {
+		preferenceType, weekday, err := ParseKey(key)
+		if err != nil {
+			t.Errorf("ParseKey(%s): %v", key, err)
+			continue
+		}
+		if got := Key(preferenceType, weekday); got != key {
+			t.Errorf("Key(ParseKey(%s)) = %s", key, got)
+		}
+	}
+	for _, key := range []string{"", "REMOTE_WEEKDAY", "REMOTE_WEEKDAY:friday", "ARRIVE_BEFORE:MONDAY", "LUNCH"} {
+		if _, _, err := ParseKey(key); err == nil {
+			t.Errorf("ParseKey accepted %q", key)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package progress estimates how far along a running job is, and when it will complete,
+// from how long each planning step took in recently completed jobs, rather than from the
+// progress the worker reports.
+package progress
+
+import (
+	"sort"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// minSamples is how many completed jobs must have run a step before its average is used
+const minSamples = 3
+
+// Model is the typical course of a job's IN_PROGRESS steps
+type Model struct {
+	// Steps are the steps seen often enough, in the order jobs usually run them
+	Steps []Step
+	// Jobs counts the completed jobs the model was learned from
+	Jobs int
+}
+
+// Step is a planning step's typical duration and start, from when its job started
+type Step struct {
+	Name     string
+	Duration time.Duration
+	Offset   time.Duration
+	Samples  int
+}
+
+// Learn builds a model from the histories of completed jobs, each oldest first
+func Learn(histories [][]*models.JobEvent) *Model {
+	type totals struct {
+		duration, offset time.Duration
+		samples          int
+	}
+	steps := map[string]*totals{}
+	model := &Model{}
+	for _, history := range histories {
+		var started *time.Time
+		seen := map[string]bool{}
+		for _, entry := range models.BuildJobTimeline(history) {
+			if entry.Status != models.JobStatusInProgress {
+				// A job put back on the queue starts over
+				started = nil
+				continue
+			}
+			if started == nil {
+				started = &entry.StartedAt
+			}
+			if entry.Step == nil || entry.DurationSeconds == nil || seen[*entry.Step] {
+				continue
+			}
+			seen[*entry.Step] = true
+			t := steps[*entry.Step]
+			if t == nil {
+				t = &totals{}
+				steps[*entry.Step] = t
+			}
+			t.duration += time.Duration(*entry.DurationSeconds * float64(time.Second))
+			t.offset += entry.StartedAt.Sub(*started)
+			t.samples++
+		}
+		if len(seen) > 0 {
+			model.Jobs++
+		}
+	}
+
+	for name, t := range steps {
+		if t.samples < minSamples {
+			continue
+		}
+		n := time.Duration(t.samples)
+		model.Steps = append(model.Steps, Step{Name: name, Duration: t.duration / n, Offset: t.offset / n, Samples: t.samples})
+	}
+	sort.Slice(model.Steps, func(i, j int) bool {
+		if model.Steps[i].Offset != model.Steps[j].Offset {
+			return model.Steps[i].Offset < model.Steps[j].Offset
+		}
+		return model.Steps[i].Name < model.Steps[j].Name
+	})
+	return model
+}
+
+// Estimate is a running job's estimated progress, between 0 and 1, and completion time
+type Estimate struct {
+	Progress    float64
+	CompletesAt time.Time
+}
+
+// Estimate estimates the progress of an IN_PROGRESS job from its history, oldest first,
+// as of now. It reports false when the job isn't running or is on a step the model
+// doesn't know.
+func (m *Model) Estimate(history []*models.JobEvent, now time.Time) (Estimate, bool) {
+	timeline := models.BuildJobTimeline(history)
+	if len(timeline) == 0 || m == nil {
+		return Estimate{}, false
+	}
+	current := timeline[len(timeline)-1]
+	if current.Status != models.JobStatusInProgress || current.Step == nil {
+		return Estimate{}, false
+	}
+	at := -1
+	for i, step := range m.Steps {
+		if step.Name == *current.Step {
+			at = i
+		}
+	}
+	if at < 0 {
+		return Estimate{}, false
+	}
+
+	// Steps before the current one count as done, those after it as to do, whether or
+	// not this job runs them all
+	var done, remaining time.Duration
+	for _, step := range m.Steps[:at] {
+		done += step.Duration
+	}
+	for _, step := range m.Steps[at+1:] {
+		remaining += step.Duration
+	}
+	step := m.Steps[at]
+	elapsed := max(now.Sub(current.StartedAt), 0)
+	if elapsed < step.Duration {
+		done += elapsed
+		remaining += step.Duration - elapsed
+	} else {
+		// The step is overrunning; assume it ends about now
+		done += step.Duration
+	}
+
+	estimate := Estimate{CompletesAt: now.Add(remaining)}
+	if total := done + remaining; total > 0 {
+		estimate.Progress = float64(done) / float64(total)
+	}
+	return estimate, true
+}
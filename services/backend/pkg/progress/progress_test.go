@@ -0,0 +1,64 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// history builds a job's events: created at start, then one IN_PROGRESS event per step,
+// each lasting its duration, then COMPLETED unless the job is still running
+func history(start time.Time, steps []string, durations []time.Duration, running bool) []*models.JobEvent {
+	pending, inProgress := models.JobStatusPending, models.JobStatusInProgress
+	events := []*models.JobEvent{{Sequence: 1, ToStatus: pending, CreatedAt: start}}
+	at := start.Add(time.Minute)
+	from := pending
+	for i, step := range steps {
+		name := step
+		events = append(events, &models.JobEvent{Sequence: len(events) + 1, FromStatus: &from, ToStatus: inProgress, CurrentStep: &name, CreatedAt: at})
+		from = inProgress
+		at = at.Add(durations[i])
+	}
+	if !running {
+		events = append(events, &models.JobEvent{Sequence: len(events) + 1, FromStatus: &inProgress, ToStatus: models.JobStatusCompleted, CreatedAt: at})
+	}
+	return events
+}
+
+func TestEstimate(t *testing.T) {
+	start := time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC)
+	steps := []string{"Fetching calendar", "Analyzing calendar", "Ranking options"}
+	var histories [][]*models.JobEvent
+	for i := 0; i < 3; i++ {
+		histories = append(histories, history(start, steps, []time.Duration{10 * time.Second, 60 * time.Second, 30 * time.Second}, false))
+	}
+	// A step too rare to average
+	histories = append(histories, history(start, []string{"Retrying"}, []time.Duration{time.Hour}, false))
+
+	model := Learn(histories)
+	if model.Jobs != 4 || len(model.Steps) != 3 || model.Steps[1].Name != "Analyzing calendar" || model.Steps[1].Duration != time.Minute {
+		t.Fatalf("model = %+v, want the three common steps in order", model)
+	}
+
+	// 20 seconds into analyzing: 30 of 100 seconds done
+	running := history(start, steps[:2], []time.Duration{10 * time.Second, 0}, true)
+	now := running[len(running)-1].CreatedAt.Add(20 * time.Second)
+	estimate, ok := model.Estimate(running, now)
+	if !ok || estimate.Progress != 0.3 || !estimate.CompletesAt.Equal(now.Add(70*time.Second)) {
+		t.Errorf("estimate = %+v, %v; want 30%% done with 70 seconds to go", estimate, ok)
+	}
+
+	// An overrunning step doesn't go backwards or past the steps still to come
+	estimate, _ = model.Estimate(running, now.Add(time.Hour))
+	if estimate.Progress != 0.7 || !estimate.CompletesAt.Equal(now.Add(time.Hour+30*time.Second)) {
+		t.Errorf("overrunning estimate = %+v, want 70%% done with the last step to go", estimate)
+	}
+
+	if _, ok := model.Estimate(history(start, []string{"Retrying"}, []time.Duration{0}, true), now); ok {
+		t.Error("estimated a step the model doesn't know")
+	}
+	if _, ok := model.Estimate(histories[0], now); ok {
+		t.Error("estimated a completed job")
+	}
+}
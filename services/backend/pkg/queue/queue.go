@@ -0,0 +1,133 @@
+// Package queue defines the message broker interface used by the job pipeline. Jobs are
+// published by the backend and consumed by the AI service workers; the broker behind it is
+// Redis (pkg/redis) or RabbitMQ (pkg/queue/rabbitmq). Only the job transport is pluggable:
+// Redis is still required for the token denylist, scheduled jobs and progress updates.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// Supported brokers, selected with QUEUE_BROKER
+const (
+	BrokerRedis    = "redis"
+	BrokerRabbitMQ = "rabbitmq"
+)
+
+// Depth is the backlog of one broker queue
+type Depth struct {
+	Queue string `json:"queue"`
+	// Messages counts the jobs held: waiting ones, and for Redis streams also the
+	// retained entries workers already read
+	Messages int64 `json:"messages"`
+	// Pending counts jobs a worker took but hasn't acknowledged (Redis streams only)
+	Pending int64 `json:"pending"`
+	// Oldest is when the oldest job no worker has taken yet was queued; nil when there
+	// is none or the broker doesn't say (Redis streams only)
+	Oldest *time.Time `json:"oldest,omitempty"`
+	// Scheduled marks the jobs waiting for their scheduled time rather than a worker
+	Scheduled bool `json:"scheduled,omitempty"`
+}
+
+// Message is the job payload expected by the AI service workers
+type Message struct {
+	JobID      string  `json:"job_id"`
+	UserID     string  `json:"user_id"`
+	TargetDate string  `json:"target_date"`
+	InputData  *string `json:"input_data,omitempty"`
+	// Priority selects the interactive or batch queue; empty is interactive
+	Priority models.JobPriority `json:"priority,omitempty"`
+}
+
+// IsBatch reports whether the message belongs on the batch queue
+func (m Message) IsBatch() bool {
+	return m.Priority == models.JobPriorityBatch
+}
+
+// Encode marshals a message into the JSON body sent over the wire
+func (m Message) Encode() ([]byte, error) {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job message: %w", err)
+	}
+	return body, nil
+}
+
+// Decode parses a message body written by Encode
+func Decode(body []byte) (Message, error) {
+	var m Message
+	if err := json.Unmarshal(body, &m); err != nil {
+		return m, fmt.Errorf("failed to decode job message: %w", err)
+	}
+	return m, nil
+}
+
+// Publisher hands jobs to the broker
+type Publisher interface {
+	// Publish returns once the broker has accepted the message
+	Publish(ctx context.Context, msg Message) error
+	// Healthy reports whether the broker was reachable at the last check; jobs buffered in
+	// the outbox are only flushed while it is
+	Healthy() bool
+	// Ping checks that the broker is reachable, for the readiness endpoint
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Consumer receives jobs from the broker. A delivered message stays owned by the consumer
+// until it is acknowledged; a consumer that dies first gets it redelivered elsewhere. The
+// AI service workers follow the same contract in Python (workers/job_worker.py).
+type Consumer interface {
+	// Receive blocks until at least one message is available or ctx is done, and returns
+	// at most max messages
+	Receive(ctx context.Context, max int) ([]*Delivery, error)
+	Close() error
+}
+
+// Delivery is a received message awaiting acknowledgement
+type Delivery struct {
+	Message Message
+	// ID is the broker's identifier of the delivery (stream entry ID, delivery tag)
+	ID string
+
+	ack  func(ctx context.Context) error
+	nack func(ctx context.Context, requeue bool) error
+}
+
+// NewDelivery is used by broker implementations to wrap a received message
+func NewDelivery(msg Message, id string, ack func(ctx context.Context) error, nack func(ctx context.Context, requeue bool) error) *Delivery {
+	return &Delivery{Message: msg, ID: id, ack: ack, nack: nack}
+}
+
+// Ack marks the message as processed so it is not delivered again
+func (d *Delivery) Ack(ctx context.Context) error {
+	return d.ack(ctx)
+}
+
+// Nack gives the message up; with requeue it is delivered again, otherwise it is dropped
+func (d *Delivery) Nack(ctx context.Context, requeue bool) error {
+	return d.nack(ctx, requeue)
+}
+
+// Scheduler holds jobs until their scheduled time. Claimed jobs are leased rather than
+// removed, so a job claimed by an instance that dies before publishing it becomes due
+// again once the lease expires.
+type Scheduler interface {
+	Schedule(ctx context.Context, msg Message, at time.Time) error
+	// ClaimDue leases up to limit jobs due at now
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Scheduled, error)
+	// Remove drops a claimed job once it has been handed to the broker
+	Remove(ctx context.Context, job *Scheduled) error
+}
+
+// Scheduled is a job claimed from a Scheduler
+type Scheduled struct {
+	Message Message
+	// Key identifies the entry in the scheduler's storage
+	Key string
+}
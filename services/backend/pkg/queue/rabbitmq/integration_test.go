@@ -0,0 +1,131 @@
+//go:build integration
+
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/queue"
+)
+
+// startRabbitMQ runs a broker for the test, returning its amqp URL
+func startRabbitMQ(t *testing.T) string {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	ctx := context.Background()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "rabbitmq:3-alpine",
+			ExposedPorts: []string{"5672/tcp"},
+			WaitingFor:   wait.ForLog("Server startup complete").WithStartupTimeout(2 * time.Minute),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("starting RabbitMQ: %v", err)
+	}
+	t.Cleanup(func() { container.Terminate(context.Background()) })
+	endpoint, err := container.Endpoint(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("amqp://guest:guest@%s/", endpoint)
+}
+
+// receiveIDs receives until n jobs arrived, returning them in delivery order
+func receiveIDs(t *testing.T, c *Consumer, n int, ack bool) []string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var ids []string
+	for len(ids) < n {
+		deliveries, err := c.Receive(ctx, n-len(ids))
+		if err != nil {
+			t.Fatalf("Receive after %v: %v", ids, err)
+		}
+		for _, d := range deliveries {
+			ids = append(ids, d.Message.JobID)
+			if ack {
+				if err := d.Ack(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	return ids
+}
+
+func TestPublishAndConsume(t *testing.T) {
+	url := startRabbitMQ(t)
+	ctx := context.Background()
+	cfg := Config{URL: url}
+
+	publisher := NewPublisher(cfg)
+	defer publisher.Close()
+	if !publisher.Healthy() {
+		t.Fatalf("publisher not connected: %v", publisher.Ping(ctx))
+	}
+	// The batch job is published first; interactive jobs are delivered ahead of it
+	for _, msg := range []queue.Message{
+		{JobID: "batch", Priority: models.JobPriorityBatch},
+		{JobID: "interactive"},
+	} {
+		if err := publisher.Publish(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	depths, err := publisher.QueueDepth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(depths) != 2 || depths[0].Messages != 1 || depths[1].Messages != 1 {
+		t.Errorf("depths = %+v, want one job in each queue", depths)
+	}
+
+	consumer, err := NewConsumer(ConsumerConfig{Config: cfg, Name: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+	if ids := receiveIDs(t, consumer, 2, true); fmt.Sprint(ids) != "[interactive batch]" {
+		t.Errorf("received %v, want [interactive batch]", ids)
+	}
+}
+
+func TestUnacknowledgedJobsAreRedelivered(t *testing.T) {
+	url := startRabbitMQ(t)
+	ctx := context.Background()
+	cfg := Config{URL: url}
+
+	publisher := NewPublisher(cfg)
+	defer publisher.Close()
+	if err := publisher.Publish(ctx, queue.Message{JobID: "job"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A consumer that dies before acknowledging hands the job to the next one
+	crashed, err := NewConsumer(ConsumerConfig{Config: cfg, Name: "crashed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := receiveIDs(t, crashed, 1, false); fmt.Sprint(ids) != "[job]" {
+		t.Fatalf("received %v, want [job]", ids)
+	}
+	crashed.Close()
+
+	survivor, err := NewConsumer(ConsumerConfig{Config: cfg, Name: "survivor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer survivor.Close()
+	if ids := receiveIDs(t, survivor, 1, true); fmt.Sprint(ids) != "[job]" {
+		t.Errorf("redelivered %v, want [job]", ids)
+	}
+}
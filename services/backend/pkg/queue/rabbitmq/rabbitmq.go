@@ -0,0 +1,419 @@
+// Package rabbitmq publishes and consumes jobs through a durable RabbitMQ queue, for
+// deployments that run the job pipeline on existing RabbitMQ infrastructure instead of Redis
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/queue"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Config describes how to reach RabbitMQ. amqps:// URLs use TLS.
+type Config struct {
+	URL string
+	// Queue is the durable queue interactive jobs are published to; batch jobs go to
+	// Queue + ".batch". Empty uses "commute_jobs".
+	Queue       string
+	DialTimeout time.Duration
+	// HealthCheckInterval is how often Monitor reconnects a lost connection
+	HealthCheckInterval time.Duration
+}
+
+func (cfg *Config) setDefaults() {
+	if cfg.Queue == "" {
+		cfg.Queue = "commute_jobs"
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = 10 * time.Second
+	}
+}
+
+// batchQueue names the queue of batch jobs
+func (cfg Config) batchQueue() string {
+	return cfg.Queue + ".batch"
+}
+
+// queueFor routes a message to the interactive or batch queue
+func (cfg Config) queueFor(msg queue.Message) string {
+	if msg.IsBatch() {
+		return cfg.batchQueue()
+	}
+	return cfg.Queue
+}
+
+// connection is a connection plus one channel with the job queues declared on it
+type connection struct {
+	conn *amqp.Connection
+	ch   *amqp.Channel
+}
+
+// dial connects and declares the job queues. The declaration is idempotent, so publishers
+// and consumers can start in any order.
+func dial(cfg Config) (*connection, error) {
+	conn, err := amqp.DialConfig(cfg.URL, amqp.Config{
+		Heartbeat: 10 * time.Second,
+		Dial:      amqp.DefaultDial(cfg.DialTimeout),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open RabbitMQ channel: %w", err)
+	}
+	for _, name := range []string{cfg.Queue, cfg.batchQueue()} {
+		if _, err := ch.QueueDeclare(name, true, false, false, false, nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to declare queue %s: %w", name, err)
+		}
+	}
+	return &connection{conn: conn, ch: ch}, nil
+}
+
+func (c *connection) close() {
+	if c != nil && !c.conn.IsClosed() {
+		c.conn.Close()
+	}
+}
+
+// Publisher publishes jobs as persistent messages and waits for the broker's publisher
+// confirm, so a job counts as queued only once RabbitMQ has taken responsibility for it
+type Publisher struct {
+	cfg Config
+
+	mu      sync.Mutex
+	current *connection
+	lastErr error
+}
+
+// NewPublisher creates a publisher. An unreachable broker is not fatal: jobs are buffered
+// in the outbox and Monitor reconnects.
+func NewPublisher(cfg Config) *Publisher {
+	cfg.setDefaults()
+	p := &Publisher{cfg: cfg}
+
+	p.mu.Lock()
+	err := p.connectLocked()
+	p.mu.Unlock()
+	if err != nil {
+		log.Printf("Warning: Could not connect to RabbitMQ at %s: %v", redactURL(cfg.URL), err)
+		log.Printf("Jobs will be buffered in the database until RabbitMQ is reachable")
+	} else {
+		log.Printf("Connected to RabbitMQ at %s (queue %s)", redactURL(cfg.URL), cfg.Queue)
+	}
+	return p
+}
+
+// connectLocked replaces a missing or closed connection; p.mu must be held
+func (p *Publisher) connectLocked() error {
+	if p.current != nil && !p.current.conn.IsClosed() {
+		return nil
+	}
+	p.current.close()
+	p.current = nil
+
+	c, err := dial(p.cfg)
+	if err == nil {
+		err = c.ch.Confirm(false)
+		if err != nil {
+			c.close()
+			err = fmt.Errorf("failed to enable publisher confirms: %w", err)
+		}
+	}
+	p.lastErr = err
+	if err != nil {
+		metrics.RabbitMQUp.Set(0)
+		return err
+	}
+	p.current = c
+	metrics.RabbitMQUp.Set(1)
+	return nil
+}
+
+// Publish sends a job and waits for the broker to confirm it
+func (p *Publisher) Publish(ctx context.Context, msg queue.Message) error {
+	body, err := msg.Encode()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.connectLocked(); err != nil {
+		return err
+	}
+
+	name := p.cfg.queueFor(msg)
+	confirm, err := p.current.ch.PublishWithDeferredConfirmWithContext(ctx, "", name, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.JobID,
+		Timestamp:    time.Now(),
+		Body:         body,
+	})
+	if err != nil {
+		p.failLocked(err)
+		return fmt.Errorf("failed to publish job: %w", err)
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		// The confirm may still arrive on this channel; a fresh one keeps later
+		// publishes from reading it
+		p.failLocked(err)
+		return fmt.Errorf("failed to confirm job: %w", err)
+	}
+	if !acked {
+		return fmt.Errorf("RabbitMQ rejected job %s", msg.JobID)
+	}
+
+	log.Printf("Added job %s to RabbitMQ queue %s for processing", msg.JobID, name)
+	return nil
+}
+
+// failLocked drops the connection after an error so the next attempt reconnects
+func (p *Publisher) failLocked(err error) {
+	p.lastErr = err
+	p.current.close()
+	p.current = nil
+	metrics.RabbitMQUp.Set(0)
+}
+
+// Monitor reconnects a lost connection until ctx is cancelled, logging changes
+func (p *Publisher) Monitor(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		wasHealthy := p.Healthy()
+		p.mu.Lock()
+		err := p.connectLocked()
+		p.mu.Unlock()
+
+		switch {
+		case wasHealthy && err != nil:
+			log.Printf("RabbitMQ connection lost: %v", err)
+		case !wasHealthy && err == nil:
+			log.Printf("RabbitMQ connection restored")
+		}
+	}
+}
+
+// Healthy reports whether the publisher holds an open connection
+func (p *Publisher) Healthy() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	healthy := p.current != nil && !p.current.conn.IsClosed()
+	if !healthy {
+		metrics.RabbitMQUp.Set(0)
+	}
+	return healthy
+}
+
+// Ping reports the connection state for the readiness check
+func (p *Publisher) Ping(ctx context.Context) error {
+	if p.Healthy() {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lastErr != nil {
+		return p.lastErr
+	}
+	return fmt.Errorf("not connected to RabbitMQ")
+}
+
+// QueueDepth reports the messages ready in the interactive and batch queues
+func (p *Publisher) QueueDepth(ctx context.Context) ([]queue.Depth, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.connectLocked(); err != nil {
+		return nil, err
+	}
+	// A failed passive declare closes its channel, so it gets its own
+	ch, err := p.current.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open RabbitMQ channel: %w", err)
+	}
+	defer ch.Close()
+
+	var depths []queue.Depth
+	for _, name := range []string{p.cfg.Queue, p.cfg.batchQueue()} {
+		q, err := ch.QueueDeclarePassive(name, true, false, false, false, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect queue %s: %w", name, err)
+		}
+		depths = append(depths, queue.Depth{Queue: name, Messages: int64(q.Messages)})
+	}
+	return depths, nil
+}
+
+// Close closes the connection
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current.close()
+	p.current = nil
+	return nil
+}
+
+// ConsumerConfig configures a consumer of the job queue
+type ConsumerConfig struct {
+	Config
+	// Name identifies the consumer in the management UI
+	Name string
+	// Prefetch caps unacknowledged deliveries held by this consumer. 0 uses 5.
+	Prefetch int
+}
+
+// Consumer receives jobs with manual acknowledgement; deliveries not acknowledged when
+// the connection drops are redelivered to another consumer. Interactive jobs are
+// returned ahead of batch jobs.
+type Consumer struct {
+	cfg         ConsumerConfig
+	current     *connection
+	interactive <-chan amqp.Delivery
+	batch       <-chan amqp.Delivery
+}
+
+// NewConsumer connects and starts consuming the job queue
+func NewConsumer(cfg ConsumerConfig) (*Consumer, error) {
+	cfg.setDefaults()
+	if cfg.Prefetch <= 0 {
+		cfg.Prefetch = 5
+	}
+	c := &Consumer{cfg: cfg}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Consumer) connect() error {
+	conn, err := dial(c.cfg.Config)
+	if err != nil {
+		return err
+	}
+	if err := conn.ch.Qos(c.cfg.Prefetch, 0, false); err != nil {
+		conn.close()
+		return fmt.Errorf("failed to set prefetch: %w", err)
+	}
+	// Consumer tags must be unique per channel
+	interactive, err := conn.ch.Consume(c.cfg.Queue, c.cfg.Name, false, false, false, false, nil)
+	if err != nil {
+		conn.close()
+		return fmt.Errorf("failed to consume queue %s: %w", c.cfg.Queue, err)
+	}
+	batchTag := ""
+	if c.cfg.Name != "" {
+		batchTag = c.cfg.Name + ".batch"
+	}
+	batch, err := conn.ch.Consume(c.cfg.batchQueue(), batchTag, false, false, false, false, nil)
+	if err != nil {
+		conn.close()
+		return fmt.Errorf("failed to consume queue %s: %w", c.cfg.batchQueue(), err)
+	}
+	c.current = conn
+	c.interactive = interactive
+	c.batch = batch
+	return nil
+}
+
+// Receive waits for a delivery, then returns it with any others already buffered, up to
+// max. A closed connection is re-established on the next call.
+func (c *Consumer) Receive(ctx context.Context, max int) ([]*queue.Delivery, error) {
+	if max <= 0 {
+		max = 1
+	}
+	if c.current == nil || c.current.conn.IsClosed() {
+		c.current.close()
+		c.current = nil
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Take whatever is already buffered, interactive first, before waiting on either queue
+	received := drain(c.interactive, nil, max)
+	received = drain(c.batch, received, max)
+	if len(received) > 0 {
+		return received, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case d, ok := <-c.interactive:
+		if !ok {
+			return nil, fmt.Errorf("RabbitMQ delivery channel closed")
+		}
+		received = append(received, wrap(d))
+	case d, ok := <-c.batch:
+		if !ok {
+			return nil, fmt.Errorf("RabbitMQ delivery channel closed")
+		}
+		received = append(received, wrap(d))
+	}
+	received = drain(c.interactive, received, max)
+	return drain(c.batch, received, max), nil
+}
+
+// drain appends buffered deliveries without blocking until received holds max
+func drain(deliveries <-chan amqp.Delivery, received []*queue.Delivery, max int) []*queue.Delivery {
+	for len(received) < max {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				return received
+			}
+			received = append(received, wrap(d))
+		default:
+			return received
+		}
+	}
+	return received
+}
+
+// Close closes the connection; unacknowledged deliveries are requeued by the broker
+func (c *Consumer) Close() error {
+	c.current.close()
+	c.current = nil
+	return nil
+}
+
+func wrap(d amqp.Delivery) *queue.Delivery {
+	// Undecodable bodies are still delivered so the caller can drop them with Nack
+	msg, _ := queue.Decode(d.Body)
+	return queue.NewDelivery(msg, strconv.FormatUint(d.DeliveryTag, 10),
+		func(context.Context) error { return d.Ack(false) },
+		func(_ context.Context, requeue bool) error { return d.Nack(false, requeue) },
+	)
+}
+
+// redactURL hides the password of an amqp URL for logging
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,266 @@
+// Package queuemonitor samples the job pipeline's backlog: how many jobs each broker queue
+// holds, how long the oldest one has waited for a worker, and how many jobs are stuck
+// IN_PROGRESS. The samples are exported as metrics, and when an alert URL is configured
+// a threshold being crossed, or recovered from, is posted to it as JSON or as a Slack
+// message.
+package queuemonitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Alert formats
+const (
+	// FormatWebhook posts an Alert as JSON
+	FormatWebhook = "webhook"
+	// FormatSlack posts a Slack incoming webhook message
+	FormatSlack = "slack"
+)
+
+// Alert statuses
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Config tunes sampling and alerting
+type Config struct {
+	Interval time.Duration
+	// StuckAfter is how long an IN_PROGRESS job may go without a progress update before
+	// it counts as stuck
+	StuckAfter time.Duration
+	// MaxMessages, MaxAge and MaxStuck are the alert thresholds: jobs held by a queue,
+	// the wait of a queue's oldest job and stuck jobs. 0 disables an alert.
+	MaxMessages int64
+	MaxAge      time.Duration
+	MaxStuck    int
+	// AlertURL receives alerts in AlertFormat, FormatWebhook or FormatSlack; without it
+	// alerts are only logged
+	AlertURL    string
+	AlertFormat string
+}
+
+// AlertState records which alerts are firing, so that with several replicas sampling
+// the same queues only one notifies; implemented by the Redis client
+type AlertState interface {
+	// RaiseAlert marks the alert firing, reporting whether it wasn't already
+	RaiseAlert(ctx context.Context, name string) (bool, error)
+	// ClearAlert marks the alert resolved, reporting whether it was firing
+	ClearAlert(ctx context.Context, name string) (bool, error)
+}
+
+// Alert is a threshold crossed or recovered from, the body of a FormatWebhook alert
+type Alert struct {
+	Name      string    `json:"alert"`
+	Status    string    `json:"status"`
+	Message   string    `json:"message"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	At        time.Time `json:"at"`
+}
+
+// Monitor samples the job pipeline
+type Monitor struct {
+	depth  func(ctx context.Context) ([]queue.Depth, error)
+	jobs   repository.JobRepository
+	state  AlertState
+	client *http.Client
+	cfg    Config
+}
+
+// New creates a monitor over the broker's queues, as reported by depth; call Run to start
+// sampling. Without state, alerts are tracked by this process alone.
+func New(depth func(ctx context.Context) ([]queue.Depth, error), jobs repository.JobRepository, state AlertState, cfg Config) (*Monitor, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.StuckAfter <= 0 {
+		cfg.StuckAfter = 15 * time.Minute
+	}
+	if cfg.AlertFormat == "" {
+		cfg.AlertFormat = FormatWebhook
+	}
+	if cfg.AlertFormat != FormatWebhook && cfg.AlertFormat != FormatSlack {
+		return nil, fmt.Errorf("unknown alert format %q (expected %s or %s)", cfg.AlertFormat, FormatWebhook, FormatSlack)
+	}
+	if state == nil {
+		state = &memoryState{firing: map[string]bool{}}
+	}
+	return &Monitor{depth: depth, jobs: jobs, state: state, client: &http.Client{Timeout: 10 * time.Second}, cfg: cfg}, nil
+}
+
+// Run samples every interval until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.sample(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) sample(ctx context.Context) {
+	now := time.Now()
+	if m.depth != nil {
+		depths, err := m.depth(ctx)
+		if err != nil {
+			log.Printf("Queue monitor: failed to read queue depth: %v", err)
+		}
+		for _, depth := range depths {
+			m.sampleQueue(ctx, depth, now)
+		}
+	}
+
+	stuck, err := m.jobs.CountStale(ctx, models.JobStatusInProgress, now.Add(-m.cfg.StuckAfter))
+	if err != nil {
+		log.Printf("Queue monitor: failed to count stuck jobs: %v", err)
+		return
+	}
+	metrics.JobsInProgressStale.Set(float64(stuck))
+	if m.cfg.MaxStuck > 0 {
+		m.check(ctx, Alert{
+			Name:      "stuck_jobs",
+			Message:   fmt.Sprintf("%d jobs IN_PROGRESS without progress for %s (threshold %d)", stuck, m.cfg.StuckAfter, m.cfg.MaxStuck),
+			Value:     float64(stuck),
+			Threshold: float64(m.cfg.MaxStuck),
+			At:        now,
+		}, stuck > m.cfg.MaxStuck)
+	}
+}
+
+// sampleQueue exports one queue's depth and checks it against the thresholds. Scheduled
+// jobs aren't waiting for a worker, so they don't alert.
+func (m *Monitor) sampleQueue(ctx context.Context, depth queue.Depth, now time.Time) {
+	var age time.Duration
+	if depth.Oldest != nil {
+		age = max(now.Sub(*depth.Oldest), 0)
+	}
+	metrics.JobQueueMessages.WithLabelValues(depth.Queue).Set(float64(depth.Messages))
+	metrics.JobQueuePending.WithLabelValues(depth.Queue).Set(float64(depth.Pending))
+	metrics.JobQueueOldestAge.WithLabelValues(depth.Queue).Set(age.Seconds())
+	if depth.Scheduled {
+		return
+	}
+
+	if m.cfg.MaxMessages > 0 {
+		m.check(ctx, Alert{
+			Name:      "queue_messages:" + depth.Queue,
+			Message:   fmt.Sprintf("%s holds %d jobs (threshold %d)", depth.Queue, depth.Messages, m.cfg.MaxMessages),
+			Value:     float64(depth.Messages),
+			Threshold: float64(m.cfg.MaxMessages),
+			At:        now,
+		}, depth.Messages > m.cfg.MaxMessages)
+	}
+	if m.cfg.MaxAge > 0 {
+		m.check(ctx, Alert{
+			Name:      "queue_age:" + depth.Queue,
+			Message:   fmt.Sprintf("the oldest job in %s has waited %s for a worker (threshold %s)", depth.Queue, age.Round(time.Second), m.cfg.MaxAge),
+			Value:     age.Seconds(),
+			Threshold: m.cfg.MaxAge.Seconds(),
+			At:        now,
+		}, age > m.cfg.MaxAge)
+	}
+}
+
+// check records whether alert is firing and notifies when it starts or stops
+func (m *Monitor) check(ctx context.Context, alert Alert, firing bool) {
+	var changed bool
+	var err error
+	if firing {
+		metrics.QueueAlertFiring.WithLabelValues(alert.Name).Set(1)
+		alert.Status = StatusFiring
+		changed, err = m.state.RaiseAlert(ctx, alert.Name)
+	} else {
+		metrics.QueueAlertFiring.WithLabelValues(alert.Name).Set(0)
+		alert.Status = StatusResolved
+		changed, err = m.state.ClearAlert(ctx, alert.Name)
+	}
+	if err != nil {
+		log.Printf("Queue monitor: failed to record alert %s: %v", alert.Name, err)
+		return
+	}
+	if !changed {
+		return
+	}
+
+	log.Printf("ALERT queue monitor: %s: %s", alert.Status, alert.Message)
+	if m.cfg.AlertURL == "" {
+		return
+	}
+	if err := m.notify(ctx, alert); err != nil {
+		metrics.QueueAlertNotifications.WithLabelValues("failed").Inc()
+		log.Printf("Queue monitor: failed to send alert %s: %v", alert.Name, err)
+		return
+	}
+	metrics.QueueAlertNotifications.WithLabelValues("sent").Inc()
+}
+
+// notify posts alert to the alert URL
+func (m *Monitor) notify(ctx context.Context, alert Alert) error {
+	var body interface{} = alert
+	if m.cfg.AlertFormat == FormatSlack {
+		icon := ":rotating_light:"
+		if alert.Status == StatusResolved {
+			icon = ":white_check_mark:"
+		}
+		body = map[string]string{"text": fmt.Sprintf("%s Job queue %s: %s", icon, alert.Status, alert.Message)}
+	}
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.AlertURL, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert URL answered %s", resp.Status)
+	}
+	return nil
+}
+
+// memoryState tracks alerts within this process
+type memoryState struct {
+	mu     sync.Mutex
+	firing map[string]bool
+}
+
+func (s *memoryState) RaiseAlert(ctx context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raised := !s.firing[name]
+	s.firing[name] = true
+	return raised, nil
+}
+
+func (s *memoryState) ClearAlert(ctx context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cleared := s.firing[name]
+	delete(s.firing, name)
+	return cleared, nil
+}
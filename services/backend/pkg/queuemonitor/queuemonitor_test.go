@@ -0,0 +1,87 @@
+package queuemonitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestMonitorAlerts(t *testing.T) {
+	var mu sync.Mutex
+	var alerts []Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("invalid alert: %v", err)
+		}
+		mu.Lock()
+		alerts = append(alerts, alert)
+		mu.Unlock()
+	}))
+	defer server.Close()
+	received := func() []Alert {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]Alert(nil), alerts...)
+	}
+
+	oldest := time.Now().Add(-10 * time.Minute)
+	depths := []queue.Depth{
+		{Queue: "commute_jobs:stream", Messages: 3, Oldest: &oldest},
+		{Queue: "commute_jobs:delayed", Messages: 500, Scheduled: true},
+	}
+	depth := func(ctx context.Context) ([]queue.Depth, error) { return depths, nil }
+	monitor, err := New(depth, repository.NewMemoryRepositories().Jobs, nil, Config{
+		MaxMessages: 100,
+		MaxAge:      5 * time.Minute,
+		AlertURL:    server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stream's oldest job has waited too long; the scheduled jobs don't count
+	monitor.sample(context.Background())
+	monitor.sample(context.Background())
+	if alerts := received(); len(alerts) != 1 || alerts[0].Name != "queue_age:commute_jobs:stream" || alerts[0].Status != StatusFiring {
+		t.Fatalf("alerts = %+v, want one firing age alert", alerts)
+	}
+
+	// Workers caught up
+	depths[0].Oldest = nil
+	monitor.sample(context.Background())
+	if alerts := received(); len(alerts) != 2 || alerts[1].Name != "queue_age:commute_jobs:stream" || alerts[1].Status != StatusResolved {
+		t.Fatalf("alerts = %+v, want the age alert resolved", alerts)
+	}
+
+	if _, err := New(depth, nil, nil, Config{AlertFormat: "pager"}); err == nil {
+		t.Error("unknown alert format accepted")
+	}
+}
+
+func TestNotifySlack(t *testing.T) {
+	var message map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&message)
+	}))
+	defer server.Close()
+
+	monitor, err := New(nil, nil, nil, Config{AlertURL: server.URL, AlertFormat: FormatSlack})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = monitor.notify(context.Background(), Alert{Name: "stuck_jobs", Status: StatusFiring, Message: "3 jobs IN_PROGRESS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message["text"] != ":rotating_light: Job queue firing: 3 jobs IN_PROGRESS" {
+		t.Errorf("slack message = %+v", message)
+	}
+}
@@ -0,0 +1,165 @@
+package reaper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/webhooks"
+)
+
+// Queue puts a job back on the worker queue through its outbox, so the job is queued if
+// and only if the transaction of ctx commits; implemented by jobqueue.Queue
+type Queue interface {
+	AddJobToOutbox(ctx context.Context, jobID, userID, targetDate string, inputData *string, priority models.JobPriority) error
+}
+
+// Transactor runs a function in a database transaction; implemented by database.DB
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Publisher emits the job.failed webhook for reaped jobs; implemented by the webhook dispatcher
+type Publisher interface {
+	Publish(ctx context.Context, userID, eventType string, data interface{}) error
+}
+
+// Config tunes stuck-job detection
+type Config struct {
+	Interval time.Duration
+	// StaleAfter is how long an IN_PROGRESS job may go without a progress update
+	StaleAfter time.Duration
+	// MaxRequeues is how many times a stuck job is put back on the queue before it is
+	// marked FAILED; 0 fails it straight away
+	MaxRequeues int
+	BatchSize   int
+}
+
+// Reaper finds jobs left IN_PROGRESS by a crashed or hung worker
+type Reaper struct {
+	db        Transactor
+	jobs      repository.JobRepository
+	queue     Queue
+	publisher Publisher
+	cfg       Config
+}
+
+// NewReaper creates a reaper; call Run to start sweeping. queue and publisher may be nil.
+func NewReaper(db Transactor, jobs repository.JobRepository, queue Queue, publisher Publisher, cfg Config) *Reaper {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = 15 * time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &Reaper{db: db, jobs: jobs, queue: queue, publisher: publisher, cfg: cfg}
+}
+
+// Run sweeps for stuck jobs until ctx is cancelled
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reaper) sweep(ctx context.Context) {
+	cutoff := time.Now().Add(-r.cfg.StaleAfter)
+	jobs, err := r.jobs.ListStale(ctx, models.JobStatusInProgress, cutoff, r.cfg.BatchSize)
+	if err != nil {
+		log.Printf("Job reaper: failed to list stuck jobs: %v", err)
+		return
+	}
+	metrics.StuckJobs.Set(float64(len(jobs)))
+
+	for _, job := range jobs {
+		action, err := r.reap(ctx, job)
+		var transitionErr *models.JobTransitionError
+		if errors.As(err, &transitionErr) || errors.Is(err, repository.ErrConflict) || errors.Is(err, repository.ErrNotFound) {
+			// The worker finished (or another instance reaped it) since the sweep started
+			continue
+		}
+		if err != nil {
+			log.Printf("Job reaper: failed to reap job %s: %v", job.ID, err)
+			continue
+		}
+		metrics.JobsReaped.WithLabelValues(action).Inc()
+		log.Printf("ALERT job reaper: job %s had no progress since %s, %s", job.ID, job.UpdatedAt.Format(time.RFC3339), action)
+	}
+}
+
+// reap requeues or fails a stuck job, returning the action taken
+func (r *Reaper) reap(ctx context.Context, job *models.Job) (string, error) {
+	requeues, err := r.requeueCount(ctx, job.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if requeues < r.cfg.MaxRequeues && r.queue != nil {
+		// The job goes back to PENDING together with its outbox entry, so a job the
+		// queue didn't take stays IN_PROGRESS and is reaped again on the next sweep
+		err := r.db.InTx(ctx, func(ctx context.Context) error {
+			status := string(models.JobStatusPending)
+			step := fmt.Sprintf("Requeued after no progress for %s", r.cfg.StaleAfter)
+			progress := 0.0
+			if _, err := r.jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &status, Progress: &progress, CurrentStep: &step}); err != nil {
+				return err
+			}
+			targetDate := job.TargetDate
+			if len(targetDate) > 10 {
+				targetDate = targetDate[:10]
+			}
+			if err := r.queue.AddJobToOutbox(ctx, job.ID, job.UserID, targetDate, job.InputData, job.Priority); err != nil {
+				return fmt.Errorf("requeue: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return "requeued", nil
+	}
+
+	status := string(models.JobStatusFailed)
+	message := fmt.Sprintf("Job timed out: no progress update for %s", r.cfg.StaleAfter)
+	failed, err := r.jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &status, ErrorMessage: &message})
+	if err != nil {
+		return "", err
+	}
+	if r.publisher != nil {
+		if err := r.publisher.Publish(ctx, failed.UserID, webhooks.EventJobFailed, failed); err != nil {
+			log.Printf("Job reaper: failed to publish %s webhook for job %s: %v", webhooks.EventJobFailed, job.ID, err)
+		}
+	}
+	return "failed", nil
+}
+
+// requeueCount counts IN_PROGRESS -> PENDING transitions in the job's history
+func (r *Reaper) requeueCount(ctx context.Context, jobID string) (int, error) {
+	events, err := r.jobs.Events(ctx, jobID)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, event := range events {
+		if event.ToStatus == models.JobStatusPending && event.FromStatus != nil && *event.FromStatus == models.JobStatusInProgress {
+			count++
+		}
+	}
+	return count, nil
+}
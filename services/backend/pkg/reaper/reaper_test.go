@@ -0,0 +1,128 @@
+package reaper
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/webhooks"
+)
+
+type fakeQueue struct {
+	queued []string
+	// err fails every enqueue
+	err error
+}
+
+func (q *fakeQueue) AddJobToOutbox(ctx context.Context, jobID, userID, targetDate string, inputData *string, priority models.JobPriority) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.queued = append(q.queued, jobID)
+	return nil
+}
+
+type fakePublisher struct {
+	events []string
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, userID, eventType string, data interface{}) error {
+	p.events = append(p.events, eventType)
+	return nil
+}
+
+func TestReaperSweep(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	jobs := repository.NewSQLJobRepository(db)
+	user, err := repository.NewSQLUserRepository(db).Create(ctx, repository.NewUser{Email: "ada@example.com", Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// start puts a new job IN_PROGRESS and, unless fresh, leaves it without progress for an hour
+	start := func(fresh bool) *models.Job {
+		t.Helper()
+		job, err := jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: "2026-03-02"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		status := string(models.JobStatusInProgress)
+		if _, err := jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &status}); err != nil {
+			t.Fatal(err)
+		}
+		if !fresh {
+			testdb.Backdate(t, db, "jobs", job.ID, time.Now().Add(-time.Hour))
+		}
+		return job
+	}
+	status := func(id string) models.JobStatus {
+		t.Helper()
+		job, err := jobs.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return job.Status
+	}
+
+	queue := &fakeQueue{}
+	publisher := &fakePublisher{}
+	reaper := NewReaper(db, jobs, queue, publisher, Config{StaleAfter: 15 * time.Minute, MaxRequeues: 1})
+
+	stuck := start(false)
+	active := start(true)
+
+	// A job the queue doesn't take stays IN_PROGRESS, to be reaped again
+	queue.err = errors.New("outbox unavailable")
+	reaper.sweep(ctx)
+	if got := status(stuck.ID); got != models.JobStatusInProgress {
+		t.Fatalf("stuck job is %s after a failed requeue, want it left IN_PROGRESS", got)
+	}
+
+	queue.err = nil
+	reaper.sweep(ctx)
+
+	if got := status(stuck.ID); got != models.JobStatusPending {
+		t.Fatalf("stuck job is %s after the first sweep, want PENDING", got)
+	}
+	if len(queue.queued) != 1 || queue.queued[0] != stuck.ID {
+		t.Fatalf("queued %v, want the stuck job", queue.queued)
+	}
+	if got := status(active.ID); got != models.JobStatusInProgress {
+		t.Errorf("active job is %s, want it left IN_PROGRESS", got)
+	}
+
+	// Picked up and stuck again: it has used its requeue, so it fails
+	setInProgress := string(models.JobStatusInProgress)
+	if _, err := jobs.Update(ctx, stuck.ID, repository.JobUpdate{Status: &setInProgress}); err != nil {
+		t.Fatal(err)
+	}
+	testdb.Backdate(t, db, "jobs", stuck.ID, time.Now().Add(-time.Hour))
+	reaper.sweep(ctx)
+
+	failed, err := jobs.Get(ctx, stuck.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status != models.JobStatusFailed || failed.ErrorMessage == nil {
+		t.Fatalf("stuck job is %s (%v) after the second sweep, want FAILED with a message", failed.Status, failed.ErrorMessage)
+	}
+	if len(queue.queued) != 1 {
+		t.Errorf("queued %v, want no second requeue", queue.queued)
+	}
+	if len(publisher.events) != 1 || publisher.events[0] != webhooks.EventJobFailed {
+		t.Errorf("published %v, want job.failed", publisher.events)
+	}
+
+	events, err := jobs.Events(ctx, stuck.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.ReplayJobEvents(events); err != nil {
+		t.Errorf("reaped job's history doesn't replay: %v", err)
+	}
+}
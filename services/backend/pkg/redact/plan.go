@@ -0,0 +1,106 @@
+package redact
+
+import (
+	"encoding/json"
+
+	"github.com/commute-planner/backend/pkg/i18n"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// meetingTimeFields are the keys a meeting's start and end are stored under, by the AI
+// service and by the seed and demo data
+var meetingTimeFields = [][2]string{{"start_time", "end_time"}, {"start", "end"}}
+
+// Recommendations applies a user's share redaction policy to the recommendations of a
+// plan shown through a share link, in place. MEETING_DETAILS, the default, reduces each
+// meeting to its time block and drops what could name or quote one: the AI's free-text
+// reasoning, the travel legs' places and free-text reason notes. TIME_BLOCKS keeps only
+// the options' ranks, types and time blocks.
+func Recommendations(policy *models.ShareRedaction, recommendations []*models.CommuteRecommendation) {
+	level := models.ShareRedactionMeetingDetails
+	if policy != nil {
+		level = *policy
+	}
+	if level == models.ShareRedactionNone {
+		return
+	}
+	for i, rec := range recommendations {
+		if level == models.ShareRedactionTimeBlocks {
+			recommendations[i] = timeBlocks(rec)
+			continue
+		}
+		rec.OfficeMeetings = meetingBlocks(rec.OfficeMeetings)
+		rec.RemoteMeetings = meetingBlocks(rec.RemoteMeetings)
+		rec.OffsiteMeetings = meetingBlocks(rec.OffsiteMeetings)
+		rec.Reasoning, rec.TradeOffs, rec.PerceptionAnalysis, rec.BusinessRuleCompliance = nil, nil, nil, nil
+
+		rec.TravelLegs = nil
+		for j := range rec.Legs {
+			rec.Legs[j].From, rec.Legs[j].To, rec.Legs[j].MeetingID = nil, nil, nil
+		}
+
+		var codes []models.ReasonCode
+		for _, code := range rec.ReasonCodes {
+			if code.Param("note") == "" {
+				codes = append(codes, code)
+			}
+		}
+		rec.ReasonCodes = codes
+		if rec.Localized != nil {
+			rec.Localized = i18n.Render(rec.Localized.Locale, codes)
+		}
+	}
+}
+
+// timeBlocks returns the option's rank, type and time blocks, and nothing else
+func timeBlocks(rec *models.CommuteRecommendation) *models.CommuteRecommendation {
+	return &models.CommuteRecommendation{
+		ID:              rec.ID,
+		JobID:           rec.JobID,
+		OptionRank:      rec.OptionRank,
+		OptionType:      rec.OptionType,
+		CommuteStart:    rec.CommuteStart,
+		OfficeArrival:   rec.OfficeArrival,
+		OfficeDeparture: rec.OfficeDeparture,
+		CommuteEnd:      rec.CommuteEnd,
+		OfficeDuration:  rec.OfficeDuration,
+		OfficeMeetings:  meetingBlocks(rec.OfficeMeetings),
+		RemoteMeetings:  meetingBlocks(rec.RemoteMeetings),
+		OffsiteMeetings: meetingBlocks(rec.OffsiteMeetings),
+		AcceptedAt:      rec.AcceptedAt,
+		CreatedAt:       rec.CreatedAt,
+	}
+}
+
+// meetingBlocks reduces a JSON list of meetings to their start and end times. Meetings
+// stored as bare titles, or that can't be read, keep only their place in the list, so
+// the number of meetings still shows.
+func meetingBlocks(meetings *string) *string {
+	if meetings == nil {
+		return nil
+	}
+	var list []interface{}
+	if err := json.Unmarshal([]byte(*meetings), &list); err != nil {
+		empty := "[]"
+		return &empty
+	}
+	blocks := make([]map[string]string, len(list))
+	for i, meeting := range list {
+		blocks[i] = map[string]string{}
+		fields, ok := meeting.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, keys := range meetingTimeFields {
+			start, _ := fields[keys[0]].(string)
+			end, _ := fields[keys[1]].(string)
+			if start != "" || end != "" {
+				blocks[i]["start"], blocks[i]["end"] = start, end
+				break
+			}
+		}
+	}
+	data, _ := json.Marshal(blocks)
+	redacted := string(data)
+	return &redacted
+}
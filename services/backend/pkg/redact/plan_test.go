@@ -0,0 +1,72 @@
+package redact
+
+import (
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/i18n"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func sharedRecommendation() *models.CommuteRecommendation {
+	str := func(s string) *string { return &s }
+	arrival := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	codes := []models.ReasonCode{
+		{Code: "FULL_DAY_OFFICE", Params: []models.ReasonParam{{Name: "officeMeetings", Value: "2"}}},
+		{Code: "DAY_NOTE", Params: []models.ReasonParam{{Name: "note", Value: "Board review with Ada"}}},
+	}
+	return &models.CommuteRecommendation{
+		ID:             "r1",
+		OptionRank:     1,
+		OptionType:     models.CommuteOptionFullDayOffice,
+		OfficeArrival:  &arrival,
+		OfficeMeetings: str(`[{"summary":"Board review","attendees":["ada@example.com"],"start_time":"2026-03-02T10:00:00Z","end_time":"2026-03-02T11:00:00Z"},"Lunch with Bob"]`),
+		TravelLegs:     str(`[{"from":"12 Home Street","to":"HQ"}]`),
+		Legs:           []models.TravelLeg{{From: str("12 Home Street"), To: str("HQ")}},
+		Reasoning:      str("Go in for the board review"),
+		ReasonCodes:    codes,
+		Localized:      i18n.Render("en", codes),
+		Experiment:     str("ranking-v2"),
+	}
+}
+
+func TestRecommendations(t *testing.T) {
+	none := models.ShareRedactionNone
+	recs := []*models.CommuteRecommendation{sharedRecommendation()}
+	Recommendations(&none, recs)
+	if recs[0].Reasoning == nil || recs[0].TravelLegs == nil {
+		t.Errorf("NONE redacted the plan: %+v", recs[0])
+	}
+
+	// The default strips meeting details
+	recs = []*models.CommuteRecommendation{sharedRecommendation()}
+	Recommendations(nil, recs)
+	rec := recs[0]
+	wantMeetings := `[{"end":"2026-03-02T11:00:00Z","start":"2026-03-02T10:00:00Z"},{}]`
+	if rec.OfficeMeetings == nil || *rec.OfficeMeetings != wantMeetings {
+		t.Errorf("office meetings = %v, want %s", rec.OfficeMeetings, wantMeetings)
+	}
+	if rec.Reasoning != nil || rec.TravelLegs != nil || rec.Legs[0].From != nil || rec.Legs[0].To != nil {
+		t.Errorf("free text or places kept: %+v", rec)
+	}
+	if len(rec.ReasonCodes) != 1 || rec.ReasonCodes[0].Code != "FULL_DAY_OFFICE" {
+		t.Errorf("reason codes = %+v, want the note dropped", rec.ReasonCodes)
+	}
+	if rec.Localized == nil || rec.Localized.Reasoning == "" {
+		t.Errorf("localized text = %+v, want it rendered from the kept codes", rec.Localized)
+	}
+	if rec.Experiment == nil || rec.OfficeArrival == nil {
+		t.Error("fields unrelated to meetings dropped")
+	}
+
+	blocks := models.ShareRedactionTimeBlocks
+	recs = []*models.CommuteRecommendation{sharedRecommendation()}
+	Recommendations(&blocks, recs)
+	rec = recs[0]
+	if rec.OptionType != models.CommuteOptionFullDayOffice || rec.OfficeArrival == nil || rec.OfficeMeetings == nil || *rec.OfficeMeetings != wantMeetings {
+		t.Errorf("time blocks lost: %+v", rec)
+	}
+	if rec.Experiment != nil || rec.ReasonCodes != nil || rec.Localized != nil || rec.Legs != nil {
+		t.Errorf("TIME_BLOCKS kept more than time blocks: %+v", rec)
+	}
+}
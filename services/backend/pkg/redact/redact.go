@@ -0,0 +1,126 @@
+// Package redact scrubs personal data and credentials from log lines and from the error
+// messages returned to clients: email addresses, JWTs, API and SCIM tokens, and
+// Authorization header values wherever they appear, and the values of fields, such as
+// an event's summary, that aren't on an allowlist of fields known to be safe. It also
+// applies users' redaction policies to the plans they share.
+package redact
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Placeholders replacing what is scrubbed
+const (
+	Email    = "[email]"
+	Token    = "[token]"
+	Redacted = "[redacted]"
+)
+
+// DefaultSafeFields are the fields whose values are kept: identifiers, states and the
+// error messages themselves. Field names are matched ignoring case and underscores, and
+// identifiers (id, job_id, jobId, jobID) are safe too.
+var DefaultSafeFields = []string{
+	"status", "step", "state", "error", "errors", "message", "detail", "code", "type",
+	"kind", "reason", "method", "path", "operation", "operationname", "provider",
+	"priority", "targetdate", "event", "events", "queue", "attempt", "attempts", "count",
+	"duration", "version", "success", "scimtype", "scope", "scopes", "tenant",
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+	jwtPattern   = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)
+	authPattern  = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=\-]+`)
+	tokenPattern = regexp.MustCompile(`\b(?:cpat|cscim)_[A-Za-z0-9_\-]+`)
+	// jsonField matches a JSON string member, "field": "value"
+	jsonField = regexp.MustCompile(`"([A-Za-z_][A-Za-z0-9_]*)"(\s*:\s*)"((?:[^"\\]|\\.)*)"`)
+	// keyValue matches field=value, with the value bare or quoted, as in log lines and
+	// query strings
+	keyValue = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)=("(?:[^"\\]|\\.)*"|[^\s,;&"]+)`)
+)
+
+// Redactor scrubs strings. A nil Redactor leaves them as they are, for development.
+type Redactor struct {
+	safe map[string]bool
+}
+
+// New creates a redactor keeping the values of DefaultSafeFields and of safeFields
+func New(safeFields ...string) *Redactor {
+	r := &Redactor{safe: map[string]bool{}}
+	for _, field := range append(append([]string{}, DefaultSafeFields...), safeFields...) {
+		r.safe[normalize(field)] = true
+	}
+	return r
+}
+
+// String returns s with personal data and credentials replaced by placeholders
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	s = jsonField.ReplaceAllStringFunc(s, func(match string) string {
+		parts := jsonField.FindStringSubmatch(match)
+		if r.isSafe(parts[1]) {
+			return match
+		}
+		return `"` + parts[1] + `"` + parts[2] + `"` + Redacted + `"`
+	})
+	s = keyValue.ReplaceAllStringFunc(s, func(match string) string {
+		parts := keyValue.FindStringSubmatch(match)
+		if r.isSafe(parts[1]) {
+			return match
+		}
+		return parts[1] + "=" + Redacted
+	})
+	s = jwtPattern.ReplaceAllString(s, Token)
+	s = authPattern.ReplaceAllString(s, "$1 "+Token)
+	s = tokenPattern.ReplaceAllString(s, Token)
+	return emailPattern.ReplaceAllString(s, Email)
+}
+
+// Strings scrubs each of messages, returning a new slice
+func (r *Redactor) Strings(messages []string) []string {
+	if r == nil || messages == nil {
+		return messages
+	}
+	scrubbed := make([]string, len(messages))
+	for i, message := range messages {
+		scrubbed[i] = r.String(message)
+	}
+	return scrubbed
+}
+
+// Writer returns a writer scrubbing what is written to w. Each write must be whole lines,
+// as the log package writes them.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	if r == nil {
+		return w
+	}
+	return writer{r: r, w: w}
+}
+
+type writer struct {
+	r *Redactor
+	w io.Writer
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (r *Redactor) isSafe(field string) bool {
+	if field == "id" || strings.HasSuffix(field, "_id") || strings.HasSuffix(field, "Id") || strings.HasSuffix(field, "ID") {
+		return true
+	}
+	return r.safe[normalize(field)]
+}
+
+// normalize lower-cases a field name and drops underscores, so user_id, userId and
+// UserID are the same field
+func normalize(field string) string {
+	return strings.ToLower(strings.ReplaceAll(field, "_", ""))
+}
@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/commute-planner/backend/pkg/breaker"
+	"github.com/go-redis/redis/v8"
+)
+
+// isFailure counts errors meaning Redis is unreachable or too slow. Error replies (and
+// nil replies) come from a healthy server.
+func isFailure(err error) bool {
+	var reply redis.Error
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, redis.Nil) && !errors.As(err, &reply)
+}
+
+type breakerDoneKey struct{}
+
+// breakerHook runs every command, health check pings included, through a circuit breaker
+type breakerHook struct {
+	breaker *breaker.Breaker
+}
+
+func (h breakerHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return h.before(ctx)
+}
+
+func (h breakerHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.after(ctx, cmd.Err())
+	return nil
+}
+
+func (h breakerHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return h.before(ctx)
+}
+
+func (h breakerHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if err = cmd.Err(); err != nil && isFailure(err) {
+			break
+		}
+	}
+	h.after(ctx, err)
+	return nil
+}
+
+func (h breakerHook) before(ctx context.Context) (context.Context, error) {
+	done, err := h.breaker.Allow()
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, breakerDoneKey{}, done), nil
+}
+
+// after reports the command's outcome; commands the breaker rejected have nothing to report
+func (h breakerHook) after(ctx context.Context, err error) {
+	if done, ok := ctx.Value(breakerDoneKey{}).(func(error)); ok {
+		done(err)
+	}
+}
@@ -2,84 +2,611 @@ package redis
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
-	
+
+	"github.com/commute-planner/backend/pkg/breaker"
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/queue"
 	"github.com/go-redis/redis/v8"
 )
 
+// Job queue transports. Streams keep a message pending until the worker acknowledges it, so
+// a worker crash no longer loses the job; the list is kept for workers that predate them.
+const (
+	QueueModeStream = "stream"
+	QueueModeList   = "list"
+)
+
+// Job queue keys. The stream needs its own key: a key can't be both a list and a stream.
+// Batch jobs get separate keys so workers can drain interactive jobs first.
+const (
+	JobQueueList        = "commute_jobs"
+	JobQueueStream      = "commute_jobs:stream"
+	JobQueueBatchList   = "commute_jobs:batch"
+	JobQueueBatchStream = "commute_jobs:stream:batch"
+)
+
+// streamField is the stream entry field holding the JSON job message
+const streamField = "job"
+
+// Config describes how to reach Redis
+type Config struct {
+	Addr     string
+	Password string
+	DB       int
+	// TLS enables TLS (e.g. managed Redis); InsecureSkipVerify is for self-signed dev setups
+	TLS                bool
+	InsecureSkipVerify bool
+	// Commands are retried MaxRetries times with a backoff between MinRetryBackoff and
+	// MaxRetryBackoff; broken connections are replaced by the pool automatically
+	MaxRetries      int
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+	DialTimeout     time.Duration
+	// CommandTimeout bounds each command the client sends (other than the consumers'
+	// blocking reads) within whatever deadline the caller's context already has
+	CommandTimeout time.Duration
+	// HealthCheckInterval is how often Monitor pings Redis
+	HealthCheckInterval time.Duration
+	// QueueMode is QueueModeStream (default) or QueueModeList
+	QueueMode string
+	// StreamMaxLen is how many entries a job stream holds before Monitor trims the ones
+	// every consumer group has acknowledged. Entries not yet read or acknowledged are never
+	// trimmed, however long the stream grows. 0 uses 10000.
+	StreamMaxLen int64
+	// CircuitBreaker, when set, fails commands fast while Redis is failing, so jobs go
+	// straight to the outbox and token checks fail instead of waiting on retries
+	CircuitBreaker *breaker.Config
+}
+
 type Client struct {
 	client *redis.Client
-}
+	addr   string
+	cfg    Config
 
+	mu      sync.RWMutex
+	healthy bool
+	lastErr error
+}
 
-// NewClient creates a new Redis client
-func NewClient(addr string) *Client {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: "", // no password
-		DB:       0,  // default DB
-	})
+// NewClient creates a new Redis client. An unreachable server is not fatal: the client
+// reconnects on its own and Monitor reports when the connection comes back.
+func NewClient(cfg Config) *Client {
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = 10 * time.Second
+	}
+	if cfg.QueueMode == "" {
+		cfg.QueueMode = QueueModeStream
+	}
+	if cfg.StreamMaxLen <= 0 {
+		cfg.StreamMaxLen = 10000
+	}
+	opts := &redis.Options{
+		Addr:            cfg.Addr,
+		Password:        cfg.Password,
+		DB:              cfg.DB,
+		MaxRetries:      cfg.MaxRetries,
+		MinRetryBackoff: cfg.MinRetryBackoff,
+		MaxRetryBackoff: cfg.MaxRetryBackoff,
+		DialTimeout:     cfg.DialTimeout,
+	}
+	if cfg.TLS {
+		host, _, err := net.SplitHostPort(cfg.Addr)
+		if err != nil {
+			host = cfg.Addr
+		}
+		opts.TLSConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			ServerName:         host,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		}
+	}
+	c := &Client{client: redis.NewClient(opts), addr: cfg.Addr, cfg: cfg}
+	if cfg.CircuitBreaker != nil {
+		settings := *cfg.CircuitBreaker
+		settings.IsFailure = isFailure
+		c.client.AddHook(breakerHook{breaker.New("redis", settings)})
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	
-	_, err := rdb.Ping(ctx).Result()
-	if err != nil {
-		log.Printf("Warning: Could not connect to Redis at %s: %v", addr, err)
-		log.Printf("Jobs will be created in database but may not be processed by AI service")
+	c.checkHealth(ctx)
+	if c.Healthy() {
+		log.Printf("Connected to Redis at %s", cfg.Addr)
 	} else {
-		log.Printf("Connected to Redis at %s", addr)
+		log.Printf("Warning: Could not connect to Redis at %s: %v", cfg.Addr, c.LastError())
+		log.Printf("Jobs will be buffered in the database until Redis is reachable")
+	}
+
+	return c
+}
+
+// Monitor pings Redis periodically until ctx is cancelled, logging when the connection is
+// lost or restored and exporting the state as the redis_up metric
+func (c *Client) Monitor(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		wasHealthy := c.Healthy()
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		c.checkHealth(pingCtx)
+		cancel()
+		if c.Healthy() && c.cfg.QueueMode == QueueModeStream {
+			if err := c.TrimStreams(ctx); err != nil {
+				log.Printf("Failed to trim the job streams: %v", err)
+			}
+		}
+
+		switch healthy := c.Healthy(); {
+		case wasHealthy && !healthy:
+			log.Printf("Redis connection to %s lost: %v", c.addr, c.LastError())
+		case !wasHealthy && healthy:
+			log.Printf("Redis connection to %s restored", c.addr)
+		}
+	}
+}
+
+// withTimeout bounds ctx by the command timeout. Callers must defer cancel.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.cfg.CommandTimeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, c.cfg.CommandTimeout)
+}
 
-	return &Client{client: rdb}
+// Healthy reports whether the last health check succeeded
+func (c *Client) Healthy() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.healthy
 }
 
-// JobMessage represents the job data structure expected by AI service
-type JobMessage struct {
-	JobID      string  `json:"job_id"`
-	UserID     string  `json:"user_id"`
-	TargetDate string  `json:"target_date"`
-	InputData  *string `json:"input_data,omitempty"`
+// LastError returns the error of the last failed health check, if any
+func (c *Client) LastError() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastErr
 }
 
-// AddJobToQueue adds a job to the commute_jobs queue
-func (c *Client) AddJobToQueue(ctx context.Context, jobID, userID, targetDate string, inputData *string) error {
-	if c.client == nil {
-		return fmt.Errorf("redis client not initialized")
+func (c *Client) checkHealth(ctx context.Context) {
+	err := c.client.Ping(ctx).Err()
+
+	c.mu.Lock()
+	c.healthy = err == nil
+	c.lastErr = err
+	c.mu.Unlock()
+
+	if err == nil {
+		metrics.RedisUp.Set(1)
+	} else {
+		metrics.RedisUp.Set(0)
 	}
+}
 
-	// Create job message as JSON object (as expected by AI service)
-	jobMessage := JobMessage{
-		JobID:      jobID,
-		UserID:     userID,
-		TargetDate: targetDate,
-		InputData:  inputData,
+// Publish adds a job to the commute_jobs stream, or the legacy list in list mode
+func (c *Client) Publish(ctx context.Context, msg queue.Message) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client not initialized")
 	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
 
-	// Marshal to JSON
-	messageJSON, err := json.Marshal(jobMessage)
+	body, err := msg.Encode()
 	if err != nil {
-		return fmt.Errorf("failed to marshal job message: %w", err)
+		return err
 	}
 
-	// Add job JSON to the commute_jobs queue
-	err = c.client.LPush(ctx, "commute_jobs", string(messageJSON)).Err()
+	if c.cfg.QueueMode == QueueModeList {
+		key := JobQueueList
+		if msg.IsBatch() {
+			key = JobQueueBatchList
+		}
+		err = c.client.LPush(ctx, key, string(body)).Err()
+	} else {
+		stream := JobQueueStream
+		if msg.IsBatch() {
+			stream = JobQueueBatchStream
+		}
+		// The worker's consumer group creates the stream; MKSTREAM isn't needed on XADD.
+		// No MAXLEN: it would trim jobs no worker has read yet. TrimStreams trims the
+		// acknowledged ones.
+		err = c.client.XAdd(ctx, &redis.XAddArgs{
+			Stream: stream,
+			Values: map[string]interface{}{streamField: string(body)},
+		}).Err()
+	}
 	if err != nil {
 		return fmt.Errorf("failed to add job to queue: %w", err)
 	}
 
-	log.Printf("Added job %s to Redis queue for processing", jobID)
+	log.Printf("Added job %s to Redis %s for processing", msg.JobID, c.cfg.QueueMode)
+	return nil
+}
+
+// TrimStreams trims the entries of each job stream longer than StreamMaxLen that every
+// consumer group has read and acknowledged. A stream without groups isn't trimmed: no
+// worker has read any of it yet.
+func (c *Client) TrimStreams(ctx context.Context) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	for _, stream := range jobStreams {
+		n, err := c.client.XLen(ctx, stream).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", stream, err)
+		}
+		if n <= c.cfg.StreamMaxLen {
+			continue
+		}
+		minID, err := c.acknowledgedBefore(ctx, stream)
+		if err != nil {
+			return err
+		}
+		if minID == "" {
+			continue
+		}
+		// Approximate trimming may keep a few more entries, never fewer
+		trimmed, err := c.client.XTrimMinIDApprox(ctx, stream, minID, 0).Result()
+		if err != nil {
+			return fmt.Errorf("failed to trim %s: %w", stream, err)
+		}
+		if trimmed > 0 {
+			log.Printf("Trimmed %d acknowledged entries from %s", trimmed, stream)
+		}
+	}
 	return nil
 }
 
+// acknowledgedBefore returns the entry ID of stream before which every consumer group has
+// read and acknowledged every entry: the oldest entry pending with a group, or else the
+// last one delivered to it. It is empty when the stream has no groups.
+func (c *Client) acknowledgedBefore(ctx context.Context, stream string) (string, error) {
+	groups, err := c.client.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to read consumer groups of %s: %w", stream, err)
+	}
+	var minID string
+	for _, group := range groups {
+		id := group.LastDeliveredID
+		if group.Pending > 0 {
+			pending, err := c.client.XPending(ctx, stream, group.Name).Result()
+			if err != nil {
+				return "", fmt.Errorf("failed to read pending entries of %s: %w", stream, err)
+			}
+			if pending.Count > 0 {
+				id = pending.Lower
+			}
+		}
+		if minID == "" || compareEntryIDs(id, minID) < 0 {
+			minID = id
+		}
+	}
+	return minID, nil
+}
+
+// compareEntryIDs orders stream entry IDs (milliseconds-sequence)
+func compareEntryIDs(a, b string) int {
+	parse := func(id string) (uint64, uint64) {
+		millis, seq, _ := strings.Cut(id, "-")
+		ms, _ := strconv.ParseUint(millis, 10, 64)
+		n, _ := strconv.ParseUint(seq, 10, 64)
+		return ms, n
+	}
+	aMillis, aSeq := parse(a)
+	bMillis, bSeq := parse(b)
+	switch {
+	case aMillis < bMillis || aMillis == bMillis && aSeq < bSeq:
+		return -1
+	case aMillis == bMillis && aSeq == bSeq:
+		return 0
+	}
+	return 1
+}
+
+// QueueDepth reports the job queues of the configured mode and the delay queue
+func (c *Client) QueueDepth(ctx context.Context) ([]queue.Depth, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var depths []queue.Depth
+	if c.cfg.QueueMode == QueueModeList {
+		for _, key := range []string{JobQueueList, JobQueueBatchList} {
+			n, err := c.client.LLen(ctx, key).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", key, err)
+			}
+			depths = append(depths, queue.Depth{Queue: key, Messages: n})
+		}
+	} else {
+		for _, stream := range []string{JobQueueStream, JobQueueBatchStream} {
+			n, err := c.client.XLen(ctx, stream).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", stream, err)
+			}
+			depth := queue.Depth{Queue: stream, Messages: n}
+			// A stream nobody consumes yet has no groups (or doesn't exist)
+			if n > 0 {
+				groups, err := c.client.XInfoGroups(ctx, stream).Result()
+				if err != nil {
+					return nil, fmt.Errorf("failed to read consumer groups of %s: %w", stream, err)
+				}
+				for _, group := range groups {
+					depth.Pending += group.Pending
+				}
+				if depth.Oldest, err = c.oldestWaiting(ctx, stream, groups); err != nil {
+					return nil, err
+				}
+			}
+			depths = append(depths, depth)
+		}
+	}
+
+	scheduled, err := c.scheduledDepth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(depths, scheduled), nil
+}
+
+// ScheduledDepth reports how many jobs wait in the delay queue for their scheduled time.
+// QueueDepth includes it; this is for brokers that keep the job queues elsewhere.
+func (c *Client) ScheduledDepth(ctx context.Context) (queue.Depth, error) {
+	if c.client == nil {
+		return queue.Depth{}, fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.scheduledDepth(ctx)
+}
+
+func (c *Client) scheduledDepth(ctx context.Context) (queue.Depth, error) {
+	scheduled, err := c.client.ZCard(ctx, JobDelayQueue).Result()
+	if err != nil {
+		return queue.Depth{}, fmt.Errorf("failed to read %s: %w", JobDelayQueue, err)
+	}
+	return queue.Depth{Queue: JobDelayQueue, Messages: scheduled, Scheduled: true}, nil
+}
+
+// oldestWaiting returns when the oldest entry of stream that a consumer group hasn't read
+// yet was added, or nil when every group has read them all. Without groups every entry
+// is waiting. Entry IDs start with the time they were added in milliseconds.
+func (c *Client) oldestWaiting(ctx context.Context, stream string, groups []redis.XInfoGroup) (*time.Time, error) {
+	starts := []string{"-"}
+	if len(groups) > 0 {
+		starts = starts[:0]
+		for _, group := range groups {
+			starts = append(starts, "("+group.LastDeliveredID)
+		}
+	}
+
+	var oldest *time.Time
+	for _, start := range starts {
+		entries, err := c.client.XRangeN(ctx, stream, start, "+", 1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", stream, err)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		millis, _, _ := strings.Cut(entries[0].ID, "-")
+		ms, err := strconv.ParseInt(millis, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry ID %q in %s", entries[0].ID, stream)
+		}
+		if added := time.UnixMilli(ms); oldest == nil || added.Before(*oldest) {
+			oldest = &added
+		}
+	}
+	return oldest, nil
+}
+
+// PoolStats is the state of the client's connection pool
+type PoolStats struct {
+	TotalConns uint32 `json:"totalConns"`
+	IdleConns  uint32 `json:"idleConns"`
+	StaleConns uint32 `json:"staleConns"`
+	// Hits and Misses count connection checkouts that found an idle connection or dialed
+	Hits     uint32 `json:"hits"`
+	Misses   uint32 `json:"misses"`
+	Timeouts uint32 `json:"timeouts"`
+}
+
+// PoolStats reports the connection pool, for diagnostics
+func (c *Client) PoolStats() PoolStats {
+	stats := c.client.PoolStats()
+	return PoolStats{
+		TotalConns: stats.TotalConns,
+		IdleConns:  stats.IdleConns,
+		StaleConns: stats.StaleConns,
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+		Timeouts:   stats.Timeouts,
+	}
+}
+
 // Close closes the Redis connection
 func (c *Client) Close() error {
 	if c.client != nil {
 		return c.client.Close()
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// RevokeToken adds a token ID (jti) to the denylist until the token would have expired anyway
+func (c *Client) RevokeToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	if ttl <= 0 {
+		// Token already expired - nothing to revoke
+		return nil
+	}
+
+	if err := c.client.Set(ctx, "revoked_token:"+jti, "1", ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// IsTokenRevoked reports whether a token ID (jti) is on the denylist
+func (c *Client) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	if c.client == nil {
+		return false, fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	n, err := c.client.Exists(ctx, "revoked_token:"+jti).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RevokeAllUserTokens invalidates every token issued to a user before the current second
+// ("log out all devices"). Tokens of the current second are kept, so signing in again right
+// away works.
+// The cutoff only needs to live as long as the longest-lived token.
+func (c *Client) RevokeAllUserTokens(ctx context.Context, userID string, ttl time.Duration) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	cutoff := time.Now().Unix()
+	if err := c.client.Set(ctx, "tokens_revoked_before:"+userID, cutoff, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
+}
+
+// UserTokensRevokedBefore returns the cutoff set by RevokeAllUserTokens, or the zero time if none
+func (c *Client) UserTokensRevokedBefore(ctx context.Context, userID string) (time.Time, error) {
+	if c.client == nil {
+		return time.Time{}, fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	cutoff, err := c.client.Get(ctx, "tokens_revoked_before:"+userID).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read user token cutoff: %w", err)
+	}
+	return time.Unix(cutoff, 0), nil
+}
+
+// RaiseAlert marks an operator alert as firing, reporting whether it wasn't already.
+// Every replica checks the same conditions; only the one that raises or clears an alert
+// sends the notification.
+func (c *Client) RaiseAlert(ctx context.Context, name string) (bool, error) {
+	if c.client == nil {
+		return false, fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	raised, err := c.client.SetNX(ctx, "alert_firing:"+name, time.Now().Unix(), 0).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to raise alert: %w", err)
+	}
+	return raised, nil
+}
+
+// ClearAlert marks an operator alert as resolved, reporting whether it was firing
+func (c *Client) ClearAlert(ctx context.Context, name string) (bool, error) {
+	if c.client == nil {
+		return false, fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	n, err := c.client.Del(ctx, "alert_firing:"+name).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to clear alert: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Broadcast publishes message on a pub/sub channel, to every instance listening on it
+func (c *Client) Broadcast(ctx context.Context, channel string, message []byte) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if err := c.client.Publish(ctx, channel, message).Err(); err != nil {
+		return fmt.Errorf("failed to broadcast on %s: %w", channel, err)
+	}
+	return nil
+}
+
+// Listen returns the messages published on a pub/sub channel from now until ctx is
+// cancelled, when the channel is closed. The subscription reconnects on its own; messages
+// published while it is down are missed.
+func (c *Client) Listen(ctx context.Context, channel string) <-chan []byte {
+	messages := make(chan []byte)
+	if c.client == nil {
+		close(messages)
+		return messages
+	}
+	sub := c.client.Subscribe(ctx, channel)
+	go func() {
+		defer close(messages)
+		defer sub.Close()
+		received := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-received:
+				if !ok {
+					return
+				}
+				select {
+				case messages <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return messages
+}
+
+// Ping checks that Redis is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.client.Ping(ctx).Err()
+}
@@ -0,0 +1,103 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/commute-planner/backend/pkg/queue"
+)
+
+// newTestClient returns a stream mode client of an in-memory Redis
+func newTestClient(t *testing.T, cfg Config) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	cfg.Addr = server.Addr()
+	c := NewClient(cfg)
+	t.Cleanup(func() { c.Close() })
+	return c, server
+}
+
+func TestTrimStreamsKeepsUnacknowledgedEntries(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t, Config{StreamMaxLen: 2})
+	consumer, err := NewStreamConsumer(ctx, c, ConsumerConfig{Group: "workers", Consumer: "w1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		if err := c.Publish(ctx, queue.Message{JobID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Publishing never trims: no worker has read any of the five
+	if err := c.TrimStreams(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.client.XLen(ctx, JobQueueStream).Val(); n != 5 {
+		t.Fatalf("stream length before any read = %d, want 5", n)
+	}
+
+	// Read three and acknowledge the first and third: b is still pending
+	deliveries, err := consumer.Receive(ctx, 3)
+	if err != nil || len(deliveries) != 3 {
+		t.Fatalf("Receive = %d deliveries, %v", len(deliveries), err)
+	}
+	for _, i := range []int{0, 2} {
+		if err := deliveries[i].Ack(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.TrimStreams(ctx); err != nil {
+		t.Fatal(err)
+	}
+	entries := c.client.XRange(ctx, JobQueueStream, "-", "+").Val()
+	var jobs []string
+	for _, entry := range entries {
+		msg, _ := queue.Decode([]byte(entry.Values[streamField].(string)))
+		jobs = append(jobs, msg.JobID)
+	}
+	if len(jobs) != 4 || jobs[0] != "b" || jobs[3] != "e" {
+		t.Errorf("jobs left = %v, want b through e", jobs)
+	}
+
+	// Once b is acknowledged too, only the unread entries and the last read one remain
+	if err := deliveries[1].Ack(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TrimStreams(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.client.XLen(ctx, JobQueueStream).Val(); n != 3 {
+		t.Errorf("stream length after acknowledging = %d, want 3 (c, d, e)", n)
+	}
+}
+
+func TestTrimStreamsLeavesShortStreams(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t, Config{StreamMaxLen: 10})
+	consumer, err := NewStreamConsumer(ctx, c, ConsumerConfig{Group: "workers", Consumer: "w1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := c.Publish(ctx, queue.Message{JobID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deliveries, err := consumer.Receive(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range deliveries {
+		d.Ack(ctx)
+	}
+	if err := c.TrimStreams(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.client.XLen(ctx, JobQueueStream).Val(); n != 2 {
+		t.Errorf("stream length = %d, want 2: it is within StreamMaxLen", n)
+	}
+}
@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/go-redis/redis/v8"
+)
+
+// ConsumerConfig identifies a member of the job stream's consumer group
+type ConsumerConfig struct {
+	Group    string
+	Consumer string
+	// ClaimIdle is how long an entry may stay pending with another consumer before it is
+	// considered abandoned and claimed. 0 uses 5 minutes.
+	ClaimIdle time.Duration
+	// Block bounds how long Receive waits for new entries. 0 uses 5 seconds.
+	Block time.Duration
+}
+
+// StreamConsumer reads jobs from the job streams through a consumer group, the same way
+// the AI service workers do
+type StreamConsumer struct {
+	client *Client
+	cfg    ConsumerConfig
+}
+
+// NewStreamConsumer joins (creating if needed) the consumer group on the job streams
+func NewStreamConsumer(ctx context.Context, c *Client, cfg ConsumerConfig) (*StreamConsumer, error) {
+	if cfg.Group == "" || cfg.Consumer == "" {
+		return nil, fmt.Errorf("consumer group and consumer name are required")
+	}
+	if cfg.ClaimIdle <= 0 {
+		cfg.ClaimIdle = 5 * time.Minute
+	}
+	if cfg.Block <= 0 {
+		cfg.Block = 5 * time.Second
+	}
+
+	for _, stream := range jobStreams {
+		err := c.client.XGroupCreateMkStream(ctx, stream, cfg.Group, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return nil, fmt.Errorf("failed to create consumer group on %s: %w", stream, err)
+		}
+	}
+	return &StreamConsumer{client: c, cfg: cfg}, nil
+}
+
+// jobStreams lists the job streams in the order they are drained
+var jobStreams = []string{JobQueueStream, JobQueueBatchStream}
+
+// Receive claims entries abandoned by crashed consumers first, then waits for new ones.
+// Interactive jobs are always returned ahead of batch jobs.
+func (s *StreamConsumer) Receive(ctx context.Context, max int) ([]*queue.Delivery, error) {
+	if max <= 0 {
+		max = 1
+	}
+
+	for _, stream := range jobStreams {
+		claimed, _, err := s.client.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    s.cfg.Group,
+			Consumer: s.cfg.Consumer,
+			MinIdle:  s.cfg.ClaimIdle,
+			Start:    "0-0",
+			Count:    int64(max),
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim pending jobs: %w", err)
+		}
+		if len(claimed) > 0 {
+			return s.deliveries(stream, claimed), nil
+		}
+	}
+
+	// Batch jobs are only read when no interactive job is waiting (a negative Block
+	// doesn't block)
+	for _, stream := range jobStreams {
+		deliveries, err := s.read(ctx, stream, max, -1)
+		if err != nil || len(deliveries) > 0 {
+			return deliveries, err
+		}
+	}
+	// Each read covers one stream, since a read across both could return up to max
+	// entries from each. Only interactive jobs are waited for; a batch job queued
+	// meanwhile is picked up by the next call, at most Block later.
+	return s.read(ctx, JobQueueStream, max, s.cfg.Block)
+}
+
+func (s *StreamConsumer) read(ctx context.Context, stream string, max int, block time.Duration) ([]*queue.Delivery, error) {
+	result, err := s.client.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    s.cfg.Group,
+		Consumer: s.cfg.Consumer,
+		Streams:  []string{stream, ">"},
+		Count:    int64(max),
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job stream: %w", err)
+	}
+
+	var deliveries []*queue.Delivery
+	for _, r := range result {
+		deliveries = append(deliveries, s.deliveries(r.Stream, r.Messages)...)
+	}
+	return deliveries, nil
+}
+
+// Close is a no-op; the underlying client is shared and closed by its owner
+func (s *StreamConsumer) Close() error {
+	return nil
+}
+
+func (s *StreamConsumer) deliveries(stream string, messages []redis.XMessage) []*queue.Delivery {
+	deliveries := make([]*queue.Delivery, 0, len(messages))
+	for _, m := range messages {
+		id := m.ID
+		body, _ := m.Values[streamField].(string)
+		// Undecodable entries are still delivered (with an empty message) so the
+		// caller can acknowledge them instead of claiming them forever
+		msg, _ := queue.Decode([]byte(body))
+
+		ack := func(ctx context.Context) error {
+			return s.client.client.XAck(ctx, stream, s.cfg.Group, id).Err()
+		}
+		nack := func(ctx context.Context, requeue bool) error {
+			if requeue {
+				// Re-adding puts the job at the end of the stream for any consumer,
+				// rather than waiting out the claim timeout
+				if err := s.client.Publish(ctx, msg); err != nil {
+					return err
+				}
+			}
+			return ack(ctx)
+		}
+		deliveries = append(deliveries, queue.NewDelivery(msg, id, ack, nack))
+	}
+	return deliveries
+}
@@ -0,0 +1,191 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/queue"
+)
+
+func newTestConsumer(t *testing.T, c *Client, name string, claimIdle time.Duration) *StreamConsumer {
+	t.Helper()
+	consumer, err := NewStreamConsumer(context.Background(), c, ConsumerConfig{
+		Group:     "workers",
+		Consumer:  name,
+		ClaimIdle: claimIdle,
+		Block:     10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return consumer
+}
+
+// deliveredIDs lists the job IDs of deliveries, in delivery order
+func deliveredIDs(deliveries []*queue.Delivery) []string {
+	ids := make([]string, 0, len(deliveries))
+	for _, d := range deliveries {
+		ids = append(ids, d.Message.JobID)
+	}
+	return ids
+}
+
+func TestStreamConsumerDeliversInteractiveJobsFirst(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t, Config{})
+	consumer := newTestConsumer(t, c, "w1", time.Minute)
+
+	// The batch job was queued first, but interactive jobs are drained ahead of it
+	for _, msg := range []queue.Message{
+		{JobID: "batch", Priority: models.JobPriorityBatch},
+		{JobID: "interactive", Priority: models.JobPriorityInteractive},
+	} {
+		if err := c.Publish(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var order []string
+	for i := 0; i < 2; i++ {
+		deliveries, err := consumer.Receive(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range deliveries {
+			if err := d.Ack(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		order = append(order, deliveredIDs(deliveries)...)
+	}
+	if len(order) != 2 || order[0] != "interactive" || order[1] != "batch" {
+		t.Errorf("delivered %v, want interactive then batch", order)
+	}
+
+	// Nothing is left to deliver once both are acknowledged
+	deliveries, err := consumer.Receive(ctx, 1)
+	if err != nil || len(deliveries) != 0 {
+		t.Errorf("Receive after acknowledging everything = %v, %v; want nothing", deliveredIDs(deliveries), err)
+	}
+}
+
+func TestStreamConsumerDeliversEveryReadEntry(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t, Config{})
+	consumer := newTestConsumer(t, c, "w1", time.Minute)
+
+	// Two jobs wait on each stream; every call returns at most max, and none are left
+	// pending undelivered
+	for _, msg := range []queue.Message{
+		{JobID: "batch-1", Priority: models.JobPriorityBatch},
+		{JobID: "batch-2", Priority: models.JobPriorityBatch},
+		{JobID: "interactive-1", Priority: models.JobPriorityInteractive},
+		{JobID: "interactive-2", Priority: models.JobPriorityInteractive},
+	} {
+		if err := c.Publish(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var delivered []string
+	for i := 0; i < 3; i++ {
+		deliveries, err := consumer.Receive(ctx, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) > 3 {
+			t.Fatalf("Receive(3) returned %d deliveries", len(deliveries))
+		}
+		for _, d := range deliveries {
+			if err := d.Ack(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		delivered = append(delivered, deliveredIDs(deliveries)...)
+	}
+	want := []string{"interactive-1", "interactive-2", "batch-1", "batch-2"}
+	if strings.Join(delivered, ",") != strings.Join(want, ",") {
+		t.Errorf("delivered %v, want %v", delivered, want)
+	}
+
+	for _, stream := range jobStreams {
+		pending, err := c.client.XPending(ctx, stream, "workers").Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pending.Count != 0 {
+			t.Errorf("%d entries left pending on %s", pending.Count, stream)
+		}
+	}
+}
+
+func TestStreamConsumerClaimsAbandonedJobs(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t, Config{})
+	crashed := newTestConsumer(t, c, "crashed", time.Millisecond)
+	survivor := newTestConsumer(t, c, "survivor", time.Millisecond)
+
+	if err := c.Publish(ctx, queue.Message{JobID: "job"}); err != nil {
+		t.Fatal(err)
+	}
+	taken, err := crashed.Receive(ctx, 1)
+	if err != nil || len(taken) != 1 {
+		t.Fatalf("Receive = %v, %v", deliveredIDs(taken), err)
+	}
+
+	// The crashed consumer never acknowledges it; once idle it is claimed by another
+	time.Sleep(5 * time.Millisecond)
+	claimed, err := survivor.Receive(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 1 || claimed[0].Message.JobID != "job" || claimed[0].ID != taken[0].ID {
+		t.Fatalf("claimed %v, want the abandoned entry", deliveredIDs(claimed))
+	}
+	if err := claimed[0].Ack(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if pending := c.client.XPending(ctx, JobQueueStream, "workers").Val(); pending.Count != 0 {
+		t.Errorf("%d entries pending after the claimed one was acknowledged", pending.Count)
+	}
+}
+
+func TestStreamConsumerNackRequeues(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t, Config{})
+	consumer := newTestConsumer(t, c, "w1", time.Minute)
+
+	if err := c.Publish(ctx, queue.Message{JobID: "retry"}); err != nil {
+		t.Fatal(err)
+	}
+	first, err := consumer.Receive(ctx, 1)
+	if err != nil || len(first) != 1 {
+		t.Fatalf("Receive = %v, %v", deliveredIDs(first), err)
+	}
+	if err := first[0].Nack(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requeued as a new entry, the original one acknowledged
+	again, err := consumer.Receive(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 1 || again[0].Message.JobID != "retry" || again[0].ID == first[0].ID {
+		t.Fatalf("redelivered %v, want the job as a new entry", deliveredIDs(again))
+	}
+	if pending := c.client.XPending(ctx, JobQueueStream, "workers").Val(); pending.Count != 1 {
+		t.Errorf("%d entries pending, want only the redelivered one", pending.Count)
+	}
+
+	// Without requeue the job is dropped
+	if err := again[0].Nack(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	if deliveries, err := consumer.Receive(ctx, 1); err != nil || len(deliveries) != 0 {
+		t.Errorf("Receive after a dropping nack = %v, %v; want nothing", deliveredIDs(deliveries), err)
+	}
+}
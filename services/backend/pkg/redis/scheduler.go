@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/go-redis/redis/v8"
+)
+
+// JobDelayQueue is the sorted set of scheduled jobs, scored by due time in Unix milliseconds
+const JobDelayQueue = "commute_jobs:delayed"
+
+// claimDueScript leases due members by pushing their score to the end of the lease, so
+// concurrent movers never claim the same job twice
+var claimDueScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, member in ipairs(due) do
+	redis.call('ZADD', KEYS[1], ARGV[3], member)
+end
+return due
+`)
+
+// Schedule adds a job to the delay queue
+func (c *Client) Schedule(ctx context.Context, msg queue.Message, at time.Time) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	body, err := msg.Encode()
+	if err != nil {
+		return err
+	}
+	err = c.client.ZAdd(ctx, JobDelayQueue, &redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: string(body),
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to schedule job: %w", err)
+	}
+	return nil
+}
+
+// ClaimDue leases up to limit jobs due at now
+func (c *Client) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*queue.Scheduled, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	members, err := claimDueScript.Run(ctx, c.client, []string{JobDelayQueue},
+		strconv.FormatInt(now.UnixMilli(), 10),
+		limit,
+		strconv.FormatInt(now.Add(lease).UnixMilli(), 10),
+	).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim scheduled jobs: %w", err)
+	}
+
+	jobs := make([]*queue.Scheduled, 0, len(members))
+	for _, member := range members {
+		// Undecodable members are still returned so the caller can remove them
+		msg, _ := queue.Decode([]byte(member))
+		jobs = append(jobs, &queue.Scheduled{Message: msg, Key: member})
+	}
+	return jobs, nil
+}
+
+// Remove drops a claimed job from the delay queue
+func (c *Client) Remove(ctx context.Context, job *queue.Scheduled) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.client.ZRem(ctx, JobDelayQueue, job.Key).Err()
+}
@@ -0,0 +1,115 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/queue"
+)
+
+// scheduledIDs lists the job IDs of claimed jobs, in claim order
+func scheduledIDs(jobs []*queue.Scheduled) []string {
+	ids := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		ids = append(ids, job.Message.JobID)
+	}
+	return ids
+}
+
+func TestClaimDueLeasesJobs(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t, Config{})
+	now := time.Now()
+
+	// Scheduled out of order; claimed by due time
+	for id, at := range map[string]time.Time{
+		"later":  now.Add(-time.Second),
+		"first":  now.Add(-time.Minute),
+		"future": now.Add(time.Hour),
+	} {
+		if err := c.Schedule(ctx, queue.Message{JobID: id}, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs, err := c.ClaimDue(ctx, now, time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := scheduledIDs(jobs); fmt.Sprint(ids) != "[first later]" {
+		t.Fatalf("claimed %v, want [first later]", ids)
+	}
+
+	// Leased jobs aren't claimed again while the lease lasts
+	again, err := c.ClaimDue(ctx, now.Add(30*time.Second), time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("claimed %v during the lease, want none", scheduledIDs(again))
+	}
+
+	// A mover that died without removing its jobs leaves them due again once the lease
+	// expires; a removed job is gone for good
+	if err := c.Remove(ctx, jobs[0]); err != nil {
+		t.Fatal(err)
+	}
+	expired, err := c.ClaimDue(ctx, now.Add(time.Minute+time.Millisecond), time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := scheduledIDs(expired); fmt.Sprint(ids) != "[later]" {
+		t.Errorf("claimed %v after the lease expired, want [later]", ids)
+	}
+}
+
+func TestClaimDueNeverClaimsTwice(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t, Config{})
+	now := time.Now()
+	const jobs = 50
+	for i := 0; i < jobs; i++ {
+		if err := c.Schedule(ctx, queue.Message{JobID: fmt.Sprint(i)}, now.Add(-time.Duration(i)*time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Movers race for the due jobs in small batches
+	var mu sync.Mutex
+	claims := map[string]int{}
+	var wg sync.WaitGroup
+	for mover := 0; mover < 8; mover++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				claimed, err := c.ClaimDue(ctx, now, time.Minute, 3)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if len(claimed) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, id := range scheduledIDs(claimed) {
+					claims[id]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claims) != jobs {
+		t.Errorf("%d jobs claimed, want %d", len(claims), jobs)
+	}
+	for id, n := range claims {
+		if n != 1 {
+			t.Errorf("job %s claimed %d times", id, n)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Package reminders sends the reminders to leave scheduled for accepted plans, by email
+// or webhook as each user chose, with times in the user's own timezone.
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/notify"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/units"
+	"github.com/commute-planner/backend/pkg/webhooks"
+)
+
+// Publisher emits the commute.reminder webhook; implemented by the webhook dispatcher
+type Publisher interface {
+	Publish(ctx context.Context, userID, eventType string, data interface{}) error
+}
+
+// Config tunes how reminders are sent
+type Config struct {
+	// Interval is how often due reminders are checked for
+	Interval time.Duration
+	// BatchSize bounds the reminders claimed per check
+	BatchSize int
+}
+
+// Reminder is the data of a commute.reminder webhook
+type Reminder struct {
+	ID               string    `json:"id"`
+	RecommendationID string    `json:"recommendationId"`
+	TargetDate       string    `json:"targetDate"`
+	Summary          string    `json:"summary"`
+	DepartAt         time.Time `json:"departAt"`
+	// LocalDepartTime is DepartAt in the user's timezone, e.g. "08:15"
+	LocalDepartTime string `json:"localDepartTime"`
+	Timezone        string `json:"timezone"`
+}
+
+// Scheduler sends each due reminder once over its channels
+type Scheduler struct {
+	reminders repository.CommuteReminderRepository
+	users     repository.UserRepository
+	notifier  notify.Notifier
+	publisher Publisher
+	cfg       Config
+	now       func() time.Time
+}
+
+// NewScheduler creates a scheduler; call Run to start sending. notifier and publisher
+// may be nil, failing the reminders sent over their channel.
+func NewScheduler(reminders repository.CommuteReminderRepository, users repository.UserRepository, notifier notify.Notifier, publisher Publisher, cfg Config) *Scheduler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &Scheduler{reminders: reminders, users: users, notifier: notifier, publisher: publisher, cfg: cfg, now: time.Now}
+}
+
+// Run sends due reminders until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) sweep(ctx context.Context) {
+	now := s.now()
+	// Reminders are useless once the user should have left
+	if n, err := s.reminders.CancelMissed(ctx, now); err != nil {
+		log.Printf("Commute reminders: failed to cancel missed reminders: %v", err)
+	} else if n > 0 {
+		metrics.CommuteReminders.WithLabelValues("missed").Add(float64(n))
+	}
+
+	for ctx.Err() == nil {
+		due, err := s.reminders.ClaimDue(ctx, now, s.cfg.BatchSize)
+		if err != nil {
+			log.Printf("Commute reminders: failed to claim due reminders: %v", err)
+			return
+		}
+		for _, reminder := range due {
+			if err := s.send(ctx, reminder); err != nil {
+				metrics.CommuteReminders.WithLabelValues("failed").Inc()
+				log.Printf("Commute reminders: failed to send reminder %s to user %s: %v", reminder.ID, reminder.UserID, err)
+				if err := s.reminders.Fail(ctx, reminder.ID, err.Error()); err != nil {
+					log.Printf("Commute reminders: failed to record failure of reminder %s: %v", reminder.ID, err)
+				}
+				continue
+			}
+			metrics.CommuteReminders.WithLabelValues("sent").Inc()
+		}
+		if len(due) < s.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// send delivers a claimed reminder over each of its channels
+func (s *Scheduler) send(ctx context.Context, reminder *models.CommuteReminder) error {
+	user, err := s.users.Get(ctx, reminder.UserID)
+	if err != nil {
+		return fmt.Errorf("fetching user: %w", err)
+	}
+	timezone, err := s.users.PreferredTimezone(ctx, reminder.UserID)
+	if err != nil {
+		return fmt.Errorf("fetching timezone: %w", err)
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location, timezone = time.UTC, "UTC"
+	}
+
+	var failures []string
+	for _, channel := range reminder.Channels {
+		var err error
+		switch channel {
+		case models.ReminderChannelEmail:
+			err = s.email(ctx, user, reminder, location)
+		case models.ReminderChannelWebhook:
+			err = s.webhook(ctx, reminder, location, timezone)
+		default:
+			err = fmt.Errorf("unknown channel")
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", channel, err))
+		}
+	}
+	// Reaching the user on any channel is enough
+	if len(failures) > 0 && len(failures) == len(reminder.Channels) {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	for _, failure := range failures {
+		log.Printf("Commute reminders: reminder %s not sent over %s", reminder.ID, failure)
+	}
+	return nil
+}
+
+func (s *Scheduler) email(ctx context.Context, user *models.User, reminder *models.CommuteReminder, location *time.Location) error {
+	if s.notifier == nil {
+		return fmt.Errorf("email is not configured")
+	}
+	return s.notifier.Send(ctx, Render(user, reminder, location))
+}
+
+func (s *Scheduler) webhook(ctx context.Context, reminder *models.CommuteReminder, location *time.Location, timezone string) error {
+	if s.publisher == nil {
+		return fmt.Errorf("webhooks are not configured")
+	}
+	return s.publisher.Publish(ctx, reminder.UserID, webhooks.EventCommuteReminder, Reminder{
+		ID:               reminder.ID,
+		RecommendationID: reminder.RecommendationID,
+		TargetDate:       reminder.TargetDate,
+		Summary:          reminder.Summary,
+		DepartAt:         reminder.DepartAt,
+		LocalDepartTime:  reminder.DepartAt.In(location).Format("15:04"),
+		Timezone:         timezone,
+	})
+}
+
+// Render writes the reminder email for user, with times in location in the user's time
+// format
+func Render(user *models.User, reminder *models.CommuteReminder, location *time.Location) notify.Message {
+	depart := units.For(user, location).Time(reminder.DepartAt)
+	name := user.Name
+	if name == "" {
+		name = "there"
+	}
+	return notify.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("%s at %s", reminder.Summary, depart),
+		Text: fmt.Sprintf("Hi %s,\n\n%s at %s to stay on today's plan.\n\n"+
+			"You get these reminders because you turned on commute reminders in your notification settings.\n",
+			name, reminder.Summary, depart),
+	}
+}
@@ -0,0 +1,121 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/notify"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+type recordingNotifier struct {
+	sent []notify.Message
+	err  error
+}
+
+func (n *recordingNotifier) Send(ctx context.Context, msg notify.Message) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+type recordingPublisher struct {
+	events []Reminder
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, userID, eventType string, data interface{}) error {
+	p.events = append(p.events, data.(Reminder))
+	return nil
+}
+
+func TestSchedulerSweep(t *testing.T) {
+	ctx := context.Background()
+	users := repository.NewMemoryUserRepository()
+	store := repository.NewMemoryCommuteReminderRepository()
+	user, err := users.Create(ctx, repository.NewUser{Email: "ada@example.com", Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	users.SetPreferredTimezone(user.ID, "Europe/Berlin")
+
+	// 08:00 and 17:00 in Berlin
+	office := time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC)
+	home := time.Date(2026, 3, 2, 16, 0, 0, 0, time.UTC)
+	both := []models.ReminderChannel{models.ReminderChannelEmail, models.ReminderChannelWebhook}
+	err = store.Replace(ctx, user.ID, "2026-03-02", []*models.CommuteReminder{
+		{RecommendationID: "rec-1", Summary: "Leave for the office", DepartAt: office, RemindAt: office.Add(-15 * time.Minute), Channels: both},
+		{RecommendationID: "rec-1", Summary: "Leave the office for home", DepartAt: home, RemindAt: home.Add(-15 * time.Minute), Channels: []models.ReminderChannel{models.ReminderChannelEmail}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifier, publisher := &recordingNotifier{}, &recordingPublisher{}
+	scheduler := NewScheduler(store, users, notifier, publisher, Config{})
+	sweepAt := func(at time.Time) {
+		scheduler.now = func() time.Time { return at }
+		scheduler.sweep(ctx)
+	}
+
+	// Nothing is due before the lead time
+	sweepAt(office.Add(-20 * time.Minute))
+	if len(notifier.sent) != 0 || len(publisher.events) != 0 {
+		t.Fatalf("sent early: %+v %+v", notifier.sent, publisher.events)
+	}
+
+	sweepAt(office.Add(-10 * time.Minute))
+	if len(notifier.sent) != 1 || notifier.sent[0].To != "ada@example.com" || !strings.Contains(notifier.sent[0].Subject, "08:00") {
+		t.Errorf("emails = %+v, want the reminder at 08:00 Berlin time", notifier.sent)
+	}
+	if len(publisher.events) != 1 || publisher.events[0].LocalDepartTime != "08:00" || publisher.events[0].Timezone != "Europe/Berlin" {
+		t.Errorf("webhooks = %+v", publisher.events)
+	}
+	// Sent once
+	sweepAt(office.Add(-5 * time.Minute))
+	if len(notifier.sent) != 1 {
+		t.Errorf("sent %d emails, want 1", len(notifier.sent))
+	}
+
+	// A failed send is recorded
+	notifier.err = errors.New("smtp down")
+	sweepAt(home.Add(-10 * time.Minute))
+	upcoming, err := store.ListUpcoming(ctx, user.ID, office)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upcoming) != 2 || upcoming[0].Status != models.CommuteReminderSent || upcoming[1].Status != models.CommuteReminderFailed {
+		t.Errorf("reminders = %+v", upcoming)
+	}
+}
+
+func TestSchedulerCancelsMissedReminders(t *testing.T) {
+	ctx := context.Background()
+	users := repository.NewMemoryUserRepository()
+	store := repository.NewMemoryCommuteReminderRepository()
+	depart := time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC)
+	err := store.Replace(ctx, "user-1", "2026-03-02", []*models.CommuteReminder{
+		{RecommendationID: "rec-1", Summary: "Leave for the office", DepartAt: depart, RemindAt: depart.Add(-15 * time.Minute), Channels: []models.ReminderChannel{models.ReminderChannelEmail}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifier := &recordingNotifier{}
+	scheduler := NewScheduler(store, users, notifier, nil, Config{})
+	// The backend was down until after the departure
+	scheduler.now = func() time.Time { return depart.Add(time.Minute) }
+	scheduler.sweep(ctx)
+	if len(notifier.sent) != 0 {
+		t.Errorf("sent a missed reminder: %+v", notifier.sent)
+	}
+	upcoming, _ := store.ListUpcoming(ctx, "user-1", depart.Add(-time.Hour))
+	if len(upcoming) != 1 || upcoming[0].Status != models.CommuteReminderCancelled {
+		t.Errorf("reminders = %+v, want the missed one cancelled", upcoming)
+	}
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+// analyticsEventColumns is the column list scanned by scanAnalyticsEvent
+var analyticsEventColumns = []string{"id", "event", "anonymous_id", "properties", "occurred_at", "client_name", "client_version", "forwarded_at", "created_at"}
+
+// SQLAnalyticsEventRepository stores anonymized product analytics events
+type SQLAnalyticsEventRepository struct {
+	db *database.DB
+}
+
+// NewSQLAnalyticsEventRepository creates an analytics event repository
+func NewSQLAnalyticsEventRepository(db *database.DB) *SQLAnalyticsEventRepository {
+	return &SQLAnalyticsEventRepository{db: db}
+}
+
+// Create stores events in one transaction, setting their IDs
+func (r *SQLAnalyticsEventRepository) Create(ctx context.Context, events []*models.AnalyticsEvent) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	return r.db.InTx(ctx, func(ctx context.Context) error {
+		query := `INSERT INTO analytics_events (id, event, anonymous_id, properties, occurred_at, client_name, client_version)
+		          VALUES ($1, $2, $3, $4, $5, $6, $7)
+		          RETURNING created_at`
+		for _, event := range events {
+			if event.ID == "" {
+				event.ID = uuid.New().String()
+			}
+			if event.Properties == "" {
+				event.Properties = "{}"
+			}
+			err := r.db.QueryRowContext(ctx, query,
+				event.ID,
+				event.Event,
+				event.AnonymousID,
+				event.Properties,
+				event.OccurredAt.UTC(),
+				event.ClientName,
+				event.ClientVersion,
+			).Scan(&event.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListUnforwarded returns up to limit events not yet forwarded, oldest first
+func (r *SQLAnalyticsEventRepository) ListUnforwarded(ctx context.Context, limit int) ([]*models.AnalyticsEvent, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(analyticsEventColumns, ", ") + ` FROM analytics_events
+	          WHERE forwarded_at IS NULL ORDER BY occurred_at ASC, id ASC LIMIT $1`
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*models.AnalyticsEvent{}
+	for rows.Next() {
+		event, err := scanAnalyticsEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// MarkForwarded records that the events with ids were forwarded at at
+func (r *SQLAnalyticsEventRepository) MarkForwarded(ctx context.Context, ids []string, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	args := []interface{}{at.UTC()}
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		args = append(args, id)
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+	}
+	_, err := r.db.ExecContext(ctx, `UPDATE analytics_events SET forwarded_at = $1
+	          WHERE id IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+	return err
+}
+
+// scanAnalyticsEvent scans a row selected with analyticsEventColumns
+func scanAnalyticsEvent(row rowScanner) (*models.AnalyticsEvent, error) {
+	event := &models.AnalyticsEvent{}
+	err := row.Scan(
+		&event.ID,
+		&event.Event,
+		&event.AnonymousID,
+		&event.Properties,
+		&event.OccurredAt,
+		&event.ClientName,
+		&event.ClientVersion,
+		&event.ForwardedAt,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSQLAnalyticsEvents(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	events := NewSQLAnalyticsEventRepository(db)
+
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	client := "web"
+	err := events.Create(ctx, []*models.AnalyticsEvent{
+		{Event: "plan_accepted", AnonymousID: "b1", Properties: `{"rank":2}`, OccurredAt: base.Add(time.Minute), ClientName: &client},
+		{Event: "plan_requested", AnonymousID: "b1", OccurredAt: base},
+		{Event: "demo_generated", AnonymousID: "c2", OccurredAt: base.Add(2 * time.Minute)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unforwarded, err := events.ListUnforwarded(ctx, 2)
+	if err != nil || len(unforwarded) != 2 {
+		t.Fatalf("unforwarded = %+v, %v", unforwarded, err)
+	}
+	if first := unforwarded[0]; first.Event != "plan_requested" || first.Properties != "{}" || !first.OccurredAt.Equal(base) {
+		t.Errorf("first = %+v, want the oldest event with empty properties", first)
+	}
+	if second := unforwarded[1]; second.Properties != `{"rank":2}` || second.ClientName == nil || *second.ClientName != "web" {
+		t.Errorf("second = %+v", second)
+	}
+
+	if err := events.MarkForwarded(ctx, []string{unforwarded[0].ID, unforwarded[1].ID}, base.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if unforwarded, err := events.ListUnforwarded(ctx, 10); err != nil || len(unforwarded) != 1 || unforwarded[0].Event != "demo_generated" {
+		t.Errorf("unforwarded = %+v, %v, want only the demo event", unforwarded, err)
+	}
+
+	user := createUser(t, ctx, db, "ada@example.com")
+	updated, err := NewSQLUserRepository(db).SetAnalyticsOptOut(ctx, user.ID, true)
+	if err != nil || !updated.AnalyticsOptOut {
+		t.Errorf("user = %+v, %v, want them opted out", updated, err)
+	}
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// apiTokenColumns is the column list scanned by scanAPIToken
+var apiTokenColumns = []string{"id", "user_id", "name", "token_prefix", "scopes", "expires_at", "last_used_at", "created_at"}
+
+// SQLAPITokenRepository stores the hashed personal access tokens of users
+type SQLAPITokenRepository struct {
+	db *database.DB
+}
+
+// NewSQLAPITokenRepository creates an API token repository
+func NewSQLAPITokenRepository(db *database.DB) *SQLAPITokenRepository {
+	return &SQLAPITokenRepository{db: db}
+}
+
+// Create stores a token with tokenHash, setting its ID and creation time
+func (r *SQLAPITokenRepository) Create(ctx context.Context, token *models.APIToken, tokenHash string) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if token.ID == "" {
+		token.ID = uuid.New().String()
+	}
+	scopes := make(pq.StringArray, len(token.Scopes))
+	for i, scope := range token.Scopes {
+		scopes[i] = string(scope)
+	}
+	var expiresAt interface{}
+	if token.ExpiresAt != nil {
+		expiresAt = token.ExpiresAt.UTC()
+	}
+	query := `INSERT INTO api_tokens (id, user_id, name, token_prefix, token_hash, scopes, expires_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)
+	          RETURNING created_at`
+	return r.db.QueryRowContext(ctx, query, token.ID, token.UserID, token.Name, token.Prefix, tokenHash, scopes, expiresAt).Scan(&token.CreatedAt)
+}
+
+// ListByUser returns the user's tokens, newest first
+func (r *SQLAPITokenRepository) ListByUser(ctx context.Context, userID string) ([]*models.APIToken, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(apiTokenColumns, ", ") + ` FROM api_tokens
+	          WHERE user_id = $1 ORDER BY created_at DESC, id ASC`
+	rows, err := r.db.Reader().QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetByHash returns the token with tokenHash, or ErrNotFound
+func (r *SQLAPITokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(apiTokenColumns, ", ") + ` FROM api_tokens WHERE token_hash = $1`
+	token, err := scanAPIToken(r.db.QueryRowContext(ctx, query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return token, err
+}
+
+// Touch records that a token was used at at
+func (r *SQLAPITokenRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = $1 WHERE id = $2`, at.UTC(), id)
+	return err
+}
+
+// Delete revokes one of the user's tokens, reporting whether the user has it
+func (r *SQLAPITokenRepository) Delete(ctx context.Context, userID, id string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// scanAPIToken scans a row selected with apiTokenColumns
+func scanAPIToken(row rowScanner) (*models.APIToken, error) {
+	token := &models.APIToken{}
+	var scopes pq.StringArray
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.Prefix,
+		&scopes,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = make([]models.APITokenScope, len(scopes))
+	for i, scope := range scopes {
+		token.Scopes[i] = models.APITokenScope(scope)
+	}
+	return token, nil
+}
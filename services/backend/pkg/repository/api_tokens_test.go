@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSQLAPITokens(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	ada := createUser(t, ctx, db, "ada@example.com")
+	bob := createUser(t, ctx, db, "bob@example.com")
+	tokens := NewSQLAPITokenRepository(db)
+
+	expiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	token := &models.APIToken{UserID: ada.ID, Name: "extension", Prefix: "cpat_abcdef", Scopes: []models.APITokenScope{models.ScopeReadCalendar, models.ScopeWriteJobs}, ExpiresAt: &expiresAt}
+	if err := tokens.Create(ctx, token, "hash-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tokens.GetByHash(ctx, "hash-1")
+	if err != nil || got.ID != token.ID || len(got.Scopes) != 2 || !got.HasScope(models.ScopeWriteJobs) || got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) || got.LastUsedAt != nil {
+		t.Fatalf("token = %+v, %v", got, err)
+	}
+	if _, err := tokens.GetByHash(ctx, "hash-2"); err != ErrNotFound {
+		t.Errorf("unknown hash: err = %v, want ErrNotFound", err)
+	}
+
+	usedAt := time.Now().Truncate(time.Second)
+	if err := tokens.Touch(ctx, token.ID, usedAt); err != nil {
+		t.Fatal(err)
+	}
+	list, err := tokens.ListByUser(ctx, ada.ID)
+	if err != nil || len(list) != 1 || list[0].LastUsedAt == nil || !list[0].LastUsedAt.Equal(usedAt) {
+		t.Fatalf("tokens = %+v, %v", list, err)
+	}
+
+	if deleted, err := tokens.Delete(ctx, bob.ID, token.ID); err != nil || deleted {
+		t.Errorf("bob deleted ada's token: %v, %v", deleted, err)
+	}
+	if deleted, err := tokens.Delete(ctx, ada.ID, token.ID); err != nil || !deleted {
+		t.Fatalf("delete = %v, %v", deleted, err)
+	}
+	if _, err := tokens.GetByHash(ctx, "hash-1"); err != ErrNotFound {
+		t.Errorf("deleted token: err = %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+// artifactColumns is the column list scanned by scanArtifact
+var artifactColumns = []string{"id", "job_id", "kind", "storage_key", "content_type", "size_bytes", "created_at"}
+
+// SQLArtifactRepository stores references to job artifacts kept in object storage
+type SQLArtifactRepository struct {
+	db *database.DB
+}
+
+// NewSQLArtifactRepository creates an artifact repository
+func NewSQLArtifactRepository(db *database.DB) *SQLArtifactRepository {
+	return &SQLArtifactRepository{db: db}
+}
+
+func (r *SQLArtifactRepository) Create(ctx context.Context, artifact *models.JobArtifact) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if artifact.ID == "" {
+		artifact.ID = uuid.New().String()
+	}
+	query := `INSERT INTO job_artifacts (id, job_id, kind, storage_key, content_type, size_bytes)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          RETURNING created_at`
+	return r.db.QueryRowContext(ctx, query, artifact.ID, artifact.JobID, artifact.Kind, artifact.StorageKey,
+		artifact.ContentType, artifact.SizeBytes).Scan(&artifact.CreatedAt)
+}
+
+// ListByJob returns a job's artifacts, oldest first
+func (r *SQLArtifactRepository) ListByJob(ctx context.Context, jobID string) ([]*models.JobArtifact, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(artifactColumns, ", ") + ` FROM job_artifacts
+	          WHERE job_id = $1 ORDER BY created_at ASC, id ASC`
+	rows, err := r.db.Reader().QueryContext(ctx, query, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var artifacts []*models.JobArtifact
+	for rows.Next() {
+		artifact, err := scanArtifact(rows)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, rows.Err()
+}
+
+func scanArtifact(row rowScanner) (*models.JobArtifact, error) {
+	artifact := &models.JobArtifact{}
+	err := row.Scan(
+		&artifact.ID,
+		&artifact.JobID,
+		&artifact.Kind,
+		&artifact.StorageKey,
+		&artifact.ContentType,
+		&artifact.SizeBytes,
+		&artifact.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return artifact, nil
+}
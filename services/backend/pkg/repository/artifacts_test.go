@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSQLArtifacts(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	user := createUser(t, ctx, db, "ada@example.com")
+	job := createJob(t, ctx, db, user.ID)
+	artifacts := NewSQLArtifactRepository(db)
+
+	size := int64(20480)
+	for _, kind := range []models.JobArtifactKind{models.JobArtifactReasoningTrace, models.JobArtifactRouteMap} {
+		artifact := &models.JobArtifact{JobID: job.ID, Kind: kind, StorageKey: "jobs/" + job.ID + "/" + string(kind), ContentType: "application/json", SizeBytes: &size}
+		if err := artifacts.Create(ctx, artifact); err != nil {
+			t.Fatal(err)
+		}
+		if artifact.ID == "" || artifact.CreatedAt.IsZero() {
+			t.Errorf("created artifact = %+v, want an ID and creation time", artifact)
+		}
+	}
+
+	listed, err := artifacts.ListByJob(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 {
+		t.Fatalf("listed %d artifacts, want 2", len(listed))
+	}
+	for _, artifact := range listed {
+		if artifact.StorageKey != "jobs/"+job.ID+"/"+string(artifact.Kind) || artifact.SizeBytes == nil || *artifact.SizeBytes != size {
+			t.Errorf("artifact = %+v", artifact)
+		}
+	}
+
+	// Deleting the job drops its artifact references
+	if _, err := NewSQLJobRepository(db).Delete(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if listed, _ := artifacts.ListByJob(ctx, job.ID); len(listed) != 0 {
+		t.Errorf("deleted job left %d artifacts", len(listed))
+	}
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UpdateBuilder builds parameterized UPDATE statements so callers never track
+// placeholder indexes by hand.
+//
+//	query, args := Update("jobs").
+//		Set("status", "COMPLETED").
+//		SetExpr("updated_at = CURRENT_TIMESTAMP").
+//		Where("id", id).
+//		Returning("id", "status").
+//		Build()
+type UpdateBuilder struct {
+	table     string
+	sets      []string
+	args      []interface{}
+	where     []string
+	returning []string
+}
+
+// Update starts an UPDATE statement for table
+func Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{table: table}
+}
+
+// Set assigns a bound value to a column
+func (b *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	b.args = append(b.args, value)
+	b.sets = append(b.sets, fmt.Sprintf("%s = $%d", column, len(b.args)))
+	return b
+}
+
+// SetExpr adds a raw assignment without a bound value, e.g. "updated_at = CURRENT_TIMESTAMP"
+func (b *UpdateBuilder) SetExpr(expr string) *UpdateBuilder {
+	b.sets = append(b.sets, expr)
+	return b
+}
+
+// Where adds an equality condition; multiple conditions are ANDed
+func (b *UpdateBuilder) Where(column string, value interface{}) *UpdateBuilder {
+	b.args = append(b.args, value)
+	b.where = append(b.where, fmt.Sprintf("%s = $%d", column, len(b.args)))
+	return b
+}
+
+// Version increments the row's version column. With an expected version the update
+// only matches the row while it still has that version (compare-and-swap).
+func (b *UpdateBuilder) Version(expected *int) *UpdateBuilder {
+	b.SetExpr("version = version + 1")
+	if expected != nil {
+		b.Where("version", *expected)
+	}
+	return b
+}
+
+// Returning sets the RETURNING column list
+func (b *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
+	b.returning = columns
+	return b
+}
+
+// HasChanges reports whether any column assignment was added
+func (b *UpdateBuilder) HasChanges() bool {
+	return len(b.sets) > 0
+}
+
+// Build returns the SQL statement and its arguments in placeholder order
+func (b *UpdateBuilder) Build() (string, []interface{}) {
+	var sb strings.Builder
+	sb.WriteString("UPDATE ")
+	sb.WriteString(b.table)
+	sb.WriteString(" SET ")
+	sb.WriteString(strings.Join(b.sets, ", "))
+	if len(b.where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(b.where, " AND "))
+	}
+	if len(b.returning) > 0 {
+		sb.WriteString(" RETURNING ")
+		sb.WriteString(strings.Join(b.returning, ", "))
+	}
+	return sb.String(), b.args
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpdateBuilder(t *testing.T) {
+	tests := []struct {
+		name      string
+		builder   *UpdateBuilder
+		wantQuery string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "set and where",
+			builder:   Update("jobs").Set("status", "COMPLETED").Where("id", "job-1"),
+			wantQuery: "UPDATE jobs SET status = $1 WHERE id = $2",
+			wantArgs:  []interface{}{"COMPLETED", "job-1"},
+		},
+		{
+			name: "expressions take no placeholder",
+			builder: Update("jobs").
+				SetExpr("updated_at = CURRENT_TIMESTAMP").
+				Set("progress", 0.5).
+				Where("id", "job-1").
+				Where("tenant_id", "acme").
+				Returning("id", "status"),
+			wantQuery: "UPDATE jobs SET updated_at = CURRENT_TIMESTAMP, progress = $1 WHERE id = $2 AND tenant_id = $3 RETURNING id, status",
+			wantArgs:  []interface{}{0.5, "job-1", "acme"},
+		},
+		{
+			name:      "compare-and-swap on the version",
+			builder:   Update("jobs").Set("progress", 0.5).Version(&[]int{3}[0]).Where("id", "job-1"),
+			wantQuery: "UPDATE jobs SET progress = $1, version = version + 1 WHERE version = $2 AND id = $3",
+			wantArgs:  []interface{}{0.5, 3, "job-1"},
+		},
+		{
+			name:      "without conditions",
+			builder:   Update("users").Set("name", "Ada").Set("email", "ada@example.com"),
+			wantQuery: "UPDATE users SET name = $1, email = $2",
+			wantArgs:  []interface{}{"Ada", "ada@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := tt.builder.Build()
+			if query != tt.wantQuery {
+				t.Errorf("query = %q, want %q", query, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestUpdateBuilderHasChanges(t *testing.T) {
+	if Update("jobs").Where("id", "job-1").HasChanges() {
+		t.Error("HasChanges() = true without assignments")
+	}
+	if !Update("jobs").SetExpr("updated_at = CURRENT_TIMESTAMP").HasChanges() {
+		t.Error("HasChanges() = false with an expression")
+	}
+}
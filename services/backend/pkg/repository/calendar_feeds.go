@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// SQLCalendarFeedRepository stores the hashed tokens of users' ICS feeds
+type SQLCalendarFeedRepository struct {
+	db *database.DB
+}
+
+// NewSQLCalendarFeedRepository creates a calendar feed repository
+func NewSQLCalendarFeedRepository(db *database.DB) *SQLCalendarFeedRepository {
+	return &SQLCalendarFeedRepository{db: db}
+}
+
+// Put gives the user a feed with tokenHash, replacing their earlier feed
+func (r *SQLCalendarFeedRepository) Put(ctx context.Context, userID, tokenHash string) (*models.CalendarFeed, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	feed := &models.CalendarFeed{UserID: userID}
+	query := `INSERT INTO calendar_feeds (user_id, token_hash) VALUES ($1, $2)
+	          ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = CURRENT_TIMESTAMP
+	          RETURNING created_at`
+	if err := r.db.QueryRowContext(ctx, query, userID, tokenHash).Scan(&feed.CreatedAt); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// UserByToken returns the user whose feed has tokenHash, or ErrNotFound
+func (r *SQLCalendarFeedRepository) UserByToken(ctx context.Context, tokenHash string) (string, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	var userID string
+	err := r.db.Reader().QueryRowContext(ctx, `SELECT user_id FROM calendar_feeds WHERE token_hash = $1`, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return userID, err
+}
+
+// Delete removes the user's feed, reporting whether they had one
+func (r *SQLCalendarFeedRepository) Delete(ctx context.Context, userID string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM calendar_feeds WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/commute-planner/backend/internal/testdb"
+)
+
+func TestSQLCalendarFeeds(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	user := createUser(t, ctx, db, "ada@example.com")
+	feeds := NewSQLCalendarFeedRepository(db)
+
+	if _, err := feeds.Put(ctx, user.ID, "hash-1"); err != nil {
+		t.Fatal(err)
+	}
+	if userID, err := feeds.UserByToken(ctx, "hash-1"); err != nil || userID != user.ID {
+		t.Errorf("user = %q, %v", userID, err)
+	}
+
+	// A new feed replaces the old one
+	feed, err := feeds.Put(ctx, user.ID, "hash-2")
+	if err != nil || feed.CreatedAt.IsZero() {
+		t.Fatalf("feed = %+v, %v", feed, err)
+	}
+	if _, err := feeds.UserByToken(ctx, "hash-1"); err != ErrNotFound {
+		t.Errorf("replaced token: err = %v, want ErrNotFound", err)
+	}
+	if userID, _ := feeds.UserByToken(ctx, "hash-2"); userID != user.ID {
+		t.Errorf("new token maps to %q", userID)
+	}
+
+	if deleted, err := feeds.Delete(ctx, user.ID); err != nil || !deleted {
+		t.Fatalf("delete = %v, %v", deleted, err)
+	}
+	if deleted, _ := feeds.Delete(ctx, user.ID); deleted {
+		t.Error("deleted a feed twice")
+	}
+	if _, err := feeds.UserByToken(ctx, "hash-2"); err != ErrNotFound {
+		t.Errorf("deleted feed: err = %v", err)
+	}
+}
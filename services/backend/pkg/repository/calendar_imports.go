@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+// calendarImportColumns is the column list scanned by scanCalendarImport
+var calendarImportColumns = []string{"id", "user_id", "filename", "format", "status", "total_events", "processed_events", "created_events", "errors", "error_message", "started_at", "finished_at"}
+
+// SQLCalendarImportRepository stores the calendar files users imported and their progress
+type SQLCalendarImportRepository struct {
+	db *database.DB
+}
+
+// NewSQLCalendarImportRepository creates a calendar import repository
+func NewSQLCalendarImportRepository(db *database.DB) *SQLCalendarImportRepository {
+	return &SQLCalendarImportRepository{db: db}
+}
+
+// Create records the start of an import, setting its ID
+func (r *SQLCalendarImportRepository) Create(ctx context.Context, imp *models.CalendarImport) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if imp.ID == "" {
+		imp.ID = uuid.New().String()
+	}
+	if imp.Errors == nil {
+		imp.Errors = []models.ImportError{}
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO calendar_imports (`+strings.Join(calendarImportColumns, ", ")+`)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		imp.ID, imp.UserID, imp.Filename, imp.Format, imp.Status, imp.TotalEvents, imp.ProcessedEvents,
+		imp.CreatedEvents, models.JSON(imp.Errors), imp.ErrorMessage, imp.StartedAt.UTC(), imp.FinishedAt)
+	return err
+}
+
+// Update saves an import's progress and, once it has finished, its outcome
+func (r *SQLCalendarImportRepository) Update(ctx context.Context, imp *models.CalendarImport) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	query, args := Update("calendar_imports").
+		Set("status", imp.Status).
+		Set("processed_events", imp.ProcessedEvents).
+		Set("created_events", imp.CreatedEvents).
+		Set("errors", models.JSON(imp.Errors)).
+		Set("error_message", imp.ErrorMessage).
+		Set("finished_at", imp.FinishedAt).
+		Where("id", imp.ID).
+		Build()
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	return ErrNotFound
+}
+
+// Get returns one of the user's imports, or ErrNotFound
+func (r *SQLCalendarImportRepository) Get(ctx context.Context, userID, id string) (*models.CalendarImport, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(calendarImportColumns, ", ") + ` FROM calendar_imports WHERE id = $1 AND user_id = $2`
+	imp, err := scanCalendarImport(r.db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return imp, err
+}
+
+// scanCalendarImport scans a row selected with calendarImportColumns
+func scanCalendarImport(row rowScanner) (*models.CalendarImport, error) {
+	imp := &models.CalendarImport{}
+	err := row.Scan(
+		&imp.ID,
+		&imp.UserID,
+		&imp.Filename,
+		&imp.Format,
+		&imp.Status,
+		&imp.TotalEvents,
+		&imp.ProcessedEvents,
+		&imp.CreatedEvents,
+		models.JSON(&imp.Errors),
+		&imp.ErrorMessage,
+		&imp.StartedAt,
+		&imp.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if imp.Errors == nil {
+		imp.Errors = []models.ImportError{}
+	}
+	return imp, nil
+}
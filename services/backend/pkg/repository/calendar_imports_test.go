@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSQLCalendarImports(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	user := createUser(t, ctx, db, "ada@example.com")
+	imports := NewSQLCalendarImportRepository(db)
+
+	imp := &models.CalendarImport{
+		UserID:      user.ID,
+		Filename:    "work.ics",
+		Format:      models.CalendarImportICS,
+		Status:      models.CalendarImportRunning,
+		TotalEvents: 3,
+		StartedAt:   time.Now(),
+	}
+	if err := imports.Create(ctx, imp); err != nil {
+		t.Fatal(err)
+	}
+
+	uid := "standup@example.com"
+	finished := time.Now()
+	imp.Status, imp.ProcessedEvents, imp.CreatedEvents, imp.FinishedAt = models.CalendarImportCompleted, 3, 2, &finished
+	imp.Errors = []models.ImportError{{Index: 1, UID: &uid, Message: "endTime is before startTime"}}
+	if err := imports.Update(ctx, imp); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := imports.Get(ctx, user.ID, imp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.CalendarImportCompleted || stored.TotalEvents != 3 || stored.CreatedEvents != 2 || stored.FinishedAt == nil {
+		t.Errorf("import = %+v", stored)
+	}
+	if len(stored.Errors) != 1 || *stored.Errors[0].UID != uid || stored.Errors[0].Index != 1 {
+		t.Errorf("errors = %+v", stored.Errors)
+	}
+
+	// Imports are only read back by their user
+	other := createUser(t, ctx, db, "grace@example.com")
+	if _, err := imports.Get(ctx, other.ID, imp.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("another user's import: err = %v", err)
+	}
+	if err := imports.Update(ctx, &models.CalendarImport{ID: "00000000-0000-0000-0000-000000000000"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating a missing import: err = %v", err)
+	}
+}
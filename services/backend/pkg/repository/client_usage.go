@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// SQLClientUsageRepository stores daily counts of the GraphQL operations each client
+// version runs
+type SQLClientUsageRepository struct {
+	db *database.DB
+}
+
+// NewSQLClientUsageRepository creates a client usage repository
+func NewSQLClientUsageRepository(db *database.DB) *SQLClientUsageRepository {
+	return &SQLClientUsageRepository{db: db}
+}
+
+// Record adds usage to the counts of the day of each one's LastUsedAt
+func (r *SQLClientUsageRepository) Record(ctx context.Context, usage []*models.ClientOperationUsage) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	return r.db.InTx(ctx, func(ctx context.Context) error {
+		query := `INSERT INTO client_operation_usage (client_name, client_version, operation, day, requests, first_used_at, last_used_at)
+		          VALUES ($1, $2, $3, $4, $5, $6, $7)
+		          ON CONFLICT (client_name, client_version, operation, day) DO UPDATE
+		          SET requests = client_operation_usage.requests + excluded.requests, last_used_at = excluded.last_used_at`
+		for _, u := range usage {
+			_, err := r.db.ExecContext(ctx, query,
+				u.ClientName,
+				u.ClientVersion,
+				u.Operation,
+				u.LastUsedAt.UTC().Format("2006-01-02"),
+				u.Requests,
+				u.FirstUsedAt.UTC(),
+				u.LastUsedAt.UTC(),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List returns the usage of each operation by each client version since the day of since,
+// summed
+func (r *SQLClientUsageRepository) List(ctx context.Context, since time.Time) ([]*models.ClientOperationUsage, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT client_name, client_version, operation, requests, first_used_at, last_used_at
+	          FROM client_operation_usage WHERE day >= $1
+	          ORDER BY client_name, client_version, operation, day`, since.UTC().Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var daily []*models.ClientOperationUsage
+	for rows.Next() {
+		u := &models.ClientOperationUsage{}
+		if err := rows.Scan(&u.ClientName, &u.ClientVersion, &u.Operation, &u.Requests, &u.FirstUsedAt, &u.LastUsedAt); err != nil {
+			return nil, err
+		}
+		daily = append(daily, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sumClientOperationUsage(daily), nil
+}
+
+// sumClientOperationUsage sums daily counts by client version and operation, keeping their
+// order
+func sumClientOperationUsage(daily []*models.ClientOperationUsage) []*models.ClientOperationUsage {
+	usage := []*models.ClientOperationUsage{}
+	byKey := map[[3]string]*models.ClientOperationUsage{}
+	for _, u := range daily {
+		key := [3]string{u.ClientName, u.ClientVersion, u.Operation}
+		sum, ok := byKey[key]
+		if !ok {
+			copied := *u
+			byKey[key] = &copied
+			usage = append(usage, &copied)
+			continue
+		}
+		sum.Requests += u.Requests
+		if u.FirstUsedAt.Before(sum.FirstUsedAt) {
+			sum.FirstUsedAt = u.FirstUsedAt
+		}
+		if u.LastUsedAt.After(sum.LastUsedAt) {
+			sum.LastUsedAt = u.LastUsedAt
+		}
+	}
+	return usage
+}
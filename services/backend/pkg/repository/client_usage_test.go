@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSQLClientUsage(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	usage := NewSQLClientUsageRepository(db)
+
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	record := func(operation string, requests int64, at time.Time) {
+		t.Helper()
+		err := usage.Record(ctx, []*models.ClientOperationUsage{{
+			ClientName:    "web",
+			ClientVersion: "2.1.0",
+			Operation:     operation,
+			Requests:      requests,
+			FirstUsedAt:   at,
+			LastUsedAt:    at,
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	record("jobs", 2, monday)
+	record("jobs", 3, monday.Add(24*time.Hour))
+	record("createJob", 1, monday.Add(time.Hour))
+
+	all, err := usage.List(ctx, monday)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("usage = %+v, %v, want one row per operation", all, err)
+	}
+	if jobs := all[1]; jobs.Operation != "jobs" || jobs.Requests != 5 || !jobs.FirstUsedAt.Equal(monday) || !jobs.LastUsedAt.Equal(monday.Add(24*time.Hour)) {
+		t.Errorf("jobs usage = %+v, want the days summed", jobs)
+	}
+
+	// Jobs keep the client they were created from
+	user := createUser(t, ctx, db, "ada@example.com")
+	name, version := "web", "2.1.0"
+	job, err := NewSQLJobRepository(db).Create(ctx, NewJob{UserID: user.ID, TargetDate: "2026-03-02", ClientName: &name, ClientVersion: &version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ClientName == nil || *job.ClientName != "web" || job.ClientVersion == nil || *job.ClientVersion != "2.1.0" {
+		t.Errorf("job client = %v %v", job.ClientName, job.ClientVersion)
+	}
+}
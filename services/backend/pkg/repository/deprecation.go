@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// SQLDeprecationUsageRepository stores daily counts of the requests selecting deprecated
+// GraphQL fields
+type SQLDeprecationUsageRepository struct {
+	db *database.DB
+}
+
+// NewSQLDeprecationUsageRepository creates a deprecated field usage repository
+func NewSQLDeprecationUsageRepository(db *database.DB) *SQLDeprecationUsageRepository {
+	return &SQLDeprecationUsageRepository{db: db}
+}
+
+// Record adds usage to the counts of the day of each one's LastUsedAt
+func (r *SQLDeprecationUsageRepository) Record(ctx context.Context, usage []*models.DeprecatedFieldUsage) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	return r.db.InTx(ctx, func(ctx context.Context) error {
+		query := `INSERT INTO deprecated_field_usage (coordinate, client_name, client_version, day, requests, first_used_at, last_used_at)
+		          VALUES ($1, $2, $3, $4, $5, $6, $7)
+		          ON CONFLICT (coordinate, client_name, client_version, day) DO UPDATE
+		          SET requests = deprecated_field_usage.requests + excluded.requests, last_used_at = excluded.last_used_at`
+		for _, u := range usage {
+			_, err := r.db.ExecContext(ctx, query,
+				u.Coordinate,
+				u.ClientName,
+				u.ClientVersion,
+				u.LastUsedAt.UTC().Format("2006-01-02"),
+				u.Requests,
+				u.FirstUsedAt.UTC(),
+				u.LastUsedAt.UTC(),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List returns the usage of each field by each client since the day of since, summed
+func (r *SQLDeprecationUsageRepository) List(ctx context.Context, since time.Time) ([]*models.DeprecatedFieldUsage, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT coordinate, client_name, client_version, requests, first_used_at, last_used_at
+	          FROM deprecated_field_usage WHERE day >= $1
+	          ORDER BY coordinate, client_name, client_version, day`, since.UTC().Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var daily []*models.DeprecatedFieldUsage
+	for rows.Next() {
+		u := &models.DeprecatedFieldUsage{}
+		if err := rows.Scan(&u.Coordinate, &u.ClientName, &u.ClientVersion, &u.Requests, &u.FirstUsedAt, &u.LastUsedAt); err != nil {
+			return nil, err
+		}
+		daily = append(daily, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sumDeprecatedFieldUsage(daily), nil
+}
+
+// sumDeprecatedFieldUsage sums daily counts by field and client, keeping their order
+func sumDeprecatedFieldUsage(daily []*models.DeprecatedFieldUsage) []*models.DeprecatedFieldUsage {
+	usage := []*models.DeprecatedFieldUsage{}
+	byKey := map[[3]string]*models.DeprecatedFieldUsage{}
+	for _, u := range daily {
+		key := [3]string{u.Coordinate, u.ClientName, u.ClientVersion}
+		sum, ok := byKey[key]
+		if !ok {
+			copied := *u
+			byKey[key] = &copied
+			usage = append(usage, &copied)
+			continue
+		}
+		sum.Requests += u.Requests
+		if u.FirstUsedAt.Before(sum.FirstUsedAt) {
+			sum.FirstUsedAt = u.FirstUsedAt
+		}
+		if u.LastUsedAt.After(sum.LastUsedAt) {
+			sum.LastUsedAt = u.LastUsedAt
+		}
+	}
+	return usage
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSQLDeprecationUsage(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	usage := NewSQLDeprecationUsageRepository(db)
+
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	tuesday := monday.Add(24 * time.Hour)
+	record := func(clientVersion string, requests int64, at time.Time) {
+		t.Helper()
+		err := usage.Record(ctx, []*models.DeprecatedFieldUsage{{
+			Coordinate:    "CommuteRecommendation.travelLegs",
+			ClientName:    "ios",
+			ClientVersion: clientVersion,
+			Requests:      requests,
+			FirstUsedAt:   at,
+			LastUsedAt:    at.Add(time.Minute),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	record("1.0", 2, monday)
+	record("1.0", 3, monday.Add(time.Hour))
+	record("1.0", 1, tuesday)
+	record("2.0", 4, tuesday)
+
+	all, err := usage.List(ctx, monday)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("usage = %+v, %v, want one row per client version", all, err)
+	}
+	old := all[0]
+	if old.ClientVersion != "1.0" || old.Requests != 6 || !old.FirstUsedAt.Equal(monday) || !old.LastUsedAt.Equal(tuesday.Add(time.Minute)) {
+		t.Errorf("1.0 usage = %+v, want the days summed", old)
+	}
+
+	recent, err := usage.List(ctx, tuesday)
+	if err != nil || len(recent) != 2 || recent[0].Requests != 1 || recent[1].Requests != 4 {
+		t.Errorf("usage since tuesday = %+v, %v", recent, err)
+	}
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/database"
+)
+
+// reencryptBatchSize is how many rows Reencrypt reads at a time
+const reencryptBatchSize = 500
+
+// Reencrypt rewrites the values of the encrypted columns that aren't encrypted with the
+// current key: those written before encryption was enabled, and those encrypted with a key
+// that is being rotated out. It returns how many values it rewrote. It works in batches,
+// and a value changed while it runs is left to the writer, so it can run while the API
+// serves requests. Once it finishes, earlier keys can be removed.
+func Reencrypt(ctx context.Context, db *database.DB) (int, error) {
+	keys := db.Encryption()
+	if keys == nil {
+		return 0, fmt.Errorf("no encryption keys are configured")
+	}
+
+	rewritten := 0
+	for _, column := range []string{database.ColumnOAuthTokens, database.ColumnEventDescription} {
+		if !db.Encrypts(column) {
+			continue
+		}
+		table, name, _ := strings.Cut(column, ".")
+		after := ""
+		for {
+			query := `SELECT id, ` + name + ` FROM ` + table + ` WHERE ` + name + ` IS NOT NULL`
+			var args []interface{}
+			if after != "" {
+				args = append(args, after)
+				query += ` AND id > $1`
+			}
+			query += fmt.Sprintf(` ORDER BY id LIMIT %d`, reencryptBatchSize)
+
+			type row struct{ id, value string }
+			var batch []row
+			rows, err := db.QueryContext(ctx, query, args...)
+			if err != nil {
+				return rewritten, err
+			}
+			for rows.Next() {
+				var r row
+				if err := rows.Scan(&r.id, &r.value); err != nil {
+					rows.Close()
+					return rewritten, err
+				}
+				batch = append(batch, r)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return rewritten, err
+			}
+
+			for _, r := range batch {
+				if !keys.NeedsRotation(r.value) {
+					continue
+				}
+				plaintext, err := db.DecryptColumn(column, &r.value)
+				if err != nil {
+					return rewritten, fmt.Errorf("%s of %s: %w", column, r.id, err)
+				}
+				encrypted, err := db.EncryptColumn(column, plaintext)
+				if err != nil {
+					return rewritten, err
+				}
+				result, err := db.ExecContext(ctx, `UPDATE `+table+` SET `+name+` = $1 WHERE id = $2 AND `+name+` = $3`, *encrypted, r.id, r.value)
+				if err != nil {
+					return rewritten, err
+				}
+				if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+					rewritten++
+				}
+			}
+			if len(batch) < reencryptBatchSize {
+				break
+			}
+			after = batch[len(batch)-1].id
+		}
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/encryption"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+func TestSQLEncryptedColumns(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	key := func(b byte) string {
+		return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+	}
+	old, err := encryption.ParseKeys("old:" + key('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetEncryption(old, database.ColumnOAuthTokens, database.ColumnEventDescription)
+
+	ada := createUser(t, ctx, db, "ada@example.com")
+	// Tokens stored before encryption was enabled
+	tokens := `{"access_token":"ya29","refresh_token":"1//0g"}`
+	if _, err := db.ExecContext(ctx, `UPDATE users SET oauth_tokens = $1 WHERE id = $2`, tokens, ada.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	events := NewSQLEventRepository(db)
+	description := "Dial-in code 1234"
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	event := &models.CalendarEvent{
+		ID:             uuid.New().String(),
+		UserID:         ada.ID,
+		Summary:        "Board review",
+		Description:    &description,
+		StartTime:      start,
+		EndTime:        start.Add(time.Hour),
+		MeetingType:    models.MeetingTypeTeamWorkshop,
+		AttendanceMode: models.AttendanceMustBeInOffice,
+		CreatedAt:      start,
+		UpdatedAt:      start,
+	}
+	if err := events.Create(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	raw := func(query, id string) string {
+		t.Helper()
+		var value string
+		if err := db.QueryRowContext(ctx, query, id).Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+	if stored := raw(`SELECT description FROM calendar_events WHERE id = $1`, event.ID); !strings.HasPrefix(stored, "enc:v1:old:") {
+		t.Errorf("stored description = %q, want it encrypted", stored)
+	}
+	if found, err := events.Get(ctx, ada.ID, event.ID); err != nil || found.Description == nil || *found.Description != description {
+		t.Errorf("description = %v, %v; want it decrypted", found.Description, err)
+	}
+	found, err := events.Search(ctx, ada.ID, EventSearch{Query: "enc"})
+	if err != nil || len(found) != 0 {
+		t.Errorf("search for ciphertext = %d events, %v; want none", len(found), err)
+	}
+
+	// Rotation re-encrypts both the plaintext tokens and the description with the new key
+	rotated, err := encryption.ParseKeys("new:" + key('b') + ",old:" + key('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetEncryption(rotated, database.ColumnOAuthTokens, database.ColumnEventDescription)
+	if rewritten, err := Reencrypt(ctx, db); err != nil || rewritten != 2 {
+		t.Fatalf("re-encrypted %d values, %v; want 2", rewritten, err)
+	}
+	if stored := raw(`SELECT oauth_tokens FROM users WHERE id = $1`, ada.ID); !strings.HasPrefix(stored, "enc:v1:new:") {
+		t.Errorf("stored tokens = %q, want them encrypted with the new key", stored)
+	}
+	if stored, err := NewSQLUserRepository(db).OAuthTokens(ctx, ada.ID); err != nil || *stored != tokens {
+		t.Errorf("tokens = %v, %v; want them decrypted", stored, err)
+	}
+	if rewritten, err := Reencrypt(ctx, db); err != nil || rewritten != 0 {
+		t.Errorf("second run re-encrypted %d values, %v; want none", rewritten, err)
+	}
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// createUser adds a user to the tenant ctx is scoped to
+func createUser(t *testing.T, ctx context.Context, db *database.DB, email string) *models.User {
+	t.Helper()
+	user, err := NewSQLUserRepository(db).Create(ctx, NewUser{Email: email, Name: email})
+	if err != nil {
+		t.Fatalf("creating user %s: %v", email, err)
+	}
+	return user
+}
+
+// createJob adds a PENDING job for the user
+func createJob(t *testing.T, ctx context.Context, db *database.DB, userID string) *models.Job {
+	t.Helper()
+	job, err := NewSQLJobRepository(db).Create(ctx, NewJob{UserID: userID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatalf("creating job: %v", err)
+	}
+	return job
+}
+
+// setStatus moves a job through the state machine
+func setStatus(t *testing.T, ctx context.Context, jobs *SQLJobRepository, id string, status models.JobStatus) {
+	t.Helper()
+	value := string(status)
+	if _, err := jobs.Update(ctx, id, JobUpdate{Status: &value}); err != nil {
+		t.Fatalf("setting job %s to %s: %v", id, status, err)
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// SQLGeocodeCacheRepository stores geocoding results. The cache is shared by every tenant:
+// an address resolves to the same place whoever looks it up.
+type SQLGeocodeCacheRepository struct {
+	db *database.DB
+}
+
+// NewSQLGeocodeCacheRepository creates a geocode cache repository
+func NewSQLGeocodeCacheRepository(db *database.DB) *SQLGeocodeCacheRepository {
+	return &SQLGeocodeCacheRepository{db: db}
+}
+
+// Get returns the cached result for a normalised address, or ErrNotFound
+func (r *SQLGeocodeCacheRepository) Get(ctx context.Context, addressKey string) (*models.GeocodeCacheEntry, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	entry := &models.GeocodeCacheEntry{}
+	var latitude, longitude sql.NullFloat64
+	err := r.db.Reader().QueryRowContext(ctx, `SELECT address_key, found, latitude, longitude, formatted_address, provider, created_at
+	          FROM geocode_cache WHERE address_key = $1`, addressKey).Scan(
+		&entry.AddressKey,
+		&entry.Found,
+		&latitude,
+		&longitude,
+		&entry.FormattedAddress,
+		&entry.Provider,
+		&entry.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry.Latitude = latitude.Float64
+	entry.Longitude = longitude.Float64
+	return entry, nil
+}
+
+// Put stores a result, replacing any earlier one for the address
+func (r *SQLGeocodeCacheRepository) Put(ctx context.Context, entry *models.GeocodeCacheEntry) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	var latitude, longitude *float64
+	if entry.Found {
+		latitude, longitude = &entry.Latitude, &entry.Longitude
+	}
+	query := `INSERT INTO geocode_cache (address_key, found, latitude, longitude, formatted_address, provider)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          ON CONFLICT (address_key) DO UPDATE SET found = excluded.found, latitude = excluded.latitude,
+	              longitude = excluded.longitude, formatted_address = excluded.formatted_address,
+	              provider = excluded.provider, created_at = CURRENT_TIMESTAMP
+	          RETURNING created_at`
+	return r.db.QueryRowContext(ctx, query, entry.AddressKey, entry.Found, latitude, longitude,
+		entry.FormattedAddress, entry.Provider).Scan(&entry.CreatedAt)
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// googleCalendarChannelColumns is the column list scanned by scanGoogleCalendarChannel
+var googleCalendarChannelColumns = []string{"id", "user_id", "calendar_id", "resource_id", "token", "expiration", "sync_token", "last_synced_at", "created_at", "updated_at"}
+
+// SQLGoogleCalendarRepository reads and writes Google Calendar watch channels
+type SQLGoogleCalendarRepository struct {
+	db *database.DB
+}
+
+// NewSQLGoogleCalendarRepository creates a Google Calendar channel repository
+func NewSQLGoogleCalendarRepository(db *database.DB) *SQLGoogleCalendarRepository {
+	return &SQLGoogleCalendarRepository{db: db}
+}
+
+// CreateChannel inserts a channel; ErrNotFound if its user is in another tenant
+func (r *SQLGoogleCalendarRepository) CreateChannel(ctx context.Context, channel *models.GoogleCalendarChannel) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if err := checkUserTenant(ctx, r.db, channel.UserID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO google_calendar_channels (` + strings.Join(googleCalendarChannelColumns, ", ") + `)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := r.db.ExecContext(ctx, query,
+		channel.ID,
+		channel.UserID,
+		channel.CalendarID,
+		channel.ResourceID,
+		channel.Token,
+		channel.Expiration,
+		channel.SyncToken,
+		channel.LastSyncedAt,
+		channel.CreatedAt,
+		channel.UpdatedAt,
+	)
+	return err
+}
+
+// GetChannel returns a channel by ID, or ErrNotFound
+func (r *SQLGoogleCalendarRepository) GetChannel(ctx context.Context, id string) (*models.GoogleCalendarChannel, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	query := `SELECT ` + strings.Join(googleCalendarChannelColumns, ", ") + ` FROM google_calendar_channels WHERE id = $1` + scope
+	channel, err := scanGoogleCalendarChannel(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return channel, err
+}
+
+// ListChannelsByUser returns a user's channels
+func (r *SQLGoogleCalendarRepository) ListChannelsByUser(ctx context.Context, userID string) ([]*models.GoogleCalendarChannel, error) {
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID})
+	return r.list(ctx, `WHERE user_id = $1`+scope+` ORDER BY created_at ASC`, args...)
+}
+
+// ChannelsExpiringBefore returns channels that need renewing
+func (r *SQLGoogleCalendarRepository) ChannelsExpiringBefore(ctx context.Context, t time.Time) ([]*models.GoogleCalendarChannel, error) {
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{t})
+	return r.list(ctx, `WHERE expiration < $1`+scope+` ORDER BY expiration ASC`, args...)
+}
+
+// DeleteChannel removes a channel
+func (r *SQLGoogleCalendarRepository) DeleteChannel(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	result, err := r.db.ExecContext(ctx, `DELETE FROM google_calendar_channels WHERE id = $1`+scope, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// UpdateSyncState stores the next sync token for a user's calendar
+func (r *SQLGoogleCalendarRepository) UpdateSyncState(ctx context.Context, userID, calendarID string, syncToken *string, syncedAt time.Time) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{syncToken, syncedAt, userID, calendarID})
+	_, err := r.db.ExecContext(ctx, `UPDATE google_calendar_channels
+	          SET sync_token = $1, last_synced_at = $2, updated_at = CURRENT_TIMESTAMP
+	          WHERE user_id = $3 AND calendar_id = $4`+scope, args...)
+	return err
+}
+
+func (r *SQLGoogleCalendarRepository) list(ctx context.Context, where string, args ...interface{}) ([]*models.GoogleCalendarChannel, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(googleCalendarChannelColumns, ", ") + ` FROM google_calendar_channels ` + where
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []*models.GoogleCalendarChannel
+	for rows.Next() {
+		channel, err := scanGoogleCalendarChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, rows.Err()
+}
+
+// scanGoogleCalendarChannel scans a row selected with googleCalendarChannelColumns
+func scanGoogleCalendarChannel(row rowScanner) (*models.GoogleCalendarChannel, error) {
+	channel := &models.GoogleCalendarChannel{}
+	err := row.Scan(
+		&channel.ID,
+		&channel.UserID,
+		&channel.CalendarID,
+		&channel.ResourceID,
+		&channel.Token,
+		&channel.Expiration,
+		&channel.SyncToken,
+		&channel.LastSyncedAt,
+		&channel.CreatedAt,
+		&channel.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// jobOutboxColumns is the column list scanned by scanJobOutboxEntry
+var jobOutboxColumns = []string{"job_id", "user_id", "target_date", "input_data", "priority", "attempts", "last_error", "next_attempt_at", "created_at"}
+
+// SQLJobOutboxRepository stores jobs waiting to be pushed to the worker queue
+type SQLJobOutboxRepository struct {
+	db *database.DB
+}
+
+// NewSQLJobOutboxRepository creates a job outbox repository
+func NewSQLJobOutboxRepository(db *database.DB) *SQLJobOutboxRepository {
+	return &SQLJobOutboxRepository{db: db}
+}
+
+// Add buffers a job, or records the latest error if it is already buffered
+func (r *SQLJobOutboxRepository) Add(ctx context.Context, entry *models.JobOutboxEntry) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO job_queue_outbox (` + strings.Join(jobOutboxColumns, ", ") + `)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	          ON CONFLICT (job_id) DO UPDATE SET last_error = excluded.last_error`
+	_, err := r.db.ExecContext(ctx, query,
+		entry.JobID,
+		entry.UserID,
+		entry.TargetDate,
+		entry.InputData,
+		entry.Priority,
+		entry.Attempts,
+		entry.LastError,
+		entry.NextAttemptAt.UTC(),
+		entry.CreatedAt.UTC(),
+	)
+	return err
+}
+
+// ClaimDue leases up to limit due entries, oldest first
+func (r *SQLJobOutboxRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.JobOutboxEntry, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE job_queue_outbox SET next_attempt_at = $1
+	          WHERE job_id IN (
+	              SELECT job_id FROM job_queue_outbox
+	              WHERE next_attempt_at <= $2
+	              ORDER BY next_attempt_at ASC LIMIT $3
+	          ) AND next_attempt_at <= $2
+	          RETURNING ` + strings.Join(jobOutboxColumns, ", ")
+	rows, err := r.db.QueryContext(ctx, query, now.Add(lease).UTC(), now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.JobOutboxEntry
+	for rows.Next() {
+		entry, err := scanJobOutboxEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// Delete removes a delivered entry
+func (r *SQLJobOutboxRepository) Delete(ctx context.Context, jobID string) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM job_queue_outbox WHERE job_id = $1`, jobID)
+	return err
+}
+
+// RecordFailure counts a failed push and schedules the next attempt
+func (r *SQLJobOutboxRepository) RecordFailure(ctx context.Context, jobID, message string, nextAttempt time.Time) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE job_queue_outbox
+	          SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2
+	          WHERE job_id = $3`,
+		message, nextAttempt.UTC(), jobID)
+	return err
+}
+
+// Count returns the number of buffered jobs
+func (r *SQLJobOutboxRepository) Count(ctx context.Context) (int, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM job_queue_outbox`).Scan(&count)
+	return count, err
+}
+
+// scanJobOutboxEntry scans a row selected with jobOutboxColumns
+func scanJobOutboxEntry(row rowScanner) (*models.JobOutboxEntry, error) {
+	entry := &models.JobOutboxEntry{}
+	err := row.Scan(
+		&entry.JobID,
+		&entry.UserID,
+		&entry.TargetDate,
+		&entry.InputData,
+		&entry.Priority,
+		&entry.Attempts,
+		&entry.LastError,
+		&entry.NextAttemptAt,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
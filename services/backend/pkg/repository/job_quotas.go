@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// jobQuotaColumns is the column list scanned by scanJobQuota
+var jobQuotaColumns = []string{"user_id", "max_queued_jobs", "max_jobs_per_day", "exempt", "reason", "updated_at"}
+
+// SQLJobQuotaRepository stores per-user job quota overrides
+type SQLJobQuotaRepository struct {
+	db *database.DB
+}
+
+// NewSQLJobQuotaRepository creates a job quota repository
+func NewSQLJobQuotaRepository(db *database.DB) *SQLJobQuotaRepository {
+	return &SQLJobQuotaRepository{db: db}
+}
+
+// Get returns a user's override, or ErrNotFound if the defaults apply
+func (r *SQLJobQuotaRepository) Get(ctx context.Context, userID string) (*models.JobQuota, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID})
+	query := `SELECT ` + strings.Join(jobQuotaColumns, ", ") + ` FROM job_quota_overrides WHERE user_id = $1` + scope
+	quota, err := scanJobQuota(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return quota, err
+}
+
+// Put creates or replaces a user's override; ErrNotFound if the user is in another tenant
+func (r *SQLJobQuotaRepository) Put(ctx context.Context, quota *models.JobQuota) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if err := checkUserTenant(ctx, r.db, quota.UserID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO job_quota_overrides (` + strings.Join(jobQuotaColumns, ", ") + `)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          ON CONFLICT (user_id) DO UPDATE SET
+	              max_queued_jobs = excluded.max_queued_jobs,
+	              max_jobs_per_day = excluded.max_jobs_per_day,
+	              exempt = excluded.exempt,
+	              reason = excluded.reason,
+	              updated_at = excluded.updated_at`
+	_, err := r.db.ExecContext(ctx, query,
+		quota.UserID,
+		quota.MaxQueuedJobs,
+		quota.MaxJobsPerDay,
+		quota.Exempt,
+		quota.Reason,
+		quota.UpdatedAt.UTC(),
+	)
+	return err
+}
+
+// Delete removes a user's override, restoring the defaults
+func (r *SQLJobQuotaRepository) Delete(ctx context.Context, userID string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID})
+	result, err := r.db.ExecContext(ctx, `DELETE FROM job_quota_overrides WHERE user_id = $1`+scope, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// scanJobQuota scans a row selected with jobQuotaColumns
+func scanJobQuota(row rowScanner) (*models.JobQuota, error) {
+	quota := &models.JobQuota{}
+	err := row.Scan(
+		&quota.UserID,
+		&quota.MaxQueuedJobs,
+		&quota.MaxJobsPerDay,
+		&quota.Exempt,
+		&quota.Reason,
+		&quota.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return quota, nil
+}
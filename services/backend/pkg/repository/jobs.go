@@ -0,0 +1,559 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+// jobColumns is the column list scanned by scanJob
+var jobColumns = []string{"id", "user_id", "status", "priority", "progress", "current_step", "target_date", "input_data", "result", "error_message", "scheduled_at", "is_demo", "experiment", "variant", "follow_up_of", "replay_of", "client_name", "client_version", "version", "created_at", "updated_at"}
+
+// jobEventColumns is the column list scanned by scanJobEvent
+var jobEventColumns = []string{"job_id", "sequence", "from_status", "to_status", "current_step", "error_message", "created_at"}
+
+// NewJob holds the fields for creating a job
+type NewJob struct {
+	UserID     string
+	TargetDate string
+	InputData  *string            // raw JSON
+	Priority   models.JobPriority // empty means interactive
+	// ScheduledAt defers enqueueing; nil enqueues right away
+	ScheduledAt *time.Time
+	// IsDemo marks a job seeded by demo data generation; it is never enqueued
+	IsDemo bool
+	// Experiment and Variant tag the job with the planner experiment variant it runs
+	Experiment *string
+	Variant    *string
+	// FollowUpOf links a job re-planning the rest of a day to the job it follows up
+	FollowUpOf *string
+	// ReplayOf links a job replaying the inputs of another to that job
+	ReplayOf *string
+	// ClientName and ClientVersion record the client application the job was created from
+	ClientName    *string
+	ClientVersion *string
+}
+
+// JobUpdate is a partial update; nil fields are left unchanged
+type JobUpdate struct {
+	Status       *string
+	Progress     *float64
+	CurrentStep  *string
+	Result       *string
+	ErrorMessage *string
+	// ExpectedVersion, if set, is the version the caller read; the update fails with
+	// ErrConflict if the job has been updated since
+	ExpectedVersion *int
+}
+
+// SQLJobRepository reads and writes jobs in Postgres
+type SQLJobRepository struct {
+	db *database.DB
+}
+
+// NewSQLJobRepository creates a job repository
+func NewSQLJobRepository(db *database.DB) *SQLJobRepository {
+	return &SQLJobRepository{db: db}
+}
+
+// Get returns a job by ID, or ErrNotFound
+func (r *SQLJobRepository) Get(ctx context.Context, id string) (*models.Job, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	query := `SELECT ` + strings.Join(jobColumns, ", ") + ` FROM jobs WHERE id = $1` + scope
+	job, err := scanJob(r.db.Reader().QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return job, err
+}
+
+// List returns jobs newest first, optionally filtered to one user
+func (r *SQLJobRepository) List(ctx context.Context, userID *string) ([]*models.Job, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", nil)
+	query := `SELECT ` + strings.Join(jobColumns, ", ") + ` FROM jobs WHERE TRUE` + scope
+	if userID != nil {
+		args = append(args, *userID)
+		query += fmt.Sprintf(` AND user_id = $%d`, len(args))
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*models.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// CountActive counts a user's PENDING and IN_PROGRESS jobs. It reads the primary so a
+// burst of creates sees its own jobs.
+func (r *SQLJobRepository) CountActive(ctx context.Context, userID string) (int, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	var count int
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID, models.JobStatusPending, models.JobStatusInProgress})
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs WHERE user_id = $1 AND status IN ($2, $3)`+scope, args...).Scan(&count)
+	return count, err
+}
+
+// CountCreatedSince counts a user's jobs created at or after since; demo jobs don't count
+func (r *SQLJobRepository) CountCreatedSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	var count int
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID, since.UTC()})
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs WHERE user_id = $1 AND created_at >= $2 AND NOT is_demo`+scope, args...).Scan(&count)
+	return count, err
+}
+
+// ListStale returns jobs stuck in status since before, oldest first
+func (r *SQLJobRepository) ListStale(ctx context.Context, status models.JobStatus, before time.Time, limit int) ([]*models.Job, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	// UTC so SQLite's text timestamps (written by CURRENT_TIMESTAMP) compare correctly
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{status, before.UTC(), limit})
+	query := `SELECT ` + strings.Join(jobColumns, ", ") + ` FROM jobs
+	          WHERE status = $1 AND updated_at < $2` + scope + `
+	          ORDER BY updated_at ASC LIMIT $3`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*models.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// CountStale counts jobs stuck in status since before
+func (r *SQLJobRepository) CountStale(ctx context.Context, status models.JobStatus, before time.Time) (int, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	var count int
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{status, before.UTC()})
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs WHERE status = $1 AND updated_at < $2`+scope, args...).Scan(&count)
+	return count, err
+}
+
+// Load counts the jobs in the pipeline across tenants
+func (r *SQLJobRepository) Load(ctx context.Context, now, since time.Time) (JobLoad, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	var load JobLoad
+	err := r.db.QueryRowContext(ctx, `SELECT
+	          COALESCE(SUM(CASE WHEN status = $1 AND (scheduled_at IS NULL OR scheduled_at <= $3) THEN 1 ELSE 0 END), 0),
+	          COALESCE(SUM(CASE WHEN status = $1 AND (scheduled_at IS NULL OR scheduled_at <= $3) AND priority = $4 THEN 1 ELSE 0 END), 0),
+	          COALESCE(SUM(CASE WHEN status = $2 THEN 1 ELSE 0 END), 0),
+	          COALESCE(SUM(CASE WHEN status IN ($5, $6) AND updated_at >= $7 THEN 1 ELSE 0 END), 0)
+	          FROM jobs WHERE status IN ($1, $2) OR (status IN ($5, $6) AND updated_at >= $7)`,
+		models.JobStatusPending, models.JobStatusInProgress, now.UTC(), models.JobPriorityBatch,
+		models.JobStatusCompleted, models.JobStatusFailed, since.UTC(),
+	).Scan(&load.Queued, &load.QueuedBatch, &load.Running, &load.Finished)
+	return load, err
+}
+
+// jobStatusColumns is the column list scanned by scanJobStatus
+var jobStatusColumns = []string{"id", "user_id", "status", "progress", "current_step", "error_message", "target_date", "version", "updated_at"}
+
+// ListStatuses returns the status columns of the jobs matching filter, least recently
+// updated first
+func (r *SQLJobRepository) ListStatuses(ctx context.Context, filter JobStatusFilter) ([]*models.JobStatusSummary, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", nil)
+	query := `SELECT ` + strings.Join(jobStatusColumns, ", ") + ` FROM jobs WHERE TRUE` + scope
+	if len(filter.IDs) > 0 {
+		placeholders := make([]string, len(filter.IDs))
+		for i, id := range filter.IDs {
+			args = append(args, id)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		query += ` AND id IN (` + strings.Join(placeholders, ", ") + `)`
+	}
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		query += fmt.Sprintf(` AND user_id = $%d`, len(args))
+	}
+	if filter.Since != nil {
+		// UTC so SQLite's text timestamps (written by CURRENT_TIMESTAMP) compare correctly
+		args = append(args, filter.Since.UTC())
+		query += fmt.Sprintf(` AND updated_at >= $%d`, len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY updated_at ASC, id ASC LIMIT $%d`, len(args))
+
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []*models.JobStatusSummary
+	for rows.Next() {
+		status, err := scanJobStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, rows.Err()
+}
+
+// Create inserts a PENDING job along with its creation event
+func (r *SQLJobRepository) Create(ctx context.Context, input NewJob) (*models.Job, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	// InputData is already a JSON string from the frontend; pass it directly to the JSONB column
+	var inputDataJSON interface{}
+	if input.InputData != nil && *input.InputData != "" {
+		inputDataJSON = *input.InputData
+	}
+
+	priority := input.Priority
+	if priority == "" {
+		priority = models.JobPriorityInteractive
+	}
+
+	var scheduledAt interface{}
+	if input.ScheduledAt != nil {
+		scheduledAt = input.ScheduledAt.UTC()
+	}
+
+	query := `INSERT INTO jobs (id, user_id, status, priority, progress, target_date, input_data, scheduled_at, is_demo, experiment, variant, follow_up_of, replay_of, client_name, client_version, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	          RETURNING ` + strings.Join(jobColumns, ", ")
+
+	job, err := scanJob(tx.QueryRowContext(ctx, query, uuid.New().String(), input.UserID, models.JobStatusPending, priority, 0.0, input.TargetDate, inputDataJSON, scheduledAt, input.IsDemo, input.Experiment, input.Variant, input.FollowUpOf, input.ReplayOf, input.ClientName, input.ClientVersion, now, now))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO job_events (`+strings.Join(jobEventColumns, ", ")+`)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		job.ID, 1, nil, job.Status, nil, nil, now)
+	if err != nil {
+		return nil, err
+	}
+	return job, tx.Commit()
+}
+
+// Update applies a partial update, or returns ErrNotFound. Status changes go through the
+// job state machine and are recorded in job_events in the same transaction, as are
+// changes of the current step.
+func (r *SQLJobRepository) Update(ctx context.Context, id string, input JobUpdate) (*models.Job, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// A job of another tenant, or a stale update, must not get a history event before
+	// the UPDATE misses it
+	if scope, args := tenantClause(ctx, "tenant_id", []interface{}{id}); scope != "" || input.ExpectedVersion != nil {
+		var version int
+		err := tx.QueryRowContext(ctx, `SELECT version FROM jobs WHERE id = $1`+scope, args...).Scan(&version)
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := checkVersion(version, input.ExpectedVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.Status != nil || input.CurrentStep != nil {
+		if err := appendJobEvent(ctx, tx, id, input); err != nil {
+			return nil, err
+		}
+	}
+
+	b := Update("jobs").SetExpr("updated_at = CURRENT_TIMESTAMP").Version(input.ExpectedVersion)
+	if input.Status != nil {
+		b.Set("status", *input.Status)
+	}
+	if input.Progress != nil {
+		b.Set("progress", *input.Progress)
+	}
+	if input.CurrentStep != nil {
+		b.Set("current_step", *input.CurrentStep)
+	}
+	if input.Result != nil {
+		b.Set("result", *input.Result)
+	}
+	if input.ErrorMessage != nil {
+		b.Set("error_message", *input.ErrorMessage)
+	}
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(jobColumns...).Build()
+
+	job, err := scanJob(tx.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, missedUpdate(ctx, tx, "jobs", id, input.ExpectedVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, tx.Commit()
+}
+
+// appendJobEvent validates a status change against the job's latest event and records
+// it, along with a change of step within a status (an event from and to the same status).
+// The current status is derived from the history rather than jobs.status; the
+// (job_id, sequence) key turns a concurrent transition into ErrConflict.
+func appendJobEvent(ctx context.Context, tx *database.Tx, jobID string, input JobUpdate) error {
+	var sequence int
+	var current models.JobStatus
+	var step *string
+	err := tx.QueryRowContext(ctx, `SELECT sequence, to_status, current_step FROM job_events
+	          WHERE job_id = $1 ORDER BY sequence DESC LIMIT 1`, jobID).Scan(&sequence, &current, &step)
+	if err == sql.ErrNoRows {
+		// Jobs written outside the repository have no history yet; start from their row
+		err = tx.QueryRowContext(ctx, `SELECT status, current_step FROM jobs WHERE id = $1`, jobID).Scan(&current, &step)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	next := current
+	if input.Status != nil {
+		next = models.JobStatus(*input.Status)
+	}
+	if err := current.ValidateTransition(next); err != nil {
+		return err
+	}
+	if current == next && !stepChanged(step, input.CurrentStep) {
+		return nil
+	}
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO job_events (`+strings.Join(jobEventColumns, ", ")+`)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)
+	          ON CONFLICT (job_id, sequence) DO NOTHING`,
+		jobID, sequence+1, current, next, input.CurrentStep, input.ErrorMessage, time.Now())
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 && current != next {
+		return ErrConflict
+	}
+	// A step change racing another update is left out of the history rather than failing
+	// the worker's progress update
+	return nil
+}
+
+// stepChanged reports whether an update moves a job off step onto a new one
+func stepChanged(step, next *string) bool {
+	return next != nil && (step == nil || *step != *next)
+}
+
+// Events returns a job's status history, oldest first
+func (r *SQLJobRepository) Events(ctx context.Context, jobID string) ([]*models.JobEvent, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{jobID})
+	query := `SELECT ` + strings.Join(jobEventColumns, ", ") + ` FROM job_events
+	          WHERE job_id = $1 AND job_id IN (SELECT id FROM jobs WHERE id = $1` + scope + `)
+	          ORDER BY sequence ASC`
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.JobEvent
+	for rows.Next() {
+		event, err := scanJobEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// CompletedEvents returns the history of recently completed jobs across tenants
+func (r *SQLJobRepository) CompletedEvents(ctx context.Context, since time.Time, limit int) ([][]*models.JobEvent, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	columns := make([]string, len(jobEventColumns))
+	for i, column := range jobEventColumns {
+		columns[i] = "e." + column
+	}
+	query := `SELECT ` + strings.Join(columns, ", ") + ` FROM job_events e
+	          JOIN (SELECT id, updated_at FROM jobs WHERE status = $1 AND NOT is_demo AND updated_at >= $2
+	                ORDER BY updated_at DESC LIMIT $3) j ON j.id = e.job_id
+	          ORDER BY j.updated_at DESC, e.job_id, e.sequence ASC`
+	rows, err := r.db.Reader().QueryContext(ctx, query, models.JobStatusCompleted, since.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var histories [][]*models.JobEvent
+	for rows.Next() {
+		event, err := scanJobEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(histories); n == 0 || histories[n-1][0].JobID != event.JobID {
+			histories = append(histories, nil)
+		}
+		histories[len(histories)-1] = append(histories[len(histories)-1], event)
+	}
+	return histories, rows.Err()
+}
+
+// Delete removes a job, reporting whether a row was deleted
+func (r *SQLJobRepository) Delete(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	result, err := r.db.ExecContext(ctx, `DELETE FROM jobs WHERE id = $1`+scope, args...)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// DeleteDemoByUser removes the user's demo jobs; their recommendations and history go
+// with them
+func (r *SQLJobRepository) DeleteDemoByUser(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID})
+	result, err := r.db.ExecContext(ctx, `DELETE FROM jobs WHERE user_id = $1 AND is_demo`+scope, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanJob scans a row selected with jobColumns
+func scanJob(row rowScanner) (*models.Job, error) {
+	job := &models.Job{}
+	err := row.Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Status,
+		&job.Priority,
+		&job.Progress,
+		&job.CurrentStep,
+		&job.TargetDate,
+		&job.InputData,
+		&job.Result,
+		&job.ErrorMessage,
+		&job.ScheduledAt,
+		&job.IsDemo,
+		&job.Experiment,
+		&job.Variant,
+		&job.FollowUpOf,
+		&job.ReplayOf,
+		&job.ClientName,
+		&job.ClientVersion,
+		&job.Version,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// scanJobEvent scans a row selected with jobEventColumns
+func scanJobEvent(row rowScanner) (*models.JobEvent, error) {
+	event := &models.JobEvent{}
+	err := row.Scan(
+		&event.JobID,
+		&event.Sequence,
+		&event.FromStatus,
+		&event.ToStatus,
+		&event.CurrentStep,
+		&event.ErrorMessage,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func scanJobStatus(row rowScanner) (*models.JobStatusSummary, error) {
+	status := &models.JobStatusSummary{}
+	err := row.Scan(
+		&status.ID,
+		&status.UserID,
+		&status.Status,
+		&status.Progress,
+		&status.CurrentStep,
+		&status.ErrorMessage,
+		&status.TargetDate,
+		&status.Version,
+		&status.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSQLJobStatusHistory(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	jobs := NewSQLJobRepository(db)
+	job := createJob(t, ctx, db, createUser(t, ctx, db, "ada@example.com").ID)
+
+	setStatus(t, ctx, jobs, job.ID, models.JobStatusInProgress)
+	step := "Analyzing calendar"
+	progress := 0.5
+	updated, err := jobs.Update(ctx, job.ID, JobUpdate{Progress: &progress, CurrentStep: &step})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != models.JobStatusInProgress || updated.Progress != 0.5 || updated.CurrentStep == nil || *updated.CurrentStep != step {
+		t.Errorf("updated job = %+v, want IN_PROGRESS at 0.5 on %q", updated, step)
+	}
+	setStatus(t, ctx, jobs, job.ID, models.JobStatusCompleted)
+
+	// A finished job can't be restarted, and a refused change leaves no event behind
+	status := string(models.JobStatusInProgress)
+	_, err = jobs.Update(ctx, job.ID, JobUpdate{Status: &status})
+	var transitionErr *models.JobTransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("COMPLETED -> IN_PROGRESS error = %v, want a JobTransitionError", err)
+	}
+
+	events, err := jobs.Events(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The step change is recorded too, as an event within IN_PROGRESS
+	want := []models.JobStatus{models.JobStatusPending, models.JobStatusInProgress, models.JobStatusInProgress, models.JobStatusCompleted}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.Sequence != i+1 || event.ToStatus != want[i] {
+			t.Errorf("event %d = #%d to %s, want #%d to %s", i, event.Sequence, event.ToStatus, i+1, want[i])
+		}
+	}
+	if events[2].CurrentStep == nil || *events[2].CurrentStep != step {
+		t.Errorf("step event = %+v, want the step %q", events[2], step)
+	}
+	if _, err := models.ReplayJobEvents(events); err != nil {
+		t.Errorf("replaying the history: %v", err)
+	}
+
+	if _, err := jobs.Update(ctx, "00000000-0000-0000-0000-000000000000", JobUpdate{Status: &status}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating a missing job: error = %v, want ErrNotFound", err)
+	}
+}
+
+// Two transactions that both read the same latest event race for the next sequence
+// number; the loser must get ErrConflict rather than a second transition from PENDING
+func TestAppendJobEventConflict(t *testing.T) {
+	db := testdb.Open(t)
+	if db.Driver() != database.DriverPostgres {
+		t.Skip("SQLite allows a single writer, so transitions can't race")
+	}
+	ctx := context.Background()
+	job := createJob(t, ctx, db, createUser(t, ctx, db, "ada@example.com").ID)
+
+	first, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Rollback()
+	inProgress, failed := string(models.JobStatusInProgress), string(models.JobStatusFailed)
+	if err := appendJobEvent(ctx, first, job.ID, JobUpdate{Status: &inProgress}); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Rollback()
+	done := make(chan error, 1)
+	go func() {
+		// Reads PENDING as the latest event, then waits on the first transaction's row
+		done <- appendJobEvent(ctx, second, job.ID, JobUpdate{Status: &failed})
+	}()
+
+	waitForLockWait(t, db)
+	if err := first.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !errors.Is(err, ErrConflict) {
+		t.Fatalf("racing transition error = %v, want ErrConflict", err)
+	}
+}
+
+// waitForLockWait waits until a session of the test database is blocked on a lock
+func waitForLockWait(t *testing.T, db *database.DB) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		var waiting int
+		err := db.QueryRow(`SELECT COUNT(*) FROM pg_stat_activity
+		          WHERE datname = current_database() AND wait_event_type = 'Lock'`).Scan(&waiting)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if waiting > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("the second transition never waited on the first")
+}
+
+func TestSQLJobListStale(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	jobs := NewSQLJobRepository(db)
+	user := createUser(t, ctx, db, "ada@example.com")
+
+	now := time.Now()
+	stuck := createJob(t, ctx, db, user.ID)
+	setStatus(t, ctx, jobs, stuck.ID, models.JobStatusInProgress)
+	testdb.Backdate(t, db, "jobs", stuck.ID, now.Add(-time.Hour))
+
+	older := createJob(t, ctx, db, user.ID)
+	setStatus(t, ctx, jobs, older.ID, models.JobStatusInProgress)
+	testdb.Backdate(t, db, "jobs", older.ID, now.Add(-2*time.Hour))
+
+	active := createJob(t, ctx, db, user.ID)
+	setStatus(t, ctx, jobs, active.ID, models.JobStatusInProgress)
+
+	pending := createJob(t, ctx, db, user.ID)
+	testdb.Backdate(t, db, "jobs", pending.ID, now.Add(-time.Hour))
+
+	stale, err := jobs.ListStale(ctx, models.JobStatusInProgress, now.Add(-15*time.Minute), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 2 || stale[0].ID != older.ID || stale[1].ID != stuck.ID {
+		t.Fatalf("stale jobs = %v, want the two stuck jobs oldest first", jobIDs(stale))
+	}
+
+	limited, err := jobs.ListStale(ctx, models.JobStatusInProgress, now.Add(-15*time.Minute), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited) != 1 || limited[0].ID != older.ID {
+		t.Errorf("stale jobs with limit 1 = %v, want only the oldest", jobIDs(limited))
+	}
+
+	if count, err := jobs.CountStale(ctx, models.JobStatusInProgress, now.Add(-15*time.Minute)); err != nil || count != 2 {
+		t.Errorf("CountStale() = %d, %v; want the two stuck jobs", count, err)
+	}
+}
+
+func TestSQLJobLoad(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	jobs := NewSQLJobRepository(db)
+	user := createUser(t, ctx, db, "ada@example.com")
+
+	now := time.Now()
+	createJob(t, ctx, db, user.ID)
+	if _, err := jobs.Create(ctx, NewJob{UserID: user.ID, TargetDate: "2026-03-02", Priority: models.JobPriorityBatch}); err != nil {
+		t.Fatal(err)
+	}
+	later := now.Add(time.Hour)
+	if _, err := jobs.Create(ctx, NewJob{UserID: user.ID, TargetDate: "2026-03-02", ScheduledAt: &later}); err != nil {
+		t.Fatal(err)
+	}
+	running := createJob(t, ctx, db, user.ID)
+	setStatus(t, ctx, jobs, running.ID, models.JobStatusInProgress)
+	for _, age := range []time.Duration{time.Minute, time.Hour} {
+		done := createJob(t, ctx, db, user.ID)
+		setStatus(t, ctx, jobs, done.ID, models.JobStatusInProgress)
+		setStatus(t, ctx, jobs, done.ID, models.JobStatusCompleted)
+		testdb.Backdate(t, db, "jobs", done.ID, now.Add(-age))
+	}
+
+	load, err := jobs.Load(ctx, now, now.Add(-10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := JobLoad{Queued: 2, QueuedBatch: 1, Running: 1, Finished: 1}
+	if load != want {
+		t.Errorf("Load() = %+v, want %+v", load, want)
+	}
+}
+
+func TestSQLJobCompletedEvents(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	jobs := NewSQLJobRepository(db)
+	user := createUser(t, ctx, db, "ada@example.com")
+
+	now := time.Now()
+	var completed []*models.Job
+	for _, age := range []time.Duration{time.Hour, time.Minute, 30 * 24 * time.Hour} {
+		job := createJob(t, ctx, db, user.ID)
+		setStatus(t, ctx, jobs, job.ID, models.JobStatusInProgress)
+		setStatus(t, ctx, jobs, job.ID, models.JobStatusCompleted)
+		testdb.Backdate(t, db, "jobs", job.ID, now.Add(-age))
+		completed = append(completed, job)
+	}
+	setStatus(t, ctx, jobs, createJob(t, ctx, db, user.ID).ID, models.JobStatusInProgress)
+
+	histories, err := jobs.CompletedEvents(ctx, now.Add(-7*24*time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 2 || histories[0][0].JobID != completed[1].ID || histories[1][0].JobID != completed[0].ID {
+		t.Fatalf("histories = %v, want the two recently completed jobs, latest first", histories)
+	}
+	if len(histories[0]) != 3 || histories[0][2].ToStatus != models.JobStatusCompleted {
+		t.Errorf("history = %v, want PENDING, IN_PROGRESS, COMPLETED", histories[0])
+	}
+
+	limited, err := jobs.CompletedEvents(ctx, now.Add(-7*24*time.Hour), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited) != 1 || limited[0][0].JobID != completed[1].ID {
+		t.Errorf("histories with limit 1 = %v, want the latest job only", limited)
+	}
+}
+
+func jobIDs(jobs []*models.Job) []string {
+	ids := make([]string, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	return ids
+}
+
+func TestSQLJobListStatuses(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	jobs := NewSQLJobRepository(db)
+	ada := createUser(t, ctx, db, "ada@example.com")
+	first := createJob(t, ctx, db, ada.ID)
+	second := createJob(t, ctx, db, ada.ID)
+	bobs := createJob(t, ctx, db, createUser(t, ctx, db, "bob@example.com").ID)
+	testdb.Backdate(t, db, "jobs", first.ID, time.Now().Add(-time.Hour))
+
+	statuses, err := jobs.ListStatuses(ctx, JobStatusFilter{IDs: []string{second.ID, bobs.ID}, Limit: 10})
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("by ID: %+v, %v", statuses, err)
+	}
+
+	since := time.Now().Add(-time.Minute)
+	statuses, err = jobs.ListStatuses(ctx, JobStatusFilter{UserID: &ada.ID, Since: &since, Limit: 10})
+	if err != nil || len(statuses) != 1 || statuses[0].ID != second.ID || statuses[0].Status != models.JobStatusPending {
+		t.Errorf("ada's jobs since a minute ago = %+v, %v; want only the second", statuses, err)
+	}
+}
@@ -0,0 +1,2611 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/tenant"
+	"github.com/google/uuid"
+)
+
+// In-memory implementations for unit tests and local experiments. They mirror the SQL
+// behaviour (ordering, ErrNotFound, partial updates) but share no state across instances.
+// They hold a single tenant's data and ignore tenant scoping.
+
+// NewMemoryRepositories creates empty in-memory repositories
+func NewMemoryRepositories() Repositories {
+	jobs := NewMemoryJobRepository()
+	recommendations := NewMemoryRecommendationRepository(jobs)
+	return Repositories{
+		Users:           NewMemoryUserRepository(),
+		Jobs:            jobs,
+		Events:          NewMemoryEventRepository(),
+		Recommendations: recommendations,
+		Webhooks:        NewMemoryWebhookRepository(),
+		GoogleCalendar:  NewMemoryGoogleCalendarRepository(),
+		JobOutbox:       NewMemoryJobOutboxRepository(),
+		JobQuotas:       NewMemoryJobQuotaRepository(),
+		Tenants:         NewMemoryTenantRepository(),
+		Offices:         NewMemoryOfficeRepository(),
+		GeocodeCache:    NewMemoryGeocodeCacheRepository(),
+		TravelProfiles:  NewMemoryTravelProfileRepository(),
+		Preferences:     NewMemoryPreferenceFeedbackRepository(),
+		Notifications:   NewMemoryNotificationSettingsRepository(),
+		Retention:       NewMemoryRetentionRepository(jobs, recommendations),
+		Artifacts:       NewMemoryArtifactRepository(),
+		ShareLinks:      NewMemoryShareLinkRepository(),
+		CalendarFeeds:   NewMemoryCalendarFeedRepository(),
+		Reminders:       NewMemoryCommuteReminderRepository(),
+		Analytics:       NewMemoryAnalyticsEventRepository(),
+		CalendarImports: NewMemoryCalendarImportRepository(),
+		APITokens:       NewMemoryAPITokenRepository(),
+		Deprecations:    NewMemoryDeprecationUsageRepository(),
+		ClientUsage:     NewMemoryClientUsageRepository(),
+	}
+}
+
+// MemoryUserRepository is an in-memory UserRepository
+type MemoryUserRepository struct {
+	mu          sync.Mutex
+	users       map[string]*models.User
+	timezones   map[string]string
+	oauthTokens map[string]string
+}
+
+// NewMemoryUserRepository creates an empty in-memory user repository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: map[string]*models.User{}, timezones: map[string]string{}, oauthTokens: map[string]string{}}
+}
+
+func (r *MemoryUserRepository) Get(ctx context.Context, id string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	scoped, hasTenant := tenant.FromContext(ctx)
+	for _, user := range r.users {
+		if user.Email == email && (!hasTenant || user.TenantID == scoped) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUserRepository) List(ctx context.Context) ([]*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []*models.User
+	for _, user := range r.users {
+		copied := *user
+		users = append(users, &copied)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].CreatedAt.After(users[j].CreatedAt) })
+	return users, nil
+}
+
+func (r *MemoryUserRepository) ListPage(ctx context.Context, filter UserFilter) ([]*models.User, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	scoped, hasTenant := tenant.FromContext(ctx)
+	var users []*models.User
+	for _, user := range r.users {
+		if (hasTenant && user.TenantID != scoped) ||
+			(filter.SCIMExternalID != nil && (user.SCIMExternalID == nil || *user.SCIMExternalID != *filter.SCIMExternalID)) {
+			continue
+		}
+		copied := *user
+		users = append(users, &copied)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.After(users[j].CreatedAt)
+		}
+		return users[i].ID < users[j].ID
+	})
+	total := len(users)
+	if filter.Offset >= total {
+		return []*models.User{}, total, nil
+	}
+	users = users[filter.Offset:]
+	if len(users) > filter.Limit {
+		users = users[:filter.Limit]
+	}
+	return users, total, nil
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, input NewUser) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	authProvider := "local"
+	if input.AuthProvider != nil {
+		authProvider = *input.AuthProvider
+	}
+	user := &models.User{
+		ID:              uuid.New().String(),
+		TenantID:        tenant.OrDefault(ctx),
+		Email:           input.Email,
+		Name:            input.Name,
+		UserPreferences: input.UserPreferences,
+		AuthProvider:    &authProvider,
+		SCIMExternalID:  input.SCIMExternalID,
+		Version:         1,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	r.users[user.ID] = user
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) Update(ctx context.Context, id string, input UserUpdate) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err := checkVersion(user.Version, input.ExpectedVersion); err != nil {
+		return nil, err
+	}
+	if input.Email != nil {
+		user.Email = *input.Email
+	}
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	if input.UserPreferences != nil {
+		user.UserPreferences = input.UserPreferences
+	}
+	if input.SCIMExternalID != nil {
+		user.SCIMExternalID = input.SCIMExternalID
+		if *input.SCIMExternalID == "" {
+			user.SCIMExternalID = nil
+		}
+	}
+	user.Version++
+	user.UpdatedAt = time.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) SetDefaultOffice(ctx context.Context, id string, officeID *string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.DefaultOfficeID = officeID
+	user.Version++
+	user.UpdatedAt = time.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) SetFocusMinutes(ctx context.Context, id string, minutes *int) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.FocusMinutes = minutes
+	user.Version++
+	user.UpdatedAt = time.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) SetActive(ctx context.Context, id string, active bool) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if active {
+		user.DeactivatedAt = nil
+	} else if user.DeactivatedAt == nil {
+		now := time.Now()
+		user.DeactivatedAt = &now
+	}
+	user.Version++
+	user.UpdatedAt = time.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) SetOrgAdmin(ctx context.Context, id string, orgAdmin bool) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.IsOrgAdmin = orgAdmin
+	user.Version++
+	user.UpdatedAt = time.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) SetOrgReportingOptOut(ctx context.Context, id string, optOut bool) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.OrgReportingOptOut = optOut
+	user.Version++
+	user.UpdatedAt = time.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) SetAnalyticsOptOut(ctx context.Context, id string, optOut bool) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.AnalyticsOptOut = optOut
+	user.Version++
+	user.UpdatedAt = time.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) SetLocale(ctx context.Context, id string, locale *string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.Locale = locale
+	user.Version++
+	user.UpdatedAt = time.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) SetDisplayUnits(ctx context.Context, id string, distanceUnit *models.DistanceUnit, currency *string, timeFormat *models.TimeFormat) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.DistanceUnit, user.Currency, user.TimeFormat = distanceUnit, currency, timeFormat
+	user.Version++
+	user.UpdatedAt = time.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) SetShareRedaction(ctx context.Context, id string, policy *models.ShareRedaction) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.ShareRedaction = policy
+	user.Version++
+	user.UpdatedAt = time.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) SetHomeAddress(ctx context.Context, id string, address *string, latitude, longitude *float64) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.HomeAddress = address
+	user.HomeLatitude = latitude
+	user.HomeLongitude = longitude
+	user.Version++
+	user.UpdatedAt = time.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (r *MemoryUserRepository) Delete(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.users[id]
+	delete(r.users, id)
+	return ok, nil
+}
+
+func (r *MemoryUserRepository) PreferredTimezone(ctx context.Context, id string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return "", ErrNotFound
+	}
+	if tz := r.timezones[id]; tz != "" {
+		return tz, nil
+	}
+	return "UTC", nil
+}
+
+// SetPreferredTimezone sets a user's timezone (the SQL schema defaults it to UTC)
+func (r *MemoryUserRepository) SetPreferredTimezone(id, timezone string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timezones[id] = timezone
+}
+
+func (r *MemoryUserRepository) OAuthTokens(ctx context.Context, id string) (*string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return nil, ErrNotFound
+	}
+	tokens, ok := r.oauthTokens[id]
+	if !ok {
+		return nil, nil
+	}
+	return &tokens, nil
+}
+
+// SetOAuthTokens stores a user's raw oauth_tokens JSON
+func (r *MemoryUserRepository) SetOAuthTokens(id, tokens string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.oauthTokens[id] = tokens
+}
+
+// MemoryJobRepository is an in-memory JobRepository
+type MemoryJobRepository struct {
+	mu     sync.Mutex
+	jobs   map[string]*models.Job
+	events map[string][]*models.JobEvent
+}
+
+// NewMemoryJobRepository creates an empty in-memory job repository
+func NewMemoryJobRepository() *MemoryJobRepository {
+	return &MemoryJobRepository{jobs: map[string]*models.Job{}, events: map[string][]*models.JobEvent{}}
+}
+
+func (r *MemoryJobRepository) Get(ctx context.Context, id string) (*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (r *MemoryJobRepository) List(ctx context.Context, userID *string) ([]*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var jobs []*models.Job
+	for _, job := range r.jobs {
+		if userID != nil && job.UserID != *userID {
+			continue
+		}
+		copied := *job
+		jobs = append(jobs, &copied)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs, nil
+}
+
+func (r *MemoryJobRepository) ListStale(ctx context.Context, status models.JobStatus, before time.Time, limit int) ([]*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var jobs []*models.Job
+	for _, job := range r.jobs {
+		if job.Status == status && job.UpdatedAt.Before(before) {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].UpdatedAt.Before(jobs[j].UpdatedAt) })
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+func (r *MemoryJobRepository) CountStale(ctx context.Context, status models.JobStatus, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, job := range r.jobs {
+		if job.Status == status && job.UpdatedAt.Before(before) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryJobRepository) Load(ctx context.Context, now, since time.Time) (JobLoad, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var load JobLoad
+	for _, job := range r.jobs {
+		switch job.Status {
+		case models.JobStatusPending:
+			if job.ScheduledAt == nil || !job.ScheduledAt.After(now) {
+				load.Queued++
+				if job.Priority == models.JobPriorityBatch {
+					load.QueuedBatch++
+				}
+			}
+		case models.JobStatusInProgress:
+			load.Running++
+		case models.JobStatusCompleted, models.JobStatusFailed:
+			if !job.UpdatedAt.Before(since) {
+				load.Finished++
+			}
+		}
+	}
+	return load, nil
+}
+
+func (r *MemoryJobRepository) ListStatuses(ctx context.Context, filter JobStatusFilter) ([]*models.JobStatusSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := map[string]bool{}
+	for _, id := range filter.IDs {
+		ids[id] = true
+	}
+	var statuses []*models.JobStatusSummary
+	for _, job := range r.jobs {
+		if (len(ids) > 0 && !ids[job.ID]) ||
+			(filter.UserID != nil && job.UserID != *filter.UserID) ||
+			(filter.Since != nil && job.UpdatedAt.Before(*filter.Since)) {
+			continue
+		}
+		statuses = append(statuses, &models.JobStatusSummary{
+			ID:           job.ID,
+			UserID:       job.UserID,
+			Status:       job.Status,
+			Progress:     job.Progress,
+			CurrentStep:  job.CurrentStep,
+			ErrorMessage: job.ErrorMessage,
+			TargetDate:   job.TargetDate,
+			Version:      job.Version,
+			UpdatedAt:    job.UpdatedAt,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if !statuses[i].UpdatedAt.Equal(statuses[j].UpdatedAt) {
+			return statuses[i].UpdatedAt.Before(statuses[j].UpdatedAt)
+		}
+		return statuses[i].ID < statuses[j].ID
+	})
+	if len(statuses) > filter.Limit {
+		statuses = statuses[:filter.Limit]
+	}
+	return statuses, nil
+}
+
+func (r *MemoryJobRepository) Create(ctx context.Context, input NewJob) (*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	priority := input.Priority
+	if priority == "" {
+		priority = models.JobPriorityInteractive
+	}
+
+	now := time.Now()
+	job := &models.Job{
+		ID:            uuid.New().String(),
+		UserID:        input.UserID,
+		Status:        models.JobStatusPending,
+		Priority:      priority,
+		TargetDate:    input.TargetDate,
+		ScheduledAt:   input.ScheduledAt,
+		InputData:     input.InputData,
+		IsDemo:        input.IsDemo,
+		Experiment:    input.Experiment,
+		Variant:       input.Variant,
+		FollowUpOf:    input.FollowUpOf,
+		ReplayOf:      input.ReplayOf,
+		ClientName:    input.ClientName,
+		ClientVersion: input.ClientVersion,
+		Version:       1,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	r.jobs[job.ID] = job
+	r.events[job.ID] = []*models.JobEvent{{JobID: job.ID, Sequence: 1, ToStatus: job.Status, CreatedAt: now}}
+	copied := *job
+	return &copied, nil
+}
+
+func (r *MemoryJobRepository) Update(ctx context.Context, id string, input JobUpdate) (*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err := checkVersion(job.Version, input.ExpectedVersion); err != nil {
+		return nil, err
+	}
+	if input.Status != nil || input.CurrentStep != nil {
+		next := job.Status
+		if input.Status != nil {
+			next = models.JobStatus(*input.Status)
+		}
+		if err := job.Status.ValidateTransition(next); err != nil {
+			return nil, err
+		}
+		var step *string
+		if events := r.events[id]; len(events) > 0 {
+			step = events[len(events)-1].CurrentStep
+		}
+		if next != job.Status || stepChanged(step, input.CurrentStep) {
+			from := job.Status
+			r.events[id] = append(r.events[id], &models.JobEvent{
+				JobID:        id,
+				Sequence:     len(r.events[id]) + 1,
+				FromStatus:   &from,
+				ToStatus:     next,
+				CurrentStep:  input.CurrentStep,
+				ErrorMessage: input.ErrorMessage,
+				CreatedAt:    time.Now(),
+			})
+			job.Status = next
+		}
+	}
+	if input.Progress != nil {
+		job.Progress = *input.Progress
+	}
+	if input.CurrentStep != nil {
+		job.CurrentStep = input.CurrentStep
+	}
+	if input.Result != nil {
+		job.Result = input.Result
+	}
+	if input.ErrorMessage != nil {
+		job.ErrorMessage = input.ErrorMessage
+	}
+	job.Version++
+	job.UpdatedAt = time.Now()
+	copied := *job
+	return &copied, nil
+}
+
+func (r *MemoryJobRepository) CountActive(ctx context.Context, userID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, job := range r.jobs {
+		if job.UserID == userID && (job.Status == models.JobStatusPending || job.Status == models.JobStatusInProgress) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryJobRepository) CountCreatedSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, job := range r.jobs {
+		if job.UserID == userID && !job.IsDemo && !job.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryJobRepository) Delete(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.jobs[id]
+	delete(r.jobs, id)
+	delete(r.events, id)
+	return ok, nil
+}
+
+func (r *MemoryJobRepository) DeleteDemoByUser(ctx context.Context, userID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, job := range r.jobs {
+		if job.UserID == userID && job.IsDemo {
+			delete(r.jobs, id)
+			delete(r.events, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *MemoryJobRepository) Events(ctx context.Context, jobID string) ([]*models.JobEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]*models.JobEvent, len(r.events[jobID]))
+	for i, event := range r.events[jobID] {
+		copied := *event
+		events[i] = &copied
+	}
+	return events, nil
+}
+
+func (r *MemoryJobRepository) CompletedEvents(ctx context.Context, since time.Time, limit int) ([][]*models.JobEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var completed []*models.Job
+	for _, job := range r.jobs {
+		if job.Status == models.JobStatusCompleted && !job.IsDemo && !job.UpdatedAt.Before(since) {
+			completed = append(completed, job)
+		}
+	}
+	sort.Slice(completed, func(i, j int) bool { return completed[i].UpdatedAt.After(completed[j].UpdatedAt) })
+	if len(completed) > limit {
+		completed = completed[:limit]
+	}
+
+	histories := make([][]*models.JobEvent, len(completed))
+	for i, job := range completed {
+		for _, event := range r.events[job.ID] {
+			copied := *event
+			histories[i] = append(histories[i], &copied)
+		}
+	}
+	return histories, nil
+}
+
+// MemoryEventRepository is an in-memory EventRepository
+type MemoryEventRepository struct {
+	mu     sync.Mutex
+	events map[string]*models.CalendarEvent
+	// geocoded holds the IDs of events whose location has been looked up
+	geocoded map[string]bool
+}
+
+// NewMemoryEventRepository creates an empty in-memory event repository
+func NewMemoryEventRepository() *MemoryEventRepository {
+	return &MemoryEventRepository{events: map[string]*models.CalendarEvent{}, geocoded: map[string]bool{}}
+}
+
+func (r *MemoryEventRepository) ListByUser(ctx context.Context, userID string, targetDate *string) ([]*models.CalendarEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []*models.CalendarEvent
+	for _, event := range r.events {
+		if event.UserID != userID {
+			continue
+		}
+		if targetDate != nil && event.StartTime.UTC().Format("2006-01-02") != dateOnly(*targetDate) {
+			continue
+		}
+		copied := *event
+		events = append(events, &copied)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].StartTime.Before(events[j].StartTime) })
+	return events, nil
+}
+
+func (r *MemoryEventRepository) CountByUser(ctx context.Context, userID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, event := range r.events {
+		if event.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryEventRepository) CountDemoByUser(ctx context.Context, userID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, event := range r.events {
+		if event.UserID == userID && event.IsDemo {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryEventRepository) Create(ctx context.Context, event *models.CalendarEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *event
+	copied.Version = 1
+	r.events[event.ID] = &copied
+	return nil
+}
+
+func (r *MemoryEventRepository) CreateBatch(ctx context.Context, events []*models.CalendarEvent) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var inserted []string
+	for _, event := range events {
+		if _, exists := r.events[event.ID]; exists {
+			continue
+		}
+		copied := *event
+		copied.Version = 1
+		r.events[event.ID] = &copied
+		inserted = append(inserted, event.ID)
+	}
+	return inserted, nil
+}
+
+func (r *MemoryEventRepository) Stream(ctx context.Context, userID string, dates DateRange, fn func(*models.CalendarEvent) error) error {
+	events, err := r.ListByUser(ctx, userID, nil)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if !dates.contains(event.StartTime) {
+			continue
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search matches each query term as a substring, ordered by start time
+func (r *MemoryEventRepository) Search(ctx context.Context, userID string, search EventSearch) ([]*models.CalendarEvent, error) {
+	events, err := r.ListByUser(ctx, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	terms := searchTerms(search.Query)
+	var matched []*models.CalendarEvent
+	for _, event := range events {
+		if len(matched) == search.Limit {
+			break
+		}
+		if !search.Dates.contains(event.StartTime) || !eventMatches(event, terms) {
+			continue
+		}
+		if !matchesMeetingType(search.MeetingTypes, event.MeetingType) || !matchesAttendanceMode(search.AttendanceModes, event.AttendanceMode) {
+			continue
+		}
+		matched = append(matched, event)
+	}
+	return matched, nil
+}
+
+func eventMatches(event *models.CalendarEvent, terms []string) bool {
+	text := strings.ToLower(event.Summary)
+	if event.Description != nil {
+		text += " " + strings.ToLower(*event.Description)
+	}
+	if event.Location != nil {
+		text += " " + strings.ToLower(*event.Location)
+	}
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesMeetingType and matchesAttendanceMode treat an empty filter as matching everything
+func matchesMeetingType(filter []models.MeetingType, meetingType models.MeetingType) bool {
+	for _, t := range filter {
+		if t == meetingType {
+			return true
+		}
+	}
+	return len(filter) == 0
+}
+
+func matchesAttendanceMode(filter []models.AttendanceMode, mode models.AttendanceMode) bool {
+	for _, m := range filter {
+		if m == mode {
+			return true
+		}
+	}
+	return len(filter) == 0
+}
+
+func (r *MemoryEventRepository) UpsertByGoogleID(ctx context.Context, event *models.CalendarEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.events {
+		if existing.UserID != event.UserID || existing.GoogleEventID == nil || event.GoogleEventID == nil || *existing.GoogleEventID != *event.GoogleEventID {
+			continue
+		}
+		if event.Version > 0 && event.Version != existing.Version {
+			return ErrConflict
+		}
+		copied := *event
+		copied.ID = existing.ID
+		copied.Version = existing.Version + 1
+		copied.CreatedAt = existing.CreatedAt
+		if !sameLocation(existing.Location, event.Location) {
+			delete(r.geocoded, existing.ID)
+			copied.LocationLatitude, copied.LocationLongitude = nil, nil
+		}
+		copied.UpdatedAt = time.Now()
+		r.events[existing.ID] = &copied
+		return nil
+	}
+	copied := *event
+	copied.Version = 1
+	r.events[event.ID] = &copied
+	return nil
+}
+
+func (r *MemoryEventRepository) Get(ctx context.Context, userID, id string) (*models.CalendarEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[id]
+	if !ok || event.UserID != userID {
+		return nil, ErrNotFound
+	}
+	copied := *event
+	return &copied, nil
+}
+
+func (r *MemoryEventRepository) GetByGoogleID(ctx context.Context, userID, googleEventID string) (*models.CalendarEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range r.events {
+		if event.UserID == userID && event.GoogleEventID != nil && *event.GoogleEventID == googleEventID {
+			copied := *event
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryEventRepository) DeleteByGoogleID(ctx context.Context, userID, googleEventID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := false
+	for id, event := range r.events {
+		if event.UserID == userID && event.GoogleEventID != nil && *event.GoogleEventID == googleEventID {
+			delete(r.events, id)
+			deleted = true
+		}
+	}
+	return deleted, nil
+}
+
+func (r *MemoryEventRepository) ListUngeocoded(ctx context.Context, limit int) ([]*models.CalendarEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []*models.CalendarEvent
+	for _, event := range r.events {
+		if event.Location == nil || r.geocoded[event.ID] {
+			continue
+		}
+		copied := *event
+		events = append(events, &copied)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (r *MemoryEventRepository) SetLocationPoint(ctx context.Context, id string, latitude, longitude *float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[id]
+	if !ok {
+		return ErrNotFound
+	}
+	event.LocationLatitude = latitude
+	event.LocationLongitude = longitude
+	r.geocoded[id] = true
+	return nil
+}
+
+func (r *MemoryEventRepository) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, event := range r.events {
+		if event.UserID == userID {
+			delete(r.events, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *MemoryEventRepository) DeleteDemoByUser(ctx context.Context, userID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, event := range r.events {
+		if event.UserID == userID && event.IsDemo {
+			delete(r.events, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// MemoryRecommendationRepository is an in-memory RecommendationRepository. It reads jobs
+// from the job repository to resolve ownership and target dates.
+type MemoryRecommendationRepository struct {
+	mu              sync.Mutex
+	jobs            JobRepository
+	recommendations []*models.CommuteRecommendation
+}
+
+// NewMemoryRecommendationRepository creates an empty in-memory recommendation repository
+func NewMemoryRecommendationRepository(jobs JobRepository) *MemoryRecommendationRepository {
+	return &MemoryRecommendationRepository{jobs: jobs}
+}
+
+// deleteByJob drops a job's recommendations, as the jobs foreign key's cascade does
+func (r *MemoryRecommendationRepository) deleteByJob(jobID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.recommendations[:0]
+	for _, rec := range r.recommendations {
+		if rec.JobID != jobID {
+			kept = append(kept, rec)
+		}
+	}
+	r.recommendations = kept
+}
+
+func (r *MemoryRecommendationRepository) ListByJob(ctx context.Context, jobID string) ([]*models.CommuteRecommendation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var recommendations []*models.CommuteRecommendation
+	for _, rec := range r.recommendations {
+		if rec.JobID == jobID {
+			copied := *rec
+			recommendations = append(recommendations, &copied)
+		}
+	}
+	sort.Slice(recommendations, func(i, j int) bool { return recommendations[i].OptionRank < recommendations[j].OptionRank })
+	return recommendations, nil
+}
+
+func (r *MemoryRecommendationRepository) StreamByUser(ctx context.Context, userID string, dates DateRange, fn func(*models.CommuteRecommendation) error) error {
+	jobs, err := r.jobs.List(ctx, &userID)
+	if err != nil {
+		return err
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].TargetDate < jobs[j].TargetDate })
+
+	for _, job := range jobs {
+		targetDate, err := time.Parse("2006-01-02", dateOnly(job.TargetDate))
+		if err != nil || !dates.contains(targetDate) {
+			continue
+		}
+		recommendations, err := r.ListByJob(ctx, job.ID)
+		if err != nil {
+			return err
+		}
+		for _, rec := range recommendations {
+			rec.Job = &models.Job{ID: job.ID, TargetDate: job.TargetDate}
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRecommendationRepository) Accept(ctx context.Context, userID, id string) (*models.CommuteRecommendation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rec := range r.recommendations {
+		if rec.ID == id {
+			if job, err := r.jobs.Get(ctx, rec.JobID); err != nil || job.UserID != userID {
+				return nil, ErrNotFound
+			}
+			now := time.Now()
+			rec.AcceptedAt = &now
+			copied := *rec
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryRecommendationRepository) UpdateExplanation(ctx context.Context, id, reasoning, perceptionAnalysis string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rec := range r.recommendations {
+		if rec.ID == id {
+			rec.Reasoning = &reasoning
+			rec.PerceptionAnalysis = &perceptionAnalysis
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryRecommendationRepository) SetRanks(ctx context.Context, jobID string, ids []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ranks := make(map[string]int, len(ids))
+	for i, id := range ids {
+		ranks[id] = i + 1
+	}
+	var found []*models.CommuteRecommendation
+	for _, rec := range r.recommendations {
+		if rec.JobID == jobID && ranks[rec.ID] > 0 {
+			found = append(found, rec)
+		}
+	}
+	if len(found) != len(ids) {
+		return ErrNotFound
+	}
+	for _, rec := range found {
+		rec.OptionRank = ranks[rec.ID]
+	}
+	return nil
+}
+
+func (r *MemoryRecommendationRepository) Create(ctx context.Context, rec *models.CommuteRecommendation) error {
+	// Like the database trigger, recommendations take their job's experiment and variant
+	if job, err := r.jobs.Get(ctx, rec.JobID); err == nil {
+		rec.Experiment, rec.Variant = job.Experiment, job.Variant
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec.ID == "" {
+		rec.ID = uuid.New().String()
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+	copied := *rec
+	r.recommendations = append(r.recommendations, &copied)
+	return nil
+}
+
+func (r *MemoryRecommendationRepository) ExperimentStats(ctx context.Context, experiment string, dates DateRange) ([]models.VariantResult, error) {
+	jobs, err := r.jobs.List(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byVariant := map[string]*models.VariantResult{}
+	for _, job := range jobs {
+		if job.IsDemo || job.Experiment == nil || *job.Experiment != experiment || job.Variant == nil {
+			continue
+		}
+		targetDate, err := time.Parse("2006-01-02", dateOnly(job.TargetDate))
+		if err != nil || !dates.contains(targetDate) {
+			continue
+		}
+		result, ok := byVariant[*job.Variant]
+		if !ok {
+			result = &models.VariantResult{Variant: *job.Variant}
+			byVariant[*job.Variant] = result
+		}
+		result.Jobs++
+		if job.Status == models.JobStatusCompleted {
+			result.CompletedJobs++
+		}
+		topRank := 0
+		for _, rec := range r.recommendations {
+			if rec.JobID == job.ID && rec.AcceptedAt != nil && (topRank == 0 || rec.OptionRank < topRank) {
+				topRank = rec.OptionRank
+			}
+		}
+		if topRank > 0 {
+			result.AcceptedJobs++
+		}
+		if topRank == 1 {
+			result.TopChoiceAccepted++
+		}
+	}
+
+	results := make([]models.VariantResult, 0, len(byVariant))
+	for _, result := range byVariant {
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Variant < results[j].Variant })
+	return results, nil
+}
+
+// MemoryWebhookRepository is an in-memory WebhookRepository
+type MemoryWebhookRepository struct {
+	mu         sync.Mutex
+	endpoints  map[string]*models.WebhookEndpoint
+	deliveries map[string]*models.WebhookDelivery
+}
+
+// NewMemoryWebhookRepository creates an empty in-memory webhook repository
+func NewMemoryWebhookRepository() *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{
+		endpoints:  map[string]*models.WebhookEndpoint{},
+		deliveries: map[string]*models.WebhookDelivery{},
+	}
+}
+
+func (r *MemoryWebhookRepository) CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *endpoint
+	r.endpoints[endpoint.ID] = &copied
+	return nil
+}
+
+func (r *MemoryWebhookRepository) GetEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	endpoint, ok := r.endpoints[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *endpoint
+	return &copied, nil
+}
+
+func (r *MemoryWebhookRepository) ListEndpoints(ctx context.Context, userID string) ([]*models.WebhookEndpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var endpoints []*models.WebhookEndpoint
+	for _, endpoint := range r.endpoints {
+		if endpoint.UserID == userID {
+			copied := *endpoint
+			endpoints = append(endpoints, &copied)
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].CreatedAt.Before(endpoints[j].CreatedAt) })
+	return endpoints, nil
+}
+
+func (r *MemoryWebhookRepository) DeleteEndpoint(ctx context.Context, userID, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if endpoint, ok := r.endpoints[id]; !ok || endpoint.UserID != userID {
+		return false, nil
+	}
+	delete(r.endpoints, id)
+	for deliveryID, delivery := range r.deliveries {
+		if delivery.EndpointID == id {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+	return true, nil
+}
+
+func (r *MemoryWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *delivery
+	r.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+func (r *MemoryWebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == models.WebhookDeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*models.WebhookDelivery, 0, len(due))
+	leaseUntil := now.Add(lease)
+	for _, delivery := range due {
+		delivery.NextAttemptAt = &leaseUntil
+		copied := *delivery
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (r *MemoryWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.deliveries[delivery.ID]; !ok {
+		return ErrNotFound
+	}
+	copied := *delivery
+	copied.UpdatedAt = time.Now()
+	r.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+func (r *MemoryWebhookRepository) ListDeliveries(ctx context.Context, userID, endpointID string, limit int) ([]*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if endpoint, ok := r.endpoints[endpointID]; !ok || endpoint.UserID != userID {
+		return nil, nil
+	}
+	var deliveries []*models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.EndpointID == endpointID {
+			copied := *delivery
+			deliveries = append(deliveries, &copied)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt) })
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// MemoryGoogleCalendarRepository is an in-memory GoogleCalendarRepository
+type MemoryGoogleCalendarRepository struct {
+	mu       sync.Mutex
+	channels map[string]*models.GoogleCalendarChannel
+}
+
+// NewMemoryGoogleCalendarRepository creates an empty in-memory channel repository
+func NewMemoryGoogleCalendarRepository() *MemoryGoogleCalendarRepository {
+	return &MemoryGoogleCalendarRepository{channels: map[string]*models.GoogleCalendarChannel{}}
+}
+
+func (r *MemoryGoogleCalendarRepository) CreateChannel(ctx context.Context, channel *models.GoogleCalendarChannel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *channel
+	r.channels[channel.ID] = &copied
+	return nil
+}
+
+func (r *MemoryGoogleCalendarRepository) GetChannel(ctx context.Context, id string) (*models.GoogleCalendarChannel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	channel, ok := r.channels[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *channel
+	return &copied, nil
+}
+
+func (r *MemoryGoogleCalendarRepository) ListChannelsByUser(ctx context.Context, userID string) ([]*models.GoogleCalendarChannel, error) {
+	channels := r.filter(func(c *models.GoogleCalendarChannel) bool { return c.UserID == userID })
+	sort.Slice(channels, func(i, j int) bool { return channels[i].CreatedAt.Before(channels[j].CreatedAt) })
+	return channels, nil
+}
+
+func (r *MemoryGoogleCalendarRepository) ChannelsExpiringBefore(ctx context.Context, t time.Time) ([]*models.GoogleCalendarChannel, error) {
+	channels := r.filter(func(c *models.GoogleCalendarChannel) bool { return c.Expiration.Before(t) })
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Expiration.Before(channels[j].Expiration) })
+	return channels, nil
+}
+
+func (r *MemoryGoogleCalendarRepository) DeleteChannel(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.channels[id]
+	delete(r.channels, id)
+	return ok, nil
+}
+
+func (r *MemoryGoogleCalendarRepository) UpdateSyncState(ctx context.Context, userID, calendarID string, syncToken *string, syncedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, channel := range r.channels {
+		if channel.UserID == userID && channel.CalendarID == calendarID {
+			channel.SyncToken = syncToken
+			channel.LastSyncedAt = &syncedAt
+			channel.UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (r *MemoryGoogleCalendarRepository) filter(keep func(*models.GoogleCalendarChannel) bool) []*models.GoogleCalendarChannel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var channels []*models.GoogleCalendarChannel
+	for _, channel := range r.channels {
+		if keep(channel) {
+			copied := *channel
+			channels = append(channels, &copied)
+		}
+	}
+	return channels
+}
+
+// MemoryJobOutboxRepository is an in-memory JobOutboxRepository
+type MemoryJobOutboxRepository struct {
+	mu      sync.Mutex
+	entries map[string]*models.JobOutboxEntry
+}
+
+// NewMemoryJobOutboxRepository creates an empty in-memory job outbox
+func NewMemoryJobOutboxRepository() *MemoryJobOutboxRepository {
+	return &MemoryJobOutboxRepository{entries: map[string]*models.JobOutboxEntry{}}
+}
+
+func (r *MemoryJobOutboxRepository) Add(ctx context.Context, entry *models.JobOutboxEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.entries[entry.JobID]; ok {
+		existing.LastError = entry.LastError
+		return nil
+	}
+	copied := *entry
+	r.entries[entry.JobID] = &copied
+	return nil
+}
+
+func (r *MemoryJobOutboxRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.JobOutboxEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*models.JobOutboxEntry
+	for _, entry := range r.entries {
+		if !entry.NextAttemptAt.After(now) {
+			due = append(due, entry)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*models.JobOutboxEntry, len(due))
+	for i, entry := range due {
+		entry.NextAttemptAt = now.Add(lease)
+		copied := *entry
+		claimed[i] = &copied
+	}
+	return claimed, nil
+}
+
+func (r *MemoryJobOutboxRepository) Delete(ctx context.Context, jobID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, jobID)
+	return nil
+}
+
+func (r *MemoryJobOutboxRepository) RecordFailure(ctx context.Context, jobID, message string, nextAttempt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.entries[jobID]; ok {
+		entry.Attempts++
+		entry.LastError = &message
+		entry.NextAttemptAt = nextAttempt
+	}
+	return nil
+}
+
+func (r *MemoryJobOutboxRepository) Count(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries), nil
+}
+
+// MemoryJobQuotaRepository is an in-memory JobQuotaRepository
+type MemoryJobQuotaRepository struct {
+	mu     sync.Mutex
+	quotas map[string]*models.JobQuota
+}
+
+// NewMemoryJobQuotaRepository creates an empty in-memory job quota repository
+func NewMemoryJobQuotaRepository() *MemoryJobQuotaRepository {
+	return &MemoryJobQuotaRepository{quotas: map[string]*models.JobQuota{}}
+}
+
+func (r *MemoryJobQuotaRepository) Get(ctx context.Context, userID string) (*models.JobQuota, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	quota, ok := r.quotas[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *quota
+	return &copied, nil
+}
+
+func (r *MemoryJobQuotaRepository) Put(ctx context.Context, quota *models.JobQuota) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *quota
+	r.quotas[quota.UserID] = &copied
+	return nil
+}
+
+func (r *MemoryJobQuotaRepository) Delete(ctx context.Context, userID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.quotas[userID]
+	delete(r.quotas, userID)
+	return ok, nil
+}
+
+// MemoryTravelProfileRepository is an in-memory TravelProfileRepository
+type MemoryTravelProfileRepository struct {
+	mu       sync.Mutex
+	profiles map[string]*models.TravelProfile
+}
+
+// NewMemoryTravelProfileRepository creates an empty in-memory travel profile repository
+func NewMemoryTravelProfileRepository() *MemoryTravelProfileRepository {
+	return &MemoryTravelProfileRepository{profiles: map[string]*models.TravelProfile{}}
+}
+
+func (r *MemoryTravelProfileRepository) Get(ctx context.Context, userID string) (*models.TravelProfile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	profile, ok := r.profiles[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *profile
+	copied.Modes = append([]models.TravelMode{}, profile.Modes...)
+	return &copied, nil
+}
+
+func (r *MemoryTravelProfileRepository) Put(ctx context.Context, profile *models.TravelProfile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *profile
+	copied.Modes = append([]models.TravelMode{}, profile.Modes...)
+	r.profiles[profile.UserID] = &copied
+	return nil
+}
+
+// MemoryPreferenceFeedbackRepository is an in-memory PreferenceFeedbackRepository
+type MemoryPreferenceFeedbackRepository struct {
+	mu       sync.Mutex
+	feedback map[string]map[string]*models.PreferenceFeedback
+}
+
+// NewMemoryPreferenceFeedbackRepository creates an empty in-memory preference feedback
+// repository
+func NewMemoryPreferenceFeedbackRepository() *MemoryPreferenceFeedbackRepository {
+	return &MemoryPreferenceFeedbackRepository{feedback: map[string]map[string]*models.PreferenceFeedback{}}
+}
+
+func (r *MemoryPreferenceFeedbackRepository) List(ctx context.Context, userID string) ([]*models.PreferenceFeedback, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feedback := []*models.PreferenceFeedback{}
+	for _, fb := range r.feedback[userID] {
+		copied := *fb
+		feedback = append(feedback, &copied)
+	}
+	sort.Slice(feedback, func(i, j int) bool { return feedback[i].Key < feedback[j].Key })
+	return feedback, nil
+}
+
+func (r *MemoryPreferenceFeedbackRepository) Put(ctx context.Context, fb *models.PreferenceFeedback) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.feedback[fb.UserID] == nil {
+		r.feedback[fb.UserID] = map[string]*models.PreferenceFeedback{}
+	}
+	copied := *fb
+	r.feedback[fb.UserID][fb.Key] = &copied
+	return nil
+}
+
+func (r *MemoryPreferenceFeedbackRepository) Delete(ctx context.Context, userID, key string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.feedback[userID][key]
+	delete(r.feedback[userID], key)
+	return ok, nil
+}
+
+// MemoryNotificationSettingsRepository is an in-memory NotificationSettingsRepository
+type MemoryNotificationSettingsRepository struct {
+	mu       sync.Mutex
+	settings map[string]*models.NotificationSettings
+}
+
+// NewMemoryNotificationSettingsRepository creates an empty in-memory notification
+// settings repository
+func NewMemoryNotificationSettingsRepository() *MemoryNotificationSettingsRepository {
+	return &MemoryNotificationSettingsRepository{settings: map[string]*models.NotificationSettings{}}
+}
+
+func (r *MemoryNotificationSettingsRepository) Get(ctx context.Context, userID string) (*models.NotificationSettings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	settings, ok := r.settings[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *settings
+	return &copied, nil
+}
+
+func (r *MemoryNotificationSettingsRepository) Put(ctx context.Context, settings *models.NotificationSettings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	copied := *settings
+	copied.LastDigestWeek, copied.UpdatedAt = nil, &now
+	copied.ReminderChannels = append([]models.ReminderChannel{}, settings.ReminderChannels...)
+	if current, ok := r.settings[settings.UserID]; ok {
+		copied.LastDigestWeek = current.LastDigestWeek
+	}
+	r.settings[settings.UserID] = &copied
+	return nil
+}
+
+func (r *MemoryNotificationSettingsRepository) ListDigestSubscribers(ctx context.Context) ([]*models.NotificationSettings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var subscribers []*models.NotificationSettings
+	for _, settings := range r.settings {
+		if settings.WeeklyDigest {
+			copied := *settings
+			subscribers = append(subscribers, &copied)
+		}
+	}
+	sort.Slice(subscribers, func(i, j int) bool { return subscribers[i].UserID < subscribers[j].UserID })
+	return subscribers, nil
+}
+
+func (r *MemoryNotificationSettingsRepository) ClaimDigest(ctx context.Context, userID, week string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	settings, ok := r.settings[userID]
+	if !ok || !settings.WeeklyDigest || (settings.LastDigestWeek != nil && *settings.LastDigestWeek >= week) {
+		return false, nil
+	}
+	settings.LastDigestWeek = &week
+	return true, nil
+}
+
+func (r *MemoryNotificationSettingsRepository) ReleaseDigest(ctx context.Context, userID, week string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if settings, ok := r.settings[userID]; ok && settings.LastDigestWeek != nil && *settings.LastDigestWeek == week {
+		settings.LastDigestWeek = nil
+	}
+	return nil
+}
+
+// MemoryTenantRepository is an in-memory TenantRepository holding the default tenant
+type MemoryTenantRepository struct {
+	mu      sync.Mutex
+	tenants map[string]*models.Tenant
+	// scimTokens maps SCIM token hashes to tenant IDs
+	scimTokens map[string]string
+}
+
+// NewMemoryTenantRepository creates a tenant repository with only the default tenant
+func NewMemoryTenantRepository() *MemoryTenantRepository {
+	return &MemoryTenantRepository{tenants: map[string]*models.Tenant{
+		tenant.DefaultID: {ID: tenant.DefaultID, Name: "Default", CreatedAt: time.Now()},
+	}, scimTokens: map[string]string{}}
+}
+
+func (r *MemoryTenantRepository) Get(ctx context.Context, id string) (*models.Tenant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tenants[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *t
+	return &copied, nil
+}
+
+func (r *MemoryTenantRepository) List(ctx context.Context) ([]*models.Tenant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenants := make([]*models.Tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		copied := *t
+		tenants = append(tenants, &copied)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants, nil
+}
+
+func (r *MemoryTenantRepository) Put(ctx context.Context, t *models.Tenant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.tenants[t.ID]; ok {
+		t.CreatedAt, t.OfficeDaysPerWeek, t.SCIMEnabled = existing.CreatedAt, existing.OfficeDaysPerWeek, existing.SCIMEnabled
+	} else if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	copied := *t
+	r.tenants[t.ID] = &copied
+	return nil
+}
+
+func (r *MemoryTenantRepository) SetOfficeDayPolicy(ctx context.Context, id string, officeDaysPerWeek *int) (*models.Tenant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tenants[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	t.OfficeDaysPerWeek = officeDaysPerWeek
+	copied := *t
+	return &copied, nil
+}
+
+func (r *MemoryTenantRepository) SetSCIMTokenHash(ctx context.Context, id string, tokenHash *string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tenants[id]
+	if !ok {
+		return ErrNotFound
+	}
+	for hash, tenantID := range r.scimTokens {
+		if tenantID == id {
+			delete(r.scimTokens, hash)
+		}
+	}
+	if tokenHash != nil {
+		r.scimTokens[*tokenHash] = id
+	}
+	t.SCIMEnabled = tokenHash != nil
+	return nil
+}
+
+func (r *MemoryTenantRepository) GetBySCIMTokenHash(ctx context.Context, tokenHash string) (*models.Tenant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tenants[r.scimTokens[tokenHash]]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *t
+	return &copied, nil
+}
+
+// MemoryOfficeRepository is an in-memory OfficeRepository
+type MemoryOfficeRepository struct {
+	mu      sync.Mutex
+	offices map[string]*models.Office
+}
+
+// NewMemoryOfficeRepository creates an empty in-memory office repository
+func NewMemoryOfficeRepository() *MemoryOfficeRepository {
+	return &MemoryOfficeRepository{offices: map[string]*models.Office{}}
+}
+
+func (r *MemoryOfficeRepository) Get(ctx context.Context, id string) (*models.Office, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	office, ok := r.offices[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *office
+	return &copied, nil
+}
+
+func (r *MemoryOfficeRepository) List(ctx context.Context) ([]*models.Office, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var offices []*models.Office
+	for _, office := range r.offices {
+		copied := *office
+		offices = append(offices, &copied)
+	}
+	sort.Slice(offices, func(i, j int) bool { return offices[i].Name < offices[j].Name })
+	return offices, nil
+}
+
+func (r *MemoryOfficeRepository) Create(ctx context.Context, office *models.Office) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if office.ID == "" {
+		office.ID = uuid.New().String()
+	}
+	if office.Amenities == nil {
+		office.Amenities = []string{}
+	}
+	office.CreatedAt = time.Now()
+	office.UpdatedAt = office.CreatedAt
+	copied := *office
+	r.offices[office.ID] = &copied
+	return nil
+}
+
+func (r *MemoryOfficeRepository) Update(ctx context.Context, id string, input OfficeUpdate) (*models.Office, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	office, ok := r.offices[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if input.Name != nil {
+		office.Name = *input.Name
+	}
+	if input.Address != nil {
+		office.Address = input.Address
+	}
+	if input.Latitude != nil {
+		office.Latitude = input.Latitude
+	}
+	if input.Longitude != nil {
+		office.Longitude = input.Longitude
+	}
+	if input.Amenities != nil {
+		office.Amenities = input.Amenities
+	}
+	if input.Capacity != nil {
+		office.Capacity = input.Capacity
+	}
+	if input.ParkingCost != nil {
+		office.ParkingCost = input.ParkingCost
+	}
+	if input.CongestionCharge != nil {
+		office.CongestionCharge = input.CongestionCharge
+	}
+	office.UpdatedAt = time.Now()
+	copied := *office
+	return &copied, nil
+}
+
+func (r *MemoryOfficeRepository) Delete(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.offices[id]
+	delete(r.offices, id)
+	return ok, nil
+}
+
+// MemoryGeocodeCacheRepository is an in-memory GeocodeCacheRepository
+type MemoryGeocodeCacheRepository struct {
+	mu      sync.Mutex
+	entries map[string]*models.GeocodeCacheEntry
+}
+
+// NewMemoryGeocodeCacheRepository creates an empty in-memory geocode cache
+func NewMemoryGeocodeCacheRepository() *MemoryGeocodeCacheRepository {
+	return &MemoryGeocodeCacheRepository{entries: map[string]*models.GeocodeCacheEntry{}}
+}
+
+func (r *MemoryGeocodeCacheRepository) Get(ctx context.Context, addressKey string) (*models.GeocodeCacheEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[addressKey]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *entry
+	return &copied, nil
+}
+
+func (r *MemoryGeocodeCacheRepository) Put(ctx context.Context, entry *models.GeocodeCacheEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.CreatedAt = time.Now()
+	copied := *entry
+	r.entries[entry.AddressKey] = &copied
+	return nil
+}
+
+// sameLocation compares two optional locations
+func sameLocation(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// MemoryRetentionRepository is an in-memory RetentionRepository over the in-memory job
+// and recommendation repositories
+type MemoryRetentionRepository struct {
+	mu              sync.Mutex
+	jobs            *MemoryJobRepository
+	recommendations *MemoryRecommendationRepository
+	archive         map[string]*models.ArchivedJob
+	runs            []*models.RetentionRun
+}
+
+// NewMemoryRetentionRepository creates an empty in-memory retention repository
+func NewMemoryRetentionRepository(jobs *MemoryJobRepository, recommendations *MemoryRecommendationRepository) *MemoryRetentionRepository {
+	return &MemoryRetentionRepository{jobs: jobs, recommendations: recommendations, archive: map[string]*models.ArchivedJob{}}
+}
+
+func (r *MemoryRetentionRepository) Archive(ctx context.Context, jobID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, err := r.jobs.Get(ctx, jobID)
+	if err != nil {
+		return 0, err
+	}
+	recommendations, err := r.recommendations.ListByJob(ctx, jobID)
+	if err != nil {
+		return 0, err
+	}
+	events, err := r.jobs.Events(ctx, jobID)
+	if err != nil {
+		return 0, err
+	}
+	if recommendations == nil {
+		recommendations = []*models.CommuteRecommendation{}
+	}
+	r.archive[jobID] = &models.ArchivedJob{Job: *job, Recommendations: recommendations, Events: events, ArchivedAt: time.Now()}
+	r.recommendations.deleteByJob(jobID)
+	_, err = r.jobs.Delete(ctx, jobID)
+	return len(recommendations), err
+}
+
+func (r *MemoryRetentionRepository) GetArchived(ctx context.Context, jobID string) (*models.ArchivedJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	archived, ok := r.archive[jobID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *archived
+	return &copied, nil
+}
+
+func (r *MemoryRetentionRepository) CreateRun(ctx context.Context, run *models.RetentionRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *run
+	r.runs = append(r.runs, &copied)
+	return nil
+}
+
+func (r *MemoryRetentionRepository) UpdateRun(ctx context.Context, run *models.RetentionRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.runs {
+		if existing.ID == run.ID {
+			copied := *run
+			r.runs[i] = &copied
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryRetentionRepository) GetRun(ctx context.Context, id string) (*models.RetentionRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, run := range r.runs {
+		if run.ID == id {
+			copied := *run
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryRetentionRepository) ListRuns(ctx context.Context, limit int) ([]*models.RetentionRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var runs []*models.RetentionRun
+	for i := len(r.runs) - 1; i >= 0 && len(runs) < limit; i-- {
+		copied := *r.runs[i]
+		runs = append(runs, &copied)
+	}
+	return runs, nil
+}
+
+// MemoryArtifactRepository is an in-memory ArtifactRepository
+type MemoryArtifactRepository struct {
+	mu        sync.Mutex
+	artifacts []*models.JobArtifact
+}
+
+// NewMemoryArtifactRepository creates an empty in-memory artifact repository
+func NewMemoryArtifactRepository() *MemoryArtifactRepository {
+	return &MemoryArtifactRepository{}
+}
+
+func (r *MemoryArtifactRepository) Create(ctx context.Context, artifact *models.JobArtifact) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if artifact.ID == "" {
+		artifact.ID = uuid.New().String()
+	}
+	artifact.CreatedAt = time.Now()
+	copied := *artifact
+	r.artifacts = append(r.artifacts, &copied)
+	return nil
+}
+
+func (r *MemoryArtifactRepository) ListByJob(ctx context.Context, jobID string) ([]*models.JobArtifact, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var artifacts []*models.JobArtifact
+	for _, artifact := range r.artifacts {
+		if artifact.JobID == jobID {
+			copied := *artifact
+			artifacts = append(artifacts, &copied)
+		}
+	}
+	return artifacts, nil
+}
+
+// MemoryShareLinkRepository is an in-memory ShareLinkRepository
+type MemoryShareLinkRepository struct {
+	mu    sync.Mutex
+	links []*models.ShareLink
+}
+
+// NewMemoryShareLinkRepository creates an empty in-memory share link repository
+func NewMemoryShareLinkRepository() *MemoryShareLinkRepository {
+	return &MemoryShareLinkRepository{}
+}
+
+func (r *MemoryShareLinkRepository) Create(ctx context.Context, link *models.ShareLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if link.ID == "" {
+		link.ID = uuid.New().String()
+	}
+	link.CreatedAt = time.Now()
+	copied := *link
+	r.links = append(r.links, &copied)
+	return nil
+}
+
+func (r *MemoryShareLinkRepository) Get(ctx context.Context, id string) (*models.ShareLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, link := range r.links {
+		if link.ID == id {
+			copied := *link
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryShareLinkRepository) ListByJob(ctx context.Context, jobID string) ([]*models.ShareLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var links []*models.ShareLink
+	for i := len(r.links) - 1; i >= 0; i-- {
+		if r.links[i].JobID == jobID {
+			copied := *r.links[i]
+			links = append(links, &copied)
+		}
+	}
+	return links, nil
+}
+
+func (r *MemoryShareLinkRepository) Revoke(ctx context.Context, userID, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, link := range r.links {
+		if link.ID == id && link.UserID == userID {
+			if link.RevokedAt == nil {
+				now := time.Now()
+				link.RevokedAt = &now
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MemoryCalendarFeedRepository is an in-memory CalendarFeedRepository
+type MemoryCalendarFeedRepository struct {
+	mu    sync.Mutex
+	feeds map[string]*models.CalendarFeed
+	// tokens maps each feed's token hash to its user
+	tokens map[string]string
+}
+
+// NewMemoryCalendarFeedRepository creates an empty in-memory calendar feed repository
+func NewMemoryCalendarFeedRepository() *MemoryCalendarFeedRepository {
+	return &MemoryCalendarFeedRepository{feeds: map[string]*models.CalendarFeed{}, tokens: map[string]string{}}
+}
+
+func (r *MemoryCalendarFeedRepository) Put(ctx context.Context, userID, tokenHash string) (*models.CalendarFeed, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, user := range r.tokens {
+		if user == userID {
+			delete(r.tokens, hash)
+		}
+	}
+	feed := &models.CalendarFeed{UserID: userID, CreatedAt: time.Now()}
+	r.feeds[userID], r.tokens[tokenHash] = feed, userID
+	copied := *feed
+	return &copied, nil
+}
+
+func (r *MemoryCalendarFeedRepository) UserByToken(ctx context.Context, tokenHash string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID, ok := r.tokens[tokenHash]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return userID, nil
+}
+
+func (r *MemoryCalendarFeedRepository) Delete(ctx context.Context, userID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.feeds[userID]; !ok {
+		return false, nil
+	}
+	delete(r.feeds, userID)
+	for hash, user := range r.tokens {
+		if user == userID {
+			delete(r.tokens, hash)
+		}
+	}
+	return true, nil
+}
+
+// MemoryCommuteReminderRepository is an in-memory CommuteReminderRepository
+type MemoryCommuteReminderRepository struct {
+	mu        sync.Mutex
+	reminders map[string]*models.CommuteReminder
+}
+
+// NewMemoryCommuteReminderRepository creates an empty in-memory commute reminder repository
+func NewMemoryCommuteReminderRepository() *MemoryCommuteReminderRepository {
+	return &MemoryCommuteReminderRepository{reminders: map[string]*models.CommuteReminder{}}
+}
+
+func (r *MemoryCommuteReminderRepository) Replace(ctx context.Context, userID, targetDate string, reminders []*models.CommuteReminder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, reminder := range r.reminders {
+		if reminder.UserID == userID && reminder.TargetDate == targetDate && reminder.Status == models.CommuteReminderScheduled {
+			reminder.Status = models.CommuteReminderCancelled
+		}
+	}
+	now := time.Now()
+	for _, reminder := range reminders {
+		if reminder.ID == "" {
+			reminder.ID = uuid.New().String()
+		}
+		reminder.UserID, reminder.TargetDate, reminder.Status = userID, targetDate, models.CommuteReminderScheduled
+		reminder.CreatedAt = now
+		copied := *reminder
+		copied.Channels = append([]models.ReminderChannel{}, reminder.Channels...)
+		r.reminders[reminder.ID] = &copied
+	}
+	return nil
+}
+
+func (r *MemoryCommuteReminderRepository) CancelScheduled(ctx context.Context, userID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, reminder := range r.reminders {
+		if reminder.UserID == userID && reminder.Status == models.CommuteReminderScheduled {
+			reminder.Status = models.CommuteReminderCancelled
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *MemoryCommuteReminderRepository) Cancel(ctx context.Context, userID, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reminder, ok := r.reminders[id]
+	if !ok || reminder.UserID != userID || reminder.Status != models.CommuteReminderScheduled {
+		return false, nil
+	}
+	reminder.Status = models.CommuteReminderCancelled
+	return true, nil
+}
+
+func (r *MemoryCommuteReminderRepository) ListUpcoming(ctx context.Context, userID string, since time.Time) ([]*models.CommuteReminder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reminders := []*models.CommuteReminder{}
+	for _, reminder := range r.reminders {
+		if reminder.UserID == userID && !reminder.DepartAt.Before(since) {
+			copied := *reminder
+			reminders = append(reminders, &copied)
+		}
+	}
+	sortCommuteReminders(reminders, func(reminder *models.CommuteReminder) time.Time { return reminder.DepartAt })
+	return reminders, nil
+}
+
+func (r *MemoryCommuteReminderRepository) ClaimDue(ctx context.Context, now time.Time, limit int) ([]*models.CommuteReminder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*models.CommuteReminder
+	for _, reminder := range r.reminders {
+		if reminder.Status == models.CommuteReminderScheduled && !reminder.RemindAt.After(now) && reminder.DepartAt.After(now) {
+			due = append(due, reminder)
+		}
+	}
+	sortCommuteReminders(due, func(reminder *models.CommuteReminder) time.Time { return reminder.RemindAt })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := []*models.CommuteReminder{}
+	for _, reminder := range due {
+		sentAt := now
+		reminder.Status, reminder.SentAt = models.CommuteReminderSent, &sentAt
+		copied := *reminder
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (r *MemoryCommuteReminderRepository) Fail(ctx context.Context, id, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if reminder, ok := r.reminders[id]; ok {
+		reminder.Status, reminder.ErrorMessage, reminder.SentAt = models.CommuteReminderFailed, &message, nil
+	}
+	return nil
+}
+
+func (r *MemoryCommuteReminderRepository) CancelMissed(ctx context.Context, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, reminder := range r.reminders {
+		if reminder.Status == models.CommuteReminderScheduled && !reminder.DepartAt.After(now) {
+			reminder.Status = models.CommuteReminderCancelled
+			n++
+		}
+	}
+	return n, nil
+}
+
+// sortCommuteReminders orders reminders by the time at returns, then by ID
+func sortCommuteReminders(reminders []*models.CommuteReminder, at func(*models.CommuteReminder) time.Time) {
+	sort.Slice(reminders, func(i, j int) bool {
+		if !at(reminders[i]).Equal(at(reminders[j])) {
+			return at(reminders[i]).Before(at(reminders[j]))
+		}
+		return reminders[i].ID < reminders[j].ID
+	})
+}
+
+// MemoryDeprecationUsageRepository is an in-memory DeprecationUsageRepository
+type MemoryDeprecationUsageRepository struct {
+	mu    sync.Mutex
+	daily map[string][]*models.DeprecatedFieldUsage
+}
+
+// NewMemoryDeprecationUsageRepository creates an empty in-memory deprecated field usage
+// repository
+func NewMemoryDeprecationUsageRepository() *MemoryDeprecationUsageRepository {
+	return &MemoryDeprecationUsageRepository{daily: map[string][]*models.DeprecatedFieldUsage{}}
+}
+
+func (r *MemoryDeprecationUsageRepository) Record(ctx context.Context, usage []*models.DeprecatedFieldUsage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range usage {
+		day := u.LastUsedAt.UTC().Format("2006-01-02")
+		copied := *u
+		r.daily[day] = sumDeprecatedFieldUsage(append(r.daily[day], &copied))
+	}
+	return nil
+}
+
+func (r *MemoryDeprecationUsageRepository) List(ctx context.Context, since time.Time) ([]*models.DeprecatedFieldUsage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var days []string
+	for day := range r.daily {
+		if day >= since.UTC().Format("2006-01-02") {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	var daily []*models.DeprecatedFieldUsage
+	for _, day := range days {
+		daily = append(daily, r.daily[day]...)
+	}
+	usage := sumDeprecatedFieldUsage(daily)
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if a.Coordinate != b.Coordinate {
+			return a.Coordinate < b.Coordinate
+		}
+		if a.ClientName != b.ClientName {
+			return a.ClientName < b.ClientName
+		}
+		return a.ClientVersion < b.ClientVersion
+	})
+	return usage, nil
+}
+
+// MemoryClientUsageRepository is an in-memory ClientUsageRepository
+type MemoryClientUsageRepository struct {
+	mu    sync.Mutex
+	daily map[string][]*models.ClientOperationUsage
+}
+
+// NewMemoryClientUsageRepository creates an empty in-memory client usage repository
+func NewMemoryClientUsageRepository() *MemoryClientUsageRepository {
+	return &MemoryClientUsageRepository{daily: map[string][]*models.ClientOperationUsage{}}
+}
+
+func (r *MemoryClientUsageRepository) Record(ctx context.Context, usage []*models.ClientOperationUsage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range usage {
+		day := u.LastUsedAt.UTC().Format("2006-01-02")
+		copied := *u
+		r.daily[day] = sumClientOperationUsage(append(r.daily[day], &copied))
+	}
+	return nil
+}
+
+func (r *MemoryClientUsageRepository) List(ctx context.Context, since time.Time) ([]*models.ClientOperationUsage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var days []string
+	for day := range r.daily {
+		if day >= since.UTC().Format("2006-01-02") {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	var daily []*models.ClientOperationUsage
+	for _, day := range days {
+		daily = append(daily, r.daily[day]...)
+	}
+	usage := sumClientOperationUsage(daily)
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if a.ClientName != b.ClientName {
+			return a.ClientName < b.ClientName
+		}
+		if a.ClientVersion != b.ClientVersion {
+			return a.ClientVersion < b.ClientVersion
+		}
+		return a.Operation < b.Operation
+	})
+	return usage, nil
+}
+
+// MemoryAnalyticsEventRepository is an in-memory AnalyticsEventRepository
+type MemoryAnalyticsEventRepository struct {
+	mu     sync.Mutex
+	events []*models.AnalyticsEvent
+}
+
+// NewMemoryAnalyticsEventRepository creates an empty in-memory analytics event repository
+func NewMemoryAnalyticsEventRepository() *MemoryAnalyticsEventRepository {
+	return &MemoryAnalyticsEventRepository{}
+}
+
+func (r *MemoryAnalyticsEventRepository) Create(ctx context.Context, events []*models.AnalyticsEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range events {
+		if event.ID == "" {
+			event.ID = uuid.New().String()
+		}
+		event.CreatedAt = time.Now()
+		copied := *event
+		r.events = append(r.events, &copied)
+	}
+	return nil
+}
+
+func (r *MemoryAnalyticsEventRepository) ListUnforwarded(ctx context.Context, limit int) ([]*models.AnalyticsEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := []*models.AnalyticsEvent{}
+	for _, event := range r.events {
+		if event.ForwardedAt == nil {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].OccurredAt.Before(events[j].OccurredAt) })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (r *MemoryAnalyticsEventRepository) MarkForwarded(ctx context.Context, ids []string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	forwarded := map[string]bool{}
+	for _, id := range ids {
+		forwarded[id] = true
+	}
+	for _, event := range r.events {
+		if forwarded[event.ID] {
+			forwardedAt := at
+			event.ForwardedAt = &forwardedAt
+		}
+	}
+	return nil
+}
+
+// MemoryCalendarImportRepository is an in-memory CalendarImportRepository
+type MemoryCalendarImportRepository struct {
+	mu      sync.Mutex
+	imports map[string]*models.CalendarImport
+}
+
+// NewMemoryCalendarImportRepository creates an empty in-memory calendar import repository
+func NewMemoryCalendarImportRepository() *MemoryCalendarImportRepository {
+	return &MemoryCalendarImportRepository{imports: map[string]*models.CalendarImport{}}
+}
+
+func (r *MemoryCalendarImportRepository) Create(ctx context.Context, imp *models.CalendarImport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if imp.ID == "" {
+		imp.ID = uuid.New().String()
+	}
+	if imp.Errors == nil {
+		imp.Errors = []models.ImportError{}
+	}
+	copied := *imp
+	copied.Errors = append([]models.ImportError{}, imp.Errors...)
+	r.imports[imp.ID] = &copied
+	return nil
+}
+
+func (r *MemoryCalendarImportRepository) Update(ctx context.Context, imp *models.CalendarImport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.imports[imp.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Status, stored.ProcessedEvents, stored.CreatedEvents = imp.Status, imp.ProcessedEvents, imp.CreatedEvents
+	stored.Errors = append([]models.ImportError{}, imp.Errors...)
+	stored.ErrorMessage, stored.FinishedAt = imp.ErrorMessage, imp.FinishedAt
+	return nil
+}
+
+func (r *MemoryCalendarImportRepository) Get(ctx context.Context, userID, id string) (*models.CalendarImport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	imp, ok := r.imports[id]
+	if !ok || imp.UserID != userID {
+		return nil, ErrNotFound
+	}
+	copied := *imp
+	copied.Errors = append([]models.ImportError{}, imp.Errors...)
+	return &copied, nil
+}
+
+// MemoryAPITokenRepository is an in-memory APITokenRepository
+type MemoryAPITokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]*models.APIToken
+	// hashes maps token hashes to token IDs
+	hashes map[string]string
+}
+
+// NewMemoryAPITokenRepository creates an empty in-memory API token repository
+func NewMemoryAPITokenRepository() *MemoryAPITokenRepository {
+	return &MemoryAPITokenRepository{tokens: map[string]*models.APIToken{}, hashes: map[string]string{}}
+}
+
+func (r *MemoryAPITokenRepository) Create(ctx context.Context, token *models.APIToken, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token.ID == "" {
+		token.ID = uuid.New().String()
+	}
+	token.CreatedAt = time.Now()
+	copied := *token
+	copied.Token = ""
+	copied.Scopes = append([]models.APITokenScope{}, token.Scopes...)
+	r.tokens[token.ID] = &copied
+	r.hashes[tokenHash] = token.ID
+	return nil
+}
+
+func (r *MemoryAPITokenRepository) ListByUser(ctx context.Context, userID string) ([]*models.APIToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var tokens []*models.APIToken
+	for _, token := range r.tokens {
+		if token.UserID == userID {
+			copied := *token
+			tokens = append(tokens, &copied)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+func (r *MemoryAPITokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[r.hashes[tokenHash]]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (r *MemoryAPITokenRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token, ok := r.tokens[id]; ok {
+		token.LastUsedAt = &at
+	}
+	return nil
+}
+
+func (r *MemoryAPITokenRepository) Delete(ctx context.Context, userID, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UserID != userID {
+		return false, nil
+	}
+	delete(r.tokens, id)
+	for hash, tokenID := range r.hashes {
+		if tokenID == id {
+			delete(r.hashes, hash)
+		}
+	}
+	return true, nil
+}
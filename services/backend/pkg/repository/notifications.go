@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// notificationSettingsColumns is the column list scanned by scanNotificationSettings
+var notificationSettingsColumns = []string{"user_id", "weekly_digest", "last_digest_week", "commute_reminders", "reminder_lead_minutes", "reminder_channels", "updated_at"}
+
+// SQLNotificationSettingsRepository stores per-user notification opt-ins
+type SQLNotificationSettingsRepository struct {
+	db *database.DB
+}
+
+// NewSQLNotificationSettingsRepository creates a notification settings repository
+func NewSQLNotificationSettingsRepository(db *database.DB) *SQLNotificationSettingsRepository {
+	return &SQLNotificationSettingsRepository{db: db}
+}
+
+// Get returns a user's settings, or ErrNotFound if they haven't saved any
+func (r *SQLNotificationSettingsRepository) Get(ctx context.Context, userID string) (*models.NotificationSettings, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID})
+	query := `SELECT ` + strings.Join(notificationSettingsColumns, ", ") + ` FROM notification_settings WHERE user_id = $1` + scope
+	settings, err := scanNotificationSettings(r.db.Reader().QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return settings, err
+}
+
+// Put creates or replaces a user's opt-ins, keeping the record of digests sent;
+// ErrNotFound if the user is in another tenant
+func (r *SQLNotificationSettingsRepository) Put(ctx context.Context, settings *models.NotificationSettings) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if err := checkUserTenant(ctx, r.db, settings.UserID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO notification_settings (user_id, weekly_digest, commute_reminders, reminder_lead_minutes, reminder_channels, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          ON CONFLICT (user_id) DO UPDATE SET
+	              weekly_digest = excluded.weekly_digest,
+	              commute_reminders = excluded.commute_reminders,
+	              reminder_lead_minutes = excluded.reminder_lead_minutes,
+	              reminder_channels = excluded.reminder_channels,
+	              updated_at = excluded.updated_at`
+	_, err := r.db.ExecContext(ctx, query, settings.UserID, settings.WeeklyDigest, settings.CommuteReminders,
+		settings.ReminderLeadMinutes, reminderChannelArray(settings.ReminderChannels), settings.UpdatedAt)
+	return err
+}
+
+// ListDigestSubscribers returns the settings of every user who opted into the weekly
+// digest
+func (r *SQLNotificationSettingsRepository) ListDigestSubscribers(ctx context.Context) ([]*models.NotificationSettings, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", nil)
+	query := `SELECT ` + strings.Join(notificationSettingsColumns, ", ") + ` FROM notification_settings WHERE weekly_digest` + scope + ` ORDER BY user_id`
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscribers []*models.NotificationSettings
+	for rows.Next() {
+		settings, err := scanNotificationSettings(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, settings)
+	}
+	return subscribers, rows.Err()
+}
+
+// ClaimDigest records that the digest of week (its Monday, YYYY-MM-DD) is being sent to
+// a subscriber. It returns false when that week's digest was already claimed, or the
+// user unsubscribed, so concurrent senders send it once.
+func (r *SQLNotificationSettingsRepository) ClaimDigest(ctx context.Context, userID, week string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID, week})
+	result, err := r.db.ExecContext(ctx, `UPDATE notification_settings SET last_digest_week = $2
+	          WHERE user_id = $1 AND weekly_digest AND (last_digest_week IS NULL OR last_digest_week < $2)`+scope, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ReleaseDigest undoes ClaimDigest after the digest couldn't be sent, so it is retried
+func (r *SQLNotificationSettingsRepository) ReleaseDigest(ctx context.Context, userID, week string) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID, week})
+	_, err := r.db.ExecContext(ctx, `UPDATE notification_settings SET last_digest_week = NULL
+	          WHERE user_id = $1 AND last_digest_week = $2`+scope, args...)
+	return err
+}
+
+// scanNotificationSettings scans a row selected with notificationSettingsColumns
+func scanNotificationSettings(row rowScanner) (*models.NotificationSettings, error) {
+	settings := &models.NotificationSettings{}
+	var channels pq.StringArray
+	err := row.Scan(
+		&settings.UserID,
+		&settings.WeeklyDigest,
+		&settings.LastDigestWeek,
+		&settings.CommuteReminders,
+		&settings.ReminderLeadMinutes,
+		&channels,
+		&settings.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	settings.ReminderChannels = reminderChannels(channels)
+	if settings.LastDigestWeek != nil {
+		week := dateOnly(*settings.LastDigestWeek)
+		settings.LastDigestWeek = &week
+	}
+	return settings, nil
+}
+
+// reminderChannelArray converts reminder channels to the TEXT[] they are stored as
+func reminderChannelArray(channels []models.ReminderChannel) pq.StringArray {
+	array := pq.StringArray{}
+	for _, channel := range channels {
+		array = append(array, string(channel))
+	}
+	return array
+}
+
+// reminderChannels converts stored reminder channels back
+func reminderChannels(array pq.StringArray) []models.ReminderChannel {
+	channels := []models.ReminderChannel{}
+	for _, channel := range array {
+		channels = append(channels, models.ReminderChannel(channel))
+	}
+	return channels
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/tenant"
+	"github.com/google/uuid"
+)
+
+// officeColumns is the column list scanned by scanOffice
+var officeColumns = []string{"id", "name", "address", "latitude", "longitude", "amenities", "capacity", "parking_cost", "congestion_charge", "created_at", "updated_at"}
+
+// OfficeUpdate is a partial update; nil fields are left unchanged
+type OfficeUpdate struct {
+	Name             *string
+	Address          *string
+	Latitude         *float64
+	Longitude        *float64
+	Amenities        []string
+	Capacity         *int
+	ParkingCost      *float64
+	CongestionCharge *float64
+}
+
+// SQLOfficeRepository stores the offices of the tenant ctx is scoped to
+type SQLOfficeRepository struct {
+	db *database.DB
+}
+
+// NewSQLOfficeRepository creates an office repository
+func NewSQLOfficeRepository(db *database.DB) *SQLOfficeRepository {
+	return &SQLOfficeRepository{db: db}
+}
+
+// Get returns an office, or ErrNotFound
+func (r *SQLOfficeRepository) Get(ctx context.Context, id string) (*models.Office, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	query := `SELECT ` + strings.Join(officeColumns, ", ") + ` FROM offices WHERE id = $1` + scope
+	office, err := scanOffice(r.db.Reader().QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return office, err
+}
+
+// List returns the tenant's offices ordered by name
+func (r *SQLOfficeRepository) List(ctx context.Context) ([]*models.Office, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", nil)
+	query := `SELECT ` + strings.Join(officeColumns, ", ") + ` FROM offices WHERE TRUE` + scope + ` ORDER BY name ASC`
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offices []*models.Office
+	for rows.Next() {
+		office, err := scanOffice(rows)
+		if err != nil {
+			return nil, err
+		}
+		offices = append(offices, office)
+	}
+	return offices, rows.Err()
+}
+
+// Create inserts an office into the tenant ctx is scoped to, assigning its ID if empty
+func (r *SQLOfficeRepository) Create(ctx context.Context, office *models.Office) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if office.ID == "" {
+		office.ID = uuid.New().String()
+	}
+	query := `INSERT INTO offices (id, tenant_id, name, address, latitude, longitude, amenities, capacity, parking_cost, congestion_charge)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	          RETURNING created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, office.ID, tenant.OrDefault(ctx), office.Name, office.Address,
+		office.Latitude, office.Longitude, encodeAmenities(office.Amenities), office.Capacity,
+		office.ParkingCost, office.CongestionCharge).Scan(&office.CreatedAt, &office.UpdatedAt)
+}
+
+// Update applies a partial update, or returns ErrNotFound
+func (r *SQLOfficeRepository) Update(ctx context.Context, id string, input OfficeUpdate) (*models.Office, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("offices").SetExpr("updated_at = CURRENT_TIMESTAMP")
+	if input.Name != nil {
+		b.Set("name", *input.Name)
+	}
+	if input.Address != nil {
+		b.Set("address", *input.Address)
+	}
+	if input.Latitude != nil {
+		b.Set("latitude", *input.Latitude)
+	}
+	if input.Longitude != nil {
+		b.Set("longitude", *input.Longitude)
+	}
+	if input.Amenities != nil {
+		b.Set("amenities", encodeAmenities(input.Amenities))
+	}
+	if input.Capacity != nil {
+		b.Set("capacity", *input.Capacity)
+	}
+	if input.ParkingCost != nil {
+		b.Set("parking_cost", *input.ParkingCost)
+	}
+	if input.CongestionCharge != nil {
+		b.Set("congestion_charge", *input.CongestionCharge)
+	}
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(officeColumns...).Build()
+
+	office, err := scanOffice(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return office, err
+}
+
+// Delete removes an office, reporting whether a row was deleted. Users defaulting to it
+// and recommendations planned for it keep their rows with the office cleared.
+func (r *SQLOfficeRepository) Delete(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	result, err := r.db.ExecContext(ctx, `DELETE FROM offices WHERE id = $1`+scope, args...)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// scanOffice scans a row selected with officeColumns
+func scanOffice(row rowScanner) (*models.Office, error) {
+	office := &models.Office{}
+	err := row.Scan(
+		&office.ID,
+		&office.Name,
+		&office.Address,
+		&office.Latitude,
+		&office.Longitude,
+		models.JSON(&office.Amenities),
+		&office.Capacity,
+		&office.ParkingCost,
+		&office.CongestionCharge,
+		&office.CreatedAt,
+		&office.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if office.Amenities == nil {
+		office.Amenities = []string{}
+	}
+	return office, nil
+}
+
+// encodeAmenities stores amenities as a JSON array, never null
+func encodeAmenities(amenities []string) *models.JSONColumn {
+	if amenities == nil {
+		amenities = []string{}
+	}
+	return models.JSON(amenities)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// preferenceFeedbackColumns is the column list scanned by scanPreferenceFeedback
+var preferenceFeedbackColumns = []string{"user_id", "key", "status", "time", "mode", "updated_at"}
+
+// SQLPreferenceFeedbackRepository stores users' reviews of their learned preferences
+type SQLPreferenceFeedbackRepository struct {
+	db *database.DB
+}
+
+// NewSQLPreferenceFeedbackRepository creates a preference feedback repository
+func NewSQLPreferenceFeedbackRepository(db *database.DB) *SQLPreferenceFeedbackRepository {
+	return &SQLPreferenceFeedbackRepository{db: db}
+}
+
+// List returns a user's feedback in key order
+func (r *SQLPreferenceFeedbackRepository) List(ctx context.Context, userID string) ([]*models.PreferenceFeedback, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID})
+	query := `SELECT ` + strings.Join(preferenceFeedbackColumns, ", ") + ` FROM preference_feedback WHERE user_id = $1` + scope + ` ORDER BY key`
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feedback := []*models.PreferenceFeedback{}
+	for rows.Next() {
+		fb, err := scanPreferenceFeedback(rows)
+		if err != nil {
+			return nil, err
+		}
+		feedback = append(feedback, fb)
+	}
+	return feedback, rows.Err()
+}
+
+// Put creates or replaces a user's feedback on a preference; ErrNotFound if the user is
+// in another tenant
+func (r *SQLPreferenceFeedbackRepository) Put(ctx context.Context, fb *models.PreferenceFeedback) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if err := checkUserTenant(ctx, r.db, fb.UserID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO preference_feedback (` + strings.Join(preferenceFeedbackColumns, ", ") + `)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          ON CONFLICT (user_id, key) DO UPDATE SET
+	              status = excluded.status,
+	              time = excluded.time,
+	              mode = excluded.mode,
+	              updated_at = excluded.updated_at`
+	_, err := r.db.ExecContext(ctx, query,
+		fb.UserID,
+		fb.Key,
+		fb.Status,
+		fb.Time,
+		fb.Mode,
+		fb.UpdatedAt.UTC(),
+	)
+	return err
+}
+
+// Delete removes a user's feedback on a preference, so it is learned afresh
+func (r *SQLPreferenceFeedbackRepository) Delete(ctx context.Context, userID, key string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID, key})
+	result, err := r.db.ExecContext(ctx, `DELETE FROM preference_feedback WHERE user_id = $1 AND key = $2`+scope, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// scanPreferenceFeedback scans a row selected with preferenceFeedbackColumns
+func scanPreferenceFeedback(row rowScanner) (*models.PreferenceFeedback, error) {
+	fb := &models.PreferenceFeedback{}
+	err := row.Scan(
+		&fb.UserID,
+		&fb.Key,
+		&fb.Status,
+		&fb.Time,
+		&fb.Mode,
+		&fb.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return fb, nil
+}
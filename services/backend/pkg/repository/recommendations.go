@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+// recommendationColumns is the column list scanned by scanRecommendation
+var recommendationColumns = []string{"id", "job_id", "option_rank", "option_type", "commute_start", "office_arrival", "office_departure", "commute_end", "office_duration", "office_meetings", "remote_meetings", "offsite_meetings", "travel_legs", "travel_mode", "mode_options", "business_rule_compliance", "perception_analysis", "reasoning", "trade_offs", "reason_codes", "office_id", "experiment", "variant", "accepted_at", "created_at"}
+
+// SQLRecommendationRepository reads commute recommendations from Postgres
+type SQLRecommendationRepository struct {
+	db *database.DB
+}
+
+// NewSQLRecommendationRepository creates a recommendation repository
+func NewSQLRecommendationRepository(db *database.DB) *SQLRecommendationRepository {
+	return &SQLRecommendationRepository{db: db}
+}
+
+// ListByJob returns a job's recommendations ordered by rank
+func (r *SQLRecommendationRepository) ListByJob(ctx context.Context, jobID string) ([]*models.CommuteRecommendation, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{jobID})
+	query := `SELECT ` + strings.Join(recommendationColumns, ", ") + ` FROM commute_recommendations WHERE job_id = $1` + scope + ` ORDER BY option_rank ASC`
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recommendations []*models.CommuteRecommendation
+	for rows.Next() {
+		rec, err := scanRecommendation(rows)
+		if err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, rec)
+	}
+	return recommendations, rows.Err()
+}
+
+// Create inserts a recommendation, assigning its ID if empty
+func (r *SQLRecommendationRepository) Create(ctx context.Context, rec *models.CommuteRecommendation) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if rec.ID == "" {
+		rec.ID = uuid.New().String()
+	}
+	query := `INSERT INTO commute_recommendations (id, job_id, option_rank, option_type, commute_start, office_arrival, office_departure, commute_end,
+	          office_duration, office_meetings, remote_meetings, offsite_meetings, travel_legs, travel_mode, mode_options, business_rule_compliance, perception_analysis, reasoning, trade_offs, reason_codes, office_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+	          RETURNING created_at`
+	return r.db.QueryRowContext(ctx, query, rec.ID, rec.JobID, rec.OptionRank, rec.OptionType,
+		rec.CommuteStart, rec.OfficeArrival, rec.OfficeDeparture, rec.CommuteEnd,
+		rec.OfficeDuration, rec.OfficeMeetings, rec.RemoteMeetings, rec.OffsiteMeetings, rec.TravelLegs, rec.TravelMode, rec.ModeOptions, rec.BusinessRuleCompliance,
+		rec.PerceptionAnalysis, rec.Reasoning, rec.TradeOffs, encodeReasonCodes(rec.ReasonCodes), rec.OfficeID).Scan(&rec.CreatedAt)
+}
+
+// Accept marks a recommendation as the option the user chose, or returns ErrNotFound
+func (r *SQLRecommendationRepository) Accept(ctx context.Context, userID, id string) (*models.CommuteRecommendation, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id, userID})
+	query := `UPDATE commute_recommendations SET accepted_at = CURRENT_TIMESTAMP
+	          WHERE id = $1 AND job_id IN (SELECT id FROM jobs WHERE user_id = $2)` + scope + `
+	          RETURNING ` + strings.Join(recommendationColumns, ", ")
+	rec, err := scanRecommendation(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return rec, err
+}
+
+// UpdateExplanation replaces the generated text of a recommendation
+func (r *SQLRecommendationRepository) UpdateExplanation(ctx context.Context, id, reasoning, perceptionAnalysis string) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{reasoning, perceptionAnalysis, id})
+	result, err := r.db.ExecContext(ctx, `UPDATE commute_recommendations SET reasoning = $1, perception_analysis = $2 WHERE id = $3`+scope, args...)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetRanks renumbers a job's recommendations in one transaction, or returns ErrNotFound
+// when one of ids isn't among them
+func (r *SQLRecommendationRepository) SetRanks(ctx context.Context, jobID string, ids []string) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range ids {
+		scope, args := tenantClause(ctx, "tenant_id", []interface{}{i + 1, id, jobID})
+		result, err := tx.ExecContext(ctx, `UPDATE commute_recommendations SET option_rank = $1 WHERE id = $2 AND job_id = $3`+scope, args...)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrNotFound
+		}
+	}
+	return tx.Commit()
+}
+
+// StreamByUser calls fn for each recommendation of the user's jobs targeting a day in range
+func (r *SQLRecommendationRepository) StreamByUser(ctx context.Context, userID string, dates DateRange, fn func(*models.CommuteRecommendation) error) error {
+	columns := make([]string, len(recommendationColumns))
+	for i, column := range recommendationColumns {
+		columns[i] = "r." + column
+	}
+	scope, args := tenantClause(ctx, "r.tenant_id", []interface{}{userID})
+	query := `SELECT ` + strings.Join(columns, ", ") + `, j.target_date
+	          FROM commute_recommendations r JOIN jobs j ON j.id = r.job_id
+	          WHERE j.user_id = $1` + scope
+	// target_date is a DATE; compare against YYYY-MM-DD strings, which Postgres casts and
+	// SQLite compares lexically
+	if dates.From != nil {
+		args = append(args, dates.From.Format("2006-01-02"))
+		query += fmt.Sprintf(` AND j.target_date >= $%d`, len(args))
+	}
+	if dates.To != nil {
+		args = append(args, dates.To.Format("2006-01-02"))
+		query += fmt.Sprintf(` AND j.target_date < $%d`, len(args))
+	}
+	query += ` ORDER BY j.target_date ASC, r.job_id, r.option_rank ASC`
+
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rec := &models.CommuteRecommendation{}
+		job := &models.Job{}
+		err := rows.Scan(append(recommendationFields(rec), &job.TargetDate)...)
+		if err != nil {
+			return err
+		}
+		job.ID = rec.JobID
+		job.TargetDate = dateOnly(job.TargetDate)
+		rec.Job = job
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ExperimentStats counts an experiment's jobs per variant. A job's top choice was
+// accepted when its first-ranked recommendation was.
+func (r *SQLRecommendationRepository) ExperimentStats(ctx context.Context, experiment string, dates DateRange) ([]models.VariantResult, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "j.tenant_id", []interface{}{experiment, models.JobStatusCompleted})
+	query := `SELECT j.variant, COUNT(*),
+	                 SUM(CASE WHEN j.status = $2 THEN 1 ELSE 0 END),
+	                 SUM(CASE WHEN a.job_id IS NOT NULL THEN 1 ELSE 0 END),
+	                 SUM(CASE WHEN a.top_rank = 1 THEN 1 ELSE 0 END)
+	          FROM jobs j
+	          LEFT JOIN (SELECT job_id, MIN(option_rank) AS top_rank FROM commute_recommendations
+	                     WHERE accepted_at IS NOT NULL GROUP BY job_id) a ON a.job_id = j.id
+	          WHERE j.experiment = $1 AND j.variant IS NOT NULL AND NOT j.is_demo` + scope
+	if dates.From != nil {
+		args = append(args, dates.From.Format("2006-01-02"))
+		query += fmt.Sprintf(` AND j.target_date >= $%d`, len(args))
+	}
+	if dates.To != nil {
+		args = append(args, dates.To.Format("2006-01-02"))
+		query += fmt.Sprintf(` AND j.target_date < $%d`, len(args))
+	}
+	query += ` GROUP BY j.variant ORDER BY j.variant`
+
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.VariantResult
+	for rows.Next() {
+		var result models.VariantResult
+		if err := rows.Scan(&result.Variant, &result.Jobs, &result.CompletedJobs, &result.AcceptedJobs, &result.TopChoiceAccepted); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// scanRecommendation scans a row selected with recommendationColumns
+func scanRecommendation(row rowScanner) (*models.CommuteRecommendation, error) {
+	rec := &models.CommuteRecommendation{}
+	if err := row.Scan(recommendationFields(rec)...); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// recommendationFields returns scan destinations matching recommendationColumns
+func recommendationFields(rec *models.CommuteRecommendation) []interface{} {
+	return []interface{}{
+		&rec.ID,
+		&rec.JobID,
+		&rec.OptionRank,
+		&rec.OptionType,
+		&rec.CommuteStart,
+		&rec.OfficeArrival,
+		&rec.OfficeDeparture,
+		&rec.CommuteEnd,
+		&rec.OfficeDuration,
+		&rec.OfficeMeetings,
+		&rec.RemoteMeetings,
+		&rec.OffsiteMeetings,
+		&rec.TravelLegs,
+		&rec.TravelMode,
+		&rec.ModeOptions,
+		&rec.BusinessRuleCompliance,
+		&rec.PerceptionAnalysis,
+		&rec.Reasoning,
+		&rec.TradeOffs,
+		models.JSON(&rec.ReasonCodes),
+		&rec.OfficeID,
+		&rec.Experiment,
+		&rec.Variant,
+		&rec.AcceptedAt,
+		&rec.CreatedAt,
+	}
+}
+
+// encodeReasonCodes stores reason codes as JSON, or NULL without any
+func encodeReasonCodes(codes []models.ReasonCode) interface{} {
+	if len(codes) == 0 {
+		return nil
+	}
+	return models.JSON(codes)
+}
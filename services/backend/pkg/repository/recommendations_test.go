@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSQLExperimentStats(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	jobs := NewSQLJobRepository(db)
+	recommendations := NewSQLRecommendationRepository(db)
+	user := createUser(t, ctx, db, "ada@example.com")
+
+	experiment := "ranking"
+	// Each job gets two options; accept is the rank accepted, 0 for none
+	for _, tc := range []struct {
+		variant string
+		demo    bool
+		accept  int
+	}{
+		{"control", false, 2},
+		{"control", false, 0},
+		{"ranked", false, 1},
+		{"ranked", true, 1},
+	} {
+		variant := tc.variant
+		job, err := jobs.Create(ctx, NewJob{UserID: user.ID, TargetDate: "2026-03-02", IsDemo: tc.demo, Experiment: &experiment, Variant: &variant})
+		if err != nil {
+			t.Fatal(err)
+		}
+		setStatus(t, ctx, jobs, job.ID, models.JobStatusInProgress)
+		setStatus(t, ctx, jobs, job.ID, models.JobStatusCompleted)
+		for rank := 1; rank <= 2; rank++ {
+			rec := &models.CommuteRecommendation{JobID: job.ID, OptionRank: rank, OptionType: models.CommuteOptionFullRemoteRecommended}
+			if err := recommendations.Create(ctx, rec); err != nil {
+				t.Fatal(err)
+			}
+			if rank == tc.accept {
+				// Only the job's owner accepts its recommendations
+				if _, err := recommendations.Accept(ctx, "someone-else", rec.ID); err != ErrNotFound {
+					t.Errorf("accepted by another user: err = %v, want ErrNotFound", err)
+				}
+				accepted, err := recommendations.Accept(ctx, user.ID, rec.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if accepted.Variant == nil || *accepted.Variant != variant {
+					t.Errorf("recommendation variant = %v, want the job's %s", accepted.Variant, variant)
+				}
+			}
+		}
+	}
+	// Jobs outside the experiment aren't counted
+	createJob(t, ctx, db, user.ID)
+
+	stats, err := recommendations.ExperimentStats(ctx, experiment, DateRange{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.VariantResult{
+		{Variant: "control", Jobs: 2, CompletedJobs: 2, AcceptedJobs: 1},
+		{Variant: "ranked", Jobs: 1, CompletedJobs: 1, AcceptedJobs: 1, TopChoiceAccepted: 1},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("stats = %+v, want %+v", stats, want)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("stats[%d] = %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+}
+
+func TestSQLRecommendationReasonCodes(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	recommendations := NewSQLRecommendationRepository(db)
+	user := createUser(t, ctx, db, "ada@example.com")
+	job := createJob(t, ctx, db, user.ID)
+
+	rec := &models.CommuteRecommendation{
+		JobID: job.ID, OptionRank: 1, OptionType: models.CommuteOptionFullDayOffice,
+		ReasonCodes: []models.ReasonCode{
+			{Code: "RANK_1"},
+			{Code: "FULL_DAY_OFFICE", Params: []models.ReasonParam{{Name: "officeMeetings", Value: "3"}}},
+		},
+	}
+	if err := recommendations.Create(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	// The AI service writes params as an object
+	other := &models.CommuteRecommendation{JobID: job.ID, OptionRank: 2, OptionType: models.CommuteOptionFullRemoteRecommended}
+	if err := recommendations.Create(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE commute_recommendations SET reason_codes = $1 WHERE id = $2`,
+		`[{"code": "FULL_REMOTE", "params": {"remoteMeetings": 4, "commuteMinutes": 90}}]`, other.ID); err != nil {
+		t.Fatal(err)
+	}
+	untouched := &models.CommuteRecommendation{JobID: job.ID, OptionRank: 3, OptionType: models.CommuteOptionFullRemoteRecommended}
+	if err := recommendations.Create(ctx, untouched); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := recommendations.ListByJob(ctx, job.ID)
+	if err != nil || len(stored) != 3 {
+		t.Fatalf("recommendations = %+v, %v", stored, err)
+	}
+	if codes := stored[0].ReasonCodes; len(codes) != 2 || codes[1].Param("officeMeetings") != "3" {
+		t.Errorf("reason codes = %+v", codes)
+	}
+	if codes := stored[1].ReasonCodes; len(codes) != 1 || codes[0].Param("remoteMeetings") != "4" || codes[0].Param("commuteMinutes") != "90" {
+		t.Errorf("AI service reason codes = %+v", codes)
+	}
+	if stored[2].ReasonCodes != nil {
+		t.Errorf("reason codes = %+v, want none", stored[2].ReasonCodes)
+	}
+
+	locale := "de"
+	updated, err := NewSQLUserRepository(db).SetLocale(ctx, user.ID, &locale)
+	if err != nil || updated.Locale == nil || *updated.Locale != "de" {
+		t.Errorf("user = %+v, %v, want locale de", updated, err)
+	}
+
+	mi, eur, twelve := models.DistanceUnitMi, "EUR", models.TimeFormat12Hour
+	updated, err = NewSQLUserRepository(db).SetDisplayUnits(ctx, user.ID, &mi, &eur, &twelve)
+	if err != nil || *updated.DistanceUnit != mi || *updated.Currency != eur || *updated.TimeFormat != twelve {
+		t.Errorf("user = %+v, %v, want miles, EUR and 12-hour times", updated, err)
+	}
+
+	blocks := models.ShareRedactionTimeBlocks
+	updated, err = NewSQLUserRepository(db).SetShareRedaction(ctx, user.ID, &blocks)
+	if err != nil || updated.ShareRedaction == nil || *updated.ShareRedaction != blocks {
+		t.Errorf("user = %+v, %v, want TIME_BLOCKS share redaction", updated, err)
+	}
+}
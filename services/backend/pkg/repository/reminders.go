@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+// commuteReminderColumns is the column list scanned by scanCommuteReminder
+var commuteReminderColumns = []string{"id", "user_id", "recommendation_id", "target_date", "summary", "depart_at", "remind_at", "channels", "status", "error_message", "sent_at", "created_at"}
+
+// SQLCommuteReminderRepository stores the reminders to leave scheduled for accepted plans
+type SQLCommuteReminderRepository struct {
+	db *database.DB
+}
+
+// NewSQLCommuteReminderRepository creates a commute reminder repository
+func NewSQLCommuteReminderRepository(db *database.DB) *SQLCommuteReminderRepository {
+	return &SQLCommuteReminderRepository{db: db}
+}
+
+// Replace cancels the reminders still scheduled for the user's day and schedules
+// reminders in their place, in one transaction
+func (r *SQLCommuteReminderRepository) Replace(ctx context.Context, userID, targetDate string, reminders []*models.CommuteReminder) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	return r.db.InTx(ctx, func(ctx context.Context) error {
+		_, err := r.db.ExecContext(ctx, `UPDATE commute_reminders SET status = 'CANCELLED'
+		          WHERE user_id = $1 AND target_date = $2 AND status = 'SCHEDULED'`, userID, targetDate)
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO commute_reminders (id, user_id, recommendation_id, target_date, summary, depart_at, remind_at, channels, status)
+		          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		          RETURNING created_at`
+		for _, reminder := range reminders {
+			if reminder.ID == "" {
+				reminder.ID = uuid.New().String()
+			}
+			reminder.UserID, reminder.TargetDate, reminder.Status = userID, targetDate, models.CommuteReminderScheduled
+			err := r.db.QueryRowContext(ctx, query,
+				reminder.ID,
+				reminder.UserID,
+				reminder.RecommendationID,
+				reminder.TargetDate,
+				reminder.Summary,
+				reminder.DepartAt.UTC(),
+				reminder.RemindAt.UTC(),
+				reminderChannelArray(reminder.Channels),
+				reminder.Status,
+			).Scan(&reminder.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CancelScheduled cancels every reminder still scheduled for the user, returning how many
+func (r *SQLCommuteReminderRepository) CancelScheduled(ctx context.Context, userID string) (int, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE commute_reminders SET status = 'CANCELLED'
+	          WHERE user_id = $1 AND status = 'SCHEDULED'`, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// Cancel cancels one of the user's scheduled reminders, reporting whether it was still
+// scheduled
+func (r *SQLCommuteReminderRepository) Cancel(ctx context.Context, userID, id string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE commute_reminders SET status = 'CANCELLED'
+	          WHERE id = $1 AND user_id = $2 AND status = 'SCHEDULED'`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ListUpcoming returns the user's reminders for departures from since on, soonest first
+func (r *SQLCommuteReminderRepository) ListUpcoming(ctx context.Context, userID string, since time.Time) ([]*models.CommuteReminder, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(commuteReminderColumns, ", ") + ` FROM commute_reminders
+	          WHERE user_id = $1 AND depart_at >= $2 ORDER BY depart_at ASC, id ASC`
+	rows, err := r.db.Reader().QueryContext(ctx, query, userID, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCommuteReminders(rows)
+}
+
+// ClaimDue marks up to limit scheduled reminders due at now as sent and returns them, so
+// concurrent schedulers send each once. Reminders whose departure has passed are left
+// for CancelMissed.
+func (r *SQLCommuteReminderRepository) ClaimDue(ctx context.Context, now time.Time, limit int) ([]*models.CommuteReminder, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE commute_reminders SET status = 'SENT', sent_at = $1
+	          WHERE id IN (
+	              SELECT id FROM commute_reminders
+	              WHERE status = 'SCHEDULED' AND remind_at <= $1 AND depart_at > $1
+	              ORDER BY remind_at ASC LIMIT $2
+	          ) AND status = 'SCHEDULED'
+	          RETURNING ` + strings.Join(commuteReminderColumns, ", ")
+	rows, err := r.db.QueryContext(ctx, query, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCommuteReminders(rows)
+}
+
+// Fail records that a claimed reminder couldn't be sent
+func (r *SQLCommuteReminderRepository) Fail(ctx context.Context, id, message string) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE commute_reminders SET status = 'FAILED', error_message = $2, sent_at = NULL
+	          WHERE id = $1`, id, message)
+	return err
+}
+
+// CancelMissed cancels the scheduled reminders whose departure passed before they were
+// sent, e.g. while the backend was down, returning how many
+func (r *SQLCommuteReminderRepository) CancelMissed(ctx context.Context, now time.Time) (int, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE commute_reminders SET status = 'CANCELLED'
+	          WHERE status = 'SCHEDULED' AND depart_at <= $1`, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// scanCommuteReminders scans every row of a query selecting commuteReminderColumns
+func scanCommuteReminders(rows *sql.Rows) ([]*models.CommuteReminder, error) {
+	reminders := []*models.CommuteReminder{}
+	for rows.Next() {
+		reminder, err := scanCommuteReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, rows.Err()
+}
+
+// scanCommuteReminder scans a row selected with commuteReminderColumns
+func scanCommuteReminder(row rowScanner) (*models.CommuteReminder, error) {
+	reminder := &models.CommuteReminder{}
+	var channels pq.StringArray
+	err := row.Scan(
+		&reminder.ID,
+		&reminder.UserID,
+		&reminder.RecommendationID,
+		&reminder.TargetDate,
+		&reminder.Summary,
+		&reminder.DepartAt,
+		&reminder.RemindAt,
+		&channels,
+		&reminder.Status,
+		&reminder.ErrorMessage,
+		&reminder.SentAt,
+		&reminder.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	reminder.TargetDate = dateOnly(reminder.TargetDate)
+	reminder.Channels = reminderChannels(channels)
+	return reminder, nil
+}
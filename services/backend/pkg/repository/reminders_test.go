@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSQLCommuteReminders(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	user := createUser(t, ctx, db, "ada@example.com")
+	job := createJob(t, ctx, db, user.ID)
+	rec := &models.CommuteRecommendation{JobID: job.ID, OptionRank: 1, OptionType: models.CommuteOptionFullDayOffice}
+	if err := NewSQLRecommendationRepository(db).Create(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	reminders := NewSQLCommuteReminderRepository(db)
+
+	base := time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC)
+	reminder := func(summary string, depart time.Time) *models.CommuteReminder {
+		return &models.CommuteReminder{
+			RecommendationID: rec.ID,
+			Summary:          summary,
+			DepartAt:         depart,
+			RemindAt:         depart.Add(-15 * time.Minute),
+			Channels:         []models.ReminderChannel{models.ReminderChannelEmail, models.ReminderChannelWebhook},
+		}
+	}
+	err := reminders.Replace(ctx, user.ID, "2026-03-02", []*models.CommuteReminder{
+		reminder("Leave for the office", base.Add(time.Hour)),
+		reminder("Leave for home", base.Add(9*time.Hour)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replacing the day's plan cancels its earlier reminders
+	err = reminders.Replace(ctx, user.ID, "2026-03-02", []*models.CommuteReminder{
+		reminder("Leave for the office", base.Add(2*time.Hour)),
+		reminder("Leave for home", base.Add(10*time.Hour)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upcoming, err := reminders.ListUpcoming(ctx, user.ID, base)
+	if err != nil || len(upcoming) != 4 {
+		t.Fatalf("upcoming = %+v, %v", upcoming, err)
+	}
+	statuses := map[models.CommuteReminderStatus]int{}
+	for _, r := range upcoming {
+		statuses[r.Status]++
+	}
+	if statuses[models.CommuteReminderCancelled] != 2 || statuses[models.CommuteReminderScheduled] != 2 {
+		t.Errorf("statuses = %v, want the first plan's reminders cancelled", statuses)
+	}
+	if first := upcoming[0]; first.TargetDate != "2026-03-02" || len(first.Channels) != 2 || first.Channels[1] != models.ReminderChannelWebhook {
+		t.Errorf("reminder = %+v", first)
+	}
+
+	// Due at 08:45: only the reminder to leave at 09:00
+	due, err := reminders.ClaimDue(ctx, base.Add(105*time.Minute), 10)
+	if err != nil || len(due) != 1 || due[0].Status != models.CommuteReminderSent || due[0].SentAt == nil {
+		t.Fatalf("due = %+v, %v", due, err)
+	}
+	if again, err := reminders.ClaimDue(ctx, base.Add(105*time.Minute), 10); err != nil || len(again) != 0 {
+		t.Errorf("claimed twice: %+v, %v", again, err)
+	}
+	if err := reminders.Fail(ctx, due[0].ID, "smtp down"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The evening reminder is missed once its departure passes
+	if n, err := reminders.CancelMissed(ctx, base.Add(11*time.Hour)); err != nil || n != 1 {
+		t.Errorf("missed = %d, %v", n, err)
+	}
+	if ok, err := reminders.Cancel(ctx, user.ID, due[0].ID); err != nil || ok {
+		t.Errorf("cancelled a failed reminder: %v, %v", ok, err)
+	}
+	upcoming, err = reminders.ListUpcoming(ctx, user.ID, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range upcoming {
+		if r.ID == due[0].ID && (r.Status != models.CommuteReminderFailed || r.ErrorMessage == nil || *r.ErrorMessage != "smtp down") {
+			t.Errorf("failed reminder = %+v", r)
+		}
+	}
+}
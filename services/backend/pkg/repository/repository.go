@@ -0,0 +1,500 @@
+// Package repository holds the SQL data access used by resolvers and handlers.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// ErrNotFound is returned when a lookup or update matches no rows
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when a concurrent writer changed the row first
+var ErrConflict = errors.New("concurrent update")
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// DateRange is a half-open [From, To) range of UTC days; nil bounds are open
+type DateRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// contains reports whether t falls within the range
+func (d DateRange) contains(t time.Time) bool {
+	if d.From != nil && t.Before(*d.From) {
+		return false
+	}
+	if d.To != nil && !t.Before(*d.To) {
+		return false
+	}
+	return true
+}
+
+// UserRepository stores users
+type UserRepository interface {
+	Get(ctx context.Context, id string) (*models.User, error)
+	// GetByEmail returns the tenant's user with email, or ErrNotFound
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	List(ctx context.Context) ([]*models.User, error)
+	// ListPage returns a page of the tenant's users matching filter, newest first, and
+	// how many match in all
+	ListPage(ctx context.Context, filter UserFilter) ([]*models.User, int, error)
+	Create(ctx context.Context, input NewUser) (*models.User, error)
+	// Update applies a partial update and increments the user's version; with
+	// ExpectedVersion set it fails with ErrConflict if the version has moved on
+	Update(ctx context.Context, id string, input UserUpdate) (*models.User, error)
+	// SetDefaultOffice sets or, with nil, clears the user's default office
+	SetDefaultOffice(ctx context.Context, id string, officeID *string) (*models.User, error)
+	// SetHomeAddress replaces the user's home address and its coordinates; nil clears them
+	SetHomeAddress(ctx context.Context, id string, address *string, latitude, longitude *float64) (*models.User, error)
+	// SetFocusMinutes sets or, with nil, clears the user's daily focus time
+	SetFocusMinutes(ctx context.Context, id string, minutes *int) (*models.User, error)
+	// SetActive deactivates the user, who then can't sign in, or reactivates them
+	SetActive(ctx context.Context, id string, active bool) (*models.User, error)
+	// SetOrgAdmin grants or revokes the user's org admin role
+	SetOrgAdmin(ctx context.Context, id string, orgAdmin bool) (*models.User, error)
+	// SetOrgReportingOptOut leaves the user out of, or back in, org reports
+	SetOrgReportingOptOut(ctx context.Context, id string, optOut bool) (*models.User, error)
+	// SetAnalyticsOptOut keeps the user's actions out of, or back in, product analytics
+	SetAnalyticsOptOut(ctx context.Context, id string, optOut bool) (*models.User, error)
+	// SetLocale sets or, with nil, clears the language the user's recommendation text is
+	// shown in
+	SetLocale(ctx context.Context, id string, locale *string) (*models.User, error)
+	// SetDisplayUnits sets how the user's distances, costs and times are shown; nil fields
+	// return to the defaults
+	SetDisplayUnits(ctx context.Context, id string, distanceUnit *models.DistanceUnit, currency *string, timeFormat *models.TimeFormat) (*models.User, error)
+	// SetShareRedaction sets or, with nil, clears what the user's share links leave out
+	SetShareRedaction(ctx context.Context, id string, policy *models.ShareRedaction) (*models.User, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	PreferredTimezone(ctx context.Context, id string) (string, error)
+	// OAuthTokens returns the oauth_tokens JSON stored for the user, decrypted, or nil
+	OAuthTokens(ctx context.Context, id string) (*string, error)
+}
+
+// JobRepository stores commute planning jobs
+type JobRepository interface {
+	Get(ctx context.Context, id string) (*models.Job, error)
+	List(ctx context.Context, userID *string) ([]*models.Job, error)
+	Create(ctx context.Context, input NewJob) (*models.Job, error)
+	// Update applies a partial update and increments the job's version. A status change is
+	// validated against the job's latest event and appended to its history
+	// (*models.JobTransitionError, ErrConflict). With ExpectedVersion set the update fails
+	// with ErrConflict if the version has moved on.
+	Update(ctx context.Context, id string, input JobUpdate) (*models.Job, error)
+	// ListStale returns up to limit jobs in status whose last update is older than before,
+	// oldest first
+	ListStale(ctx context.Context, status models.JobStatus, before time.Time, limit int) ([]*models.Job, error)
+	// CountStale counts the jobs in status whose last update is older than before
+	CountStale(ctx context.Context, status models.JobStatus, before time.Time) (int, error)
+	// Load counts the jobs in the pipeline as of now, and those finished since since. It
+	// isn't scoped by the request's tenant: every tenant's jobs share the workers.
+	Load(ctx context.Context, now, since time.Time) (JobLoad, error)
+	// ListStatuses returns the status of up to filter.Limit jobs matching filter, least
+	// recently updated first
+	ListStatuses(ctx context.Context, filter JobStatusFilter) ([]*models.JobStatusSummary, error)
+	// Events returns a job's status history, oldest first
+	Events(ctx context.Context, jobID string) ([]*models.JobEvent, error)
+	// CompletedEvents returns the history of up to limit jobs COMPLETED since since, most
+	// recent first, each job's events oldest first. Demo jobs are left out. It isn't scoped
+	// by the request's tenant: every tenant's jobs run on the same planner.
+	CompletedEvents(ctx context.Context, since time.Time, limit int) ([][]*models.JobEvent, error)
+	// CountActive counts a user's PENDING and IN_PROGRESS jobs
+	CountActive(ctx context.Context, userID string) (int, error)
+	// CountCreatedSince counts a user's non-demo jobs created at or after since
+	CountCreatedSince(ctx context.Context, userID string, since time.Time) (int, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	// DeleteDemoByUser removes only the user's jobs flagged IsDemo
+	DeleteDemoByUser(ctx context.Context, userID string) (int64, error)
+}
+
+// EventRepository stores calendar events
+type EventRepository interface {
+	ListByUser(ctx context.Context, userID string, targetDate *string) ([]*models.CalendarEvent, error)
+	CountByUser(ctx context.Context, userID string) (int, error)
+	CountDemoByUser(ctx context.Context, userID string) (int, error)
+	// Get returns one of a user's events, or ErrNotFound
+	Get(ctx context.Context, userID, id string) (*models.CalendarEvent, error)
+	Create(ctx context.Context, event *models.CalendarEvent) error
+	// CreateBatch inserts events in one transaction, skipping IDs that already exist.
+	// It returns the IDs that were inserted.
+	CreateBatch(ctx context.Context, events []*models.CalendarEvent) ([]string, error)
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+	// DeleteDemoByUser removes only the user's events flagged IsDemo
+	DeleteDemoByUser(ctx context.Context, userID string) (int64, error)
+	// Stream calls fn for each of a user's events starting within the range, in start time
+	// order, without loading them all into memory
+	Stream(ctx context.Context, userID string, dates DateRange, fn func(*models.CalendarEvent) error) error
+	// UpsertByGoogleID updates the user's event with event.GoogleEventID, inserting it if
+	// there is none. A non-zero event.Version must match the stored one (ErrConflict).
+	UpsertByGoogleID(ctx context.Context, event *models.CalendarEvent) error
+	DeleteByGoogleID(ctx context.Context, userID, googleEventID string) (bool, error)
+	// GetByGoogleID returns the user's copy of a Google event, or ErrNotFound
+	GetByGoogleID(ctx context.Context, userID, googleEventID string) (*models.CalendarEvent, error)
+	// ListUngeocoded returns up to limit events of any tenant that have a location which
+	// hasn't been geocoded yet, oldest first
+	ListUngeocoded(ctx context.Context, limit int) ([]*models.CalendarEvent, error)
+	// SetLocationPoint stores the coordinates of an event's location and marks it
+	// geocoded; nil coordinates record that the location couldn't be placed
+	SetLocationPoint(ctx context.Context, id string, latitude, longitude *float64) error
+	// Search returns up to search.Limit of a user's events matching every term of
+	// search.Query and the filters
+	Search(ctx context.Context, userID string, search EventSearch) ([]*models.CalendarEvent, error)
+}
+
+// EventSearch filters a user's calendar events. Query terms match by prefix against the
+// summary, description and location; empty filters match everything.
+type EventSearch struct {
+	Query           string
+	Dates           DateRange
+	MeetingTypes    []models.MeetingType
+	AttendanceModes []models.AttendanceMode
+	Limit           int
+}
+
+// JobLoad is the work the job pipeline holds
+type JobLoad struct {
+	// Queued counts PENDING jobs due now, waiting for a worker; QueuedBatch those of
+	// them with BATCH priority
+	Queued      int
+	QueuedBatch int
+	// Running counts IN_PROGRESS jobs
+	Running int
+	// Finished counts the jobs COMPLETED or FAILED in the period asked for
+	Finished int
+}
+
+// JobStatusFilter selects jobs for ListStatuses; empty fields match everything
+type JobStatusFilter struct {
+	IDs    []string
+	UserID *string
+	// Since keeps jobs updated at or after it
+	Since *time.Time
+	Limit int
+}
+
+// RecommendationRepository stores commute recommendations
+type RecommendationRepository interface {
+	ListByJob(ctx context.Context, jobID string) ([]*models.CommuteRecommendation, error)
+	// Create inserts a recommendation. They are normally written by the AI service; the
+	// backend only creates them for demo data.
+	Create(ctx context.Context, rec *models.CommuteRecommendation) error
+	// StreamByUser calls fn for each recommendation of a user's jobs whose target date is in
+	// the range, ordered by target date and rank. rec.Job carries the job ID and target date.
+	StreamByUser(ctx context.Context, userID string, dates DateRange, fn func(*models.CommuteRecommendation) error) error
+	// Accept marks a recommendation of one of the user's jobs chosen, or returns ErrNotFound
+	Accept(ctx context.Context, userID, id string) (*models.CommuteRecommendation, error)
+	// UpdateExplanation replaces the reasoning and perception analysis, or returns ErrNotFound
+	UpdateExplanation(ctx context.Context, id, reasoning, perceptionAnalysis string) error
+	// SetRanks renumbers a job's recommendations from 1 in the order of ids, which must be
+	// the IDs of all of them
+	SetRanks(ctx context.Context, jobID string, ids []string) error
+	// ExperimentStats counts the non-demo jobs of each variant of an experiment whose
+	// target date is in the range, with how many completed and had a recommendation
+	// accepted, ordered by variant. Rates are left to the caller.
+	ExperimentStats(ctx context.Context, experiment string, dates DateRange) ([]models.VariantResult, error)
+}
+
+// WebhookRepository stores webhook endpoints and their delivery log
+type WebhookRepository interface {
+	CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error
+	GetEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context, userID string) ([]*models.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, userID, id string) (bool, error)
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, userID, endpointID string, limit int) ([]*models.WebhookDelivery, error)
+}
+
+// GoogleCalendarRepository stores Google Calendar watch channels and sync state
+type GoogleCalendarRepository interface {
+	CreateChannel(ctx context.Context, channel *models.GoogleCalendarChannel) error
+	GetChannel(ctx context.Context, id string) (*models.GoogleCalendarChannel, error)
+	ListChannelsByUser(ctx context.Context, userID string) ([]*models.GoogleCalendarChannel, error)
+	ChannelsExpiringBefore(ctx context.Context, t time.Time) ([]*models.GoogleCalendarChannel, error)
+	DeleteChannel(ctx context.Context, id string) (bool, error)
+	// UpdateSyncState stores the sync token for every channel of a user's calendar
+	UpdateSyncState(ctx context.Context, userID, calendarID string, syncToken *string, syncedAt time.Time) error
+}
+
+// JobOutboxRepository buffers jobs that couldn't be pushed to the worker queue
+type JobOutboxRepository interface {
+	// Add buffers a job; adding a job that is already buffered records the new error
+	Add(ctx context.Context, entry *models.JobOutboxEntry) error
+	// ClaimDue returns up to limit entries whose next attempt is due, pushing their next
+	// attempt out by lease so concurrent flushers don't push them twice
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.JobOutboxEntry, error)
+	Delete(ctx context.Context, jobID string) error
+	RecordFailure(ctx context.Context, jobID, message string, nextAttempt time.Time) error
+	Count(ctx context.Context) (int, error)
+}
+
+// JobQuotaRepository stores per-user overrides of the default job quotas
+type JobQuotaRepository interface {
+	// Get returns a user's override, or ErrNotFound if the defaults apply
+	Get(ctx context.Context, userID string) (*models.JobQuota, error)
+	Put(ctx context.Context, quota *models.JobQuota) error
+	Delete(ctx context.Context, userID string) (bool, error)
+}
+
+// TravelProfileRepository stores how each user is willing to get to work
+type TravelProfileRepository interface {
+	// Get returns a user's travel profile, or ErrNotFound if they haven't set one
+	Get(ctx context.Context, userID string) (*models.TravelProfile, error)
+	// Put creates or replaces a user's travel profile
+	Put(ctx context.Context, profile *models.TravelProfile) error
+}
+
+// PreferenceFeedbackRepository stores users' reviews of the preferences learned from
+// their commute history, keyed by user and preference key
+type PreferenceFeedbackRepository interface {
+	// List returns a user's feedback in key order
+	List(ctx context.Context, userID string) ([]*models.PreferenceFeedback, error)
+	// Put creates or replaces a user's feedback on a preference
+	Put(ctx context.Context, feedback *models.PreferenceFeedback) error
+	Delete(ctx context.Context, userID, key string) (bool, error)
+}
+
+// NotificationSettingsRepository stores the notifications each user opted into, and which
+// weekly digests have been sent
+type NotificationSettingsRepository interface {
+	// Get returns a user's settings, or ErrNotFound if they haven't saved any
+	Get(ctx context.Context, userID string) (*models.NotificationSettings, error)
+	// Put creates or replaces a user's opt-ins, keeping the record of digests sent
+	Put(ctx context.Context, settings *models.NotificationSettings) error
+	ListDigestSubscribers(ctx context.Context) ([]*models.NotificationSettings, error)
+	// ClaimDigest marks the digest of week (its Monday, YYYY-MM-DD) as sent to a
+	// subscriber; false when it already was, or the user unsubscribed
+	ClaimDigest(ctx context.Context, userID, week string) (bool, error)
+	// ReleaseDigest undoes a claim whose digest couldn't be sent
+	ReleaseDigest(ctx context.Context, userID, week string) error
+}
+
+// TenantRepository stores the tenants of a multi-tenant deployment. Tenants are global;
+// they are not scoped by the request's tenant.
+type TenantRepository interface {
+	// Get returns a tenant, or ErrNotFound
+	Get(ctx context.Context, id string) (*models.Tenant, error)
+	List(ctx context.Context) ([]*models.Tenant, error)
+	// Put creates a tenant or renames an existing one
+	Put(ctx context.Context, tenant *models.Tenant) error
+	// SetOfficeDayPolicy sets or, with nil, clears the office days a week the tenant
+	// expects; ErrNotFound for unknown tenants
+	SetOfficeDayPolicy(ctx context.Context, id string, officeDaysPerWeek *int) (*models.Tenant, error)
+	// SetSCIMTokenHash replaces or, with nil, removes the hash of the tenant's SCIM token;
+	// ErrNotFound for unknown tenants
+	SetSCIMTokenHash(ctx context.Context, id string, tokenHash *string) error
+	// GetBySCIMTokenHash returns the tenant whose SCIM token has tokenHash, or ErrNotFound
+	GetBySCIMTokenHash(ctx context.Context, tokenHash string) (*models.Tenant, error)
+}
+
+// OfficeRepository stores the offices of the tenant ctx is scoped to
+type OfficeRepository interface {
+	// Get returns an office, or ErrNotFound
+	Get(ctx context.Context, id string) (*models.Office, error)
+	List(ctx context.Context) ([]*models.Office, error)
+	Create(ctx context.Context, office *models.Office) error
+	// Update applies a partial update, or returns ErrNotFound
+	Update(ctx context.Context, id string, input OfficeUpdate) (*models.Office, error)
+	Delete(ctx context.Context, id string) (bool, error)
+}
+
+// GeocodeCacheRepository caches geocoding results by normalised address. The cache is
+// global; it is not scoped by the request's tenant.
+type GeocodeCacheRepository interface {
+	// Get returns the cached result, or ErrNotFound
+	Get(ctx context.Context, addressKey string) (*models.GeocodeCacheEntry, error)
+	// Put stores a result, replacing any earlier one for the address
+	Put(ctx context.Context, entry *models.GeocodeCacheEntry) error
+}
+
+// ArtifactRepository stores references to job artifacts kept in object storage. It is
+// not scoped by the request's tenant; artifacts are reached through their job.
+type ArtifactRepository interface {
+	Create(ctx context.Context, artifact *models.JobArtifact) error
+	// ListByJob returns a job's artifacts, oldest first
+	ListByJob(ctx context.Context, jobID string) ([]*models.JobArtifact, error)
+}
+
+// ShareLinkRepository stores read-only links to jobs' recommendations. It is not scoped
+// by the request's tenant: links are opened without signing in.
+type ShareLinkRepository interface {
+	Create(ctx context.Context, link *models.ShareLink) error
+	// Get returns a share link, or ErrNotFound
+	Get(ctx context.Context, id string) (*models.ShareLink, error)
+	// ListByJob returns a job's share links, newest first
+	ListByJob(ctx context.Context, jobID string) ([]*models.ShareLink, error)
+	// Revoke ends one of the user's links, reporting whether the user has it
+	Revoke(ctx context.Context, userID, id string) (bool, error)
+}
+
+// CalendarFeedRepository stores the hashed tokens of users' ICS feeds. It is not scoped
+// by the request's tenant: calendar apps fetch feeds without signing in.
+type CalendarFeedRepository interface {
+	// Put gives the user a feed with tokenHash, replacing their earlier feed
+	Put(ctx context.Context, userID, tokenHash string) (*models.CalendarFeed, error)
+	// UserByToken returns the user whose feed has tokenHash, or ErrNotFound
+	UserByToken(ctx context.Context, tokenHash string) (string, error)
+	// Delete removes the user's feed, reporting whether they had one
+	Delete(ctx context.Context, userID string) (bool, error)
+}
+
+// CommuteReminderRepository stores the reminders to leave scheduled for accepted plans.
+// It is not scoped by the request's tenant: reminders are sent by a background scheduler.
+type CommuteReminderRepository interface {
+	// Replace cancels the reminders still scheduled for the user's day (YYYY-MM-DD) and
+	// schedules reminders in their place
+	Replace(ctx context.Context, userID, targetDate string, reminders []*models.CommuteReminder) error
+	// CancelScheduled cancels every reminder still scheduled for the user, returning how many
+	CancelScheduled(ctx context.Context, userID string) (int, error)
+	// Cancel cancels one of the user's reminders, reporting whether it was still scheduled
+	Cancel(ctx context.Context, userID, id string) (bool, error)
+	// ListUpcoming returns the user's reminders for departures from since on, soonest first
+	ListUpcoming(ctx context.Context, userID string, since time.Time) ([]*models.CommuteReminder, error)
+	// ClaimDue marks up to limit reminders due at now, whose departure hasn't passed, as
+	// sent and returns them
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]*models.CommuteReminder, error)
+	// Fail records that a claimed reminder couldn't be sent
+	Fail(ctx context.Context, id, message string) error
+	// CancelMissed cancels the scheduled reminders whose departure has passed
+	CancelMissed(ctx context.Context, now time.Time) (int, error)
+}
+
+// CalendarImportRepository stores the calendar files users imported and their progress.
+// It is not scoped by the request's tenant: imports are carried on in the background.
+type CalendarImportRepository interface {
+	// Create records the start of an import, setting its ID
+	Create(ctx context.Context, imp *models.CalendarImport) error
+	// Update saves an import's progress and outcome, or returns ErrNotFound
+	Update(ctx context.Context, imp *models.CalendarImport) error
+	// Get returns one of the user's imports, or ErrNotFound
+	Get(ctx context.Context, userID, id string) (*models.CalendarImport, error)
+}
+
+// APITokenRepository stores the hashed personal access tokens of users. It is not scoped
+// by the request's tenant: a token is looked up before its request has a user.
+type APITokenRepository interface {
+	// Create stores a token with tokenHash, setting its ID and creation time
+	Create(ctx context.Context, token *models.APIToken, tokenHash string) error
+	// ListByUser returns the user's tokens, newest first
+	ListByUser(ctx context.Context, userID string) ([]*models.APIToken, error)
+	// GetByHash returns the token with tokenHash, or ErrNotFound
+	GetByHash(ctx context.Context, tokenHash string) (*models.APIToken, error)
+	// Touch records that a token was used at at
+	Touch(ctx context.Context, id string, at time.Time) error
+	// Delete revokes one of the user's tokens, reporting whether the user has it
+	Delete(ctx context.Context, userID, id string) (bool, error)
+}
+
+// AnalyticsEventRepository stores anonymized product analytics events until they are
+// forwarded. It is not scoped by the request's tenant: events name neither user nor tenant.
+type AnalyticsEventRepository interface {
+	// Create stores events, setting their IDs
+	Create(ctx context.Context, events []*models.AnalyticsEvent) error
+	// ListUnforwarded returns up to limit events not yet forwarded, oldest first
+	ListUnforwarded(ctx context.Context, limit int) ([]*models.AnalyticsEvent, error)
+	// MarkForwarded records that the events with ids were forwarded at at
+	MarkForwarded(ctx context.Context, ids []string, at time.Time) error
+}
+
+// DeprecationUsageRepository counts the requests selecting deprecated GraphQL fields. It
+// is not scoped by the request's tenant: clients are counted across every tenant.
+type DeprecationUsageRepository interface {
+	// Record adds usage to the counts of the day of each one's LastUsedAt
+	Record(ctx context.Context, usage []*models.DeprecatedFieldUsage) error
+	// List returns the usage of each field by each client since the day of since, summed
+	List(ctx context.Context, since time.Time) ([]*models.DeprecatedFieldUsage, error)
+}
+
+// ClientUsageRepository counts the GraphQL operations each client version runs, across
+// every tenant
+type ClientUsageRepository interface {
+	// Record adds usage to the counts of the day of each one's LastUsedAt
+	Record(ctx context.Context, usage []*models.ClientOperationUsage) error
+	// List returns the usage of each operation by each client version since the day of
+	// since, summed
+	List(ctx context.Context, since time.Time) ([]*models.ClientOperationUsage, error)
+}
+
+// RetentionRepository archives old jobs and records retention runs. It is not scoped by
+// the request's tenant: retention runs across every tenant.
+type RetentionRepository interface {
+	// Archive copies a job, its recommendations and its status history into the archive
+	// and deletes them, returning how many recommendations were archived, or ErrNotFound
+	Archive(ctx context.Context, jobID string) (int, error)
+	// GetArchived returns an archived job, or ErrNotFound
+	GetArchived(ctx context.Context, jobID string) (*models.ArchivedJob, error)
+	CreateRun(ctx context.Context, run *models.RetentionRun) error
+	// UpdateRun saves a run's counts, status and finish time, or returns ErrNotFound
+	UpdateRun(ctx context.Context, run *models.RetentionRun) error
+	// GetRun returns a retention run, or ErrNotFound
+	GetRun(ctx context.Context, id string) (*models.RetentionRun, error)
+	// ListRuns returns the latest runs, newest first
+	ListRuns(ctx context.Context, limit int) ([]*models.RetentionRun, error)
+}
+
+// Repositories bundles every repository so they can be injected together
+type Repositories struct {
+	Users           UserRepository
+	Jobs            JobRepository
+	Events          EventRepository
+	Recommendations RecommendationRepository
+	Webhooks        WebhookRepository
+	GoogleCalendar  GoogleCalendarRepository
+	JobOutbox       JobOutboxRepository
+	JobQuotas       JobQuotaRepository
+	Tenants         TenantRepository
+	Offices         OfficeRepository
+	GeocodeCache    GeocodeCacheRepository
+	TravelProfiles  TravelProfileRepository
+	Preferences     PreferenceFeedbackRepository
+	Notifications   NotificationSettingsRepository
+	Retention       RetentionRepository
+	Artifacts       ArtifactRepository
+	ShareLinks      ShareLinkRepository
+	CalendarFeeds   CalendarFeedRepository
+	Reminders       CommuteReminderRepository
+	Analytics       AnalyticsEventRepository
+	CalendarImports CalendarImportRepository
+	APITokens       APITokenRepository
+	Deprecations    DeprecationUsageRepository
+	ClientUsage     ClientUsageRepository
+}
+
+// NewSQLRepositories creates Postgres-backed repositories
+func NewSQLRepositories(db *database.DB) Repositories {
+	return Repositories{
+		Users:           NewSQLUserRepository(db),
+		Jobs:            NewSQLJobRepository(db),
+		Events:          NewSQLEventRepository(db),
+		Recommendations: NewSQLRecommendationRepository(db),
+		Webhooks:        NewSQLWebhookRepository(db),
+		GoogleCalendar:  NewSQLGoogleCalendarRepository(db),
+		JobOutbox:       NewSQLJobOutboxRepository(db),
+		JobQuotas:       NewSQLJobQuotaRepository(db),
+		Tenants:         NewSQLTenantRepository(db),
+		Offices:         NewSQLOfficeRepository(db),
+		GeocodeCache:    NewSQLGeocodeCacheRepository(db),
+		TravelProfiles:  NewSQLTravelProfileRepository(db),
+		Preferences:     NewSQLPreferenceFeedbackRepository(db),
+		Notifications:   NewSQLNotificationSettingsRepository(db),
+		Retention:       NewSQLRetentionRepository(db),
+		Artifacts:       NewSQLArtifactRepository(db),
+		ShareLinks:      NewSQLShareLinkRepository(db),
+		CalendarFeeds:   NewSQLCalendarFeedRepository(db),
+		Reminders:       NewSQLCommuteReminderRepository(db),
+		Analytics:       NewSQLAnalyticsEventRepository(db),
+		CalendarImports: NewSQLCalendarImportRepository(db),
+		APITokens:       NewSQLAPITokenRepository(db),
+		Deprecations:    NewSQLDeprecationUsageRepository(db),
+		ClientUsage:     NewSQLClientUsageRepository(db),
+	}
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// retentionRunColumns is the column list scanned by scanRetentionRun
+var retentionRunColumns = []string{"id", "triggered_by", "status", "completed_before", "failed_before", "jobs_archived", "recommendations_archived", "error_message", "started_at", "finished_at"}
+
+// SQLRetentionRepository archives jobs in Postgres
+type SQLRetentionRepository struct {
+	db *database.DB
+}
+
+// NewSQLRetentionRepository creates a retention repository
+func NewSQLRetentionRepository(db *database.DB) *SQLRetentionRepository {
+	return &SQLRetentionRepository{db: db}
+}
+
+// Archive moves a job, its recommendations and its status history into job_archive in
+// one transaction. Deleting the job cascades to the recommendations, history and outbox.
+func (r *SQLRetentionRepository) Archive(ctx context.Context, jobID string) (int, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	archived := 0
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		var tenantID string
+		job, err := scanJob(r.db.QueryRowContext(ctx, `SELECT `+strings.Join(jobColumns, ", ")+` FROM jobs WHERE id = $1`, jobID))
+		if err == nil {
+			err = r.db.QueryRowContext(ctx, `SELECT tenant_id FROM jobs WHERE id = $1`, jobID).Scan(&tenantID)
+		}
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		recommendations, err := r.listRecommendations(ctx, jobID)
+		if err != nil {
+			return err
+		}
+		events, err := r.listEvents(ctx, jobID)
+		if err != nil {
+			return err
+		}
+
+		_, err = r.db.ExecContext(ctx, `INSERT INTO job_archive (job_id, user_id, tenant_id, status, target_date, job, recommendations, events, archived_at)
+		          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			job.ID, job.UserID, tenantID, job.Status, dateOnly(job.TargetDate),
+			models.JSON(job), models.JSON(recommendations), models.JSON(events), time.Now())
+		if err != nil {
+			return fmt.Errorf("archiving job: %w", err)
+		}
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM jobs WHERE id = $1`, jobID); err != nil {
+			return fmt.Errorf("deleting archived job: %w", err)
+		}
+		archived = len(recommendations)
+		return nil
+	})
+	return archived, err
+}
+
+func (r *SQLRetentionRepository) listRecommendations(ctx context.Context, jobID string) ([]*models.CommuteRecommendation, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+strings.Join(recommendationColumns, ", ")+` FROM commute_recommendations
+	          WHERE job_id = $1 ORDER BY option_rank ASC`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recommendations := []*models.CommuteRecommendation{}
+	for rows.Next() {
+		rec, err := scanRecommendation(rows)
+		if err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, rec)
+	}
+	return recommendations, rows.Err()
+}
+
+func (r *SQLRetentionRepository) listEvents(ctx context.Context, jobID string) ([]*models.JobEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+strings.Join(jobEventColumns, ", ")+` FROM job_events
+	          WHERE job_id = $1 ORDER BY sequence ASC`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*models.JobEvent{}
+	for rows.Next() {
+		event, err := scanJobEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// GetArchived returns an archived job, or ErrNotFound
+func (r *SQLRetentionRepository) GetArchived(ctx context.Context, jobID string) (*models.ArchivedJob, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	archived := &models.ArchivedJob{}
+	err := r.db.Reader().QueryRowContext(ctx, `SELECT job, recommendations, events, archived_at FROM job_archive WHERE job_id = $1`, jobID).Scan(
+		models.JSON(&archived.Job),
+		models.JSON(&archived.Recommendations),
+		models.JSON(&archived.Events),
+		&archived.ArchivedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+// CreateRun records the start of a retention run
+func (r *SQLRetentionRepository) CreateRun(ctx context.Context, run *models.RetentionRun) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO retention_runs (`+strings.Join(retentionRunColumns, ", ")+`)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		run.ID, run.TriggeredBy, run.Status, run.CompletedBefore, run.FailedBefore,
+		run.JobsArchived, run.RecommendationsArchived, run.ErrorMessage, run.StartedAt, run.FinishedAt)
+	return err
+}
+
+// UpdateRun saves a run's progress and, once it has finished, its outcome
+func (r *SQLRetentionRepository) UpdateRun(ctx context.Context, run *models.RetentionRun) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	query, args := Update("retention_runs").
+		Set("status", run.Status).
+		Set("jobs_archived", run.JobsArchived).
+		Set("recommendations_archived", run.RecommendationsArchived).
+		Set("error_message", run.ErrorMessage).
+		Set("finished_at", run.FinishedAt).
+		Where("id", run.ID).
+		Build()
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	return ErrNotFound
+}
+
+// GetRun returns a retention run, or ErrNotFound
+func (r *SQLRetentionRepository) GetRun(ctx context.Context, id string) (*models.RetentionRun, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(retentionRunColumns, ", ") + ` FROM retention_runs WHERE id = $1`
+	run, err := scanRetentionRun(r.db.Reader().QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return run, err
+}
+
+// ListRuns returns the latest limit retention runs, newest first
+func (r *SQLRetentionRepository) ListRuns(ctx context.Context, limit int) ([]*models.RetentionRun, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(retentionRunColumns, ", ") + ` FROM retention_runs ORDER BY started_at DESC LIMIT $1`
+	rows, err := r.db.Reader().QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*models.RetentionRun
+	for rows.Next() {
+		run, err := scanRetentionRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func scanRetentionRun(row rowScanner) (*models.RetentionRun, error) {
+	run := &models.RetentionRun{}
+	err := row.Scan(
+		&run.ID,
+		&run.TriggeredBy,
+		&run.Status,
+		&run.CompletedBefore,
+		&run.FailedBefore,
+		&run.JobsArchived,
+		&run.RecommendationsArchived,
+		&run.ErrorMessage,
+		&run.StartedAt,
+		&run.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
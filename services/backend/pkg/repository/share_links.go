@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+// shareLinkColumns is the column list scanned by scanShareLink
+var shareLinkColumns = []string{"id", "job_id", "user_id", "expires_at", "revoked_at", "created_at"}
+
+// SQLShareLinkRepository stores read-only links to jobs' recommendations
+type SQLShareLinkRepository struct {
+	db *database.DB
+}
+
+// NewSQLShareLinkRepository creates a share link repository
+func NewSQLShareLinkRepository(db *database.DB) *SQLShareLinkRepository {
+	return &SQLShareLinkRepository{db: db}
+}
+
+func (r *SQLShareLinkRepository) Create(ctx context.Context, link *models.ShareLink) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if link.ID == "" {
+		link.ID = uuid.New().String()
+	}
+	query := `INSERT INTO share_links (id, job_id, user_id, expires_at)
+	          VALUES ($1, $2, $3, $4)
+	          RETURNING created_at`
+	return r.db.QueryRowContext(ctx, query, link.ID, link.JobID, link.UserID, link.ExpiresAt.UTC()).Scan(&link.CreatedAt)
+}
+
+// Get returns a share link, or ErrNotFound. It reads the primary: opening a link checks
+// whether it was revoked, and a lagging replica would keep a revoked link working.
+func (r *SQLShareLinkRepository) Get(ctx context.Context, id string) (*models.ShareLink, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(shareLinkColumns, ", ") + ` FROM share_links WHERE id = $1`
+	link, err := scanShareLink(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return link, err
+}
+
+// ListByJob returns a job's share links, newest first
+func (r *SQLShareLinkRepository) ListByJob(ctx context.Context, jobID string) ([]*models.ShareLink, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(shareLinkColumns, ", ") + ` FROM share_links
+	          WHERE job_id = $1 ORDER BY created_at DESC, id ASC`
+	rows, err := r.db.Reader().QueryContext(ctx, query, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*models.ShareLink
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// Revoke ends one of the user's share links; revoking it again keeps the first
+// revocation time. It reports whether the user has the link.
+func (r *SQLShareLinkRepository) Revoke(ctx context.Context, userID, id string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE share_links SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+	          WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func scanShareLink(row rowScanner) (*models.ShareLink, error) {
+	link := &models.ShareLink{}
+	err := row.Scan(
+		&link.ID,
+		&link.JobID,
+		&link.UserID,
+		&link.ExpiresAt,
+		&link.RevokedAt,
+		&link.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSQLShareLinks(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	ada := createUser(t, ctx, db, "ada@example.com")
+	bob := createUser(t, ctx, db, "bob@example.com")
+	job := createJob(t, ctx, db, ada.ID)
+	links := NewSQLShareLinkRepository(db)
+
+	expires := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	link := &models.ShareLink{JobID: job.ID, UserID: ada.ID, ExpiresAt: expires}
+	if err := links.Create(ctx, link); err != nil {
+		t.Fatal(err)
+	}
+	got, err := links.Get(ctx, link.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.ExpiresAt.Equal(expires) || got.RevokedAt != nil || !got.Active(time.Now()) {
+		t.Errorf("link = %+v, want active until %v", got, expires)
+	}
+	if _, err := links.Get(ctx, "00000000-0000-0000-0000-000000000000"); err != ErrNotFound {
+		t.Errorf("unknown link: err = %v, want ErrNotFound", err)
+	}
+
+	// Only the link's owner revokes it
+	if revoked, err := links.Revoke(ctx, bob.ID, link.ID); err != nil || revoked {
+		t.Errorf("another user revoked the link: %v, %v", revoked, err)
+	}
+	if revoked, err := links.Revoke(ctx, ada.ID, link.ID); err != nil || !revoked {
+		t.Fatalf("revoke = %v, %v", revoked, err)
+	}
+	if got, _ := links.Get(ctx, link.ID); got.RevokedAt == nil || got.Active(time.Now()) {
+		t.Errorf("revoked link = %+v", got)
+	}
+
+	listed, err := links.ListByJob(ctx, job.ID)
+	if err != nil || len(listed) != 1 || listed[0].ID != link.ID {
+		t.Errorf("listed %+v, %v", listed, err)
+	}
+
+	// Deleting the job drops its links
+	if _, err := NewSQLJobRepository(db).Delete(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := links.Get(ctx, link.ID); err != ErrNotFound {
+		t.Errorf("deleted job left its link: err = %v", err)
+	}
+}
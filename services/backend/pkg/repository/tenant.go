@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+// tenantClause returns " AND column = $n" restricting a query to the tenant ctx is scoped
+// to, with the tenant appended to args. Unscoped contexts (background workers) get no
+// condition and args back unchanged.
+func tenantClause(ctx context.Context, column string, args []interface{}) (string, []interface{}) {
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return "", args
+	}
+	args = append(args, id)
+	return fmt.Sprintf(" AND %s = $%d", column, len(args)), args
+}
+
+// checkUserTenant returns ErrNotFound when ctx is scoped to a tenant the user isn't in.
+// Per-user rows take their user's tenant on insert, so this keeps a scoped caller from
+// writing them for another tenant's user.
+func checkUserTenant(ctx context.Context, db *database.DB, userID string) error {
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID})
+	if scope == "" {
+		return nil
+	}
+	var exists int
+	err := db.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = $1`+scope, args...).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+// scopeUpdate restricts an UPDATE to the tenant ctx is scoped to
+func scopeUpdate(ctx context.Context, b *UpdateBuilder) *UpdateBuilder {
+	if id, ok := tenant.FromContext(ctx); ok {
+		b.Where("tenant_id", id)
+	}
+	return b
+}
@@ -0,0 +1,331 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/tenant"
+	"github.com/google/uuid"
+)
+
+func TestSQLTenantScoping(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	if err := NewSQLTenantRepository(db).Put(ctx, &models.Tenant{ID: "acme", Name: "Acme"}); err != nil {
+		t.Fatal(err)
+	}
+	acme := tenant.WithID(ctx, "acme")
+	other := tenant.WithID(ctx, tenant.DefaultID)
+
+	users := NewSQLUserRepository(db)
+	jobs := NewSQLJobRepository(db)
+	events := NewSQLEventRepository(db)
+
+	// The same email can sign up in both tenants
+	ada := createUser(t, acme, db, "ada@example.com")
+	bob := createUser(t, other, db, "ada@example.com")
+	if ada.TenantID != "acme" || bob.TenantID != tenant.DefaultID {
+		t.Fatalf("tenants = %s, %s, want acme, default", ada.TenantID, bob.TenantID)
+	}
+
+	// Jobs and events take their user's tenant, whatever the writer's context
+	adaJob := createJob(t, ctx, db, ada.ID)
+	bobJob := createJob(t, other, db, bob.ID)
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	adaEvent := &models.CalendarEvent{
+		ID:             uuid.New().String(),
+		UserID:         ada.ID,
+		Summary:        "Planning",
+		StartTime:      start,
+		EndTime:        start.Add(time.Hour),
+		MeetingType:    models.MeetingTypeTeamWorkshop,
+		AttendanceMode: models.AttendanceMustBeInOffice,
+		CreatedAt:      start,
+		UpdatedAt:      start,
+	}
+	if err := events.Create(ctx, adaEvent); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("reads", func(t *testing.T) {
+		if _, err := users.Get(other, ada.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("user of another tenant: error = %v, want ErrNotFound", err)
+		}
+		if _, err := users.Get(acme, ada.ID); err != nil {
+			t.Errorf("user of the tenant: %v", err)
+		}
+		if _, err := jobs.Get(other, adaJob.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("job of another tenant: error = %v, want ErrNotFound", err)
+		}
+
+		list, err := jobs.List(acme, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].ID != adaJob.ID {
+			t.Errorf("acme jobs = %v, want only %s", jobIDs(list), adaJob.ID)
+		}
+		list, err = jobs.List(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 {
+			t.Errorf("unscoped jobs = %v, want both tenants' jobs", jobIDs(list))
+		}
+
+		found, err := events.ListByUser(other, ada.ID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 0 {
+			t.Errorf("another tenant listed %d of ada's events", len(found))
+		}
+		found, err = events.ListByUser(acme, ada.ID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 1 {
+			t.Errorf("acme listed %d of ada's events, want 1", len(found))
+		}
+	})
+
+	t.Run("writes", func(t *testing.T) {
+		name := "Mallory"
+		if _, err := users.Update(other, ada.ID, UserUpdate{Name: &name}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("updating a user of another tenant: error = %v, want ErrNotFound", err)
+		}
+
+		status := string(models.JobStatusInProgress)
+		if _, err := jobs.Update(other, adaJob.ID, JobUpdate{Status: &status}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("updating a job of another tenant: error = %v, want ErrNotFound", err)
+		}
+		history, err := jobs.Events(ctx, adaJob.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 1 {
+			t.Errorf("refused cross-tenant update left %d events, want only the creation event", len(history))
+		}
+
+		if deleted, err := jobs.Delete(other, adaJob.ID); err != nil || deleted {
+			t.Errorf("deleting a job of another tenant = %v, %v, want false", deleted, err)
+		}
+		if deleted, err := users.Delete(other, ada.ID); err != nil || deleted {
+			t.Errorf("deleting a user of another tenant = %v, %v, want false", deleted, err)
+		}
+		if _, err := jobs.Get(acme, adaJob.ID); err != nil {
+			t.Errorf("acme's job after the refused delete: %v", err)
+		}
+
+		setStatus(t, other, jobs, bobJob.ID, models.JobStatusInProgress)
+	})
+}
+
+func TestSQLUserSettingsTenantScoping(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	if err := NewSQLTenantRepository(db).Put(ctx, &models.Tenant{ID: "acme", Name: "Acme"}); err != nil {
+		t.Fatal(err)
+	}
+	acme := tenant.WithID(ctx, "acme")
+	other := tenant.WithID(ctx, tenant.DefaultID)
+	ada := createUser(t, acme, db, "ada@example.com")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	profiles := NewSQLTravelProfileRepository(db)
+	quotas := NewSQLJobQuotaRepository(db)
+	channels := NewSQLGoogleCalendarRepository(db)
+	webhooks := NewSQLWebhookRepository(db)
+	preferences := NewSQLPreferenceFeedbackRepository(db)
+
+	// Another tenant can't write settings for ada
+	profile := &models.TravelProfile{UserID: ada.ID, Modes: []models.TravelMode{models.TravelModeBike}}
+	if err := profiles.Put(other, profile); !errors.Is(err, ErrNotFound) {
+		t.Errorf("travel profile for another tenant's user: error = %v, want ErrNotFound", err)
+	}
+	quota := &models.JobQuota{UserID: ada.ID, Exempt: true, UpdatedAt: now}
+	if err := quotas.Put(other, quota); !errors.Is(err, ErrNotFound) {
+		t.Errorf("job quota for another tenant's user: error = %v, want ErrNotFound", err)
+	}
+	channel := &models.GoogleCalendarChannel{
+		ID: uuid.New().String(), UserID: ada.ID, CalendarID: "primary", ResourceID: "resource-1",
+		Token: "token", Expiration: now.Add(time.Hour), CreatedAt: now, UpdatedAt: now,
+	}
+	if err := channels.CreateChannel(other, channel); !errors.Is(err, ErrNotFound) {
+		t.Errorf("calendar channel for another tenant's user: error = %v, want ErrNotFound", err)
+	}
+	endpoint := &models.WebhookEndpoint{
+		ID: uuid.New().String(), UserID: ada.ID, URL: "https://hooks.example.com/ada", Secret: "whsec_0123456789abcdef",
+		Events: []string{}, Active: true, CreatedAt: now, UpdatedAt: now,
+	}
+	if err := webhooks.CreateEndpoint(other, endpoint); !errors.Is(err, ErrNotFound) {
+		t.Errorf("webhook endpoint for another tenant's user: error = %v, want ErrNotFound", err)
+	}
+	rejected := &models.PreferenceFeedback{UserID: ada.ID, Key: "TRAVEL_MODE", Status: models.PreferenceRejected, UpdatedAt: now}
+	if err := preferences.Put(other, rejected); !errors.Is(err, ErrNotFound) {
+		t.Errorf("preference feedback for another tenant's user: error = %v, want ErrNotFound", err)
+	}
+
+	// Ada's own tenant can, and background workers (unscoped) see everything
+	if err := profiles.Put(acme, profile); err != nil {
+		t.Fatal(err)
+	}
+	if err := quotas.Put(acme, quota); err != nil {
+		t.Fatal(err)
+	}
+	if err := channels.CreateChannel(acme, channel); err != nil {
+		t.Fatal(err)
+	}
+	if err := webhooks.CreateEndpoint(acme, endpoint); err != nil {
+		t.Fatal(err)
+	}
+	if err := preferences.Put(acme, rejected); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := profiles.Get(ctx, ada.ID); err != nil {
+		t.Errorf("unscoped travel profile: %v", err)
+	}
+	if expiring, err := channels.ChannelsExpiringBefore(ctx, now.Add(2*time.Hour)); err != nil || len(expiring) != 1 {
+		t.Errorf("unscoped expiring channels = %d, %v, want 1", len(expiring), err)
+	}
+
+	// The rows took ada's tenant, so another tenant can't read or remove them
+	if _, err := profiles.Get(other, ada.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("travel profile of another tenant: error = %v, want ErrNotFound", err)
+	}
+	if _, err := quotas.Get(other, ada.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("job quota of another tenant: error = %v, want ErrNotFound", err)
+	}
+	if deleted, err := quotas.Delete(other, ada.ID); err != nil || deleted {
+		t.Errorf("deleting a job quota of another tenant = %v, %v, want false", deleted, err)
+	}
+	if _, err := channels.GetChannel(other, channel.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("calendar channel of another tenant: error = %v, want ErrNotFound", err)
+	}
+	if found, err := channels.ListChannelsByUser(other, ada.ID); err != nil || len(found) != 0 {
+		t.Errorf("another tenant listed %d of ada's channels (%v)", len(found), err)
+	}
+	if deleted, err := channels.DeleteChannel(other, channel.ID); err != nil || deleted {
+		t.Errorf("deleting a calendar channel of another tenant = %v, %v, want false", deleted, err)
+	}
+	if _, err := webhooks.GetEndpoint(other, endpoint.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("webhook endpoint of another tenant: error = %v, want ErrNotFound", err)
+	}
+	if found, err := webhooks.ListEndpoints(other, ada.ID); err != nil || len(found) != 0 {
+		t.Errorf("another tenant listed %d of ada's endpoints (%v)", len(found), err)
+	}
+	if deleted, err := webhooks.DeleteEndpoint(other, ada.ID, endpoint.ID); err != nil || deleted {
+		t.Errorf("deleting a webhook endpoint of another tenant = %v, %v, want false", deleted, err)
+	}
+	if found, err := preferences.List(other, ada.ID); err != nil || len(found) != 0 {
+		t.Errorf("another tenant listed %d of ada's preference feedback (%v)", len(found), err)
+	}
+	if deleted, err := preferences.Delete(other, ada.ID, rejected.Key); err != nil || deleted {
+		t.Errorf("deleting preference feedback of another tenant = %v, %v, want false", deleted, err)
+	}
+
+	if _, err := profiles.Get(acme, ada.ID); err != nil {
+		t.Errorf("acme's travel profile: %v", err)
+	}
+	if _, err := quotas.Get(acme, ada.ID); err != nil {
+		t.Errorf("acme's job quota: %v", err)
+	}
+	if found, err := webhooks.ListEndpoints(acme, ada.ID); err != nil || len(found) != 1 {
+		t.Errorf("acme's endpoints = %d, %v, want 1", len(found), err)
+	}
+	if found, err := preferences.List(acme, ada.ID); err != nil || len(found) != 1 || found[0].Status != models.PreferenceRejected {
+		t.Errorf("acme's preference feedback = %v, %v, want the rejection", found, err)
+	}
+}
+
+func TestSQLOrgReportingSettings(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	tenants := NewSQLTenantRepository(db)
+	if err := tenants.Put(ctx, &models.Tenant{ID: "acme", Name: "Acme"}); err != nil {
+		t.Fatal(err)
+	}
+	acme := tenant.WithID(ctx, "acme")
+	other := tenant.WithID(ctx, tenant.DefaultID)
+	ada := createUser(t, acme, db, "ada@example.com")
+
+	days := 3
+	if policy, err := tenants.SetOfficeDayPolicy(ctx, "acme", &days); err != nil || policy.OfficeDaysPerWeek == nil || *policy.OfficeDaysPerWeek != 3 {
+		t.Fatalf("policy = %+v, %v", policy, err)
+	}
+	// Renaming the tenant keeps its policy
+	renamed := &models.Tenant{ID: "acme", Name: "Acme Corp"}
+	if err := tenants.Put(ctx, renamed); err != nil || renamed.OfficeDaysPerWeek == nil {
+		t.Errorf("renamed tenant = %+v, %v, want the policy kept", renamed, err)
+	}
+	if _, err := tenants.SetOfficeDayPolicy(ctx, "globex", &days); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown tenant: error = %v, want ErrNotFound", err)
+	}
+
+	users := NewSQLUserRepository(db)
+	if _, err := users.SetOrgAdmin(other, ada.ID, true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("org admin of another tenant: error = %v, want ErrNotFound", err)
+	}
+	if user, err := users.SetOrgAdmin(acme, ada.ID, true); err != nil || !user.IsOrgAdmin {
+		t.Errorf("org admin = %+v, %v", user, err)
+	}
+	if user, err := users.SetOrgReportingOptOut(acme, ada.ID, true); err != nil || !user.OrgReportingOptOut || !user.IsOrgAdmin {
+		t.Errorf("opted out user = %+v, %v", user, err)
+	}
+}
+
+func TestSQLSCIMProvisioning(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	tenants := NewSQLTenantRepository(db)
+	if err := tenants.Put(ctx, &models.Tenant{ID: "acme", Name: "Acme"}); err != nil {
+		t.Fatal(err)
+	}
+	hash := "0f1e2d"
+	if err := tenants.SetSCIMTokenHash(ctx, "acme", &hash); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := tenants.GetBySCIMTokenHash(ctx, hash); err != nil || found.ID != "acme" || !found.SCIMEnabled {
+		t.Fatalf("tenant = %+v, %v; want acme with SCIM enabled", found, err)
+	}
+	if err := tenants.SetSCIMTokenHash(ctx, "acme", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tenants.GetBySCIMTokenHash(ctx, hash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("disabled: error = %v, want ErrNotFound", err)
+	}
+
+	acme := tenant.WithID(ctx, "acme")
+	users := NewSQLUserRepository(db)
+	externalID := "00u1"
+	ada, err := users.Create(acme, NewUser{Email: "ada@acme.com", Name: "Ada", SCIMExternalID: &externalID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	createUser(t, ctx, db, "ada@acme.com")
+	if found, err := users.GetByEmail(acme, "ada@acme.com"); err != nil || found.ID != ada.ID || *found.SCIMExternalID != externalID {
+		t.Errorf("user by email = %+v, %v; want %s in acme", found, err, ada.ID)
+	}
+
+	deactivated, err := users.SetActive(ctx, ada.ID, false)
+	if err != nil || deactivated.DeactivatedAt == nil {
+		t.Fatalf("deactivated user = %+v, %v", deactivated, err)
+	}
+	// Deactivating again keeps when the user was first deactivated
+	again, err := users.SetActive(ctx, ada.ID, false)
+	if err != nil || !again.DeactivatedAt.Equal(*deactivated.DeactivatedAt) {
+		t.Errorf("deactivated again at %v, want %v", again.DeactivatedAt, deactivated.DeactivatedAt)
+	}
+	if reactivated, err := users.SetActive(ctx, ada.ID, true); err != nil || reactivated.DeactivatedAt != nil {
+		t.Errorf("reactivated user = %+v, %v", reactivated, err)
+	}
+
+	empty := ""
+	if updated, err := users.Update(ctx, ada.ID, UserUpdate{SCIMExternalID: &empty}); err != nil || updated.SCIMExternalID != nil {
+		t.Errorf("cleared external ID = %v, %v; want nil", updated.SCIMExternalID, err)
+	}
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// tenantColumns is the column list scanned by scanTenant
+var tenantColumns = []string{"id", "name", "office_days_per_week", "scim_token_hash IS NOT NULL", "created_at"}
+
+// SQLTenantRepository stores tenants
+type SQLTenantRepository struct {
+	db *database.DB
+}
+
+// NewSQLTenantRepository creates a tenant repository
+func NewSQLTenantRepository(db *database.DB) *SQLTenantRepository {
+	return &SQLTenantRepository{db: db}
+}
+
+// Get returns a tenant, or ErrNotFound
+func (r *SQLTenantRepository) Get(ctx context.Context, id string) (*models.Tenant, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(tenantColumns, ", ") + ` FROM tenants WHERE id = $1`
+	t, err := scanTenant(r.db.Reader().QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return t, err
+}
+
+// List returns all tenants ordered by ID
+func (r *SQLTenantRepository) List(ctx context.Context) ([]*models.Tenant, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.Reader().QueryContext(ctx, `SELECT `+strings.Join(tenantColumns, ", ")+` FROM tenants ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []*models.Tenant
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, rows.Err()
+}
+
+// Put creates a tenant or renames an existing one, filling in its creation time
+func (r *SQLTenantRepository) Put(ctx context.Context, t *models.Tenant) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO tenants (id, name) VALUES ($1, $2)
+	          ON CONFLICT (id) DO UPDATE SET name = excluded.name
+	          RETURNING office_days_per_week, created_at`
+	return r.db.QueryRowContext(ctx, query, t.ID, t.Name).Scan(&t.OfficeDaysPerWeek, &t.CreatedAt)
+}
+
+// SetOfficeDayPolicy sets or clears the office days a week the tenant expects
+func (r *SQLTenantRepository) SetOfficeDayPolicy(ctx context.Context, id string, officeDaysPerWeek *int) (*models.Tenant, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE tenants SET office_days_per_week = $2 WHERE id = $1 RETURNING ` + strings.Join(tenantColumns, ", ")
+	t, err := scanTenant(r.db.QueryRowContext(ctx, query, id, officeDaysPerWeek))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return t, err
+}
+
+// SetSCIMTokenHash replaces or, with nil, removes the hash of the tenant's SCIM token
+func (r *SQLTenantRepository) SetSCIMTokenHash(ctx context.Context, id string, tokenHash *string) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE tenants SET scim_token_hash = $2 WHERE id = $1`, id, tokenHash)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetBySCIMTokenHash returns the tenant whose SCIM token has tokenHash, or ErrNotFound
+func (r *SQLTenantRepository) GetBySCIMTokenHash(ctx context.Context, tokenHash string) (*models.Tenant, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + strings.Join(tenantColumns, ", ") + ` FROM tenants WHERE scim_token_hash = $1`
+	t, err := scanTenant(r.db.QueryRowContext(ctx, query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return t, err
+}
+
+// scanTenant scans a row selected with tenantColumns
+func scanTenant(row rowScanner) (*models.Tenant, error) {
+	t := &models.Tenant{}
+	if err := row.Scan(&t.ID, &t.Name, &t.OfficeDaysPerWeek, &t.SCIMEnabled, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
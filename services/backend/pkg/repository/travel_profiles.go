@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// travelProfileColumns is the column list scanned by scanTravelProfile
+var travelProfileColumns = []string{"user_id", "modes", "preferred_mode", "max_bike_minutes", "max_walk_minutes", "parking_cost", "transit_fare", "fuel_cost_per_km", "updated_at"}
+
+// SQLTravelProfileRepository stores per-user travel profiles
+type SQLTravelProfileRepository struct {
+	db *database.DB
+}
+
+// NewSQLTravelProfileRepository creates a travel profile repository
+func NewSQLTravelProfileRepository(db *database.DB) *SQLTravelProfileRepository {
+	return &SQLTravelProfileRepository{db: db}
+}
+
+// Get returns a user's travel profile, or ErrNotFound if they haven't set one
+func (r *SQLTravelProfileRepository) Get(ctx context.Context, userID string) (*models.TravelProfile, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID})
+	query := `SELECT ` + strings.Join(travelProfileColumns, ", ") + ` FROM travel_profiles WHERE user_id = $1` + scope
+	profile, err := scanTravelProfile(r.db.Reader().QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return profile, err
+}
+
+// Put creates or replaces a user's travel profile; ErrNotFound if the user is in another
+// tenant
+func (r *SQLTravelProfileRepository) Put(ctx context.Context, profile *models.TravelProfile) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if err := checkUserTenant(ctx, r.db, profile.UserID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO travel_profiles (` + strings.Join(travelProfileColumns, ", ") + `)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	          ON CONFLICT (user_id) DO UPDATE SET
+	              modes = excluded.modes,
+	              preferred_mode = excluded.preferred_mode,
+	              max_bike_minutes = excluded.max_bike_minutes,
+	              max_walk_minutes = excluded.max_walk_minutes,
+	              parking_cost = excluded.parking_cost,
+	              transit_fare = excluded.transit_fare,
+	              fuel_cost_per_km = excluded.fuel_cost_per_km,
+	              updated_at = excluded.updated_at`
+	_, err := r.db.ExecContext(ctx, query,
+		profile.UserID,
+		encodeTravelModes(profile.Modes),
+		profile.PreferredMode,
+		profile.MaxBikeMinutes,
+		profile.MaxWalkMinutes,
+		profile.ParkingCost,
+		profile.TransitFare,
+		profile.FuelCostPerKm,
+		profile.UpdatedAt,
+	)
+	return err
+}
+
+// scanTravelProfile scans a row selected with travelProfileColumns
+func scanTravelProfile(row rowScanner) (*models.TravelProfile, error) {
+	profile := &models.TravelProfile{}
+	err := row.Scan(
+		&profile.UserID,
+		models.JSON(&profile.Modes),
+		&profile.PreferredMode,
+		&profile.MaxBikeMinutes,
+		&profile.MaxWalkMinutes,
+		&profile.ParkingCost,
+		&profile.TransitFare,
+		&profile.FuelCostPerKm,
+		&profile.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Modes == nil {
+		profile.Modes = []models.TravelMode{}
+	}
+	return profile, nil
+}
+
+// encodeTravelModes stores modes as a JSON array, never null
+func encodeTravelModes(modes []models.TravelMode) *models.JSONColumn {
+	if modes == nil {
+		modes = []models.TravelMode{}
+	}
+	return models.JSON(modes)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+func TestInTx(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	user := createUser(t, ctx, db, "ada@example.com")
+	events := NewSQLEventRepository(db)
+	jobs := NewSQLJobRepository(db)
+
+	newEvent := func() *models.CalendarEvent {
+		now := time.Now().UTC().Truncate(time.Second)
+		return &models.CalendarEvent{
+			ID: uuid.New().String(), UserID: user.ID, Summary: "Standup", StartTime: now, EndTime: now.Add(time.Hour),
+			MeetingType: models.MeetingTypeUnknown, AttendanceMode: models.AttendanceFlexible, CreatedAt: now, UpdatedAt: now,
+		}
+	}
+	counts := func() (int, int) {
+		t.Helper()
+		eventCount, err := events.CountByUser(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobCount, err := jobs.CountActive(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return eventCount, jobCount
+	}
+
+	// Side effects deferred with AfterCommit only happen when the transaction commits
+	committed := 0
+	afterCommit := func() { committed++ }
+
+	// A failure rolls back everything, including the job's own (nested) transaction
+	failed := errors.New("later operation failed")
+	err := db.InTx(ctx, func(ctx context.Context) error {
+		database.AfterCommit(ctx, afterCommit)
+		if _, err := events.CreateBatch(ctx, []*models.CalendarEvent{newEvent(), newEvent()}); err != nil {
+			return err
+		}
+		if _, err := jobs.Create(ctx, NewJob{UserID: user.ID, TargetDate: "2026-03-02"}); err != nil {
+			return err
+		}
+		// Reads in the transaction see its writes
+		if count, err := events.CountByUser(ctx, user.ID); err != nil || count != 2 {
+			t.Errorf("events seen in the transaction = %d, %v, want 2", count, err)
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("InTx() = %v, want the callback's error", err)
+	}
+	if eventCount, jobCount := counts(); eventCount != 0 || jobCount != 0 {
+		t.Fatalf("after rollback: %d events and %d jobs, want none", eventCount, jobCount)
+	}
+	if committed != 0 {
+		t.Errorf("AfterCommit ran %d times after a rollback, want 0", committed)
+	}
+
+	// A nested transaction rolled back on its own leaves the rest of the batch to commit
+	err = db.InTx(ctx, func(ctx context.Context) error {
+		if err := events.Create(ctx, newEvent()); err != nil {
+			return err
+		}
+		nested, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := nested.ExecContext(ctx, `DELETE FROM calendar_events WHERE user_id = $1`, user.ID); err != nil {
+			return err
+		}
+		if err := nested.Rollback(); err != nil {
+			return err
+		}
+		_, err = jobs.Create(ctx, NewJob{UserID: user.ID, TargetDate: "2026-03-02"})
+		if err == nil {
+			database.AfterCommit(ctx, afterCommit)
+			if committed != 0 {
+				t.Error("AfterCommit ran before the transaction committed")
+			}
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if committed != 1 {
+		t.Errorf("AfterCommit ran %d times after a commit, want 1", committed)
+	}
+	if eventCount, jobCount := counts(); eventCount != 1 || jobCount != 1 {
+		t.Fatalf("after commit: %d events and %d jobs, want 1 of each", eventCount, jobCount)
+	}
+}
@@ -0,0 +1,449 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/tenant"
+	"github.com/google/uuid"
+)
+
+// userColumns is the column list scanned by scanUser
+var userColumns = []string{"id", "tenant_id", "email", "name", "user_preferences", "default_office_id", "home_address", "home_latitude", "home_longitude", "focus_minutes", "is_org_admin", "org_reporting_opt_out", "analytics_opt_out", "locale", "distance_unit", "currency", "time_format", "share_redaction", "scim_external_id", "deactivated_at", "version", "created_at", "updated_at"}
+
+// NewUser holds the fields for creating a user
+type NewUser struct {
+	Email           string
+	Name            string
+	UserPreferences *string
+	// AuthProvider is how the user signs in; "local" when nil
+	AuthProvider *string
+	// SCIMExternalID is the user's ID in the identity provider provisioning them
+	SCIMExternalID *string
+}
+
+// UserUpdate is a partial update; nil fields are left unchanged
+type UserUpdate struct {
+	Email           *string
+	Name            *string
+	UserPreferences *string
+	// SCIMExternalID replaces the user's SCIM external ID; an empty one clears it
+	SCIMExternalID *string
+	// ExpectedVersion, if set, is the version the caller read; the update fails with
+	// ErrConflict if the user has been updated since
+	ExpectedVersion *int
+}
+
+// UserFilter selects a page of users for ListPage; a nil SCIMExternalID matches every user
+type UserFilter struct {
+	SCIMExternalID *string
+	Offset         int
+	Limit          int
+}
+
+// SQLUserRepository reads and writes users in Postgres
+type SQLUserRepository struct {
+	db *database.DB
+}
+
+// NewSQLUserRepository creates a user repository
+func NewSQLUserRepository(db *database.DB) *SQLUserRepository {
+	return &SQLUserRepository{db: db}
+}
+
+// Get returns a user by ID, or ErrNotFound
+func (r *SQLUserRepository) Get(ctx context.Context, id string) (*models.User, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	query := `SELECT ` + strings.Join(userColumns, ", ") + ` FROM users WHERE id = $1` + scope
+	user, err := scanUser(r.db.Reader().QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// GetByEmail returns the tenant's user with email, or ErrNotFound
+func (r *SQLUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{email})
+	query := `SELECT ` + strings.Join(userColumns, ", ") + ` FROM users WHERE email = $1` + scope
+	user, err := scanUser(r.db.Reader().QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// List returns all users of the tenant, newest first
+func (r *SQLUserRepository) List(ctx context.Context) ([]*models.User, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", nil)
+	query := `SELECT ` + strings.Join(userColumns, ", ") + ` FROM users WHERE TRUE` + scope + ` ORDER BY created_at DESC`
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// ListPage returns a page of the tenant's users matching filter, newest first, and how
+// many match in all
+func (r *SQLUserRepository) ListPage(ctx context.Context, filter UserFilter) ([]*models.User, int, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", nil)
+	from := ` FROM users WHERE TRUE` + scope
+	if filter.SCIMExternalID != nil {
+		args = append(args, *filter.SCIMExternalID)
+		from += fmt.Sprintf(` AND scim_external_id = $%d`, len(args))
+	}
+	var total int
+	if err := r.db.Reader().QueryRowContext(ctx, `SELECT COUNT(*)`+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := `SELECT ` + strings.Join(userColumns, ", ") + from + fmt.Sprintf(` ORDER BY created_at DESC, id ASC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+	return users, total, rows.Err()
+}
+
+// Create inserts a user into the tenant ctx is scoped to
+func (r *SQLUserRepository) Create(ctx context.Context, input NewUser) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	authProvider := "local"
+	if input.AuthProvider != nil {
+		authProvider = *input.AuthProvider
+	}
+	query := `INSERT INTO users (id, tenant_id, email, name, user_preferences, auth_provider, scim_external_id, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	          RETURNING ` + strings.Join(userColumns, ", ")
+
+	return scanUser(r.db.QueryRowContext(ctx, query, uuid.New().String(), tenant.OrDefault(ctx), input.Email, input.Name, input.UserPreferences, authProvider, input.SCIMExternalID, now, now))
+}
+
+// Update applies a partial update, or returns ErrNotFound
+func (r *SQLUserRepository) Update(ctx context.Context, id string, input UserUpdate) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("users").SetExpr("updated_at = CURRENT_TIMESTAMP").Version(input.ExpectedVersion)
+	if input.Email != nil {
+		b.Set("email", *input.Email)
+	}
+	if input.Name != nil {
+		b.Set("name", *input.Name)
+	}
+	if input.UserPreferences != nil {
+		b.Set("user_preferences", *input.UserPreferences)
+	}
+	if input.SCIMExternalID != nil {
+		if *input.SCIMExternalID == "" {
+			b.Set("scim_external_id", nil)
+		} else {
+			b.Set("scim_external_id", *input.SCIMExternalID)
+		}
+	}
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(userColumns...).Build()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, missedUpdate(ctx, r.db, "users", id, input.ExpectedVersion)
+	}
+	return user, err
+}
+
+// SetDefaultOffice sets the office the user normally commutes to; nil clears it. It
+// returns ErrNotFound if the user doesn't exist.
+func (r *SQLUserRepository) SetDefaultOffice(ctx context.Context, id string, officeID *string) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("users").Set("default_office_id", officeID).SetExpr("updated_at = CURRENT_TIMESTAMP").Version(nil)
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(userColumns...).Build()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// SetHomeAddress replaces the user's home address and its coordinates; nil clears them.
+// It returns ErrNotFound if the user doesn't exist.
+func (r *SQLUserRepository) SetHomeAddress(ctx context.Context, id string, address *string, latitude, longitude *float64) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("users").
+		Set("home_address", address).
+		Set("home_latitude", latitude).
+		Set("home_longitude", longitude).
+		SetExpr("updated_at = CURRENT_TIMESTAMP").
+		Version(nil)
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(userColumns...).Build()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// SetFocusMinutes sets the shortest deep-work block the user wants kept free each day;
+// nil clears it. It returns ErrNotFound if the user doesn't exist.
+func (r *SQLUserRepository) SetFocusMinutes(ctx context.Context, id string, minutes *int) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("users").Set("focus_minutes", minutes).SetExpr("updated_at = CURRENT_TIMESTAMP").Version(nil)
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(userColumns...).Build()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// SetActive deactivates the user, keeping their data, or reactivates them. It returns
+// ErrNotFound if the user doesn't exist.
+func (r *SQLUserRepository) SetActive(ctx context.Context, id string, active bool) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("users").SetExpr("updated_at = CURRENT_TIMESTAMP").Version(nil)
+	if active {
+		b.Set("deactivated_at", nil)
+	} else {
+		b.SetExpr("deactivated_at = COALESCE(deactivated_at, CURRENT_TIMESTAMP)")
+	}
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(userColumns...).Build()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// SetOrgAdmin grants or revokes the user's org admin role
+func (r *SQLUserRepository) SetOrgAdmin(ctx context.Context, id string, orgAdmin bool) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("users").Set("is_org_admin", orgAdmin).SetExpr("updated_at = CURRENT_TIMESTAMP").Version(nil)
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(userColumns...).Build()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// SetOrgReportingOptOut leaves the user out of, or back in, org reports
+func (r *SQLUserRepository) SetOrgReportingOptOut(ctx context.Context, id string, optOut bool) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("users").Set("org_reporting_opt_out", optOut).SetExpr("updated_at = CURRENT_TIMESTAMP").Version(nil)
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(userColumns...).Build()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// SetAnalyticsOptOut keeps the user's actions out of, or back in, product analytics
+func (r *SQLUserRepository) SetAnalyticsOptOut(ctx context.Context, id string, optOut bool) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("users").Set("analytics_opt_out", optOut).SetExpr("updated_at = CURRENT_TIMESTAMP").Version(nil)
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(userColumns...).Build()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// SetLocale sets or, with nil, clears the language the user's recommendation text is
+// shown in
+func (r *SQLUserRepository) SetLocale(ctx context.Context, id string, locale *string) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("users").Set("locale", locale).SetExpr("updated_at = CURRENT_TIMESTAMP").Version(nil)
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(userColumns...).Build()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// SetDisplayUnits sets how the user's distances, costs and times are shown; nil fields
+// return to the defaults
+func (r *SQLUserRepository) SetDisplayUnits(ctx context.Context, id string, distanceUnit *models.DistanceUnit, currency *string, timeFormat *models.TimeFormat) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("users").Set("distance_unit", distanceUnit).Set("currency", currency).Set("time_format", timeFormat).
+		SetExpr("updated_at = CURRENT_TIMESTAMP").Version(nil)
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(userColumns...).Build()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// SetShareRedaction sets or, with nil, clears what the user's share links leave out
+func (r *SQLUserRepository) SetShareRedaction(ctx context.Context, id string, policy *models.ShareRedaction) (*models.User, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	b := Update("users").Set("share_redaction", policy).SetExpr("updated_at = CURRENT_TIMESTAMP").Version(nil)
+	query, args := scopeUpdate(ctx, b.Where("id", id)).Returning(userColumns...).Build()
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// PreferredTimezone returns the user's IANA timezone, defaulting to UTC
+func (r *SQLUserRepository) PreferredTimezone(ctx context.Context, id string) (string, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	var timezone sql.NullString
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	err := r.db.Reader().QueryRowContext(ctx, `SELECT preferred_timezone FROM users WHERE id = $1`+scope, args...).Scan(&timezone)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if !timezone.Valid || timezone.String == "" {
+		return "UTC", nil
+	}
+	return timezone.String, nil
+}
+
+// OAuthTokens returns the user's stored OAuth tokens JSON, decrypted, or nil if none are
+// stored
+func (r *SQLUserRepository) OAuthTokens(ctx context.Context, id string) (*string, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	var tokens *string
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	err := r.db.QueryRowContext(ctx, `SELECT oauth_tokens FROM users WHERE id = $1`+scope, args...).Scan(&tokens)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.db.DecryptColumn(database.ColumnOAuthTokens, tokens)
+}
+
+// Delete removes a user, reporting whether a row was deleted
+func (r *SQLUserRepository) Delete(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	result, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`+scope, args...)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// scanUser scans a row selected with userColumns
+func scanUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID,
+		&user.TenantID,
+		&user.Email,
+		&user.Name,
+		&user.UserPreferences,
+		&user.DefaultOfficeID,
+		&user.HomeAddress,
+		&user.HomeLatitude,
+		&user.HomeLongitude,
+		&user.FocusMinutes,
+		&user.IsOrgAdmin,
+		&user.OrgReportingOptOut,
+		&user.AnalyticsOptOut,
+		&user.Locale,
+		&user.DistanceUnit,
+		&user.Currency,
+		&user.TimeFormat,
+		&user.ShareRedaction,
+		&user.SCIMExternalID,
+		&user.DeactivatedAt,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
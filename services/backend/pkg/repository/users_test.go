@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+func TestListUsersPage(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := tenant.WithID(context.Background(), tenant.DefaultID)
+	users := NewSQLUserRepository(db)
+	externalID := "00u1"
+	ada, err := users.Create(ctx, NewUser{Email: "ada@example.com", Name: "Ada", SCIMExternalID: &externalID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	createUser(t, ctx, db, "bob@example.com")
+	createUser(t, ctx, db, "grace@example.com")
+
+	seen := map[string]bool{}
+	for offset := 0; offset < 3; offset += 2 {
+		page, total, err := users.ListPage(ctx, UserFilter{Offset: offset, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 3 {
+			t.Errorf("offset %d: total = %d, want 3", offset, total)
+		}
+		for _, user := range page {
+			seen[user.ID] = true
+		}
+	}
+	if len(seen) != 3 {
+		t.Errorf("pages held %d distinct users, want all 3", len(seen))
+	}
+
+	page, total, err := users.ListPage(ctx, UserFilter{SCIMExternalID: &externalID, Limit: 10})
+	if err != nil || total != 1 || len(page) != 1 || page[0].ID != ada.ID {
+		t.Errorf("users with external ID = %v (of %d), %v; want Ada", page, total, err)
+	}
+	if page, total, err := users.ListPage(ctx, UserFilter{Offset: 5, Limit: 10}); err != nil || total != 3 || len(page) != 0 {
+		t.Errorf("past the end = %v (of %d), %v; want none of 3", page, total, err)
+	}
+}
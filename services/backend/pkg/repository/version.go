@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// queryRower is a *database.DB or a *database.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// missedUpdate tells why an UPDATE of row id in table, guarded by the expected version
+// (if any), matched nothing: ErrConflict if the row is there but has moved on since the
+// caller read it, ErrNotFound if it isn't
+func missedUpdate(ctx context.Context, q queryRower, table, id string, expected *int) error {
+	if expected == nil {
+		return ErrNotFound
+	}
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	var exists int
+	err := q.QueryRowContext(ctx, `SELECT 1 FROM `+table+` WHERE id = $1`+scope, args...).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+// checkVersion returns ErrConflict when an update expecting version would be stale
+func checkVersion(version int, expected *int) error {
+	if expected != nil && *expected != version {
+		return ErrConflict
+	}
+	return nil
+}
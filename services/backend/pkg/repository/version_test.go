@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+func TestSQLVersionedUpdates(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	user := createUser(t, ctx, db, "ada@example.com")
+	job := createJob(t, ctx, db, user.ID)
+	if user.Version != 1 || job.Version != 1 {
+		t.Fatalf("new rows at versions %d and %d, want 1", user.Version, job.Version)
+	}
+
+	// Two writers read version 1; the first update wins, the second is stale
+	jobs := NewSQLJobRepository(db)
+	progress, stale := 0.5, 1
+	updated, err := jobs.Update(ctx, job.ID, JobUpdate{Progress: &progress, ExpectedVersion: &stale})
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("first update: version %v, err %v", updated, err)
+	}
+	status := string(models.JobStatusInProgress)
+	if _, err := jobs.Update(ctx, job.ID, JobUpdate{Status: &status, ExpectedVersion: &stale}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("stale job update: err = %v, want ErrConflict", err)
+	}
+	if history, _ := jobs.Events(ctx, job.ID); len(history) != 1 {
+		t.Errorf("stale update left %d history events, want 1", len(history))
+	}
+	// Without an expected version an update always applies
+	if updated, err := jobs.Update(ctx, job.ID, JobUpdate{Status: &status}); err != nil || updated.Version != 3 {
+		t.Errorf("unconditional update: %v, %v", updated, err)
+	}
+	missing := 1
+	if _, err := jobs.Update(ctx, uuid.New().String(), JobUpdate{Progress: &progress, ExpectedVersion: &missing}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing job: err = %v, want ErrNotFound", err)
+	}
+
+	users := NewSQLUserRepository(db)
+	name := "Ada Lovelace"
+	if _, err := users.Update(ctx, user.ID, UserUpdate{Name: &name, ExpectedVersion: &stale}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Update(ctx, user.ID, UserUpdate{Name: &name, ExpectedVersion: &stale}); !errors.Is(err, ErrConflict) {
+		t.Errorf("stale user update: err = %v, want ErrConflict", err)
+	}
+
+	events := NewSQLEventRepository(db)
+	googleID := "google-1"
+	event := &models.CalendarEvent{
+		ID: uuid.New().String(), UserID: user.ID, Summary: "Standup", GoogleEventID: &googleID,
+		StartTime: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), EndTime: time.Date(2026, 3, 2, 9, 15, 0, 0, time.UTC),
+		MeetingType: models.MeetingTypeUnknown, AttendanceMode: models.AttendanceFlexible,
+	}
+	if err := events.UpsertByGoogleID(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	read, err := events.GetByGoogleID(ctx, user.ID, googleID)
+	if err != nil || read.Version != 1 {
+		t.Fatalf("inserted event: %v, %v", read, err)
+	}
+	read.Summary = "Team standup"
+	if err := events.UpsertByGoogleID(ctx, read); err != nil {
+		t.Fatal(err)
+	}
+	read.Summary = "Cancelled standup"
+	if err := events.UpsertByGoogleID(ctx, read); !errors.Is(err, ErrConflict) {
+		t.Errorf("stale event update: err = %v, want ErrConflict", err)
+	}
+	if current, _ := events.GetByGoogleID(ctx, user.ID, googleID); current.Summary != "Team standup" || current.Version != 2 {
+		t.Errorf("event = %q at version %d, want the first update at 2", current.Summary, current.Version)
+	}
+	if current, err := events.Get(ctx, user.ID, event.ID); err != nil || current.Summary != "Team standup" {
+		t.Errorf("event by ID: %v, %v", current, err)
+	}
+	if _, err := events.Get(ctx, uuid.New().String(), event.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("another user's event: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// webhookEndpointColumns is the column list scanned by scanWebhookEndpoint
+var webhookEndpointColumns = []string{"id", "user_id", "url", "secret", "events", "active", "created_at", "updated_at"}
+
+// webhookDeliveryColumns is the column list scanned by scanWebhookDelivery
+var webhookDeliveryColumns = []string{"id", "endpoint_id", "event_type", "payload", "status", "attempts", "last_status_code", "last_error", "next_attempt_at", "delivered_at", "created_at", "updated_at"}
+
+// SQLWebhookRepository reads and writes webhook endpoints and deliveries
+type SQLWebhookRepository struct {
+	db *database.DB
+}
+
+// NewSQLWebhookRepository creates a webhook repository
+func NewSQLWebhookRepository(db *database.DB) *SQLWebhookRepository {
+	return &SQLWebhookRepository{db: db}
+}
+
+// CreateEndpoint inserts an endpoint with its ID and secret already set; ErrNotFound if its
+// user is in another tenant
+func (r *SQLWebhookRepository) CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	if err := checkUserTenant(ctx, r.db, endpoint.UserID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO webhook_endpoints (` + strings.Join(webhookEndpointColumns, ", ") + `)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.ExecContext(ctx, query,
+		endpoint.ID,
+		endpoint.UserID,
+		endpoint.URL,
+		endpoint.Secret,
+		pq.StringArray(endpoint.Events),
+		endpoint.Active,
+		endpoint.CreatedAt,
+		endpoint.UpdatedAt,
+	)
+	return err
+}
+
+// GetEndpoint returns an endpoint by ID, or ErrNotFound
+func (r *SQLWebhookRepository) GetEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id})
+	query := `SELECT ` + strings.Join(webhookEndpointColumns, ", ") + ` FROM webhook_endpoints WHERE id = $1` + scope
+	endpoint, err := scanWebhookEndpoint(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return endpoint, err
+}
+
+// ListEndpoints returns a user's endpoints, oldest first
+func (r *SQLWebhookRepository) ListEndpoints(ctx context.Context, userID string) ([]*models.WebhookEndpoint, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{userID})
+	query := `SELECT ` + strings.Join(webhookEndpointColumns, ", ") + ` FROM webhook_endpoints WHERE user_id = $1` + scope + ` ORDER BY created_at ASC`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []*models.WebhookEndpoint
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+// DeleteEndpoint removes one of the user's endpoints and its delivery log
+func (r *SQLWebhookRepository) DeleteEndpoint(ctx context.Context, userID, id string) (bool, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{id, userID})
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2`+scope, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// CreateDelivery queues a delivery with its ID, payload and first attempt time set
+func (r *SQLWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO webhook_deliveries (` + strings.Join(webhookDeliveryColumns, ", ") + `)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	_, err := r.db.ExecContext(ctx, query, deliveryValues(delivery)...)
+	return err
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries whose next attempt is due, pushing
+// their next attempt out by lease so concurrent dispatchers don't send them twice
+func (r *SQLWebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE webhook_deliveries SET next_attempt_at = $1, updated_at = CURRENT_TIMESTAMP
+	          WHERE id IN (
+	              SELECT id FROM webhook_deliveries
+	              WHERE status = 'PENDING' AND next_attempt_at <= $2
+	              ORDER BY next_attempt_at ASC LIMIT $3
+	          ) AND status = 'PENDING' AND next_attempt_at <= $2
+	          RETURNING ` + strings.Join(webhookDeliveryColumns, ", ")
+	rows, err := r.db.QueryContext(ctx, query, now.Add(lease), now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// UpdateDelivery records the outcome of a delivery attempt
+func (r *SQLWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, cancel := r.db.WithWriteTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE webhook_deliveries
+	          SET status = $1, attempts = $2, last_status_code = $3, last_error = $4,
+	              next_attempt_at = $5, delivered_at = $6, updated_at = CURRENT_TIMESTAMP
+	          WHERE id = $7`,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.LastStatusCode,
+		delivery.LastError,
+		delivery.NextAttemptAt,
+		delivery.DeliveredAt,
+		delivery.ID,
+	)
+	return err
+}
+
+// ListDeliveries returns the most recent deliveries to one of the user's endpoints, newest
+// first. Another user's endpoint has no deliveries.
+func (r *SQLWebhookRepository) ListDeliveries(ctx context.Context, userID, endpointID string, limit int) ([]*models.WebhookDelivery, error) {
+	ctx, cancel := r.db.WithReadTimeout(ctx)
+	defer cancel()
+
+	scope, args := tenantClause(ctx, "tenant_id", []interface{}{endpointID, userID, limit})
+	query := `SELECT ` + strings.Join(webhookDeliveryColumns, ", ") + ` FROM webhook_deliveries
+	          WHERE endpoint_id = $1
+	            AND endpoint_id IN (SELECT id FROM webhook_endpoints WHERE user_id = $2` + scope + `)
+	          ORDER BY created_at DESC LIMIT $3`
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// deliveryValues returns insert values matching webhookDeliveryColumns
+func deliveryValues(d *models.WebhookDelivery) []interface{} {
+	return []interface{}{
+		d.ID,
+		d.EndpointID,
+		d.EventType,
+		d.Payload,
+		d.Status,
+		d.Attempts,
+		d.LastStatusCode,
+		d.LastError,
+		d.NextAttemptAt,
+		d.DeliveredAt,
+		d.CreatedAt,
+		d.UpdatedAt,
+	}
+}
+
+// scanWebhookEndpoint scans a row selected with webhookEndpointColumns
+func scanWebhookEndpoint(row rowScanner) (*models.WebhookEndpoint, error) {
+	endpoint := &models.WebhookEndpoint{}
+	var events pq.StringArray
+	err := row.Scan(
+		&endpoint.ID,
+		&endpoint.UserID,
+		&endpoint.URL,
+		&endpoint.Secret,
+		&events,
+		&endpoint.Active,
+		&endpoint.CreatedAt,
+		&endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Events = []string(events)
+	if endpoint.Events == nil {
+		endpoint.Events = []string{}
+	}
+	return endpoint, nil
+}
+
+// scanWebhookDelivery scans a row selected with webhookDeliveryColumns
+func scanWebhookDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.EndpointID,
+		&delivery.EventType,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.NextAttemptAt,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+func TestSQLWebhookOwnerScoping(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	webhooks := NewSQLWebhookRepository(db)
+
+	ada := createUser(t, ctx, db, "ada@example.com")
+	bob := createUser(t, ctx, db, "bob@example.com")
+	now := time.Now().UTC().Truncate(time.Second)
+	endpoint := &models.WebhookEndpoint{
+		ID:        uuid.New().String(),
+		UserID:    ada.ID,
+		URL:       "https://hooks.example.com/ada",
+		Secret:    "whsec_0123456789abcdef",
+		Events:    []string{},
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := webhooks.CreateEndpoint(ctx, endpoint); err != nil {
+		t.Fatal(err)
+	}
+	delivery := &models.WebhookDelivery{
+		ID:            uuid.New().String(),
+		EndpointID:    endpoint.ID,
+		EventType:     "job.created",
+		Payload:       `{}`,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := webhooks.CreateDelivery(ctx, delivery); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another user sees no deliveries and can't delete the endpoint
+	deliveries, err := webhooks.ListDeliveries(ctx, bob.ID, endpoint.ID, 10)
+	if err != nil || len(deliveries) != 0 {
+		t.Fatalf("bob's deliveries = %d, %v, want none", len(deliveries), err)
+	}
+	if deleted, err := webhooks.DeleteEndpoint(ctx, bob.ID, endpoint.ID); err != nil || deleted {
+		t.Fatalf("bob deleting ada's endpoint = %v, %v, want false", deleted, err)
+	}
+
+	deliveries, err = webhooks.ListDeliveries(ctx, ada.ID, endpoint.ID, 10)
+	if err != nil || len(deliveries) != 1 || deliveries[0].ID != delivery.ID {
+		t.Fatalf("ada's deliveries = %v, %v, want %s", deliveries, err, delivery.ID)
+	}
+	if deleted, err := webhooks.DeleteEndpoint(ctx, ada.ID, endpoint.ID); err != nil || !deleted {
+		t.Fatalf("ada deleting her endpoint = %v, %v, want true", deleted, err)
+	}
+}
@@ -0,0 +1,75 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/allday"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// markerEvents returns a user's events that may cover a day in [from, to) (midnight UTC
+// dates): those starting in the range, plus earlier ones a multi-day marker could span
+func (r *Resolver) markerEvents(ctx context.Context, userID string, from, to time.Time) ([]*models.CalendarEvent, error) {
+	// Local days run up to 14 hours either side of the UTC ones
+	start, end := from.Add(-allday.MaxSpan), to.Add(14*time.Hour)
+	var events []*models.CalendarEvent
+	err := r.events.Stream(ctx, userID, repository.DateRange{From: &start, To: &end}, func(event *models.CalendarEvent) error {
+		if !event.StartTime.Before(from.Add(-14*time.Hour)) || allday.Classify(event) != "" {
+			events = append(events, event)
+		}
+		return nil
+	})
+	return events, err
+}
+
+// withDayMarkers adds what the calendar says about targetDate as a whole (the user is out
+// of office, or blocked it for remote work) to the job's input data as context.day, so
+// the planner skips the commute or plans the day remote. Like preferences they are
+// context only: failures are logged and the job planned without them.
+func (r *Resolver) withDayMarkers(ctx context.Context, userID, targetDate string, inputData *string) *string {
+	if len(targetDate) > 10 {
+		targetDate = targetDate[:10]
+	}
+	date, err := time.Parse("2006-01-02", targetDate)
+	if err != nil {
+		return inputData
+	}
+	location, err := r.userLocation(ctx, userID)
+	if err != nil {
+		return inputData
+	}
+	events, err := r.markerEvents(ctx, userID, date, date.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("Failed to read the all-day events of user %s: %v", userID, err)
+		return inputData
+	}
+	day := allday.Plan(events, date, location)
+	if day == nil {
+		return inputData
+	}
+
+	data := map[string]interface{}{}
+	if inputData != nil && strings.TrimSpace(*inputData) != "" {
+		if err := json.Unmarshal([]byte(*inputData), &data); err != nil {
+			return inputData
+		}
+	}
+	jobContext, ok := data["context"].(map[string]interface{})
+	if !ok {
+		jobContext = map[string]interface{}{}
+	}
+	jobContext["day"] = day
+	data["context"] = jobContext
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return inputData
+	}
+	enriched := string(encoded)
+	return &enriched
+}
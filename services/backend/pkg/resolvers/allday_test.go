@@ -0,0 +1,62 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/allday"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+func TestCreateJobDayMarkers(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	date := func(value string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return parsed
+	}
+	add := func(summary string, start, end time.Time, allDay bool) {
+		t.Helper()
+		event := &models.CalendarEvent{ID: uuid.New().String(), UserID: user.ID, Summary: summary, StartTime: start, EndTime: end, IsAllDay: allDay}
+		if err := repos.Events.Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two weeks off from Monday 2 March, and a focus day on the Monday after
+	add("Vacation", date("2026-03-02"), date("2026-03-14"), true)
+	add("Focus day", date("2026-03-16"), date("2026-03-17"), true)
+	add("Standup", date("2026-03-16").Add(9*time.Hour), date("2026-03-16").Add(9*time.Hour+15*time.Minute), false)
+
+	day := func(targetDate string) *allday.Day {
+		t.Helper()
+		job, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: targetDate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var input struct {
+			Context struct {
+				Day *allday.Day `json:"day"`
+			} `json:"context"`
+		}
+		if job.InputData != nil {
+			if err := json.Unmarshal([]byte(*job.InputData), &input); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return input.Context.Day
+	}
+
+	if got := day("2026-03-11"); got == nil || got.Status != allday.StatusSkip || got.Reason != "Out of office: Vacation" {
+		t.Errorf("day in the second week of a vacation = %+v, want SKIP", got)
+	}
+	if got := day("2026-03-16"); got == nil || got.Status != allday.StatusRemote || len(got.Markers) != 1 {
+		t.Errorf("focus day = %+v, want REMOTE with one marker", got)
+	}
+	if got := day("2026-03-17"); got != nil {
+		t.Errorf("ordinary day = %+v, want no day context", got)
+	}
+}
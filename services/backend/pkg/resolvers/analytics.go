@@ -0,0 +1,40 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// AnalyticsTracker records anonymized product analytics events; implemented by
+// analytics.Tracker
+type AnalyticsTracker interface {
+	Track(ctx context.Context, userID, event string, properties map[string]interface{})
+}
+
+// TrackAnalyticsWith records product analytics events with tracker
+func (r *Resolver) TrackAnalyticsWith(tracker AnalyticsTracker) {
+	r.analytics = tracker
+}
+
+// track records an analytics event of the user's, if analytics are on
+func (r *Resolver) track(ctx context.Context, userID, event string, properties map[string]interface{}) {
+	if r.analytics == nil {
+		return
+	}
+	r.analytics.Track(ctx, userID, event, properties)
+}
+
+// SetAnalyticsOptOut keeps the user's actions out of, or back in, product analytics
+func (r *Resolver) SetAnalyticsOptOut(ctx context.Context, userID string, optOut bool) (*models.User, error) {
+	user, err := r.users.SetAnalyticsOptOut(ctx, userID, optOut)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("user not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	return user, nil
+}
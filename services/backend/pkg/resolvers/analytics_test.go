@@ -0,0 +1,76 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/analytics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+type trackedEvent struct {
+	userID     string
+	event      string
+	properties map[string]interface{}
+}
+
+type recordingTracker struct {
+	events []trackedEvent
+}
+
+func (t *recordingTracker) Track(ctx context.Context, userID, event string, properties map[string]interface{}) {
+	t.events = append(t.events, trackedEvent{userID, event, properties})
+}
+
+func TestAnalyticsEvents(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	tracker := &recordingTracker{}
+	r.TrackAnalyticsWith(tracker)
+	user := createTestUser(t, repos, "ada@example.com")
+
+	job, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	completed, inProgress := string(models.JobStatusCompleted), string(models.JobStatusInProgress)
+	for _, status := range []*string{&inProgress, &completed} {
+		if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := &models.CommuteRecommendation{JobID: job.ID, OptionRank: 2, OptionType: models.CommuteOptionFullRemoteRecommended}
+	if err := repos.Recommendations.Create(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing is recorded when another user tries to accept the recommendation
+	bob := createTestUser(t, repos, "bob@example.com")
+	if _, err := r.AcceptCommuteRecommendation(ctx, bob.ID, rec.ID); err == nil {
+		t.Error("another user accepted the recommendation")
+	}
+	if _, err := r.AcceptCommuteRecommendation(ctx, user.ID, rec.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{analytics.EventPlanRequested, analytics.EventPlanAccepted, analytics.EventOptionRankChosen}
+	if len(tracker.events) != len(want) {
+		t.Fatalf("events = %+v, want %v", tracker.events, want)
+	}
+	for i, event := range tracker.events {
+		if event.event != want[i] || event.userID != user.ID {
+			t.Errorf("event %d = %+v, want %s", i, event, want[i])
+		}
+	}
+	if requested := tracker.events[0].properties; requested["priority"] != models.JobPriorityInteractive || requested["scheduled"] != false {
+		t.Errorf("plan_requested properties = %v", requested)
+	}
+	if chosen := tracker.events[2].properties; chosen["rank"] != 2 {
+		t.Errorf("option_rank_chosen properties = %v", chosen)
+	}
+
+	updated, err := r.SetAnalyticsOptOut(ctx, user.ID, true)
+	if err != nil || !updated.AnalyticsOptOut {
+		t.Errorf("user = %+v, %v, want them opted out", updated, err)
+	}
+}
@@ -0,0 +1,163 @@
+package resolvers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// APITokenPrefix starts every API token, telling them apart from session JWTs
+const APITokenPrefix = "cpat_"
+
+// ErrInvalidAPIToken is returned for unknown and expired API tokens
+var ErrInvalidAPIToken = errors.New("invalid API token")
+
+const (
+	// maxAPITokens caps the tokens a user can have
+	maxAPITokens = 20
+	// maxAPITokenDays caps how long a token can be valid for when it expires at all
+	maxAPITokenDays = 365
+	// apiTokenTouchInterval is how often a token's last use is recorded, so that every
+	// request doesn't write it
+	apiTokenTouchInterval = time.Minute
+)
+
+// CreateAPITokenInput creates a personal access token
+type CreateAPITokenInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresInDays is how long the token is valid for, up to a year; nil never expires
+	ExpiresInDays *int `json:"expiresInDays"`
+}
+
+// CreateAPIToken gives the user a personal access token with scopes. Only a hash of the
+// token is kept, so its Token is only returned here.
+func (r *Resolver) CreateAPIToken(ctx context.Context, userID string, input CreateAPITokenInput) (*models.APIToken, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return nil, fmt.Errorf("name is required, up to 100 characters")
+	}
+	if len(input.Scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	var scopes []models.APITokenScope
+	for _, s := range input.Scopes {
+		scope := models.APITokenScope(s)
+		if !scope.IsValid() {
+			return nil, fmt.Errorf("unknown scope %q", s)
+		}
+		if !containsScope(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	token := &models.APIToken{UserID: userID, Name: name, Scopes: scopes}
+	if input.ExpiresInDays != nil {
+		if *input.ExpiresInDays < 1 || *input.ExpiresInDays > maxAPITokenDays {
+			return nil, fmt.Errorf("expiresInDays must be between 1 and %d", maxAPITokenDays)
+		}
+		expiresAt := time.Now().AddDate(0, 0, *input.ExpiresInDays).Truncate(time.Second)
+		token.ExpiresAt = &expiresAt
+	}
+
+	existing, err := r.apiTokens.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching API tokens: %w", err)
+	}
+	if len(existing) >= maxAPITokens {
+		return nil, fmt.Errorf("a user can have at most %d API tokens; revoke one first", maxAPITokens)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("error generating API token: %w", err)
+	}
+	secret := APITokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	token.Prefix = secret[:len(APITokenPrefix)+6]
+	if err := r.apiTokens.Create(ctx, token, apiTokenHash(secret)); err != nil {
+		return nil, fmt.Errorf("error saving API token: %w", err)
+	}
+	token.Token = secret
+	return token, nil
+}
+
+// APITokens returns the user's API tokens, newest first, without their secrets
+func (r *Resolver) APITokens(ctx context.Context, userID string) ([]*models.APIToken, error) {
+	tokens, err := r.apiTokens.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching API tokens: %w", err)
+	}
+	if tokens == nil {
+		tokens = []*models.APIToken{}
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken stops one of the user's tokens working, reporting whether they have it
+func (r *Resolver) RevokeAPIToken(ctx context.Context, userID, id string) (bool, error) {
+	revoked, err := r.apiTokens.Delete(ctx, userID, id)
+	if err != nil {
+		return false, fmt.Errorf("error revoking API token: %w", err)
+	}
+	return revoked, nil
+}
+
+// AuthenticateAPIToken returns the user an API token acts for and the token, or
+// ErrInvalidAPIToken
+func (r *Resolver) AuthenticateAPIToken(ctx context.Context, secret string) (*models.User, *models.APIToken, error) {
+	if !strings.HasPrefix(secret, APITokenPrefix) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	token, err := r.apiTokens.GetByHash(ctx, apiTokenHash(secret))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching API token: %w", err)
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && !now.Before(*token.ExpiresAt) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	user, err := r.users.Get(ctx, token.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		// The user belongs to another tenant than the request
+		return nil, nil, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.DeactivatedAt != nil {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if err := r.apiTokens.Touch(ctx, token.ID, now); err != nil {
+			log.Printf("Failed to record the use of API token %s: %v", token.ID, err)
+		}
+	}
+	return user, token, nil
+}
+
+func apiTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func containsScope(scopes []models.APITokenScope, scope models.APITokenScope) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestAPITokens(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+
+	if _, err := r.CreateAPIToken(ctx, user.ID, CreateAPITokenInput{Name: "cli"}); err == nil {
+		t.Error("token without scopes created")
+	}
+	if _, err := r.CreateAPIToken(ctx, user.ID, CreateAPITokenInput{Name: "cli", Scopes: []string{"admin"}}); err == nil {
+		t.Error("token with an unknown scope created")
+	}
+
+	input := CreateAPITokenInput{Name: "extension", Scopes: []string{"read:calendar", "read:calendar"}}
+	token, err := r.CreateAPIToken(ctx, user.ID, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token.Token, APITokenPrefix) || !strings.HasPrefix(token.Token, token.Prefix) || len(token.Scopes) != 1 {
+		t.Fatalf("token = %+v, want a %s token with one scope", token, APITokenPrefix)
+	}
+
+	got, authenticated, err := r.AuthenticateAPIToken(ctx, token.Token)
+	if err != nil || got.ID != user.ID || !authenticated.HasScope(models.ScopeReadCalendar) || authenticated.HasScope(models.ScopeWriteJobs) {
+		t.Fatalf("authenticated %+v, %+v, %v; want ada with read:calendar only", got, authenticated, err)
+	}
+	tokens, err := r.APITokens(ctx, user.ID)
+	if err != nil || len(tokens) != 1 || tokens[0].Token != "" || tokens[0].LastUsedAt == nil {
+		t.Fatalf("tokens = %+v, %v; want the used token without its secret", tokens, err)
+	}
+	if _, _, err := r.AuthenticateAPIToken(ctx, token.Token+"x"); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("wrong token: err = %v, want %v", err, ErrInvalidAPIToken)
+	}
+
+	if revoked, err := r.RevokeAPIToken(ctx, createTestUser(t, repos, "bob@example.com").ID, token.ID); err != nil || revoked {
+		t.Errorf("another user revoked the token: %v, %v", revoked, err)
+	}
+	if revoked, err := r.RevokeAPIToken(ctx, user.ID, token.ID); err != nil || !revoked {
+		t.Fatalf("revoke = %v, %v", revoked, err)
+	}
+	if _, _, err := r.AuthenticateAPIToken(ctx, token.Token); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("revoked token: err = %v, want %v", err, ErrInvalidAPIToken)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	expired := &models.APIToken{UserID: user.ID, Name: "ci", Prefix: "cpat_expire", Scopes: []models.APITokenScope{models.ScopeWriteJobs}, ExpiresAt: &past}
+	if err := repos.APITokens.Create(ctx, expired, apiTokenHash(APITokenPrefix+"expired")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.AuthenticateAPIToken(ctx, APITokenPrefix+"expired"); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("expired token: err = %v, want %v", err, ErrInvalidAPIToken)
+	}
+}
@@ -0,0 +1,129 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/google/uuid"
+)
+
+// ArtifactStore presigns URLs to the bucket job artifacts are kept in; implemented by
+// storage.Client
+type ArtifactStore interface {
+	PresignGet(key string, expires time.Duration) (string, error)
+	PresignPut(key, contentType string, expires time.Duration) (string, error)
+}
+
+// ErrArtifactStorageDisabled is returned for artifact uploads without object storage
+var ErrArtifactStorageDisabled = errors.New("artifact storage is not configured")
+
+const (
+	defaultArtifactURLTTL = 15 * time.Minute
+	// maxArtifactURLTTL is the longest S3-compatible stores accept
+	maxArtifactURLTTL = 7 * 24 * time.Hour
+)
+
+// JobArtifactUploadInput describes an artifact the AI worker is about to upload
+type JobArtifactUploadInput struct {
+	Kind        models.JobArtifactKind `json:"kind"`
+	ContentType string                 `json:"contentType"`
+	SizeBytes   *int64                 `json:"sizeBytes"`
+}
+
+// JobArtifactUpload is where to PUT a new artifact
+type JobArtifactUpload struct {
+	Artifact  *models.JobArtifact `json:"artifact"`
+	UploadURL string              `json:"uploadUrl"`
+	ExpiresAt time.Time           `json:"expiresAt"`
+}
+
+// StoreArtifactsIn keeps job artifacts in store, with presigned URLs valid for urlTTL.
+// Without it artifacts can't be uploaded.
+func (r *Resolver) StoreArtifactsIn(store ArtifactStore, urlTTL time.Duration) {
+	if urlTTL <= 0 || urlTTL > maxArtifactURLTTL {
+		urlTTL = defaultArtifactURLTTL
+	}
+	r.artifactStore, r.artifactURLTTL = store, urlTTL
+}
+
+// CreateJobArtifactUpload records an artifact of a job and returns a presigned URL the
+// AI worker uploads it to, sending input.ContentType as its Content-Type. Only the admin
+// token, which the worker runs with, may upload: owners download what is stored here.
+func (r *Resolver) CreateJobArtifactUpload(ctx context.Context, viewer authz.Viewer, jobID string, input JobArtifactUploadInput) (*JobArtifactUpload, error) {
+	if !viewer.Has(authz.RoleAdmin) {
+		return nil, authz.ErrForbidden
+	}
+	if r.artifactStore == nil {
+		return nil, ErrArtifactStorageDisabled
+	}
+	if !input.Kind.IsValid() {
+		return nil, fmt.Errorf("invalid artifact kind %q", input.Kind)
+	}
+	if strings.TrimSpace(input.ContentType) == "" {
+		return nil, errors.New("contentType is required")
+	}
+	if input.SizeBytes != nil && *input.SizeBytes < 0 {
+		return nil, errors.New("sizeBytes cannot be negative")
+	}
+	job, err := r.jobs.Get(ctx, jobID)
+	if err == repository.ErrNotFound {
+		return nil, fmt.Errorf("job not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching job: %w", err)
+	}
+
+	artifact := &models.JobArtifact{
+		ID:          uuid.New().String(),
+		JobID:       job.ID,
+		Kind:        input.Kind,
+		ContentType: input.ContentType,
+		SizeBytes:   input.SizeBytes,
+	}
+	artifact.StorageKey = fmt.Sprintf("jobs/%s/%s/%s", job.ID, strings.ToLower(string(input.Kind)), artifact.ID)
+	expiresAt := time.Now().Add(r.artifactURLTTL)
+	uploadURL, err := r.artifactStore.PresignPut(artifact.StorageKey, artifact.ContentType, r.artifactURLTTL)
+	if err != nil {
+		return nil, fmt.Errorf("error presigning artifact upload: %w", err)
+	}
+	if err := r.artifacts.Create(ctx, artifact); err != nil {
+		return nil, fmt.Errorf("error saving artifact: %w", err)
+	}
+	return &JobArtifactUpload{Artifact: artifact, UploadURL: uploadURL, ExpiresAt: expiresAt}, nil
+}
+
+// JobArtifacts returns the artifacts of one of the user's jobs with presigned download
+// URLs, valid for expiresIn seconds when given
+func (r *Resolver) JobArtifacts(ctx context.Context, userID, jobID string, expiresIn *int) ([]*models.JobArtifact, error) {
+	ttl := r.artifactURLTTL
+	if expiresIn != nil {
+		ttl = time.Duration(*expiresIn) * time.Second
+		if ttl < time.Minute || ttl > maxArtifactURLTTL {
+			return nil, fmt.Errorf("expiresIn must be between 60 and %d seconds", int(maxArtifactURLTTL.Seconds()))
+		}
+	}
+	job, err := r.userJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts, err := r.artifacts.ListByJob(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching job artifacts: %w", err)
+	}
+	if r.artifactStore == nil {
+		return artifacts, nil
+	}
+	for _, artifact := range artifacts {
+		if artifact.URL, err = r.artifactStore.PresignGet(artifact.StorageKey, ttl); err != nil {
+			return nil, fmt.Errorf("error presigning artifact download: %w", err)
+		}
+	}
+	return artifacts, nil
+}
@@ -0,0 +1,73 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+type fakeArtifactStore struct{}
+
+func (fakeArtifactStore) PresignGet(key string, expires time.Duration) (string, error) {
+	return "https://bucket.example/" + key + "?get=" + expires.String(), nil
+}
+
+func (fakeArtifactStore) PresignPut(key, contentType string, expires time.Duration) (string, error) {
+	return "https://bucket.example/" + key + "?put", nil
+}
+
+func TestJobArtifacts(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	ada := createTestUser(t, repos, "ada@example.com")
+	bob := createTestUser(t, repos, "bob@example.com")
+	job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: ada.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := JobArtifactUploadInput{Kind: models.JobArtifactReasoningTrace, ContentType: "application/json"}
+	worker := authz.Viewer{Admin: true}
+
+	if _, err := r.CreateJobArtifactUpload(ctx, worker, job.ID, input); !errors.Is(err, ErrArtifactStorageDisabled) {
+		t.Fatalf("upload without storage: err = %v", err)
+	}
+	r.StoreArtifactsIn(fakeArtifactStore{}, 0)
+
+	// Users, the job's owner included, can't replace what the worker stored
+	for _, viewer := range []authz.Viewer{{UserID: ada.ID}, {}} {
+		if _, err := r.CreateJobArtifactUpload(ctx, viewer, job.ID, input); !errors.Is(err, authz.ErrForbidden) {
+			t.Errorf("upload by %+v: err = %v, want %v", viewer, err, authz.ErrForbidden)
+		}
+	}
+	upload, err := r.CreateJobArtifactUpload(ctx, worker, job.ID, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantKey := "jobs/" + job.ID + "/reasoning_trace/" + upload.Artifact.ID
+	if upload.Artifact.StorageKey != wantKey || upload.UploadURL != "https://bucket.example/"+wantKey+"?put" {
+		t.Errorf("upload = %+v, want key %s", upload, wantKey)
+	}
+	if _, err := r.CreateJobArtifactUpload(ctx, worker, job.ID, JobArtifactUploadInput{Kind: "VIDEO", ContentType: "video/mp4"}); err == nil {
+		t.Error("unknown artifact kind accepted")
+	}
+
+	artifacts, err := r.JobArtifacts(ctx, ada.ID, job.ID, nil)
+	if err != nil || len(artifacts) != 1 {
+		t.Fatalf("artifacts = %v, %v", artifacts, err)
+	}
+	if artifacts[0].URL != "https://bucket.example/"+wantKey+"?get=15m0s" {
+		t.Errorf("download URL = %s, want one valid for the default 15 minutes", artifacts[0].URL)
+	}
+	hour := 3600
+	if artifacts, _ := r.JobArtifacts(ctx, ada.ID, job.ID, &hour); len(artifacts) != 1 || artifacts[0].URL != "https://bucket.example/"+wantKey+"?get=1h0m0s" {
+		t.Errorf("expiresIn ignored: %v", artifacts)
+	}
+	if _, err := r.JobArtifacts(ctx, bob.ID, job.ID, nil); err == nil {
+		t.Error("another user's job artifacts listed")
+	}
+}
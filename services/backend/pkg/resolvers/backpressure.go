@@ -0,0 +1,160 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// ErrCodeRetryLater prefixes the error of a job turned away because the pipeline is
+// saturated
+const ErrCodeRetryLater = "RETRY_LATER"
+
+// What createJob does with jobs while the pipeline is saturated
+const (
+	// BackpressureReject turns jobs away with a *RetryLaterError
+	BackpressureReject = "reject"
+	// BackpressureOverflow queues interactive jobs as BATCH, behind every interactive
+	// job, until the overflow is full too
+	BackpressureOverflow = "overflow"
+)
+
+// backpressureSampleTTL is how long a count of the pipeline's jobs is reused for, so a
+// burst of creates doesn't count the jobs table for each
+const backpressureSampleTTL = 5 * time.Second
+
+// backpressureRateWindow is how far back finished jobs are counted to estimate how fast
+// workers get through the queue
+const backpressureRateWindow = 10 * time.Minute
+
+// Bounds of the wait a RetryLaterError estimates
+const (
+	minRetryAfter = 5 * time.Second
+	maxRetryAfter = time.Hour
+)
+
+// BackpressureLimits saturate the pipeline. Zero disables a limit.
+type BackpressureLimits struct {
+	// MaxQueued caps the jobs due now and waiting for a worker
+	MaxQueued int
+	// MaxInProgress caps the jobs workers are running
+	MaxInProgress int
+	// Mode is BackpressureReject or BackpressureOverflow
+	Mode string
+	// MaxOverflow caps the BATCH jobs waiting in overflow mode, past which jobs are
+	// rejected after all; 0 doesn't cap them
+	MaxOverflow int
+}
+
+// RetryLaterError is returned by CreateJob when the pipeline is saturated
+type RetryLaterError struct {
+	Reason string
+	// RetryAfter estimates when the pipeline will have room, from how fast workers
+	// have been finishing jobs
+	RetryAfter time.Duration
+}
+
+func (e *RetryLaterError) Error() string {
+	return fmt.Sprintf("%s: %s; retry in about %d seconds", ErrCodeRetryLater, e.Reason, int(e.RetryAfter.Seconds()))
+}
+
+// backpressure sheds load when the pipeline is saturated
+type backpressure struct {
+	limits BackpressureLimits
+
+	mu        sync.Mutex
+	load      repository.JobLoad
+	sampledAt time.Time
+}
+
+// ApplyBackpressure keeps createJob from growing the pipeline's backlog past limits: jobs
+// created while it is saturated are rejected, or overflow into the batch queue
+func (r *Resolver) ApplyBackpressure(limits BackpressureLimits) error {
+	if limits.Mode == "" {
+		limits.Mode = BackpressureReject
+	}
+	if limits.Mode != BackpressureReject && limits.Mode != BackpressureOverflow {
+		return fmt.Errorf("unknown backpressure mode %q (expected %s or %s)", limits.Mode, BackpressureReject, BackpressureOverflow)
+	}
+	if limits.MaxQueued <= 0 && limits.MaxInProgress <= 0 {
+		r.backpressure = nil
+		return nil
+	}
+	r.backpressure = &backpressure{limits: limits}
+	return nil
+}
+
+// checkBackpressure returns the priority to create a job due now with, or a
+// *RetryLaterError when the pipeline has no room for it. Like the quotas, the count is a
+// few seconds old, so a burst can overshoot a limit slightly.
+func (r *Resolver) checkBackpressure(ctx context.Context, priority models.JobPriority) (models.JobPriority, error) {
+	if r.backpressure == nil {
+		return priority, nil
+	}
+	limits := r.backpressure.limits
+	load, err := r.pipelineLoad(ctx)
+	if err != nil {
+		// Shedding load is a safeguard; failing every create with the database is worse
+		log.Printf("Failed to count the pipeline's jobs: %v", err)
+		return priority, nil
+	}
+
+	var reason string
+	var excess int
+	switch {
+	case limits.MaxQueued > 0 && load.Queued >= limits.MaxQueued:
+		reason = fmt.Sprintf("%d jobs are waiting for a worker", load.Queued)
+		excess = load.Queued - limits.MaxQueued + 1
+	case limits.MaxInProgress > 0 && load.Running >= limits.MaxInProgress:
+		reason = fmt.Sprintf("%d jobs are running", load.Running)
+		excess = load.Running - limits.MaxInProgress + 1
+	default:
+		return priority, nil
+	}
+
+	if limits.Mode == BackpressureOverflow && (limits.MaxOverflow <= 0 || load.QueuedBatch < limits.MaxOverflow) {
+		metrics.JobBackpressure.WithLabelValues("overflowed").Inc()
+		return models.JobPriorityBatch, nil
+	}
+	metrics.JobBackpressure.WithLabelValues("rejected").Inc()
+	return "", &RetryLaterError{
+		Reason:     "the planner is at capacity (" + reason + ")",
+		RetryAfter: retryAfter(excess, load.Finished),
+	}
+}
+
+// pipelineLoad counts the pipeline's jobs, reusing a count taken in the last few seconds
+func (r *Resolver) pipelineLoad(ctx context.Context) (repository.JobLoad, error) {
+	b := r.backpressure
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(b.sampledAt) < backpressureSampleTTL {
+		return b.load, nil
+	}
+	load, err := r.jobs.Load(ctx, now, now.Add(-backpressureRateWindow))
+	if err != nil {
+		return load, err
+	}
+	b.load, b.sampledAt = load, now
+	return load, nil
+}
+
+// retryAfter estimates how long workers take to get through excess jobs, at the rate
+// they finished jobs over the last backpressureRateWindow
+func retryAfter(excess, finished int) time.Duration {
+	if finished == 0 {
+		return time.Minute
+	}
+	wait := float64(backpressureRateWindow) * float64(excess) / float64(finished)
+	wait = math.Min(math.Max(wait, float64(minRetryAfter)), float64(maxRetryAfter))
+	return time.Duration(wait).Round(time.Second)
+}
@@ -0,0 +1,65 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestCreateJobBackpressure(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	create := func(email string) (*models.Job, error) {
+		user := createTestUser(t, repos, email)
+		return r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+	}
+	if err := r.ApplyBackpressure(BackpressureLimits{MaxQueued: 1, Mode: BackpressureOverflow, MaxOverflow: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, err := create("ada@example.com"); err != nil || job.Priority != models.JobPriorityInteractive {
+		t.Fatalf("first job = %+v, %v; want it queued as usual", job, err)
+	}
+	r.backpressure.sampledAt = time.Time{}
+	if job, err := create("bob@example.com"); err != nil || job.Priority != models.JobPriorityBatch {
+		t.Fatalf("job while saturated = %+v, %v; want it queued as BATCH", job, err)
+	}
+
+	// The overflow is full too
+	r.backpressure.sampledAt = time.Time{}
+	_, err := create("cy@example.com")
+	var retryErr *RetryLaterError
+	if !errors.As(err, &retryErr) || retryErr.RetryAfter != time.Minute {
+		t.Fatalf("job with the overflow full: error = %v, want %s with the default wait", err, ErrCodeRetryLater)
+	}
+
+	// A job scheduled ahead doesn't add to the backlog
+	user := createTestUser(t, repos, "dee@example.com")
+	scheduleAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	if _, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02", ScheduleAt: &scheduleAt}); err != nil {
+		t.Errorf("scheduled job: %v", err)
+	}
+
+	if err := r.ApplyBackpressure(BackpressureLimits{MaxQueued: 1, Mode: "drop"}); err == nil {
+		t.Error("unknown backpressure mode accepted")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, tt := range []struct {
+		excess, finished int
+		want             time.Duration
+	}{
+		{excess: 1, finished: 0, want: time.Minute},
+		{excess: 10, finished: 60, want: 100 * time.Second},
+		{excess: 1, finished: 600, want: 5 * time.Second},
+		{excess: 100000, finished: 1, want: time.Hour},
+	} {
+		if got := retryAfter(tt.excess, tt.finished); got != tt.want {
+			t.Errorf("retryAfter(%d, %d) = %s, want %s", tt.excess, tt.finished, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package resolvers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestBulkCreateCalendarEvents(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	text := func(s string) *string { return &s }
+	row := func(id string) CalendarEventInput {
+		return CalendarEventInput{ID: text(id), UserID: user.ID, Summary: "Meeting " + id, StartTime: start, EndTime: start.Add(time.Hour)}
+	}
+
+	typed := row("typed")
+	typed.MeetingType = text(string(models.MeetingTypeClientMeeting))
+	typed.AttendanceMode = text(string(models.AttendanceMustBeInOffice))
+	badType := row("bad-type")
+	badType.MeetingType = text("STANDUP")
+	badMode := row("bad-mode")
+	badMode.AttendanceMode = text("REMOTE_WITH_VIDEO")
+	reversed := row("reversed")
+	reversed.EndTime = start.Add(-time.Hour)
+	// Rows default to the user, and can't add to another user's calendar
+	omitted := row("omitted")
+	omitted.UserID = ""
+	other := row("other")
+	other.UserID = "someone-else"
+
+	result, err := r.BulkCreateCalendarEvents(ctx, user.ID, []CalendarEventInput{
+		row("plain"), badType, typed, badMode, row("plain"), reversed, omitted, other,
+	})
+	if err != nil {
+		t.Fatalf("BulkCreateCalendarEvents() error = %v", err)
+	}
+
+	if result.Created != 3 {
+		t.Errorf("created %d events, want 3", result.Created)
+	}
+	wantErrors := []BulkRowError{
+		{Index: 1, ID: text("bad-type"), Message: `unknown meeting type "STANDUP"`},
+		{Index: 3, ID: text("bad-mode"), Message: `unknown attendance mode "REMOTE_WITH_VIDEO"`},
+		{Index: 4, ID: text("plain"), Message: "duplicate id within this request"},
+		{Index: 5, ID: text("reversed"), Message: "endTime is before startTime"},
+		{Index: 7, ID: text("other"), Message: "cannot import events for another user"},
+	}
+	if !reflect.DeepEqual(result.Errors, wantErrors) {
+		t.Errorf("errors = %+v, want %+v", result.Errors, wantErrors)
+	}
+
+	events, err := repos.Events.ListByUser(ctx, user.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes := map[string]models.AttendanceMode{}
+	for _, event := range events {
+		modes[event.ID] = event.AttendanceMode
+	}
+	want := map[string]models.AttendanceMode{"plain": models.AttendanceFlexible, "typed": models.AttendanceMustBeInOffice, "omitted": models.AttendanceFlexible}
+	if !reflect.DeepEqual(modes, want) {
+		t.Errorf("stored attendance modes = %v, want %v", modes, want)
+	}
+}
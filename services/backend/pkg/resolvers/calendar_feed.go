@@ -0,0 +1,220 @@
+package resolvers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/costs"
+	"github.com/commute-planner/backend/pkg/ics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/units"
+)
+
+// CalendarFeedPath is where calendar feeds are served, followed by the feed's token and .ics
+const CalendarFeedPath = "/calendar/feed/"
+
+// Errors of calendar feeds
+var (
+	ErrCalendarFeedsDisabled = errors.New("calendar feeds are not configured")
+	ErrCalendarFeedNotFound  = errors.New("calendar feed not found")
+)
+
+// A feed covers the plans of the past month and of every planned day ahead of it
+const (
+	calendarFeedPast  = 30 * 24 * time.Hour
+	calendarFeedAhead = 90 * 24 * time.Hour
+)
+
+// ServeCalendarFeedsAt lets users subscribe to their plans with feed URLs under baseURL,
+// the API's public URL. Without it feeds can't be created.
+func (r *Resolver) ServeCalendarFeedsAt(baseURL string) {
+	r.calendarFeedBaseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// CreateCalendarFeed gives the user a new secret feed URL, replacing their earlier one.
+// Only a hash of its token is kept, so the URL is only returned here.
+func (r *Resolver) CreateCalendarFeed(ctx context.Context, userID string) (*models.CalendarFeed, error) {
+	if r.calendarFeedBaseURL == "" {
+		return nil, ErrCalendarFeedsDisabled
+	}
+	if _, err := r.users.Get(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("error generating feed token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	feed, err := r.calendarFeeds.Put(ctx, userID, calendarFeedTokenHash(token))
+	if err != nil {
+		return nil, fmt.Errorf("error saving calendar feed: %w", err)
+	}
+	feed.URL = r.calendarFeedBaseURL + CalendarFeedPath + token + ".ics"
+	return feed, nil
+}
+
+// DeleteCalendarFeed stops the user's feed URL working, reporting whether they had one
+func (r *Resolver) DeleteCalendarFeed(ctx context.Context, userID string) (bool, error) {
+	deleted, err := r.calendarFeeds.Delete(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("error deleting calendar feed: %w", err)
+	}
+	return deleted, nil
+}
+
+// CalendarFeed returns the events of the feed with token: each planned office day as an
+// all-day event, free so it doesn't hide meetings, and its commutes as busy blocks.
+// Remote days have no events.
+func (r *Resolver) CalendarFeed(ctx context.Context, token string) ([]ics.Event, error) {
+	userID, err := r.calendarFeeds.UserByToken(ctx, calendarFeedTokenHash(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrCalendarFeedNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching calendar feed: %w", err)
+	}
+	location, err := r.userLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.users.Get(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	format := units.For(user, location)
+
+	now := time.Now().In(location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	plans, err := r.weekPlans(ctx, userID, today.Add(-calendarFeedPast), today.Add(calendarFeedAhead))
+	if err != nil {
+		return nil, err
+	}
+	dates := make([]string, 0, len(plans))
+	for date := range plans {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	offices := map[string]string{}
+	var events []ics.Event
+	for _, date := range dates {
+		plan := plans[date]
+		if plan.CommuteStart == nil {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			continue
+		}
+		stamp := plan.CreatedAt
+		if plan.AcceptedAt != nil {
+			stamp = *plan.AcceptedAt
+		}
+		office := r.feedOfficeLocation(ctx, plan.OfficeID, offices)
+
+		officeDay := ics.Event{
+			UID:      plan.ID + "-office@commute-planner",
+			Summary:  "Office day",
+			Location: office,
+			Start:    day,
+			End:      day.AddDate(0, 0, 1),
+			AllDay:   true,
+			Free:     true,
+			Stamp:    stamp,
+		}
+		if plan.OfficeArrival != nil && plan.OfficeDeparture != nil {
+			officeDay.Description = fmt.Sprintf("In the office %s-%s", format.Time(*plan.OfficeArrival), format.Time(*plan.OfficeDeparture))
+		}
+		if cost := costs.Estimate(plan); cost != nil && cost.Total > 0 {
+			officeDay.Description = strings.TrimSpace(officeDay.Description + "\nCommute cost " + format.Money(cost.Total))
+		}
+		if plan.Reasoning != nil && *plan.Reasoning != "" {
+			officeDay.Description = strings.TrimSpace(officeDay.Description + "\n\n" + *plan.Reasoning)
+		}
+		events = append(events, officeDay)
+		events = append(events, commuteBlocks(plan, stamp, format)...)
+	}
+	return events, nil
+}
+
+// commuteBlocks returns a plan's travel as busy events: its legs, with their distances in
+// format, or without them the trips to and from the office
+func commuteBlocks(plan *models.CommuteRecommendation, stamp time.Time, format units.Format) []ics.Event {
+	var blocks []ics.Event
+	for i, leg := range plan.DecodeTravelLegs() {
+		if leg.Depart == nil || leg.Arrive == nil {
+			continue
+		}
+		block := ics.Event{
+			UID:     fmt.Sprintf("%s-leg-%d@commute-planner", plan.ID, i),
+			Summary: "Commute",
+			Start:   *leg.Depart,
+			End:     *leg.Arrive,
+			Stamp:   stamp,
+		}
+		if leg.From != nil && leg.To != nil {
+			block.Summary = fmt.Sprintf("Commute: %s → %s", *leg.From, *leg.To)
+		}
+		var about []string
+		if leg.Mode != nil {
+			about = append(about, "By "+strings.ToLower(string(*leg.Mode)))
+		}
+		if leg.DistanceKm != nil {
+			about = append(about, format.Distance(*leg.DistanceKm))
+		}
+		block.Description = strings.Join(about, ", ")
+		blocks = append(blocks, block)
+	}
+	if len(blocks) > 0 {
+		return blocks
+	}
+
+	trip := func(suffix, summary string, start, end *time.Time) {
+		if start != nil && end != nil {
+			blocks = append(blocks, ics.Event{UID: plan.ID + suffix, Summary: summary, Start: *start, End: *end, Stamp: stamp})
+		}
+	}
+	trip("-to-office@commute-planner", "Commute to the office", plan.CommuteStart, plan.OfficeArrival)
+	trip("-home@commute-planner", "Commute home", plan.OfficeDeparture, plan.CommuteEnd)
+	return blocks
+}
+
+// feedOfficeLocation returns the name and address of an office for the feed, caching it
+// in names; empty when it's unknown
+func (r *Resolver) feedOfficeLocation(ctx context.Context, officeID *string, names map[string]string) string {
+	if officeID == nil {
+		return ""
+	}
+	if name, ok := names[*officeID]; ok {
+		return name
+	}
+	office, err := r.offices.Get(ctx, *officeID)
+	name := ""
+	if err == nil {
+		name = office.Name
+		if office.Address != nil && *office.Address != "" {
+			name += ", " + *office.Address
+		}
+	}
+	names[*officeID] = name
+	return name
+}
+
+// calendarFeedTokenHash is the hash a feed's token is stored as
+func calendarFeedTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,95 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestCalendarFeed(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	completed, inProgress := string(models.JobStatusCompleted), string(models.JobStatusInProgress)
+
+	// plan completes a job for the day offset days from today with one option
+	plan := func(offset int, rec *models.CommuteRecommendation) {
+		t.Helper()
+		day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, offset)
+		job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: day.Format("2006-01-02")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, status := range []*string{&inProgress, &completed} {
+			if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: status}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		rec.JobID, rec.OptionRank = job.ID, 1
+		if err := repos.Recommendations.Create(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	at := func(day time.Time, hour, minute int) *time.Time {
+		t := day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		return &t
+	}
+
+	if _, err := r.CreateCalendarFeed(ctx, user.ID); !errors.Is(err, ErrCalendarFeedsDisabled) {
+		t.Fatalf("feed without a base URL: err = %v", err)
+	}
+	r.ServeCalendarFeedsAt("https://api.example.com/")
+	feed, err := r.CreateCalendarFeed(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(feed.URL, "https://api.example.com/calendar/feed/") || !strings.HasSuffix(feed.URL, ".ics") {
+		t.Fatalf("feed URL = %s", feed.URL)
+	}
+	token := strings.TrimSuffix(strings.TrimPrefix(feed.URL, "https://api.example.com"+CalendarFeedPath), ".ics")
+
+	// Tomorrow in the office with its trips, the day after remote
+	tomorrow := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	plan(1, &models.CommuteRecommendation{
+		OptionType:   models.CommuteOptionFullDayOffice,
+		CommuteStart: at(tomorrow, 8, 15), OfficeArrival: at(tomorrow, 9, 0),
+		OfficeDeparture: at(tomorrow, 17, 0), CommuteEnd: at(tomorrow, 17, 45),
+	})
+	plan(2, &models.CommuteRecommendation{OptionType: models.CommuteOptionFullRemoteRecommended})
+
+	events, err := r.CalendarFeed(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("events = %+v, want the office day and two commutes", events)
+	}
+	if office := events[0]; !office.AllDay || !office.Free || !office.Start.Equal(tomorrow) || office.Description != "In the office 09:00-17:00" {
+		t.Errorf("office day = %+v", office)
+	}
+	if home := events[2]; home.Summary != "Commute home" || !home.Start.Equal(*at(tomorrow, 17, 0)) || home.Free {
+		t.Errorf("commute home = %+v", home)
+	}
+
+	// Times follow the user's time format
+	twelve := models.TimeFormat12Hour
+	if _, err := r.SetDisplayUnits(ctx, user.ID, nil, nil, &twelve); err != nil {
+		t.Fatal(err)
+	}
+	if events, err := r.CalendarFeed(ctx, token); err != nil || events[0].Description != "In the office 9:00am-5:00pm" {
+		t.Errorf("12-hour office day = %+v, %v", events, err)
+	}
+
+	// A new feed replaces the old URL
+	if _, err := r.CreateCalendarFeed(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CalendarFeed(ctx, token); !errors.Is(err, ErrCalendarFeedNotFound) {
+		t.Errorf("replaced feed: err = %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package resolvers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/ics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+// MaxCalendarImportEvents bounds the events of one imported file
+const MaxCalendarImportEvents = 10000
+
+// calendarImportBatch is how many events an import adds at a time; its progress is saved
+// after each batch
+const calendarImportBatch = 100
+
+// calendarImportTimeout bounds how long an import adds events for
+const calendarImportTimeout = 10 * time.Minute
+
+// ImportCalendar reads the events of an ICS file and starts adding them to the user's
+// calendar, returning the import to poll for progress. The file is read before this
+// returns, so one that isn't a calendar fails here; events are then added in batches in
+// the background. Events keep their UID, so importing a file again only adds new events.
+func (r *Resolver) ImportCalendar(ctx context.Context, userID, filename string, content io.Reader) (*models.CalendarImport, error) {
+	location, err := r.userLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := ics.Parse(content, location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar file: %w", err)
+	}
+	if len(events) > MaxCalendarImportEvents {
+		return nil, fmt.Errorf("too many events: %d (max %d per import)", len(events), MaxCalendarImportEvents)
+	}
+
+	rows := make([]CalendarEventInput, len(events))
+	for i, event := range events {
+		rows[i] = calendarImportRow(userID, event)
+	}
+	imp := &models.CalendarImport{
+		UserID:      userID,
+		Filename:    filename,
+		Format:      models.CalendarImportICS,
+		Status:      models.CalendarImportRunning,
+		TotalEvents: len(rows),
+		Errors:      []models.ImportError{},
+		StartedAt:   time.Now(),
+	}
+	if err := r.calendarImports.Create(ctx, imp); err != nil {
+		return nil, fmt.Errorf("error saving calendar import: %w", err)
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+	started := *imp
+	go r.runCalendarImport(tenantID, &started, events, rows)
+	return imp, nil
+}
+
+// CalendarImport returns one of the user's imports with its progress
+func (r *Resolver) CalendarImport(ctx context.Context, userID, id string) (*models.CalendarImport, error) {
+	imp, err := r.calendarImports.Get(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("calendar import not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("error fetching calendar import: %w", err)
+	}
+	return imp, nil
+}
+
+// runCalendarImport adds an import's events in batches, saving its progress after each
+func (r *Resolver) runCalendarImport(tenantID string, imp *models.CalendarImport, events []ics.Event, rows []CalendarEventInput) {
+	ctx, cancel := context.WithTimeout(context.Background(), calendarImportTimeout)
+	defer cancel()
+	if tenantID != "" {
+		ctx = tenant.WithID(ctx, tenantID)
+	}
+
+	imp.Status = models.CalendarImportCompleted
+	for start := 0; start < len(rows); start += calendarImportBatch {
+		end := min(start+calendarImportBatch, len(rows))
+		result, err := r.BulkCreateCalendarEvents(ctx, imp.UserID, rows[start:end])
+		if err != nil {
+			message := err.Error()
+			imp.Status, imp.ErrorMessage = models.CalendarImportFailed, &message
+			break
+		}
+		for _, rowErr := range result.Errors {
+			importErr := models.ImportError{Index: start + rowErr.Index, Message: rowErr.Message}
+			if uid := events[start+rowErr.Index].UID; uid != "" {
+				importErr.UID = &uid
+			}
+			imp.Errors = append(imp.Errors, importErr)
+		}
+		imp.ProcessedEvents, imp.CreatedEvents = end, imp.CreatedEvents+result.Created
+		if end < len(rows) {
+			if err := r.calendarImports.Update(ctx, imp); err != nil {
+				log.Printf("Failed to save progress of calendar import %s: %v", imp.ID, err)
+			}
+		}
+	}
+
+	finished := time.Now()
+	imp.FinishedAt = &finished
+	if err := r.calendarImports.Update(ctx, imp); err != nil {
+		log.Printf("Failed to save calendar import %s: %v", imp.ID, err)
+	}
+}
+
+// calendarImportRow is the event row an imported event is added as. Its ID is derived
+// from the user and the event's UID, so the same event imported again is a duplicate.
+func calendarImportRow(userID string, event ics.Event) CalendarEventInput {
+	row := CalendarEventInput{
+		UserID:      userID,
+		Summary:     event.Summary,
+		StartTime:   event.Start,
+		EndTime:     event.End,
+		IsAllDay:    event.AllDay,
+		IsRecurring: event.Recurring,
+	}
+	if row.Summary == "" {
+		row.Summary = "(No title)"
+	}
+	if event.UID != "" {
+		sum := sha256.Sum256([]byte(userID + "\x00" + event.UID))
+		id := "ics-" + hex.EncodeToString(sum[:16])
+		row.ID = &id
+	}
+	if event.Description != "" {
+		row.Description = &event.Description
+	}
+	if event.Location != "" {
+		row.Location = &event.Location
+	}
+	return row
+}
@@ -0,0 +1,89 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestImportCalendar(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+
+	// calendar builds an ICS file of events numbered from..to, the first of them invalid
+	calendar := func(from, to int) string {
+		lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0"}
+		for i := from; i < to; i++ {
+			end := "20260302T100000Z"
+			if i == from {
+				end = "20260302T080000Z"
+			}
+			lines = append(lines, "BEGIN:VEVENT", fmt.Sprintf("UID:event-%d@example.com", i), fmt.Sprintf("SUMMARY:Meeting %d", i),
+				"DTSTART:20260302T090000Z", "DTEND:"+end, "END:VEVENT")
+		}
+		return strings.Join(append(lines, "END:VCALENDAR"), "\r\n")
+	}
+	// wait polls an import until it has finished
+	wait := func(id string) *models.CalendarImport {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			imp, err := r.CalendarImport(ctx, user.ID, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if imp.Status != models.CalendarImportRunning {
+				return imp
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("import still running: %+v", imp)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// More events than a batch
+	imp, err := r.ImportCalendar(ctx, user.ID, "work.ics", strings.NewReader(calendar(0, 150)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Status != models.CalendarImportRunning || imp.TotalEvents != 150 || imp.Filename != "work.ics" {
+		t.Errorf("started import = %+v", imp)
+	}
+	done := wait(imp.ID)
+	if done.Status != models.CalendarImportCompleted || done.ProcessedEvents != 150 || done.CreatedEvents != 149 || done.FinishedAt == nil {
+		t.Errorf("finished import = %+v", done)
+	}
+	if len(done.Errors) != 1 || done.Errors[0].Index != 0 || *done.Errors[0].UID != "event-0@example.com" {
+		t.Errorf("errors = %+v, want the first event's", done.Errors)
+	}
+	events, err := repos.Events.ListByUser(ctx, user.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 149 {
+		t.Errorf("%d events imported, want 149", len(events))
+	}
+
+	// Importing the file again adds only the events it didn't have
+	again, err := r.ImportCalendar(ctx, user.ID, "work.ics", strings.NewReader(calendar(100, 160)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done := wait(again.ID); done.CreatedEvents != 10 || len(done.Errors) != 50 {
+		t.Errorf("re-import = %+v, want 10 new events and the rest reported", done)
+	}
+
+	if _, err := r.ImportCalendar(ctx, user.ID, "notes.txt", strings.NewReader("Not a calendar")); err == nil {
+		t.Error("imported a file that isn't a calendar")
+	}
+	other := createTestUser(t, repos, "grace@example.com")
+	if _, err := r.CalendarImport(ctx, other.ID, imp.ID); err == nil {
+		t.Error("read another user's import")
+	}
+}
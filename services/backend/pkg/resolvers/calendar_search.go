@@ -0,0 +1,125 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Calendar event search returns 50 events unless asked for more, and at most 200
+const (
+	defaultEventSearchLimit = 50
+	maxEventSearchLimit     = 200
+)
+
+// DateRangeInput is an inclusive range of YYYY-MM-DD days (UTC); either end may be open
+type DateRangeInput struct {
+	From *string `json:"from"`
+	To   *string `json:"to"`
+}
+
+// dates converts the range to the repository's half-open range
+func (input *DateRangeInput) dates() (repository.DateRange, error) {
+	var dates repository.DateRange
+	if input == nil {
+		return dates, nil
+	}
+	if input.From != nil && *input.From != "" {
+		day, err := time.Parse("2006-01-02", *input.From)
+		if err != nil {
+			return dates, fmt.Errorf("invalid from date %q (expected YYYY-MM-DD)", *input.From)
+		}
+		dates.From = &day
+	}
+	if input.To != nil && *input.To != "" {
+		day, err := time.Parse("2006-01-02", *input.To)
+		if err != nil {
+			return dates, fmt.Errorf("invalid to date %q (expected YYYY-MM-DD)", *input.To)
+		}
+		end := day.AddDate(0, 0, 1)
+		dates.To = &end
+	}
+	if dates.From != nil && dates.To != nil && !dates.From.Before(*dates.To) {
+		return dates, fmt.Errorf("from must not be after to")
+	}
+	return dates, nil
+}
+
+// CalendarEventSearchInput filters a calendar event search; empty filters match every event
+type CalendarEventSearchInput struct {
+	Query           string                  `json:"query"`
+	DateRange       *DateRangeInput         `json:"dateRange"`
+	MeetingTypes    []models.MeetingType    `json:"meetingTypes"`
+	AttendanceModes []models.AttendanceMode `json:"attendanceModes"`
+	Limit           *int                    `json:"limit"`
+}
+
+// SearchCalendarEvents finds a user's events whose summary, description or location
+// contain every word of the query, for the event picker and for checking imported data.
+// Without a query it lists the events matching the filters by start time.
+func (r *Resolver) SearchCalendarEvents(ctx context.Context, userID string, input CalendarEventSearchInput) ([]*models.CalendarEvent, error) {
+	search := repository.EventSearch{
+		Query:           input.Query,
+		MeetingTypes:    input.MeetingTypes,
+		AttendanceModes: input.AttendanceModes,
+		Limit:           defaultEventSearchLimit,
+	}
+	if input.Limit != nil {
+		if *input.Limit <= 0 || *input.Limit > maxEventSearchLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxEventSearchLimit)
+		}
+		search.Limit = *input.Limit
+	}
+	for _, meetingType := range input.MeetingTypes {
+		if !isMeetingType(meetingType) {
+			return nil, fmt.Errorf("unknown meeting type %q", meetingType)
+		}
+	}
+	for _, mode := range input.AttendanceModes {
+		if !isAttendanceMode(mode) {
+			return nil, fmt.Errorf("unknown attendance mode %q", mode)
+		}
+	}
+	dates, err := input.DateRange.dates()
+	if err != nil {
+		return nil, err
+	}
+	search.Dates = dates
+
+	if _, err := r.users.Get(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	events, err := r.events.Search(ctx, userID, search)
+	if err != nil {
+		return nil, fmt.Errorf("error searching calendar events: %w", err)
+	}
+	if events == nil {
+		events = []*models.CalendarEvent{}
+	}
+	return withAttendeeLists(events), nil
+}
+
+func isMeetingType(meetingType models.MeetingType) bool {
+	for _, known := range models.MeetingTypes {
+		if meetingType == known {
+			return true
+		}
+	}
+	return false
+}
+
+func isAttendanceMode(mode models.AttendanceMode) bool {
+	for _, known := range models.AttendanceModes {
+		if mode == known {
+			return true
+		}
+	}
+	return false
+}
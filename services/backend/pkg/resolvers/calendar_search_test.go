@@ -0,0 +1,121 @@
+package resolvers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestSearchCalendarEvents(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	other := createTestUser(t, repos, "bob@example.com")
+
+	day := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	description := "Quarterly roadmap review with the client"
+	hq := "HQ Room 4"
+	events := []*models.CalendarEvent{
+		{ID: "standup", UserID: user.ID, Summary: "Team standup", StartTime: day, MeetingType: models.MeetingTypeStatusUpdate, AttendanceMode: models.AttendanceCanBeRemote},
+		{ID: "roadmap", UserID: user.ID, Summary: "Roadmap", Description: &description, StartTime: day.Add(2 * time.Hour), MeetingType: models.MeetingTypeClientMeeting, AttendanceMode: models.AttendanceMustBeInOffice},
+		{ID: "workshop", UserID: user.ID, Summary: "Design workshop", Location: &hq, StartTime: day.AddDate(0, 0, 1), MeetingType: models.MeetingTypeTeamWorkshop, AttendanceMode: models.AttendanceMustBeInOffice},
+		{ID: "one-on-one", UserID: user.ID, Summary: "1:1 with Sam", StartTime: day.AddDate(0, 0, 3), MeetingType: models.MeetingTypeOneOnOne, AttendanceMode: models.AttendanceFlexible},
+		{ID: "others", UserID: other.ID, Summary: "Team standup", StartTime: day, MeetingType: models.MeetingTypeStatusUpdate, AttendanceMode: models.AttendanceCanBeRemote},
+	}
+	for _, event := range events {
+		event.EndTime = event.StartTime.Add(30 * time.Minute)
+		if err := repos.Events.Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	date := func(s string) *string { return &s }
+	limit := func(n int) *int { return &n }
+	tests := []struct {
+		name  string
+		input CalendarEventSearchInput
+		want  []string
+	}{
+		{"no filters lists every event by start time", CalendarEventSearchInput{}, []string{"standup", "roadmap", "workshop", "one-on-one"}},
+		{"terms match case-insensitively", CalendarEventSearchInput{Query: "STANDUP"}, []string{"standup"}},
+		{"every term must match", CalendarEventSearchInput{Query: "roadmap client"}, []string{"roadmap"}},
+		{"terms match the location", CalendarEventSearchInput{Query: "hq"}, []string{"workshop"}},
+		{"punctuation separates terms", CalendarEventSearchInput{Query: "room-4"}, []string{"workshop"}},
+		{"no match", CalendarEventSearchInput{Query: "offsite"}, nil},
+		{"the to date is inclusive", CalendarEventSearchInput{DateRange: &DateRangeInput{From: date("2026-03-02"), To: date("2026-03-03")}}, []string{"standup", "roadmap", "workshop"}},
+		{"open-ended range", CalendarEventSearchInput{DateRange: &DateRangeInput{From: date("2026-03-03")}}, []string{"workshop", "one-on-one"}},
+		{"meeting types are ORed", CalendarEventSearchInput{MeetingTypes: []models.MeetingType{models.MeetingTypeOneOnOne, models.MeetingTypeStatusUpdate}}, []string{"standup", "one-on-one"}},
+		{"filters are ANDed", CalendarEventSearchInput{Query: "workshop", AttendanceModes: []models.AttendanceMode{models.AttendanceCanBeRemote}}, nil},
+		{"attendance mode", CalendarEventSearchInput{AttendanceModes: []models.AttendanceMode{models.AttendanceMustBeInOffice}}, []string{"roadmap", "workshop"}},
+		{"limit", CalendarEventSearchInput{Limit: limit(2)}, []string{"standup", "roadmap"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := r.SearchCalendarEvents(ctx, user.ID, tt.input)
+			if err != nil {
+				t.Fatalf("SearchCalendarEvents() error = %v", err)
+			}
+			var ids []string
+			for _, event := range found {
+				ids = append(ids, event.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("found %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchCalendarEventsValidation(t *testing.T) {
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	date := func(s string) *string { return &s }
+	limit := func(n int) *int { return &n }
+
+	tests := []struct {
+		name    string
+		userID  string
+		input   CalendarEventSearchInput
+		wantErr string
+	}{
+		{"zero limit", user.ID, CalendarEventSearchInput{Limit: limit(0)}, "limit must be between 1 and 200"},
+		{"limit over the maximum", user.ID, CalendarEventSearchInput{Limit: limit(201)}, "limit must be between 1 and 200"},
+		{"unknown meeting type", user.ID, CalendarEventSearchInput{MeetingTypes: []models.MeetingType{"STANDUP"}}, `unknown meeting type "STANDUP"`},
+		{"unknown attendance mode", user.ID, CalendarEventSearchInput{AttendanceModes: []models.AttendanceMode{"REMOTE"}}, `unknown attendance mode "REMOTE"`},
+		{"malformed date", user.ID, CalendarEventSearchInput{DateRange: &DateRangeInput{From: date("03/02/2026")}}, `invalid from date "03/02/2026" (expected YYYY-MM-DD)`},
+		{"reversed range", user.ID, CalendarEventSearchInput{DateRange: &DateRangeInput{From: date("2026-03-05"), To: date("2026-03-01")}}, "from must not be after to"},
+		{"unknown user", "missing", CalendarEventSearchInput{}, "user not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.SearchCalendarEvents(context.Background(), tt.userID, tt.input)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("SearchCalendarEvents() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDateRangeInputDates(t *testing.T) {
+	from, to := "2026-03-02", "2026-03-02"
+	dates, err := (&DateRangeInput{From: &from, To: &to}).dates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := repository.DateRange{}
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+	want.From, want.To = &start, &end
+	if !dates.From.Equal(*want.From) || !dates.To.Equal(*want.To) {
+		t.Errorf("dates() = [%s, %s), want [%s, %s)", dates.From, dates.To, want.From, want.To)
+	}
+
+	var open *DateRangeInput
+	if dates, err := open.dates(); err != nil || dates.From != nil || dates.To != nil {
+		t.Errorf("nil range = %+v, %v, want an open range", dates, err)
+	}
+}
@@ -0,0 +1,116 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/carbon"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Carbon stats periods; each is the current calendar period in the user's timezone, with
+// weeks starting on Monday
+const (
+	CarbonPeriodWeek    = "WEEK"
+	CarbonPeriodMonth   = "MONTH"
+	CarbonPeriodQuarter = "QUARTER"
+	CarbonPeriodYear    = "YEAR"
+)
+
+// CarbonStats totals the CO2 of the user's commute history over period (MONTH when
+// empty). A day counts when the user accepted one of its recommendations; with several
+// accepted, the latest wins. Remote days are credited with the drive they avoided, taken
+// from the day's shortest office option.
+func (r *Resolver) CarbonStats(ctx context.Context, userID, period string) (*models.CarbonStats, error) {
+	if period == "" {
+		period = CarbonPeriodMonth
+	}
+	period = strings.ToUpper(period)
+
+	now, err := r.userNow(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := carbonPeriod(period, now)
+	if err != nil {
+		return nil, err
+	}
+	days, err := r.commuteHistory(ctx, userID, repository.DateRange{From: &from, To: &to})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.CarbonStats{
+		UserID: userID,
+		Period: period,
+		From:   from.Format("2006-01-02"),
+		To:     to.AddDate(0, 0, -1).Format("2006-01-02"),
+		ByMode: []models.ModeEmissions{},
+	}
+	for _, day := range days {
+		if day.accepted.CommuteStart == nil {
+			stats.RemoteDays++
+			if avoided := avoidedDriveKg(day.options); avoided != nil {
+				stats.DriveCO2Kg += *avoided
+			}
+			continue
+		}
+		stats.CommuteDays++
+		emissions := carbon.Estimate(day.accepted)
+		if emissions == nil {
+			stats.UnestimatedDays++
+			continue
+		}
+		stats.Trips += emissions.Trips
+		stats.DistanceKm += emissions.DistanceKm
+		stats.CO2Kg += emissions.CO2Kg
+		stats.DriveCO2Kg += emissions.DriveCO2Kg
+		stats.ByMode = carbon.SumByMode(stats.ByMode, emissions.ByMode)
+	}
+	stats.DistanceKm = carbon.Round(stats.DistanceKm)
+	stats.CO2Kg = carbon.Round(stats.CO2Kg)
+	stats.DriveCO2Kg = carbon.Round(stats.DriveCO2Kg)
+	if stats.DriveCO2Kg > stats.CO2Kg {
+		stats.SavedCO2Kg = carbon.Round(stats.DriveCO2Kg - stats.CO2Kg)
+	}
+	return stats, nil
+}
+
+// avoidedDriveKg is the drive baseline of the shortest office option, or nil when no
+// office option has known distances
+func avoidedDriveKg(options []*models.CommuteRecommendation) *float64 {
+	var avoided *float64
+	for _, rec := range options {
+		if rec.CommuteStart == nil {
+			continue
+		}
+		if emissions := carbon.Estimate(rec); emissions != nil && (avoided == nil || emissions.DriveCO2Kg < *avoided) {
+			avoided = &emissions.DriveCO2Kg
+		}
+	}
+	return avoided
+}
+
+// carbonPeriod returns the [from, to) days of the calendar period containing now, as
+// UTC midnights for filtering on target dates
+func carbonPeriod(period string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case CarbonPeriodWeek:
+		from := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return from, from.AddDate(0, 0, 7), nil
+	case CarbonPeriodMonth:
+		from := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 1, 0), nil
+	case CarbonPeriodQuarter:
+		from := time.Date(today.Year(), today.Month()-(today.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 3, 0), nil
+	case CarbonPeriodYear:
+		from := time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown period %q (expected WEEK, MONTH, QUARTER or YEAR)", period)
+}
@@ -0,0 +1,28 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/clientinfo"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// ClientUsage breaks the GraphQL requests since since (YYYY-MM-DD; the last 30 days when
+// empty) down by client version and operation, so a breaking change can be held until the
+// versions running the operation are gone. Only the admin token may read it.
+func (r *Resolver) ClientUsage(ctx context.Context, viewer authz.Viewer, since string) ([]*models.ClientUsage, error) {
+	if !viewer.Has(authz.RoleAdmin) {
+		return nil, authz.ErrForbidden
+	}
+	start, err := usageSince(since)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := r.clientUsage.List(ctx, start)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching client usage: %w", err)
+	}
+	return clientinfo.Report(usage), nil
+}
@@ -0,0 +1,50 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/clientinfo"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestClientUsage(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+
+	web := clientinfo.WithClient(ctx, clientinfo.Client{Name: "web", Version: "2.1.0"})
+	job, err := r.CreateJob(web, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ClientName == nil || *job.ClientName != "web" || *job.ClientVersion != "2.1.0" {
+		t.Errorf("job client = %v %v", job.ClientName, job.ClientVersion)
+	}
+	background, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-03"})
+	if err != nil || background.ClientName != nil {
+		t.Errorf("job created outside a request = %+v, %v, want no client", background, err)
+	}
+
+	now := time.Now()
+	err = repos.ClientUsage.Record(ctx, []*models.ClientOperationUsage{
+		{ClientName: "web", ClientVersion: "2.1.0", Operation: "createJob", Requests: 1, FirstUsedAt: now, LastUsedAt: now},
+		{ClientName: "ios", ClientVersion: "1.0", Operation: "jobs", Requests: 4, FirstUsedAt: now, LastUsedAt: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, err := r.ClientUsage(ctx, authz.Viewer{Admin: true}, "")
+	if err != nil || len(usage) != 2 || usage[0].ClientName != "ios" || usage[1].Operations[0].Operation != "createJob" {
+		t.Errorf("usage = %+v, %v", usage, err)
+	}
+	if _, err := r.ClientUsage(ctx, authz.Viewer{Admin: true}, "yesterday"); err == nil {
+		t.Error("invalid since accepted")
+	}
+	if _, err := r.ClientUsage(ctx, authz.Viewer{UserID: user.ID}, ""); !errors.Is(err, authz.ErrForbidden) {
+		t.Errorf("usage read by a user: err = %v, want %v", err, authz.ErrForbidden)
+	}
+}
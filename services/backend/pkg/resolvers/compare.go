@@ -0,0 +1,325 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// optionTimes are the recommendation times a comparison reports, with how the summary
+// describes each
+var optionTimes = []struct {
+	field string
+	label string
+	value func(*models.CommuteRecommendation) *time.Time
+}{
+	{"commuteStart", "leave home", func(rec *models.CommuteRecommendation) *time.Time { return rec.CommuteStart }},
+	{"officeArrival", "arrive at the office", func(rec *models.CommuteRecommendation) *time.Time { return rec.OfficeArrival }},
+	{"officeDeparture", "leave the office", func(rec *models.CommuteRecommendation) *time.Time { return rec.OfficeDeparture }},
+	{"commuteEnd", "get home", func(rec *models.CommuteRecommendation) *time.Time { return rec.CommuteEnd }},
+}
+
+// CompareJobs diffs the plans of two of a user's jobs for the same day, so a user who
+// re-planned can see why the plan changed. Options are matched by type; job A is taken as
+// the earlier plan.
+func (r *Resolver) CompareJobs(ctx context.Context, userID, jobIDA, jobIDB string) (*models.JobComparison, error) {
+	jobA, err := r.userJob(ctx, userID, jobIDA)
+	if err != nil {
+		return nil, err
+	}
+	jobB, err := r.userJob(ctx, userID, jobIDB)
+	if err != nil {
+		return nil, err
+	}
+	if jobA.TargetDate != jobB.TargetDate {
+		return nil, fmt.Errorf("jobs plan different days (%s and %s)", jobA.TargetDate, jobB.TargetDate)
+	}
+
+	recsA, err := r.recommendations.ListByJob(ctx, jobA.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching commute recommendations: %w", err)
+	}
+	recsB, err := r.recommendations.ListByJob(ctx, jobB.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching commute recommendations: %w", err)
+	}
+	location, err := r.userLocation(ctx, jobA.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := compareRecommendations(recsA, recsB, location)
+	comparison.JobIDA = jobA.ID
+	comparison.JobIDB = jobB.ID
+	comparison.TargetDate = jobA.TargetDate
+	comparison.ConstraintsAdded = constraintsMissingFrom(jobConstraints(jobB), jobConstraints(jobA))
+	comparison.ConstraintsRemoved = constraintsMissingFrom(jobConstraints(jobA), jobConstraints(jobB))
+
+	// Constraints explain option changes, so they come right after the top option
+	var constraintSummary []string
+	for _, constraint := range comparison.ConstraintsAdded {
+		constraintSummary = append(constraintSummary, fmt.Sprintf("Constraint added: %s", describeConstraint(constraint)))
+	}
+	for _, constraint := range comparison.ConstraintsRemoved {
+		constraintSummary = append(constraintSummary, fmt.Sprintf("Constraint removed: %s", describeConstraint(constraint)))
+	}
+	at := 0
+	if comparison.TopOptionChanged {
+		at = 1
+	}
+	comparison.Summary = append(comparison.Summary[:at], append(constraintSummary, comparison.Summary[at:]...)...)
+	return comparison, nil
+}
+
+// compareRecommendations matches two plans' options by type, in rank order, and reports
+// how each changed. Times in the summary are shown in location.
+func compareRecommendations(recsA, recsB []*models.CommuteRecommendation, location *time.Location) *models.JobComparison {
+	byRank := func(recs []*models.CommuteRecommendation) []*models.CommuteRecommendation {
+		sorted := append([]*models.CommuteRecommendation(nil), recs...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].OptionRank < sorted[j].OptionRank })
+		return sorted
+	}
+	recsA, recsB = byRank(recsA), byRank(recsB)
+
+	comparison := &models.JobComparison{
+		Options:            []*models.OptionComparison{},
+		ConstraintsAdded:   []models.PlanningConstraint{},
+		ConstraintsRemoved: []models.PlanningConstraint{},
+		Summary:            []string{},
+	}
+	if len(recsA) > 0 && len(recsB) > 0 && recsA[0].OptionType != recsB[0].OptionType {
+		comparison.TopOptionChanged = true
+		comparison.Summary = append(comparison.Summary, fmt.Sprintf("%s is now the top option, ahead of %s",
+			capitalize(optionName(recsB[0].OptionType)), optionName(recsA[0].OptionType)))
+	}
+
+	matched := make([]bool, len(recsA))
+	for _, b := range recsB {
+		var a *models.CommuteRecommendation
+		for i, candidate := range recsA {
+			if !matched[i] && candidate.OptionType == b.OptionType {
+				matched[i] = true
+				a = candidate
+				break
+			}
+		}
+		rankB := b.OptionRank
+		option := &models.OptionComparison{OptionType: b.OptionType, RankB: &rankB, Changes: []models.FieldChange{}}
+		comparison.Options = append(comparison.Options, option)
+		name := capitalize(optionName(b.OptionType))
+		if a == nil {
+			option.Status = models.OptionAdded
+			comparison.Summary = append(comparison.Summary, fmt.Sprintf("%s was added at rank %d", name, b.OptionRank))
+			continue
+		}
+
+		rankA := a.OptionRank
+		option.RankA = &rankA
+		option.Status = models.OptionUnchanged
+		if rankA != rankB {
+			option.Status = models.OptionChanged
+			comparison.Summary = append(comparison.Summary, fmt.Sprintf("%s moved from rank %d to rank %d", name, rankA, rankB))
+		}
+		for _, change := range optionChanges(a, b, location) {
+			option.Status = models.OptionChanged
+			option.Changes = append(option.Changes, change.FieldChange)
+			comparison.Summary = append(comparison.Summary, fmt.Sprintf("%s: %s", name, change.summary))
+		}
+	}
+	for i, a := range recsA {
+		if matched[i] {
+			continue
+		}
+		rankA := a.OptionRank
+		comparison.Options = append(comparison.Options, &models.OptionComparison{
+			OptionType: a.OptionType, Status: models.OptionRemoved, RankA: &rankA, Changes: []models.FieldChange{},
+		})
+		comparison.Summary = append(comparison.Summary, fmt.Sprintf("%s is no longer offered", capitalize(optionName(a.OptionType))))
+	}
+	return comparison
+}
+
+// describedChange is a field change with its summary sentence
+type describedChange struct {
+	models.FieldChange
+	summary string
+}
+
+// optionChanges compares the times, travel mode and office of two plans of an option
+func optionChanges(a, b *models.CommuteRecommendation, location *time.Location) []describedChange {
+	var changes []describedChange
+	for _, t := range optionTimes {
+		before, after := t.value(a), t.value(b)
+		if before == nil && after == nil || before != nil && after != nil && before.Equal(*after) {
+			continue
+		}
+		change := describedChange{FieldChange: models.FieldChange{Field: t.field, Before: formatTime(before), After: formatTime(after)}}
+		switch {
+		case before == nil:
+			change.summary = fmt.Sprintf("now %s at %s", t.label, after.In(location).Format("15:04"))
+		case after == nil:
+			change.summary = fmt.Sprintf("no longer %s (was %s)", t.label, before.In(location).Format("15:04"))
+		default:
+			shift := int(after.Sub(*before).Minutes())
+			change.ShiftMinutes = &shift
+			direction := "later"
+			if shift < 0 {
+				direction = "earlier"
+			}
+			change.summary = fmt.Sprintf("%s at %s instead of %s (%s %s)", t.label,
+				after.In(location).Format("15:04"), before.In(location).Format("15:04"), minutes(abs(shift)), direction)
+		}
+		changes = append(changes, change)
+	}
+
+	if modeA, modeB := modeName(a.TravelMode), modeName(b.TravelMode); modeA != modeB {
+		changes = append(changes, describedChange{
+			FieldChange: models.FieldChange{Field: "travelMode", Before: modeValue(a.TravelMode), After: modeValue(b.TravelMode)},
+			summary:     fmt.Sprintf("travel by %s instead of %s", modeB, modeA),
+		})
+	}
+	if officeA, officeB := stringValue(a.OfficeID), stringValue(b.OfficeID); officeA != officeB {
+		changes = append(changes, describedChange{
+			FieldChange: models.FieldChange{Field: "officeId", Before: a.OfficeID, After: b.OfficeID},
+			summary:     "commute to a different office",
+		})
+	}
+	return changes
+}
+
+// jobConstraints returns the constraints the job was created with, from its input data
+func jobConstraints(job *models.Job) []models.PlanningConstraint {
+	if job.InputData == nil {
+		return nil
+	}
+	var data struct {
+		Context struct {
+			Constraints []models.PlanningConstraint `json:"constraints"`
+		} `json:"context"`
+	}
+	if err := json.Unmarshal([]byte(*job.InputData), &data); err != nil {
+		return nil
+	}
+	return data.Context.Constraints
+}
+
+// constraintsMissingFrom returns the constraints of list that other doesn't have
+func constraintsMissingFrom(list, other []models.PlanningConstraint) []models.PlanningConstraint {
+	have := map[string]bool{}
+	for _, constraint := range other {
+		have[describeConstraint(constraint)] = true
+	}
+	missing := []models.PlanningConstraint{}
+	for _, constraint := range list {
+		if !have[describeConstraint(constraint)] {
+			missing = append(missing, constraint)
+		}
+	}
+	return missing
+}
+
+// describeConstraint is a constraint as text, e.g. "busy 07:00-08:00 (gym)". Mirrors
+// describe_constraint in the AI service's utils/constraints.py.
+func describeConstraint(constraint models.PlanningConstraint) string {
+	var text string
+	switch constraint.Type {
+	case models.ConstraintHomeBy:
+		text = "home by " + stringValue(constraint.Time)
+	case models.ConstraintLeaveAfter:
+		text = "leave after " + stringValue(constraint.Time)
+	case models.ConstraintBusy:
+		text = fmt.Sprintf("busy %s-%s", stringValue(constraint.Start), stringValue(constraint.End))
+	case models.ConstraintAvoidMode:
+		text = "no " + modeActivity(constraint.Mode)
+	default:
+		text = strings.ToLower(string(constraint.Type))
+	}
+	if constraint.Label != nil && *constraint.Label != "" {
+		return fmt.Sprintf("%s (%s)", text, *constraint.Label)
+	}
+	return text
+}
+
+// modeActivity is how an avoided mode reads, e.g. "driving"
+func modeActivity(mode *models.TravelMode) string {
+	switch {
+	case mode == nil:
+		return "mode"
+	case *mode == models.TravelModeDrive:
+		return "driving"
+	case *mode == models.TravelModeTransit:
+		return "transit"
+	case *mode == models.TravelModeBike:
+		return "biking"
+	case *mode == models.TravelModeWalk:
+		return "walking"
+	}
+	return strings.ToLower(string(*mode))
+}
+
+// optionName is an option type as text, e.g. "full day office"
+func optionName(optionType models.CommuteOptionType) string {
+	return strings.ToLower(strings.ReplaceAll(string(optionType), "_", " "))
+}
+
+func modeName(mode *models.TravelMode) string {
+	if mode == nil {
+		return "no travel"
+	}
+	return strings.ToLower(string(*mode))
+}
+
+func modeValue(mode *models.TravelMode) *string {
+	if mode == nil {
+		return nil
+	}
+	value := string(*mode)
+	return &value
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	value := t.UTC().Format(time.RFC3339)
+	return &value
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// minutes formats a duration in minutes, e.g. "1 minute" or "1h 30m"
+func minutes(n int) string {
+	switch {
+	case n == 1:
+		return "1 minute"
+	case n < 60:
+		return fmt.Sprintf("%d minutes", n)
+	case n%60 == 0:
+		return fmt.Sprintf("%dh", n/60)
+	}
+	return fmt.Sprintf("%dh %dm", n/60, n%60)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,150 @@
+package resolvers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestCompareJobs(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	repos.Users.(*repository.MemoryUserRepository).SetPreferredTimezone(user.ID, "Europe/London")
+	other := createTestUser(t, repos, "bob@example.com")
+
+	createJob := func(userID, targetDate, inputData string) *models.Job {
+		t.Helper()
+		job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: userID, TargetDate: targetDate, InputData: &inputData})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return job
+	}
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) *time.Time {
+		t := day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		return &t
+	}
+	drive, transit := models.TravelModeDrive, models.TravelModeTransit
+	addOptions := func(job *models.Job, recs ...*models.CommuteRecommendation) {
+		t.Helper()
+		for i, rec := range recs {
+			rec.ID = job.ID + "-" + string(rec.OptionType)
+			rec.JobID = job.ID
+			rec.OptionRank = i + 1
+			if err := repos.Recommendations.Create(ctx, rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// The first plan drives in for the whole day; after a gym session was added the
+	// afternoon option by transit wins and the full day starts later
+	before := createJob(user.ID, "2026-03-02", `{"context": {"constraints": [{"type": "HOME_BY", "time": "19:00"}]}}`)
+	addOptions(before,
+		&models.CommuteRecommendation{OptionType: models.CommuteOptionFullDayOffice, TravelMode: &drive,
+			CommuteStart: at(7, 30), OfficeArrival: at(8, 15), OfficeDeparture: at(17, 0), CommuteEnd: at(17, 45)},
+		&models.CommuteRecommendation{OptionType: models.CommuteOptionFullRemoteRecommended},
+	)
+	after := createJob(user.ID, "2026-03-02", `{"context": {"constraints": [{"type": "HOME_BY", "time": "19:00"},
+		{"type": "BUSY", "start": "07:00", "end": "08:00", "label": "gym"}]}}`)
+	addOptions(after,
+		&models.CommuteRecommendation{OptionType: models.CommuteOptionStrategicAfternoon, TravelMode: &transit,
+			CommuteStart: at(12, 0), OfficeArrival: at(12, 45), OfficeDeparture: at(17, 0), CommuteEnd: at(17, 45)},
+		&models.CommuteRecommendation{OptionType: models.CommuteOptionFullDayOffice, TravelMode: &transit,
+			CommuteStart: at(8, 0), OfficeArrival: at(8, 50), OfficeDeparture: at(17, 0), CommuteEnd: at(17, 50)},
+	)
+
+	comparison, err := r.CompareJobs(ctx, user.ID, before.ID, after.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !comparison.TopOptionChanged || comparison.TargetDate != "2026-03-02" {
+		t.Errorf("comparison = %+v, want the top option changed on 2026-03-02", comparison)
+	}
+
+	rank := func(n int) *int { return &n }
+	shift := func(n int) *int { return &n }
+	text := func(s string) *string { return &s }
+	wantOptions := []*models.OptionComparison{
+		{OptionType: models.CommuteOptionStrategicAfternoon, Status: models.OptionAdded, RankB: rank(1), Changes: []models.FieldChange{}},
+		{OptionType: models.CommuteOptionFullDayOffice, Status: models.OptionChanged, RankA: rank(1), RankB: rank(2), Changes: []models.FieldChange{
+			{Field: "commuteStart", Before: text("2026-03-02T07:30:00Z"), After: text("2026-03-02T08:00:00Z"), ShiftMinutes: shift(30)},
+			{Field: "officeArrival", Before: text("2026-03-02T08:15:00Z"), After: text("2026-03-02T08:50:00Z"), ShiftMinutes: shift(35)},
+			{Field: "commuteEnd", Before: text("2026-03-02T17:45:00Z"), After: text("2026-03-02T17:50:00Z"), ShiftMinutes: shift(5)},
+			{Field: "travelMode", Before: text("DRIVE"), After: text("TRANSIT")},
+		}},
+		{OptionType: models.CommuteOptionFullRemoteRecommended, Status: models.OptionRemoved, RankA: rank(2), Changes: []models.FieldChange{}},
+	}
+	if !reflect.DeepEqual(comparison.Options, wantOptions) {
+		for _, option := range comparison.Options {
+			t.Logf("%+v", *option)
+		}
+		t.Errorf("options differ from the expected comparison")
+	}
+
+	gym := "gym"
+	start, end := "07:00", "08:00"
+	wantAdded := []models.PlanningConstraint{{Type: models.ConstraintBusy, Start: &start, End: &end, Label: &gym}}
+	if !reflect.DeepEqual(comparison.ConstraintsAdded, wantAdded) || len(comparison.ConstraintsRemoved) != 0 {
+		t.Errorf("constraints added %+v, removed %+v; want the gym slot added", comparison.ConstraintsAdded, comparison.ConstraintsRemoved)
+	}
+
+	// Times read in the user's timezone (GMT in March)
+	wantSummary := []string{
+		"Strategic afternoon is now the top option, ahead of full day office",
+		"Constraint added: busy 07:00-08:00 (gym)",
+		"Strategic afternoon was added at rank 1",
+		"Full day office moved from rank 1 to rank 2",
+		"Full day office: leave home at 08:00 instead of 07:30 (30 minutes later)",
+		"Full day office: arrive at the office at 08:50 instead of 08:15 (35 minutes later)",
+		"Full day office: get home at 17:50 instead of 17:45 (5 minutes later)",
+		"Full day office: travel by transit instead of drive",
+		"Full remote recommended is no longer offered",
+	}
+	if !reflect.DeepEqual(comparison.Summary, wantSummary) {
+		t.Errorf("summary = %#v, want %#v", comparison.Summary, wantSummary)
+	}
+
+	// Nobody else can compare the user's jobs
+	if _, err := r.CompareJobs(ctx, other.ID, before.ID, after.ID); err == nil || err.Error() != "job not found" {
+		t.Errorf("comparing another user's jobs: err = %v, want job not found", err)
+	}
+
+	// A job compared with itself hasn't changed
+	same, err := r.CompareJobs(ctx, user.ID, after.ID, after.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.TopOptionChanged || len(same.Summary) != 0 {
+		t.Errorf("comparing a job with itself: %+v", same)
+	}
+	for _, option := range same.Options {
+		if option.Status != models.OptionUnchanged {
+			t.Errorf("%s is %s, want UNCHANGED", option.OptionType, option.Status)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		jobIDA       string
+		jobIDB       string
+		wantErrorMsg string
+	}{
+		{"unknown job", before.ID, "missing", "job not found"},
+		{"another user's job", before.ID, createJob(other.ID, "2026-03-02", "{}").ID, "job not found"},
+		{"another day", before.ID, createJob(user.ID, "2026-03-03", "{}").ID, "jobs plan different days (2026-03-02 and 2026-03-03)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := r.CompareJobs(ctx, user.ID, tt.jobIDA, tt.jobIDB); err == nil || err.Error() != tt.wantErrorMsg {
+				t.Fatalf("CompareJobs() error = %v, want %q", err, tt.wantErrorMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package resolvers
+
+import "fmt"
+
+// ErrCodeConflict prefixes the error of an update that lost a race with another writer
+const ErrCodeConflict = "CONFLICT"
+
+// ConflictError is returned when an update was made against a stale version of a row:
+// its expectedVersion is behind, or a concurrent writer got there first
+type ConflictError struct {
+	// Kind is what was updated, e.g. "job"
+	Kind string
+	ID   string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %s %s was updated concurrently; fetch its current version and retry", ErrCodeConflict, e.Kind, e.ID)
+}
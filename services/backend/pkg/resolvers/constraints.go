@@ -0,0 +1,112 @@
+package resolvers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// A job takes at most this many constraints, and labels are kept short for the reasoning
+const (
+	maxPlanningConstraints = 10
+	maxConstraintLabel     = 100
+)
+
+// validateConstraints checks a job's constraints: each has the fields its type needs in
+// HH:MM form, busy slots end after they start, and at least one travel mode is left.
+func validateConstraints(constraints []models.PlanningConstraint) error {
+	if len(constraints) > maxPlanningConstraints {
+		return fmt.Errorf("too many constraints: %d (max %d)", len(constraints), maxPlanningConstraints)
+	}
+
+	avoided := map[models.TravelMode]bool{}
+	var homeBy, leaveAfter *time.Time
+	for i, constraint := range constraints {
+		if constraint.Label != nil && len(*constraint.Label) > maxConstraintLabel {
+			return fmt.Errorf("constraint %d: label is longer than %d characters", i+1, maxConstraintLabel)
+		}
+		switch constraint.Type {
+		case models.ConstraintHomeBy, models.ConstraintLeaveAfter:
+			at, err := constraintTime(constraint.Time, "time")
+			if err != nil {
+				return fmt.Errorf("constraint %d: %w", i+1, err)
+			}
+			if constraint.Type == models.ConstraintHomeBy {
+				homeBy = &at
+			} else {
+				leaveAfter = &at
+			}
+		case models.ConstraintBusy:
+			start, err := constraintTime(constraint.Start, "start")
+			if err != nil {
+				return fmt.Errorf("constraint %d: %w", i+1, err)
+			}
+			end, err := constraintTime(constraint.End, "end")
+			if err != nil {
+				return fmt.Errorf("constraint %d: %w", i+1, err)
+			}
+			if !start.Before(end) {
+				return fmt.Errorf("constraint %d: busy slot must end after it starts", i+1)
+			}
+		case models.ConstraintAvoidMode:
+			if constraint.Mode == nil || !isTravelMode(*constraint.Mode) {
+				return fmt.Errorf("constraint %d: AVOID_MODE needs a travel mode", i+1)
+			}
+			avoided[*constraint.Mode] = true
+		default:
+			return fmt.Errorf("constraint %d: unknown constraint type %q", i+1, constraint.Type)
+		}
+	}
+
+	if len(avoided) == len(models.TravelModes) {
+		return fmt.Errorf("constraints avoid every travel mode")
+	}
+	if homeBy != nil && leaveAfter != nil && !leaveAfter.Before(*homeBy) {
+		return fmt.Errorf("can't leave after %s and be home by %s", leaveAfter.Format("15:04"), homeBy.Format("15:04"))
+	}
+	return nil
+}
+
+// constraintTime parses a required HH:MM constraint field
+func constraintTime(value *string, field string) (time.Time, error) {
+	if value == nil || *value == "" {
+		return time.Time{}, fmt.Errorf("%s is required", field)
+	}
+	at, err := time.Parse("15:04", *value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q (expected HH:MM)", field, *value)
+	}
+	return at, nil
+}
+
+// withConstraints adds the job's constraints to its input data under context, for the
+// planner. Unlike the location context they can't be dropped, so input data that isn't a
+// JSON object is rejected.
+func withConstraints(inputData *string, constraints []models.PlanningConstraint) (*string, error) {
+	if len(constraints) == 0 {
+		return inputData, nil
+	}
+
+	data := map[string]interface{}{}
+	if inputData != nil && strings.TrimSpace(*inputData) != "" {
+		if err := json.Unmarshal([]byte(*inputData), &data); err != nil {
+			return nil, fmt.Errorf("inputData must be a JSON object to add constraints")
+		}
+	}
+	jobContext, ok := data["context"].(map[string]interface{})
+	if !ok {
+		jobContext = map[string]interface{}{}
+	}
+	jobContext["constraints"] = constraints
+	data["context"] = jobContext
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job input: %w", err)
+	}
+	constrained := string(encoded)
+	return &constrained, nil
+}
@@ -0,0 +1,144 @@
+package resolvers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestValidateConstraints(t *testing.T) {
+	text := func(s string) *string { return &s }
+	avoid := func(m models.TravelMode) models.PlanningConstraint {
+		return models.PlanningConstraint{Type: models.ConstraintAvoidMode, Mode: &m}
+	}
+	homeBy := func(at string) models.PlanningConstraint {
+		return models.PlanningConstraint{Type: models.ConstraintHomeBy, Time: text(at)}
+	}
+	leaveAfter := func(at string) models.PlanningConstraint {
+		return models.PlanningConstraint{Type: models.ConstraintLeaveAfter, Time: text(at)}
+	}
+	busy := func(start, end string) models.PlanningConstraint {
+		return models.PlanningConstraint{Type: models.ConstraintBusy, Start: text(start), End: text(end)}
+	}
+
+	tests := []struct {
+		name        string
+		constraints []models.PlanningConstraint
+		wantErr     string
+	}{
+		{"none", nil, ""},
+		{"every type", []models.PlanningConstraint{
+			homeBy("18:30"), leaveAfter("07:45"), busy("12:00", "13:00"), avoid(models.TravelModeDrive),
+		}, ""},
+		{"too many", []models.PlanningConstraint{
+			homeBy("18:00"), homeBy("18:00"), homeBy("18:00"), homeBy("18:00"), homeBy("18:00"), homeBy("18:00"),
+			homeBy("18:00"), homeBy("18:00"), homeBy("18:00"), homeBy("18:00"), homeBy("18:00"),
+		}, "too many constraints: 11 (max 10)"},
+		{"long label", []models.PlanningConstraint{
+			{Type: models.ConstraintHomeBy, Time: text("18:00"), Label: text(strings.Repeat("x", 101))},
+		}, "constraint 1: label is longer than 100 characters"},
+		{"missing time", []models.PlanningConstraint{{Type: models.ConstraintHomeBy}}, "constraint 1: time is required"},
+		{"empty time", []models.PlanningConstraint{leaveAfter("")}, "constraint 1: time is required"},
+		{"time with seconds", []models.PlanningConstraint{homeBy("18:00:00")}, `constraint 1: invalid time "18:00:00" (expected HH:MM)`},
+		{"time out of range", []models.PlanningConstraint{leaveAfter("24:00")}, `constraint 1: invalid time "24:00" (expected HH:MM)`},
+		{"12-hour time", []models.PlanningConstraint{homeBy("6pm")}, `constraint 1: invalid time "6pm" (expected HH:MM)`},
+		{"busy slot without an end", []models.PlanningConstraint{
+			homeBy("19:00"), {Type: models.ConstraintBusy, Start: text("07:00")},
+		}, "constraint 2: end is required"},
+		{"busy slot bad start", []models.PlanningConstraint{busy("7am", "08:00")}, `constraint 1: invalid start "7am" (expected HH:MM)`},
+		{"busy slot ending before it starts", []models.PlanningConstraint{busy("08:00", "07:00")}, "constraint 1: busy slot must end after it starts"},
+		{"empty busy slot", []models.PlanningConstraint{busy("08:00", "08:00")}, "constraint 1: busy slot must end after it starts"},
+		{"avoid without a mode", []models.PlanningConstraint{{Type: models.ConstraintAvoidMode}}, "constraint 1: AVOID_MODE needs a travel mode"},
+		{"avoid an unknown mode", []models.PlanningConstraint{avoid("FERRY")}, "constraint 1: AVOID_MODE needs a travel mode"},
+		{"unknown type", []models.PlanningConstraint{{Type: "ARRIVE_BY", Time: text("09:00")}}, `constraint 1: unknown constraint type "ARRIVE_BY"`},
+		{"avoid all but one mode", []models.PlanningConstraint{
+			avoid(models.TravelModeDrive), avoid(models.TravelModeTransit), avoid(models.TravelModeBike),
+		}, ""},
+		{"avoid every mode", []models.PlanningConstraint{
+			avoid(models.TravelModeDrive), avoid(models.TravelModeTransit), avoid(models.TravelModeBike), avoid(models.TravelModeWalk),
+		}, "constraints avoid every travel mode"},
+		{"avoiding a mode twice still leaves others", []models.PlanningConstraint{
+			avoid(models.TravelModeDrive), avoid(models.TravelModeDrive), avoid(models.TravelModeTransit), avoid(models.TravelModeBike),
+		}, ""},
+		{"leave after home by", []models.PlanningConstraint{homeBy("09:00"), leaveAfter("17:00")}, "can't leave after 17:00 and be home by 09:00"},
+		{"leave at home by", []models.PlanningConstraint{leaveAfter("18:00"), homeBy("18:00")}, "can't leave after 18:00 and be home by 18:00"},
+		{"leave before home by", []models.PlanningConstraint{leaveAfter("08:00"), homeBy("18:00")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConstraints(tt.constraints)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateConstraints() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("validateConstraints() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithConstraints(t *testing.T) {
+	text := func(s string) *string { return &s }
+	gym := models.PlanningConstraint{Type: models.ConstraintBusy, Start: text("07:00"), End: text("08:00"), Label: text("gym")}
+	constraints := []models.PlanningConstraint{gym}
+	wantConstraints := []interface{}{map[string]interface{}{"type": "BUSY", "start": "07:00", "end": "08:00", "label": "gym"}}
+
+	tests := []struct {
+		name      string
+		inputData *string
+		want      map[string]interface{}
+		wantErr   bool
+	}{
+		{"no input", nil, map[string]interface{}{
+			"context": map[string]interface{}{"constraints": wantConstraints},
+		}, false},
+		{"blank input", text("  "), map[string]interface{}{
+			"context": map[string]interface{}{"constraints": wantConstraints},
+		}, false},
+		{"keeps the rest of the input", text(`{"targetDate": "2026-03-02", "context": {"user_timezone": "Europe/London"}}`), map[string]interface{}{
+			"targetDate": "2026-03-02",
+			"context":    map[string]interface{}{"user_timezone": "Europe/London", "constraints": wantConstraints},
+		}, false},
+		{"replaces a context that isn't an object", text(`{"context": "office"}`), map[string]interface{}{
+			"context": map[string]interface{}{"constraints": wantConstraints},
+		}, false},
+		{"array input", text(`[1, 2]`), nil, true},
+		{"string input", text(`"plan my week"`), nil, true},
+		{"malformed input", text(`{"context":`), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withConstraints(tt.inputData, constraints)
+			if tt.wantErr {
+				if err == nil || err.Error() != "inputData must be a JSON object to add constraints" {
+					t.Fatalf("withConstraints() error = %v, want the JSON object error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(*got), &data); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(data, tt.want) {
+				t.Fatalf("withConstraints() = %s, want %v", *got, tt.want)
+			}
+		})
+	}
+
+	// Without constraints the input is passed through untouched, even when it isn't JSON
+	for _, inputData := range []*string{nil, text("not json")} {
+		got, err := withConstraints(inputData, nil)
+		if err != nil || got != inputData {
+			t.Fatalf("withConstraints(%v, nil) = %v, %v, want the input unchanged", inputData, got, err)
+		}
+	}
+}
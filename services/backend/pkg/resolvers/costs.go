@@ -0,0 +1,99 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/costs"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Commute cost history covers this many calendar months by default, and at most maxCostMonths
+const (
+	defaultCostMonths = 6
+	maxCostMonths     = 24
+)
+
+// CommuteCosts totals the cost of the user's commute history per calendar month, for the
+// last months months (6 when nil) up to and including the current one, oldest first.
+// Days count like in CarbonStats; remote days are credited with the cost of the day's
+// cheapest office option.
+func (r *Resolver) CommuteCosts(ctx context.Context, userID string, months *int) ([]*models.MonthlyCommuteCost, error) {
+	count := defaultCostMonths
+	if months != nil {
+		count = *months
+	}
+	if count < 1 || count > maxCostMonths {
+		return nil, fmt.Errorf("months must be between 1 and %d", maxCostMonths)
+	}
+
+	now, err := r.userNow(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	from := to.AddDate(0, -count, 0)
+	days, err := r.commuteHistory(ctx, userID, repository.DateRange{From: &from, To: &to})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.MonthlyCommuteCost, count)
+	byMonth := map[string]*models.MonthlyCommuteCost{}
+	for i := range result {
+		month := from.AddDate(0, i, 0).Format("2006-01")
+		result[i] = &models.MonthlyCommuteCost{Month: month}
+		byMonth[month] = result[i]
+	}
+	for _, day := range days {
+		totals, ok := byMonth[day.date[:len("2006-01")]]
+		if !ok {
+			continue
+		}
+		if day.accepted.CommuteStart == nil {
+			totals.RemoteDays++
+			if avoided := cheapestOfficeCost(day.options); avoided != nil {
+				totals.AvoidedCost += *avoided
+			}
+			continue
+		}
+		totals.CommuteDays++
+		cost := costs.Estimate(day.accepted)
+		if cost == nil {
+			totals.UnestimatedDays++
+			continue
+		}
+		totals.Total += cost.Total
+		totals.Fuel += cost.Fuel
+		totals.Parking += cost.Parking
+		totals.Congestion += cost.Congestion
+		totals.Fares += cost.Fares
+	}
+
+	for _, totals := range result {
+		totals.Total = costs.Round(totals.Total)
+		totals.Fuel = costs.Round(totals.Fuel)
+		totals.Parking = costs.Round(totals.Parking)
+		totals.Congestion = costs.Round(totals.Congestion)
+		totals.Fares = costs.Round(totals.Fares)
+		totals.AvoidedCost = costs.Round(totals.AvoidedCost)
+	}
+	return result, nil
+}
+
+// cheapestOfficeCost is the cost of the cheapest costed office option, or nil when none
+// was costed
+func cheapestOfficeCost(options []*models.CommuteRecommendation) *float64 {
+	var cheapest *float64
+	for _, rec := range options {
+		if rec.CommuteStart == nil {
+			continue
+		}
+		if cost := costs.Estimate(rec); cost != nil && (cheapest == nil || cost.Total < *cheapest) {
+			cheapest = &cost.Total
+		}
+	}
+	return cheapest
+}
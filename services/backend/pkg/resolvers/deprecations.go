@@ -0,0 +1,54 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/deprecation"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// defaultUsageWindow is how far back usage reports look without a since
+const defaultUsageWindow = 30 * 24 * time.Hour
+
+// ReportDeprecationsOf reports the use of the deprecated fields of index; without it the
+// deprecatedFieldUsage query fails
+func (r *Resolver) ReportDeprecationsOf(index *deprecation.Index) {
+	r.deprecations = index
+}
+
+// DeprecatedFieldUsage lists every deprecated field and argument of the schema with the
+// clients that used it since since (YYYY-MM-DD), the last 30 days when empty. Only the
+// admin token may read it.
+func (r *Resolver) DeprecatedFieldUsage(ctx context.Context, viewer authz.Viewer, since string) ([]*models.DeprecatedField, error) {
+	if !viewer.Has(authz.RoleAdmin) {
+		return nil, authz.ErrForbidden
+	}
+	if r.deprecations == nil {
+		return nil, errors.New("deprecated field usage isn't tracked")
+	}
+	start, err := usageSince(since)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := r.deprecated.List(ctx, start)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching deprecated field usage: %w", err)
+	}
+	return r.deprecations.Report(usage, time.Now()), nil
+}
+
+// usageSince parses the since of a usage report, defaulting to defaultUsageWindow ago
+func usageSince(since string) (time.Time, error) {
+	if since == "" {
+		return time.Now().Add(-defaultUsageWindow), nil
+	}
+	start, err := time.Parse("2006-01-02", since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since %q: expected YYYY-MM-DD", since)
+	}
+	return start, nil
+}
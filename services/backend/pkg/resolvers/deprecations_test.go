@@ -0,0 +1,55 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/deprecation"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestDeprecatedFieldUsage(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	admin := authz.Viewer{Admin: true}
+	if _, err := r.DeprecatedFieldUsage(ctx, admin, ""); err == nil {
+		t.Error("report without tracking didn't fail")
+	}
+
+	index, err := deprecation.Load(`type Query { plan: String, legacyPlan: String @deprecated(reason: "Use plan") }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.ReportDeprecationsOf(index)
+	now := time.Now()
+	err = repos.Deprecations.Record(ctx, []*models.DeprecatedFieldUsage{
+		{Coordinate: "Query.legacyPlan", ClientName: "ios", ClientVersion: "1.0", Requests: 2, FirstUsedAt: now, LastUsedAt: now},
+		{Coordinate: "Query.legacyPlan", ClientName: "web", ClientVersion: "7", Requests: 1, FirstUsedAt: now.AddDate(0, 0, -40), LastUsedAt: now.AddDate(0, 0, -40)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := r.DeprecatedFieldUsage(ctx, admin, "")
+	if err != nil || len(fields) != 1 {
+		t.Fatalf("fields = %+v, %v", fields, err)
+	}
+	if legacy := fields[0]; legacy.Coordinate != "Query.legacyPlan" || legacy.Requests != 2 || len(legacy.Clients) != 1 {
+		t.Errorf("usage in the last 30 days = %+v", legacy)
+	}
+	since := now.AddDate(0, 0, -60).Format("2006-01-02")
+	if fields, err := r.DeprecatedFieldUsage(ctx, admin, since); err != nil || fields[0].Requests != 3 {
+		t.Errorf("usage since %s = %+v, %v", since, fields, err)
+	}
+	if _, err := r.DeprecatedFieldUsage(ctx, admin, "last week"); err == nil {
+		t.Error("invalid since accepted")
+	}
+	for _, viewer := range []authz.Viewer{{}, {UserID: "ada", OrgAdmin: true}} {
+		if _, err := r.DeprecatedFieldUsage(ctx, viewer, ""); !errors.Is(err, authz.ErrForbidden) {
+			t.Errorf("report for %+v: err = %v, want %v", viewer, err, authz.ErrForbidden)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/allday"
+	"github.com/commute-planner/backend/pkg/costs"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/units"
+)
+
+// WeeklyDigest summarises the seven days from weekStart (YYYY-MM-DD; the current week
+// from Monday when empty) in the user's timezone: each day's plan, the meetings that need
+// the user in the office, the expected commute time and the meetings the plan misses.
+// Days an all-day entry has the user out of office need no plan.
+func (r *Resolver) WeeklyDigest(ctx context.Context, userID, weekStart string) (*models.WeeklyDigest, error) {
+	location, err := r.userLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.users.Get(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	format := units.For(user, location)
+	var start time.Time
+	if weekStart == "" {
+		now := time.Now().In(location)
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		start = today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	} else {
+		start, err = time.Parse("2006-01-02", weekStart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weekStart %q: expected YYYY-MM-DD", weekStart)
+		}
+	}
+	end := start.AddDate(0, 0, 7)
+
+	digest := &models.WeeklyDigest{
+		UserID:    userID,
+		WeekStart: start.Format("2006-01-02"),
+		WeekEnd:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Days:      make([]models.DigestDay, 7),
+		Conflicts: []models.DigestConflict{},
+	}
+	byDate := map[string]*models.DigestDay{}
+	for i := range digest.Days {
+		date := start.AddDate(0, 0, i)
+		day := &digest.Days[i]
+		day.Date = date.Format("2006-01-02")
+		day.Weekday = date.Weekday().String()
+		day.InPersonMeetings = []*models.CalendarEvent{}
+		byDate[day.Date] = day
+	}
+
+	plans, err := r.weekPlans(ctx, userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := r.markerEvents(ctx, userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching calendar events: %w", err)
+	}
+	for _, event := range events {
+		if event.AttendanceMode != models.AttendanceMustBeInOffice || !allday.Busy(event) {
+			continue
+		}
+		if day, ok := byDate[event.StartTime.In(location).Format("2006-01-02")]; ok {
+			day.InPersonMeetings = append(day.InPersonMeetings, event)
+		}
+	}
+
+	for i := range digest.Days {
+		day := &digest.Days[i]
+		date, _ := time.Parse("2006-01-02", day.Date)
+		if marked := allday.Plan(events, date, location); marked != nil && marked.Status != "" {
+			day.OutOfOffice = marked.Status == allday.StatusSkip
+			day.Note = &marked.Reason
+		}
+		if day.OutOfOffice {
+			// Days off need no commute; their meetings are the user's to decline, not the
+			// plan's to cover
+			digest.OutOfOfficeDays++
+			continue
+		}
+
+		plan := plans[day.Date]
+		withPlan(day, plan)
+		switch {
+		case plan == nil:
+			digest.UnplannedDays++
+		case day.InOffice:
+			digest.OfficeDays++
+		default:
+			digest.RemoteDays++
+		}
+		digest.CommuteMinutes += day.CommuteMinutes
+		digest.CommuteDistanceKm = addKnown(digest.CommuteDistanceKm, day.DistanceKm)
+		digest.CommuteCost = addKnown(digest.CommuteCost, day.Cost)
+		digest.InPersonMeetings += len(day.InPersonMeetings)
+
+		for _, event := range day.InPersonMeetings {
+			if conflict := digestConflict(day, event, format); conflict != nil {
+				digest.Conflicts = append(digest.Conflicts, *conflict)
+			}
+		}
+	}
+	return digest, nil
+}
+
+// weekPlans returns the plan of each day in [start, end) by date: the recommendation the
+// user accepted, or else the top option of the day's latest completed job
+func (r *Resolver) weekPlans(ctx context.Context, userID string, start, end time.Time) (map[string]*models.CommuteRecommendation, error) {
+	plans := map[string]*models.CommuteRecommendation{}
+	history, err := r.commuteHistory(ctx, userID, repository.DateRange{From: &start, To: &end})
+	if err != nil {
+		return nil, err
+	}
+	for _, day := range history {
+		plans[day.date] = day.accepted
+	}
+
+	jobs, err := r.jobs.List(ctx, &userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching jobs: %w", err)
+	}
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+	latest := map[string]*models.Job{}
+	for _, job := range jobs {
+		if job.Status != models.JobStatusCompleted || len(job.TargetDate) < 10 {
+			continue
+		}
+		date := job.TargetDate[:10]
+		if date < from || date >= to || plans[date] != nil {
+			continue
+		}
+		if current, ok := latest[date]; !ok || job.CreatedAt.After(current.CreatedAt) {
+			latest[date] = job
+		}
+	}
+	for date, job := range latest {
+		options, err := r.recommendations.ListByJob(ctx, job.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching recommendations: %w", err)
+		}
+		if len(options) > 0 {
+			plans[date] = options[0]
+		}
+	}
+	return plans, nil
+}
+
+// withPlan fills in the plan fields of day; plan may be nil
+func withPlan(day *models.DigestDay, plan *models.CommuteRecommendation) {
+	if plan == nil {
+		return
+	}
+	day.RecommendationID = &plan.ID
+	day.OptionType = &plan.OptionType
+	day.Accepted = plan.AcceptedAt != nil
+	day.InOffice = plan.CommuteStart != nil
+	day.CommuteStart, day.OfficeArrival = plan.CommuteStart, plan.OfficeArrival
+	day.OfficeDeparture, day.CommuteEnd = plan.OfficeDeparture, plan.CommuteEnd
+	day.CommuteMinutes = minutesBetween(plan.CommuteStart, plan.OfficeArrival) + minutesBetween(plan.OfficeDeparture, plan.CommuteEnd)
+	for _, leg := range plan.DecodeTravelLegs() {
+		day.DistanceKm = addKnown(day.DistanceKm, leg.DistanceKm)
+	}
+	if cost := costs.Estimate(plan); cost != nil {
+		day.Cost = &cost.Total
+	}
+}
+
+// addKnown adds value to total, either of which may be unknown (nil)
+func addKnown(total, value *float64) *float64 {
+	if value == nil {
+		return total
+	}
+	sum := *value
+	if total != nil {
+		sum += *total
+	}
+	return &sum
+}
+
+// digestConflict reports an in-person meeting the day's plan doesn't have the user in the
+// office for, or nil; times are shown in format
+func digestConflict(day *models.DigestDay, event *models.CalendarEvent, format units.Format) *models.DigestConflict {
+	clock := format.Time
+	conflict := &models.DigestConflict{Date: day.Date, EventID: event.ID, Summary: event.Summary}
+	switch {
+	case day.RecommendationID == nil:
+		conflict.Type = models.ConflictUnplannedDay
+		conflict.Description = fmt.Sprintf("%s at %s on %s needs you in the office, but the day isn't planned yet",
+			event.Summary, clock(event.StartTime), day.Weekday)
+	case !day.InOffice || day.OfficeArrival == nil || day.OfficeDeparture == nil:
+		conflict.Type = models.ConflictRemoteDay
+		conflict.Description = fmt.Sprintf("%s at %s on %s needs you in the office, but the day is planned remote",
+			event.Summary, clock(event.StartTime), day.Weekday)
+	case event.StartTime.Before(*day.OfficeArrival) || event.EndTime.After(*day.OfficeDeparture):
+		conflict.Type = models.ConflictOutsideOfficeHours
+		conflict.Description = fmt.Sprintf("%s runs %s-%s on %s, outside your planned office hours %s-%s",
+			event.Summary, clock(event.StartTime), clock(event.EndTime), day.Weekday,
+			clock(*day.OfficeArrival), clock(*day.OfficeDeparture))
+	default:
+		return nil
+	}
+	return conflict
+}
+
+// minutesBetween is the whole minutes from a to b, or 0 when either is unknown
+func minutesBetween(a, b *time.Time) int {
+	if a == nil || b == nil || b.Before(*a) {
+		return 0
+	}
+	return int(b.Sub(*a).Minutes())
+}
@@ -0,0 +1,139 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/google/uuid"
+)
+
+func TestWeeklyDigest(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	completed, inProgress := string(models.JobStatusCompleted), string(models.JobStatusInProgress)
+	at := func(date string, hour, minute int) *time.Time {
+		day, _ := time.Parse("2006-01-02", date)
+		t := day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		return &t
+	}
+
+	// plan completes a job for date with an office option (arriving at 9:30, leaving at
+	// 17:00, 45 minutes each way) and a remote one, ranked in that order unless remoteFirst
+	plan := func(date string, remoteFirst bool) []*models.CommuteRecommendation {
+		t.Helper()
+		job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: date})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, status := range []*string{&inProgress, &completed} {
+			if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: status}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		office := &models.CommuteRecommendation{
+			JobID: job.ID, OptionRank: 1, OptionType: models.CommuteOptionFullDayOffice,
+			CommuteStart: at(date, 8, 45), OfficeArrival: at(date, 9, 30),
+			OfficeDeparture: at(date, 17, 0), CommuteEnd: at(date, 17, 45),
+		}
+		remote := &models.CommuteRecommendation{JobID: job.ID, OptionRank: 2, OptionType: models.CommuteOptionFullRemoteRecommended}
+		if remoteFirst {
+			office.OptionRank, remote.OptionRank = 2, 1
+		}
+		for _, rec := range []*models.CommuteRecommendation{office, remote} {
+			if err := repos.Recommendations.Create(ctx, rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return []*models.CommuteRecommendation{office, remote}
+	}
+	meeting := func(summary, date string, hour int, mode models.AttendanceMode) *models.CalendarEvent {
+		t.Helper()
+		event := &models.CalendarEvent{
+			ID: uuid.New().String(), UserID: user.ID, Summary: summary, StartTime: *at(date, hour, 0), EndTime: *at(date, hour+1, 0),
+			AttendanceMode: mode,
+		}
+		if err := repos.Events.Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+		return event
+	}
+
+	// Week of Monday 2026-03-02: Monday in the office; Tuesday planned remote; Wednesday's
+	// office option accepted although remote was ranked first; Thursday unplanned
+	plan("2026-03-02", false)
+	plan("2026-03-03", true)
+	wednesday := plan("2026-03-04", true)
+	if _, err := r.AcceptCommuteRecommendation(ctx, user.ID, wednesday[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	plan("2026-03-09", false) // next week
+
+	meeting("Board review", "2026-03-02", 10, models.AttendanceMustBeInOffice)
+	early := meeting("Breakfast with the CEO", "2026-03-02", 8, models.AttendanceMustBeInOffice)
+	remoteDay := meeting("Workshop", "2026-03-03", 14, models.AttendanceMustBeInOffice)
+	meeting("Standup", "2026-03-03", 9, models.AttendanceCanBeRemote)
+	unplanned := meeting("Interview", "2026-03-05", 11, models.AttendanceMustBeInOffice)
+	meeting("Offsite prep", "2026-03-06", 10, models.AttendanceMustBeInOffice)
+	// Friday to Sunday off, and Tuesday blocked for focus work
+	for _, entry := range []struct{ summary, from, to string }{
+		{"Vacation", "2026-03-06", "2026-03-09"},
+		{"Focus day", "2026-03-03", "2026-03-04"},
+	} {
+		event := &models.CalendarEvent{
+			ID: uuid.New().String(), UserID: user.ID, Summary: entry.summary, StartTime: *at(entry.from, 0, 0), EndTime: *at(entry.to, 0, 0),
+			AttendanceMode: models.AttendanceMustBeInOffice, IsAllDay: true,
+		}
+		if err := repos.Events.Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	digest, err := r.WeeklyDigest(ctx, user.ID, "2026-03-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest.WeekEnd != "2026-03-08" || len(digest.Days) != 7 {
+		t.Fatalf("week %s-%s with %d days", digest.WeekStart, digest.WeekEnd, len(digest.Days))
+	}
+	if digest.OfficeDays != 2 || digest.RemoteDays != 1 || digest.UnplannedDays != 1 || digest.OutOfOfficeDays != 3 {
+		t.Errorf("%d office, %d remote, %d unplanned, %d out-of-office days, want 2, 1, 1, 3",
+			digest.OfficeDays, digest.RemoteDays, digest.UnplannedDays, digest.OutOfOfficeDays)
+	}
+	if friday := digest.Days[4]; !friday.OutOfOffice || friday.Note == nil || *friday.Note != "Out of office: Vacation" {
+		t.Errorf("Friday = %+v, want out of office", friday)
+	}
+	if tuesday := digest.Days[1]; tuesday.OutOfOffice || tuesday.Note == nil || *tuesday.Note != "Blocked for remote work: Focus day" {
+		t.Errorf("Tuesday = %+v, want a note on the focus day", tuesday)
+	}
+	if digest.CommuteMinutes != 2*90 {
+		t.Errorf("commute minutes = %d, want 180", digest.CommuteMinutes)
+	}
+	if digest.InPersonMeetings != 4 || len(digest.Days[0].InPersonMeetings) != 2 {
+		t.Errorf("%d in-person meetings, %d on Monday, want 4 and 2", digest.InPersonMeetings, len(digest.Days[0].InPersonMeetings))
+	}
+	if wed := digest.Days[2]; !wed.Accepted || !wed.InOffice || *wed.RecommendationID != wednesday[0].ID {
+		t.Errorf("Wednesday = %+v, want the accepted office option", wed)
+	}
+
+	want := map[string]models.DigestConflictType{
+		early.ID:     models.ConflictOutsideOfficeHours,
+		remoteDay.ID: models.ConflictRemoteDay,
+		unplanned.ID: models.ConflictUnplannedDay,
+	}
+	if len(digest.Conflicts) != len(want) {
+		t.Fatalf("conflicts = %+v, want %d", digest.Conflicts, len(want))
+	}
+	for _, conflict := range digest.Conflicts {
+		if conflict.Type != want[conflict.EventID] || conflict.Description == "" {
+			t.Errorf("conflict %+v, want type %s", conflict, want[conflict.EventID])
+		}
+	}
+
+	if _, err := r.WeeklyDigest(ctx, user.ID, "next week"); err == nil {
+		t.Error("invalid weekStart accepted")
+	}
+}
@@ -0,0 +1,112 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/experiments"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// RunExperiment assigns each new job's user a variant of experiment, tags the job with it
+// and passes it to the planner in the job's input data
+func (r *Resolver) RunExperiment(experiment *experiments.Experiment) {
+	r.experiment = experiment
+}
+
+// assignVariant returns the user's variant of the running experiment, or nil
+func (r *Resolver) assignVariant(userID string) *experiments.Assignment {
+	if r.experiment == nil {
+		return nil
+	}
+	assignment := r.experiment.Assign(userID)
+	return &assignment
+}
+
+// withExperiment adds the job's variant to its input data as context.experiment.
+// Input data that isn't a JSON object is left as is; the job is still tagged.
+func withExperiment(inputData *string, assignment *experiments.Assignment) (*string, error) {
+	if assignment == nil {
+		return inputData, nil
+	}
+
+	data := map[string]interface{}{}
+	if inputData != nil && strings.TrimSpace(*inputData) != "" {
+		if err := json.Unmarshal([]byte(*inputData), &data); err != nil {
+			return inputData, nil
+		}
+	}
+	jobContext, ok := data["context"].(map[string]interface{})
+	if !ok {
+		jobContext = map[string]interface{}{}
+	}
+	jobContext["experiment"] = assignment
+	data["context"] = jobContext
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job input: %w", err)
+	}
+	tagged := string(encoded)
+	return &tagged, nil
+}
+
+// ExperimentResults compares the acceptance rate of each variant of an experiment over
+// jobs targeting days from from to to (YYYY-MM-DD, inclusive, either may be empty). Each
+// variant is compared against the control when experiment is the one running.
+func (r *Resolver) ExperimentResults(ctx context.Context, experiment, from, to string) (*models.ExperimentResults, error) {
+	if experiment == "" {
+		return nil, fmt.Errorf("experiment is required")
+	}
+	var dates repository.DateRange
+	if from != "" {
+		day, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from %q: expected YYYY-MM-DD", from)
+		}
+		dates.From = &day
+	}
+	if to != "" {
+		day, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to %q: expected YYYY-MM-DD", to)
+		}
+		day = day.AddDate(0, 0, 1)
+		dates.To = &day
+	}
+
+	variants, err := r.recommendations.ExperimentStats(ctx, experiment, dates)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching experiment stats: %w", err)
+	}
+
+	results := &models.ExperimentResults{Experiment: experiment, Variants: []models.VariantResult{}}
+	if r.experiment != nil && r.experiment.Name == experiment {
+		control := r.experiment.Control()
+		results.Control = &control
+	}
+	var control *models.VariantResult
+	for i := range variants {
+		v := &variants[i]
+		v.AcceptanceRate = experiments.Rate(v.AcceptedJobs, v.CompletedJobs)
+		v.TopChoiceRate = experiments.Rate(v.TopChoiceAccepted, v.CompletedJobs)
+		if results.Control != nil && v.Variant == *results.Control {
+			control = v
+		}
+	}
+	for i := range variants {
+		v := &variants[i]
+		if control == nil || v == control || control.CompletedJobs == 0 || v.CompletedJobs == 0 {
+			continue
+		}
+		lift := v.AcceptanceRate - control.AcceptanceRate
+		v.Lift = &lift
+		v.PValue = experiments.PValue(control.AcceptedJobs, control.CompletedJobs, v.AcceptedJobs, v.CompletedJobs)
+	}
+	results.Variants = append(results.Variants, variants...)
+	return results, nil
+}
@@ -0,0 +1,116 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/experiments"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestExperiment(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	experiment, err := experiments.Parse("ranking", []string{"control", "ranked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.RunExperiment(experiment)
+
+	completed := string(models.JobStatusCompleted)
+	inProgress := string(models.JobStatusInProgress)
+	jobs := map[string][]*models.Job{}
+	for i := 0; i < 40; i++ {
+		user := createTestUser(t, repos, fmt.Sprintf("user%d@example.com", i))
+		job, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := experiment.Assign(user.ID).Variant
+		if job.Experiment == nil || *job.Experiment != "ranking" || job.Variant == nil || *job.Variant != want {
+			t.Fatalf("job tagged %v/%v, want ranking/%s", job.Experiment, job.Variant, want)
+		}
+		var input struct {
+			Context struct {
+				Experiment experiments.Assignment `json:"experiment"`
+			} `json:"context"`
+		}
+		if err := json.Unmarshal([]byte(*job.InputData), &input); err != nil || input.Context.Experiment.Variant != want {
+			t.Fatalf("input data %s doesn't pass variant %s to the planner", *job.InputData, want)
+		}
+
+		for _, status := range []*string{&inProgress, &completed} {
+			if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: status}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for rank := 1; rank <= 2; rank++ {
+			if err := repos.Recommendations.Create(ctx, &models.CommuteRecommendation{JobID: job.ID, OptionRank: rank}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		jobs[want] = append(jobs[want], job)
+	}
+	if len(jobs["control"]) == 0 || len(jobs["ranked"]) == 0 {
+		t.Fatalf("users weren't split between variants: %d control, %d ranked", len(jobs["control"]), len(jobs["ranked"]))
+	}
+
+	// Control users accept the second option of every other plan; ranked users accept
+	// the first option of every plan
+	accept := func(job *models.Job, rank int) {
+		t.Helper()
+		recs, err := repos.Recommendations.ListByJob(ctx, job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recs[rank-1].Variant == nil || *recs[rank-1].Variant != *job.Variant {
+			t.Fatalf("recommendation variant = %v, want the job's %s", recs[rank-1].Variant, *job.Variant)
+		}
+		if _, err := r.AcceptCommuteRecommendation(ctx, job.UserID, recs[rank-1].ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	controlAccepted := 0
+	for i, job := range jobs["control"] {
+		if i%2 == 0 {
+			accept(job, 2)
+			controlAccepted++
+		}
+	}
+	for _, job := range jobs["ranked"] {
+		accept(job, 1)
+	}
+
+	results, err := r.ExperimentResults(ctx, "ranking", "2026-03-01", "2026-03-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Control == nil || *results.Control != "control" || len(results.Variants) != 2 {
+		t.Fatalf("results = %+v, want control and ranked compared", results)
+	}
+	control, ranked := results.Variants[0], results.Variants[1]
+	if control.Jobs != len(jobs["control"]) || control.CompletedJobs != control.Jobs || control.AcceptedJobs != controlAccepted || control.TopChoiceAccepted != 0 {
+		t.Errorf("control = %+v, want %d jobs with %d accepted, none top choice", control, len(jobs["control"]), controlAccepted)
+	}
+	if control.Lift != nil || control.PValue != nil {
+		t.Errorf("control compared against itself: %+v", control)
+	}
+	if ranked.AcceptanceRate != 1 || ranked.TopChoiceRate != 1 {
+		t.Errorf("ranked rates = %v, %v; want 1", ranked.AcceptanceRate, ranked.TopChoiceRate)
+	}
+	if ranked.Lift == nil || *ranked.Lift != 1-control.AcceptanceRate || ranked.PValue == nil {
+		t.Errorf("ranked = %+v, want its lift over control and a p-value", ranked)
+	}
+
+	// Days outside the range aren't counted
+	results, err = r.ExperimentResults(ctx, "ranking", "2026-03-03", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Variants) != 0 {
+		t.Errorf("results after the jobs' day = %+v, want none", results.Variants)
+	}
+}
@@ -0,0 +1,48 @@
+package resolvers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/ai"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// RecommendationExplainer generates recommendation text; satisfied by *ai.Explainer
+type RecommendationExplainer interface {
+	Explain(ctx context.Context, job *models.Job, rec *models.CommuteRecommendation) (*ai.Explanation, error)
+}
+
+// explainTimeout bounds regenerating the text of all of a job's recommendations
+const explainTimeout = 2 * time.Minute
+
+// explainRecommendations replaces the reasoning and perception analysis written by the AI
+// service with text from the configured provider. It runs after the job has completed, so
+// failures are logged and leave the AI service's text in place.
+func (r *Resolver) explainRecommendations(job *models.Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+	defer cancel()
+
+	recommendations, err := r.recommendations.ListByJob(ctx, job.ID)
+	if err != nil {
+		log.Printf("Failed to load recommendations of job %s for explanation: %v", job.ID, err)
+		return
+	}
+
+	for _, rec := range recommendations {
+		explanation, err := r.explainer.Explain(ctx, job, rec)
+		if err != nil {
+			log.Printf("Failed to explain recommendation %s of job %s: %v", rec.ID, job.ID, err)
+			continue
+		}
+		perception, err := explanation.PerceptionJSON()
+		if err != nil {
+			log.Printf("Failed to encode perception analysis of recommendation %s: %v", rec.ID, err)
+			continue
+		}
+		if err := r.recommendations.UpdateExplanation(ctx, rec.ID, explanation.Reasoning, perception); err != nil {
+			log.Printf("Failed to save explanation of recommendation %s: %v", rec.ID, err)
+		}
+	}
+}
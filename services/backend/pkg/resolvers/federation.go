@@ -0,0 +1,62 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// UserEntity and JobEntity are entities as Query._entities returns them, named by their
+// __typename for the gateway
+type UserEntity struct {
+	Typename string `json:"__typename"`
+	*models.User
+}
+
+type JobEntity struct {
+	Typename string `json:"__typename"`
+	*models.Job
+}
+
+// Entities resolves the representations an Apollo Federation gateway sends to
+// Query._entities, e.g. {"__typename": "User", "id": "..."}, in order. Users and jobs
+// are read like the user and job queries, within the tenant of ctx; those not found are
+// nil, so the gateway nulls just them.
+func (r *Resolver) Entities(ctx context.Context, representations []interface{}) ([]interface{}, error) {
+	entities := make([]interface{}, len(representations))
+	for i, representation := range representations {
+		fields, _ := representation.(map[string]interface{})
+		typename, _ := fields["__typename"].(string)
+		id, _ := fields["id"].(string)
+		if id == "" {
+			return nil, fmt.Errorf("representation %d has no id", i)
+		}
+
+		switch typename {
+		case "User":
+			user, err := r.users.Get(ctx, id)
+			if err == repository.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error fetching user: %w", err)
+			}
+			entities[i] = UserEntity{Typename: typename, User: user}
+		case "Job":
+			job, err := r.jobs.Get(ctx, id)
+			if err == repository.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error fetching job: %w", err)
+			}
+			r.estimateProgress(ctx, job)
+			entities[i] = JobEntity{Typename: typename, Job: job}
+		default:
+			return nil, fmt.Errorf("representation %d: %q is not an entity of this subgraph", i, typename)
+		}
+	}
+	return entities, nil
+}
@@ -0,0 +1,51 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestEntities(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	ada := createTestUser(t, repos, "ada@example.com")
+	job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: ada.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entities, err := r.Entities(ctx, []interface{}{
+		map[string]interface{}{"__typename": "Job", "id": job.ID},
+		map[string]interface{}{"__typename": "User", "id": "missing"},
+		map[string]interface{}{"__typename": "User", "id": ada.ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := json.Marshal(entities)
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 3 || decoded[0]["__typename"] != "Job" || decoded[0]["targetDate"] != "2026-03-02" {
+		t.Errorf("job entity = %v", decoded)
+	}
+	if decoded[1] != nil {
+		t.Errorf("missing user = %v, want null", decoded[1])
+	}
+	if decoded[2]["__typename"] != "User" || decoded[2]["email"] != "ada@example.com" {
+		t.Errorf("user entity = %v", decoded[2])
+	}
+
+	for _, representation := range []map[string]interface{}{
+		{"__typename": "Office", "id": "hq"},
+		{"__typename": "User"},
+	} {
+		if _, err := r.Entities(ctx, []interface{}{representation}); err == nil {
+			t.Errorf("representation %v resolved", representation)
+		}
+	}
+}
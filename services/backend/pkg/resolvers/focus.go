@@ -0,0 +1,100 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/focus"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// SetFocusTime sets the shortest deep-work block a user wants kept free each day; nil
+// turns focus time off
+func (r *Resolver) SetFocusTime(ctx context.Context, userID string, minutes *int) (*models.User, error) {
+	if minutes != nil && (*minutes < focus.MinMinutes || *minutes > focus.MaxMinutes) {
+		return nil, fmt.Errorf("focus time must be between %d and %d minutes", focus.MinMinutes, focus.MaxMinutes)
+	}
+	user, err := r.users.SetFocusMinutes(ctx, userID, minutes)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	return user, nil
+}
+
+// focusDay returns the focus time of a user who set one, and the free blocks of
+// targetDate's working hours in their timezone. minutes is 0 for users without focus time.
+func (r *Resolver) focusDay(ctx context.Context, userID, targetDate string) (minutes int, free []models.FocusWindow, err error) {
+	user, err := r.users.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return 0, nil, fmt.Errorf("user not found")
+		}
+		return 0, nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.FocusMinutes == nil {
+		return 0, nil, nil
+	}
+	if len(targetDate) > 10 {
+		targetDate = targetDate[:10]
+	}
+	date, err := time.Parse("2006-01-02", targetDate)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid target date %q", targetDate)
+	}
+	location, err := r.userLocation(ctx, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	events, err := r.markerEvents(ctx, userID, date, date.AddDate(0, 0, 1))
+	if err != nil {
+		return 0, nil, fmt.Errorf("error fetching calendar events: %w", err)
+	}
+	return *user.FocusMinutes, focus.Free(events, date, location), nil
+}
+
+// withFocusTime adds the user's focus time and the day's free blocks to the job's input
+// data as context.focus, so the planner can prefer options whose travel leaves a block
+// long enough. Like preferences it is best effort: failures are logged and the job
+// planned without it.
+func (r *Resolver) withFocusTime(ctx context.Context, userID, targetDate string, inputData *string) *string {
+	minutes, free, err := r.focusDay(ctx, userID, targetDate)
+	if err != nil {
+		log.Printf("Failed to find the focus time of user %s: %v", userID, err)
+		return inputData
+	}
+	if minutes == 0 {
+		return inputData
+	}
+
+	data := map[string]interface{}{}
+	if inputData != nil && strings.TrimSpace(*inputData) != "" {
+		if err := json.Unmarshal([]byte(*inputData), &data); err != nil {
+			return inputData
+		}
+	}
+	jobContext, ok := data["context"].(map[string]interface{})
+	if !ok {
+		jobContext = map[string]interface{}{}
+	}
+	jobContext["focus"] = map[string]interface{}{
+		"minimumMinutes": minutes,
+		"free":           free,
+	}
+	data["context"] = jobContext
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return inputData
+	}
+	enriched := string(encoded)
+	return &enriched
+}
@@ -0,0 +1,96 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/google/uuid"
+)
+
+func TestFocusTime(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	repos.Users.(*repository.MemoryUserRepository).SetPreferredTimezone(user.ID, "Europe/London")
+
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) *time.Time {
+		t := day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		return &t
+	}
+	// Free 08:00-09:00, 09:15-13:00 and 14:00-18:00 London time (UTC in March)
+	for _, meeting := range []struct {
+		summary    string
+		start, end *time.Time
+	}{{"Standup", at(9, 0), at(9, 15)}, {"Lunch with the team", at(13, 0), at(14, 0)}} {
+		event := &models.CalendarEvent{ID: uuid.New().String(), UserID: user.ID, Summary: meeting.summary, StartTime: *meeting.start, EndTime: *meeting.end}
+		if err := repos.Events.Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, minutes := range []int{10, 300} {
+		if _, err := r.SetFocusTime(ctx, user.ID, &minutes); err == nil {
+			t.Errorf("focus time of %d minutes accepted", minutes)
+		}
+	}
+	twoHours := 120
+	updated, err := r.SetFocusTime(ctx, user.ID, &twoHours)
+	if err != nil || updated.FocusMinutes == nil || *updated.FocusMinutes != 120 {
+		t.Fatalf("SetFocusTime = %+v, %v", updated, err)
+	}
+
+	job, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input struct {
+		Context struct {
+			Focus struct {
+				MinimumMinutes int                  `json:"minimumMinutes"`
+				Free           []models.FocusWindow `json:"free"`
+			} `json:"focus"`
+		} `json:"context"`
+	}
+	if err := json.Unmarshal([]byte(*job.InputData), &input); err != nil {
+		t.Fatal(err)
+	}
+	if focus := input.Context.Focus; focus.MinimumMinutes != 120 || len(focus.Free) != 3 {
+		t.Errorf("context.focus = %+v, want 120 minutes and three free blocks", focus)
+	}
+
+	// Commuting home at 15:30 splits the afternoon block into two too short for focus time;
+	// the remote day keeps it
+	for i, rec := range []*models.CommuteRecommendation{
+		{OptionType: models.CommuteOptionFullDayOffice, CommuteStart: at(8, 0), OfficeArrival: at(8, 45), OfficeDeparture: at(15, 30), CommuteEnd: at(16, 15)},
+		{OptionType: models.CommuteOptionFullRemoteRecommended},
+	} {
+		rec.ID, rec.JobID, rec.OptionRank = uuid.New().String(), job.ID, i+1
+		if err := repos.Recommendations.Create(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recommendations, err := r.CommuteRecommendations(ctx, authz.Viewer{UserID: user.ID}, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows := map[models.CommuteOptionType]int{}
+	for _, rec := range recommendations {
+		windows[rec.OptionType] = len(rec.FocusWindows)
+	}
+	if windows[models.CommuteOptionFullDayOffice] != 1 || windows[models.CommuteOptionFullRemoteRecommended] != 2 {
+		t.Errorf("focus windows by option = %v, want 1 in the office and 2 remote", windows)
+	}
+
+	if updated, err := r.SetFocusTime(ctx, user.ID, nil); err != nil || updated.FocusMinutes != nil {
+		t.Errorf("turning focus time off = %+v, %v", updated, err)
+	}
+	if recommendations, err = r.CommuteRecommendations(ctx, authz.Viewer{UserID: user.ID}, job.ID); err != nil || recommendations[0].FocusWindows != nil {
+		t.Errorf("focus windows reported without focus time: %v", err)
+	}
+}
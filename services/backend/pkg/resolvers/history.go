@@ -0,0 +1,90 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// historyDay is a day of a user's commute history: the recommendation they accepted, and
+// the options of the job it came from
+type historyDay struct {
+	date     string
+	accepted *models.CommuteRecommendation
+	options  []*models.CommuteRecommendation
+}
+
+// commuteHistory returns the days in range the user accepted a recommendation for, in
+// date order. With several accepted for a day, the latest wins.
+func (r *Resolver) commuteHistory(ctx context.Context, userID string, dates repository.DateRange) ([]*historyDay, error) {
+	var days []*historyDay
+	byDate := map[string]*historyDay{}
+	var jobOptions []*models.CommuteRecommendation
+
+	// Recommendations stream by day and job, so each job's options arrive together
+	flush := func() {
+		var accepted *models.CommuteRecommendation
+		for _, rec := range jobOptions {
+			if rec.AcceptedAt != nil && (accepted == nil || rec.AcceptedAt.After(*accepted.AcceptedAt)) {
+				accepted = rec
+			}
+		}
+		if accepted == nil {
+			return
+		}
+		day := &historyDay{date: accepted.Job.TargetDate, accepted: accepted, options: jobOptions}
+		current, ok := byDate[day.date]
+		if !ok {
+			days = append(days, day)
+			byDate[day.date] = day
+		} else if accepted.AcceptedAt.After(*current.accepted.AcceptedAt) {
+			*current = *day
+		}
+	}
+
+	err := r.recommendations.StreamByUser(ctx, userID, dates, func(rec *models.CommuteRecommendation) error {
+		if len(jobOptions) > 0 && rec.JobID != jobOptions[0].JobID {
+			flush()
+			jobOptions = nil
+		}
+		jobOptions = append(jobOptions, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching commute history: %w", err)
+	}
+	flush()
+	return days, nil
+}
+
+// userNow returns the current time in the user's preferred timezone
+func (r *Resolver) userNow(ctx context.Context, userID string) (time.Time, error) {
+	location, err := r.userLocation(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(location), nil
+}
+
+// userLocation returns the user's preferred timezone; UTC when it isn't a known zone
+func (r *Resolver) userLocation(ctx context.Context, userID string) (*time.Location, error) {
+	if _, err := r.users.Get(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	timezone, err := r.users.PreferredTimezone(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user timezone: %w", err)
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	return location, nil
+}
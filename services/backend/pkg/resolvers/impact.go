@@ -0,0 +1,187 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/allday"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/units"
+)
+
+// ImpactOfChange reports what a proposed change to one of the user's meetings would do to
+// the plans of the days it touches, without re-planning them: whether each plan would
+// still get the user to the day's in-person meetings, and what it would need to change.
+// Nothing is saved.
+func (r *Resolver) ImpactOfChange(ctx context.Context, userID, eventID string, change models.ProposedMeetingChange) (*models.MeetingChangeImpact, error) {
+	event, err := r.events.Get(ctx, userID, eventID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("calendar event not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("error fetching calendar event: %w", err)
+	}
+	proposed, err := changedMeeting(event, change)
+	if err != nil {
+		return nil, err
+	}
+	location, err := r.userLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := []string{event.StartTime.In(location).Format("2006-01-02")}
+	if moved := proposed.StartTime.In(location).Format("2006-01-02"); moved != dates[0] {
+		dates = append(dates, moved)
+	}
+	impact := &models.MeetingChangeImpact{EventID: event.ID, PlanValid: true, Days: []models.MeetingChangeDay{}}
+	for _, date := range dates {
+		day, err := r.dayImpact(ctx, userID, date, event, proposed, location)
+		if err != nil {
+			return nil, err
+		}
+		impact.PlanValid = impact.PlanValid && day.PlanValid
+		impact.Days = append(impact.Days, *day)
+	}
+	return impact, nil
+}
+
+// changedMeeting returns a copy of event with change applied
+func changedMeeting(event *models.CalendarEvent, change models.ProposedMeetingChange) (*models.CalendarEvent, error) {
+	if change.StartTime == nil && change.EndTime == nil && change.AttendanceMode == nil {
+		return nil, fmt.Errorf("proposedChange must change startTime, endTime or attendanceMode")
+	}
+	proposed := *event
+	if change.StartTime != nil {
+		proposed.StartTime = *change.StartTime
+		proposed.EndTime = change.StartTime.Add(event.EndTime.Sub(event.StartTime))
+	}
+	if change.EndTime != nil {
+		proposed.EndTime = *change.EndTime
+	}
+	if !proposed.EndTime.After(proposed.StartTime) {
+		return nil, fmt.Errorf("endTime must be after startTime")
+	}
+	if change.AttendanceMode != nil {
+		if !isAttendanceMode(*change.AttendanceMode) {
+			return nil, fmt.Errorf("unknown attendance mode %q", *change.AttendanceMode)
+		}
+		proposed.AttendanceMode = *change.AttendanceMode
+	}
+	return &proposed, nil
+}
+
+// dayImpact compares the conflicts of date's plan with its in-person meetings before and
+// after event is replaced by proposed
+func (r *Resolver) dayImpact(ctx context.Context, userID, date string, event, proposed *models.CalendarEvent, location *time.Location) (*models.MeetingChangeDay, error) {
+	start, _ := time.Parse("2006-01-02", date)
+	end := start.AddDate(0, 0, 1)
+	plans, err := r.weekPlans(ctx, userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	events, err := r.markerEvents(ctx, userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching calendar events: %w", err)
+	}
+
+	day := &models.DigestDay{Date: date, Weekday: start.Weekday().String()}
+	withPlan(day, plans[date])
+	impact := &models.MeetingChangeDay{
+		Date:             date,
+		RecommendationID: day.RecommendationID,
+		OptionType:       day.OptionType,
+		PlanValid:        true,
+		Conflicts:        []models.DigestConflict{},
+		Resolved:         []models.DigestConflict{},
+		Changes:          []string{},
+	}
+	if marked := allday.Plan(events, start, location); marked != nil && marked.Status == allday.StatusSkip {
+		// As in the weekly digest, days off need no commute
+		return impact, nil
+	}
+
+	inPerson := func(meeting *models.CalendarEvent) []*models.CalendarEvent {
+		meetings := []*models.CalendarEvent{}
+		for _, e := range events {
+			if e.ID != event.ID && isInPersonOn(e, date, location) {
+				meetings = append(meetings, e)
+			}
+		}
+		if isInPersonOn(meeting, date, location) {
+			meetings = append(meetings, meeting)
+		}
+		return meetings
+	}
+	conflicts := func(meetings []*models.CalendarEvent) map[string]models.DigestConflict {
+		found := map[string]models.DigestConflict{}
+		for _, meeting := range meetings {
+			if conflict := digestConflict(day, meeting, units.Format{Location: location}); conflict != nil {
+				found[meeting.ID] = *conflict
+			}
+		}
+		return found
+	}
+	before, after := inPerson(event), inPerson(proposed)
+	conflictsBefore, conflictsAfter := conflicts(before), conflicts(after)
+	for _, meeting := range after {
+		if conflict, ok := conflictsAfter[meeting.ID]; ok {
+			impact.Conflicts = append(impact.Conflicts, conflict)
+		}
+	}
+	for _, meeting := range before {
+		if conflict, ok := conflictsBefore[meeting.ID]; ok {
+			if _, still := conflictsAfter[meeting.ID]; !still {
+				impact.Resolved = append(impact.Resolved, conflict)
+			}
+		}
+	}
+	impact.PlanValid = len(impact.Conflicts) == 0
+	impact.Changes = planChanges(day, len(before), after, location)
+	return impact, nil
+}
+
+// isInPersonOn reports whether event is a meeting on date (in location) that must be
+// attended in the office
+func isInPersonOn(event *models.CalendarEvent, date string, location *time.Location) bool {
+	return event.AttendanceMode == models.AttendanceMustBeInOffice && allday.Busy(event) &&
+		event.StartTime.In(location).Format("2006-01-02") == date
+}
+
+// planChanges says what day's plan would need to change to fit the in-person meetings
+// after a change; before is how many there were
+func planChanges(day *models.DigestDay, before int, after []*models.CalendarEvent, location *time.Location) []string {
+	clock := func(t time.Time) string { return t.In(location).Format("15:04") }
+	changes := []string{}
+	if len(after) == 0 {
+		if day.InOffice && before > 0 {
+			changes = append(changes, "No meeting needs you in the office any more; the day could be worked remotely")
+		}
+		return changes
+	}
+	first, last := after[0].StartTime, after[0].EndTime
+	for _, meeting := range after[1:] {
+		if meeting.StartTime.Before(first) {
+			first = meeting.StartTime
+		}
+		if meeting.EndTime.After(last) {
+			last = meeting.EndTime
+		}
+	}
+	switch {
+	case day.RecommendationID == nil:
+		changes = append(changes, fmt.Sprintf("Plan the day in the office from %s to %s", clock(first), clock(last)))
+	case !day.InOffice || day.OfficeArrival == nil || day.OfficeDeparture == nil:
+		changes = append(changes, fmt.Sprintf("Go into the office from %s to %s instead of working remotely", clock(first), clock(last)))
+	default:
+		if first.Before(*day.OfficeArrival) {
+			changes = append(changes, fmt.Sprintf("Arrive at the office by %s instead of %s", clock(first), clock(*day.OfficeArrival)))
+		}
+		if last.After(*day.OfficeDeparture) {
+			changes = append(changes, fmt.Sprintf("Stay at the office until %s instead of %s", clock(last), clock(*day.OfficeDeparture)))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,129 @@
+package resolvers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/google/uuid"
+)
+
+func TestImpactOfChange(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	completed, inProgress := string(models.JobStatusCompleted), string(models.JobStatusInProgress)
+	at := func(date string, hour int) *time.Time {
+		day, _ := time.Parse("2006-01-02", date)
+		t := day.Add(time.Duration(hour) * time.Hour)
+		return &t
+	}
+
+	// Monday is planned in the office from 9 to 17, Tuesday is unplanned
+	job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range []*string{&inProgress, &completed} {
+		if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan := &models.CommuteRecommendation{
+		JobID: job.ID, OptionRank: 1, OptionType: models.CommuteOptionFullDayOffice,
+		CommuteStart: at("2026-03-02", 8), OfficeArrival: at("2026-03-02", 9),
+		OfficeDeparture: at("2026-03-02", 17), CommuteEnd: at("2026-03-02", 18),
+	}
+	if err := repos.Recommendations.Create(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+	meeting := func(summary string, hour int, mode models.AttendanceMode) *models.CalendarEvent {
+		t.Helper()
+		event := &models.CalendarEvent{
+			ID: uuid.New().String(), UserID: user.ID, Summary: summary,
+			StartTime: *at("2026-03-02", hour), EndTime: *at("2026-03-02", hour+1), AttendanceMode: mode,
+		}
+		if err := repos.Events.Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+		return event
+	}
+	remote, inOffice, unknown := models.AttendanceCanBeRemote, models.AttendanceMustBeInOffice, models.AttendanceMode("REMOTE")
+	review := meeting("Design review", 10, models.AttendanceMustBeInOffice)
+	standup := meeting("Standup", 9, models.AttendanceCanBeRemote)
+
+	tests := []struct {
+		name    string
+		eventID string
+		change  models.ProposedMeetingChange
+		valid   bool
+		changes [][]string
+	}{
+		{"moved within office hours", review.ID, models.ProposedMeetingChange{StartTime: at("2026-03-02", 14)}, true, [][]string{{}}},
+		{"moved past departure", review.ID, models.ProposedMeetingChange{StartTime: at("2026-03-02", 17)}, false,
+			[][]string{{"Stay at the office until 18:00 instead of 17:00"}}},
+		{"now remote", review.ID, models.ProposedMeetingChange{AttendanceMode: &remote}, true,
+			[][]string{{"No meeting needs you in the office any more"}}},
+		{"now in person before arrival", standup.ID, models.ProposedMeetingChange{StartTime: at("2026-03-02", 8), AttendanceMode: &inOffice}, false,
+			[][]string{{"Arrive at the office by 08:00 instead of 09:00"}}},
+		{"moved to an unplanned day", review.ID, models.ProposedMeetingChange{StartTime: at("2026-03-03", 10)}, false,
+			[][]string{{"No meeting needs you in the office any more"}, {"Plan the day in the office from 10:00 to 11:00"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			impact, err := r.ImpactOfChange(ctx, user.ID, tt.eventID, tt.change)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if impact.PlanValid != tt.valid || len(impact.Days) != len(tt.changes) {
+				t.Fatalf("impact = %+v, want valid %v over %d days", impact, tt.valid, len(tt.changes))
+			}
+			for i, want := range tt.changes {
+				got := impact.Days[i].Changes
+				if len(got) != len(want) {
+					t.Fatalf("day %d changes = %v, want %v", i, got, want)
+				}
+				for j := range want {
+					if !strings.HasPrefix(got[j], want[j]) {
+						t.Errorf("day %d change %d = %q, want %q", i, j, got[j], want[j])
+					}
+				}
+			}
+		})
+	}
+
+	// The meeting itself is left alone
+	if stored, _ := repos.Events.Get(ctx, user.ID, review.ID); !stored.StartTime.Equal(*at("2026-03-02", 10)) {
+		t.Errorf("assessing a change moved the meeting to %v", stored.StartTime)
+	}
+
+	// A conflict the change fixes is reported as resolved
+	late := meeting("Offsite prep", 17, models.AttendanceMustBeInOffice)
+	impact, err := r.ImpactOfChange(ctx, user.ID, late.ID, models.ProposedMeetingChange{StartTime: at("2026-03-02", 15)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !impact.PlanValid || len(impact.Days[0].Resolved) != 1 || impact.Days[0].Resolved[0].EventID != late.ID {
+		t.Errorf("impact = %+v, want the late meeting's conflict resolved", impact.Days[0])
+	}
+
+	errs := []struct {
+		name    string
+		userID  string
+		eventID string
+		change  models.ProposedMeetingChange
+	}{
+		{"no change", user.ID, review.ID, models.ProposedMeetingChange{}},
+		{"ends before it starts", user.ID, review.ID, models.ProposedMeetingChange{EndTime: at("2026-03-02", 9)}},
+		{"unknown mode", user.ID, review.ID, models.ProposedMeetingChange{AttendanceMode: &unknown}},
+		{"another user's meeting", uuid.New().String(), review.ID, models.ProposedMeetingChange{StartTime: at("2026-03-02", 14)}},
+	}
+	for _, tt := range errs {
+		if _, err := r.ImpactOfChange(ctx, tt.userID, tt.eventID, tt.change); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// maxJobStatusBatch caps the jobs one jobsByIds or jobStatuses call returns
+const maxJobStatusBatch = 200
+
+// JobsByIDs returns the status of each of the user's jobs in ids, in the same order, with
+// nil for jobs that don't exist or are someone else's. It lets a dashboard refresh many
+// jobs in one call.
+func (r *Resolver) JobsByIDs(ctx context.Context, userID string, ids []string) ([]*models.JobStatusSummary, error) {
+	if len(ids) > maxJobStatusBatch {
+		return nil, fmt.Errorf("too many job IDs: %d (max %d)", len(ids), maxJobStatusBatch)
+	}
+	if len(ids) == 0 {
+		return []*models.JobStatusSummary{}, nil
+	}
+	statuses, err := r.jobs.ListStatuses(ctx, repository.JobStatusFilter{UserID: &userID, IDs: ids, Limit: len(ids)})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching job statuses: %w", err)
+	}
+
+	byID := make(map[string]*models.JobStatusSummary, len(statuses))
+	for _, status := range statuses {
+		byID[status.ID] = status
+	}
+	ordered := make([]*models.JobStatusSummary, len(ids))
+	for i, id := range ids {
+		ordered[i] = byID[id]
+	}
+	return ordered, nil
+}
+
+// JobStatuses returns the status of a user's jobs updated at or after since (all of them
+// when since is nil), least recently updated first. Polling with since set to the last
+// updatedAt seen returns only what changed; that job itself is returned again.
+func (r *Resolver) JobStatuses(ctx context.Context, userID string, since *time.Time) ([]*models.JobStatusSummary, error) {
+	statuses, err := r.jobs.ListStatuses(ctx, repository.JobStatusFilter{UserID: &userID, Since: since, Limit: maxJobStatusBatch})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching job statuses: %w", err)
+	}
+	if statuses == nil {
+		statuses = []*models.JobStatusSummary{}
+	}
+	return statuses, nil
+}
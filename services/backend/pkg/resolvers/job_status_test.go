@@ -0,0 +1,66 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+func TestJobsByIDs(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	other := createTestUser(t, repos, "bob@example.com")
+	first, _ := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+	second, _ := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-03"})
+	bobs, _ := r.CreateJob(ctx, CreateJobInput{UserID: other.ID, TargetDate: "2026-03-02"})
+
+	// Someone else's job is reported the same as one that doesn't exist
+	unknown := uuid.New().String()
+	statuses, err := r.JobsByIDs(ctx, user.ID, []string{second.ID, unknown, bobs.ID, first.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 4 || statuses[0].ID != second.ID || statuses[1] != nil || statuses[2] != nil || statuses[3].ID != first.ID {
+		t.Fatalf("statuses = %+v, want second, nil, nil, first", statuses)
+	}
+	if statuses[3].Status != models.JobStatusPending || statuses[3].TargetDate != "2026-03-02" {
+		t.Errorf("first job status = %+v", statuses[3])
+	}
+
+	if _, err := r.JobsByIDs(ctx, user.ID, make([]string, maxJobStatusBatch+1)); err == nil {
+		t.Error("oversized batch accepted")
+	}
+}
+
+func TestJobStatusesSince(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	other := createTestUser(t, repos, "bob@example.com")
+	first, _ := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+	r.CreateJob(ctx, CreateJobInput{UserID: other.ID, TargetDate: "2026-03-02"})
+
+	all, err := r.JobStatuses(ctx, user.ID, nil)
+	if err != nil || len(all) != 1 || all[0].ID != first.ID {
+		t.Fatalf("statuses = %+v, %v; want the user's one job", all, err)
+	}
+
+	// Only jobs updated since the last poll come back
+	since := time.Now()
+	time.Sleep(time.Millisecond)
+	if changed, _ := r.JobStatuses(ctx, user.ID, &since); len(changed) != 0 {
+		t.Errorf("unchanged jobs returned: %+v", changed)
+	}
+	status := string(models.JobStatusInProgress)
+	if _, err := r.UpdateJob(ctx, first.ID, UpdateJobInput{Status: &status}); err != nil {
+		t.Fatal(err)
+	}
+	changed, _ := r.JobStatuses(ctx, user.ID, &since)
+	if len(changed) != 1 || changed[0].Status != models.JobStatusInProgress {
+		t.Errorf("changed = %+v, want the job now IN_PROGRESS", changed)
+	}
+}
@@ -0,0 +1,47 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/i18n"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// SetLocale sets the language the user's recommendation text is shown in, e.g. "de";
+// nil or "" returns them to English
+func (r *Resolver) SetLocale(ctx context.Context, userID string, locale *string) (*models.User, error) {
+	if locale != nil && *locale == "" {
+		locale = nil
+	}
+	if locale != nil {
+		matched, ok := i18n.Match(*locale)
+		if !ok {
+			return nil, fmt.Errorf("unsupported locale %q: expected one of %s", *locale, strings.Join(i18n.Locales, ", "))
+		}
+		locale = &matched
+	}
+	user, err := r.users.SetLocale(ctx, userID, locale)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("user not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	return user, nil
+}
+
+// localize fills in the reason codes of the user's recommendations and renders them in
+// the user's locale. A user who can't be read gets English.
+func (r *Resolver) localize(ctx context.Context, userID string, recommendations ...*models.CommuteRecommendation) {
+	locale := i18n.DefaultLocale
+	if user, err := r.users.Get(ctx, userID); err == nil && user.Locale != nil {
+		locale = *user.Locale
+	}
+	for _, rec := range recommendations {
+		rec.ReasonCodes = i18n.ReasonCodes(rec)
+		rec.Localized = i18n.Render(locale, rec.ReasonCodes)
+	}
+}
@@ -0,0 +1,70 @@
+package resolvers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestLocalizedRecommendations(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := &models.CommuteRecommendation{
+		JobID: job.ID, OptionRank: 1, OptionType: models.CommuteOptionFullRemoteRecommended,
+		ReasonCodes: []models.ReasonCode{
+			{Code: "RECOMMENDED"},
+			{Code: "FULL_REMOTE", Params: []models.ReasonParam{{Name: "remoteMeetings", Value: "4"}}},
+			{Code: "PRO_NO_COMMUTE"},
+		},
+	}
+	// Planned before reason codes existed
+	older := &models.CommuteRecommendation{JobID: job.ID, OptionRank: 2, OptionType: models.CommuteOptionFullDayOffice}
+	for _, rec := range []*models.CommuteRecommendation{written, older} {
+		if err := repos.Recommendations.Create(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recommendations, err := r.CommuteRecommendations(ctx, authz.Viewer{UserID: user.ID}, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := recommendations[0].Localized; text == nil || text.Locale != "en" || !strings.HasPrefix(text.Reasoning, "Recommended option:") {
+		t.Errorf("localized = %+v, want English by default", text)
+	}
+
+	de := "de-CH"
+	updated, err := r.SetLocale(ctx, user.ID, &de)
+	if err != nil || updated.Locale == nil || *updated.Locale != "de" {
+		t.Fatalf("user = %+v, %v, want locale de", updated, err)
+	}
+	recommendations, err = r.CommuteRecommendations(ctx, authz.Viewer{UserID: user.ID}, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := recommendations[0].Localized
+	if first.Locale != "de" || !strings.Contains(first.Reasoning, "Alle 4 Meetings") || len(first.Pros) != 1 || first.Pros[0] != "Keine Pendelzeit und -kosten" {
+		t.Errorf("localized = %+v", first)
+	}
+	second := recommendations[1]
+	if len(second.ReasonCodes) == 0 || !strings.HasPrefix(second.Localized.Reasoning, "Starke Alternative: Ein ganzer Tag im Büro") {
+		t.Errorf("derived = %+v, %+v", second.ReasonCodes, second.Localized)
+	}
+
+	pt := "pt-BR"
+	if _, err := r.SetLocale(ctx, user.ID, &pt); err == nil {
+		t.Error("accepted an unsupported locale")
+	}
+	if updated, err := r.SetLocale(ctx, user.ID, nil); err != nil || updated.Locale != nil {
+		t.Errorf("user = %+v, %v, want the locale cleared", updated, err)
+	}
+}
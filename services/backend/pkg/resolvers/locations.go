@@ -0,0 +1,117 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/geo"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// SetHomeAddress sets the address a user commutes from and geocodes it; nil or an empty
+// address clears it. An address that can't be geocoded is still saved, without
+// coordinates.
+func (r *Resolver) SetHomeAddress(ctx context.Context, userID string, address *string) (*models.User, error) {
+	var latitude, longitude *float64
+	if address != nil {
+		trimmed := strings.TrimSpace(*address)
+		if trimmed == "" {
+			address = nil
+		} else {
+			address = &trimmed
+			if point := r.geocodeAddress(ctx, trimmed); point != nil {
+				latitude, longitude = &point.Lat, &point.Lng
+			}
+		}
+	}
+
+	user, err := r.users.SetHomeAddress(ctx, userID, address, latitude, longitude)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	return user, nil
+}
+
+// geocodeAddress returns the coordinates of an address, or nil when no geocoder is
+// configured or the address can't be placed. Geocoding is best effort: provider failures
+// are logged rather than failing the request.
+func (r *Resolver) geocodeAddress(ctx context.Context, address string) *geo.Point {
+	if r.geocoder == nil || strings.TrimSpace(address) == "" {
+		return nil
+	}
+	result, err := r.geocoder.Geocode(ctx, address)
+	if err != nil {
+		if !errors.Is(err, geo.ErrNoResults) {
+			log.Printf("Failed to geocode %q with %s: %v", address, r.geocoder.Provider(), err)
+		}
+		return nil
+	}
+	return &result.Point
+}
+
+// withLocationContext adds the places a commute runs between to the job's input data under
+// context: the tenant's offices, the user's default office, the user's geocoded home and
+// how they travel. Input data that isn't a JSON object is returned unchanged, as is
+// everything when there is nothing to add.
+func (r *Resolver) withLocationContext(ctx context.Context, userID string, inputData *string) (*string, error) {
+	offices, err := r.offices.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching offices: %w", err)
+	}
+	user, err := r.users.Get(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	hasHome := user != nil && user.HomeLatitude != nil && user.HomeLongitude != nil
+	profile, err := r.travelProfiles.Get(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("error fetching travel profile: %w", err)
+	}
+	if len(offices) == 0 && !hasHome && profile == nil {
+		return inputData, nil
+	}
+
+	data := map[string]interface{}{}
+	if inputData != nil && strings.TrimSpace(*inputData) != "" {
+		if err := json.Unmarshal([]byte(*inputData), &data); err != nil {
+			return inputData, nil
+		}
+	}
+	jobContext, ok := data["context"].(map[string]interface{})
+	if !ok {
+		jobContext = map[string]interface{}{}
+	}
+
+	if len(offices) > 0 {
+		jobContext["offices"] = offices
+		if user != nil && user.DefaultOfficeID != nil {
+			jobContext["default_office_id"] = *user.DefaultOfficeID
+		}
+	}
+	if hasHome {
+		jobContext["home"] = map[string]interface{}{
+			"address":   user.HomeAddress,
+			"latitude":  *user.HomeLatitude,
+			"longitude": *user.HomeLongitude,
+		}
+	}
+	if profile != nil {
+		jobContext["travel_profile"] = profile
+	}
+	data["context"] = jobContext
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job input: %w", err)
+	}
+	enriched := string(encoded)
+	return &enriched, nil
+}
@@ -0,0 +1,74 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/meetingload"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// LimitMeetingLoad sets the thresholds a day's meetings are flagged at; without it the
+// meetingload defaults apply
+func (r *Resolver) LimitMeetingLoad(limits meetingload.Limits) {
+	r.meetingLoadLimits = limits
+}
+
+// MeetingLoad analyses a user's meetings on date (YYYY-MM-DD, in their timezone):
+// back-to-back runs without a break, a missing lunch break and too many meeting hours
+func (r *Resolver) MeetingLoad(ctx context.Context, userID, date string) (*models.MeetingLoad, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q: expected YYYY-MM-DD", date)
+	}
+	location, err := r.userLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := r.markerEvents(ctx, userID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching calendar events: %w", err)
+	}
+	return meetingload.Analyze(events, day, location, r.meetingLoadLimits), nil
+}
+
+// withMeetingLoad adds the warnings about targetDate's meetings to the job's input data
+// as context.meeting_load, for the planner to list in each recommendation's trade-offs.
+// Days without warnings add nothing; like preferences it is best effort.
+func (r *Resolver) withMeetingLoad(ctx context.Context, userID, targetDate string, inputData *string) *string {
+	if len(targetDate) > 10 {
+		targetDate = targetDate[:10]
+	}
+	load, err := r.MeetingLoad(ctx, userID, targetDate)
+	if err != nil {
+		log.Printf("Failed to analyse the meeting load of user %s on %s: %v", userID, targetDate, err)
+		return inputData
+	}
+	if len(load.Warnings) == 0 {
+		return inputData
+	}
+
+	data := map[string]interface{}{}
+	if inputData != nil && strings.TrimSpace(*inputData) != "" {
+		if err := json.Unmarshal([]byte(*inputData), &data); err != nil {
+			return inputData
+		}
+	}
+	jobContext, ok := data["context"].(map[string]interface{})
+	if !ok {
+		jobContext = map[string]interface{}{}
+	}
+	jobContext["meeting_load"] = load
+	data["context"] = jobContext
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return inputData
+	}
+	enriched := string(encoded)
+	return &enriched
+}
@@ -0,0 +1,68 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/meetingload"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/google/uuid"
+)
+
+func TestMeetingLoad(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	repos.Users.(*repository.MemoryUserRepository).SetPreferredTimezone(user.ID, "America/New_York")
+
+	// 10:00-15:00 New York time on Monday 2 March is 15:00-20:00 UTC
+	start := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		event := &models.CalendarEvent{ID: uuid.New().String(), UserID: user.ID, Summary: "Interview",
+			StartTime: start.Add(time.Duration(i) * time.Hour), EndTime: start.Add(time.Duration(i+1) * time.Hour)}
+		if err := repos.Events.Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := r.MeetingLoad(ctx, user.ID, "Monday"); err == nil {
+		t.Error("invalid date accepted")
+	}
+	load, err := r.MeetingLoad(ctx, user.ID, "2026-03-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if load.Meetings != 5 || load.MeetingMinutes != 300 || len(load.Warnings) != 2 {
+		t.Errorf("load = %+v, want 5 meetings over 5 hours with back-to-back and lunch warnings", load)
+	}
+
+	// The configured limits apply, and jobs carry the warnings for the planner
+	r.LimitMeetingLoad(meetingload.Limits{MaxMeetingHours: 4})
+	job, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input struct {
+		Context struct {
+			MeetingLoad *models.MeetingLoad `json:"meeting_load"`
+		} `json:"context"`
+	}
+	if err := json.Unmarshal([]byte(*job.InputData), &input); err != nil {
+		t.Fatal(err)
+	}
+	if got := input.Context.MeetingLoad; got == nil || len(got.Warnings) != 3 {
+		t.Errorf("context.meeting_load = %+v, want three warnings", got)
+	}
+
+	job, err = r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-03"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.Context.MeetingLoad = nil
+	if job.InputData != nil && json.Unmarshal([]byte(*job.InputData), &input) == nil && input.Context.MeetingLoad != nil {
+		t.Errorf("a day without meetings has load %+v", input.Context.MeetingLoad)
+	}
+}
@@ -0,0 +1,85 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// NotificationSettingsInput changes a user's notification opt-ins; nil fields are kept
+type NotificationSettingsInput struct {
+	WeeklyDigest     *bool `json:"weeklyDigest"`
+	CommuteReminders *bool `json:"commuteReminders"`
+	// ReminderLeadMinutes is how long before each departure reminders are sent
+	ReminderLeadMinutes *int                     `json:"reminderLeadMinutes"`
+	ReminderChannels    []models.ReminderChannel `json:"reminderChannels"`
+}
+
+// NotificationSettings returns the notifications a user opted into; none until they
+// save settings
+func (r *Resolver) NotificationSettings(ctx context.Context, userID string) (*models.NotificationSettings, error) {
+	if _, err := r.users.Get(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	settings, err := r.notifications.Get(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &models.NotificationSettings{
+			UserID:              userID,
+			ReminderLeadMinutes: defaultReminderLeadMinutes,
+			ReminderChannels:    []models.ReminderChannel{models.ReminderChannelEmail},
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching notification settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SetNotificationSettings opts a user into or out of notifications. Opting out of commute
+// reminders cancels those already scheduled; changing when or how they are sent applies
+// to plans accepted afterwards.
+func (r *Resolver) SetNotificationSettings(ctx context.Context, userID string, input NotificationSettingsInput) (*models.NotificationSettings, error) {
+	settings, err := r.NotificationSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if input.WeeklyDigest != nil {
+		settings.WeeklyDigest = *input.WeeklyDigest
+	}
+	if input.CommuteReminders != nil {
+		settings.CommuteReminders = *input.CommuteReminders
+	}
+	if input.ReminderLeadMinutes != nil {
+		if *input.ReminderLeadMinutes < 0 || *input.ReminderLeadMinutes > maxReminderLeadMinutes {
+			return nil, fmt.Errorf("reminderLeadMinutes must be between 0 and %d", maxReminderLeadMinutes)
+		}
+		settings.ReminderLeadMinutes = *input.ReminderLeadMinutes
+	}
+	if input.ReminderChannels != nil {
+		if len(input.ReminderChannels) == 0 {
+			return nil, fmt.Errorf("reminderChannels must include at least one channel")
+		}
+		for _, channel := range input.ReminderChannels {
+			if !channel.IsValid() {
+				return nil, fmt.Errorf("unknown reminder channel %q", channel)
+			}
+		}
+		settings.ReminderChannels = input.ReminderChannels
+	}
+	if err := r.notifications.Put(ctx, settings); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error saving notification settings: %w", err)
+	}
+	if !settings.CommuteReminders {
+		r.cancelCommuteReminders(ctx, userID)
+	}
+	return r.notifications.Get(ctx, userID)
+}
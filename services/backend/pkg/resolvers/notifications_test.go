@@ -0,0 +1,40 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNotificationSettings(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+
+	settings, err := r.NotificationSettings(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.WeeklyDigest || settings.UpdatedAt != nil {
+		t.Errorf("default settings = %+v, want no notifications", settings)
+	}
+
+	on := true
+	if settings, err = r.SetNotificationSettings(ctx, user.ID, NotificationSettingsInput{WeeklyDigest: &on}); err != nil {
+		t.Fatal(err)
+	}
+	if !settings.WeeklyDigest || settings.UpdatedAt == nil {
+		t.Errorf("settings = %+v, want the weekly digest on", settings)
+	}
+	subscribers, err := repos.Notifications.ListDigestSubscribers(ctx)
+	if err != nil || len(subscribers) != 1 || subscribers[0].UserID != user.ID {
+		t.Errorf("subscribers = %+v, %v", subscribers, err)
+	}
+
+	// Omitted fields are kept
+	if settings, err = r.SetNotificationSettings(ctx, user.ID, NotificationSettingsInput{}); err != nil || !settings.WeeklyDigest {
+		t.Errorf("settings = %+v, %v, want the weekly digest still on", settings, err)
+	}
+	if _, err := r.SetNotificationSettings(ctx, "no-such-user", NotificationSettingsInput{WeeklyDigest: &on}); err == nil {
+		t.Error("settings saved for an unknown user")
+	}
+}
@@ -0,0 +1,175 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// OfficeInput creates an office or, with nil fields left unchanged, updates one
+type OfficeInput struct {
+	Name      *string  `json:"name"`
+	Address   *string  `json:"address"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Amenities []string `json:"amenities"`
+	Capacity  *int     `json:"capacity"`
+	// ParkingCost and CongestionCharge are per day
+	ParkingCost      *float64 `json:"parkingCost"`
+	CongestionCharge *float64 `json:"congestionCharge"`
+}
+
+// validate checks the fields that are set; creating additionally requires a name
+func (input OfficeInput) validate(creating bool) error {
+	if (creating && input.Name == nil) || (input.Name != nil && strings.TrimSpace(*input.Name) == "") {
+		return fmt.Errorf("office name is required")
+	}
+	if input.Latitude != nil && (*input.Latitude < -90 || *input.Latitude > 90) {
+		return fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if input.Longitude != nil && (*input.Longitude < -180 || *input.Longitude > 180) {
+		return fmt.Errorf("longitude must be between -180 and 180")
+	}
+	if input.Capacity != nil && *input.Capacity < 0 {
+		return fmt.Errorf("capacity can't be negative")
+	}
+	if (input.ParkingCost != nil && *input.ParkingCost < 0) || (input.CongestionCharge != nil && *input.CongestionCharge < 0) {
+		return fmt.Errorf("costs can't be negative")
+	}
+	for _, amenity := range input.Amenities {
+		if strings.TrimSpace(amenity) == "" {
+			return fmt.Errorf("amenities can't be empty")
+		}
+	}
+	return nil
+}
+
+// Offices lists the tenant's offices by name
+func (r *Resolver) Offices(ctx context.Context) ([]*models.Office, error) {
+	offices, err := r.offices.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching offices: %w", err)
+	}
+	return offices, nil
+}
+
+// CreateOffice adds an office to the tenant. Names are unique within a tenant. An address
+// without coordinates is geocoded when a geocoder is configured.
+func (r *Resolver) CreateOffice(ctx context.Context, input OfficeInput) (*models.Office, error) {
+	if err := input.validate(true); err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(*input.Name)
+	if err := r.checkOfficeName(ctx, "", name); err != nil {
+		return nil, err
+	}
+
+	office := &models.Office{
+		Name:             name,
+		Address:          input.Address,
+		Latitude:         input.Latitude,
+		Longitude:        input.Longitude,
+		Amenities:        input.Amenities,
+		Capacity:         input.Capacity,
+		ParkingCost:      input.ParkingCost,
+		CongestionCharge: input.CongestionCharge,
+	}
+	if office.Amenities == nil {
+		office.Amenities = []string{}
+	}
+	if office.Address != nil && office.Latitude == nil && office.Longitude == nil {
+		if point := r.geocodeAddress(ctx, *office.Address); point != nil {
+			office.Latitude, office.Longitude = &point.Lat, &point.Lng
+		}
+	}
+	if err := r.offices.Create(ctx, office); err != nil {
+		return nil, fmt.Errorf("error creating office: %w", err)
+	}
+	return office, nil
+}
+
+// UpdateOffice changes the fields set in input. A new address without coordinates is
+// geocoded like in CreateOffice.
+func (r *Resolver) UpdateOffice(ctx context.Context, id string, input OfficeInput) (*models.Office, error) {
+	if err := input.validate(false); err != nil {
+		return nil, err
+	}
+	update := repository.OfficeUpdate{
+		Address:          input.Address,
+		Latitude:         input.Latitude,
+		Longitude:        input.Longitude,
+		Amenities:        input.Amenities,
+		Capacity:         input.Capacity,
+		ParkingCost:      input.ParkingCost,
+		CongestionCharge: input.CongestionCharge,
+	}
+	if input.Address != nil && input.Latitude == nil && input.Longitude == nil {
+		if point := r.geocodeAddress(ctx, *input.Address); point != nil {
+			update.Latitude, update.Longitude = &point.Lat, &point.Lng
+		}
+	}
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if err := r.checkOfficeName(ctx, id, name); err != nil {
+			return nil, err
+		}
+		update.Name = &name
+	}
+
+	office, err := r.offices.Update(ctx, id, update)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("office not found")
+		}
+		return nil, fmt.Errorf("error updating office: %w", err)
+	}
+	return office, nil
+}
+
+// DeleteOffice removes an office. Users who defaulted to it go back to having no default.
+func (r *Resolver) DeleteOffice(ctx context.Context, id string) (bool, error) {
+	deleted, err := r.offices.Delete(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting office: %w", err)
+	}
+	return deleted, nil
+}
+
+// SetDefaultOffice sets the office a user normally commutes to; nil clears it
+func (r *Resolver) SetDefaultOffice(ctx context.Context, userID string, officeID *string) (*models.User, error) {
+	if officeID != nil {
+		if _, err := r.offices.Get(ctx, *officeID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, fmt.Errorf("office not found")
+			}
+			return nil, fmt.Errorf("error fetching office: %w", err)
+		}
+	}
+
+	user, err := r.users.SetDefaultOffice(ctx, userID, officeID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	return user, nil
+}
+
+// checkOfficeName rejects a name already used by another of the tenant's offices
+func (r *Resolver) checkOfficeName(ctx context.Context, id, name string) error {
+	offices, err := r.offices.List(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching offices: %w", err)
+	}
+	for _, office := range offices {
+		if office.ID != id && strings.EqualFold(office.Name, name) {
+			return fmt.Errorf("an office named %q already exists", office.Name)
+		}
+	}
+	return nil
+}
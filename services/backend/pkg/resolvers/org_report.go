@@ -0,0 +1,181 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/orgreport"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+// maxOrgReportDays bounds the range of one org report
+const maxOrgReportDays = 366
+
+// AnonymizeOrgReports sets the fewest users an org report figure is drawn from; without
+// it orgreport.DefaultMinGroupSize applies
+func (r *Resolver) AnonymizeOrgReports(minGroupSize int) {
+	r.orgReportMinGroup = minGroupSize
+}
+
+// authorizeOrgAdmin checks that viewer may administer tenantID: the admin token, or a
+// signed-in user who is an org admin of that tenant as stored now. The schema's @auth
+// directive checks the role too; this keeps the org operations closed should a request
+// get past it.
+func (r *Resolver) authorizeOrgAdmin(ctx context.Context, viewer authz.Viewer, tenantID string) error {
+	if viewer.Admin {
+		return nil
+	}
+	if viewer.UserID == "" {
+		return authz.ErrUnauthenticated
+	}
+	user, err := r.users.Get(tenant.WithID(ctx, tenantID), viewer.UserID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && (!user.IsOrgAdmin || user.TenantID != tenantID)) {
+		return authz.ErrOrgForbidden
+	} else if err != nil {
+		return fmt.Errorf("error fetching user: %w", err)
+	}
+	return nil
+}
+
+// OrgReport aggregates the accepted plans of tenantID's users from from to to (YYYY-MM-DD,
+// inclusive): office attendance, commute times and compliance with the tenant's
+// office-day policy. Users who opted out are left out, and no user ID leaves the resolver.
+// Only the tenant's org admins and the admin token may read it.
+func (r *Resolver) OrgReport(ctx context.Context, viewer authz.Viewer, tenantID, from, to string) (*models.OrgReport, error) {
+	if err := r.authorizeOrgAdmin(ctx, viewer, tenantID); err != nil {
+		return nil, err
+	}
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from %q: expected YYYY-MM-DD", from)
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to %q: expected YYYY-MM-DD", to)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("to must not be before from")
+	}
+	if end.Sub(start) >= maxOrgReportDays*24*time.Hour {
+		return nil, fmt.Errorf("org reports cover at most %d days", maxOrgReportDays)
+	}
+	org, err := r.tenants.Get(ctx, tenantID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("tenant not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("error fetching tenant: %w", err)
+	}
+
+	ctx = tenant.WithID(ctx, tenantID)
+	users, err := r.users.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching users: %w", err)
+	}
+	var plans []orgreport.Plan
+	optedOut, participant := 0, 0
+	after := end.AddDate(0, 0, 1)
+	for _, user := range users {
+		if user.TenantID != tenantID {
+			continue
+		}
+		if user.OrgReportingOptOut {
+			optedOut++
+			continue
+		}
+		history, err := r.commuteHistory(ctx, user.ID, repository.DateRange{From: &start, To: &after})
+		if err != nil {
+			return nil, err
+		}
+		for _, day := range history {
+			date, err := time.Parse("2006-01-02", day.date[:10])
+			if err != nil {
+				continue
+			}
+			rec := day.accepted
+			plans = append(plans, orgreport.Plan{
+				Participant:    participant,
+				Date:           date,
+				InOffice:       rec.CommuteStart != nil,
+				OfficeID:       rec.OfficeID,
+				CommuteMinutes: minutesBetween(rec.CommuteStart, rec.OfficeArrival) + minutesBetween(rec.OfficeDeparture, rec.CommuteEnd),
+			})
+		}
+		participant++
+	}
+
+	report := orgreport.Build(plans, orgreport.Options{
+		From:              start,
+		To:                end,
+		OfficeDaysPerWeek: org.OfficeDaysPerWeek,
+		MinGroupSize:      r.orgReportMinGroup,
+	})
+	report.TenantID, report.OptedOut = tenantID, optedOut
+	if len(report.Offices) > 0 {
+		offices, err := r.offices.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching offices: %w", err)
+		}
+		names := map[string]string{}
+		for _, office := range offices {
+			names[office.ID] = office.Name
+		}
+		for i := range report.Offices {
+			report.Offices[i].Name = names[report.Offices[i].OfficeID]
+		}
+	}
+	return report, nil
+}
+
+// SetOfficeDayPolicy sets the office days a week tenantID expects of its users, which
+// org reports measure compliance with; nil clears it. Only the tenant's org admins and
+// the admin token may set it.
+func (r *Resolver) SetOfficeDayPolicy(ctx context.Context, viewer authz.Viewer, tenantID string, officeDaysPerWeek *int) (*models.Tenant, error) {
+	if err := r.authorizeOrgAdmin(ctx, viewer, tenantID); err != nil {
+		return nil, err
+	}
+	if officeDaysPerWeek != nil && (*officeDaysPerWeek < 1 || *officeDaysPerWeek > 7) {
+		return nil, fmt.Errorf("officeDaysPerWeek must be between 1 and 7")
+	}
+	org, err := r.tenants.SetOfficeDayPolicy(ctx, tenantID, officeDaysPerWeek)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("tenant not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("error saving office-day policy: %w", err)
+	}
+	return org, nil
+}
+
+// SetOrgAdmin grants or revokes the org admin role of a user of tenantID. Only the
+// tenant's org admins and the admin token may.
+func (r *Resolver) SetOrgAdmin(ctx context.Context, viewer authz.Viewer, tenantID, userID string, orgAdmin bool) (*models.User, error) {
+	if err := r.authorizeOrgAdmin(ctx, viewer, tenantID); err != nil {
+		return nil, err
+	}
+	ctx = tenant.WithID(ctx, tenantID)
+	if user, err := r.users.Get(ctx, userID); errors.Is(err, repository.ErrNotFound) || (err == nil && user.TenantID != tenantID) {
+		return nil, fmt.Errorf("user not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	user, err := r.users.SetOrgAdmin(ctx, userID, orgAdmin)
+	if err != nil {
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	return user, nil
+}
+
+// SetOrgReportingOptOut leaves the user's plans out of, or back in, their org's reports
+func (r *Resolver) SetOrgReportingOptOut(ctx context.Context, userID string, optOut bool) (*models.User, error) {
+	user, err := r.users.SetOrgReportingOptOut(ctx, userID, optOut)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("user not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	return user, nil
+}
@@ -0,0 +1,138 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+func TestOrgReport(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	r.AnonymizeOrgReports(2)
+	admin := authz.Viewer{Admin: true}
+	completed, inProgress := string(models.JobStatusCompleted), string(models.JobStatusInProgress)
+	hq := &models.Office{ID: "hq", Name: "HQ"}
+	if err := repos.Offices.Create(ctx, hq); err != nil {
+		t.Fatal(err)
+	}
+
+	// accept completes a job for date and accepts an office day at HQ (an hour each way)
+	// or a remote one
+	accept := func(userID, date string, inOffice bool) {
+		t.Helper()
+		job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: userID, TargetDate: date})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, status := range []*string{&inProgress, &completed} {
+			if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: status}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		rec := &models.CommuteRecommendation{JobID: job.ID, OptionRank: 1, OptionType: models.CommuteOptionFullRemoteRecommended}
+		if inOffice {
+			day, _ := time.Parse("2006-01-02", date)
+			start, arrival := day.Add(8*time.Hour), day.Add(9*time.Hour)
+			departure, end := day.Add(17*time.Hour), day.Add(18*time.Hour)
+			rec.OptionType, rec.OfficeID = models.CommuteOptionFullDayOffice, &hq.ID
+			rec.CommuteStart, rec.OfficeArrival, rec.OfficeDeparture, rec.CommuteEnd = &start, &arrival, &departure, &end
+		}
+		if err := repos.Recommendations.Create(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.AcceptCommuteRecommendation(ctx, userID, rec.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var users []*models.User
+	for i := 0; i < 3; i++ {
+		user := createTestUser(t, repos, fmt.Sprintf("user%d@example.com", i))
+		users = append(users, user)
+		accept(user.ID, "2026-03-02", true)
+		accept(user.ID, "2026-03-03", i == 0)
+	}
+	// A planned but unaccepted day isn't counted
+	if _, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: users[1].ID, TargetDate: "2026-03-04"}); err != nil {
+		t.Fatal(err)
+	}
+
+	days := 2
+	if _, err := r.SetOfficeDayPolicy(ctx, admin, tenant.DefaultID, &days); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SetOrgReportingOptOut(ctx, users[2].ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := r.OrgReport(ctx, admin, tenant.DefaultID, "2026-03-02", "2026-03-08")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Suppressed || report.Participants != 2 || report.OptedOut != 1 {
+		t.Fatalf("report = %+v, want two participants and one opted out", report)
+	}
+	if report.PlannedDays != 4 || report.OfficeDays != 3 || *report.AttendanceRate != 0.75 || *report.AverageCommuteMinutes != 120 {
+		t.Errorf("report = %+v", report)
+	}
+	if report.Compliance == nil || *report.Compliance != 0.5 {
+		t.Errorf("compliance = %v, want one of two users", report.Compliance)
+	}
+	if len(report.Offices) != 1 || report.Offices[0].Name != "HQ" || report.Offices[0].OfficeDays != 3 {
+		t.Errorf("offices = %+v", report.Offices)
+	}
+
+	// With another opt-out there are too few users left to report on
+	if _, err := r.SetOrgReportingOptOut(ctx, users[1].ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if report, err := r.OrgReport(ctx, admin, tenant.DefaultID, "2026-03-02", "2026-03-08"); err != nil || !report.Suppressed || report.OfficeDays != 0 {
+		t.Errorf("report = %+v, %v, want it suppressed", report, err)
+	}
+
+	for _, tt := range []struct{ tenantID, from, to string }{
+		{tenant.DefaultID, "2026-03-08", "2026-03-02"},
+		{tenant.DefaultID, "2026-01-01", "2027-01-02"},
+		{tenant.DefaultID, "March", "2026-03-02"},
+		{"globex", "2026-03-02", "2026-03-08"},
+	} {
+		if _, err := r.OrgReport(ctx, admin, tt.tenantID, tt.from, tt.to); err == nil {
+			t.Errorf("OrgReport(%s, %s, %s): no error", tt.tenantID, tt.from, tt.to)
+		}
+	}
+	bad := 8
+	if _, err := r.SetOfficeDayPolicy(ctx, admin, tenant.DefaultID, &bad); err == nil {
+		t.Error("accepted an 8-day office week")
+	}
+
+	// Org admins are only made within their tenant
+	if user, err := r.SetOrgAdmin(ctx, admin, tenant.DefaultID, users[0].ID, true); err != nil || !user.IsOrgAdmin {
+		t.Errorf("org admin = %+v, %v", user, err)
+	}
+	if _, err := r.SetOrgAdmin(ctx, admin, "globex", users[1].ID, true); err == nil {
+		t.Error("made another tenant's user an org admin")
+	}
+
+	// The resolvers check the viewer themselves, against the org admins as stored
+	orgAdmin := authz.Viewer{UserID: users[0].ID, OrgAdmin: true}
+	if _, err := r.OrgReport(ctx, orgAdmin, tenant.DefaultID, "2026-03-02", "2026-03-08"); err != nil {
+		t.Errorf("org admin's report: %v", err)
+	}
+	if _, err := r.OrgReport(ctx, orgAdmin, "globex", "2026-03-02", "2026-03-08"); !errors.Is(err, authz.ErrOrgForbidden) {
+		t.Errorf("another tenant's report: err = %v, want %v", err, authz.ErrOrgForbidden)
+	}
+	claimed := authz.Viewer{UserID: users[1].ID, OrgAdmin: true}
+	if _, err := r.SetOrgAdmin(ctx, claimed, tenant.DefaultID, users[1].ID, true); !errors.Is(err, authz.ErrOrgForbidden) {
+		t.Errorf("user made themselves org admin: err = %v", err)
+	}
+	if _, err := r.SetOfficeDayPolicy(ctx, authz.Viewer{}, tenant.DefaultID, &days); !errors.Is(err, authz.ErrUnauthenticated) {
+		t.Errorf("anonymous office-day policy: err = %v, want %v", err, authz.ErrUnauthenticated)
+	}
+}
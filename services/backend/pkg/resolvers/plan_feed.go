@@ -0,0 +1,37 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// PlanFeed streams the updates of users' upcoming plans; implemented by planfeed.Feed
+type PlanFeed interface {
+	Subscribe(userID string) (updates <-chan *models.PlanUpdate, cancel func())
+}
+
+// StreamPlansWith serves the myPlans subscription from feed
+func (r *Resolver) StreamPlansWith(feed PlanFeed) {
+	r.planFeed = feed
+}
+
+// MyPlans subscribes to changes to the user's upcoming jobs and plans: jobs created,
+// recommended or failed, plans accepted, calendar conflicts and reminders. The caller must
+// cancel the subscription when done with it.
+func (r *Resolver) MyPlans(ctx context.Context, userID string) (<-chan *models.PlanUpdate, func(), error) {
+	if r.planFeed == nil {
+		return nil, nil, errors.New("plan updates are not available")
+	}
+	if _, err := r.users.Get(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, fmt.Errorf("user not found")
+		}
+		return nil, nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	updates, cancel := r.planFeed.Subscribe(userID)
+	return updates, cancel, nil
+}
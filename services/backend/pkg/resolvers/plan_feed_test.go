@@ -0,0 +1,39 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/planfeed"
+	"github.com/commute-planner/backend/pkg/webhooks"
+)
+
+func TestMyPlans(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	if _, _, err := r.MyPlans(ctx, user.ID); err == nil {
+		t.Error("subscribed without a plan feed")
+	}
+
+	feed := planfeed.New(nil)
+	r.StreamPlansWith(feed)
+	if _, _, err := r.MyPlans(ctx, "nobody"); err == nil {
+		t.Error("subscribed to an unknown user's plans")
+	}
+	updates, cancel, err := r.MyPlans(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	job := &models.Job{ID: "j1", UserID: user.ID, TargetDate: "2099-03-02"}
+	if err := feed.Publish(ctx, user.ID, webhooks.EventJobCompleted, job); err != nil {
+		t.Fatal(err)
+	}
+	update := <-updates
+	if update.Kind != models.PlanUpdateRecommendationsReady || update.JobID == nil || *update.JobID != "j1" {
+		t.Errorf("update = %+v", update)
+	}
+}
@@ -0,0 +1,193 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/preferences"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// preferenceHistoryWeeks is how far back preferences are learned from, so habits that
+// changed are unlearned within a quarter
+const preferenceHistoryWeeks = 12
+
+// PreferenceFeedbackInput reviews a learned preference. Confirming an ARRIVE_BEFORE or
+// TRAVEL_MODE preference may correct its time or mode.
+type PreferenceFeedbackInput struct {
+	Status string             `json:"status"`
+	Time   *string            `json:"time"`
+	Mode   *models.TravelMode `json:"mode"`
+}
+
+// LearnedPreferences derives a user's preferences from the recommendations they accepted
+// over the last 12 weeks, with their feedback applied
+func (r *Resolver) LearnedPreferences(ctx context.Context, userID string) ([]models.LearnedPreference, error) {
+	location, err := r.userLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(location)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -7*preferenceHistoryWeeks)
+	history, err := r.commuteHistory(ctx, userID, repository.DateRange{From: &from, To: &to})
+	if err != nil {
+		return nil, err
+	}
+
+	days := make([]preferences.Day, 0, len(history))
+	for _, day := range history {
+		date, err := time.Parse("2006-01-02", day.date)
+		if err != nil {
+			continue
+		}
+		learned := preferences.Day{Date: date, InOffice: day.accepted.CommuteStart != nil, Mode: day.accepted.TravelMode}
+		if learned.InOffice && day.accepted.OfficeArrival != nil {
+			arrival := day.accepted.OfficeArrival.In(location)
+			learned.Arrival = &arrival
+		}
+		days = append(days, learned)
+	}
+
+	feedback, err := r.preferences.List(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching preference feedback: %w", err)
+	}
+	merged := preferences.Apply(preferences.Learn(days), feedback)
+	if merged == nil {
+		merged = []models.LearnedPreference{}
+	}
+	return merged, nil
+}
+
+// SetPreferenceFeedback confirms or rejects one of a user's preferences and returns their
+// preferences with it applied. Confirming stores the learned time or mode unless the
+// input corrects it, so the preference holds even if the history stops showing it.
+func (r *Resolver) SetPreferenceFeedback(ctx context.Context, userID, key string, input PreferenceFeedbackInput) ([]models.LearnedPreference, error) {
+	preferenceType, _, err := preferences.ParseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	status := models.PreferenceStatus(strings.ToUpper(input.Status))
+	if status != models.PreferenceConfirmed && status != models.PreferenceRejected {
+		return nil, fmt.Errorf("invalid preference status %q: expected CONFIRMED or REJECTED", input.Status)
+	}
+	if status == models.PreferenceRejected && (input.Time != nil || input.Mode != nil) {
+		return nil, fmt.Errorf("only confirmed preferences take a time or mode")
+	}
+	if input.Time != nil {
+		if preferenceType != models.PreferenceArriveBefore {
+			return nil, fmt.Errorf("only %s preferences take a time", models.PreferenceArriveBefore)
+		}
+		if _, err := constraintTime(input.Time, "time"); err != nil {
+			return nil, err
+		}
+	}
+	if input.Mode != nil {
+		if preferenceType != models.PreferenceTravelMode {
+			return nil, fmt.Errorf("only %s preferences take a mode", models.PreferenceTravelMode)
+		}
+		if !isTravelMode(*input.Mode) {
+			return nil, fmt.Errorf("unknown travel mode %q", *input.Mode)
+		}
+	}
+
+	current, err := r.LearnedPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	fb := &models.PreferenceFeedback{UserID: userID, Key: key, Status: status, Time: input.Time, Mode: input.Mode, UpdatedAt: time.Now().UTC()}
+	if status == models.PreferenceConfirmed {
+		for _, preference := range current {
+			if preference.Key != key {
+				continue
+			}
+			if fb.Time == nil {
+				fb.Time = preference.Time
+			}
+			if fb.Mode == nil {
+				fb.Mode = preference.Mode
+			}
+		}
+		if preferenceType == models.PreferenceArriveBefore && fb.Time == nil {
+			return nil, fmt.Errorf("no arrival time has been learned yet: confirm with a time")
+		}
+		if preferenceType == models.PreferenceTravelMode && fb.Mode == nil {
+			return nil, fmt.Errorf("no travel mode has been learned yet: confirm with a mode")
+		}
+		// A confirmed preference replaces the confirmation of its opposite
+		if contradiction := preferences.Contradiction(key); contradiction != "" {
+			if _, err := r.preferences.Delete(ctx, userID, contradiction); err != nil {
+				return nil, fmt.Errorf("error saving preference feedback: %w", err)
+			}
+		}
+	}
+	if err := r.preferences.Put(ctx, fb); err != nil {
+		return nil, fmt.Errorf("error saving preference feedback: %w", err)
+	}
+	return r.LearnedPreferences(ctx, userID)
+}
+
+// ResetPreferenceFeedback forgets a user's feedback on a preference, so it is learned
+// from their history again
+func (r *Resolver) ResetPreferenceFeedback(ctx context.Context, userID, key string) (bool, error) {
+	if _, _, err := preferences.ParseKey(key); err != nil {
+		return false, err
+	}
+	if _, err := r.userLocation(ctx, userID); err != nil {
+		return false, err
+	}
+	deleted, err := r.preferences.Delete(ctx, userID, key)
+	if err != nil {
+		return false, fmt.Errorf("error deleting preference feedback: %w", err)
+	}
+	return deleted, nil
+}
+
+// withPreferences adds the user's preferences that apply on targetDate to the job's input
+// data as context.preferences, for the planner to follow as soft constraints. Preferences
+// are best effort: when they can't be learned, or the input data isn't a JSON object, it
+// is returned unchanged.
+func (r *Resolver) withPreferences(ctx context.Context, userID, targetDate string, inputData *string) *string {
+	learned, err := r.LearnedPreferences(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to learn preferences of user %s: %v", userID, err)
+		return inputData
+	}
+	date, dateErr := time.Parse("2006-01-02", targetDate)
+	var applicable []models.LearnedPreference
+	for _, preference := range preferences.Active(learned) {
+		if weekday, ok := preferences.Weekday(preference); ok && (dateErr != nil || weekday != date.Weekday()) {
+			continue
+		}
+		applicable = append(applicable, preference)
+	}
+	if len(applicable) == 0 {
+		return inputData
+	}
+
+	data := map[string]interface{}{}
+	if inputData != nil && strings.TrimSpace(*inputData) != "" {
+		if err := json.Unmarshal([]byte(*inputData), &data); err != nil {
+			return inputData
+		}
+	}
+	jobContext, ok := data["context"].(map[string]interface{})
+	if !ok {
+		jobContext = map[string]interface{}{}
+	}
+	jobContext["preferences"] = applicable
+	data["context"] = jobContext
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return inputData
+	}
+	enriched := string(encoded)
+	return &enriched
+}
@@ -0,0 +1,130 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestLearnedPreferences(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+
+	// Six weeks of working from home on Fridays and biking in by 8:50 otherwise
+	bike := models.TravelModeBike
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 1; i <= 42; i++ {
+		date := today.AddDate(0, 0, -i)
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+		job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: date.Format("2006-01-02")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := &models.CommuteRecommendation{JobID: job.ID, OptionRank: 1, OptionType: models.CommuteOptionFullRemoteRecommended}
+		if date.Weekday() != time.Friday {
+			start, arrival := date.Add(8*time.Hour+20*time.Minute), date.Add(8*time.Hour+50*time.Minute)
+			rec = &models.CommuteRecommendation{
+				JobID: job.ID, OptionRank: 1, OptionType: models.CommuteOptionFullDayOffice,
+				CommuteStart: &start, OfficeArrival: &arrival, TravelMode: &bike,
+			}
+		}
+		if err := repos.Recommendations.Create(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.AcceptCommuteRecommendation(ctx, user.ID, rec.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	learned, err := r.LearnedPreferences(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byKey := map[string]models.LearnedPreference{}
+	for _, preference := range learned {
+		byKey[preference.Key] = preference
+	}
+	if friday, ok := byKey["REMOTE_WEEKDAY:FRIDAY"]; !ok || friday.Status != models.PreferenceLearned {
+		t.Errorf("remote Fridays weren't learned: %+v", learned)
+	}
+	if arrive := byKey["ARRIVE_BEFORE"]; arrive.Time == nil || *arrive.Time != "09:00" {
+		t.Errorf("arrival = %v, want 09:00", arrive.Time)
+	}
+	if mode := byKey["TRAVEL_MODE"]; mode.Mode == nil || *mode.Mode != models.TravelModeBike {
+		t.Errorf("mode = %v, want BIKE", mode.Mode)
+	}
+
+	// Corrections and rejections
+	later := "09:30"
+	if _, err := r.SetPreferenceFeedback(ctx, user.ID, "ARRIVE_BEFORE", PreferenceFeedbackInput{Status: "CONFIRMED", Time: &later}); err != nil {
+		t.Fatal(err)
+	}
+	learned, err = r.SetPreferenceFeedback(ctx, user.ID, "TRAVEL_MODE", PreferenceFeedbackInput{Status: "rejected"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, preference := range learned {
+		if preference.Key == "TRAVEL_MODE" && preference.Status != models.PreferenceRejected {
+			t.Errorf("travel mode status = %s, want REJECTED", preference.Status)
+		}
+	}
+	for name, input := range map[string]struct {
+		key   string
+		input PreferenceFeedbackInput
+	}{
+		"unknown key":        {"LUNCH_AT", PreferenceFeedbackInput{Status: "CONFIRMED"}},
+		"unknown status":     {"TRAVEL_MODE", PreferenceFeedbackInput{Status: "MAYBE"}},
+		"time on a mode":     {"TRAVEL_MODE", PreferenceFeedbackInput{Status: "CONFIRMED", Time: &later}},
+		"rejected with time": {"ARRIVE_BEFORE", PreferenceFeedbackInput{Status: "REJECTED", Time: &later}},
+	} {
+		if _, err := r.SetPreferenceFeedback(ctx, user.ID, input.key, input.input); err == nil {
+			t.Errorf("%s: feedback accepted", name)
+		}
+	}
+
+	// A Friday job is planned with the preferences that apply on Fridays
+	friday := today.AddDate(0, 0, 1)
+	for friday.Weekday() != time.Friday {
+		friday = friday.AddDate(0, 0, 1)
+	}
+	job, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: friday.Format("2006-01-02")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input struct {
+		Context struct {
+			Preferences []models.LearnedPreference `json:"preferences"`
+		} `json:"context"`
+	}
+	if job.InputData == nil || json.Unmarshal([]byte(*job.InputData), &input) != nil {
+		t.Fatalf("job input %v has no preferences", job.InputData)
+	}
+	var keys []string
+	for _, preference := range input.Context.Preferences {
+		keys = append(keys, preference.Key)
+		if preference.Key == "ARRIVE_BEFORE" && *preference.Time != "09:30" {
+			t.Errorf("planned arriving by %s, want the corrected 09:30", *preference.Time)
+		}
+	}
+	if len(keys) != 2 || keys[0] != "REMOTE_WEEKDAY:FRIDAY" || keys[1] != "ARRIVE_BEFORE" {
+		t.Errorf("Friday job planned with %v, want REMOTE_WEEKDAY:FRIDAY and ARRIVE_BEFORE", keys)
+	}
+
+	// Resetting learns the preference afresh
+	if reset, err := r.ResetPreferenceFeedback(ctx, user.ID, "TRAVEL_MODE"); err != nil || !reset {
+		t.Fatalf("reset = %v, %v", reset, err)
+	}
+	learned, _ = r.LearnedPreferences(ctx, user.ID)
+	for _, preference := range learned {
+		if preference.Key == "TRAVEL_MODE" && preference.Status != models.PreferenceLearned {
+			t.Errorf("travel mode status after reset = %s, want LEARNED", preference.Status)
+		}
+	}
+}
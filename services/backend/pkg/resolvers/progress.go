@@ -0,0 +1,68 @@
+package resolvers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/progress"
+)
+
+// The completed jobs progress estimates learn step durations from
+const (
+	progressModelJobs   = 200
+	progressModelWindow = 7 * 24 * time.Hour
+	// progressModelTTL is how long a learned model is used before it is learned again
+	progressModelTTL = 10 * time.Minute
+)
+
+// progressModelCache holds the model learned from recently completed jobs
+type progressModelCache struct {
+	mu        sync.Mutex
+	model     *progress.Model
+	learnedAt time.Time
+}
+
+// estimateProgress replaces the progress the worker reported for a running job with one
+// estimated from how long its steps took in recently completed jobs, and sets when the job
+// should complete. Jobs the model can't estimate keep the worker's progress.
+func (r *Resolver) estimateProgress(ctx context.Context, job *models.Job) {
+	if job.Status != models.JobStatusInProgress {
+		return
+	}
+	model, err := r.learnedProgressModel(ctx)
+	if err != nil {
+		log.Printf("Failed to learn job step durations: %v", err)
+		return
+	}
+	events, err := r.jobs.Events(ctx, job.ID)
+	if err != nil {
+		log.Printf("Failed to fetch the history of job %s: %v", job.ID, err)
+		return
+	}
+	if estimate, ok := model.Estimate(events, time.Now()); ok {
+		job.Progress = estimate.Progress
+		job.EstimatedCompletionAt = &estimate.CompletesAt
+	}
+}
+
+// learnedProgressModel returns the model of recently completed jobs, learning it again
+// when it is older than progressModelTTL
+func (r *Resolver) learnedProgressModel(ctx context.Context) (*progress.Model, error) {
+	c := &r.progressModel
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.model != nil && now.Sub(c.learnedAt) < progressModelTTL {
+		return c.model, nil
+	}
+	histories, err := r.jobs.CompletedEvents(ctx, now.Add(-progressModelWindow), progressModelJobs)
+	if err != nil {
+		return nil, err
+	}
+	c.model, c.learnedAt = progress.Learn(histories), now
+	return c.model, nil
+}
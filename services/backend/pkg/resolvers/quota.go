@@ -0,0 +1,145 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Quota error codes, returned at the start of the error message so clients can match them
+const (
+	ErrCodeJobQueueLimit = "JOB_QUEUE_LIMIT_EXCEEDED"
+	ErrCodeDailyJobLimit = "DAILY_JOB_LIMIT_EXCEEDED"
+)
+
+// JobQuotaLimits are the default per-user job quotas. Zero disables a limit.
+type JobQuotaLimits struct {
+	// MaxQueuedJobs caps a user's PENDING and IN_PROGRESS jobs
+	MaxQueuedJobs int `json:"maxQueuedJobs"`
+	// MaxJobsPerDay caps the jobs a user creates in any 24 hours
+	MaxJobsPerDay int `json:"maxJobsPerDay"`
+}
+
+// QuotaExceededError is returned by CreateJob when a user is over a quota
+type QuotaExceededError struct {
+	Code  string
+	Limit int
+}
+
+func (e *QuotaExceededError) Error() string {
+	switch e.Code {
+	case ErrCodeJobQueueLimit:
+		return fmt.Sprintf("%s: you already have the maximum of %d jobs queued or running; wait for one to finish", e.Code, e.Limit)
+	default:
+		return fmt.Sprintf("%s: you can create at most %d jobs per 24 hours", e.Code, e.Limit)
+	}
+}
+
+// checkJobQuota returns a *QuotaExceededError when the user may not create another job.
+// The counts aren't taken under a lock, so concurrent creates can overshoot a limit by
+// a few jobs; the quotas protect worker capacity, not billing.
+func (r *Resolver) checkJobQuota(ctx context.Context, userID string) error {
+	limits := r.quotaLimits
+	override, err := r.quotas.Get(ctx, userID)
+	switch {
+	case err == nil:
+		if override.Exempt {
+			return nil
+		}
+		if override.MaxQueuedJobs != nil {
+			limits.MaxQueuedJobs = *override.MaxQueuedJobs
+		}
+		if override.MaxJobsPerDay != nil {
+			limits.MaxJobsPerDay = *override.MaxJobsPerDay
+		}
+	case !errors.Is(err, repository.ErrNotFound):
+		return fmt.Errorf("error checking job quota: %w", err)
+	}
+
+	if limits.MaxQueuedJobs > 0 {
+		active, err := r.jobs.CountActive(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("error checking job quota: %w", err)
+		}
+		if active >= limits.MaxQueuedJobs {
+			return quotaExceeded(ErrCodeJobQueueLimit, limits.MaxQueuedJobs)
+		}
+	}
+	if limits.MaxJobsPerDay > 0 {
+		created, err := r.jobs.CountCreatedSince(ctx, userID, time.Now().Add(-24*time.Hour))
+		if err != nil {
+			return fmt.Errorf("error checking job quota: %w", err)
+		}
+		if created >= limits.MaxJobsPerDay {
+			return quotaExceeded(ErrCodeDailyJobLimit, limits.MaxJobsPerDay)
+		}
+	}
+	return nil
+}
+
+func quotaExceeded(code string, limit int) error {
+	metrics.JobQuotaRejections.WithLabelValues(code).Inc()
+	return &QuotaExceededError{Code: code, Limit: limit}
+}
+
+// JobQuota returns a user's override, or nil when the defaults apply
+func (r *Resolver) JobQuota(ctx context.Context, userID string) (*models.JobQuota, error) {
+	quota, err := r.quotas.Get(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return quota, err
+}
+
+// DefaultJobQuota returns the limits that apply to users without an override
+func (r *Resolver) DefaultJobQuota() JobQuotaLimits {
+	return r.quotaLimits
+}
+
+// SetJobQuotaInput overrides a user's quotas; nil limits keep the defaults
+type SetJobQuotaInput struct {
+	MaxQueuedJobs *int    `json:"maxQueuedJobs"`
+	MaxJobsPerDay *int    `json:"maxJobsPerDay"`
+	Exempt        bool    `json:"exempt"`
+	Reason        *string `json:"reason"`
+}
+
+// SetJobQuota creates or replaces a user's override
+func (r *Resolver) SetJobQuota(ctx context.Context, userID string, input SetJobQuotaInput) (*models.JobQuota, error) {
+	if (input.MaxQueuedJobs != nil && *input.MaxQueuedJobs < 0) || (input.MaxJobsPerDay != nil && *input.MaxJobsPerDay < 0) {
+		return nil, fmt.Errorf("quota limits can't be negative (0 disables a limit)")
+	}
+	if _, err := r.users.Get(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+
+	quota := &models.JobQuota{
+		UserID:        userID,
+		MaxQueuedJobs: input.MaxQueuedJobs,
+		MaxJobsPerDay: input.MaxJobsPerDay,
+		Exempt:        input.Exempt,
+		Reason:        input.Reason,
+		UpdatedAt:     time.Now(),
+	}
+	if err := r.quotas.Put(ctx, quota); err != nil {
+		return nil, fmt.Errorf("error saving job quota: %w", err)
+	}
+	return quota, nil
+}
+
+// DeleteJobQuota removes a user's override, restoring the defaults
+func (r *Resolver) DeleteJobQuota(ctx context.Context, userID string) (bool, error) {
+	deleted, err := r.quotas.Delete(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("error deleting job quota: %w", err)
+	}
+	return deleted, nil
+}
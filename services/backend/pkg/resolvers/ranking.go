@@ -0,0 +1,64 @@
+package resolvers
+
+import (
+	"context"
+	"log"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/planner"
+)
+
+// RankWith re-ranks each completed job's recommendations with the ranker selector picks
+// for it. Without it the AI service's order is kept.
+func (r *Resolver) RankWith(selector planner.Selector) {
+	r.rankers = &selector
+}
+
+// rankRecommendations re-ranks a job that just completed. It runs before the completion
+// is announced, so clients see the final order; failures are logged and keep the AI
+// service's order.
+func (r *Resolver) rankRecommendations(ctx context.Context, job *models.Job) {
+	if r.rankers == nil {
+		return
+	}
+	tenantID := ""
+	if user, err := r.users.Get(ctx, job.UserID); err == nil {
+		tenantID = user.TenantID
+	}
+	name := r.rankers.Select(tenantID, job.Variant)
+	if name == planner.RankerPlanner {
+		return
+	}
+	ranker, ok := planner.Lookup(name)
+	if !ok {
+		log.Printf("Ranker %s of job %s is not registered", name, job.ID)
+		return
+	}
+
+	options, err := r.recommendations.ListByJob(ctx, job.ID)
+	if err != nil {
+		log.Printf("Failed to load recommendations of job %s for ranking: %v", job.ID, err)
+		return
+	}
+	if len(options) < 2 {
+		return
+	}
+	events, err := r.events.ListByUser(ctx, job.UserID, &job.TargetDate)
+	if err != nil {
+		log.Printf("Failed to load calendar events of job %s for ranking: %v", job.ID, err)
+		return
+	}
+
+	ranked, err := planner.Rank(ctx, ranker, planner.Day{Job: job, Options: options, Events: events})
+	if err != nil {
+		log.Printf("Ranker %s failed on job %s: %v", name, job.ID, err)
+		return
+	}
+	ids := make([]string, len(ranked))
+	for i, option := range ranked {
+		ids[i] = option.ID
+	}
+	if err := r.recommendations.SetRanks(ctx, job.ID, ids); err != nil {
+		log.Printf("Failed to save %s ranking of job %s: %v", name, job.ID, err)
+	}
+}
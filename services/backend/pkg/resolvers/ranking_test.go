@@ -0,0 +1,46 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/planner"
+)
+
+func TestCompletedJobsAreRanked(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	r.RankWith(planner.Selector{Default: planner.RankerCostOptimized})
+	user := createTestUser(t, repos, "ada@example.com")
+
+	job, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The AI service ranks the pricier trip first
+	mode := models.TravelModeTransit
+	for rank, cost := range []float64{9, 3} {
+		options := fmt.Sprintf(`[{"mode":"TRANSIT","cost":%g,"chosen":true}]`, cost)
+		rec := &models.CommuteRecommendation{JobID: job.ID, OptionRank: rank + 1, TravelMode: &mode, ModeOptions: &options}
+		if err := repos.Recommendations.Create(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, status := range []models.JobStatus{models.JobStatusInProgress, models.JobStatusCompleted} {
+		value := string(status)
+		if _, err := r.UpdateJob(ctx, job.ID, UpdateJobInput{Status: &value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recs, err := r.CommuteRecommendations(ctx, authz.Viewer{UserID: user.ID}, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 || recs[0].Cost == nil || recs[0].Cost.Total != 3 || recs[0].OptionRank != 1 {
+		t.Fatalf("first option = %+v, want the 3.00 trip re-ranked first", recs[0])
+	}
+}
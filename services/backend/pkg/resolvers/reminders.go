@@ -0,0 +1,111 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// Bounds of how long before a departure commute reminders are sent
+const (
+	defaultReminderLeadMinutes = 15
+	maxReminderLeadMinutes     = 180
+)
+
+// CommuteReminders returns the user's reminders for departures from now on, soonest first
+func (r *Resolver) CommuteReminders(ctx context.Context, userID string) ([]*models.CommuteReminder, error) {
+	reminders, err := r.reminders.ListUpcoming(ctx, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error fetching commute reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+// CancelCommuteReminder stops one of the user's reminders being sent, reporting whether it
+// was still scheduled
+func (r *Resolver) CancelCommuteReminder(ctx context.Context, userID, id string) (bool, error) {
+	cancelled, err := r.reminders.Cancel(ctx, userID, id)
+	if err != nil {
+		return false, fmt.Errorf("error cancelling commute reminder: %w", err)
+	}
+	return cancelled, nil
+}
+
+// scheduleCommuteReminders replaces the reminders of the day of job with ones for the
+// departures of rec, the option the user just accepted, if they opted into reminders.
+// Accepting a remote option leaves the day without reminders.
+func (r *Resolver) scheduleCommuteReminders(ctx context.Context, job *models.Job, rec *models.CommuteRecommendation) error {
+	settings, err := r.NotificationSettings(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+	if !settings.CommuteReminders || len(job.TargetDate) < 10 {
+		return nil
+	}
+	lead := time.Duration(settings.ReminderLeadMinutes) * time.Minute
+
+	now := time.Now()
+	reminders := []*models.CommuteReminder{}
+	for _, departure := range reminderDepartures(rec) {
+		if !departure.at.After(now) {
+			continue
+		}
+		remindAt := departure.at.Add(-lead)
+		if remindAt.Before(now) {
+			remindAt = now
+		}
+		reminders = append(reminders, &models.CommuteReminder{
+			RecommendationID: rec.ID,
+			Summary:          departure.summary,
+			DepartAt:         departure.at,
+			RemindAt:         remindAt,
+			Channels:         settings.ReminderChannels,
+		})
+	}
+	if err := r.reminders.Replace(ctx, job.UserID, job.TargetDate[:10], reminders); err != nil {
+		return fmt.Errorf("error scheduling commute reminders: %w", err)
+	}
+	return nil
+}
+
+// reminderDeparture is a time a plan has the user leave
+type reminderDeparture struct {
+	at      time.Time
+	summary string
+}
+
+// reminderDepartures returns when a plan has the user leave: the start of each of its
+// travel legs, or without legs its trips to and from the office
+func reminderDepartures(rec *models.CommuteRecommendation) []reminderDeparture {
+	var departures []reminderDeparture
+	for _, leg := range rec.DecodeTravelLegs() {
+		if leg.Depart == nil {
+			continue
+		}
+		summary := "Time to leave"
+		if leg.To != nil && *leg.To != "" {
+			summary = "Leave for " + *leg.To
+		}
+		departures = append(departures, reminderDeparture{at: *leg.Depart, summary: summary})
+	}
+	if len(departures) > 0 {
+		return departures
+	}
+	if rec.CommuteStart != nil {
+		departures = append(departures, reminderDeparture{at: *rec.CommuteStart, summary: "Leave for the office"})
+	}
+	if rec.OfficeDeparture != nil {
+		departures = append(departures, reminderDeparture{at: *rec.OfficeDeparture, summary: "Leave the office for home"})
+	}
+	return departures
+}
+
+// cancelCommuteReminders cancels the user's scheduled reminders after they opt out
+func (r *Resolver) cancelCommuteReminders(ctx context.Context, userID string) {
+	if _, err := r.reminders.CancelScheduled(ctx, userID); err != nil {
+		log.Printf("Failed to cancel commute reminders of user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,120 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestCommuteReminders(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	completed, inProgress := string(models.JobStatusCompleted), string(models.JobStatusInProgress)
+
+	tomorrow := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: tomorrow.Format("2006-01-02")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range []*string{&inProgress, &completed} {
+		if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	at := func(hour int) *time.Time {
+		t := tomorrow.Add(time.Duration(hour) * time.Hour)
+		return &t
+	}
+	option := func(rank int, rec *models.CommuteRecommendation) *models.CommuteRecommendation {
+		t.Helper()
+		rec.JobID, rec.OptionRank = job.ID, rank
+		if err := repos.Recommendations.Create(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+	early := option(1, &models.CommuteRecommendation{OptionType: models.CommuteOptionFullDayOffice, CommuteStart: at(8), OfficeDeparture: at(16)})
+	late := option(2, &models.CommuteRecommendation{OptionType: models.CommuteOptionFullDayOffice, CommuteStart: at(9), OfficeDeparture: at(17)})
+	remote := option(3, &models.CommuteRecommendation{OptionType: models.CommuteOptionFullRemoteRecommended})
+
+	scheduled := func() []*models.CommuteReminder {
+		t.Helper()
+		reminders, err := r.CommuteReminders(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var active []*models.CommuteReminder
+		for _, reminder := range reminders {
+			if reminder.Status == models.CommuteReminderScheduled {
+				active = append(active, reminder)
+			}
+		}
+		return active
+	}
+
+	// Without opting in nothing is scheduled
+	if _, err := r.AcceptCommuteRecommendation(ctx, user.ID, early.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := scheduled(); len(got) != 0 {
+		t.Fatalf("reminders without opting in: %+v", got)
+	}
+
+	on, lead := true, 30
+	_, err = r.SetNotificationSettings(ctx, user.ID, NotificationSettingsInput{
+		CommuteReminders:    &on,
+		ReminderLeadMinutes: &lead,
+		ReminderChannels:    []models.ReminderChannel{models.ReminderChannelWebhook},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.AcceptCommuteRecommendation(ctx, user.ID, early.ID); err != nil {
+		t.Fatal(err)
+	}
+	got := scheduled()
+	if len(got) != 2 || !got[0].RemindAt.Equal(at(8).Add(-30*time.Minute)) || got[1].Summary != "Leave the office for home" {
+		t.Fatalf("reminders = %+v, want both trips 30 minutes ahead", got)
+	}
+	if len(got[0].Channels) != 1 || got[0].Channels[0] != models.ReminderChannelWebhook {
+		t.Errorf("channels = %v", got[0].Channels)
+	}
+
+	// Choosing another option replaces the day's reminders
+	if _, err := r.AcceptCommuteRecommendation(ctx, user.ID, late.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := scheduled(); len(got) != 2 || got[0].RecommendationID != late.ID || !got[0].DepartAt.Equal(*at(9)) {
+		t.Errorf("reminders after changing plan = %+v", got)
+	}
+	if _, err := r.AcceptCommuteRecommendation(ctx, user.ID, remote.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := scheduled(); len(got) != 0 {
+		t.Errorf("reminders after choosing remote work = %+v", got)
+	}
+
+	// Opting out cancels what is scheduled
+	if _, err := r.AcceptCommuteRecommendation(ctx, user.ID, early.ID); err != nil {
+		t.Fatal(err)
+	}
+	off := false
+	if _, err := r.SetNotificationSettings(ctx, user.ID, NotificationSettingsInput{CommuteReminders: &off}); err != nil {
+		t.Fatal(err)
+	}
+	if got := scheduled(); len(got) != 0 {
+		t.Errorf("reminders after opting out = %+v", got)
+	}
+
+	bad := maxReminderLeadMinutes + 1
+	if _, err := r.SetNotificationSettings(ctx, user.ID, NotificationSettingsInput{ReminderLeadMinutes: &bad}); err == nil {
+		t.Error("accepted a lead time over the maximum")
+	}
+	if _, err := r.SetNotificationSettings(ctx, user.ID, NotificationSettingsInput{ReminderChannels: []models.ReminderChannel{"SMS"}}); err == nil {
+		t.Error("accepted an unknown channel")
+	}
+}
@@ -0,0 +1,169 @@
+package resolvers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/tenant"
+	"github.com/commute-planner/backend/pkg/webhooks"
+)
+
+// ReplanConfig controls re-planning days whose calendar changed after they were planned
+type ReplanConfig struct {
+	// Debounce is how long changes to a day must stop before it is re-planned, so an
+	// edit burst or a calendar sync creates one job
+	Debounce time.Duration
+	// HorizonDays is how many days ahead, counting today, are re-planned
+	HorizonDays int
+}
+
+// replanTimeout bounds creating and queueing one re-plan job
+const replanTimeout = 30 * time.Second
+
+// PlanStale is the data of a plan.stale webhook: the day's plan no longer matches the
+// calendar. ReplanJobID is the job planning it again; nil when none could be created,
+// e.g. because the user is over their job quota.
+type PlanStale struct {
+	UserID      string  `json:"userId"`
+	TargetDate  string  `json:"targetDate"`
+	StaleJobID  string  `json:"staleJobId"`
+	ReplanJobID *string `json:"replanJobId"`
+}
+
+// replanner holds the debounce timer of each user's changed days
+type replanner struct {
+	cfg     ReplanConfig
+	mu      sync.Mutex
+	pending map[string]*time.Timer
+}
+
+// ReplanOnCalendarChanges makes calendar changes re-plan the upcoming days they affect
+func (r *Resolver) ReplanOnCalendarChanges(cfg ReplanConfig) {
+	if cfg.Debounce <= 0 {
+		cfg.Debounce = 2 * time.Minute
+	}
+	if cfg.HorizonDays <= 0 {
+		cfg.HorizonDays = 7
+	}
+	r.replanner = &replanner{cfg: cfg, pending: map[string]*time.Timer{}}
+}
+
+// CalendarChanged is called with events that were created, updated or deleted; for
+// moved events pass both copies. Each upcoming day they start on is re-planned once the
+// changes to it have settled, if it was planned. Without ReplanOnCalendarChanges it does
+// nothing.
+func (r *Resolver) CalendarChanged(ctx context.Context, events ...*models.CalendarEvent) {
+	if r.replanner == nil {
+		return
+	}
+
+	today := map[string]time.Time{}
+	for _, event := range events {
+		if event == nil || event.IsDemo {
+			continue
+		}
+		now, ok := today[event.UserID]
+		if !ok {
+			var err error
+			if now, err = r.userNow(ctx, event.UserID); err != nil {
+				log.Printf("Failed to check calendar change of user %s for re-planning: %v", event.UserID, err)
+				continue
+			}
+			now = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+			today[event.UserID] = now
+		}
+		// Jobs plan with the events starting on their (UTC) target date
+		day := event.StartTime.UTC().Truncate(24 * time.Hour)
+		if day.Before(now) || !day.Before(now.AddDate(0, 0, r.replanner.cfg.HorizonDays)) {
+			continue
+		}
+		r.scheduleReplan(ctx, event.UserID, day.Format("2006-01-02"))
+	}
+}
+
+// scheduleReplan (re)starts the debounce timer of a user's day
+func (r *Resolver) scheduleReplan(ctx context.Context, userID, date string) {
+	tenantID, _ := tenant.FromContext(ctx)
+	key := tenantID + "/" + userID + "/" + date
+
+	p := r.replanner
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if timer, ok := p.pending[key]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(p.cfg.Debounce, func() {
+		p.mu.Lock()
+		if p.pending[key] == timer {
+			delete(p.pending, key)
+		}
+		p.mu.Unlock()
+		r.replan(tenantID, userID, date)
+	})
+	p.pending[key] = timer
+}
+
+// replan queues a new plan of a user's day and reports the current one as stale. Days
+// that weren't planned, or whose latest job hasn't started (it will see the changes),
+// are left alone. The new job keeps the latest job's constraints.
+func (r *Resolver) replan(tenantID, userID, date string) {
+	ctx, cancel := context.WithTimeout(context.Background(), replanTimeout)
+	defer cancel()
+	if tenantID != "" {
+		ctx = tenant.WithID(ctx, tenantID)
+	}
+
+	jobs, err := r.jobs.List(ctx, &userID)
+	if err != nil {
+		log.Printf("Failed to load jobs of user %s for re-planning %s: %v", userID, date, err)
+		return
+	}
+	var latest *models.Job
+	for _, job := range jobs {
+		if job.IsDemo || len(job.TargetDate) < 10 || job.TargetDate[:10] != date {
+			continue
+		}
+		if latest == nil || job.CreatedAt.After(latest.CreatedAt) {
+			latest = job
+		}
+	}
+	if latest == nil || (latest.Status != models.JobStatusCompleted && latest.Status != models.JobStatusInProgress) {
+		return
+	}
+
+	stale := PlanStale{UserID: userID, TargetDate: date, StaleJobID: latest.ID}
+	priority := string(models.JobPriorityBatch)
+	job, err := r.CreateJob(ctx, CreateJobInput{
+		UserID:      userID,
+		TargetDate:  latest.TargetDate,
+		Priority:    &priority,
+		Constraints: jobConstraints(latest),
+	})
+	if err != nil {
+		log.Printf("Failed to create re-plan job for user %s on %s: %v", userID, date, err)
+	} else {
+		stale.ReplanJobID = &job.ID
+		var inputData interface{}
+		if job.InputData != nil {
+			inputData = *job.InputData
+		}
+		err := r.QueueJob(ctx, map[string]interface{}{
+			"job_id":       job.ID,
+			"user_id":      job.UserID,
+			"target_date":  job.TargetDate,
+			"input_data":   inputData,
+			"priority":     job.Priority,
+			"scheduled_at": job.ScheduledAt,
+		})
+		if err != nil {
+			log.Printf("Failed to queue re-plan job %s: %v", job.ID, err)
+		} else {
+			log.Printf("Queued re-plan job %s for user %s on %s after calendar changes", job.ID, userID, date)
+		}
+	}
+	r.publish(ctx, userID, webhooks.EventPlanStale, stale)
+}
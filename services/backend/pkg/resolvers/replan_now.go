@@ -0,0 +1,202 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/allday"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Where the user is when they ask to re-plan, going by the plan they are on
+const (
+	replanAtHome     = "HOME"
+	replanAtOffice   = "OFFICE"
+	replanTravelling = "TRAVELLING"
+)
+
+// ReplanContext is added to a follow-up job's input data as context.replan: the planner
+// plans the rest of the day from Now, around the meetings that haven't ended, starting
+// from where the current plan has the user. Travel is estimated for departures from Now,
+// i.e. in the traffic there is now.
+type ReplanContext struct {
+	FollowUpOf string    `json:"followUpOf"`
+	Now        time.Time `json:"now"`
+	// Location is HOME, OFFICE or TRAVELLING; empty when the day had no plan
+	Location          string          `json:"location,omitempty"`
+	OfficeID          *string         `json:"officeId,omitempty"`
+	CurrentPlan       *ReplanPlan     `json:"currentPlan,omitempty"`
+	RemainingMeetings []ReplanMeeting `json:"remainingMeetings"`
+}
+
+// ReplanPlan is the plan a re-plan replaces
+type ReplanPlan struct {
+	RecommendationID string                   `json:"recommendationId"`
+	OptionType       models.CommuteOptionType `json:"optionType"`
+	CommuteStart     *time.Time               `json:"commuteStart,omitempty"`
+	OfficeArrival    *time.Time               `json:"officeArrival,omitempty"`
+	OfficeDeparture  *time.Time               `json:"officeDeparture,omitempty"`
+	CommuteEnd       *time.Time               `json:"commuteEnd,omitempty"`
+}
+
+// ReplanMeeting is a meeting of the day that hasn't ended yet. ChangedSincePlan marks
+// the ones edited after the current plan was made, e.g. a 2pm that went remote.
+type ReplanMeeting struct {
+	ID               string                `json:"id"`
+	Summary          string                `json:"summary"`
+	StartTime        time.Time             `json:"startTime"`
+	EndTime          time.Time             `json:"endTime"`
+	AttendanceMode   models.AttendanceMode `json:"attendanceMode"`
+	Location         *string               `json:"location,omitempty"`
+	ChangedSincePlan bool                  `json:"changedSincePlan"`
+}
+
+// ReplanNow re-optimizes the rest of today from now: it creates an interactive job for
+// the day of jobID, linked to it as a follow-up, with the job's constraints, a LEAVE_AFTER
+// constraint at the current time and the remaining meetings. The caller queues the job.
+func (r *Resolver) ReplanNow(ctx context.Context, userID, jobID string) (*models.Job, error) {
+	job, err := r.userJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.IsDemo {
+		return nil, fmt.Errorf("demo jobs can't be re-planned")
+	}
+	location, err := r.userLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if len(job.TargetDate) < 10 || job.TargetDate[:10] != now.In(location).Format("2006-01-02") {
+		return nil, fmt.Errorf("only today's plan can be re-planned now")
+	}
+
+	replan := ReplanContext{FollowUpOf: job.ID, Now: now.UTC().Truncate(time.Second), RemainingMeetings: []ReplanMeeting{}}
+	plannedAt := job.UpdatedAt
+	if plan, err := r.currentPlan(ctx, job); err != nil {
+		return nil, err
+	} else if plan != nil {
+		replan.CurrentPlan = &ReplanPlan{
+			RecommendationID: plan.ID,
+			OptionType:       plan.OptionType,
+			CommuteStart:     plan.CommuteStart,
+			OfficeArrival:    plan.OfficeArrival,
+			OfficeDeparture:  plan.OfficeDeparture,
+			CommuteEnd:       plan.CommuteEnd,
+		}
+		replan.Location, replan.OfficeID = whereOnPlan(plan, now), plan.OfficeID
+		plannedAt = plan.CreatedAt
+		if plan.AcceptedAt != nil {
+			plannedAt = *plan.AcceptedAt
+		}
+	}
+
+	// Jobs plan with the events starting on their (UTC) target date
+	from, _ := time.Parse("2006-01-02", job.TargetDate[:10])
+	to := from.AddDate(0, 0, 1)
+	err = r.events.Stream(ctx, userID, repository.DateRange{From: &from, To: &to}, func(event *models.CalendarEvent) error {
+		if event.IsDemo || !allday.Busy(event) || !event.EndTime.After(now) {
+			return nil
+		}
+		replan.RemainingMeetings = append(replan.RemainingMeetings, ReplanMeeting{
+			ID:               event.ID,
+			Summary:          event.Summary,
+			StartTime:        event.StartTime,
+			EndTime:          event.EndTime,
+			AttendanceMode:   event.AttendanceMode,
+			Location:         event.Location,
+			ChangedSincePlan: event.UpdatedAt.After(plannedAt),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching calendar events: %w", err)
+	}
+
+	inputData, err := withReplan(replan)
+	if err != nil {
+		return nil, err
+	}
+	priority := string(models.JobPriorityInteractive)
+	return r.CreateJob(ctx, CreateJobInput{
+		UserID:      userID,
+		TargetDate:  job.TargetDate,
+		InputData:   inputData,
+		Priority:    &priority,
+		Constraints: leavingAfter(jobConstraints(job), now.In(location).Format("15:04")),
+		followUpOf:  &job.ID,
+	})
+}
+
+// currentPlan returns the option of job the user accepted, else its top option; nil when
+// it has none
+func (r *Resolver) currentPlan(ctx context.Context, job *models.Job) (*models.CommuteRecommendation, error) {
+	options, err := r.recommendations.ListByJob(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching recommendations: %w", err)
+	}
+	for _, option := range options {
+		if option.AcceptedAt != nil {
+			return option, nil
+		}
+	}
+	if len(options) > 0 {
+		return options[0], nil
+	}
+	return nil, nil
+}
+
+// whereOnPlan returns where plan has the user at now
+func whereOnPlan(plan *models.CommuteRecommendation, now time.Time) string {
+	if plan.CommuteStart == nil {
+		return replanAtHome
+	}
+	switch {
+	case now.Before(*plan.CommuteStart):
+		return replanAtHome
+	case plan.OfficeArrival != nil && now.Before(*plan.OfficeArrival):
+		return replanTravelling
+	case plan.OfficeDeparture == nil || now.Before(*plan.OfficeDeparture):
+		return replanAtOffice
+	case plan.CommuteEnd != nil && now.Before(*plan.CommuteEnd):
+		return replanTravelling
+	default:
+		return replanAtHome
+	}
+}
+
+// leavingAfter returns constraints with their LEAVE_AFTER replaced by one at clock (HH:MM),
+// unless theirs is later. A HOME_BY that has passed is dropped; it can't be met any more.
+func leavingAfter(constraints []models.PlanningConstraint, clock string) []models.PlanningConstraint {
+	label := "Re-planning from now"
+	leaveAfter := models.PlanningConstraint{Type: models.ConstraintLeaveAfter, Time: &clock, Label: &label}
+	kept := []models.PlanningConstraint{}
+	for _, constraint := range constraints {
+		switch {
+		case constraint.Type == models.ConstraintLeaveAfter && constraint.Time != nil:
+			if *constraint.Time > *leaveAfter.Time {
+				leaveAfter = constraint
+			}
+			continue
+		case constraint.Type == models.ConstraintHomeBy && constraint.Time != nil && *constraint.Time <= clock:
+			continue
+		}
+		kept = append(kept, constraint)
+	}
+	return append(kept, leaveAfter)
+}
+
+// withReplan encodes the input data of a follow-up job with its re-plan context
+func withReplan(replan ReplanContext) (*string, error) {
+	encoded, err := json.Marshal(map[string]interface{}{
+		"context": map[string]interface{}{"replan": replan},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job input: %w", err)
+	}
+	inputData := string(encoded)
+	return &inputData, nil
+}
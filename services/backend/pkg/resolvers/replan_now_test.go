@@ -0,0 +1,118 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/google/uuid"
+)
+
+func TestReplanNow(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	completed, inProgress := string(models.JobStatusCompleted), string(models.JobStatusInProgress)
+
+	now := time.Now().UTC()
+	day := now.Truncate(24 * time.Hour)
+	input := `{"context":{"constraints":[{"type":"HOME_BY","time":"00:00"},{"type":"AVOID_MODE","mode":"DRIVE"}]}}`
+	job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: day.Format("2006-01-02"), InputData: &input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range []*string{&inProgress, &completed} {
+		if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start, arrival, departure := now.Add(-2*time.Hour), now.Add(-time.Hour), now.Add(4*time.Hour)
+	rec := &models.CommuteRecommendation{
+		JobID: job.ID, OptionRank: 1, OptionType: models.CommuteOptionFullDayOffice,
+		CommuteStart: &start, OfficeArrival: &arrival, OfficeDeparture: &departure,
+	}
+	if err := repos.Recommendations.Create(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	meeting := func(summary string, start, end, updated time.Time) {
+		t.Helper()
+		err := repos.Events.Create(ctx, &models.CalendarEvent{
+			ID: uuid.New().String(), UserID: user.ID, Summary: summary, StartTime: start, EndTime: end,
+			AttendanceMode: models.AttendanceCanBeRemote, UpdatedAt: updated,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	meeting("Standup", day, day.Add(time.Second), rec.CreatedAt.Add(-time.Hour))
+	meeting("Design review", now, now.Add(time.Hour), rec.CreatedAt.Add(time.Hour))
+
+	followUp, err := r.ReplanNow(ctx, user.ID, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if followUp.FollowUpOf == nil || *followUp.FollowUpOf != job.ID || followUp.Status != models.JobStatusPending {
+		t.Fatalf("follow-up = %+v, want a pending job linked to %s", followUp, job.ID)
+	}
+
+	var data struct {
+		Context struct {
+			Replan      ReplanContext               `json:"replan"`
+			Constraints []models.PlanningConstraint `json:"constraints"`
+		} `json:"context"`
+	}
+	if err := json.Unmarshal([]byte(*followUp.InputData), &data); err != nil {
+		t.Fatal(err)
+	}
+	replan := data.Context.Replan
+	if replan.Location != replanAtOffice || replan.CurrentPlan == nil || replan.CurrentPlan.RecommendationID != rec.ID {
+		t.Errorf("replan = %+v, want the user in the office on the top option", replan)
+	}
+	if len(replan.RemainingMeetings) != 1 || replan.RemainingMeetings[0].Summary != "Design review" || !replan.RemainingMeetings[0].ChangedSincePlan {
+		t.Errorf("remaining meetings = %+v, want the changed design review", replan.RemainingMeetings)
+	}
+	// The passed HOME_BY is dropped; the user can't leave before now
+	var kinds []models.PlanningConstraintType
+	for _, constraint := range data.Context.Constraints {
+		kinds = append(kinds, constraint.Type)
+		if constraint.Type == models.ConstraintLeaveAfter && *constraint.Time < now.Format("15:04") {
+			t.Errorf("leave after %s, want now", *constraint.Time)
+		}
+	}
+	if len(kinds) != 2 || kinds[0] != models.ConstraintAvoidMode || kinds[1] != models.ConstraintLeaveAfter {
+		t.Errorf("constraints = %v", kinds)
+	}
+
+	// Only today's plans can be re-planned, only by their user
+	other := createTestUser(t, repos, "grace@example.com")
+	if _, err := r.ReplanNow(ctx, other.ID, job.ID); err == nil {
+		t.Error("another user re-planned the job")
+	}
+	tomorrow, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: day.AddDate(0, 0, 1).Format("2006-01-02")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReplanNow(ctx, user.ID, tomorrow.ID); err == nil {
+		t.Error("re-planned tomorrow now")
+	}
+}
+
+func TestWhereOnPlan(t *testing.T) {
+	at := func(hour int) *time.Time {
+		t := time.Date(2026, 3, 2, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+	plan := &models.CommuteRecommendation{CommuteStart: at(8), OfficeArrival: at(9), OfficeDeparture: at(17), CommuteEnd: at(18)}
+	for hour, want := range map[int]string{7: replanAtHome, 8: replanTravelling, 12: replanAtOffice, 17: replanTravelling, 19: replanAtHome} {
+		if got := whereOnPlan(plan, *at(hour)); got != want {
+			t.Errorf("at %d:00 = %s, want %s", hour, got, want)
+		}
+	}
+	if got := whereOnPlan(&models.CommuteRecommendation{}, *at(12)); got != replanAtHome {
+		t.Errorf("remote plan = %s, want %s", got, replanAtHome)
+	}
+}
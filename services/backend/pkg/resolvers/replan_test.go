@@ -0,0 +1,124 @@
+package resolvers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/webhooks"
+)
+
+type recordingQueue struct {
+	mu     sync.Mutex
+	queued []string
+}
+
+func (q *recordingQueue) AddJobToQueue(ctx context.Context, jobID, userID, targetDate string, inputData *string, priority models.JobPriority) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queued = append(q.queued, jobID)
+	return nil
+}
+
+func (q *recordingQueue) ScheduleJob(ctx context.Context, jobID, userID, targetDate string, inputData *string, priority models.JobPriority, at time.Time) error {
+	return q.AddJobToQueue(ctx, jobID, userID, targetDate, inputData, priority)
+}
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	stales []PlanStale
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, userID, eventType string, data interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if eventType == webhooks.EventPlanStale {
+		p.stales = append(p.stales, data.(PlanStale))
+	}
+	return nil
+}
+
+func (p *recordingPublisher) AllowInsecureURLs() bool { return false }
+
+func (p *recordingPublisher) planStales() []PlanStale {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PlanStale(nil), p.stales...)
+}
+
+func TestReplanOnCalendarChanges(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	queue := &recordingQueue{}
+	publisher := &recordingPublisher{}
+	r := NewResolver(repos, queue, publisher, JobQuotaLimits{}, nil, nil)
+	r.ReplanOnCalendarChanges(ReplanConfig{Debounce: 20 * time.Millisecond, HorizonDays: 3})
+	user := createTestUser(t, repos, "ada@example.com")
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	day := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+	plan := func(offset int, statuses ...models.JobStatus) *models.Job {
+		t.Helper()
+		inputData := `{"context": {"constraints": [{"type": "HOME_BY", "time": "18:00"}]}}`
+		job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: day(offset), InputData: &inputData})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, status := range statuses {
+			value := string(status)
+			if job, err = repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &value}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return job
+	}
+	// Tomorrow was planned; the day after has a plan that hasn't started yet; the third
+	// day wasn't planned; the fourth is past the horizon
+	planned := plan(1, models.JobStatusInProgress, models.JobStatusCompleted)
+	plan(2)
+	plan(4, models.JobStatusInProgress, models.JobStatusCompleted)
+
+	var input []CalendarEventInput
+	for _, offset := range []int{1, 1, 1, 2, 3, 4, -1} {
+		start := today.AddDate(0, 0, offset).Add(10 * time.Hour)
+		input = append(input, CalendarEventInput{UserID: user.ID, Summary: "Meeting", StartTime: start, EndTime: start.Add(time.Hour)})
+	}
+	if _, err := r.BulkCreateCalendarEvents(ctx, user.ID, input); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(publisher.planStales()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Give any further (unwanted) re-plans a chance to fire
+	time.Sleep(100 * time.Millisecond)
+
+	stales := publisher.planStales()
+	if len(stales) != 1 {
+		t.Fatalf("published %d plan.stale events, want 1 for tomorrow: %+v", len(stales), stales)
+	}
+	stale := stales[0]
+	if stale.TargetDate != day(1) || stale.StaleJobID != planned.ID || stale.ReplanJobID == nil {
+		t.Fatalf("plan.stale = %+v, want tomorrow's job %s re-planned", stale, planned.ID)
+	}
+
+	replan, err := repos.Jobs.Get(ctx, *stale.ReplanJobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replan.Priority != models.JobPriorityBatch || replan.TargetDate != day(1) {
+		t.Errorf("re-plan job = %s on %s, want a batch job on %s", replan.Priority, replan.TargetDate, day(1))
+	}
+	if constraints := jobConstraints(replan); len(constraints) != 1 || constraints[0].Type != models.ConstraintHomeBy {
+		t.Errorf("re-plan constraints = %+v, want the planned job's HOME_BY", constraints)
+	}
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if len(queue.queued) != 1 || queue.queued[0] != replan.ID {
+		t.Errorf("queued %v, want only the re-plan job %s", queue.queued, replan.ID)
+	}
+}
@@ -0,0 +1,73 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// derivedContext are the keys of a job's input context that CreateJob adds from the
+// user's data when the job is created, rather than the caller passing them in
+var derivedContext = []string{
+	"offices", "default_office_id", "home", "travel_profile",
+	"preferences", "day", "focus", "meeting_load", "experiment",
+}
+
+// ReplayJob creates a new job with the inputs of one of userID's finished jobs, linked to
+// it by ReplayOf, e.g. to plan the day again after a planner upgrade or a failed run. The
+// job keeps its priority, input data and constraints; the context CreateJob derives from
+// the user's data is derived again, so the new job plans with what holds now. The caller
+// queues the job.
+func (r *Resolver) ReplayJob(ctx context.Context, userID, jobID string) (*models.Job, error) {
+	job, err := r.userJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.IsDemo {
+		return nil, fmt.Errorf("demo jobs can't be replayed")
+	}
+	if !job.Status.IsTerminal() {
+		return nil, fmt.Errorf("only finished jobs can be replayed; this one is %s", job.Status)
+	}
+
+	inputData, err := replayInput(job.InputData)
+	if err != nil {
+		return nil, err
+	}
+	priority := string(job.Priority)
+	return r.CreateJob(ctx, CreateJobInput{
+		UserID:     userID,
+		TargetDate: job.TargetDate,
+		InputData:  inputData,
+		Priority:   &priority,
+		followUpOf: job.FollowUpOf,
+		replayOf:   &job.ID,
+	})
+}
+
+// replayInput returns a job's input data without the context CreateJob derives. The
+// constraints stay in the context, so CreateJob needn't add them again.
+func replayInput(inputData *string) (*string, error) {
+	if inputData == nil || strings.TrimSpace(*inputData) == "" {
+		return inputData, nil
+	}
+	data := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(*inputData), &data); err != nil {
+		return nil, fmt.Errorf("the job's input data can't be replayed: %w", err)
+	}
+	if jobContext, ok := data["context"].(map[string]interface{}); ok {
+		for _, key := range derivedContext {
+			delete(jobContext, key)
+		}
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job input: %w", err)
+	}
+	replayed := string(encoded)
+	return &replayed, nil
+}
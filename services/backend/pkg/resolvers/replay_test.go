@@ -0,0 +1,56 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestReplayJob(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+
+	input := `{"schemaVersion":2,"note":"early start","context":{"constraints":[{"type":"AVOID_MODE","mode":"DRIVE"}],"preferences":[{"key":"stale"}],"user_timezone":"Europe/Berlin"}}`
+	job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: "2026-03-02", InputData: &input, Priority: models.JobPriorityBatch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReplayJob(ctx, user.ID, job.ID); err == nil {
+		t.Fatal("pending job replayed")
+	}
+	for _, status := range []models.JobStatus{models.JobStatusInProgress, models.JobStatusFailed} {
+		value := string(status)
+		if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := r.ReplayJob(ctx, createTestUser(t, repos, "bob@example.com").ID, job.ID); err == nil {
+		t.Fatal("another user's job replayed")
+	}
+	replay, err := r.ReplayJob(ctx, user.ID, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.ReplayOf == nil || *replay.ReplayOf != job.ID || replay.Priority != models.JobPriorityBatch || replay.TargetDate != job.TargetDate {
+		t.Fatalf("replay = %+v, want a BATCH job for the same day linked to %s", replay, job.ID)
+	}
+
+	var data struct {
+		Note    string                     `json:"note"`
+		Context map[string]json.RawMessage `json:"context"`
+	}
+	if err := json.Unmarshal([]byte(*replay.InputData), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Note != "early start" || data.Context["user_timezone"] == nil || data.Context["constraints"] == nil {
+		t.Errorf("replayed input = %s, want the original's own inputs", *replay.InputData)
+	}
+	if data.Context["preferences"] != nil {
+		t.Errorf("replayed input = %s, want the stale preferences derived again", *replay.InputData)
+	}
+}
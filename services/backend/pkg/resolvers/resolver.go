@@ -2,25 +2,131 @@ package resolvers
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/analytics"
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/carbon"
+	"github.com/commute-planner/backend/pkg/clientinfo"
+	"github.com/commute-planner/backend/pkg/costs"
+	"github.com/commute-planner/backend/pkg/deprecation"
+	"github.com/commute-planner/backend/pkg/experiments"
+	"github.com/commute-planner/backend/pkg/focus"
+	"github.com/commute-planner/backend/pkg/geo"
+	"github.com/commute-planner/backend/pkg/jobinput"
+	"github.com/commute-planner/backend/pkg/meetingload"
 	"github.com/commute-planner/backend/pkg/models"
-	"github.com/commute-planner/backend/pkg/redis"
+	"github.com/commute-planner/backend/pkg/perception"
+	"github.com/commute-planner/backend/pkg/planner"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/webhooks"
 	"github.com/google/uuid"
 )
 
+// JobQueue hands jobs to the AI worker
+type JobQueue interface {
+	AddJobToQueue(ctx context.Context, jobID, userID, targetDate string, inputData *string, priority models.JobPriority) error
+	ScheduleJob(ctx context.Context, jobID, userID, targetDate string, inputData *string, priority models.JobPriority, at time.Time) error
+}
+
+// maxScheduleAhead bounds how far in the future a job can be scheduled
+const maxScheduleAhead = 30 * 24 * time.Hour
+
 type Resolver struct {
-	db          *database.DB
-	redisClient *redis.Client
+	queue           JobQueue
+	users           repository.UserRepository
+	jobs            repository.JobRepository
+	events          repository.EventRepository
+	recommendations repository.RecommendationRepository
+	webhooks        repository.WebhookRepository
+	quotas          repository.JobQuotaRepository
+	tenants         repository.TenantRepository
+	offices         repository.OfficeRepository
+	travelProfiles  repository.TravelProfileRepository
+	preferences     repository.PreferenceFeedbackRepository
+	notifications   repository.NotificationSettingsRepository
+	artifacts       repository.ArtifactRepository
+	shareLinks      repository.ShareLinkRepository
+	calendarFeeds   repository.CalendarFeedRepository
+	reminders       repository.CommuteReminderRepository
+	calendarImports repository.CalendarImportRepository
+	apiTokens       repository.APITokenRepository
+	deprecated      repository.DeprecationUsageRepository
+	clientUsage     repository.ClientUsageRepository
+	publisher       WebhookPublisher
+	quotaLimits     JobQuotaLimits
+	// explainer is nil unless the backend generates recommendation text itself
+	explainer RecommendationExplainer
+	// geocoder is nil unless a geocoding provider is configured
+	geocoder geo.Geocoder
+	// replanner is nil unless calendar changes re-plan days (ReplanOnCalendarChanges)
+	replanner *replanner
+	// experiment is the planner A/B test new jobs are assigned to (RunExperiment), or nil
+	experiment *experiments.Experiment
+	// rankers picks the ranker of completed jobs (RankWith); nil keeps the AI service's order
+	rankers *planner.Selector
+	// meetingLoadLimits are the thresholds of MeetingLoad (LimitMeetingLoad)
+	meetingLoadLimits meetingload.Limits
+	// artifactStore presigns job artifact URLs (StoreArtifactsIn); nil without object storage
+	artifactStore ArtifactStore
+	// artifactURLTTL is how long presigned artifact URLs are valid for
+	artifactURLTTL time.Duration
+	// shareLinkSigner signs share link URLs (ShareLinksWith); nil disables share links
+	shareLinkSigner ShareLinkSigner
+	// shareLinkTTL is how long share links are valid for unless asked otherwise
+	shareLinkTTL time.Duration
+	// calendarFeedBaseURL is the public URL feed URLs start with (ServeCalendarFeedsAt);
+	// empty disables calendar feeds
+	calendarFeedBaseURL string
+	// orgReportMinGroup is the fewest users an org report figure is drawn from
+	// (AnonymizeOrgReports); 0 uses the orgreport default
+	orgReportMinGroup int
+	// deprecations are the deprecated fields deprecatedFieldUsage reports on
+	// (ReportDeprecationsOf); nil unless their use is tracked
+	deprecations *deprecation.Index
+	// analytics records product analytics events (TrackAnalyticsWith); nil records none
+	analytics AnalyticsTracker
+	// planFeed streams plan updates to myPlans subscribers (StreamPlansWith); nil
+	// refuses the subscription
+	planFeed PlanFeed
+	// backpressure sheds new jobs while the pipeline is saturated (ApplyBackpressure); nil
+	// lets the backlog grow
+	backpressure *backpressure
+	// progressModel estimates the progress of running jobs from completed ones
+	progressModel progressModelCache
 }
 
-func NewResolver(db *database.DB, redisClient *redis.Client) *Resolver {
+func NewResolver(repos repository.Repositories, queue JobQueue, publisher WebhookPublisher, quotaLimits JobQuotaLimits, explainer RecommendationExplainer, geocoder geo.Geocoder) *Resolver {
 	return &Resolver{
-		db:          db,
-		redisClient: redisClient,
+		queue:           queue,
+		users:           repos.Users,
+		jobs:            repos.Jobs,
+		events:          repos.Events,
+		recommendations: repos.Recommendations,
+		webhooks:        repos.Webhooks,
+		quotas:          repos.JobQuotas,
+		tenants:         repos.Tenants,
+		offices:         repos.Offices,
+		travelProfiles:  repos.TravelProfiles,
+		preferences:     repos.Preferences,
+		notifications:   repos.Notifications,
+		artifacts:       repos.Artifacts,
+		shareLinks:      repos.ShareLinks,
+		calendarFeeds:   repos.CalendarFeeds,
+		reminders:       repos.Reminders,
+		calendarImports: repos.CalendarImports,
+		apiTokens:       repos.APITokens,
+		deprecated:      repos.Deprecations,
+		clientUsage:     repos.ClientUsage,
+		publisher:       publisher,
+		quotaLimits:     quotaLimits,
+		explainer:       explainer,
+		geocoder:        geocoder,
 	}
 }
 
@@ -38,10 +144,12 @@ type QueryResolver interface {
 	Health(ctx context.Context) (string, error)
 	User(ctx context.Context, id string) (*models.User, error)
 	Users(ctx context.Context) ([]*models.User, error)
-	Job(ctx context.Context, id string) (*models.Job, error)
-	Jobs(ctx context.Context, userID *string) ([]*models.Job, error)
-	CalendarEvents(ctx context.Context, userID string, targetDate *string) ([]*models.CalendarEvent, error)
-	CommuteRecommendations(ctx context.Context, jobID string) ([]*models.CommuteRecommendation, error)
+	Job(ctx context.Context, viewer authz.Viewer, id string) (*models.Job, error)
+	Jobs(ctx context.Context, viewer authz.Viewer, userID *string) ([]*models.Job, error)
+	CalendarEvents(ctx context.Context, viewer authz.Viewer, userID string, targetDate *string) ([]*models.CalendarEvent, error)
+	CommuteRecommendations(ctx context.Context, viewer authz.Viewer, jobID string) ([]*models.CommuteRecommendation, error)
+	WebhookEndpoints(ctx context.Context, userID string) ([]*models.WebhookEndpoint, error)
+	WebhookDeliveries(ctx context.Context, userID, endpointID string, limit *int) ([]*models.WebhookDelivery, error)
 }
 
 type MutationResolver interface {
@@ -50,7 +158,11 @@ type MutationResolver interface {
 	DeleteUser(ctx context.Context, id string) (bool, error)
 	CreateJob(ctx context.Context, input CreateJobInput) (*models.Job, error)
 	UpdateJob(ctx context.Context, id string, input UpdateJobInput) (*models.Job, error)
-	DeleteJob(ctx context.Context, id string) (bool, error)
+	DeleteJob(ctx context.Context, userID, id string) (bool, error)
+	BulkCreateCalendarEvents(ctx context.Context, userID string, input []CalendarEventInput) (*BulkCreateCalendarEventsResult, error)
+	AcceptCommuteRecommendation(ctx context.Context, userID, id string) (*models.CommuteRecommendation, error)
+	CreateWebhookEndpoint(ctx context.Context, userID string, input CreateWebhookEndpointInput) (*models.WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, userID, id string) (bool, error)
 }
 
 // Health check
@@ -63,66 +175,39 @@ func (r *Resolver) QueueJob(ctx context.Context, jobData map[string]interface{})
 	jobID := jobData["job_id"].(string)
 	userID := jobData["user_id"].(string)
 	targetDate := jobData["target_date"].(string)
-	
+
 	var inputData *string
 	if data, exists := jobData["input_data"]; exists && data != nil {
 		dataStr := data.(string)
 		inputData = &dataStr
 	}
-	
-	return r.redisClient.AddJobToQueue(ctx, jobID, userID, targetDate, inputData)
+	priority, _ := jobData["priority"].(models.JobPriority)
+
+	if scheduledAt, _ := jobData["scheduled_at"].(*time.Time); scheduledAt != nil {
+		return r.queue.ScheduleJob(ctx, jobID, userID, targetDate, inputData, priority, *scheduledAt)
+	}
+	return r.queue.AddJobToQueue(ctx, jobID, userID, targetDate, inputData, priority)
 }
 
 // User resolvers
 func (r *Resolver) User(ctx context.Context, id string) (*models.User, error) {
-	query := `SELECT id, email, name, user_preferences, created_at, updated_at FROM users WHERE id = $1`
-	
-	user := &models.User{}
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Name,
-		&user.UserPreferences,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	
+	user, err := r.users.Get(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == repository.ErrNotFound {
 			return nil, fmt.Errorf("user not found")
 		}
 		return nil, fmt.Errorf("error fetching user: %w", err)
 	}
-	
+
 	return user, nil
 }
 
 func (r *Resolver) Users(ctx context.Context) ([]*models.User, error) {
-	query := `SELECT id, email, name, user_preferences, created_at, updated_at FROM users ORDER BY created_at DESC`
-	
-	rows, err := r.db.Query(query)
+	users, err := r.users.List(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching users: %w", err)
 	}
-	defer rows.Close()
-	
-	var users []*models.User
-	for rows.Next() {
-		user := &models.User{}
-		err := rows.Scan(
-			&user.ID,
-			&user.Email,
-			&user.Name,
-			&user.UserPreferences,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("error scanning user: %w", err)
-		}
-		users = append(users, user)
-	}
-	
+
 	return users, nil
 }
 
@@ -133,27 +218,15 @@ type CreateUserInput struct {
 }
 
 func (r *Resolver) CreateUser(ctx context.Context, input CreateUserInput) (*models.User, error) {
-	id := uuid.New().String()
-	now := time.Now()
-	
-	query := `INSERT INTO users (id, email, name, user_preferences, created_at, updated_at) 
-	          VALUES ($1, $2, $3, $4, $5, $6) 
-	          RETURNING id, email, name, user_preferences, created_at, updated_at`
-	
-	user := &models.User{}
-	err := r.db.QueryRow(query, id, input.Email, input.Name, input.UserPreferences, now, now).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Name,
-		&user.UserPreferences,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	
+	user, err := r.users.Create(ctx, repository.NewUser{
+		Email:           input.Email,
+		Name:            input.Name,
+		UserPreferences: input.UserPreferences,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error creating user: %w", err)
 	}
-	
+
 	return user, nil
 }
 
@@ -161,139 +234,83 @@ type UpdateUserInput struct {
 	Email           *string `json:"email"`
 	Name            *string `json:"name"`
 	UserPreferences *string `json:"userPreferences"`
+	// ExpectedVersion is the version the caller read; the update is rejected with a
+	// *ConflictError if the user has changed since. Unset, the update always applies.
+	ExpectedVersion *int `json:"expectedVersion"`
 }
 
 func (r *Resolver) UpdateUser(ctx context.Context, id string, input UpdateUserInput) (*models.User, error) {
-	query := `UPDATE users SET updated_at = NOW()`
-	args := []interface{}{}
-	argIndex := 1
-	
-	if input.Email != nil {
-		query += fmt.Sprintf(", email = $%d", argIndex)
-		args = append(args, *input.Email)
-		argIndex++
-	}
-	if input.Name != nil {
-		query += fmt.Sprintf(", name = $%d", argIndex)
-		args = append(args, *input.Name)
-		argIndex++
-	}
-	if input.UserPreferences != nil {
-		query += fmt.Sprintf(", user_preferences = $%d", argIndex)
-		args = append(args, *input.UserPreferences)
-		argIndex++
-	}
-	
-	query += fmt.Sprintf(" WHERE id = $%d RETURNING id, email, name, user_preferences, created_at, updated_at", argIndex)
-	args = append(args, id)
-	
-	user := &models.User{}
-	err := r.db.QueryRow(query, args...).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Name,
-		&user.UserPreferences,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	
+	user, err := r.users.Update(ctx, id, repository.UserUpdate{
+		Email:           input.Email,
+		Name:            input.Name,
+		UserPreferences: input.UserPreferences,
+		ExpectedVersion: input.ExpectedVersion,
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == repository.ErrNotFound {
 			return nil, fmt.Errorf("user not found")
 		}
+		if err == repository.ErrConflict {
+			return nil, &ConflictError{Kind: "user", ID: id}
+		}
 		return nil, fmt.Errorf("error updating user: %w", err)
 	}
-	
+
 	return user, nil
 }
 
 func (r *Resolver) DeleteUser(ctx context.Context, id string) (bool, error) {
-	query := `DELETE FROM users WHERE id = $1`
-	
-	result, err := r.db.Exec(query, id)
+	deleted, err := r.users.Delete(ctx, id)
 	if err != nil {
 		return false, fmt.Errorf("error deleting user: %w", err)
 	}
-	
-	rowsAffected, err := result.RowsAffected()
+
+	return deleted, nil
+}
+
+// Job resolvers
+
+// Job returns one of the viewer's jobs; the admin token reads any job
+func (r *Resolver) Job(ctx context.Context, viewer authz.Viewer, id string) (*models.Job, error) {
+	job, err := r.viewerJob(ctx, viewer, id)
 	if err != nil {
-		return false, fmt.Errorf("error getting rows affected: %w", err)
+		return nil, err
 	}
-	
-	return rowsAffected > 0, nil
+	r.estimateProgress(ctx, job)
+
+	return job, nil
 }
 
-// Job resolvers
-func (r *Resolver) Job(ctx context.Context, id string) (*models.Job, error) {
-	query := `SELECT id, user_id, status, progress, current_step, target_date, input_data, result, error_message, created_at, updated_at 
-	          FROM jobs WHERE id = $1`
-	
-	job := &models.Job{}
-	err := r.db.QueryRow(query, id).Scan(
-		&job.ID,
-		&job.UserID,
-		&job.Status,
-		&job.Progress,
-		&job.CurrentStep,
-		&job.TargetDate,
-		&job.InputData,
-		&job.Result,
-		&job.ErrorMessage,
-		&job.CreatedAt,
-		&job.UpdatedAt,
-	)
-	
+// viewerJob returns a job the viewer owns. Other users' jobs are reported as not found,
+// like unknown ones.
+func (r *Resolver) viewerJob(ctx context.Context, viewer authz.Viewer, jobID string) (*models.Job, error) {
+	job, err := r.jobs.Get(ctx, jobID)
+	if err == repository.ErrNotFound || (err == nil && !viewer.Owns(job.UserID)) {
+		return nil, fmt.Errorf("job not found")
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("job not found")
-		}
 		return nil, fmt.Errorf("error fetching job: %w", err)
 	}
-	
 	return job, nil
 }
 
-func (r *Resolver) Jobs(ctx context.Context, userID *string) ([]*models.Job, error) {
-	var query string
-	var args []interface{}
-	
-	if userID != nil {
-		query = `SELECT id, user_id, status, progress, current_step, target_date, input_data, result, error_message, created_at, updated_at 
-		         FROM jobs WHERE user_id = $1 ORDER BY created_at DESC`
-		args = append(args, *userID)
-	} else {
-		query = `SELECT id, user_id, status, progress, current_step, target_date, input_data, result, error_message, created_at, updated_at 
-		         FROM jobs ORDER BY created_at DESC`
-	}
-	
-	rows, err := r.db.Query(query, args...)
+// Jobs returns the signed-in viewer's jobs; userID may only name them. The admin token
+// lists a user's jobs, or everyone's when userID is nil.
+func (r *Resolver) Jobs(ctx context.Context, viewer authz.Viewer, userID *string) ([]*models.Job, error) {
+	if !viewer.Admin {
+		if !viewer.Has(authz.RoleUser) {
+			return nil, authz.ErrUnauthenticated
+		}
+		if userID != nil && *userID != viewer.UserID {
+			return nil, errors.New("jobs only lists the signed-in user's jobs")
+		}
+		userID = &viewer.UserID
+	}
+	jobs, err := r.jobs.List(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching jobs: %w", err)
 	}
-	defer rows.Close()
-	
-	var jobs []*models.Job
-	for rows.Next() {
-		job := &models.Job{}
-		err := rows.Scan(
-			&job.ID,
-			&job.UserID,
-			&job.Status,
-			&job.Progress,
-			&job.CurrentStep,
-			&job.TargetDate,
-			&job.InputData,
-			&job.Result,
-			&job.ErrorMessage,
-			&job.CreatedAt,
-			&job.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("error scanning job: %w", err)
-		}
-		jobs = append(jobs, job)
-	}
-	
+
 	return jobs, nil
 }
 
@@ -301,45 +318,107 @@ type CreateJobInput struct {
 	UserID     string  `json:"userId"`
 	TargetDate string  `json:"targetDate"`
 	InputData  *string `json:"inputData"`
+	Priority   *string `json:"priority"`
+	// ScheduleAt is an RFC 3339 time to enqueue the job at; past times enqueue right away
+	ScheduleAt *string `json:"scheduleAt"`
+	// Constraints are one-off limits on this job's plan, passed to the planner
+	Constraints []models.PlanningConstraint `json:"constraints"`
+	// followUpOf links a job created by ReplanNow to the job it follows up
+	followUpOf *string
+	// replayOf links a job created by ReplayJob to the job whose inputs it replays
+	replayOf *string
 }
 
 func (r *Resolver) CreateJob(ctx context.Context, input CreateJobInput) (*models.Job, error) {
-	id := uuid.New().String()
-	now := time.Now()
-	
-	// Handle JSON input data - pass JSON string directly to PostgreSQL
-	var inputDataJSON interface{}
-	if input.InputData != nil && *input.InputData != "" {
-		// InputData is already a JSON string from frontend, pass it directly
-		inputDataJSON = *input.InputData
-	}
-	
-	query := `INSERT INTO jobs (id, user_id, status, progress, target_date, input_data, created_at, updated_at) 
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
-	          RETURNING id, user_id, status, progress, current_step, target_date, input_data, result, error_message, created_at, updated_at`
-	
-	job := &models.Job{}
-	err := r.db.QueryRow(query, id, input.UserID, models.JobStatusPending, 0.0, input.TargetDate, inputDataJSON, now, now).Scan(
-		&job.ID,
-		&job.UserID,
-		&job.Status,
-		&job.Progress,
-		&job.CurrentStep,
-		&job.TargetDate,
-		&job.InputData,
-		&job.Result,
-		&job.ErrorMessage,
-		&job.CreatedAt,
-		&job.UpdatedAt,
-	)
-	
+	priority := models.JobPriorityInteractive
+	if input.Priority != nil {
+		priority = models.JobPriority(*input.Priority)
+		if !priority.IsValid() {
+			return nil, fmt.Errorf("invalid job priority %q", *input.Priority)
+		}
+	}
+
+	var scheduledAt *time.Time
+	if input.ScheduleAt != nil {
+		at, err := time.Parse(time.RFC3339, *input.ScheduleAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scheduleAt %q: expected an RFC 3339 time", *input.ScheduleAt)
+		}
+		if at.After(time.Now().Add(maxScheduleAhead)) {
+			return nil, fmt.Errorf("scheduleAt can be at most %d days ahead", int(maxScheduleAhead.Hours()/24))
+		}
+		scheduledAt = &at
+	}
+	if err := validateConstraints(input.Constraints); err != nil {
+		return nil, err
+	}
+
+	if err := r.checkJobQuota(ctx, input.UserID); err != nil {
+		return nil, err
+	}
+	if scheduledAt == nil || !scheduledAt.After(time.Now()) {
+		// A job scheduled ahead isn't due yet, so it doesn't add to the backlog
+		var err error
+		if priority, err = r.checkBackpressure(ctx, priority); err != nil {
+			return nil, err
+		}
+	}
+
+	inputData, err := jobinput.Normalize(input.InputData)
+	if err != nil {
+		return nil, err
+	}
+	inputData, err = withConstraints(inputData, input.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	inputData, err = r.withLocationContext(ctx, input.UserID, inputData)
+	if err != nil {
+		return nil, err
+	}
+	inputData = r.withPreferences(ctx, input.UserID, input.TargetDate, inputData)
+	inputData = r.withDayMarkers(ctx, input.UserID, input.TargetDate, inputData)
+	inputData = r.withFocusTime(ctx, input.UserID, input.TargetDate, inputData)
+	inputData = r.withMeetingLoad(ctx, input.UserID, input.TargetDate, inputData)
+	assignment := r.assignVariant(input.UserID)
+	inputData, err = withExperiment(inputData, assignment)
+	if err != nil {
+		return nil, err
+	}
+	if err := jobinput.Validate(inputData); err != nil {
+		return nil, fmt.Errorf("error building job input: %w", err)
+	}
+
+	newJob := repository.NewJob{
+		UserID:      input.UserID,
+		TargetDate:  input.TargetDate,
+		InputData:   inputData,
+		Priority:    priority,
+		ScheduledAt: scheduledAt,
+		FollowUpOf:  input.followUpOf,
+		ReplayOf:    input.replayOf,
+	}
+	newJob.ClientName, newJob.ClientVersion = clientinfo.Fields(ctx)
+	if assignment != nil {
+		newJob.Experiment = &assignment.Experiment
+		newJob.Variant = &assignment.Variant
+	}
+	job, err := r.jobs.Create(ctx, newJob)
 	if err != nil {
 		return nil, fmt.Errorf("error creating job: %w", err)
 	}
-	
+
 	// Note: Job queueing to Redis is handled in main.go after successful GraphQL mutation
 	// to avoid duplicate queueing
-	
+	r.publish(ctx, job.UserID, webhooks.EventJobCreated, job)
+	r.track(ctx, job.UserID, analytics.EventPlanRequested, map[string]interface{}{
+		"priority":    priority,
+		"scheduled":   scheduledAt != nil,
+		"constraints": len(input.Constraints),
+		"followUp":    input.followUpOf != nil,
+		"replay":      input.replayOf != nil,
+	})
+
 	return job, nil
 }
 
@@ -349,182 +428,318 @@ type UpdateJobInput struct {
 	CurrentStep  *string  `json:"currentStep"`
 	Result       *string  `json:"result"`
 	ErrorMessage *string  `json:"errorMessage"`
+	// ExpectedVersion is the version the caller read; the update is rejected with a
+	// *ConflictError if the job has changed since. Unset, the update always applies.
+	ExpectedVersion *int `json:"expectedVersion"`
 }
 
 func (r *Resolver) UpdateJob(ctx context.Context, id string, input UpdateJobInput) (*models.Job, error) {
-	query := `UPDATE jobs SET updated_at = NOW()`
-	args := []interface{}{}
-	argIndex := 1
-	
-	if input.Status != nil {
-		query += fmt.Sprintf(", status = $%d", argIndex)
-		args = append(args, *input.Status)
-		argIndex++
-	}
-	if input.Progress != nil {
-		query += fmt.Sprintf(", progress = $%d", argIndex)
-		args = append(args, *input.Progress)
-		argIndex++
-	}
-	if input.CurrentStep != nil {
-		query += fmt.Sprintf(", current_step = $%d", argIndex)
-		args = append(args, *input.CurrentStep)
-		argIndex++
-	}
-	if input.Result != nil {
-		query += fmt.Sprintf(", result = $%d", argIndex)
-		args = append(args, *input.Result)
-		argIndex++
-	}
-	if input.ErrorMessage != nil {
-		query += fmt.Sprintf(", error_message = $%d", argIndex)
-		args = append(args, *input.ErrorMessage)
-		argIndex++
-	}
-	
-	query += fmt.Sprintf(" WHERE id = $%d RETURNING id, user_id, status, progress, current_step, target_date, input_data, result, error_message, created_at, updated_at", argIndex)
-	args = append(args, id)
-	
-	job := &models.Job{}
-	err := r.db.QueryRow(query, args...).Scan(
-		&job.ID,
-		&job.UserID,
-		&job.Status,
-		&job.Progress,
-		&job.CurrentStep,
-		&job.TargetDate,
-		&job.InputData,
-		&job.Result,
-		&job.ErrorMessage,
-		&job.CreatedAt,
-		&job.UpdatedAt,
-	)
-	
+	if input.Status != nil && !models.JobStatus(*input.Status).IsValid() {
+		return nil, fmt.Errorf("invalid job status %q", *input.Status)
+	}
+
+	// Look up the current status first so completion events fire once, on the transition
+	var previous models.JobStatus
+	if input.Status != nil && isTerminalStatus(models.JobStatus(*input.Status)) {
+		if current, err := r.jobs.Get(ctx, id); err == nil {
+			previous = current.Status
+		}
+	}
+
+	job, err := r.jobs.Update(ctx, id, repository.JobUpdate{
+		Status:          input.Status,
+		Progress:        input.Progress,
+		CurrentStep:     input.CurrentStep,
+		Result:          input.Result,
+		ErrorMessage:    input.ErrorMessage,
+		ExpectedVersion: input.ExpectedVersion,
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == repository.ErrNotFound {
 			return nil, fmt.Errorf("job not found")
 		}
+		var transitionErr *models.JobTransitionError
+		if errors.As(err, &transitionErr) {
+			return nil, err
+		}
+		if err == repository.ErrConflict {
+			return nil, &ConflictError{Kind: "job", ID: id}
+		}
 		return nil, fmt.Errorf("error updating job: %w", err)
 	}
-	
+
+	if input.Status != nil && job.Status != previous {
+		switch job.Status {
+		case models.JobStatusCompleted:
+			r.rankRecommendations(ctx, job)
+			r.publish(ctx, job.UserID, webhooks.EventJobCompleted, job)
+			if r.explainer != nil {
+				go r.explainRecommendations(job)
+			}
+		case models.JobStatusFailed:
+			r.publish(ctx, job.UserID, webhooks.EventJobFailed, job)
+		}
+	}
+
 	return job, nil
 }
 
-func (r *Resolver) DeleteJob(ctx context.Context, id string) (bool, error) {
-	query := `DELETE FROM jobs WHERE id = $1`
-	
-	result, err := r.db.Exec(query, id)
+// JobEvents returns the status history of one of the viewer's jobs, oldest first; the
+// admin token reads any job's
+func (r *Resolver) JobEvents(ctx context.Context, viewer authz.Viewer, jobID string) ([]*models.JobEvent, error) {
+	if _, err := r.viewerJob(ctx, viewer, jobID); err != nil {
+		return nil, err
+	}
+	events, err := r.jobs.Events(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching job events: %w", err)
+	}
+	return events, nil
+}
+
+// JobTimeline returns the statuses and steps one of the viewer's jobs went through, oldest
+// first
+func (r *Resolver) JobTimeline(ctx context.Context, viewer authz.Viewer, jobID string) ([]*models.JobTimelineEntry, error) {
+	events, err := r.JobEvents(ctx, viewer, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return models.BuildJobTimeline(events), nil
+}
+
+// DeleteJob deletes one of the user's jobs; false when they have no such job
+func (r *Resolver) DeleteJob(ctx context.Context, userID, id string) (bool, error) {
+	job, err := r.jobs.Get(ctx, id)
+	if err == repository.ErrNotFound || (err == nil && job.UserID != userID) {
+		return false, nil
+	}
 	if err != nil {
 		return false, fmt.Errorf("error deleting job: %w", err)
 	}
-	
-	rowsAffected, err := result.RowsAffected()
+	deleted, err := r.jobs.Delete(ctx, id)
 	if err != nil {
-		return false, fmt.Errorf("error getting rows affected: %w", err)
+		return false, fmt.Errorf("error deleting job: %w", err)
 	}
-	
-	return rowsAffected > 0, nil
+
+	return deleted, nil
 }
 
 // CalendarEvent resolvers
-func (r *Resolver) CalendarEvents(ctx context.Context, userID string, targetDate *string) ([]*models.CalendarEvent, error) {
-	var query string
-	var args []interface{}
-	
-	if targetDate != nil {
-		// Filter by specific date - events that start or occur on the target date
-		// Use timezone-aware date filtering for timestamptz columns
-		// Extract YYYY-MM-DD and create timezone-aware range
-		dateStr := (*targetDate)[:10] // Extract just YYYY-MM-DD part
-		
-		// Query events that fall within the target date in the stored timezone
-		// This works because our times are stored with timezone info (timestamptz)
-		query = `SELECT id, user_id, summary, description, start_time, end_time, location, attendees, meeting_type, attendance_mode, is_all_day, is_recurring, google_event_id, created_at, updated_at 
-		         FROM calendar_events 
-		         WHERE user_id = $1 
-		           AND start_time >= $2::date 
-		           AND start_time < ($2::date + INTERVAL '1 day')
-		         ORDER BY start_time ASC`
-		args = []interface{}{userID, dateStr}
-	} else {
-		// No date filter - return all user events
-		query = `SELECT id, user_id, summary, description, start_time, end_time, location, attendees, meeting_type, attendance_mode, is_all_day, is_recurring, google_event_id, created_at, updated_at 
-		         FROM calendar_events WHERE user_id = $1 ORDER BY start_time ASC`
-		args = []interface{}{userID}
-	}
-	
-	rows, err := r.db.Query(query, args...)
+
+// CalendarEvents returns a user's events, of targetDate when set. Signed-in users, API
+// tokens included, read only their own; the admin token reads anyone's.
+func (r *Resolver) CalendarEvents(ctx context.Context, viewer authz.Viewer, userID string, targetDate *string) ([]*models.CalendarEvent, error) {
+	if !viewer.Owns(userID) {
+		return nil, errors.New("calendarEvents only reads the signed-in user's events")
+	}
+	events, err := r.events.ListByUser(ctx, userID, targetDate)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching calendar events: %w", err)
 	}
-	defer rows.Close()
-	
+	return withAttendeeLists(events), nil
+}
+
+// withAttendeeLists decodes the attendees of events read through the API
+func withAttendeeLists(events []*models.CalendarEvent) []*models.CalendarEvent {
+	for _, event := range events {
+		event.AttendeeList = event.DecodeAttendees()
+	}
+	return events
+}
+
+// MaxBulkCalendarEvents caps a single bulk import so one request can't hold a transaction
+// open indefinitely
+const MaxBulkCalendarEvents = 1000
+
+type CalendarEventInput struct {
+	ID             *string   `json:"id"` // generated when omitted
+	UserID         string    `json:"userId"`
+	Summary        string    `json:"summary"`
+	Description    *string   `json:"description"`
+	StartTime      time.Time `json:"startTime"`
+	EndTime        time.Time `json:"endTime"`
+	Location       *string   `json:"location"`
+	Attendees      *string   `json:"attendees"`
+	MeetingType    *string   `json:"meetingType"`
+	AttendanceMode *string   `json:"attendanceMode"`
+	IsAllDay       bool      `json:"isAllDay"`
+	IsRecurring    bool      `json:"isRecurring"`
+	GoogleEventID  *string   `json:"googleEventId"`
+}
+
+// BulkRowError reports why one input row was not imported
+type BulkRowError struct {
+	Index   int     `json:"index"`
+	ID      *string `json:"id,omitempty"`
+	Message string  `json:"message"`
+}
+
+type BulkCreateCalendarEventsResult struct {
+	Created int                     `json:"created"`
+	Events  []*models.CalendarEvent `json:"events"`
+	Errors  []BulkRowError          `json:"errors"`
+}
+
+// BulkCreateCalendarEvents imports many events into a user's calendar at once. Rows may
+// omit userId; rows for another user, invalid rows and rows whose ID already exists are
+// reported in Errors. The valid rows are inserted in one transaction.
+func (r *Resolver) BulkCreateCalendarEvents(ctx context.Context, userID string, input []CalendarEventInput) (*BulkCreateCalendarEventsResult, error) {
+	if len(input) > MaxBulkCalendarEvents {
+		return nil, fmt.Errorf("too many events: %d (max %d per request)", len(input), MaxBulkCalendarEvents)
+	}
+
+	result := &BulkCreateCalendarEventsResult{
+		Events: []*models.CalendarEvent{},
+		Errors: []BulkRowError{},
+	}
+	now := time.Now()
 	var events []*models.CalendarEvent
-	for rows.Next() {
-		event := &models.CalendarEvent{}
-		err := rows.Scan(
-			&event.ID,
-			&event.UserID,
-			&event.Summary,
-			&event.Description,
-			&event.StartTime,
-			&event.EndTime,
-			&event.Location,
-			&event.Attendees,
-			&event.MeetingType,
-			&event.AttendanceMode,
-			&event.IsAllDay,
-			&event.IsRecurring,
-			&event.GoogleEventID,
-			&event.CreatedAt,
-			&event.UpdatedAt,
-		)
+	rowIndex := map[string]int{}
+
+	for i, row := range input {
+		if row.UserID == "" {
+			row.UserID = userID
+		}
+		var event *models.CalendarEvent
+		err := fmt.Errorf("cannot import events for another user")
+		if row.UserID == userID {
+			event, err = newCalendarEvent(row, now)
+		}
+		if err == nil {
+			if _, dup := rowIndex[event.ID]; dup {
+				err = fmt.Errorf("duplicate id within this request")
+			}
+		}
 		if err != nil {
-			return nil, fmt.Errorf("error scanning calendar event: %w", err)
+			result.Errors = append(result.Errors, BulkRowError{Index: i, ID: row.ID, Message: err.Error()})
+			continue
 		}
+		rowIndex[event.ID] = i
 		events = append(events, event)
 	}
-	
-	return events, nil
+
+	if len(events) > 0 {
+		inserted, err := r.events.CreateBatch(ctx, events)
+		if err != nil {
+			return nil, fmt.Errorf("error importing calendar events: %w", err)
+		}
+		created := make(map[string]bool, len(inserted))
+		for _, id := range inserted {
+			created[id] = true
+		}
+		for _, event := range events {
+			if created[event.ID] {
+				result.Events = append(result.Events, event)
+				continue
+			}
+			id := event.ID
+			result.Errors = append(result.Errors, BulkRowError{Index: rowIndex[id], ID: &id, Message: "an event with this id already exists"})
+		}
+		r.CalendarChanged(ctx, result.Events...)
+	}
+
+	result.Created = len(result.Events)
+	sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].Index < result.Errors[j].Index })
+	return result, nil
+}
+
+// newCalendarEvent validates an input row and fills in defaults
+func newCalendarEvent(row CalendarEventInput, now time.Time) (*models.CalendarEvent, error) {
+	if row.UserID == "" {
+		return nil, fmt.Errorf("userId is required")
+	}
+	if strings.TrimSpace(row.Summary) == "" {
+		return nil, fmt.Errorf("summary is required")
+	}
+	if row.StartTime.IsZero() || row.EndTime.IsZero() {
+		return nil, fmt.Errorf("startTime and endTime are required")
+	}
+	if row.EndTime.Before(row.StartTime) {
+		return nil, fmt.Errorf("endTime is before startTime")
+	}
+
+	event := &models.CalendarEvent{
+		ID:             uuid.New().String(),
+		UserID:         row.UserID,
+		Summary:        row.Summary,
+		Description:    row.Description,
+		StartTime:      row.StartTime.UTC(),
+		EndTime:        row.EndTime.UTC(),
+		Location:       row.Location,
+		Attendees:      row.Attendees,
+		MeetingType:    models.MeetingTypeUnknown,
+		AttendanceMode: models.AttendanceFlexible,
+		IsAllDay:       row.IsAllDay,
+		IsRecurring:    row.IsRecurring,
+		GoogleEventID:  row.GoogleEventID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if row.ID != nil && *row.ID != "" {
+		event.ID = *row.ID
+	}
+	// Checked here so one bad row is reported on its own rather than failing the batch insert
+	if row.MeetingType != nil && *row.MeetingType != "" {
+		event.MeetingType = models.MeetingType(*row.MeetingType)
+		if !isMeetingType(event.MeetingType) {
+			return nil, fmt.Errorf("unknown meeting type %q", *row.MeetingType)
+		}
+	}
+	if row.AttendanceMode != nil && *row.AttendanceMode != "" {
+		event.AttendanceMode = models.AttendanceMode(*row.AttendanceMode)
+		if !isAttendanceMode(event.AttendanceMode) {
+			return nil, fmt.Errorf("unknown attendance mode %q", *row.AttendanceMode)
+		}
+	}
+	if event.Attendees != nil && !json.Valid([]byte(*event.Attendees)) {
+		return nil, fmt.Errorf("attendees must be valid JSON")
+	}
+	return event, nil
 }
 
 // CommuteRecommendation resolvers
-func (r *Resolver) CommuteRecommendations(ctx context.Context, jobID string) ([]*models.CommuteRecommendation, error) {
-	query := `SELECT id, job_id, option_rank, option_type, commute_start, office_arrival, office_departure, commute_end, office_duration, office_meetings, remote_meetings, business_rule_compliance, perception_analysis, reasoning, trade_offs, created_at 
-	          FROM commute_recommendations WHERE job_id = $1 ORDER BY option_rank ASC`
-	
-	rows, err := r.db.Query(query, jobID)
+
+// CommuteRecommendations returns the recommendations of one of the viewer's jobs; the
+// admin token reads any job's
+func (r *Resolver) CommuteRecommendations(ctx context.Context, viewer authz.Viewer, jobID string) ([]*models.CommuteRecommendation, error) {
+	job, err := r.viewerJob(ctx, viewer, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return r.jobRecommendations(ctx, job)
+}
+
+// jobRecommendations returns a job's recommendations ordered by rank, with the breakdowns
+// derived from them
+func (r *Resolver) jobRecommendations(ctx context.Context, job *models.Job) ([]*models.CommuteRecommendation, error) {
+	recommendations, err := r.recommendations.ListByJob(ctx, job.ID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching commute recommendations: %w", err)
 	}
-	defer rows.Close()
-	
-	var recommendations []*models.CommuteRecommendation
-	for rows.Next() {
-		rec := &models.CommuteRecommendation{}
-		err := rows.Scan(
-			&rec.ID,
-			&rec.JobID,
-			&rec.OptionRank,
-			&rec.OptionType,
-			&rec.CommuteStart,
-			&rec.OfficeArrival,
-			&rec.OfficeDeparture,
-			&rec.CommuteEnd,
-			&rec.OfficeDuration,
-			&rec.OfficeMeetings,
-			&rec.RemoteMeetings,
-			&rec.BusinessRuleCompliance,
-			&rec.PerceptionAnalysis,
-			&rec.Reasoning,
-			&rec.TradeOffs,
-			&rec.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("error scanning commute recommendation: %w", err)
+	if len(recommendations) == 0 {
+		return recommendations, nil
+	}
+
+	// The perception breakdown is scored against the job day's calendar
+	events, err := r.events.ListByUser(ctx, job.UserID, &job.TargetDate)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching calendar events: %w", err)
+	}
+	// Focus windows are only reported to users who asked for focus time
+	minutes, free, err := r.focusDay(ctx, job.UserID, job.TargetDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range recommendations {
+		rec.PerceptionBreakdown = perception.Analyze(rec, events)
+		rec.Emissions = carbon.Estimate(rec)
+		rec.Cost = costs.Estimate(rec)
+		rec.Legs = rec.DecodeTravelLegs()
+		rec.Modes = rec.DecodeModeOptions()
+		if minutes > 0 {
+			rec.FocusWindows = focus.Protected(rec, free, minutes)
 		}
-		recommendations = append(recommendations, rec)
 	}
-	
+	r.localize(ctx, job.UserID, recommendations...)
 	return recommendations, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,324 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/authz"
+	"github.com/commute-planner/backend/pkg/jobinput"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// newTestResolver returns a resolver over empty in-memory repositories
+func newTestResolver(t *testing.T, limits JobQuotaLimits) (*Resolver, repository.Repositories) {
+	t.Helper()
+	repos := repository.NewMemoryRepositories()
+	return NewResolver(repos, nil, nil, limits, nil, nil), repos
+}
+
+func createTestUser(t *testing.T, repos repository.Repositories, email string) *models.User {
+	t.Helper()
+	user, err := repos.Users.Create(context.Background(), repository.NewUser{Email: email, Name: email})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestCreateJobQuota(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{MaxQueuedJobs: 2, MaxJobsPerDay: 3})
+	user := createTestUser(t, repos, "ada@example.com")
+	create := func() error {
+		_, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := create(); err != nil {
+			t.Fatalf("job %d: %v", i+1, err)
+		}
+	}
+	var quotaErr *QuotaExceededError
+	if err := create(); !errors.As(err, &quotaErr) || quotaErr.Code != ErrCodeJobQueueLimit {
+		t.Fatalf("third queued job: error = %v, want %s", err, ErrCodeJobQueueLimit)
+	}
+
+	// Finished jobs free their queue slots, but the daily limit still counts them
+	finishAll := func() {
+		t.Helper()
+		jobs, err := repos.Jobs.List(ctx, &user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, job := range jobs {
+			if job.Status.IsTerminal() {
+				continue
+			}
+			for _, status := range []models.JobStatus{models.JobStatusInProgress, models.JobStatusCompleted} {
+				value := string(status)
+				if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &value}); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	finishAll()
+	if err := create(); err != nil {
+		t.Fatalf("job after the queue emptied: %v", err)
+	}
+	finishAll()
+	if err := create(); !errors.As(err, &quotaErr) || quotaErr.Code != ErrCodeDailyJobLimit {
+		t.Fatalf("fourth job of the day: error = %v, want %s", err, ErrCodeDailyJobLimit)
+	}
+
+	// An exempt user isn't limited
+	if err := repos.JobQuotas.Put(ctx, &models.JobQuota{UserID: user.ID, Exempt: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := create(); err != nil {
+		t.Errorf("exempt user: %v", err)
+	}
+}
+
+func TestCreateJobValidation(t *testing.T) {
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	priority := "URGENT"
+	tooFar := time.Now().Add(60 * 24 * time.Hour).Format(time.RFC3339)
+	notRFC3339 := "tomorrow"
+	newerInput := `{"schemaVersion": 99}`
+
+	tests := []struct {
+		name  string
+		input CreateJobInput
+	}{
+		{"unknown priority", CreateJobInput{Priority: &priority}},
+		{"schedule too far ahead", CreateJobInput{ScheduleAt: &tooFar}},
+		{"schedule not a time", CreateJobInput{ScheduleAt: &notRFC3339}},
+		{"invalid constraint", CreateJobInput{Constraints: []models.PlanningConstraint{{Type: models.ConstraintHomeBy}}}},
+		{"newer input schema", CreateJobInput{InputData: &newerInput}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.UserID = user.ID
+			tt.input.TargetDate = "2026-03-02"
+			if _, err := r.CreateJob(context.Background(), tt.input); err == nil {
+				t.Fatal("CreateJob() accepted invalid input")
+			}
+			jobs, _ := repos.Jobs.List(context.Background(), &user.ID)
+			if len(jobs) != 0 {
+				t.Errorf("invalid input created %d jobs", len(jobs))
+			}
+		})
+	}
+}
+
+func TestCreateJobInputVersion(t *testing.T) {
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	legacy := "plan around my dentist appointment"
+	job, err := r.CreateJob(context.Background(), CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02", InputData: &legacy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data struct {
+		SchemaVersion int    `json:"schemaVersion"`
+		RawInput      string `json:"raw_input"`
+	}
+	if err := json.Unmarshal([]byte(*job.InputData), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.SchemaVersion != jobinput.CurrentVersion || data.RawInput != legacy {
+		t.Errorf("inputData = %s, want the text upgraded to version %d", *job.InputData, jobinput.CurrentVersion)
+	}
+}
+
+func TestJobOwnership(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	other := createTestUser(t, repos, "bob@example.com")
+	job, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The job's owner and the worker's admin token read it; other users get not found
+	for _, viewer := range []authz.Viewer{{UserID: user.ID}, {Admin: true}} {
+		if _, err := r.Job(ctx, viewer, job.ID); err != nil {
+			t.Errorf("job read by %+v: %v", viewer, err)
+		}
+		if _, err := r.CommuteRecommendations(ctx, viewer, job.ID); err != nil {
+			t.Errorf("recommendations read by %+v: %v", viewer, err)
+		}
+		if _, err := r.JobEvents(ctx, viewer, job.ID); err != nil {
+			t.Errorf("events read by %+v: %v", viewer, err)
+		}
+	}
+	if jobs, err := r.Jobs(ctx, authz.Viewer{UserID: other.ID}, nil); err != nil || len(jobs) != 0 {
+		t.Errorf("other user's jobs = %v, %v; want none", jobs, err)
+	}
+	if _, err := r.Jobs(ctx, authz.Viewer{UserID: other.ID}, &user.ID); err == nil {
+		t.Error("jobs listed another user's jobs")
+	}
+	if jobs, err := r.Jobs(ctx, authz.Viewer{Admin: true}, &user.ID); err != nil || len(jobs) != 1 {
+		t.Errorf("jobs by the admin token = %v, %v; want the user's job", jobs, err)
+	}
+	if deleted, err := r.DeleteJob(ctx, other.ID, job.ID); err != nil || deleted {
+		t.Errorf("another user deleted the job: %v, %v", deleted, err)
+	}
+	for _, viewer := range []authz.Viewer{{UserID: other.ID}, {}} {
+		if _, err := r.Job(ctx, viewer, job.ID); err == nil || err.Error() != "job not found" {
+			t.Errorf("job read by %+v: err = %v, want job not found", viewer, err)
+		}
+		if _, err := r.CommuteRecommendations(ctx, viewer, job.ID); err == nil || err.Error() != "job not found" {
+			t.Errorf("recommendations read by %+v: err = %v, want job not found", viewer, err)
+		}
+		if _, err := r.JobEvents(ctx, viewer, job.ID); err == nil || err.Error() != "job not found" {
+			t.Errorf("events read by %+v: err = %v, want job not found", viewer, err)
+		}
+	}
+}
+
+func TestCalendarEventsOwnership(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	other := createTestUser(t, repos, "bob@example.com")
+
+	// A token of read:calendar only reads its own user's calendar
+	token := authz.Viewer{UserID: other.ID, Token: true, Scopes: []string{string(models.ScopeReadCalendar)}}
+	for _, viewer := range []authz.Viewer{token, {UserID: other.ID}, {}} {
+		if _, err := r.CalendarEvents(ctx, viewer, user.ID, nil); err == nil {
+			t.Errorf("calendar read by %+v", viewer)
+		}
+	}
+	for _, viewer := range []authz.Viewer{{UserID: user.ID}, {Admin: true}} {
+		if _, err := r.CalendarEvents(ctx, viewer, user.ID, nil); err != nil {
+			t.Errorf("calendar read by %+v: %v", viewer, err)
+		}
+	}
+}
+
+func TestUpdateJobConflict(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	job, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The worker and the API both read the job at its first version
+	read := job.Version
+	progress := 0.5
+	if _, err := r.UpdateJob(ctx, job.ID, UpdateJobInput{Progress: &progress, ExpectedVersion: &read}); err != nil {
+		t.Fatal(err)
+	}
+	status := string(models.JobStatusFailed)
+	_, err = r.UpdateJob(ctx, job.ID, UpdateJobInput{Status: &status, ExpectedVersion: &read})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Kind != "job" {
+		t.Fatalf("stale update error = %v, want a job ConflictError", err)
+	}
+	if current, _ := r.Job(ctx, authz.Viewer{Admin: true}, job.ID); current.Status != models.JobStatusPending || current.Version != read+1 {
+		t.Errorf("job = %s at version %d, want the first update only", current.Status, current.Version)
+	}
+}
+
+func TestJobTimeline(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	job, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inProgress, completed := string(models.JobStatusInProgress), string(models.JobStatusCompleted)
+	fetching, analyzing := "Fetching calendar", "Analyzing calendar"
+	half := 0.5
+	for _, update := range []UpdateJobInput{
+		{Status: &inProgress, CurrentStep: &fetching},
+		{Progress: &half},
+		{Status: &inProgress, CurrentStep: &fetching},
+		{CurrentStep: &analyzing},
+	} {
+		if _, err := r.UpdateJob(ctx, job.ID, update); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timeline, err := r.JobTimeline(ctx, authz.Viewer{UserID: user.ID}, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Repeating a step doesn't start a new entry
+	if len(timeline) != 3 || timeline[1].Step == nil || *timeline[1].Step != fetching || timeline[2].Step == nil || *timeline[2].Step != analyzing {
+		t.Fatalf("timeline = %+v, want PENDING, then the two steps", timeline)
+	}
+	if timeline[0].Status != models.JobStatusPending || timeline[0].EndedAt == nil || !timeline[0].EndedAt.Equal(timeline[1].StartedAt) {
+		t.Errorf("first entry = %+v, want PENDING until the job started", timeline[0])
+	}
+	if timeline[2].EndedAt != nil || timeline[2].DurationSeconds != nil {
+		t.Errorf("last entry = %+v, want it still going", timeline[2])
+	}
+
+	if _, err := r.UpdateJob(ctx, job.ID, UpdateJobInput{Status: &completed}); err != nil {
+		t.Fatal(err)
+	}
+	timeline, err = r.JobTimeline(ctx, authz.Viewer{UserID: user.ID}, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := timeline[len(timeline)-1]; last.Status != models.JobStatusCompleted || last.DurationSeconds == nil || *last.DurationSeconds != 0 {
+		t.Errorf("last entry = %+v, want the instant the job completed", last)
+	}
+	if timeline[2].EndedAt == nil {
+		t.Errorf("analyzing entry = %+v, want it ended by completion", timeline[2])
+	}
+}
+
+func TestJobProgressEstimate(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+	inProgress, completed := string(models.JobStatusInProgress), string(models.JobStatusCompleted)
+	fetching, analyzing := "Fetching calendar", "Analyzing calendar"
+	reported := 0.9
+	run := func(updates ...UpdateJobInput) *models.Job {
+		t.Helper()
+		job, err := r.CreateJob(ctx, CreateJobInput{UserID: user.ID, TargetDate: "2026-03-02"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, update := range updates {
+			if _, err := r.UpdateJob(ctx, job.ID, update); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return job
+	}
+
+	running := run(UpdateJobInput{Status: &inProgress, CurrentStep: &fetching, Progress: &reported})
+	if job, err := r.Job(ctx, authz.Viewer{UserID: user.ID}, running.ID); err != nil || job.EstimatedCompletionAt != nil || job.Progress != reported {
+		t.Fatalf("job = %+v, %v; want the worker's progress without completed jobs to learn from", job, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		run(UpdateJobInput{Status: &inProgress, CurrentStep: &fetching}, UpdateJobInput{CurrentStep: &analyzing}, UpdateJobInput{Status: &completed})
+	}
+	r.progressModel.learnedAt = time.Time{}
+	job, err := r.Job(ctx, authz.Viewer{UserID: user.ID}, running.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.EstimatedCompletionAt == nil || job.Progress < 0 || job.Progress > 1 {
+		t.Errorf("job = %+v, want an estimate learned from the completed jobs", job)
+	}
+}
@@ -0,0 +1,218 @@
+package resolvers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/scim"
+)
+
+// SCIMTokenPrefix starts every SCIM token
+const SCIMTokenPrefix = "cscim_"
+
+// Errors of SCIM provisioning
+var (
+	ErrSCIMTokenInvalid = errors.New("invalid SCIM token")
+	ErrUserExists       = errors.New("a user with this email already exists")
+	// ErrSCIMFilterUnsupported is returned for filters on attributes users can't be
+	// looked up by
+	ErrSCIMFilterUnsupported = errors.New("users can only be filtered by userName or externalId")
+	// ErrProvisionedUserInvalid wraps why the identity provider's description of a user
+	// can't be saved
+	ErrProvisionedUserInvalid = errors.New("invalid user")
+)
+
+// ProvisionedUser is a user as a tenant's identity provider describes them
+type ProvisionedUser struct {
+	Email      string
+	Name       string
+	ExternalID *string
+	Active     bool
+	// AuthProvider is how users created by provisioning sign in
+	AuthProvider string
+}
+
+// RotateSCIMToken gives a tenant a new SCIM token for its identity provider, replacing
+// any it had. Only a hash of the token is kept, so it is only returned here.
+func (r *Resolver) RotateSCIMToken(ctx context.Context, tenantID string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating SCIM token: %w", err)
+	}
+	token := SCIMTokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	hash := scimTokenHash(token)
+	if err := r.tenants.SetSCIMTokenHash(ctx, tenantID, &hash); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", fmt.Errorf("tenant %q doesn't exist", tenantID)
+		}
+		return "", fmt.Errorf("error saving SCIM token: %w", err)
+	}
+	return token, nil
+}
+
+// DisableSCIM removes a tenant's SCIM token, stopping provisioning
+func (r *Resolver) DisableSCIM(ctx context.Context, tenantID string) error {
+	if err := r.tenants.SetSCIMTokenHash(ctx, tenantID, nil); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("tenant %q doesn't exist", tenantID)
+		}
+		return fmt.Errorf("error removing SCIM token: %w", err)
+	}
+	return nil
+}
+
+// SCIMTenant returns the tenant a SCIM token belongs to, or ErrSCIMTokenInvalid
+func (r *Resolver) SCIMTenant(ctx context.Context, token string) (*models.Tenant, error) {
+	if !strings.HasPrefix(token, SCIMTokenPrefix) {
+		return nil, ErrSCIMTokenInvalid
+	}
+	t, err := r.tenants.GetBySCIMTokenHash(ctx, scimTokenHash(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSCIMTokenInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching tenant: %w", err)
+	}
+	return t, nil
+}
+
+// ProvisionedUsers returns up to limit of the users of the tenant ctx is scoped to
+// matching filter, or of all of them with a nil filter, skipping the first offset, along
+// with how many match in all. Users are matched by userName (their email) or externalId;
+// other filters fail with ErrSCIMFilterUnsupported.
+func (r *Resolver) ProvisionedUsers(ctx context.Context, filter *scim.Filter, offset, limit int) ([]*models.User, int, error) {
+	if filter != nil && filter.Attribute == "username" {
+		user, err := r.users.GetByEmail(ctx, filter.Value)
+		if errors.Is(err, repository.ErrNotFound) {
+			return []*models.User{}, 0, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("error fetching user: %w", err)
+		}
+		if offset > 0 || limit < 1 {
+			return []*models.User{}, 1, nil
+		}
+		return []*models.User{user}, 1, nil
+	}
+	if filter != nil && filter.Attribute != "externalid" {
+		return nil, 0, ErrSCIMFilterUnsupported
+	}
+
+	page := repository.UserFilter{Offset: offset, Limit: limit}
+	if filter != nil {
+		page.SCIMExternalID = &filter.Value
+	}
+	users, total, err := r.users.ListPage(ctx, page)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching users: %w", err)
+	}
+	return users, total, nil
+}
+
+// ProvisionedUser returns one of the users of the tenant ctx is scoped to, or
+// repository.ErrNotFound
+func (r *Resolver) ProvisionedUser(ctx context.Context, id string) (*models.User, error) {
+	user, err := r.users.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	return user, nil
+}
+
+// ProvisionUser creates a user in the tenant ctx is scoped to, or fails with
+// ErrUserExists when one has the email
+func (r *Resolver) ProvisionUser(ctx context.Context, input ProvisionedUser) (*models.User, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+	if _, err := r.users.GetByEmail(ctx, input.Email); err == nil {
+		return nil, ErrUserExists
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+
+	authProvider := input.AuthProvider
+	user, err := r.users.Create(ctx, repository.NewUser{
+		Email:          input.Email,
+		Name:           input.Name,
+		AuthProvider:   &authProvider,
+		SCIMExternalID: input.ExternalID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating user: %w", err)
+	}
+	if !input.Active {
+		return r.setUserActive(ctx, user.ID, false)
+	}
+	return user, nil
+}
+
+// UpdateProvisionedUser replaces the provisioned attributes of one of the tenant's
+// users: their email, name, external ID and whether they are active. Deactivated users
+// can't sign in, and their sessions and API tokens stop working, but their data is kept.
+func (r *Resolver) UpdateProvisionedUser(ctx context.Context, id string, input ProvisionedUser) (*models.User, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+	user, err := r.users.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if input.Email != user.Email {
+		if _, err := r.users.GetByEmail(ctx, input.Email); err == nil {
+			return nil, ErrUserExists
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("error fetching user: %w", err)
+		}
+	}
+
+	externalID := ""
+	if input.ExternalID != nil {
+		externalID = *input.ExternalID
+	}
+	user, err = r.users.Update(ctx, id, repository.UserUpdate{Email: &input.Email, Name: &input.Name, SCIMExternalID: &externalID})
+	if err != nil {
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	if input.Active != (user.DeactivatedAt == nil) {
+		return r.setUserActive(ctx, id, input.Active)
+	}
+	return user, nil
+}
+
+func (r *Resolver) setUserActive(ctx context.Context, id string, active bool) (*models.User, error) {
+	user, err := r.users.SetActive(ctx, id, active)
+	if err != nil {
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	return user, nil
+}
+
+func (p ProvisionedUser) validate() error {
+	if !strings.Contains(p.Email, "@") {
+		return fmt.Errorf("%w: userName or a primary email must be an email address", ErrProvisionedUserInvalid)
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("%w: a name is required", ErrProvisionedUserInvalid)
+	}
+	return nil
+}
+
+func scimTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,94 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/scim"
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+func TestSCIMProvisioning(t *testing.T) {
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	if err := repos.Tenants.Put(context.Background(), &models.Tenant{ID: "acme", Name: "Acme"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	token, err := r.RotateSCIMToken(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, err := r.SCIMTenant(ctx, token); err != nil || found.ID != "acme" || !found.SCIMEnabled {
+		t.Fatalf("tenant = %+v, %v; want acme with SCIM enabled", found, err)
+	}
+	rotated, err := r.RotateSCIMToken(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SCIMTenant(ctx, token); !errors.Is(err, ErrSCIMTokenInvalid) {
+		t.Errorf("rotated token: error = %v, want ErrSCIMTokenInvalid", err)
+	}
+
+	externalID := "00u1"
+	input := ProvisionedUser{Email: "ada@acme.com", Name: "Ada Lovelace", ExternalID: &externalID, Active: true, AuthProvider: "okta"}
+	user, err := r.ProvisionUser(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.TenantID != "acme" || *user.AuthProvider != "okta" || user.DeactivatedAt != nil {
+		t.Fatalf("user = %+v, want an active okta user of acme", user)
+	}
+	if _, err := r.ProvisionUser(ctx, input); !errors.Is(err, ErrUserExists) {
+		t.Errorf("duplicate: error = %v, want ErrUserExists", err)
+	}
+
+	found, total, err := r.ProvisionedUsers(ctx, &scim.Filter{Attribute: "externalid", Value: externalID}, 0, 10)
+	if err != nil || total != 1 || len(found) != 1 || found[0].ID != user.ID {
+		t.Errorf("users with external ID = %v (of %d), %v; want %s", found, total, err, user.ID)
+	}
+	if _, _, err := r.ProvisionedUsers(ctx, &scim.Filter{Attribute: "displayname", Value: "Ada"}, 0, 10); !errors.Is(err, ErrSCIMFilterUnsupported) {
+		t.Errorf("displayName filter: error = %v, want ErrSCIMFilterUnsupported", err)
+	}
+	if _, err := r.ProvisionUser(ctx, ProvisionedUser{Email: "grace@acme.com", Active: true}); !errors.Is(err, ErrProvisionedUserInvalid) {
+		t.Errorf("nameless user: error = %v, want ErrProvisionedUserInvalid", err)
+	}
+
+	// Pages are cut by the repository; the total counts every match
+	grace, err := r.ProvisionUser(ctx, ProvisionedUser{Email: "grace@acme.com", Name: "Grace Hopper", Active: true, AuthProvider: "okta"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, total, err := r.ProvisionedUsers(ctx, nil, 1, 1)
+	if err != nil || total != 2 || len(page) != 1 {
+		t.Fatalf("second page = %v (of %d), %v; want 1 of 2 users", page, total, err)
+	}
+	first, _, _ := r.ProvisionedUsers(ctx, nil, 0, 1)
+	if len(first) != 1 || first[0].ID == page[0].ID || (first[0].ID != user.ID && first[0].ID != grace.ID) {
+		t.Errorf("pages = %v and %v, want each of the users once", first, page)
+	}
+
+	// Deprovisioning keeps the user, deactivated, until the identity provider reactivates them
+	input.Active = false
+	input.Name = "Ada King"
+	user, err = r.UpdateProvisionedUser(ctx, user.ID, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.DeactivatedAt == nil || user.Name != "Ada King" {
+		t.Errorf("user = %+v, want deactivated and renamed", user)
+	}
+	input.Active = true
+	if user, err = r.UpdateProvisionedUser(ctx, user.ID, input); err != nil || user.DeactivatedAt != nil {
+		t.Errorf("reactivated user = %+v, %v", user, err)
+	}
+
+	if err := r.DisableSCIM(ctx, "acme"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SCIMTenant(ctx, rotated); !errors.Is(err, ErrSCIMTokenInvalid) {
+		t.Errorf("disabled: error = %v, want ErrSCIMTokenInvalid", err)
+	}
+}
@@ -0,0 +1,182 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/redact"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// ShareLinkSigner signs and verifies share link URLs; implemented by sharelink.Signer
+type ShareLinkSigner interface {
+	URL(id string, expires time.Time) string
+	Verify(id, expires, signature string, now time.Time) error
+}
+
+// Errors of share links
+var (
+	ErrShareLinksDisabled = errors.New("share links are not configured")
+	// ErrShareLinkInvalid doesn't say why a link was refused, so links can't be probed
+	ErrShareLinkInvalid = errors.New("share link is invalid, expired or revoked")
+)
+
+const (
+	defaultShareLinkTTL = 7 * 24 * time.Hour
+	maxShareLinkTTL     = 90 * 24 * time.Hour
+)
+
+// SharedPlan is what a share link shows: a job's recommendations, without the job's
+// input or its user, redacted as the user chose
+type SharedPlan struct {
+	TargetDate      string                          `json:"targetDate"`
+	Status          models.JobStatus                `json:"status"`
+	Recommendations []*models.CommuteRecommendation `json:"recommendations"`
+	Redaction       models.ShareRedaction           `json:"redaction"`
+	ExpiresAt       time.Time                       `json:"expiresAt"`
+}
+
+// ShareLinksWith lets users share read-only links to their plans, signed by signer and
+// valid for defaultTTL unless they ask otherwise. Without it links can't be created.
+func (r *Resolver) ShareLinksWith(signer ShareLinkSigner, defaultTTL time.Duration) {
+	if defaultTTL <= 0 || defaultTTL > maxShareLinkTTL {
+		defaultTTL = defaultShareLinkTTL
+	}
+	r.shareLinkSigner, r.shareLinkTTL = signer, defaultTTL
+}
+
+// CreateShareLink creates a link to one of the user's jobs valid for ttl seconds, or the
+// default when nil
+func (r *Resolver) CreateShareLink(ctx context.Context, userID, jobID string, ttl *int) (*models.ShareLink, error) {
+	if r.shareLinkSigner == nil {
+		return nil, ErrShareLinksDisabled
+	}
+	valid := r.shareLinkTTL
+	if ttl != nil {
+		valid = time.Duration(*ttl) * time.Second
+		if valid < time.Minute || valid > maxShareLinkTTL {
+			return nil, fmt.Errorf("ttl must be between 60 and %d seconds", int(maxShareLinkTTL.Seconds()))
+		}
+	}
+	job, err := r.userJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	link := &models.ShareLink{JobID: job.ID, UserID: userID, ExpiresAt: time.Now().Add(valid).Truncate(time.Second)}
+	if err := r.shareLinks.Create(ctx, link); err != nil {
+		return nil, fmt.Errorf("error saving share link: %w", err)
+	}
+	link.URL = r.shareLinkSigner.URL(link.ID, link.ExpiresAt)
+	return link, nil
+}
+
+// ShareLinks returns the links to one of the user's jobs, newest first, including
+// expired and revoked ones
+func (r *Resolver) ShareLinks(ctx context.Context, userID, jobID string) ([]*models.ShareLink, error) {
+	job, err := r.userJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	links, err := r.shareLinks.ListByJob(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching share links: %w", err)
+	}
+	if r.shareLinkSigner != nil {
+		for _, link := range links {
+			link.URL = r.shareLinkSigner.URL(link.ID, link.ExpiresAt)
+		}
+	}
+	return links, nil
+}
+
+// RevokeShareLink ends one of the user's links before it expires
+func (r *Resolver) RevokeShareLink(ctx context.Context, userID, id string) (bool, error) {
+	revoked, err := r.shareLinks.Revoke(ctx, userID, id)
+	if err != nil {
+		return false, fmt.Errorf("error revoking share link: %w", err)
+	}
+	return revoked, nil
+}
+
+// SharedPlan returns the plan of link id for anyone holding its signed URL, while the
+// link is neither expired nor revoked
+func (r *Resolver) SharedPlan(ctx context.Context, id, expires, signature string) (*SharedPlan, error) {
+	if r.shareLinkSigner == nil {
+		return nil, ErrShareLinksDisabled
+	}
+	now := time.Now()
+	if err := r.shareLinkSigner.Verify(id, expires, signature, now); err != nil {
+		return nil, ErrShareLinkInvalid
+	}
+	link, err := r.shareLinks.Get(ctx, id)
+	if err == repository.ErrNotFound {
+		return nil, ErrShareLinkInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching share link: %w", err)
+	}
+	if !link.Active(now) {
+		return nil, ErrShareLinkInvalid
+	}
+	job, err := r.userJob(ctx, link.UserID, link.JobID)
+	if err != nil {
+		return nil, ErrShareLinkInvalid
+	}
+
+	recommendations, err := r.jobRecommendations(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range recommendations {
+		rec.Job = nil
+	}
+	if recommendations == nil {
+		recommendations = []*models.CommuteRecommendation{}
+	}
+	// The user's current policy applies, so tightening it covers links already handed out
+	user, err := r.users.Get(ctx, link.UserID)
+	if err != nil {
+		return nil, ErrShareLinkInvalid
+	}
+	redaction := models.ShareRedactionMeetingDetails
+	if user.ShareRedaction != nil {
+		redaction = *user.ShareRedaction
+	}
+	redact.Recommendations(&redaction, recommendations)
+	return &SharedPlan{TargetDate: job.TargetDate, Status: job.Status, Recommendations: recommendations, Redaction: redaction, ExpiresAt: link.ExpiresAt}, nil
+}
+
+// SetShareRedaction sets what the user's share links leave out of their plans, including
+// links already created; nil returns to the default, MEETING_DETAILS
+func (r *Resolver) SetShareRedaction(ctx context.Context, userID string, policy *models.ShareRedaction) (*models.User, error) {
+	if policy != nil {
+		switch *policy {
+		case models.ShareRedactionNone, models.ShareRedactionMeetingDetails, models.ShareRedactionTimeBlocks:
+		default:
+			return nil, fmt.Errorf("invalid shareRedaction %q: expected NONE, MEETING_DETAILS or TIME_BLOCKS", *policy)
+		}
+	}
+	user, err := r.users.SetShareRedaction(ctx, userID, policy)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("user not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	return user, nil
+}
+
+// userJob returns one of the user's jobs; another user's job is not found
+func (r *Resolver) userJob(ctx context.Context, userID, jobID string) (*models.Job, error) {
+	job, err := r.jobs.Get(ctx, jobID)
+	if err == repository.ErrNotFound || (err == nil && job.UserID != userID) {
+		return nil, fmt.Errorf("job not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching job: %w", err)
+	}
+	return job, nil
+}
@@ -0,0 +1,109 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/sharelink"
+)
+
+func TestShareLinks(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	ada := createTestUser(t, repos, "ada@example.com")
+	bob := createTestUser(t, repos, "bob@example.com")
+	job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: ada.ID, TargetDate: "2026-03-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasoning := "Go in for the board review"
+	if err := repos.Recommendations.Create(ctx, &models.CommuteRecommendation{JobID: job.ID, OptionRank: 1, OptionType: models.CommuteOptionFullDayOffice, Reasoning: &reasoning}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.CreateShareLink(ctx, ada.ID, job.ID, nil); !errors.Is(err, ErrShareLinksDisabled) {
+		t.Fatalf("link without a signer: err = %v", err)
+	}
+	signer, err := sharelink.NewSigner(strings.Repeat("s", 32), "https://api.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.ShareLinksWith(signer, 0)
+
+	if _, err := r.CreateShareLink(ctx, bob.ID, job.ID, nil); err == nil {
+		t.Error("link to another user's job created")
+	}
+	tooLong := int((100 * 24 * time.Hour).Seconds())
+	if _, err := r.CreateShareLink(ctx, ada.ID, job.ID, &tooLong); err == nil {
+		t.Error("ttl past the maximum accepted")
+	}
+	link, err := r.CreateShareLink(ctx, ada.ID, job.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(link.ExpiresAt); until < 6*24*time.Hour || until > 7*24*time.Hour {
+		t.Errorf("link expires in %s, want the default 7 days", until)
+	}
+
+	shared, err := url.Parse(link.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strings.TrimPrefix(shared.Path, sharelink.Path)
+	expires, signature := shared.Query().Get("expires"), shared.Query().Get("signature")
+	plan, err := r.SharedPlan(ctx, id, expires, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.TargetDate != "2026-03-02" || len(plan.Recommendations) != 1 || plan.Recommendations[0].Job != nil {
+		t.Errorf("shared plan = %+v", plan)
+	}
+	if plan.Redaction != models.ShareRedactionMeetingDetails || plan.Recommendations[0].Reasoning != nil {
+		t.Errorf("shared plan redacted as %s with reasoning %v, want meeting details stripped by default", plan.Redaction, plan.Recommendations[0].Reasoning)
+	}
+
+	// The user's policy applies to links already handed out
+	invalid := models.ShareRedaction("EVERYTHING")
+	if _, err := r.SetShareRedaction(ctx, ada.ID, &invalid); err == nil {
+		t.Error("invalid share redaction accepted")
+	}
+	none := models.ShareRedactionNone
+	if _, err := r.SetShareRedaction(ctx, ada.ID, &none); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err = r.SharedPlan(ctx, id, expires, signature); err != nil || plan.Recommendations[0].Reasoning == nil {
+		t.Errorf("shared plan with NONE = %+v, %v; want the reasoning kept", plan, err)
+	}
+	blocks := models.ShareRedactionTimeBlocks
+	if _, err := r.SetShareRedaction(ctx, ada.ID, &blocks); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err = r.SharedPlan(ctx, id, expires, signature); err != nil || plan.Redaction != blocks || plan.Recommendations[0].ReasonCodes != nil {
+		t.Errorf("shared plan with TIME_BLOCKS = %+v, %v", plan, err)
+	}
+	if _, err := r.SharedPlan(ctx, id, expires, "forged"); !errors.Is(err, ErrShareLinkInvalid) {
+		t.Errorf("forged signature: err = %v", err)
+	}
+
+	links, err := r.ShareLinks(ctx, ada.ID, job.ID)
+	if err != nil || len(links) != 1 || links[0].URL != link.URL {
+		t.Errorf("links = %+v, %v", links, err)
+	}
+
+	// Revoking a link stops it working before it expires
+	if revoked, _ := r.RevokeShareLink(ctx, bob.ID, link.ID); revoked {
+		t.Error("another user revoked the link")
+	}
+	if revoked, err := r.RevokeShareLink(ctx, ada.ID, link.ID); err != nil || !revoked {
+		t.Fatalf("revoke = %v, %v", revoked, err)
+	}
+	if _, err := r.SharedPlan(ctx, id, expires, signature); !errors.Is(err, ErrShareLinkInvalid) {
+		t.Errorf("revoked link: err = %v", err)
+	}
+}
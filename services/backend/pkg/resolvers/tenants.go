@@ -0,0 +1,37 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/tenant"
+)
+
+// Tenants lists the tenants of the deployment
+func (r *Resolver) Tenants(ctx context.Context) ([]*models.Tenant, error) {
+	tenants, err := r.tenants.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching tenants: %w", err)
+	}
+	return tenants, nil
+}
+
+// PutTenant creates a tenant or renames an existing one. The ID is also the tenant's
+// subdomain, so it must be a valid DNS label.
+func (r *Resolver) PutTenant(ctx context.Context, id, name string) (*models.Tenant, error) {
+	if !tenant.ValidID(id) {
+		return nil, fmt.Errorf("tenant ID must be a lowercase DNS label (letters, digits and hyphens)")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("tenant name is required")
+	}
+
+	t := &models.Tenant{ID: id, Name: name}
+	if err := r.tenants.Put(ctx, t); err != nil {
+		return nil, fmt.Errorf("error saving tenant: %w", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,103 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/allday"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Today returns the user's day at a glance: the latest job planning today, the plan the
+// weekly digest would show for it, when to leave next and the meetings that need the user
+// in the office
+func (r *Resolver) Today(ctx context.Context, userID string) (*models.Today, error) {
+	location, err := r.userLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	local := now.In(location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	today := &models.Today{Date: day.Format("2006-01-02"), InPersonMeetings: []models.TodayMeeting{}}
+
+	jobs, err := r.jobs.List(ctx, &userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching jobs: %w", err)
+	}
+	var latest *models.Job
+	for _, job := range jobs {
+		if len(job.TargetDate) >= 10 && job.TargetDate[:10] == today.Date && (latest == nil || job.CreatedAt.After(latest.CreatedAt)) {
+			latest = job
+		}
+	}
+	if latest != nil {
+		today.Job = &models.TodayJob{
+			ID:          latest.ID,
+			Status:      latest.Status,
+			Progress:    latest.Progress,
+			CurrentStep: latest.CurrentStep,
+			UpdatedAt:   latest.UpdatedAt,
+		}
+	}
+
+	plans, err := r.weekPlans(ctx, userID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	if plan := plans[today.Date]; plan != nil {
+		today.Plan = &models.TodayPlan{
+			RecommendationID: plan.ID,
+			OptionType:       plan.OptionType,
+			Accepted:         plan.AcceptedAt != nil,
+			InOffice:         plan.CommuteStart != nil,
+			TravelMode:       plan.TravelMode,
+			CommuteStart:     plan.CommuteStart,
+			OfficeArrival:    plan.OfficeArrival,
+			OfficeDeparture:  plan.OfficeDeparture,
+			CommuteEnd:       plan.CommuteEnd,
+		}
+		today.NextDeparture = nextDeparture(plan, now)
+	}
+
+	// Meetings starting during the user's local day
+	from := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	to := from.AddDate(0, 0, 1)
+	err = r.events.Stream(ctx, userID, repository.DateRange{From: &from, To: &to}, func(event *models.CalendarEvent) error {
+		if event.AttendanceMode == models.AttendanceMustBeInOffice && allday.Busy(event) {
+			today.InPersonMeetings = append(today.InPersonMeetings, models.TodayMeeting{
+				ID:        event.ID,
+				Summary:   event.Summary,
+				StartTime: event.StartTime,
+				EndTime:   event.EndTime,
+				Location:  event.Location,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching calendar events: %w", err)
+	}
+	return today, nil
+}
+
+// nextDeparture returns the first time after now the plan has the user leave: the start of
+// one of its travel legs, or without legs its trips to and from the office
+func nextDeparture(plan *models.CommuteRecommendation, now time.Time) *time.Time {
+	var departures []*time.Time
+	for _, leg := range plan.DecodeTravelLegs() {
+		departures = append(departures, leg.Depart)
+	}
+	if len(departures) == 0 {
+		departures = []*time.Time{plan.CommuteStart, plan.OfficeDeparture}
+	}
+	var next *time.Time
+	for _, at := range departures {
+		if at != nil && at.After(now) && (next == nil || at.Before(*next)) {
+			next = at
+		}
+	}
+	return next
+}
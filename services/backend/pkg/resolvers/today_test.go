@@ -0,0 +1,76 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/google/uuid"
+)
+
+func TestToday(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+
+	today, err := r.Today(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if today.Job != nil || today.Plan != nil || today.NextDeparture != nil || len(today.InPersonMeetings) != 0 {
+		t.Errorf("unplanned day = %+v", today)
+	}
+
+	// Planned this morning: the commute home is still ahead
+	now := time.Now().UTC()
+	date := now.Format("2006-01-02")
+	completed, inProgress := string(models.JobStatusCompleted), string(models.JobStatusInProgress)
+	job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range []*string{&inProgress, &completed} {
+		if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	leave, home := now.Add(-2*time.Hour), now.Add(3*time.Hour)
+	arrive, back := leave.Add(45*time.Minute), home.Add(45*time.Minute)
+	rec := &models.CommuteRecommendation{
+		JobID: job.ID, OptionRank: 1, OptionType: models.CommuteOptionFullDayOffice,
+		CommuteStart: &leave, OfficeArrival: &arrive, OfficeDeparture: &home, CommuteEnd: &back,
+	}
+	if err := repos.Recommendations.Create(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	// A re-plan in progress is the live status
+	replan, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Events.Create(ctx, &models.CalendarEvent{
+		ID: uuid.New().String(), UserID: user.ID, Summary: "Design review", StartTime: now, EndTime: now.Add(time.Hour),
+		AttendanceMode: models.AttendanceMustBeInOffice,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	today, err = r.Today(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if today.Job == nil || today.Job.ID != replan.ID || today.Job.Status != models.JobStatusPending {
+		t.Errorf("job = %+v, want the pending re-plan", today.Job)
+	}
+	if today.Plan == nil || today.Plan.RecommendationID != rec.ID || !today.Plan.InOffice || today.Plan.Accepted {
+		t.Errorf("plan = %+v", today.Plan)
+	}
+	if today.NextDeparture == nil || !today.NextDeparture.Equal(home) {
+		t.Errorf("next departure = %v, want %v", today.NextDeparture, home)
+	}
+	if len(today.InPersonMeetings) != 1 || today.InPersonMeetings[0].Summary != "Design review" {
+		t.Errorf("in-person meetings = %+v", today.InPersonMeetings)
+	}
+}
@@ -0,0 +1,113 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// TravelProfileInput replaces a user's travel profile. Empty modes allow every mode.
+type TravelProfileInput struct {
+	Modes          []models.TravelMode `json:"modes"`
+	PreferredMode  *models.TravelMode  `json:"preferredMode"`
+	MaxBikeMinutes *int                `json:"maxBikeMinutes"`
+	MaxWalkMinutes *int                `json:"maxWalkMinutes"`
+	ParkingCost    *float64            `json:"parkingCost"`
+	TransitFare    *float64            `json:"transitFare"`
+	FuelCostPerKm  *float64            `json:"fuelCostPerKm"`
+}
+
+func (input TravelProfileInput) validate() error {
+	seen := map[models.TravelMode]bool{}
+	for _, mode := range input.Modes {
+		if !isTravelMode(mode) {
+			return fmt.Errorf("unknown travel mode %q", mode)
+		}
+		if seen[mode] {
+			return fmt.Errorf("travel mode %q is listed twice", mode)
+		}
+		seen[mode] = true
+	}
+	if input.PreferredMode != nil {
+		if !isTravelMode(*input.PreferredMode) {
+			return fmt.Errorf("unknown travel mode %q", *input.PreferredMode)
+		}
+		if len(input.Modes) > 0 && !seen[*input.PreferredMode] {
+			return fmt.Errorf("preferred mode %q isn't one of the modes", *input.PreferredMode)
+		}
+	}
+	if (input.MaxBikeMinutes != nil && *input.MaxBikeMinutes <= 0) || (input.MaxWalkMinutes != nil && *input.MaxWalkMinutes <= 0) {
+		return fmt.Errorf("maximum bike and walk minutes must be positive")
+	}
+	if (input.ParkingCost != nil && *input.ParkingCost < 0) || (input.TransitFare != nil && *input.TransitFare < 0) ||
+		(input.FuelCostPerKm != nil && *input.FuelCostPerKm < 0) {
+		return fmt.Errorf("costs can't be negative")
+	}
+	return nil
+}
+
+func isTravelMode(mode models.TravelMode) bool {
+	for _, known := range models.TravelModes {
+		if mode == known {
+			return true
+		}
+	}
+	return false
+}
+
+// TravelProfile returns a user's travel profile; users who haven't set one get the
+// default of every mode with no preference
+func (r *Resolver) TravelProfile(ctx context.Context, userID string) (*models.TravelProfile, error) {
+	if _, err := r.users.Get(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+
+	profile, err := r.travelProfiles.Get(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &models.TravelProfile{UserID: userID, Modes: append([]models.TravelMode{}, models.TravelModes...)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching travel profile: %w", err)
+	}
+	return profile, nil
+}
+
+// SetTravelProfile creates or replaces a user's travel profile
+func (r *Resolver) SetTravelProfile(ctx context.Context, userID string, input TravelProfileInput) (*models.TravelProfile, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+	if _, err := r.users.Get(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+
+	now := time.Now().UTC()
+	profile := &models.TravelProfile{
+		UserID:         userID,
+		Modes:          input.Modes,
+		PreferredMode:  input.PreferredMode,
+		MaxBikeMinutes: input.MaxBikeMinutes,
+		MaxWalkMinutes: input.MaxWalkMinutes,
+		ParkingCost:    input.ParkingCost,
+		TransitFare:    input.TransitFare,
+		FuelCostPerKm:  input.FuelCostPerKm,
+		UpdatedAt:      &now,
+	}
+	if len(profile.Modes) == 0 {
+		profile.Modes = append([]models.TravelMode{}, models.TravelModes...)
+	}
+	if err := r.travelProfiles.Put(ctx, profile); err != nil {
+		return nil, fmt.Errorf("error saving travel profile: %w", err)
+	}
+	return profile, nil
+}
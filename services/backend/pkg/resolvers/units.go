@@ -0,0 +1,40 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/units"
+)
+
+// SetDisplayUnits sets how the user's digest emails, reminders and calendar feed show
+// distances, costs and times. Each nil preference returns to its default: kilometres,
+// amounts without a currency and 24-hour times.
+func (r *Resolver) SetDisplayUnits(ctx context.Context, userID string, distanceUnit *models.DistanceUnit, currency *string, timeFormat *models.TimeFormat) (*models.User, error) {
+	if distanceUnit != nil && *distanceUnit != models.DistanceUnitKm && *distanceUnit != models.DistanceUnitMi {
+		return nil, fmt.Errorf("invalid distanceUnit %q: expected KM or MI", *distanceUnit)
+	}
+	if timeFormat != nil && *timeFormat != models.TimeFormat24Hour && *timeFormat != models.TimeFormat12Hour {
+		return nil, fmt.Errorf("invalid timeFormat %q: expected TWENTY_FOUR_HOUR or TWELVE_HOUR", *timeFormat)
+	}
+	if currency != nil && *currency == "" {
+		currency = nil
+	}
+	if currency != nil {
+		code, err := units.ParseCurrency(*currency)
+		if err != nil {
+			return nil, err
+		}
+		currency = &code
+	}
+	user, err := r.users.SetDisplayUnits(ctx, userID, distanceUnit, currency, timeFormat)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("user not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	return user, nil
+}
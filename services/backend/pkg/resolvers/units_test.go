@@ -0,0 +1,48 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestSetDisplayUnits(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	user := createTestUser(t, repos, "ada@example.com")
+
+	mi, twelve, usd := models.DistanceUnitMi, models.TimeFormat12Hour, "usd"
+	updated, err := r.SetDisplayUnits(ctx, user.ID, &mi, &usd, &twelve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *updated.DistanceUnit != mi || *updated.Currency != "USD" || *updated.TimeFormat != twelve {
+		t.Errorf("user = %+v, want miles, USD and 12-hour times", updated)
+	}
+
+	// Nil and empty preferences return to the defaults
+	empty := ""
+	if updated, err := r.SetDisplayUnits(ctx, user.ID, nil, &empty, nil); err != nil || updated.DistanceUnit != nil || updated.Currency != nil || updated.TimeFormat != nil {
+		t.Errorf("cleared user = %+v, %v", updated, err)
+	}
+
+	furlong, sundial, euro := models.DistanceUnit("FURLONG"), models.TimeFormat("SUNDIAL"), "EURO"
+	for _, tt := range []struct {
+		name         string
+		distanceUnit *models.DistanceUnit
+		currency     *string
+		timeFormat   *models.TimeFormat
+	}{
+		{"unknown distance unit", &furlong, nil, nil},
+		{"invalid currency", nil, &euro, nil},
+		{"unknown time format", nil, nil, &sundial},
+	} {
+		if _, err := r.SetDisplayUnits(ctx, user.ID, tt.distanceUnit, tt.currency, tt.timeFormat); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+	if _, err := r.SetDisplayUnits(ctx, "missing", nil, nil, nil); err == nil {
+		t.Error("updated a missing user")
+	}
+}
@@ -0,0 +1,167 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/analytics"
+	"github.com/commute-planner/backend/pkg/carbon"
+	"github.com/commute-planner/backend/pkg/costs"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/commute-planner/backend/pkg/webhooks"
+	"github.com/google/uuid"
+)
+
+// WebhookPublisher queues webhook events; satisfied by *webhooks.Dispatcher
+type WebhookPublisher interface {
+	Publish(ctx context.Context, userID, eventType string, data interface{}) error
+	AllowInsecureURLs() bool
+}
+
+// Delivery log page size
+const (
+	defaultWebhookDeliveries = 50
+	maxWebhookDeliveries     = 200
+)
+
+// publish queues a webhook event. Failures are logged rather than failing the mutation
+// that triggered the event.
+func (r *Resolver) publish(ctx context.Context, userID, eventType string, data interface{}) {
+	if r.publisher == nil {
+		return
+	}
+	if err := r.publisher.Publish(ctx, userID, eventType, data); err != nil {
+		log.Printf("Failed to publish %s webhook for user %s: %v", eventType, userID, err)
+	}
+}
+
+func isTerminalStatus(status models.JobStatus) bool {
+	return status == models.JobStatusCompleted || status == models.JobStatusFailed
+}
+
+// CreateWebhookEndpointInput registers an endpoint for the signed-in user
+type CreateWebhookEndpointInput struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"` // empty subscribes to every event
+	// Secret is generated when omitted
+	Secret *string `json:"secret"`
+}
+
+// CreateWebhookEndpoint registers an endpoint. The returned endpoint is the only place the
+// secret is exposed.
+func (r *Resolver) CreateWebhookEndpoint(ctx context.Context, userID string, input CreateWebhookEndpointInput) (*models.WebhookEndpoint, error) {
+	allowInsecure := r.publisher != nil && r.publisher.AllowInsecureURLs()
+	if err := webhooks.ValidateURL(input.URL, allowInsecure); err != nil {
+		return nil, err
+	}
+	if err := webhooks.ValidateEvents(input.Events); err != nil {
+		return nil, err
+	}
+
+	secret := ""
+	if input.Secret != nil {
+		secret = *input.Secret
+		if len(secret) < 16 {
+			return nil, fmt.Errorf("webhook secret must be at least 16 characters")
+		}
+	} else {
+		generated, err := webhooks.GenerateSecret()
+		if err != nil {
+			return nil, fmt.Errorf("error generating webhook secret: %w", err)
+		}
+		secret = generated
+	}
+
+	now := time.Now()
+	endpoint := &models.WebhookEndpoint{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		URL:       input.URL,
+		Secret:    secret,
+		Events:    input.Events,
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if endpoint.Events == nil {
+		endpoint.Events = []string{}
+	}
+	if err := r.webhooks.CreateEndpoint(ctx, endpoint); err != nil {
+		return nil, fmt.Errorf("error creating webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+func (r *Resolver) WebhookEndpoints(ctx context.Context, userID string) ([]*models.WebhookEndpoint, error) {
+	endpoints, err := r.webhooks.ListEndpoints(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhook endpoints: %w", err)
+	}
+	for _, endpoint := range endpoints {
+		endpoint.Secret = ""
+	}
+	return endpoints, nil
+}
+
+// DeleteWebhookEndpoint removes one of the user's endpoints; false if they have no such endpoint
+func (r *Resolver) DeleteWebhookEndpoint(ctx context.Context, userID, id string) (bool, error) {
+	deleted, err := r.webhooks.DeleteEndpoint(ctx, userID, id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting webhook endpoint: %w", err)
+	}
+	return deleted, nil
+}
+
+// WebhookDeliveries returns the delivery log of one of the user's endpoints, newest first
+func (r *Resolver) WebhookDeliveries(ctx context.Context, userID, endpointID string, limit *int) ([]*models.WebhookDelivery, error) {
+	n := defaultWebhookDeliveries
+	if limit != nil && *limit > 0 {
+		n = *limit
+	}
+	if n > maxWebhookDeliveries {
+		n = maxWebhookDeliveries
+	}
+
+	deliveries, err := r.webhooks.ListDeliveries(ctx, userID, endpointID, n)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// AcceptCommuteRecommendation records the option the user chose, notifies their webhooks
+// and schedules reminders to leave in place of those of the day's earlier choice. Only
+// recommendations of the user's own jobs can be accepted.
+func (r *Resolver) AcceptCommuteRecommendation(ctx context.Context, userID, id string) (*models.CommuteRecommendation, error) {
+	rec, err := r.recommendations.Accept(ctx, userID, id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, fmt.Errorf("commute recommendation not found")
+		}
+		return nil, fmt.Errorf("error accepting commute recommendation: %w", err)
+	}
+	rec.Emissions = carbon.Estimate(rec)
+	rec.Cost = costs.Estimate(rec)
+	rec.Legs = rec.DecodeTravelLegs()
+	rec.Modes = rec.DecodeModeOptions()
+
+	job, err := r.jobs.Get(ctx, rec.JobID)
+	if err != nil {
+		log.Printf("Accepted recommendation %s but could not load job %s: %v", rec.ID, rec.JobID, err)
+		return rec, nil
+	}
+	r.localize(ctx, job.UserID, rec)
+	r.publish(ctx, job.UserID, webhooks.EventRecommendationAccepted, rec)
+	r.track(ctx, job.UserID, analytics.EventPlanAccepted, map[string]interface{}{
+		"optionType": rec.OptionType,
+		"inOffice":   rec.CommuteStart != nil,
+	})
+	r.track(ctx, job.UserID, analytics.EventOptionRankChosen, map[string]interface{}{"rank": rec.OptionRank})
+	if err := r.scheduleCommuteReminders(ctx, job, rec); err != nil {
+		log.Printf("Accepted recommendation %s but could not schedule its reminders: %v", rec.ID, err)
+	}
+	return rec, nil
+}
@@ -0,0 +1,49 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestWebhookEndpointOwnership(t *testing.T) {
+	ctx := context.Background()
+	r, repos := newTestResolver(t, JobQuotaLimits{})
+	ada := createTestUser(t, repos, "ada@example.com")
+	bob := createTestUser(t, repos, "bob@example.com")
+
+	// A public IP literal passes URL validation without a DNS lookup
+	endpoint, err := r.CreateWebhookEndpoint(ctx, ada.ID, CreateWebhookEndpointInput{URL: "https://93.184.216.34/hooks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.UserID != ada.ID || endpoint.Secret == "" {
+		t.Fatalf("endpoint = %+v, want ada's with a generated secret", endpoint)
+	}
+	now := time.Now()
+	if err := repos.Webhooks.CreateDelivery(ctx, &models.WebhookDelivery{
+		ID: "delivery-1", EndpointID: endpoint.ID, EventType: "job.created", Payload: "{}",
+		Status: models.WebhookDeliveryPending, NextAttemptAt: &now, CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if endpoints, err := r.WebhookEndpoints(ctx, bob.ID); err != nil || len(endpoints) != 0 {
+		t.Fatalf("bob's endpoints = %v, %v, want none", endpoints, err)
+	}
+	if deliveries, err := r.WebhookDeliveries(ctx, bob.ID, endpoint.ID, nil); err != nil || len(deliveries) != 0 {
+		t.Fatalf("bob's deliveries = %v, %v, want none", deliveries, err)
+	}
+	if deleted, err := r.DeleteWebhookEndpoint(ctx, bob.ID, endpoint.ID); err != nil || deleted {
+		t.Fatalf("bob deleting ada's endpoint = %v, %v, want false", deleted, err)
+	}
+
+	if deliveries, err := r.WebhookDeliveries(ctx, ada.ID, endpoint.ID, nil); err != nil || len(deliveries) != 1 {
+		t.Fatalf("ada's deliveries = %v, %v, want 1", deliveries, err)
+	}
+	if deleted, err := r.DeleteWebhookEndpoint(ctx, ada.ID, endpoint.ID); err != nil || !deleted {
+		t.Fatalf("ada deleting her endpoint = %v, %v, want true", deleted, err)
+	}
+}
@@ -0,0 +1,201 @@
+// Package retention archives old jobs: once a finished job is older than its status's
+// TTL, it and its recommendations and status history are copied into the archive and
+// deleted from the live tables.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/commute-planner/backend/pkg/metrics"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// ErrRunning is returned by Trigger while another run is in progress
+var ErrRunning = errors.New("a retention run is already in progress")
+
+// Config tunes retention
+type Config struct {
+	Interval time.Duration
+	// CompletedTTL is how long COMPLETED jobs are kept after their last update; 0 keeps
+	// them forever
+	CompletedTTL time.Duration
+	// FailedTTL is the same for FAILED and CANCELLED jobs
+	FailedTTL time.Duration
+	BatchSize int
+}
+
+// Archiver runs retention, on a schedule and on demand
+type Archiver struct {
+	jobs      repository.JobRepository
+	retention repository.RetentionRepository
+	cfg       Config
+
+	mu      sync.Mutex
+	running bool
+}
+
+// NewArchiver creates an archiver; call Run to archive on a schedule
+func NewArchiver(jobs repository.JobRepository, retention repository.RetentionRepository, cfg Config) *Archiver {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 24 * time.Hour
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &Archiver{jobs: jobs, retention: retention, cfg: cfg}
+}
+
+// Run archives old jobs every interval until ctx is cancelled
+func (a *Archiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		run, err := a.start(ctx, models.RetentionTriggerSchedule)
+		if err != nil && !errors.Is(err, ErrRunning) {
+			log.Printf("Retention: failed to start run: %v", err)
+		}
+		if run != nil {
+			a.archive(ctx, run)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// start records a new run, or returns ErrRunning
+func (a *Archiver) start(ctx context.Context, trigger models.RetentionTrigger) (*models.RetentionRun, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.running {
+		return nil, ErrRunning
+	}
+
+	now := time.Now()
+	run := &models.RetentionRun{
+		ID:          uuid.New().String(),
+		TriggeredBy: trigger,
+		Status:      models.RetentionRunRunning,
+		StartedAt:   now,
+	}
+	if a.cfg.CompletedTTL > 0 {
+		before := now.Add(-a.cfg.CompletedTTL)
+		run.CompletedBefore = &before
+	}
+	if a.cfg.FailedTTL > 0 {
+		before := now.Add(-a.cfg.FailedTTL)
+		run.FailedBefore = &before
+	}
+	if err := a.retention.CreateRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("recording retention run: %w", err)
+	}
+	a.running = true
+	return run, nil
+}
+
+// Trigger starts a run and archives in the background, returning the run as started
+func (a *Archiver) Trigger(ctx context.Context, trigger models.RetentionTrigger) (*models.RetentionRun, error) {
+	run, err := a.start(ctx, trigger)
+	if err != nil {
+		return nil, err
+	}
+	started := *run
+	// The run outlives the request that triggered it
+	go a.archive(context.Background(), run)
+	return &started, nil
+}
+
+// GetRun returns a run, or repository.ErrNotFound
+func (a *Archiver) GetRun(ctx context.Context, id string) (*models.RetentionRun, error) {
+	return a.retention.GetRun(ctx, id)
+}
+
+// ListRuns returns the latest runs, newest first
+func (a *Archiver) ListRuns(ctx context.Context, limit int) ([]*models.RetentionRun, error) {
+	return a.retention.ListRuns(ctx, limit)
+}
+
+// GetArchived returns an archived job, or repository.ErrNotFound
+func (a *Archiver) GetArchived(ctx context.Context, jobID string) (*models.ArchivedJob, error) {
+	return a.retention.GetArchived(ctx, jobID)
+}
+
+// archive archives the jobs past their TTL and records the run's outcome
+func (a *Archiver) archive(ctx context.Context, run *models.RetentionRun) {
+	defer func() {
+		a.mu.Lock()
+		a.running = false
+		a.mu.Unlock()
+	}()
+
+	var err error
+	if run.CompletedBefore != nil {
+		err = a.archiveStatus(ctx, run, models.JobStatusCompleted, *run.CompletedBefore)
+	}
+	if run.FailedBefore != nil {
+		for _, status := range []models.JobStatus{models.JobStatusFailed, models.JobStatusCancelled} {
+			if err == nil {
+				err = a.archiveStatus(ctx, run, status, *run.FailedBefore)
+			}
+		}
+	}
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Status = models.RetentionRunCompleted
+	if err != nil {
+		message := err.Error()
+		run.Status, run.ErrorMessage = models.RetentionRunFailed, &message
+		log.Printf("Retention: run %s failed after archiving %d jobs: %v", run.ID, run.JobsArchived, err)
+	} else {
+		log.Printf("Retention: run %s archived %d jobs and %d recommendations", run.ID, run.JobsArchived, run.RecommendationsArchived)
+	}
+	if err := a.retention.UpdateRun(ctx, run); err != nil {
+		log.Printf("Retention: failed to record the outcome of run %s: %v", run.ID, err)
+	}
+}
+
+// archiveStatus archives the jobs of status last updated before cutoff, a batch at a time
+func (a *Archiver) archiveStatus(ctx context.Context, run *models.RetentionRun, status models.JobStatus, cutoff time.Time) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		jobs, err := a.jobs.ListStale(ctx, status, cutoff, a.cfg.BatchSize)
+		if err != nil {
+			return fmt.Errorf("listing %s jobs: %w", status, err)
+		}
+		archived := 0
+		for _, job := range jobs {
+			recommendations, err := a.retention.Archive(ctx, job.ID)
+			if errors.Is(err, repository.ErrNotFound) {
+				// Deleted since the batch was listed
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("archiving job %s: %w", job.ID, err)
+			}
+			archived++
+			run.JobsArchived++
+			run.RecommendationsArchived += recommendations
+			metrics.JobsArchived.WithLabelValues(string(status)).Inc()
+		}
+		if err := a.retention.UpdateRun(ctx, run); err != nil {
+			log.Printf("Retention: failed to record the progress of run %s: %v", run.ID, err)
+		}
+		if archived == 0 || len(jobs) < a.cfg.BatchSize {
+			return nil
+		}
+	}
+}
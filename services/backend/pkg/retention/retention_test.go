@@ -0,0 +1,97 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+	"github.com/google/uuid"
+)
+
+func TestArchiverRun(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	repos := repository.NewSQLRepositories(db)
+	user, err := repos.Users.Create(ctx, repository.NewUser{Email: "ada@example.com", Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// finish creates a job, moves it through to status and leaves it untouched for age
+	finish := func(status models.JobStatus, age time.Duration) *models.Job {
+		t.Helper()
+		job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: "2026-03-02"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, next := range []models.JobStatus{models.JobStatusInProgress, status} {
+			value := string(next)
+			if _, err := repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &value}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		testdb.Backdate(t, db, "jobs", job.ID, time.Now().Add(-age))
+		return job
+	}
+
+	old := finish(models.JobStatusCompleted, 100*24*time.Hour)
+	for rank := 1; rank <= 2; rank++ {
+		rec := &models.CommuteRecommendation{ID: uuid.New().String(), JobID: old.ID, OptionRank: rank, OptionType: models.CommuteOptionFullDayOffice}
+		if err := repos.Recommendations.Create(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recent := finish(models.JobStatusCompleted, 10*24*time.Hour)
+	failed := finish(models.JobStatusFailed, 40*24*time.Hour)
+
+	archiver := NewArchiver(repos.Jobs, repos.Retention, Config{CompletedTTL: 90 * 24 * time.Hour, FailedTTL: 30 * 24 * time.Hour, BatchSize: 1})
+	run, err := archiver.start(ctx, models.RetentionTriggerAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := archiver.Trigger(ctx, models.RetentionTriggerAdmin); !errors.Is(err, ErrRunning) {
+		t.Errorf("second run: err = %v, want ErrRunning", err)
+	}
+	archiver.archive(ctx, run)
+
+	run, err = archiver.GetRun(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != models.RetentionRunCompleted || run.JobsArchived != 2 || run.RecommendationsArchived != 2 || run.FinishedAt == nil {
+		t.Fatalf("run = %+v, want 2 jobs and 2 recommendations archived", run)
+	}
+
+	if _, err := repos.Jobs.Get(ctx, old.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("archived job still live: err = %v", err)
+	}
+	if recs, _ := repos.Recommendations.ListByJob(ctx, old.ID); len(recs) != 0 {
+		t.Errorf("archived job left %d recommendations", len(recs))
+	}
+	archived, err := archiver.GetArchived(ctx, old.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived.Job.ID != old.ID || archived.Job.Status != models.JobStatusCompleted || len(archived.Recommendations) != 2 || len(archived.Events) != 3 {
+		t.Errorf("archived snapshot = %+v, want the job, its 2 recommendations and 3 status events", archived)
+	}
+	if _, err := archiver.GetArchived(ctx, failed.ID); err != nil {
+		t.Errorf("failed job past its TTL not archived: %v", err)
+	}
+	if _, err := repos.Jobs.Get(ctx, recent.ID); err != nil {
+		t.Errorf("recent job archived: %v", err)
+	}
+
+	runs, err := archiver.ListRuns(ctx, 10)
+	if err != nil || len(runs) != 1 {
+		t.Errorf("runs = %v, %v", runs, err)
+	}
+	// The archiver is free for the next run
+	if _, err := archiver.start(ctx, models.RetentionTriggerSchedule); err != nil {
+		t.Errorf("next run: %v", err)
+	}
+}
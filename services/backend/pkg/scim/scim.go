@@ -0,0 +1,274 @@
+// Package scim implements the parts of SCIM 2.0 (RFC 7643 and 7644) that identity
+// providers such as Okta and Azure AD use to provision users: the User resource, list
+// responses, errors, equality filters and PATCH operations.
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema URNs
+const (
+	UserSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ErrorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	PatchOpSchema      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+)
+
+// ContentType is the media type of SCIM requests and responses
+const ContentType = "application/scim+json"
+
+// Error types of RFC 7644 section 3.12
+const (
+	ErrInvalidFilter = "invalidFilter"
+	ErrInvalidValue  = "invalidValue"
+	ErrInvalidSyntax = "invalidSyntax"
+	ErrUniqueness    = "uniqueness"
+	ErrNoTarget      = "noTarget"
+)
+
+// User is a SCIM User resource
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []Email  `json:"emails,omitempty"`
+	// Active is nil when a request leaves it out, which means active
+	Active *bool `json:"active,omitempty"`
+	Meta   *Meta `json:"meta,omitempty"`
+}
+
+// Name is a user's name in its parts
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// Email is one of a user's email addresses
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Meta is a resource's metadata
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location,omitempty"`
+	Version      string    `json:"version,omitempty"`
+}
+
+// ListResponse is a page of resources; StartIndex is 1-based
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// Error is a SCIM error response
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// NewError creates an error response with an HTTP status and, optionally, a SCIM error type
+func NewError(status int, scimType, detail string) Error {
+	return Error{Schemas: []string{ErrorSchema}, Status: strconv.Itoa(status), ScimType: scimType, Detail: detail}
+}
+
+// Email returns the address the user should be reached at: userName when it is an email
+// address, as identity providers usually send, or else the primary email
+func (u *User) Email() string {
+	if strings.Contains(u.UserName, "@") {
+		return strings.TrimSpace(u.UserName)
+	}
+	for _, email := range u.Emails {
+		if email.Primary {
+			return strings.TrimSpace(email.Value)
+		}
+	}
+	if len(u.Emails) > 0 {
+		return strings.TrimSpace(u.Emails[0].Value)
+	}
+	return ""
+}
+
+// FullName returns the user's displayName, or else their name put together
+func (u *User) FullName() string {
+	if name := strings.TrimSpace(u.DisplayName); name != "" {
+		return name
+	}
+	if u.Name == nil {
+		return ""
+	}
+	if name := strings.TrimSpace(u.Name.Formatted); name != "" {
+		return name
+	}
+	return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+}
+
+// IsActive reports whether the user should be able to sign in
+func (u *User) IsActive() bool {
+	return u.Active == nil || *u.Active
+}
+
+// Filter is an equality filter on one attribute, e.g. userName eq "ada@example.com",
+// the only kind identity providers send when looking a user up
+type Filter struct {
+	// Attribute is lowercased, as attribute names are case-insensitive
+	Attribute string
+	Value     string
+}
+
+// ParseFilter parses an equality filter
+func ParseFilter(filter string) (*Filter, error) {
+	parts := strings.SplitN(strings.TrimSpace(filter), " ", 3)
+	if len(parts) != 3 || !strings.EqualFold(parts[1], "eq") {
+		return nil, fmt.Errorf("only equality filters (attribute eq \"value\") are supported")
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(parts[2]))
+	if err != nil {
+		return nil, fmt.Errorf("the filter's value must be a quoted string")
+	}
+	return &Filter{Attribute: strings.ToLower(parts[0]), Value: value}, nil
+}
+
+// PatchOp is a PATCH request
+type PatchOp struct {
+	Schemas    []string    `json:"schemas"`
+	Operations []Operation `json:"Operations"`
+}
+
+// Operation is one change of a PATCH request
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies the operations to u. Operations on attributes the planner doesn't keep,
+// such as a user's title or addresses, are ignored, since identity providers send them
+// whatever the application supports.
+func (p *PatchOp) Apply(u *User) error {
+	for _, op := range p.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+			if op.Path == "" {
+				// The value holds attributes by name
+				var values map[string]json.RawMessage
+				if err := json.Unmarshal(op.Value, &values); err != nil {
+					return fmt.Errorf("an operation without a path needs an object value")
+				}
+				for path, value := range values {
+					if err := u.set(path, value); err != nil {
+						return err
+					}
+				}
+			} else if err := u.set(op.Path, op.Value); err != nil {
+				return err
+			}
+		case "remove":
+			if err := u.remove(op.Path); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown operation %q", op.Op)
+		}
+	}
+	return nil
+}
+
+func (u *User) set(path string, value json.RawMessage) error {
+	switch attribute := strings.ToLower(path); {
+	case attribute == "active":
+		active, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		u.Active = &active
+	case attribute == "username":
+		return json.Unmarshal(value, &u.UserName)
+	case attribute == "externalid":
+		return json.Unmarshal(value, &u.ExternalID)
+	case attribute == "displayname":
+		return json.Unmarshal(value, &u.DisplayName)
+	case attribute == "name":
+		return json.Unmarshal(value, &u.Name)
+	case strings.HasPrefix(attribute, "name."):
+		var part string
+		if err := json.Unmarshal(value, &part); err != nil {
+			return err
+		}
+		if u.Name == nil {
+			u.Name = &Name{}
+		}
+		if attribute != "name.formatted" {
+			// The planner keeps one name, so a changed part replaces the whole of it
+			u.Name.Formatted = ""
+			u.DisplayName = ""
+		}
+		switch attribute {
+		case "name.givenname":
+			u.Name.GivenName = part
+		case "name.familyname":
+			u.Name.FamilyName = part
+		case "name.formatted":
+			u.Name.Formatted = part
+		}
+	case attribute == "emails":
+		return json.Unmarshal(value, &u.Emails)
+	case strings.HasPrefix(attribute, "emails["):
+		// e.g. emails[type eq "work"].value, the address of the user's work email
+		var address string
+		if err := json.Unmarshal(value, &address); err != nil {
+			return err
+		}
+		u.Emails = []Email{{Value: address, Primary: true}}
+	}
+	return nil
+}
+
+func (u *User) remove(path string) error {
+	switch attribute := strings.ToLower(path); attribute {
+	case "":
+		return fmt.Errorf("remove needs a path")
+	case "externalid":
+		u.ExternalID = ""
+	case "displayname":
+		u.DisplayName = ""
+	case "username", "active":
+		return fmt.Errorf("%s can't be removed", path)
+	}
+	return nil
+}
+
+// parseBool parses a boolean, also accepting the "True" and "False" strings Azure AD sends
+func parseBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, fmt.Errorf("active must be a boolean")
+	}
+	b, err := strconv.ParseBool(strings.ToLower(s))
+	if err != nil {
+		return false, fmt.Errorf("active must be a boolean")
+	}
+	return b, nil
+}
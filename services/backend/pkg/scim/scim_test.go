@@ -0,0 +1,71 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	filter, err := ParseFilter(`userName eq "ada@example.com"`)
+	if err != nil || filter.Attribute != "username" || filter.Value != "ada@example.com" {
+		t.Fatalf("filter = %+v, %v; want userName ada@example.com", filter, err)
+	}
+	for _, invalid := range []string{`userName co "ada"`, `userName eq ada`, `active`} {
+		if _, err := ParseFilter(invalid); err == nil {
+			t.Errorf("parsed %q", invalid)
+		}
+	}
+}
+
+func TestPatchOpApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		check func(u *User) bool
+	}{
+		{
+			name:  "okta deactivation",
+			patch: `{"Operations":[{"op":"replace","value":{"active":false}}]}`,
+			check: func(u *User) bool { return !u.IsActive() },
+		},
+		{
+			name:  "azure deactivation",
+			patch: `{"Operations":[{"op":"Replace","path":"active","value":"False"}]}`,
+			check: func(u *User) bool { return !u.IsActive() },
+		},
+		{
+			name:  "azure email and name",
+			patch: `{"Operations":[{"op":"Replace","path":"emails[type eq \"work\"].value","value":"ada@acme.com"},{"op":"Add","path":"name.familyName","value":"King"},{"op":"Add","path":"title","value":"Engineer"}]}`,
+			check: func(u *User) bool {
+				return len(u.Emails) == 1 && u.Emails[0].Value == "ada@acme.com" && u.FullName() == "Ada King"
+			},
+		},
+		{
+			name:  "remove external ID",
+			patch: `{"Operations":[{"op":"remove","path":"externalId"}]}`,
+			check: func(u *User) bool { return u.ExternalID == "" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active := true
+			u := &User{UserName: "ada@example.com", ExternalID: "00u1", DisplayName: "Ada Lovelace", Name: &Name{Formatted: "Ada Lovelace", GivenName: "Ada", FamilyName: "Lovelace"}, Active: &active}
+			var patch PatchOp
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatal(err)
+			}
+			if err := patch.Apply(u); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(u) {
+				t.Errorf("patched user = %+v", u)
+			}
+		})
+	}
+
+	var patch PatchOp
+	json.Unmarshal([]byte(`{"Operations":[{"op":"replace","path":"active","value":"maybe"}]}`), &patch)
+	if err := patch.Apply(&User{}); err == nil {
+		t.Error("applied a non-boolean active")
+	}
+}
@@ -0,0 +1,108 @@
+// Package secrets reads the backend's credentials (JWT keys, OAuth client secrets and
+// API keys) from wherever a deployment keeps them: environment variables, files mounted
+// by Docker or Kubernetes, AWS Secrets Manager or HashiCorp Vault. Code asks for a
+// secret by name, e.g. "jwt_secret", and each provider maps the name onto its own store.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned for a secret the store doesn't hold
+var ErrNotFound = errors.New("secret not found")
+
+// Provider reads secrets by name
+type Provider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// Config selects and configures a provider
+type Config struct {
+	// Provider is "env", "file", "aws" or "vault"
+	Provider string
+	// Dir holds one file per secret for the file provider, e.g. /run/secrets
+	Dir string
+	// AWSRegion and AWSPrefix locate secrets in AWS Secrets Manager; a secret is named
+	// its prefix followed by its name, e.g. "commute-planner/jwt_secret"
+	AWSRegion string
+	AWSPrefix string
+	// VaultAddress, VaultToken and VaultPath locate the KV v2 secret in Vault holding
+	// every secret as one of its keys, e.g. secret/data/commute-planner
+	VaultAddress   string
+	VaultToken     string
+	VaultPath      string
+	VaultNamespace string
+	// Timeout bounds each request to AWS or Vault
+	Timeout time.Duration
+}
+
+// New creates the provider cfg selects
+func New(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case "", "env":
+		return Env{}, nil
+	case "file":
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("the file secrets provider needs a directory")
+		}
+		return File{Dir: cfg.Dir}, nil
+	case "aws":
+		return NewAWS(cfg.AWSRegion, cfg.AWSPrefix, cfg.Timeout)
+	case "vault":
+		return NewVault(cfg.VaultAddress, cfg.VaultToken, cfg.VaultPath, cfg.VaultNamespace, cfg.Timeout)
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q (expected env, file, aws or vault)", cfg.Provider)
+	}
+}
+
+// Env reads secrets from environment variables named by their upper-cased names, e.g.
+// JWT_SECRET for "jwt_secret"
+type Env struct{}
+
+// Get returns the variable's value, or ErrNotFound when it is unset or empty
+func (Env) Get(ctx context.Context, name string) (string, error) {
+	if value := os.Getenv(strings.ToUpper(name)); value != "" {
+		return value, nil
+	}
+	return "", ErrNotFound
+}
+
+// File reads secrets from files named after them in a directory, as Docker and
+// Kubernetes mount them
+type File struct {
+	Dir string
+}
+
+// Get returns the file's contents without a trailing newline, or ErrNotFound when there
+// is no such file
+func (f File) Get(ctx context.Context, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(f.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Lookup returns a secret, or "" when the store doesn't hold it
+func Lookup(ctx context.Context, p Provider, name string) (string, error) {
+	value, err := p.Get(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %w", name, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,123 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvAndFile(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("JWT_SECRET", "from-env")
+	if value, err := (Env{}).Get(ctx, "jwt_secret"); err != nil || value != "from-env" {
+		t.Errorf("env secret = %q, %v", value, err)
+	}
+	if _, err := (Env{}).Get(ctx, "ai_api_key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unset env secret: error = %v, want ErrNotFound", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "jwt_secret"), []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	files := File{Dir: dir}
+	if value, err := files.Get(ctx, "jwt_secret"); err != nil || value != "from-file" {
+		t.Errorf("file secret = %q, %v", value, err)
+	}
+	if value, err := Lookup(ctx, files, "ai_api_key"); err != nil || value != "" {
+		t.Errorf("missing file secret = %q, %v; want empty", value, err)
+	}
+	if _, err := files.Get(ctx, "../jwt_secret"); err == nil {
+		t.Error("read a secret outside the directory")
+	}
+}
+
+func TestVault(t *testing.T) {
+	reads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reads++
+		if r.URL.Path != "/v1/secret/data/commute-planner" || r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"jwt_secret":"from-vault"},"metadata":{"version":3}}}`))
+	}))
+	defer server.Close()
+
+	vault, err := NewVault(server.URL, "s.token", "/secret/data/commute-planner", "", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if value, err := vault.Get(ctx, "jwt_secret"); err != nil || value != "from-vault" {
+		t.Errorf("vault secret = %q, %v", value, err)
+	}
+	if _, err := vault.Get(ctx, "ai_api_key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing vault key: error = %v, want ErrNotFound", err)
+	}
+	if reads != 1 {
+		t.Errorf("read the secret %d times, want once", reads)
+	}
+
+	denied, _ := NewVault(server.URL, "s.other", "secret/data/commute-planner", "", time.Second)
+	if _, err := denied.Get(ctx, "jwt_secret"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("denied read: error = %v, want a failure", err)
+	}
+}
+
+func TestAWS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20260302/eu-west-1/secretsmanager/aws4_request") ||
+			r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var input struct{ SecretId string }
+		json.NewDecoder(r.Body).Decode(&input)
+		if input.SecretId != "commute-planner/jwt_secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`))
+			return
+		}
+		w.Write([]byte(`{"Name":"commute-planner/jwt_secret","SecretString":"from-aws"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	aws, err := NewAWS("eu-west-1", "commute-planner/", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aws.endpoint = server.URL
+	aws.now = func() time.Time { return time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC) }
+
+	ctx := context.Background()
+	if value, err := aws.Get(ctx, "jwt_secret"); err != nil || value != "from-aws" {
+		t.Errorf("aws secret = %q, %v", value, err)
+	}
+	if _, err := aws.Get(ctx, "ai_api_key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing aws secret: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, cfg := range []Config{
+		{Provider: "file"},
+		{Provider: "vault", VaultAddress: "http://vault:8200"},
+		{Provider: "gcp"},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) succeeded", cfg)
+		}
+	}
+}
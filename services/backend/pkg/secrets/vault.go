@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vault reads secrets from the keys of one KV v2 secret in HashiCorp Vault, e.g. the
+// jwt_secret key of secret/data/commute-planner
+type Vault struct {
+	address   string
+	token     string
+	path      string
+	namespace string
+	client    *http.Client
+
+	// The secret is read once, for all its keys
+	mu   sync.Mutex
+	data map[string]interface{}
+}
+
+// NewVault creates a provider for the KV v2 secret at path, e.g.
+// "secret/data/commute-planner", read with token
+func NewVault(address, token, path, namespace string, timeout time.Duration) (*Vault, error) {
+	if _, err := url.ParseRequestURI(address); err != nil || address == "" {
+		return nil, fmt.Errorf("invalid Vault address %q", address)
+	}
+	if token == "" {
+		return nil, errors.New("the vault secrets provider needs a token")
+	}
+	if path == "" {
+		return nil, errors.New("the vault secrets provider needs the path of a secret")
+	}
+	return &Vault{
+		address:   strings.TrimSuffix(address, "/"),
+		token:     token,
+		path:      strings.Trim(path, "/"),
+		namespace: namespace,
+		client:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Get returns the secret's key called name, or ErrNotFound when it has no such key
+func (v *Vault) Get(ctx context.Context, name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.data == nil {
+		data, err := v.read(ctx)
+		if err != nil {
+			return "", err
+		}
+		v.data = data
+	}
+	value, ok := v.data[name]
+	if !ok || value == nil {
+		return "", ErrNotFound
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("vault key %s is not a string", name)
+}
+
+func (v *Vault) read(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", v.address+"/v1/"+v.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Vault: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Vault response: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// A missing secret holds no keys
+		return map[string]interface{}{}, nil
+	default:
+		return nil, fmt.Errorf("Vault returned %d reading %s", resp.StatusCode, v.path)
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("invalid Vault response: %w", err)
+	}
+	if secret.Data.Data == nil {
+		return map[string]interface{}{}, nil
+	}
+	return secret.Data.Data, nil
+}
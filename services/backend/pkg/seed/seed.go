@@ -0,0 +1,341 @@
+// Package seed loads local development fixtures: users with their calendars, jobs and
+// recommendations, described in YAML files.
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/commute-planner/backend/pkg/contracts"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Fixtures is the content of one or more fixture files. Days count from the day the
+// fixtures are applied (0 is today) and times of day are UTC, so a seeded environment
+// always has an upcoming week to plan.
+type Fixtures struct {
+	Users []User `yaml:"users"`
+}
+
+// User is a local account; it signs in with Password
+type User struct {
+	Email       string  `yaml:"email"`
+	Name        string  `yaml:"name"`
+	Password    string  `yaml:"password"`
+	HomeAddress string  `yaml:"homeAddress"`
+	Events      []Event `yaml:"events"`
+	Jobs        []Job   `yaml:"jobs"`
+}
+
+// Event is a calendar event on Day from Start to End ("09:30")
+type Event struct {
+	Summary        string                `yaml:"summary"`
+	Day            int                   `yaml:"day"`
+	Start          string                `yaml:"start"`
+	End            string                `yaml:"end"`
+	Location       string                `yaml:"location"`
+	MeetingType    models.MeetingType    `yaml:"meetingType"`
+	AttendanceMode models.AttendanceMode `yaml:"attendanceMode"`
+}
+
+// Job plans Day. PENDING jobs aren't queued; COMPLETED ones carry their recommendations.
+type Job struct {
+	Day             int              `yaml:"day"`
+	Status          models.JobStatus `yaml:"status"`
+	ErrorMessage    string           `yaml:"errorMessage"`
+	Recommendations []Recommendation `yaml:"recommendations"`
+}
+
+// Recommendation is one option of a job, ranked in file order. Options without
+// OfficeArrival are remote.
+type Recommendation struct {
+	OptionType      models.CommuteOptionType `yaml:"optionType"`
+	OfficeArrival   string                   `yaml:"officeArrival"`
+	OfficeDeparture string                   `yaml:"officeDeparture"`
+	CommuteMinutes  int                      `yaml:"commuteMinutes"`
+	OfficeMeetings  []string                 `yaml:"officeMeetings"`
+	RemoteMeetings  []string                 `yaml:"remoteMeetings"`
+	Reasoning       string                   `yaml:"reasoning"`
+}
+
+// Load reads every .yaml file in dir, in name order
+func Load(dir string) (*Fixtures, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no fixture files in %s", dir)
+	}
+	sort.Strings(files)
+
+	fixtures := &Fixtures{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var f Fixtures
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		fixtures.Users = append(fixtures.Users, f.Users...)
+	}
+	return fixtures, nil
+}
+
+// Seeder writes fixtures
+type Seeder struct {
+	Repos repository.Repositories
+	// CreateUser creates a local account that signs in with password
+	CreateUser func(ctx context.Context, email, password, name string) (*models.User, error)
+}
+
+// Result counts what Apply created
+type Result struct {
+	Users           int
+	Skipped         []string // emails of users that already existed
+	Events          int
+	Jobs            int
+	Recommendations int
+}
+
+// Apply creates the fixtures' users with their data, counting days from today. Users
+// that already exist are skipped, or with reset deleted (with everything they own) and
+// created again. Event IDs derive from the user's email and the event's position, so
+// applying the same fixtures again yields the same events.
+func (s *Seeder) Apply(ctx context.Context, fixtures *Fixtures, today time.Time, reset bool) (*Result, error) {
+	existing, err := s.Repos.Users.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	byEmail := map[string]*models.User{}
+	for _, user := range existing {
+		byEmail[strings.ToLower(user.Email)] = user
+	}
+
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	result := &Result{}
+	for _, fixture := range fixtures.Users {
+		if user, ok := byEmail[strings.ToLower(fixture.Email)]; ok {
+			if !reset {
+				result.Skipped = append(result.Skipped, fixture.Email)
+				continue
+			}
+			if _, err := s.Repos.Users.Delete(ctx, user.ID); err != nil {
+				return result, fmt.Errorf("failed to delete %s: %w", fixture.Email, err)
+			}
+		}
+		if err := s.applyUser(ctx, fixture, today, result); err != nil {
+			return result, fmt.Errorf("%s: %w", fixture.Email, err)
+		}
+	}
+	return result, nil
+}
+
+func (s *Seeder) applyUser(ctx context.Context, fixture User, today time.Time, result *Result) error {
+	if fixture.Email == "" || fixture.Password == "" {
+		return fmt.Errorf("users need an email and a password")
+	}
+	user, err := s.CreateUser(ctx, fixture.Email, fixture.Password, fixture.Name)
+	if err != nil {
+		return err
+	}
+	result.Users++
+	if fixture.HomeAddress != "" {
+		if _, err := s.Repos.Users.SetHomeAddress(ctx, user.ID, &fixture.HomeAddress, nil, nil); err != nil {
+			return fmt.Errorf("failed to set home address: %w", err)
+		}
+	}
+
+	for i, e := range fixture.Events {
+		event, err := newEvent(user.ID, fixture.Email, i, e, today)
+		if err != nil {
+			return fmt.Errorf("event %q: %w", e.Summary, err)
+		}
+		if err := s.Repos.Events.Create(ctx, event); err != nil {
+			return fmt.Errorf("failed to create event %q: %w", e.Summary, err)
+		}
+		result.Events++
+	}
+
+	for _, j := range fixture.Jobs {
+		if err := s.applyJob(ctx, user.ID, j, today, result); err != nil {
+			return fmt.Errorf("job on day %d: %w", j.Day, err)
+		}
+	}
+	return nil
+}
+
+// applyJob creates a job and walks it through the state machine to its fixture status
+func (s *Seeder) applyJob(ctx context.Context, userID string, fixture Job, today time.Time, result *Result) error {
+	date := today.AddDate(0, 0, fixture.Day)
+	inputData := `{"source":"seed"}`
+	job, err := s.Repos.Jobs.Create(ctx, repository.NewJob{
+		UserID:     userID,
+		TargetDate: date.Format("2006-01-02"),
+		InputData:  &inputData,
+	})
+	if err != nil {
+		return err
+	}
+	result.Jobs++
+
+	status := fixture.Status
+	if status == "" {
+		status = models.JobStatusCompleted
+	}
+	if status == models.JobStatusPending {
+		return nil
+	}
+	if status != models.JobStatusCancelled {
+		inProgress := string(models.JobStatusInProgress)
+		if _, err := s.Repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &inProgress}); err != nil {
+			return err
+		}
+	}
+
+	update := repository.JobUpdate{}
+	switch status {
+	case models.JobStatusCompleted:
+		for i, r := range fixture.Recommendations {
+			rec, err := newRecommendation(job.ID, i+1, r, date)
+			if err != nil {
+				return fmt.Errorf("recommendation %d: %w", i+1, err)
+			}
+			if err := s.Repos.Recommendations.Create(ctx, rec); err != nil {
+				return err
+			}
+			result.Recommendations++
+		}
+		progress := 1.0
+		step := "Recommendations complete"
+		output, err := contracts.JobResult{TotalOptions: len(fixture.Recommendations), AnalysisComplete: true}.Encode()
+		if err != nil {
+			return err
+		}
+		update = repository.JobUpdate{Progress: &progress, CurrentStep: &step, Result: &output}
+	case models.JobStatusFailed:
+		message := fixture.ErrorMessage
+		if message == "" {
+			message = "Planning failed"
+		}
+		update.ErrorMessage = &message
+	}
+	if status != models.JobStatusInProgress {
+		value := string(status)
+		update.Status = &value
+	}
+	_, err = s.Repos.Jobs.Update(ctx, job.ID, update)
+	return err
+}
+
+// seedNamespace derives stable event IDs
+var seedNamespace = uuid.MustParse("5c0b0c8e-7f5e-4c55-9a0e-2b8d3f3d4a11")
+
+func newEvent(userID, email string, index int, fixture Event, today time.Time) (*models.CalendarEvent, error) {
+	date := today.AddDate(0, 0, fixture.Day)
+	start, err := atTime(date, fixture.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := atTime(date, fixture.End)
+	if err != nil {
+		return nil, err
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("ends before it starts")
+	}
+
+	event := &models.CalendarEvent{
+		ID:             uuid.NewSHA1(seedNamespace, []byte(fmt.Sprintf("%s/%d", strings.ToLower(email), index))).String(),
+		UserID:         userID,
+		Summary:        fixture.Summary,
+		StartTime:      start,
+		EndTime:        end,
+		MeetingType:    fixture.MeetingType,
+		AttendanceMode: fixture.AttendanceMode,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	if event.MeetingType == "" {
+		event.MeetingType = models.MeetingTypeUnknown
+	}
+	if event.AttendanceMode == "" {
+		event.AttendanceMode = models.AttendanceFlexible
+	}
+	if fixture.Location != "" {
+		event.Location = &fixture.Location
+	}
+	return event, nil
+}
+
+func newRecommendation(jobID string, rank int, fixture Recommendation, date time.Time) (*models.CommuteRecommendation, error) {
+	rec := &models.CommuteRecommendation{
+		JobID:          jobID,
+		OptionRank:     rank,
+		OptionType:     fixture.OptionType,
+		OfficeMeetings: jsonString(nonNil(fixture.OfficeMeetings)),
+		RemoteMeetings: jsonString(nonNil(fixture.RemoteMeetings)),
+		TravelLegs:     jsonString([]string{}),
+		ModeOptions:    jsonString([]string{}),
+	}
+	if fixture.Reasoning != "" {
+		rec.Reasoning = &fixture.Reasoning
+	}
+	if fixture.OfficeArrival == "" {
+		duration := "0 hours"
+		rec.OfficeDuration = &duration
+		return rec, nil
+	}
+
+	arrival, err := atTime(date, fixture.OfficeArrival)
+	if err != nil {
+		return nil, err
+	}
+	departure, err := atTime(date, fixture.OfficeDeparture)
+	if err != nil {
+		return nil, err
+	}
+	commute := time.Duration(fixture.CommuteMinutes) * time.Minute
+	start, end := arrival.Add(-commute), departure.Add(commute)
+	rec.CommuteStart, rec.OfficeArrival, rec.OfficeDeparture, rec.CommuteEnd = &start, &arrival, &departure, &end
+	inOffice := departure.Sub(arrival)
+	duration := fmt.Sprintf("%d hours %d minutes", int(inOffice/time.Hour), int(inOffice%time.Hour/time.Minute))
+	rec.OfficeDuration = &duration
+	mode := models.TravelModeDrive
+	rec.TravelMode = &mode
+	return rec, nil
+}
+
+// atTime is the given "15:04" time of day on date
+func atTime(date time.Time, clock string) (time.Time, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (want HH:MM)", clock)
+	}
+	return date.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute), nil
+}
+
+func jsonString(v interface{}) *string {
+	data, _ := json.Marshal(v)
+	s := string(data)
+	return &s
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,116 @@
+package seed
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/internal/testdb"
+	"github.com/commute-planner/backend/pkg/auth"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+func TestApply(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	fixtures, err := Load("../../fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeder := &Seeder{
+		Repos: repository.NewSQLRepositories(db),
+		CreateUser: func(ctx context.Context, email, password, name string) (*models.User, error) {
+			return auth.CreateLocalUser(ctx, db, email, password, name)
+		},
+	}
+	today := time.Date(2024, 3, 4, 15, 30, 0, 0, time.UTC)
+
+	result, err := seeder.Apply(ctx, fixtures, today, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Users != 2 || result.Events != 9 || result.Jobs != 4 || result.Recommendations != 3 {
+		t.Errorf("result = %+v, want 2 users, 9 events, 4 jobs and 3 recommendations", result)
+	}
+
+	users, err := seeder.Repos.Users.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ada *models.User
+	for _, user := range users {
+		if user.Email == "ada@example.com" {
+			ada = user
+		}
+	}
+	if ada == nil || ada.HomeAddress == nil {
+		t.Fatalf("ada = %+v, want a user with a home address", ada)
+	}
+	jobs, err := seeder.Repos.Jobs.List(ctx, &ada.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]models.JobStatus{}
+	for _, job := range jobs {
+		statuses[job.TargetDate[:10]] = job.Status
+	}
+	want := map[string]models.JobStatus{
+		"2024-03-05": models.JobStatusCompleted,
+		"2024-03-06": models.JobStatusFailed,
+		"2024-03-07": models.JobStatusPending,
+	}
+	for date, status := range want {
+		if statuses[date] != status {
+			t.Errorf("job on %s is %q, want %q", date, statuses[date], status)
+		}
+	}
+	before := eventIDs(t, seeder.Repos, ada.ID)
+
+	// Seeding again leaves existing users alone
+	result, err = seeder.Apply(ctx, fixtures, today, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Users != 0 || len(result.Skipped) != 2 {
+		t.Errorf("second apply = %+v, want both users skipped", result)
+	}
+
+	// and reset recreates them with the same events
+	result, err = seeder.Apply(ctx, fixtures, today, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Users != 2 || result.Events != 9 {
+		t.Errorf("reset = %+v, want 2 users and 9 events", result)
+	}
+	if _, err := seeder.Repos.Users.Get(ctx, ada.ID); err == nil {
+		t.Error("reset kept the old user")
+	}
+	users, err = seeder.Repos.Users.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range users {
+		if user.Email == "ada@example.com" {
+			if after := eventIDs(t, seeder.Repos, user.ID); after != before {
+				t.Errorf("event IDs after reset = %s, want %s", after, before)
+			}
+		}
+	}
+}
+
+// eventIDs lists a user's event IDs in start time order
+func eventIDs(t *testing.T, repos repository.Repositories, userID string) string {
+	t.Helper()
+	events, err := repos.Events.ListByUser(context.Background(), userID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,74 @@
+// Package sharelink signs the URLs of read-only links to a plan. A link's URL carries its
+// ID and expiry with an HMAC-SHA256 signature of both, so links can't be guessed or
+// extended; revocation is checked against the stored link.
+package sharelink
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Path is where shared plans are served, followed by the link ID
+const Path = "/share/"
+
+// minSecretLength keeps the signing key out of reach of brute force
+const minSecretLength = 32
+
+// Errors of links that grant no access
+var (
+	ErrInvalidSignature = errors.New("invalid share link signature")
+	ErrExpired          = errors.New("share link expired")
+)
+
+// Signer signs and verifies share link URLs
+type Signer struct {
+	secret  []byte
+	baseURL string
+}
+
+// NewSigner creates a signer with secret, for links under baseURL, the API's public URL
+func NewSigner(secret, baseURL string) (*Signer, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("share link secret must be at least %d characters", minSecretLength)
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Host == "" || (base.Scheme != "https" && base.Scheme != "http") {
+		return nil, fmt.Errorf("invalid share link base URL %q", baseURL)
+	}
+	return &Signer{secret: []byte(secret), baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// URL returns the signed URL of link id, valid until expires
+func (s *Signer) URL(id string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{"expires": {exp}, "signature": {s.sign(id, exp)}}
+	return s.baseURL + Path + url.PathEscape(id) + "?" + query.Encode()
+}
+
+// Verify checks the expires and signature query parameters of a request for link id
+func (s *Signer) Verify(id, expires, signature string, now time.Time) error {
+	if !hmac.Equal([]byte(signature), []byte(s.sign(id, expires))) {
+		return ErrInvalidSignature
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !now.Before(time.Unix(exp, 0)) {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (s *Signer) sign(id, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s.%s", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,52 @@
+package sharelink
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSigner(t *testing.T) {
+	secret := strings.Repeat("s", 32)
+	signer, err := NewSigner(secret, "https://api.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	link, err := url.Parse(signer.URL("link-1", now.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Host != "api.example.com" || link.Path != "/share/link-1" {
+		t.Fatalf("URL = %s", link)
+	}
+	expires, signature := link.Query().Get("expires"), link.Query().Get("signature")
+
+	if err := signer.Verify("link-1", expires, signature, now); err != nil {
+		t.Errorf("valid link: %v", err)
+	}
+	if err := signer.Verify("link-1", expires, signature, now.Add(time.Hour)); err != ErrExpired {
+		t.Errorf("expired link: err = %v, want ErrExpired", err)
+	}
+	// Neither the ID nor the expiry can be changed
+	if err := signer.Verify("link-2", expires, signature, now); err != ErrInvalidSignature {
+		t.Errorf("other ID: err = %v", err)
+	}
+	later := strconv.FormatInt(now.Add(24*time.Hour).Unix(), 10)
+	if err := signer.Verify("link-1", later, signature, now); err != ErrInvalidSignature {
+		t.Errorf("extended expiry: err = %v", err)
+	}
+	other, _ := NewSigner(strings.Repeat("x", 32), "https://api.example.com")
+	if err := other.Verify("link-1", expires, signature, now); err != ErrInvalidSignature {
+		t.Errorf("other secret: err = %v", err)
+	}
+
+	if _, err := NewSigner("short", "https://api.example.com"); err == nil {
+		t.Error("short secret accepted")
+	}
+	if _, err := NewSigner(secret, "api.example.com"); err == nil {
+		t.Error("base URL without a scheme accepted")
+	}
+}
@@ -0,0 +1,175 @@
+// Package storage presigns URLs to an S3-compatible bucket holding large job artifacts:
+// AWS S3, MinIO, or Google Cloud Storage through its XML API with HMAC keys. Clients
+// upload and download objects directly with the URLs; the backend never proxies them.
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxExpiry is the longest a SigV4 presigned URL can be valid for
+const MaxExpiry = 7 * 24 * time.Hour
+
+// Config is the bucket artifacts are stored in
+type Config struct {
+	// Endpoint is the storage service's base URL, e.g. https://s3.eu-west-1.amazonaws.com,
+	// https://storage.googleapis.com or http://minio:9000
+	Endpoint string
+	// Region signs requests; GCS and MinIO accept any, such as the default us-east-1
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses the bucket as endpoint/bucket/key rather than as a subdomain of
+	// the endpoint, as MinIO needs
+	PathStyle bool
+}
+
+// Client presigns object URLs with AWS Signature Version 4
+type Client struct {
+	cfg      Config
+	endpoint *url.URL
+	now      func() time.Time
+}
+
+// NewClient creates a client for the bucket in cfg
+func NewClient(cfg Config) (*Client, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "https" && endpoint.Scheme != "http") {
+		return nil, fmt.Errorf("invalid storage endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("storage bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("storage access key ID and secret are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &Client{cfg: cfg, endpoint: endpoint, now: time.Now}, nil
+}
+
+// PresignGet returns a URL that downloads key until it expires
+func (c *Client) PresignGet(key string, expires time.Duration) (string, error) {
+	return c.presign("GET", key, "", expires)
+}
+
+// PresignPut returns a URL that uploads key until it expires. The upload must send
+// contentType as its Content-Type.
+func (c *Client) PresignPut(key, contentType string, expires time.Duration) (string, error) {
+	return c.presign("PUT", key, contentType, expires)
+}
+
+func (c *Client) presign(method, key, contentType string, expires time.Duration) (string, error) {
+	if key == "" {
+		return "", errors.New("object key is required")
+	}
+	if expires <= 0 || expires > MaxExpiry {
+		return "", fmt.Errorf("presigned URLs must expire within %s", MaxExpiry)
+	}
+
+	host, path := c.endpoint.Host, strings.TrimSuffix(c.endpoint.Path, "/")
+	if c.cfg.PathStyle {
+		path += "/" + c.cfg.Bucket
+	} else {
+		host = c.cfg.Bucket + "." + host
+	}
+	path += "/" + key
+
+	now := c.now().UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + c.cfg.Region + "/s3/aws4_request"
+	headers := map[string]string{"host": host}
+	if contentType != "" {
+		headers["content-type"] = contentType
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	signedHeaders := strings.Join(names, ";")
+
+	query := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    c.cfg.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          now.Format("20060102T150405Z"),
+		"X-Amz-Expires":       fmt.Sprint(int(expires.Seconds())),
+		"X-Amz-SignedHeaders": signedHeaders,
+	}
+	canonicalQuery := canonicalQueryString(query)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		method,
+		escapePath(path),
+		canonicalQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", query["X-Amz-Date"], scope, hex.EncodeToString(hashed[:])}, "\n")
+
+	key4 := hmacSHA256([]byte("AWS4"+c.cfg.SecretAccessKey), date)
+	key4 = hmacSHA256(key4, c.cfg.Region)
+	key4 = hmacSHA256(key4, "s3")
+	key4 = hmacSHA256(key4, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key4, stringToSign))
+
+	return c.endpoint.Scheme + "://" + host + escapePath(path) + "?" + canonicalQuery + "&X-Amz-Signature=" + signature, nil
+}
+
+func canonicalQueryString(query map[string]string) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = escape(key, false) + "=" + escape(query[key], false)
+	}
+	return strings.Join(pairs, "&")
+}
+
+// escapePath URI-encodes an object path, keeping its slashes
+func escapePath(path string) string {
+	return escape(path, true)
+}
+
+// escape URI-encodes s as SigV4 requires: everything but unreserved characters, and
+// slashes when keepSlash is set
+func escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,175 @@
+// Package synthetic stands in for the AI service when load testing: its workers take jobs
+// off the queue and complete them after a configurable delay with the same canned
+// recommendations as demo data, so queue throughput, database contention and API latency
+// can be measured without model calls.
+package synthetic
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/contracts"
+	"github.com/commute-planner/backend/pkg/handlers"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Config tunes the simulated processing
+type Config struct {
+	// Latency is how long a job takes, varied by up to Jitter either way
+	Latency time.Duration
+	Jitter  time.Duration
+	// FailureRate is the share of jobs (0-1) that fail instead of completing
+	FailureRate float64
+}
+
+// steps are the progress updates a job goes through, as the AI service reports them;
+// the latency is spread evenly over them
+var steps = []struct {
+	name     string
+	progress float64
+}{
+	{"Analyzing calendar", 0.25},
+	{"Planning commute options", 0.6},
+	{"Writing recommendations", 0.9},
+}
+
+// Worker processes jobs the way the AI service does, writing progress and results to the
+// database directly
+type Worker struct {
+	repos repository.Repositories
+	cfg   Config
+}
+
+// NewWorker creates a worker
+func NewWorker(repos repository.Repositories, cfg Config) *Worker {
+	return &Worker{repos: repos, cfg: cfg}
+}
+
+// Run processes deliveries from consumer one at a time until ctx is cancelled. Run one per
+// consumer for concurrency.
+func (w *Worker) Run(ctx context.Context, consumer queue.Consumer) {
+	defer consumer.Close()
+	for ctx.Err() == nil {
+		deliveries, err := consumer.Receive(ctx, 1)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Synthetic worker: %v", err)
+				sleep(ctx, time.Second)
+			}
+			continue
+		}
+		for _, delivery := range deliveries {
+			if err := w.Process(ctx, delivery.Message); err != nil {
+				log.Printf("Synthetic worker: job %s: %v", delivery.Message.JobID, err)
+			}
+			// Like the AI service, a job is acknowledged whatever the outcome; the reaper
+			// deals with any left IN_PROGRESS
+			if err := delivery.Ack(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Synthetic worker: failed to acknowledge job %s: %v", delivery.Message.JobID, err)
+			}
+		}
+	}
+}
+
+// Process runs one job from PENDING to COMPLETED (or FAILED, at the failure rate)
+func (w *Worker) Process(ctx context.Context, msg queue.Message) error {
+	if msg.JobID == "" {
+		return fmt.Errorf("message has no job ID")
+	}
+	inProgress := string(models.JobStatusInProgress)
+	job, err := w.repos.Jobs.Update(ctx, msg.JobID, repository.JobUpdate{Status: &inProgress})
+	if err != nil {
+		return fmt.Errorf("failed to start: %w", err)
+	}
+
+	// Reading the day's calendar is the database work the planner does
+	location := time.UTC
+	if timezone, err := w.repos.Users.PreferredTimezone(ctx, job.UserID); err == nil {
+		if loaded, err := time.LoadLocation(timezone); err == nil {
+			location = loaded
+		}
+	}
+	targetDate := job.TargetDate[:len("2006-01-02")]
+	date, err := time.ParseInLocation("2006-01-02", targetDate, location)
+	if err != nil {
+		return w.fail(ctx, job.ID, fmt.Sprintf("invalid target date %q", job.TargetDate))
+	}
+	events, err := w.repos.Events.ListByUser(ctx, job.UserID, &targetDate)
+	if err != nil {
+		return w.fail(ctx, job.ID, "failed to load calendar events")
+	}
+
+	latency := w.latency()
+	for _, step := range steps {
+		if !sleep(ctx, latency/time.Duration(len(steps))) {
+			return ctx.Err()
+		}
+		name, progress := step.name, step.progress
+		if _, err := w.repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Progress: &progress, CurrentStep: &name}); err != nil {
+			return fmt.Errorf("failed to report progress: %w", err)
+		}
+	}
+
+	if w.cfg.FailureRate > 0 && rand.Float64() < w.cfg.FailureRate {
+		return w.fail(ctx, job.ID, "Synthetic failure")
+	}
+
+	recommendations := handlers.DemoRecommendations(job.ID, date, events)
+	for _, rec := range recommendations {
+		if err := w.repos.Recommendations.Create(ctx, rec); err != nil {
+			return fmt.Errorf("failed to create recommendation: %w", err)
+		}
+	}
+	completed := string(models.JobStatusCompleted)
+	progress := 1.0
+	step := "Recommendations complete"
+	result, err := contracts.JobResult{TotalOptions: len(recommendations), AnalysisComplete: true, Synthetic: true}.Encode()
+	if err != nil {
+		return err
+	}
+	if _, err := w.repos.Jobs.Update(ctx, job.ID, repository.JobUpdate{Status: &completed, Progress: &progress, CurrentStep: &step, Result: &result}); err != nil {
+		return fmt.Errorf("failed to complete: %w", err)
+	}
+	return nil
+}
+
+// fail marks the job FAILED with message
+func (w *Worker) fail(ctx context.Context, jobID, message string) error {
+	failed := string(models.JobStatusFailed)
+	if _, err := w.repos.Jobs.Update(ctx, jobID, repository.JobUpdate{Status: &failed, ErrorMessage: &message}); err != nil {
+		return fmt.Errorf("failed to record failure %q: %w", message, err)
+	}
+	return nil
+}
+
+// latency picks this job's processing time
+func (w *Worker) latency() time.Duration {
+	latency := w.cfg.Latency
+	if w.cfg.Jitter > 0 {
+		latency += time.Duration(rand.Int63n(int64(2*w.cfg.Jitter))) - w.cfg.Jitter
+	}
+	if latency < 0 {
+		return 0
+	}
+	return latency
+}
+
+// sleep waits for d, returning false if ctx is cancelled first
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
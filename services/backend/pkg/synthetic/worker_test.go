@@ -0,0 +1,134 @@
+package synthetic
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/queue"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// channelConsumer delivers messages sent on a channel and records acknowledgements
+type channelConsumer struct {
+	messages chan queue.Message
+	mu       sync.Mutex
+	acked    []string
+}
+
+func (c *channelConsumer) Receive(ctx context.Context, max int) ([]*queue.Delivery, error) {
+	select {
+	case msg := <-c.messages:
+		ack := func(context.Context) error {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.acked = append(c.acked, msg.JobID)
+			return nil
+		}
+		nack := func(context.Context, bool) error { return nil }
+		return []*queue.Delivery{queue.NewDelivery(msg, msg.JobID, ack, nack)}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *channelConsumer) Close() error { return nil }
+
+func (c *channelConsumer) ackedCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.acked)
+}
+
+func TestWorker(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	user, err := repos.Users.Create(ctx, repository.NewUser{Email: "ada@example.com", Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	location := "Board room"
+	if err := repos.Events.Create(ctx, &models.CalendarEvent{
+		ID:             "client-kickoff",
+		UserID:         user.ID,
+		Summary:        "Client kickoff",
+		StartTime:      time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC),
+		EndTime:        time.Date(2024, 3, 5, 11, 0, 0, 0, time.UTC),
+		Location:       &location,
+		MeetingType:    models.MeetingTypeClientMeeting,
+		AttendanceMode: models.AttendanceMustBeInOffice,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	newJob := func() *models.Job {
+		job, err := repos.Jobs.Create(ctx, repository.NewJob{UserID: user.ID, TargetDate: "2024-03-05"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return job
+	}
+
+	t.Run("completes", func(t *testing.T) {
+		job := newJob()
+		consumer := &channelConsumer{messages: make(chan queue.Message, 1)}
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			NewWorker(repos, Config{}).Run(runCtx, consumer)
+			close(done)
+		}()
+		consumer.messages <- queue.Message{JobID: job.ID, UserID: user.ID, TargetDate: job.TargetDate}
+		for deadline := time.Now().Add(5 * time.Second); consumer.ackedCount() == 0; {
+			if time.Now().After(deadline) {
+				t.Fatal("job wasn't acknowledged")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		<-done
+
+		got, err := repos.Jobs.Get(ctx, job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != models.JobStatusCompleted || got.Progress != 1 {
+			t.Errorf("job = %s at %v, want COMPLETED at 1", got.Status, got.Progress)
+		}
+		recs, err := repos.Recommendations.ListByJob(ctx, job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recs) != 3 || recs[0].OptionType == models.CommuteOptionFullRemoteRecommended {
+			t.Errorf("recommendations = %d, first %+v; want 3 with an office option first", len(recs), recs)
+		}
+	})
+
+	t.Run("fails at the failure rate", func(t *testing.T) {
+		job := newJob()
+		err := NewWorker(repos, Config{FailureRate: 1}).Process(ctx, queue.Message{JobID: job.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := repos.Jobs.Get(ctx, job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != models.JobStatusFailed || got.ErrorMessage == nil {
+			t.Errorf("job = %s (%v), want FAILED with a message", got.Status, got.ErrorMessage)
+		}
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		w := NewWorker(repos, Config{Latency: time.Second, Jitter: 200 * time.Millisecond})
+		for i := 0; i < 100; i++ {
+			if d := w.latency(); d < 800*time.Millisecond || d > 1200*time.Millisecond {
+				t.Fatalf("latency = %v, want 1s ± 200ms", d)
+			}
+		}
+		if d := NewWorker(repos, Config{Latency: 0, Jitter: time.Second}).latency(); d < 0 {
+			t.Errorf("latency = %v, want it clamped at 0", d)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+// Package tenant scopes requests to one tenant (a company) of a multi-tenant deployment.
+// The tenant travels in the request context; repositories filter every read and write of
+// tenant-owned rows by it. Contexts without a tenant (background workers, single-tenant
+// deployments before authentication) are unscoped.
+package tenant
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// DefaultID is the tenant that owns all data of a single-tenant deployment, and rows
+// written before multi-tenancy was enabled
+const DefaultID = "default"
+
+// Supported deployment modes, selected with TENANCY_MODE
+const (
+	ModeSingle = "single"
+	ModeMulti  = "multi"
+)
+
+// idPattern matches tenant IDs, which double as subdomain labels
+var idPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ValidID reports whether id can name a tenant
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+type contextKey struct{}
+
+// WithID returns a context scoped to the tenant
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant ctx is scoped to, if any
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// OrDefault returns the tenant ctx is scoped to, or DefaultID. New rows are written to it.
+func OrDefault(ctx context.Context) string {
+	if id, ok := FromContext(ctx); ok {
+		return id
+	}
+	return DefaultID
+}
+
+// FromHost extracts the tenant from the subdomain of host under baseDomain, e.g. "acme"
+// from "acme.commute.example.com" with base domain "commute.example.com". Only a single
+// label is accepted; the bare base domain and unrelated hosts have no tenant.
+func FromHost(host, baseDomain string) (string, bool) {
+	if baseDomain == "" {
+		return "", false
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	suffix := "." + strings.ToLower(strings.TrimPrefix(baseDomain, "."))
+	if !strings.HasSuffix(host, suffix) {
+		return "", false
+	}
+	label := strings.TrimSuffix(host, suffix)
+	if strings.Contains(label, ".") || !ValidID(label) {
+		return "", false
+	}
+	return label, true
+}
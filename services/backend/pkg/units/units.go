@@ -0,0 +1,108 @@
+// Package units formats the times, distances and costs the backend renders itself, in
+// digest emails, reminders and calendar feeds, the way each user asked to read them.
+// Costs are priced in whatever currency the user's travel profile is in, so the
+// currency is only shown, never converted.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// kmPerMile converts miles to kilometres
+const kmPerMile = 1.609344
+
+// symbols are the currencies shown with a symbol rather than their code
+var symbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"INR": "₹",
+	"AUD": "A$",
+	"CAD": "C$",
+	"NZD": "NZ$",
+}
+
+// wholeUnits are the currencies without minor units
+var wholeUnits = map[string]bool{"JPY": true, "KRW": true, "ISK": true}
+
+// Format formats values for one user
+type Format struct {
+	// Location is the timezone times are shown in
+	Location     *time.Location
+	DistanceUnit models.DistanceUnit
+	// Currency is an ISO 4217 code; empty shows bare amounts
+	Currency   string
+	TimeFormat models.TimeFormat
+}
+
+// For returns user's format with times in location. Unset preferences are kilometres,
+// bare amounts and 24-hour times.
+func For(user *models.User, location *time.Location) Format {
+	f := Format{Location: location, DistanceUnit: models.DistanceUnitKm, TimeFormat: models.TimeFormat24Hour}
+	if f.Location == nil {
+		f.Location = time.UTC
+	}
+	if user == nil {
+		return f
+	}
+	if user.DistanceUnit != nil {
+		f.DistanceUnit = *user.DistanceUnit
+	}
+	if user.Currency != nil {
+		f.Currency = *user.Currency
+	}
+	if user.TimeFormat != nil {
+		f.TimeFormat = *user.TimeFormat
+	}
+	return f
+}
+
+// Time formats t's clock time, "17:30" or "5:30pm"
+func (f Format) Time(t time.Time) string {
+	if f.Location != nil {
+		t = t.In(f.Location)
+	}
+	if f.TimeFormat == models.TimeFormat12Hour {
+		return t.Format("3:04pm")
+	}
+	return t.Format("15:04")
+}
+
+// Distance formats km to one decimal, "12.4 km" or "7.7 mi"
+func (f Format) Distance(km float64) string {
+	if f.DistanceUnit == models.DistanceUnitMi {
+		return fmt.Sprintf("%.1f mi", km/kmPerMile)
+	}
+	return fmt.Sprintf("%.1f km", km)
+}
+
+// Money formats amount in the currency, "€8.40", "CHF 8.40" or, without one, "8.40"
+func (f Format) Money(amount float64) string {
+	value := fmt.Sprintf("%.2f", amount)
+	if wholeUnits[f.Currency] {
+		value = fmt.Sprintf("%.0f", math.Round(amount))
+	}
+	if symbol, ok := symbols[f.Currency]; ok {
+		return symbol + value
+	}
+	if f.Currency != "" {
+		return f.Currency + " " + value
+	}
+	return value
+}
+
+// ParseCurrency returns code as an upper-case ISO 4217 code, or an error when it
+// isn't shaped like one
+func ParseCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("invalid currency %q: expected an ISO 4217 code such as EUR", code)
+	}
+	return code, nil
+}
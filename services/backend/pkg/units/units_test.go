@@ -0,0 +1,56 @@
+package units
+
+import (
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+func TestFormat(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone data")
+	}
+	at := time.Date(2026, 3, 2, 16, 5, 0, 0, time.UTC)
+
+	defaults := For(&models.User{}, berlin)
+	if got := defaults.Time(at); got != "17:05" {
+		t.Errorf("24-hour time = %q", got)
+	}
+	if got := defaults.Distance(12.44); got != "12.4 km" {
+		t.Errorf("km = %q", got)
+	}
+	if got := defaults.Money(8.4); got != "8.40" {
+		t.Errorf("bare amount = %q", got)
+	}
+
+	mi, usd, twelve := models.DistanceUnitMi, "USD", models.TimeFormat12Hour
+	f := For(&models.User{DistanceUnit: &mi, Currency: &usd, TimeFormat: &twelve}, berlin)
+	if got := f.Time(at); got != "5:05pm" {
+		t.Errorf("12-hour time = %q", got)
+	}
+	if got := f.Distance(16.09344); got != "10.0 mi" {
+		t.Errorf("miles = %q", got)
+	}
+	if got := f.Money(8.4); got != "$8.40" {
+		t.Errorf("dollars = %q", got)
+	}
+	for currency, want := range map[string]string{"CHF": "CHF 8.40", "JPY": "¥8", "EUR": "€8.40"} {
+		f.Currency = currency
+		if got := f.Money(8.4); got != want {
+			t.Errorf("%s = %q, want %q", currency, got, want)
+		}
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	if code, err := ParseCurrency(" eur "); err != nil || code != "EUR" {
+		t.Errorf("ParseCurrency(eur) = %q, %v", code, err)
+	}
+	for _, code := range []string{"", "EU", "EURO", "E1R", "€"} {
+		if _, err := ParseCurrency(code); err == nil {
+			t.Errorf("ParseCurrency(%q): no error", code)
+		}
+	}
+}
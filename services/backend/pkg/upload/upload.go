@@ -0,0 +1,117 @@
+// Package upload reads GraphQL requests sent as multipart forms, following the GraphQL
+// multipart request spec (https://github.com/jaydenseric/graphql-multipart-request-spec):
+// an "operations" field holding the operation or batch as JSON with null in place of
+// each file, a "map" field saying which variables each file goes in, and the files.
+package upload
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxMemory is how much of a form is held in memory; larger files are spooled to disk
+const maxMemory = 8 << 20
+
+// Upload is a file sent with an operation, in place of a variable
+type Upload struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	header      *multipart.FileHeader
+}
+
+// Open opens the file's content
+func (u *Upload) Open() (multipart.File, error) {
+	return u.header.Open()
+}
+
+// MarshalJSON writes the upload as its filename, so operations holding one can still be
+// logged or echoed
+func (u *Upload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.Filename)
+}
+
+// IsMultipart reports whether r is a multipart form
+func IsMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// Parse reads the operations of a multipart request, decoded from JSON, with each file in
+// the place the map puts it: a map[string]interface{} for one operation or an
+// []interface{} for a batch. Limit the request body's size before calling it.
+func Parse(r *http.Request) (interface{}, error) {
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		return nil, fmt.Errorf("invalid multipart request: %w", err)
+	}
+	var operations interface{}
+	if err := json.Unmarshal([]byte(r.FormValue("operations")), &operations); err != nil {
+		return nil, fmt.Errorf("invalid operations field: %w", err)
+	}
+	var fileMap map[string][]string
+	if err := json.Unmarshal([]byte(r.FormValue("map")), &fileMap); err != nil {
+		return nil, fmt.Errorf("invalid map field: %w", err)
+	}
+
+	for key, paths := range fileMap {
+		headers := r.MultipartForm.File[key]
+		if len(headers) == 0 {
+			return nil, fmt.Errorf("file %q is missing", key)
+		}
+		header := headers[0]
+		file := &Upload{
+			Filename:    header.Filename,
+			ContentType: header.Header.Get("Content-Type"),
+			Size:        header.Size,
+			header:      header,
+		}
+		for _, path := range paths {
+			if err := place(operations, strings.Split(path, "."), file); err != nil {
+				return nil, fmt.Errorf("invalid map path %q for file %q: %w", path, key, err)
+			}
+		}
+	}
+	return operations, nil
+}
+
+// place puts file at path within value, where the operations hold null
+func place(value interface{}, path []string, file *Upload) error {
+	if len(path) == 0 {
+		return fmt.Errorf("empty path")
+	}
+	key, rest := path[0], path[1:]
+	switch v := value.(type) {
+	case map[string]interface{}:
+		next, ok := v[key]
+		if !ok {
+			return fmt.Errorf("no %q", key)
+		}
+		if len(rest) > 0 {
+			return place(next, rest, file)
+		}
+		if next != nil {
+			return fmt.Errorf("%q is not null", key)
+		}
+		v[key] = file
+		return nil
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(v) {
+			return fmt.Errorf("no index %q", key)
+		}
+		if len(rest) > 0 {
+			return place(v[i], rest, file)
+		}
+		if v[i] != nil {
+			return fmt.Errorf("index %d is not null", i)
+		}
+		v[i] = file
+		return nil
+	}
+	return fmt.Errorf("%q is not an object or list", key)
+}
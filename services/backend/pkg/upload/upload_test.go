@@ -0,0 +1,81 @@
+package upload
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// request builds a multipart GraphQL request with a file for each key of files
+func request(t *testing.T, operations, fileMap string, files map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("operations", operations)
+	form.WriteField("map", fileMap)
+	for key, content := range files {
+		part, err := form.CreateFormFile(key, key+".ics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(content))
+	}
+	form.Close()
+	r := httptest.NewRequest(http.MethodPost, "/graphql", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	return r
+}
+
+func TestParse(t *testing.T) {
+	r := request(t,
+		`{"query": "mutation ($file: Upload!) { importCalendar(file: $file) { id } }", "variables": {"file": null}}`,
+		`{"0": ["variables.file"]}`, map[string]string{"0": "BEGIN:VCALENDAR"})
+	if !IsMultipart(r) {
+		t.Fatal("not recognised as multipart")
+	}
+	operations, err := Parse(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, ok := operations.(map[string]interface{})["variables"].(map[string]interface{})["file"].(*Upload)
+	if !ok {
+		t.Fatalf("operations = %+v, want the file in variables.file", operations)
+	}
+	if file.Filename != "0.ics" || file.Size != int64(len("BEGIN:VCALENDAR")) {
+		t.Errorf("file = %+v", file)
+	}
+	content, err := file.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(content); string(data) != "BEGIN:VCALENDAR" {
+		t.Errorf("content = %q", data)
+	}
+
+	// In a batch, a file may go in a list
+	r = request(t, `[{"query": "a"}, {"query": "b", "variables": {"files": [null, null]}}]`,
+		`{"a": ["1.variables.files.0"], "b": ["1.variables.files.1"]}`, map[string]string{"a": "A", "b": "B"})
+	operations, err = Parse(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := operations.([]interface{})[1].(map[string]interface{})["variables"].(map[string]interface{})["files"].([]interface{})
+	if files[0].(*Upload).Filename != "a.ics" || files[1].(*Upload).Filename != "b.ics" {
+		t.Errorf("files = %+v", files)
+	}
+
+	for _, tt := range []struct{ name, operations, fileMap string }{
+		{"invalid operations", `{`, `{"0": ["variables.file"]}`},
+		{"missing file", `{"variables": {"file": null}}`, `{"1": ["variables.file"]}`},
+		{"unknown variable", `{"variables": {}}`, `{"0": ["variables.file"]}`},
+		{"not null", `{"variables": {"file": "x"}}`, `{"0": ["variables.file"]}`},
+		{"bad index", `[{"variables": {"file": null}}]`, `{"0": ["1.variables.file"]}`},
+	} {
+		if _, err := Parse(request(t, tt.operations, tt.fileMap, map[string]string{"0": "x"})); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// Config tunes delivery
+type Config struct {
+	PollInterval time.Duration
+	Timeout      time.Duration // per request
+	MaxAttempts  int
+	BatchSize    int
+	// AllowInsecureURLs accepts http:// and private-network endpoints, for local development only
+	AllowInsecureURLs bool
+}
+
+// Backoff bounds between attempts
+const (
+	initialBackoff = 30 * time.Second
+	maxBackoff     = 6 * time.Hour
+)
+
+// Dispatcher sends queued deliveries
+type Dispatcher struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	cfg    Config
+}
+
+// NewDispatcher creates a dispatcher; call Run to start delivering
+func NewDispatcher(repo repository.WebhookRepository, cfg Config) *Dispatcher {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 20
+	}
+	return &Dispatcher{
+		repo: repo,
+		cfg:  cfg,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: newTransport(cfg.AllowInsecureURLs),
+			// Don't follow redirects: a 3xx is treated as a failed delivery
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// newTransport returns the delivery transport. Unless insecure URLs are allowed it refuses
+// to connect to non-public addresses, checking the address actually dialled so a host can't
+// pass ValidateURL and then be re-pointed at the internal network. Proxies from the
+// environment aren't used, since the check would then only see the proxy.
+func newTransport(allowInsecure bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowInsecure {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+				return fmt.Errorf("refusing to connect to non-public address %s", host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// AllowInsecureURLs reports whether http:// endpoints may be registered
+func (d *Dispatcher) AllowInsecureURLs() bool {
+	return d.cfg.AllowInsecureURLs
+}
+
+// Run delivers due webhooks until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue claims and sends one batch of due deliveries
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	// The lease covers the request timeout so a claimed delivery isn't picked up again
+	// by another instance while it is in flight
+	lease := d.cfg.Timeout + time.Minute
+	deliveries, err := d.repo.ClaimDueDeliveries(ctx, time.Now().UTC(), lease, d.cfg.BatchSize)
+	if err != nil {
+		log.Printf("Failed to claim webhook deliveries: %v", err)
+		return
+	}
+	for _, delivery := range deliveries {
+		d.deliver(ctx, delivery)
+	}
+}
+
+// deliver makes one attempt and records the outcome. An endpoint that can't be loaded
+// counts as a failed attempt, so the delivery is retried rather than left claimed.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+	var statusCode int
+	var sendErr error
+	endpoint, err := d.repo.GetEndpoint(ctx, delivery.EndpointID)
+	if err != nil {
+		sendErr = fmt.Errorf("error loading endpoint: %w", err)
+	} else {
+		statusCode, sendErr = d.send(ctx, endpoint, delivery)
+	}
+	if statusCode != 0 {
+		delivery.LastStatusCode = &statusCode
+	}
+	// Retrying can't help a deleted or disabled endpoint
+	gone := errors.Is(err, repository.ErrNotFound) || (endpoint != nil && !endpoint.Active)
+
+	now := time.Now().UTC()
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.LastError = nil
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= d.cfg.MaxAttempts || gone:
+		message := sendErr.Error()
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = &message
+		delivery.NextAttemptAt = nil
+		log.Printf("Webhook delivery %s to endpoint %s failed permanently after %d attempts: %v", delivery.ID, delivery.EndpointID, delivery.Attempts, sendErr)
+	default:
+		message := sendErr.Error()
+		next := now.Add(backoff(delivery.Attempts))
+		delivery.LastError = &message
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := d.repo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// send POSTs the signed payload. Any 2xx response counts as delivered.
+func (d *Dispatcher) send(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, error) {
+	if !endpoint.Active {
+		return 0, fmt.Errorf("endpoint is disabled")
+	}
+	if err := ValidateURL(endpoint.URL, d.cfg.AllowInsecureURLs); err != nil {
+		return 0, err
+	}
+
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "commute-planner-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the wait before the next attempt: 30s, 1m, 2m, ... capped at 6h
+func backoff(attempts int) time.Duration {
+	wait := initialBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// failingEndpoints is a webhook repository whose endpoint lookups fail
+type failingEndpoints struct {
+	*repository.MemoryWebhookRepository
+	err error
+}
+
+func (r failingEndpoints) GetEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	return nil, r.err
+}
+
+func TestDeliverRecordsEndpointLookupFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		maxAttempts int
+		wantStatus  models.WebhookDeliveryStatus
+	}{
+		{"transient error is retried", errors.New("connection reset"), 8, models.WebhookDeliveryPending},
+		{"last attempt fails", errors.New("connection reset"), 1, models.WebhookDeliveryFailed},
+		{"deleted endpoint fails", repository.ErrNotFound, 8, models.WebhookDeliveryFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := failingEndpoints{repository.NewMemoryWebhookRepository(), tt.err}
+			now := time.Now().UTC()
+			if err := repo.CreateDelivery(ctx, &models.WebhookDelivery{
+				ID: "delivery-1", EndpointID: "endpoint-1", EventType: EventJobCreated, Payload: "{}",
+				Status: models.WebhookDeliveryPending, NextAttemptAt: &now, CreatedAt: now, UpdatedAt: now,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			d := NewDispatcher(repo, Config{MaxAttempts: tt.maxAttempts})
+			d.deliverDue(ctx)
+
+			// Read the delivery back through a fresh claim far in the future
+			claimed, err := repo.ClaimDueDeliveries(ctx, now.Add(24*time.Hour), time.Minute, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantStatus == models.WebhookDeliveryFailed {
+				if len(claimed) != 0 {
+					t.Fatalf("failed delivery is still due: %+v", claimed[0])
+				}
+				return
+			}
+			if len(claimed) != 1 {
+				t.Fatalf("claimed %d deliveries, want the rescheduled one", len(claimed))
+			}
+			delivery := claimed[0]
+			if delivery.Attempts != 1 || delivery.LastError == nil {
+				t.Fatalf("delivery = attempts %d, last error %v, want 1 attempt with an error", delivery.Attempts, delivery.LastError)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Package webhooks delivers signed job lifecycle events to user-registered HTTPS endpoints.
+//
+// Publish writes one delivery row per matching endpoint (the delivery log doubles as an
+// outbox), and the Dispatcher worker sends due deliveries, retrying failures with
+// exponential backoff until MaxAttempts is reached.
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	EventJobCreated             = "job.created"
+	EventJobCompleted           = "job.completed"
+	EventJobFailed              = "job.failed"
+	EventRecommendationAccepted = "recommendation.accepted"
+	// EventPlanStale: a planned day's calendar changed and it is being re-planned
+	EventPlanStale = "plan.stale"
+	// EventCommuteReminder: it's nearly time to leave for a departure of an accepted plan
+	EventCommuteReminder = "commute.reminder"
+)
+
+// EventTypes lists every event an endpoint can subscribe to
+var EventTypes = []string{EventJobCreated, EventJobCompleted, EventJobFailed, EventRecommendationAccepted, EventPlanStale, EventCommuteReminder}
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Envelope is the JSON body POSTed to endpoints
+type Envelope struct {
+	ID        string      `json:"id"` // event ID, shared by every endpoint receiving the event
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// Sign computes the signature header value: hex HMAC-SHA256 over "<timestamp>.<body>".
+// Receivers recompute it with their secret and should reject stale timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret returns a random signing secret
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// ValidateURL checks an endpoint URL. Its host must resolve to public addresses only, so
+// endpoints can't be pointed at the backend's own network. Plain http and private hosts are
+// only accepted when allowInsecure is set (local development).
+func ValidateURL(raw string, allowInsecure bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q", raw)
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !allowInsecure {
+			return fmt.Errorf("webhook URL must use https")
+		}
+	default:
+		return fmt.Errorf("unsupported webhook URL scheme %q", u.Scheme)
+	}
+	if allowInsecure {
+		return nil
+	}
+
+	host := u.Hostname()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("can't resolve webhook host %q", host)
+	}
+	for _, addr := range addrs {
+		if blockedIP(addr.IP) {
+			return fmt.Errorf("webhook host %q resolves to a non-public address", host)
+		}
+	}
+	return nil
+}
+
+// lookupIPAddr resolves endpoint hosts; replaced in tests
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which net.IP doesn't class as private
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// blockedIP reports whether webhooks must not be sent to ip: loopback, private, link-local
+// (which covers the 169.254.169.254 cloud metadata service), unspecified and multicast addresses
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// ValidateEvents checks subscribed event types. An empty list subscribes to every event.
+func ValidateEvents(events []string) error {
+	for _, event := range events {
+		if !subscribes(EventTypes, event) {
+			return fmt.Errorf("unknown webhook event %q (expected one of %s)", event, strings.Join(EventTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// subscribes reports whether an endpoint's event list includes eventType
+func subscribes(events []string, eventType string) bool {
+	if len(events) == 0 {
+		return true
+	}
+	for _, event := range events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish queues an event for every active endpoint of the user subscribed to it
+func (d *Dispatcher) Publish(ctx context.Context, userID, eventType string, data interface{}) error {
+	endpoints, err := d.repo.ListEndpoints(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+
+	now := time.Now().UTC()
+	payload, err := json.Marshal(Envelope{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		CreatedAt: now,
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	for _, endpoint := range endpoints {
+		if !endpoint.Active || !subscribes(endpoint.Events, eventType) {
+			continue
+		}
+		delivery := &models.WebhookDelivery{
+			ID:            uuid.New().String(),
+			EndpointID:    endpoint.ID,
+			EventType:     eventType,
+			Payload:       string(payload),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := d.repo.CreateDelivery(ctx, delivery); err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
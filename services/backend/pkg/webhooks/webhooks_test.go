@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateURL(t *testing.T) {
+	hosts := map[string][]string{
+		"hooks.example.com":    {"93.184.216.34"},
+		"metadata.example.com": {"169.254.169.254"},
+		"mixed.example.com":    {"93.184.216.34", "10.0.0.5"},
+		"intranet.example.com": {"192.168.1.20"},
+		"cgnat.example.com":    {"100.64.0.1"},
+		"v6local.example.com":  {"fd00::1"},
+	}
+	previous := lookupIPAddr
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		if ip := net.ParseIP(host); ip != nil {
+			return []net.IPAddr{{IP: ip}}, nil
+		}
+		var addrs []net.IPAddr
+		for _, ip := range hosts[host] {
+			addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+		}
+		if addrs == nil {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return addrs, nil
+	}
+	t.Cleanup(func() { lookupIPAddr = previous })
+
+	tests := []struct {
+		name          string
+		url           string
+		allowInsecure bool
+		wantErr       string
+	}{
+		{"public https", "https://hooks.example.com/events", false, ""},
+		{"public ip", "https://93.184.216.34/events", false, ""},
+		{"http rejected", "http://hooks.example.com/events", false, "must use https"},
+		{"unsupported scheme", "ftp://hooks.example.com/events", false, "unsupported webhook URL scheme"},
+		{"no host", "https:///events", false, "invalid webhook URL"},
+		{"unresolvable", "https://missing.example.com/events", false, "can't resolve"},
+		{"loopback", "https://127.0.0.1/events", false, "non-public"},
+		{"ipv6 loopback", "https://[::1]/events", false, "non-public"},
+		{"unspecified", "https://0.0.0.0/events", false, "non-public"},
+		{"private literal", "https://10.1.2.3:8443/events", false, "non-public"},
+		{"metadata literal", "http://169.254.169.254/latest/meta-data", false, "must use https"},
+		{"metadata over https", "https://169.254.169.254/latest/meta-data", false, "non-public"},
+		{"metadata hostname", "https://metadata.example.com/", false, "non-public"},
+		{"any private address", "https://mixed.example.com/", false, "non-public"},
+		{"rfc1918 hostname", "https://intranet.example.com/", false, "non-public"},
+		{"shared address space", "https://cgnat.example.com/", false, "non-public"},
+		{"ipv6 unique local", "https://v6local.example.com/", false, "non-public"},
+		{"insecure allows http", "http://hooks.example.com/events", true, ""},
+		{"insecure allows localhost", "http://127.0.0.1:9000/events", true, ""},
+		{"insecure still checks scheme", "ftp://127.0.0.1/events", true, "unsupported webhook URL scheme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateURL(tt.url, tt.allowInsecure)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateURL(%q) = %v, want nil", tt.url, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateURL(%q) = %v, want error containing %q", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTransportRefusesNonPublicAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// The dial-time check catches hosts that resolve somewhere else than when they were validated
+	client := &http.Client{Transport: newTransport(false)}
+	_, err := client.Post(server.URL, "application/json", nil)
+	if err == nil || !strings.Contains(err.Error(), "non-public address") {
+		t.Fatalf("POST to %s = %v, want non-public address error", server.URL, err)
+	}
+
+	client = &http.Client{Transport: newTransport(true)}
+	resp, err := client.Post(server.URL, "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST with insecure URLs allowed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build embedui
+
+package webui
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// dist is the frontend's production build, copied here before building with -tags embedui
+//
+//go:embed all:dist
+var dist embed.FS
+
+// Embedded returns the frontend built into the binary
+func Embedded() (fs.FS, bool) {
+	assets, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil, false
+	}
+	return assets, true
+}
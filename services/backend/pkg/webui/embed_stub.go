@@ -0,0 +1,11 @@
+//go:build !embedui
+
+package webui
+
+import "io/fs"
+
+// Embedded returns the frontend built into the binary; binaries built without -tags
+// embedui have none
+func Embedded() (fs.FS, bool) {
+	return nil, false
+}
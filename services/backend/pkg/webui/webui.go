@@ -0,0 +1,62 @@
+// Package webui serves the frontend's production build from the API server, so small
+// deployments can run a single container.
+package webui
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Handler serves the single-page app in assets. Paths that aren't files get index.html,
+// so the app's client-side routes (/calendar, /jobs/123) survive a reload; missing
+// files with an extension (a stale /static/js/main.abc.js) are still 404s. Hashed
+// build output under /static is cached for good, everything else is revalidated.
+func Handler(assets fs.FS) http.Handler {
+	files := http.FileServer(http.FS(assets))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" {
+			name = "index.html"
+		}
+		info, err := fs.Stat(assets, name)
+		switch {
+		case err == nil && !info.IsDir():
+			if strings.HasPrefix(name, "static/") {
+				w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			} else {
+				w.Header().Set("Cache-Control", "no-cache")
+			}
+			// index.html is served directly: FileServer redirects it to the directory
+			if name == "index.html" {
+				serveIndex(w, r, assets)
+				return
+			}
+			files.ServeHTTP(w, r)
+		case path.Ext(name) != "":
+			http.NotFound(w, r)
+		default:
+			w.Header().Set("Cache-Control", "no-cache")
+			serveIndex(w, r, assets)
+		}
+	})
+}
+
+// serveIndex serves the app's entry point for r's path
+func serveIndex(w http.ResponseWriter, r *http.Request, assets fs.FS) {
+	index, err := fs.ReadFile(assets, "index.html")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodGet {
+		w.Write(index)
+	}
+}
@@ -0,0 +1,50 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHandler(t *testing.T) {
+	assets := fstest.MapFS{
+		"index.html":               {Data: []byte("<html>app</html>")},
+		"favicon.ico":              {Data: []byte("icon")},
+		"static/js/main.abc123.js": {Data: []byte("console.log(1)")},
+	}
+	handler := Handler(assets)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		wantStatus   int
+		wantBody     string
+		wantCacheCtl string
+	}{
+		{"root", http.MethodGet, "/", http.StatusOK, "<html>app</html>", "no-cache"},
+		{"index", http.MethodGet, "/index.html", http.StatusOK, "<html>app</html>", "no-cache"},
+		{"client route", http.MethodGet, "/jobs/123", http.StatusOK, "<html>app</html>", "no-cache"},
+		{"asset", http.MethodGet, "/favicon.ico", http.StatusOK, "icon", "no-cache"},
+		{"hashed asset", http.MethodGet, "/static/js/main.abc123.js", http.StatusOK, "console.log(1)", "public, max-age=31536000, immutable"},
+		{"missing asset", http.MethodGet, "/static/js/main.old.js", http.StatusNotFound, "", ""},
+		{"path traversal", http.MethodGet, "/../../etc/passwd", http.StatusOK, "<html>app</html>", "no-cache"},
+		{"write", http.MethodPost, "/jobs", http.StatusMethodNotAllowed, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get("Cache-Control"); tt.wantCacheCtl != "" && got != tt.wantCacheCtl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCacheCtl)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+// Package backend holds the API's GraphQL schema, schema.graphql, for the server to
+// serve and introspect.
+package backend
+
+import _ "embed"
+
+// Subgraph is the API's schema as an Apollo Federation subgraph, the SDL the gateway
+// composes into the supergraph
+//
+//go:embed schema.graphql
+var Subgraph string
+
+//go:embed federation.graphql
+var federation string
+
+// Schema is the GraphQL schema in SDL: the subgraph with the federation directives and
+// the gateway's entry points, _service and _entities
+var Schema = Subgraph + "\n" + federation
@@ -0,0 +1,104 @@
+//go:build integration
+
+package test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/commute-planner/backend/pkg/auth"
+	"github.com/commute-planner/backend/pkg/handlers"
+	"github.com/commute-planner/backend/pkg/models"
+)
+
+// TestPlanningFlow follows a new user from signup to the recommendations for a day of
+// their (demo) calendar
+func TestPlanningFlow(t *testing.T) {
+	s := startStack(t)
+	s.runWorker(t)
+
+	var signup struct {
+		Success bool             `json:"success"`
+		Data    *auth.AuthResult `json:"data"`
+		Error   string           `json:"error"`
+	}
+	status := s.call(t, http.MethodPost, "/auth/signup", "", handlers.SignupRequest{
+		Email:    "ada@example.com",
+		Password: "correct horse battery staple",
+		Name:     "Ada Lovelace",
+	}, &signup)
+	if status != http.StatusOK || !signup.Success {
+		t.Fatalf("signup: status %d, error %q", status, signup.Error)
+	}
+
+	var login struct {
+		Success bool             `json:"success"`
+		Data    *auth.AuthResult `json:"data"`
+		Error   string           `json:"error"`
+	}
+	status = s.call(t, http.MethodPost, "/auth/login", "", handlers.LoginRequest{
+		Email:    "ada@example.com",
+		Password: "correct horse battery staple",
+	}, &login)
+	if status != http.StatusOK || !login.Success || login.Data.AccessToken == "" {
+		t.Fatalf("login: status %d, error %q", status, login.Error)
+	}
+	token, userID := login.Data.AccessToken, login.Data.User.ID
+	if userID != signup.Data.User.ID {
+		t.Fatalf("login user = %s, want the signed up user %s", userID, signup.Data.User.ID)
+	}
+
+	var demo handlers.DemoResponse
+	status = s.call(t, http.MethodPost, "/demo/generate", token, handlers.DemoRequest{Days: 7, Seed: 42}, &demo)
+	if status != http.StatusOK || !demo.Success || demo.Data.CalendarEventsGenerated == 0 {
+		t.Fatalf("demo generation: status %d, response %+v", status, demo)
+	}
+	// Plan the first day after today that has meetings
+	var targetDate string
+	for _, day := range demo.Data.Days {
+		if day.Date > today().Format("2006-01-02") && day.Events > 0 {
+			targetDate = day.Date
+			break
+		}
+	}
+	if targetDate == "" {
+		t.Fatalf("no upcoming day with meetings in %+v", demo.Data.Days)
+	}
+
+	var created struct {
+		CreateJob models.Job `json:"createJob"`
+	}
+	s.graphql(t, token, `mutation CreateJob($input: CreateJobInput!) { createJob(input: $input) { id status } }`,
+		map[string]interface{}{"input": map[string]interface{}{"userId": userID, "targetDate": targetDate}}, &created)
+	if created.CreateJob.ID == "" {
+		t.Fatal("createJob returned no job")
+	}
+
+	// The worker picks the job up from the queue and completes it
+	var planned struct {
+		Job models.Job `json:"job"`
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		s.graphql(t, token, `query Job($id: ID!) { job(id: $id) { id status recommendations { optionType reasoning } } }`,
+			map[string]interface{}{"id": created.CreateJob.ID}, &planned)
+		if planned.Job.Status == models.JobStatusCompleted || planned.Job.Status == models.JobStatusFailed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s after 30s", planned.Job.Status)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	if planned.Job.Status != models.JobStatusCompleted {
+		t.Fatalf("job status = %s, want COMPLETED", planned.Job.Status)
+	}
+	if len(planned.Job.Recommendations) != 1 {
+		t.Fatalf("recommendations = %+v, want the worker's one", planned.Job.Recommendations)
+	}
+	rec := planned.Job.Recommendations[0]
+	if rec.OptionType != models.CommuteOptionFullDayOffice || rec.Reasoning == nil || *rec.Reasoning == "" {
+		t.Errorf("recommendation = %+v, want a reasoned FULL_DAY_OFFICE option", rec)
+	}
+}
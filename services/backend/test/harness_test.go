@@ -0,0 +1,339 @@
+//go:build integration
+
+// Package test holds end-to-end tests that run the backend binary against real Postgres
+// and Redis containers, with a stand-in for the AI service consuming the job queue.
+//
+//	go test -tags integration ./test/...
+//
+// Docker is required; without it the tests are skipped.
+package test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/commute-planner/backend/pkg/database"
+	"github.com/commute-planner/backend/pkg/models"
+	"github.com/commute-planner/backend/pkg/redis"
+	"github.com/commute-planner/backend/pkg/repository"
+)
+
+// stack is a running backend with its databases
+type stack struct {
+	// URL is the backend's base URL
+	URL         string
+	DatabaseURL string
+	RedisAddr   string
+}
+
+// backendDir is services/backend, found relative to this file
+func backendDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..")
+}
+
+// startStack starts Postgres and Redis, migrates the database the way a deployment does
+// and runs the server against them. Everything is torn down when the test ends.
+func startStack(t *testing.T) *stack {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	ctx := context.Background()
+
+	s := &stack{
+		DatabaseURL: startPostgres(t, ctx),
+		RedisAddr:   startRedis(t, ctx),
+	}
+	s.URL = startServer(t, map[string]string{
+		"DB_DRIVER":        database.DriverPostgres,
+		"DATABASE_URL":     s.DatabaseURL,
+		"REDIS_ADDR":       s.RedisAddr,
+		"REDIS_QUEUE_MODE": redis.QueueModeStream,
+		"AUTH_PROVIDER":    "jwt",
+		"JWT_SECRET":       "integration-test-secret-of-at-least-32-bytes",
+	})
+	return s
+}
+
+func startPostgres(t *testing.T, ctx context.Context) string {
+	t.Helper()
+	container, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage("postgres:15-alpine"),
+		postgres.WithDatabase("commute_planner"),
+		postgres.WithUsername("commute_planner"),
+		postgres.WithPassword("dev_password"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Minute)),
+	)
+	if err != nil {
+		t.Fatalf("starting Postgres: %v", err)
+	}
+	t.Cleanup(func() { container.Terminate(context.Background()) })
+	url, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// schemas/init.sql is the docker-compose init script; the migrations follow
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	databaseDir := filepath.Join(backendDir(), "..", "..", "database")
+	script, err := os.ReadFile(filepath.Join(databaseDir, "schemas", "init.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, string(script)); err != nil {
+		t.Fatalf("init.sql: %v", err)
+	}
+	if _, err := database.MigratePostgres(ctx, db, filepath.Join(databaseDir, "migrations"), false); err != nil {
+		t.Fatal(err)
+	}
+	return url
+}
+
+func startRedis(t *testing.T, ctx context.Context) string {
+	t.Helper()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "redis:7-alpine",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections").WithStartupTimeout(time.Minute),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("starting Redis: %v", err)
+	}
+	t.Cleanup(func() { container.Terminate(context.Background()) })
+	addr, err := container.Endpoint(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr
+}
+
+// startServer builds the server and runs it with env on a free port, returning its URL
+// once it is ready
+func startServer(t *testing.T, env map[string]string) string {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "server")
+	build := exec.Command("go", "build", "-o", binary, "./cmd")
+	build.Dir = backendDir()
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building the server: %v\n%s", err, out)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	server := exec.Command(binary)
+	server.Env = append(os.Environ(), fmt.Sprintf("PORT=%d", port))
+	for key, value := range env {
+		server.Env = append(server.Env, key+"="+value)
+	}
+	var logs bytes.Buffer
+	server.Stdout, server.Stderr = &logs, &logs
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		server.Process.Kill()
+		server.Wait()
+		if t.Failed() {
+			t.Logf("server logs:\n%s", logs.String())
+		}
+	})
+
+	url := fmt.Sprintf("http://127.0.0.1:%d", port)
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(url + "/readyz")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return url
+			}
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	t.Fatalf("server wasn't ready within 30s:\n%s", logs.String())
+	return ""
+}
+
+// call sends a JSON request, with token as the bearer token when set, and decodes the
+// response into out
+func (s *stack) call(t *testing.T, method, path, token string, body, out interface{}) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, path, data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// graphql runs a query and decodes its data into out, failing on errors
+func (s *stack) graphql(t *testing.T, token, query string, variables map[string]interface{}, out interface{}) {
+	t.Helper()
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	status := s.call(t, http.MethodPost, "/graphql", token, map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	}, &response)
+	if status != http.StatusOK || len(response.Errors) > 0 {
+		t.Fatalf("graphql %q: status %d, errors %v", query, status, response.Errors)
+	}
+	if err := json.Unmarshal(response.Data, out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runWorker stands in for the AI service: it takes jobs from the queue through a consumer
+// group and, like the service, writes progress and recommendations to the database
+// directly. Each job gets one recommendation putting its day's in-office meetings in the
+// office. It stops when the test ends.
+func (s *stack) runWorker(t *testing.T) {
+	t.Helper()
+	db, err := database.NewConnection(database.Config{Driver: database.DriverPostgres, URL: s.DatabaseURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(redis.Config{Addr: s.RedisAddr, QueueMode: redis.QueueModeStream})
+	ctx, cancel := context.WithCancel(context.Background())
+	consumer, err := redis.NewStreamConsumer(ctx, client, redis.ConsumerConfig{
+		Group:    "commute_workers",
+		Consumer: "integration-test",
+		Block:    time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		client.Close()
+		db.Close()
+	})
+
+	repos := repository.NewSQLRepositories(db)
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			deliveries, err := consumer.Receive(ctx, 1)
+			if err != nil {
+				if ctx.Err() == nil {
+					t.Logf("worker: %v", err)
+					time.Sleep(100 * time.Millisecond)
+				}
+				continue
+			}
+			for _, delivery := range deliveries {
+				if err := processJob(ctx, repos, delivery.Message.JobID); err != nil {
+					t.Errorf("worker: job %s: %v", delivery.Message.JobID, err)
+				}
+				delivery.Ack(ctx)
+			}
+		}
+	}()
+}
+
+func processJob(ctx context.Context, repos repository.Repositories, jobID string) error {
+	inProgress := string(models.JobStatusInProgress)
+	job, err := repos.Jobs.Update(ctx, jobID, repository.JobUpdate{Status: &inProgress})
+	if err != nil {
+		return err
+	}
+
+	targetDate := job.TargetDate[:10]
+	events, err := repos.Events.ListByUser(ctx, job.UserID, &targetDate)
+	if err != nil {
+		return err
+	}
+	officeMeetings, remoteMeetings := []string{}, []string{}
+	for _, event := range events {
+		if event.AttendanceMode == models.AttendanceMustBeInOffice {
+			officeMeetings = append(officeMeetings, event.Summary)
+		} else {
+			remoteMeetings = append(remoteMeetings, event.Summary)
+		}
+	}
+	office, _ := json.Marshal(officeMeetings)
+	remote, _ := json.Marshal(remoteMeetings)
+	officeJSON, remoteJSON := string(office), string(remote)
+	reasoning := fmt.Sprintf("%d of %d meetings need you in the office", len(officeMeetings), len(events))
+	if err := repos.Recommendations.Create(ctx, &models.CommuteRecommendation{
+		JobID:          jobID,
+		OptionRank:     1,
+		OptionType:     models.CommuteOptionFullDayOffice,
+		OfficeMeetings: &officeJSON,
+		RemoteMeetings: &remoteJSON,
+		Reasoning:      &reasoning,
+	}); err != nil {
+		return err
+	}
+
+	completed := string(models.JobStatusCompleted)
+	progress := 1.0
+	result := `{"total_options":1,"analysis_complete":true}`
+	_, err = repos.Jobs.Update(ctx, jobID, repository.JobUpdate{Status: &completed, Progress: &progress, Result: &result})
+	return err
+}
+
+// today is the current date in UTC, as the demo generator counts days
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
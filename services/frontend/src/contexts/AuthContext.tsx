@@ -62,6 +62,34 @@ export const AuthProvider: React.FC<{ children: ReactNode }> = ({ children }) =>
   // Initialize auth state from localStorage
   useEffect(() => {
     const initAuth = async () => {
+      // A redeemed magic link lands here with the session's tokens in the URL fragment
+      const fragment = new URLSearchParams(window.location.hash.slice(1));
+      const linkToken = fragment.get('access_token');
+      if (linkToken) {
+        window.history.replaceState(null, '', window.location.pathname + window.location.search);
+        try {
+          const response = await fetch('http://localhost:8080/auth/me', {
+            headers: {
+              'Authorization': `Bearer ${linkToken}`,
+            },
+          });
+          const result = await response.json();
+          if (result.success) {
+            saveAuthData({
+              user: result.data.user,
+              accessToken: linkToken,
+              refreshToken: fragment.get('refresh_token') || undefined,
+              tokenType: fragment.get('token_type') || 'Bearer',
+              expiresIn: Number(fragment.get('expires_in')) || 0,
+            });
+            setIsLoading(false);
+            return;
+          }
+        } catch (error) {
+          console.error('Failed to complete magic link login:', error);
+        }
+      }
+
       const token = localStorage.getItem(TOKEN_KEY);
       const userData = localStorage.getItem(USER_KEY);
       
@@ -157,6 +185,16 @@ export const AuthProvider: React.FC<{ children: ReactNode }> = ({ children }) =>
   };
 
   const logout = () => {
+    // Revoke the token server-side so it can't be reused; clear local state regardless
+    const token = localStorage.getItem(TOKEN_KEY);
+    if (token) {
+      fetch('http://localhost:8080/auth/logout', {
+        method: 'POST',
+        headers: {
+          'Authorization': `Bearer ${token}`,
+        },
+      }).catch((error) => console.warn('Failed to revoke token on logout:', error));
+    }
     clearAuthData();
   };
 
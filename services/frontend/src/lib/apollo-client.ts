@@ -22,7 +22,12 @@ const wsLink = new GraphQLWsLink(
   })
 );
 
-// Auth link - automatically adds JWT token to requests
+// Identifies this app to the API, which counts each client version's operations before
+// breaking changes. Set REACT_APP_VERSION at build time.
+const CLIENT_NAME = 'web';
+const CLIENT_VERSION = process.env.REACT_APP_VERSION || 'dev';
+
+// Auth link - automatically adds JWT token and client headers to requests
 const authLink = setContext((_, { headers }) => {
   const token = localStorage.getItem('commute_planner_token');
   
@@ -30,6 +35,8 @@ const authLink = setContext((_, { headers }) => {
     headers: {
       ...headers,
       authorization: token ? `Bearer ${token}` : '',
+      'X-Client-Name': CLIENT_NAME,
+      'X-Client-Version': CLIENT_VERSION,
     },
   };
 });